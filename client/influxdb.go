@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -89,6 +90,10 @@ func ParseConnectionString(path string, ssl bool) (url.URL, error) {
 // Username/Password are optional. They will be passed via basic auth if provided.
 // UserAgent: If not provided, will default "InfluxDBClient",
 // Timeout: If not provided, will default to 0 (no timeout)
+// CACert: If provided, the PEM file of the certificate authorities that verify the server,
+// instead of the system pool.
+// ClientCert/ClientKey: If provided, the PEM files of the key pair presented to servers
+// requiring client certificates.
 type Config struct {
 	URL              url.URL
 	UnixSocket       string
@@ -99,6 +104,9 @@ type Config struct {
 	Precision        string
 	WriteConsistency string
 	UnsafeSsl        bool
+	CACert           string
+	ClientCert       string
+	ClientKey        string
 }
 
 // NewConfig will create a config to be used in connecting to the client
@@ -138,6 +146,26 @@ func NewClient(c Config) (*Client, error) {
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: c.UnsafeSsl,
 	}
+	if c.CACert != "" {
+		pem, err := ioutil.ReadFile(c.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA certificate: %s", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA certificate %s", c.CACert)
+		}
+	}
+	if c.ClientCert != "" || c.ClientKey != "" {
+		if c.ClientCert == "" || c.ClientKey == "" {
+			return nil, errors.New("client certificate and key must be provided together")
+		}
+		cert, err := tls.LoadX509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
 
 	tr := &http.Transport{
 		TLSClientConfig: tlsConfig,
@@ -375,7 +403,7 @@ func (c *Client) WriteLineProtocol(data, database, retentionPolicy, precision, w
 	}
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf(string(body))
+		err := &WriteError{StatusCode: resp.StatusCode, Body: string(body)}
 		response.Err = err
 		return &response, err
 	}
@@ -383,6 +411,19 @@ func (c *Client) WriteLineProtocol(data, database, retentionPolicy, precision, w
 	return nil, nil
 }
 
+// WriteError is returned by WriteLineProtocol when the server rejects a write.
+type WriteError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Body is the body of the response, holding the error of the server.
+	Body string
+}
+
+func (e *WriteError) Error() string {
+	return e.Body
+}
+
 // Ping will check to see if the server is up
 // Ping returns how long the request took, the version of the server it connected to, and an error if one occurred.
 func (c *Client) Ping() (time.Duration, string, error) {
@@ -2,15 +2,24 @@ package client_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -879,6 +888,120 @@ war3JNM1mGB3o2iAtuOJlFIKLpI1x+1e8pI=
 	}
 }
 
+func TestClient_ClientCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influx-tls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A private CA signs both the certificate of the server and of the client.
+	caKey, caCert := generateCertificate(t, "ca", nil, nil)
+	serverKey, serverCert := generateCertificate(t, "server", caKey, caCert)
+	clientKey, clientCert := generateCertificate(t, "client", caKey, caCert)
+
+	caFile := writeCertificate(t, dir, "ca", nil, caCert)
+	certFile := writeCertificate(t, dir, "client", nil, clientCert)
+	keyFile := writeCertificate(t, dir, "client-key", clientKey, nil)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data client.Response
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(data)
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+
+	tests := []struct {
+		name    string
+		config  client.Config
+		connect bool
+	}{
+		{name: "no CA", config: client.Config{ClientCert: certFile, ClientKey: keyFile}},
+		{name: "no client certificate", config: client.Config{CACert: caFile}},
+		{name: "CA and client certificate", config: client.Config{CACert: caFile, ClientCert: certFile, ClientKey: keyFile}, connect: true},
+	}
+
+	for _, test := range tests {
+		config := test.config
+		config.URL = *u
+		c, err := client.NewClient(config)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		_, err = c.Query(client.Query{})
+		if test.connect != (err == nil) {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+	}
+
+	if _, err := client.NewClient(client.Config{URL: *u, ClientCert: certFile}); err == nil {
+		t.Fatal("expected error for client certificate without key")
+	} else if _, err := client.NewClient(client.Config{URL: *u, CACert: keyFile}); err == nil {
+		t.Fatal("expected error for CA file without certificates")
+	}
+}
+
+// generateCertificate returns a key and its certificate for 127.0.0.1,
+// signed by the parent, or self-signed if parent is nil.
+func generateCertificate(t *testing.T, commonName string, parentKey *ecdsa.PrivateKey, parent *x509.Certificate) (*ecdsa.PrivateKey, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
+
+// writeCertificate writes the key or the certificate as a PEM file in dir and
+// returns its path.
+func writeCertificate(t *testing.T, dir, name string, key *ecdsa.PrivateKey, cert *x509.Certificate) string {
+	block := &pem.Block{}
+	if key != nil {
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.Type, block.Bytes = "EC PRIVATE KEY", der
+	} else {
+		block.Type, block.Bytes = "CERTIFICATE", cert.Raw
+	}
+	path := filepath.Join(dir, name+".pem")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
 func TestChunkedResponse(t *testing.T) {
 	s := `{"results":[{},{}]}{"results":[{}]}`
 	r := client.NewChunkedResponse(strings.NewReader(s))
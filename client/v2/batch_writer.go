@@ -0,0 +1,354 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultBatchWriterBatchSize is the default number of points written at once.
+	DefaultBatchWriterBatchSize = 5000
+
+	// DefaultBatchWriterFlushInterval is the default interval partial
+	// batches are written at.
+	DefaultBatchWriterFlushInterval = time.Second
+
+	// DefaultBatchWriterMaxRetries is the default number of times a failed
+	// batch is retried.
+	DefaultBatchWriterMaxRetries = 3
+
+	// DefaultBatchWriterRetryInterval is the default time before the first
+	// retry of a failed batch.
+	DefaultBatchWriterRetryInterval = time.Second
+
+	// DefaultBatchWriterMaxRetryInterval is the default limit of the time
+	// between retries.
+	DefaultBatchWriterMaxRetryInterval = 30 * time.Second
+)
+
+var (
+	// ErrBatchWriterClosed is returned when writing to a closed BatchWriter.
+	ErrBatchWriterClosed = errors.New("batch writer closed")
+
+	// ErrBufferFull is returned when a point is dropped by the
+	// OverflowDropNewest policy.
+	ErrBufferFull = errors.New("batch writer buffer full")
+)
+
+// OverflowPolicy is what a BatchWriter does with a point written while its
+// buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the write until there is room in the buffer.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest drops the oldest buffered point to make room.
+	OverflowDropOldest
+
+	// OverflowDropNewest drops the written point, and returns ErrBufferFull.
+	OverflowDropNewest
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowDropNewest:
+		return "drop-newest"
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
+// BatchWriterConfig is the config data needed to create a BatchWriter.
+type BatchWriterConfig struct {
+	// BatchPointsConfig is the database, retention policy, precision and
+	// write consistency of the written batches.
+	BatchPointsConfig
+
+	// BatchSize is the number of points written at once, defaults to 5000.
+	BatchSize int
+
+	// FlushInterval is the interval a partial batch is written at, defaults
+	// to 1 second.
+	FlushInterval time.Duration
+
+	// BufferSize is the number of points that can be buffered while batches
+	// are written, defaults to 10 batches.
+	BufferSize int
+
+	// Overflow is what is done with a point written while the buffer is
+	// full, defaults to blocking the write.
+	Overflow OverflowPolicy
+
+	// MaxRetries is the number of times a failed batch is retried before its
+	// points are dropped, defaults to 3.  A negative number disables retries.
+	// Only transport errors and server errors are retried, batches rejected
+	// by the server are dropped.
+	MaxRetries int
+
+	// RetryInterval is the time before the first retry of a failed batch,
+	// doubled with each retry up to MaxRetryInterval.  They default to 1
+	// second and 30 seconds.
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+
+	// OnError is called, if set, with the error and the points of a batch
+	// that failed every retry.  It is called from the goroutine of the
+	// writer, so it should not block.
+	OnError func(err error, points []*Point)
+}
+
+// BatchWriterStats are the counters of a BatchWriter.
+type BatchWriterStats struct {
+	// Written is the number of points written.
+	Written int64
+
+	// Dropped is the number of points dropped because the buffer was full,
+	// or their batch failed every retry.
+	Dropped int64
+
+	// Retried is the number of times a failed batch was retried.
+	Retried int64
+}
+
+// BatchWriter writes individual points in batches, by size and age, in the
+// background.  Batches failed by transport or server errors are retried with
+// exponential backoff.
+// BatchWriter is safe for concurrent use by multiple goroutines.
+type BatchWriter struct {
+	client Client
+	config BatchWriterConfig
+
+	mu     sync.Mutex
+	cond   *sync.Cond // signalled when there is room in the buffer
+	buf    []*Point
+	closed bool
+
+	full    chan struct{} // signals the buffer holds a full batch
+	closing chan struct{}
+	done    chan struct{}
+
+	stats BatchWriterStats
+}
+
+// NewBatchWriter returns a BatchWriter of the points written to the client.
+// It must be closed to write the buffered points.
+func NewBatchWriter(c Client, conf BatchWriterConfig) (*BatchWriter, error) {
+	if conf.Precision == "" {
+		conf.Precision = "ns"
+	}
+	if _, err := NewBatchPoints(conf.BatchPointsConfig); err != nil {
+		return nil, err
+	}
+
+	if conf.BatchSize < 0 {
+		return nil, errors.New("batch size must be non-negative")
+	} else if conf.BatchSize == 0 {
+		conf.BatchSize = DefaultBatchWriterBatchSize
+	}
+	if conf.FlushInterval < 0 {
+		return nil, errors.New("flush interval must be non-negative")
+	} else if conf.FlushInterval == 0 {
+		conf.FlushInterval = DefaultBatchWriterFlushInterval
+	}
+	if conf.BufferSize < 0 {
+		return nil, errors.New("buffer size must be non-negative")
+	} else if conf.BufferSize == 0 {
+		conf.BufferSize = 10 * conf.BatchSize
+	} else if conf.BufferSize < conf.BatchSize {
+		return nil, errors.New("buffer size must be at least the batch size")
+	}
+	switch conf.Overflow {
+	case OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+	default:
+		return nil, fmt.Errorf("invalid overflow policy: %s", conf.Overflow)
+	}
+	if conf.MaxRetries == 0 {
+		conf.MaxRetries = DefaultBatchWriterMaxRetries
+	}
+	if conf.RetryInterval <= 0 {
+		conf.RetryInterval = DefaultBatchWriterRetryInterval
+	}
+	if conf.MaxRetryInterval <= 0 {
+		conf.MaxRetryInterval = DefaultBatchWriterMaxRetryInterval
+	}
+
+	w := &BatchWriter{
+		client:  c,
+		config:  conf,
+		full:    make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	w.cond = sync.NewCond(&w.mu)
+	go w.run()
+	return w, nil
+}
+
+// Write buffers a point to be written with the next batch.  When the buffer
+// is full, it blocks or drops a point according to the overflow policy.
+func (w *BatchWriter) Write(p *Point) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for !w.closed && len(w.buf) >= w.config.BufferSize {
+		switch w.config.Overflow {
+		case OverflowDropOldest:
+			w.buf[0] = nil
+			w.buf = w.buf[1:]
+			atomic.AddInt64(&w.stats.Dropped, 1)
+		case OverflowDropNewest:
+			atomic.AddInt64(&w.stats.Dropped, 1)
+			return ErrBufferFull
+		default:
+			w.cond.Wait()
+		}
+	}
+	if w.closed {
+		return ErrBatchWriterClosed
+	}
+
+	w.buf = append(w.buf, p)
+	if len(w.buf) >= w.config.BatchSize {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Stats returns the counters of the writer.
+func (w *BatchWriter) Stats() BatchWriterStats {
+	return BatchWriterStats{
+		Written: atomic.LoadInt64(&w.stats.Written),
+		Dropped: atomic.LoadInt64(&w.stats.Dropped),
+		Retried: atomic.LoadInt64(&w.stats.Retried),
+	}
+}
+
+// Close stops the writer, after writing or dropping the buffered points.
+// Failed batches are not retried once the writer is closed.  Blocked writes
+// return ErrBatchWriterClosed.
+func (w *BatchWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+
+	close(w.closing)
+	<-w.done
+	return nil
+}
+
+// run writes the full batches as they are buffered, and the partial batch
+// every flush interval, until the writer is closed.
+func (w *BatchWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		partial := false
+		select {
+		case <-w.closing:
+			for batch := w.next(true); batch != nil; batch = w.next(true) {
+				w.writeBatch(batch)
+			}
+			return
+		case <-w.full:
+		case <-ticker.C:
+			partial = true
+		}
+
+		for batch := w.next(partial); batch != nil; batch = w.next(partial) {
+			w.writeBatch(batch)
+		}
+	}
+}
+
+// next removes and returns the next batch of the buffer, or nil if it holds
+// less than a batch and partial batches aren't taken.
+func (w *BatchWriter) next(partial bool) []*Point {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := len(w.buf)
+	if n == 0 || (n < w.config.BatchSize && !partial) {
+		return nil
+	} else if n > w.config.BatchSize {
+		n = w.config.BatchSize
+	}
+
+	batch := make([]*Point, n)
+	copy(batch, w.buf)
+	w.buf = append(w.buf[:0], w.buf[n:]...)
+	w.cond.Broadcast()
+	return batch
+}
+
+// writeBatch writes a batch, retrying it with exponential backoff.  The
+// points are dropped if the batch is rejected, every retry fails, or the
+// writer is closed while waiting to retry.
+func (w *BatchWriter) writeBatch(points []*Point) {
+	bp, _ := NewBatchPoints(w.config.BatchPointsConfig)
+	bp.AddPoints(points)
+
+	interval := w.config.RetryInterval
+	for retry := 0; ; retry++ {
+		err := w.client.WriteCtx(context.Background(), bp)
+		if err == nil {
+			atomic.AddInt64(&w.stats.Written, int64(len(points)))
+			return
+		}
+
+		if retry >= w.config.MaxRetries || !retryable(err) {
+			w.drop(err, points)
+			return
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-w.closing:
+			timer.Stop()
+			w.drop(err, points)
+			return
+		}
+		if interval *= 2; interval > w.config.MaxRetryInterval {
+			interval = w.config.MaxRetryInterval
+		}
+		atomic.AddInt64(&w.stats.Retried, 1)
+	}
+}
+
+// drop drops the points of a failed batch.
+func (w *BatchWriter) drop(err error, points []*Point) {
+	atomic.AddInt64(&w.stats.Dropped, int64(len(points)))
+	if w.config.OnError != nil {
+		w.config.OnError(err, points)
+	}
+}
+
+// retryable returns true if a write failed by a transport or server error.
+// Writes rejected by the server, for their points or their database, fail
+// the same way when retried.
+func retryable(err error) bool {
+	if err, ok := err.(*WriteError); ok {
+		return err.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatchWriter_Write(t *testing.T) {
+	var mu sync.Mutex
+	var requests, lines int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		if requests++; requests == 1 {
+			// Fail the first write, so it is retried.
+			http.Error(w, "timeout", http.StatusServiceUnavailable)
+			return
+		} else if db := r.URL.Query().Get("db"); db != "db0" {
+			t.Errorf("unexpected database: %s", db)
+		}
+		lines += strings.Count(string(body), "\n")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	c, _ := NewHTTPClient(HTTPConfig{Addr: ts.URL})
+	defer c.Close()
+
+	w, err := NewBatchWriter(c, BatchWriterConfig{
+		BatchPointsConfig: BatchPointsConfig{Database: "db0"},
+		BatchSize:         2,
+		FlushInterval:     time.Hour,
+		RetryInterval:     time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := w.Write(newTestPoint(t, i)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Wait for the full batches, as failed batches aren't retried once the
+	// writer is closed.
+	for deadline := time.Now().Add(10 * time.Second); w.Stats().Written < 4; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for writes: %+v", w.Stats())
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := w.Write(newTestPoint(t, 5)); err != ErrBatchWriterClosed {
+		t.Fatalf("unexpected error after close: %v", err)
+	}
+
+	if lines != 5 {
+		t.Fatalf("unexpected number of written points: %d", lines)
+	} else if stats := w.Stats(); stats != (BatchWriterStats{Written: 5, Retried: 1}) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestBatchWriter_Rejected(t *testing.T) {
+	var requests int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		http.Error(w, `{"error":"partial write: field type conflict"}`, http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	c, _ := NewHTTPClient(HTTPConfig{Addr: ts.URL})
+	defer c.Close()
+
+	var failed error
+	w, err := NewBatchWriter(c, BatchWriterConfig{
+		BatchPointsConfig: BatchPointsConfig{Database: "db0"},
+		BatchSize:         1,
+		FlushInterval:     time.Hour,
+		RetryInterval:     time.Millisecond,
+		OnError:           func(err error, points []*Point) { failed = err },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := w.Write(newTestPoint(t, 0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.Close()
+
+	// Rejected batches fail the same way when retried, so they are dropped.
+	if n := atomic.LoadInt64(&requests); n != 1 {
+		t.Fatalf("unexpected number of requests: %d", n)
+	} else if err, ok := failed.(*WriteError); !ok || err.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected error: %v", failed)
+	} else if stats := w.Stats(); stats != (BatchWriterStats{Dropped: 1}) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestBatchWriter_CloseDuringRetry(t *testing.T) {
+	requested := make(chan struct{}, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+		http.Error(w, "timeout", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c, _ := NewHTTPClient(HTTPConfig{Addr: ts.URL})
+	defer c.Close()
+
+	w, err := NewBatchWriter(c, BatchWriterConfig{
+		BatchPointsConfig: BatchPointsConfig{Database: "db0"},
+		BatchSize:         1,
+		FlushInterval:     time.Hour,
+		RetryInterval:     time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := w.Write(newTestPoint(t, 0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-requested
+
+	// Close doesn't wait for the retry of the failed batch.
+	closed := make(chan struct{})
+	go func() {
+		w.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for close")
+	}
+	if stats := w.Stats(); stats != (BatchWriterStats{Dropped: 1}) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestBatchWriter_Overflow(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowDropOldest, OverflowDropNewest} {
+		release := make(chan struct{})
+		var failed []*Point
+		c := &blockingClient{writing: make(chan struct{}), release: release, err: errors.New("write failed")}
+		w, err := NewBatchWriter(c, BatchWriterConfig{
+			BatchSize:     1,
+			BufferSize:    2,
+			Overflow:      policy,
+			FlushInterval: time.Hour,
+			MaxRetries:    -1,
+			OnError:       func(err error, points []*Point) { failed = append(failed, points...) },
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// The first point is written, and blocks the writer while the next
+		// points overflow the buffer.
+		if err := w.Write(newTestPoint(t, 0)); err != nil {
+			t.Fatalf("%s: unexpected error: %v", policy, err)
+		}
+		c.waitForWrite()
+
+		var dropped int
+		for i := 1; i <= 4; i++ {
+			if err := w.Write(newTestPoint(t, i)); err == ErrBufferFull {
+				dropped++
+			} else if err != nil {
+				t.Fatalf("%s: unexpected error: %v", policy, err)
+			}
+		}
+		if policy == OverflowDropNewest && dropped != 2 {
+			t.Fatalf("%s: unexpected number of rejected points: %d", policy, dropped)
+		}
+
+		close(release)
+		w.Close()
+
+		if stats := w.Stats(); stats.Dropped != 5 || stats.Written != 0 {
+			t.Fatalf("%s: unexpected stats: %+v", policy, stats)
+		} else if len(failed) != 3 {
+			t.Fatalf("%s: unexpected failed points: %v", policy, failed)
+		}
+		// The oldest points are dropped by OverflowDropOldest.
+		if exp := map[OverflowPolicy]int64{OverflowDropOldest: 3, OverflowDropNewest: 1}[policy]; failed[1].UnixNano() != exp {
+			t.Fatalf("%s: unexpected failed point: %v", policy, failed[1])
+		}
+	}
+}
+
+func TestNewBatchWriter_InvalidConfig(t *testing.T) {
+	for _, conf := range []BatchWriterConfig{
+		{BatchSize: -1},
+		{FlushInterval: -1},
+		{BatchSize: 10, BufferSize: 5},
+		{Overflow: OverflowPolicy(10)},
+		{BatchPointsConfig: BatchPointsConfig{Precision: "invalid"}},
+	} {
+		if _, err := NewBatchWriter(nil, conf); err == nil {
+			t.Fatalf("expected error for config %+v", conf)
+		}
+	}
+}
+
+// blockingClient fails writes, after waiting for release.
+type blockingClient struct {
+	Client
+	writing chan struct{}
+	release chan struct{}
+	once    sync.Once
+	err     error
+}
+
+func (c *blockingClient) WriteCtx(ctx context.Context, bp BatchPoints) error {
+	c.once.Do(func() { close(c.writing) })
+	<-c.release
+	return c.err
+}
+
+func (c *blockingClient) waitForWrite() {
+	<-c.writing
+}
+
+func newTestPoint(t *testing.T, i int) *Point {
+	pt, err := NewPoint("cpu", nil, map[string]interface{}{"value": i}, time.Unix(0, int64(i)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return pt
+}
@@ -3,6 +3,7 @@ package client // import "github.com/influxdata/influxdb/client/v2"
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -70,10 +71,24 @@ type Client interface {
 	// Write takes a BatchPoints object and writes all Points to InfluxDB.
 	Write(bp BatchPoints) error
 
+	// WriteCtx is like Write, but the write is cancelled when the context
+	// is done.
+	WriteCtx(ctx context.Context, bp BatchPoints) error
+
 	// Query makes an InfluxDB Query on the database. This will fail if using
 	// the UDP client.
 	Query(q Query) (*Response, error)
 
+	// QueryCtx is like Query, but the query is cancelled when the context
+	// is done.
+	QueryCtx(ctx context.Context, q Query) (*Response, error)
+
+	// QueryAsChunk makes a chunked InfluxDB Query on the database and returns
+	// its chunks as they are received, instead of buffering the entire
+	// result.  The ChunkedResponse must be closed.  This will fail if using
+	// the UDP client.
+	QueryAsChunk(ctx context.Context, q Query) (*ChunkedResponse, error)
+
 	// Close releases any resources a Client may be using.
 	Close() error
 }
@@ -353,7 +368,13 @@ func NewPointFrom(pt models.Point) *Point {
 	return &Point{pt: pt}
 }
 
+// Write writes the points of the batch.
 func (c *client) Write(bp BatchPoints) error {
+	return c.WriteCtx(context.Background(), bp)
+}
+
+// WriteCtx writes the points of the batch, until the context is done.
+func (c *client) WriteCtx(ctx context.Context, bp BatchPoints) error {
 	var b bytes.Buffer
 
 	for _, p := range bp.Points() {
@@ -385,7 +406,7 @@ func (c *client) Write(bp BatchPoints) error {
 	params.Set("consistency", bp.WriteConsistency())
 	req.URL.RawQuery = params.Encode()
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
 	if err != nil {
 		return err
 	}
@@ -397,13 +418,25 @@ func (c *client) Write(bp BatchPoints) error {
 	}
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		var err = fmt.Errorf(string(body))
-		return err
+		return &WriteError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
 }
 
+// WriteError is returned by Write when the server rejects a write.
+type WriteError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Body is the body of the response, holding the error of the server.
+	Body string
+}
+
+func (e *WriteError) Error() string {
+	return e.Body
+}
+
 // Query defines a query to send to the server.
 type Query struct {
 	Command    string
@@ -468,10 +501,86 @@ type Result struct {
 	Series   []models.Row
 	Messages []*Message
 	Err      string `json:"error,omitempty"`
+
+	// Partial is set on the chunks of a chunked response when the next chunk
+	// continues the result of the same statement.
+	Partial bool `json:"partial,omitempty"`
 }
 
 // Query sends a command to the server and returns the Response.
 func (c *client) Query(q Query) (*Response, error) {
+	return c.QueryCtx(context.Background(), q)
+}
+
+// QueryCtx sends a command to the server and returns the Response, until the
+// context is done.
+func (c *client) QueryCtx(ctx context.Context, q Query) (*Response, error) {
+	resp, err := c.query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response Response
+	if q.Chunked {
+		cr := NewChunkedResponse(resp.Body)
+		for {
+			r, err := cr.NextResponse()
+			if err != nil {
+				// If we got an error while decoding the response, send that back.
+				return nil, err
+			}
+
+			if r == nil {
+				break
+			}
+
+			response.Results = append(response.Results, r.Results...)
+			if r.Err != "" {
+				response.Err = r.Err
+				break
+			}
+		}
+	} else {
+		dec := json.NewDecoder(resp.Body)
+		dec.UseNumber()
+		decErr := dec.Decode(&response)
+
+		// ignore this error if we got an invalid status code
+		if decErr != nil && decErr.Error() == "EOF" && resp.StatusCode != http.StatusOK {
+			decErr = nil
+		}
+		// If we got a valid decode error, send that back
+		if decErr != nil {
+			return nil, fmt.Errorf("unable to decode json: received status code %d err: %s", resp.StatusCode, decErr)
+		}
+	}
+
+	// If we don't have an error in our json response, and didn't get statusOK
+	// then send back an error
+	if resp.StatusCode != http.StatusOK && response.Error() == nil {
+		return &response, fmt.Errorf("received status code %d from server", resp.StatusCode)
+	}
+	return &response, nil
+}
+
+// QueryAsChunk sends a command to the server with a chunked response, and
+// returns the response to read its chunks from as they are received.
+func (c *client) QueryAsChunk(ctx context.Context, q Query) (*ChunkedResponse, error) {
+	q.Chunked = true
+	resp, err := c.query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := NewChunkedResponse(resp.Body)
+	cr.body = resp.Body
+	return cr, nil
+}
+
+// query sends a query request and returns the response of the server, after
+// checking that it is a JSON response from InfluxDB.
+func (c *client) query(ctx context.Context, q Query) (*http.Response, error) {
 	u := c.url
 	u.Path = "query"
 
@@ -509,22 +618,31 @@ func (c *client) Query(q Query) (*Response, error) {
 	}
 	req.URL.RawQuery = params.Encode()
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// checkResponse returns an error if the response isn't a JSON response from
+// InfluxDB.
+func checkResponse(resp *http.Response) error {
 	// If we lack a X-Influxdb-Version header, then we didn't get a response from influxdb
 	// but instead some other service. If the error code is also a 500+ code, then some
 	// downstream loadbalancer/proxy/etc had an issue and we should report that.
 	if resp.Header.Get("X-Influxdb-Version") == "" && resp.StatusCode >= http.StatusInternalServerError {
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil || len(body) == 0 {
-			return nil, fmt.Errorf("received status code %d from downstream server", resp.StatusCode)
+			return fmt.Errorf("received status code %d from downstream server", resp.StatusCode)
 		}
 
-		return nil, fmt.Errorf("received status code %d from downstream server, with response body: %q", resp.StatusCode, body)
+		return fmt.Errorf("received status code %d from downstream server, with response body: %q", resp.StatusCode, body)
 	}
 
 	// If we get an unexpected content type, then it is also not from influx direct and therefore
@@ -534,53 +652,12 @@ func (c *client) Query(q Query) (*Response, error) {
 		// like downstream serving a large file
 		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
 		if err != nil || len(body) == 0 {
-			return nil, fmt.Errorf("expected json response, got %q, with status: %v", cType, resp.StatusCode)
-		}
-
-		return nil, fmt.Errorf("expected json response, got %q, with status: %v and response body: %q", cType, resp.StatusCode, body)
-	}
-
-	var response Response
-	if q.Chunked {
-		cr := NewChunkedResponse(resp.Body)
-		for {
-			r, err := cr.NextResponse()
-			if err != nil {
-				// If we got an error while decoding the response, send that back.
-				return nil, err
-			}
-
-			if r == nil {
-				break
-			}
-
-			response.Results = append(response.Results, r.Results...)
-			if r.Err != "" {
-				response.Err = r.Err
-				break
-			}
+			return fmt.Errorf("expected json response, got %q, with status: %v", cType, resp.StatusCode)
 		}
-	} else {
-		dec := json.NewDecoder(resp.Body)
-		dec.UseNumber()
-		decErr := dec.Decode(&response)
 
-		// ignore this error if we got an invalid status code
-		if decErr != nil && decErr.Error() == "EOF" && resp.StatusCode != http.StatusOK {
-			decErr = nil
-		}
-		// If we got a valid decode error, send that back
-		if decErr != nil {
-			return nil, fmt.Errorf("unable to decode json: received status code %d err: %s", resp.StatusCode, decErr)
-		}
+		return fmt.Errorf("expected json response, got %q, with status: %v and response body: %q", cType, resp.StatusCode, body)
 	}
-
-	// If we don't have an error in our json response, and didn't get statusOK
-	// then send back an error
-	if resp.StatusCode != http.StatusOK && response.Error() == nil {
-		return &response, fmt.Errorf("received status code %d from server", resp.StatusCode)
-	}
-	return &response, nil
+	return nil
 }
 
 // duplexReader reads responses and writes it to another writer while
@@ -604,6 +681,7 @@ type ChunkedResponse struct {
 	dec    *json.Decoder
 	duplex *duplexReader
 	buf    bytes.Buffer
+	body   io.Closer
 }
 
 // NewChunkedResponse reads a stream and produces responses from the stream.
@@ -633,3 +711,33 @@ func (r *ChunkedResponse) NextResponse() (*Response, error) {
 	r.buf.Reset()
 	return &response, nil
 }
+
+// ForEach calls fn with each response of the stream until the end of the
+// stream, an error response, or fn returns an error, and returns that error.
+// The response is closed when ForEach returns.
+func (r *ChunkedResponse) ForEach(fn func(*Response) error) error {
+	defer r.Close()
+	for {
+		response, err := r.NextResponse()
+		if err != nil {
+			return err
+		} else if response == nil {
+			return nil
+		}
+
+		if err := fn(response); err != nil {
+			return err
+		} else if err := response.Error(); err != nil {
+			return err
+		}
+	}
+}
+
+// Close closes the stream of the response, cancelling the rest of a query
+// that isn't read.
+func (r *ChunkedResponse) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	return r.body.Close()
+}
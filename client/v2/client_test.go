@@ -1,8 +1,10 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -296,6 +298,74 @@ func TestClient_ChunkedQuery(t *testing.T) {
 	}
 }
 
+func TestClient_QueryAsChunk(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("chunked") != "true" {
+			t.Errorf("expected chunked query")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Influxdb-Version", "1.3.1")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","value"],"values":[[1,1]]}],"partial":true}]}`+"\n")
+		io.WriteString(w, `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","value"],"values":[[2,2]]}]}]}`+"\n")
+		io.WriteString(w, `{"results":[{"statement_id":1,"error":"database not found: db1"}]}`+"\n")
+	}))
+	defer ts.Close()
+
+	c, err := NewHTTPClient(HTTPConfig{Addr: ts.URL})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	cr, err := c.QueryAsChunk(context.Background(), Query{Command: "SELECT value FROM cpu"})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+	var values int
+	var partial []bool
+	err = cr.ForEach(func(r *Response) error {
+		for _, result := range r.Results {
+			partial = append(partial, result.Partial)
+			for _, row := range result.Series {
+				values += len(row.Values)
+			}
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "database not found: db1" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if values != 2 {
+		t.Fatalf("unexpected number of values: %d", values)
+	} else if !reflect.DeepEqual(partial, []bool{true, false, false}) {
+		t.Fatalf("unexpected partial results: %v", partial)
+	}
+}
+
+func TestClient_QueryCtx_Cancel(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	c, err := NewHTTPClient(HTTPConfig{Addr: ts.URL})
+	if err != nil {
+		t.Fatalf("unexpected error.  expected %v, actual %v", nil, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.QueryCtx(ctx, Query{}); err == nil {
+		t.Fatal("expected error for cancelled query")
+	}
+
+	bp, _ := NewBatchPoints(BatchPointsConfig{})
+	if err := c.WriteCtx(ctx, bp); err == nil {
+		t.Fatal("expected error for cancelled write")
+	}
+}
+
 func TestClientDownstream500WithBody_ChunkedQuery(t *testing.T) {
 	const err500page = `<html>
 	<head>
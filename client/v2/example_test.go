@@ -1,6 +1,7 @@
 package client_test
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"os"
@@ -247,6 +248,40 @@ func ExampleClient_query() {
 	}
 }
 
+// Stream the result of a large query, without buffering it
+func ExampleClient_queryAsChunk() {
+	// Make client
+	c, err := client.NewHTTPClient(client.HTTPConfig{
+		Addr: "http://localhost:8086",
+	})
+	if err != nil {
+		fmt.Println("Error creating InfluxDB Client: ", err.Error())
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	q := client.NewQuery("SELECT * FROM shapes", "square_holes", "ns")
+	q.ChunkSize = 10000
+	response, err := c.QueryAsChunk(ctx, q)
+	if err != nil {
+		fmt.Println("Error: ", err.Error())
+		return
+	}
+	err = response.ForEach(func(r *client.Response) error {
+		for _, result := range r.Results {
+			for _, row := range result.Series {
+				fmt.Println(row.Name, len(row.Values))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Println("Error: ", err.Error())
+	}
+}
+
 // Create a Database with a query
 func ExampleClient_createDatabase() {
 	// Make client
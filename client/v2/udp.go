@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net"
@@ -103,10 +104,27 @@ func (uc *udpclient) Write(bp BatchPoints) error {
 	return delayedError
 }
 
+// WriteCtx writes the points of the batch, unless the context is done.  The
+// datagrams can't be cancelled once they are being sent.
+func (uc *udpclient) WriteCtx(ctx context.Context, bp BatchPoints) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return uc.Write(bp)
+}
+
 func (uc *udpclient) Query(q Query) (*Response, error) {
 	return nil, fmt.Errorf("Querying via UDP is not supported")
 }
 
+func (uc *udpclient) QueryCtx(ctx context.Context, q Query) (*Response, error) {
+	return uc.Query(q)
+}
+
+func (uc *udpclient) QueryAsChunk(ctx context.Context, q Query) (*ChunkedResponse, error) {
+	return nil, fmt.Errorf("Querying via UDP is not supported")
+}
+
 func (uc *udpclient) Ping(timeout time.Duration) (time.Duration, string, error) {
 	return 0, "", nil
 }
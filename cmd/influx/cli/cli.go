@@ -44,8 +44,10 @@ type CommandLine struct {
 	ClientVersion   string
 	ServerVersion   string
 	Pretty          bool   // controls pretty print for json
-	Format          string // controls the output format.  Valid values are json, csv, or column
+	Format          string // controls the output format.  Valid values are json, csv, column, or table
 	Execute         string
+	ExecuteFile     string // path of a script of commands to execute
+	Quiet           bool   // suppresses informational output, leaving results and errors
 	ShowVersion     bool
 	Import          bool
 	Chunked         bool
@@ -56,6 +58,15 @@ type CommandLine struct {
 	osSignals       chan os.Signal
 	historyFilePath string
 
+	// commandErr is the error of the last command that failed without
+	// returning one, such as an INSERT rejected by the server, so scripts
+	// can stop at it.
+	commandErr error
+
+	// completions are the names completed by completeWord, by their
+	// database and SHOW query.  They are fetched again after each command.
+	completions map[string][]string
+
 	Client         *client.Client
 	ClientConfig   client.Config // Client config options.
 	ImporterConfig v8.Config     // Importer configuration options.
@@ -64,10 +75,11 @@ type CommandLine struct {
 // New returns an instance of CommandLine with the specified client version.
 func New(version string) *CommandLine {
 	return &CommandLine{
-		ClientVersion: version,
-		Quit:          make(chan struct{}, 1),
-		osSignals:     make(chan os.Signal, 1),
-		Chunked:       true,
+		ClientVersion:  version,
+		Quit:           make(chan struct{}, 1),
+		osSignals:      make(chan os.Signal, 1),
+		Chunked:        true,
+		ImporterConfig: v8.NewConfig(),
 	}
 }
 
@@ -110,6 +122,17 @@ func (c *CommandLine) Run() error {
 		c.ClientConfig.Password = os.Getenv("INFLUX_PASSWORD")
 	}
 
+	// Read environment variables for the TLS certificates.
+	if c.ClientConfig.CACert == "" {
+		c.ClientConfig.CACert = os.Getenv("INFLUX_CACERT")
+	}
+	if c.ClientConfig.ClientCert == "" {
+		c.ClientConfig.ClientCert = os.Getenv("INFLUX_CERT")
+	}
+	if c.ClientConfig.ClientKey == "" {
+		c.ClientConfig.ClientKey = os.Getenv("INFLUX_KEY")
+	}
+
 	if err := c.Connect(""); err != nil {
 		msg := "Please check your connection settings and ensure 'influxd' is running."
 		if !c.Ssl && strings.Contains(err.Error(), "malformed HTTP response") {
@@ -129,6 +152,8 @@ func (c *CommandLine) Run() error {
 				msg = "You may use -unsafeSsl to connect anyway, but the SSL connection will not be secure."
 			}
 			c.ClientConfig.UnsafeSsl = false
+		} else if c.Ssl && strings.Contains(err.Error(), "certificate signed by unknown authority") {
+			msg = "Please use the -cacert flag to verify the server with the certificate of its authority."
 		}
 		return fmt.Errorf("Failed to connect to %s: %s\n%s", c.Client.Addr(), err.Error(), msg)
 	}
@@ -136,16 +161,25 @@ func (c *CommandLine) Run() error {
 	// Modify precision.
 	c.SetPrecision(c.ClientConfig.Precision)
 
-	if c.Execute != "" {
-		// Make the non-interactive mode send everything through the CLI's parser
-		// the same way the interactive mode works
-		lines := strings.Split(c.Execute, "\n")
-		for _, line := range lines {
-			if err := c.ParseCommand(line); err != nil {
-				return err
-			}
+	if c.Format == "" || c.Format == "table" {
+		c.Format = "column"
+	}
+	switch c.Format {
+	case "json", "csv", "column":
+	default:
+		return fmt.Errorf("Unknown format %q. Please use json, csv, column, or table.", c.Format)
+	}
+
+	if c.Execute != "" && c.ExecuteFile != "" {
+		return errors.New("-execute and -execute-file cannot be used together")
+	} else if c.ExecuteFile != "" {
+		script, err := ioutil.ReadFile(c.ExecuteFile)
+		if err != nil {
+			return err
 		}
-		return nil
+		return c.executeScript(string(script))
+	} else if c.Execute != "" {
+		return c.executeScript(c.Execute)
 	}
 
 	if c.Import {
@@ -186,25 +220,69 @@ func (c *CommandLine) Run() error {
 	defer c.Line.Close()
 
 	c.Line.SetMultiLineMode(true)
+	c.Line.SetWordCompleter(c.completeWord)
 
 	fmt.Printf("Connected to %s version %s\n", c.Client.Addr(), c.ServerVersion)
 
 	c.Version()
 
-	// Only load/write history if HOME environment variable is set.
-	if homeDir := os.Getenv("HOME"); homeDir != "" {
-		// Attempt to load the history file.
-		c.historyFilePath = filepath.Join(homeDir, ".influx_history")
-		if historyFile, err := os.Open(c.historyFilePath); err == nil {
-			c.Line.ReadHistory(historyFile)
-			historyFile.Close()
-		}
-	}
+	c.loadHistory()
 
 	// read from prompt until exit is run
 	return c.mainLoop()
 }
 
+// executeScript sends each command of a script through the CLI's parser the
+// same way the interactive mode does, and stops at the first command that
+// fails.  Commands are separated by newlines, and a line ending with a
+// backslash continues on the next line.  Lines starting with -- or # are
+// comments.
+func (c *CommandLine) executeScript(script string) error {
+	for _, cmd := range scriptCommands(script) {
+		err := c.ParseCommand(cmd)
+		if err == nil {
+			err = c.commandErr
+		}
+		if err != nil && err != ErrBlankCommand {
+			return err
+		}
+	}
+	return nil
+}
+
+// scriptCommands splits a script into its commands.
+func scriptCommands(script string) []string {
+	var cmds []string
+	var cmd string
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if cmd == "" && (strings.HasPrefix(trimmed, "--") || strings.HasPrefix(trimmed, "#")) {
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			cmd += strings.TrimSuffix(line, "\\") + "\n"
+			continue
+		}
+		cmd += line
+		if strings.TrimSpace(cmd) != "" {
+			cmds = append(cmds, cmd)
+		}
+		cmd = ""
+	}
+	if strings.TrimSpace(cmd) != "" {
+		cmds = append(cmds, cmd)
+	}
+	return cmds
+}
+
+// info prints informational output, unless the CLI is quiet.
+func (c *CommandLine) info(format string, a ...interface{}) {
+	if !c.Quiet {
+		fmt.Printf(format, a...)
+	}
+}
+
 // mainLoop runs the main prompt loop for the CLI.
 func (c *CommandLine) mainLoop() error {
 	for {
@@ -224,11 +302,31 @@ func (c *CommandLine) mainLoop() error {
 				c.exit()
 				return e
 			}
+
+			// Read the following lines of a statement that continues, such as
+			// a long SELECT INTO query.  Ctrl-D discards the statement.
+			var discard bool
+			for statementContinues(l) {
+				next, e := c.Line.Prompt("... ")
+				if e != nil {
+					discard = true
+					break
+				}
+				l = strings.TrimSuffix(l, "\\") + "\n" + next
+			}
+			if discard {
+				fmt.Println()
+				continue
+			}
+
 			if err := c.ParseCommand(l); err != ErrBlankCommand && !strings.HasPrefix(strings.TrimSpace(l), "auth") {
-				l = influxql.Sanitize(l)
+				// Statements are recalled on a single line, so they can be
+				// edited as a whole.
+				l = strings.Replace(influxql.Sanitize(l), "\n", " ", -1)
 				c.Line.AppendHistory(l)
 				c.saveHistory()
 			}
+			c.completions = nil
 		}
 	}
 }
@@ -236,6 +334,7 @@ func (c *CommandLine) mainLoop() error {
 // ParseCommand parses an instruction and calls the related method
 // or executes the command as a query against InfluxDB.
 func (c *CommandLine) ParseCommand(cmd string) error {
+	c.commandErr = nil
 	lcmd := strings.TrimSpace(strings.ToLower(cmd))
 	tokens := strings.Fields(lcmd)
 
@@ -264,21 +363,21 @@ func (c *CommandLine) ParseCommand(cmd string) error {
 		case "chunked":
 			c.Chunked = !c.Chunked
 			if c.Chunked {
-				fmt.Println("chunked responses enabled")
+				c.info("chunked responses enabled\n")
 			} else {
-				fmt.Println("chunked reponses disabled")
+				c.info("chunked reponses disabled\n")
 			}
 		case "chunk":
 			c.SetChunkSize(cmd)
 		case "pretty":
 			c.Pretty = !c.Pretty
 			if c.Pretty {
-				fmt.Println("Pretty print enabled")
+				c.info("Pretty print enabled\n")
 			} else {
-				fmt.Println("Pretty print disabled")
+				c.info("Pretty print disabled\n")
 			}
 		case "use":
-			c.use(cmd)
+			c.commandErr = c.use(cmd)
 		case "insert":
 			return c.Insert(cmd)
 		case "clear":
@@ -334,6 +433,11 @@ func (c *CommandLine) Connect(cmd string) error {
 		}
 	}
 
+	// Switch to the history of the server when connecting interactively.
+	if c.Line != nil {
+		c.loadHistory()
+	}
+
 	return nil
 }
 
@@ -376,11 +480,11 @@ func (c *CommandLine) clear(cmd string) {
 	switch v {
 	case "database", "db":
 		c.Database = ""
-		fmt.Println("database context cleared")
+		c.info("database context cleared\n")
 		return
 	case "retention policy", "rp":
 		c.RetentionPolicy = ""
-		fmt.Println("retention policy context cleared")
+		c.info("retention policy context cleared\n")
 		return
 	default:
 		if len(args) > 1 {
@@ -398,34 +502,35 @@ func (c *CommandLine) clear(cmd string) {
 	}
 }
 
-func (c *CommandLine) use(cmd string) {
+func (c *CommandLine) use(cmd string) error {
 	args := strings.Split(strings.TrimSuffix(strings.TrimSpace(cmd), ";"), " ")
 	if len(args) != 2 {
 		fmt.Printf("Could not parse database name from %q.\n", cmd)
-		return
+		return fmt.Errorf("could not parse database name from %q", cmd)
 	}
 
 	stmt := args[1]
 	db, rp, err := parseDatabaseAndRetentionPolicy([]byte(stmt))
 	if err != nil {
 		fmt.Printf("Unable to parse database or retention policy from %s", stmt)
-		return
+		return err
 	}
 
 	if !c.databaseExists(db) {
-		return
+		return fmt.Errorf("database %s doesn't exist", db)
 	}
 
 	c.Database = db
-	fmt.Printf("Using database %s\n", db)
+	c.info("Using database %s\n", db)
 
 	if rp != "" {
 		if !c.retentionPolicyExists(db, rp) {
-			return
+			return fmt.Errorf("retention policy %s doesn't exist", rp)
 		}
 		c.RetentionPolicy = rp
-		fmt.Printf("Using retention policy %s\n", rp)
+		c.info("Using retention policy %s\n", rp)
 	}
+	return nil
 }
 
 func (c *CommandLine) databaseExists(db string) bool {
@@ -526,7 +631,7 @@ func (c *CommandLine) SetChunkSize(cmd string) {
 		if c.ChunkSize <= 0 {
 			c.ChunkSize = 0
 		}
-		fmt.Printf("chunk size set to %d\n", c.ChunkSize)
+		c.info("chunk size set to %d\n", c.ChunkSize)
 	} else {
 		fmt.Printf("unable to parse chunk size from %q\n", cmd)
 	}
@@ -562,8 +667,10 @@ func (c *CommandLine) SetFormat(cmd string) {
 	switch cmd {
 	case "json", "csv", "column":
 		c.Format = cmd
+	case "table":
+		c.Format = "column"
 	default:
-		fmt.Printf("Unknown format %q. Please use json, csv, or column.\n", cmd)
+		fmt.Printf("Unknown format %q. Please use json, csv, column, or table.\n", cmd)
 	}
 }
 
@@ -685,15 +792,17 @@ func (c *CommandLine) Insert(stmt string) error {
 	bp, err := c.parseInsert(stmt)
 	if err != nil {
 		fmt.Printf("ERR: %s\n", err)
+		c.commandErr = err
 		return nil
 	}
 	if _, err := c.Client.Write(*bp); err != nil {
 		fmt.Printf("ERR: %s\n", err)
 		if c.Database == "" {
-			fmt.Println("Note: error may be due to not setting a database or retention policy.")
-			fmt.Println(`Please set a database with the command "use <database>" or`)
-			fmt.Println("INSERT INTO <database>.<retention-policy> <point>")
+			c.info("Note: error may be due to not setting a database or retention policy.\n")
+			c.info(`Please set a database with the command "use <database>" or` + "\n")
+			c.info("INSERT INTO <database>.<retention-policy> <point>\n")
 		}
+		c.commandErr = err
 	}
 	return nil
 }
@@ -765,8 +874,8 @@ func (c *CommandLine) ExecuteQuery(query string) error {
 	if err := response.Error(); err != nil {
 		fmt.Printf("ERR: %s\n", response.Error())
 		if c.Database == "" {
-			fmt.Println("Warning: It is possible this error is due to not setting a database.")
-			fmt.Println(`Please set a database with the command "use <database>".`)
+			c.info("Warning: It is possible this error is due to not setting a database.\n")
+			c.info(`Please set a database with the command "use <database>".` + "\n")
 		}
 		return err
 	}
@@ -999,14 +1108,19 @@ func (c *CommandLine) help() {
         chunked               turns on chunked responses from server
         chunk size <size>     sets the size of the chunked responses.  Set to 0 to reset to the default chunked size
         use <db_name>         sets current database
-        format <format>       specifies the format of the server responses: json, csv, or column
+        format <format>       specifies the format of the server responses: json, csv, column, or table
         precision <format>    specifies the format of the timestamp: rfc3339, h, m, s, ms, u or ns
         consistency <level>   sets write consistency level: any, one, quorum, or all
         history               displays command history
+        ctrl+r                searches the command history
+        tab                   completes database, measurement and tag key names
         settings              outputs the current settings for the shell
         clear                 clears settings such as database or retention policy.  run 'clear' for help
         exit/quit/ctrl+d      quits the influx shell
 
+        A statement continues on the next line if a line ends with a backslash,
+        or has an unterminated quote or parenthesis.
+
         show databases        show database names
         show series           show series information
         show measurements     show measurement information
@@ -1024,7 +1138,47 @@ func (c *CommandLine) history() {
 	fmt.Print(buf.String())
 }
 
+// loadHistory replaces the history with the history file of the server.
+// Only load/write history if HOME environment variable is set.
+func (c *CommandLine) loadHistory() {
+	homeDir := os.Getenv("HOME")
+	if homeDir == "" {
+		return
+	}
+
+	c.historyFilePath = filepath.Join(homeDir, historyFileName(c.Host, c.Port, c.ClientConfig.UnixSocket))
+	c.Line.ClearHistory()
+	if historyFile, err := os.Open(c.historyFilePath); err == nil {
+		c.Line.ReadHistory(historyFile)
+		historyFile.Close()
+	}
+}
+
+// historyFileName returns the name of the history file of a server, so the
+// commands of each server are recalled separately.  The default server keeps
+// the .influx_history file.
+func historyFileName(host string, port int, socket string) string {
+	var server string
+	if socket != "" {
+		server = socket
+	} else if host != client.DefaultHost || port != client.DefaultPort {
+		server = net.JoinHostPort(host, strconv.Itoa(port))
+	} else {
+		return ".influx_history"
+	}
+
+	return ".influx_history_" + strings.Map(func(r rune) rune {
+		if isLetter(r) || isDigit(r) || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, strings.Trim(server, "/"))
+}
+
 func (c *CommandLine) saveHistory() {
+	if c.historyFilePath == "" {
+		return
+	}
 	if historyFile, err := os.Create(c.historyFilePath); err != nil {
 		fmt.Printf("There was an error writing history file: %s\n", err)
 	} else {
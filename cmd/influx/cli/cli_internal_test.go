@@ -1,6 +1,15 @@
 package cli
 
-import "testing"
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/influxdb/client"
+)
 
 func TestParseCommand_InsertInto(t *testing.T) {
 	t.Parallel()
@@ -56,3 +65,109 @@ func TestParseCommand_InsertInto(t *testing.T) {
 		}
 	}
 }
+
+func TestScriptCommands(t *testing.T) {
+	t.Parallel()
+
+	cmds := scriptCommands("-- rollup\nuse metrics\n\nSELECT mean(value) INTO cpu_1h \\\nFROM cpu GROUP BY time(1h)\r\n# done\nSHOW DATABASES")
+	exp := []string{
+		"use metrics",
+		"SELECT mean(value) INTO cpu_1h \nFROM cpu GROUP BY time(1h)",
+		"SHOW DATABASES",
+	}
+	if len(cmds) != len(exp) {
+		t.Fatalf("unexpected commands: %q", cmds)
+	}
+	for i := range exp {
+		if cmds[i] != exp[i] {
+			t.Fatalf("unexpected command %d: %q, expected %q", i, cmds[i], exp[i])
+		}
+	}
+}
+
+func TestStatementContinues(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		stmt      string
+		continues bool
+	}{
+		{stmt: `SELECT mean(value) INTO cpu_1h FROM cpu GROUP BY time(1h)`},
+		{stmt: `SELECT mean(value) INTO cpu_1h \`, continues: true},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY time(1h`, continues: true},
+		{stmt: `SELECT * FROM cpu WHERE host = 'server`, continues: true},
+		{stmt: `SELECT * FROM "cpu \" load"`},
+		{stmt: `insert cpu,host=o'neil value=1`},
+	}
+	for _, test := range tests {
+		if continues := statementContinues(test.stmt); continues != test.continues {
+			t.Errorf("unexpected continuation of %q: %v", test.stmt, continues)
+		}
+	}
+}
+
+func TestHistoryFileName(t *testing.T) {
+	t.Parallel()
+
+	if name := historyFileName("localhost", 8086, ""); name != ".influx_history" {
+		t.Fatalf("unexpected default history file: %s", name)
+	} else if name := historyFileName("db.example.com", 8087, ""); name != ".influx_history_db.example.com_8087" {
+		t.Fatalf("unexpected history file: %s", name)
+	} else if name := historyFileName("localhost", 8086, "/var/run/influxdb.sock"); name != ".influx_history_var_run_influxdb.sock" {
+		t.Fatalf("unexpected socket history file: %s", name)
+	}
+}
+
+func TestCompleteWord(t *testing.T) {
+	t.Parallel()
+
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		queries = append(queries, q)
+		switch q {
+		case "SHOW DATABASES":
+			io.WriteString(w, `{"results":[{"series":[{"name":"databases","columns":["name"],"values":[["metrics"],["telegraf"]]}]}]}`)
+		case "SHOW MEASUREMENTS":
+			io.WriteString(w, `{"results":[{"series":[{"name":"measurements","columns":["name"],"values":[["cpu"],["cpu load"],["mem"]]}]}]}`)
+		case "SHOW TAG KEYS FROM cpu":
+			io.WriteString(w, `{"results":[{"series":[{"name":"cpu","columns":["tagKey"],"values":[["host"],["region"]]}]}]}`)
+		default:
+			io.WriteString(w, `{"results":[{}]}`)
+		}
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	cl, err := client.NewClient(client.Config{URL: *u})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := CommandLine{Client: cl, Database: "metrics"}
+
+	tests := []struct {
+		line        string
+		head        string
+		completions []string
+	}{
+		{line: "use te", head: "use ", completions: []string{"telegraf"}},
+		{line: "SELECT * FROM c", head: "SELECT * FROM ", completions: []string{"cpu", `"cpu load"`}},
+		{line: `SELECT * FROM "cpu`, head: "SELECT * FROM ", completions: []string{`"cpu"`, `"cpu load"`}},
+		{line: "SELECT * FROM cpu WHERE h", head: "SELECT * FROM cpu WHERE ", completions: []string{"host"}},
+		{line: "SELECT * FROM cpu WHERE host = 'a' GROUP BY ", head: "SELECT * FROM cpu WHERE host = 'a' GROUP BY ", completions: []string{"host", "region"}},
+		{line: "SHOW ", head: "SHOW "},
+	}
+	for _, test := range tests {
+		head, completions, tail := c.completeWord(test.line, len(test.line))
+		if head != test.head || tail != "" {
+			t.Fatalf("%q: unexpected head %q and tail %q", test.line, head, tail)
+		} else if !reflect.DeepEqual(completions, test.completions) {
+			t.Fatalf("%q: unexpected completions %q, expected %q", test.line, completions, test.completions)
+		}
+	}
+
+	// Names are fetched once until the next command.
+	if exp := []string{"SHOW DATABASES", "SHOW MEASUREMENTS", "SHOW TAG KEYS FROM cpu"}; !reflect.DeepEqual(queries, exp) {
+		t.Fatalf("unexpected queries %q, expected %q", queries, exp)
+	}
+}
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/influxdb/client"
+	"github.com/influxdata/influxdb/influxql"
+)
+
+// completionKeywords are the keywords that select the kind of names that are
+// completed after them, up to the next of them.
+var completionKeywords = map[string]completionKind{
+	"USE":         completeDatabases,
+	"ON":          completeDatabases,
+	"FROM":        completeMeasurements,
+	"INTO":        completeMeasurements,
+	"MEASUREMENT": completeMeasurements,
+	"SELECT":      completeTagKeys,
+	"WHERE":       completeTagKeys,
+	"AND":         completeTagKeys,
+	"OR":          completeTagKeys,
+	"BY":          completeTagKeys,
+	"KEY":         completeTagKeys,
+}
+
+// completionKind is the kind of names completed in a statement.
+type completionKind int
+
+const (
+	completeNothing completionKind = iota
+	completeDatabases
+	completeMeasurements
+	completeTagKeys
+)
+
+// fromMeasurement matches the measurement of the FROM clause of a statement.
+var fromMeasurement = regexp.MustCompile(`(?i)\bFROM\s+("(?:[^"\\]|\\.)+"|[^\s,;()]+)`)
+
+// completeWord is the word completer of the interactive shell.  It completes
+// the names of databases after USE and ON, of measurements after FROM and
+// INTO, and of tag keys in the other clauses of a statement.  The names are
+// fetched with SHOW queries the first time they are completed after a
+// command, so completion doesn't query the server until it is used.
+func (c *CommandLine) completeWord(line string, pos int) (head string, completions []string, tail string) {
+	head, tail = line[:pos], line[pos:]
+	i := strings.LastIndexAny(head, " \t\n(,=") + 1
+	head, word := head[:i], head[i:]
+
+	var names []string
+	switch completionKindOf(head) {
+	case completeDatabases:
+		names = c.completionNames("SHOW DATABASES", "")
+	case completeMeasurements:
+		if c.Database == "" {
+			return head, nil, tail
+		}
+		names = c.completionNames("SHOW MEASUREMENTS", c.Database)
+	case completeTagKeys:
+		if c.Database == "" {
+			return head, nil, tail
+		}
+		cmd := "SHOW TAG KEYS"
+		if m := fromMeasurement.FindStringSubmatch(line); m != nil {
+			cmd += " FROM " + m[1]
+		}
+		names = c.completionNames(cmd, c.Database)
+	}
+
+	quoted := strings.HasPrefix(word, `"`)
+	prefix := strings.TrimPrefix(word, `"`)
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if quoted {
+			completions = append(completions, `"`+name+`"`)
+		} else {
+			completions = append(completions, influxql.QuoteIdent(name))
+		}
+	}
+	return head, completions, tail
+}
+
+// completionKindOf returns the kind of names completed after the head of a
+// statement, from the last keyword of the head that selects one.
+func completionKindOf(head string) completionKind {
+	fields := strings.FieldsFunc(head, func(r rune) bool {
+		return isWhitespace(r) || r == ',' || r == '('
+	})
+	for i := len(fields) - 1; i >= 0; i-- {
+		if kind, ok := completionKeywords[strings.ToUpper(fields[i])]; ok {
+			return kind
+		}
+	}
+	return completeNothing
+}
+
+// completionNames returns the sorted names of the first column of the
+// results of a SHOW query, running it if it isn't cached.
+func (c *CommandLine) completionNames(cmd, db string) []string {
+	key := fmt.Sprintf("%s\x00%s", db, cmd)
+	if names, ok := c.completions[key]; ok {
+		return names
+	}
+	if c.Client == nil {
+		return nil
+	}
+
+	response, err := c.Client.Query(client.Query{Command: cmd, Database: db})
+	if err != nil || response.Error() != nil {
+		return nil
+	}
+
+	set := make(map[string]struct{})
+	for _, result := range response.Results {
+		for _, row := range result.Series {
+			for _, values := range row.Values {
+				if len(values) == 0 {
+					continue
+				}
+				if name, ok := values[0].(string); ok {
+					set[name] = struct{}{}
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if c.completions == nil {
+		c.completions = make(map[string][]string)
+	}
+	c.completions[key] = names
+	return names
+}
+
+// statementContinues returns true if a statement continues on the next line,
+// because its line ends with a backslash, or it has an unterminated quote or
+// parenthesis.  Quotes don't continue the line protocol of INSERT commands,
+// where they may be unbalanced.
+func statementContinues(stmt string) bool {
+	if strings.HasSuffix(stmt, "\\") {
+		return true
+	} else if fields := strings.Fields(stmt); len(fields) > 0 && strings.EqualFold(fields[0], "insert") {
+		return false
+	}
+
+	var quote rune
+	var depth int
+	var escaped bool
+	for _, ch := range stmt {
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		}
+	}
+	return quote != 0 || depth > 0
+}
@@ -46,16 +46,27 @@ func main() {
 	fs.StringVar(&c.Database, "database", c.Database, "Database to connect to the server.")
 	fs.BoolVar(&c.Ssl, "ssl", false, "Use https for connecting to cluster.")
 	fs.BoolVar(&c.ClientConfig.UnsafeSsl, "unsafeSsl", false, "Set this when connecting to the cluster using https and not use SSL verification.")
-	fs.StringVar(&c.Format, "format", defaultFormat, "Format specifies the format of the server responses:  json, csv, or column.")
+	fs.StringVar(&c.ClientConfig.CACert, "cacert", "", "PEM file of the certificate authorities that verify the server.  Defaults to $INFLUX_CACERT.")
+	fs.StringVar(&c.ClientConfig.ClientCert, "cert", "", "PEM file of the client certificate presented to the server.  Defaults to $INFLUX_CERT.")
+	fs.StringVar(&c.ClientConfig.ClientKey, "key", "", "PEM file of the key of the client certificate.  Defaults to $INFLUX_KEY.")
+	fs.StringVar(&c.Format, "format", defaultFormat, "Format specifies the format of the server responses:  json, csv, column, or table.")
 	fs.StringVar(&c.ClientConfig.Precision, "precision", defaultPrecision, "Precision specifies the format of the timestamp:  rfc3339,h,m,s,ms,u or ns.")
 	fs.StringVar(&c.ClientConfig.WriteConsistency, "consistency", "all", "Set write consistency level: any, one, quorum, or all.")
 	fs.BoolVar(&c.Pretty, "pretty", false, "Turns on pretty print for the json format.")
 	fs.StringVar(&c.Execute, "execute", c.Execute, "Execute command and quit.")
+	fs.StringVar(&c.ExecuteFile, "execute-file", "", "Execute the commands of a script file and quit.")
+	fs.BoolVar(&c.Quiet, "quiet", false, "Only output results and errors.")
 	fs.BoolVar(&c.ShowVersion, "version", false, "Displays the InfluxDB version.")
 	fs.BoolVar(&c.Import, "import", false, "Import a previous database.")
 	fs.IntVar(&c.ImporterConfig.PPS, "pps", defaultPPS, "How many points per second the import will allow.  By default it is zero and will not throttle importing.")
 	fs.StringVar(&c.ImporterConfig.Path, "path", "", "path to the file to import")
 	fs.BoolVar(&c.ImporterConfig.Compressed, "compressed", false, "set to true if the import file is compressed")
+	fs.IntVar(&c.ImporterConfig.BatchSize, "batch-size", c.ImporterConfig.BatchSize, "Number of points the import writes at once.")
+	fs.IntVar(&c.ImporterConfig.Writers, "writers", c.ImporterConfig.Writers, "Number of batches the import writes concurrently.")
+	fs.IntVar(&c.ImporterConfig.Retries, "retries", c.ImporterConfig.Retries, "Number of times the import retries a failed batch.")
+	fs.DurationVar(&c.ImporterConfig.RetryInterval, "retry-interval", c.ImporterConfig.RetryInterval, "Time before the import retries a failed batch, doubled with each retry.")
+	fs.StringVar(&c.ImporterConfig.FailedPath, "failed-path", "", "File the lines of the batches that failed every retry are appended to.")
+	fs.Int64Var(&c.ImporterConfig.Offset, "offset", 0, "Number of points of the import file to skip, to resume an import.")
 
 	// Define our own custom usage to print
 	fs.Usage = func() {
@@ -78,10 +89,24 @@ func main() {
         Use https for requests.
   -unsafeSsl
         Set this when connecting to the cluster using https and not use SSL verification.
+  -cacert 'path'
+        PEM file of the certificate authorities that verify the server, such as a private CA,
+        instead of the system certificates.  Defaults to $INFLUX_CACERT.
+  -cert 'path'
+        PEM file of the client certificate presented to servers requiring mutual TLS.
+        Defaults to $INFLUX_CERT.
+  -key 'path'
+        PEM file of the key of the client certificate.  Defaults to $INFLUX_KEY.
   -execute 'command'
        Execute command and quit.
-  -format 'json|csv|column'
-       Format specifies the format of the server responses:  json, csv, or column.
+  -execute-file 'path'
+       Execute the commands of a script file, one per line, and quit.  A line ending with a
+       backslash continues on the next line, and lines starting with -- or # are comments.
+       The exit code is non-zero if any command fails.
+  -quiet
+       Only output results and errors, such as for cron jobs.
+  -format 'json|csv|column|table'
+       Format specifies the format of the server responses:  json, csv, column, or table.
   -precision 'rfc3339|h|m|s|ms|u|ns'
        Precision specifies the format of the timestamp:  rfc3339, h, m, s, ms, u or ns.
   -consistency 'any|one|quorum|all'
@@ -96,14 +121,39 @@ func main() {
        Path to file to import
   -compressed
        Set to true if the import file is compressed
+  -batch-size
+       Number of points the import writes at once.  Defaults to 5000.
+  -writers
+       Number of batches the import writes concurrently.  Defaults to 1.
+  -retries
+       Number of times the import retries a failed batch.  Defaults to 3.
+  -retry-interval
+       Time before the import retries a failed batch, doubled with each retry.  Defaults to 1s.
+  -failed-path
+       File the lines of the batches that failed every retry are appended to.  It can be imported again.
+  -offset
+       Number of points of the import file to skip, to resume an import from the offset it reported.
 
 Examples:
 
     # Use influx in a non-interactive mode to query the database "metrics" and pretty print json:
     $ influx -database 'metrics' -execute 'select * from cpu' -format 'json' -pretty
 
+    # Run a script from cron, writing CSV and failing if any statement fails:
+    $ influx -database 'metrics' -execute-file 'rollup.iql' -format 'csv' -quiet
+
     # Connect to a specific database on startup and set database context:
     $ influx -database 'metrics' -host 'localhost' -port '8086'
+
+    # Connect to a server requiring mutual TLS with a certificate of a private CA:
+    $ influx -ssl -host 'influxdb.internal' -cacert 'ca.pem' -cert 'client.pem' -key 'client-key.pem'
+
+    # Connect through the unix socket of a local server:
+    $ influx -socket '/var/run/influxdb.sock'
+
+    # Import a large export with 4 writers, keeping the rejected lines, then resume it:
+    $ influx -import -path 'export.gz' -compressed -writers 4 -failed-path 'failed.txt'
+    $ influx -import -path 'export.gz' -compressed -writers 4 -failed-path 'failed.txt' -offset 123450000
 `)
 	}
 	fs.Parse(os.Args[1:])
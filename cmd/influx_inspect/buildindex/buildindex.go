@@ -0,0 +1,295 @@
+// Package buildindex rebuilds the index of a shard from its TSM and WAL files.
+package buildindex
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+	"github.com/influxdata/influxdb/tsdb/index/inmem"
+	"github.com/influxdata/influxdb/tsdb/index/tsi1"
+	"github.com/uber-go/zap"
+)
+
+// Command represents the program execution for "influx_inspect buildindex".
+type Command struct {
+	Stderr io.Writer
+	Stdout io.Writer
+
+	Verbose bool
+	Logger  zap.Logger
+
+	shardPath string
+	walPath   string
+	index     string
+
+	series       map[string]struct{}
+	measurements map[string]struct{}
+}
+
+// NewCommand returns a new instance of Command.
+func NewCommand() *Command {
+	return &Command{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+		Logger: zap.New(zap.NullEncoder()),
+	}
+}
+
+// Run executes the command.
+func (cmd *Command) Run(args ...string) error {
+	fs := flag.NewFlagSet("buildindex", flag.ExitOnError)
+	fs.StringVar(&cmd.shardPath, "shard", "", "shard data directory")
+	fs.StringVar(&cmd.walPath, "waldir", "", "shard WAL directory")
+	fs.StringVar(&cmd.index, "index", tsi1.IndexName, "index type to build")
+	fs.BoolVar(&cmd.Verbose, "v", false, "verbose")
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = cmd.printUsage
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 || cmd.shardPath == "" {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	cmd.Logger = zap.New(
+		zap.NewTextEncoder(),
+		zap.Output(os.Stderr),
+	)
+
+	return cmd.run()
+}
+
+func (cmd *Command) run() error {
+	switch cmd.index {
+	case tsi1.IndexName, inmem.IndexName:
+	default:
+		return fmt.Errorf("unknown index type %q", cmd.index)
+	}
+
+	if cmd.walPath == "" {
+		cmd.walPath = walPathFor(cmd.shardPath)
+	}
+
+	cmd.Logger.Info("opening shard",
+		zap.String("datadir", cmd.shardPath),
+		zap.String("waldir", cmd.walPath),
+	)
+
+	tsmPaths, err := collectFiles(cmd.shardPath, "."+tsm1.TSMFileExtension)
+	if err != nil {
+		return err
+	}
+	walPaths, err := collectFiles(cmd.walPath, "."+tsm1.WALFileExtension)
+	if os.IsNotExist(err) {
+		cmd.Logger.Warn("wal directory not found, using tsm files only", zap.String("path", cmd.walPath))
+	} else if err != nil {
+		return err
+	}
+
+	cmd.series = make(map[string]struct{})
+	cmd.measurements = make(map[string]struct{})
+
+	var tsiIndex *tsi1.Index
+	tmpPath := filepath.Join(cmd.shardPath, ".index")
+	if cmd.index == tsi1.IndexName {
+		// Remove temporary index files if this is being re-run.
+		cmd.Logger.Info("cleaning up partial index from previous run, if any")
+		if err := os.RemoveAll(tmpPath); err != nil {
+			return err
+		}
+
+		tsiIndex = tsi1.NewIndex()
+		tsiIndex.Path = tmpPath
+		tsiIndex.WithLogger(cmd.Logger)
+		cmd.Logger.Info("opening tsi index in temporary location", zap.String("path", tmpPath))
+		if err := tsiIndex.Open(); err != nil {
+			return err
+		}
+		defer tsiIndex.Close()
+	}
+
+	cmd.Logger.Info("iterating over tsm files")
+	for _, path := range tsmPaths {
+		cmd.Logger.Info("processing tsm file", zap.String("path", path))
+		if err := cmd.processTSMFile(tsiIndex, path); err != nil {
+			return err
+		}
+	}
+
+	cmd.Logger.Info("building cache from wal files")
+	cache := tsm1.NewCache(tsdb.DefaultCacheMaxMemorySize, "")
+	loader := tsm1.NewCacheLoader(walPaths)
+	loader.WithLogger(cmd.Logger)
+	if err := loader.Load(cache); err != nil {
+		return err
+	}
+
+	cmd.Logger.Info("iterating over cache")
+	for _, key := range cache.Keys() {
+		if err := cmd.addSeries(tsiIndex, key); err != nil {
+			return err
+		}
+	}
+
+	indexPath := filepath.Join(cmd.shardPath, "index")
+	if tsiIndex != nil {
+		cmd.Logger.Info("compacting index")
+		tsiIndex.Compact()
+		tsiIndex.Wait()
+
+		cmd.Logger.Info("closing tsi index")
+		if err := tsiIndex.Close(); err != nil {
+			return err
+		}
+	}
+
+	// Keep any existing index until the rebuilt one is in place.
+	if err := cmd.moveAside(indexPath); err != nil {
+		return err
+	}
+	if tsiIndex != nil {
+		cmd.Logger.Info("moving tsi to permanent location")
+		if err := os.Rename(tmpPath, indexPath); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(cmd.Stdout, "Rebuilt %s index for %s: %d measurements, %d series\n",
+		cmd.index, cmd.shardPath, len(cmd.measurements), len(cmd.series))
+	return nil
+}
+
+// moveAside renames an existing index directory to "index.old", replacing
+// the one left by any previous run. Shards without an index directory use
+// the in-memory index when they are opened.
+func (cmd *Command) moveAside(indexPath string) error {
+	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	oldPath := indexPath + ".old"
+	cmd.Logger.Info("moving existing index aside", zap.String("path", oldPath))
+	if err := os.RemoveAll(oldPath); err != nil {
+		return err
+	}
+	return os.Rename(indexPath, oldPath)
+}
+
+func (cmd *Command) processTSMFile(index *tsi1.Index, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		cmd.Logger.Warn("unable to read, skipping", zap.String("path", path), zap.Error(err))
+		return nil
+	}
+	defer r.Close()
+
+	for i := 0; i < r.KeyCount(); i++ {
+		key, _ := r.KeyAt(i)
+		if err := cmd.addSeries(index, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addSeries adds the series of a TSM key to index, if set, and to the counts.
+func (cmd *Command) addSeries(index *tsi1.Index, key []byte) error {
+	seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+	if _, ok := cmd.series[string(seriesKey)]; ok {
+		return nil
+	}
+
+	name, tags := models.ParseKey(seriesKey)
+	if name == "" {
+		return errors.New("cannot create series: empty measurement name")
+	}
+	cmd.series[string(seriesKey)] = struct{}{}
+	cmd.measurements[name] = struct{}{}
+
+	if cmd.Verbose {
+		cmd.Logger.Info("series", zap.String("name", name), zap.String("tags", tags.String()))
+	}
+
+	if index == nil {
+		return nil
+	}
+	if err := index.CreateSeriesIfNotExists(nil, []byte(name), tags); err != nil {
+		return fmt.Errorf("cannot create series: %s %s (%s)", name, tags.String(), err)
+	}
+	return nil
+}
+
+// walPathFor returns the WAL directory of a shard stored under
+// <root>/data/<db>/<rp>/<id>, or an empty string for other layouts.
+func walPathFor(shardPath string) string {
+	rp := filepath.Dir(filepath.Clean(shardPath))
+	db := filepath.Dir(rp)
+	data := filepath.Dir(db)
+	if filepath.Base(data) != "data" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(data), "wal", filepath.Base(db), filepath.Base(rp), filepath.Base(shardPath))
+}
+
+// collectFiles returns the files in dir with the extension ext.
+func collectFiles(dir, ext string) ([]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, fi := range fis {
+		if filepath.Ext(fi.Name()) != ext {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, fi.Name()))
+	}
+	return paths, nil
+}
+
+func (cmd *Command) printUsage() {
+	usage := `Rebuilds the index of a shard from its TSM and WAL files.
+
+The shard must not be open by a running server. Any existing index is moved
+to "index.old" in the shard directory once the new index has been built.
+
+Usage: influx_inspect buildindex [flags]
+
+    -shard <path>
+            Shard data directory, e.g. "$HOME/.influxdb/data/db/rp/1".
+    -waldir <path>
+            Shard WAL directory.
+            Defaults to the matching directory under "wal" when the shard
+            is stored under a "data" directory.
+    -index <type>
+            Index to build: "tsi1" or "inmem". Building an "inmem" index
+            checks that every series can be indexed and removes the on-disk
+            index, so the shard is indexed in memory when it is opened.
+            Defaults to "tsi1".
+    -v
+            Log every series added.
+`
+
+	fmt.Fprintf(cmd.Stdout, usage)
+}
@@ -0,0 +1,154 @@
+package buildindex
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+	"github.com/influxdata/influxdb/tsdb/index/inmem"
+	"github.com/influxdata/influxdb/tsdb/index/tsi1"
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	shardPath := filepath.Join(dir, "data", "db0", "rp0", "1")
+	MustWriteTSM(filepath.Join(shardPath, "000000001-000000001.tsm"), "cpu,host=a#!~#value", "cpu,host=b#!~#value", "cpu,host=b#!~#idle")
+	MustWriteWAL(filepath.Join(dir, "wal", "db0", "rp0", "1", "_00001.wal"), "mem,host=a#!~#free")
+
+	// Simulate a corrupt index.
+	if err := os.MkdirAll(filepath.Join(shardPath, "index"), 0777); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(filepath.Join(shardPath, "index", "MANIFEST"), []byte("garbage"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewCommand()
+	cmd.Stdout = ioutil.Discard
+	cmd.shardPath = shardPath
+	cmd.index = tsi1.IndexName
+	if err := cmd.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(shardPath, "index.old", "MANIFEST")); err != nil {
+		t.Fatalf("expected previous index to be moved aside: %s", err)
+	}
+
+	idx := tsi1.NewIndex()
+	idx.Path = filepath.Join(shardPath, "index")
+	if err := idx.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	if n := idx.SeriesN(); n != 3 {
+		t.Fatalf("unexpected series count: %d", n)
+	}
+	for _, name := range []string{"cpu", "mem"} {
+		if ok, err := idx.MeasurementExists([]byte(name)); err != nil {
+			t.Fatal(err)
+		} else if !ok {
+			t.Fatalf("expected measurement %q to exist", name)
+		}
+	}
+}
+
+func TestCommand_Run_Inmem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	MustWriteTSM(filepath.Join(dir, "000000001-000000001.tsm"), "cpu,host=a#!~#value")
+	if err := os.MkdirAll(filepath.Join(dir, "index"), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewCommand()
+	cmd.Stdout = ioutil.Discard
+	cmd.shardPath = dir
+	cmd.index = inmem.IndexName
+	if err := cmd.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "index")); !os.IsNotExist(err) {
+		t.Fatal("expected on-disk index to be removed")
+	} else if len(cmd.series) != 1 {
+		t.Fatalf("unexpected series count: %d", len(cmd.series))
+	}
+}
+
+func TestWalPathFor(t *testing.T) {
+	if got, exp := walPathFor("/var/lib/influxdb/data/db/rp/1"), "/var/lib/influxdb/wal/db/rp/1"; got != exp {
+		t.Fatalf("got %q, expected %q", got, exp)
+	} else if got := walPathFor("/tmp/shard"); got != "" {
+		t.Fatalf("unexpected wal path: %q", got)
+	}
+}
+
+// MustWriteTSM writes a TSM file containing keys to path.
+func MustWriteTSM(path string, keys ...string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		panic(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		panic(err)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := w.Write([]byte(key), []tsm1.Value{tsm1.NewValue(0, 1.0)}); err != nil {
+			panic(err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		panic(err)
+	} else if err := w.Close(); err != nil {
+		panic(err)
+	}
+}
+
+// MustWriteWAL writes a WAL segment containing keys to path.
+func MustWriteWAL(path string, keys ...string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		panic(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	entry := &tsm1.WriteWALEntry{Values: make(map[string][]tsm1.Value)}
+	for _, key := range keys {
+		entry.Values[key] = []tsm1.Value{tsm1.NewValue(0, 1.0)}
+	}
+
+	b, err := entry.Encode(nil)
+	if err != nil {
+		panic(err)
+	}
+
+	w := tsm1.NewWALSegmentWriter(f)
+	if err := w.Write(entry.Type(), snappy.Encode(nil, b)); err != nil {
+		panic(err)
+	} else if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,194 @@
+// Package compact runs a full compaction of a shard while the server is
+// stopped.
+package compact
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+// progressInterval is how often the progress of a compaction is printed.
+const progressInterval = 10 * time.Second
+
+// Command represents the program execution for "influx_inspect compact".
+type Command struct {
+	Stderr io.Writer
+	Stdout io.Writer
+
+	path  string
+	level string
+	force bool
+
+	// diskFree returns the free bytes of the filesystem at a path, and false
+	// if unknown. Overridden for testing.
+	diskFree func(path string) (uint64, bool)
+}
+
+// NewCommand returns a new instance of Command.
+func NewCommand() *Command {
+	return &Command{
+		Stderr:   os.Stderr,
+		Stdout:   os.Stdout,
+		diskFree: diskFree,
+	}
+}
+
+// Run executes the command.
+func (cmd *Command) Run(args ...string) error {
+	fs := flag.NewFlagSet("compact", flag.ExitOnError)
+	fs.StringVar(&cmd.path, "shard", "", "Shard data directory")
+	fs.StringVar(&cmd.level, "level", "full", "Compaction level")
+	fs.BoolVar(&cmd.force, "force", false, "Compact even if the disk may not have enough free space")
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if cmd.path == "" {
+		return errors.New("-shard is required")
+	} else if cmd.level != "full" {
+		return fmt.Errorf("unsupported -level %q: only full is supported", cmd.level)
+	}
+
+	return cmd.run()
+}
+
+func (cmd *Command) run() error {
+	if fi, err := os.Stat(cmd.path); err != nil {
+		return err
+	} else if !fi.IsDir() {
+		return fmt.Errorf("%s is not a shard directory", cmd.path)
+	}
+
+	fs := tsm1.NewFileStore(cmd.path)
+	if err := fs.Open(); err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	stats := fs.Stats()
+	var size uint64
+	var tombstones int
+	paths := make([]string, 0, len(stats))
+	for _, st := range stats {
+		paths = append(paths, st.Path)
+		size += uint64(st.Size)
+		if st.HasTombstone {
+			tombstones++
+		}
+	}
+
+	if len(paths) == 0 {
+		fmt.Fprintf(cmd.Stdout, "%s: no TSM files to compact\n", cmd.path)
+		return nil
+	} else if len(paths) == 1 && tombstones == 0 {
+		fmt.Fprintf(cmd.Stdout, "%s: already fully compacted\n", cmd.path)
+		return nil
+	}
+
+	// The new files are written before the old ones are removed, so the
+	// compaction can need as much free space as the shard uses.
+	if free, ok := cmd.diskFree(cmd.path); ok && free < size && !cmd.force {
+		return fmt.Errorf("compaction may need %s of free space but %s is available; use -force to compact anyway", formatSize(size), formatSize(free))
+	}
+
+	fmt.Fprintf(cmd.Stdout, "%s: compacting %d TSM files (%s, %d with tombstones)\n", cmd.path, len(paths), formatSize(size), tombstones)
+
+	c := &tsm1.Compactor{
+		Dir:       cmd.path,
+		FileStore: fs,
+	}
+	c.Open()
+	defer c.Close()
+
+	start := time.Now()
+	done := make(chan struct{})
+	go cmd.progress(size, done)
+
+	files, err := c.CompactFull(paths)
+	close(done)
+	if err != nil {
+		// Temporary files left by a failed compaction are removed when the
+		// server next opens the shard.
+		return err
+	}
+
+	if err := fs.Replace(paths, files); err != nil {
+		return err
+	}
+
+	var newSize uint64
+	for _, st := range fs.Stats() {
+		newSize += uint64(st.Size)
+	}
+	fmt.Fprintf(cmd.Stdout, "%s: compacted %d TSM files into %d (%s to %s) in %0.1fs\n", cmd.path, len(paths), len(files), formatSize(size), formatSize(newSize), time.Since(start).Seconds())
+	return nil
+}
+
+// progress prints the size of the files written so far until done is closed.
+func (cmd *Command) progress(total uint64, done chan struct{}) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			paths, _ := filepath.Glob(filepath.Join(cmd.path, "*."+tsm1.TSMFileExtension+"."+tsm1.CompactionTempExtension))
+			var written uint64
+			for _, path := range paths {
+				if fi, err := os.Stat(path); err == nil {
+					written += uint64(fi.Size())
+				}
+			}
+			fmt.Fprintf(cmd.Stdout, "%s: wrote %s of about %s\n", cmd.path, formatSize(written), formatSize(total))
+		}
+	}
+}
+
+func formatSize(v uint64) string {
+	denom := uint64(1)
+	var uom string
+	for _, uom = range []string{"b", "kb", "mb", "gb", "tb"} {
+		if denom*1024 > v {
+			break
+		}
+		denom *= 1024
+	}
+	return fmt.Sprintf("%0.01f%s", float64(v)/float64(denom), uom)
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *Command) printUsage() {
+	usage := `Runs a full compaction of a shard, rewriting all of its TSM files into as
+few files as possible and applying and removing their tombstones. The
+InfluxDB process must not be running.
+
+The new files are written before the old ones are removed, so the compaction
+can need as much free space as the shard uses. The shard's WAL is not
+compacted.
+
+Usage: influx_inspect compact [flags]
+
+    -shard <path>
+            Required. Shard data directory.
+    -level <level>
+            Compaction level. Only "full" is supported.
+            Defaults to "full".
+    -force
+            Compact even if the disk may not have enough free space.
+            Defaults to "false".
+`
+
+	fmt.Fprintf(cmd.Stdout, usage)
+}
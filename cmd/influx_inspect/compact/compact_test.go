@@ -0,0 +1,154 @@
+package compact
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+func TestCommand_Full(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	MustWriteTSM(t, dir, 1, map[string][]tsm1.Value{
+		"cpu,host=A#!~#value": {tsm1.NewValue(1, 1.0), tsm1.NewValue(2, 2.0)},
+		"mem,host=A#!~#value": {tsm1.NewValue(1, 1.0)},
+	})
+	MustWriteTSM(t, dir, 2, map[string][]tsm1.Value{
+		"cpu,host=A#!~#value": {tsm1.NewValue(3, 3.0)},
+	})
+
+	// Tombstone a key in the first file.
+	f, err := os.Open(filepath.Join(dir, "000000001-000000001.tsm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Delete([][]byte{[]byte("mem,host=A#!~#value")}); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	var stdout bytes.Buffer
+	cmd := NewCommand()
+	cmd.Stdout = &stdout
+	if err := cmd.Run("-shard", dir); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "compacted 2 TSM files into 1") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(paths) != 1 || !strings.HasSuffix(paths[0], "."+tsm1.TSMFileExtension) {
+		t.Fatalf("unexpected files: %v", paths)
+	}
+
+	f, err = os.Open(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if got, exp := r.KeyCount(), 1; got != exp {
+		t.Fatalf("unexpected key count: got %d, exp %d", got, exp)
+	}
+	values, err := r.ReadAll([]byte("cpu,host=A#!~#value"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(values) != 3 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+
+	// A fully compacted shard is left alone.
+	stdout.Reset()
+	if err := cmd.Run("-shard", dir); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(stdout.String(), "already fully compacted") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+}
+
+func TestCommand_DiskFree(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	for i := 1; i <= 2; i++ {
+		MustWriteTSM(t, dir, i, map[string][]tsm1.Value{
+			"cpu,host=A#!~#value": {tsm1.NewValue(int64(i), 1.0)},
+		})
+	}
+
+	cmd := NewCommand()
+	cmd.Stdout = ioutil.Discard
+	cmd.diskFree = func(string) (uint64, bool) { return 1, true }
+	if err := cmd.Run("-shard", dir); err == nil || !strings.Contains(err.Error(), "free space") {
+		t.Fatalf("expected free space error, got %v", err)
+	}
+
+	if err := cmd.Run("-shard", dir, "-force"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommand_Level(t *testing.T) {
+	cmd := NewCommand()
+	cmd.Stdout = ioutil.Discard
+	if err := cmd.Run("-shard", "x", "-level", "1"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// MustWriteTSM writes values to a TSM file of generation gen in dir.
+func MustWriteTSM(t *testing.T, dir string, gen int, values map[string][]tsm1.Value) {
+	f, err := os.Create(filepath.Join(dir, fmt.Sprintf("%09d-%09d.%s", gen, 1, tsm1.TSMFileExtension)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := w.Write([]byte(k), values[k]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.WriteIndex(); err != nil {
+		t.Fatal(err)
+	} else if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func MustTempDir() string {
+	dir, err := ioutil.TempDir("", "compact-")
+	if err != nil {
+		panic(fmt.Sprintf("failed to create temp dir: %v", err))
+	}
+	return dir
+}
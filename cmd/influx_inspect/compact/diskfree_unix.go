@@ -0,0 +1,15 @@
+// +build !windows
+
+package compact
+
+import "syscall"
+
+// diskFree returns the bytes available to unprivileged users on the
+// filesystem at path.
+func diskFree(path string) (uint64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, false
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), true
+}
@@ -0,0 +1,290 @@
+// Package deletetsm removes the series of measurements from the TSM files of
+// a shard while the server is stopped.
+package deletetsm
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+// Command represents the program execution for "influx_inspect deletetsm".
+type Command struct {
+	Stderr io.Writer
+	Stdout io.Writer
+
+	dir         string
+	measurement *regexp.Regexp
+	dryRun      bool
+	verbose     bool
+
+	seriesRemoved int
+	filesChanged  int
+}
+
+// NewCommand returns a new instance of Command.
+func NewCommand() *Command {
+	return &Command{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+	}
+}
+
+// Run executes the command.
+func (cmd *Command) Run(args ...string) error {
+	var measurement string
+	fs := flag.NewFlagSet("deletetsm", flag.ExitOnError)
+	fs.StringVar(&cmd.dir, "dir", "", "Shard data directory or TSM file")
+	fs.StringVar(&measurement, "measurement", "", "Regular expression matching the measurements to delete")
+	fs.BoolVar(&cmd.dryRun, "dry-run", false, "Report the series that would be deleted without deleting them")
+	fs.BoolVar(&cmd.verbose, "v", false, "Print each deleted series")
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if cmd.dir == "" {
+		return errors.New("-dir is required")
+	} else if measurement == "" {
+		return errors.New("-measurement is required")
+	}
+
+	re, err := regexp.Compile(measurement)
+	if err != nil {
+		return fmt.Errorf("invalid measurement regex: %s", err)
+	}
+	cmd.measurement = re
+
+	return cmd.run()
+}
+
+func (cmd *Command) run() error {
+	fi, err := os.Stat(cmd.dir)
+	if err != nil {
+		return err
+	}
+
+	paths := []string{cmd.dir}
+	if fi.IsDir() {
+		paths, err = filepath.Glob(filepath.Join(cmd.dir, "*."+tsm1.TSMFileExtension))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, path := range paths {
+		if err := cmd.process(path); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+	}
+
+	verb := "Deleted"
+	if cmd.dryRun {
+		verb = "Would delete"
+	}
+	fmt.Fprintf(cmd.Stdout, "%s %d series from %d of %d TSM files\n", verb, cmd.seriesRemoved, cmd.filesChanged, len(paths))
+
+	if fi.IsDir() && cmd.filesChanged > 0 && !cmd.dryRun {
+		if _, err := os.Stat(filepath.Join(cmd.dir, "index")); err == nil {
+			fmt.Fprintf(cmd.Stdout, "The shard has a tsi1 index. Rebuild it with: influx_inspect buildindex -shard %s\n", cmd.dir)
+		}
+	}
+	return nil
+}
+
+// matches returns true if the measurement of the TSM key matches.
+func (cmd *Command) matches(key []byte) (bool, error) {
+	seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+	name, err := models.ParseName(seriesKey)
+	if err != nil {
+		return false, err
+	}
+	return cmd.measurement.Match(name), nil
+}
+
+// process rewrites the TSM file at path without the series of matching
+// measurements. Files without matching series are left untouched.
+func (cmd *Command) process(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	n, deleted, err := cmd.find(path, r)
+	if err != nil || n == 0 || cmd.dryRun {
+		r.Close()
+		return err
+	}
+	tombstones := r.TombstoneFiles()
+
+	// Write the remaining series to a temporary file, which the engine
+	// removes on startup if this is interrupted.
+	tmpPath := strings.TrimSuffix(path, "."+tsm1.TSMFileExtension) + "." + tsm1.CompactionTempExtension
+	empty, err := cmd.rewrite(r, deleted, tmpPath)
+	r.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if empty {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	} else if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// The tombstones have been applied to the rewritten file.
+	for _, t := range tombstones {
+		if err := os.Remove(t.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	fmt.Fprintf(cmd.Stdout, "%s: deleted %d series\n", path, n)
+	return nil
+}
+
+// find returns the number of series of r in matching measurements, and
+// which keys of r belong to them.
+func (cmd *Command) find(path string, r *tsm1.TSMReader) (int, []bool, error) {
+	// Find the series to delete. Keys are sorted, so the fields of a series
+	// are adjacent.
+	deleted := make([]bool, r.KeyCount())
+	var n int
+	var prev []byte
+	for i := range deleted {
+		key, _ := r.KeyAt(i)
+		ok, err := cmd.matches(key)
+		if err != nil {
+			return 0, nil, err
+		} else if !ok {
+			continue
+		}
+		deleted[i] = true
+
+		seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+		if string(seriesKey) == string(prev) {
+			continue
+		}
+		prev = seriesKey
+		n++
+
+		if cmd.verbose {
+			fmt.Fprintf(cmd.Stdout, "%s: %s\n", path, seriesKey)
+		}
+	}
+
+	if n > 0 {
+		cmd.seriesRemoved += n
+		cmd.filesChanged++
+	}
+	return n, deleted, nil
+}
+
+// rewrite writes the keys of r that are not deleted to path. Blocks are
+// copied as is unless part of the key has been tombstoned. It returns true,
+// and removes path, if no values remain.
+func (cmd *Command) rewrite(r *tsm1.TSMReader, deleted []bool, path string) (bool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		return false, err
+	}
+
+	var entries []tsm1.IndexEntry
+	for i := range deleted {
+		if deleted[i] {
+			continue
+		}
+		key, _ := r.KeyAt(i)
+
+		if len(r.TombstoneRange(key)) > 0 {
+			values, err := r.ReadAll(key)
+			if err != nil {
+				return false, err
+			}
+			for len(values) > 0 {
+				n := len(values)
+				if n > tsdb.DefaultMaxPointsPerBlock {
+					n = tsdb.DefaultMaxPointsPerBlock
+				}
+				if err := w.Write(key, values[:n]); err != nil {
+					return false, err
+				}
+				values = values[n:]
+			}
+			continue
+		}
+
+		// Copy the blocks as they are stored, so encrypted blocks stay encrypted.
+		entries = r.ReadEntries(key, &entries)
+		for j := range entries {
+			_, b, err := r.ReadBytes(&entries[j], nil)
+			if err != nil {
+				return false, err
+			}
+			if err := w.WriteBlock(key, entries[j].MinTime, entries[j].MaxTime, b); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if err := w.WriteIndex(); err == tsm1.ErrNoValues {
+		w.Close()
+		return true, os.Remove(path)
+	} else if err != nil {
+		return false, err
+	}
+	return false, w.Close()
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *Command) printUsage() {
+	usage := `Deletes the series of measurements from the TSM files of a shard by rewriting
+the files. The InfluxDB process must not be running.
+
+Series still in the shard's WAL are not deleted and are written back when the
+server starts, so flush the WAL before stopping the server. Rebuild a tsi1
+index afterwards with "influx_inspect buildindex".
+
+Usage: influx_inspect deletetsm [flags]
+
+    -dir <path>
+            Required. Shard data directory, or a single TSM file.
+    -measurement <regex>
+            Required. Regular expression matching the names of the
+            measurements to delete. Use ^ and $ to match a whole name.
+    -dry-run
+            Report the series that would be deleted without deleting them.
+            Defaults to "false".
+    -v
+            Print each deleted series.
+            Defaults to "false".
+`
+
+	fmt.Fprintf(cmd.Stdout, usage)
+}
@@ -0,0 +1,146 @@
+package deletetsm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deletetsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mixed := filepath.Join(dir, "000000001-000000001.tsm")
+	MustWriteTSM(mixed, "cpu,host=a#!~#value", "cpu,host=b#!~#value", "cpu_load,host=a#!~#value", "mem,host=a#!~#free")
+	only := filepath.Join(dir, "000000002-000000001.tsm")
+	MustWriteTSM(only, "cpu,host=c#!~#value")
+	untouched := filepath.Join(dir, "000000003-000000001.tsm")
+	MustWriteTSM(untouched, "mem,host=b#!~#free")
+
+	// Tombstone part of a remaining series.
+	ts := tsm1.Tombstoner{Path: mixed}
+	if err := ts.AddRange([][]byte{[]byte("mem,host=a#!~#free")}, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := os.Stat(untouched)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := NewCommand()
+	cmd.Stdout = ioutil.Discard
+	cmd.dir = dir
+	cmd.measurement = regexp.MustCompile("^cpu$")
+	if err := cmd.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd.seriesRemoved != 3 || cmd.filesChanged != 2 {
+		t.Fatalf("unexpected counts: series=%d files=%d", cmd.seriesRemoved, cmd.filesChanged)
+	}
+
+	values := MustReadTSM(t, mixed)
+	if len(values) != 2 {
+		t.Fatalf("unexpected keys: %v", values)
+	} else if got := len(values["cpu_load,host=a#!~#value"]); got != 2 {
+		t.Fatalf("unexpected cpu_load values: %d", got)
+	} else if got := len(values["mem,host=a#!~#free"]); got != 1 {
+		t.Fatalf("unexpected mem values: %d", got)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.tombstone")); len(matches) != 0 {
+		t.Fatalf("unexpected tombstones: %v", matches)
+	}
+
+	if _, err := os.Stat(only); !os.IsNotExist(err) {
+		t.Fatalf("expected file with only deleted series to be removed: %v", err)
+	}
+
+	if after, err := os.Stat(untouched); err != nil {
+		t.Fatal(err)
+	} else if !after.ModTime().Equal(before.ModTime()) {
+		t.Fatal("expected file without deleted series to be untouched")
+	}
+}
+
+func TestCommand_Run_DryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deletetsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "000000001-000000001.tsm")
+	MustWriteTSM(path, "cpu,host=a#!~#idle", "cpu,host=a#!~#value", "mem,host=a#!~#free")
+
+	cmd := NewCommand()
+	cmd.Stdout = ioutil.Discard
+	cmd.dir = path
+	cmd.measurement = regexp.MustCompile("cpu")
+	cmd.dryRun = true
+	if err := cmd.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd.seriesRemoved != 1 {
+		t.Fatalf("unexpected series count: %d", cmd.seriesRemoved)
+	} else if values := MustReadTSM(t, path); len(values) != 3 {
+		t.Fatalf("unexpected keys: %v", values)
+	}
+}
+
+// MustWriteTSM writes a TSM file containing two values for each of keys,
+// which must be sorted.
+func MustWriteTSM(path string, keys ...string) {
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		panic(err)
+	}
+	for _, key := range keys {
+		if err := w.Write([]byte(key), []tsm1.Value{tsm1.NewValue(0, 1.0), tsm1.NewValue(1, 2.0)}); err != nil {
+			panic(err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		panic(err)
+	} else if err := w.Close(); err != nil {
+		panic(err)
+	}
+}
+
+// MustReadTSM returns the values of each key of the TSM file at path.
+func MustReadTSM(t *testing.T, path string) map[string][]tsm1.Value {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	values := make(map[string][]tsm1.Value)
+	for i := 0; i < r.KeyCount(); i++ {
+		key, _ := r.KeyAt(i)
+		v, err := r.ReadAll(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values[string(key)] = v
+	}
+	return values
+}
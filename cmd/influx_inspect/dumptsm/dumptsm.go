@@ -12,6 +12,7 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/influxdata/influxdb/pkg/encryption"
 	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
 )
 
@@ -26,6 +27,9 @@ type Command struct {
 	dumpAll    bool
 	filterKey  string
 	path       string
+
+	keyProvider string
+	keySource   string
 }
 
 // NewCommand returns a new instance of Command.
@@ -43,6 +47,8 @@ func (cmd *Command) Run(args ...string) error {
 	fs.BoolVar(&cmd.dumpBlocks, "blocks", false, "Dump raw block data")
 	fs.BoolVar(&cmd.dumpAll, "all", false, "Dump all data. Caution: This may print a lot of information")
 	fs.StringVar(&cmd.filterKey, "filter-key", "", "Only display index and block data match this key substring")
+	fs.StringVar(&cmd.keyProvider, "encryption-key-provider", "file", "Provider of the keys encrypted blocks are decrypted with")
+	fs.StringVar(&cmd.keySource, "encryption-key-source", "", "Source of the keys encrypted blocks are decrypted with, such as a key file path")
 
 	fs.SetOutput(cmd.Stdout)
 	fs.Usage = cmd.printUsage
@@ -59,6 +65,14 @@ func (cmd *Command) Run(args ...string) error {
 	cmd.path = fs.Args()[0]
 	cmd.dumpBlocks = cmd.dumpBlocks || cmd.dumpAll || cmd.filterKey != ""
 	cmd.dumpIndex = cmd.dumpIndex || cmd.dumpAll || cmd.filterKey != ""
+
+	if cmd.keySource != "" {
+		p, err := encryption.NewKeyProvider(cmd.keyProvider, cmd.keySource)
+		if err != nil {
+			return err
+		}
+		tsm1.SetCipher(encryption.NewCipher(p))
+	}
 	return cmd.dump()
 }
 
@@ -164,6 +178,13 @@ func (cmd *Command) dump() error {
 				continue
 			}
 
+			// Encrypted blocks are decrypted to inspect their encodings, but
+			// their stored lengths are reported.
+			n := len(buf)
+			if buf, err = tsm1.DecryptBlock(buf); err != nil {
+				return err
+			}
+
 			blockType := buf[0]
 
 			encoded := buf[1:]
@@ -193,14 +214,14 @@ func (cmd *Command) dump() error {
 
 			blockStats.inc(0, ts[0]>>4)
 			blockStats.inc(int(blockType+1), values[0]>>4)
-			blockStats.size(len(buf))
+			blockStats.size(n)
 
 			if cmd.dumpBlocks {
 				fmt.Fprintln(tw, "  "+strings.Join([]string{
 					strconv.FormatInt(blockCount, 10),
 					strconv.FormatUint(uint64(chksum), 10),
 					strconv.FormatInt(i, 10),
-					strconv.FormatInt(int64(len(buf)), 10),
+					strconv.FormatInt(int64(n), 10),
 					typeDesc,
 					startTime.UTC().Format(time.RFC3339Nano),
 					strconv.FormatInt(int64(len(v)), 10),
@@ -275,6 +296,10 @@ Usage: influx_inspect dumptsm [flags] <path
             Dump all data. Caution: This may print a lot of information
     -filter-key <name>
             Only display index and block data match this key substring
+    -encryption-key-provider <name>
+            Provider of the keys encrypted blocks are decrypted with (default "file")
+    -encryption-key-source <source>
+            Source of the keys encrypted blocks are decrypted with, such as a key file path
 `
 
 	fmt.Fprintf(cmd.Stdout, usage)
@@ -0,0 +1,337 @@
+// Package dumpwal decodes the entries of tsm1 WAL segments, either into a
+// human-readable dump or into line protocol for replay into another instance.
+package dumpwal
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/escape"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+// Command represents the program execution for "influx_inspect dumpwal".
+type Command struct {
+	Stderr io.Writer
+	Stdout io.Writer
+
+	filter
+	values bool
+}
+
+// NewCommand returns a new instance of Command.
+func NewCommand() *Command {
+	return &Command{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+	}
+}
+
+// Run executes the command.
+func (cmd *Command) Run(args ...string) error {
+	fs := flag.NewFlagSet("dumpwal", flag.ExitOnError)
+	cmd.register(fs)
+	fs.BoolVar(&cmd.values, "values", false, "Print each value of write entries")
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if err := cmd.parse(); err != nil {
+		return err
+	} else if fs.NArg() == 0 {
+		return errors.New("no WAL segments or directories specified")
+	}
+
+	return walk(fs.Args(), cmd.Stderr, cmd.dump)
+}
+
+// dump prints the parts of entry that match the filter.
+func (cmd *Command) dump(path string, n int, entry tsm1.WALEntry) error {
+	w := cmd.Stdout
+	if n == 1 {
+		fmt.Fprintln(w, path)
+	}
+
+	switch e := entry.(type) {
+	case *tsm1.WriteWALEntry:
+		keys := make([]string, 0, len(e.Values))
+		var count int
+		for key, values := range e.Values {
+			if !cmd.matchesKey([]byte(key)) {
+				continue
+			}
+			if c, _, _ := cmd.count(values); c > 0 {
+				keys = append(keys, key)
+				count += c
+			}
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(w, "  entry %d: write, %d values in %d keys\n", n, count, len(keys))
+		for _, key := range keys {
+			values := e.Values[key]
+			if !cmd.values {
+				c, min, max := cmd.count(values)
+				fmt.Fprintf(w, "    %s %s, %d values, %s to %s\n", key, valueType(values[0]), c, formatTime(min), formatTime(max))
+				continue
+			}
+			for _, v := range values {
+				if cmd.matchesTime(v.UnixNano()) {
+					fmt.Fprintf(w, "    %s %s %v\n", key, formatTime(v.UnixNano()), v.Value())
+				}
+			}
+		}
+
+	case *tsm1.DeleteWALEntry:
+		keys := cmd.matchingKeys(e.Keys)
+		if len(keys) == 0 {
+			return nil
+		}
+		fmt.Fprintf(w, "  entry %d: delete, %d keys\n", n, len(keys))
+		for _, key := range keys {
+			fmt.Fprintf(w, "    %s\n", key)
+		}
+
+	case *tsm1.DeleteRangeWALEntry:
+		if e.Max < cmd.start || e.Min > cmd.end {
+			return nil
+		}
+		keys := cmd.matchingKeys(e.Keys)
+		if len(keys) == 0 {
+			return nil
+		}
+		fmt.Fprintf(w, "  entry %d: delete range %s to %s, %d keys\n", n, formatTime(e.Min), formatTime(e.Max), len(keys))
+		for _, key := range keys {
+			fmt.Fprintf(w, "    %s\n", key)
+		}
+	}
+	return nil
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *Command) printUsage() {
+	usage := `Decodes the entries of WAL segments into human-readable writes and deletes.
+
+Usage: influx_inspect dumpwal [flags] <path>...
+
+    <path>
+            WAL segment files, or shard WAL directories to dump all the
+            segments of, in order.
+    -key <regex>
+            Only dump keys matching the regular expression. Write keys are
+            matched with their field, as in "cpu,host=A#!~#value".
+    -start <time>
+            Only dump values and deletes at or after the RFC3339 time.
+    -end <time>
+            Only dump values and deletes at or before the RFC3339 time.
+    -values
+            Print each value of write entries instead of a summary of
+            each key.
+            Defaults to "false".
+`
+
+	fmt.Fprintf(cmd.Stdout, usage)
+}
+
+// filter holds the key and time flags shared by dumpwal and replaywal.
+type filter struct {
+	keyExpr    string
+	startExpr  string
+	endExpr    string
+	key        *regexp.Regexp
+	start, end int64
+}
+
+func (f *filter) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.keyExpr, "key", "", "Regular expression matching the keys to include")
+	fs.StringVar(&f.startExpr, "start", "", "Optional. Include only values at or after this RFC3339 time")
+	fs.StringVar(&f.endExpr, "end", "", "Optional. Include only values at or before this RFC3339 time")
+}
+
+// parse parses the flag values of f.
+func (f *filter) parse() error {
+	f.start, f.end = math.MinInt64, math.MaxInt64
+
+	if f.keyExpr != "" {
+		re, err := regexp.Compile(f.keyExpr)
+		if err != nil {
+			return fmt.Errorf("invalid key regex: %s", err)
+		}
+		f.key = re
+	}
+
+	if f.startExpr != "" {
+		t, err := time.Parse(time.RFC3339Nano, f.startExpr)
+		if err != nil {
+			return fmt.Errorf("invalid -start time: %s", err)
+		}
+		f.start = t.UnixNano()
+	}
+	if f.endExpr != "" {
+		t, err := time.Parse(time.RFC3339Nano, f.endExpr)
+		if err != nil {
+			return fmt.Errorf("invalid -end time: %s", err)
+		}
+		f.end = t.UnixNano()
+	}
+	if f.start > f.end {
+		return errors.New("-end must not be before -start")
+	}
+	return nil
+}
+
+func (f *filter) matchesKey(key []byte) bool {
+	return f.key == nil || f.key.Match(key)
+}
+
+func (f *filter) matchesTime(ts int64) bool {
+	return ts >= f.start && ts <= f.end
+}
+
+// count returns the number of values in the time range, and the minimum and
+// maximum of their timestamps.
+func (f *filter) count(values []tsm1.Value) (n int, min, max int64) {
+	min, max = math.MaxInt64, math.MinInt64
+	for _, v := range values {
+		ts := v.UnixNano()
+		if !f.matchesTime(ts) {
+			continue
+		}
+		n++
+		if ts < min {
+			min = ts
+		}
+		if ts > max {
+			max = ts
+		}
+	}
+	return n, min, max
+}
+
+// matchingKeys returns the sorted keys that match.
+func (f *filter) matchingKeys(keys [][]byte) []string {
+	var matched []string
+	for _, key := range keys {
+		if f.matchesKey(key) {
+			matched = append(matched, string(key))
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+// walk calls fn with each entry of the WAL segments at paths, numbered from 1
+// in each segment. Directories are expanded to the segments in them. A corrupt
+// segment is reported to stderr and the entries after the corruption skipped.
+func walk(paths []string, stderr io.Writer, fn func(path string, n int, entry tsm1.WALEntry) error) error {
+	var segments []string
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			segments = append(segments, path)
+			continue
+		}
+
+		// Segment names are zero padded, so they sort in the order written.
+		names, err := filepath.Glob(filepath.Join(path, fmt.Sprintf("%s*.%s", tsm1.WALFilePrefix, tsm1.WALFileExtension)))
+		if err != nil {
+			return err
+		}
+		sort.Strings(names)
+		segments = append(segments, names...)
+	}
+
+	for _, path := range segments {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		r := tsm1.NewWALSegmentReader(f)
+		for n := 1; r.Next(); n++ {
+			entry, err := r.Read()
+			if err != nil {
+				fmt.Fprintf(stderr, "file %s corrupt at position %d: %s\n", path, r.Count(), err)
+				break
+			}
+			if err := fn(path, n, entry); err != nil {
+				r.Close()
+				return err
+			}
+		}
+		r.Close()
+	}
+	return nil
+}
+
+// valueType returns the name of the field type of v.
+func valueType(v tsm1.Value) string {
+	switch v.Value().(type) {
+	case float64:
+		return "float"
+	case int64:
+		return "integer"
+	case uint64:
+		return "unsigned"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
+func formatTime(ts int64) string {
+	return time.Unix(0, ts).UTC().Format(time.RFC3339Nano)
+}
+
+// appendLine appends v as a line of line protocol for the field of the
+// series key.
+func appendLine(buf []byte, seriesKey []byte, field []byte, v tsm1.Value) []byte {
+	buf = append(buf, seriesKey...)
+	buf = append(buf, ' ')
+	// Series keys are stored escaped, field names are not.
+	buf = append(buf, escape.Bytes(field)...)
+	buf = append(buf, '=')
+
+	switch v := v.Value().(type) {
+	case float64:
+		buf = strconv.AppendFloat(buf, v, 'g', -1, 64)
+	case int64:
+		buf = strconv.AppendInt(buf, v, 10)
+		buf = append(buf, 'i')
+	case uint64:
+		buf = strconv.AppendUint(buf, v, 10)
+		buf = append(buf, 'u')
+	case bool:
+		buf = strconv.AppendBool(buf, v)
+	case string:
+		buf = append(buf, '"')
+		buf = append(buf, models.EscapeStringField(v)...)
+		buf = append(buf, '"')
+	default:
+		buf = append(buf, fmt.Sprintf("%v", v)...)
+	}
+
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, v.UnixNano(), 10)
+	return append(buf, '\n')
+}
@@ -0,0 +1,122 @@
+package dumpwal_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/dumpwal"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+func TestDumpWAL(t *testing.T) {
+	dir := MustWriteWAL()
+	defer os.RemoveAll(dir)
+
+	var stdout bytes.Buffer
+	cmd := dumpwal.NewCommand()
+	cmd.Stdout = &stdout
+	if err := cmd.Run("-key", "^cpu", dir); err != nil {
+		t.Fatal(err)
+	}
+
+	got := stdout.String()
+	for _, exp := range []string{
+		"entry 1: write, 2 values in 1 keys",
+		"cpu,host=A#!~#value float, 2 values, 1970-01-01T00:00:01Z to 1970-01-01T00:00:02Z",
+		"entry 2: delete range 1970-01-01T00:00:00Z to 1970-01-01T00:00:01Z, 1 keys",
+	} {
+		if !strings.Contains(got, exp) {
+			t.Fatalf("expected %q in output:\n%s", exp, got)
+		}
+	}
+	if strings.Contains(got, "mem") {
+		t.Fatalf("unexpected filtered key in output:\n%s", got)
+	}
+}
+
+func TestDumpWAL_Values(t *testing.T) {
+	dir := MustWriteWAL()
+	defer os.RemoveAll(dir)
+
+	var stdout bytes.Buffer
+	cmd := dumpwal.NewCommand()
+	cmd.Stdout = &stdout
+	if err := cmd.Run("-values", "-start", "1970-01-01T00:00:02Z", dir); err != nil {
+		t.Fatal(err)
+	}
+
+	got := stdout.String()
+	if exp := "cpu,host=A#!~#value 1970-01-01T00:00:02Z 2.5"; !strings.Contains(got, exp) {
+		t.Fatalf("expected %q in output:\n%s", exp, got)
+	} else if strings.Contains(got, "00:00:01Z 1.5") || strings.Contains(got, "delete") {
+		t.Fatalf("unexpected value outside time range in output:\n%s", got)
+	}
+}
+
+func TestReplayWAL(t *testing.T) {
+	dir := MustWriteWAL()
+	defer os.RemoveAll(dir)
+
+	var stdout, stderr bytes.Buffer
+	cmd := dumpwal.NewReplayCommand()
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run("-database", "db0", filepath.Join(dir, "_00001.wal")); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `# DML
+# CONTEXT-DATABASE:db0
+cpu,host=A value=1.5 1000000000
+cpu,host=A value=2.5 2000000000
+mem,host=A free=10i 1000000000
+mem,host=A msg="a \"b\"" 1000000000
+`
+	if got := stdout.String(); got != exp {
+		t.Fatalf("unexpected output:\ngot:\n%s\nexp:\n%s", got, exp)
+	} else if !strings.Contains(stderr.String(), "skipped 1 delete entries") {
+		t.Fatalf("expected delete warning, got %q", stderr.String())
+	}
+}
+
+// MustWriteWAL writes a WAL segment with a write and a delete range entry to a
+// new directory, and returns the directory.
+func MustWriteWAL() string {
+	dir, err := ioutil.TempDir("", "dumpwal-")
+	if err != nil {
+		panic(err)
+	}
+
+	f, err := os.Create(filepath.Join(dir, "_00001.wal"))
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	w := tsm1.NewWALSegmentWriter(f)
+	for _, e := range []tsm1.WALEntry{
+		&tsm1.WriteWALEntry{Values: map[string][]tsm1.Value{
+			"cpu,host=A#!~#value": {tsm1.NewValue(1000000000, 1.5), tsm1.NewValue(2000000000, 2.5)},
+			"mem,host=A#!~#free":  {tsm1.NewValue(1000000000, int64(10))},
+			"mem,host=A#!~#msg":   {tsm1.NewValue(1000000000, `a "b"`)},
+		}},
+		&tsm1.DeleteRangeWALEntry{Keys: [][]byte{[]byte("cpu,host=A#!~#value")}, Min: 0, Max: 1000000000},
+	} {
+		b, err := e.Encode(nil)
+		if err != nil {
+			panic(err)
+		}
+		if err := w.Write(e.Type(), snappy.Encode(nil, b)); err != nil {
+			panic(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+	return dir
+}
@@ -0,0 +1,169 @@
+package dumpwal
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+// ReplayCommand represents the program execution for "influx_inspect replaywal".
+type ReplayCommand struct {
+	Stderr io.Writer
+	Stdout io.Writer
+
+	filter
+	out       string
+	database  string
+	retention string
+
+	lines   int
+	deletes int
+}
+
+// NewReplayCommand returns a new instance of ReplayCommand.
+func NewReplayCommand() *ReplayCommand {
+	return &ReplayCommand{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+	}
+}
+
+// Run executes the command.
+func (cmd *ReplayCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("replaywal", flag.ExitOnError)
+	cmd.register(fs)
+	fs.StringVar(&cmd.out, "out", "", "Destination file, or STDOUT if empty")
+	fs.StringVar(&cmd.database, "database", "", "Optional. Database to write the points to when imported with the influx CLI")
+	fs.StringVar(&cmd.retention, "retention", "", "Optional. Retention policy to write the points to when imported with the influx CLI")
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if err := cmd.parse(); err != nil {
+		return err
+	} else if fs.NArg() == 0 {
+		return errors.New("no WAL segments or directories specified")
+	} else if cmd.retention != "" && cmd.database == "" {
+		return errors.New("-retention requires -database")
+	}
+
+	var w io.Writer = cmd.Stdout
+	if cmd.out != "" {
+		f, err := os.Create(cmd.out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	if cmd.database != "" {
+		fmt.Fprintln(bw, "# DML")
+		fmt.Fprintf(bw, "# CONTEXT-DATABASE:%s\n", cmd.database)
+		if cmd.retention != "" {
+			fmt.Fprintf(bw, "# CONTEXT-RETENTION-POLICY:%s\n", cmd.retention)
+		}
+	}
+
+	var buf []byte
+	err := walk(fs.Args(), cmd.Stderr, func(path string, n int, entry tsm1.WALEntry) error {
+		var err error
+		buf, err = cmd.replay(bw, buf, entry)
+		return err
+	})
+	if err != nil {
+		return err
+	} else if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	if cmd.deletes > 0 {
+		fmt.Fprintf(cmd.Stderr, "warning: skipped %d delete entries, which cannot be expressed as line protocol. Deleted data may be restored by the replay.\n", cmd.deletes)
+	}
+	if cmd.out != "" {
+		fmt.Fprintf(cmd.Stdout, "wrote %d lines to %s\n", cmd.lines, cmd.out)
+	}
+	return nil
+}
+
+// replay writes the values of a write entry that match the filter to w as
+// line protocol, using buf as scratch space.
+func (cmd *ReplayCommand) replay(w io.Writer, buf []byte, entry tsm1.WALEntry) ([]byte, error) {
+	switch e := entry.(type) {
+	case *tsm1.WriteWALEntry:
+		keys := make([]string, 0, len(e.Values))
+		for key := range e.Values {
+			if cmd.matchesKey([]byte(key)) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			seriesKey, field := tsm1.SeriesAndFieldFromCompositeKey([]byte(key))
+			for _, v := range e.Values[key] {
+				if !cmd.matchesTime(v.UnixNano()) {
+					continue
+				}
+				buf = appendLine(buf[:0], seriesKey, field, v)
+				if _, err := w.Write(buf); err != nil {
+					return buf, err
+				}
+				cmd.lines++
+			}
+		}
+
+	case *tsm1.DeleteWALEntry:
+		if len(cmd.matchingKeys(e.Keys)) > 0 {
+			cmd.deletes++
+		}
+
+	case *tsm1.DeleteRangeWALEntry:
+		if e.Max >= cmd.start && e.Min <= cmd.end && len(cmd.matchingKeys(e.Keys)) > 0 {
+			cmd.deletes++
+		}
+	}
+	return buf, nil
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *ReplayCommand) printUsage() {
+	usage := `Converts the writes in WAL segments to line protocol, to replay them into
+another instance with "influx -import" or the /write endpoint. Use it to recover
+data that a crashed node never snapshotted to TSM files.
+
+Deletes cannot be expressed as line protocol and are skipped with a warning,
+so data deleted after it was written is written again by the replay.
+
+Usage: influx_inspect replaywal [flags] <path>...
+
+    <path>
+            WAL segment files, or shard WAL directories to convert all the
+            segments of, in order.
+    -key <regex>
+            Only convert keys matching the regular expression. Keys are
+            matched with their field, as in "cpu,host=A#!~#value".
+    -start <time>
+            Only convert values at or after the RFC3339 time.
+    -end <time>
+            Only convert values at or before the RFC3339 time.
+    -out <path>
+            Destination file. Defaults to STDOUT.
+    -database <name>
+            Database to write the points to when the output is imported
+            with "influx -import".
+    -retention <name>
+            Retention policy to write the points to when the output is
+            imported with "influx -import". Requires -database.
+`
+
+	fmt.Fprintf(cmd.Stdout, usage)
+}
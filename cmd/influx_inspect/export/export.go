@@ -10,6 +10,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -20,6 +21,14 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/pkg/escape"
 	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported output compression formats.
+const (
+	compressNone = ""
+	compressGzip = "gzip"
+	compressZstd = "zstd"
 )
 
 // Command represents the program execution for "influx_inspect export".
@@ -35,7 +44,8 @@ type Command struct {
 	retentionPolicy string
 	startTime       int64
 	endTime         int64
-	compress        bool
+	compress        compression
+	measurement     *regexp.Regexp
 
 	manifest map[string]struct{}
 	tsmFiles map[string][]string
@@ -56,7 +66,7 @@ func NewCommand() *Command {
 
 // Run executes the command.
 func (cmd *Command) Run(args ...string) error {
-	var start, end string
+	var start, end, measurement string
 	fs := flag.NewFlagSet("export", flag.ExitOnError)
 	fs.StringVar(&cmd.dataDir, "datadir", os.Getenv("HOME")+"/.influxdb/data", "Data storage path")
 	fs.StringVar(&cmd.walDir, "waldir", os.Getenv("HOME")+"/.influxdb/wal", "WAL storage path")
@@ -65,7 +75,8 @@ func (cmd *Command) Run(args ...string) error {
 	fs.StringVar(&cmd.retentionPolicy, "retention", "", "Optional: the retention policy to export (requires -database)")
 	fs.StringVar(&start, "start", "", "Optional: the start time to export (RFC3339 format)")
 	fs.StringVar(&end, "end", "", "Optional: the end time to export (RFC3339 format)")
-	fs.BoolVar(&cmd.compress, "compress", false, "Compress the output")
+	fs.StringVar(&measurement, "measurement", "", "Optional: a regular expression matching the measurements to export")
+	fs.Var(&cmd.compress, "compress", "Compress the output with gzip, or with the given format (gzip or zstd)")
 
 	fs.SetOutput(cmd.Stdout)
 	fs.Usage = func() {
@@ -74,7 +85,7 @@ func (cmd *Command) Run(args ...string) error {
 		fs.PrintDefaults()
 	}
 
-	if err := fs.Parse(args); err != nil {
+	if err := fs.Parse(joinCompressArg(args)); err != nil {
 		return err
 	}
 
@@ -99,6 +110,14 @@ func (cmd *Command) Run(args ...string) error {
 		cmd.endTime = math.MaxInt64
 	}
 
+	if measurement != "" {
+		re, err := regexp.Compile(measurement)
+		if err != nil {
+			return fmt.Errorf("invalid measurement regex: %s", err)
+		}
+		cmd.measurement = re
+	}
+
 	if err := cmd.validate(); err != nil {
 		return err
 	}
@@ -203,10 +222,18 @@ func (cmd *Command) write() error {
 
 	var w io.Writer = bw
 
-	if cmd.compress {
+	switch cmd.compress {
+	case compressGzip:
 		gzw := gzip.NewWriter(w)
 		defer gzw.Close()
 		w = gzw
+	case compressZstd:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		defer zw.Close()
+		w = zw
 	}
 
 	s, e := time.Unix(0, cmd.startTime).Format(time.RFC3339), time.Unix(0, cmd.endTime).Format(time.RFC3339)
@@ -269,7 +296,9 @@ func (cmd *Command) exportTSMFile(tsmFilePath string, w io.Writer) error {
 	}
 	defer f.Close()
 
-	r, err := tsm1.NewTSMReader(f)
+	// Each block is read once, so keep the export from evicting the page
+	// cache of a running server.
+	r, err := tsm1.NewTSMReader(f, tsm1.WithDropPageCache(true))
 	if err != nil {
 		fmt.Fprintf(cmd.Stderr, "unable to read %s, skipping: %s\n", tsmFilePath, err.Error())
 		return nil
@@ -280,8 +309,14 @@ func (cmd *Command) exportTSMFile(tsmFilePath string, w io.Writer) error {
 		return nil
 	}
 
+	var entries []tsm1.IndexEntry
 	for i := 0; i < r.KeyCount(); i++ {
-		key, _ := r.KeyAt(i)
+		var key []byte
+		key, _, entries = r.Key(i, &entries)
+		if !cmd.matchesKey(key) || !overlaps(entries, cmd.startTime, cmd.endTime) {
+			continue
+		}
+
 		values, err := r.ReadAll(key)
 		if err != nil {
 			fmt.Fprintf(cmd.Stderr, "unable to read key %q in %s, skipping: %s\n", string(key), tsmFilePath, err.Error())
@@ -354,6 +389,10 @@ func (cmd *Command) exportWALFile(walFilePath string, w io.Writer, warnDelete fu
 			continue
 		case *tsm1.WriteWALEntry:
 			for key, values := range t.Values {
+				if !cmd.matchesKey([]byte(key)) {
+					continue
+				}
+
 				measurement, field := tsm1.SeriesAndFieldFromCompositeKey([]byte(key))
 				// measurements are stored escaped, field names are not
 				field = escape.Bytes(field)
@@ -368,6 +407,31 @@ func (cmd *Command) exportWALFile(walFilePath string, w io.Writer, warnDelete fu
 	return nil
 }
 
+// matchesKey returns true if the measurement of the composite series and
+// field key matches the -measurement filter.
+func (cmd *Command) matchesKey(key []byte) bool {
+	if cmd.measurement == nil {
+		return true
+	}
+
+	seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+	name, err := models.ParseName(seriesKey)
+	if err != nil {
+		return false
+	}
+	return cmd.measurement.Match(escape.Unescape(name))
+}
+
+// overlaps returns true if any of the blocks in entries contain values between min and max.
+func overlaps(entries []tsm1.IndexEntry, min, max int64) bool {
+	for _, e := range entries {
+		if e.OverlapsTimeRange(min, max) {
+			return true
+		}
+	}
+	return false
+}
+
 // writeValues writes every value in values to w, using the given series key and field name.
 // If any call to w.Write fails, that error is returned.
 func (cmd *Command) writeValues(w io.Writer, seriesKey []byte, field string, values []tsm1.Value) error {
@@ -417,3 +481,44 @@ func (cmd *Command) writeValues(w io.Writer, seriesKey []byte, field string, val
 
 	return nil
 }
+
+// compression is a flag.Value holding the output compression format. It may
+// be given as a bare boolean flag, which selects gzip.
+type compression string
+
+func (c *compression) String() string { return string(*c) }
+
+func (c *compression) IsBoolFlag() bool { return true }
+
+func (c *compression) Set(s string) error {
+	switch s {
+	case "true", compressGzip:
+		*c = compressGzip
+	case "false", "none":
+		*c = compressNone
+	case compressZstd:
+		*c = compressZstd
+	default:
+		return fmt.Errorf("unsupported compression %q, must be gzip or zstd", s)
+	}
+	return nil
+}
+
+// joinCompressArg rewrites "-compress <format>" as "-compress=<format>". The
+// compress flag is a boolean flag, so the flag package would otherwise treat
+// the format as a positional argument.
+func joinCompressArg(args []string) []string {
+	a := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if (args[i] == "-compress" || args[i] == "--compress") && i+1 < len(args) {
+			switch args[i+1] {
+			case compressGzip, compressZstd, "none":
+				a = append(a, args[i]+"="+args[i+1])
+				i++
+				continue
+			}
+		}
+		a = append(a, args[i])
+	}
+	return a
+}
@@ -2,11 +2,14 @@ package export
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"math/rand"
 	"os"
+	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -148,6 +151,69 @@ func Test_exportTSMFile(t *testing.T) {
 	}
 }
 
+func Test_exportTSMFile_Filter(t *testing.T) {
+	tsmFile := writeCorpusToTSMFile(basicCorpus)
+	defer os.Remove(tsmFile.Name())
+
+	cmd := newCommand()
+	cmd.measurement = regexp.MustCompile("^(ints|strings)$")
+	cmd.startTime, cmd.endTime = 20, 1000
+
+	var out bytes.Buffer
+	if err := cmd.exportTSMFile(tsmFile.Name(), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "ints,k=i i=30i 20\nstrings,k=s s=\"1k\" 1000\n"
+	if got := out.String(); got != exp {
+		t.Fatalf("unexpected output:\ngot: %s\nexp: %s", got, exp)
+	}
+}
+
+func Test_exportWALFile_Filter(t *testing.T) {
+	walFile := writeCorpusToWALFile(basicCorpus)
+	defer os.Remove(walFile.Name())
+
+	cmd := newCommand()
+	cmd.measurement = regexp.MustCompile("^floats$")
+
+	var out bytes.Buffer
+	if err := cmd.exportWALFile(walFile.Name(), &out, func() {}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	sort.Strings(lines)
+	if exp := []string{"floats,k=f f=1.5 1", "floats,k=f f=3 2"}; !reflect.DeepEqual(lines, exp) {
+		t.Fatalf("unexpected lines: %q", lines)
+	}
+}
+
+func Test_joinCompressArg(t *testing.T) {
+	for _, c := range []struct {
+		args []string
+		exp  string
+	}{
+		{args: []string{"-out", "f"}, exp: compressNone},
+		{args: []string{"-compress", "-out", "f"}, exp: compressGzip},
+		{args: []string{"-compress", "zstd", "-out", "f"}, exp: compressZstd},
+		{args: []string{"--compress", "gzip"}, exp: compressGzip},
+		{args: []string{"-compress=zstd"}, exp: compressZstd},
+	} {
+		var comp compression
+		fs := flag.NewFlagSet("export", flag.ContinueOnError)
+		fs.Var(&comp, "compress", "")
+		fs.String("out", "", "")
+		if err := fs.Parse(joinCompressArg(c.args)); err != nil {
+			t.Fatalf("%q: %s", c.args, err)
+		} else if string(comp) != c.exp {
+			t.Fatalf("%q: got %q, exp %q", c.args, comp, c.exp)
+		} else if fs.NArg() != 0 {
+			t.Fatalf("%q: unexpected positional args %q", c.args, fs.Args())
+		}
+	}
+}
+
 var sink interface{}
 
 func benchmarkExportTSM(c corpus, b *testing.B) {
@@ -31,13 +31,24 @@ Usage: influx_inspect [[command] [arguments]]
 
 The commands are:
 
+    buildindex           rebuilds a shard index from its TSM and WAL files
+    compact              runs a full compaction of a shard offline
+    deletetsm            deletes measurements from the TSM files of a shard offline
     dumptsi              dumps low-level details about tsi1 files.
     dumptsm              dumps low-level details about tsm1 files.
+    dumpwal              dumps the writes and deletes in WAL segments
     export               exports raw data from a shard to line protocol
+    import-tsm           writes line protocol directly into TSM shards
     inmem2tsi            generates a tsi1 index from an in-memory index shard
     help                 display this help message
+    merge-shards         merges shards of a retention policy into one shard
+    replaywal            converts the writes in WAL segments to line protocol
     report               displays a shard level report
-    verify               verifies integrity of TSM files
+    report-cardinality   reports series cardinality by measurement and tag key
+    reshard              rewrites shards to match a changed shard group duration
+    scrub                hashes or redacts tag and field values in line protocol
+    split-shard          splits a shard into two shards at a time boundary
+    verify               verifies integrity of TSM and WAL files
 
 "help" is the default command.
 
@@ -0,0 +1,471 @@
+// Package importtsm writes line protocol directly into TSM shard files.
+package importtsm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+// DefaultCacheSize is the default amount of memory used to buffer points
+// before they are written to TSM files.
+const DefaultCacheSize = 1024 * 1024 * 1024
+
+// Comments written by "influx_inspect export".
+const (
+	ddlSection      = "# DDL"
+	dmlSection      = "# DML"
+	contextDatabase = "# CONTEXT-DATABASE:"
+	contextRP       = "# CONTEXT-RETENTION-POLICY:"
+)
+
+var timeBytes = []byte("time")
+
+// Command represents the program execution for "influx_inspect import-tsm".
+type Command struct {
+	// Standard input/output, overridden for testing.
+	Stdin  io.Reader
+	Stderr io.Writer
+	Stdout io.Writer
+
+	metaDir         string
+	dataDir         string
+	path            string
+	database        string
+	retentionPolicy string
+	precision       string
+	compressed      bool
+	cacheSize       uint64
+
+	client *meta.Client
+
+	// groups holds the shard group last written to for each database and
+	// retention policy pair.
+	groups map[string]*meta.ShardGroupInfo
+	shards map[uint64]*shardWriter
+
+	pointsWritten int64
+	linesSkipped  int64
+	valuesDropped int64
+}
+
+// NewCommand returns a new instance of Command.
+func NewCommand() *Command {
+	return &Command{
+		Stdin:  os.Stdin,
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+
+		groups: make(map[string]*meta.ShardGroupInfo),
+		shards: make(map[uint64]*shardWriter),
+	}
+}
+
+// Run executes the command.
+func (cmd *Command) Run(args ...string) error {
+	fs := flag.NewFlagSet("import-tsm", flag.ExitOnError)
+	fs.StringVar(&cmd.metaDir, "metadir", os.Getenv("HOME")+"/.influxdb/meta", "Meta storage path")
+	fs.StringVar(&cmd.dataDir, "datadir", os.Getenv("HOME")+"/.influxdb/data", "Data storage path")
+	fs.StringVar(&cmd.path, "path", "", "Line protocol file to import, or - to read from stdin")
+	fs.StringVar(&cmd.database, "database", "", "Database to import into, unless set by the file")
+	fs.StringVar(&cmd.retentionPolicy, "retention", "", "Optional: the retention policy to import into (default: the database's default)")
+	fs.StringVar(&cmd.precision, "precision", "ns", "Precision of the timestamps in the file: h, m, s, ms, u or ns")
+	fs.BoolVar(&cmd.compressed, "compressed", false, "Set if the file is gzip compressed")
+	fs.Uint64Var(&cmd.cacheSize, "cache-size", DefaultCacheSize, "Memory in bytes used to buffer points before writing them to disk")
+
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = func() {
+		fmt.Fprintf(cmd.Stdout, "Writes line protocol directly into TSM shards. The server must not be running.\n\n")
+		fmt.Fprintf(cmd.Stdout, "Usage: %s import-tsm [flags]\n\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if cmd.path == "" {
+		return errors.New("-path is required")
+	}
+	switch cmd.precision {
+	case "h", "m", "s", "ms", "u", "ns":
+	default:
+		return fmt.Errorf("invalid precision %q", cmd.precision)
+	}
+
+	return cmd.run()
+}
+
+func (cmd *Command) run() error {
+	var r io.Reader = cmd.Stdin
+	if cmd.path != "-" {
+		f, err := os.Open(cmd.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if cmd.compressed {
+		gzr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gzr.Close()
+		r = gzr
+	}
+
+	if err := os.MkdirAll(cmd.metaDir, 0777); err != nil {
+		return err
+	}
+
+	c := meta.NewConfig()
+	c.Dir = cmd.metaDir
+	cmd.client = meta.NewClient(c)
+	if err := cmd.client.Open(); err != nil {
+		return err
+	}
+	defer cmd.client.Close()
+
+	start := time.Now()
+	err := cmd.process(r)
+
+	// Close every shard even if the import failed so that the points
+	// imported so far are not lost.
+	fmt.Fprintf(cmd.Stdout, "compacting %d shards...\n", len(cmd.shards))
+	for _, id := range cmd.shardIDs() {
+		if cerr := cmd.shards[id].close(); cerr != nil && err == nil {
+			err = fmt.Errorf("shard %d: %s", id, cerr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.Stdout, "imported %d points into %d shards in %s (%d lines skipped, %d values dropped)\n",
+		cmd.pointsWritten, len(cmd.shards), time.Since(start), cmd.linesSkipped, cmd.valuesDropped)
+	return nil
+}
+
+// process imports every line read from r. Comments are ignored, except the
+// context lines and DDL section written by "influx_inspect export".
+func (cmd *Command) process(r io.Reader) error {
+	db, rp := cmd.database, cmd.retentionPolicy
+	var ddl bool
+
+	br := bufio.NewReaderSize(r, 1024*1024)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		} else if err != nil && err != io.EOF {
+			return err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if line[0] == '#' {
+			s := string(line)
+			switch {
+			case s == ddlSection:
+				ddl = true
+			case s == dmlSection:
+				ddl = false
+			case strings.HasPrefix(s, contextDatabase):
+				db = strings.TrimPrefix(s, contextDatabase)
+			case strings.HasPrefix(s, contextRP):
+				rp = strings.TrimPrefix(s, contextRP)
+			}
+			continue
+		} else if ddl {
+			continue
+		}
+
+		if db == "" {
+			return fmt.Errorf("line %d: no database specified", n)
+		}
+
+		points, perr := models.ParsePointsWithPrecision(line, time.Now().UTC(), cmd.precision)
+		if perr != nil {
+			fmt.Fprintf(cmd.Stderr, "line %d: %s\n", n, perr)
+			cmd.linesSkipped++
+			continue
+		}
+
+		for _, p := range points {
+			sw, err := cmd.shardWriter(db, rp, p.Time())
+			if err != nil {
+				return fmt.Errorf("line %d: %s", n, err)
+			}
+
+			dropped, err := sw.writePoint(p)
+			if err != nil {
+				return fmt.Errorf("line %d: %s", n, err)
+			}
+			cmd.valuesDropped += int64(dropped)
+			cmd.pointsWritten++
+		}
+
+		if n%1000 == 0 && cmd.cachedSize() >= cmd.cacheSize {
+			if err := cmd.flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// shardWriter returns the writer for the shard that owns timestamp t,
+// creating the database and shard group if they do not exist.
+func (cmd *Command) shardWriter(db, rp string, t time.Time) (*shardWriter, error) {
+	key := db + "\x00" + rp
+	if sg := cmd.groups[key]; sg != nil && sg.Contains(t) {
+		return cmd.shards[sg.Shards[0].ID], nil
+	}
+
+	dbi := cmd.client.Database(db)
+	if dbi == nil {
+		var err error
+		if dbi, err = cmd.client.CreateDatabase(db); err != nil {
+			return nil, err
+		}
+	}
+	if rp == "" {
+		if dbi.DefaultRetentionPolicy == "" {
+			return nil, fmt.Errorf("database %q has no default retention policy", db)
+		}
+		rp = dbi.DefaultRetentionPolicy
+	}
+
+	sg, err := cmd.client.CreateShardGroup(db, rp, t)
+	if err != nil {
+		return nil, err
+	}
+	cmd.groups[key] = sg
+
+	id := sg.Shards[0].ID
+	if sw := cmd.shards[id]; sw != nil {
+		return sw, nil
+	}
+
+	sw := newShardWriter(filepath.Join(cmd.dataDir, db, rp, strconv.FormatUint(id, 10)))
+	if err := sw.open(); err != nil {
+		return nil, fmt.Errorf("shard %d: %s", id, err)
+	}
+	cmd.shards[id] = sw
+	return sw, nil
+}
+
+// cachedSize returns the size of the points buffered across all shards.
+func (cmd *Command) cachedSize() uint64 {
+	var n uint64
+	for _, sw := range cmd.shards {
+		n += sw.cache.Size()
+	}
+	return n
+}
+
+// flush writes the points buffered for every shard to TSM files.
+func (cmd *Command) flush() error {
+	for _, id := range cmd.shardIDs() {
+		if err := cmd.shards[id].flush(); err != nil {
+			return fmt.Errorf("shard %d: %s", id, err)
+		}
+	}
+	return nil
+}
+
+func (cmd *Command) shardIDs() []uint64 {
+	ids := make([]uint64, 0, len(cmd.shards))
+	for id := range cmd.shards {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// shardWriter buffers the points of a single shard and writes them to TSM
+// files, which are fully compacted when the writer is closed.
+type shardWriter struct {
+	path      string
+	cache     *tsm1.Cache
+	fileStore *tsm1.FileStore
+	compactor *tsm1.Compactor
+
+	// fields holds the block type of each measurement's fields so that values
+	// conflicting with the first type seen can be dropped.
+	fields map[string]byte
+}
+
+func newShardWriter(path string) *shardWriter {
+	return &shardWriter{
+		path:   path,
+		cache:  tsm1.NewCache(0, path),
+		fields: make(map[string]byte),
+	}
+}
+
+// open loads any existing TSM files in the shard.
+func (w *shardWriter) open() error {
+	if err := os.MkdirAll(w.path, 0777); err != nil {
+		return err
+	}
+
+	w.fileStore = tsm1.NewFileStore(w.path)
+	if err := w.fileStore.Open(); err != nil {
+		return err
+	}
+	w.compactor = &tsm1.Compactor{Dir: w.path, FileStore: w.fileStore}
+	w.compactor.Open()
+
+	// Seed the field types from existing data so appended files do not
+	// conflict with it.
+	for _, f := range w.fileStore.Files() {
+		for i := 0; i < f.KeyCount(); i++ {
+			key, typ := f.KeyAt(i)
+			seriesKey, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+			name, err := models.ParseName(seriesKey)
+			if err != nil {
+				return err
+			}
+			w.fields[fieldKey(name, field)] = typ
+		}
+	}
+	return nil
+}
+
+// writePoint adds the fields of p to the cache. It returns the number of
+// fields dropped because their type conflicts with earlier values.
+func (w *shardWriter) writePoint(p models.Point) (int, error) {
+	var dropped int
+	values := make(map[string][]tsm1.Value)
+	seriesKey := p.Key()
+	name := p.Name()
+	t := p.Time().UnixNano()
+
+	iter := p.FieldIterator()
+	for iter.Next() {
+		// Skip fields name "time", they are illegal
+		if bytes.Equal(iter.FieldKey(), timeBytes) {
+			continue
+		}
+
+		var v tsm1.Value
+		var typ byte
+		switch iter.Type() {
+		case models.Float:
+			fv, err := iter.FloatValue()
+			if err != nil {
+				return dropped, err
+			}
+			v, typ = tsm1.NewFloatValue(t, fv), tsm1.BlockFloat64
+		case models.Integer:
+			iv, err := iter.IntegerValue()
+			if err != nil {
+				return dropped, err
+			}
+			v, typ = tsm1.NewIntegerValue(t, iv), tsm1.BlockInteger
+		case models.Unsigned:
+			iv, err := iter.UnsignedValue()
+			if err != nil {
+				return dropped, err
+			}
+			v, typ = tsm1.NewUnsignedValue(t, iv), tsm1.BlockUnsigned
+		case models.String:
+			v, typ = tsm1.NewStringValue(t, iter.StringValue()), tsm1.BlockString
+		case models.Boolean:
+			bv, err := iter.BooleanValue()
+			if err != nil {
+				return dropped, err
+			}
+			v, typ = tsm1.NewBooleanValue(t, bv), tsm1.BlockBoolean
+		default:
+			return dropped, fmt.Errorf("unknown field type for %s: %s", string(iter.FieldKey()), p.String())
+		}
+
+		fk := fieldKey(name, iter.FieldKey())
+		if prev, ok := w.fields[fk]; !ok {
+			w.fields[fk] = typ
+		} else if prev != typ {
+			dropped++
+			continue
+		}
+
+		key := string(tsm1.SeriesFieldKeyBytes(string(seriesKey), string(iter.FieldKey())))
+		values[key] = append(values[key], v)
+	}
+
+	return dropped, w.cache.WriteMulti(values)
+}
+
+// flush writes the cached points to new TSM files.
+func (w *shardWriter) flush() error {
+	if w.cache.Size() == 0 {
+		return nil
+	}
+
+	snapshot, err := w.cache.Snapshot()
+	if err != nil {
+		return err
+	}
+	snapshot.Deduplicate()
+
+	files, err := w.compactor.WriteSnapshot(snapshot)
+	if err != nil {
+		w.cache.ClearSnapshot(false)
+		return err
+	}
+
+	if err := w.fileStore.Replace(nil, files); err != nil {
+		w.cache.ClearSnapshot(false)
+		return err
+	}
+	w.cache.ClearSnapshot(true)
+	return nil
+}
+
+// close flushes the cache and compacts the shard's TSM files.
+func (w *shardWriter) close() error {
+	defer w.fileStore.Close()
+	defer w.compactor.Close()
+
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	var files []string
+	for _, f := range w.fileStore.Files() {
+		files = append(files, f.Path())
+	}
+	if len(files) <= 1 {
+		return nil
+	}
+
+	compacted, err := w.compactor.CompactFull(files)
+	if err != nil {
+		return err
+	}
+	return w.fileStore.Replace(files, compacted)
+}
+
+// fieldKey returns the key used to track the type of a measurement's field.
+func fieldKey(name, field []byte) string {
+	return string(name) + "\x00" + string(field)
+}
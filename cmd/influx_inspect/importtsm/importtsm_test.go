@@ -0,0 +1,98 @@
+package importtsm
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+func TestCommand_Import(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import-tsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Write points spanning two shard groups, forcing several flushes.
+	var buf bytes.Buffer
+	buf.WriteString("# DDL\nCREATE DATABASE ignored\n# DML\n# CONTEXT-DATABASE:db0\n")
+	base := time.Date(2017, 1, 2, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 2500; i++ {
+		ts := base.Add(time.Duration(i) * 5 * time.Minute)
+		fmt.Fprintf(&buf, "cpu,host=a value=%d %d\n", i, ts.Unix())
+	}
+	buf.WriteString("cpu,host=a value=\"conflict\" 1483315200\n")
+	buf.WriteString("not line protocol\n")
+
+	cmd := NewCommand()
+	cmd.Stdin = &buf
+	cmd.Stdout, cmd.Stderr = ioutil.Discard, ioutil.Discard
+	cmd.metaDir = filepath.Join(dir, "meta")
+	cmd.dataDir = filepath.Join(dir, "data")
+	cmd.path = "-"
+	cmd.precision = "s"
+	cmd.cacheSize = 1
+
+	if err := cmd.run(); err != nil {
+		t.Fatal(err)
+	}
+	if cmd.pointsWritten != 2501 {
+		t.Fatalf("unexpected points written: %d", cmd.pointsWritten)
+	} else if cmd.valuesDropped != 1 {
+		t.Fatalf("unexpected values dropped: %d", cmd.valuesDropped)
+	} else if cmd.linesSkipped != 1 {
+		t.Fatalf("unexpected lines skipped: %d", cmd.linesSkipped)
+	}
+
+	c := meta.NewConfig()
+	c.Dir = cmd.metaDir
+	client := meta.NewClient(c)
+	if err := client.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	groups, err := client.ShardGroupsByTimeRange("db0", "autogen", base, base.Add(30*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(groups) != 2 {
+		t.Fatalf("unexpected shard group count: %d", len(groups))
+	}
+
+	var n int
+	for _, sg := range groups {
+		path := filepath.Join(cmd.dataDir, "db0", "autogen", strconv.FormatUint(sg.Shards[0].ID, 10))
+		files, err := filepath.Glob(filepath.Join(path, "*."+tsm1.TSMFileExtension))
+		if err != nil {
+			t.Fatal(err)
+		} else if len(files) != 1 {
+			t.Fatalf("expected shard %d to be fully compacted, got %d files", sg.Shards[0].ID, len(files))
+		}
+
+		f, err := os.Open(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := tsm1.NewTSMReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values, err := r.ReadAll([]byte(tsm1.SeriesFieldKey("cpu,host=a", "value")))
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n += len(values)
+	}
+	if n != 2500 {
+		t.Fatalf("unexpected value count: %d", n)
+	}
+}
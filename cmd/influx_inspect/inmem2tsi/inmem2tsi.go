@@ -40,11 +40,14 @@ func (cmd *Command) Run(args ...string) error {
 	fs := flag.NewFlagSet("inmem2tsi", flag.ExitOnError)
 	dataDir := fs.String("datadir", "", "shard data directory")
 	walDir := fs.String("waldir", "", "shard WAL directory")
+	database := fs.String("database", "", "convert every shard of a database, with -datadir and -waldir the data and WAL directories of influxd")
 	fs.BoolVar(&cmd.Verbose, "v", false, "verbose")
 	fs.SetOutput(cmd.Stdout)
+	fs.Usage = cmd.printUsage
 	if err := fs.Parse(args); err != nil {
 		return err
 	} else if fs.NArg() > 0 || *dataDir == "" || *walDir == "" {
+		fs.Usage()
 		return flag.ErrHelp
 	}
 
@@ -53,9 +56,48 @@ func (cmd *Command) Run(args ...string) error {
 		zap.Output(os.Stderr),
 	)
 
+	if *database != "" {
+		return cmd.runDatabase(*dataDir, *walDir, *database)
+	}
 	return cmd.run(*dataDir, *walDir)
 }
 
+// runDatabase converts the shards of a database that do not have a TSI index yet.
+func (cmd *Command) runDatabase(dataDir, walDir, database string) error {
+	rps, err := ioutil.ReadDir(filepath.Join(dataDir, database))
+	if err != nil {
+		return err
+	}
+
+	for _, rp := range rps {
+		if !rp.IsDir() {
+			continue
+		}
+
+		shards, err := ioutil.ReadDir(filepath.Join(dataDir, database, rp.Name()))
+		if err != nil {
+			return err
+		}
+
+		for _, sh := range shards {
+			if !sh.IsDir() {
+				continue
+			}
+
+			shardDir := filepath.Join(dataDir, database, rp.Name(), sh.Name())
+			if _, err := os.Stat(filepath.Join(shardDir, "index")); err == nil {
+				cmd.Logger.Info("shard already has a tsi1 index, skipping", zap.String("path", shardDir))
+				continue
+			}
+
+			if err := cmd.run(shardDir, filepath.Join(walDir, database, rp.Name(), sh.Name())); err != nil {
+				return fmt.Errorf("%s: %s", shardDir, err)
+			}
+		}
+	}
+	return nil
+}
+
 func (cmd *Command) run(dataDir, walDir string) error {
 	// Check if shard already has a TSI index.
 	indexPath := filepath.Join(dataDir, "index")
@@ -196,7 +238,10 @@ func (cmd *Command) collectTSMFiles(path string) ([]string, error) {
 
 func (cmd *Command) collectWALFiles(path string) ([]string, error) {
 	fis, err := ioutil.ReadDir(path)
-	if err != nil {
+	if os.IsNotExist(err) {
+		// A shard without a WAL directory has no unflushed writes.
+		return nil, nil
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -214,6 +259,12 @@ func (cmd *Command) printUsage() {
 	usage := `Converts a shard from an in-memory index to a TSI index.
 
 Usage: influx_inspect inmem2tsi [-v] -datadir DATADIR -waldir WALDIR
+       influx_inspect inmem2tsi [-v] -datadir DATADIR -waldir WALDIR -database DATABASE
+
+With -database, DATADIR and WALDIR are the data and WAL directories of influxd,
+and every shard of the database without a TSI index is converted.  influxd must
+not be running.  New shards of the database use TSI once it is listed in the
+tsi1-databases setting of the [data] section.
 `
 
 	fmt.Fprintf(cmd.Stdout, usage)
@@ -8,12 +8,20 @@ import (
 	"os"
 
 	"github.com/influxdata/influxdb/cmd"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/buildindex"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/compact"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/deletetsm"
 	"github.com/influxdata/influxdb/cmd/influx_inspect/dumptsi"
 	"github.com/influxdata/influxdb/cmd/influx_inspect/dumptsm"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/dumpwal"
 	"github.com/influxdata/influxdb/cmd/influx_inspect/export"
 	"github.com/influxdata/influxdb/cmd/influx_inspect/help"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/importtsm"
 	"github.com/influxdata/influxdb/cmd/influx_inspect/inmem2tsi"
 	"github.com/influxdata/influxdb/cmd/influx_inspect/report"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/reportcardinality"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/reshard"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/scrub"
 	"github.com/influxdata/influxdb/cmd/influx_inspect/verify"
 	_ "github.com/influxdata/influxdb/tsdb/engine"
 )
@@ -55,6 +63,21 @@ func (m *Main) Run(args ...string) error {
 		if err := help.NewCommand().Run(args...); err != nil {
 			return fmt.Errorf("help: %s", err)
 		}
+	case "buildindex":
+		name := buildindex.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("buildindex: %s", err)
+		}
+	case "compact":
+		name := compact.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("compact: %s", err)
+		}
+	case "deletetsm":
+		name := deletetsm.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("deletetsm: %s", err)
+		}
 	case "dumptsi":
 		name := dumptsi.NewCommand()
 		if err := name.Run(args...); err != nil {
@@ -68,21 +91,61 @@ func (m *Main) Run(args ...string) error {
 		if err := name.Run(args...); err != nil {
 			return fmt.Errorf("dumptsm: %s", err)
 		}
+	case "dumpwal":
+		name := dumpwal.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("dumpwal: %s", err)
+		}
 	case "export":
 		name := export.NewCommand()
 		if err := name.Run(args...); err != nil {
 			return fmt.Errorf("export: %s", err)
 		}
+	case "import-tsm":
+		name := importtsm.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("import-tsm: %s", err)
+		}
 	case "inmem2tsi":
 		name := inmem2tsi.NewCommand()
 		if err := name.Run(args...); err != nil {
 			return fmt.Errorf("inmem2tsi: %s", err)
 		}
+	case "merge-shards":
+		name := reshard.NewMergeCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("merge-shards: %s", err)
+		}
+	case "replaywal":
+		name := dumpwal.NewReplayCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("replaywal: %s", err)
+		}
 	case "report":
 		name := report.NewCommand()
 		if err := name.Run(args...); err != nil {
 			return fmt.Errorf("report: %s", err)
 		}
+	case "report-cardinality":
+		name := reportcardinality.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("report-cardinality: %s", err)
+		}
+	case "reshard":
+		name := reshard.NewReshardCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("reshard: %s", err)
+		}
+	case "scrub":
+		name := scrub.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("scrub: %s", err)
+		}
+	case "split-shard":
+		name := reshard.NewSplitCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("split-shard: %s", err)
+		}
 	case "verify":
 		name := verify.NewCommand()
 		if err := name.Run(args...); err != nil {
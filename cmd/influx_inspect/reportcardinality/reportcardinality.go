@@ -0,0 +1,332 @@
+// Package reportcardinality reports series cardinality by database,
+// measurement and tag key.
+package reportcardinality
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/report"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+	"github.com/retailnext/hllpp"
+)
+
+// DefaultTop is the default number of tag values reported for each tag key.
+const DefaultTop = 10
+
+// Command represents the program execution for "influx_inspect report-cardinality".
+type Command struct {
+	Stderr io.Writer
+	Stdout io.Writer
+
+	dir      string
+	top      int
+	estimate bool
+
+	databases map[string]*measurementSet
+}
+
+// NewCommand returns a new instance of Command.
+func NewCommand() *Command {
+	return &Command{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+	}
+}
+
+// Run executes the command.
+func (cmd *Command) Run(args ...string) error {
+	fs := flag.NewFlagSet("report-cardinality", flag.ExitOnError)
+	fs.StringVar(&cmd.dir, "dir", os.Getenv("HOME")+"/.influxdb/data", "Data storage path")
+	fs.IntVar(&cmd.top, "top", DefaultTop, "Number of tag values to report for each tag key")
+	fs.BoolVar(&cmd.estimate, "estimate", false, "Report only estimated counts")
+
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := cmd.run(); err != nil {
+		return err
+	}
+	return cmd.print()
+}
+
+func (cmd *Command) run() error {
+	cmd.databases = make(map[string]*measurementSet)
+	return report.NewCommand().WalkShardDirs(cmd.dir, func(db, rp, id, path string) error {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(cmd.Stderr, "error: %s: %v. Skipping.\n", path, err)
+			return nil
+		}
+
+		r, err := tsm1.NewTSMReader(f)
+		if err != nil {
+			fmt.Fprintf(cmd.Stderr, "error: %s: %v. Skipping.\n", path, err)
+			return nil
+		}
+		defer r.Close()
+
+		ms := cmd.databases[db]
+		if ms == nil {
+			ms = newMeasurementSet(!cmd.estimate)
+			cmd.databases[db] = ms
+		}
+
+		var prev []byte
+		for i := 0; i < r.KeyCount(); i++ {
+			key, _ := r.KeyAt(i)
+			seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+
+			// Keys are sorted, so the fields of a series are adjacent.
+			if string(seriesKey) == string(prev) {
+				continue
+			}
+			prev = seriesKey
+
+			ms.add(seriesKey, cmd.top > 0)
+		}
+		return nil
+	})
+}
+
+// print writes the report to STDOUT.
+func (cmd *Command) print() error {
+	tw := tabwriter.NewWriter(cmd.Stdout, 8, 2, 1, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"DB", "Measurement", "Tag Key", "Series", "Series (est)", "Tag Values"}, "\t"))
+
+	type topValue struct {
+		db, measurement, key, value string
+		c                           *cardinality
+	}
+	var top []topValue
+
+	for _, db := range sortedKeys(cmd.databases) {
+		ms := cmd.databases[db]
+		fmt.Fprintln(tw, strings.Join([]string{db, "", "", ms.series.exactString(), ms.series.estString(), ""}, "\t"))
+
+		for _, name := range ms.sorted() {
+			m := ms.measurements[name]
+			fmt.Fprintln(tw, strings.Join([]string{db, name, "", m.series.exactString(), m.series.estString(), ""}, "\t"))
+
+			for _, key := range m.sorted() {
+				t := m.tags[key]
+				fmt.Fprintln(tw, strings.Join([]string{db, name, key, t.series.exactString(), t.series.estString(), t.values.countString()}, "\t"))
+
+				for _, v := range t.top(cmd.top) {
+					top = append(top, topValue{db: db, measurement: name, key: key, value: v, c: t.valueSeries[v]})
+				}
+			}
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(top) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(cmd.Stdout, "\nTop %d tag values by series:\n", cmd.top)
+	fmt.Fprintln(tw, strings.Join([]string{"DB", "Measurement", "Tag Key", "Tag Value", "Series", "Series (est)"}, "\t"))
+	for _, v := range top {
+		fmt.Fprintln(tw, strings.Join([]string{v.db, v.measurement, v.key, v.value, v.c.exactString(), v.c.estString()}, "\t"))
+	}
+	return tw.Flush()
+}
+
+// cardinality counts distinct keys, both exactly and using a HyperLogLog
+// estimate. The exact count is only kept if enabled.
+type cardinality struct {
+	exact map[string]struct{}
+	est   *hllpp.HLLPP
+}
+
+func newCardinality(exact bool) *cardinality {
+	c := &cardinality{est: hllpp.New()}
+	if exact {
+		c.exact = make(map[string]struct{})
+	}
+	return c
+}
+
+func (c *cardinality) add(key string) {
+	if c.exact != nil {
+		c.exact[key] = struct{}{}
+	}
+	c.est.Add([]byte(key))
+}
+
+// count returns the exact count if kept, and the estimate otherwise.
+func (c *cardinality) count() uint64 {
+	if c.exact != nil {
+		return uint64(len(c.exact))
+	}
+	return c.est.Count()
+}
+
+func (c *cardinality) exactString() string {
+	if c.exact == nil {
+		return "-"
+	}
+	return strconv.Itoa(len(c.exact))
+}
+
+func (c *cardinality) estString() string {
+	return strconv.FormatUint(c.est.Count(), 10)
+}
+
+func (c *cardinality) countString() string {
+	return strconv.FormatUint(c.count(), 10)
+}
+
+// measurementSet holds the cardinality of a database's measurements.
+type measurementSet struct {
+	exact        bool
+	series       *cardinality
+	measurements map[string]*measurement
+}
+
+func newMeasurementSet(exact bool) *measurementSet {
+	return &measurementSet{
+		exact:        exact,
+		series:       newCardinality(exact),
+		measurements: make(map[string]*measurement),
+	}
+}
+
+// add adds a series, and its tag values if withValues is set.
+func (ms *measurementSet) add(seriesKey []byte, withValues bool) {
+	// Share one copy of the key between all counts.
+	key := string(seriesKey)
+	ms.series.add(key)
+
+	name, tags := models.ParseKey(seriesKey)
+	m := ms.measurements[name]
+	if m == nil {
+		m = &measurement{series: newCardinality(ms.exact), tags: make(map[string]*tagKey)}
+		ms.measurements[name] = m
+	}
+	m.series.add(key)
+
+	for _, tag := range tags {
+		t := m.tags[string(tag.Key)]
+		if t == nil {
+			t = &tagKey{series: newCardinality(ms.exact), values: newCardinality(ms.exact)}
+			if withValues {
+				t.valueSeries = make(map[string]*cardinality)
+			}
+			m.tags[string(tag.Key)] = t
+		}
+		t.series.add(key)
+		t.values.add(string(tag.Value))
+
+		if t.valueSeries != nil {
+			c := t.valueSeries[string(tag.Value)]
+			if c == nil {
+				c = newCardinality(ms.exact)
+				t.valueSeries[string(tag.Value)] = c
+			}
+			c.add(key)
+		}
+	}
+}
+
+// sorted returns the measurement names, highest cardinality first.
+func (ms *measurementSet) sorted() []string {
+	names := make([]string, 0, len(ms.measurements))
+	for name := range ms.measurements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.SliceStable(names, func(i, j int) bool {
+		return ms.measurements[names[i]].series.count() > ms.measurements[names[j]].series.count()
+	})
+	return names
+}
+
+// measurement holds the cardinality of a measurement and its tag keys.
+type measurement struct {
+	series *cardinality
+	tags   map[string]*tagKey
+}
+
+// sorted returns the tag keys, highest cardinality first.
+func (m *measurement) sorted() []string {
+	keys := make([]string, 0, len(m.tags))
+	for k := range m.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return m.tags[keys[i]].values.count() > m.tags[keys[j]].values.count()
+	})
+	return keys
+}
+
+// tagKey holds the series with a tag key and the distinct values of the key.
+type tagKey struct {
+	series      *cardinality
+	values      *cardinality
+	valueSeries map[string]*cardinality
+}
+
+// top returns up to n tag values with the most series.
+func (t *tagKey) top(n int) []string {
+	values := make([]string, 0, len(t.valueSeries))
+	for v := range t.valueSeries {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	sort.SliceStable(values, func(i, j int) bool {
+		return t.valueSeries[values[i]].count() > t.valueSeries[values[j]].count()
+	})
+	if len(values) > n {
+		values = values[:n]
+	}
+	return values
+}
+
+// sortedKeys returns the sorted keys of m.
+func sortedKeys(m map[string]*measurementSet) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *Command) printUsage() {
+	usage := fmt.Sprintf(`Reports series cardinality by database, measurement and tag key.
+
+Counts are of the series in TSM files. Series only written to the WAL are not
+counted. Measurements and tag keys are listed highest cardinality first.
+
+Usage: influx_inspect report-cardinality [flags]
+
+    -dir <path>
+            Data storage path.
+            Defaults to "%[1]s/.influxdb/data".
+    -top <n>
+            Number of tag values with the most series to report for each
+            tag key. 0 disables the tag value breakdown.
+            Defaults to %[2]d.
+    -estimate
+            Report only estimated counts. Exact counts can use a lot of memory.
+            Defaults to "false".
+`, os.Getenv("HOME"), DefaultTop)
+
+	fmt.Fprintf(cmd.Stdout, usage)
+}
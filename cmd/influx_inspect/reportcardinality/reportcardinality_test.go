@@ -0,0 +1,117 @@
+package reportcardinality
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report-cardinality")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The same series in two shards is counted once.
+	MustWriteTSM(filepath.Join(dir, "db0", "rp0", "1", "000000001-000000001.tsm"),
+		"cpu,host=a,region=west#!~#idle",
+		"cpu,host=a,region=west#!~#value",
+		"cpu,host=b,region=west#!~#value",
+		"mem,host=a#!~#free",
+	)
+	MustWriteTSM(filepath.Join(dir, "db0", "rp0", "2", "000000001-000000001.tsm"),
+		"cpu,host=a,region=west#!~#value",
+		"cpu,host=c,region=east#!~#value",
+	)
+
+	var out bytes.Buffer
+	cmd := NewCommand()
+	cmd.Stdout, cmd.Stderr = &out, ioutil.Discard
+	if err := cmd.Run("-dir", dir, "-top", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	ms := cmd.databases["db0"]
+	if n := ms.series.count(); n != 4 {
+		t.Fatalf("unexpected series count: %d", n)
+	} else if n := ms.measurements["cpu"].series.count(); n != 3 {
+		t.Fatalf("unexpected cpu series count: %d", n)
+	} else if n := ms.measurements["cpu"].tags["host"].values.count(); n != 3 {
+		t.Fatalf("unexpected host values: %d", n)
+	} else if n := ms.measurements["cpu"].tags["region"].values.count(); n != 2 {
+		t.Fatalf("unexpected region values: %d", n)
+	}
+
+	if got := ms.measurements["cpu"].tags["region"].top(1); len(got) != 1 || got[0] != "west" {
+		t.Fatalf("unexpected top region values: %v", got)
+	} else if got := ms.sorted(); strings.Join(got, ",") != "cpu,mem" {
+		t.Fatalf("unexpected measurement order: %v", got)
+	} else if got := ms.measurements["cpu"].sorted(); strings.Join(got, ",") != "host,region" {
+		t.Fatalf("unexpected tag key order: %v", got)
+	}
+
+	if !strings.Contains(out.String(), "Top 1 tag values by series:") {
+		t.Fatalf("expected tag value breakdown:\n%s", out.String())
+	}
+}
+
+func TestCommand_Run_Estimate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report-cardinality")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	MustWriteTSM(filepath.Join(dir, "db0", "rp0", "1", "000000001-000000001.tsm"),
+		"cpu,host=a#!~#value",
+		"cpu,host=b#!~#value",
+	)
+
+	var out bytes.Buffer
+	cmd := NewCommand()
+	cmd.Stdout, cmd.Stderr = &out, ioutil.Discard
+	if err := cmd.Run("-dir", dir, "-estimate", "-top", "0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := cmd.databases["db0"].series; c.exact != nil {
+		t.Fatal("expected no exact counts")
+	} else if n := c.count(); n != 2 {
+		t.Fatalf("unexpected estimated series count: %d", n)
+	}
+	if strings.Contains(out.String(), "Top") {
+		t.Fatalf("unexpected tag value breakdown:\n%s", out.String())
+	}
+}
+
+// MustWriteTSM writes a TSM file containing keys, which must be sorted, to path.
+func MustWriteTSM(path string, keys ...string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		panic(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		panic(err)
+	}
+	for _, key := range keys {
+		if err := w.Write([]byte(key), []tsm1.Value{tsm1.NewValue(0, 1.0)}); err != nil {
+			panic(err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		panic(err)
+	} else if err := w.Close(); err != nil {
+		panic(err)
+	}
+}
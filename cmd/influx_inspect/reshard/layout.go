@@ -0,0 +1,172 @@
+package reshard
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// ReshardCommand represents the program execution for "influx_inspect reshard".
+type ReshardCommand struct {
+	Stderr io.Writer
+	Stdout io.Writer
+
+	options
+	dryRun bool
+}
+
+// NewReshardCommand returns a new instance of ReshardCommand.
+func NewReshardCommand() *ReshardCommand {
+	return &ReshardCommand{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+	}
+}
+
+// Run executes the command.
+func (cmd *ReshardCommand) Run(args ...string) error {
+	fs := flag.NewFlagSet("reshard", flag.ExitOnError)
+	cmd.register(fs)
+	fs.BoolVar(&cmd.dryRun, "dry-run", false, "Print the shard groups that would be replaced without changing them")
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if err := cmd.validate(); err != nil {
+		return err
+	}
+
+	return cmd.run()
+}
+
+func (cmd *ReshardCommand) run() error {
+	client, err := cmd.openMeta()
+	if err != nil {
+		return err
+	}
+	data := client.Data()
+	client.Close()
+
+	rpi, err := data.RetentionPolicy(cmd.database, cmd.retention)
+	if err != nil {
+		return err
+	} else if rpi == nil {
+		return influxdb.ErrRetentionPolicyNotFound(cmd.retention)
+	}
+
+	groups, err := data.ShardGroups(cmd.database, cmd.retention)
+	if err != nil {
+		return err
+	}
+
+	old, layout := planLayout(groups, rpi.ShardGroupDuration)
+	if len(old) == 0 {
+		fmt.Fprintf(cmd.Stdout, "shard groups of %s.%s already match the shard group duration %s\n", cmd.database, cmd.retention, rpi.ShardGroupDuration)
+		return nil
+	}
+
+	if cmd.dryRun {
+		for _, g := range old {
+			fmt.Fprintf(cmd.Stdout, "would replace shard group %d (%s to %s)\n", g.ID, g.StartTime.UTC().Format(timeFormat), g.EndTime.UTC().Format(timeFormat))
+		}
+		for _, g := range layout {
+			fmt.Fprintf(cmd.Stdout, "would create shard group %s to %s\n", g.StartTime.UTC().Format(timeFormat), g.EndTime.UTC().Format(timeFormat))
+		}
+		return nil
+	}
+
+	var ids []uint64
+	for _, g := range old {
+		for _, sh := range g.Shards {
+			ids = append(ids, sh.ID)
+		}
+	}
+	return cmd.reshard(cmd.Stdout, ids, layout)
+}
+
+// planLayout returns the shard groups whose time ranges don't match the shard
+// group duration d, and the shard groups of duration d replacing them, in the
+// same layout the server creates new shard groups with. Shard groups matching
+// d that overlap a replacement are replaced too.
+func planLayout(groups []meta.ShardGroupInfo, d time.Duration) (old, layout []meta.ShardGroupInfo) {
+	replace := make([]bool, len(groups))
+	for i, g := range groups {
+		replace[i] = !g.StartTime.Equal(g.StartTime.Truncate(d)) || g.EndTime.Sub(g.StartTime) != d
+	}
+
+	for {
+		layout = layout[:0]
+		for i, g := range groups {
+			if !replace[i] {
+				continue
+			}
+			for t := g.StartTime.Truncate(d); t.Before(g.EndTime); t = t.Add(d) {
+				// Adjacent groups may share a replacement.
+				if len(layout) > 0 && !t.After(layout[len(layout)-1].StartTime) {
+					continue
+				}
+				layout = append(layout, meta.ShardGroupInfo{StartTime: t, EndTime: t.Add(d)})
+			}
+		}
+
+		var changed bool
+		for i, g := range groups {
+			if replace[i] {
+				continue
+			}
+			for _, n := range layout {
+				if g.StartTime.Before(n.EndTime) && n.StartTime.Before(g.EndTime) {
+					replace[i], changed = true, true
+					break
+				}
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	for i, g := range groups {
+		if replace[i] {
+			old = append(old, g)
+		}
+	}
+	return old, layout
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *ReshardCommand) printUsage() {
+	usage := `Rewrites the shards of a retention policy into shard groups of its current
+shard group duration, after the duration was changed. The InfluxDB process
+must not be running.
+
+Shard groups whose time ranges don't match the duration are replaced by shard
+groups of the duration, aligned as the server aligns new shard groups. The TSM
+and WAL files of their shards are rewritten to the new shards, the meta store
+is updated in a single change, and the old shards are removed.
+
+Usage: influx_inspect reshard [flags]
+
+    -datadir <path>
+            Required. Data storage path.
+    -waldir <path>
+            Required. WAL storage path.
+    -metadir <path>
+            Required. Meta store path.
+    -database <name>
+            Required. Database of the retention policy.
+    -retention <name>
+            Required. Retention policy to reshard.
+    -dry-run
+            Print the shard groups that would be replaced and created,
+            without changing them.
+`
+
+	fmt.Fprintf(cmd.Stdout, usage)
+}
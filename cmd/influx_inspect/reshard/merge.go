@@ -0,0 +1,132 @@
+package reshard
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// MergeCommand represents the program execution for "influx_inspect merge-shards".
+type MergeCommand struct {
+	Stderr io.Writer
+	Stdout io.Writer
+
+	options
+	shards []uint64
+}
+
+// NewMergeCommand returns a new instance of MergeCommand.
+func NewMergeCommand() *MergeCommand {
+	return &MergeCommand{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+	}
+}
+
+// Run executes the command.
+func (cmd *MergeCommand) Run(args ...string) error {
+	var shards string
+	fs := flag.NewFlagSet("merge-shards", flag.ExitOnError)
+	cmd.register(fs)
+	fs.StringVar(&shards, "shards", "", "Comma separated IDs of the shards to merge")
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if err := cmd.validate(); err != nil {
+		return err
+	}
+
+	ids, err := parseShardIDs(shards)
+	if err != nil {
+		return err
+	} else if len(ids) < 2 {
+		return errors.New("at least two -shards are required")
+	}
+	cmd.shards = ids
+
+	return cmd.run()
+}
+
+func (cmd *MergeCommand) run() error {
+	client, err := cmd.openMeta()
+	if err != nil {
+		return err
+	}
+	data := client.Data()
+	client.Close()
+
+	groups, err := cmd.shardGroups(&data, cmd.shards)
+	if err != nil {
+		return err
+	}
+
+	// The merged shard group covers the time ranges of all the merged groups.
+	merged := meta.ShardGroupInfo{StartTime: groups[0].StartTime, EndTime: groups[0].EndTime}
+	for _, g := range groups[1:] {
+		if g.StartTime.Before(merged.StartTime) {
+			merged.StartTime = g.StartTime
+		}
+		if g.EndTime.After(merged.EndTime) {
+			merged.EndTime = g.EndTime
+		}
+	}
+
+	return cmd.reshard(cmd.Stdout, cmd.shards, []meta.ShardGroupInfo{merged})
+}
+
+// parseShardIDs parses a comma separated list of shard IDs.
+func parseShardIDs(s string) ([]uint64, error) {
+	var ids []uint64
+	seen := make(map[uint64]bool)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shard id %q", v)
+		} else if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *MergeCommand) printUsage() {
+	usage := `Merges shards of a retention policy into one shard covering the time ranges
+of all of them, such as the small shards left by a shorter shard group
+duration. The InfluxDB process must not be running.
+
+The TSM and WAL files of the shards are rewritten to a new shard, the shard
+groups of the merged shards are replaced in the meta store by one for the new
+shard, and the merged shards are removed. The merged time range may not
+overlap any shard group that is not merged.
+
+Usage: influx_inspect merge-shards [flags]
+
+    -datadir <path>
+            Required. Data storage path.
+    -waldir <path>
+            Required. WAL storage path.
+    -metadir <path>
+            Required. Meta store path.
+    -database <name>
+            Required. Database of the shards.
+    -retention <name>
+            Required. Retention policy of the shards.
+    -shards <id,id,...>
+            Required. IDs of the shards to merge.
+`
+
+	fmt.Fprintf(cmd.Stdout, usage)
+}
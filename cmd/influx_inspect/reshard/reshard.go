@@ -0,0 +1,375 @@
+// Package reshard merges and splits the shards of a retention policy while
+// the server is stopped, rewriting their TSM files and updating the meta
+// store to match.
+package reshard
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/buildindex"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+	"github.com/influxdata/influxdb/tsdb/index/tsi1"
+)
+
+// maxTSMFileSize is the size at which a new TSM file is started.
+const maxTSMFileSize = 2048 * 1024 * 1024 // 2GB
+
+// options holds the flags shared by merge-shards and split-shard.
+type options struct {
+	dataDir   string
+	walDir    string
+	metaDir   string
+	database  string
+	retention string
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.dataDir, "datadir", "", "Data storage path")
+	fs.StringVar(&o.walDir, "waldir", "", "WAL storage path")
+	fs.StringVar(&o.metaDir, "metadir", "", "Meta store path")
+	fs.StringVar(&o.database, "database", "", "Database of the shards")
+	fs.StringVar(&o.retention, "retention", "", "Retention policy of the shards")
+}
+
+func (o *options) validate() error {
+	if o.dataDir == "" {
+		return errors.New("-datadir is required")
+	} else if o.walDir == "" {
+		return errors.New("-waldir is required")
+	} else if o.metaDir == "" {
+		return errors.New("-metadir is required")
+	} else if o.database == "" {
+		return errors.New("-database is required")
+	} else if o.retention == "" {
+		return errors.New("-retention is required")
+	}
+	return nil
+}
+
+// shardPath returns the data directory of shard id.
+func (o *options) shardPath(id uint64) string {
+	return filepath.Join(o.dataDir, o.database, o.retention, strconv.FormatUint(id, 10))
+}
+
+// walPath returns the WAL directory of shard id.
+func (o *options) walPath(id uint64) string {
+	return filepath.Join(o.walDir, o.database, o.retention, strconv.FormatUint(id, 10))
+}
+
+// openMeta opens the meta store, which must already exist.
+func (o *options) openMeta() (*meta.Client, error) {
+	if _, err := os.Stat(filepath.Join(o.metaDir, "meta.db")); err != nil {
+		return nil, fmt.Errorf("meta store not found: %s", err)
+	}
+
+	c := meta.NewConfig()
+	c.Dir = o.metaDir
+	client := meta.NewClient(c)
+	if err := client.Open(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// shardGroups returns the shard groups of the shards with the given IDs, in
+// the same order.
+func (o *options) shardGroups(data *meta.Data, ids []uint64) ([]meta.ShardGroupInfo, error) {
+	groups, err := data.ShardGroups(o.database, o.retention)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]meta.ShardGroupInfo, 0, len(ids))
+	for _, id := range ids {
+		var ok bool
+		for _, g := range groups {
+			for _, sh := range g.Shards {
+				if sh.ID == id {
+					found = append(found, g)
+					ok = true
+				}
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("shard %d not found in %s.%s", id, o.database, o.retention)
+		}
+	}
+	return found, nil
+}
+
+// reshard writes the data of the shards with the given IDs to new shards with
+// the time ranges of groups, replaces the shard groups of the old shards with
+// the new ones in the meta store, and removes the old shards. Values are
+// written to the new shard whose time range contains them, or else to the
+// first or last new shard.
+func (o *options) reshard(w io.Writer, ids []uint64, groups []meta.ShardGroupInfo) error {
+	client, err := o.openMeta()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	data := client.Data()
+	old, err := o.shardGroups(&data, ids)
+	if err != nil {
+		return err
+	}
+
+	var oldIDs []uint64
+	seen := make(map[uint64]bool)
+	for _, g := range old {
+		if !seen[g.ID] {
+			seen[g.ID] = true
+			oldIDs = append(oldIDs, g.ID)
+		}
+		if len(g.Shards) != 1 {
+			return fmt.Errorf("shard group %d has %d shards, expected 1", g.ID, len(g.Shards))
+		}
+	}
+
+	created, err := data.ReplaceShardGroups(o.database, o.retention, oldIDs, groups)
+	if err != nil {
+		return err
+	}
+
+	var sources []source
+	var buildTSI bool
+	for _, id := range ids {
+		sources = append(sources, source{path: o.shardPath(id), walPath: o.walPath(id)})
+		if _, err := os.Stat(filepath.Join(o.shardPath(id), "index")); err == nil {
+			buildTSI = true
+		}
+	}
+
+	targets := make([]*target, len(created))
+	for i, g := range created {
+		targets[i] = &target{
+			path: o.shardPath(g.Shards[0].ID),
+			min:  g.StartTime.UnixNano(),
+			max:  g.EndTime.UnixNano() - 1,
+		}
+	}
+	targets[0].min = math.MinInt64
+	targets[len(targets)-1].max = math.MaxInt64
+
+	if err := rewrite(sources, targets); err != nil {
+		for _, t := range targets {
+			os.RemoveAll(t.path)
+		}
+		return err
+	}
+
+	if buildTSI {
+		for _, g := range created {
+			id := g.Shards[0].ID
+			cmd := buildindex.NewCommand()
+			cmd.Stdout = ioutil.Discard
+			if err := cmd.Run("-shard", o.shardPath(id), "-waldir", o.walPath(id), "-index", tsi1.IndexName); err != nil {
+				return fmt.Errorf("building index of shard %d: %s", id, err)
+			}
+		}
+	}
+
+	if err := client.SetData(&data); err != nil {
+		return err
+	}
+
+	// The server only removes the shards of expired shard groups, so remove
+	// the old shards now that they are no longer in the meta store.
+	for _, id := range ids {
+		if err := os.RemoveAll(o.shardPath(id)); err != nil {
+			return err
+		} else if err := os.RemoveAll(o.walPath(id)); err != nil {
+			return err
+		}
+	}
+
+	for _, g := range old {
+		fmt.Fprintf(w, "deleted shard group %d (shard %d, %s to %s)\n", g.ID, g.Shards[0].ID, g.StartTime.UTC().Format(timeFormat), g.EndTime.UTC().Format(timeFormat))
+	}
+	for i, g := range created {
+		fmt.Fprintf(w, "created shard group %d (shard %d, %s to %s) with %d values\n", g.ID, g.Shards[0].ID, g.StartTime.Format(timeFormat), g.EndTime.Format(timeFormat), targets[i].n)
+	}
+	return nil
+}
+
+// timeFormat is the format times are printed in.
+const timeFormat = "2006-01-02T15:04:05Z07:00"
+
+// source is a shard whose data is rewritten.
+type source struct {
+	path    string
+	walPath string
+}
+
+// target is a new shard that the values in [min, max] are written to.
+type target struct {
+	path     string
+	min, max int64
+
+	w        tsm1.TSMWriter
+	sequence int
+	n        int
+}
+
+// write writes the values of key to the current TSM file of t, starting a
+// new file if needed.
+func (t *target) write(key []byte, values tsm1.Values) error {
+	if t.w == nil {
+		t.sequence++
+		f, err := os.Create(filepath.Join(t.path, fmt.Sprintf("%09d-%09d.%s", 1, t.sequence, tsm1.TSMFileExtension)))
+		if err != nil {
+			return err
+		}
+		w, err := tsm1.NewTSMWriter(f)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		t.w = w
+	}
+
+	t.n += len(values)
+	for len(values) > 0 {
+		n := len(values)
+		if n > tsdb.DefaultMaxPointsPerBlock {
+			n = tsdb.DefaultMaxPointsPerBlock
+		}
+		if err := t.w.Write(key, values[:n]); err != nil {
+			return err
+		}
+		values = values[n:]
+	}
+
+	if t.w.Size() > maxTSMFileSize {
+		return t.close()
+	}
+	return nil
+}
+
+// close finishes the current TSM file of t, if any.
+func (t *target) close() error {
+	if t.w == nil {
+		return nil
+	}
+	w := t.w
+	t.w = nil
+	if err := w.WriteIndex(); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// rewrite writes the values of the TSM and WAL files of sources to targets.
+// Values with the same key and timestamp are deduplicated, keeping the value
+// from the last source, or from the WAL.
+func rewrite(sources []source, targets []*target) error {
+	for _, t := range targets {
+		if _, err := os.Stat(t.path); err == nil {
+			return fmt.Errorf("shard already present: %s", t.path)
+		}
+		if err := os.MkdirAll(t.path, 0777); err != nil {
+			return err
+		}
+	}
+
+	var readers []*tsm1.TSMReader
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+
+	cache := tsm1.NewCache(0, "")
+	keys := make(map[string]struct{})
+	for _, src := range sources {
+		paths, err := filepath.Glob(filepath.Join(src.path, "*."+tsm1.TSMFileExtension))
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			r, err := tsm1.NewTSMReader(f)
+			if err != nil {
+				f.Close()
+				return fmt.Errorf("%s: %s", path, err)
+			}
+			readers = append(readers, r)
+
+			for i := 0; i < r.KeyCount(); i++ {
+				key, _ := r.KeyAt(i)
+				keys[string(key)] = struct{}{}
+			}
+		}
+
+		walPaths, err := filepath.Glob(filepath.Join(src.walPath, "*."+tsm1.WALFileExtension))
+		if err != nil {
+			return err
+		}
+		// Backfill segments are loaded last, as they hold the newer values.
+		backfillPaths, err := filepath.Glob(filepath.Join(src.walPath, tsm1.BackfillWALDirName, "*."+tsm1.WALFileExtension))
+		if err != nil {
+			return err
+		}
+		walPaths = append(walPaths, backfillPaths...)
+		if err := tsm1.NewCacheLoader(walPaths).Load(cache); err != nil {
+			return err
+		}
+	}
+	for _, key := range cache.Keys() {
+		keys[string(key)] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	for _, k := range sorted {
+		key := []byte(k)
+
+		var values tsm1.Values
+		for _, r := range readers {
+			v, err := r.ReadAll(key)
+			if err != nil {
+				return err
+			}
+			values = append(values, v...)
+		}
+		values = append(values, cache.Values(key)...)
+		values = values.Deduplicate()
+
+		for _, t := range targets {
+			i := sort.Search(len(values), func(i int) bool { return values[i].UnixNano() >= t.min })
+			j := sort.Search(len(values), func(i int) bool { return values[i].UnixNano() > t.max })
+			if i < j {
+				if err := t.write(key, values[i:j]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, t := range targets {
+		if err := t.close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
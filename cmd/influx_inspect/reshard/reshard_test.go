@@ -0,0 +1,263 @@
+package reshard_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/reshard"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+var day0 = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestMergeAndSplit(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	// Create three daily shards with a value at noon each day.
+	ids := MustCreateShards(t, dir, 3)
+	for i, id := range ids {
+		ts := day0.Add(time.Duration(i)*24*time.Hour + 12*time.Hour)
+		MustWriteTSM(t, filepath.Join(dir, "data", "db0", "rp0", fmt.Sprint(id)), "cpu,host=A#!~#value", tsm1.NewValue(ts.UnixNano(), float64(i)))
+	}
+
+	var buf bytes.Buffer
+	merge := reshard.NewMergeCommand()
+	merge.Stdout = &buf
+	if err := merge.Run(append(flags(dir), "-shards", fmt.Sprintf("%d,%d", ids[0], ids[1]))...); err != nil {
+		t.Fatal(err)
+	}
+
+	groups := MustShardGroups(t, dir)
+	if len(groups) != 2 {
+		t.Fatalf("unexpected shard groups: %v", groups)
+	} else if !groups[0].StartTime.Equal(day0) || !groups[0].EndTime.Equal(day0.Add(48*time.Hour)) {
+		t.Fatalf("unexpected merged time range: %s to %s", groups[0].StartTime, groups[0].EndTime)
+	} else if groups[1].Shards[0].ID != ids[2] {
+		t.Fatalf("unexpected shard: %d", groups[1].Shards[0].ID)
+	}
+	for _, id := range ids[:2] {
+		if _, err := os.Stat(filepath.Join(dir, "data", "db0", "rp0", fmt.Sprint(id))); !os.IsNotExist(err) {
+			t.Fatalf("expected shard %d to be removed: %v", id, err)
+		}
+	}
+
+	merged := groups[0].Shards[0].ID
+	if got := MustReadShard(t, dir, merged); len(got) != 2 || got[0].Value() != 0.0 || got[1].Value() != 1.0 {
+		t.Fatalf("unexpected merged values: %v", got)
+	}
+
+	split := reshard.NewSplitCommand()
+	split.Stdout = &buf
+	if err := split.Run(append(flags(dir), "-shard", fmt.Sprint(merged), "-at", day0.Add(24*time.Hour).Format(time.RFC3339))...); err != nil {
+		t.Fatal(err)
+	}
+
+	groups = MustShardGroups(t, dir)
+	if len(groups) != 3 {
+		t.Fatalf("unexpected shard groups: %v", groups)
+	}
+	for i, g := range groups[:2] {
+		if start := day0.Add(time.Duration(i) * 24 * time.Hour); !g.StartTime.Equal(start) || !g.EndTime.Equal(start.Add(24*time.Hour)) {
+			t.Fatalf("unexpected split time range: %s to %s", g.StartTime, g.EndTime)
+		}
+		if got := MustReadShard(t, dir, g.Shards[0].ID); len(got) != 1 || got[0].Value() != float64(i) {
+			t.Fatalf("unexpected split values: %v", got)
+		}
+	}
+}
+
+func TestSplit_OutOfRange(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	ids := MustCreateShards(t, dir, 1)
+
+	split := reshard.NewSplitCommand()
+	split.Stdout = ioutil.Discard
+	if err := split.Run(append(flags(dir), "-shard", fmt.Sprint(ids[0]), "-at", day0.Add(48*time.Hour).Format(time.RFC3339))...); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestReshard(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	// Create two daily shards with a value at noon each day, then change the
+	// shard group duration to a week.
+	ids := MustCreateShards(t, dir, 2)
+	for i, id := range ids {
+		ts := day0.Add(time.Duration(i)*24*time.Hour + 12*time.Hour)
+		MustWriteTSM(t, filepath.Join(dir, "data", "db0", "rp0", fmt.Sprint(id)), "cpu,host=A#!~#value", tsm1.NewValue(ts.UnixNano(), float64(i)))
+	}
+
+	week := 7 * 24 * time.Hour
+	client := openMeta(t, dir)
+	if err := client.UpdateRetentionPolicy("db0", "rp0", &meta.RetentionPolicyUpdate{ShardGroupDuration: &week}, false); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	// A dry run leaves the shards alone.
+	var buf bytes.Buffer
+	cmd := reshard.NewReshardCommand()
+	cmd.Stdout = &buf
+	if err := cmd.Run(append(flags(dir), "-dry-run")...); err != nil {
+		t.Fatal(err)
+	} else if groups := MustShardGroups(t, dir); len(groups) != 2 {
+		t.Fatalf("unexpected shard groups: %v", groups)
+	}
+
+	cmd = reshard.NewReshardCommand()
+	cmd.Stdout = &buf
+	if err := cmd.Run(flags(dir)...); err != nil {
+		t.Fatal(err)
+	}
+
+	start := day0.Truncate(week)
+	groups := MustShardGroups(t, dir)
+	if len(groups) != 1 {
+		t.Fatalf("unexpected shard groups: %v", groups)
+	} else if !groups[0].StartTime.Equal(start) || !groups[0].EndTime.Equal(start.Add(week)) {
+		t.Fatalf("unexpected time range: %s to %s", groups[0].StartTime, groups[0].EndTime)
+	}
+	if got := MustReadShard(t, dir, groups[0].Shards[0].ID); len(got) != 2 || got[0].Value() != 0.0 || got[1].Value() != 1.0 {
+		t.Fatalf("unexpected values: %v", got)
+	}
+
+	// The shards now match the duration, so there is nothing left to do.
+	buf.Reset()
+	cmd = reshard.NewReshardCommand()
+	cmd.Stdout = &buf
+	if err := cmd.Run(flags(dir)...); err != nil {
+		t.Fatal(err)
+	} else if got := buf.String(); got != "shard groups of db0.rp0 already match the shard group duration 168h0m0s\n" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+}
+
+func flags(dir string) []string {
+	return []string{
+		"-datadir", filepath.Join(dir, "data"),
+		"-waldir", filepath.Join(dir, "wal"),
+		"-metadir", filepath.Join(dir, "meta"),
+		"-database", "db0",
+		"-retention", "rp0",
+	}
+}
+
+func openMeta(t *testing.T, dir string) *meta.Client {
+	c := meta.NewConfig()
+	c.Dir = filepath.Join(dir, "meta")
+	if err := os.MkdirAll(c.Dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	client := meta.NewClient(c)
+	if err := client.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// MustCreateShards creates db0.rp0 with n daily shard groups starting at
+// day0, and returns the IDs of their shards.
+func MustCreateShards(t *testing.T, dir string, n int) []uint64 {
+	client := openMeta(t, dir)
+	defer client.Close()
+
+	d := 24 * time.Hour
+	if _, err := client.CreateDatabaseWithRetentionPolicy("db0", &meta.RetentionPolicySpec{Name: "rp0", ShardGroupDuration: d}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []uint64
+	for i := 0; i < n; i++ {
+		g, err := client.CreateShardGroup("db0", "rp0", day0.Add(time.Duration(i)*d))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, g.Shards[0].ID)
+	}
+	return ids
+}
+
+// MustShardGroups returns the shard groups of db0.rp0.
+func MustShardGroups(t *testing.T, dir string) []meta.ShardGroupInfo {
+	client := openMeta(t, dir)
+	defer client.Close()
+
+	data := client.Data()
+	groups, err := data.ShardGroups("db0", "rp0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return groups
+}
+
+// MustWriteTSM writes a TSM file with values for key to the shard at path.
+func MustWriteTSM(t *testing.T, path, key string, values ...tsm1.Value) {
+	if err := os.MkdirAll(path, 0777); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(path, "000000001-000000001.tsm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]byte(key), values); err != nil {
+		t.Fatal(err)
+	} else if err := w.WriteIndex(); err != nil {
+		t.Fatal(err)
+	} else if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// MustReadShard returns all values of the TSM files of shard id.
+func MustReadShard(t *testing.T, dir string, id uint64) tsm1.Values {
+	paths, err := filepath.Glob(filepath.Join(dir, "data", "db0", "rp0", fmt.Sprint(id), "*.tsm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var values tsm1.Values
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := tsm1.NewTSMReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < r.KeyCount(); i++ {
+			key, _ := r.KeyAt(i)
+			v, err := r.ReadAll(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			values = append(values, v...)
+		}
+		r.Close()
+	}
+	return values
+}
+
+func MustTempDir() string {
+	dir, err := ioutil.TempDir("", "reshard-")
+	if err != nil {
+		panic(fmt.Sprintf("failed to create temp dir: %v", err))
+	}
+	return dir
+}
@@ -0,0 +1,115 @@
+package reshard
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// SplitCommand represents the program execution for "influx_inspect split-shard".
+type SplitCommand struct {
+	Stderr io.Writer
+	Stdout io.Writer
+
+	options
+	shard uint64
+	at    time.Time
+}
+
+// NewSplitCommand returns a new instance of SplitCommand.
+func NewSplitCommand() *SplitCommand {
+	return &SplitCommand{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+	}
+}
+
+// Run executes the command.
+func (cmd *SplitCommand) Run(args ...string) error {
+	var at string
+	fs := flag.NewFlagSet("split-shard", flag.ExitOnError)
+	cmd.register(fs)
+	fs.Uint64Var(&cmd.shard, "shard", 0, "ID of the shard to split")
+	fs.StringVar(&at, "at", "", "RFC3339 time to split the shard at")
+	fs.SetOutput(cmd.Stdout)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if err := cmd.validate(); err != nil {
+		return err
+	} else if cmd.shard == 0 {
+		return errors.New("-shard is required")
+	} else if at == "" {
+		return errors.New("-at is required")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return fmt.Errorf("invalid -at time: %s", err)
+	}
+	cmd.at = t.UTC()
+
+	return cmd.run()
+}
+
+func (cmd *SplitCommand) run() error {
+	client, err := cmd.openMeta()
+	if err != nil {
+		return err
+	}
+	data := client.Data()
+	client.Close()
+
+	groups, err := cmd.shardGroups(&data, []uint64{cmd.shard})
+	if err != nil {
+		return err
+	}
+
+	g := groups[0]
+	if !cmd.at.After(g.StartTime) || !cmd.at.Before(g.EndTime) {
+		return fmt.Errorf("-at must be within the shard group time range %s to %s", g.StartTime.Format(timeFormat), g.EndTime.Format(timeFormat))
+	}
+
+	return cmd.reshard(cmd.Stdout, []uint64{cmd.shard}, []meta.ShardGroupInfo{
+		{StartTime: g.StartTime, EndTime: cmd.at},
+		{StartTime: cmd.at, EndTime: g.EndTime},
+	})
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *SplitCommand) printUsage() {
+	usage := `Splits a shard into two shards at a time boundary. The InfluxDB process must
+not be running.
+
+The TSM and WAL files of the shard are rewritten to two new shards, the shard
+group of the shard is replaced in the meta store by one for each new shard,
+and the shard is removed.
+
+Usage: influx_inspect split-shard [flags]
+
+    -datadir <path>
+            Required. Data storage path.
+    -waldir <path>
+            Required. WAL storage path.
+    -metadir <path>
+            Required. Meta store path.
+    -database <name>
+            Required. Database of the shard.
+    -retention <name>
+            Required. Retention policy of the shard.
+    -shard <id>
+            Required. ID of the shard to split.
+    -at <time>
+            Required. RFC3339 time to split the shard at. Values before the
+            time are written to the first new shard, and the rest to the
+            second.
+`
+
+	fmt.Fprintf(cmd.Stdout, usage)
+}
@@ -0,0 +1,185 @@
+// Package scrub hashes or redacts tag values and string field values in line
+// protocol files, such as those written by "influx_inspect export".
+package scrub
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	scrubrules "github.com/influxdata/influxdb/pkg/scrub"
+)
+
+// Sections written by "influx_inspect export".
+const (
+	ddlSection = "# DDL"
+	dmlSection = "# DML"
+)
+
+// Command represents the program execution for "influx_inspect scrub".
+type Command struct {
+	// Standard input/output, overridden for testing.
+	Stdin  io.Reader
+	Stderr io.Writer
+	Stdout io.Writer
+
+	path       string
+	out        string
+	precision  string
+	compressed bool
+	rules      *scrubrules.Rules
+
+	pointsWritten int64
+	linesSkipped  int64
+}
+
+// NewCommand returns a new instance of Command.
+func NewCommand() *Command {
+	return &Command{
+		Stdin:  os.Stdin,
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+		rules:  scrubrules.NewRules(),
+	}
+}
+
+// Run executes the command.
+func (cmd *Command) Run(args ...string) error {
+	fs := flag.NewFlagSet("scrub", flag.ExitOnError)
+	fs.StringVar(&cmd.path, "path", "", "Line protocol file to scrub, or - to read from stdin")
+	fs.StringVar(&cmd.out, "out", "-", "File to write the scrubbed line protocol to, or - to write to stdout")
+	fs.StringVar(&cmd.precision, "precision", "ns", "Precision of the timestamps in the file: h, m, s, ms, u or ns")
+	fs.BoolVar(&cmd.compressed, "compressed", false, "Set if the file is gzip compressed")
+	fs.Var(cmd.rules, "scrub", "Rule of the form tag:<key>=<hash|redact> or field:<key>=<hash|redact>. May be repeated")
+	fs.StringVar(&cmd.rules.Salt, "scrub-salt", "", "Secret used to hash values")
+
+	fs.SetOutput(cmd.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(cmd.Stderr, "Hashes or redacts tag values and string field values in line protocol.\n")
+		fmt.Fprintf(cmd.Stderr, "Lines that cannot be parsed are dropped.\n\n")
+		fmt.Fprintf(cmd.Stderr, "Usage: %s scrub [flags]\n\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if cmd.path == "" {
+		return errors.New("-path is required")
+	} else if cmd.rules.Empty() {
+		return errors.New("at least one -scrub rule is required")
+	}
+	switch cmd.precision {
+	case "h", "m", "s", "ms", "u", "ns":
+	default:
+		return fmt.Errorf("invalid precision %q", cmd.precision)
+	}
+
+	return cmd.run()
+}
+
+func (cmd *Command) run() error {
+	var r io.Reader = cmd.Stdin
+	if cmd.path != "-" {
+		f, err := os.Open(cmd.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	if cmd.compressed {
+		gzr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gzr.Close()
+		r = gzr
+	}
+
+	w := cmd.Stdout
+	if cmd.out != "-" {
+		f, err := os.Create(cmd.out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriterSize(w, 1024*1024)
+	start := time.Now()
+	if err := cmd.process(r, bw); err != nil {
+		return err
+	} else if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.Stderr, "scrubbed %d points in %s (%d lines skipped)\n", cmd.pointsWritten, time.Since(start), cmd.linesSkipped)
+	return nil
+}
+
+// process writes every line read from r to w with its points scrubbed.
+// Comments and the DDL section written by "influx_inspect export" are
+// copied unchanged.
+func (cmd *Command) process(r io.Reader, w io.Writer) error {
+	var ddl bool
+
+	br := bufio.NewReaderSize(r, 1024*1024)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		} else if err != nil && err != io.EOF {
+			return err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if line[0] == '#' {
+			switch string(line) {
+			case ddlSection:
+				ddl = true
+			case dmlSection:
+				ddl = false
+			}
+		}
+		if line[0] == '#' || ddl {
+			if _, err := fmt.Fprintf(w, "%s\n", line); err != nil {
+				return err
+			}
+			continue
+		}
+
+		points, perr := models.ParsePointsWithPrecision(line, time.Now().UTC(), cmd.precision)
+		if perr != nil {
+			fmt.Fprintf(cmd.Stderr, "line %d: %s\n", n, perr)
+			cmd.linesSkipped++
+			continue
+		}
+
+		for _, p := range points {
+			scrubbed, err := cmd.rules.Point(p)
+			if err != nil {
+				return fmt.Errorf("line %d: %s", n, err)
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", scrubbed.PrecisionString(cmd.precision)); err != nil {
+				return err
+			}
+			cmd.pointsWritten++
+		}
+	}
+}
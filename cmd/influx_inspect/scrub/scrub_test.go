@@ -0,0 +1,44 @@
+package scrub
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCommand_Run(t *testing.T) {
+	in := strings.Join([]string{
+		"# DDL",
+		"CREATE DATABASE db0 WITH NAME autogen",
+		"# DML",
+		"# CONTEXT-DATABASE:db0",
+		`cpu,host=a,region=west user="alice",value=1 10`,
+		`cpu,host=b value=2 20`,
+		"not line protocol",
+		"",
+	}, "\n")
+
+	var out bytes.Buffer
+	cmd := NewCommand()
+	cmd.Stdin = strings.NewReader(in)
+	cmd.Stdout, cmd.Stderr = &out, ioutil.Discard
+	if err := cmd.Run("-path", "-", "-scrub", "tag:host=redact", "-scrub", "field:user=redact"); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := strings.Join([]string{
+		"# DDL",
+		"CREATE DATABASE db0 WITH NAME autogen",
+		"# DML",
+		"# CONTEXT-DATABASE:db0",
+		`cpu,host=redacted,region=west user="redacted",value=1 10`,
+		`cpu,host=redacted value=2 20`,
+		"",
+	}, "\n")
+	if got := out.String(); got != exp {
+		t.Fatalf("unexpected output:\n%s", got)
+	} else if cmd.linesSkipped != 1 {
+		t.Fatalf("unexpected lines skipped: %d", cmd.linesSkipped)
+	}
+}
@@ -1,13 +1,16 @@
-// Package verify verifies integrity of TSM files.
+// Package verify verifies integrity of TSM and WAL files.
 package verify
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -18,6 +21,13 @@ import (
 type Command struct {
 	Stderr io.Writer
 	Stdout io.Writer
+
+	dir        string
+	quarantine string
+
+	totalBlocks  int
+	brokenBlocks int
+	corrupt      []string
 }
 
 // NewCommand returns a new instance of Command.
@@ -30,9 +40,9 @@ func NewCommand() *Command {
 
 // Run executes the command.
 func (cmd *Command) Run(args ...string) error {
-	var path string
 	fs := flag.NewFlagSet("verify", flag.ExitOnError)
-	fs.StringVar(&path, "dir", os.Getenv("HOME")+"/.influxdb", "Root storage path. [$HOME/.influxdb]")
+	fs.StringVar(&cmd.dir, "dir", os.Getenv("HOME")+"/.influxdb", "Root storage path. [$HOME/.influxdb]")
+	fs.StringVar(&cmd.quarantine, "quarantine", "", "Move corrupt files to this directory")
 
 	fs.SetOutput(cmd.Stdout)
 	fs.Usage = cmd.printUsage
@@ -42,78 +52,201 @@ func (cmd *Command) Run(args ...string) error {
 	}
 
 	start := time.Now()
-	dataPath := filepath.Join(path, "data")
-
-	brokenBlocks := 0
-	totalBlocks := 0
 
-	// No need to do this in a loop
-	ext := fmt.Sprintf(".%s", tsm1.TSMFileExtension)
-
-	// Get all TSM files by walking through the data dir
-	files := []string{}
-	err := filepath.Walk(dataPath, func(path string, f os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if filepath.Ext(path) == ext {
-			files = append(files, path)
-		}
-		return nil
+	tsmFiles, err := findFiles(filepath.Join(cmd.dir, "data"), func(path string) bool {
+		return filepath.Ext(path) == "."+tsm1.TSMFileExtension
 	})
 	if err != nil {
-		panic(err)
+		return err
+	}
+	walFiles, err := findFiles(filepath.Join(cmd.dir, "wal"), func(path string) bool {
+		return filepath.Ext(path) == "."+tsm1.WALFileExtension && strings.HasPrefix(filepath.Base(path), tsm1.WALFilePrefix)
+	})
+	if err != nil {
+		return err
 	}
 
 	tw := tabwriter.NewWriter(cmd.Stdout, 16, 8, 0, '\t', 0)
 
-	// Verify the checksums of every block in every file
-	for _, f := range files {
-		file, err := os.OpenFile(f, os.O_RDONLY, 0600)
-		if err != nil {
-			return err
-		}
+	for _, f := range tsmFiles {
+		cmd.report(tw, f, cmd.verifyTSM(f))
+	}
+	for _, f := range walFiles {
+		cmd.report(tw, f, cmd.verifyWAL(f))
+	}
 
-		reader, err := tsm1.NewTSMReader(file)
-		if err != nil {
-			return err
+	fmt.Fprintf(tw, "Broken Blocks: %d / %d, in %vs\n", cmd.brokenBlocks, cmd.totalBlocks, time.Since(start).Seconds())
+	fmt.Fprintf(tw, "Corrupt Files: %d / %d\n", len(cmd.corrupt), len(tsmFiles)+len(walFiles))
+	tw.Flush()
+
+	if len(cmd.corrupt) > 0 {
+		return fmt.Errorf("%d corrupt files found", len(cmd.corrupt))
+	}
+	return nil
+}
+
+// report prints the problems found in a file and quarantines it if requested.
+func (cmd *Command) report(w io.Writer, path string, problems []string) {
+	if len(problems) == 0 {
+		fmt.Fprintf(w, "%s: healthy\n", path)
+		return
+	}
+
+	cmd.corrupt = append(cmd.corrupt, path)
+	for _, p := range problems {
+		fmt.Fprintf(w, "%s: %s\n", path, p)
+	}
+
+	if cmd.quarantine == "" {
+		return
+	}
+	moved, err := cmd.quarantineFile(path)
+	if err != nil {
+		fmt.Fprintf(w, "%s: unable to quarantine: %s\n", path, err)
+		return
+	}
+	for _, m := range moved {
+		fmt.Fprintf(w, "%s: quarantined to %s\n", path, m)
+	}
+}
+
+// verifyTSM checks the index, block checksums and tombstones of a TSM file.
+func (cmd *Command) verifyTSM(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return []string{fmt.Sprintf("invalid index: %s", err)}
+	}
+	defer r.Close()
+
+	var problems []string
+	var prev []byte
+	var entries []tsm1.IndexEntry
+	for i := 0; i < r.KeyCount(); i++ {
+		var key []byte
+		key, _, entries = r.Key(i, &entries)
+
+		if i > 0 && bytes.Compare(prev, key) >= 0 {
+			problems = append(problems, fmt.Sprintf("index key %q at position %d is out of order", key, i))
 		}
+		prev = append(prev[:0], key...)
+
+		for j := range entries {
+			e := &entries[j]
+			cmd.totalBlocks++
 
-		blockItr := reader.BlockIterator()
-		brokenFileBlocks := 0
-		count := 0
-		for blockItr.Next() {
-			totalBlocks++
-			key, _, _, _, checksum, buf, err := blockItr.Read()
+			if e.MinTime > e.MaxTime {
+				cmd.brokenBlocks++
+				problems = append(problems, fmt.Sprintf("block %d of key %q at offset %d has min time %d after max time %d", j, key, e.Offset, e.MinTime, e.MaxTime))
+				continue
+			}
+
+			checksum, buf, err := r.ReadBytes(e, nil)
 			if err != nil {
-				brokenBlocks++
-				fmt.Fprintf(tw, "%s: could not get checksum for key %v block %d due to error: %q\n", f, key, count, err)
+				cmd.brokenBlocks++
+				problems = append(problems, fmt.Sprintf("could not read block %d of key %q at offset %d: %s", j, key, e.Offset, err))
 			} else if expected := crc32.ChecksumIEEE(buf); checksum != expected {
-				brokenBlocks++
-				fmt.Fprintf(tw, "%s: got %d but expected %d for key %v, block %d\n", f, checksum, expected, key, count)
+				cmd.brokenBlocks++
+				problems = append(problems, fmt.Sprintf("got checksum %d but expected %d for block %d of key %q at offset %d", checksum, expected, j, key, e.Offset))
 			}
-			count++
-		}
-		if brokenFileBlocks == 0 {
-			fmt.Fprintf(tw, "%s: healthy\n", f)
 		}
-		reader.Close()
 	}
 
-	fmt.Fprintf(tw, "Broken Blocks: %d / %d, in %vs\n", brokenBlocks, totalBlocks, time.Since(start).Seconds())
-	tw.Flush()
+	ts := tsm1.Tombstoner{Path: path}
+	if err := ts.Walk(func(t tsm1.Tombstone) error { return nil }); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid tombstone file: %s", err))
+	}
+
+	return problems
+}
+
+// verifyWAL checks the framing and encoding of every entry in a WAL segment.
+func (cmd *Command) verifyWAL(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	r := tsm1.NewWALSegmentReader(f)
+	defer r.Close()
+
+	for r.Next() {
+		if _, err := r.Read(); err != nil {
+			return []string{fmt.Sprintf("corrupt entry at offset %d: %s", r.Count(), err)}
+		}
+	}
 	return nil
 }
 
+// quarantineFile moves a corrupt file, and any tombstone file belonging to
+// it, under the quarantine directory, keeping its path relative to the root
+// storage path. It returns the new paths.
+func (cmd *Command) quarantineFile(path string) ([]string, error) {
+	paths := []string{path}
+	if filepath.Ext(path) == "."+tsm1.TSMFileExtension {
+		tombstone := strings.TrimSuffix(path, "."+tsm1.TSMFileExtension) + ".tombstone"
+		if _, err := os.Stat(tombstone); err == nil {
+			paths = append(paths, tombstone)
+		}
+	}
+
+	var moved []string
+	for _, p := range paths {
+		rel, err := filepath.Rel(cmd.dir, p)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return moved, errors.New("file is outside the storage path")
+		}
+
+		dst := filepath.Join(cmd.quarantine, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+			return moved, err
+		}
+		if err := os.Rename(p, dst); err != nil {
+			return moved, err
+		}
+		moved = append(moved, dst)
+	}
+	return moved, nil
+}
+
+// findFiles returns the paths of all files under dir accepted by fn. A
+// missing dir is treated as empty.
+func findFiles(dir string, fn func(path string) bool) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+		if os.IsNotExist(err) && path == dir {
+			return filepath.SkipDir
+		} else if err != nil {
+			return err
+		}
+		if !f.IsDir() && fn(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
 // printUsage prints the usage message to STDERR.
 func (cmd *Command) printUsage() {
-	usage := fmt.Sprintf(`Verifies the integrity of TSM files.
+	usage := fmt.Sprintf(`Verifies the integrity of TSM and WAL files.
+
+TSM files are checked for a readable, ordered index, block checksums and
+readable tombstone files. WAL segments are checked for corrupt entries.
 
 Usage: influx_inspect verify [flags]
 
     -dir <path>
             Root storage path
             Defaults to "%[1]s/.influxdb".
+    -quarantine <path>
+            Move corrupt files, keeping their path relative to the root
+            storage path, to this directory. The server must not be running.
  `, os.Getenv("HOME"))
 
 	fmt.Fprintf(cmd.Stdout, usage)
@@ -1,3 +1,209 @@
 package verify_test
 
-// TODO: write some tests
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/verify"
+	"github.com/influxdata/influxdb/pkg/encryption"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	shardDir := filepath.Join(dir, "data", "db0", "rp0", "1")
+	walDir := filepath.Join(dir, "wal", "db0", "rp0", "1")
+	quarantineDir := filepath.Join(dir, "quarantine")
+
+	healthy := filepath.Join(shardDir, "000000001-000000001.tsm")
+	MustWriteTSM(healthy)
+
+	// Flip a byte in the block data so its checksum no longer matches.
+	corrupt := filepath.Join(shardDir, "000000002-000000001.tsm")
+	MustWriteTSM(corrupt)
+	b, err := ioutil.ReadFile(corrupt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[5+4+1] ^= 0xff
+	if err := ioutil.WriteFile(corrupt, b, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// Truncate a WAL entry.
+	wal := filepath.Join(walDir, "_00001.wal")
+	MustWriteWAL(wal)
+	b, err = ioutil.ReadFile(wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(wal, b[:len(b)-2], 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	cmd := verify.NewCommand()
+	cmd.Stdout = &out
+	if err := cmd.Run("-dir", dir, "-quarantine", quarantineDir); err == nil || err.Error() != "2 corrupt files found" {
+		t.Fatalf("unexpected error: %v\n%s", err, out.String())
+	}
+
+	if !strings.Contains(out.String(), healthy+": healthy") {
+		t.Fatalf("expected healthy file to be reported:\n%s", out.String())
+	} else if !strings.Contains(out.String(), "Broken Blocks: 1 / 2") {
+		t.Fatalf("expected broken block to be reported:\n%s", out.String())
+	}
+
+	if _, err := os.Stat(healthy); err != nil {
+		t.Fatalf("expected healthy file to remain: %s", err)
+	}
+	for _, path := range []string{corrupt, wal} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be quarantined", path)
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if _, err := os.Stat(filepath.Join(quarantineDir, rel)); err != nil {
+			t.Fatalf("expected %s in quarantine: %s", rel, err)
+		}
+	}
+}
+
+func TestCommand_Run_Encrypted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	shardDir := filepath.Join(dir, "data", "db0", "rp0", "1")
+	quarantineDir := filepath.Join(dir, "quarantine")
+	path := MustWriteEncryptedTSM(shardDir, filepath.Join(dir, "keys"))
+
+	// Verifying a shard doesn't need its keys.
+	var out bytes.Buffer
+	cmd := verify.NewCommand()
+	cmd.Stdout = &out
+	if err := cmd.Run("-dir", dir, "-quarantine", quarantineDir); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out.String())
+	}
+
+	if !strings.Contains(out.String(), path+": healthy") {
+		t.Fatalf("expected encrypted file to be reported healthy:\n%s", out.String())
+	} else if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected encrypted file to remain: %s", err)
+	}
+}
+
+// MustWriteTSM writes a TSM file with a single block to path.
+func MustWriteTSM(path string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		panic(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		panic(err)
+	}
+	if err := w.Write([]byte("cpu#!~#value"), []tsm1.Value{tsm1.NewValue(0, 1.0), tsm1.NewValue(1, 2.0)}); err != nil {
+		panic(err)
+	} else if err := w.WriteIndex(); err != nil {
+		panic(err)
+	} else if err := w.Close(); err != nil {
+		panic(err)
+	}
+}
+
+// MustWriteEncryptedTSM writes a TSM file with a single encrypted block to
+// dir, using a key file written to keyPath, and returns its path.
+func MustWriteEncryptedTSM(dir, keyPath string) string {
+	key := "1 000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f\n"
+	if err := ioutil.WriteFile(keyPath, []byte(key), 0600); err != nil {
+		panic(err)
+	}
+	p, err := encryption.NewFileKeyProvider(keyPath)
+	if err != nil {
+		panic(err)
+	}
+	cipher := encryption.NewCipher(p)
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		panic(err)
+	}
+	cache := tsm1.NewCache(0, "")
+	if err := cache.Write([]byte("cpu#!~#value"), []tsm1.Value{tsm1.NewValue(0, 1.0), tsm1.NewValue(1, 2.0)}); err != nil {
+		panic(err)
+	}
+
+	fs := tsm1.NewFileStore(dir)
+	if err := fs.Open(); err != nil {
+		panic(err)
+	}
+	defer fs.Close()
+
+	c := &tsm1.Compactor{Dir: dir, FileStore: fs, Cipher: cipher}
+	c.Open()
+	defer c.Close()
+
+	files, err := c.WriteSnapshot(cache)
+	if err != nil {
+		panic(err)
+	} else if len(files) != 1 {
+		panic(fmt.Sprintf("unexpected file count: %d", len(files)))
+	}
+
+	// Snapshots are written as temporary files, renamed once the file store
+	// takes them.
+	path := strings.TrimSuffix(files[0], ".tmp")
+	if err := os.Rename(files[0], path); err != nil {
+		panic(err)
+	}
+	return path
+}
+
+// MustWriteWAL writes a WAL segment with a single write entry to path.
+func MustWriteWAL(path string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		panic(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	w := tsm1.NewWALSegmentWriter(f)
+	entry := &tsm1.WriteWALEntry{Values: map[string][]tsm1.Value{
+		"cpu#!~#value": {tsm1.NewValue(0, 1.0)},
+	}}
+	if err := w.Write(mustMarshalEntry(entry)); err != nil {
+		panic(err)
+	} else if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
+
+func mustMarshalEntry(entry tsm1.WALEntry) (tsm1.WalEntryType, []byte) {
+	bytes := make([]byte, 1024<<2)
+
+	b, err := entry.Encode(bytes)
+	if err != nil {
+		panic(err)
+	}
+
+	return entry.Type(), snappy.Encode(b, b)
+}
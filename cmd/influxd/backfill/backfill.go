@@ -0,0 +1,277 @@
+// Package backfill is the backfill-cq subcommand for the influxd command.
+package backfill
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	client "github.com/influxdata/influxdb/client/v2"
+	"github.com/influxdata/influxdb/influxql"
+)
+
+const (
+	// DefaultChunkSize is the default time range of data processed by each
+	// query of a backfill.
+	DefaultChunkSize = 24 * time.Hour
+
+	// DefaultThrottle is the default delay between the queries of a backfill.
+	DefaultThrottle = time.Second
+)
+
+// Command represents the program execution for "influxd backfill-cq".
+type Command struct {
+	// Standard input/output, overridden for testing.
+	Stderr io.Writer
+	Stdout io.Writer
+
+	host     string
+	username string
+	password string
+	ssl      bool
+	unsafe   bool
+	database string
+	cq       string
+	start    string
+	end      string
+	chunk    time.Duration
+	throttle time.Duration
+}
+
+// NewCommand returns a new instance of Command with default settings.
+func NewCommand() *Command {
+	return &Command{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+	}
+}
+
+// Run executes the program.
+func (cmd *Command) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.StringVar(&cmd.host, "host", "localhost:8086", "")
+	fs.StringVar(&cmd.username, "username", "", "")
+	fs.StringVar(&cmd.password, "password", "", "")
+	fs.BoolVar(&cmd.ssl, "ssl", false, "")
+	fs.BoolVar(&cmd.unsafe, "unsafeSsl", false, "")
+	fs.StringVar(&cmd.database, "database", "", "")
+	fs.StringVar(&cmd.cq, "cq", "", "")
+	fs.StringVar(&cmd.start, "start", "", "")
+	fs.StringVar(&cmd.end, "end", "", "")
+	fs.DurationVar(&cmd.chunk, "chunk", DefaultChunkSize, "")
+	fs.DurationVar(&cmd.throttle, "throttle", DefaultThrottle, "")
+	fs.SetOutput(cmd.Stderr)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 0 {
+		return errors.New("backfill-cq takes no arguments")
+	} else if cmd.database == "" {
+		return errors.New("-database is required")
+	} else if cmd.cq == "" {
+		return errors.New("-cq is required")
+	} else if cmd.chunk <= 0 {
+		return errors.New("-chunk must be positive")
+	} else if cmd.throttle < 0 {
+		return errors.New("-throttle must not be negative")
+	}
+
+	start, err := time.Parse(time.RFC3339, cmd.start)
+	if err != nil {
+		return fmt.Errorf("invalid -start: %s", err)
+	}
+	end := time.Now()
+	if cmd.end != "" {
+		if end, err = time.Parse(time.RFC3339, cmd.end); err != nil {
+			return fmt.Errorf("invalid -end: %s", err)
+		}
+	}
+	if !end.After(start) {
+		return errors.New("-end must be after -start")
+	}
+
+	addr := "http://" + cmd.host
+	if cmd.ssl {
+		addr = "https://" + cmd.host
+	}
+	c, err := client.NewHTTPClient(client.HTTPConfig{
+		Addr:               addr,
+		Username:           cmd.username,
+		Password:           cmd.password,
+		InsecureSkipVerify: cmd.unsafe,
+	})
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	stmt, err := cmd.continuousQuery(c)
+	if err != nil {
+		return err
+	}
+	return cmd.backfill(c, stmt.Source, start, end)
+}
+
+// continuousQuery returns the statement of the continuous query to backfill.
+func (cmd *Command) continuousQuery(c client.Client) (*influxql.CreateContinuousQueryStatement, error) {
+	resp, err := c.Query(client.NewQuery("SHOW CONTINUOUS QUERIES", "", ""))
+	if err != nil {
+		return nil, err
+	} else if err := resp.Error(); err != nil {
+		return nil, err
+	}
+
+	for _, r := range resp.Results {
+		for _, row := range r.Series {
+			if row.Name != cmd.database {
+				continue
+			}
+			for _, values := range row.Values {
+				if len(values) < 2 || values[0] != cmd.cq {
+					continue
+				}
+				q, ok := values[1].(string)
+				if !ok {
+					return nil, fmt.Errorf("unexpected query of continuous query %s: %v", cmd.cq, values[1])
+				}
+				stmt, err := influxql.ParseStatement(q)
+				if err != nil {
+					return nil, fmt.Errorf("cannot parse continuous query %s: %s", cmd.cq, err)
+				}
+				cq, ok := stmt.(*influxql.CreateContinuousQueryStatement)
+				if !ok {
+					return nil, fmt.Errorf("unexpected statement for continuous query %s: %s", cmd.cq, q)
+				}
+				return cq, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("continuous query not found: %s.%s", cmd.database, cmd.cq)
+}
+
+// backfill runs the SELECT INTO of a continuous query between start and end
+// one chunk at a time. The range is widened to whole GROUP BY intervals so
+// the first and last intervals are complete.
+func (cmd *Command) backfill(c client.Client, stmt *influxql.SelectStatement, start, end time.Time) error {
+	interval, err := stmt.GroupByInterval()
+	if err != nil {
+		return err
+	} else if interval == 0 {
+		return errors.New("continuous query has no GROUP BY time interval")
+	}
+	offset, err := stmt.GroupByOffset()
+	if err != nil {
+		return err
+	}
+
+	start = truncate(start.Add(-offset), interval).Add(offset)
+	if t := truncate(end.Add(-offset), interval).Add(offset); t.Before(end) {
+		end = t.Add(interval)
+	}
+
+	// Each chunk holds whole intervals.
+	chunk := cmd.chunk
+	if r := chunk % interval; r != 0 {
+		chunk += interval - r
+	}
+
+	var total int64
+	for t := start; t.Before(end); t = t.Add(chunk) {
+		if t != start && cmd.throttle > 0 {
+			time.Sleep(cmd.throttle)
+		}
+
+		next := t.Add(chunk)
+		if next.After(end) {
+			next = end
+		}
+
+		q := stmt.Clone()
+		if err := q.SetTimeRange(t, next); err != nil {
+			return err
+		}
+
+		resp, err := c.Query(client.NewQuery(q.String(), cmd.database, ""))
+		if err != nil {
+			return err
+		} else if err := resp.Error(); err != nil {
+			return fmt.Errorf("backfill %s to %s: %s", t.UTC().Format(time.RFC3339), next.UTC().Format(time.RFC3339), err)
+		}
+
+		written := pointsWritten(resp)
+		total += written
+		fmt.Fprintf(cmd.Stdout, "%s to %s: wrote %d points\n", t.UTC().Format(time.RFC3339), next.UTC().Format(time.RFC3339), written)
+	}
+
+	fmt.Fprintf(cmd.Stdout, "backfilled %s.%s: wrote %d points\n", cmd.database, cmd.cq, total)
+	return nil
+}
+
+// pointsWritten returns the number of points written by a SELECT INTO.
+func pointsWritten(resp *client.Response) int64 {
+	var n int64
+	for _, r := range resp.Results {
+		for _, row := range r.Series {
+			for _, values := range row.Values {
+				if len(values) < 2 {
+					continue
+				}
+				if v, ok := values[1].(json.Number); ok {
+					i, _ := v.Int64()
+					n += i
+				}
+			}
+		}
+	}
+	return n
+}
+
+// truncate truncates t to a multiple of d since the epoch. Unlike
+// time.Time.Truncate, times before the epoch are truncated downward.
+func truncate(t time.Time, d time.Duration) time.Time {
+	ts := t.UnixNano()
+	offset := ts % int64(d)
+	if offset < 0 {
+		offset += int64(d)
+	}
+	return time.Unix(0, ts-offset).UTC()
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *Command) printUsage() {
+	fmt.Fprintf(cmd.Stdout, `Runs a continuous query over historical data.
+
+The SELECT INTO of the continuous query is run from the start time to the end
+time in chunks, widened to whole GROUP BY time intervals.
+
+Usage: influxd backfill-cq [flags]
+
+    -host <host:port>
+            The HTTP address of the server. Defaults to localhost:8086.
+    -username <name>
+    -password <password>
+            The credentials used to authenticate with the server.
+    -ssl
+            Use https to connect to the server.
+    -unsafeSsl
+            Do not verify the certificate of the server.
+    -database <name>
+            The database of the continuous query. Required.
+    -cq <name>
+            The name of the continuous query. Required.
+    -start <time>
+            The RFC3339 time to start the backfill from. Required.
+    -end <time>
+            The RFC3339 time to end the backfill at. Defaults to now.
+    -chunk <duration>
+            The time range processed by each query. Defaults to 24h.
+    -throttle <duration>
+            The delay between queries. Defaults to 1s.
+
+`)
+}
@@ -155,7 +155,38 @@ func (cmd *Command) backupShard(retentionPolicy string, shardID string, since ti
 	}
 
 	// TODO: verify shard backup data
-	return cmd.downloadAndVerify(req, shardArchivePath, nil)
+	if err := cmd.downloadAndVerify(req, shardArchivePath, nil); err != nil {
+		return err
+	}
+
+	return cmd.updateManifest(retentionPolicy, id, shardArchivePath)
+}
+
+// updateManifest records the timestamp precisions observed in a shard archive
+// in the backup manifest.
+func (cmd *Command) updateManifest(retentionPolicy string, shardID uint64, path string) error {
+	// Nothing was downloaded if the shard had no new data.
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	precisions, err := ScanPrecisions(path)
+	if err != nil {
+		return fmt.Errorf("scan precisions: %s", err)
+	}
+
+	m, err := ReadManifest(cmd.path)
+	if err != nil {
+		return fmt.Errorf("read manifest: %s", err)
+	}
+
+	m.Files[filepath.Base(path)] = &FileManifest{
+		Database:        cmd.database,
+		RetentionPolicy: retentionPolicy,
+		ShardID:         shardID,
+		Precisions:      precisions,
+	}
+	return m.Write(cmd.path)
 }
 
 // backupDatabase will request the database information from the server and then backup the metastore and
@@ -0,0 +1,178 @@
+package backup
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+// Manifestfile is the base name of the backup manifest.
+const Manifestfile = "manifest"
+
+// Precisions lists the timestamp precisions from coarsest to finest.
+var Precisions = []string{"h", "m", "s", "ms", "u", "ns"}
+
+// Manifest describes the shard archives in a backup directory.
+type Manifest struct {
+	// Files is keyed by the base name of each shard archive.
+	Files map[string]*FileManifest `json:"files"`
+}
+
+// FileManifest describes a single shard archive.
+type FileManifest struct {
+	Database        string `json:"database"`
+	RetentionPolicy string `json:"retentionPolicy"`
+	ShardID         uint64 `json:"shardID"`
+
+	// Precisions counts the values in each measurement by the coarsest
+	// precision that represents their timestamp exactly.
+	Precisions map[string]PrecisionCounts `json:"precisions"`
+}
+
+// PrecisionCounts maps a precision, such as "s" or "ns", to a number of values.
+type PrecisionCounts map[string]int64
+
+// ReadManifest reads the manifest in dir. An empty manifest is returned if none exists.
+func ReadManifest(dir string) (*Manifest, error) {
+	m := &Manifest{Files: make(map[string]*FileManifest)}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, Manifestfile))
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, err
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]*FileManifest)
+	}
+	return m, nil
+}
+
+// Write writes the manifest to dir.
+func (m *Manifest) Write(dir string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, Manifestfile)
+	if err := ioutil.WriteFile(path+Suffix, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+Suffix, path)
+}
+
+// Precision returns the coarsest precision that represents the timestamp ts exactly.
+func Precision(ts int64) string {
+	for _, p := range Precisions {
+		if ts%int64(PrecisionDuration(p)) == 0 {
+			return p
+		}
+	}
+	return "ns"
+}
+
+// PrecisionDuration returns the duration of a precision, or zero if it is unknown.
+func PrecisionDuration(precision string) time.Duration {
+	switch precision {
+	case "h":
+		return time.Hour
+	case "m":
+		return time.Minute
+	case "s":
+		return time.Second
+	case "ms":
+		return time.Millisecond
+	case "u":
+		return time.Microsecond
+	case "ns":
+		return time.Nanosecond
+	}
+	return 0
+}
+
+// ScanPrecisions counts the values in each measurement of the TSM files in a
+// shard archive by timestamp precision.
+func ScanPrecisions(path string) (map[string]PrecisionCounts, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := make(map[string]PrecisionCounts)
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return m, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasSuffix(hdr.Name, "."+tsm1.TSMFileExtension) {
+			continue
+		}
+
+		if err := scanTSMPrecisions(tr, m); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// scanTSMPrecisions adds the precisions of the values in the TSM file read from r to m.
+func scanTSMPrecisions(r io.Reader, m map[string]PrecisionCounts) error {
+	// The TSM reader requires a file, so copy the archived file to disk.
+	tmp, err := ioutil.TempFile("", "influxd-backup")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return err
+	}
+
+	tr, err := tsm1.NewTSMReader(tmp)
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+
+	for i := 0; i < tr.KeyCount(); i++ {
+		key, _ := tr.KeyAt(i)
+		values, err := tr.ReadAll(key)
+		if err != nil {
+			return err
+		}
+
+		seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+		name, err := models.ParseName(seriesKey)
+		if err != nil {
+			return err
+		}
+
+		counts := m[string(name)]
+		if counts == nil {
+			counts = make(PrecisionCounts)
+			m[string(name)] = counts
+		}
+		for _, v := range values {
+			counts[Precision(v.UnixNano())]++
+		}
+	}
+	return nil
+}
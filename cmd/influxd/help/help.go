@@ -33,9 +33,13 @@ Usage: influxd [[command] [arguments]]
 
 The commands are:
 
+    backfill-cq          runs a continuous query over historical data
     backup               downloads a snapshot of a data node and saves it to disk
     config               display the default configuration
     help                 display this help message
+    meta-export          exports the meta store as JSON
+    meta-import          imports a meta store export, or shows its changes
+    promote              promotes a replication follower so it accepts writes
     restore              uses a snapshot of a data node to rebuild a cluster
     run                  run node with existing configuration
     version              displays the InfluxDB version
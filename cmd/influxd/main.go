@@ -8,14 +8,20 @@ import (
 	"math/rand"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/influxdata/influxdb/cmd"
+	"github.com/influxdata/influxdb/cmd/influxd/backfill"
 	"github.com/influxdata/influxdb/cmd/influxd/backup"
 	"github.com/influxdata/influxdb/cmd/influxd/help"
+	"github.com/influxdata/influxdb/cmd/influxd/metaexport"
+	"github.com/influxdata/influxdb/cmd/influxd/metaimport"
+	"github.com/influxdata/influxdb/cmd/influxd/promote"
 	"github.com/influxdata/influxdb/cmd/influxd/restore"
 	"github.com/influxdata/influxdb/cmd/influxd/run"
+	"github.com/influxdata/influxdb/services/audit"
 	"github.com/uber-go/zap"
 )
 
@@ -89,23 +95,47 @@ func (m *Main) Run(args ...string) error {
 		if err := cmd.Run(args...); err != nil {
 			return fmt.Errorf("run: %s", err)
 		}
+		m.Logger = cmd.Logger
 
 		signalCh := make(chan os.Signal, 1)
 		signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+		reloadCh := make(chan os.Signal, 1)
+		signal.Notify(reloadCh, syscall.SIGHUP)
 		m.Logger.Info("Listening for signals")
 
-		// Block until one of the signals above is received
-		<-signalCh
+		// Block until one of the signals above is received, reloading the
+		// configuration on SIGHUP.
+	wait:
+		for {
+			select {
+			case <-signalCh:
+				break wait
+			case <-reloadCh:
+				m.Logger.Info("SIGHUP received, reloading configuration")
+				applied, restartRequired, err := cmd.Server.Reload()
+				if cmd.Server.AuditService != nil {
+					cmd.Server.AuditService.AuditAction(audit.ActionConfigReload, "", "", cmd.Server.ConfigPath, err)
+				}
+				if err != nil {
+					m.Logger.Info(fmt.Sprintf("failed to reload configuration: %s", err))
+					continue
+				}
+				m.Logger.Info(fmt.Sprintf("configuration reloaded, applied: [%s], restart required: [%s]",
+					strings.Join(applied, ", "), strings.Join(restartRequired, ", ")))
+			}
+		}
+		signal.Stop(reloadCh)
 		m.Logger.Info("Signal received, initializing clean shutdown...")
 		go cmd.Close()
 
 		// Block again until another signal is received, a shutdown timeout elapses,
-		// or the Command is gracefully closed
+		// or the Command is gracefully closed.  The running queries are given the
+		// drain timeout on top of the shutdown timeout.
 		m.Logger.Info("Waiting for clean shutdown...")
 		select {
 		case <-signalCh:
 			m.Logger.Info("second signal received, initializing hard shutdown")
-		case <-time.After(time.Second * 30):
+		case <-time.After(time.Second*30 + cmd.Server.DrainTimeout()):
 			m.Logger.Info("time limit reached, initializing hard shutdown")
 		case <-cmd.Closed:
 			m.Logger.Info("server shutdown completed")
@@ -113,6 +143,11 @@ func (m *Main) Run(args ...string) error {
 
 		// goodbye.
 
+	case "backfill-cq":
+		name := backfill.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("backfill-cq: %s", err)
+		}
 	case "backup":
 		name := backup.NewCommand()
 		if err := name.Run(args...); err != nil {
@@ -123,6 +158,21 @@ func (m *Main) Run(args ...string) error {
 		if err := name.Run(args...); err != nil {
 			return fmt.Errorf("restore: %s", err)
 		}
+	case "promote":
+		name := promote.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("promote: %s", err)
+		}
+	case "meta-export":
+		name := metaexport.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("meta-export: %s", err)
+		}
+	case "meta-import":
+		name := metaimport.NewCommand()
+		if err := name.Run(args...); err != nil {
+			return fmt.Errorf("meta-import: %s", err)
+		}
 	case "config":
 		if err := run.NewPrintConfigCommand().Run(args...); err != nil {
 			return fmt.Errorf("config: %s", err)
@@ -0,0 +1,98 @@
+// Package metaexport is the meta-export subcommand for the influxd command.
+package metaexport
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// Command represents the program execution for "influxd meta-export".
+type Command struct {
+	// Standard input/output, overridden for testing.
+	Stderr io.Writer
+	Stdout io.Writer
+
+	metadir string
+	out     string
+}
+
+// NewCommand returns a new instance of Command with default settings.
+func NewCommand() *Command {
+	return &Command{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+	}
+}
+
+// Run executes the program.
+func (cmd *Command) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.StringVar(&cmd.metadir, "metadir", "", "")
+	fs.StringVar(&cmd.out, "out", "", "")
+	fs.SetOutput(cmd.Stderr)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 0 {
+		return errors.New("meta-export takes no arguments")
+	} else if cmd.metadir == "" {
+		return errors.New("-metadir is required")
+	}
+
+	// Don't let the client create an empty meta store.
+	if _, err := os.Stat(filepath.Join(cmd.metadir, "meta.db")); err != nil {
+		return fmt.Errorf("no meta store in %s: %s", cmd.metadir, err)
+	}
+
+	c := meta.NewConfig()
+	c.Dir = cmd.metadir
+	client := meta.NewClient(c)
+	if err := client.Open(); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	data := client.Data()
+	b, err := json.MarshalIndent(&data, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if cmd.out == "" {
+		_, err := cmd.Stdout.Write(b)
+		return err
+	}
+	if err := ioutil.WriteFile(cmd.out, b, 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.Stderr, "Exported meta store of %s to %s\n", cmd.metadir, cmd.out)
+	return nil
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *Command) printUsage() {
+	fmt.Fprintf(cmd.Stdout, `Exports the meta store of a server as JSON.  The databases, retention
+policies, shard groups, continuous queries, subscriptions, quotas, users and
+tokens are written in a canonical order so exports can be kept under version
+control and compared, and re-applied with influxd meta-import.  The export
+holds the password hashes of users and the secret hashes of tokens.
+
+Usage: influxd meta-export [flags]
+
+    -metadir <path>
+            Required. The meta directory of the server.
+    -out <path>
+            Optional. The file the export is written to. Defaults to STDOUT.
+
+`)
+}
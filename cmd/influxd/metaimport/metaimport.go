@@ -0,0 +1,261 @@
+// Package metaimport is the meta-import subcommand for the influxd command.
+package metaimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/influxdata/influxdb/services/audit"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// Command represents the program execution for "influxd meta-import".
+type Command struct {
+	// Standard input/output, overridden for testing.
+	Stderr io.Writer
+	Stdout io.Writer
+
+	metadir  string
+	diff     bool
+	auditLog string
+}
+
+// NewCommand returns a new instance of Command with default settings.
+func NewCommand() *Command {
+	return &Command{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+	}
+}
+
+// Run executes the program.
+func (cmd *Command) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.StringVar(&cmd.metadir, "metadir", "", "")
+	fs.BoolVar(&cmd.diff, "diff", false, "")
+	fs.StringVar(&cmd.auditLog, "audit-log", "", "")
+	fs.SetOutput(cmd.Stderr)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return errors.New("meta-import takes the path of an export")
+	} else if cmd.metadir == "" {
+		return errors.New("-metadir is required")
+	}
+	path := fs.Arg(0)
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var data meta.Data
+	if err := json.Unmarshal(b, &data); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	// The diff of a meta store that doesn't exist is against an empty one,
+	// which must not be created.
+	var cur meta.Data
+	if _, err := os.Stat(filepath.Join(cmd.metadir, "meta.db")); err == nil || !cmd.diff {
+		c := meta.NewConfig()
+		c.Dir = cmd.metadir
+		if err := os.MkdirAll(c.Dir, 0777); err != nil {
+			return err
+		}
+		client := meta.NewClient(c)
+		if err := client.Open(); err != nil {
+			return err
+		}
+		defer client.Close()
+		cur = client.Data()
+
+		if !cmd.diff {
+			// Keep the raft term and index moving forward.
+			data.Term, data.Index = cur.Term, cur.Index
+			err := client.SetData(&data)
+			if aerr := cmd.audit(err); aerr != nil {
+				return aerr
+			} else if err != nil {
+				return fmt.Errorf("set data: %s", err)
+			}
+			fmt.Fprintf(cmd.Stdout, "Imported %s into the meta store of %s\n", path, cmd.metadir)
+			return nil
+		}
+	}
+
+	n, err := diffData(cmd.Stdout, &cur, &data)
+	if err != nil {
+		return err
+	} else if n == 0 {
+		fmt.Fprintln(cmd.Stdout, "No changes.")
+	}
+	return nil
+}
+
+// audit records the import into the meta store in the audit log, if one was
+// given.
+func (cmd *Command) audit(err error) error {
+	if cmd.auditLog == "" {
+		return nil
+	}
+	e := audit.Entry{Action: audit.ActionMetaImport, Target: cmd.metadir}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if err := audit.Append(cmd.auditLog, e); err != nil {
+		return fmt.Errorf("audit: %s", err)
+	}
+	return nil
+}
+
+// diffData writes the changes importing b into a meta store holding a would
+// make, and returns their number.
+func diffData(w io.Writer, a, b *meta.Data) (int, error) {
+	var values [2]interface{}
+	for i, data := range []*meta.Data{a, b} {
+		buf, err := json.Marshal(data)
+		if err != nil {
+			return 0, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(buf))
+		dec.UseNumber()
+		if err := dec.Decode(&values[i]); err != nil {
+			return 0, err
+		}
+	}
+	return diff(w, "", values[0], values[1]), nil
+}
+
+// diff writes the differences between the decoded JSON values a and b at path
+// to w, one per line, and returns their number.  Lines are prefixed by + for
+// added values, - for removed values and ~ for changed values.  Objects are
+// compared field by field, and lists of objects with a name or an id element
+// by element.
+func diff(w io.Writer, path string, a, b interface{}) int {
+	if a == nil && b == nil {
+		return 0
+	} else if a == nil {
+		fmt.Fprintf(w, "+ %s: %s\n", path, encode(b))
+		return 1
+	} else if b == nil {
+		fmt.Fprintf(w, "- %s: %s\n", path, encode(a))
+		return 1
+	}
+
+	switch a := a.(type) {
+	case map[string]interface{}:
+		if b, ok := b.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(a)+len(b))
+			for k := range a {
+				keys = append(keys, k)
+			}
+			for k := range b {
+				if _, ok := a[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+
+			var n int
+			for _, k := range keys {
+				p := k
+				if path != "" {
+					p = path + "." + k
+				}
+				n += diff(w, p, a[k], b[k])
+			}
+			return n
+		}
+	case []interface{}:
+		if b, ok := b.([]interface{}); ok {
+			ka, oka := keyed(a)
+			kb, okb := keyed(b)
+			if oka && okb {
+				keys := make([]string, 0, len(ka)+len(kb))
+				for k := range ka {
+					keys = append(keys, k)
+				}
+				for k := range kb {
+					if _, ok := ka[k]; !ok {
+						keys = append(keys, k)
+					}
+				}
+				sort.Strings(keys)
+
+				var n int
+				for _, k := range keys {
+					n += diff(w, path+"["+k+"]", ka[k], kb[k])
+				}
+				return n
+			}
+		}
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return 0
+	}
+	fmt.Fprintf(w, "~ %s: %s -> %s\n", path, encode(a), encode(b))
+	return 1
+}
+
+// keyed returns the objects of a by their name or id, and false if a holds
+// anything else.
+func keyed(a []interface{}) (map[string]interface{}, bool) {
+	m := make(map[string]interface{}, len(a))
+	for _, v := range a {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		var key string
+		if name, ok := obj["name"].(string); ok {
+			key = name
+		} else if id, ok := obj["id"]; ok {
+			key = fmt.Sprint(id)
+		} else {
+			return nil, false
+		}
+		m[key] = obj
+	}
+	return m, true
+}
+
+// encode returns the compact JSON encoding of v.
+func encode(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *Command) printUsage() {
+	fmt.Fprintf(cmd.Stdout, `Imports a JSON export written by influxd meta-export, replacing all of the
+databases, retention policies, shard groups, continuous queries, subscriptions,
+quotas, users and tokens of a meta store.  The server must be stopped.  Shard
+groups imported from another server must be restored with their data.
+
+Usage: influxd meta-import [flags] PATH
+
+    -metadir <path>
+            Required. The meta directory of the server.
+    -diff
+            Optional. Prints the changes the import would make instead of
+            importing. Lines starting with + are added, - removed and ~ changed.
+    -audit-log <path>
+            Optional. If given, the import is recorded in the audit log at the
+            given path, which should be the path of the [audit] section of the
+            configuration.
+
+`)
+}
@@ -0,0 +1,62 @@
+// Package promote is the promote subcommand for the influxd command.
+package promote
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/influxdata/influxdb/services/replication"
+)
+
+// Command represents the program execution for "influxd promote".
+type Command struct {
+	// Standard input/output, overridden for testing.
+	Stderr io.Writer
+	Stdout io.Writer
+
+	host string
+}
+
+// NewCommand returns a new instance of Command with default settings.
+func NewCommand() *Command {
+	return &Command{
+		Stderr: os.Stderr,
+		Stdout: os.Stdout,
+	}
+}
+
+// Run executes the program.
+func (cmd *Command) Run(args ...string) error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.StringVar(&cmd.host, "host", "localhost:8088", "")
+	fs.SetOutput(cmd.Stderr)
+	fs.Usage = cmd.printUsage
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 0 {
+		return errors.New("promote takes no arguments")
+	}
+
+	if err := replication.NewClient(cmd.host).Promote(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.Stdout, "%s promoted, now accepting writes\n", cmd.host)
+	return nil
+}
+
+// printUsage prints the usage message to STDERR.
+func (cmd *Command) printUsage() {
+	fmt.Fprintf(cmd.Stdout, `Promotes a replication follower so that it stops replicating and accepts writes.
+
+Usage: influxd promote [flags]
+
+    -host <host:port>
+            The bind address of the follower. Defaults to localhost:8088.
+
+`)
+}
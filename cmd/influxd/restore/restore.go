@@ -12,13 +12,22 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/influxdata/influxdb/cmd/influxd/backup"
+	"github.com/influxdata/influxdb/pkg/scrub"
+	"github.com/influxdata/influxdb/services/audit"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/services/snapshotter"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
 )
 
+// tombstoneFileExtension is the extension of TSM tombstone files.
+const tombstoneFileExtension = "tombstone"
+
 // Command represents the program execution for "influxd restore".
 type Command struct {
 	Stdout io.Writer
@@ -30,6 +39,19 @@ type Command struct {
 	database        string
 	retention       string
 	shard           string
+	precision       string
+	scrub           *scrub.Rules
+	auditLog        string
+
+	// staged holds the restore paths of tombstone files waiting for the
+	// TSM file they belong to.
+	staged map[string]struct{}
+
+	// stagingDirs holds the directories files are staged in while decoding.
+	stagingDirs map[string]struct{}
+
+	// skippedIndexes holds the shard directories whose index was not restored.
+	skippedIndexes map[string]struct{}
 
 	// TODO: when the new meta stuff is done this should not be exported or be gone
 	MetaConfig *meta.Config
@@ -50,6 +72,21 @@ func (cmd *Command) Run(args ...string) error {
 		return err
 	}
 
+	err := cmd.restore()
+	if cmd.auditLog != "" {
+		e := audit.Entry{Action: audit.ActionRestore, Target: cmd.auditTarget()}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		if aerr := audit.Append(cmd.auditLog, e); aerr != nil && err == nil {
+			err = fmt.Errorf("audit: %s", aerr)
+		}
+	}
+	return err
+}
+
+// restore restores the metastore and data selected by the flags.
+func (cmd *Command) restore() error {
 	if cmd.metadir != "" {
 		if err := cmd.unpackMeta(); err != nil {
 			return err
@@ -66,6 +103,22 @@ func (cmd *Command) Run(args ...string) error {
 	return nil
 }
 
+// auditTarget returns what the flags restore, for the audit log.
+func (cmd *Command) auditTarget() string {
+	var targets []string
+	if cmd.metadir != "" {
+		targets = append(targets, "meta")
+	}
+	if cmd.shard != "" {
+		targets = append(targets, fmt.Sprintf("shard %s.%s.%s", cmd.database, cmd.retention, cmd.shard))
+	} else if cmd.retention != "" {
+		targets = append(targets, fmt.Sprintf("retention policy %s.%s", cmd.database, cmd.retention))
+	} else if cmd.datadir != "" {
+		targets = append(targets, fmt.Sprintf("database %s", cmd.database))
+	}
+	return strings.Join(targets, ", ")
+}
+
 // parseFlags parses and validates the command line arguments.
 func (cmd *Command) parseFlags(args []string) error {
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
@@ -74,6 +127,11 @@ func (cmd *Command) parseFlags(args []string) error {
 	fs.StringVar(&cmd.database, "database", "", "")
 	fs.StringVar(&cmd.retention, "retention", "", "")
 	fs.StringVar(&cmd.shard, "shard", "", "")
+	fs.StringVar(&cmd.precision, "precision", "", "")
+	cmd.scrub = scrub.NewRules()
+	fs.Var(cmd.scrub, "scrub", "")
+	fs.StringVar(&cmd.scrub.Salt, "scrub-salt", "", "")
+	fs.StringVar(&cmd.auditLog, "audit-log", "", "")
 	fs.SetOutput(cmd.Stdout)
 	fs.Usage = cmd.printUsage
 	if err := fs.Parse(args); err != nil {
@@ -109,9 +167,23 @@ func (cmd *Command) parseFlags(args []string) error {
 		return fmt.Errorf("-database is required to restore retention policy")
 	}
 
+	if cmd.precision != "" && backup.PrecisionDuration(cmd.precision) == 0 {
+		return fmt.Errorf("invalid precision %q: must be one of %s", cmd.precision, strings.Join(backup.Precisions, ", "))
+	}
+
+	if !cmd.scrub.Empty() && cmd.database == "" {
+		return fmt.Errorf("-database is required to scrub restored data")
+	}
+
 	return nil
 }
 
+// decode returns true if TSM files are decoded and rewritten when restored,
+// rather than copied.
+func (cmd *Command) decode() bool {
+	return cmd.precision != "" || !cmd.scrub.Empty()
+}
+
 // unpackMeta reads the metadata from the backup directory and initializes a raft
 // cluster and replaces the root metadata.
 func (cmd *Command) unpackMeta() error {
@@ -261,7 +333,7 @@ func (cmd *Command) unpackRetention() error {
 
 // unpackFiles will look for backup files matching the pattern and restore them to the data dir
 func (cmd *Command) unpackFiles(pat string) error {
-	fmt.Printf("Restoring from backup %s\n", pat)
+	fmt.Fprintf(cmd.Stdout, "Restoring from backup %s\n", pat)
 
 	backupFiles, err := filepath.Glob(pat)
 	if err != nil {
@@ -272,13 +344,22 @@ func (cmd *Command) unpackFiles(pat string) error {
 		return fmt.Errorf("no backup files for %s in %s", pat, cmd.backupFilesPath)
 	}
 
+	if cmd.precision != "" {
+		if err := cmd.printTruncated(backupFiles); err != nil {
+			return err
+		}
+	}
+
+	cmd.staged = make(map[string]struct{})
+	cmd.stagingDirs = make(map[string]struct{})
+	cmd.skippedIndexes = make(map[string]struct{})
 	for _, fn := range backupFiles {
 		if err := cmd.unpackTar(fn); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return cmd.unpackStagedTombstones()
 }
 
 // unpackTar will restore a single tar archive to the data dir
@@ -305,16 +386,57 @@ func (cmd *Command) unpackTar(tarFile string) error {
 	}
 }
 
+// printTruncated prints the number of values in the backup files, according
+// to the backup manifest, with timestamps finer than the restore precision.
+func (cmd *Command) printTruncated(backupFiles []string) error {
+	m, err := backup.ReadManifest(cmd.backupFilesPath)
+	if err != nil {
+		return err
+	}
+
+	d := backup.PrecisionDuration(cmd.precision)
+	for _, fn := range backupFiles {
+		fm := m.Files[filepath.Base(fn)]
+		if fm == nil {
+			fmt.Fprintf(cmd.Stdout, "no precision statistics for %s in manifest\n", fn)
+			continue
+		}
+
+		for name, counts := range fm.Precisions {
+			var n int64
+			for p, count := range counts {
+				if backup.PrecisionDuration(p) < d {
+					n += count
+				}
+			}
+			if n > 0 {
+				fmt.Fprintf(cmd.Stdout, "truncating %d values in measurement %q of %s to precision %s\n", n, name, fn, cmd.precision)
+			}
+		}
+	}
+	return nil
+}
+
 // unpackFile will copy the current file from the tar archive to the data dir
 func (cmd *Command) unpackFile(tr *tar.Reader, fileName string) error {
 	nativeFileName := filepath.FromSlash(fileName)
 	fn := filepath.Join(cmd.datadir, nativeFileName)
-	fmt.Printf("unpacking %s\n", fn)
+	fmt.Fprintf(cmd.Stdout, "unpacking %s\n", fn)
 
 	if err := os.MkdirAll(filepath.Dir(fn), 0777); err != nil {
 		return fmt.Errorf("error making restore dir: %s", err.Error())
 	}
 
+	if cmd.decode() {
+		if !cmd.scrub.Empty() && isIndexFile(fileName) {
+			return cmd.skipIndexFile(fn)
+		} else if strings.HasSuffix(fn, "."+tombstoneFileExtension) {
+			return cmd.stageTombstone(tr, fn)
+		} else if strings.HasSuffix(fn, "."+tsm1.TSMFileExtension) {
+			return cmd.unpackTSMFile(tr, fn)
+		}
+	}
+
 	ff, err := os.Create(fn)
 	if err != nil {
 		return err
@@ -328,6 +450,219 @@ func (cmd *Command) unpackFile(tr *tar.Reader, fileName string) error {
 	return nil
 }
 
+// isIndexFile returns true if the archived file, named <db>/<rp>/<id>/..., is
+// part of a shard's TSI index.
+func isIndexFile(fileName string) bool {
+	parts := strings.Split(fileName, "/")
+	return len(parts) > 4 && parts[3] == "index"
+}
+
+// skipIndexFile skips restoring an index file when scrubbing, as the index
+// holds the original tag values.
+func (cmd *Command) skipIndexFile(fn string) error {
+	shardDir := fn
+	for filepath.Base(shardDir) != "index" {
+		shardDir = filepath.Dir(shardDir)
+	}
+	shardDir = filepath.Dir(shardDir)
+
+	if _, ok := cmd.skippedIndexes[shardDir]; !ok {
+		cmd.skippedIndexes[shardDir] = struct{}{}
+		fmt.Fprintf(cmd.Stdout, "skipping tsi1 index of %s, rebuild it with: influx_inspect buildindex -shard %s\n", shardDir, shardDir)
+	}
+	return nil
+}
+
+// stagingPath returns the path a file is staged at before the file restored
+// to fn is written.
+func stagingPath(fn string) string {
+	return filepath.Join(filepath.Dir(fn), ".restore", filepath.Base(fn))
+}
+
+// createStaged creates the staging file for fn.
+func (cmd *Command) createStaged(fn string) (*os.File, error) {
+	path := stagingPath(fn)
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return nil, err
+	}
+	cmd.stagingDirs[filepath.Dir(path)] = struct{}{}
+	return os.Create(path)
+}
+
+// stageTombstone copies a tombstone file from the archive to the staging
+// directory, so that it is applied when its TSM file is decoded.
+func (cmd *Command) stageTombstone(r io.Reader, fn string) error {
+	f, err := cmd.createStaged(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return err
+	}
+	cmd.staged[fn] = struct{}{}
+	return f.Close()
+}
+
+// unpackStagedTombstones restores tombstone files whose TSM file was not in
+// the backup, as in incremental backups, and removes the staging directories.
+func (cmd *Command) unpackStagedTombstones() error {
+	for fn := range cmd.staged {
+		path := stagingPath(fn)
+		if cmd.scrub.Empty() {
+			if err := os.Rename(path, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Rewrite the tombstoned keys so they match the scrubbed TSM files,
+		// adding keys deleted over the same time range together.
+		src := tsm1.Tombstoner{Path: path}
+		dst := tsm1.Tombstoner{Path: fn}
+		var prev tsm1.Tombstone
+		var keys [][]byte
+		if err := src.Walk(func(t tsm1.Tombstone) error {
+			if len(keys) > 0 && (t.Min != prev.Min || t.Max != prev.Max) {
+				if err := dst.AddRange(keys, prev.Min, prev.Max); err != nil {
+					return err
+				}
+				keys = nil
+			}
+			keys = append(keys, cmd.restoredKey(t.Key))
+			prev = t
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := dst.AddRange(keys, prev.Min, prev.Max); err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	for dir := range cmd.stagingDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoredKey returns the TSM key that key is restored as.
+func (cmd *Command) restoredKey(key []byte) []byte {
+	if cmd.scrub.Empty() {
+		return key
+	}
+	seriesKey, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+	return tsm1.SeriesFieldKeyBytes(string(cmd.scrub.SeriesKey(seriesKey)), string(field))
+}
+
+// unpackTSMFile decodes the TSM file read from r, applying any staged
+// tombstones, and writes it to fn with all timestamps truncated to the restore
+// precision and all scrubbed tag and field values replaced. When truncation
+// causes several values of a series to share a timestamp, or scrubbing maps
+// several series to one, the last value is kept.
+func (cmd *Command) unpackTSMFile(r io.Reader, fn string) error {
+	// The TSM reader requires a file, so copy the archived file to disk first.
+	tmp, err := cmd.createStaged(fn)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return err
+	}
+
+	tr, err := tsm1.NewTSMReader(tmp)
+	if err != nil {
+		return err
+	}
+	defer tr.Close()
+
+	// The tombstones have been applied to the decoded values.
+	tombstone := strings.TrimSuffix(fn, "."+tsm1.TSMFileExtension) + "." + tombstoneFileExtension
+	if _, ok := cmd.staged[tombstone]; ok {
+		delete(cmd.staged, tombstone)
+		defer os.Remove(stagingPath(tombstone))
+	}
+
+	// Scrubbing may restore several keys as one, so group the keys by the
+	// key they are restored as and write the groups in order.
+	groups := make(map[string][][]byte)
+	for i := 0; i < tr.KeyCount(); i++ {
+		key, _ := tr.KeyAt(i)
+		k := string(cmd.restoredKey(key))
+		groups[k] = append(groups[k], key)
+	}
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		return err
+	}
+
+	d := int64(backup.PrecisionDuration(cmd.precision))
+	for _, key := range keys {
+		_, field := tsm1.SeriesAndFieldFromCompositeKey([]byte(key))
+
+		var restored tsm1.Values
+		for _, k := range groups[key] {
+			values, err := tr.ReadAll(k)
+			if err != nil {
+				return err
+			}
+
+			for _, v := range values {
+				t := v.UnixNano()
+				if d > 0 {
+					t -= t % d
+					if t > v.UnixNano() {
+						t -= d
+					}
+				}
+				restored = append(restored, tsm1.NewValue(t, cmd.scrub.FieldValue(string(field), v.Value())))
+			}
+		}
+		restored = restored.Deduplicate()
+
+		for len(restored) > 0 {
+			n := len(restored)
+			if n > tsdb.DefaultMaxPointsPerBlock {
+				n = tsdb.DefaultMaxPointsPerBlock
+			}
+			if err := w.Write([]byte(key), restored[:n]); err != nil {
+				return err
+			}
+			restored = restored[n:]
+		}
+	}
+
+	if err := w.WriteIndex(); err == tsm1.ErrNoValues {
+		// The file contained only deleted data.
+		w.Close()
+		return os.Remove(fn)
+	} else if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
 // printUsage prints the usage message to STDERR.
 func (cmd *Command) printUsage() {
 	fmt.Fprintf(cmd.Stdout, `Uses backups from the PATH to restore the metastore, databases,
@@ -350,6 +685,23 @@ Usage: influxd restore [flags] PATH
     -shard <id>
             Optional. If given, database and retention are required. Will restore the shard's
             TSM files.
+    -precision <h|m|s|ms|u>
+            Optional. If given, the timestamps of the restored TSM files are decoded
+            and truncated to the given precision. Values whose truncated timestamps
+            collide are deduplicated, keeping the last value.
+    -scrub <tag|field>:<key>=<hash|redact>
+            Optional. May be repeated. If given, the restored TSM files are decoded
+            and the values of the tag key, or the string values of the field key,
+            are replaced by a hash of the value or by "redacted". Series that
+            become identical are merged. TSI index files are not restored and
+            must be rebuilt with "influx_inspect buildindex".
+    -scrub-salt <salt>
+            Optional. The secret used to hash scrubbed values. Without a salt,
+            hashes of known identifiers can be recovered by brute force.
+    -audit-log <path>
+            Optional. If given, the restore is recorded in the audit log at the
+            given path, which should be the path of the [audit] section of the
+            configuration.
 
 `)
 }
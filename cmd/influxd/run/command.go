@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -85,7 +84,8 @@ func (cmd *Command) Run(args ...string) error {
 	cmd.pidfile = options.PIDFile
 
 	// Parse config
-	config, err := cmd.ParseConfig(options.GetConfigPath())
+	configPath := options.GetConfigPath()
+	config, err := cmd.ParseConfig(configPath)
 	if err != nil {
 		return fmt.Errorf("parse config: %s", err)
 	}
@@ -117,9 +117,11 @@ func (cmd *Command) Run(args ...string) error {
 	if err != nil {
 		return fmt.Errorf("create server: %s", err)
 	}
-	s.Logger = cmd.Logger
+	// Log with the configured format and levels from now on.
+	cmd.Logger = s.Logger
 	s.CPUProfile = options.CPUProfile
 	s.MemProfile = options.MemProfile
+	s.ConfigPath = configPath
 	if err := s.Open(); err != nil {
 		return fmt.Errorf("open server: %s", err)
 	}
@@ -143,11 +145,10 @@ func (cmd *Command) Close() error {
 }
 
 func (cmd *Command) monitorServerErrors() {
-	logger := log.New(cmd.Stderr, "", log.LstdFlags)
 	for {
 		select {
 		case err := <-cmd.Server.Err():
-			logger.Println(err)
+			cmd.Logger.Error("server error", zap.Error(err))
 		case <-cmd.closing:
 			return
 		}
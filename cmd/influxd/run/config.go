@@ -16,19 +16,30 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/monitor"
 	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/services/audit"
 	"github.com/influxdata/influxdb/services/collectd"
 	"github.com/influxdata/influxdb/services/continuous_querier"
+	"github.com/influxdata/influxdb/services/forwarder"
 	"github.com/influxdata/influxdb/services/graphite"
 	"github.com/influxdata/influxdb/services/httpd"
+	"github.com/influxdata/influxdb/services/kafka_consumer"
+	"github.com/influxdata/influxdb/services/ldap"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/services/nats"
 	"github.com/influxdata/influxdb/services/opentsdb"
 	"github.com/influxdata/influxdb/services/precreator"
+	"github.com/influxdata/influxdb/services/quota"
+	"github.com/influxdata/influxdb/services/replication"
 	"github.com/influxdata/influxdb/services/retention"
+	"github.com/influxdata/influxdb/services/slowquery"
 	"github.com/influxdata/influxdb/services/storage"
 	"github.com/influxdata/influxdb/services/subscriber"
+	"github.com/influxdata/influxdb/services/udf"
 	"github.com/influxdata/influxdb/services/udp"
+	"github.com/influxdata/influxdb/services/zipkin"
 	"github.com/influxdata/influxdb/tsdb"
 )
 
@@ -43,17 +54,29 @@ type Config struct {
 	Data        tsdb.Config        `toml:"data"`
 	Coordinator coordinator.Config `toml:"coordinator"`
 	Retention   retention.Config   `toml:"retention"`
+	Quota       quota.Config       `toml:"quota"`
 	Precreator  precreator.Config  `toml:"shard-precreation"`
+	Replication replication.Config `toml:"replication"`
+	Logging     logger.Config      `toml:"logging"`
 
 	Monitor        monitor.Config    `toml:"monitor"`
 	Subscriber     subscriber.Config `toml:"subscriber"`
+	Audit          audit.Config      `toml:"audit"`
+	SlowQueryLog   slowquery.Config  `toml:"slow-query-log"`
+	Zipkin         zipkin.Config     `toml:"zipkin"`
+	LDAP           ldap.Config       `toml:"ldap"`
+	Forwarder      forwarder.Config  `toml:"forwarder"`
 	HTTPD          httpd.Config      `toml:"http"`
 	Storage        storage.Config    `toml:"storage"`
+	UDF            udf.Config        `toml:"udf"`
 	GraphiteInputs []graphite.Config `toml:"graphite"`
 	CollectdInputs []collectd.Config `toml:"collectd"`
 	OpenTSDBInputs []opentsdb.Config `toml:"opentsdb"`
 	UDPInputs      []udp.Config      `toml:"udp"`
 
+	KafkaConsumerInputs []kafka_consumer.Config `toml:"kafka_consumer"`
+	NATSInputs          []nats.Config           `toml:"nats"`
+
 	ContinuousQuery continuous_querier.Config `toml:"continuous_queries"`
 
 	// Server reporting
@@ -70,19 +93,30 @@ func NewConfig() *Config {
 	c.Data = tsdb.NewConfig()
 	c.Coordinator = coordinator.NewConfig()
 	c.Precreator = precreator.NewConfig()
+	c.Replication = replication.NewConfig()
+	c.Logging = logger.NewConfig()
 
 	c.Monitor = monitor.NewConfig()
 	c.Subscriber = subscriber.NewConfig()
+	c.Audit = audit.NewConfig()
+	c.SlowQueryLog = slowquery.NewConfig()
+	c.Zipkin = zipkin.NewConfig()
+	c.LDAP = ldap.NewConfig()
+	c.Forwarder = forwarder.NewConfig()
 	c.HTTPD = httpd.NewConfig()
 	c.Storage = storage.NewConfig()
+	c.UDF = udf.NewConfig()
 
 	c.GraphiteInputs = []graphite.Config{graphite.NewConfig()}
 	c.CollectdInputs = []collectd.Config{collectd.NewConfig()}
 	c.OpenTSDBInputs = []opentsdb.Config{opentsdb.NewConfig()}
 	c.UDPInputs = []udp.Config{udp.NewConfig()}
+	c.KafkaConsumerInputs = []kafka_consumer.Config{kafka_consumer.NewConfig()}
+	c.NATSInputs = []nats.Config{nats.NewConfig()}
 
 	c.ContinuousQuery = continuous_querier.NewConfig()
 	c.Retention = retention.NewConfig()
+	c.Quota = quota.NewConfig()
 	c.BindAddress = DefaultBindAddress
 
 	return c
@@ -106,6 +140,8 @@ func NewDemoConfig() (*Config, error) {
 	c.Meta.Dir = filepath.Join(homeDir, ".influxdb/meta")
 	c.Data.Dir = filepath.Join(homeDir, ".influxdb/data")
 	c.Data.WALDir = filepath.Join(homeDir, ".influxdb/wal")
+	c.Forwarder.Dir = filepath.Join(homeDir, ".influxdb/forwarder")
+	c.Subscriber.QueueDir = filepath.Join(homeDir, ".influxdb/subscriber")
 
 	return c, nil
 }
@@ -129,17 +165,21 @@ func (c *Config) FromTomlFile(fpath string) error {
 
 // FromToml loads the config from TOML.
 func (c *Config) FromToml(input string) error {
+	_, err := toml.Decode(replaceDeprecated(input), c)
+	return err
+}
+
+// replaceDeprecated replaces the deprecated sections of the TOML with the
+// sections replacing them.
+func replaceDeprecated(input string) string {
 	// Replace deprecated [cluster] with [coordinator]
 	re := regexp.MustCompile(`(?m)^\s*\[cluster\]`)
-	input = re.ReplaceAllStringFunc(input, func(in string) string {
+	return re.ReplaceAllStringFunc(input, func(in string) string {
 		in = strings.TrimSpace(in)
 		out := "[coordinator]"
 		log.Printf("deprecated config option %s replaced with %s; %s will not be supported in a future release\n", in, out, in)
 		return out
 	})
-
-	_, err := toml.Decode(input, c)
-	return err
 }
 
 // Validate returns an error if the config is invalid.
@@ -156,6 +196,10 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if err := c.Coordinator.Validate(); err != nil {
+		return err
+	}
+
 	if err := c.ContinuousQuery.Validate(); err != nil {
 		return err
 	}
@@ -164,14 +208,54 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if err := c.Quota.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.HTTPD.Validate(); err != nil {
+		return err
+	}
+
 	if err := c.Precreator.Validate(); err != nil {
 		return err
 	}
 
+	if err := c.Replication.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Logging.Validate(); err != nil {
+		return err
+	}
+
 	if err := c.Subscriber.Validate(); err != nil {
 		return err
 	}
 
+	if err := c.Audit.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.SlowQueryLog.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Zipkin.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.LDAP.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Forwarder.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.UDF.Validate(); err != nil {
+		return err
+	}
+
 	for _, graphite := range c.GraphiteInputs {
 		if err := graphite.Validate(); err != nil {
 			return fmt.Errorf("invalid graphite config: %v", err)
@@ -184,6 +268,22 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if err := udp.Configs(c.UDPInputs).Validate(); err != nil {
+		return fmt.Errorf("invalid udp config: %v", err)
+	}
+
+	for _, kafka := range c.KafkaConsumerInputs {
+		if err := kafka.Validate(); err != nil {
+			return fmt.Errorf("invalid kafka_consumer config: %v", err)
+		}
+	}
+
+	for _, nats := range c.NATSInputs {
+		if err := nats.Validate(); err != nil {
+			return fmt.Errorf("invalid nats config: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -328,11 +428,20 @@ func (c *Config) diagnosticsClients() map[string]diagnostics.Client {
 		"config-meta":        c.Meta,
 		"config-coordinator": c.Coordinator,
 		"config-retention":   c.Retention,
+		"config-quota":       c.Quota,
 		"config-precreator":  c.Precreator,
-
-		"config-monitor":    c.Monitor,
-		"config-subscriber": c.Subscriber,
-		"config-httpd":      c.HTTPD,
+		"config-replication": c.Replication,
+		"config-logging":     c.Logging,
+
+		"config-monitor":        c.Monitor,
+		"config-subscriber":     c.Subscriber,
+		"config-audit":          c.Audit,
+		"config-slow-query-log": c.SlowQueryLog,
+		"config-zipkin":         c.Zipkin,
+		"config-ldap":           c.LDAP,
+		"config-forwarder":      c.Forwarder,
+		"config-httpd":          c.HTTPD,
+		"config-udf":            c.UDF,
 
 		"config-cqs": c.ContinuousQuery,
 	}
@@ -350,6 +459,12 @@ func (c *Config) diagnosticsClients() map[string]diagnostics.Client {
 	if u := udp.Configs(c.UDPInputs); u.Enabled() {
 		m["config-udp"] = u
 	}
+	if k := kafka_consumer.Configs(c.KafkaConsumerInputs); k.Enabled() {
+		m["config-kafka_consumer"] = k
+	}
+	if n := nats.Configs(c.NATSInputs); n.Enabled() {
+		m["config-nats"] = n
+	}
 
 	return m
 }
@@ -0,0 +1,413 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ConfigError is a problem of a configuration file found by CheckConfig.
+type ConfigError struct {
+	// Line is the line of the file the problem is on, or 0 if it is not
+	// about a line of the file.
+	Line int
+
+	// Setting is the name of the setting the problem is about, such as
+	// "graphite[1].bind-address", if any.
+	Setting string
+
+	Err error
+}
+
+// Error returns the line, setting and description of the problem.
+func (e *ConfigError) Error() string {
+	var parts []string
+	if e.Line > 0 {
+		parts = append(parts, fmt.Sprintf("line %d", e.Line))
+	}
+	if e.Setting != "" {
+		parts = append(parts, e.Setting)
+	}
+	return strings.Join(append(parts, e.Err.Error()), ": ")
+}
+
+// CheckConfigFile reads the configuration file at path and checks it with
+// CheckConfig.
+func CheckConfigFile(path string, getenv func(string) string) ([]*ConfigError, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	_, errs := CheckConfig(string(trimBOM(bs)), getenv)
+	return errs, nil
+}
+
+// CheckConfig parses the configuration in input as the server would, with
+// the environment overrides of getenv applied, and returns it along with the
+// problems found: syntax errors, unknown settings, values of the wrong type,
+// invalid settings, listeners bound to the same address and directories the
+// server cannot write to.
+func CheckConfig(input string, getenv func(string) string) (*Config, []*ConfigError) {
+	input = replaceDeprecated(input)
+
+	// Decode each setting on its own first, to find the settings that
+	// cannot be decoded.
+	if errs := decodeSettings(input); len(errs) > 0 {
+		return nil, sortErrors(errs)
+	}
+
+	c := NewConfig()
+	if _, err := toml.Decode(input, c); err != nil {
+		return nil, []*ConfigError{{Line: errorLine(err), Err: err}}
+	} else if err := c.ApplyEnvOverrides(getenv); err != nil {
+		return nil, []*ConfigError{{Err: err}}
+	}
+
+	errs := append(validateSections(input, c), checkListeners(input, c)...)
+	if len(errs) == 0 {
+		// Catch anything else the server would refuse to start with.
+		if err := c.Validate(); err != nil {
+			errs = append(errs, &ConfigError{Err: err})
+		}
+	}
+	errs = append(errs, checkPaths(input, c)...)
+	return c, sortErrors(errs)
+}
+
+// sortErrors sorts the errors by line.
+func sortErrors(errs []*ConfigError) []*ConfigError {
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+	return errs
+}
+
+// decodeSettings decodes each setting of input into a new config, returning
+// the syntax errors of input, or the settings that are unknown or cannot be
+// decoded.
+func decodeSettings(input string) []*ConfigError {
+	var sections map[string]toml.Primitive
+	md, err := toml.Decode(input, &sections)
+	if err != nil {
+		return []*ConfigError{{Line: errorLine(err), Err: err}}
+	}
+
+	var errs []*ConfigError
+	decode := func(st setting, prim toml.Primitive, v reflect.Value) {
+		if err := md.PrimitiveDecode(prim, v.Addr().Interface()); err != nil {
+			errs = append(errs, &ConfigError{Line: st.line(input), Setting: st.String(), Err: err})
+		}
+	}
+	decodeSection := func(section string, index int, prim toml.Primitive, v reflect.Value) {
+		var keys map[string]toml.Primitive
+		if err := md.PrimitiveDecode(prim, &keys); err != nil {
+			st := setting{section: section, index: index}
+			errs = append(errs, &ConfigError{Line: st.line(input), Setting: st.String(), Err: err})
+			return
+		}
+		for key, prim := range keys {
+			st := setting{section: section, index: index, key: key}
+			if f, ok := lookupField(v, key); !ok {
+				errs = append(errs, &ConfigError{Line: st.line(input), Setting: st.String(), Err: errors.New("unknown setting")})
+			} else {
+				decode(st, prim, f)
+			}
+		}
+	}
+
+	v := reflect.ValueOf(NewConfig()).Elem()
+	for name, prim := range sections {
+		f, ok := lookupField(v, name)
+		if !ok {
+			line := setting{section: name, index: -1}.line(input)
+			if line == 0 {
+				line = setting{index: -1, key: name}.line(input)
+			}
+			errs = append(errs, &ConfigError{Line: line, Setting: name, Err: errors.New("unknown setting")})
+			continue
+		}
+
+		f = reflect.Indirect(f)
+		switch {
+		case f.Kind() == reflect.Struct:
+			decodeSection(name, -1, prim, f)
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Struct:
+			var tables []toml.Primitive
+			if err := md.PrimitiveDecode(prim, &tables); err != nil {
+				st := setting{section: name, index: -1}
+				errs = append(errs, &ConfigError{Line: st.line(input), Setting: name, Err: err})
+				continue
+			}
+			for i, table := range tables {
+				decodeSection(name, i, table, reflect.New(f.Type().Elem()).Elem())
+			}
+		default:
+			decode(setting{index: -1, key: name}, prim, f)
+		}
+	}
+	return errs
+}
+
+// validateSections validates each section of the config, and each input of
+// the sections with inputs, returning the errors at the line of the section.
+func validateSections(input string, c *Config) []*ConfigError {
+	type validator interface {
+		Validate() error
+	}
+
+	var errs []*ConfigError
+	validate := func(st setting, v reflect.Value) {
+		if val, ok := v.Addr().Interface().(validator); ok {
+			if err := val.Validate(); err != nil {
+				errs = append(errs, &ConfigError{Line: st.line(input), Setting: st.String(), Err: err})
+			}
+		}
+	}
+
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, ok := tomlName(v.Type().Field(i))
+		if !ok {
+			continue
+		}
+
+		f := reflect.Indirect(v.Field(i))
+		switch {
+		case f.Kind() == reflect.Struct:
+			validate(setting{section: name, index: -1}, f)
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Struct:
+			for j := 0; j < f.Len(); j++ {
+				validate(setting{section: name, index: j}, f.Index(j))
+			}
+		}
+	}
+	return errs
+}
+
+// listener is an address a service of the config listens on.
+type listener struct {
+	setting setting
+	network string
+	addr    string
+}
+
+// checkListeners returns an error for each listener bound to the address of
+// a listener before it.
+func checkListeners(input string, c *Config) []*ConfigError {
+	listeners := []listener{{setting{index: -1, key: "bind-address"}, "tcp", c.BindAddress}}
+	if c.HTTPD.Enabled {
+		listeners = append(listeners, listener{setting{"http", -1, "bind-address"}, "tcp", c.HTTPD.BindAddress})
+	}
+	if c.Storage.Enabled {
+		listeners = append(listeners, listener{setting{"storage", -1, "bind-address"}, "tcp", c.Storage.BindAddress})
+	}
+	for i, gc := range c.GraphiteInputs {
+		if gc.Enabled {
+			d := gc.WithDefaults()
+			listeners = append(listeners, listener{setting{"graphite", i, "bind-address"}, d.Protocol, d.BindAddress})
+		}
+	}
+	for i, cc := range c.CollectdInputs {
+		if cc.Enabled {
+			listeners = append(listeners, listener{setting{"collectd", i, "bind-address"}, "udp", cc.WithDefaults().BindAddress})
+		}
+	}
+	for i, oc := range c.OpenTSDBInputs {
+		if oc.Enabled {
+			listeners = append(listeners, listener{setting{"opentsdb", i, "bind-address"}, "tcp", oc.WithDefaults().BindAddress})
+		}
+	}
+	for i, uc := range c.UDPInputs {
+		if uc.Enabled {
+			listeners = append(listeners, listener{setting{"udp", i, "bind-address"}, "udp", uc.WithDefaults().BindAddress})
+		}
+	}
+
+	var errs []*ConfigError
+	for i, l := range listeners {
+		for _, prev := range listeners[:i] {
+			if conflicts(l, prev) {
+				errs = append(errs, &ConfigError{
+					Line:    l.setting.line(input),
+					Setting: l.setting.String(),
+					Err:     fmt.Errorf("%s address %q conflicts with %s", l.network, l.addr, prev.setting),
+				})
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// conflicts returns true if both listeners cannot be bound at once.
+func conflicts(a, b listener) bool {
+	if a.network != b.network {
+		return false
+	}
+	ahost, aport, err := net.SplitHostPort(a.addr)
+	if err != nil {
+		return false
+	}
+	bhost, bport, err := net.SplitHostPort(b.addr)
+	if err != nil || aport != bport {
+		return false
+	}
+	wildcard := func(host string) bool {
+		return host == "" || host == "0.0.0.0" || host == "::"
+	}
+	return ahost == bhost || wildcard(ahost) || wildcard(bhost)
+}
+
+// checkPaths returns an error for each directory the server writes to, or
+// the directory of each file, that it cannot create or write to.
+func checkPaths(input string, c *Config) []*ConfigError {
+	type path struct {
+		setting setting
+		path    string
+		file    bool
+	}
+	paths := []path{
+		{setting{"meta", -1, "dir"}, c.Meta.Dir, false},
+		{setting{"data", -1, "dir"}, c.Data.Dir, false},
+		{setting{"data", -1, "wal-dir"}, c.Data.WALDir, false},
+	}
+	if c.Retention.Enabled && c.Retention.ArchivePath != "" {
+		paths = append(paths, path{setting{"retention", -1, "archive-path"}, c.Retention.ArchivePath, false})
+	}
+	if c.Retention.Enabled && c.Retention.AuditLogPath != "" {
+		paths = append(paths, path{setting{"retention", -1, "audit-log-path"}, c.Retention.AuditLogPath, true})
+	}
+	if c.Subscriber.Enabled && c.Subscriber.QueueEnabled {
+		paths = append(paths, path{setting{"subscriber", -1, "queue-dir"}, c.Subscriber.QueueDir, false})
+	}
+	if c.Forwarder.Enabled {
+		paths = append(paths, path{setting{"forwarder", -1, "dir"}, c.Forwarder.Dir, false})
+	}
+	if c.Audit.Enabled {
+		paths = append(paths, path{setting{"audit", -1, "path"}, c.Audit.Path, true})
+	}
+	if c.SlowQueryLog.Enabled && c.SlowQueryLog.Path != "" {
+		paths = append(paths, path{setting{"slow-query-log", -1, "path"}, c.SlowQueryLog.Path, true})
+	}
+
+	var errs []*ConfigError
+	for _, p := range paths {
+		if p.path == "" {
+			continue
+		}
+		dir := p.path
+		if p.file {
+			dir = filepath.Dir(p.path)
+		}
+		if err := checkWritable(dir); err != nil {
+			errs = append(errs, &ConfigError{Line: p.setting.line(input), Setting: p.setting.String(), Err: err})
+		}
+	}
+	return errs
+}
+
+// checkWritable returns an error if files cannot be created in dir, or in
+// the nearest directory containing it if it does not exist.
+func checkWritable(dir string) error {
+	for {
+		fi, err := os.Stat(dir)
+		if err == nil {
+			if !fi.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+
+	f, err := ioutil.TempFile(dir, ".influxd-write-test")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %s", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// lookupField returns the field of the struct with the given toml name.
+func lookupField(v reflect.Value, name string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		if n, ok := tomlName(v.Type().Field(i)); ok && strings.EqualFold(n, name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+var (
+	errorLineRegexp = regexp.MustCompile(`(?i)line (\d+)`)
+	tableRegexp     = regexp.MustCompile(`^\[\s*([^\[\]]+?)\s*\]`)
+	arrayRegexp     = regexp.MustCompile(`^\[\[\s*([^\[\]]+?)\s*\]\]`)
+)
+
+// errorLine returns the line in the message of a TOML error, or 0.
+func errorLine(err error) int {
+	m := errorLineRegexp.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// line returns the line of input the setting is on, the line of its section
+// if the setting is not in input, or 0 if neither is.
+func (st setting) line(input string) int {
+	var table string
+	index, header := -1, 0
+	counts := make(map[string]int)
+	for i, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if m := arrayRegexp.FindStringSubmatch(line); m != nil {
+			table, index = m[1], counts[m[1]]
+			counts[m[1]]++
+		} else if m := tableRegexp.FindStringSubmatch(line); m != nil {
+			table, index = m[1], -1
+		} else {
+			if table == st.section && index == st.index && st.key != "" && isKey(line, st.key) {
+				return i + 1
+			}
+			continue
+		}
+
+		if table == st.section && index == st.index {
+			if st.key == "" {
+				return i + 1
+			} else if header == 0 {
+				header = i + 1
+			}
+		} else if st.key != "" && table == st.section+"."+st.key {
+			// The setting is a table of its own.
+			return i + 1
+		}
+	}
+	return header
+}
+
+// isKey returns true if the TOML line sets the key.
+func isKey(line, key string) bool {
+	if !strings.HasPrefix(line, key) && !strings.HasPrefix(line, `"`+key+`"`) {
+		return false
+	}
+	line = strings.TrimPrefix(strings.TrimPrefix(line, `"`+key+`"`), key)
+	return strings.HasPrefix(strings.TrimSpace(line), "=")
+}
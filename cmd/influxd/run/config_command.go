@@ -30,14 +30,24 @@ func (cmd *PrintConfigCommand) Run(args ...string) error {
 	// Parse command flags.
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
 	configPath := fs.String("config", "", "")
+	testPath := fs.String("test", "", "")
+	effective := fs.Bool("effective", false, "")
 	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, printConfigUsage) }
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
+	if *testPath != "" {
+		return cmd.test(*testPath)
+	}
+
 	// Parse config from path.
 	opt := Options{ConfigPath: *configPath}
-	config, err := cmd.parseConfig(opt.GetConfigPath())
+	parse := cmd.parseConfig
+	if *effective {
+		parse = cmd.parseEffectiveConfig
+	}
+	config, err := parse(opt.GetConfigPath())
 	if err != nil {
 		return fmt.Errorf("parse config: %s", err)
 	}
@@ -78,6 +88,40 @@ func (cmd *PrintConfigCommand) parseConfig(path string) (*Config, error) {
 	return config, nil
 }
 
+// parseEffectiveConfig parses the config at path as "influxd run" does,
+// starting from the defaults of the server rather than the demo settings.
+// Returns a demo configuration if path is blank.
+func (cmd *PrintConfigCommand) parseEffectiveConfig(path string) (*Config, error) {
+	if path == "" {
+		return NewDemoConfig()
+	}
+
+	fmt.Fprintf(cmd.Stderr, "Using configuration at: %s\n", path)
+
+	config := NewConfig()
+	if err := config.FromTomlFile(path); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// test checks the config at path, printing its problems.
+func (cmd *PrintConfigCommand) test(path string) error {
+	errs, err := CheckConfigFile(path, os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	for _, err := range errs {
+		fmt.Fprintf(cmd.Stdout, "%s: %s\n", path, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d problem(s) found in %s", len(errs), path)
+	}
+	fmt.Fprintf(cmd.Stdout, "Configuration file %s is valid.\n", path)
+	return nil
+}
+
 var printConfigUsage = `Displays the default configuration.
 
 Usage: influxd config [flags]
@@ -89,4 +133,13 @@ Usage: influxd config [flags]
             is present at any of these locations.
             Disable the automatic loading of a configuration file using
             the null device (such as /dev/null).
+    -effective
+            Print the configuration the server would run with: the
+            configuration file merged with the defaults of the server and
+            the environment variables overriding it.
+    -test <path>
+            Check the configuration file at path, and the environment
+            variables overriding it, without printing it. Reports syntax
+            errors, unknown settings, invalid values, conflicting listeners
+            and directories that cannot be written to, with their line.
 `
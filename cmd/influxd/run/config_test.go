@@ -2,7 +2,9 @@ package run_test
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/BurntSushi/toml"
@@ -49,6 +51,12 @@ bind-address = ":2020"
 [[udp]]
 bind-address = ":4444"
 
+[[kafka_consumer]]
+topics = ["metrics"]
+
+[[nats]]
+queue-group = "influx"
+
 [monitoring]
 enabled = true
 
@@ -86,6 +94,10 @@ enabled = true
 		t.Fatalf("unexpected opentsdb bind address: %s", c.OpenTSDBInputs[2].BindAddress)
 	} else if c.UDPInputs[0].BindAddress != ":4444" {
 		t.Fatalf("unexpected udp bind address: %s", c.UDPInputs[0].BindAddress)
+	} else if c.KafkaConsumerInputs[0].Topics[0] != "metrics" {
+		t.Fatalf("unexpected kafka_consumer topics: %v", c.KafkaConsumerInputs[0].Topics)
+	} else if c.NATSInputs[0].QueueGroup != "influx" {
+		t.Fatalf("unexpected nats queue group: %s", c.NATSInputs[0].QueueGroup)
 	} else if c.Subscriber.Enabled != true {
 		t.Fatalf("unexpected subscriber enabled: %v", c.Subscriber.Enabled)
 	} else if c.ContinuousQuery.Enabled != true {
@@ -310,3 +322,89 @@ func TestConfig_InvalidSubsections(t *testing.T) {
 		}
 	}
 }
+
+// Ensure the problems of a configuration are reported at their line.
+func TestCheckConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influxd-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	valid := fmt.Sprintf(`
+[meta]
+dir = %[1]q
+
+[data]
+dir = %[1]q
+wal-dir = %[1]q
+`, dir)
+	if _, errs := run.CheckConfig(valid, noenv); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	for _, tt := range []struct {
+		input string
+		exp   []string
+	}{
+		{
+			input: valid + `
+[retention]
+check-interval = "10x"
+unknown-key = 1
+`,
+			exp: []string{
+				`line 10: retention.check-interval: time: `,
+				`line 11: retention.unknown-key: unknown setting`,
+			},
+		},
+		{
+			input: valid + `
+[[graphite]]
+enabled = true
+bind-address = ":2003"
+
+[[opentsdb]]
+enabled = true
+bind-address = ":2003"
+`,
+			exp: []string{
+				`line 15: opentsdb[0].bind-address: tcp address ":2003" conflicts with graphite[0].bind-address`,
+			},
+		},
+		{
+			input: valid + `
+[continuous_queries]
+run-interval = "0s"
+`,
+			exp: []string{
+				`line 9: continuous_queries: run-interval must be positive`,
+			},
+		},
+		{
+			input: valid + `
+[http
+`,
+		},
+	} {
+		_, errs := run.CheckConfig(tt.input, noenv)
+		if tt.exp == nil {
+			if len(errs) != 1 || errs[0].Line != 9 {
+				t.Errorf("unexpected syntax errors: %v", errs)
+			}
+			continue
+		}
+
+		if len(errs) != len(tt.exp) {
+			t.Errorf("unexpected errors: %v", errs)
+			continue
+		}
+		for i, err := range errs {
+			if !strings.HasPrefix(err.Error(), tt.exp[i]) {
+				t.Errorf("unexpected error: got=%q exp=%q", err, tt.exp[i])
+			}
+		}
+	}
+}
+
+func noenv(string) string { return "" }
@@ -0,0 +1,94 @@
+package run
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/influxdata/influxdb/services/httpd"
+)
+
+// States of the server.
+const (
+	serverStarting int32 = iota
+	serverOpen
+	serverClosing
+)
+
+// HealthChecks returns the status of the meta store, the TSDB store, the
+// WAL and the free disk space and, if ready is true, whether the server is
+// open and its retention and continuous query services are running.
+func (s *Server) HealthChecks(ready bool) []httpd.HealthCheck {
+	checks := []httpd.HealthCheck{
+		checkDir("meta", s.config.Meta.Dir),
+		s.checkTSDB(),
+		checkDir("wal", s.config.Data.WALDir),
+		s.checkDisk(),
+	}
+	if !ready {
+		return checks
+	}
+
+	state := atomic.LoadInt32(&s.state)
+	checks = append(checks, checkState("server", state))
+	if s.config.Retention.Enabled {
+		checks = append(checks, checkState("retention", state))
+	}
+	if s.config.ContinuousQuery.Enabled {
+		checks = append(checks, checkState("continuous_queries", state))
+	}
+	return checks
+}
+
+// checkTSDB checks the TSDB store is open.
+func (s *Server) checkTSDB() httpd.HealthCheck {
+	if s.TSDBStore == nil || !s.TSDBStore.Opened() {
+		return healthFail("tsdb", "store is not open")
+	}
+	return healthPass("tsdb", fmt.Sprintf("%d shards open", s.TSDBStore.ShardN()))
+}
+
+// checkDisk checks the free space of the data and WAL directories is above
+// the minimum of the HTTP service.
+func (s *Server) checkDisk() httpd.HealthCheck {
+	min := uint64(s.config.HTTPD.HealthMinDiskFree)
+	if min == 0 {
+		return healthPass("disk", "not checked")
+	}
+
+	for _, dir := range []string{s.config.Data.Dir, s.config.Data.WALDir} {
+		free, ok := diskFree(dir)
+		if !ok {
+			return healthPass("disk", "not checked")
+		} else if free < min {
+			return healthFail("disk", fmt.Sprintf("%d bytes free in %s, below %d", free, dir, min))
+		}
+	}
+	return healthPass("disk", "")
+}
+
+// checkDir checks files can be written to dir.
+func checkDir(name, dir string) httpd.HealthCheck {
+	if err := checkWritable(dir); err != nil {
+		return healthFail(name, err.Error())
+	}
+	return healthPass(name, "")
+}
+
+// checkState checks the server, and so the named service, is running.
+func checkState(name string, state int32) httpd.HealthCheck {
+	switch state {
+	case serverStarting:
+		return healthFail(name, "starting")
+	case serverClosing:
+		return healthFail(name, "shutting down")
+	}
+	return healthPass(name, "")
+}
+
+func healthPass(name, msg string) httpd.HealthCheck {
+	return httpd.HealthCheck{Name: name, Status: httpd.HealthPass, Message: msg}
+}
+
+func healthFail(name, msg string) httpd.HealthCheck {
+	return httpd.HealthCheck{Name: name, Status: httpd.HealthFail, Message: msg}
+}
@@ -0,0 +1,244 @@
+package run
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/coordinator"
+)
+
+// reloadable are the settings Reload applies to the running server, by
+// section.  Changes to any other setting require a restart.
+var reloadable = map[string]map[string]bool{
+	"logging": {
+		"level":          true,
+		"service-levels": true,
+	},
+	"retention": {
+		"check-interval": true,
+	},
+	"continuous_queries": {
+		"log-enabled":            true,
+		"query-stats-enabled":    true,
+		"run-interval":           true,
+		"max-jitter":             true,
+		"max-concurrent-queries": true,
+		"alert-webhook-url":      true,
+		"alert-timeout":          true,
+	},
+	"coordinator": {
+		"max-concurrent-queries": true,
+		"max-queued-queries":     true,
+		"user-query-priorities":  true,
+		"query-timeout":          true,
+		"log-queries-after":      true,
+		"max-select-point":       true,
+		"max-select-series":      true,
+		"max-select-buckets":     true,
+		"max-memory-per-query":   true,
+		"show-series-warn":       true,
+	},
+	"graphite": {
+		"templates": true,
+	},
+	"subscriber": {
+		"http-timeout":         true,
+		"insecure-skip-verify": true,
+		"ca-certs":             true,
+		"write-concurrency":    true,
+		"write-buffer-size":    true,
+		"batch-size":           true,
+		"write-timeout":        true,
+	},
+}
+
+// Reload reads the configuration file again and applies the changes to the
+// settings that can change while the server runs: the levels of the logs,
+// the retention check interval, the continuous query settings, the query
+// limits, the graphite templates and the settings of the writes to the
+// subscription destinations.  Nothing is applied if the configuration is
+// invalid.
+//
+// It returns the names of the settings changed since the server started or
+// was last reloaded: those applied to the running server, and those that take
+// effect only once the server is restarted.
+func (s *Server) Reload() (applied, restartRequired []string, err error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	c, err := s.loadConfig()
+	if err != nil {
+		return nil, nil, err
+	} else if err := c.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var changes []setting
+	sections := make(map[string]bool)
+	for _, st := range diffConfig(s.config, c) {
+		if !reloadable[st.section][st.key] {
+			restartRequired = append(restartRequired, st.String())
+			continue
+		}
+		changes = append(changes, st)
+		sections[st.section] = true
+	}
+
+	if sections["logging"] && s.LogLevels != nil {
+		if err := s.LogLevels.Set(c.Logging); err != nil {
+			return nil, nil, err
+		}
+	}
+	if sections["retention"] && s.retentionService != nil {
+		s.retentionService.SetCheckInterval(time.Duration(c.Retention.CheckInterval))
+	}
+	if sections["continuous_queries"] && s.continuousQuerier != nil {
+		s.continuousQuerier.SetConfig(c.ContinuousQuery)
+	}
+	if sections["coordinator"] {
+		userPriorities, err := c.Coordinator.UserPriorities()
+		if err != nil {
+			return nil, nil, err
+		}
+		s.QueryExecutor.TaskManager.SetLimits(
+			time.Duration(c.Coordinator.QueryTimeout),
+			time.Duration(c.Coordinator.LogQueriesAfter),
+			c.Coordinator.MaxConcurrentQueries,
+			c.Coordinator.MaxQueuedQueries,
+			userPriorities,
+		)
+		if e, ok := s.QueryExecutor.StatementExecutor.(*coordinator.StatementExecutor); ok {
+			e.SetLimits(c.Coordinator)
+		}
+	}
+	if sections["graphite"] {
+		if err := s.setGraphiteTemplates(c.GraphiteInputs); err != nil {
+			return nil, nil, err
+		}
+	}
+	if sections["subscriber"] {
+		if err := s.Subscriber.SetConfig(c.Subscriber); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Keep the applied settings, so the next reload only reports what has
+	// changed since.
+	for _, st := range changes {
+		st.value(s.config).Set(st.value(c))
+		applied = append(applied, st.String())
+	}
+	return applied, restartRequired, nil
+}
+
+// setting identifies a setting of the configuration: the key of a section,
+// or of one of the inputs of a section when index is not negative.  A setting
+// without a key is a whole section, and a setting without a section a key at
+// the top of the configuration.
+type setting struct {
+	section string
+	index   int
+	key     string
+}
+
+// String returns the name of the setting, such as "graphite[0].templates".
+func (st setting) String() string {
+	name := st.section
+	if st.index >= 0 {
+		name += fmt.Sprintf("[%d]", st.index)
+	}
+	if st.key != "" {
+		if name != "" {
+			name += "."
+		}
+		name += st.key
+	}
+	return name
+}
+
+// value returns the value of the setting in c.
+func (st setting) value(c *Config) reflect.Value {
+	v := reflect.ValueOf(c).Elem()
+	if st.section != "" {
+		v = reflect.Indirect(fieldByName(v, st.section))
+	}
+	if st.index >= 0 {
+		v = v.Index(st.index)
+	}
+	if st.key != "" {
+		v = fieldByName(v, st.key)
+	}
+	return v
+}
+
+// diffConfig returns the settings that differ between the configs.  When
+// the number of inputs of a section differs, the whole section differs.
+func diffConfig(old, new *Config) []setting {
+	var settings []setting
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	for i := 0; i < ov.NumField(); i++ {
+		name, ok := tomlName(ov.Type().Field(i))
+		if !ok {
+			continue
+		}
+
+		o, n := reflect.Indirect(ov.Field(i)), reflect.Indirect(nv.Field(i))
+		switch {
+		case o.Kind() == reflect.Struct:
+			settings = append(settings, diffSection(name, -1, o, n)...)
+		case o.Kind() == reflect.Slice && o.Type().Elem().Kind() == reflect.Struct:
+			if o.Len() != n.Len() {
+				settings = append(settings, setting{section: name, index: -1})
+				continue
+			}
+			for j := 0; j < o.Len(); j++ {
+				settings = append(settings, diffSection(name, j, o.Index(j), n.Index(j))...)
+			}
+		case !reflect.DeepEqual(o.Interface(), n.Interface()):
+			settings = append(settings, setting{index: -1, key: name})
+		}
+	}
+	return settings
+}
+
+// diffSection returns the keys that differ between two configs of a section.
+func diffSection(section string, index int, o, n reflect.Value) []setting {
+	var settings []setting
+	for i := 0; i < o.NumField(); i++ {
+		key, ok := tomlName(o.Type().Field(i))
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(o.Field(i).Interface(), n.Field(i).Interface()) {
+			settings = append(settings, setting{section: section, index: index, key: key})
+		}
+	}
+	return settings
+}
+
+// fieldByName returns the field of the struct with the given toml name.
+func fieldByName(v reflect.Value, name string) reflect.Value {
+	for i := 0; i < v.NumField(); i++ {
+		if n, ok := tomlName(v.Type().Field(i)); ok && n == name {
+			return v.Field(i)
+		}
+	}
+	panic(fmt.Sprintf("unknown setting: %s", name))
+}
+
+// tomlName returns the name of the field in the configuration file, or false
+// if the field is not part of it.
+func tomlName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	name := strings.Split(f.Tag.Get("toml"), ",")[0]
+	if name == "-" {
+		return "", false
+	} else if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
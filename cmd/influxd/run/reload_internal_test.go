@@ -0,0 +1,50 @@
+package run
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+)
+
+// Ensure the changed settings are reported by name.
+func TestDiffConfig(t *testing.T) {
+	old, new := NewConfig(), NewConfig()
+	new.Logging.Level = "debug"
+	new.Retention.CheckInterval = toml.Duration(time.Minute)
+	new.GraphiteInputs[0].Templates = []string{"measurement.field*"}
+	new.Data.Dir = "/var/lib/influxdb/data"
+	new.BindAddress = ":8089"
+	new.UDPInputs = append(new.UDPInputs, new.UDPInputs[0])
+
+	var names []string
+	for _, st := range diffConfig(old, new) {
+		names = append(names, st.String())
+	}
+	exp := []string{
+		"data.dir",
+		"retention.check-interval",
+		"logging.level",
+		"graphite[0].templates",
+		"udp",
+		"bind-address",
+	}
+	if !reflect.DeepEqual(names, exp) {
+		t.Fatalf("unexpected settings: %v", names)
+	}
+}
+
+// Ensure a setting can be copied from one config to another.
+func TestSetting_Value(t *testing.T) {
+	old, new := NewConfig(), NewConfig()
+	new.GraphiteInputs[0].Templates = []string{"measurement.field*"}
+
+	st := setting{section: "graphite", index: 0, key: "templates"}
+	st.value(old).Set(st.value(new))
+	if !reflect.DeepEqual(old.GraphiteInputs[0].Templates, new.GraphiteInputs[0].Templates) {
+		t.Fatalf("unexpected templates: %v", old.GraphiteInputs[0].Templates)
+	} else if settings := diffConfig(old, new); len(settings) != 0 {
+		t.Fatalf("unexpected settings: %v", settings)
+	}
+}
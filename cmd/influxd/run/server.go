@@ -1,6 +1,7 @@
 package run
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -9,24 +10,37 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/audit"
 	"github.com/influxdata/influxdb/services/collectd"
 	"github.com/influxdata/influxdb/services/continuous_querier"
+	"github.com/influxdata/influxdb/services/forwarder"
 	"github.com/influxdata/influxdb/services/graphite"
 	"github.com/influxdata/influxdb/services/httpd"
+	"github.com/influxdata/influxdb/services/kafka_consumer"
+	"github.com/influxdata/influxdb/services/ldap"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/services/nats"
 	"github.com/influxdata/influxdb/services/opentsdb"
 	"github.com/influxdata/influxdb/services/precreator"
+	"github.com/influxdata/influxdb/services/quota"
+	"github.com/influxdata/influxdb/services/replication"
 	"github.com/influxdata/influxdb/services/retention"
+	"github.com/influxdata/influxdb/services/slowquery"
 	"github.com/influxdata/influxdb/services/snapshotter"
 	"github.com/influxdata/influxdb/services/subscriber"
+	"github.com/influxdata/influxdb/services/udf"
 	"github.com/influxdata/influxdb/services/udp"
+	"github.com/influxdata/influxdb/services/zipkin"
 	"github.com/influxdata/influxdb/tcp"
 	"github.com/influxdata/influxdb/tsdb"
 	client "github.com/influxdata/usage-client/v1"
@@ -61,11 +75,19 @@ type Server struct {
 	err     chan error
 	closing chan struct{}
 
+	// state is whether the server is starting, open or closing, for the
+	// readiness checks.  It is read and written atomically.
+	state int32
+
 	BindAddress string
 	Listener    net.Listener
 
 	Logger zap.Logger
 
+	// LogLevels are the levels of the logs of the logger, which may be
+	// changed while the server runs.
+	LogLevels *logger.Levels
+
 	MetaClient *meta.Client
 
 	TSDBStore     *tsdb.Store
@@ -77,6 +99,22 @@ type Server struct {
 
 	// These references are required for the tcp muxer.
 	SnapshotterService *snapshotter.Service
+	ReplicationService *replication.Service
+
+	AuditService  *audit.Service
+	LDAPService   *ldap.Service
+	ZipkinService *zipkin.Service
+
+	// graphiteInputs are the running graphite services, so their templates
+	// can be reloaded.
+	graphiteInputs []graphiteInput
+
+	// The services whose settings are changed by Reload, if enabled.
+	retentionService  *retention.Service
+	continuousQuerier *continuous_querier.Service
+
+	// reloadMu serializes the reloads of the configuration file.
+	reloadMu sync.Mutex
 
 	Monitor *monitor.Monitor
 
@@ -87,6 +125,10 @@ type Server struct {
 	CPUProfile string
 	MemProfile string
 
+	// ConfigPath is the path of the configuration file the server was
+	// started with, read again when the configuration is reloaded.
+	ConfigPath string
+
 	// httpAPIAddr is the host:port combination for the main HTTP API for querying and writing data
 	httpAPIAddr string
 
@@ -135,6 +177,11 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 	// The old location to keep things backwards compatible
 	bind := c.BindAddress
 
+	lg, levels, err := logger.New(os.Stderr, c.Logging)
+	if err != nil {
+		return nil, err
+	}
+
 	s := &Server{
 		buildInfo: *buildInfo,
 		err:       make(chan error),
@@ -142,10 +189,8 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 
 		BindAddress: bind,
 
-		Logger: zap.New(
-			zap.NewTextEncoder(),
-			zap.Output(os.Stderr),
-		),
+		Logger:    lg,
+		LogLevels: levels,
 
 		MetaClient: meta.NewClient(c.Meta),
 
@@ -171,6 +216,9 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 	s.TSDBStore.EngineOptions.EngineVersion = c.Data.Engine
 	s.TSDBStore.EngineOptions.IndexVersion = c.Data.Index
 
+	// A replication primary retains closed WAL segments for its followers.
+	s.TSDBStore.EngineOptions.WALArchiveEnabled = c.Replication.Mode == replication.ModePrimary
+
 	// Create the Subscriber service
 	s.Subscriber = subscriber.NewService(c.Subscriber)
 
@@ -194,10 +242,16 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 		MaxSelectPointN:   c.Coordinator.MaxSelectPointN,
 		MaxSelectSeriesN:  c.Coordinator.MaxSelectSeriesN,
 		MaxSelectBucketsN: c.Coordinator.MaxSelectBucketsN,
+		MaxMemoryPerQuery: int64(c.Coordinator.MaxMemoryPerQuery),
+		ShowSeriesWarnN:   c.Coordinator.ShowSeriesWarnN,
 	}
 	s.QueryExecutor.TaskManager.QueryTimeout = time.Duration(c.Coordinator.QueryTimeout)
 	s.QueryExecutor.TaskManager.LogQueriesAfter = time.Duration(c.Coordinator.LogQueriesAfter)
 	s.QueryExecutor.TaskManager.MaxConcurrentQueries = c.Coordinator.MaxConcurrentQueries
+	s.QueryExecutor.TaskManager.MaxQueuedQueries = c.Coordinator.MaxQueuedQueries
+	if s.QueryExecutor.TaskManager.UserPriorities, err = c.Coordinator.UserPriorities(); err != nil {
+		return nil, err
+	}
 
 	// Initialize the monitor
 	s.Monitor.Version = s.buildInfo.Version
@@ -205,6 +259,7 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 	s.Monitor.Branch = s.buildInfo.Branch
 	s.Monitor.BuildTime = s.buildInfo.Time
 	s.Monitor.PointsWriter = (*monitorPointsWriter)(s.PointsWriter)
+	s.Monitor.ReadOnly = s.PointsWriter.ReadOnly
 	return s, nil
 }
 
@@ -223,18 +278,85 @@ func (s *Server) Statistics(tags map[string]string) []models.Statistic {
 	return statistics
 }
 
+func (s *Server) appendAuditService(c audit.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := audit.NewService(c)
+	s.QueryExecutor.Auditor = srv
+	s.Services = append(s.Services, srv)
+	s.AuditService = srv
+}
+
+func (s *Server) appendZipkinService(c zipkin.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := zipkin.NewService(c)
+	s.Services = append(s.Services, srv)
+	s.ZipkinService = srv
+}
+
+func (s *Server) appendSlowQueryService(c slowquery.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := slowquery.NewService(c)
+	srv.MetaClient = s.MetaClient
+	srv.PointsWriter = s.PointsWriter
+	s.QueryExecutor.SlowQueryLog = srv
+	s.Services = append(s.Services, srv)
+}
+
+func (s *Server) appendLDAPService(c ldap.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := ldap.NewService(c)
+	srv.MetaClient = s.MetaClient
+	s.Services = append(s.Services, srv)
+	s.LDAPService = srv
+}
+
 func (s *Server) appendSnapshotterService() {
 	srv := snapshotter.NewService()
 	srv.TSDBStore = s.TSDBStore
 	srv.MetaClient = s.MetaClient
+	if s.AuditService != nil {
+		srv.Auditor = s.AuditService
+	}
 	s.Services = append(s.Services, srv)
 	s.SnapshotterService = srv
 }
 
+func (s *Server) appendForwarderService(c forwarder.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := forwarder.NewService(c)
+	srv.InternalDatabase = s.config.Monitor.StoreDatabase
+	s.PointsWriter.Forwarder = srv
+	s.Services = append(s.Services, srv)
+}
+
+func (s *Server) appendReplicationService(c replication.Config) {
+	if !c.Enabled() {
+		return
+	}
+	srv := replication.NewService(c)
+	srv.Dir = s.config.Meta.Dir
+	srv.MetaClient = s.MetaClient
+	srv.TSDBStore = s.TSDBStore
+	srv.PointsWriter = s.PointsWriter
+	s.Services = append(s.Services, srv)
+	s.ReplicationService = srv
+}
+
 // SetLogOutput sets the logger used for all messages. It must not be called
 // after the Open method has been called.
 func (s *Server) SetLogOutput(w io.Writer) {
-	s.Logger = zap.New(zap.NewTextEncoder(), zap.Output(zap.AddSync(w)))
+	// The logging config was validated when the server was created.
+	s.Logger, s.LogLevels, _ = logger.New(w, s.config.Logging)
 }
 
 func (s *Server) appendMonitorService() {
@@ -248,6 +370,22 @@ func (s *Server) appendRetentionPolicyService(c retention.Config) {
 	srv := retention.NewService(c)
 	srv.MetaClient = s.MetaClient
 	srv.TSDBStore = s.TSDBStore
+	srv.QueryExecutor = s.QueryExecutor
+	if e, ok := s.QueryExecutor.StatementExecutor.(*coordinator.StatementExecutor); ok {
+		e.RetentionEnforcer = srv
+	}
+	s.retentionService = srv
+	s.Services = append(s.Services, srv)
+}
+
+func (s *Server) appendQuotaService(c quota.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := quota.NewService(c)
+	srv.MetaClient = s.MetaClient
+	srv.TSDBStore = s.TSDBStore
+	s.PointsWriter.Quotas = srv
 	s.Services = append(s.Services, srv)
 }
 
@@ -259,12 +397,31 @@ func (s *Server) appendHTTPDService(c httpd.Config) {
 	srv.Handler.MetaClient = s.MetaClient
 	srv.Handler.QueryAuthorizer = meta.NewQueryAuthorizer(s.MetaClient)
 	srv.Handler.WriteAuthorizer = meta.NewWriteAuthorizer(s.MetaClient)
+	if s.LDAPService != nil {
+		srv.Handler.Authenticator = s.LDAPService
+	}
 	srv.Handler.QueryExecutor = s.QueryExecutor
 	srv.Handler.Monitor = s.Monitor
 	srv.Handler.PointsWriter = s.PointsWriter
+	srv.Handler.Store = s.TSDBStore
+	srv.Handler.Graphite = s
+	srv.Handler.LogLevels = s.LogLevels
+	srv.Handler.ConfigReloader = s
+	srv.Handler.Health = s
+	if s.ZipkinService != nil {
+		srv.Handler.Tracer = s.ZipkinService
+	}
+	if s.AuditService != nil {
+		srv.Handler.Auditor = s.AuditService
+	}
 	srv.Handler.Version = s.buildInfo.Version
 	srv.Handler.BuildType = "OSS"
 
+	// Writes must discard the query results they make stale.
+	if srv.Handler.QueryCache != nil {
+		s.PointsWriter.QueryCache = srv.Handler.QueryCache
+	}
+
 	s.Services = append(s.Services, srv)
 }
 
@@ -316,9 +473,112 @@ func (s *Server) appendGraphiteService(c graphite.Config) error {
 	srv.MetaClient = s.MetaClient
 	srv.Monitor = s.Monitor
 	s.Services = append(s.Services, srv)
+
+	d := c.WithDefaults()
+	s.graphiteInputs = append(s.graphiteInputs, graphiteInput{
+		protocol:    d.Protocol,
+		bindAddress: d.BindAddress,
+		service:     srv,
+	})
 	return nil
 }
 
+// graphiteInput is a running graphite service.
+type graphiteInput struct {
+	protocol    string
+	bindAddress string
+	service     *graphite.Service
+}
+
+// ReloadGraphiteTemplates reads the configuration file again and replaces the
+// templates of the running graphite inputs with the ones it configures.
+// Inputs are matched by protocol and bind address.  Any other change to the
+// graphite inputs requires a restart.
+func (s *Server) ReloadGraphiteTemplates() error {
+	c, err := s.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Validate every input before changing any of them.
+	for _, gc := range c.GraphiteInputs {
+		if err := gc.Validate(); err != nil {
+			return fmt.Errorf("invalid graphite config: %v", err)
+		}
+	}
+	return s.setGraphiteTemplates(c.GraphiteInputs)
+}
+
+// loadConfig reads the configuration file of the server again, with the
+// environment overrides applied.
+func (s *Server) loadConfig() (*Config, error) {
+	if s.ConfigPath == "" {
+		return nil, errors.New("no configuration file to reload")
+	}
+
+	c := NewConfig()
+	if err := c.FromTomlFile(s.ConfigPath); err != nil {
+		return nil, err
+	} else if err := c.ApplyEnvOverrides(os.Getenv); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// setGraphiteTemplates replaces the templates of the running graphite inputs
+// with those of the matching configs, which must be valid.
+func (s *Server) setGraphiteTemplates(inputs []graphite.Config) error {
+	configs := make([]*graphite.Config, 0, len(inputs))
+	for _, gc := range inputs {
+		configs = append(configs, gc.WithDefaults())
+	}
+
+	for _, in := range s.graphiteInputs {
+		var config *graphite.Config
+		for _, gc := range configs {
+			if gc.Enabled && gc.Protocol == in.protocol && gc.BindAddress == in.bindAddress {
+				config = gc
+				break
+			}
+		}
+		if config == nil {
+			s.Logger.Info(fmt.Sprintf("graphite input %s %s is no longer configured, restart to remove it", in.protocol, in.bindAddress))
+			continue
+		}
+
+		if err := in.service.SetTemplates(config.Templates); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TestGraphiteTemplates parses a graphite metric line with templates, or with
+// the current templates of the graphite input bound to bindAddress if
+// templates is nil.  An empty bindAddress is the first graphite input, or the
+// default graphite settings if none are enabled.
+func (s *Server) TestGraphiteTemplates(bindAddress string, templates []string, line string) (models.Point, error) {
+	var srv *graphite.Service
+	for _, in := range s.graphiteInputs {
+		if bindAddress == "" || in.bindAddress == bindAddress {
+			srv = in.service
+			break
+		}
+	}
+
+	if srv == nil {
+		if bindAddress != "" {
+			return nil, fmt.Errorf("no graphite input is bound to %s", bindAddress)
+		}
+
+		var err error
+		if srv, err = graphite.NewService(graphite.NewConfig()); err != nil {
+			return nil, err
+		}
+	}
+	return srv.TestTemplates(templates, line)
+}
+
 func (s *Server) appendPrecreatorService(c precreator.Config) error {
 	if !c.Enabled {
 		return nil
@@ -333,6 +593,13 @@ func (s *Server) appendPrecreatorService(c precreator.Config) error {
 	return nil
 }
 
+func (s *Server) appendUDFService(c udf.Config) {
+	if !c.Enabled {
+		return
+	}
+	s.Services = append(s.Services, udf.NewService(c))
+}
+
 func (s *Server) appendUDPService(c udp.Config) {
 	if !c.Enabled {
 		return
@@ -343,6 +610,26 @@ func (s *Server) appendUDPService(c udp.Config) {
 	s.Services = append(s.Services, srv)
 }
 
+func (s *Server) appendKafkaConsumerService(c kafka_consumer.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := kafka_consumer.NewService(c)
+	srv.PointsWriter = s.PointsWriter
+	srv.MetaClient = s.MetaClient
+	s.Services = append(s.Services, srv)
+}
+
+func (s *Server) appendNATSService(c nats.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := nats.NewService(c)
+	srv.PointsWriter = s.PointsWriter
+	srv.MetaClient = s.MetaClient
+	s.Services = append(s.Services, srv)
+}
+
 func (s *Server) appendContinuousQueryService(c continuous_querier.Config) {
 	if !c.Enabled {
 		return
@@ -351,6 +638,11 @@ func (s *Server) appendContinuousQueryService(c continuous_querier.Config) {
 	srv.MetaClient = s.MetaClient
 	srv.QueryExecutor = s.QueryExecutor
 	srv.Monitor = s.Monitor
+	srv.ReadOnly = s.PointsWriter.ReadOnly
+	if e, ok := s.QueryExecutor.StatementExecutor.(*coordinator.StatementExecutor); ok {
+		e.ContinuousQuerier = srv
+	}
+	s.continuousQuerier = srv
 	s.Services = append(s.Services, srv)
 }
 
@@ -371,16 +663,25 @@ func (s *Server) Open() error {
 
 	// Multiplex listener.
 	mux := tcp.NewMux()
+	mux.WithLogger(s.Logger)
 	go mux.Serve(ln)
 
 	// Append services.
 	s.appendMonitorService()
+	s.appendAuditService(s.config.Audit)
+	s.appendSlowQueryService(s.config.SlowQueryLog)
+	s.appendZipkinService(s.config.Zipkin)
+	s.appendLDAPService(s.config.LDAP)
+	s.appendUDFService(s.config.UDF)
 	s.appendPrecreatorService(s.config.Precreator)
 	s.appendSnapshotterService()
+	s.appendReplicationService(s.config.Replication)
+	s.appendForwarderService(s.config.Forwarder)
 	s.appendContinuousQueryService(s.config.ContinuousQuery)
 	s.appendHTTPDService(s.config.HTTPD)
 	s.appendStorageService(s.config.Storage)
 	s.appendRetentionPolicyService(s.config.Retention)
+	s.appendQuotaService(s.config.Quota)
 	for _, i := range s.config.GraphiteInputs {
 		if err := s.appendGraphiteService(i); err != nil {
 			return err
@@ -397,12 +698,21 @@ func (s *Server) Open() error {
 	for _, i := range s.config.UDPInputs {
 		s.appendUDPService(i)
 	}
+	for _, i := range s.config.KafkaConsumerInputs {
+		s.appendKafkaConsumerService(i)
+	}
+	for _, i := range s.config.NATSInputs {
+		s.appendNATSService(i)
+	}
 
 	s.Subscriber.MetaClient = s.MetaClient
 	s.PointsWriter.MetaClient = s.MetaClient
 	s.Monitor.MetaClient = s.MetaClient
 
 	s.SnapshotterService.Listener = mux.Listen(snapshotter.MuxHeader)
+	if s.ReplicationService != nil {
+		s.ReplicationService.Listener = mux.Listen(replication.MuxHeader)
+	}
 
 	// Configure logging for all services and clients.
 	if s.config.Meta.LoggingEnabled {
@@ -448,11 +758,19 @@ func (s *Server) Open() error {
 		go s.startServerReporting()
 	}
 
+	atomic.StoreInt32(&s.state, serverOpen)
 	return nil
 }
 
 // Close shuts down the meta and data stores and all services.
+//
+// The server stops accepting connections and queries first.  The running
+// queries are given the drain timeout to finish while the services close,
+// and are killed afterwards.  The cache of every shard is then written to
+// TSM files before the store closes, so the WAL is not replayed on the next
+// start.
 func (s *Server) Close() error {
+	atomic.StoreInt32(&s.state, serverClosing)
 	stopProfile()
 
 	// Close the listener first to stop any new connections
@@ -460,11 +778,29 @@ func (s *Server) Close() error {
 		s.Listener.Close()
 	}
 
+	// Stop executing new queries and give the running ones time to finish.
+	// Queries still running after the drain timeout are killed, so the
+	// services waiting for them can close.
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		if s.QueryExecutor == nil {
+			return
+		}
+		if !s.QueryExecutor.Drain(s.DrainTimeout()) {
+			s.Logger.Info(fmt.Sprintf("Killing %d queries still running after the drain timeout",
+				len(s.QueryExecutor.TaskManager.Queries())))
+		}
+		s.QueryExecutor.Close()
+	}()
+
 	// Close services to allow any inflight requests to complete
-	// and prevent new requests from being accepted.
+	// and prevent new requests from being accepted.  This includes the
+	// snapshotter, which waits for the backups in progress.
 	for _, service := range s.Services {
 		service.Close()
 	}
+	<-drained
 
 	s.config.deregisterDiagnostics(s.Monitor)
 
@@ -472,12 +808,11 @@ func (s *Server) Close() error {
 		s.PointsWriter.Close()
 	}
 
-	if s.QueryExecutor != nil {
-		s.QueryExecutor.Close()
-	}
-
 	// Close the TSDBStore, no more reads or writes at this point
 	if s.TSDBStore != nil {
+		if err := s.TSDBStore.Flush(); err != nil {
+			s.Logger.Info(fmt.Sprintf("error flushing shards on shutdown: %s", err))
+		}
 		s.TSDBStore.Close()
 	}
 
@@ -493,6 +828,12 @@ func (s *Server) Close() error {
 	return nil
 }
 
+// DrainTimeout returns the time the running queries have to finish when the
+// server is closed.
+func (s *Server) DrainTimeout() time.Duration {
+	return time.Duration(s.config.Coordinator.QueryDrainTimeout)
+}
+
 // startServerReporting starts periodic server reporting.
 func (s *Server) startServerReporting() {
 	s.reportServer()
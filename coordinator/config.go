@@ -3,6 +3,7 @@
 package coordinator
 
 import (
+	"errors"
 	"time"
 
 	"github.com/influxdata/influxdb/monitor/diagnostics"
@@ -25,6 +26,18 @@ const (
 	// DefaultMaxSelectSeriesN is the maximum number of series a SELECT can run.
 	// A value of zero will make the maximum series count unlimited.
 	DefaultMaxSelectSeriesN = 0
+
+	// DefaultMaxMemoryPerQuery is the maximum memory, in bytes, the iterators
+	// of a query can hold. A value of zero will make the memory unlimited.
+	DefaultMaxMemoryPerQuery = 0
+
+	// DefaultShowSeriesWarnN is the series cardinality above which SHOW SERIES
+	// and SHOW TAG VALUES without a LIMIT return a warning.
+	DefaultShowSeriesWarnN = 1000000
+
+	// DefaultQueryDrainTimeout is the default time the running queries have
+	// to finish when the server shuts down, before they are killed.
+	DefaultQueryDrainTimeout = 10 * time.Second
 )
 
 // Config represents the configuration for the coordinator service.
@@ -36,6 +49,18 @@ type Config struct {
 	MaxSelectPointN      int           `toml:"max-select-point"`
 	MaxSelectSeriesN     int           `toml:"max-select-series"`
 	MaxSelectBucketsN    int           `toml:"max-select-buckets"`
+	MaxMemoryPerQuery    toml.Size     `toml:"max-memory-per-query"`
+	ShowSeriesWarnN      int           `toml:"show-series-warn"`
+	QueryDrainTimeout    toml.Duration `toml:"query-drain-timeout"`
+
+	// MaxQueuedQueries is the number of queries over the concurrency limit
+	// that wait, by priority, for a running query to finish.  Queries over
+	// both limits are rejected.
+	MaxQueuedQueries int `toml:"max-queued-queries"`
+
+	// UserQueryPriorities are the priority classes, background, interactive
+	// or system, of the queries of users that don't request one.
+	UserQueryPriorities map[string]string `toml:"user-query-priorities"`
 }
 
 // NewConfig returns an instance of Config with defaults.
@@ -46,18 +71,39 @@ func NewConfig() Config {
 		MaxConcurrentQueries: DefaultMaxConcurrentQueries,
 		MaxSelectPointN:      DefaultMaxSelectPointN,
 		MaxSelectSeriesN:     DefaultMaxSelectSeriesN,
+		MaxMemoryPerQuery:    DefaultMaxMemoryPerQuery,
+		ShowSeriesWarnN:      DefaultShowSeriesWarnN,
+		QueryDrainTimeout:    toml.Duration(DefaultQueryDrainTimeout),
 	}
 }
 
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if c.MaxQueuedQueries < 0 {
+		return errors.New("max-queued-queries must be non-negative")
+	}
+	_, err := c.UserPriorities()
+	return err
+}
+
+// UserPriorities returns the parsed priorities of the queries of users.
+func (c Config) UserPriorities() (map[string]query.Priority, error) {
+	return query.ParseUserPriorities(c.UserQueryPriorities)
+}
+
 // Diagnostics returns a diagnostics representation of a subset of the Config.
 func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 	return diagnostics.RowFromMap(map[string]interface{}{
 		"write-timeout":          c.WriteTimeout,
 		"max-concurrent-queries": c.MaxConcurrentQueries,
+		"max-queued-queries":     c.MaxQueuedQueries,
 		"query-timeout":          c.QueryTimeout,
 		"log-queries-after":      c.LogQueriesAfter,
 		"max-select-point":       c.MaxSelectPointN,
 		"max-select-series":      c.MaxSelectSeriesN,
 		"max-select-buckets":     c.MaxSelectBucketsN,
+		"max-memory-per-query":   c.MaxMemoryPerQuery,
+		"show-series-warn":       c.ShowSeriesWarnN,
+		"query-drain-timeout":    c.QueryDrainTimeout,
 	}), nil
 }
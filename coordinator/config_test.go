@@ -6,6 +6,7 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/query"
 )
 
 func TestConfig_Parse(t *testing.T) {
@@ -13,6 +14,10 @@ func TestConfig_Parse(t *testing.T) {
 	var c coordinator.Config
 	if _, err := toml.Decode(`
 write-timeout = "20s"
+max-queued-queries = 10
+
+[user-query-priorities]
+cq_user = "background"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -20,5 +25,24 @@ write-timeout = "20s"
 	// Validate configuration.
 	if time.Duration(c.WriteTimeout) != 20*time.Second {
 		t.Fatalf("unexpected write timeout s: %s", c.WriteTimeout)
+	} else if c.MaxQueuedQueries != 10 {
+		t.Fatalf("unexpected max queued queries: %d", c.MaxQueuedQueries)
+	} else if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	priorities, err := c.UserPriorities()
+	if err != nil {
+		t.Fatal(err)
+	} else if priorities["cq_user"] != query.PriorityBackground {
+		t.Fatalf("unexpected user query priorities: %v", priorities)
+	}
+}
+
+func TestConfig_Validate_UserQueryPriorities(t *testing.T) {
+	c := coordinator.NewConfig()
+	c.UserQueryPriorities = map[string]string{"fred": "urgent"}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for unknown priority")
 	}
 }
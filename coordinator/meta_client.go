@@ -13,7 +13,8 @@ type MetaClient interface {
 	CreateDatabase(name string) (*meta.DatabaseInfo, error)
 	CreateDatabaseWithRetentionPolicy(name string, spec *meta.RetentionPolicySpec) (*meta.DatabaseInfo, error)
 	CreateRetentionPolicy(database string, spec *meta.RetentionPolicySpec, makeDefault bool) (*meta.RetentionPolicyInfo, error)
-	CreateSubscription(database, rp, name, mode string, destinations []string) error
+	CreateSubscription(database, rp, name, mode string, destinations []string, filter string) error
+	CreateToken(username, database string, p influxql.Privilege, expires time.Time) (*meta.TokenInfo, string, error)
 	CreateUser(name, password string, admin bool) (meta.User, error)
 	Database(name string) *meta.DatabaseInfo
 	Databases() []meta.DatabaseInfo
@@ -22,11 +23,15 @@ type MetaClient interface {
 	DropDatabase(name string) error
 	DropRetentionPolicy(database, name string) error
 	DropSubscription(database, rp, name string) error
+	DropToken(id string) error
 	DropUser(name string) error
 	RetentionPolicy(database, name string) (rpi *meta.RetentionPolicyInfo, err error)
 	SetAdminPrivilege(username string, admin bool) error
 	SetPrivilege(username, database string, p influxql.Privilege) error
 	ShardGroupsByTimeRange(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error)
+	Tokens() []meta.TokenInfo
+	UpdateQuota(database string, qu *meta.QuotaUpdate) error
+	UpdateWriteLimits(database string, wlu *meta.WriteLimitsUpdate) error
 	UpdateRetentionPolicy(database, name string, rpu *meta.RetentionPolicyUpdate, makeDefault bool) error
 	UpdateUser(name, password string) error
 	UserPrivilege(username, database string) (*influxql.Privilege, error)
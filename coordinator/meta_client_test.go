@@ -13,7 +13,8 @@ type MetaClient struct {
 	CreateDatabaseFn                    func(name string) (*meta.DatabaseInfo, error)
 	CreateDatabaseWithRetentionPolicyFn func(name string, spec *meta.RetentionPolicySpec) (*meta.DatabaseInfo, error)
 	CreateRetentionPolicyFn             func(database string, spec *meta.RetentionPolicySpec, makeDefault bool) (*meta.RetentionPolicyInfo, error)
-	CreateSubscriptionFn                func(database, rp, name, mode string, destinations []string) error
+	CreateSubscriptionFn                func(database, rp, name, mode string, destinations []string, filter string) error
+	CreateTokenFn                       func(username, database string, p influxql.Privilege, expires time.Time) (*meta.TokenInfo, string, error)
 	CreateUserFn                        func(name, password string, admin bool) (meta.User, error)
 	DatabaseFn                          func(name string) *meta.DatabaseInfo
 	DatabasesFn                         func() []meta.DatabaseInfo
@@ -26,14 +27,18 @@ type MetaClient struct {
 	DropRetentionPolicyFn               func(database, name string) error
 	DropSubscriptionFn                  func(database, rp, name string) error
 	DropShardFn                         func(id uint64) error
+	DropTokenFn                         func(id string) error
 	DropUserFn                          func(name string) error
 	MetaNodesFn                         func() ([]meta.NodeInfo, error)
 	RetentionPolicyFn                   func(database, name string) (rpi *meta.RetentionPolicyInfo, err error)
 	SetAdminPrivilegeFn                 func(username string, admin bool) error
 	SetPrivilegeFn                      func(username, database string, p influxql.Privilege) error
 	ShardGroupsByTimeRangeFn            func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error)
+	TokensFn                            func() []meta.TokenInfo
+	UpdateQuotaFn                       func(database string, qu *meta.QuotaUpdate) error
 	UpdateRetentionPolicyFn             func(database, name string, rpu *meta.RetentionPolicyUpdate, makeDefault bool) error
 	UpdateUserFn                        func(name, password string) error
+	UpdateWriteLimitsFn                 func(database string, wlu *meta.WriteLimitsUpdate) error
 	UserPrivilegeFn                     func(username, database string) (*influxql.Privilege, error)
 	UserPrivilegesFn                    func(username string) (map[string]influxql.Privilege, error)
 	UsersFn                             func() []meta.UserInfo
@@ -59,8 +64,12 @@ func (c *MetaClient) DropShard(id uint64) error {
 	return c.DropShardFn(id)
 }
 
-func (c *MetaClient) CreateSubscription(database, rp, name, mode string, destinations []string) error {
-	return c.CreateSubscriptionFn(database, rp, name, mode, destinations)
+func (c *MetaClient) CreateSubscription(database, rp, name, mode string, destinations []string, filter string) error {
+	return c.CreateSubscriptionFn(database, rp, name, mode, destinations, filter)
+}
+
+func (c *MetaClient) CreateToken(username, database string, p influxql.Privilege, expires time.Time) (*meta.TokenInfo, string, error) {
+	return c.CreateTokenFn(username, database, p, expires)
 }
 
 func (c *MetaClient) CreateUser(name, password string, admin bool) (meta.User, error) {
@@ -107,6 +116,10 @@ func (c *MetaClient) DropSubscription(database, rp, name string) error {
 	return c.DropSubscriptionFn(database, rp, name)
 }
 
+func (c *MetaClient) DropToken(id string) error {
+	return c.DropTokenFn(id)
+}
+
 func (c *MetaClient) DropUser(name string) error {
 	return c.DropUserFn(name)
 }
@@ -131,6 +144,18 @@ func (c *MetaClient) ShardGroupsByTimeRange(database, policy string, min, max ti
 	return c.ShardGroupsByTimeRangeFn(database, policy, min, max)
 }
 
+func (c *MetaClient) Tokens() []meta.TokenInfo {
+	return c.TokensFn()
+}
+
+func (c *MetaClient) UpdateQuota(database string, qu *meta.QuotaUpdate) error {
+	return c.UpdateQuotaFn(database, qu)
+}
+
+func (c *MetaClient) UpdateWriteLimits(database string, wlu *meta.WriteLimitsUpdate) error {
+	return c.UpdateWriteLimitsFn(database, wlu)
+}
+
 func (c *MetaClient) UpdateRetentionPolicy(database, name string, rpu *meta.RetentionPolicyUpdate, makeDefault bool) error {
 	return c.UpdateRetentionPolicyFn(database, name, rpu, makeDefault)
 }
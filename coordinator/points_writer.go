@@ -1,15 +1,20 @@
 package coordinator
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/tracing"
+	"github.com/influxdata/influxdb/pkg/tracing/fields"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/uber-go/zap"
@@ -26,6 +31,11 @@ const (
 	statWriteErr           = "writeError"
 	statSubWriteOK         = "subWriteOk"
 	statSubWriteDrop       = "subWriteDrop"
+	statPointTimeReject    = "pointTimeReject"
+	statPointTimeClamp     = "pointTimeClamp"
+
+	statSchemaViolation = "violation"
+	statSchemaDrop      = "drop"
 )
 
 var (
@@ -38,6 +48,10 @@ var (
 
 	// ErrWriteFailed is returned when no writes succeeded.
 	ErrWriteFailed = errors.New("write failed")
+
+	// ErrReadOnly is returned when a write is attempted while the node is
+	// running as a read-only replica.
+	ErrReadOnly = errors.New("write rejected: node is a read-only replica")
 )
 
 // PointsWriter handles writes across multiple local and remote data nodes.
@@ -62,7 +76,32 @@ type PointsWriter struct {
 
 	subPoints []chan<- *WritePointsRequest
 
+	// Forwarder, if set, is handed every write that succeeds locally.
+	Forwarder interface {
+		Forward(p *WritePointsRequest)
+	}
+
+	// QueryCache, if set, is told about every write so it can discard the
+	// cached query results the write makes stale.
+	QueryCache interface {
+		Invalidate(database, retentionPolicy string, points []models.Point)
+	}
+
+	// Quotas, if set, is asked whether a database may be written to before
+	// points are written to it.
+	Quotas interface {
+		CheckWrite(database string, n int) error
+	}
+
+	// readOnly is non-zero when writes must be rejected.
+	readOnly int32
+
 	stats *WriteStatistics
+
+	// schemaStats are the points not conforming to the schema of each
+	// database.
+	schemaMu    sync.Mutex
+	schemaStats map[string]*SchemaStatistics
 }
 
 // WritePointsRequest represents a request to write point data to the cluster.
@@ -90,6 +129,7 @@ func NewPointsWriter() *PointsWriter {
 		WriteTimeout: DefaultWriteTimeout,
 		Logger:       zap.New(zap.NullEncoder()),
 		stats:        &WriteStatistics{},
+		schemaStats:  make(map[string]*SchemaStatistics),
 	}
 }
 
@@ -147,6 +187,20 @@ func (w *PointsWriter) AddWriteSubscriber(c chan<- *WritePointsRequest) {
 	w.subPoints = append(w.subPoints, c)
 }
 
+// SetReadOnly sets whether w rejects all writes with ErrReadOnly.
+func (w *PointsWriter) SetReadOnly(readOnly bool) {
+	var v int32
+	if readOnly {
+		v = 1
+	}
+	atomic.StoreInt32(&w.readOnly, v)
+}
+
+// ReadOnly returns true if w is rejecting writes.
+func (w *PointsWriter) ReadOnly() bool {
+	return atomic.LoadInt32(&w.readOnly) != 0
+}
+
 // WithLogger sets the Logger on w.
 func (w *PointsWriter) WithLogger(log zap.Logger) {
 	w.Logger = log.With(zap.String("service", "write"))
@@ -163,11 +217,20 @@ type WriteStatistics struct {
 	WriteErr           int64
 	SubWriteOK         int64
 	SubWriteDrop       int64
+	PointTimeRejected  int64
+	PointTimeClamped   int64
+}
+
+// SchemaStatistics keeps statistics related to the points written to a
+// database that don't conform to its schema.
+type SchemaStatistics struct {
+	Violations int64
+	Dropped    int64
 }
 
 // Statistics returns statistics for periodic monitoring.
 func (w *PointsWriter) Statistics(tags map[string]string) []models.Statistic {
-	return []models.Statistic{{
+	statistics := []models.Statistic{{
 		Name: "write",
 		Tags: tags,
 		Values: map[string]interface{}{
@@ -180,8 +243,24 @@ func (w *PointsWriter) Statistics(tags map[string]string) []models.Statistic {
 			statWriteErr:           atomic.LoadInt64(&w.stats.WriteErr),
 			statSubWriteOK:         atomic.LoadInt64(&w.stats.SubWriteOK),
 			statSubWriteDrop:       atomic.LoadInt64(&w.stats.SubWriteDrop),
+			statPointTimeReject:    atomic.LoadInt64(&w.stats.PointTimeRejected),
+			statPointTimeClamp:     atomic.LoadInt64(&w.stats.PointTimeClamped),
 		},
 	}}
+
+	w.schemaMu.Lock()
+	defer w.schemaMu.Unlock()
+	for database, stats := range w.schemaStats {
+		statistics = append(statistics, models.Statistic{
+			Name: "writeSchema",
+			Tags: models.StatisticTags{"database": database}.Merge(tags),
+			Values: map[string]interface{}{
+				statSchemaViolation: stats.Violations,
+				statSchemaDrop:      stats.Dropped,
+			},
+		})
+	}
+	return statistics
 }
 
 // MapShards maps the points contained in wp to a ShardMapping.  If a point
@@ -283,32 +362,103 @@ func (w *PointsWriter) WritePointsInto(p *IntoWriteRequest) error {
 }
 
 // WritePoints writes the data to the underlying storage. consitencyLevel and user are only used for clustered scenarios
+//
+// The points of client writes are checked against the write limits of the
+// database, unlike those written by the server itself, such as the results
+// of SELECT INTO statements, continuous queries and rollups.
 func (w *PointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
-	return w.WritePointsPrivileged(database, retentionPolicy, consistencyLevel, points)
+	return w.writePoints(context.Background(), database, retentionPolicy, consistencyLevel, points, true)
+}
+
+// WritePointsContext is WritePoints recording the mapping of the points to
+// shards and the write to each shard as children of the span of ctx, if any.
+func (w *PointsWriter) WritePointsContext(ctx context.Context, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+	return w.writePoints(ctx, database, retentionPolicy, consistencyLevel, points, true)
 }
 
 // WritePointsPrivileged writes the data to the underlying storage, consitencyLevel is only used for clustered scenarios
 func (w *PointsWriter) WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	return w.writePoints(context.Background(), database, retentionPolicy, consistencyLevel, points, false)
+}
+
+// writePoints writes the points, checking them against the write limits of
+// the database if checkLimits is true.
+func (w *PointsWriter) writePoints(ctx context.Context, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point, checkLimits bool) error {
+	span := tracing.SpanFromContext(ctx)
+	if span != nil {
+		span = span.StartSpan("write_points")
+		defer span.Finish()
+
+		span.SetLabels("db", database, "rp", retentionPolicy)
+		span.SetFields(fields.New(fields.Int64("points", int64(len(points)))))
+	}
+
 	atomic.AddInt64(&w.stats.WriteReq, 1)
 	atomic.AddInt64(&w.stats.PointWriteReq, int64(len(points)))
 
+	if w.ReadOnly() {
+		atomic.AddInt64(&w.stats.WriteErr, 1)
+		return ErrReadOnly
+	}
+
+	if w.Quotas != nil {
+		if err := w.Quotas.CheckWrite(database, len(points)); err != nil {
+			atomic.AddInt64(&w.stats.WriteErr, 1)
+			return err
+		}
+	}
+
+	di := w.MetaClient.Database(database)
+
+	// Drop the points not conforming to the schema of the database or beyond
+	// its write limits.  They are reported once the other points are written.
+	var partial tsdb.PartialWriteError
+	if di != nil {
+		points = w.checkSchema(database, di.Schema, points, &partial)
+		if checkLimits {
+			points = w.checkWriteLimits(di.WriteLimits, points, &partial)
+		}
+		if len(points) == 0 && partial.Dropped > 0 {
+			return partial
+		}
+	}
+
 	if retentionPolicy == "" {
-		db := w.MetaClient.Database(database)
-		if db == nil {
+		if di == nil {
 			return influxdb.ErrDatabaseNotFound(database)
 		}
-		retentionPolicy = db.DefaultRetentionPolicy
+		retentionPolicy = di.DefaultRetentionPolicy
 	}
 
+	var mapSpan *tracing.Span
+	if span != nil {
+		mapSpan = span.StartSpan("map_shards")
+	}
 	shardMappings, err := w.MapShards(&WritePointsRequest{Database: database, RetentionPolicy: retentionPolicy, Points: points})
+	if mapSpan != nil {
+		mapSpan.Finish()
+	}
 	if err != nil {
 		return err
 	}
 
+	// Discard cached query results once the shards have been written to,
+	// whether or not the write succeeds.
+	if w.QueryCache != nil {
+		defer w.QueryCache.Invalidate(database, retentionPolicy, points)
+	}
+
 	// Write each shard in it's own goroutine and return as soon as one fails.
 	ch := make(chan error, len(shardMappings.Points))
 	for shardID, points := range shardMappings.Points {
 		go func(shard *meta.ShardInfo, database, retentionPolicy string, points []models.Point) {
+			if span != nil {
+				span := span.StartSpan("write_shard")
+				defer span.Finish()
+
+				span.SetLabels("shard_id", strconv.FormatUint(shard.ID, 10))
+				span.SetFields(fields.New(fields.Int64("points", int64(len(points)))))
+			}
 			ch <- w.writeToShard(shard, database, retentionPolicy, points)
 		}(shardMappings.Shards[shardID], database, retentionPolicy, points)
 	}
@@ -336,6 +486,9 @@ func (w *PointsWriter) WritePointsPrivileged(database, retentionPolicy string, c
 		atomic.AddInt64(&w.stats.SubWriteDrop, dropped)
 	}
 
+	if err == nil && partial.Dropped > 0 {
+		err = partial
+	}
 	if err == nil && len(shardMappings.Dropped) > 0 {
 		err = tsdb.PartialWriteError{Reason: "points beyond retention policy", Dropped: len(shardMappings.Dropped)}
 
@@ -356,9 +509,168 @@ func (w *PointsWriter) WritePointsPrivileged(database, retentionPolicy string, c
 			}
 		}
 	}
+
+	if w.Forwarder != nil {
+		w.Forwarder.Forward(pts)
+	}
 	return err
 }
 
+// checkSchema returns the points conforming to the schema of a database, if
+// it has one.  If the schema is strict, the other points are dropped and
+// added to partial.  Otherwise they are only counted, and all the points are
+// returned.
+func (w *PointsWriter) checkSchema(database string, schema *meta.SchemaInfo, points []models.Point, partial *tsdb.PartialWriteError) []models.Point {
+	if schema == nil {
+		return points
+	}
+	strict := schema.Mode == meta.SchemaStrict
+
+	var conforming []models.Point
+	var violations int
+	var reason string
+	for i, p := range points {
+		r := schemaViolation(schema, p)
+		if r == "" {
+			if conforming != nil {
+				conforming = append(conforming, p)
+			}
+			continue
+		}
+
+		violations++
+		if reason == "" {
+			reason = r
+		}
+		if strict && conforming == nil {
+			conforming = append(make([]models.Point, 0, len(points)), points[:i]...)
+		}
+	}
+	if violations == 0 {
+		return points
+	}
+
+	w.schemaMu.Lock()
+	stats := w.schemaStats[database]
+	if stats == nil {
+		stats = &SchemaStatistics{}
+		w.schemaStats[database] = stats
+	}
+	stats.Violations += int64(violations)
+	if strict {
+		stats.Dropped += int64(violations)
+	}
+	w.schemaMu.Unlock()
+
+	if !strict {
+		return points
+	}
+	if partial.Reason == "" {
+		partial.Reason = "schema violation: " + reason
+	}
+	partial.Dropped += violations
+	return conforming
+}
+
+// checkWriteLimits returns the points whose timestamps are within the write
+// limits of a database.  The timestamps of the other points are moved to the
+// limits if they are clamped.  Otherwise the points are dropped and added to
+// partial.
+func (w *PointsWriter) checkWriteLimits(limits meta.WriteLimitsInfo, points []models.Point, partial *tsdb.PartialWriteError) []models.Point {
+	if limits.IsZero() {
+		return points
+	}
+
+	now := time.Now().UTC()
+	var future, past time.Time
+	if limits.Future > 0 {
+		future = now.Add(limits.Future)
+	}
+	if limits.Past > 0 {
+		past = now.Add(-limits.Past)
+	}
+
+	var within []models.Point
+	for i, p := range points {
+		var limit time.Time
+		var reason string
+		if t := p.Time(); !future.IsZero() && t.After(future) {
+			limit, reason = future, fmt.Sprintf("point time %s is more than %s ahead of the server time (future write limit)", t.UTC().Format(time.RFC3339Nano), limits.Future)
+		} else if !past.IsZero() && t.Before(past) {
+			limit, reason = past, fmt.Sprintf("point time %s is more than %s behind the server time (past write limit)", t.UTC().Format(time.RFC3339Nano), limits.Past)
+		} else {
+			if within != nil {
+				within = append(within, p)
+			}
+			continue
+		}
+
+		if limits.Clamp {
+			atomic.AddInt64(&w.stats.PointTimeClamped, 1)
+			p.SetTime(limit)
+			if within != nil {
+				within = append(within, p)
+			}
+			continue
+		}
+
+		atomic.AddInt64(&w.stats.PointTimeRejected, 1)
+		if partial.Reason == "" {
+			partial.Reason = reason
+		}
+		partial.Dropped++
+		if within == nil {
+			within = append(make([]models.Point, 0, len(points)), points[:i]...)
+		}
+	}
+	if within == nil {
+		return points
+	}
+	return within
+}
+
+// schemaViolation returns why a point doesn't conform to a schema, or an
+// empty string if it does.
+func schemaViolation(schema *meta.SchemaInfo, p models.Point) string {
+	name := p.Name()
+	msi := schema.Measurement(string(name))
+	if msi == nil {
+		return fmt.Sprintf("measurement %q is not in the schema", name)
+	}
+
+	for _, t := range p.Tags() {
+		if !msi.HasTagKey(string(t.Key)) {
+			return fmt.Sprintf("tag key %q is not in the schema of measurement %q", t.Key, name)
+		}
+	}
+
+	iter := p.FieldIterator()
+	for iter.Next() {
+		f := msi.Field(string(iter.FieldKey()))
+		if f == nil {
+			return fmt.Sprintf("field %q is not in the schema of measurement %q", iter.FieldKey(), name)
+		}
+
+		var typ influxql.DataType
+		switch iter.Type() {
+		case models.Float:
+			typ = influxql.Float
+		case models.Integer:
+			typ = influxql.Integer
+		case models.Unsigned:
+			typ = influxql.Unsigned
+		case models.Boolean:
+			typ = influxql.Boolean
+		case models.String:
+			typ = influxql.String
+		}
+		if typ != f.Type {
+			return fmt.Sprintf("field %q of measurement %q is type %s, the schema type is %s", iter.FieldKey(), name, typ, f.Type)
+		}
+	}
+	return ""
+}
+
 // writeToShards writes points to a shard.
 func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPolicy string, points []models.Point) error {
 	atomic.AddInt64(&w.stats.PointWriteReqLocal, int64(len(points)))
@@ -1,8 +1,10 @@
 package coordinator_test
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/tsdb"
@@ -428,6 +431,288 @@ func TestPointsWriter_WritePoints_Dropped(t *testing.T) {
 	}
 }
 
+// Ensures the points writer rejects all writes while it is read-only.
+func TestPointsWriter_WritePoints_ReadOnly(t *testing.T) {
+	pr := &coordinator.WritePointsRequest{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	store := &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			t.Fatal("unexpected write to shard")
+			return nil
+		},
+	}
+
+	c := coordinator.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.TSDBStore = store
+	c.Node = &influxdb.Node{ID: 1}
+	c.SetReadOnly(true)
+
+	c.Open()
+	defer c.Close()
+
+	if err := c.WritePointsPrivileged(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != coordinator.ErrReadOnly {
+		t.Fatalf("PointsWriter.WritePoints(): got %v, exp %v", err, coordinator.ErrReadOnly)
+	}
+
+	c.SetReadOnly(false)
+	if c.ReadOnly() {
+		t.Fatal("expected points writer to be writable")
+	}
+}
+
+// Ensures the points writer rejects writes to databases over their quotas.
+func TestPointsWriter_WritePoints_Quota(t *testing.T) {
+	pr := &coordinator.WritePointsRequest{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+	pr.AddPoint("cpu", 2.0, time.Now(), nil)
+
+	store := &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			t.Fatal("unexpected write to shard")
+			return nil
+		},
+	}
+
+	quotaErr := influxdb.QuotaError{Database: "mydb", Quota: influxdb.QuotaDisk, Limit: 100}
+	c := coordinator.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.TSDBStore = store
+	c.Node = &influxdb.Node{ID: 1}
+	c.Quotas = quotasFunc(func(database string, n int) error {
+		if database != "mydb" || n != 2 {
+			t.Fatalf("unexpected quota check: %s %d", database, n)
+		}
+		return quotaErr
+	})
+
+	c.Open()
+	defer c.Close()
+
+	if err := c.WritePointsPrivileged(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != quotaErr {
+		t.Fatalf("PointsWriter.WritePoints(): got %v, exp %v", err, quotaErr)
+	}
+}
+
+// Ensures the points writer drops points not conforming to a strict schema,
+// and only counts them for a schema in warn mode.
+func TestPointsWriter_WritePoints_Schema(t *testing.T) {
+	var written int
+	store := &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			written += len(points)
+			return nil
+		},
+	}
+
+	schema := &meta.SchemaInfo{
+		Mode: meta.SchemaStrict,
+		Measurements: []meta.MeasurementSchemaInfo{{
+			Name:    "cpu",
+			TagKeys: []string{"host"},
+			Fields:  []meta.FieldSchemaInfo{{Name: "value", Type: influxql.Float}},
+		}},
+	}
+	ms := NewPointsWriterMetaClient()
+	ms.DatabaseFn = func(database string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{Name: database, Schema: schema}
+	}
+
+	// The points are parsed after the shard groups are created, so that they
+	// are timestamped within the first shard group.
+	points, err := models.ParsePointsString("cpu,host=a value=1\ncpu,hostname=a value=2\ncpu value=3i\nmem value=4\ncpu value=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := coordinator.NewPointsWriter()
+	c.MetaClient = ms
+	c.TSDBStore = store
+	c.Node = &influxdb.Node{ID: 1}
+
+	c.Open()
+	defer c.Close()
+
+	err = c.WritePointsPrivileged("mydb", "myrp", models.ConsistencyLevelOne, points)
+	if werr, ok := err.(tsdb.PartialWriteError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if werr.Dropped != 3 || werr.Reason != `schema violation: tag key "hostname" is not in the schema of measurement "cpu"` {
+		t.Fatalf("unexpected partial write: %+v", werr)
+	} else if written != 2 {
+		t.Fatalf("unexpected points written: got %d, exp 2", written)
+	}
+
+	// Points not conforming to a schema in warn mode are written.
+	schema.Mode = meta.SchemaWarn
+	written = 0
+	if err := c.WritePointsPrivileged("mydb", "myrp", models.ConsistencyLevelOne, points); err != nil {
+		t.Fatal(err)
+	} else if written != 5 {
+		t.Fatalf("unexpected points written: got %d, exp 5", written)
+	}
+
+	var found bool
+	for _, s := range c.Statistics(nil) {
+		if s.Name != "writeSchema" {
+			continue
+		}
+		found = true
+		if s.Tags["database"] != "mydb" || s.Values["violation"] != int64(6) || s.Values["drop"] != int64(3) {
+			t.Fatalf("unexpected statistic: %+v", s)
+		}
+	}
+	if !found {
+		t.Fatal("expected schema statistics")
+	}
+}
+
+func TestPointsWriter_WritePoints_WriteLimits(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour)
+	points, err := models.ParsePointsString(fmt.Sprintf("cpu value=1\ncpu value=2 %d", future.UnixNano()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written []models.Point
+	store := &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			written = append(written, points...)
+			return nil
+		},
+	}
+
+	limits := meta.WriteLimitsInfo{Future: time.Hour}
+	ms := NewPointsWriterMetaClient()
+	ms.DatabaseFn = func(database string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{Name: database, WriteLimits: limits}
+	}
+	// A single shard group covers the points and the clamped points.
+	rp, _ := ms.RetentionPolicyFn("mydb", "myrp")
+	rp.ShardGroups[0].StartTime = time.Now().Add(-time.Hour)
+	rp.ShardGroups[0].EndTime = future.Add(time.Hour)
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		return &rp.ShardGroups[0], nil
+	}
+
+	c := coordinator.NewPointsWriter()
+	c.MetaClient = ms
+	c.TSDBStore = store
+	c.Node = &influxdb.Node{ID: 1}
+
+	c.Open()
+	defer c.Close()
+
+	err = c.WritePoints("mydb", "myrp", models.ConsistencyLevelOne, nil, points)
+	if werr, ok := err.(tsdb.PartialWriteError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if werr.Dropped != 1 || !strings.Contains(werr.Reason, "future write limit") {
+		t.Fatalf("unexpected partial write: %+v", werr)
+	} else if len(written) != 1 {
+		t.Fatalf("unexpected points written: got %d, exp 1", len(written))
+	}
+
+	// The points written by the server, such as by rollups, are not limited.
+	written = nil
+	if err := c.WritePointsInto(&coordinator.IntoWriteRequest{Database: "mydb", RetentionPolicy: "myrp", Points: points}); err != nil {
+		t.Fatal(err)
+	} else if len(written) != 2 {
+		t.Fatalf("unexpected points written: got %d, exp 2", len(written))
+	}
+
+	// Points outside of the limits are moved to the limits when clamped.
+	limits.Clamp = true
+	written = nil
+	if err := c.WritePoints("mydb", "myrp", models.ConsistencyLevelOne, nil, points); err != nil {
+		t.Fatal(err)
+	} else if len(written) != 2 {
+		t.Fatalf("unexpected points written: got %d, exp 2", len(written))
+	}
+	for _, p := range written {
+		if p.Time().After(time.Now().Add(time.Hour)) {
+			t.Fatalf("point time not clamped: %s", p.Time())
+		}
+	}
+
+	for _, s := range c.Statistics(nil) {
+		if s.Name != "write" {
+			continue
+		}
+		if s.Values["pointTimeReject"] != int64(1) || s.Values["pointTimeClamp"] != int64(1) {
+			t.Fatalf("unexpected statistic: %+v", s.Values)
+		}
+	}
+}
+
+// quotasFunc adapts a function to the Quotas of a PointsWriter.
+type quotasFunc func(database string, n int) error
+
+func (fn quotasFunc) CheckWrite(database string, n int) error { return fn(database, n) }
+
+func TestPointsWriter_WritePoints_Forwarder(t *testing.T) {
+	// Ensure that the test shard groups are created before the points
+	// are created.
+	ms := NewPointsWriterMetaClient()
+
+	pr := &coordinator.WritePointsRequest{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	writeErr := errors.New("write failed")
+	var fail bool
+	store := &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			if fail {
+				return writeErr
+			}
+			return nil
+		},
+	}
+
+	fwd := &fakeForwarder{}
+
+	c := coordinator.NewPointsWriter()
+	c.MetaClient = ms
+	c.TSDBStore = store
+	c.Forwarder = fwd
+	c.Node = &influxdb.Node{ID: 1}
+
+	c.Open()
+	defer c.Close()
+
+	if err := c.WritePointsPrivileged(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != nil {
+		t.Fatal(err)
+	} else if len(fwd.requests) != 1 {
+		t.Fatalf("unexpected forwarded requests: got %d, exp 1", len(fwd.requests))
+	} else if got := fwd.requests[0]; got.Database != "mydb" || got.RetentionPolicy != "myrp" || len(got.Points) != 1 {
+		t.Fatalf("unexpected forwarded request: %+v", got)
+	}
+
+	// Failed writes are not forwarded.
+	fail = true
+	if err := c.WritePointsPrivileged(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != writeErr {
+		t.Fatalf("PointsWriter.WritePoints(): got %v, exp %v", err, writeErr)
+	} else if len(fwd.requests) != 1 {
+		t.Fatalf("unexpected forwarded requests: got %d, exp 1", len(fwd.requests))
+	}
+}
+
+type fakeForwarder struct {
+	requests []*coordinator.WritePointsRequest
+}
+
+func (f *fakeForwarder) Forward(p *coordinator.WritePointsRequest) {
+	f.requests = append(f.requests, p)
+}
+
 type fakePointsWriter struct {
 	WritePointsIntoFn func(*coordinator.IntoWriteRequest) error
 }
@@ -609,6 +894,9 @@ func (m PointsWriterMetaClient) CreateShardGroup(database, policy string, timest
 }
 
 func (m PointsWriterMetaClient) Database(database string) *meta.DatabaseInfo {
+	if m.DatabaseFn == nil {
+		return nil
+	}
 	return m.DatabaseFn(database)
 }
 
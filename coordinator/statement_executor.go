@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/influxdb"
@@ -18,7 +19,9 @@ import (
 	"github.com/influxdata/influxdb/pkg/tracing"
 	"github.com/influxdata/influxdb/pkg/tracing/fields"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/continuous_querier"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/services/retention"
 	"github.com/influxdata/influxdb/tsdb"
 )
 
@@ -46,6 +49,18 @@ type StatementExecutor struct {
 	// Holds monitoring data for SHOW STATS and SHOW DIAGNOSTICS.
 	Monitor *monitor.Monitor
 
+	// Reports the status of continuous queries for SHOW CONTINUOUS QUERIES
+	// STATUS. It is nil if the continuous query service is disabled.
+	ContinuousQuerier interface {
+		Statuses() []continuous_querier.Status
+	}
+
+	// Reports the shard groups due for deletion for SHOW RETENTION
+	// ENFORCEMENT. It is nil if retention policy enforcement is disabled.
+	RetentionEnforcer interface {
+		PendingDeletions() []retention.Deletion
+	}
+
 	// Used for rewriting points back into system for SELECT INTO statements.
 	PointsWriter pointsWriter
 
@@ -53,19 +68,53 @@ type StatementExecutor struct {
 	MaxSelectPointN   int
 	MaxSelectSeriesN  int
 	MaxSelectBucketsN int
+
+	// Maximum memory, in bytes, held by the iterators of a query.
+	MaxMemoryPerQuery int64
+
+	// Series cardinality above which unlimited SHOW SERIES and SHOW TAG VALUES
+	// statements return a warning. Zero disables the warning.
+	ShowSeriesWarnN int
+
+	// Guards the limits above once the executor is in use.
+	limitsMu sync.RWMutex
+}
+
+// SetLimits changes the query limits of the executor to those of c.  Queries
+// already running keep the limits they started with.
+func (e *StatementExecutor) SetLimits(c Config) {
+	e.limitsMu.Lock()
+	defer e.limitsMu.Unlock()
+
+	e.MaxSelectPointN = c.MaxSelectPointN
+	e.MaxSelectSeriesN = c.MaxSelectSeriesN
+	e.MaxSelectBucketsN = c.MaxSelectBucketsN
+	e.MaxMemoryPerQuery = int64(c.MaxMemoryPerQuery)
+	e.ShowSeriesWarnN = c.ShowSeriesWarnN
 }
 
 // ExecuteStatement executes the given statement with the given execution context.
 func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx query.ExecutionContext) error {
 	// Select statements are handled separately so that they can be streamed.
 	if stmt, ok := stmt.(*influxql.SelectStatement); ok {
-		return e.executeSelectStatement(context.Background(), stmt, &ctx)
+		// Trace the creation of the iterators of each shard in the span of
+		// the statement, if it is traced.
+		c := context.Background()
+		if ctx.Span != nil {
+			c = tracing.NewContextWithSpan(c, ctx.Span)
+		}
+		return e.executeSelectStatement(c, stmt, &ctx)
 	}
 
 	var rows models.Rows
 	var messages []*query.Message
 	var err error
 	switch stmt := stmt.(type) {
+	case *influxql.AlterDatabaseStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeAlterDatabaseStatement(stmt)
 	case *influxql.AlterRetentionPolicyStatement:
 		if ctx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
@@ -91,6 +140,11 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx query.
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
 		err = e.executeCreateSubscriptionStatement(stmt)
+	case *influxql.CreateTokenStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		rows, err = e.executeCreateTokenStatement(stmt)
 	case *influxql.CreateUserStatement:
 		if ctx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
@@ -133,6 +187,11 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx query.
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
 		err = e.executeDropSubscriptionStatement(stmt)
+	case *influxql.DropTokenStatement:
+		if ctx.ReadOnly {
+			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
+		}
+		err = e.executeDropTokenStatement(stmt)
 	case *influxql.DropUserStatement:
 		if ctx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
@@ -176,6 +235,10 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx query.
 		return e.executeShowMeasurementsStatement(stmt, &ctx)
 	case *influxql.ShowMeasurementCardinalityStatement:
 		rows, err = e.executeShowMeasurementCardinalityStatement(stmt)
+	case *influxql.ShowQuotasStatement:
+		rows, err = e.executeShowQuotasStatement(stmt)
+	case *influxql.ShowRetentionEnforcementStatement:
+		rows, err = e.executeShowRetentionEnforcementStatement(stmt)
 	case *influxql.ShowRetentionPoliciesStatement:
 		rows, err = e.executeShowRetentionPoliciesStatement(stmt)
 	case *influxql.ShowSeriesCardinalityStatement:
@@ -190,6 +253,8 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx query.
 		rows, err = e.executeShowSubscriptionsStatement(stmt)
 	case *influxql.ShowTagValuesStatement:
 		return e.executeShowTagValues(stmt, &ctx)
+	case *influxql.ShowTokensStatement:
+		rows, err = e.executeShowTokensStatement(stmt)
 	case *influxql.ShowUsersStatement:
 		rows, err = e.executeShowUsersStatement(stmt)
 	case *influxql.SetPasswordUserStatement:
@@ -215,11 +280,32 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx query.
 	})
 }
 
+func (e *StatementExecutor) executeAlterDatabaseStatement(stmt *influxql.AlterDatabaseStatement) error {
+	if stmt.SetsWriteLimits() {
+		return e.MetaClient.UpdateWriteLimits(stmt.Name, &meta.WriteLimitsUpdate{
+			Future: stmt.FutureWriteLimit,
+			Past:   stmt.PastWriteLimit,
+			Clamp:  stmt.ClampWrites,
+		})
+	}
+	return e.MetaClient.UpdateQuota(stmt.Name, &meta.QuotaUpdate{
+		DiskBytes:       stmt.DiskQuota,
+		SeriesN:         stmt.SeriesQuota,
+		WritesPerSecond: stmt.WriteQuota,
+	})
+}
+
 func (e *StatementExecutor) executeAlterRetentionPolicyStatement(stmt *influxql.AlterRetentionPolicyStatement) error {
 	rpu := &meta.RetentionPolicyUpdate{
-		Duration:           stmt.Duration,
-		ReplicaN:           stmt.Replication,
-		ShardGroupDuration: stmt.ShardGroupDuration,
+		Duration:             stmt.Duration,
+		ReplicaN:             stmt.Replication,
+		ShardGroupDuration:   stmt.ShardGroupDuration,
+		MeasurementDurations: stmt.MeasurementDurations,
+	}
+	if stmt.Rollup != nil {
+		rpu.SetRollup(stmt.Rollup.String())
+	} else if stmt.DropRollup {
+		rpu.SetRollup("")
 	}
 
 	// Update the retention policy.
@@ -302,6 +388,20 @@ func (e *StatementExecutor) executeCreateRetentionPolicyStatement(stmt *influxql
 		ShardGroupDuration: stmt.ShardGroupDuration,
 	}
 
+	// A measurement duration of INF is the same as no duration.
+	for name, d := range stmt.MeasurementDurations {
+		if d == 0 {
+			continue
+		}
+		if spec.MeasurementDurations == nil {
+			spec.MeasurementDurations = make(map[string]time.Duration)
+		}
+		spec.MeasurementDurations[name] = d
+	}
+	if stmt.Rollup != nil {
+		spec.Rollup = stmt.Rollup.String()
+	}
+
 	// Create new retention policy.
 	_, err := e.MetaClient.CreateRetentionPolicy(stmt.Database, &spec, stmt.Default)
 	if err != nil {
@@ -312,7 +412,27 @@ func (e *StatementExecutor) executeCreateRetentionPolicyStatement(stmt *influxql
 }
 
 func (e *StatementExecutor) executeCreateSubscriptionStatement(q *influxql.CreateSubscriptionStatement) error {
-	return e.MetaClient.CreateSubscription(q.Database, q.RetentionPolicy, q.Name, q.Mode, q.Destinations)
+	var filter string
+	if q.Condition != nil {
+		filter = q.Condition.String()
+	}
+	return e.MetaClient.CreateSubscription(q.Database, q.RetentionPolicy, q.Name, q.Mode, q.Destinations, filter)
+}
+
+func (e *StatementExecutor) executeCreateTokenStatement(q *influxql.CreateTokenStatement) (models.Rows, error) {
+	var expires time.Time
+	if q.Expires > 0 {
+		expires = time.Now().UTC().Add(q.Expires)
+	}
+
+	ti, token, err := e.MetaClient.CreateToken(q.User, q.Database, q.Privilege, expires)
+	if err != nil {
+		return nil, err
+	}
+	return []*models.Row{{
+		Columns: []string{"id", "token"},
+		Values:  [][]interface{}{{ti.ID, token}},
+	}}, nil
 }
 
 func (e *StatementExecutor) executeCreateUserStatement(q *influxql.CreateUserStatement) error {
@@ -408,16 +528,21 @@ func (e *StatementExecutor) executeDropSubscriptionStatement(q *influxql.DropSub
 	return e.MetaClient.DropSubscription(q.Database, q.RetentionPolicy, q.Name)
 }
 
+func (e *StatementExecutor) executeDropTokenStatement(q *influxql.DropTokenStatement) error {
+	return e.MetaClient.DropToken(q.ID)
+}
+
 func (e *StatementExecutor) executeDropUserStatement(q *influxql.DropUserStatement) error {
 	return e.MetaClient.DropUser(q.Name)
 }
 
 func (e *StatementExecutor) executeExplainStatement(q *influxql.ExplainStatement, ectx *query.ExecutionContext) (models.Rows, error) {
+	_, maxSeriesN, maxBucketsN := e.selectLimits(ectx)
 	opt := query.SelectOptions{
 		InterruptCh: ectx.InterruptCh,
 		NodeID:      ectx.ExecutionOptions.NodeID,
-		MaxSeriesN:  e.MaxSelectSeriesN,
-		MaxBucketsN: e.MaxSelectBucketsN,
+		MaxSeriesN:  maxSeriesN,
+		MaxBucketsN: maxBucketsN,
 		Authorizer:  ectx.Authorizer,
 	}
 
@@ -453,7 +578,7 @@ func (e *StatementExecutor) executeExplainAnalyzeStatement(q *influxql.ExplainSt
 	ctx = query.NewContextWithIterators(ctx, &aux)
 	start := time.Now()
 
-	itrs, columns, err := e.createIterators(ctx, stmt, ectx)
+	itrs, columns, err := e.createIterators(ctx, stmt, ectx, e.newMemoryAccountant())
 	if err != nil {
 		return nil, err
 	}
@@ -504,6 +629,7 @@ CLEANUP:
 		fields.Duration("total_time", totalTime),
 		fields.Duration("planning_time", iterTime),
 		fields.Duration("execution_time", totalTime-iterTime),
+		fields.Int64("total_rows", writeN),
 	)
 	span.Finish()
 
@@ -550,7 +676,17 @@ func (e *StatementExecutor) executeSetPasswordUserStatement(q *influxql.SetPassw
 }
 
 func (e *StatementExecutor) executeSelectStatement(ctx context.Context, stmt *influxql.SelectStatement, ectx *query.ExecutionContext) error {
-	itrs, columns, err := e.createIterators(ctx, stmt, ectx)
+	// Warn before streaming the series of a high cardinality database.
+	var messages []*query.Message
+	if database, ok := showSeriesDatabase(stmt); ok {
+		var err error
+		if messages, err = e.showSeriesWarning(database, stmt.Limit); err != nil {
+			return err
+		}
+	}
+
+	mem := e.newMemoryAccountant()
+	itrs, columns, err := e.createIterators(ctx, stmt, ectx, mem)
 	if err != nil {
 		return err
 	}
@@ -563,8 +699,16 @@ func (e *StatementExecutor) executeSelectStatement(ctx context.Context, stmt *in
 	}
 	em.OmitTime = stmt.OmitTime
 	em.EmitName = stmt.EmitName
+	em.Memory = mem
 	defer em.Close()
 
+	// Rows sorted by a field are sorted and limited by the emitter.
+	if em.SortKeys, err = query.SortKeys(stmt, columns); err != nil {
+		return err
+	} else if len(em.SortKeys) > 0 {
+		em.Limit, em.Offset = stmt.Limit, stmt.Offset
+	}
+
 	// Emit rows to the results channel.
 	var writeN int64
 	var emitted bool
@@ -588,6 +732,11 @@ func (e *StatementExecutor) executeSelectStatement(ctx context.Context, stmt *in
 			break
 		}
 
+		// Track the row for SHOW QUERIES.
+		if ectx.Query != nil {
+			ectx.Query.TrackRow(row)
+		}
+
 		// Write points back into system for INTO statements.
 		if stmt.Target != nil {
 			if err := e.writeInto(pointsWriter, stmt, row); err != nil {
@@ -600,6 +749,7 @@ func (e *StatementExecutor) executeSelectStatement(ctx context.Context, stmt *in
 		result := &query.Result{
 			StatementID: ectx.StatementID,
 			Series:      []*models.Row{row},
+			Messages:    messages,
 			Partial:     partial,
 		}
 
@@ -608,6 +758,7 @@ func (e *StatementExecutor) executeSelectStatement(ctx context.Context, stmt *in
 			return err
 		}
 
+		messages = nil
 		emitted = true
 	}
 
@@ -638,19 +789,36 @@ func (e *StatementExecutor) executeSelectStatement(ctx context.Context, stmt *in
 		return ectx.Send(&query.Result{
 			StatementID: ectx.StatementID,
 			Series:      make([]*models.Row, 0),
+			Messages:    messages,
 		})
 	}
 
 	return nil
 }
 
-func (e *StatementExecutor) createIterators(ctx context.Context, stmt *influxql.SelectStatement, ectx *query.ExecutionContext) ([]query.Iterator, []string, error) {
+// newMemoryAccountant returns the accountant of the memory held by a query,
+// or nil if the memory of queries is unlimited.
+func (e *StatementExecutor) newMemoryAccountant() *query.MemoryAccountant {
+	e.limitsMu.RLock()
+	maxMemory := e.MaxMemoryPerQuery
+	e.limitsMu.RUnlock()
+	if maxMemory > 0 {
+		return query.NewMemoryAccountant(maxMemory)
+	}
+	return nil
+}
+
+// createIterators creates the iterators of a SELECT, accounting the memory
+// they hold to mem.
+func (e *StatementExecutor) createIterators(ctx context.Context, stmt *influxql.SelectStatement, ectx *query.ExecutionContext, mem *query.MemoryAccountant) ([]query.Iterator, []string, error) {
+	maxPointN, maxSeriesN, maxBucketsN := e.selectLimits(ectx)
 	opt := query.SelectOptions{
 		InterruptCh: ectx.InterruptCh,
 		NodeID:      ectx.ExecutionOptions.NodeID,
-		MaxSeriesN:  e.MaxSelectSeriesN,
-		MaxBucketsN: e.MaxSelectBucketsN,
+		MaxSeriesN:  maxSeriesN,
+		MaxBucketsN: maxBucketsN,
 		Authorizer:  ectx.Authorizer,
+		Memory:      mem,
 	}
 
 	// Create a set of iterators from a selection.
@@ -658,16 +826,40 @@ func (e *StatementExecutor) createIterators(ctx context.Context, stmt *influxql.
 	if err != nil {
 		return nil, nil, err
 	}
+	if ectx.Query != nil {
+		ectx.Query.TrackSeries(query.Iterators(itrs).Stats().SeriesN)
+	}
 
-	if e.MaxSelectPointN > 0 {
-		monitor := query.PointLimitMonitor(itrs, query.DefaultStatsInterval, e.MaxSelectPointN)
+	if maxPointN > 0 {
+		monitor := query.PointLimitMonitor(itrs, query.DefaultStatsInterval, maxPointN)
 		ectx.Query.Monitor(monitor)
 	}
 	return itrs, columns, nil
 }
 
+// selectLimits returns the point, series and bucket limits of a SELECT.
+// The limits set for the query override those of the executor.
+func (e *StatementExecutor) selectLimits(ectx *query.ExecutionContext) (pointN, seriesN, bucketsN int) {
+	e.limitsMu.RLock()
+	pointN, seriesN, bucketsN = e.MaxSelectPointN, e.MaxSelectSeriesN, e.MaxSelectBucketsN
+	e.limitsMu.RUnlock()
+	if n := ectx.MaxSelectPointN; n > 0 {
+		pointN = n
+	}
+	if n := ectx.MaxSelectSeriesN; n > 0 {
+		seriesN = n
+	}
+	if n := ectx.MaxSelectBucketsN; n > 0 {
+		bucketsN = n
+	}
+	return pointN, seriesN, bucketsN
+}
+
 func (e *StatementExecutor) executeShowContinuousQueriesStatement(stmt *influxql.ShowContinuousQueriesStatement) (models.Rows, error) {
 	dis := e.MetaClient.Databases()
+	if stmt.Status {
+		return e.continuousQueryStatusRows(dis), nil
+	}
 
 	rows := []*models.Row{}
 	for _, di := range dis {
@@ -680,6 +872,59 @@ func (e *StatementExecutor) executeShowContinuousQueriesStatement(stmt *influxql
 	return rows, nil
 }
 
+// continuousQueryStatusRows returns the execution status of each continuous
+// query. Queries that have not run since the server started have no status.
+func (e *StatementExecutor) continuousQueryStatusRows(dis []meta.DatabaseInfo) models.Rows {
+	statuses := make(map[[2]string]continuous_querier.Status)
+	if e.ContinuousQuerier != nil {
+		for _, st := range e.ContinuousQuerier.Statuses() {
+			statuses[[2]string{st.Database, st.Name}] = st
+		}
+	}
+
+	formatTime := func(t time.Time) interface{} {
+		if t.IsZero() {
+			return nil
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+
+	rows := []*models.Row{}
+	for _, di := range dis {
+		row := &models.Row{
+			Columns: []string{"name", "last_run", "duration", "points_written", "runs", "failures", "last_error", "last_error_time"},
+			Name:    di.Name,
+		}
+		for _, cqi := range di.ContinuousQueries {
+			st, ok := statuses[[2]string{di.Name, cqi.Name}]
+			if !ok {
+				row.Values = append(row.Values, []interface{}{cqi.Name, nil, nil, nil, int64(0), int64(0), nil, nil})
+				continue
+			}
+
+			var duration, lastError interface{}
+			if st.Runs > 0 {
+				duration = st.Duration.String()
+			}
+			if st.LastError != "" {
+				lastError = st.LastError
+			}
+			row.Values = append(row.Values, []interface{}{
+				cqi.Name,
+				formatTime(st.LastRun),
+				duration,
+				st.PointsWritten,
+				st.Runs,
+				st.Failures,
+				lastError,
+				formatTime(st.LastErrorTime),
+			})
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
 func (e *StatementExecutor) executeShowDatabasesStatement(q *influxql.ShowDatabasesStatement, ctx *query.ExecutionContext) (models.Rows, error) {
 	dis := e.MetaClient.Databases()
 	a := ctx.ExecutionOptions.Authorizer
@@ -812,12 +1057,44 @@ func (e *StatementExecutor) executeShowRetentionPoliciesStatement(q *influxql.Sh
 	return []*models.Row{row}, nil
 }
 
+func (e *StatementExecutor) executeShowQuotasStatement(stmt *influxql.ShowQuotasStatement) (models.Rows, error) {
+	row := &models.Row{Name: "quotas", Columns: []string{"name", "disk", "series", "writes"}}
+	for _, di := range e.MetaClient.Databases() {
+		row.Values = append(row.Values, []interface{}{di.Name, di.Quota.DiskBytes, di.Quota.SeriesN, di.Quota.WritesPerSecond})
+	}
+	return []*models.Row{row}, nil
+}
+
+func (e *StatementExecutor) executeShowRetentionEnforcementStatement(stmt *influxql.ShowRetentionEnforcementStatement) (models.Rows, error) {
+	row := &models.Row{Columns: []string{"database", "retention_policy", "shard_group", "shards", "start_time", "end_time", "size"}, Name: "retention enforcement"}
+	if e.RetentionEnforcer != nil {
+		for _, d := range e.RetentionEnforcer.PendingDeletions() {
+			row.Values = append(row.Values, []interface{}{
+				d.Database,
+				d.RetentionPolicy,
+				d.ShardGroupID,
+				joinUint64(d.ShardIDs),
+				d.StartTime.UTC().Format(time.RFC3339),
+				d.EndTime.UTC().Format(time.RFC3339),
+				d.Size,
+			})
+		}
+	}
+	return []*models.Row{row}, nil
+}
+
 func (e *StatementExecutor) executeShowShardsStatement(stmt *influxql.ShowShardsStatement) (models.Rows, error) {
 	dis := e.MetaClient.Databases()
 
+	// The storage of shards not open on this node is left empty.
+	storage := make(map[uint64]tsdb.ShardStorage)
+	for _, st := range e.TSDBStore.ShardsStorage() {
+		storage[st.ID] = st
+	}
+
 	rows := []*models.Row{}
 	for _, di := range dis {
-		row := &models.Row{Columns: []string{"id", "database", "retention_policy", "shard_group", "start_time", "end_time", "expiry_time", "owners"}, Name: di.Name}
+		row := &models.Row{Columns: []string{"id", "database", "retention_policy", "shard_group", "start_time", "end_time", "expiry_time", "owners", "disk_bytes", "tsm_files", "series", "last_write", "compaction_backlog"}, Name: di.Name}
 		for _, rpi := range di.RetentionPolicies {
 			for _, sgi := range rpi.ShardGroups {
 				// Shards associated with deleted shard groups are effectively deleted.
@@ -832,7 +1109,7 @@ func (e *StatementExecutor) executeShowShardsStatement(stmt *influxql.ShowShards
 						ownerIDs[i] = owner.NodeID
 					}
 
-					row.Values = append(row.Values, []interface{}{
+					values := []interface{}{
 						si.ID,
 						di.Name,
 						rpi.Name,
@@ -841,7 +1118,17 @@ func (e *StatementExecutor) executeShowShardsStatement(stmt *influxql.ShowShards
 						sgi.EndTime.UTC().Format(time.RFC3339),
 						sgi.EndTime.Add(rpi.Duration).UTC().Format(time.RFC3339),
 						joinUint64(ownerIDs),
-					})
+					}
+					if st, ok := storage[si.ID]; ok {
+						var lastWrite interface{}
+						if !st.LastModified.IsZero() {
+							lastWrite = st.LastModified.UTC().Format(time.RFC3339)
+						}
+						values = append(values, st.DiskBytes, st.TSMFiles, st.SeriesN, lastWrite, st.CompactionBacklog)
+					} else {
+						values = append(values, nil, nil, nil, nil, nil)
+					}
+					row.Values = append(row.Values, values)
 				}
 			}
 		}
@@ -919,10 +1206,10 @@ func (e *StatementExecutor) executeShowSubscriptionsStatement(stmt *influxql.Sho
 
 	rows := []*models.Row{}
 	for _, di := range dis {
-		row := &models.Row{Columns: []string{"retention_policy", "name", "mode", "destinations"}, Name: di.Name}
+		row := &models.Row{Columns: []string{"retention_policy", "name", "mode", "destinations", "filter"}, Name: di.Name}
 		for _, rpi := range di.RetentionPolicies {
 			for _, si := range rpi.Subscriptions {
-				row.Values = append(row.Values, []interface{}{rpi.Name, si.Name, si.Mode, si.Destinations})
+				row.Values = append(row.Values, []interface{}{rpi.Name, si.Name, si.Mode, si.Destinations, si.Filter})
 			}
 		}
 		if len(row.Values) > 0 {
@@ -937,10 +1224,18 @@ func (e *StatementExecutor) executeShowTagValues(q *influxql.ShowTagValuesStatem
 		return ErrDatabaseNameRequired
 	}
 
-	tagValues, err := e.TSDBStore.TagValues(ctx.Authorizer, q.Database, q.Condition)
+	messages, err := e.showSeriesWarning(q.Database, q.Limit)
+	if err != nil {
+		return err
+	}
+
+	// The offset and limit apply to each measurement and are pushed down into
+	// the store so that values beyond the limit are never collected.
+	tagValues, err := e.TSDBStore.TagValuesLimit(ctx.Authorizer, q.Database, q.Condition, q.Offset, q.Limit)
 	if err != nil {
 		return ctx.Send(&query.Result{
 			StatementID: ctx.StatementID,
+			Messages:    messages,
 			Err:         err,
 		})
 	}
@@ -949,51 +1244,106 @@ func (e *StatementExecutor) executeShowTagValues(q *influxql.ShowTagValuesStatem
 	for _, m := range tagValues {
 		values := m.Values
 
-		if q.Offset > 0 {
-			if q.Offset >= len(values) {
-				values = nil
-			} else {
-				values = values[q.Offset:]
+		// Stream the values of each measurement in chunks, marking all but the
+		// last chunk as partial, in the same way as SELECT results.
+		for len(values) > 0 {
+			n := len(values)
+			if ctx.ChunkSize > 0 && n > ctx.ChunkSize {
+				n = ctx.ChunkSize
 			}
-		}
 
-		if q.Limit > 0 {
-			if q.Limit < len(values) {
-				values = values[:q.Limit]
+			row := &models.Row{
+				Name:    m.Measurement,
+				Columns: []string{"key", "value"},
+				Values:  make([][]interface{}, n),
 			}
+			for i, v := range values[:n] {
+				row.Values[i] = []interface{}{v.Key, v.Value}
+			}
+			values = values[n:]
+
+			if err := ctx.Send(&query.Result{
+				StatementID: ctx.StatementID,
+				Series:      []*models.Row{row},
+				Messages:    messages,
+				Partial:     len(values) > 0,
+			}); err != nil {
+				return err
+			}
+			messages = nil
+			emitted = true
 		}
-
-		if len(values) == 0 {
-			continue
-		}
-
-		row := &models.Row{
-			Name:    m.Measurement,
-			Columns: []string{"key", "value"},
-			Values:  make([][]interface{}, len(values)),
-		}
-		for i, v := range values {
-			row.Values[i] = []interface{}{v.Key, v.Value}
-		}
-
-		if err := ctx.Send(&query.Result{
-			StatementID: ctx.StatementID,
-			Series:      []*models.Row{row},
-		}); err != nil {
-			return err
-		}
-		emitted = true
 	}
 
 	// Ensure at least one result is emitted.
 	if !emitted {
 		return ctx.Send(&query.Result{
 			StatementID: ctx.StatementID,
+			Messages:    messages,
 		})
 	}
 	return nil
 }
 
+// showSeriesDatabase returns the database of a SELECT statement rewritten
+// from a SHOW SERIES statement.
+func showSeriesDatabase(stmt *influxql.SelectStatement) (string, bool) {
+	if len(stmt.Fields) != 1 || len(stmt.Sources) == 0 {
+		return "", false
+	} else if ref, ok := stmt.Fields[0].Expr.(*influxql.VarRef); !ok || ref.Val != "_seriesKey" {
+		return "", false
+	}
+
+	m, ok := stmt.Sources[0].(*influxql.Measurement)
+	if !ok {
+		return "", false
+	}
+	return m.Database, true
+}
+
+// showSeriesWarning returns a warning if a SHOW SERIES or SHOW TAG VALUES
+// statement against database may return more rows than ShowSeriesWarnN.
+func (e *StatementExecutor) showSeriesWarning(database string, limit int) ([]*query.Message, error) {
+	e.limitsMu.RLock()
+	warnN := e.ShowSeriesWarnN
+	e.limitsMu.RUnlock()
+
+	if warnN <= 0 || (limit > 0 && limit <= warnN) {
+		return nil, nil
+	}
+
+	n, err := e.TSDBStore.SeriesCardinality(database)
+	if err != nil {
+		return nil, err
+	} else if n <= int64(warnN) {
+		return nil, nil
+	}
+
+	return []*query.Message{{
+		Level: query.WarningLevel,
+		Text:  fmt.Sprintf("database %q has an estimated %d series, results may be very large: consider adding a LIMIT or a WHERE clause", database, n),
+	}}, nil
+}
+
+func (e *StatementExecutor) executeShowTokensStatement(q *influxql.ShowTokensStatement) (models.Rows, error) {
+	row := &models.Row{Columns: []string{"id", "user", "database", "privilege", "expires"}}
+	for _, ti := range e.MetaClient.Tokens() {
+		if q.User != "" && ti.User != q.User {
+			continue
+		}
+
+		var privilege, expires string
+		if ti.Database != "" {
+			privilege = ti.Privilege.String()
+		}
+		if !ti.Expires.IsZero() {
+			expires = ti.Expires.UTC().Format(time.RFC3339Nano)
+		}
+		row.Values = append(row.Values, []interface{}{ti.ID, ti.User, ti.Database, privilege, expires})
+	}
+	return []*models.Row{row}, nil
+}
+
 func (e *StatementExecutor) executeShowUsersStatement(q *influxql.ShowUsersStatement) (models.Rows, error) {
 	row := &models.Row{Columns: []string{"user", "admin"}}
 	for _, ui := range e.MetaClient.Users() {
@@ -1252,9 +1602,12 @@ type TSDBStore interface {
 
 	MeasurementNames(database string, cond influxql.Expr) ([][]byte, error)
 	TagValues(auth query.Authorizer, database string, cond influxql.Expr) ([]tsdb.TagValues, error)
+	TagValuesLimit(auth query.Authorizer, database string, cond influxql.Expr, offset, limit int) ([]tsdb.TagValues, error)
 
 	SeriesCardinality(database string) (int64, error)
 	MeasurementsCardinality(database string) (int64, error)
+
+	ShardsStorage() []tsdb.ShardStorage
 }
 
 var _ TSDBStore = LocalTSDBStore{}
@@ -258,6 +258,158 @@ func TestQueryExecutor_ExecuteQuery_ShowDatabases(t *testing.T) {
 	}
 }
 
+// Ensure SHOW TAG VALUES pushes down its limit, streams chunks and warns on high cardinality.
+func TestQueryExecutor_ExecuteQuery_ShowTagValues(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.StatementExecutor.ShowSeriesWarnN = 2
+	e.TSDBStore.SeriesCardinalityFn = func(database string) (int64, error) {
+		return 3, nil
+	}
+	e.TSDBStore.TagValuesLimitFn = func(database string, cond influxql.Expr, offset, limit int) ([]tsdb.TagValues, error) {
+		if offset != 1 || limit != 3 {
+			t.Fatalf("unexpected offset/limit: %d/%d", offset, limit)
+		}
+		return []tsdb.TagValues{{
+			Measurement: "cpu",
+			Values: []tsdb.KeyValue{
+				{Key: "host", Value: "server01"},
+				{Key: "host", Value: "server02"},
+				{Key: "host", Value: "server03"},
+			},
+		}}, nil
+	}
+
+	results := ReadAllResults(e.ExecuteQuery(`SHOW TAG VALUES WITH KEY = "host" LIMIT 3 OFFSET 1`, "db0", 2))
+	exp := []*query.Result{
+		{
+			StatementID: 0,
+			Series: []*models.Row{{
+				Name:    "cpu",
+				Columns: []string{"key", "value"},
+				Values:  [][]interface{}{{"host", "server01"}, {"host", "server02"}},
+			}},
+			Messages: []*query.Message{{
+				Level: query.WarningLevel,
+				Text:  `database "db0" has an estimated 3 series, results may be very large: consider adding a LIMIT or a WHERE clause`,
+			}},
+			Partial: true,
+		},
+		{
+			StatementID: 0,
+			Series: []*models.Row{{
+				Name:    "cpu",
+				Columns: []string{"key", "value"},
+				Values:  [][]interface{}{{"host", "server03"}},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(results, exp) {
+		t.Fatalf("unexpected results: exp %s, got %s", spew.Sdump(exp), spew.Sdump(results))
+	}
+}
+
+// Ensure CREATE TOKEN returns the new token and SHOW TOKENS lists tokens without their secrets.
+func TestQueryExecutor_ExecuteQuery_Tokens(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.MetaClient.CreateTokenFn = func(username, database string, p influxql.Privilege, expires time.Time) (*meta.TokenInfo, string, error) {
+		if username != "fred" || database != "db0" || p != influxql.WritePrivilege {
+			t.Fatalf("unexpected token: %s %s %s", username, database, p)
+		} else if d := time.Until(expires); d <= 0 || d > time.Hour {
+			t.Fatalf("unexpected expiry: %s", expires)
+		}
+		return &meta.TokenInfo{ID: "abc", User: username, Database: database, Privilege: p, Expires: expires}, "abc.secret", nil
+	}
+	e.MetaClient.TokensFn = func() []meta.TokenInfo {
+		return []meta.TokenInfo{
+			{ID: "abc", User: "fred", Hash: "hash", Database: "db0", Privilege: influxql.WritePrivilege, Expires: time.Unix(0, 0).UTC()},
+			{ID: "def", User: "wilma", Hash: "hash"},
+		}
+	}
+
+	results := ReadAllResults(e.ExecuteQuery(`CREATE TOKEN FOR USER fred WITH WRITE ON db0 EXPIRES 1h; SHOW TOKENS FOR USER fred`, "", 0))
+	exp := []*query.Result{
+		{
+			StatementID: 0,
+			Series: []*models.Row{{
+				Columns: []string{"id", "token"},
+				Values:  [][]interface{}{{"abc", "abc.secret"}},
+			}},
+		},
+		{
+			StatementID: 1,
+			Series: []*models.Row{{
+				Columns: []string{"id", "user", "database", "privilege", "expires"},
+				Values:  [][]interface{}{{"abc", "fred", "db0", "WRITE", "1970-01-01T00:00:00Z"}},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(results, exp) {
+		t.Fatalf("unexpected results: exp %s, got %s", spew.Sdump(exp), spew.Sdump(results))
+	}
+}
+
+func TestQueryExecutor_ExecuteQuery_Quotas(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.MetaClient.UpdateQuotaFn = func(database string, qu *meta.QuotaUpdate) error {
+		if database != "db0" {
+			t.Fatalf("unexpected database: %s", database)
+		} else if qu.DiskBytes == nil || *qu.DiskBytes != 1<<30 {
+			t.Fatalf("unexpected disk quota: %v", qu.DiskBytes)
+		} else if qu.SeriesN != nil {
+			t.Fatalf("unexpected series quota: %v", qu.SeriesN)
+		} else if qu.WritesPerSecond == nil || *qu.WritesPerSecond != 0 {
+			t.Fatalf("unexpected writes quota: %v", qu.WritesPerSecond)
+		}
+		return nil
+	}
+	e.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		return []meta.DatabaseInfo{
+			{Name: "db0", Quota: meta.QuotaInfo{DiskBytes: 1 << 30}},
+			{Name: "db1"},
+		}
+	}
+
+	results := ReadAllResults(e.ExecuteQuery(`ALTER DATABASE db0 SET QUOTA disk=1GB, writes=0; SHOW QUOTAS`, "", 0))
+	exp := []*query.Result{
+		{StatementID: 0},
+		{
+			StatementID: 1,
+			Series: []*models.Row{{
+				Name:    "quotas",
+				Columns: []string{"name", "disk", "series", "writes"},
+				Values: [][]interface{}{
+					{"db0", int64(1 << 30), int64(0), int64(0)},
+					{"db1", int64(0), int64(0), int64(0)},
+				},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(results, exp) {
+		t.Fatalf("unexpected results: exp %s, got %s", spew.Sdump(exp), spew.Sdump(results))
+	}
+}
+
+func TestQueryExecutor_ExecuteQuery_WriteLimits(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.MetaClient.UpdateWriteLimitsFn = func(database string, wlu *meta.WriteLimitsUpdate) error {
+		if database != "db0" {
+			t.Fatalf("unexpected database: %s", database)
+		} else if wlu.Future == nil || *wlu.Future != time.Hour {
+			t.Fatalf("unexpected future write limit: %v", wlu.Future)
+		} else if wlu.Past != nil {
+			t.Fatalf("unexpected past write limit: %v", wlu.Past)
+		} else if wlu.Clamp == nil || !*wlu.Clamp {
+			t.Fatalf("unexpected clamp: %v", wlu.Clamp)
+		}
+		return nil
+	}
+
+	results := ReadAllResults(e.ExecuteQuery(`ALTER DATABASE db0 SET WRITE LIMIT future=1h, clamp=true`, "", 0))
+	if exp := []*query.Result{{StatementID: 0}}; !reflect.DeepEqual(results, exp) {
+		t.Fatalf("unexpected results: exp %s, got %s", spew.Sdump(exp), spew.Sdump(results))
+	}
+}
+
 // QueryExecutor is a test wrapper for coordinator.QueryExecutor.
 type QueryExecutor struct {
 	*query.QueryExecutor
@@ -326,7 +478,9 @@ type TSDBStore struct {
 	DeleteSeriesFn            func(database string, sources []influxql.Source, condition influxql.Expr) error
 	ShardGroupFn              func(ids []uint64) tsdb.ShardGroup
 	MeasurementsCardinalityFn func(database string) (int64, error)
+	ShardsStorageFn           func() []tsdb.ShardStorage
 	SeriesCardinalityFn       func(database string) (int64, error)
+	TagValuesLimitFn          func(database string, cond influxql.Expr, offset, limit int) ([]tsdb.TagValues, error)
 }
 
 func (s *TSDBStore) CreateShard(database, policy string, shardID uint64, enabled bool) error {
@@ -384,10 +538,21 @@ func (s *TSDBStore) MeasurementsCardinality(database string) (int64, error) {
 	return s.MeasurementsCardinalityFn(database)
 }
 
+func (s *TSDBStore) ShardsStorage() []tsdb.ShardStorage {
+	return s.ShardsStorageFn()
+}
+
 func (s *TSDBStore) TagValues(_ query.Authorizer, database string, cond influxql.Expr) ([]tsdb.TagValues, error) {
 	return nil, nil
 }
 
+func (s *TSDBStore) TagValuesLimit(_ query.Authorizer, database string, cond influxql.Expr, offset, limit int) ([]tsdb.TagValues, error) {
+	if s.TagValuesLimitFn == nil {
+		return nil, nil
+	}
+	return s.TagValuesLimitFn(database, cond, offset, limit)
+}
+
 func (s *TSDBStore) SeriesCardinality(database string) (int64, error) {
 	return s.SeriesCardinalityFn(database)
 }
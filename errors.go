@@ -20,6 +20,25 @@ func ErrRetentionPolicyNotFound(name string) error {
 	return fmt.Errorf("retention policy not found: %s", name)
 }
 
+// Quotas of a database.
+const (
+	QuotaDisk   = "disk"
+	QuotaSeries = "series"
+	QuotaWrites = "writes"
+)
+
+// QuotaError is returned when a write to a database is rejected because the
+// database has exceeded one of its quotas.
+type QuotaError struct {
+	Database string
+	Quota    string
+	Limit    int64
+}
+
+func (e QuotaError) Error() string {
+	return fmt.Sprintf("quota exceeded: database %s has reached its %s quota of %d", e.Database, e.Quota, e.Limit)
+}
+
 // IsAuthorizationError indicates whether an error is due to an authorization failure
 func IsAuthorizationError(err error) bool {
 	e, ok := err.(interface {
@@ -4,17 +4,42 @@ package v8 // import "github.com/influxdata/influxdb/importer/v8"
 import (
 	"bufio"
 	"compress/gzip"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/influxdb/client"
 )
 
-const batchSize = 5000
+const (
+	// DefaultBatchSize is the default number of points written at once.
+	DefaultBatchSize = 5000
+
+	// DefaultWriters is the default number of batches written concurrently.
+	DefaultWriters = 1
+
+	// DefaultRetries is the default number of times a failed batch is
+	// written again.
+	DefaultRetries = 3
+
+	// DefaultRetryInterval is the default time before a failed batch is
+	// written again.  It doubles with each retry.
+	DefaultRetryInterval = time.Second
+
+	// maxLineSize is the size of the longest line of the import file.
+	maxLineSize = 64 * 1024 * 1024
+
+	// progressInterval is the interval the progress is reported at.
+	progressInterval = time.Second
+)
 
 // Config is the config used to initialize a Importer importer
 type Config struct {
@@ -23,40 +48,93 @@ type Config struct {
 	Compressed bool // Whether import data is gzipped.
 	PPS        int  // points per second importer imports with.
 
+	BatchSize     int           // Number of points written at once.
+	Writers       int           // Number of batches written concurrently.
+	Retries       int           // Number of times a failed batch is written again.
+	RetryInterval time.Duration // Time before the first retry, doubled with each retry.
+
+	// FailedPath is the file the lines of the batches that failed every
+	// retry, or were rejected by the server, are appended to, in a format
+	// that can be imported again.
+	FailedPath string
+
+	// Offset is the number of points of the import file that are skipped,
+	// to resume an import that stopped.
+	Offset int64
+
 	client.Config
 }
 
 // NewConfig returns an initialized *Config
 func NewConfig() Config {
-	return Config{Config: client.NewConfig()}
+	return Config{
+		BatchSize:     DefaultBatchSize,
+		Writers:       DefaultWriters,
+		Retries:       DefaultRetries,
+		RetryInterval: DefaultRetryInterval,
+		Config:        client.NewConfig(),
+	}
+}
+
+// batch is a batch of lines written to a database at once.
+type batch struct {
+	seq             int
+	lines           []string
+	database        string
+	retentionPolicy string
 }
 
 // Importer is the importer used for importing 0.8 data
 type Importer struct {
-	client                *client.Client
-	database              string
-	retentionPolicy       string
-	config                Config
-	batch                 []string
-	totalInserts          int
-	failedInserts         int
-	totalCommands         int
-	throttlePointsWritten int
-	lastWrite             time.Time
-	throttle              *time.Ticker
+	client          *client.Client
+	database        string
+	retentionPolicy string
+	config          Config
+	batch           []string
+	totalCommands   int
+
+	// Counted atomically, as batches are written concurrently.
+	totalInserts  int64
+	failedInserts int64
+	retries       int64
+	bytesRead     int64
+
+	// The batches being written, and the sequence number of the next one.
+	batches chan *batch
+	nextSeq int
+
+	// The number of points of the file, from its start, whose batches all
+	// were either written or appended to the failure file.  completed holds
+	// the sizes of the batches completed out of order.
+	mu        sync.Mutex
+	offset    int64
+	doneSeq   int
+	completed map[int]int
+
+	failed *os.File
 
 	stderrLogger *log.Logger
 	stdoutLogger *log.Logger
+	progress     io.Writer
 }
 
 // NewImporter will return an intialized Importer struct
 func NewImporter(config Config) *Importer {
 	config.UserAgent = fmt.Sprintf("influxDB importer/%s", config.Version)
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.Writers <= 0 {
+		config.Writers = DefaultWriters
+	}
 	return &Importer{
 		config:       config,
-		batch:        make([]string, 0, batchSize),
+		batch:        make([]string, 0, config.BatchSize),
+		offset:       config.Offset,
+		completed:    make(map[int]int),
 		stdoutLogger: log.New(os.Stdout, "", log.LstdFlags),
 		stderrLogger: log.New(os.Stderr, "", log.LstdFlags),
+		progress:     os.Stderr,
 	}
 }
 
@@ -78,10 +156,11 @@ func (i *Importer) Import() error {
 	}
 
 	defer func() {
-		if i.totalInserts > 0 {
+		if i.totalInserts > 0 || i.failedInserts > 0 {
 			i.stdoutLogger.Printf("Processed %d commands\n", i.totalCommands)
 			i.stdoutLogger.Printf("Processed %d inserts\n", i.totalInserts)
 			i.stdoutLogger.Printf("Failed %d inserts\n", i.failedInserts)
+			i.stdoutLogger.Printf("Retried %d batches\n", i.retries)
 		}
 	}()
 
@@ -92,42 +171,72 @@ func (i *Importer) Import() error {
 	}
 	defer f.Close()
 
-	var r io.Reader
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	// Count the bytes read from the file, compressed or not, to report the
+	// progress.
+	var r io.Reader = &countingReader{r: f, n: &i.bytesRead}
 
 	// If gzipped, wrap in a gzip reader
 	if i.config.Compressed {
-		gr, err := gzip.NewReader(f)
+		gr, err := gzip.NewReader(r)
 		if err != nil {
 			return err
 		}
 		defer gr.Close()
 		// Set the reader to the gzip reader
 		r = gr
-	} else {
-		// Standard text file so our reader can just be the file
-		r = f
+	}
+
+	if i.config.FailedPath != "" {
+		if i.failed, err = openFailureFile(i.config.FailedPath); err != nil {
+			return err
+		}
+		defer i.failed.Close()
 	}
 
 	// Get our reader
 	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
 
 	// Process the DDL
 	i.processDDL(scanner)
 
-	// Set up our throttle channel.  Since there is effectively no other activity at this point
-	// the smaller resolution gets us much closer to the requested PPS
-	i.throttle = time.NewTicker(time.Microsecond)
-	defer i.throttle.Stop()
+	// Start the writers and report the progress until they are done.
+	i.batches = make(chan *batch, i.config.Writers)
+	var wg sync.WaitGroup
+	for n := 0; n < i.config.Writers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range i.batches {
+				i.writeBatch(b)
+			}
+		}()
+	}
 
-	// Prime the last write
-	i.lastWrite = time.Now()
+	start := time.Now()
+	done := make(chan struct{})
+	var progressWG sync.WaitGroup
+	progressWG.Add(1)
+	go func() {
+		defer progressWG.Done()
+		i.reportProgress(fi.Size(), start, done)
+	}()
 
 	// Process the DML
-	i.processDML(scanner)
+	i.processDML(scanner, start)
+	close(i.batches)
+	wg.Wait()
+	close(done)
+	progressWG.Wait()
 
 	// Check if we had any errors scanning the file
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("reading standard input: %s", err)
+		return fmt.Errorf("reading standard input: %s; resume the import with -offset %d", err, i.Offset())
 	}
 
 	// If there were any failed inserts then return an error so that a non-zero
@@ -138,12 +247,24 @@ func (i *Importer) Import() error {
 			plural = "s were"
 		}
 
+		if i.failed != nil {
+			return fmt.Errorf("%d point%s not inserted; they were written to %s", i.failedInserts, plural, i.config.FailedPath)
+		}
 		return fmt.Errorf("%d point%s not inserted", i.failedInserts, plural)
 	}
 
 	return nil
 }
 
+// Offset returns the number of points of the file, from its start, that
+// were either written or appended to the failure file.  An import that
+// stops can be resumed from it.
+func (i *Importer) Offset() int64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.offset
+}
+
 func (i *Importer) processDDL(scanner *bufio.Scanner) {
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -162,14 +283,16 @@ func (i *Importer) processDDL(scanner *bufio.Scanner) {
 	}
 }
 
-func (i *Importer) processDML(scanner *bufio.Scanner) {
-	start := time.Now()
+func (i *Importer) processDML(scanner *bufio.Scanner, start time.Time) {
+	var skipped, dispatched int64
 	for scanner.Scan() {
 		line := scanner.Text()
 		if strings.HasPrefix(line, "# CONTEXT-DATABASE:") {
+			i.flush(&dispatched, start)
 			i.database = strings.TrimSpace(strings.Split(line, ":")[1])
 		}
 		if strings.HasPrefix(line, "# CONTEXT-RETENTION-POLICY:") {
+			i.flush(&dispatched, start)
 			i.retentionPolicy = strings.TrimSpace(strings.Split(line, ":")[1])
 		}
 		if strings.HasPrefix(line, "#") {
@@ -179,10 +302,18 @@ func (i *Importer) processDML(scanner *bufio.Scanner) {
 		if strings.TrimSpace(line) == "" {
 			continue
 		}
-		i.batchAccumulator(line, start)
+		// Skip the points written by a previous import.
+		if skipped < i.config.Offset {
+			skipped++
+			continue
+		}
+		i.batch = append(i.batch, line)
+		if len(i.batch) == i.config.BatchSize {
+			i.flush(&dispatched, start)
+		}
 	}
-	// Call batchWrite one last time to flush anything out in the batch
-	i.batchWrite()
+	// Flush anything left in the batch
+	i.flush(&dispatched, start)
 }
 
 func (i *Importer) execute(command string) {
@@ -201,56 +332,274 @@ func (i *Importer) queryExecutor(command string) {
 	i.execute(command)
 }
 
-func (i *Importer) batchAccumulator(line string, start time.Time) {
-	i.batch = append(i.batch, line)
-	if len(i.batch) == batchSize {
-		i.batchWrite()
-		i.batch = i.batch[:0]
-		// Give some status feedback every 100000 lines processed
-		processed := i.totalInserts + i.failedInserts
-		if processed%100000 == 0 {
-			since := time.Since(start)
-			pps := float64(processed) / since.Seconds()
-			i.stdoutLogger.Printf("Processed %d lines.  Time elapsed: %s.  Points per second (PPS): %d", processed, since.String(), int64(pps))
+// flush hands the accumulated lines to the writers, once the points per
+// second limit allows it.
+func (i *Importer) flush(dispatched *int64, start time.Time) {
+	if len(i.batch) == 0 {
+		return
+	}
+
+	if i.config.PPS > 0 {
+		due := time.Duration(float64(*dispatched) / float64(i.config.PPS) * float64(time.Second))
+		if d := due - time.Since(start); d > 0 {
+			time.Sleep(d)
 		}
 	}
+	*dispatched += int64(len(i.batch))
+
+	i.batches <- &batch{
+		seq:             i.nextSeq,
+		lines:           i.batch,
+		database:        i.database,
+		retentionPolicy: i.retentionPolicy,
+	}
+	i.nextSeq++
+	i.batch = make([]string, 0, i.config.BatchSize)
 }
 
-func (i *Importer) batchWrite() {
-	// Accumulate the batch size to see how many points we have written this second
-	i.throttlePointsWritten += len(i.batch)
+// writeBatch writes a batch, retrying with an exponential backoff.  Batches
+// rejected by the server aren't retried.  The lines of a batch that fails
+// every retry, or the lines the server rejected, are appended to the failure
+// file.
+func (i *Importer) writeBatch(b *batch) {
+	data := strings.Join(b.lines, "\n")
+	interval := i.config.RetryInterval
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		if _, err = i.client.WriteLineProtocol(data, b.database, b.retentionPolicy, i.config.Precision, i.config.WriteConsistency); err == nil {
+			atomic.AddInt64(&i.totalInserts, int64(len(b.lines)))
+			i.complete(b)
+			return
+		} else if attempt >= i.config.Retries || !retryable(err) {
+			break
+		}
 
-	// Find out when we last wrote data
-	since := time.Since(i.lastWrite)
+		atomic.AddInt64(&i.retries, 1)
+		time.Sleep(interval)
+		interval *= 2
+	}
 
-	// Check to see if we've exceeded our points per second for the current timeframe
-	var currentPPS int
-	if since.Seconds() > 0 {
-		currentPPS = int(float64(i.throttlePointsWritten) / since.Seconds())
+	i.stderrLogger.Println("error writing batch: ", err)
+	lines, failed := b.lines, len(b.lines)
+	if rejected := rejectedLines(err, b.lines); rejected != nil {
+		lines, failed = rejected, len(rejected)
+	} else if n := droppedPoints(err); n > 0 && n < len(b.lines) {
+		// The server doesn't report which points it dropped, so the whole
+		// batch is appended.  Importing it again overwrites the points that
+		// were written with the same values.
+		failed = n
+	}
+	// The other lines of the batch were written.
+	atomic.AddInt64(&i.totalInserts, int64(len(b.lines)-failed))
+	atomic.AddInt64(&i.failedInserts, int64(failed))
+	if i.failed != nil {
+		if err := i.writeFailed(b, lines); err != nil {
+			i.stderrLogger.Printf("error writing to %s: %s", i.config.FailedPath, err)
+			i.stderrLogger.Println(strings.Join(lines, "\n"))
+		}
 	} else {
-		currentPPS = i.throttlePointsWritten
+		i.stderrLogger.Println(strings.Join(lines, "\n"))
 	}
+	i.complete(b)
+}
 
-	// If our currentPPS is greater than the PPS specified, then we wait and retry
-	if int(currentPPS) > i.config.PPS && i.config.PPS != 0 {
-		// Wait for the next tick
-		<-i.throttle.C
+// retryable returns true if a write failed by a transport or server error.
+// Writes rejected by the server fail the same way when retried.
+func retryable(err error) bool {
+	if err, ok := err.(*client.WriteError); ok {
+		return err.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
 
-		// Decrement the batch size back out as it is going to get called again
-		i.throttlePointsWritten -= len(i.batch)
-		i.batchWrite()
-		return
+// rejectedLines returns the lines of a batch the server reported it couldn't
+// parse, or nil if it didn't report which lines of the batch it rejected.
+func rejectedLines(err error, lines []string) []string {
+	msg := rejectionMessage(err)
+	if msg == "" {
+		return nil
 	}
 
-	_, e := i.client.WriteLineProtocol(strings.Join(i.batch, "\n"), i.database, i.retentionPolicy, i.config.Precision, i.config.WriteConsistency)
-	if e != nil {
-		i.stderrLogger.Println("error writing batch: ", e)
-		i.stderrLogger.Println(strings.Join(i.batch, "\n"))
-		i.failedInserts += len(i.batch)
-	} else {
-		i.totalInserts += len(i.batch)
+	// Parse errors are reported as "unable to parse '<line>': <error>", one
+	// per line.  The lines are matched against the batch, as they may hold
+	// the separator.
+	const prefix = "unable to parse '"
+	batch := make(map[string]struct{}, len(lines))
+	for _, line := range lines {
+		batch[strings.TrimSpace(line)] = struct{}{}
 	}
-	i.throttlePointsWritten = 0
-	i.lastWrite = time.Now()
-	return
+	failed := make(map[string]struct{})
+	for _, msg := range strings.Split(strings.TrimPrefix(msg, "partial write: "), "\n") {
+		if !strings.HasPrefix(msg, prefix) {
+			continue
+		}
+		msg = msg[len(prefix):]
+		for n := 0; ; {
+			j := strings.Index(msg[n:], "': ")
+			if j < 0 {
+				break
+			}
+			n += j
+			if _, ok := batch[msg[:n]]; ok {
+				failed[msg[:n]] = struct{}{}
+				break
+			}
+			n++
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	var rejected []string
+	for _, line := range lines {
+		if _, ok := failed[strings.TrimSpace(line)]; ok {
+			rejected = append(rejected, line)
+		}
+	}
+	return rejected
+}
+
+// droppedPoints returns the number of points of a batch the server reported
+// it dropped in a partial write, or 0 if it didn't report a partial write.
+func droppedPoints(err error) int {
+	msg := rejectionMessage(err)
+	if !strings.HasPrefix(msg, "partial write: ") {
+		return 0
+	}
+
+	// Partial writes are reported as "partial write: <reason> dropped=<n>".
+	const sep = " dropped="
+	j := strings.LastIndex(msg, sep)
+	if j < 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(msg[j+len(sep):])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// rejectionMessage returns the error the server reported for a write it
+// rejected as bad, or an empty string if err isn't such a rejection.
+func rejectionMessage(err error) string {
+	werr, ok := err.(*client.WriteError)
+	if !ok || werr.StatusCode != http.StatusBadRequest {
+		return ""
+	}
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(werr.Body), &resp); err != nil {
+		return ""
+	}
+	return resp.Error
+}
+
+// complete records that a batch was either written or appended to the
+// failure file, and advances the offset past the batches completed in order.
+func (i *Importer) complete(b *batch) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.completed[b.seq] = len(b.lines)
+	for {
+		n, ok := i.completed[i.doneSeq]
+		if !ok {
+			return
+		}
+		delete(i.completed, i.doneSeq)
+		i.offset += int64(n)
+		i.doneSeq++
+	}
+}
+
+// writeFailed appends lines of a batch to the failure file, after the
+// context of their database and retention policy.
+func (i *Importer) writeFailed(b *batch, lines []string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	w := bufio.NewWriter(i.failed)
+	fmt.Fprintf(w, "# CONTEXT-DATABASE:%s\n", b.database)
+	fmt.Fprintf(w, "# CONTEXT-RETENTION-POLICY:%s\n", b.retentionPolicy)
+	for _, line := range lines {
+		w.WriteString(line)
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
+
+// reportProgress reports the bytes of the file read, the points written and
+// their rate every progressInterval until done is closed.
+func (i *Importer) reportProgress(size int64, start time.Time, done <-chan struct{}) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			i.printProgress(size, start)
+			fmt.Fprintln(i.progress)
+			return
+		case <-ticker.C:
+			i.printProgress(size, start)
+		}
+	}
+}
+
+func (i *Importer) printProgress(size int64, start time.Time) {
+	const width = 30
+
+	read := atomic.LoadInt64(&i.bytesRead)
+	points := atomic.LoadInt64(&i.totalInserts) + atomic.LoadInt64(&i.failedInserts)
+	pps := float64(points) / time.Since(start).Seconds()
+
+	var ratio float64
+	if size > 0 {
+		ratio = float64(read) / float64(size)
+	}
+	filled := int(ratio * width)
+	if filled > width {
+		filled = width
+	}
+
+	fmt.Fprintf(i.progress, "\r[%s%s] %5.1f%% %d points, %d PPS, offset %d",
+		strings.Repeat("=", filled), strings.Repeat(" ", width-filled),
+		ratio*100, points, int64(pps), i.Offset())
+}
+
+// openFailureFile opens the failure file for appending, starting it with the
+// DML marker if it is new so it can be imported again.
+func openFailureFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.Size() == 0 {
+		if _, err := f.WriteString("# DML\n"); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// countingReader counts the bytes read from a reader.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	atomic.AddInt64(r.n, int64(n))
+	return n, err
 }
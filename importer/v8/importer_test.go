@@ -0,0 +1,237 @@
+package v8
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Ensure the importer retries failed batches, appends the batches that fail
+// every retry to the failure file, and skips the points before the offset.
+func TestImporter_Import(t *testing.T) {
+	var mu sync.Mutex
+	var written []string
+	var failures int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ping":
+			w.WriteHeader(http.StatusNoContent)
+		case "/query":
+			w.Write([]byte(`{"results":[{}]}`))
+		case "/write":
+			mu.Lock()
+			defer mu.Unlock()
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.URL.Query().Get("db") == "bad" {
+				http.Error(w, "database not found", http.StatusNotFound)
+				return
+			}
+			// Fail the first write, so it is retried.
+			if failures++; failures == 1 {
+				http.Error(w, "timeout", http.StatusServiceUnavailable)
+				return
+			}
+			written = append(written, strings.Split(string(body), "\n")...)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "export.txt")
+	if err := ioutil.WriteFile(path, []byte(`# DDL
+CREATE DATABASE db0
+# DML
+# CONTEXT-DATABASE: db0
+# CONTEXT-RETENTION-POLICY: autogen
+cpu value=1 1
+cpu value=2 2
+cpu value=3 3
+cpu value=4 4
+cpu value=5 5
+# CONTEXT-DATABASE: bad
+cpu value=6 6
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(srv.URL)
+	config := NewConfig()
+	config.URL = *u
+	config.Path = path
+	config.BatchSize = 2
+	config.Writers = 2
+	config.RetryInterval = time.Millisecond
+	config.FailedPath = filepath.Join(dir, "failed.txt")
+	config.Offset = 1
+
+	i := NewImporter(config)
+	i.stdoutLogger = log.New(ioutil.Discard, "", 0)
+	i.stderrLogger = log.New(ioutil.Discard, "", 0)
+	i.progress = ioutil.Discard
+	if err := i.Import(); err == nil || !strings.Contains(err.Error(), "1 point was not inserted") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(written) != 4 || strings.Contains(strings.Join(written, "\n"), "value=1 ") {
+		t.Fatalf("unexpected written points: %q", written)
+	} else if i.retries < 1 {
+		t.Fatalf("expected a retry, got %d", i.retries)
+	} else if offset := i.Offset(); offset != 6 {
+		t.Fatalf("unexpected offset: %d", offset)
+	}
+
+	failed, err := ioutil.ReadFile(config.FailedPath)
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := "# DML\n# CONTEXT-DATABASE:bad\n# CONTEXT-RETENTION-POLICY:autogen\ncpu value=6 6\n"; string(failed) != exp {
+		t.Fatalf("unexpected failure file: %q", failed)
+	}
+}
+
+// Ensure the importer doesn't retry batches rejected by the server, and only
+// appends the lines the server couldn't parse to the failure file.
+func TestImporter_Import_PartialWrite(t *testing.T) {
+	var mu sync.Mutex
+	var writes int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ping":
+			w.WriteHeader(http.StatusNoContent)
+		case "/query":
+			w.Write([]byte(`{"results":[{}]}`))
+		case "/write":
+			mu.Lock()
+			defer mu.Unlock()
+			writes++
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error": "partial write: unable to parse 'cpu value=x': invalid boolean\nunable to parse 'cpu value=': missing field value dropped=0",
+			})
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "export.txt")
+	if err := ioutil.WriteFile(path, []byte(`# DML
+# CONTEXT-DATABASE: db0
+cpu value=1 1
+cpu value=x
+cpu value=3 3
+cpu value=
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(srv.URL)
+	config := NewConfig()
+	config.URL = *u
+	config.Path = path
+	config.RetryInterval = time.Millisecond
+	config.FailedPath = filepath.Join(dir, "failed.txt")
+
+	i := NewImporter(config)
+	i.stdoutLogger = log.New(ioutil.Discard, "", 0)
+	i.stderrLogger = log.New(ioutil.Discard, "", 0)
+	i.progress = ioutil.Discard
+	if err := i.Import(); err == nil || !strings.Contains(err.Error(), "2 points were not inserted") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if writes != 1 || i.retries != 0 {
+		t.Fatalf("unexpected writes: %d, retries: %d", writes, i.retries)
+	} else if i.totalInserts != 2 {
+		t.Fatalf("unexpected inserts: %d", i.totalInserts)
+	}
+
+	failed, err := ioutil.ReadFile(config.FailedPath)
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := "# DML\n# CONTEXT-DATABASE:db0\n# CONTEXT-RETENTION-POLICY:\ncpu value=x\ncpu value=\n"; string(failed) != exp {
+		t.Fatalf("unexpected failure file: %q", failed)
+	}
+}
+
+// Ensure the importer counts only the points the server dropped from a
+// partial write as failed.
+func TestImporter_Import_DroppedPoints(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ping":
+			w.WriteHeader(http.StatusNoContent)
+		case "/query":
+			w.Write([]byte(`{"results":[{}]}`))
+		case "/write":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error": "partial write: points beyond retention policy dropped=1",
+			})
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "export.txt")
+	if err := ioutil.WriteFile(path, []byte(`# DML
+# CONTEXT-DATABASE: db0
+cpu value=1 1
+cpu value=2 2
+cpu value=3 3
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(srv.URL)
+	config := NewConfig()
+	config.URL = *u
+	config.Path = path
+	config.RetryInterval = time.Millisecond
+	config.FailedPath = filepath.Join(dir, "failed.txt")
+
+	i := NewImporter(config)
+	i.stdoutLogger = log.New(ioutil.Discard, "", 0)
+	i.stderrLogger = log.New(ioutil.Discard, "", 0)
+	i.progress = ioutil.Discard
+	if err := i.Import(); err == nil || !strings.Contains(err.Error(), "1 point was not inserted") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if i.totalInserts != 2 || i.retries != 0 {
+		t.Fatalf("unexpected inserts: %d, retries: %d", i.totalInserts, i.retries)
+	}
+
+	// The dropped point isn't known, so the whole batch is kept.
+	failed, err := ioutil.ReadFile(config.FailedPath)
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := "# DML\n# CONTEXT-DATABASE:db0\n# CONTEXT-RETENTION-POLICY:\ncpu value=1 1\ncpu value=2 2\ncpu value=3 3\n"; string(failed) != exp {
+		t.Fatalf("unexpected failure file: %q", failed)
+	}
+}
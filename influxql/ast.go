@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -125,6 +126,24 @@ func (d DataType) String() string {
 	return "unknown"
 }
 
+// DataTypeFromString returns the data type of a field named by String, or
+// Unknown if the name isn't the name of a field type.
+func DataTypeFromString(s string) DataType {
+	switch s {
+	case "float":
+		return Float
+	case "integer":
+		return Integer
+	case "unsigned":
+		return Unsigned
+	case "string":
+		return String
+	case "boolean":
+		return Boolean
+	}
+	return Unknown
+}
+
 // Node represents a node in the InfluxDB abstract syntax tree.
 type Node interface {
 	// node is unexported to ensure implementations of Node
@@ -136,11 +155,13 @@ type Node interface {
 func (*Query) node()     {}
 func (Statements) node() {}
 
+func (*AlterDatabaseStatement) node()              {}
 func (*AlterRetentionPolicyStatement) node()       {}
 func (*CreateContinuousQueryStatement) node()      {}
 func (*CreateDatabaseStatement) node()             {}
 func (*CreateRetentionPolicyStatement) node()      {}
 func (*CreateSubscriptionStatement) node()         {}
+func (*CreateTokenStatement) node()                {}
 func (*CreateUserStatement) node()                 {}
 func (*Distinct) node()                            {}
 func (*DeleteSeriesStatement) node()               {}
@@ -152,6 +173,7 @@ func (*DropRetentionPolicyStatement) node()        {}
 func (*DropSeriesStatement) node()                 {}
 func (*DropShardStatement) node()                  {}
 func (*DropSubscriptionStatement) node()           {}
+func (*DropTokenStatement) node()                  {}
 func (*DropUserStatement) node()                   {}
 func (*ExplainStatement) node()                    {}
 func (*GrantStatement) node()                      {}
@@ -166,10 +188,12 @@ func (*ShowGrantsForUserStatement) node()          {}
 func (*ShowDatabasesStatement) node()              {}
 func (*ShowFieldKeyCardinalityStatement) node()    {}
 func (*ShowFieldKeysStatement) node()              {}
+func (*ShowRetentionEnforcementStatement) node()   {}
 func (*ShowRetentionPoliciesStatement) node()      {}
 func (*ShowMeasurementCardinalityStatement) node() {}
 func (*ShowMeasurementsStatement) node()           {}
 func (*ShowQueriesStatement) node()                {}
+func (*ShowQuotasStatement) node()                 {}
 func (*ShowSeriesStatement) node()                 {}
 func (*ShowSeriesCardinalityStatement) node()      {}
 func (*ShowShardGroupsStatement) node()            {}
@@ -181,6 +205,7 @@ func (*ShowTagKeyCardinalityStatement) node()      {}
 func (*ShowTagKeysStatement) node()                {}
 func (*ShowTagValuesCardinalityStatement) node()   {}
 func (*ShowTagValuesStatement) node()              {}
+func (*ShowTokensStatement) node()                 {}
 func (*ShowUsersStatement) node()                  {}
 
 func (*BinaryExpr) node()      {}
@@ -264,11 +289,13 @@ type ExecutionPrivilege struct {
 // ExecutionPrivileges is a list of privileges required to execute a statement.
 type ExecutionPrivileges []ExecutionPrivilege
 
+func (*AlterDatabaseStatement) stmt()              {}
 func (*AlterRetentionPolicyStatement) stmt()       {}
 func (*CreateContinuousQueryStatement) stmt()      {}
 func (*CreateDatabaseStatement) stmt()             {}
 func (*CreateRetentionPolicyStatement) stmt()      {}
 func (*CreateSubscriptionStatement) stmt()         {}
+func (*CreateTokenStatement) stmt()                {}
 func (*CreateUserStatement) stmt()                 {}
 func (*DeleteSeriesStatement) stmt()               {}
 func (*DeleteStatement) stmt()                     {}
@@ -278,6 +305,7 @@ func (*DropMeasurementStatement) stmt()            {}
 func (*DropRetentionPolicyStatement) stmt()        {}
 func (*DropSeriesStatement) stmt()                 {}
 func (*DropSubscriptionStatement) stmt()           {}
+func (*DropTokenStatement) stmt()                  {}
 func (*DropUserStatement) stmt()                   {}
 func (*ExplainStatement) stmt()                    {}
 func (*GrantStatement) stmt()                      {}
@@ -291,6 +319,8 @@ func (*ShowFieldKeysStatement) stmt()              {}
 func (*ShowMeasurementCardinalityStatement) stmt() {}
 func (*ShowMeasurementsStatement) stmt()           {}
 func (*ShowQueriesStatement) stmt()                {}
+func (*ShowQuotasStatement) stmt()                 {}
+func (*ShowRetentionEnforcementStatement) stmt()   {}
 func (*ShowRetentionPoliciesStatement) stmt()      {}
 func (*ShowSeriesStatement) stmt()                 {}
 func (*ShowSeriesCardinalityStatement) stmt()      {}
@@ -304,6 +334,7 @@ func (*ShowTagKeyCardinalityStatement) stmt()      {}
 func (*ShowTagKeysStatement) stmt()                {}
 func (*ShowTagValuesCardinalityStatement) stmt()   {}
 func (*ShowTagValuesStatement) stmt()              {}
+func (*ShowTokensStatement) stmt()                 {}
 func (*ShowUsersStatement) stmt()                  {}
 func (*RevokeStatement) stmt()                     {}
 func (*RevokeAdminStatement) stmt()                {}
@@ -474,7 +505,7 @@ type SortField struct {
 func (field *SortField) String() string {
 	var buf bytes.Buffer
 	if field.Name != "" {
-		_, _ = buf.WriteString(field.Name)
+		_, _ = buf.WriteString(QuoteIdent(field.Name))
 		_, _ = buf.WriteString(" ")
 	}
 	if field.Ascending {
@@ -485,6 +516,12 @@ func (field *SortField) String() string {
 	return buf.String()
 }
 
+// IsTime returns true if the field sorts by time. A field without a name
+// sorts by time.
+func (field *SortField) IsTime() bool {
+	return field.Name == "" || field.Name == "time"
+}
+
 // SortFields represents an ordered list of ORDER BY fields.
 type SortFields []*SortField
 
@@ -648,6 +685,59 @@ func (s *DropUserStatement) RequiredPrivileges() (ExecutionPrivileges, error) {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}, nil
 }
 
+// CreateTokenStatement represents a command for creating an API token.
+type CreateTokenStatement struct {
+	// Name of the user the token authenticates as.
+	User string
+
+	// Privilege and database the token is scoped to.  If Database is empty,
+	// the token has the privileges of its user.
+	Privilege Privilege
+	Database  string
+
+	// Duration after which the token expires.  Zero means it doesn't expire.
+	Expires time.Duration
+}
+
+// String returns a string representation of the create token statement.
+func (s *CreateTokenStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("CREATE TOKEN FOR USER ")
+	_, _ = buf.WriteString(QuoteIdent(s.User))
+	if s.Database != "" {
+		_, _ = buf.WriteString(" WITH ")
+		_, _ = buf.WriteString(s.Privilege.String())
+		_, _ = buf.WriteString(" ON ")
+		_, _ = buf.WriteString(QuoteIdent(s.Database))
+	}
+	if s.Expires > 0 {
+		_, _ = buf.WriteString(" EXPIRES ")
+		_, _ = buf.WriteString(FormatDuration(s.Expires))
+	}
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a CreateTokenStatement.
+func (s *CreateTokenStatement) RequiredPrivileges() (ExecutionPrivileges, error) {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}, nil
+}
+
+// DropTokenStatement represents a command for revoking an API token.
+type DropTokenStatement struct {
+	// ID of the token to revoke.
+	ID string
+}
+
+// String returns a string representation of the drop token statement.
+func (s *DropTokenStatement) String() string {
+	return "DROP TOKEN " + QuoteString(s.ID)
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a DropTokenStatement.
+func (s *DropTokenStatement) RequiredPrivileges() (ExecutionPrivileges, error) {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}, nil
+}
+
 // Privilege is a type of action a user can be granted the right to use.
 type Privilege int
 
@@ -680,6 +770,20 @@ func (p Privilege) String() string {
 	return ""
 }
 
+// ParsePrivilege returns the privilege named s, ignoring case: READ, WRITE or
+// ALL.
+func ParsePrivilege(s string) (Privilege, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "READ":
+		return ReadPrivilege, nil
+	case "WRITE":
+		return WritePrivilege, nil
+	case "ALL", "ALL PRIVILEGES":
+		return AllPrivileges, nil
+	}
+	return NoPrivileges, fmt.Errorf("invalid privilege: %q", s)
+}
+
 // GrantStatement represents a command for granting a privilege.
 type GrantStatement struct {
 	// The privilege to be granted.
@@ -855,6 +959,12 @@ type CreateRetentionPolicyStatement struct {
 
 	// Shard Duration.
 	ShardGroupDuration time.Duration
+
+	// Durations of measurements that expire before the policy duration.
+	MeasurementDurations map[string]time.Duration
+
+	// SELECT INTO statement run over each shard group before it expires.
+	Rollup *SelectStatement
 }
 
 // String returns a string representation of the create retention policy.
@@ -872,12 +982,39 @@ func (s *CreateRetentionPolicyStatement) String() string {
 		_, _ = buf.WriteString(" SHARD DURATION ")
 		_, _ = buf.WriteString(FormatDuration(s.ShardGroupDuration))
 	}
+	writeMeasurementDurations(&buf, s.MeasurementDurations)
+	if s.Rollup != nil {
+		_, _ = buf.WriteString(" ROLLUP BEGIN ")
+		_, _ = buf.WriteString(s.Rollup.String())
+		_, _ = buf.WriteString(" END")
+	}
 	if s.Default {
 		_, _ = buf.WriteString(" DEFAULT")
 	}
 	return buf.String()
 }
 
+// writeMeasurementDurations writes the MEASUREMENT options of a retention
+// policy statement to buf, sorted by measurement name.
+func writeMeasurementDurations(buf *bytes.Buffer, durations map[string]time.Duration) {
+	names := make([]string, 0, len(durations))
+	for name := range durations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		_, _ = buf.WriteString(" MEASUREMENT ")
+		_, _ = buf.WriteString(QuoteIdent(name))
+		_, _ = buf.WriteString(" DURATION ")
+		if d := durations[name]; d == 0 {
+			_, _ = buf.WriteString("INF")
+		} else {
+			_, _ = buf.WriteString(FormatDuration(d))
+		}
+	}
+}
+
 // RequiredPrivileges returns the privilege required to execute a CreateRetentionPolicyStatement.
 func (s *CreateRetentionPolicyStatement) RequiredPrivileges() (ExecutionPrivileges, error) {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}, nil
@@ -888,6 +1025,77 @@ func (s *CreateRetentionPolicyStatement) DefaultDatabase() string {
 	return s.Database
 }
 
+// AlterDatabaseStatement represents a command to set the quotas or the write
+// limits of a database.
+type AlterDatabaseStatement struct {
+	// Name of the database to alter.
+	Name string
+
+	// Quotas to set, in bytes on disk, series and points written per
+	// second.  A nil quota is left unchanged and a zero quota is removed.
+	DiskQuota   *int64
+	SeriesQuota *int64
+	WriteQuota  *int64
+
+	// Write limits to set, on how far ahead of and behind the current time
+	// the timestamps of points may be, and whether the timestamps beyond a
+	// limit are clamped to it rather than the points rejected.  A nil limit
+	// is left unchanged and a zero limit is removed.
+	FutureWriteLimit *time.Duration
+	PastWriteLimit   *time.Duration
+	ClampWrites      *bool
+}
+
+// SetsWriteLimits returns true if the statement sets write limits rather
+// than quotas.
+func (s *AlterDatabaseStatement) SetsWriteLimits() bool {
+	return s.FutureWriteLimit != nil || s.PastWriteLimit != nil || s.ClampWrites != nil
+}
+
+// String returns a string representation of the alter database statement.
+func (s *AlterDatabaseStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("ALTER DATABASE ")
+	_, _ = buf.WriteString(QuoteIdent(s.Name))
+
+	if s.SetsWriteLimits() {
+		_, _ = buf.WriteString(" SET WRITE LIMIT ")
+
+		var limits []string
+		if s.FutureWriteLimit != nil {
+			limits = append(limits, "future="+FormatDuration(*s.FutureWriteLimit))
+		}
+		if s.PastWriteLimit != nil {
+			limits = append(limits, "past="+FormatDuration(*s.PastWriteLimit))
+		}
+		if s.ClampWrites != nil {
+			limits = append(limits, "clamp="+strconv.FormatBool(*s.ClampWrites))
+		}
+		_, _ = buf.WriteString(strings.Join(limits, ", "))
+		return buf.String()
+	}
+
+	_, _ = buf.WriteString(" SET QUOTA ")
+
+	var quotas []string
+	if s.DiskQuota != nil {
+		quotas = append(quotas, "disk="+strconv.FormatInt(*s.DiskQuota, 10))
+	}
+	if s.SeriesQuota != nil {
+		quotas = append(quotas, "series="+strconv.FormatInt(*s.SeriesQuota, 10))
+	}
+	if s.WriteQuota != nil {
+		quotas = append(quotas, "writes="+strconv.FormatInt(*s.WriteQuota, 10)+"/s")
+	}
+	_, _ = buf.WriteString(strings.Join(quotas, ", "))
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege required to execute an AlterDatabaseStatement.
+func (s *AlterDatabaseStatement) RequiredPrivileges() (ExecutionPrivileges, error) {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}, nil
+}
+
 // AlterRetentionPolicyStatement represents a command to alter an existing retention policy.
 type AlterRetentionPolicyStatement struct {
 	// Name of policy to alter.
@@ -907,6 +1115,16 @@ type AlterRetentionPolicyStatement struct {
 
 	// Duration of the Shard.
 	ShardGroupDuration *time.Duration
+
+	// Durations of measurements to set. A zero duration removes the
+	// duration of the measurement.
+	MeasurementDurations map[string]time.Duration
+
+	// SELECT INTO statement to run over each shard group before it expires.
+	Rollup *SelectStatement
+
+	// Should the rollup be removed?
+	DropRollup bool
 }
 
 // String returns a string representation of the alter retention policy statement.
@@ -932,6 +1150,16 @@ func (s *AlterRetentionPolicyStatement) String() string {
 		_, _ = buf.WriteString(FormatDuration(*s.ShardGroupDuration))
 	}
 
+	writeMeasurementDurations(&buf, s.MeasurementDurations)
+
+	if s.Rollup != nil {
+		_, _ = buf.WriteString(" ROLLUP BEGIN ")
+		_, _ = buf.WriteString(s.Rollup.String())
+		_, _ = buf.WriteString(" END")
+	} else if s.DropRollup {
+		_, _ = buf.WriteString(" ROLLUP NONE")
+	}
+
 	if s.Default {
 		_, _ = buf.WriteString(" DEFAULT")
 	}
@@ -1026,11 +1254,30 @@ type SelectStatement struct {
 
 	// Removes duplicate rows from raw queries.
 	Dedupe bool
+
+	// Whether the sources are joined on time and tags instead of merged.
+	Join bool
 }
 
 // TimeAscending returns true if the time field is sorted in chronological order.
 func (s *SelectStatement) TimeAscending() bool {
-	return len(s.SortFields) == 0 || s.SortFields[0].Ascending
+	for _, f := range s.SortFields {
+		if f.IsTime() {
+			return f.Ascending
+		}
+	}
+	return true
+}
+
+// SortedByTime returns true if the rows are only sorted by time, which is
+// the order they are read in.
+func (s *SelectStatement) SortedByTime() bool {
+	for _, f := range s.SortFields {
+		if !f.IsTime() {
+			return false
+		}
+	}
+	return true
 }
 
 // TimeFieldName returns the name of the time field.
@@ -1248,7 +1495,7 @@ func (s *SelectStatement) RewriteFields(m FieldMapper) (*SelectStatement, error)
 
 				// Add additional types for certain functions.
 				switch call.Name {
-				case "count", "first", "last", "distinct", "elapsed", "mode", "sample":
+				case "count", "first", "last", "distinct", "elapsed", "mode", "sample", "count_hll":
 					supportedTypes[String] = struct{}{}
 					fallthrough
 				case "min", "max":
@@ -1569,7 +1816,16 @@ func (s *SelectStatement) String() string {
 	}
 	if len(s.Sources) > 0 {
 		_, _ = buf.WriteString(" FROM ")
-		_, _ = buf.WriteString(s.Sources.String())
+		if s.Join {
+			for i, src := range s.Sources {
+				if i > 0 {
+					_, _ = buf.WriteString(" JOIN ")
+				}
+				_, _ = buf.WriteString(src.String())
+			}
+		} else {
+			_, _ = buf.WriteString(s.Sources.String())
+		}
 	}
 	if s.Condition != nil {
 		_, _ = buf.WriteString(" WHERE ")
@@ -2168,10 +2424,19 @@ func (s *ShowSeriesCardinalityStatement) DefaultDatabase() string {
 }
 
 // ShowContinuousQueriesStatement represents a command for listing continuous queries.
-type ShowContinuousQueriesStatement struct{}
+type ShowContinuousQueriesStatement struct {
+	// Status lists the execution status of each continuous query
+	// instead of its query.
+	Status bool
+}
 
 // String returns a string representation of the show continuous queries statement.
-func (s *ShowContinuousQueriesStatement) String() string { return "SHOW CONTINUOUS QUERIES" }
+func (s *ShowContinuousQueriesStatement) String() string {
+	if s.Status {
+		return "SHOW CONTINUOUS QUERIES STATUS"
+	}
+	return "SHOW CONTINUOUS QUERIES"
+}
 
 // RequiredPrivileges returns the privilege required to execute a ShowContinuousQueriesStatement.
 func (s *ShowContinuousQueriesStatement) RequiredPrivileges() (ExecutionPrivileges, error) {
@@ -2226,6 +2491,10 @@ type CreateContinuousQueryStatement struct {
 	// Interval to resample previous queries.
 	ResampleEvery time.Duration
 
+	// Duration to delay each resample by so queries on the same interval
+	// do not all run at the same time.
+	ResampleOffset time.Duration
+
 	// Maximum duration to resample previous queries.
 	ResampleFor time.Duration
 }
@@ -2239,6 +2508,9 @@ func (s *CreateContinuousQueryStatement) String() string {
 		buf.WriteString("RESAMPLE ")
 		if s.ResampleEvery > 0 {
 			fmt.Fprintf(&buf, "EVERY %s ", FormatDuration(s.ResampleEvery))
+			if s.ResampleOffset > 0 {
+				fmt.Fprintf(&buf, "OFFSET %s ", FormatDuration(s.ResampleOffset))
+			}
 		}
 		if s.ResampleFor > 0 {
 			fmt.Fprintf(&buf, "FOR %s ", FormatDuration(s.ResampleFor))
@@ -2280,6 +2552,10 @@ func (s *CreateContinuousQueryStatement) validate() error {
 		return err
 	}
 
+	if s.ResampleOffset != 0 && s.ResampleOffset >= s.ResampleEvery {
+		return fmt.Errorf("OFFSET duration must be < EVERY duration: must be less than %s, got %s", FormatDuration(s.ResampleEvery), FormatDuration(s.ResampleOffset))
+	}
+
 	if s.ResampleFor != 0 {
 		if s.ResampleEvery != 0 && s.ResampleEvery > interval {
 			interval = s.ResampleEvery
@@ -2500,6 +2776,18 @@ func (s *ShowRetentionPoliciesStatement) DefaultDatabase() string {
 	return s.Database
 }
 
+// ShowRetentionEnforcementStatement represents a command for listing the
+// shard groups due to be deleted by retention policy enforcement.
+type ShowRetentionEnforcementStatement struct{}
+
+// String returns a string representation of a ShowRetentionEnforcementStatement.
+func (s *ShowRetentionEnforcementStatement) String() string { return "SHOW RETENTION ENFORCEMENT" }
+
+// RequiredPrivileges returns the privilege(s) required to execute a ShowRetentionEnforcementStatement.
+func (s *ShowRetentionEnforcementStatement) RequiredPrivileges() (ExecutionPrivileges, error) {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}, nil
+}
+
 // ShowStatsStatement displays statistics for a given module.
 type ShowStatsStatement struct {
 	Module string
@@ -2572,6 +2860,10 @@ type CreateSubscriptionStatement struct {
 	RetentionPolicy string
 	Destinations    []string
 	Mode            string
+
+	// Condition restricts the points forwarded to those of the matching
+	// measurements and tags.
+	Condition Expr
 }
 
 // String returns a string representation of the CreateSubscriptionStatement.
@@ -2583,6 +2875,10 @@ func (s *CreateSubscriptionStatement) String() string {
 	_, _ = buf.WriteString(QuoteIdent(s.Database))
 	_, _ = buf.WriteString(".")
 	_, _ = buf.WriteString(QuoteIdent(s.RetentionPolicy))
+	if s.Condition != nil {
+		_, _ = buf.WriteString(" WHERE ")
+		_, _ = buf.WriteString(s.Condition.String())
+	}
 	_, _ = buf.WriteString(" DESTINATIONS ")
 	_, _ = buf.WriteString(s.Mode)
 	_, _ = buf.WriteString(" ")
@@ -2932,6 +3228,38 @@ func (s *ShowUsersStatement) RequiredPrivileges() (ExecutionPrivileges, error) {
 	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}, nil
 }
 
+// ShowTokensStatement represents a command for listing API tokens.
+type ShowTokensStatement struct {
+	// Name of the user to list the tokens of.  All tokens are listed if empty.
+	User string
+}
+
+// String returns a string representation of the ShowTokensStatement.
+func (s *ShowTokensStatement) String() string {
+	if s.User == "" {
+		return "SHOW TOKENS"
+	}
+	return "SHOW TOKENS FOR USER " + QuoteIdent(s.User)
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a ShowTokensStatement
+func (s *ShowTokensStatement) RequiredPrivileges() (ExecutionPrivileges, error) {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}, nil
+}
+
+// ShowQuotasStatement represents a command for listing the quotas of databases.
+type ShowQuotasStatement struct{}
+
+// String returns a string representation of the ShowQuotasStatement.
+func (s *ShowQuotasStatement) String() string {
+	return "SHOW QUOTAS"
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a ShowQuotasStatement
+func (s *ShowQuotasStatement) RequiredPrivileges() (ExecutionPrivileges, error) {
+	return ExecutionPrivileges{{Admin: true, Name: "", Privilege: AllPrivileges}}, nil
+}
+
 // ShowFieldKeyCardinalityStatement represents a command for listing field key cardinality.
 type ShowFieldKeyCardinalityStatement struct {
 	Database      string
@@ -4297,13 +4625,16 @@ func EvalType(expr Expr, sources Sources, typmap TypeMapper) DataType {
 		return typ
 	case *Call:
 		switch expr.Name {
-		case "mean", "median", "integral":
+		case "mean", "median", "integral", "sqrt", "log", "pow", "if", "percentile_tdigest":
 			return Float
-		case "count":
+		case "count", "count_hll":
 			return Integer
 		case "elapsed":
 			return Integer
 		default:
+			if _, ok := LookupUserFunction(expr.Name); ok {
+				return Float
+			}
 			return EvalType(expr.Args[0], sources, typmap)
 		}
 	case *ParenExpr:
@@ -5026,8 +5357,12 @@ type containsVarRefVisitor struct {
 }
 
 func (v *containsVarRefVisitor) Visit(n Node) Visitor {
-	switch n.(type) {
+	switch n := n.(type) {
 	case *Call:
+		// Math functions are evaluated per point so look through them.
+		if IsMathFunction(n) {
+			return v
+		}
 		return nil
 	case *VarRef:
 		v.contains = true
@@ -5045,6 +5380,50 @@ func IsSelector(expr Expr) bool {
 	return false
 }
 
+// IsMathFunction returns true if the call is a math or conditional function
+// that is applied to each point rather than aggregating them.
+func IsMathFunction(call *Call) bool {
+	switch call.Name {
+	case "abs", "ceil", "floor", "round", "sqrt", "log", "pow", "if":
+		return true
+	}
+	aggregate, ok := LookupUserFunction(call.Name)
+	return ok && !aggregate
+}
+
+// userFunctions holds the names of the registered user-defined functions.
+// The value is true for aggregate functions.
+var userFunctions = struct {
+	sync.RWMutex
+	m map[string]bool
+}{m: make(map[string]bool)}
+
+// RegisterUserFunction registers the name of a user-defined function so
+// calls to it are resolved when statements are validated. Scalar functions
+// are applied to each point like math functions and aggregate functions
+// reduce the points of each interval. User-defined functions return floats.
+func RegisterUserFunction(name string, aggregate bool) {
+	userFunctions.Lock()
+	userFunctions.m[name] = aggregate
+	userFunctions.Unlock()
+}
+
+// UnregisterUserFunction removes a user-defined function.
+func UnregisterUserFunction(name string) {
+	userFunctions.Lock()
+	delete(userFunctions.m, name)
+	userFunctions.Unlock()
+}
+
+// LookupUserFunction returns whether name is a registered user-defined
+// function and whether that function is an aggregate.
+func LookupUserFunction(name string) (aggregate, ok bool) {
+	userFunctions.RLock()
+	aggregate, ok = userFunctions.m[name]
+	userFunctions.RUnlock()
+	return aggregate, ok
+}
+
 // stringSetSlice returns a sorted slice of keys from a string set.
 func stringSetSlice(m map[string]struct{}) []string {
 	if m == nil {
@@ -62,6 +62,25 @@ func TestDataType_String(t *testing.T) {
 	}
 }
 
+func TestDataTypeFromString(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		typ influxql.DataType
+	}{
+		{"float", influxql.Float},
+		{"integer", influxql.Integer},
+		{"unsigned", influxql.Unsigned},
+		{"boolean", influxql.Boolean},
+		{"string", influxql.String},
+		{"time", influxql.Unknown},
+		{"double", influxql.Unknown},
+	} {
+		if typ := influxql.DataTypeFromString(tt.s); tt.typ != typ {
+			t.Errorf("%d. %s: unexpected type: %s", i, tt.s, typ)
+		}
+	}
+}
+
 func TestDataType_LessThan(t *testing.T) {
 	for i, tt := range []struct {
 		typ   influxql.DataType
@@ -139,8 +139,11 @@ func init() {
 		show.Handle(QUERIES, func(p *Parser) (Statement, error) {
 			return p.parseShowQueriesStatement()
 		})
-		show.Group(RETENTION).Handle(POLICIES, func(p *Parser) (Statement, error) {
-			return p.parseShowRetentionPoliciesStatement()
+		show.Handle(QUOTAS, func(p *Parser) (Statement, error) {
+			return p.parseShowQuotasStatement()
+		})
+		show.Handle(RETENTION, func(p *Parser) (Statement, error) {
+			return p.parseShowRetentionStatement()
 		})
 		show.Handle(SERIES, func(p *Parser) (Statement, error) {
 			return p.parseShowSeriesStatement()
@@ -168,6 +171,9 @@ func init() {
 				return p.parseShowTagValuesStatement()
 			})
 		})
+		show.Handle(TOKENS, func(p *Parser) (Statement, error) {
+			return p.parseShowTokensStatement()
+		})
 		show.Handle(USERS, func(p *Parser) (Statement, error) {
 			return p.parseShowUsersStatement()
 		})
@@ -188,6 +194,9 @@ func init() {
 		create.Handle(SUBSCRIPTION, func(p *Parser) (Statement, error) {
 			return p.parseCreateSubscriptionStatement()
 		})
+		create.Handle(TOKEN, func(p *Parser) (Statement, error) {
+			return p.parseCreateTokenStatement()
+		})
 	})
 	Language.Group(DROP).With(func(drop *ParseTree) {
 		drop.Group(CONTINUOUS).Handle(QUERY, func(p *Parser) (Statement, error) {
@@ -211,6 +220,9 @@ func init() {
 		drop.Handle(SUBSCRIPTION, func(p *Parser) (Statement, error) {
 			return p.parseDropSubscriptionStatement()
 		})
+		drop.Handle(TOKEN, func(p *Parser) (Statement, error) {
+			return p.parseDropTokenStatement()
+		})
 		drop.Handle(USER, func(p *Parser) (Statement, error) {
 			return p.parseDropUserStatement()
 		})
@@ -224,8 +236,13 @@ func init() {
 	Language.Handle(REVOKE, func(p *Parser) (Statement, error) {
 		return p.parseRevokeStatement()
 	})
-	Language.Group(ALTER, RETENTION).Handle(POLICY, func(p *Parser) (Statement, error) {
-		return p.parseAlterRetentionPolicyStatement()
+	Language.Group(ALTER).With(func(alter *ParseTree) {
+		alter.Handle(DATABASE, func(p *Parser) (Statement, error) {
+			return p.parseAlterDatabaseStatement()
+		})
+		alter.Group(RETENTION).Handle(POLICY, func(p *Parser) (Statement, error) {
+			return p.parseAlterRetentionPolicyStatement()
+		})
 	})
 	Language.Group(SET, PASSWORD).Handle(FOR, func(p *Parser) (Statement, error) {
 		return p.parseSetPasswordUserStatement()
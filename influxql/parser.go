@@ -24,6 +24,10 @@ const (
 type Parser struct {
 	s      *bufScanner
 	params map[string]interface{}
+
+	// measurementRef allows the MEASUREMENT keyword to be used as a
+	// variable reference, as in the filter of a subscription.
+	measurementRef bool
 }
 
 // NewParser returns a new instance of Parser.
@@ -175,6 +179,19 @@ func (p *Parser) parseCreateSubscriptionStatement() (*CreateSubscriptionStatemen
 	}
 	stmt.RetentionPolicy = ident
 
+	// Parse optional condition: "WHERE EXPR".
+	p.measurementRef = true
+	condition, err := p.parseCondition()
+	p.measurementRef = false
+	if err != nil {
+		return nil, err
+	} else if condition != nil {
+		if err := validateSubscriptionCondition(condition); err != nil {
+			return nil, err
+		}
+		stmt.Condition = condition
+	}
+
 	// Expect a "DESTINATIONS" keyword.
 	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != DESTINATIONS {
 		return nil, newParseError(tokstr(tok, lit), []string{"DESTINATIONS"}, pos)
@@ -197,6 +214,37 @@ func (p *Parser) parseCreateSubscriptionStatement() (*CreateSubscriptionStatemen
 	return stmt, nil
 }
 
+// validateSubscriptionCondition returns an error if the condition of a
+// subscription is not made of comparisons of the measurement or tags with
+// strings or regular expressions.
+func validateSubscriptionCondition(expr Expr) error {
+	switch expr := expr.(type) {
+	case *ParenExpr:
+		return validateSubscriptionCondition(expr.Expr)
+	case *BinaryExpr:
+		switch expr.Op {
+		case AND, OR:
+			if err := validateSubscriptionCondition(expr.LHS); err != nil {
+				return err
+			}
+			return validateSubscriptionCondition(expr.RHS)
+		case EQ, NEQ:
+			if _, ok := expr.LHS.(*VarRef); ok {
+				if _, ok := expr.RHS.(*StringLiteral); ok {
+					return nil
+				}
+			}
+		case EQREGEX, NEQREGEX:
+			if _, ok := expr.LHS.(*VarRef); ok {
+				if _, ok := expr.RHS.(*RegexLiteral); ok {
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("invalid subscription condition: %s", expr)
+}
+
 // parseCreateRetentionPolicyStatement parses a string and returns a create retention policy statement.
 // This function assumes the CREATE RETENTION POLICY tokens have already been consumed.
 func (p *Parser) parseCreateRetentionPolicyStatement() (*CreateRetentionPolicyStatement, error) {
@@ -270,6 +318,37 @@ func (p *Parser) parseCreateRetentionPolicyStatement() (*CreateRetentionPolicySt
 		p.Unscan()
 	}
 
+	// Parse optional MEASUREMENT <name> DURATION <duration> options.
+	for {
+		tok, pos, _ := p.ScanIgnoreWhitespace()
+		if tok != MEASUREMENT {
+			p.Unscan()
+			break
+		}
+
+		name, d, err := p.parseMeasurementDuration()
+		if err != nil {
+			return nil, err
+		} else if _, ok := stmt.MeasurementDurations[name]; ok {
+			return nil, &ParseError{Message: fmt.Sprintf("found duplicate MEASUREMENT %s option", QuoteIdent(name)), Pos: pos}
+		}
+		if stmt.MeasurementDurations == nil {
+			stmt.MeasurementDurations = make(map[string]time.Duration)
+		}
+		stmt.MeasurementDurations[name] = d
+	}
+
+	// Parse optional ROLLUP BEGIN <select> END.
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok == IDENT && strings.ToLower(lit) == "rollup" {
+		source, err := p.parseRollup()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Rollup = source
+	} else {
+		p.Unscan()
+	}
+
 	// Parse optional DEFAULT token.
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == DEFAULT {
 		stmt.Default = true
@@ -280,6 +359,225 @@ func (p *Parser) parseCreateRetentionPolicyStatement() (*CreateRetentionPolicySt
 	return stmt, nil
 }
 
+// parseMeasurementDuration parses the name and duration of a MEASUREMENT
+// option of a retention policy. A duration of INF is returned as zero.
+// This function assumes the MEASUREMENT token has already been consumed.
+func (p *Parser) parseMeasurementDuration() (string, time.Duration, error) {
+	name, err := p.ParseIdent()
+	if err != nil {
+		return "", 0, err
+	}
+
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != DURATION {
+		return "", 0, newParseError(tokstr(tok, lit), []string{"DURATION"}, pos)
+	}
+
+	d, err := p.ParseDuration()
+	if err != nil {
+		return "", 0, err
+	}
+	return name, d, nil
+}
+
+// parseRollup parses the SELECT INTO statement of a ROLLUP option of a
+// retention policy. This function assumes the ROLLUP token has already been
+// consumed.
+func (p *Parser) parseRollup() (*SelectStatement, error) {
+	// Expect a "BEGIN SELECT" tokens.
+	if err := p.parseTokens([]Token{BEGIN, SELECT}); err != nil {
+		return nil, err
+	}
+
+	source, err := p.parseSelectStatement(targetRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expect a "END" keyword.
+	if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != END {
+		return nil, newParseError(tokstr(tok, lit), []string{"END"}, pos)
+	}
+	return source, nil
+}
+
+// parseAlterDatabaseStatement parses a string and returns an AlterDatabaseStatement.
+// This function assumes the ALTER DATABASE tokens have already been consumed.
+func (p *Parser) parseAlterDatabaseStatement() (*AlterDatabaseStatement, error) {
+	stmt := &AlterDatabaseStatement{}
+
+	// Parse the database name.
+	ident, err := p.ParseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = ident
+
+	if err := p.parseTokens([]Token{SET}); err != nil {
+		return nil, err
+	}
+
+	switch tok, pos, lit := p.ScanIgnoreWhitespace(); tok {
+	case QUOTA:
+		if err := p.parseDatabaseQuotas(stmt); err != nil {
+			return nil, err
+		}
+	case WRITE:
+		if err := p.parseTokens([]Token{LIMIT}); err != nil {
+			return nil, err
+		} else if err := p.parseDatabaseWriteLimits(stmt); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, newParseError(tokstr(tok, lit), []string{"QUOTA", "WRITE"}, pos)
+	}
+	return stmt, nil
+}
+
+// parseDatabaseQuotas parses the comma-separated quotas of an ALTER DATABASE
+// statement.  DISK and WRITES are not keywords so they are read as
+// identifiers.
+func (p *Parser) parseDatabaseQuotas(stmt *AlterDatabaseStatement) error {
+	for {
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		name := strings.ToLower(lit)
+		if tok == SERIES {
+			name = "series"
+		} else if tok != IDENT || (name != "disk" && name != "writes") {
+			return newParseError(tokstr(tok, lit), []string{"disk", "series", "writes"}, pos)
+		}
+
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != EQ {
+			return newParseError(tokstr(tok, lit), []string{"="}, pos)
+		}
+
+		var quota **int64
+		units := countUnits
+		switch name {
+		case "disk":
+			quota, units = &stmt.DiskQuota, byteUnits
+		case "series":
+			quota = &stmt.SeriesQuota
+		case "writes":
+			quota = &stmt.WriteQuota
+		}
+		if *quota != nil {
+			return &ParseError{Message: fmt.Sprintf("found duplicate %s quota", name), Pos: pos}
+		}
+
+		n, err := p.parseQuota(units)
+		if err != nil {
+			return err
+		}
+		*quota = &n
+
+		// Write quotas may be followed by a /s unit.
+		if name == "writes" {
+			if tok, _, _ := p.Scan(); tok == DIV {
+				if tok, pos, lit := p.Scan(); tok != IDENT || lit != "s" {
+					return newParseError(tokstr(tok, lit), []string{"s"}, pos)
+				}
+			} else {
+				p.Unscan()
+			}
+		}
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != COMMA {
+			p.Unscan()
+			return nil
+		}
+	}
+}
+
+// parseDatabaseWriteLimits parses the comma-separated write limits of an
+// ALTER DATABASE statement.  FUTURE, PAST and CLAMP are not keywords so they
+// are read as identifiers.
+func (p *Parser) parseDatabaseWriteLimits(stmt *AlterDatabaseStatement) error {
+	for {
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		name := strings.ToLower(lit)
+		if tok != IDENT || (name != "future" && name != "past" && name != "clamp") {
+			return newParseError(tokstr(tok, lit), []string{"future", "past", "clamp"}, pos)
+		}
+
+		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != EQ {
+			return newParseError(tokstr(tok, lit), []string{"="}, pos)
+		}
+
+		if name == "clamp" {
+			if stmt.ClampWrites != nil {
+				return &ParseError{Message: "found duplicate clamp write limit", Pos: pos}
+			}
+			tok, pos, lit := p.ScanIgnoreWhitespace()
+			if tok != TRUE && tok != FALSE {
+				return newParseError(tokstr(tok, lit), []string{"true", "false"}, pos)
+			}
+			clamp := tok == TRUE
+			stmt.ClampWrites = &clamp
+		} else {
+			limit := &stmt.FutureWriteLimit
+			if name == "past" {
+				limit = &stmt.PastWriteLimit
+			}
+			if *limit != nil {
+				return &ParseError{Message: fmt.Sprintf("found duplicate %s write limit", name), Pos: pos}
+			}
+
+			d, err := p.ParseDuration()
+			if err != nil {
+				return err
+			}
+			*limit = &d
+		}
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != COMMA {
+			p.Unscan()
+			return nil
+		}
+	}
+}
+
+// byteUnits and countUnits map the units of quotas, in upper case, to their
+// multipliers.
+var (
+	byteUnits = map[string]int64{
+		"": 1, "B": 1,
+		"K": 1 << 10, "KB": 1 << 10,
+		"M": 1 << 20, "MB": 1 << 20,
+		"G": 1 << 30, "GB": 1 << 30,
+		"T": 1 << 40, "TB": 1 << 40,
+	}
+	countUnits = map[string]int64{
+		"":  1,
+		"K": 1000,
+		"M": 1000 * 1000,
+	}
+)
+
+// parseQuota parses a non-negative integer quota followed by an optional unit
+// of units.
+func (p *Parser) parseQuota(units map[string]int64) (int64, error) {
+	// An integer immediately followed by letters is scanned as a duration.
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	if tok != INTEGER && tok != DURATIONVAL {
+		return 0, newParseError(tokstr(tok, lit), []string{"integer"}, pos)
+	}
+
+	i := strings.IndexFunc(lit, func(r rune) bool { return r < '0' || r > '9' })
+	if i == -1 {
+		i = len(lit)
+	}
+	m, ok := units[strings.ToUpper(lit[i:])]
+	if !ok {
+		return 0, &ParseError{Message: fmt.Sprintf("invalid quota unit %q", lit[i:]), Pos: pos}
+	}
+
+	n, err := strconv.ParseInt(lit[:i], 10, 64)
+	if err != nil || n > math.MaxInt64/m {
+		return 0, &ParseError{Message: fmt.Sprintf("quota out of range: %s", lit), Pos: pos}
+	}
+	return n * m, nil
+}
+
 // parseAlterRetentionPolicyStatement parses a string and returns an alter retention policy statement.
 // This function assumes the ALTER RETENTION POLICY tokens have already been consumed.
 func (p *Parser) parseAlterRetentionPolicyStatement() (*AlterRetentionPolicyStatement, error) {
@@ -312,13 +610,31 @@ func (p *Parser) parseAlterRetentionPolicyStatement() (*AlterRetentionPolicyStat
 Loop:
 	for {
 		tok, pos, lit := p.ScanIgnoreWhitespace()
-		if _, ok := found[tok]; ok {
+		if _, ok := found[tok]; ok && tok != MEASUREMENT {
 			return nil, &ParseError{
 				Message: fmt.Sprintf("found duplicate %s option", tok),
 				Pos:     pos,
 			}
 		}
 
+		// ROLLUP is not a keyword so it is read as an identifier.
+		if tok == IDENT && strings.ToLower(lit) == "rollup" {
+			if stmt.Rollup != nil || stmt.DropRollup {
+				return nil, &ParseError{Message: "found duplicate ROLLUP option", Pos: pos}
+			}
+			if tok, _, lit := p.ScanIgnoreWhitespace(); tok == IDENT && strings.ToLower(lit) == "none" {
+				stmt.DropRollup = true
+			} else {
+				p.Unscan()
+				source, err := p.parseRollup()
+				if err != nil {
+					return nil, err
+				}
+				stmt.Rollup = source
+			}
+			continue
+		}
+
 		switch tok {
 		case DURATION:
 			d, err := p.ParseDuration()
@@ -353,11 +669,22 @@ Loop:
 			} else {
 				return nil, newParseError(tokstr(tok, lit), []string{"DURATION"}, pos)
 			}
+		case MEASUREMENT:
+			name, d, err := p.parseMeasurementDuration()
+			if err != nil {
+				return nil, err
+			} else if _, ok := stmt.MeasurementDurations[name]; ok {
+				return nil, &ParseError{Message: fmt.Sprintf("found duplicate MEASUREMENT %s option", QuoteIdent(name)), Pos: pos}
+			}
+			if stmt.MeasurementDurations == nil {
+				stmt.MeasurementDurations = make(map[string]time.Duration)
+			}
+			stmt.MeasurementDurations[name] = d
 		case DEFAULT:
 			stmt.Default = true
 		default:
-			if len(found) == 0 {
-				return nil, newParseError(tokstr(tok, lit), []string{"DURATION", "REPLICATION", "SHARD", "DEFAULT"}, pos)
+			if len(found) == 0 && stmt.Rollup == nil && !stmt.DropRollup {
+				return nil, newParseError(tokstr(tok, lit), []string{"DURATION", "REPLICATION", "SHARD", "MEASUREMENT", "ROLLUP", "DEFAULT"}, pos)
 			}
 			p.Unscan()
 			break Loop
@@ -738,6 +1065,24 @@ func (p *Parser) parseSelectStatement(tr targetRequirement) (*SelectStatement, e
 		return nil, err
 	}
 
+	// Parse joined sources: "JOIN source".
+	for {
+		tok, pos, _ := p.ScanIgnoreWhitespace()
+		if tok != JOIN {
+			p.Unscan()
+			break
+		} else if !stmt.Join && len(stmt.Sources) > 1 {
+			return nil, &ParseError{Message: "JOIN cannot be combined with a list of sources", Pos: pos}
+		}
+
+		source, err := p.parseSource(true)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Sources = append(stmt.Sources, source)
+		stmt.Join = true
+	}
+
 	// Parse condition: "WHERE EXPR".
 	if stmt.Condition, err = p.parseCondition(); err != nil {
 		return nil, err
@@ -754,7 +1099,7 @@ func (p *Parser) parseSelectStatement(tr targetRequirement) (*SelectStatement, e
 	}
 
 	// Parse sort: "ORDER BY FIELD+".
-	if stmt.SortFields, err = p.parseOrderBy(); err != nil {
+	if stmt.SortFields, err = p.parseOrderByFields(); err != nil {
 		return nil, err
 	}
 
@@ -786,7 +1131,7 @@ func (p *Parser) parseSelectStatement(tr targetRequirement) (*SelectStatement, e
 	// Set if the query is a raw data query or one with an aggregate
 	stmt.IsRawQuery = true
 	WalkFunc(stmt.Fields, func(n Node) {
-		if _, ok := n.(*Call); ok {
+		if call, ok := n.(*Call); ok && !IsMathFunction(call) {
 			stmt.IsRawQuery = false
 		}
 	})
@@ -1122,6 +1467,24 @@ func (p *Parser) parseShowQueriesStatement() (*ShowQueriesStatement, error) {
 	return &ShowQueriesStatement{}, nil
 }
 
+// parseShowRetentionStatement parses a string and returns either a
+// ShowRetentionPoliciesStatement or a ShowRetentionEnforcementStatement.
+// This function assumes the "SHOW RETENTION" tokens have been consumed.
+func (p *Parser) parseShowRetentionStatement() (Statement, error) {
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	if tok == POLICIES {
+		stmt, err := p.parseShowRetentionPoliciesStatement()
+		if err != nil {
+			return nil, err
+		}
+		return stmt, nil
+	} else if tok == IDENT && strings.ToLower(lit) == "enforcement" {
+		// ENFORCEMENT is not a keyword so it remains usable as an identifier.
+		return &ShowRetentionEnforcementStatement{}, nil
+	}
+	return nil, newParseError(tokstr(tok, lit), []string{"POLICIES", "ENFORCEMENT"}, pos)
+}
+
 // parseShowRetentionPoliciesStatement parses a string and returns a ShowRetentionPoliciesStatement.
 // This function assumes the "SHOW RETENTION POLICIES" tokens have been consumed.
 func (p *Parser) parseShowRetentionPoliciesStatement() (*ShowRetentionPoliciesStatement, error) {
@@ -1617,7 +1980,16 @@ func (p *Parser) parseDropShardStatement() (*DropShardStatement, error) {
 // parseShowContinuousQueriesStatement parses a string and returns a ShowContinuousQueriesStatement.
 // This function assumes the "SHOW CONTINUOUS" tokens have already been consumed.
 func (p *Parser) parseShowContinuousQueriesStatement() (*ShowContinuousQueriesStatement, error) {
-	return &ShowContinuousQueriesStatement{}, nil
+	stmt := &ShowContinuousQueriesStatement{}
+
+	// Parse the optional STATUS. It is not a keyword so it remains
+	// usable as an identifier.
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok == IDENT && strings.ToLower(lit) == "status" {
+		stmt.Status = true
+	} else {
+		p.Unscan()
+	}
+	return stmt, nil
 }
 
 // parseGrantsForUserStatement parses a string and returns a ShowGrantsForUserStatement.
@@ -1665,7 +2037,7 @@ func (p *Parser) parseCreateContinuousQueryStatement() (*CreateContinuousQuerySt
 	stmt.Database = ident
 
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == RESAMPLE {
-		stmt.ResampleEvery, stmt.ResampleFor, err = p.parseResample()
+		stmt.ResampleEvery, stmt.ResampleOffset, stmt.ResampleFor, err = p.parseResample()
 		if err != nil {
 			return nil, err
 		}
@@ -1905,6 +2277,87 @@ func (p *Parser) parseCreateUserStatement() (*CreateUserStatement, error) {
 	return stmt, nil
 }
 
+// parseCreateTokenStatement parses a string and returns a CreateTokenStatement.
+// This function assumes the "CREATE TOKEN" tokens have already been consumed.
+func (p *Parser) parseCreateTokenStatement() (*CreateTokenStatement, error) {
+	stmt := &CreateTokenStatement{}
+
+	// Parse the name of the user the token authenticates as.
+	if err := p.parseTokens([]Token{FOR, USER}); err != nil {
+		return nil, err
+	}
+	ident, err := p.ParseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.User = ident
+
+	// Parse the optional privilege and database the token is scoped to.
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == WITH {
+		if stmt.Privilege, err = p.parsePrivilege(); err != nil {
+			return nil, err
+		}
+		if err := p.parseTokens([]Token{ON}); err != nil {
+			return nil, err
+		}
+		if stmt.Database, err = p.ParseIdent(); err != nil {
+			return nil, err
+		}
+	} else {
+		p.Unscan()
+	}
+
+	// Parse the optional expiry.  EXPIRES is not a keyword so it remains
+	// usable as an identifier.
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok == IDENT && strings.ToLower(lit) == "expires" {
+		if stmt.Expires, err = p.ParseDuration(); err != nil {
+			return nil, err
+		}
+	} else {
+		p.Unscan()
+	}
+
+	return stmt, nil
+}
+
+// parseDropTokenStatement parses a string and returns a DropTokenStatement.
+// This function assumes the "DROP TOKEN" tokens have already been consumed.
+func (p *Parser) parseDropTokenStatement() (*DropTokenStatement, error) {
+	id, err := p.parseString()
+	if err != nil {
+		return nil, err
+	}
+	return &DropTokenStatement{ID: id}, nil
+}
+
+// parseShowQuotasStatement parses a string and returns a ShowQuotasStatement.
+// This function assumes the "SHOW QUOTAS" tokens have already been consumed.
+func (p *Parser) parseShowQuotasStatement() (*ShowQuotasStatement, error) {
+	return &ShowQuotasStatement{}, nil
+}
+
+// parseShowTokensStatement parses a string and returns a ShowTokensStatement.
+// This function assumes the "SHOW TOKENS" tokens have already been consumed.
+func (p *Parser) parseShowTokensStatement() (*ShowTokensStatement, error) {
+	stmt := &ShowTokensStatement{}
+
+	// Parse the optional user the tokens are listed for.
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != FOR {
+		p.Unscan()
+		return stmt, nil
+	}
+	if err := p.parseTokens([]Token{USER}); err != nil {
+		return nil, err
+	}
+	ident, err := p.ParseIdent()
+	if err != nil {
+		return nil, err
+	}
+	stmt.User = ident
+
+	return stmt, nil
+}
+
 // parseDropUserStatement parses a string and returns a DropUserStatement.
 // This function assumes the DROP USER tokens have already been consumed.
 func (p *Parser) parseDropUserStatement() (*DropUserStatement, error) {
@@ -2082,6 +2535,13 @@ type validateField struct {
 func (c *validateField) Visit(n Node) Visitor {
 	e, ok := n.(*BinaryExpr)
 	if !ok {
+		// The condition of if() is allowed to use comparison operators.
+		if call, ok := n.(*Call); ok && call.Name == "if" && len(call.Args) > 0 {
+			for _, arg := range call.Args[1:] {
+				Walk(c, arg)
+			}
+			return nil
+		}
 		return c
 	}
 
@@ -2384,8 +2844,21 @@ func (p *Parser) ParseOptionalTokenAndInt(t Token) (int, error) {
 	return int(n), nil
 }
 
-// parseOrderBy parses the "ORDER BY" clause of a query, if it exists.
+// parseOrderBy parses the "ORDER BY" clause of a query, if it exists. Only
+// time may be sorted by.
 func (p *Parser) parseOrderBy() (SortFields, error) {
+	fields, err := p.parseOrderByFields()
+	if err != nil {
+		return nil, err
+	} else if len(fields) > 1 || (len(fields) == 1 && !fields[0].IsTime()) {
+		return nil, errors.New("only ORDER BY time supported at this time")
+	}
+	return fields, nil
+}
+
+// parseOrderByFields parses the "ORDER BY" clause of a SELECT statement, if
+// it exists. The rows may be sorted by any number of fields.
+func (p *Parser) parseOrderByFields() (SortFields, error) {
 	// Return nil result and nil error if no ORDER token at this position.
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != ORDER {
 		p.Unscan()
@@ -2423,11 +2896,6 @@ func (p *Parser) parseSortFields() (SortFields, error) {
 		if err != nil {
 			return nil, err
 		}
-
-		if lit != "time" {
-			return nil, errors.New("only ORDER BY time supported at this time")
-		}
-
 		fields = append(fields, field)
 	// Parse error...
 	default:
@@ -2451,10 +2919,6 @@ func (p *Parser) parseSortFields() (SortFields, error) {
 		fields = append(fields, field)
 	}
 
-	if len(fields) > 1 {
-		return nil, errors.New("only ORDER BY time supported at this time")
-	}
-
 	return fields, nil
 }
 
@@ -2613,6 +3077,11 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 
 		// Parse it as a VarRef.
 		return p.ParseVarRef()
+	case MEASUREMENT:
+		if !p.measurementRef {
+			return nil, newParseError(tokstr(tok, lit), []string{"identifier", "string", "number", "bool"}, pos)
+		}
+		return &VarRef{Val: "measurement"}, nil
 	case DISTINCT:
 		// If the next immediate token is a left parentheses, parse as function call.
 		// Otherwise parse as a Distinct expression.
@@ -2842,22 +3311,37 @@ func (p *Parser) parseCall(name string) (*Call, error) {
 	return &Call{Name: name, Args: args}, nil
 }
 
-// parseResample parses a RESAMPLE [EVERY <duration>] [FOR <duration>].
+// parseResample parses a RESAMPLE [EVERY <duration> [OFFSET <duration>]] [FOR <duration>].
 // This function assumes RESAMPLE has already been consumed.
 // EVERY and FOR are optional, but at least one of the two has to be used.
-func (p *Parser) parseResample() (time.Duration, time.Duration, error) {
-	var interval time.Duration
+func (p *Parser) parseResample() (time.Duration, time.Duration, time.Duration, error) {
+	var interval, offset time.Duration
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == EVERY {
 		tok, pos, lit := p.ScanIgnoreWhitespace()
 		if tok != DURATIONVAL {
-			return 0, 0, newParseError(tokstr(tok, lit), []string{"duration"}, pos)
+			return 0, 0, 0, newParseError(tokstr(tok, lit), []string{"duration"}, pos)
 		}
 
 		d, err := ParseDuration(lit)
 		if err != nil {
-			return 0, 0, &ParseError{Message: err.Error(), Pos: pos}
+			return 0, 0, 0, &ParseError{Message: err.Error(), Pos: pos}
 		}
 		interval = d
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok == OFFSET {
+			tok, pos, lit := p.ScanIgnoreWhitespace()
+			if tok != DURATIONVAL {
+				return 0, 0, 0, newParseError(tokstr(tok, lit), []string{"duration"}, pos)
+			}
+
+			d, err := ParseDuration(lit)
+			if err != nil {
+				return 0, 0, 0, &ParseError{Message: err.Error(), Pos: pos}
+			}
+			offset = d
+		} else {
+			p.Unscan()
+		}
 	} else {
 		p.Unscan()
 	}
@@ -2866,12 +3350,12 @@ func (p *Parser) parseResample() (time.Duration, time.Duration, error) {
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == FOR {
 		tok, pos, lit := p.ScanIgnoreWhitespace()
 		if tok != DURATIONVAL {
-			return 0, 0, newParseError(tokstr(tok, lit), []string{"duration"}, pos)
+			return 0, 0, 0, newParseError(tokstr(tok, lit), []string{"duration"}, pos)
 		}
 
 		d, err := ParseDuration(lit)
 		if err != nil {
-			return 0, 0, &ParseError{Message: err.Error(), Pos: pos}
+			return 0, 0, 0, &ParseError{Message: err.Error(), Pos: pos}
 		}
 		maxDuration = d
 	} else {
@@ -2882,9 +3366,9 @@ func (p *Parser) parseResample() (time.Duration, time.Duration, error) {
 	// so we can return a suitable error message.
 	if interval == 0 && maxDuration == 0 {
 		tok, pos, lit := p.ScanIgnoreWhitespace()
-		return 0, 0, newParseError(tokstr(tok, lit), []string{"EVERY", "FOR"}, pos)
+		return 0, 0, 0, newParseError(tokstr(tok, lit), []string{"EVERY", "FOR"}, pos)
 	}
-	return interval, maxDuration, nil
+	return interval, offset, maxDuration, nil
 }
 
 // scan returns the next token from the underlying scanner.
@@ -650,23 +650,63 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// SELECT statement with joined sources
+		{
+			s: `SELECT errors.value / requests.value FROM errors JOIN requests`,
+			stmt: &influxql.SelectStatement{
+				IsRawQuery: true,
+				Fields: []*influxql.Field{{
+					Expr: &influxql.BinaryExpr{
+						Op:  influxql.DIV,
+						LHS: &influxql.VarRef{Val: "errors.value"},
+						RHS: &influxql.VarRef{Val: "requests.value"},
+					},
+				}},
+				Sources: []influxql.Source{
+					&influxql.Measurement{Name: "errors"},
+					&influxql.Measurement{Name: "requests"},
+				},
+				Join: true,
+			},
+		},
+
 		// SELECT statement with multiple ORDER BY fields
 		{
-			skip: true,
-			s:    `SELECT field1 FROM myseries ORDER BY ASC, field1, field2 DESC LIMIT 10`,
+			s: `SELECT field1 FROM myseries ORDER BY ASC, field1, field2 DESC LIMIT 10`,
 			stmt: &influxql.SelectStatement{
 				IsRawQuery: true,
 				Fields:     []*influxql.Field{{Expr: &influxql.VarRef{Val: "field1"}}},
 				Sources:    []influxql.Source{&influxql.Measurement{Name: "myseries"}},
 				SortFields: []*influxql.SortField{
 					{Ascending: true},
-					{Name: "field1"},
+					{Name: "field1", Ascending: true},
 					{Name: "field2"},
 				},
 				Limit: 10,
 			},
 		},
 
+		// SELECT statement sorted by a field
+		{
+			s: `SELECT max(value) FROM cpu GROUP BY time(1m) ORDER BY max DESC, time LIMIT 3`,
+			stmt: &influxql.SelectStatement{
+				Fields: []*influxql.Field{{Expr: &influxql.Call{
+					Name: "max",
+					Args: []influxql.Expr{&influxql.VarRef{Val: "value"}},
+				}}},
+				Sources: []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+				Dimensions: []*influxql.Dimension{{Expr: &influxql.Call{
+					Name: "time",
+					Args: []influxql.Expr{&influxql.DurationLiteral{Val: time.Minute}},
+				}}},
+				SortFields: []*influxql.SortField{
+					{Name: "max"},
+					{Name: "time", Ascending: true},
+				},
+				Limit: 3,
+			},
+		},
+
 		// SELECT statement with SLIMIT and SOFFSET
 		{
 			s: `SELECT field1 FROM myseries SLIMIT 10 SOFFSET 5`,
@@ -1802,6 +1842,12 @@ func TestParser_ParseStatement(t *testing.T) {
 				Database: "db0",
 			},
 		},
+
+		// SHOW RETENTION ENFORCEMENT
+		{
+			s:    `SHOW RETENTION ENFORCEMENT`,
+			stmt: &influxql.ShowRetentionEnforcementStatement{},
+		},
 		// SHOW TAG KEY CARDINALITY statement
 		{
 			s:    `SHOW TAG KEY CARDINALITY`,
@@ -2326,6 +2372,22 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.ShowUsersStatement{},
 		},
 
+		// SHOW QUOTAS
+		{
+			s:    `SHOW QUOTAS`,
+			stmt: &influxql.ShowQuotasStatement{},
+		},
+
+		// SHOW TOKENS
+		{
+			s:    `SHOW TOKENS`,
+			stmt: &influxql.ShowTokensStatement{},
+		},
+		{
+			s:    `SHOW TOKENS FOR USER jdoe`,
+			stmt: &influxql.ShowTokensStatement{User: "jdoe"},
+		},
+
 		// SHOW FIELD KEYS
 		{
 			skip: true,
@@ -2551,6 +2613,10 @@ func TestParser_ParseStatement(t *testing.T) {
 			s:    `SHOW CONTINUOUS QUERIES`,
 			stmt: &influxql.ShowContinuousQueriesStatement{},
 		},
+		{
+			s:    `SHOW CONTINUOUS QUERIES STATUS`,
+			stmt: &influxql.ShowContinuousQueriesStatement{Status: true},
+		},
 
 		// CREATE CONTINUOUS QUERY ... INTO <measurement>
 		{
@@ -2626,6 +2692,32 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		{
+			s: `CREATE CONTINUOUS QUERY myquery ON testdb RESAMPLE EVERY 1h OFFSET 5m FOR 2h BEGIN SELECT count(field1) INTO measure1 FROM myseries GROUP BY time(1h) END`,
+			stmt: &influxql.CreateContinuousQueryStatement{
+				Name:     "myquery",
+				Database: "testdb",
+				Source: &influxql.SelectStatement{
+					Fields:  []*influxql.Field{{Expr: &influxql.Call{Name: "count", Args: []influxql.Expr{&influxql.VarRef{Val: "field1"}}}}},
+					Target:  &influxql.Target{Measurement: &influxql.Measurement{Name: "measure1", IsTarget: true}},
+					Sources: []influxql.Source{&influxql.Measurement{Name: "myseries"}},
+					Dimensions: []*influxql.Dimension{
+						{
+							Expr: &influxql.Call{
+								Name: "time",
+								Args: []influxql.Expr{
+									&influxql.DurationLiteral{Val: time.Hour},
+								},
+							},
+						},
+					},
+				},
+				ResampleEvery:  time.Hour,
+				ResampleOffset: 5 * time.Minute,
+				ResampleFor:    2 * time.Hour,
+			},
+		},
+
 		{
 			s: `create continuous query "this.is-a.test" on segments begin select * into measure1 from cpu_load_short end`,
 			stmt: &influxql.CreateContinuousQueryStatement{
@@ -2852,6 +2944,35 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.DropUserStatement{Name: "jdoe"},
 		},
 
+		// CREATE TOKEN statement
+		{
+			s:    `CREATE TOKEN FOR USER jdoe`,
+			stmt: &influxql.CreateTokenStatement{User: "jdoe"},
+		},
+		{
+			s: `CREATE TOKEN FOR USER jdoe WITH READ ON db0 EXPIRES 30d`,
+			stmt: &influxql.CreateTokenStatement{
+				User:      "jdoe",
+				Privilege: influxql.ReadPrivilege,
+				Database:  "db0",
+				Expires:   30 * 24 * time.Hour,
+			},
+		},
+		{
+			s: `CREATE TOKEN FOR USER jdoe WITH ALL PRIVILEGES ON db0`,
+			stmt: &influxql.CreateTokenStatement{
+				User:      "jdoe",
+				Privilege: influxql.AllPrivileges,
+				Database:  "db0",
+			},
+		},
+
+		// DROP TOKEN statement
+		{
+			s:    `DROP TOKEN '0123456789abcdef'`,
+			stmt: &influxql.DropTokenStatement{ID: "0123456789abcdef"},
+		},
+
 		// GRANT READ
 		{
 			s: `GRANT READ ON testdb TO jdoe`,
@@ -3029,6 +3150,67 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// CREATE RETENTION POLICY with MEASUREMENT durations and ROLLUP
+		{
+			s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 7d REPLICATION 1 MEASUREMENT cpu DURATION 1d MEASUREMENT "mem" DURATION 2d ROLLUP BEGIN SELECT mean(*) INTO "long".:MEASUREMENT FROM /.*/ GROUP BY time(1h), * END DEFAULT`,
+			stmt: &influxql.CreateRetentionPolicyStatement{
+				Name:        "policy1",
+				Database:    "testdb",
+				Duration:    7 * 24 * time.Hour,
+				Replication: 1,
+				MeasurementDurations: map[string]time.Duration{
+					"cpu": 24 * time.Hour,
+					"mem": 48 * time.Hour,
+				},
+				Rollup: &influxql.SelectStatement{
+					Fields: []*influxql.Field{{Expr: &influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.Wildcard{}}}}},
+					Target: &influxql.Target{Measurement: &influxql.Measurement{RetentionPolicy: "long", IsTarget: true}},
+					Sources: []influxql.Source{&influxql.Measurement{Regex: &influxql.RegexLiteral{Val: regexp.MustCompile(".*")}}},
+					Dimensions: []*influxql.Dimension{
+						{Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{&influxql.DurationLiteral{Val: time.Hour}}}},
+						{Expr: &influxql.Wildcard{}},
+					},
+				},
+				Default: true,
+			},
+		},
+
+		// ALTER DATABASE
+		{
+			s: `ALTER DATABASE db0 SET QUOTA disk=500GB, series=5M, writes=200k/s`,
+			stmt: &influxql.AlterDatabaseStatement{
+				Name:        "db0",
+				DiskQuota:   int64ptr(500 << 30),
+				SeriesQuota: int64ptr(5000000),
+				WriteQuota:  int64ptr(200000),
+			},
+		},
+		{
+			s: `ALTER DATABASE "db0" SET QUOTA writes=1000, disk=0`,
+			stmt: &influxql.AlterDatabaseStatement{
+				Name:       "db0",
+				DiskQuota:  int64ptr(0),
+				WriteQuota: int64ptr(1000),
+			},
+		},
+		{
+			s:    `ALTER DATABASE db0 SET QUOTA series = 10k`,
+			stmt: &influxql.AlterDatabaseStatement{Name: "db0", SeriesQuota: int64ptr(10000)},
+		},
+		{
+			s: `ALTER DATABASE db0 SET WRITE LIMIT future=1h, past=52w, clamp=true`,
+			stmt: &influxql.AlterDatabaseStatement{
+				Name:             "db0",
+				FutureWriteLimit: duration(time.Hour),
+				PastWriteLimit:   duration(52 * 7 * 24 * time.Hour),
+				ClampWrites:      boolptr(true),
+			},
+		},
+		{
+			s:    `ALTER DATABASE "db0" SET WRITE LIMIT past = INF`,
+			stmt: &influxql.AlterDatabaseStatement{Name: "db0", PastWriteLimit: duration(0)},
+		},
+
 		// ALTER RETENTION POLICY
 		{
 			s:    `ALTER RETENTION POLICY policy1 ON testdb DURATION 1m REPLICATION 4 DEFAULT`,
@@ -3084,6 +3266,35 @@ func TestParser_ParseStatement(t *testing.T) {
 			s:    `ALTER RETENTION POLICY default ON testdb DURATION 0s REPLICATION 1 SHARD DURATION 0s`,
 			stmt: newAlterRetentionPolicyStatement("default", "testdb", time.Duration(0), 0, 1, false),
 		},
+		// ALTER RETENTION POLICY with MEASUREMENT durations and ROLLUP NONE
+		{
+			s: `ALTER RETENTION POLICY policy1 ON testdb MEASUREMENT cpu DURATION 1d ROLLUP NONE MEASUREMENT mem DURATION INF`,
+			stmt: &influxql.AlterRetentionPolicyStatement{
+				Name:     "policy1",
+				Database: "testdb",
+				MeasurementDurations: map[string]time.Duration{
+					"cpu": 24 * time.Hour,
+					"mem": 0,
+				},
+				DropRollup: true,
+			},
+		},
+		// ALTER RETENTION POLICY with ROLLUP
+		{
+			s: `ALTER RETENTION POLICY policy1 ON testdb ROLLUP BEGIN SELECT max(value) INTO "long".cpu_max FROM cpu GROUP BY time(1h) END`,
+			stmt: &influxql.AlterRetentionPolicyStatement{
+				Name:     "policy1",
+				Database: "testdb",
+				Rollup: &influxql.SelectStatement{
+					Fields:  []*influxql.Field{{Expr: &influxql.Call{Name: "max", Args: []influxql.Expr{&influxql.VarRef{Val: "value"}}}}},
+					Target:  &influxql.Target{Measurement: &influxql.Measurement{RetentionPolicy: "long", Name: "cpu_max", IsTarget: true}},
+					Sources: []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+					Dimensions: []*influxql.Dimension{
+						{Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{&influxql.DurationLiteral{Val: time.Hour}}}},
+					},
+				},
+			},
+		},
 
 		// SHOW STATS
 		{
@@ -3134,6 +3345,29 @@ func TestParser_ParseStatement(t *testing.T) {
 				Mode:            "ANY",
 			},
 		},
+		{
+			s: `CREATE SUBSCRIPTION "name" ON "db"."rp" WHERE measurement =~ /^api_/ AND region = 'us-east' DESTINATIONS ALL 'udp://host1:9093'`,
+			stmt: &influxql.CreateSubscriptionStatement{
+				Name:            "name",
+				Database:        "db",
+				RetentionPolicy: "rp",
+				Destinations:    []string{"udp://host1:9093"},
+				Mode:            "ALL",
+				Condition: &influxql.BinaryExpr{
+					Op: influxql.AND,
+					LHS: &influxql.BinaryExpr{
+						Op:  influxql.EQREGEX,
+						LHS: &influxql.VarRef{Val: "measurement"},
+						RHS: &influxql.RegexLiteral{Val: regexp.MustCompile("^api_")},
+					},
+					RHS: &influxql.BinaryExpr{
+						Op:  influxql.EQ,
+						LHS: &influxql.VarRef{Val: "region"},
+						RHS: &influxql.StringLiteral{Val: "us-east"},
+					},
+				},
+			},
+		},
 
 		// DROP SUBSCRIPTION
 		{
@@ -3158,6 +3392,8 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SELECT field1 X`, err: `found X, expected FROM at line 1, char 15`},
 		{s: `SELECT field1 FROM "series" WHERE X +;`, err: `found ;, expected identifier, string, number, bool at line 1, char 38`},
 		{s: `SELECT field1 FROM myseries GROUP`, err: `found EOF, expected BY at line 1, char 35`},
+		{s: `SELECT field1 FROM myseries JOIN`, err: `found EOF, expected identifier at line 1, char 34`},
+		{s: `SELECT field1 FROM cpu, mem JOIN disk`, err: `JOIN cannot be combined with a list of sources at line 1, char 29`},
 		{s: `SELECT field1 FROM myseries LIMIT`, err: `found EOF, expected integer at line 1, char 35`},
 		{s: `SELECT field1 FROM myseries LIMIT 10.5`, err: `found 10.5, expected integer at line 1, char 35`},
 		{s: `SELECT field1 FROM myseries OFFSET`, err: `found EOF, expected integer at line 1, char 36`},
@@ -3167,7 +3403,8 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `SELECT field1 FROM myseries ORDER BY /`, err: `found /, expected identifier, ASC, DESC at line 1, char 38`},
 		{s: `SELECT field1 FROM myseries ORDER BY 1`, err: `found 1, expected identifier, ASC, DESC at line 1, char 38`},
 		{s: `SELECT field1 FROM myseries ORDER BY time ASC,`, err: `found EOF, expected identifier at line 1, char 47`},
-		{s: `SELECT field1 FROM myseries ORDER BY time, field1`, err: `only ORDER BY time supported at this time`},
+		{s: `SHOW MEASUREMENTS ORDER BY time, field1`, err: `only ORDER BY time supported at this time`},
+		{s: `SHOW MEASUREMENTS ORDER BY field1`, err: `only ORDER BY time supported at this time`},
 		{s: `SELECT field1 AS`, err: `found EOF, expected identifier at line 1, char 18`},
 		{s: `SELECT field1 FROM 12`, err: `found 12, expected identifier at line 1, char 20`},
 		{s: `SELECT 1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000 FROM myseries`, err: `unable to parse integer at line 1, char 8`},
@@ -3196,11 +3433,11 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `DROP SERIES FROM "foo".myseries`, err: `retention policy not supported at line 1, char 1`},
 		{s: `DROP SERIES FROM foo..myseries`, err: `database not supported at line 1, char 1`},
 		{s: `SHOW CONTINUOUS`, err: `found EOF, expected QUERIES at line 1, char 17`},
-		{s: `SHOW RETENTION`, err: `found EOF, expected POLICIES at line 1, char 16`},
-		{s: `SHOW RETENTION ON`, err: `found ON, expected POLICIES at line 1, char 16`},
+		{s: `SHOW RETENTION`, err: `found EOF, expected POLICIES, ENFORCEMENT at line 1, char 16`},
+		{s: `SHOW RETENTION ON`, err: `found ON, expected POLICIES, ENFORCEMENT at line 1, char 16`},
 		{s: `SHOW RETENTION POLICIES ON`, err: `found EOF, expected identifier at line 1, char 28`},
 		{s: `SHOW SHARD`, err: `found EOF, expected GROUPS at line 1, char 12`},
-		{s: `SHOW FOO`, err: `found FOO, expected CONTINUOUS, DATABASES, DIAGNOSTICS, FIELD, GRANTS, MEASUREMENT, MEASUREMENTS, QUERIES, RETENTION, SERIES, SHARD, SHARDS, STATS, SUBSCRIPTIONS, TAG, USERS at line 1, char 6`},
+		{s: `SHOW FOO`, err: `found FOO, expected CONTINUOUS, DATABASES, DIAGNOSTICS, FIELD, GRANTS, MEASUREMENT, MEASUREMENTS, QUERIES, QUOTAS, RETENTION, SERIES, SHARD, SHARDS, STATS, SUBSCRIPTIONS, TAG, TOKENS, USERS at line 1, char 6`},
 		{s: `SHOW STATS FOR`, err: `found EOF, expected string at line 1, char 16`},
 		{s: `SHOW DIAGNOSTICS FOR`, err: `found EOF, expected string at line 1, char 22`},
 		{s: `SHOW GRANTS`, err: `found EOF, expected FOR at line 1, char 13`},
@@ -3213,8 +3450,10 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `CREATE CONTINUOUS QUERY`, err: `found EOF, expected identifier at line 1, char 25`},
 		{s: `CREATE CONTINUOUS QUERY cq ON db RESAMPLE FOR 5s BEGIN SELECT mean(value) INTO cpu_mean FROM cpu GROUP BY time(10s) END`, err: `FOR duration must be >= GROUP BY time duration: must be a minimum of 10s, got 5s`},
 		{s: `CREATE CONTINUOUS QUERY cq ON db RESAMPLE EVERY 10s FOR 5s BEGIN SELECT mean(value) INTO cpu_mean FROM cpu GROUP BY time(5s) END`, err: `FOR duration must be >= GROUP BY time duration: must be a minimum of 10s, got 5s`},
-		{s: `DROP FOO`, err: `found FOO, expected CONTINUOUS, DATABASE, MEASUREMENT, RETENTION, SERIES, SHARD, SUBSCRIPTION, USER at line 1, char 6`},
-		{s: `CREATE FOO`, err: `found FOO, expected CONTINUOUS, DATABASE, USER, RETENTION, SUBSCRIPTION at line 1, char 8`},
+		{s: `CREATE CONTINUOUS QUERY cq ON db RESAMPLE EVERY 10s OFFSET 10s BEGIN SELECT mean(value) INTO cpu_mean FROM cpu GROUP BY time(10s) END`, err: `OFFSET duration must be < EVERY duration: must be less than 10s, got 10s`},
+		{s: `CREATE CONTINUOUS QUERY cq ON db RESAMPLE EVERY 10s OFFSET BEGIN SELECT mean(value) INTO cpu_mean FROM cpu GROUP BY time(10s) END`, err: `found BEGIN, expected duration at line 1, char 60`},
+		{s: `DROP FOO`, err: `found FOO, expected CONTINUOUS, DATABASE, MEASUREMENT, RETENTION, SERIES, SHARD, SUBSCRIPTION, TOKEN, USER at line 1, char 6`},
+		{s: `CREATE FOO`, err: `found FOO, expected CONTINUOUS, DATABASE, USER, RETENTION, SUBSCRIPTION, TOKEN at line 1, char 8`},
 		{s: `CREATE DATABASE`, err: `found EOF, expected identifier at line 1, char 17`},
 		{s: `CREATE DATABASE "testdb" WITH`, err: `found EOF, expected DURATION, NAME, REPLICATION, SHARD at line 1, char 31`},
 		{s: `CREATE DATABASE "testdb" WITH DURATION`, err: `found EOF, expected duration at line 1, char 40`},
@@ -3227,6 +3466,11 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `DROP RETENTION POLICY "1h.cpu"`, err: `found EOF, expected ON at line 1, char 31`},
 		{s: `DROP RETENTION POLICY "1h.cpu" ON`, err: `found EOF, expected identifier at line 1, char 35`},
 		{s: `DROP USER`, err: `found EOF, expected identifier at line 1, char 11`},
+		{s: `DROP TOKEN`, err: `found EOF, expected string at line 1, char 12`},
+		{s: `CREATE TOKEN`, err: `found EOF, expected FOR at line 1, char 14`},
+		{s: `CREATE TOKEN FOR USER jdoe WITH READ`, err: `found EOF, expected ON at line 1, char 38`},
+		{s: `CREATE TOKEN FOR USER jdoe EXPIRES`, err: `found EOF, expected duration at line 1, char 36`},
+		{s: `SHOW TOKENS FOR jdoe`, err: `found jdoe, expected USER at line 1, char 17`},
 		{s: `DROP SUBSCRIPTION`, err: `found EOF, expected identifier at line 1, char 19`},
 		{s: `DROP SUBSCRIPTION "name"`, err: `found EOF, expected ON at line 1, char 25`},
 		{s: `DROP SUBSCRIPTION "name" ON `, err: `found EOF, expected identifier at line 1, char 30`},
@@ -3245,6 +3489,9 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `CREATE SUBSCRIPTION "name" ON "db"."rp"`, err: `found EOF, expected DESTINATIONS at line 1, char 40`},
 		{s: `CREATE SUBSCRIPTION "name" ON "db"."rp" DESTINATIONS`, err: `found EOF, expected ALL, ANY at line 1, char 54`},
 		{s: `CREATE SUBSCRIPTION "name" ON "db"."rp" DESTINATIONS ALL `, err: `found EOF, expected string at line 1, char 59`},
+		{s: `CREATE SUBSCRIPTION "name" ON "db"."rp" WHERE value > 1 DESTINATIONS ALL 'udp://host1:9093'`, err: `invalid subscription condition: value > 1`},
+		{s: `CREATE SUBSCRIPTION "name" ON "db"."rp" WHERE host = 'a' OR region = host DESTINATIONS ALL 'udp://host1:9093'`, err: `invalid subscription condition: region = host`},
+		{s: `SELECT value FROM cpu WHERE measurement = 'cpu'`, err: `found MEASUREMENT, expected identifier, string, number, bool at line 1, char 29`},
 		{s: `GRANT`, err: `found EOF, expected READ, WRITE, ALL [PRIVILEGES] at line 1, char 7`},
 		{s: `GRANT BOGUS`, err: `found BOGUS, expected READ, WRITE, ALL [PRIVILEGES] at line 1, char 7`},
 		{s: `GRANT READ`, err: `found EOF, expected ON at line 1, char 12`},
@@ -3325,11 +3572,32 @@ func TestParser_ParseStatement(t *testing.T) {
 		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION 0`, err: `invalid value 0: must be 1 <= n <= 2147483647 at line 1, char 67`},
 		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION bad`, err: `found bad, expected integer at line 1, char 67`},
 		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION 2 SHARD DURATION INF`, err: `invalid duration INF for shard duration at line 1, char 84`},
-		{s: `ALTER`, err: `found EOF, expected RETENTION at line 1, char 7`},
+		{s: `ALTER`, err: `found EOF, expected DATABASE, RETENTION at line 1, char 7`},
+		{s: `ALTER DATABASE db0`, err: `found EOF, expected SET at line 1, char 20`},
+		{s: `ALTER DATABASE db0 SET QUOTA`, err: `found EOF, expected disk, series, writes at line 1, char 30`},
+		{s: `ALTER DATABASE db0 SET QUOTA memory=1`, err: `found memory, expected disk, series, writes at line 1, char 30`},
+		{s: `ALTER DATABASE db0 SET QUOTA disk 1`, err: `found 1, expected = at line 1, char 35`},
+		{s: `ALTER DATABASE db0 SET QUOTA disk=`, err: `found EOF, expected integer at line 1, char 35`},
+		{s: `ALTER DATABASE db0 SET QUOTA disk=-1`, err: `found -, expected integer at line 1, char 35`},
+		{s: `ALTER DATABASE db0 SET QUOTA disk=5PB`, err: `invalid quota unit "PB" at line 1, char 35`},
+		{s: `ALTER DATABASE db0 SET QUOTA series=5GB`, err: `invalid quota unit "GB" at line 1, char 37`},
+		{s: `ALTER DATABASE db0 SET QUOTA disk=9000000000TB`, err: `quota out of range: 9000000000TB at line 1, char 35`},
+		{s: `ALTER DATABASE db0 SET QUOTA writes=1/m`, err: `found m, expected s at line 1, char 39`},
+		{s: `ALTER DATABASE db0 SET QUOTA series=1, series=2`, err: `found duplicate series quota at line 1, char 40`},
+		{s: `ALTER DATABASE db0 SET LIMIT`, err: `found LIMIT, expected QUOTA, WRITE at line 1, char 24`},
+		{s: `ALTER DATABASE db0 SET WRITE LIMIT`, err: `found EOF, expected future, past, clamp at line 1, char 36`},
+		{s: `ALTER DATABASE db0 SET WRITE LIMIT future=1`, err: `found 1, expected duration at line 1, char 43`},
+		{s: `ALTER DATABASE db0 SET WRITE LIMIT clamp=yes`, err: `found yes, expected true, false at line 1, char 42`},
+		{s: `ALTER DATABASE db0 SET WRITE LIMIT past=1d, past=2d`, err: `found duplicate past write limit at line 1, char 45`},
 		{s: `ALTER RETENTION`, err: `found EOF, expected POLICY at line 1, char 17`},
 		{s: `ALTER RETENTION POLICY`, err: `found EOF, expected identifier at line 1, char 24`},
 		{s: `ALTER RETENTION POLICY policy1`, err: `found EOF, expected ON at line 1, char 32`}, {s: `ALTER RETENTION POLICY policy1 ON`, err: `found EOF, expected identifier at line 1, char 35`},
-		{s: `ALTER RETENTION POLICY policy1 ON testdb`, err: `found EOF, expected DURATION, REPLICATION, SHARD, DEFAULT at line 1, char 42`},
+		{s: `ALTER RETENTION POLICY policy1 ON testdb`, err: `found EOF, expected DURATION, REPLICATION, SHARD, MEASUREMENT, ROLLUP, DEFAULT at line 1, char 42`},
+		{s: `ALTER RETENTION POLICY policy1 ON testdb MEASUREMENT cpu DURATION 1d MEASUREMENT cpu DURATION 2d`, err: `found duplicate MEASUREMENT cpu option at line 1, char 70`},
+		{s: `ALTER RETENTION POLICY policy1 ON testdb ROLLUP NONE ROLLUP NONE`, err: `found duplicate ROLLUP option at line 1, char 54`},
+		{s: `ALTER RETENTION POLICY policy1 ON testdb ROLLUP BEGIN SELECT mean(value) FROM cpu GROUP BY time(1h) END`, err: `found FROM, expected INTO at line 1, char 74`},
+		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1d REPLICATION 1 MEASUREMENT cpu`, err: `found EOF, expected DURATION at line 1, char 85`},
+		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1d REPLICATION 1 ROLLUP BEGIN SELECT mean(value) INTO cpu_mean FROM cpu GROUP BY time(1h)`, err: `found EOF, expected END at line 1, char 141`},
 		{s: `ALTER RETENTION POLICY policy1 ON testdb REPLICATION 1 REPLICATION 2`, err: `found duplicate REPLICATION option at line 1, char 56`},
 		{s: `ALTER RETENTION POLICY policy1 ON testdb DURATION 15251w`, err: `overflowed duration 15251w: choose a smaller duration or INF at line 1, char 51`},
 		{s: `ALTER RETENTION POLICY policy1 ON testdb DURATION INF SHARD DURATION INF`, err: `invalid duration INF for shard duration at line 1, char 70`},
@@ -4042,3 +4310,11 @@ func duration(v time.Duration) *time.Duration {
 func intptr(v int) *int {
 	return &v
 }
+
+func int64ptr(v int64) *int64 {
+	return &v
+}
+
+func boolptr(v bool) *bool {
+	return &v
+}
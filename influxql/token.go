@@ -101,6 +101,7 @@ const (
 	INF
 	INSERT
 	INTO
+	JOIN
 	KEY
 	KEYS
 	KILL
@@ -117,6 +118,8 @@ const (
 	PRIVILEGES
 	QUERIES
 	QUERY
+	QUOTA
+	QUOTAS
 	READ
 	REPLICATION
 	RESAMPLE
@@ -135,6 +138,8 @@ const (
 	SUBSCRIPTIONS
 	TAG
 	TO
+	TOKEN
+	TOKENS
 	USER
 	USERS
 	VALUES
@@ -224,6 +229,7 @@ var tokens = [...]string{
 	INF:           "INF",
 	INSERT:        "INSERT",
 	INTO:          "INTO",
+	JOIN:          "JOIN",
 	KEY:           "KEY",
 	KEYS:          "KEYS",
 	KILL:          "KILL",
@@ -240,6 +246,8 @@ var tokens = [...]string{
 	PRIVILEGES:    "PRIVILEGES",
 	QUERIES:       "QUERIES",
 	QUERY:         "QUERY",
+	QUOTA:         "QUOTA",
+	QUOTAS:        "QUOTAS",
 	READ:          "READ",
 	REPLICATION:   "REPLICATION",
 	RESAMPLE:      "RESAMPLE",
@@ -258,6 +266,8 @@ var tokens = [...]string{
 	SUBSCRIPTIONS: "SUBSCRIPTIONS",
 	TAG:           "TAG",
 	TO:            "TO",
+	TOKEN:         "TOKEN",
+	TOKENS:        "TOKENS",
 	USER:          "USER",
 	USERS:         "USERS",
 	VALUES:        "VALUES",
@@ -15,7 +15,8 @@ type MetaClientMock struct {
 	CreateDatabaseWithRetentionPolicyFn func(name string, spec *meta.RetentionPolicySpec) (*meta.DatabaseInfo, error)
 	CreateRetentionPolicyFn             func(database string, spec *meta.RetentionPolicySpec, makeDefault bool) (*meta.RetentionPolicyInfo, error)
 	CreateShardGroupFn                  func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error)
-	CreateSubscriptionFn                func(database, rp, name, mode string, destinations []string) error
+	CreateSubscriptionFn                func(database, rp, name, mode string, destinations []string, filter string) error
+	CreateTokenFn                       func(username, database string, p influxql.Privilege, expires time.Time) (*meta.TokenInfo, string, error)
 	CreateUserFn                        func(name, password string, admin bool) (meta.User, error)
 
 	DatabaseFn  func(name string) *meta.DatabaseInfo
@@ -28,6 +29,7 @@ type MetaClientMock struct {
 	DropRetentionPolicyFn func(database, name string) error
 	DropSubscriptionFn    func(database, rp, name string) error
 	DropShardFn           func(id uint64) error
+	DropTokenFn           func(id string) error
 	DropUserFn            func(name string) error
 
 	OpenFn func() error
@@ -37,12 +39,17 @@ type MetaClientMock struct {
 	RetentionPolicyFn func(database, name string) (rpi *meta.RetentionPolicyInfo, err error)
 
 	AuthenticateFn           func(username, password string) (ui meta.User, err error)
+	AuthenticateTokenFn      func(token string) (meta.User, error)
 	AdminUserExistsFn        func() bool
 	SetAdminPrivilegeFn      func(username string, admin bool) error
 	SetDataFn                func(*meta.Data) error
 	SetPrivilegeFn           func(username, database string, p influxql.Privilege) error
+	SetSchemaFn              func(database string, schema *meta.SchemaInfo) error
 	ShardGroupsByTimeRangeFn func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error)
 	ShardOwnerFn             func(shardID uint64) (database, policy string, sgi *meta.ShardGroupInfo)
+	TokensFn                 func() []meta.TokenInfo
+	UpdateQuotaFn            func(database string, qu *meta.QuotaUpdate) error
+	UpdateWriteLimitsFn      func(database string, wlu *meta.WriteLimitsUpdate) error
 	UpdateRetentionPolicyFn  func(database, name string, rpu *meta.RetentionPolicyUpdate, makeDefault bool) error
 	UpdateUserFn             func(name, password string) error
 	UserPrivilegeFn          func(username, database string) (*influxql.Privilege, error)
@@ -75,8 +82,12 @@ func (c *MetaClientMock) CreateShardGroup(database, policy string, timestamp tim
 	return c.CreateShardGroupFn(database, policy, timestamp)
 }
 
-func (c *MetaClientMock) CreateSubscription(database, rp, name, mode string, destinations []string) error {
-	return c.CreateSubscriptionFn(database, rp, name, mode, destinations)
+func (c *MetaClientMock) CreateSubscription(database, rp, name, mode string, destinations []string, filter string) error {
+	return c.CreateSubscriptionFn(database, rp, name, mode, destinations, filter)
+}
+
+func (c *MetaClientMock) CreateToken(username, database string, p influxql.Privilege, expires time.Time) (*meta.TokenInfo, string, error) {
+	return c.CreateTokenFn(username, database, p, expires)
 }
 
 func (c *MetaClientMock) CreateUser(name, password string, admin bool) (meta.User, error) {
@@ -131,6 +142,10 @@ func (c *MetaClientMock) SetPrivilege(username, database string, p influxql.Priv
 	return c.SetPrivilegeFn(username, database, p)
 }
 
+func (c *MetaClientMock) SetSchema(database string, schema *meta.SchemaInfo) error {
+	return c.SetSchemaFn(database, schema)
+}
+
 func (c *MetaClientMock) ShardGroupsByTimeRange(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
 	return c.ShardGroupsByTimeRangeFn(database, policy, min, max)
 }
@@ -139,6 +154,14 @@ func (c *MetaClientMock) ShardOwner(shardID uint64) (database, policy string, sg
 	return c.ShardOwnerFn(shardID)
 }
 
+func (c *MetaClientMock) UpdateQuota(database string, qu *meta.QuotaUpdate) error {
+	return c.UpdateQuotaFn(database, qu)
+}
+
+func (c *MetaClientMock) UpdateWriteLimits(database string, wlu *meta.WriteLimitsUpdate) error {
+	return c.UpdateWriteLimitsFn(database, wlu)
+}
+
 func (c *MetaClientMock) UpdateRetentionPolicy(database, name string, rpu *meta.RetentionPolicyUpdate, makeDefault bool) error {
 	return c.UpdateRetentionPolicyFn(database, name, rpu, makeDefault)
 }
@@ -158,11 +181,17 @@ func (c *MetaClientMock) UserPrivileges(username string) (map[string]influxql.Pr
 func (c *MetaClientMock) Authenticate(username, password string) (meta.User, error) {
 	return c.AuthenticateFn(username, password)
 }
+func (c *MetaClientMock) AuthenticateToken(token string) (meta.User, error) {
+	return c.AuthenticateTokenFn(token)
+}
 func (c *MetaClientMock) AdminUserExists() bool { return c.AdminUserExistsFn() }
 
 func (c *MetaClientMock) User(username string) (meta.User, error) { return c.UserFn(username) }
 func (c *MetaClientMock) Users() []meta.UserInfo                  { return c.UsersFn() }
 
+func (c *MetaClientMock) DropToken(id string) error { return c.DropTokenFn(id) }
+func (c *MetaClientMock) Tokens() []meta.TokenInfo  { return c.TokensFn() }
+
 func (c *MetaClientMock) Open() error                { return c.OpenFn() }
 func (c *MetaClientMock) Data() meta.Data            { return c.DataFn() }
 func (c *MetaClientMock) SetData(d *meta.Data) error { return c.SetDataFn(d) }
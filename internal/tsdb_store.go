@@ -20,6 +20,7 @@ type TSDBStoreMock struct {
 	DatabasesFn               func() []string
 	DeleteDatabaseFn          func(name string) error
 	DeleteMeasurementFn       func(database, name string) error
+	DeleteMeasurementRangeFn  func(shardIDs []uint64, name string, min, max int64) error
 	DeleteRetentionPolicyFn   func(database, name string) error
 	DeleteSeriesFn            func(database string, sources []influxql.Source, condition influxql.Expr) error
 	DeleteShardFn             func(id uint64) error
@@ -37,11 +38,13 @@ type TSDBStoreMock struct {
 	ShardFn                   func(id uint64) *tsdb.Shard
 	ShardGroupFn              func(ids []uint64) tsdb.ShardGroup
 	ShardIDsFn                func() []uint64
+	ShardsStorageFn           func() []tsdb.ShardStorage
 	ShardNFn                  func() int
 	ShardRelativePathFn       func(id uint64) (string, error)
 	ShardsFn                  func(ids []uint64) []*tsdb.Shard
 	StatisticsFn              func(tags map[string]string) []models.Statistic
 	TagValuesFn               func(auth query.Authorizer, database string, cond influxql.Expr) ([]tsdb.TagValues, error)
+	TagValuesLimitFn          func(auth query.Authorizer, database string, cond influxql.Expr, offset, limit int) ([]tsdb.TagValues, error)
 	WithLoggerFn              func(log zap.Logger)
 	WriteToShardFn            func(shardID uint64, points []models.Point) error
 }
@@ -65,6 +68,9 @@ func (s *TSDBStoreMock) DeleteDatabase(name string) error {
 func (s *TSDBStoreMock) DeleteMeasurement(database string, name string) error {
 	return s.DeleteMeasurementFn(database, name)
 }
+func (s *TSDBStoreMock) DeleteMeasurementRange(shardIDs []uint64, name string, min, max int64) error {
+	return s.DeleteMeasurementRangeFn(shardIDs, name, min, max)
+}
 func (s *TSDBStoreMock) DeleteRetentionPolicy(database string, name string) error {
 	return s.DeleteRetentionPolicyFn(database, name)
 }
@@ -116,6 +122,9 @@ func (s *TSDBStoreMock) ShardGroup(ids []uint64) tsdb.ShardGroup {
 func (s *TSDBStoreMock) ShardIDs() []uint64 {
 	return s.ShardIDsFn()
 }
+func (s *TSDBStoreMock) ShardsStorage() []tsdb.ShardStorage {
+	return s.ShardsStorageFn()
+}
 func (s *TSDBStoreMock) ShardN() int {
 	return s.ShardNFn()
 }
@@ -131,6 +140,9 @@ func (s *TSDBStoreMock) Statistics(tags map[string]string) []models.Statistic {
 func (s *TSDBStoreMock) TagValues(auth query.Authorizer, database string, cond influxql.Expr) ([]tsdb.TagValues, error) {
 	return s.TagValuesFn(auth, database, cond)
 }
+func (s *TSDBStoreMock) TagValuesLimit(auth query.Authorizer, database string, cond influxql.Expr, offset, limit int) ([]tsdb.TagValues, error) {
+	return s.TagValuesLimitFn(auth, database, cond, offset, limit)
+}
 func (s *TSDBStoreMock) WithLogger(log zap.Logger) {
 	s.WithLoggerFn(log)
 }
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"fmt"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+)
+
+const (
+	// DefaultFormat is the default format of the logs.
+	DefaultFormat = "text"
+
+	// DefaultLevel is the default minimum level of the logs.
+	DefaultLevel = "info"
+)
+
+// Config represents the configuration of the logs.
+type Config struct {
+	// Format is the format of the logs, "text" or "json".
+	Format string `toml:"format"`
+
+	// Level is the minimum level of the logs, which ServiceLevels overrides
+	// for the logs of a service.
+	Level         string            `toml:"level"`
+	ServiceLevels map[string]string `toml:"service-levels"`
+}
+
+// NewConfig returns a new instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Format: DefaultFormat,
+		Level:  DefaultLevel,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	switch c.Format {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("unknown logging format: %q", c.Format)
+	}
+
+	if c.Level != "" {
+		if _, err := ParseLevel(c.Level); err != nil {
+			return err
+		}
+	}
+	for service, level := range c.ServiceLevels {
+		if _, err := ParseLevel(level); err != nil {
+			return fmt.Errorf("service %s: %s", service, err)
+		}
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"format": c.Format,
+		"level":  c.Level,
+	}), nil
+}
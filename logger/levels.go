@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/uber-go/zap"
+)
+
+// levelNames maps the names of the levels of the logs to their zap levels.
+var levelNames = map[string]zap.Level{
+	"debug": zap.DebugLevel,
+	"info":  zap.InfoLevel,
+	"warn":  zap.WarnLevel,
+	"error": zap.ErrorLevel,
+}
+
+// ParseLevel returns the level with the given name.
+func ParseLevel(name string) (zap.Level, error) {
+	level, ok := levelNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown logging level: %q", name)
+	}
+	return level, nil
+}
+
+// LevelName returns the name of the level.
+func LevelName(level zap.Level) string {
+	for name, l := range levelNames {
+		if l == level {
+			return name
+		}
+	}
+	return level.String()
+}
+
+// Levels holds the minimum levels of the logs, which can be changed while the
+// logs are written.  The level of the logs of a service overrides the default
+// level.
+type Levels struct {
+	mu       sync.RWMutex
+	level    zap.Level
+	services map[string]zap.Level
+}
+
+// NewLevels returns the levels of the config.
+func NewLevels(c Config) (*Levels, error) {
+	l := &Levels{
+		level:    zap.InfoLevel,
+		services: make(map[string]zap.Level),
+	}
+	if c.Level != "" {
+		level, err := ParseLevel(c.Level)
+		if err != nil {
+			return nil, err
+		}
+		l.level = level
+	}
+	for service, name := range c.ServiceLevels {
+		level, err := ParseLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		l.services[service] = level
+	}
+	return l, nil
+}
+
+// Set replaces the default level and the levels of the services with those
+// of the config.
+func (l *Levels) Set(c Config) error {
+	levels, err := NewLevels(c)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level, l.services = levels.level, levels.services
+	return nil
+}
+
+// Enabled returns true if logs of the service at the given level are written.
+func (l *Levels) Enabled(service string, level zap.Level) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	min, ok := l.services[service]
+	if !ok {
+		min = l.level
+	}
+	return level >= min
+}
+
+// SetLevel sets the minimum level of the logs of the service, or the default
+// level if service is empty.
+func (l *Levels) SetLevel(service, name string) error {
+	level, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if service == "" {
+		l.level = level
+	} else {
+		l.services[service] = level
+	}
+	return nil
+}
+
+// ResetLevel removes the level of the logs of the service, which are then
+// written at the default level.
+func (l *Levels) ResetLevel(service string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.services, service)
+}
+
+// Level returns the name of the default level.
+func (l *Levels) Level() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return LevelName(l.level)
+}
+
+// ServiceLevels returns the names of the levels of the services that
+// override the default level.
+func (l *Levels) ServiceLevels() map[string]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	levels := make(map[string]string, len(l.services))
+	for service, level := range l.services {
+		levels[service] = LevelName(level)
+	}
+	return levels
+}
@@ -0,0 +1,125 @@
+// Package logger creates the structured, leveled loggers of the services.
+package logger // import "github.com/influxdata/influxdb/logger"
+
+import (
+	"io"
+
+	"github.com/uber-go/zap"
+)
+
+// New returns a logger writing to w in the format of the config, along with
+// the levels of its logs, which may be changed while it is used.
+//
+// The level of a log is that of the service set by the "service" field of
+// the logger, such as the loggers passed to the WithLogger method of the
+// services.
+func New(w io.Writer, c Config) (zap.Logger, *Levels, error) {
+	levels, err := NewLevels(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var enc zap.Encoder
+	switch c.Format {
+	case "json":
+		enc = zap.NewJSONEncoder()
+	default:
+		enc = zap.NewTextEncoder()
+	}
+
+	// The levels are checked by the logger before the logs are encoded, so
+	// the underlying logger passes it all of the logs.
+	log := &levelLogger{
+		Logger: zap.New(enc, zap.DebugLevel, zap.Output(zap.AddSync(w))),
+		levels: levels,
+	}
+	return log, levels, nil
+}
+
+// levelLogger is a logger dropping the logs below the level of the service
+// of the logger, before they are encoded.
+type levelLogger struct {
+	zap.Logger
+	levels  *Levels
+	service string
+}
+
+// With returns a child logger with the fields, recording the service of the
+// logger.
+func (log *levelLogger) With(fields ...zap.Field) zap.Logger {
+	return &levelLogger{
+		Logger:  log.Logger.With(fields...),
+		levels:  log.levels,
+		service: log.serviceOf(fields),
+	}
+}
+
+// Check returns a message to be written if its level is enabled for the
+// service, or nil.
+func (log *levelLogger) Check(level zap.Level, msg string) *zap.CheckedMessage {
+	if !log.levels.Enabled(log.service, level) {
+		return nil
+	}
+	return log.Logger.Check(level, msg)
+}
+
+// Log writes the message if its level is enabled for the service.
+func (log *levelLogger) Log(level zap.Level, msg string, fields ...zap.Field) {
+	if log.levels.Enabled(log.serviceOf(fields), level) {
+		log.Logger.Log(level, msg, fields...)
+	}
+}
+
+// Debug writes the message if debug logs are enabled for the service.
+func (log *levelLogger) Debug(msg string, fields ...zap.Field) {
+	if log.levels.Enabled(log.serviceOf(fields), zap.DebugLevel) {
+		log.Logger.Debug(msg, fields...)
+	}
+}
+
+// Info writes the message if info logs are enabled for the service.
+func (log *levelLogger) Info(msg string, fields ...zap.Field) {
+	if log.levels.Enabled(log.serviceOf(fields), zap.InfoLevel) {
+		log.Logger.Info(msg, fields...)
+	}
+}
+
+// Warn writes the message if warnings are enabled for the service.
+func (log *levelLogger) Warn(msg string, fields ...zap.Field) {
+	if log.levels.Enabled(log.serviceOf(fields), zap.WarnLevel) {
+		log.Logger.Warn(msg, fields...)
+	}
+}
+
+// Error writes the message if error logs are enabled for the service.
+func (log *levelLogger) Error(msg string, fields ...zap.Field) {
+	if log.levels.Enabled(log.serviceOf(fields), zap.ErrorLevel) {
+		log.Logger.Error(msg, fields...)
+	}
+}
+
+// serviceOf returns the service set by the "service" field of the fields,
+// or the service of the logger.
+func (log *levelLogger) serviceOf(fields []zap.Field) string {
+	if len(fields) == 0 {
+		return log.service
+	}
+	enc := serviceEncoder{Encoder: zap.NullEncoder(), service: log.service}
+	for _, f := range fields {
+		f.AddTo(&enc)
+	}
+	return enc.service
+}
+
+// serviceEncoder is an encoder recording the "service" field added to it.
+type serviceEncoder struct {
+	zap.Encoder
+	service string
+}
+
+// AddString adds a string field, recording the service.
+func (enc *serviceEncoder) AddString(key, value string) {
+	if key == "service" {
+		enc.service = value
+	}
+}
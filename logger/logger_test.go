@@ -0,0 +1,110 @@
+package logger_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/logger"
+	"github.com/uber-go/zap"
+)
+
+// Ensure the logs are written at the level of their service.
+func TestNew_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	c := logger.NewConfig()
+	c.ServiceLevels = map[string]string{"httpd": "debug", "subscriber": "error"}
+	log, levels, err := logger.New(&buf, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.Debug("default debug")
+	log.Info("default info")
+	log.With(zap.String("service", "httpd")).Debug("httpd debug")
+	log.With(zap.String("service", "subscriber")).Warn("subscriber dropped")
+
+	// Change the level of a service at runtime.
+	subscriber := log.With(zap.String("service", "subscriber"))
+	if err := levels.SetLevel("subscriber", "warn"); err != nil {
+		t.Fatal(err)
+	}
+	subscriber.Warn("subscriber written")
+
+	out := buf.String()
+	for _, exp := range []string{"default info", "httpd debug", "subscriber written"} {
+		if !strings.Contains(out, exp) {
+			t.Errorf("expected %q in logs:\n%s", exp, out)
+		}
+	}
+	for _, unexp := range []string{"default debug", "subscriber dropped"} {
+		if strings.Contains(out, unexp) {
+			t.Errorf("unexpected %q in logs:\n%s", unexp, out)
+		}
+	}
+}
+
+// Ensure the levels of a config replace those already set.
+func TestLevels_Set(t *testing.T) {
+	c := logger.NewConfig()
+	c.ServiceLevels = map[string]string{"httpd": "debug"}
+	levels, err := logger.NewLevels(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Level = "warn"
+	c.ServiceLevels = map[string]string{"retention": "error"}
+	if err := levels.Set(c); err != nil {
+		t.Fatal(err)
+	}
+	if levels.Enabled("httpd", zap.InfoLevel) {
+		t.Error("expected httpd info logs to be dropped")
+	} else if levels.Enabled("retention", zap.WarnLevel) {
+		t.Error("expected retention warnings to be dropped")
+	} else if !levels.Enabled("", zap.WarnLevel) {
+		t.Error("expected warnings to be written")
+	}
+
+	c.Level = "verbose"
+	if err := levels.Set(c); err == nil {
+		t.Fatal("expected error")
+	} else if levels.Level() != "warn" {
+		t.Fatalf("unexpected level: %s", levels.Level())
+	}
+}
+
+// Ensure logs are written as JSON with the json format.
+func TestNew_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	c := logger.NewConfig()
+	c.Format = "json"
+	log, _, err := logger.New(&buf, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.Info("hello", zap.String("service", "httpd"))
+	if out := buf.String(); !strings.HasPrefix(out, "{") || !strings.Contains(out, `"msg":"hello"`) {
+		t.Fatalf("unexpected logs: %s", out)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := logger.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+
+	c = logger.NewConfig()
+	c.Format = "xml"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+
+	c = logger.NewConfig()
+	c.ServiceLevels = map[string]string{"httpd": "verbose"}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for unknown level")
+	}
+}
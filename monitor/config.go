@@ -2,6 +2,8 @@ package monitor
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/influxdata/influxdb/monitor/diagnostics"
@@ -18,6 +20,13 @@ const (
 
 	// DefaultStoreInterval is the period between storing gathered information.
 	DefaultStoreInterval = 10 * time.Second
+
+	// DefaultRemoteTimeout is the timeout of writes to a remote InfluxDB.
+	DefaultRemoteTimeout = 10 * time.Second
+
+	// DefaultRemoteBufferSize is the maximum number of points buffered while
+	// a remote InfluxDB is unavailable.
+	DefaultRemoteBufferSize = 100000
 )
 
 // Config represents the configuration for the monitor service.
@@ -25,6 +34,16 @@ type Config struct {
 	StoreEnabled  bool          `toml:"store-enabled"`
 	StoreDatabase string        `toml:"store-database"`
 	StoreInterval toml.Duration `toml:"store-interval"`
+
+	// RemoteURL is the URL of an InfluxDB the gathered information is
+	// written to instead of the local database, if set.
+	RemoteURL                string        `toml:"remote-url"`
+	RemoteRetentionPolicy    string        `toml:"remote-retention-policy"`
+	RemoteUsername           string        `toml:"remote-username"`
+	RemotePassword           string        `toml:"remote-password"`
+	RemoteInsecureSkipVerify bool          `toml:"remote-insecure-skip-verify"`
+	RemoteTimeout            toml.Duration `toml:"remote-timeout"`
+	RemoteBufferSize         int           `toml:"remote-buffer-size"`
 }
 
 // NewConfig returns an instance of Config with defaults.
@@ -33,6 +52,9 @@ func NewConfig() Config {
 		StoreEnabled:  true,
 		StoreDatabase: DefaultStoreDatabase,
 		StoreInterval: toml.Duration(DefaultStoreInterval),
+
+		RemoteTimeout:    toml.Duration(DefaultRemoteTimeout),
+		RemoteBufferSize: DefaultRemoteBufferSize,
 	}
 }
 
@@ -44,6 +66,17 @@ func (c Config) Validate() error {
 	if c.StoreDatabase == "" {
 		return errors.New("monitor store database name must not be empty")
 	}
+	if c.RemoteURL != "" {
+		u, err := url.Parse(c.RemoteURL)
+		if err != nil {
+			return fmt.Errorf("invalid monitor remote url: %s", err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("monitor remote url scheme must be http or https: %s", c.RemoteURL)
+		}
+		if c.RemoteBufferSize < 0 {
+			return errors.New("monitor remote buffer size must not be negative")
+		}
+	}
 	return nil
 }
 
@@ -59,5 +92,6 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 		"store-enabled":  true,
 		"store-database": c.StoreDatabase,
 		"store-interval": c.StoreInterval,
+		"remote-url":     c.RemoteURL,
 	}), nil
 }
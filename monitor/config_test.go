@@ -15,6 +15,9 @@ func TestConfig_Parse(t *testing.T) {
 store-enabled=true
 store-database="the_db"
 store-interval="10m"
+remote-url="https://monitor.example.com:8086"
+remote-username="monitor"
+remote-buffer-size=1000
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -26,6 +29,12 @@ store-interval="10m"
 		t.Fatalf("unexpected store-database: %s", c.StoreDatabase)
 	} else if time.Duration(c.StoreInterval) != 10*time.Minute {
 		t.Fatalf("unexpected store-interval:  %s", c.StoreInterval)
+	} else if c.RemoteURL != "https://monitor.example.com:8086" {
+		t.Fatalf("unexpected remote-url: %s", c.RemoteURL)
+	} else if c.RemoteUsername != "monitor" {
+		t.Fatalf("unexpected remote-username: %s", c.RemoteUsername)
+	} else if c.RemoteBufferSize != 1000 {
+		t.Fatalf("unexpected remote-buffer-size: %d", c.RemoteBufferSize)
 	}
 }
 
@@ -49,4 +58,11 @@ func TestConfig_Validate(t *testing.T) {
 	if err := c.Validate(); err == nil {
 		t.Fatalf("unexpected successful validation for %#v", c)
 	}
+
+	// Remote URL must be HTTP or HTTPS.
+	c = monitor.NewConfig()
+	c.RemoteURL = "udp://monitor.example.com:8089"
+	if err := c.Validate(); err == nil {
+		t.Fatalf("unexpected successful validation for %#v", c)
+	}
 }
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/client/v2"
+	"github.com/influxdata/influxdb/models"
+)
+
+// remoteWriter is a PointsWriter that writes points to a remote InfluxDB over
+// HTTP.  Points that cannot be written are buffered and written along with
+// the next points, dropping the oldest ones once the buffer is full.
+type remoteWriter struct {
+	client          client.Client
+	retentionPolicy string
+	bufferSize      int
+
+	mu      sync.Mutex
+	buffer  models.Points
+	dropped int64
+}
+
+// newRemoteWriter returns a remoteWriter for the remote InfluxDB of c.
+func newRemoteWriter(c Config) (*remoteWriter, error) {
+	cl, err := client.NewHTTPClient(client.HTTPConfig{
+		Addr:               c.RemoteURL,
+		Username:           c.RemoteUsername,
+		Password:           c.RemotePassword,
+		Timeout:            time.Duration(c.RemoteTimeout),
+		InsecureSkipVerify: c.RemoteInsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bufferSize := c.RemoteBufferSize
+	if bufferSize == 0 {
+		bufferSize = DefaultRemoteBufferSize
+	}
+	return &remoteWriter{
+		client:          cl,
+		retentionPolicy: c.RemoteRetentionPolicy,
+		bufferSize:      bufferSize,
+	}, nil
+}
+
+// WritePoints writes the buffered points and points to database on the
+// remote InfluxDB.  The retention policy of the remote writer is used
+// instead of retentionPolicy.
+func (w *remoteWriter) WritePoints(database, retentionPolicy string, points models.Points) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buffer = append(w.buffer, points...)
+	if n := len(w.buffer) - w.bufferSize; n > 0 {
+		w.buffer = append(w.buffer[:0], w.buffer[n:]...)
+		w.dropped += int64(n)
+	}
+
+	bp, err := client.NewBatchPoints(client.BatchPointsConfig{
+		Database:        database,
+		RetentionPolicy: w.retentionPolicy,
+	})
+	if err != nil {
+		return err
+	}
+	for _, pt := range w.buffer {
+		bp.AddPoint(client.NewPointFrom(pt))
+	}
+
+	if err := w.client.Write(bp); err != nil {
+		return fmt.Errorf("remote write of %d points failed (%d dropped so far): %s", len(w.buffer), w.dropped, err)
+	}
+	w.buffer = nil
+	return nil
+}
+
+// Close closes the connections to the remote InfluxDB.
+func (w *remoteWriter) Close() error {
+	return w.client.Close()
+}
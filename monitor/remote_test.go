@@ -0,0 +1,69 @@
+package monitor
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// Ensure points that cannot be written to the remote InfluxDB are buffered
+// and written with the next points.
+func TestRemoteWriter_WritePoints(t *testing.T) {
+	var fail int32 = 1
+	bodies := make(chan string, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if got, exp := r.URL.Query().Get("db"), "_internal"; got != exp {
+			t.Errorf("unexpected database: got %s, exp %s", got, exp)
+		} else if got, exp := r.URL.Query().Get("rp"), "stats"; got != exp {
+			t.Errorf("unexpected retention policy: got %s, exp %s", got, exp)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies <- string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	c := NewConfig()
+	c.RemoteURL = ts.URL
+	c.RemoteRetentionPolicy = "stats"
+	c.RemoteBufferSize = 2
+	w, err := newRemoteWriter(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	points := models.Points{
+		models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(0, 1)),
+		models.MustNewPoint("cpu", nil, models.Fields{"value": 2.0}, time.Unix(0, 2)),
+		models.MustNewPoint("cpu", nil, models.Fields{"value": 3.0}, time.Unix(0, 3)),
+	}
+
+	// The first points are buffered while the remote InfluxDB fails.
+	if err := w.WritePoints("_internal", MonitorRetentionPolicy, points[:2]); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// The oldest point is dropped once the buffer is full.
+	atomic.StoreInt32(&fail, 0)
+	if err := w.WritePoints("_internal", MonitorRetentionPolicy, points[2:]); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := strings.TrimSpace(<-bodies), "cpu value=2 2\ncpu value=3 3"; got != exp {
+		t.Fatalf("unexpected body:\ngot=%s\nexp=%s", got, exp)
+	}
+	if w.dropped != 1 {
+		t.Fatalf("unexpected dropped points: %d", w.dropped)
+	}
+}
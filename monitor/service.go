@@ -53,6 +53,11 @@ type Monitor struct {
 	storeRetentionPolicy string
 	storeInterval        time.Duration
 
+	// Statistics are written to a remote InfluxDB instead of the local
+	// database if remoteConfig has a remote URL.
+	remoteConfig Config
+	remote       *remoteWriter
+
 	MetaClient interface {
 		CreateDatabaseWithRetentionPolicy(name string, spec *meta.RetentionPolicySpec) (*meta.DatabaseInfo, error)
 		Database(name string) *meta.DatabaseInfo
@@ -61,6 +66,10 @@ type Monitor struct {
 	// Writer for pushing stats back into the database.
 	PointsWriter PointsWriter
 
+	// ReadOnly, if set, reports whether the node is a read-only replica,
+	// whose statistics are only stored if they're written to a remote InfluxDB.
+	ReadOnly func() bool
+
 	Logger zap.Logger
 }
 
@@ -79,6 +88,7 @@ func New(r Reporter, c Config) *Monitor {
 		storeDatabase:        c.StoreDatabase,
 		storeInterval:        time.Duration(c.StoreInterval),
 		storeRetentionPolicy: MonitorRetentionPolicy,
+		remoteConfig:         c,
 		Logger:               zap.New(zap.NullEncoder()),
 	}
 }
@@ -112,6 +122,14 @@ func (m *Monitor) Open() error {
 	m.RegisterDiagnosticsClient("system", &system{})
 
 	m.mu.Lock()
+	if m.remoteConfig.RemoteURL != "" {
+		remote, err := newRemoteWriter(m.remoteConfig)
+		if err != nil {
+			m.mu.Unlock()
+			return err
+		}
+		m.remote = remote
+	}
 	m.done = make(chan struct{})
 	m.mu.Unlock()
 
@@ -148,7 +166,12 @@ func (m *Monitor) writePoints(p models.Points) error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if err := m.PointsWriter.WritePoints(m.storeDatabase, m.storeRetentionPolicy, p); err != nil {
+	var pw PointsWriter = m.PointsWriter
+	if m.remote != nil {
+		pw = m.remote
+	}
+
+	if err := pw.WritePoints(m.storeDatabase, m.storeRetentionPolicy, p); err != nil {
 		m.Logger.Info(fmt.Sprintf("failed to store statistics: %s", err))
 	}
 	return nil
@@ -170,6 +193,12 @@ func (m *Monitor) Close() error {
 
 	m.mu.Lock()
 	m.done = nil
+	if m.remote != nil {
+		if err := m.remote.Close(); err != nil {
+			m.Logger.Info(fmt.Sprintf("failed to close remote writer: %s", err))
+		}
+		m.remote = nil
+	}
 	m.mu.Unlock()
 
 	m.DeregisterDiagnosticsClient("build")
@@ -370,7 +399,7 @@ func (m *Monitor) Diagnostics() (map[string]*diagnostics.Diagnostics, error) {
 
 // createInternalStorage ensures the internal storage has been created.
 func (m *Monitor) createInternalStorage() {
-	if m.storeCreated {
+	if m.storeCreated || m.remote != nil {
 		return
 	}
 
@@ -412,8 +441,13 @@ func (m *Monitor) waitUntilInterval(d time.Duration) error {
 // storeStatistics writes the statistics to an InfluxDB system.
 func (m *Monitor) storeStatistics() {
 	defer m.wg.Done()
-	m.Logger.Info(fmt.Sprintf("Storing statistics in database '%s' retention policy '%s', at interval %s",
-		m.storeDatabase, m.storeRetentionPolicy, m.storeInterval))
+	if m.remote != nil {
+		m.Logger.Info(fmt.Sprintf("Storing statistics in database '%s' of remote InfluxDB %s, at interval %s",
+			m.storeDatabase, m.remoteConfig.RemoteURL, m.storeInterval))
+	} else {
+		m.Logger.Info(fmt.Sprintf("Storing statistics in database '%s' retention policy '%s', at interval %s",
+			m.storeDatabase, m.storeRetentionPolicy, m.storeInterval))
+	}
 
 	// Wait until an even interval to start recording monitor statistics.
 	// If we are interrupted before the interval for some reason, exit early.
@@ -427,6 +461,10 @@ func (m *Monitor) storeStatistics() {
 	for {
 		select {
 		case now := <-tick.C:
+			if m.remote == nil && m.ReadOnly != nil && m.ReadOnly() {
+				continue
+			}
+
 			now = now.Truncate(m.storeInterval)
 			func() {
 				m.mu.Lock()
@@ -0,0 +1,524 @@
+// Package diskqueue provides a size-bounded FIFO queue persisted to disk.
+package diskqueue
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// positionFile is the name of the file recording the read position of a queue.
+	positionFile = "position"
+
+	// corruptSuffix is appended to the name of a quarantined segment.
+	corruptSuffix = ".corrupt"
+
+	// DefaultSyncInterval is the default interval at which a queue is synced to disk.
+	DefaultSyncInterval = time.Second
+)
+
+// ErrQueueFull is returned when an entry does not fit in the queue.
+var ErrQueueFull = errors.New("queue is full")
+
+// CorruptSegmentError is returned when a segment holds an incomplete entry.
+// The segment is quarantined and the queue resumes from the next segment.
+type CorruptSegmentError struct {
+	Path   string
+	Offset int64
+}
+
+func (e *CorruptSegmentError) Error() string {
+	return fmt.Sprintf("corrupt queue segment %s at offset %d, moved to %s", e.Path, e.Offset, e.Path+corruptSuffix)
+}
+
+// Queue is a FIFO of entries persisted to a directory of segment files.
+// Each entry is stored as a 4-byte big-endian length followed by its data.
+// The read position is stored in a separate file so entries survive restarts
+// until they have been advanced past.
+type Queue struct {
+	mu  sync.Mutex
+	wg  sync.WaitGroup
+	dir string
+
+	// SyncInterval is how often appended entries and the read position are
+	// synced to disk. They are also synced whenever a segment is rolled. If
+	// zero, the queue is only synced on roll and on close.
+	SyncInterval time.Duration
+
+	closing chan struct{}
+
+	// dirty is true if entries were appended or the read position changed
+	// since the queue was last synced.
+	dirty bool
+
+	maxSize        int64
+	maxSegmentSize int64
+
+	// segments holds the IDs of the segment files, oldest first.
+	segments []int
+
+	tail     *os.File
+	tailSize int64
+
+	// size is the total size of all segment files.
+	size int64
+
+	// headID and headOffset identify the next entry to be read.
+	headID     int
+	headOffset int64
+
+	// headLen is the size of the entry last returned by Current.
+	headLen int64
+}
+
+// New returns a queue stored in dir. It must be opened before use.
+func New(dir string, maxSize, maxSegmentSize int64) *Queue {
+	return &Queue{
+		dir:            dir,
+		maxSize:        maxSize,
+		maxSegmentSize: maxSegmentSize,
+		SyncInterval:   DefaultSyncInterval,
+	}
+}
+
+// Open loads the segments and read position from disk.
+func (q *Queue) Open() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := os.MkdirAll(q.dir, 0777); err != nil {
+		return err
+	}
+
+	fis, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		return err
+	}
+
+	q.size = 0
+	q.segments = q.segments[:0]
+	for _, fi := range fis {
+		id, err := strconv.Atoi(fi.Name())
+		if err != nil {
+			continue
+		}
+		q.segments = append(q.segments, id)
+		q.size += fi.Size()
+	}
+	sort.Ints(q.segments)
+
+	if len(q.segments) == 0 {
+		q.segments = append(q.segments, 1)
+	}
+
+	if err := q.openTail(); err != nil {
+		return err
+	}
+
+	// Resume from the stored position if its segment still exists.
+	q.headID, q.headOffset = q.segments[0], 0
+	if b, err := ioutil.ReadFile(filepath.Join(q.dir, positionFile)); err == nil && len(b) == 16 {
+		id, offset := int(binary.BigEndian.Uint64(b[:8])), int64(binary.BigEndian.Uint64(b[8:]))
+		if q.indexOf(id) >= 0 {
+			q.headID, q.headOffset = id, offset
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Remove segments that were fully read before the queue was last closed.
+	for q.segments[0] != q.headID {
+		if _, err := q.removeHeadSegment(false); err != nil {
+			return err
+		}
+	}
+
+	q.closing = make(chan struct{})
+	if q.SyncInterval > 0 {
+		q.wg.Add(1)
+		go q.runSync(q.closing, q.SyncInterval)
+	}
+	return nil
+}
+
+// runSync periodically syncs the queue until closing is closed.
+func (q *Queue) runSync(closing chan struct{}, interval time.Duration) {
+	defer q.wg.Done()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-closing:
+			return
+		case <-t.C:
+			// An error is returned again by the next sync or by Close.
+			q.Sync()
+		}
+	}
+}
+
+// openTail opens the newest segment for appending, truncating any partially
+// written entry left by a crash.
+func (q *Queue) openTail() error {
+	path := q.segmentPath(q.segments[len(q.segments)-1])
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	valid, err := validSize(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if valid < fi.Size() {
+		if err := f.Truncate(valid); err != nil {
+			f.Close()
+			return err
+		}
+		q.size -= fi.Size() - valid
+	}
+
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	q.tail, q.tailSize = f, valid
+	return nil
+}
+
+// Close syncs and closes the queue.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closing != nil {
+		close(q.closing)
+		q.closing = nil
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.tail == nil {
+		return nil
+	}
+	err := q.sync()
+	if e := q.tail.Close(); err == nil {
+		err = e
+	}
+	q.tail = nil
+	return err
+}
+
+// Sync flushes the appended entries and the read position to disk.
+func (q *Queue) Sync() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.tail == nil || !q.dirty {
+		return nil
+	}
+	return q.sync()
+}
+
+func (q *Queue) sync() error {
+	if err := q.tail.Sync(); err != nil {
+		return err
+	}
+	if err := q.writePosition(true); err != nil {
+		return err
+	}
+	q.dirty = false
+	return nil
+}
+
+// DiskSize returns the total size of the queue's segment files.
+func (q *Queue) DiskSize() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+// Append adds b to the end of the queue. If the queue is full and dropOldest
+// is true, the oldest segments are discarded to make room; otherwise
+// ErrQueueFull is returned. The number of bytes discarded is returned.
+func (q *Queue) Append(b []byte, dropOldest bool) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := int64(4 + len(b))
+	if n > q.maxSize {
+		return 0, ErrQueueFull
+	}
+
+	var dropped int64
+	for q.size+n > q.maxSize {
+		if !dropOldest {
+			return dropped, ErrQueueFull
+		}
+
+		m, err := q.removeHeadSegment(false)
+		if err != nil {
+			return dropped, err
+		}
+		dropped += m
+	}
+
+	if q.tailSize > 0 && q.tailSize+n > q.maxSegmentSize {
+		if err := q.roll(); err != nil {
+			return dropped, err
+		}
+	}
+
+	buf := make([]byte, n)
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(b)))
+	copy(buf[4:], b)
+	if _, err := q.tail.Write(buf); err != nil {
+		return dropped, err
+	}
+
+	q.tailSize += n
+	q.size += n
+	q.dirty = true
+	return dropped, nil
+}
+
+// Current returns the entry at the head of the queue. io.EOF is returned if
+// the queue is empty. If the head segment is corrupt, it's quarantined and a
+// *CorruptSegmentError is returned; the next call resumes from the following
+// segment.
+func (q *Queue) Current() ([]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		b, err := q.readAt(q.headID, q.headOffset)
+		if err == nil {
+			q.headLen = int64(4 + len(b))
+			return b, nil
+		} else if e, ok := err.(*CorruptSegmentError); ok {
+			if _, err := q.removeHeadSegment(true); err != nil {
+				return nil, err
+			}
+			return nil, e
+		} else if err != io.EOF {
+			return nil, err
+		}
+
+		// The head segment has been fully read. Remove it unless it is
+		// still being written to.
+		if q.headID == q.segments[len(q.segments)-1] {
+			return nil, io.EOF
+		}
+		if _, err := q.removeHeadSegment(false); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Advance moves the head past the entry last returned by Current.
+func (q *Queue) Advance() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.headOffset += q.headLen
+	q.headLen = 0
+	q.dirty = true
+	return q.writePosition(false)
+}
+
+// roll syncs and closes the tail segment and starts a new one.
+func (q *Queue) roll() error {
+	if err := q.sync(); err != nil {
+		return err
+	}
+	if err := q.tail.Close(); err != nil {
+		return err
+	}
+
+	id := q.segments[len(q.segments)-1] + 1
+	f, err := os.OpenFile(q.segmentPath(id), os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(q.dir); err != nil {
+		f.Close()
+		return err
+	}
+
+	q.segments = append(q.segments, id)
+	q.tail, q.tailSize = f, 0
+	return nil
+}
+
+// removeHeadSegment deletes the oldest segment, or moves it aside if
+// quarantine is true, returning the number of unread bytes discarded.
+func (q *Queue) removeHeadSegment(quarantine bool) (int64, error) {
+	if len(q.segments) == 1 {
+		if err := q.roll(); err != nil {
+			return 0, err
+		}
+	}
+
+	id := q.segments[0]
+	path := q.segmentPath(id)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if quarantine {
+		if err := os.Rename(path, path+corruptSuffix); err != nil {
+			return 0, err
+		}
+	} else if err := os.Remove(path); err != nil {
+		return 0, err
+	}
+
+	var unread int64
+	if id == q.headID {
+		unread = fi.Size() - q.headOffset
+	} else {
+		unread = fi.Size()
+	}
+
+	q.size -= fi.Size()
+	q.segments = q.segments[1:]
+	if id == q.headID {
+		q.headID, q.headOffset, q.headLen = q.segments[0], 0, 0
+		if err := q.writePosition(false); err != nil {
+			return 0, err
+		}
+	}
+	return unread, nil
+}
+
+// readAt reads the entry at offset within a segment. io.EOF is returned if
+// offset is the end of the segment and a *CorruptSegmentError if no complete
+// entry exists at offset.
+func (q *Queue) readAt(id int, offset int64) ([]byte, error) {
+	path := q.segmentPath(id)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries are appended whole while the queue is locked, so a short
+	// entry can't be one still being written.
+	if offset == fi.Size() {
+		return nil, io.EOF
+	} else if offset+4 > fi.Size() {
+		return nil, &CorruptSegmentError{Path: path, Offset: offset}
+	}
+
+	var hdr [4]byte
+	if _, err := f.ReadAt(hdr[:], offset); err != nil {
+		return nil, err
+	}
+
+	n := int64(binary.BigEndian.Uint32(hdr[:]))
+	if offset+4+n > fi.Size() {
+		return nil, &CorruptSegmentError{Path: path, Offset: offset}
+	}
+
+	b := make([]byte, n)
+	if _, err := f.ReadAt(b, offset+4); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// writePosition persists the head position, syncing it to disk if sync is true.
+func (q *Queue) writePosition(sync bool) error {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(q.headID))
+	binary.BigEndian.PutUint64(b[8:], uint64(q.headOffset))
+
+	path := filepath.Join(q.dir, positionFile)
+	f, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b[:]); err != nil {
+		f.Close()
+		return err
+	}
+	if sync {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	if sync {
+		return syncDir(q.dir)
+	}
+	return nil
+}
+
+func (q *Queue) indexOf(id int) int {
+	for i, v := range q.segments {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *Queue) segmentPath(id int) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d", id))
+}
+
+// validSize returns the size of the complete entries at the start of f.
+func validSize(f *os.File) (int64, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	var offset int64
+	var hdr [4]byte
+	for {
+		if _, err := f.ReadAt(hdr[:], offset); err == io.EOF {
+			return offset, nil
+		} else if err != nil {
+			return 0, err
+		}
+
+		next := offset + 4 + int64(binary.BigEndian.Uint32(hdr[:]))
+		if next > fi.Size() {
+			return offset, nil
+		}
+		offset = next
+	}
+}
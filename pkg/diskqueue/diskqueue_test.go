@@ -0,0 +1,151 @@
+package diskqueue_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influxdb/pkg/diskqueue"
+)
+
+func TestQueue_AppendAdvance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskqueue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q := diskqueue.New(dir, 1024, 16)
+	if err := q.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"first", "second", "third"} {
+		if _, err := q.Append([]byte(s), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := q.Current()
+	if err != nil {
+		t.Fatal(err)
+	} else if string(b) != "first" {
+		t.Fatalf("unexpected entry: %q", b)
+	} else if err := q.Advance(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen the queue and ensure the position was persisted.
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	q = diskqueue.New(dir, 1024, 16)
+	if err := q.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	for _, exp := range []string{"second", "third"} {
+		b, err := q.Current()
+		if err != nil {
+			t.Fatal(err)
+		} else if string(b) != exp {
+			t.Fatalf("unexpected entry: got %q, exp %q", b, exp)
+		} else if err := q.Advance(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := q.Current(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestQueue_Full(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskqueue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q := diskqueue.New(dir, 30, 10)
+	if err := q.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	for _, s := range []string{"aaaaaa", "bbbbbb", "cccccc"} {
+		if _, err := q.Append([]byte(s), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Dropping the newest rejects the write.
+	if _, err := q.Append([]byte("dddddd"), false); err != diskqueue.ErrQueueFull {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+
+	// Dropping the oldest discards the first segment.
+	if dropped, err := q.Append([]byte("dddddd"), true); err != nil {
+		t.Fatal(err)
+	} else if dropped != 10 {
+		t.Fatalf("unexpected bytes dropped: %d", dropped)
+	}
+
+	if b, err := q.Current(); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "bbbbbb" {
+		t.Fatalf("unexpected entry: %q", b)
+	}
+}
+
+func TestQueue_CorruptSegment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskqueue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q := diskqueue.New(dir, 1024, 10)
+	if err := q.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	for _, s := range []string{"aaaaaa", "bbbbbb"} {
+		if _, err := q.Append([]byte(s), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Truncate the first segment, leaving an incomplete entry.
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, fis[0].Name())
+	if err := os.Truncate(path, 6); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.Current(); err == nil {
+		t.Fatal("expected error")
+	} else if e, ok := err.(*diskqueue.CorruptSegmentError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e.Path != path {
+		t.Fatalf("unexpected path: %s", e.Path)
+	}
+
+	if _, err := os.Stat(path + ".corrupt"); err != nil {
+		t.Fatalf("segment not quarantined: %s", err)
+	}
+
+	// The queue resumes from the next segment.
+	if b, err := q.Current(); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "bbbbbb" {
+		t.Fatalf("unexpected entry: %q", b)
+	}
+}
@@ -0,0 +1,15 @@
+// +build !windows
+
+package diskqueue
+
+import "os"
+
+func syncDir(dirName string) error {
+	// fsync the dir to flush the rename
+	dir, err := os.OpenFile(dirName, os.O_RDONLY, os.ModeDir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
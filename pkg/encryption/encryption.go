@@ -0,0 +1,252 @@
+// Package encryption encrypts data at rest with AES-256-GCM, using keys
+// supplied by a key provider.
+package encryption
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// KeySize is the size of keys, for AES-256.
+	KeySize = 32
+
+	// nonceSize is the size of the random nonce of each encryption.
+	nonceSize = 12
+
+	// Overhead is the number of bytes encryption adds to data: the key ID,
+	// the nonce and the authentication tag.
+	Overhead = 4 + nonceSize + 16
+)
+
+// ErrInvalidData is returned when decrypting data that is too short or was
+// modified.
+var ErrInvalidData = errors.New("invalid encrypted data")
+
+// KeyProvider provides the keys data is encrypted with. Keys are identified
+// by a non-zero ID stored with the data they encrypt, so that data encrypted
+// with a previous key can still be read once a new key is in use.
+type KeyProvider interface {
+	// CurrentKeyID returns the ID of the key new data is encrypted with.
+	CurrentKeyID() (uint32, error)
+
+	// Key returns the key with the given ID.
+	Key(id uint32) ([]byte, error)
+}
+
+// NewKeyProviderFunc returns a key provider reading keys from source, such as
+// the path of a key file or the URL of a key management service.
+type NewKeyProviderFunc func(source string) (KeyProvider, error)
+
+var newKeyProviderFuncs = map[string]NewKeyProviderFunc{
+	"file": func(source string) (KeyProvider, error) { return NewFileKeyProvider(source) },
+}
+
+// RegisterKeyProvider registers a key provider, such as a client of a key
+// management service, under name.
+func RegisterKeyProvider(name string, fn NewKeyProviderFunc) {
+	if _, ok := newKeyProviderFuncs[name]; ok {
+		panic("key provider already registered: " + name)
+	}
+	newKeyProviderFuncs[name] = fn
+}
+
+// RegisteredKeyProviders returns the names of the registered key providers.
+func RegisteredKeyProviders() []string {
+	a := make([]string, 0, len(newKeyProviderFuncs))
+	for k := range newKeyProviderFuncs {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+	return a
+}
+
+// NewKeyProvider returns the key provider registered under name.
+func NewKeyProvider(name, source string) (KeyProvider, error) {
+	fn := newKeyProviderFuncs[name]
+	if fn == nil {
+		return nil, fmt.Errorf("unknown key provider: %q", name)
+	}
+	return fn(source)
+}
+
+// FileKeyProvider reads keys from a file. Each line of the file holds the
+// decimal ID of a key and the key in hex, separated by a space. Empty lines
+// and lines starting with # are ignored. The last key of the file is the
+// current key, so keys are rotated by appending a new key.
+type FileKeyProvider struct {
+	current uint32
+	keys    map[uint32][]byte
+}
+
+// NewFileKeyProvider returns a provider of the keys in the file at path.
+func NewFileKeyProvider(path string) (*FileKeyProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p := &FileKeyProvider{keys: make(map[uint32][]byte)}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a key ID and key", path, n)
+		}
+		id, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("%s:%d: invalid key ID %q", path, n, fields[0])
+		}
+		key, err := hex.DecodeString(fields[1])
+		if err != nil || len(key) != KeySize {
+			return nil, fmt.Errorf("%s:%d: key must be %d bytes in hex", path, n, KeySize)
+		}
+		if _, ok := p.keys[uint32(id)]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate key ID %d", path, n, id)
+		}
+
+		p.keys[uint32(id)] = key
+		p.current = uint32(id)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if p.current == 0 {
+		return nil, fmt.Errorf("%s: no keys", path)
+	}
+	return p, nil
+}
+
+// CurrentKeyID returns the ID of the last key of the file.
+func (p *FileKeyProvider) CurrentKeyID() (uint32, error) {
+	return p.current, nil
+}
+
+// Key returns the key with the given ID.
+func (p *FileKeyProvider) Key(id uint32) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("encryption key %d not found", id)
+	}
+	return key, nil
+}
+
+// Cipher encrypts data with the current key of a provider, and decrypts data
+// encrypted with any of its keys.
+type Cipher struct {
+	provider KeyProvider
+
+	mu    sync.RWMutex
+	aeads map[uint32]cipher.AEAD
+}
+
+// NewCipher returns a cipher using the keys of provider.
+func NewCipher(provider KeyProvider) *Cipher {
+	return &Cipher{
+		provider: provider,
+		aeads:    make(map[uint32]cipher.AEAD),
+	}
+}
+
+// aead returns the AEAD of the key with the given ID.
+func (c *Cipher) aead(id uint32) (cipher.AEAD, error) {
+	c.mu.RLock()
+	aead := c.aeads[id]
+	c.mu.RUnlock()
+	if aead != nil {
+		return aead, nil
+	}
+
+	key, err := c.provider.Key(id)
+	if err != nil {
+		return nil, err
+	} else if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key %d must be %d bytes", id, KeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.aeads[id] = aead
+	c.mu.Unlock()
+	return aead, nil
+}
+
+// Encrypt appends the encryption of plaintext with the current key to dst.
+// The ID of the key and the nonce are written before the ciphertext.
+func (c *Cipher) Encrypt(dst, plaintext []byte) ([]byte, error) {
+	id, err := c.provider.CurrentKeyID()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := c.aead(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var header [4 + nonceSize]byte
+	binary.BigEndian.PutUint32(header[:4], id)
+	if _, err := io.ReadFull(rand.Reader, header[4:]); err != nil {
+		return nil, err
+	}
+
+	dst = append(dst, header[:]...)
+	return aead.Seal(dst, header[4:], plaintext, nil), nil
+}
+
+// Decrypt appends the decryption of data to dst.
+func (c *Cipher) Decrypt(dst, data []byte) ([]byte, error) {
+	if len(data) < Overhead {
+		return nil, ErrInvalidData
+	}
+
+	aead, err := c.aead(KeyID(data))
+	if err != nil {
+		return nil, err
+	}
+
+	dst, err = aead.Open(dst, data[4:4+nonceSize], data[4+nonceSize:], nil)
+	if err != nil {
+		return nil, ErrInvalidData
+	}
+	return dst, nil
+}
+
+// CurrentKeyID returns the ID of the key new data is encrypted with.
+func (c *Cipher) CurrentKeyID() (uint32, error) {
+	return c.provider.CurrentKeyID()
+}
+
+// KeyID returns the ID of the key data was encrypted with.
+func KeyID(data []byte) uint32 {
+	if len(data) < 4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(data[:4])
+}
@@ -0,0 +1,135 @@
+package encryption_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/pkg/encryption"
+)
+
+const (
+	key1 = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	key2 = "1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100"
+)
+
+func TestCipher_Rotate(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "keys")
+	MustWriteFile(path, "# keys\n1 "+key1+"\n")
+	old := encryption.NewCipher(MustOpenFileKeyProvider(path))
+
+	plaintext := []byte("cpu,host=server01 value=1")
+	data, err := old.Encrypt(nil, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	} else if got, exp := len(data), len(plaintext)+encryption.Overhead; got != exp {
+		t.Fatalf("unexpected length: got %d, exp %d", got, exp)
+	} else if bytes.Contains(data, plaintext) {
+		t.Fatal("expected data to be encrypted")
+	} else if id := encryption.KeyID(data); id != 1 {
+		t.Fatalf("unexpected key ID: %d", id)
+	}
+
+	// Once a key is appended, data is encrypted with it, and data encrypted
+	// with the previous key is still readable.
+	MustWriteFile(path, "1 "+key1+"\n\n2 "+key2+"\n")
+	c := encryption.NewCipher(MustOpenFileKeyProvider(path))
+	if got, err := c.Decrypt(nil, data); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, plaintext) {
+		t.Fatalf("unexpected plaintext: %q", got)
+	}
+
+	data, err = c.Encrypt(nil, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	} else if id := encryption.KeyID(data); id != 2 {
+		t.Fatalf("unexpected key ID: %d", id)
+	}
+
+	// Modified data is rejected.
+	data[len(data)-1] ^= 1
+	if _, err := c.Decrypt(nil, data); err != encryption.ErrInvalidData {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Data encrypted with an unknown key can't be read.
+	if _, err := old.Decrypt(nil, data); err == nil || err.Error() != "encryption key 2 not found" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestNewFileKeyProvider_Invalid(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	for _, tt := range []struct {
+		contents string
+		err      string
+	}{
+		{contents: "# no keys\n", err: "no keys"},
+		{contents: "1\n", err: "expected a key ID and key"},
+		{contents: "0 " + key1 + "\n", err: "invalid key ID"},
+		{contents: "1 0102\n", err: "key must be 32 bytes in hex"},
+		{contents: "1 " + key1 + "\n1 " + key2 + "\n", err: "duplicate key ID 1"},
+	} {
+		path := filepath.Join(dir, "keys")
+		MustWriteFile(path, tt.contents)
+		if _, err := encryption.NewFileKeyProvider(path); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("unexpected error for %q: %v", tt.contents, err)
+		}
+	}
+}
+
+func TestNewKeyProvider(t *testing.T) {
+	if _, err := encryption.NewKeyProvider("vault", ""); err == nil || err.Error() != `unknown key provider: "vault"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	encryption.RegisterKeyProvider("test", func(source string) (encryption.KeyProvider, error) {
+		return MustOpenFileKeyProvider(source), nil
+	})
+
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "keys")
+	MustWriteFile(path, "7 "+key1+"\n")
+
+	p, err := encryption.NewKeyProvider("test", path)
+	if err != nil {
+		t.Fatal(err)
+	} else if id, err := p.CurrentKeyID(); err != nil || id != 7 {
+		t.Fatalf("unexpected current key ID: %d (%v)", id, err)
+	}
+}
+
+// MustTempDir returns a temporary directory.
+func MustTempDir() string {
+	dir, err := ioutil.TempDir("", "encryption-")
+	if err != nil {
+		panic(err)
+	}
+	return dir
+}
+
+// MustWriteFile writes contents to path.
+func MustWriteFile(path, contents string) {
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		panic(err)
+	}
+}
+
+// MustOpenFileKeyProvider returns the provider of the keys in the file at path.
+func MustOpenFileKeyProvider(path string) *encryption.FileKeyProvider {
+	p, err := encryption.NewFileKeyProvider(path)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
@@ -1,4 +1,4 @@
-// Package limiter provides concurrency limiters.
+// Package limiter provides concurrency and IO bandwidth limiters.
 package limiter
 
 // Fixed is a simple channel-based concurrency limiter.  It uses a fixed
@@ -0,0 +1,230 @@
+package limiter
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Priority orders the subsystems that share an IOManager. When the shared
+// budget is exhausted, waiting subsystems of a higher priority proceed first.
+type Priority int
+
+const (
+	// PriorityScrub is the priority of the background scrubbing of TSM files.
+	PriorityScrub Priority = iota
+
+	// PriorityCompaction is the priority of level and full compactions and
+	// of shard deletes by the retention policy service.
+	PriorityCompaction
+
+	// PriorityBackup is the priority of shard backups.
+	PriorityBackup
+
+	// PriorityRestore is the priority of shard restores.
+	PriorityRestore
+
+	// PriorityWrite is the priority of writes. Subsystems at this priority or
+	// higher are never throttled, but their IO counts against the shared budget.
+	PriorityWrite
+
+	// PriorityQuery is the priority of queries.
+	PriorityQuery
+
+	numPriorities
+)
+
+// minIOWait is the shortest time a throttled subsystem sleeps before checking
+// the budget again.
+const minIOWait = 10 * time.Millisecond
+
+// bucket is a token bucket of bytes. Takes may leave the bucket in debt,
+// which is repaid before the next take is allowed.
+type bucket struct {
+	rate   float64 // bytes per second
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(limit int, now time.Time) *bucket {
+	if limit <= 0 {
+		return nil
+	}
+	return &bucket{rate: float64(limit), tokens: float64(limit), last: now}
+}
+
+// refill adds the tokens accrued since the last refill, up to one second's worth.
+func (b *bucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+}
+
+// delay returns how long until the bucket is out of debt.
+func (b *bucket) delay() time.Duration {
+	if b.tokens > 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// IOManager is an IO bandwidth budget shared by the subsystems registered
+// with it. The zero limit places no limit on the shared budget.
+type IOManager struct {
+	mu      sync.Mutex
+	global  *bucket
+	waiting [numPriorities]int
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// NewIOManager returns an IOManager limiting all registered subsystems to
+// a total of limit bytes per second. A limit of 0 disables the shared limit.
+func NewIOManager(limit int) *IOManager {
+	m := &IOManager{
+		now:   time.Now,
+		sleep: time.Sleep,
+	}
+	m.global = newBucket(limit, m.now())
+	return m
+}
+
+// Register returns a subsystem that takes its IO from the shared budget at
+// the given priority, and is also limited to limit bytes per second. A limit
+// of 0 places no limit on the subsystem other than the shared one.
+func (m *IOManager) Register(name string, priority Priority, limit int) *IOSubsystem {
+	return &IOSubsystem{
+		name:     name,
+		priority: priority,
+		m:        m,
+		own:      newBucket(limit, m.now()),
+	}
+}
+
+// take takes n bytes from the shared budget, waiting while the budget is in
+// debt or a subsystem of a higher priority is waiting for it.
+func (m *IOManager) take(p Priority, n int) {
+	if m.global == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.global.refill(m.now())
+	if p >= PriorityWrite {
+		m.global.tokens -= float64(n)
+		return
+	}
+
+	m.waiting[p]++
+	defer func() { m.waiting[p]-- }()
+	for {
+		if m.global.tokens > 0 && !m.higherWaiting(p) {
+			m.global.tokens -= float64(n)
+			return
+		}
+
+		d := m.global.delay()
+		if d < minIOWait {
+			d = minIOWait
+		}
+
+		m.mu.Unlock()
+		m.sleep(d)
+		m.mu.Lock()
+		m.global.refill(m.now())
+	}
+}
+
+// higherWaiting returns true if a subsystem with a priority higher than p is
+// waiting for the shared budget.
+func (m *IOManager) higherWaiting(p Priority) bool {
+	for i := p + 1; i < numPriorities; i++ {
+		if m.waiting[i] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// IOSubsystem is a subsystem registered with an IOManager. A nil
+// IOSubsystem is not limited.
+type IOSubsystem struct {
+	name     string
+	priority Priority
+	m        *IOManager
+
+	mu  sync.Mutex
+	own *bucket
+}
+
+// Name returns the name the subsystem was registered with.
+func (s *IOSubsystem) Name() string {
+	if s == nil {
+		return ""
+	}
+	return s.name
+}
+
+// Wait blocks until the subsystem may do n bytes of IO.
+func (s *IOSubsystem) Wait(n int) {
+	if s == nil || n <= 0 {
+		return
+	}
+
+	if s.own != nil {
+		s.mu.Lock()
+		s.own.refill(s.m.now())
+		d := s.own.delay()
+		s.own.tokens -= float64(n)
+		s.mu.Unlock()
+
+		if d > 0 {
+			s.m.sleep(d)
+		}
+	}
+
+	s.m.take(s.priority, n)
+}
+
+// Reader returns a reader that takes each read from r out of the budget,
+// waiting if it is exhausted.
+func (s *IOSubsystem) Reader(r io.Reader) io.Reader {
+	if s == nil {
+		return r
+	}
+	return &ioReader{s: s, r: r}
+}
+
+// Writer returns a writer that waits for the budget before each write to w.
+func (s *IOSubsystem) Writer(w io.Writer) io.Writer {
+	if s == nil {
+		return w
+	}
+	return &ioWriter{s: s, w: w}
+}
+
+type ioReader struct {
+	s *IOSubsystem
+	r io.Reader
+}
+
+func (r *ioReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.s.Wait(n)
+	return n, err
+}
+
+type ioWriter struct {
+	s *IOSubsystem
+	w io.Writer
+}
+
+func (w *ioWriter) Write(p []byte) (int, error) {
+	w.s.Wait(len(p))
+	return w.w.Write(p)
+}
@@ -0,0 +1,130 @@
+package limiter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// newTestIOManager returns an IOManager with a fake clock that advances
+// only when the manager sleeps, and the total time slept.
+func newTestIOManager(limit int) (*IOManager, *time.Duration) {
+	now := time.Unix(0, 0)
+	var slept time.Duration
+
+	m := NewIOManager(0)
+	m.now = func() time.Time { return now }
+	m.sleep = func(d time.Duration) {
+		now = now.Add(d)
+		slept += d
+	}
+	m.global = newBucket(limit, now)
+	return m, &slept
+}
+
+func TestIOManager_Unlimited(t *testing.T) {
+	m, slept := newTestIOManager(0)
+	s := m.Register("compaction", PriorityCompaction, 0)
+	for i := 0; i < 10; i++ {
+		s.Wait(1 << 30)
+	}
+	if *slept != 0 {
+		t.Fatalf("unexpected wait: %v", *slept)
+	}
+}
+
+func TestIOManager_GlobalLimit(t *testing.T) {
+	m, slept := newTestIOManager(1000)
+	s := m.Register("compaction", PriorityCompaction, 0)
+
+	// The first second's worth of IO is not throttled.
+	s.Wait(1000)
+	if *slept != 0 {
+		t.Fatalf("unexpected wait: %v", *slept)
+	}
+
+	s.Wait(500)
+	s.Wait(500)
+	if *slept < 500*time.Millisecond || *slept > time.Second {
+		t.Fatalf("unexpected wait: %v", *slept)
+	}
+}
+
+func TestIOManager_SubsystemLimit(t *testing.T) {
+	m, slept := newTestIOManager(0)
+	s := m.Register("backup", PriorityBackup, 100)
+
+	// IO over the limit is allowed, and the next caller waits for it.
+	s.Wait(100)
+	s.Wait(100)
+	s.Wait(100)
+	if exp := time.Second; *slept != exp {
+		t.Fatalf("unexpected wait: got %v, exp %v", *slept, exp)
+	}
+}
+
+func TestIOManager_Foreground(t *testing.T) {
+	m, slept := newTestIOManager(1000)
+	w := m.Register("write", PriorityWrite, 0)
+	c := m.Register("compaction", PriorityCompaction, 0)
+
+	// Writes are never throttled...
+	w.Wait(3000)
+	if *slept != 0 {
+		t.Fatalf("unexpected wait: %v", *slept)
+	}
+
+	// ...but background IO waits for the budget they used.
+	c.Wait(1)
+	if *slept < 2*time.Second {
+		t.Fatalf("unexpected wait: %v", *slept)
+	}
+}
+
+func TestIOManager_Priority(t *testing.T) {
+	m, slept := newTestIOManager(1000)
+	c := m.Register("compaction", PriorityCompaction, 0)
+
+	// Simulate a restore waiting for the budget, which finishes waiting
+	// after the compaction first sleeps.
+	m.waiting[PriorityRestore]++
+	sleep := m.sleep
+	m.sleep = func(d time.Duration) {
+		m.waiting[PriorityRestore] = 0
+		sleep(d)
+	}
+
+	c.Wait(1)
+	if *slept == 0 {
+		t.Fatal("expected compaction to wait for restore")
+	}
+}
+
+func TestIOSubsystem_Nil(t *testing.T) {
+	var s *IOSubsystem
+	s.Wait(1 << 30)
+
+	var buf bytes.Buffer
+	if w := s.Writer(&buf); w != &buf {
+		t.Fatal("expected writer to be unwrapped")
+	}
+}
+
+func TestIOSubsystem_Writer(t *testing.T) {
+	m, slept := newTestIOManager(0)
+	s := m.Register("backup", PriorityBackup, 10)
+
+	var buf bytes.Buffer
+	w := s.Writer(&buf)
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write([]byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, exp := buf.Len(), 30; got != exp {
+		t.Fatalf("unexpected length: got %d, exp %d", got, exp)
+	} else if exp := time.Second; *slept != exp {
+		t.Fatalf("unexpected wait: got %v, exp %v", *slept, exp)
+	}
+}
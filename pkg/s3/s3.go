@@ -0,0 +1,316 @@
+// Package s3 implements a minimal client for S3 compatible object storage,
+// including Google Cloud Storage through its XML API.
+package s3
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultRegion is the default region of S3 buckets.
+const DefaultRegion = "us-east-1"
+
+// ErrNotFound is returned when an object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Client reads and writes the objects of a bucket. Requests are signed with
+// AWS signature version 4.
+type Client struct {
+	HTTPClient *http.Client
+
+	// Endpoint is the base URL of the service, such as
+	// https://s3.us-east-1.amazonaws.com. Buckets are addressed by path.
+	Endpoint string
+	Bucket   string
+	Region   string
+
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// ParseURL returns a client and key prefix for an s3://bucket/prefix or
+// gs://bucket/prefix URL. Credentials are read from the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables; for
+// Google Cloud Storage they are HMAC keys. The endpoint defaults to the
+// endpoint of the region, or of Google Cloud Storage.
+func ParseURL(rawurl, region, endpoint string) (*Client, string, error) {
+	bucket, prefix, err := ParseBucket(rawurl)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if strings.HasPrefix(rawurl, "gs://") {
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+		if region == "" {
+			region = "auto"
+		}
+	}
+	if region == "" {
+		region = DefaultRegion
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+
+	c := &Client{
+		HTTPClient:   &http.Client{},
+		Endpoint:     strings.TrimSuffix(endpoint, "/"),
+		Bucket:       bucket,
+		Region:       region,
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return nil, "", errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return c, prefix, nil
+}
+
+// IsURL returns true if path is an s3:// or gs:// URL.
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, "s3://") || strings.HasPrefix(path, "gs://")
+}
+
+// ParseBucket returns the bucket and key prefix of an s3://bucket/prefix
+// or gs://bucket/prefix URL.
+func ParseBucket(rawurl string) (bucket, prefix string, err error) {
+	if !IsURL(rawurl) {
+		return "", "", fmt.Errorf("not an s3:// or gs:// URL: %s", rawurl)
+	}
+
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", "", err
+	} else if u.Host == "" {
+		return "", "", fmt.Errorf("missing bucket: %s", rawurl)
+	}
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+// Put stores the contents of r under key.
+func (c *Client) Put(key string, r io.ReadSeeker) error {
+	// The payload is hashed before it is sent to sign the request.
+	h := sha256.New()
+	size, err := io.Copy(h, r)
+	if err != nil {
+		return err
+	} else if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := c.newRequest("PUT", key, ioutil.NopCloser(r))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	c.sign(req, hex.EncodeToString(h.Sum(nil)), time.Now().UTC())
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError("put", key, resp)
+	}
+	return nil
+}
+
+// Get returns a reader of the object stored under key.
+func (c *Client) Get(key string) (io.ReadCloser, error) {
+	req, err := c.newRequest("GET", key, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.sign(req, hexSHA256(nil), time.Now().UTC())
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError("get", key, resp)
+	}
+	return resp.Body, nil
+}
+
+// ReadAt reads len(p) bytes of the object stored under key, starting at
+// offset off. It returns io.EOF if fewer bytes are available.
+func (c *Client) ReadAt(key string, p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	req, err := c.newRequest("GET", key, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+	c.sign(req, hexSHA256(nil), time.Now().UTC())
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		return 0, io.EOF
+	default:
+		return 0, responseError("get", key, resp)
+	}
+
+	n, err := io.ReadFull(resp.Body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Delete removes the object stored under key. Deleting an object that does
+// not exist is not an error.
+func (c *Client) Delete(key string) error {
+	req, err := c.newRequest("DELETE", key, nil)
+	if err != nil {
+		return err
+	}
+	c.sign(req, hexSHA256(nil), time.Now().UTC())
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return responseError("delete", key, resp)
+	}
+}
+
+// newRequest returns a request for the object stored under key.
+func (c *Client) newRequest(method, key string, body io.Reader) (*http.Request, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/" + c.Bucket + "/" + key
+	u.RawPath = "/" + escape(c.Bucket) + "/" + escape(key)
+	return http.NewRequest(method, u.String(), body)
+}
+
+// sign adds an AWS signature version 4 authorization header to req.
+func (c *Client) sign(req *http.Request, payloadHash string, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	// Build the canonical headers from the host, range and x-amz headers.
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		if k := strings.ToLower(k); k == "range" || strings.HasPrefix(k, "x-amz-") {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders bytes.Buffer
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, c.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretKey), date)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKey, scope, signedHeaders, signature))
+}
+
+// responseError returns the error of an unsuccessful response.
+func responseError(op, key string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s %s: %s: %s", op, key, resp.Status, bytes.TrimSpace(body))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// escape escapes an object key as required by S3, leaving slashes
+// unescaped.
+func escape(s string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			buf.WriteByte(c)
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
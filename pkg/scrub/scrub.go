@@ -0,0 +1,180 @@
+// Package scrub hashes or redacts tag values and string field values so
+// that data can be copied out of production without exposing identifiers.
+package scrub
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// Redacted is the value that redacted tag and field values are replaced with.
+const Redacted = "redacted"
+
+// Action is what is done to a scrubbed value.
+type Action int
+
+const (
+	// Hash replaces a value with a keyed hash of it. Equal values hash to
+	// equal values, so series remain distinct and groupable.
+	Hash Action = iota + 1
+
+	// Redact replaces a value with Redacted.
+	Redact
+)
+
+// String returns the name of the action.
+func (a Action) String() string {
+	switch a {
+	case Hash:
+		return "hash"
+	case Redact:
+		return "redact"
+	}
+	return "unknown"
+}
+
+// Rules holds the tag keys and field keys to scrub. Rules implements
+// flag.Value so rules can be given as repeated command line flags of the form
+// "tag:<key>=<action>" or "field:<key>=<action>", where action is "hash" or
+// "redact".
+type Rules struct {
+	// Salt is the key used to hash values. Without a salt, hashed values
+	// of a small set of known identifiers can be reversed by brute force.
+	Salt string
+
+	tags   map[string]Action
+	fields map[string]Action
+}
+
+// NewRules returns an empty set of rules.
+func NewRules() *Rules {
+	return &Rules{
+		tags:   make(map[string]Action),
+		fields: make(map[string]Action),
+	}
+}
+
+// String returns the rules in the form accepted by Set, comma separated.
+func (r *Rules) String() string {
+	if r == nil {
+		return ""
+	}
+
+	var a []string
+	for k, action := range r.tags {
+		a = append(a, fmt.Sprintf("tag:%s=%s", k, action))
+	}
+	for k, action := range r.fields {
+		a = append(a, fmt.Sprintf("field:%s=%s", k, action))
+	}
+	sort.Strings(a)
+	return strings.Join(a, ",")
+}
+
+// Set adds a rule of the form "tag:<key>=<action>" or "field:<key>=<action>".
+func (r *Rules) Set(s string) error {
+	i := strings.Index(s, ":")
+	j := strings.LastIndex(s, "=")
+	if i < 0 || j < i+2 {
+		return fmt.Errorf("invalid scrub rule %q: expected tag:<key>=<action> or field:<key>=<action>", s)
+	}
+
+	var action Action
+	switch s[j+1:] {
+	case "hash":
+		action = Hash
+	case "redact":
+		action = Redact
+	default:
+		return fmt.Errorf("invalid scrub rule %q: action must be hash or redact", s)
+	}
+
+	key := s[i+1 : j]
+	switch s[:i] {
+	case "tag":
+		r.tags[key] = action
+	case "field":
+		r.fields[key] = action
+	default:
+		return fmt.Errorf("invalid scrub rule %q: expected tag or field", s)
+	}
+	return nil
+}
+
+// Empty returns true if there are no rules.
+func (r *Rules) Empty() bool {
+	return r == nil || (len(r.tags) == 0 && len(r.fields) == 0)
+}
+
+// apply returns v with action applied to it.
+func (r *Rules) apply(action Action, v string) string {
+	if action == Redact {
+		return Redacted
+	}
+
+	h := hmac.New(sha256.New, []byte(r.Salt))
+	h.Write([]byte(v))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Tags returns a copy of tags with the values of scrubbed tag keys replaced,
+// or tags itself if no tag is scrubbed.
+func (r *Rules) Tags(tags models.Tags) models.Tags {
+	var other models.Tags
+	for i, t := range tags {
+		action, ok := r.tags[string(t.Key)]
+		if !ok {
+			continue
+		}
+		if other == nil {
+			other = tags.Clone()
+		}
+		other[i].Value = []byte(r.apply(action, string(t.Value)))
+	}
+	if other == nil {
+		return tags
+	}
+	return other
+}
+
+// SeriesKey returns the series key with the values of scrubbed tag keys replaced.
+func (r *Rules) SeriesKey(key []byte) []byte {
+	if len(r.tags) == 0 {
+		return key
+	}
+	name, tags := models.ParseKey(key)
+	return models.MakeKey([]byte(name), r.Tags(tags))
+}
+
+// FieldValue returns v, or a replacement if v is a string value of a
+// scrubbed field key.
+func (r *Rules) FieldValue(field string, v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	if action, ok := r.fields[field]; ok {
+		return r.apply(action, s)
+	}
+	return v
+}
+
+// Point returns a copy of p with its tag values and string field values scrubbed.
+func (r *Rules) Point(p models.Point) (models.Point, error) {
+	fields, err := p.Fields()
+	if err != nil {
+		return nil, err
+	}
+
+	other := make(models.Fields, len(fields))
+	for k, v := range fields {
+		other[k] = r.FieldValue(k, v)
+	}
+	return models.NewPoint(string(p.Name()), r.Tags(p.Tags()), other, p.Time())
+}
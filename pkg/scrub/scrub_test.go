@@ -0,0 +1,84 @@
+package scrub_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/scrub"
+)
+
+func TestRules_Set(t *testing.T) {
+	r := scrub.NewRules()
+	for _, s := range []string{"tag:host=hash", "field:user=redact", "tag:a=b=redact"} {
+		if err := r.Set(s); err != nil {
+			t.Fatalf("%s: %s", s, err)
+		}
+	}
+	if got, exp := r.String(), "field:user=redact,tag:a=b=redact,tag:host=hash"; got != exp {
+		t.Fatalf("got %q, expected %q", got, exp)
+	}
+
+	for _, s := range []string{"host=hash", "tag:=hash", "tag:host=drop", "measurement:cpu=hash", "tag:host"} {
+		if err := scrub.NewRules().Set(s); err == nil {
+			t.Fatalf("%s: expected error", s)
+		}
+	}
+}
+
+func TestRules_SeriesKey(t *testing.T) {
+	r := scrub.NewRules()
+	if err := r.Set("tag:host=redact"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := string(r.SeriesKey([]byte(`cpu\ load,host=a\ b,region=west`))), `cpu\ load,host=redacted,region=west`; got != exp {
+		t.Fatalf("got %q, expected %q", got, exp)
+	} else if got, exp := string(r.SeriesKey([]byte("cpu,region=west"))), "cpu,region=west"; got != exp {
+		t.Fatalf("got %q, expected %q", got, exp)
+	}
+}
+
+func TestRules_Hash(t *testing.T) {
+	r := scrub.NewRules()
+	if err := r.Set("field:user=hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := r.FieldValue("user", "alice"), r.FieldValue("user", "bob")
+	if a == "alice" || a == b {
+		t.Fatalf("unexpected hashes: %v, %v", a, b)
+	} else if r.FieldValue("user", "alice") != a {
+		t.Fatal("expected equal values to hash equally")
+	} else if r.FieldValue("user", int64(1)) != int64(1) {
+		t.Fatal("expected non-string values to be kept")
+	}
+
+	r.Salt = "secret"
+	if r.FieldValue("user", "alice") == a {
+		t.Fatal("expected salt to change hash")
+	}
+}
+
+func TestRules_Point(t *testing.T) {
+	r := scrub.NewRules()
+	for _, s := range []string{"tag:host=redact", "field:user=redact"} {
+		if err := r.Set(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	points, err := models.ParsePointsString(`cpu,host=a,region=west user="alice",value=1 10`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := r.Point(points[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := p.String(), `cpu,host=redacted,region=west user="redacted",value=1 10`; got != exp {
+		t.Fatalf("got %q, expected %q", got, exp)
+	}
+	if got, exp := points[0].String(), `cpu,host=a,region=west user="alice",value=1 10`; got != exp {
+		t.Fatalf("original point modified: %q", got)
+	}
+}
@@ -0,0 +1,246 @@
+// Package tdigest implements the merging t-digest described by Ted Dunning
+// and Otmar Ertl in "Computing Extremely Accurate Quantiles Using t-Digests".
+//
+// A t-digest summarizes a distribution with a bounded number of weighted
+// centroids. Centroids near the tails hold fewer values than those near the
+// median so that extreme quantiles remain accurate. Digests can be merged,
+// which allows a distribution to be summarized in parts and combined later.
+package tdigest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Current version of the binary encoding.
+const version uint8 = 1
+
+// DefaultCompression is the default compression of a digest. Higher values
+// keep more centroids and are more accurate.
+const DefaultCompression = 100
+
+// Centroid is the mean of a number of values.
+type Centroid struct {
+	Mean   float64
+	Weight float64
+}
+
+// TDigest is a sketch of a distribution for estimating quantiles.
+type TDigest struct {
+	compression float64
+
+	processed   []Centroid // sorted and compressed centroids.
+	unprocessed []Centroid // centroids added since the last compression.
+
+	processedWeight   float64
+	unprocessedWeight float64
+
+	min, max float64
+}
+
+// New returns a new TDigest with the default compression.
+func New() *TDigest {
+	return NewWithCompression(DefaultCompression)
+}
+
+// NewWithCompression returns a new TDigest with compression c.
+func NewWithCompression(c float64) *TDigest {
+	return &TDigest{
+		compression: c,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// Add adds a value with weight w to the digest.
+func (t *TDigest) Add(x, w float64) {
+	if math.IsNaN(x) || w <= 0 {
+		return
+	}
+	t.add(Centroid{Mean: x, Weight: w})
+}
+
+func (t *TDigest) add(c Centroid) {
+	t.unprocessed = append(t.unprocessed, c)
+	t.unprocessedWeight += c.Weight
+	if c.Mean < t.min {
+		t.min = c.Mean
+	}
+	if c.Mean > t.max {
+		t.max = c.Mean
+	}
+
+	if len(t.unprocessed) > t.maxUnprocessed() {
+		t.process()
+	}
+}
+
+// Merge adds the centroids of another digest to this one.
+func (t *TDigest) Merge(other *TDigest) {
+	for _, c := range other.processed {
+		t.add(c)
+	}
+	for _, c := range other.unprocessed {
+		t.add(c)
+	}
+}
+
+// Count returns the total weight of the values added to the digest.
+func (t *TDigest) Count() float64 {
+	return t.processedWeight + t.unprocessedWeight
+}
+
+// Centroids returns the compressed centroids of the digest.
+func (t *TDigest) Centroids() []Centroid {
+	t.process()
+	return t.processed
+}
+
+// Quantile returns an estimate of the value at quantile q, which must be
+// between 0 and 1. It returns NaN if the digest is empty.
+func (t *TDigest) Quantile(q float64) float64 {
+	t.process()
+
+	n := len(t.processed)
+	if n == 0 || math.IsNaN(q) {
+		return math.NaN()
+	} else if n == 1 {
+		return t.processed[0].Mean
+	} else if q <= 0 {
+		return t.min
+	} else if q >= 1 {
+		return t.max
+	}
+
+	// Each centroid is centered at half of its weight past the weight of
+	// the centroids before it. Interpolate between the two centroids that
+	// surround the index of the quantile.
+	index := q * t.processedWeight
+	first := t.processed[0]
+	if index < first.Weight/2 {
+		return t.min + index/(first.Weight/2)*(first.Mean-t.min)
+	}
+
+	var cumulative float64
+	for i := 0; i < n-1; i++ {
+		c, next := t.processed[i], t.processed[i+1]
+		left := cumulative + c.Weight/2
+		right := cumulative + c.Weight + next.Weight/2
+		if index < right {
+			return c.Mean + (index-left)/(right-left)*(next.Mean-c.Mean)
+		}
+		cumulative += c.Weight
+	}
+
+	last := t.processed[n-1]
+	left := t.processedWeight - last.Weight/2
+	return last.Mean + (index-left)/(last.Weight/2)*(t.max-last.Mean)
+}
+
+// maxUnprocessed returns the number of centroids buffered before they are
+// compressed.
+func (t *TDigest) maxUnprocessed() int {
+	return int(8 * math.Ceil(t.compression))
+}
+
+// process merges the unprocessed centroids into the processed centroids.
+func (t *TDigest) process() {
+	if len(t.unprocessed) == 0 {
+		return
+	}
+
+	all := append(t.unprocessed, t.processed...)
+	sort.Sort(centroidsByMean(all))
+
+	total := t.processedWeight + t.unprocessedWeight
+	processed := make([]Centroid, 0, len(t.processed)+1)
+	processed = append(processed, all[0])
+
+	// A centroid may only grow while it spans at most one unit of the scale
+	// function between the quantiles at its edges.
+	var before float64
+	for _, c := range all[1:] {
+		last := &processed[len(processed)-1]
+		proposed := last.Weight + c.Weight
+		if t.scale((before+proposed)/total)-t.scale(before/total) <= 1 {
+			last.Mean += (c.Mean - last.Mean) * c.Weight / proposed
+			last.Weight = proposed
+			continue
+		}
+		before += last.Weight
+		processed = append(processed, c)
+	}
+
+	t.processed = processed
+	t.processedWeight = total
+	t.unprocessed = t.unprocessed[:0]
+	t.unprocessedWeight = 0
+}
+
+// scale maps quantile q to the scale function k1 of the paper. The function
+// is steepest near the tails so the centroids there are the smallest.
+func (t *TDigest) scale(q float64) float64 {
+	return t.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// MarshalBinary encodes the digest into a binary representation.
+func (t *TDigest) MarshalBinary() ([]byte, error) {
+	t.process()
+
+	buf := make([]byte, 1+3*8+4+16*len(t.processed))
+	buf[0] = version
+	binary.BigEndian.PutUint64(buf[1:], math.Float64bits(t.compression))
+	binary.BigEndian.PutUint64(buf[9:], math.Float64bits(t.min))
+	binary.BigEndian.PutUint64(buf[17:], math.Float64bits(t.max))
+	binary.BigEndian.PutUint32(buf[25:], uint32(len(t.processed)))
+
+	b := buf[29:]
+	for _, c := range t.processed {
+		binary.BigEndian.PutUint64(b, math.Float64bits(c.Mean))
+		binary.BigEndian.PutUint64(b[8:], math.Float64bits(c.Weight))
+		b = b[16:]
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a binary representation of a digest.
+func (t *TDigest) UnmarshalBinary(data []byte) error {
+	if len(data) < 29 {
+		return errors.New("tdigest: data too short")
+	} else if data[0] != version {
+		return fmt.Errorf("tdigest: unsupported version: %d", data[0])
+	}
+
+	n := int(binary.BigEndian.Uint32(data[25:]))
+	if len(data) != 29+16*n {
+		return fmt.Errorf("tdigest: invalid length for %d centroids: %d", n, len(data))
+	}
+
+	*t = TDigest{
+		compression: math.Float64frombits(binary.BigEndian.Uint64(data[1:])),
+		min:         math.Float64frombits(binary.BigEndian.Uint64(data[9:])),
+		max:         math.Float64frombits(binary.BigEndian.Uint64(data[17:])),
+		processed:   make([]Centroid, n),
+	}
+
+	b := data[29:]
+	for i := range t.processed {
+		c := Centroid{
+			Mean:   math.Float64frombits(binary.BigEndian.Uint64(b)),
+			Weight: math.Float64frombits(binary.BigEndian.Uint64(b[8:])),
+		}
+		t.processed[i] = c
+		t.processedWeight += c.Weight
+		b = b[16:]
+	}
+	return nil
+}
+
+type centroidsByMean []Centroid
+
+func (a centroidsByMean) Len() int           { return len(a) }
+func (a centroidsByMean) Less(i, j int) bool { return a[i].Mean < a[j].Mean }
+func (a centroidsByMean) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -0,0 +1,114 @@
+package tdigest_test
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/influxdata/influxdb/pkg/tdigest"
+)
+
+// Ensure quantiles of a uniform distribution are estimated accurately.
+func TestTDigest_Quantile(t *testing.T) {
+	rnd := rand.New(rand.NewSource(0))
+	td := tdigest.New()
+	values := make([]float64, 100000)
+	for i := range values {
+		values[i] = rnd.Float64() * 1000
+		td.Add(values[i], 1)
+	}
+	sort.Float64s(values)
+
+	for _, q := range []float64{0.001, 0.01, 0.1, 0.5, 0.9, 0.99, 0.999} {
+		exp := values[int(q*float64(len(values)))]
+		if got := td.Quantile(q); math.Abs(got-exp) > 0.01*1000 {
+			t.Errorf("quantile %v: got=%v exp=%v", q, got, exp)
+		}
+	}
+
+	if got, exp := td.Quantile(0), values[0]; got != exp {
+		t.Errorf("min: got=%v exp=%v", got, exp)
+	} else if got, exp := td.Quantile(1), values[len(values)-1]; got != exp {
+		t.Errorf("max: got=%v exp=%v", got, exp)
+	} else if got := td.Count(); got != float64(len(values)) {
+		t.Errorf("unexpected count: %v", got)
+	}
+
+	// The number of centroids is bounded by the compression.
+	if n := len(td.Centroids()); n > 2*tdigest.DefaultCompression {
+		t.Errorf("too many centroids: %d", n)
+	}
+}
+
+// Ensure an empty digest and a digest with a single value can be queried.
+func TestTDigest_Quantile_Small(t *testing.T) {
+	td := tdigest.New()
+	if got := td.Quantile(0.5); !math.IsNaN(got) {
+		t.Fatalf("expected NaN, got %v", got)
+	}
+
+	td.Add(3, 1)
+	if got := td.Quantile(0.99); got != 3 {
+		t.Fatalf("unexpected quantile: %v", got)
+	}
+
+	td.Add(math.NaN(), 1)
+	td.Add(5, 0)
+	if got := td.Count(); got != 1 {
+		t.Fatalf("unexpected count: %v", got)
+	}
+}
+
+// Ensure digests of parts of a distribution merge into the digest of the whole.
+func TestTDigest_Merge(t *testing.T) {
+	rnd := rand.New(rand.NewSource(0))
+	whole := tdigest.New()
+	parts := []*tdigest.TDigest{tdigest.New(), tdigest.New(), tdigest.New()}
+	for i := 0; i < 30000; i++ {
+		v := rnd.NormFloat64()*10 + 50
+		whole.Add(v, 1)
+		parts[i%len(parts)].Add(v, 1)
+	}
+
+	merged := tdigest.New()
+	for _, td := range parts {
+		merged.Merge(td)
+	}
+
+	if got, exp := merged.Count(), whole.Count(); got != exp {
+		t.Fatalf("unexpected count: got=%v exp=%v", got, exp)
+	}
+	for _, q := range []float64{0.01, 0.5, 0.99} {
+		if got, exp := merged.Quantile(q), whole.Quantile(q); math.Abs(got-exp) > 0.5 {
+			t.Errorf("quantile %v: got=%v exp=%v", q, got, exp)
+		}
+	}
+}
+
+// Ensure a digest can be encoded and decoded.
+func TestTDigest_MarshalBinary(t *testing.T) {
+	td := tdigest.New()
+	for i := 0; i < 1000; i++ {
+		td.Add(float64(i), 1)
+	}
+
+	data, err := td.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var other tdigest.TDigest
+	if err := other.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(other.Centroids(), td.Centroids()) {
+		t.Fatal("centroids mismatch")
+	} else if got, exp := other.Quantile(0.99), td.Quantile(0.99); got != exp {
+		t.Fatalf("unexpected quantile: got=%v exp=%v", got, exp)
+	}
+
+	if err := other.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Fatal("expected error")
+	}
+}
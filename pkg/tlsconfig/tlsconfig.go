@@ -0,0 +1,51 @@
+// Package tlsconfig builds the TLS configurations of listeners from PEM files.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// Server returns the TLS configuration of a listener with the certificate and
+// private key of the PEM files.  If privateKey is empty, the key is read from
+// the certificate file.  If clientCA is set, clients must present a
+// certificate signed by one of the certificate authorities of its PEM file.
+func Server(certificate, privateKey, clientCA string) (*tls.Config, error) {
+	if privateKey == "" {
+		privateKey = certificate
+	}
+
+	cert, err := tls.LoadX509KeyPair(certificate, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if clientCA != "" {
+		pool, err := certPool(clientCA)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// certPool returns a pool of the certificates of a PEM file.
+func certPool(path string) (*x509.CertPool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,186 @@
+package tlsconfig_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/pkg/tlsconfig"
+)
+
+func TestServer(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	cert, key := MustWriteCertificate(dir, "server")
+
+	// The private key can be in its own file or in the certificate file.
+	if _, err := tlsconfig.Server(cert, key, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	combined := filepath.Join(dir, "combined.pem")
+	MustConcatFiles(combined, cert, key)
+	config, err := tlsconfig.Server(combined, "", "")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(config.Certificates) != 1 {
+		t.Fatalf("unexpected certificates: %d", len(config.Certificates))
+	} else if config.ClientAuth != tls.NoClientCert {
+		t.Fatalf("unexpected client auth: %v", config.ClientAuth)
+	}
+
+	if _, err := tlsconfig.Server(filepath.Join(dir, "missing.pem"), "", ""); err == nil {
+		t.Fatal("expected error for missing certificate")
+	}
+	if _, err := tlsconfig.Server(cert, key, key); err == nil {
+		t.Fatal("expected error for client CA without certificates")
+	}
+}
+
+func TestServer_ClientCA(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	cert, key := MustWriteCertificate(dir, "server")
+	clientCert, clientKey := MustWriteCertificate(dir, "client")
+	otherCert, otherKey := MustWriteCertificate(dir, "other")
+
+	config, err := tlsconfig.Server(cert, key, clientCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	dial := func(certs ...tls.Certificate) error {
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+			Certificates:       certs,
+			InsecureSkipVerify: true,
+		})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		// The server rejects certificates after the client's handshake is
+		// done, so read until the connection is closed.
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, err = conn.Read(make([]byte, 1))
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	if err := dial(MustLoadKeyPair(clientCert, clientKey)); err != nil {
+		t.Fatalf("unexpected error for trusted client certificate: %s", err)
+	}
+	if err := dial(MustLoadKeyPair(otherCert, otherKey)); err == nil {
+		t.Fatal("expected error for untrusted client certificate")
+	}
+	if err := dial(); err == nil {
+		t.Fatal("expected error without client certificate")
+	}
+}
+
+// MustTempDir returns a temporary directory.
+func MustTempDir() string {
+	dir, err := ioutil.TempDir("", "tlsconfig-")
+	if err != nil {
+		panic(err)
+	}
+	return dir
+}
+
+// MustWriteCertificate writes a self-signed certificate for 127.0.0.1 and its
+// private key to PEM files in dir, and returns their paths.
+func MustWriteCertificate(dir, name string) (cert, key string) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		panic(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		panic(err)
+	}
+
+	cert, key = filepath.Join(dir, name+".pem"), filepath.Join(dir, name+".key")
+	MustWritePEM(cert, "CERTIFICATE", der)
+	MustWritePEM(key, "EC PRIVATE KEY", keyDER)
+	return cert, key
+}
+
+// MustWritePEM writes a PEM block to path.
+func MustWritePEM(path, typ string, b []byte) {
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b}), 0600); err != nil {
+		panic(err)
+	}
+}
+
+// MustConcatFiles writes the contents of paths to dst.
+func MustConcatFiles(dst string, paths ...string) {
+	var b []byte
+	for _, path := range paths {
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			panic(err)
+		}
+		b = append(b, buf...)
+	}
+	if err := ioutil.WriteFile(dst, b, 0600); err != nil {
+		panic(err)
+	}
+}
+
+// MustLoadKeyPair loads the certificate and private key of PEM files.
+func MustLoadKeyPair(cert, key string) tls.Certificate {
+	c, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
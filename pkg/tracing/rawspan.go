@@ -13,6 +13,7 @@ type RawSpan struct {
 	ParentSpanID uint64        // ParentSpanID identifies the parent of this span or 0 if this is the root span.
 	Name         string        // Name is the operation name given to this span.
 	Start        time.Time     // Start identifies the start time of the span.
+	Duration     time.Duration // Duration is the time from Start until the span finished. It is not encoded for remote nodes.
 	Labels       labels.Labels // Labels contains additional metadata about this span.
 	Fields       fields.Fields // Fields contains typed values associated with this span.
 }
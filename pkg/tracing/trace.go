@@ -80,6 +80,19 @@ func (t *Trace) Tree() *TreeNode {
 	return nil
 }
 
+// Spans returns the finished spans of the trace, ordered by start time.
+func (t *Trace) Spans() []RawSpan {
+	t.mu.Lock()
+	spans := make([]RawSpan, 0, len(t.spans))
+	for _, s := range t.spans {
+		spans = append(spans, s)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start.Before(spans[j].Start) })
+	return spans
+}
+
 // Merge combines other with the current trace. This is
 // typically necessary when traces are transferred from a remote.
 func (t *Trace) Merge(other *Trace) {
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/influxdata/influxdb/influxql"
@@ -17,13 +19,65 @@ const (
 
 	// fieldName is the field all prometheus values get written to
 	fieldName = "f64"
+
+	// metricNameLabel is the label holding the name of a Prometheus metric.
+	metricNameLabel = "__name__"
 )
 
 var ErrNaNDropped = errors.New("dropped NaN from Prometheus since they are not supported")
 
+// Schema holds the rules that map Prometheus time series to InfluxDB series.
+type Schema struct {
+	// Measurement is the measurement all samples are written to. If empty,
+	// the samples of each metric are written to a measurement named after
+	// the metric, and the __name__ label is not written as a tag.
+	Measurement string
+
+	// MeasurementPrefix is prepended to metric names used as measurement
+	// names.
+	MeasurementPrefix string
+
+	// Field is the field sample values are written to.
+	Field string
+
+	// DropLabels are labels that are not written as tags.
+	DropLabels []string
+}
+
+// DefaultSchema writes all samples to the "f64" field of the "_" measurement.
+var DefaultSchema = Schema{Measurement: measurementName, Field: fieldName}
+
+// Validate returns an error if the schema is invalid.
+func (s *Schema) Validate() error {
+	if s.Field == "" {
+		return errors.New("prometheus field name must not be empty")
+	} else if s.Measurement != "" && s.MeasurementPrefix != "" {
+		return errors.New("prometheus measurement prefix requires measurements named after metrics")
+	}
+	return nil
+}
+
+func (s *Schema) dropLabel(name string) bool {
+	if s.Measurement == "" && name == metricNameLabel {
+		return true
+	}
+	for _, l := range s.DropLabels {
+		if l == name {
+			return true
+		}
+	}
+	return false
+}
+
 // WriteRequestToPoints converts a Prometheus remote write request of time series and their
-// samples into Points that can be written into Influx
+// samples into Points that can be written into Influx using the default schema.
 func WriteRequestToPoints(req *remote.WriteRequest) ([]models.Point, error) {
+	return DefaultSchema.WriteRequestToPoints(req)
+}
+
+// WriteRequestToPoints converts a Prometheus remote write request of time series and their
+// samples into Points that can be written into Influx
+func (s *Schema) WriteRequestToPoints(req *remote.WriteRequest) ([]models.Point, error) {
 	var maxPoints int
 	for _, ts := range req.Timeseries {
 		maxPoints += len(ts.Samples)
@@ -33,22 +87,31 @@ func WriteRequestToPoints(req *remote.WriteRequest) ([]models.Point, error) {
 	var droppedNaN error
 
 	for _, ts := range req.Timeseries {
+		name := s.Measurement
 		tags := make(map[string]string, len(ts.Labels))
 		for _, l := range ts.Labels {
-			tags[l.Name] = l.Value
+			if s.Measurement == "" && l.Name == metricNameLabel {
+				name = s.MeasurementPrefix + l.Value
+			}
+			if !s.dropLabel(l.Name) {
+				tags[l.Name] = l.Value
+			}
+		}
+		if name == "" {
+			return nil, errors.New("time series has no metric name")
 		}
 
-		for _, s := range ts.Samples {
+		for _, sample := range ts.Samples {
 			// skip NaN values, which are valid in Prometheus
-			if math.IsNaN(s.Value) {
+			if math.IsNaN(sample.Value) {
 				droppedNaN = ErrNaNDropped
 				continue
 			}
 
 			// convert and append
-			t := time.Unix(0, s.TimestampMs*int64(time.Millisecond))
-			fields := map[string]interface{}{fieldName: s.Value}
-			p, err := models.NewPoint(measurementName, models.NewTags(tags), fields, t)
+			t := time.Unix(0, sample.TimestampMs*int64(time.Millisecond))
+			fields := map[string]interface{}{s.Field: sample.Value}
+			p, err := models.NewPoint(name, models.NewTags(tags), fields, t)
 			if err != nil {
 				return nil, err
 			}
@@ -60,27 +123,61 @@ func WriteRequestToPoints(req *remote.WriteRequest) ([]models.Point, error) {
 }
 
 // ReadRequestToInfluxQLQuery converts a Prometheus remote read request to an equivalent InfluxQL
-// query that will return the requested data when executed
+// query that will return the requested data when executed using the default schema.
 func ReadRequestToInfluxQLQuery(req *remote.ReadRequest, db, rp string) (*influxql.Query, error) {
+	return DefaultSchema.ReadRequestToInfluxQLQuery(req, db, rp)
+}
+
+// ReadRequestToInfluxQLQuery converts a Prometheus remote read request to an equivalent InfluxQL
+// query that will return the requested data when executed
+func (s *Schema) ReadRequestToInfluxQLQuery(req *remote.ReadRequest, db, rp string) (*influxql.Query, error) {
 	if len(req.Queries) != 1 {
 		return nil, errors.New("Prometheus read endpoint currently only supports one query at a time")
 	}
 	promQuery := req.Queries[0]
 
+	source := &influxql.Measurement{
+		Name:            s.Measurement,
+		Database:        db,
+		RetentionPolicy: rp,
+	}
+	matchers := promQuery.Matchers
+	if s.Measurement == "" {
+		// The metric name is the measurement name, so the __name__ matcher
+		// selects the measurements instead of being a condition.
+		matchers = make([]*remote.LabelMatcher, 0, len(promQuery.Matchers))
+		source.Regex = &influxql.RegexLiteral{Val: regexp.MustCompile("^" + regexp.QuoteMeta(s.MeasurementPrefix))}
+		for _, m := range promQuery.Matchers {
+			if m.Name != metricNameLabel {
+				matchers = append(matchers, m)
+				continue
+			}
+
+			switch m.Type {
+			case remote.MatchType_EQUAL:
+				source.Name, source.Regex = s.MeasurementPrefix+m.Value, nil
+			case remote.MatchType_REGEX_MATCH:
+				re, err := regexp.Compile("^" + regexp.QuoteMeta(s.MeasurementPrefix) + "(?:" + m.Value + ")$")
+				if err != nil {
+					return nil, err
+				}
+				source.Regex = &influxql.RegexLiteral{Val: re}
+			default:
+				return nil, fmt.Errorf("%s matcher type %v is not supported when metrics are written to their own measurements", metricNameLabel, m.Type)
+			}
+		}
+	}
+
 	stmt := &influxql.SelectStatement{
 		IsRawQuery: true,
 		Fields: []*influxql.Field{
-			{Expr: &influxql.VarRef{Val: fieldName}},
+			{Expr: &influxql.VarRef{Val: s.Field}},
 		},
-		Sources: []influxql.Source{&influxql.Measurement{
-			Name:            measurementName,
-			Database:        db,
-			RetentionPolicy: rp,
-		}},
+		Sources:    []influxql.Source{source},
 		Dimensions: []*influxql.Dimension{{Expr: &influxql.Wildcard{}}},
 	}
 
-	cond, err := condFromMatchers(promQuery, promQuery.Matchers)
+	cond, err := condFromMatchers(promQuery, matchers)
 	if err != nil {
 		return nil, err
 	}
@@ -115,8 +212,7 @@ func condFromMatcher(m *remote.LabelMatcher) (*influxql.BinaryExpr, error) {
 
 // condFromMatchers converts a Prometheus remote query and a collection of Prometheus label matchers
 // into an equivalent influxql.BinaryExpr. This assume a schema that is written via the Prometheus
-// remote write endpoint, which uses a measurement name of _ and a field name of f64. Tags and labels
-// are kept equivalent.
+// remote write endpoint, where tags and labels are kept equivalent.
 func condFromMatchers(q *remote.Query, matchers []*remote.LabelMatcher) (*influxql.BinaryExpr, error) {
 	if len(matchers) > 0 {
 		lhs, err := condFromMatcher(matchers[0])
@@ -150,6 +246,18 @@ func condFromMatchers(q *remote.Query, matchers []*remote.LabelMatcher) (*influx
 	}, nil
 }
 
+// LabelPairs converts the name and tags of a series into a slice of Prometheus label pairs.
+func (s *Schema) LabelPairs(name string, tags map[string]string) []*remote.LabelPair {
+	pairs := TagsToLabelPairs(tags)
+	if s.Measurement == "" {
+		pairs = append(pairs, &remote.LabelPair{
+			Name:  metricNameLabel,
+			Value: strings.TrimPrefix(name, s.MeasurementPrefix),
+		})
+	}
+	return pairs
+}
+
 // TagsToLabelPairs converts a map of Influx tags into a slice of Prometheus label pairs
 func TagsToLabelPairs(tags map[string]string) []*remote.LabelPair {
 	pairs := make([]*remote.LabelPair, 0, len(tags))
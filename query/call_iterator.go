@@ -52,6 +52,14 @@ func NewCallIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
 		return newLastIterator(input, opt)
 	case "mean":
 		return newMeanIterator(input, opt)
+	case "count_hll":
+		return newCountHLLIterator(input, opt)
+	case "merge_hll":
+		return newMergeHLLIterator(input, opt)
+	case "percentile_tdigest":
+		return newPercentileTDigestIterator(input, opt)
+	case "merge_tdigest":
+		return newMergeTDigestIterator(input, opt)
 	default:
 		return nil, fmt.Errorf("unsupported function call: %s", name)
 	}
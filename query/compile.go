@@ -163,6 +163,11 @@ func (c *compiledStatement) compile(stmt *influxql.SelectStatement) error {
 	if err := c.validateFields(); err != nil {
 		return err
 	}
+	if stmt.Join {
+		if err := validateJoinSources(stmt.Sources); err != nil {
+			return err
+		}
+	}
 
 	// Look through the sources and compile each of the subqueries (if they exist).
 	// We do this after compiling the outside because subqueries may require
@@ -241,12 +246,21 @@ func (c *compiledField) compileExpr(expr influxql.Expr) error {
 		c.global.HasAuxiliaryFields = true
 		return nil
 	case *influxql.Call:
+		// Math functions are applied to each point and are not aggregates.
+		if influxql.IsMathFunction(expr) {
+			return c.compileMathFunction(expr)
+		}
+
 		// Register the function call in the list of function calls.
 		c.global.FunctionCalls = append(c.global.FunctionCalls, expr)
 
 		switch expr.Name {
 		case "percentile":
-			return c.compilePercentile(expr.Args)
+			return c.compilePercentile(expr.Name, expr.Args)
+		case "percentile_tdigest":
+			// The percentile is estimated so it does not select a point.
+			c.global.OnlySelectors = false
+			return c.compilePercentile(expr.Name, expr.Args)
 		case "sample":
 			return c.compileSample(expr.Args)
 		case "distinct":
@@ -334,10 +348,14 @@ func (c *compiledField) compileFunction(expr *influxql.Call) error {
 	switch expr.Name {
 	case "max", "min", "first", "last":
 		// top/bottom are not included here since they are not typical functions.
-	case "count", "sum", "mean", "median", "mode", "stddev", "spread":
+	case "count", "sum", "mean", "median", "mode", "stddev", "spread", "count_hll":
 		// These functions are not considered selectors.
 		c.global.OnlySelectors = false
 	default:
+		if _, ok := lookupAggregateFunction(expr.Name); ok {
+			c.global.OnlySelectors = false
+			return c.compileUserFunction(expr)
+		}
 		return fmt.Errorf("undefined function %s()", expr.Name)
 	}
 
@@ -358,18 +376,93 @@ func (c *compiledField) compileFunction(expr *influxql.Call) error {
 	return c.compileSymbol(expr.Name, expr.Args[0])
 }
 
-func (c *compiledField) compilePercentile(args []influxql.Expr) error {
+func (c *compiledField) compileMathFunction(expr *influxql.Call) error {
+	// Wildcards are not expanded inside of math functions in the same way
+	// they are not expanded inside of binary expressions.
+	c.AllowWildcard = false
+
+	switch expr.Name {
+	case "abs", "ceil", "floor", "round", "sqrt":
+		if exp, got := 1, len(expr.Args); exp != got {
+			return fmt.Errorf("invalid number of arguments for %s, expected %d, got %d", expr.Name, exp, got)
+		}
+	case "log":
+		if got := len(expr.Args); got < 1 || got > 2 {
+			return fmt.Errorf("invalid number of arguments for log, expected at least 1 but no more than 2, got %d", got)
+		} else if got == 2 {
+			if _, ok := numberLiteral(expr.Args[1]); !ok {
+				return errors.New("second argument to log() must be a number")
+			}
+		}
+	case "pow":
+		if exp, got := 2, len(expr.Args); exp != got {
+			return fmt.Errorf("invalid number of arguments for pow, expected %d, got %d", exp, got)
+		} else if _, ok := numberLiteral(expr.Args[1]); !ok {
+			return errors.New("second argument to pow() must be a number")
+		}
+	case "if":
+		if exp, got := 3, len(expr.Args); exp != got {
+			return fmt.Errorf("invalid number of arguments for if, expected %d, got %d", exp, got)
+		}
+		for _, arg := range expr.Args[1:] {
+			if _, ok := arg.(influxql.Literal); ok {
+				if _, ok := numberLiteral(arg); !ok {
+					return fmt.Errorf("expected number argument in if(), got %s", arg)
+				}
+			}
+		}
+	default:
+		if _, ok := lookupScalarFunction(expr.Name); ok {
+			return c.compileUserFunction(expr)
+		}
+	}
+
+	// The first argument is what the function is applied to so it must not
+	// be a literal. The remaining arguments may be literals.
+	if _, ok := expr.Args[0].(influxql.Literal); ok {
+		return fmt.Errorf("expected field argument in %s()", expr.Name)
+	}
+	for _, arg := range expr.Args {
+		if _, ok := arg.(influxql.Literal); ok {
+			continue
+		}
+		if err := c.compileExpr(arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileUserFunction validates a call to a user-defined function. The
+// function is applied to the first argument and the remaining arguments are
+// passed to the function as literals.
+func (c *compiledField) compileUserFunction(expr *influxql.Call) error {
+	if len(expr.Args) == 0 {
+		return fmt.Errorf("invalid number of arguments for %s, expected at least 1, got 0", expr.Name)
+	} else if _, err := userFunctionArgs(expr); err != nil {
+		return err
+	}
+
+	if _, ok := expr.Args[0].(influxql.Literal); ok {
+		return fmt.Errorf("expected field argument in %s()", expr.Name)
+	} else if aggregate, _ := influxql.LookupUserFunction(expr.Name); aggregate {
+		return c.compileSymbol(expr.Name, expr.Args[0])
+	}
+	return c.compileExpr(expr.Args[0])
+}
+
+func (c *compiledField) compilePercentile(name string, args []influxql.Expr) error {
 	if exp, got := 2, len(args); got != exp {
-		return fmt.Errorf("invalid number of arguments for percentile, expected %d, got %d", exp, got)
+		return fmt.Errorf("invalid number of arguments for %s, expected %d, got %d", name, exp, got)
 	}
 
 	switch args[1].(type) {
 	case *influxql.IntegerLiteral:
 	case *influxql.NumberLiteral:
 	default:
-		return fmt.Errorf("expected float argument in percentile()")
+		return fmt.Errorf("expected float argument in %s()", name)
 	}
-	return c.compileSymbol("percentile", args[0])
+	return c.compileSymbol(name, args[0])
 }
 
 func (c *compiledField) compileSample(args []influxql.Expr) error {
@@ -750,6 +843,13 @@ func (c *compiledStatement) validateFields() error {
 // subquery compiles and validates a compiled statement for the subquery using
 // this compiledStatement as the parent.
 func (c *compiledStatement) subquery(stmt *influxql.SelectStatement) error {
+	if stmt.Join {
+		return errors.New("JOIN is not supported in a subquery")
+	}
+	if err := validateSortFields(stmt); err != nil {
+		return err
+	}
+
 	subquery := newCompiler(c.Options)
 	if err := subquery.preprocess(stmt); err != nil {
 		return err
@@ -830,12 +930,21 @@ func (c *compiledStatement) Prepare(shardMapper ShardMapper, sopt SelectOptions)
 		return nil, err
 	}
 
+	// Joined sources are read through a single iterator creator that
+	// joins the points of each source.
+	if c.stmt.Join {
+		shards = newJoinShardGroup(shards, c.stmt.Sources)
+	}
+
 	// Rewrite wildcards, if any exist.
 	stmt, err := c.stmt.RewriteFields(shards)
 	if err != nil {
 		shards.Close()
 		return nil, err
 	}
+	if stmt.Join {
+		stmt.Sources = influxql.Sources{joinMeasurement(stmt.Sources)}
+	}
 
 	// Determine base options for iterators.
 	opt, err := newIteratorOptionsStmt(stmt, sopt)
@@ -868,6 +977,10 @@ func (c *compiledStatement) Prepare(shardMapper ShardMapper, sopt SelectOptions)
 	}
 
 	columns := stmt.ColumnNames()
+	if _, err := SortKeys(stmt, columns); err != nil {
+		shards.Close()
+		return nil, err
+	}
 	return &preparedStatement{
 		stmt:    stmt,
 		opt:     opt,
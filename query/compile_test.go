@@ -80,6 +80,15 @@ func TestCompile_Success(t *testing.T) {
 		`SELECT max(value) FROM (SELECT value + total FROM cpu) WHERE time >= now() - 1m GROUP BY time(10s)`,
 		`SELECT value FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time <= '2000-01-01T01:00:00Z'`,
 		`SELECT value FROM (SELECT value FROM cpu) ORDER BY time DESC`,
+		`SELECT abs(value), round(value), floor(value), ceil(value), sqrt(value) FROM cpu`,
+		`SELECT log(value), log(value, 10), pow(value, 2) FROM cpu`,
+		`SELECT if(value > 10, value, 0) FROM cpu`,
+		`SELECT abs(mean(value)) FROM cpu WHERE time >= now() - 1m GROUP BY time(10s)`,
+		`SELECT max(value), abs(total) FROM cpu`,
+		`SELECT count_hll(value), percentile_tdigest(value, 99.9) FROM cpu WHERE time >= now() - 1h GROUP BY time(1m), host`,
+		`SELECT count_hll(*) FROM cpu`,
+		`SELECT errors.value / requests.value FROM errors JOIN requests`,
+		`SELECT sum(errors.value) / sum(requests.value) FROM errors JOIN requests WHERE time >= now() - 1m GROUP BY time(10s), host`,
 	} {
 		t.Run(tt, func(t *testing.T) {
 			stmt, err := influxql.ParseStatement(tt)
@@ -197,6 +206,23 @@ func TestCompile_Failures(t *testing.T) {
 		{s: `SELECT field1 FROM foo group by time(1s)`, err: `GROUP BY requires at least one aggregate function`},
 		{s: `SELECT field1 FROM foo fill(none)`, err: `fill(none) must be used with a function`},
 		{s: `SELECT field1 FROM foo fill(linear)`, err: `fill(linear) must be used with a function`},
+		{s: `SELECT abs() FROM cpu`, err: `invalid number of arguments for abs, expected 1, got 0`},
+		{s: `SELECT abs(1) FROM cpu`, err: `expected field argument in abs()`},
+		{s: `SELECT abs(*) FROM cpu`, err: `unable to use wildcard in a binary expression`},
+		{s: `SELECT pow(value, total) FROM cpu`, err: `second argument to pow() must be a number`},
+		{s: `SELECT log(value, 2, 3) FROM cpu`, err: `invalid number of arguments for log, expected at least 1 but no more than 2, got 3`},
+		{s: `SELECT if(value > 1, value) FROM cpu`, err: `invalid number of arguments for if, expected 3, got 2`},
+		{s: `SELECT if(value > 1, 'a', 'b') FROM cpu`, err: `expected number argument in if(), got 'a'`},
+		{s: `SELECT value, abs(mean(value)) FROM cpu`, err: `mixing aggregate and non-aggregate queries is not supported`},
+		{s: `SELECT count_hll(value, 1) FROM cpu`, err: `invalid number of arguments for count_hll, expected 1, got 2`},
+		{s: `SELECT percentile_tdigest(value) FROM cpu`, err: `invalid number of arguments for percentile_tdigest, expected 2, got 1`},
+		{s: `SELECT percentile_tdigest(value, host) FROM cpu`, err: `expected float argument in percentile_tdigest()`},
+		{s: `SELECT percentile_tdigest(value, 90), value FROM cpu`, err: `mixing aggregate and non-aggregate queries is not supported`},
+		{s: `SELECT value FROM cpu JOIN /^m/`, err: `JOIN does not support regular expressions`},
+		{s: `SELECT value FROM cpu JOIN (SELECT value FROM mem)`, err: `JOIN does not support subqueries`},
+		{s: `SELECT value FROM cpu JOIN cpu`, err: `cannot JOIN measurement cpu with itself`},
+		{s: `SELECT value FROM (SELECT value FROM cpu JOIN mem)`, err: `JOIN is not supported in a subquery`},
+		{s: `SELECT abs(value) FROM cpu WHERE time >= now() - 1m GROUP BY time(10s)`, err: `GROUP BY requires at least one aggregate function`},
 		{s: `SELECT count(value), value FROM foo`, err: `mixing aggregate and non-aggregate queries is not supported`},
 		{s: `SELECT count(value) FROM foo group by time`, err: `time() is a function and expects at least one argument`},
 		{s: `SELECT count(value) FROM foo group by 'time'`, err: `only time and tag dimensions allowed`},
@@ -317,6 +343,7 @@ func TestCompile_Failures(t *testing.T) {
 		{s: `SELECT value FROM myseries WHERE value OR time >= now() - 1m`, err: `invalid condition expression: value`},
 		{s: `SELECT value FROM myseries WHERE time >= now() - 1m OR value`, err: `invalid condition expression: value`},
 		{s: `SELECT value FROM (SELECT value FROM cpu ORDER BY time DESC) ORDER BY time ASC`, err: `subqueries must be ordered in the same direction as the query itself`},
+		{s: `SELECT value FROM (SELECT value FROM cpu ORDER BY value DESC)`, err: `subqueries can only be sorted by time`},
 	} {
 		t.Run(tt.s, func(t *testing.T) {
 			stmt, err := influxql.ParseStatement(tt.s)
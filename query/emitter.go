@@ -29,6 +29,21 @@ type Emitter struct {
 	// Removes the "time" column from output.
 	// Used for meta queries where time does not apply.
 	OmitTime bool
+
+	// The columns the values of each row are sorted by. If set, every value
+	// of a series is read before its row is emitted.
+	SortKeys []SortKey
+
+	// The number of sorted values of each row to skip and to emit. They only
+	// apply when SortKeys is set.
+	Limit, Offset int
+
+	// The memory held by the values of a series being sorted is accounted to
+	// Memory, if set.
+	Memory *MemoryAccountant
+
+	sorter *rowSorter    // values of the series being sorted
+	sorted []*models.Row // sorted rows waiting to be emitted
 }
 
 // NewEmitter returns a new instance of Emitter that pulls from itrs.
@@ -44,6 +59,10 @@ func NewEmitter(itrs []Iterator, ascending bool, chunkSize int) *Emitter {
 
 // Close closes the underlying iterators.
 func (e *Emitter) Close() error {
+	if e.sorter != nil {
+		e.sorter.release()
+		e.sorter = nil
+	}
 	return Iterators(e.itrs).Close()
 }
 
@@ -54,6 +73,10 @@ func (e *Emitter) Emit() (*models.Row, bool, error) {
 		return nil, false, nil
 	}
 
+	if len(e.SortKeys) > 0 {
+		return e.emitSorted()
+	}
+
 	// Continually read from iterators until they are exhausted.
 	for {
 		// Fill buffer. Return row if no more points remain.
@@ -97,6 +120,68 @@ func (e *Emitter) Emit() (*models.Row, bool, error) {
 	}
 }
 
+// emitSorted returns the next row with its values sorted by the sort keys.
+func (e *Emitter) emitSorted() (*models.Row, bool, error) {
+	for len(e.sorted) == 0 {
+		t, name, tags, err := e.loadBuf()
+		if err != nil {
+			return nil, false, err
+		} else if t == ZeroTime {
+			if e.sorter == nil {
+				return nil, false, nil
+			}
+			e.flushSorted()
+			continue
+		}
+
+		// Sort the previous series once the values of another are read.
+		if e.sorter != nil && (e.sorter.name != name || !e.sorter.tags.Equals(&tags)) {
+			e.flushSorted()
+		}
+		if e.sorter == nil {
+			limit := 0
+			if e.Limit > 0 {
+				limit = e.Limit + e.Offset
+			}
+			e.sorter = newRowSorter(name, tags, e.SortKeys, limit, e.Memory)
+		}
+		if err := e.sorter.add(e.readAt(t, name, tags)); err != nil {
+			return nil, false, err
+		}
+	}
+
+	row := e.sorted[0]
+	e.sorted = e.sorted[1:]
+	return row, len(e.sorted) > 0 || e.sorter != nil, nil
+}
+
+// flushSorted sorts the values of the current series and splits them into
+// rows of at most the chunk size.
+func (e *Emitter) flushSorted() {
+	e.sorter.release()
+	values := e.sorter.sorted()
+	if e.Offset >= len(values) {
+		values = nil
+	} else {
+		values = values[e.Offset:]
+	}
+	if e.Limit > 0 && len(values) > e.Limit {
+		values = values[:e.Limit]
+	}
+
+	for len(values) > 0 {
+		n := len(values)
+		if e.chunkSize > 0 && n > e.chunkSize {
+			n = e.chunkSize
+		}
+		row := e.newRow(e.sorter.name, e.sorter.tags, values[:n])
+		row.Partial = n < len(values)
+		e.sorted = append(e.sorted, row)
+		values = values[n:]
+	}
+	e.sorter = nil
+}
+
 // loadBuf reads in points into empty buffer slots.
 // Returns the next time/name/tags to emit for.
 func (e *Emitter) loadBuf() (t int64, name string, tags Tags, err error) {
@@ -142,16 +227,21 @@ func (e *Emitter) loadBuf() (t int64, name string, tags Tags, err error) {
 
 // createRow creates a new row attached to the emitter.
 func (e *Emitter) createRow(name string, tags Tags, values []interface{}) {
+	e.tags = tags
+	e.row = e.newRow(name, tags, [][]interface{}{values})
+}
+
+// newRow returns a new row of values.
+func (e *Emitter) newRow(name string, tags Tags, values [][]interface{}) *models.Row {
 	if e.EmitName != "" {
 		name = e.EmitName
 	}
 
-	e.tags = tags
-	e.row = &models.Row{
+	return &models.Row{
 		Name:    name,
 		Tags:    tags.KeyValues(),
 		Columns: e.Columns,
-		Values:  [][]interface{}{values},
+		Values:  values,
 	}
 }
 
@@ -123,3 +123,104 @@ func TestEmitter_ChunkSize(t *testing.T) {
 		t.Fatalf("unexpected eof: %s", spew.Sdump(row))
 	}
 }
+
+// Ensure the emitter sorts the values of each series by the sort keys and
+// applies the limit and offset to the sorted values.
+func TestEmitter_SortKeys(t *testing.T) {
+	e := query.NewEmitter([]query.Iterator{
+		&FloatIterator{Points: []query.FloatPoint{
+			{Name: "cpu", Tags: ParseTags("region=north"), Time: 0, Value: 3},
+			{Name: "cpu", Tags: ParseTags("region=north"), Time: 1, Value: 1},
+			{Name: "cpu", Tags: ParseTags("region=north"), Time: 2, Nil: true},
+			{Name: "cpu", Tags: ParseTags("region=north"), Time: 3, Value: 5},
+			{Name: "cpu", Tags: ParseTags("region=north"), Time: 4, Value: 3},
+			{Name: "cpu", Tags: ParseTags("region=north"), Time: 5, Value: 4},
+			{Name: "cpu", Tags: ParseTags("region=west"), Time: 0, Value: 2},
+		}},
+	}, true, 1)
+	e.Columns = []string{"time", "value"}
+	e.SortKeys = []query.SortKey{{Index: 1, Ascending: false}}
+	e.Limit, e.Offset = 3, 1
+
+	// Values that sort equally are emitted in time order. Each row is split
+	// into chunks.
+	for i, exp := range []*models.Row{
+		{
+			Name:    "cpu",
+			Tags:    map[string]string{"region": "north"},
+			Columns: []string{"time", "value"},
+			Values:  [][]interface{}{{time.Unix(0, 5).UTC(), float64(4)}},
+			Partial: true,
+		},
+		{
+			Name:    "cpu",
+			Tags:    map[string]string{"region": "north"},
+			Columns: []string{"time", "value"},
+			Values:  [][]interface{}{{time.Unix(0, 0).UTC(), float64(3)}},
+			Partial: true,
+		},
+		{
+			Name:    "cpu",
+			Tags:    map[string]string{"region": "north"},
+			Columns: []string{"time", "value"},
+			Values:  [][]interface{}{{time.Unix(0, 4).UTC(), float64(3)}},
+		},
+	} {
+		if row, partial, err := e.Emit(); err != nil {
+			t.Fatalf("unexpected error(%d): %s", i, err)
+		} else if !partial {
+			t.Fatalf("expected more rows(%d)", i)
+		} else if !deep.Equal(row, exp) {
+			t.Fatalf("unexpected row(%d): %s", i, spew.Sdump(row))
+		}
+	}
+
+	// The only value of region=west is skipped by the offset.
+	if row, _, err := e.Emit(); err != nil {
+		t.Fatalf("unexpected error(eof): %s", err)
+	} else if row != nil {
+		t.Fatalf("unexpected eof: %s", spew.Sdump(row))
+	}
+}
+
+// Ensure the values held to sort a series count against the memory limit,
+// unless a limit bounds them.
+func TestEmitter_SortKeys_MaxMemory(t *testing.T) {
+	for _, tt := range []struct {
+		limit int
+		err   bool
+	}{
+		{limit: 0, err: true},
+		{limit: 2},
+	} {
+		// Each row of a time and a value is accounted 96 bytes.
+		mem := query.NewMemoryAccountant(300)
+		e := query.NewEmitter([]query.Iterator{
+			&FloatIterator{Points: []query.FloatPoint{
+				{Name: "cpu", Time: 0, Value: 3},
+				{Name: "cpu", Time: 1, Value: 1},
+				{Name: "cpu", Time: 2, Value: 5},
+				{Name: "cpu", Time: 3, Value: 4},
+			}},
+		}, true, 0)
+		e.Columns = []string{"time", "value"}
+		e.SortKeys = []query.SortKey{{Index: 1, Ascending: true}}
+		e.Limit = tt.limit
+		e.Memory = mem
+
+		_, _, err := e.Emit()
+		if tt.err {
+			if err == nil || err.Error() != "max-memory-per-query limit exceeded: (384/300)" {
+				t.Errorf("limit %d: unexpected error: %v", tt.limit, err)
+			}
+		} else if err != nil {
+			t.Errorf("limit %d: unexpected error: %s", tt.limit, err)
+		}
+
+		// The memory is released once the series is sorted, or the emitter closed.
+		e.Close()
+		if got := mem.Used(); got != 0 {
+			t.Errorf("limit %d: memory not released: %d", tt.limit, got)
+		}
+	}
+}
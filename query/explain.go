@@ -1,59 +1,36 @@
 package query
 
 import (
-	"bytes"
 	"context"
-	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/pkg/tracing"
+	"github.com/influxdata/influxdb/pkg/tracing/fields"
 )
 
+// Explain returns the iterator tree of the statement without executing it.
+// Each iterator created against the shards is annotated with its cost.
 func (p *preparedStatement) Explain() (string, error) {
+	t, span := tracing.NewTrace("select")
+	ctx := tracing.NewContextWithTrace(context.Background(), t)
+	ctx = tracing.NewContextWithSpan(ctx, span)
+
 	// Determine the cost of all iterators created as part of this plan.
 	ic := &explainIteratorCreator{ic: p.ic}
 	p.ic = ic
-	itrs, _, err := p.Select(context.Background())
+	itrs, _, err := p.Select(ctx)
 	p.ic = ic.ic
 
 	if err != nil {
 		return "", err
 	}
 	Iterators(itrs).Close()
+	span.Finish()
 
-	var buf bytes.Buffer
-	for i, node := range ic.nodes {
-		if i > 0 {
-			buf.WriteString("\n")
-		}
-
-		expr := "<nil>"
-		if node.Expr != nil {
-			expr = node.Expr.String()
-		}
-		fmt.Fprintf(&buf, "EXPRESSION: %s\n", expr)
-		if len(node.Aux) != 0 {
-			refs := make([]string, len(node.Aux))
-			for i, ref := range node.Aux {
-				refs[i] = ref.String()
-			}
-			fmt.Fprintf(&buf, "AUXILIARY FIELDS: %s\n", strings.Join(refs, ", "))
-		}
-		fmt.Fprintf(&buf, "NUMBER OF SHARDS: %d\n", node.Cost.NumShards)
-		fmt.Fprintf(&buf, "NUMBER OF SERIES: %d\n", node.Cost.NumSeries)
-		fmt.Fprintf(&buf, "CACHED VALUES: %d\n", node.Cost.CachedValues)
-		fmt.Fprintf(&buf, "NUMBER OF FILES: %d\n", node.Cost.NumFiles)
-		fmt.Fprintf(&buf, "NUMBER OF BLOCKS: %d\n", node.Cost.BlocksRead)
-		fmt.Fprintf(&buf, "SIZE OF BLOCKS: %d\n", node.Cost.BlockSize)
-	}
-	return buf.String(), nil
-}
-
-type planNode struct {
-	Expr influxql.Expr
-	Aux  []influxql.VarRef
-	Cost IteratorCost
+	return t.Tree().String(), nil
 }
 
 type explainIteratorCreator struct {
@@ -61,7 +38,6 @@ type explainIteratorCreator struct {
 		IteratorCreator
 		io.Closer
 	}
-	nodes []planNode
 }
 
 func (e *explainIteratorCreator) CreateIterator(ctx context.Context, m *influxql.Measurement, opt IteratorOptions) (Iterator, error) {
@@ -69,11 +45,41 @@ func (e *explainIteratorCreator) CreateIterator(ctx context.Context, m *influxql
 	if err != nil {
 		return nil, err
 	}
-	e.nodes = append(e.nodes, planNode{
-		Expr: opt.Expr,
-		Aux:  opt.Aux,
-		Cost: cost,
-	})
+
+	if span := tracing.SpanFromContext(ctx); span != nil {
+		span = span.StartSpan("create_iterator")
+		labels := []string{"measurement", m.String()}
+		if opt.Expr != nil {
+			labels = append(labels, "expr", opt.Expr.String())
+		}
+		if len(opt.Aux) != 0 {
+			refs := make([]string, len(opt.Aux))
+			for i, ref := range opt.Aux {
+				refs[i] = ref.String()
+			}
+			labels = append(labels, "aux", strings.Join(refs, ", "))
+		}
+		if opt.Condition != nil {
+			labels = append(labels, "cond", opt.Condition.String())
+		}
+		if len(cost.ShardIDs) != 0 {
+			ids := make([]string, len(cost.ShardIDs))
+			for i, id := range cost.ShardIDs {
+				ids[i] = strconv.FormatUint(id, 10)
+			}
+			labels = append(labels, "shard_ids", strings.Join(ids, ", "))
+		}
+		span.SetLabels(labels...)
+		span.SetFields(fields.New(
+			fields.Int64("number_of_shards", cost.NumShards),
+			fields.Int64("number_of_series", cost.NumSeries),
+			fields.Int64("cached_values", cost.CachedValues),
+			fields.Int64("number_of_files", cost.NumFiles),
+			fields.Int64("number_of_blocks", cost.BlocksRead),
+			fields.Int64("size_of_blocks", cost.BlockSize),
+		))
+		span.Finish()
+	}
 	return &nilFloatIterator{}, nil
 }
 
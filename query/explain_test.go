@@ -0,0 +1,56 @@
+package query_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/query"
+)
+
+func TestPreparedStatement_Explain(t *testing.T) {
+	shardMapper := ShardMapper{
+		MapShardsFn: func(sources influxql.Sources, _ influxql.TimeRange) query.ShardGroup {
+			return &ShardGroup{
+				Fields: map[string]influxql.DataType{
+					"value": influxql.Float,
+				},
+				Dimensions: []string{"host"},
+				IteratorCostFn: func(m *influxql.Measurement, opt query.IteratorOptions) (query.IteratorCost, error) {
+					a := query.IteratorCost{NumShards: 1, NumSeries: 2, BlocksRead: 4, ShardIDs: []uint64{1}}
+					b := query.IteratorCost{NumShards: 1, NumSeries: 3, BlocksRead: 5, ShardIDs: []uint64{2}}
+					return a.Combine(b), nil
+				},
+			}
+		},
+	}
+
+	stmt := MustParseSelectStatement(`SELECT mean(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:10Z' GROUP BY time(5s), host`)
+	p, err := query.Prepare(stmt, &shardMapper, query.SelectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	plan, err := p.Explain()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"select",
+		"field_iterators",
+		"expression",
+		"create_iterator",
+		"measurement: cpu",
+		"expr: mean(value::float)",
+		"shard_ids: 1, 2",
+		"number_of_shards: 2",
+		"number_of_series: 5",
+		"number_of_blocks: 9",
+	} {
+		if !strings.Contains(plan, want) {
+			t.Errorf("plan does not contain %q:\n%s", want, plan)
+		}
+	}
+}
@@ -111,6 +111,8 @@ func (r *FloatSliceFuncReducer) Emit() []FloatPoint {
 	return r.fn(r.points)
 }
 
+func (r *FloatSliceFuncReducer) bufferPoints() {}
+
 // FloatReduceIntegerFunc is the function called by a FloatPoint reducer.
 type FloatReduceIntegerFunc func(prev *IntegerPoint, curr *FloatPoint) (t int64, v int64, aux []interface{})
 
@@ -181,6 +183,8 @@ func (r *FloatSliceFuncIntegerReducer) Emit() []IntegerPoint {
 	return r.fn(r.points)
 }
 
+func (r *FloatSliceFuncIntegerReducer) bufferPoints() {}
+
 // FloatReduceUnsignedFunc is the function called by a FloatPoint reducer.
 type FloatReduceUnsignedFunc func(prev *UnsignedPoint, curr *FloatPoint) (t int64, v uint64, aux []interface{})
 
@@ -251,6 +255,8 @@ func (r *FloatSliceFuncUnsignedReducer) Emit() []UnsignedPoint {
 	return r.fn(r.points)
 }
 
+func (r *FloatSliceFuncUnsignedReducer) bufferPoints() {}
+
 // FloatReduceStringFunc is the function called by a FloatPoint reducer.
 type FloatReduceStringFunc func(prev *StringPoint, curr *FloatPoint) (t int64, v string, aux []interface{})
 
@@ -321,6 +327,8 @@ func (r *FloatSliceFuncStringReducer) Emit() []StringPoint {
 	return r.fn(r.points)
 }
 
+func (r *FloatSliceFuncStringReducer) bufferPoints() {}
+
 // FloatReduceBooleanFunc is the function called by a FloatPoint reducer.
 type FloatReduceBooleanFunc func(prev *BooleanPoint, curr *FloatPoint) (t int64, v bool, aux []interface{})
 
@@ -391,6 +399,8 @@ func (r *FloatSliceFuncBooleanReducer) Emit() []BooleanPoint {
 	return r.fn(r.points)
 }
 
+func (r *FloatSliceFuncBooleanReducer) bufferPoints() {}
+
 // FloatDistinctReducer returns the distinct points in a series.
 type FloatDistinctReducer struct {
 	m map[float64]FloatPoint
@@ -595,6 +605,8 @@ func (r *IntegerSliceFuncFloatReducer) Emit() []FloatPoint {
 	return r.fn(r.points)
 }
 
+func (r *IntegerSliceFuncFloatReducer) bufferPoints() {}
+
 // IntegerReduceFunc is the function called by a IntegerPoint reducer.
 type IntegerReduceFunc func(prev *IntegerPoint, curr *IntegerPoint) (t int64, v int64, aux []interface{})
 
@@ -665,6 +677,8 @@ func (r *IntegerSliceFuncReducer) Emit() []IntegerPoint {
 	return r.fn(r.points)
 }
 
+func (r *IntegerSliceFuncReducer) bufferPoints() {}
+
 // IntegerReduceUnsignedFunc is the function called by a IntegerPoint reducer.
 type IntegerReduceUnsignedFunc func(prev *UnsignedPoint, curr *IntegerPoint) (t int64, v uint64, aux []interface{})
 
@@ -735,6 +749,8 @@ func (r *IntegerSliceFuncUnsignedReducer) Emit() []UnsignedPoint {
 	return r.fn(r.points)
 }
 
+func (r *IntegerSliceFuncUnsignedReducer) bufferPoints() {}
+
 // IntegerReduceStringFunc is the function called by a IntegerPoint reducer.
 type IntegerReduceStringFunc func(prev *StringPoint, curr *IntegerPoint) (t int64, v string, aux []interface{})
 
@@ -805,6 +821,8 @@ func (r *IntegerSliceFuncStringReducer) Emit() []StringPoint {
 	return r.fn(r.points)
 }
 
+func (r *IntegerSliceFuncStringReducer) bufferPoints() {}
+
 // IntegerReduceBooleanFunc is the function called by a IntegerPoint reducer.
 type IntegerReduceBooleanFunc func(prev *BooleanPoint, curr *IntegerPoint) (t int64, v bool, aux []interface{})
 
@@ -875,6 +893,8 @@ func (r *IntegerSliceFuncBooleanReducer) Emit() []BooleanPoint {
 	return r.fn(r.points)
 }
 
+func (r *IntegerSliceFuncBooleanReducer) bufferPoints() {}
+
 // IntegerDistinctReducer returns the distinct points in a series.
 type IntegerDistinctReducer struct {
 	m map[int64]IntegerPoint
@@ -1079,6 +1099,8 @@ func (r *UnsignedSliceFuncFloatReducer) Emit() []FloatPoint {
 	return r.fn(r.points)
 }
 
+func (r *UnsignedSliceFuncFloatReducer) bufferPoints() {}
+
 // UnsignedReduceIntegerFunc is the function called by a UnsignedPoint reducer.
 type UnsignedReduceIntegerFunc func(prev *IntegerPoint, curr *UnsignedPoint) (t int64, v int64, aux []interface{})
 
@@ -1149,6 +1171,8 @@ func (r *UnsignedSliceFuncIntegerReducer) Emit() []IntegerPoint {
 	return r.fn(r.points)
 }
 
+func (r *UnsignedSliceFuncIntegerReducer) bufferPoints() {}
+
 // UnsignedReduceFunc is the function called by a UnsignedPoint reducer.
 type UnsignedReduceFunc func(prev *UnsignedPoint, curr *UnsignedPoint) (t int64, v uint64, aux []interface{})
 
@@ -1219,6 +1243,8 @@ func (r *UnsignedSliceFuncReducer) Emit() []UnsignedPoint {
 	return r.fn(r.points)
 }
 
+func (r *UnsignedSliceFuncReducer) bufferPoints() {}
+
 // UnsignedReduceStringFunc is the function called by a UnsignedPoint reducer.
 type UnsignedReduceStringFunc func(prev *StringPoint, curr *UnsignedPoint) (t int64, v string, aux []interface{})
 
@@ -1289,6 +1315,8 @@ func (r *UnsignedSliceFuncStringReducer) Emit() []StringPoint {
 	return r.fn(r.points)
 }
 
+func (r *UnsignedSliceFuncStringReducer) bufferPoints() {}
+
 // UnsignedReduceBooleanFunc is the function called by a UnsignedPoint reducer.
 type UnsignedReduceBooleanFunc func(prev *BooleanPoint, curr *UnsignedPoint) (t int64, v bool, aux []interface{})
 
@@ -1359,6 +1387,8 @@ func (r *UnsignedSliceFuncBooleanReducer) Emit() []BooleanPoint {
 	return r.fn(r.points)
 }
 
+func (r *UnsignedSliceFuncBooleanReducer) bufferPoints() {}
+
 // UnsignedDistinctReducer returns the distinct points in a series.
 type UnsignedDistinctReducer struct {
 	m map[uint64]UnsignedPoint
@@ -1563,6 +1593,8 @@ func (r *StringSliceFuncFloatReducer) Emit() []FloatPoint {
 	return r.fn(r.points)
 }
 
+func (r *StringSliceFuncFloatReducer) bufferPoints() {}
+
 // StringReduceIntegerFunc is the function called by a StringPoint reducer.
 type StringReduceIntegerFunc func(prev *IntegerPoint, curr *StringPoint) (t int64, v int64, aux []interface{})
 
@@ -1633,6 +1665,8 @@ func (r *StringSliceFuncIntegerReducer) Emit() []IntegerPoint {
 	return r.fn(r.points)
 }
 
+func (r *StringSliceFuncIntegerReducer) bufferPoints() {}
+
 // StringReduceUnsignedFunc is the function called by a StringPoint reducer.
 type StringReduceUnsignedFunc func(prev *UnsignedPoint, curr *StringPoint) (t int64, v uint64, aux []interface{})
 
@@ -1703,6 +1737,8 @@ func (r *StringSliceFuncUnsignedReducer) Emit() []UnsignedPoint {
 	return r.fn(r.points)
 }
 
+func (r *StringSliceFuncUnsignedReducer) bufferPoints() {}
+
 // StringReduceFunc is the function called by a StringPoint reducer.
 type StringReduceFunc func(prev *StringPoint, curr *StringPoint) (t int64, v string, aux []interface{})
 
@@ -1773,6 +1809,8 @@ func (r *StringSliceFuncReducer) Emit() []StringPoint {
 	return r.fn(r.points)
 }
 
+func (r *StringSliceFuncReducer) bufferPoints() {}
+
 // StringReduceBooleanFunc is the function called by a StringPoint reducer.
 type StringReduceBooleanFunc func(prev *BooleanPoint, curr *StringPoint) (t int64, v bool, aux []interface{})
 
@@ -1843,6 +1881,8 @@ func (r *StringSliceFuncBooleanReducer) Emit() []BooleanPoint {
 	return r.fn(r.points)
 }
 
+func (r *StringSliceFuncBooleanReducer) bufferPoints() {}
+
 // StringDistinctReducer returns the distinct points in a series.
 type StringDistinctReducer struct {
 	m map[string]StringPoint
@@ -2047,6 +2087,8 @@ func (r *BooleanSliceFuncFloatReducer) Emit() []FloatPoint {
 	return r.fn(r.points)
 }
 
+func (r *BooleanSliceFuncFloatReducer) bufferPoints() {}
+
 // BooleanReduceIntegerFunc is the function called by a BooleanPoint reducer.
 type BooleanReduceIntegerFunc func(prev *IntegerPoint, curr *BooleanPoint) (t int64, v int64, aux []interface{})
 
@@ -2117,6 +2159,8 @@ func (r *BooleanSliceFuncIntegerReducer) Emit() []IntegerPoint {
 	return r.fn(r.points)
 }
 
+func (r *BooleanSliceFuncIntegerReducer) bufferPoints() {}
+
 // BooleanReduceUnsignedFunc is the function called by a BooleanPoint reducer.
 type BooleanReduceUnsignedFunc func(prev *UnsignedPoint, curr *BooleanPoint) (t int64, v uint64, aux []interface{})
 
@@ -2187,6 +2231,8 @@ func (r *BooleanSliceFuncUnsignedReducer) Emit() []UnsignedPoint {
 	return r.fn(r.points)
 }
 
+func (r *BooleanSliceFuncUnsignedReducer) bufferPoints() {}
+
 // BooleanReduceStringFunc is the function called by a BooleanPoint reducer.
 type BooleanReduceStringFunc func(prev *StringPoint, curr *BooleanPoint) (t int64, v string, aux []interface{})
 
@@ -2257,6 +2303,8 @@ func (r *BooleanSliceFuncStringReducer) Emit() []StringPoint {
 	return r.fn(r.points)
 }
 
+func (r *BooleanSliceFuncStringReducer) bufferPoints() {}
+
 // BooleanReduceFunc is the function called by a BooleanPoint reducer.
 type BooleanReduceFunc func(prev *BooleanPoint, curr *BooleanPoint) (t int64, v bool, aux []interface{})
 
@@ -2327,6 +2375,8 @@ func (r *BooleanSliceFuncReducer) Emit() []BooleanPoint {
 	return r.fn(r.points)
 }
 
+func (r *BooleanSliceFuncReducer) bufferPoints() {}
+
 // BooleanDistinctReducer returns the distinct points in a series.
 type BooleanDistinctReducer struct {
 	m map[bool]BooleanPoint
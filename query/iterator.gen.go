@@ -1109,6 +1109,9 @@ type floatReduceFloatPoint struct {
 	Tags       Tags
 	Aggregator FloatPointAggregator
 	Emitter    FloatPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -1138,7 +1141,10 @@ func (itr *floatReduceFloatIterator) reduce() ([]FloatPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*floatReduceFloatPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -1170,6 +1176,12 @@ func (itr *floatReduceFloatIterator) reduce() ([]FloatPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &floatReduceFloatPoint{
 				Name:       curr.Name,
@@ -1177,8 +1189,15 @@ func (itr *floatReduceFloatIterator) reduce() ([]FloatPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateFloat(curr)
 	}
 
@@ -1524,6 +1543,9 @@ type floatReduceIntegerPoint struct {
 	Tags       Tags
 	Aggregator FloatPointAggregator
 	Emitter    IntegerPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -1553,7 +1575,10 @@ func (itr *floatReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*floatReduceIntegerPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -1585,6 +1610,12 @@ func (itr *floatReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &floatReduceIntegerPoint{
 				Name:       curr.Name,
@@ -1592,8 +1623,15 @@ func (itr *floatReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateFloat(curr)
 	}
 
@@ -1943,6 +1981,9 @@ type floatReduceUnsignedPoint struct {
 	Tags       Tags
 	Aggregator FloatPointAggregator
 	Emitter    UnsignedPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -1972,7 +2013,10 @@ func (itr *floatReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*floatReduceUnsignedPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -2004,6 +2048,12 @@ func (itr *floatReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &floatReduceUnsignedPoint{
 				Name:       curr.Name,
@@ -2011,8 +2061,15 @@ func (itr *floatReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateFloat(curr)
 	}
 
@@ -2362,6 +2419,9 @@ type floatReduceStringPoint struct {
 	Tags       Tags
 	Aggregator FloatPointAggregator
 	Emitter    StringPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -2391,7 +2451,10 @@ func (itr *floatReduceStringIterator) reduce() ([]StringPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*floatReduceStringPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -2423,6 +2486,12 @@ func (itr *floatReduceStringIterator) reduce() ([]StringPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &floatReduceStringPoint{
 				Name:       curr.Name,
@@ -2430,8 +2499,15 @@ func (itr *floatReduceStringIterator) reduce() ([]StringPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateFloat(curr)
 	}
 
@@ -2781,6 +2857,9 @@ type floatReduceBooleanPoint struct {
 	Tags       Tags
 	Aggregator FloatPointAggregator
 	Emitter    BooleanPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -2810,7 +2889,10 @@ func (itr *floatReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*floatReduceBooleanPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -2842,6 +2924,12 @@ func (itr *floatReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &floatReduceBooleanPoint{
 				Name:       curr.Name,
@@ -2849,8 +2937,15 @@ func (itr *floatReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateFloat(curr)
 	}
 
@@ -4509,6 +4604,9 @@ type integerReduceFloatPoint struct {
 	Tags       Tags
 	Aggregator IntegerPointAggregator
 	Emitter    FloatPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -4538,7 +4636,10 @@ func (itr *integerReduceFloatIterator) reduce() ([]FloatPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*integerReduceFloatPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -4570,6 +4671,12 @@ func (itr *integerReduceFloatIterator) reduce() ([]FloatPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &integerReduceFloatPoint{
 				Name:       curr.Name,
@@ -4577,8 +4684,15 @@ func (itr *integerReduceFloatIterator) reduce() ([]FloatPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateInteger(curr)
 	}
 
@@ -4928,6 +5042,9 @@ type integerReduceIntegerPoint struct {
 	Tags       Tags
 	Aggregator IntegerPointAggregator
 	Emitter    IntegerPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -4957,7 +5074,10 @@ func (itr *integerReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*integerReduceIntegerPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -4989,6 +5109,12 @@ func (itr *integerReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &integerReduceIntegerPoint{
 				Name:       curr.Name,
@@ -4996,8 +5122,15 @@ func (itr *integerReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateInteger(curr)
 	}
 
@@ -5343,6 +5476,9 @@ type integerReduceUnsignedPoint struct {
 	Tags       Tags
 	Aggregator IntegerPointAggregator
 	Emitter    UnsignedPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -5372,7 +5508,10 @@ func (itr *integerReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*integerReduceUnsignedPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -5404,6 +5543,12 @@ func (itr *integerReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &integerReduceUnsignedPoint{
 				Name:       curr.Name,
@@ -5411,8 +5556,15 @@ func (itr *integerReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateInteger(curr)
 	}
 
@@ -5762,6 +5914,9 @@ type integerReduceStringPoint struct {
 	Tags       Tags
 	Aggregator IntegerPointAggregator
 	Emitter    StringPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -5791,7 +5946,10 @@ func (itr *integerReduceStringIterator) reduce() ([]StringPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*integerReduceStringPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -5823,6 +5981,12 @@ func (itr *integerReduceStringIterator) reduce() ([]StringPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &integerReduceStringPoint{
 				Name:       curr.Name,
@@ -5830,8 +5994,15 @@ func (itr *integerReduceStringIterator) reduce() ([]StringPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateInteger(curr)
 	}
 
@@ -6181,6 +6352,9 @@ type integerReduceBooleanPoint struct {
 	Tags       Tags
 	Aggregator IntegerPointAggregator
 	Emitter    BooleanPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -6210,7 +6384,10 @@ func (itr *integerReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*integerReduceBooleanPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -6242,6 +6419,12 @@ func (itr *integerReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &integerReduceBooleanPoint{
 				Name:       curr.Name,
@@ -6249,8 +6432,15 @@ func (itr *integerReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateInteger(curr)
 	}
 
@@ -7909,6 +8099,9 @@ type unsignedReduceFloatPoint struct {
 	Tags       Tags
 	Aggregator UnsignedPointAggregator
 	Emitter    FloatPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -7938,7 +8131,10 @@ func (itr *unsignedReduceFloatIterator) reduce() ([]FloatPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*unsignedReduceFloatPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -7970,6 +8166,12 @@ func (itr *unsignedReduceFloatIterator) reduce() ([]FloatPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &unsignedReduceFloatPoint{
 				Name:       curr.Name,
@@ -7977,8 +8179,15 @@ func (itr *unsignedReduceFloatIterator) reduce() ([]FloatPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateUnsigned(curr)
 	}
 
@@ -8328,6 +8537,9 @@ type unsignedReduceIntegerPoint struct {
 	Tags       Tags
 	Aggregator UnsignedPointAggregator
 	Emitter    IntegerPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -8357,7 +8569,10 @@ func (itr *unsignedReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*unsignedReduceIntegerPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -8389,6 +8604,12 @@ func (itr *unsignedReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &unsignedReduceIntegerPoint{
 				Name:       curr.Name,
@@ -8396,8 +8617,15 @@ func (itr *unsignedReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateUnsigned(curr)
 	}
 
@@ -8747,6 +8975,9 @@ type unsignedReduceUnsignedPoint struct {
 	Tags       Tags
 	Aggregator UnsignedPointAggregator
 	Emitter    UnsignedPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -8776,7 +9007,10 @@ func (itr *unsignedReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*unsignedReduceUnsignedPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -8808,6 +9042,12 @@ func (itr *unsignedReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &unsignedReduceUnsignedPoint{
 				Name:       curr.Name,
@@ -8815,8 +9055,15 @@ func (itr *unsignedReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateUnsigned(curr)
 	}
 
@@ -9162,6 +9409,9 @@ type unsignedReduceStringPoint struct {
 	Tags       Tags
 	Aggregator UnsignedPointAggregator
 	Emitter    StringPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -9191,7 +9441,10 @@ func (itr *unsignedReduceStringIterator) reduce() ([]StringPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*unsignedReduceStringPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -9223,6 +9476,12 @@ func (itr *unsignedReduceStringIterator) reduce() ([]StringPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &unsignedReduceStringPoint{
 				Name:       curr.Name,
@@ -9230,8 +9489,15 @@ func (itr *unsignedReduceStringIterator) reduce() ([]StringPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateUnsigned(curr)
 	}
 
@@ -9581,6 +9847,9 @@ type unsignedReduceBooleanPoint struct {
 	Tags       Tags
 	Aggregator UnsignedPointAggregator
 	Emitter    BooleanPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -9610,7 +9879,10 @@ func (itr *unsignedReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*unsignedReduceBooleanPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -9642,6 +9914,12 @@ func (itr *unsignedReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &unsignedReduceBooleanPoint{
 				Name:       curr.Name,
@@ -9649,8 +9927,15 @@ func (itr *unsignedReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateUnsigned(curr)
 	}
 
@@ -11295,6 +11580,9 @@ type stringReduceFloatPoint struct {
 	Tags       Tags
 	Aggregator StringPointAggregator
 	Emitter    FloatPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -11324,7 +11612,10 @@ func (itr *stringReduceFloatIterator) reduce() ([]FloatPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*stringReduceFloatPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -11356,6 +11647,12 @@ func (itr *stringReduceFloatIterator) reduce() ([]FloatPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &stringReduceFloatPoint{
 				Name:       curr.Name,
@@ -11363,8 +11660,15 @@ func (itr *stringReduceFloatIterator) reduce() ([]FloatPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateString(curr)
 	}
 
@@ -11714,6 +12018,9 @@ type stringReduceIntegerPoint struct {
 	Tags       Tags
 	Aggregator StringPointAggregator
 	Emitter    IntegerPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -11743,7 +12050,10 @@ func (itr *stringReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*stringReduceIntegerPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -11775,6 +12085,12 @@ func (itr *stringReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &stringReduceIntegerPoint{
 				Name:       curr.Name,
@@ -11782,8 +12098,15 @@ func (itr *stringReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateString(curr)
 	}
 
@@ -12133,6 +12456,9 @@ type stringReduceUnsignedPoint struct {
 	Tags       Tags
 	Aggregator StringPointAggregator
 	Emitter    UnsignedPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -12162,7 +12488,10 @@ func (itr *stringReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*stringReduceUnsignedPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -12194,6 +12523,12 @@ func (itr *stringReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &stringReduceUnsignedPoint{
 				Name:       curr.Name,
@@ -12201,8 +12536,15 @@ func (itr *stringReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateString(curr)
 	}
 
@@ -12552,6 +12894,9 @@ type stringReduceStringPoint struct {
 	Tags       Tags
 	Aggregator StringPointAggregator
 	Emitter    StringPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -12581,7 +12926,10 @@ func (itr *stringReduceStringIterator) reduce() ([]StringPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*stringReduceStringPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -12613,6 +12961,12 @@ func (itr *stringReduceStringIterator) reduce() ([]StringPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &stringReduceStringPoint{
 				Name:       curr.Name,
@@ -12620,8 +12974,15 @@ func (itr *stringReduceStringIterator) reduce() ([]StringPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateString(curr)
 	}
 
@@ -12967,6 +13328,9 @@ type stringReduceBooleanPoint struct {
 	Tags       Tags
 	Aggregator StringPointAggregator
 	Emitter    BooleanPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -12996,7 +13360,10 @@ func (itr *stringReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*stringReduceBooleanPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -13028,6 +13395,12 @@ func (itr *stringReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &stringReduceBooleanPoint{
 				Name:       curr.Name,
@@ -13035,8 +13408,15 @@ func (itr *stringReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateString(curr)
 	}
 
@@ -14681,6 +15061,9 @@ type booleanReduceFloatPoint struct {
 	Tags       Tags
 	Aggregator BooleanPointAggregator
 	Emitter    FloatPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -14710,7 +15093,10 @@ func (itr *booleanReduceFloatIterator) reduce() ([]FloatPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*booleanReduceFloatPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -14742,6 +15128,12 @@ func (itr *booleanReduceFloatIterator) reduce() ([]FloatPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &booleanReduceFloatPoint{
 				Name:       curr.Name,
@@ -14749,8 +15141,15 @@ func (itr *booleanReduceFloatIterator) reduce() ([]FloatPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateBoolean(curr)
 	}
 
@@ -15100,6 +15499,9 @@ type booleanReduceIntegerPoint struct {
 	Tags       Tags
 	Aggregator BooleanPointAggregator
 	Emitter    IntegerPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -15129,7 +15531,10 @@ func (itr *booleanReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*booleanReduceIntegerPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -15161,6 +15566,12 @@ func (itr *booleanReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &booleanReduceIntegerPoint{
 				Name:       curr.Name,
@@ -15168,8 +15579,15 @@ func (itr *booleanReduceIntegerIterator) reduce() ([]IntegerPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateBoolean(curr)
 	}
 
@@ -15519,6 +15937,9 @@ type booleanReduceUnsignedPoint struct {
 	Tags       Tags
 	Aggregator BooleanPointAggregator
 	Emitter    UnsignedPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -15548,7 +15969,10 @@ func (itr *booleanReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*booleanReduceUnsignedPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -15580,6 +16004,12 @@ func (itr *booleanReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &booleanReduceUnsignedPoint{
 				Name:       curr.Name,
@@ -15587,8 +16017,15 @@ func (itr *booleanReduceUnsignedIterator) reduce() ([]UnsignedPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateBoolean(curr)
 	}
 
@@ -15938,6 +16375,9 @@ type booleanReduceStringPoint struct {
 	Tags       Tags
 	Aggregator BooleanPointAggregator
 	Emitter    StringPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -15967,7 +16407,10 @@ func (itr *booleanReduceStringIterator) reduce() ([]StringPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*booleanReduceStringPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -15999,6 +16442,12 @@ func (itr *booleanReduceStringIterator) reduce() ([]StringPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &booleanReduceStringPoint{
 				Name:       curr.Name,
@@ -16006,8 +16455,15 @@ func (itr *booleanReduceStringIterator) reduce() ([]StringPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateBoolean(curr)
 	}
 
@@ -16357,6 +16813,9 @@ type booleanReduceBooleanPoint struct {
 	Tags       Tags
 	Aggregator BooleanPointAggregator
 	Emitter    BooleanPointEmitter
+
+	// buffered is set if the aggregator holds every point in memory.
+	buffered bool
 }
 
 // reduce executes fn once for every point in the next window.
@@ -16386,7 +16845,10 @@ func (itr *booleanReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 	}
 
 	// Create points by tags.
+	// The memory held by the points is accounted until the window is emitted.
 	m := make(map[string]*booleanReduceBooleanPoint)
+	var memN int
+	defer func() { itr.opt.Memory.Shrink(memN) }()
 	for {
 		// Read next point.
 		curr, err := itr.input.NextInWindow(startTime, endTime)
@@ -16418,6 +16880,12 @@ func (itr *booleanReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 		// Retrieve the aggregator for this name/tag combination or create one.
 		rp := m[id]
 		if rp == nil {
+			n := reducePointSize + len(curr.Name) + len(id)
+			if err := itr.opt.Memory.Grow(n); err != nil {
+				return nil, err
+			}
+			memN += n
+
 			aggregator, emitter := itr.create()
 			rp = &booleanReduceBooleanPoint{
 				Name:       curr.Name,
@@ -16425,8 +16893,15 @@ func (itr *booleanReduceBooleanIterator) reduce() ([]BooleanPoint, error) {
 				Aggregator: aggregator,
 				Emitter:    emitter,
 			}
+			_, rp.buffered = aggregator.(pointBuffer)
 			m[id] = rp
 		}
+		if rp.buffered {
+			if err := itr.opt.Memory.Grow(bufferedPointSize); err != nil {
+				return nil, err
+			}
+			memN += bufferedPointSize
+		}
 		rp.Aggregator.AggregateBoolean(curr)
 	}
 
@@ -143,6 +143,12 @@ func (a Iterators) Merge(opt IteratorOptions) (Iterator, error) {
 			Name: "sum",
 			Args: call.Args,
 		}
+	} else if name, ok := sketchMergeFunctions[call.Name]; ok {
+		// Approximate functions emit sketches that are merged.
+		opt.Expr = &influxql.Call{
+			Name: name,
+			Args: call.Args,
+		}
 	}
 	return NewCallIterator(itr, opt)
 }
@@ -719,6 +725,9 @@ type IteratorOptions struct {
 	// Limits on the creation of iterators.
 	MaxSeriesN int
 
+	// Tracks and limits the memory held by the iterators.
+	Memory *MemoryAccountant
+
 	// If this channel is set and is closed, the iterator should try to exit
 	// and close as soon as possible.
 	InterruptCh <-chan struct{}
@@ -789,9 +798,15 @@ func newIteratorOptionsStmt(stmt *influxql.SelectStatement, sopt SelectOptions)
 		// so fill(null) wouldn't write any null values to begin with.
 		opt.Fill = influxql.NoFill
 	}
-	opt.Limit, opt.Offset = stmt.Limit, stmt.Offset
+
+	// The limit and offset of rows sorted by a field are applied once the
+	// rows have been sorted.
+	if stmt.SortedByTime() {
+		opt.Limit, opt.Offset = stmt.Limit, stmt.Offset
+	}
 	opt.SLimit, opt.SOffset = stmt.SLimit, stmt.SOffset
 	opt.MaxSeriesN = sopt.MaxSeriesN
+	opt.Memory = sopt.Memory
 	opt.InterruptCh = sopt.InterruptCh
 	opt.Authorizer = sopt.Authorizer
 
@@ -815,6 +830,7 @@ func newIteratorOptionsSubstatement(stmt *influxql.SelectStatement, opt Iterator
 	for d := range opt.GroupBy {
 		subOpt.GroupBy[d] = struct{}{}
 	}
+	subOpt.Memory = opt.Memory
 	subOpt.InterruptCh = opt.InterruptCh
 
 	// Extract the time range and condition from the condition.
@@ -1245,6 +1261,10 @@ func newSelectInfo(stmt *influxql.SelectStatement) *selectInfo {
 func (v *selectInfo) Visit(n influxql.Node) influxql.Visitor {
 	switch n := n.(type) {
 	case *influxql.Call:
+		// Math functions are not calls themselves, but may contain them.
+		if influxql.IsMathFunction(n) {
+			return v
+		}
 		v.calls[n] = struct{}{}
 		return nil
 	case *influxql.VarRef:
@@ -1469,6 +1489,9 @@ type IteratorCost struct {
 
 	// The amount of data that can be potentially read.
 	BlockSize int64
+
+	// The IDs of the shards that are touched by this query.
+	ShardIDs []uint64
 }
 
 // Combine combines the results of two IteratorCost structures into one.
@@ -1480,6 +1503,7 @@ func (c IteratorCost) Combine(other IteratorCost) IteratorCost {
 		NumFiles:     c.NumFiles + other.NumFiles,
 		BlocksRead:   c.BlocksRead + other.BlocksRead,
 		BlockSize:    c.BlockSize + other.BlockSize,
+		ShardIDs:     append(c.ShardIDs[:len(c.ShardIDs):len(c.ShardIDs)], other.ShardIDs...),
 	}
 }
 
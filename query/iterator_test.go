@@ -1297,6 +1297,49 @@ func TestIteratorOptions_Window_Location(t *testing.T) {
 	}
 }
 
+// Ensure daily windows in a location align to local midnight on both sides
+// of a daylight saving time transition.
+func TestIteratorOptions_Window_Location_Daily(t *testing.T) {
+	chicago := mustLoadLocation("America/Chicago")
+	for _, tt := range []struct {
+		now        time.Time
+		start, end time.Time
+	}{
+		{
+			now:   mustParseTime("2000-04-01T23:59:59-06:00"),
+			start: mustParseTime("2000-04-01T00:00:00-06:00"),
+			end:   mustParseTime("2000-04-02T00:00:00-06:00"),
+		},
+		{
+			now:   mustParseTime("2000-04-02T08:00:00-05:00"),
+			start: mustParseTime("2000-04-02T00:00:00-06:00"),
+			end:   mustParseTime("2000-04-03T00:00:00-05:00"),
+		},
+		{
+			now:   mustParseTime("2000-10-29T00:30:00-05:00"),
+			start: mustParseTime("2000-10-29T00:00:00-05:00"),
+			end:   mustParseTime("2000-10-30T00:00:00-06:00"),
+		},
+		{
+			now:   mustParseTime("2000-10-30T00:00:00-06:00"),
+			start: mustParseTime("2000-10-30T00:00:00-06:00"),
+			end:   mustParseTime("2000-10-31T00:00:00-06:00"),
+		},
+	} {
+		opt := query.IteratorOptions{
+			Location: chicago,
+			Interval: query.Interval{Duration: 24 * time.Hour},
+		}
+		start, end := opt.Window(tt.now.UnixNano())
+		if have, want := time.Unix(0, start).In(chicago), tt.start; !have.Equal(want) {
+			t.Errorf("%s: unexpected start time: %s != %s", tt.now, have, want)
+		}
+		if have, want := time.Unix(0, end).In(chicago), tt.end; !have.Equal(want) {
+			t.Errorf("%s: unexpected end time: %s != %s", tt.now, have, want)
+		}
+	}
+}
+
 func TestIteratorOptions_Window_MinTime(t *testing.T) {
 	opt := query.IteratorOptions{
 		StartTime: influxql.MinTime,
@@ -0,0 +1,333 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/influxdb/influxql"
+)
+
+// validateJoinSources checks that the sources of a JOIN can be joined.
+func validateJoinSources(sources influxql.Sources) error {
+	names := make(map[string]struct{}, len(sources))
+	for _, source := range sources {
+		switch source := source.(type) {
+		case *influxql.Measurement:
+			if source.Regex != nil {
+				return errors.New("JOIN does not support regular expressions")
+			} else if _, ok := names[source.Name]; ok {
+				return fmt.Errorf("cannot JOIN measurement %s with itself", source.Name)
+			}
+			names[source.Name] = struct{}{}
+		case *influxql.SubQuery:
+			return errors.New("JOIN does not support subqueries")
+		}
+	}
+	return nil
+}
+
+// joinMeasurement returns the measurement that the points of the joined
+// sources are emitted as. Its name is the name of each source separated by
+// an underscore.
+func joinMeasurement(sources influxql.Sources) *influxql.Measurement {
+	names := make([]string, 0, len(sources))
+	for _, m := range sources.Measurements() {
+		names = append(names, m.Name)
+	}
+	return &influxql.Measurement{Name: strings.Join(names, "_")}
+}
+
+// joinShardGroup reads the sources of a JOIN as a single measurement. The
+// points of every source with the same time and tags are combined into one
+// point. A field may be qualified with the name of the source it is read
+// from, such as errors.value.
+type joinShardGroup struct {
+	ShardGroup
+	sources []*influxql.Measurement
+	name    string
+}
+
+func newJoinShardGroup(sg ShardGroup, sources influxql.Sources) ShardGroup {
+	return &joinShardGroup{
+		ShardGroup: sg,
+		sources:    sources.Measurements(),
+		name:       joinMeasurement(sources).Name,
+	}
+}
+
+// qualified splits a field qualified with the name of a source. It returns
+// false if the field is not qualified.
+func (sg *joinShardGroup) qualified(field string) (*influxql.Measurement, string, bool) {
+	i := strings.Index(field, ".")
+	if i < 0 {
+		return nil, "", false
+	}
+	for _, m := range sg.sources {
+		if m.Name == field[:i] {
+			return m, field[i+1:], true
+		}
+	}
+	return nil, "", false
+}
+
+func (sg *joinShardGroup) MapType(m *influxql.Measurement, field string) influxql.DataType {
+	if m.Name == sg.name {
+		var typ influxql.DataType
+		for _, source := range sg.sources {
+			if t := sg.MapType(source, field); typ.LessThan(t) {
+				typ = t
+			}
+		}
+		return typ
+	}
+
+	if source, name, ok := sg.qualified(field); ok {
+		if source.Name != m.Name {
+			return influxql.Unknown
+		}
+		field = name
+	}
+	return sg.ShardGroup.MapType(m, field)
+}
+
+func (sg *joinShardGroup) IteratorCost(m *influxql.Measurement, opt IteratorOptions) (IteratorCost, error) {
+	var costs IteratorCost
+	for _, source := range sg.sources {
+		sopt, ok := sg.sourceOptions(source, opt)
+		if !ok {
+			continue
+		}
+		cost, err := sg.ShardGroup.IteratorCost(source, sopt)
+		if err != nil {
+			return IteratorCost{}, err
+		}
+		costs = costs.Combine(cost)
+	}
+	return costs, nil
+}
+
+func (sg *joinShardGroup) CreateIterator(ctx context.Context, m *influxql.Measurement, opt IteratorOptions) (Iterator, error) {
+	inputs := make([]Iterator, 0, len(sg.sources))
+	for _, source := range sg.sources {
+		sopt, ok := sg.sourceOptions(source, opt)
+		if !ok {
+			continue
+		}
+		input, err := sg.ShardGroup.CreateIterator(ctx, source, sopt)
+		if err != nil {
+			Iterators(inputs).Close()
+			return nil, err
+		} else if input == nil {
+			continue
+		}
+		inputs = append(inputs, renameIterator(input, sg.name))
+	}
+
+	// Iterators for an expression only read the source of their fields so
+	// the points can be merged. Auxiliary fields are read from every source
+	// and the points of each source are joined.
+	if opt.Expr != nil || len(inputs) < 2 {
+		return Iterators(inputs).Merge(opt)
+	}
+
+	join := &floatJoinIterator{
+		inputs:     make([]*bufFloatIterator, 0, len(inputs)),
+		dimensions: opt.Dimensions,
+		ascending:  opt.Ascending,
+	}
+	for _, input := range inputs {
+		itr, ok := input.(FloatIterator)
+		if !ok {
+			// Auxiliary fields are always read with a float iterator.
+			// Anything else cannot be joined so fall back to a merge.
+			return Iterators(inputs).Merge(opt)
+		}
+		join.inputs = append(join.inputs, newBufFloatIterator(itr))
+	}
+	return join, nil
+}
+
+// sourceOptions returns the iterator options used to read from a source.
+// Fields qualified with the name of the source are replaced with the field
+// name. It returns false if the expression reads fields from another source.
+func (sg *joinShardGroup) sourceOptions(source *influxql.Measurement, opt IteratorOptions) (IteratorOptions, bool) {
+	other := false
+	rewrite := func(expr influxql.Expr) influxql.Expr {
+		return influxql.RewriteExpr(influxql.CloneExpr(expr), func(expr influxql.Expr) influxql.Expr {
+			ref, ok := expr.(*influxql.VarRef)
+			if !ok {
+				return expr
+			}
+			m, name, ok := sg.qualified(ref.Val)
+			if !ok {
+				return expr
+			} else if m != source {
+				other = true
+				return expr
+			}
+			return &influxql.VarRef{Val: name, Type: ref.Type}
+		})
+	}
+
+	if opt.Expr != nil {
+		opt.Expr = rewrite(opt.Expr)
+		if other {
+			return opt, false
+		}
+	}
+	if opt.Condition != nil {
+		opt.Condition = rewrite(opt.Condition)
+	}
+	if len(opt.Aux) > 0 {
+		aux := make([]influxql.VarRef, len(opt.Aux))
+		for i, ref := range opt.Aux {
+			if m, name, ok := sg.qualified(ref.Val); ok && m == source {
+				ref.Val = name
+			}
+			aux[i] = ref
+		}
+		opt.Aux = aux
+	}
+	return opt, true
+}
+
+// renameIterator changes the measurement name of every point of an iterator.
+func renameIterator(input Iterator, name string) Iterator {
+	switch input := input.(type) {
+	case FloatIterator:
+		return &floatTransformIterator{input: input, fn: func(p *FloatPoint) *FloatPoint {
+			if p != nil {
+				p.Name = name
+			}
+			return p
+		}}
+	case IntegerIterator:
+		return &integerTransformIterator{input: input, fn: func(p *IntegerPoint) *IntegerPoint {
+			if p != nil {
+				p.Name = name
+			}
+			return p
+		}}
+	case UnsignedIterator:
+		return &unsignedTransformIterator{input: input, fn: func(p *UnsignedPoint) *UnsignedPoint {
+			if p != nil {
+				p.Name = name
+			}
+			return p
+		}}
+	case StringIterator:
+		return &stringTransformIterator{input: input, fn: func(p *StringPoint) *StringPoint {
+			if p != nil {
+				p.Name = name
+			}
+			return p
+		}}
+	case BooleanIterator:
+		return &booleanTransformIterator{input: input, fn: func(p *BooleanPoint) *BooleanPoint {
+			if p != nil {
+				p.Name = name
+			}
+			return p
+		}}
+	default:
+		return input
+	}
+}
+
+// floatJoinIterator joins the auxiliary fields of sorted inputs. The points
+// of each input with the same tags and time are combined into a single point
+// that holds the first non-nil value of each auxiliary field.
+type floatJoinIterator struct {
+	inputs     []*bufFloatIterator
+	dimensions []string
+	ascending  bool
+}
+
+func (itr *floatJoinIterator) Stats() IteratorStats {
+	var stats IteratorStats
+	for _, input := range itr.inputs {
+		stats.Add(input.Stats())
+	}
+	return stats
+}
+
+func (itr *floatJoinIterator) Close() error {
+	for _, input := range itr.inputs {
+		input.Close()
+	}
+	return nil
+}
+
+func (itr *floatJoinIterator) Next() (*FloatPoint, error) {
+	// Find the next point to emit among the inputs.
+	var next *FloatPoint
+	for _, input := range itr.inputs {
+		p, err := input.peek()
+		if err != nil {
+			return nil, err
+		} else if p == nil {
+			continue
+		}
+		if next == nil || itr.less(p, next) {
+			next = p
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	out := &FloatPoint{
+		Name: next.Name,
+		Tags: next.Tags,
+		Time: next.Time,
+		Aux:  make([]interface{}, len(next.Aux)),
+	}
+	id := itr.tagsID(next)
+	for _, input := range itr.inputs {
+		p, err := input.peek()
+		if err != nil {
+			return nil, err
+		} else if p == nil || p.Time != out.Time || itr.tagsID(p) != id {
+			continue
+		}
+		input.Next()
+
+		for i, v := range p.Aux {
+			if i < len(out.Aux) && isNilAux(out.Aux[i]) {
+				out.Aux[i] = v
+			}
+		}
+	}
+	return out, nil
+}
+
+// less returns true if the point x is emitted before the point y.
+func (itr *floatJoinIterator) less(x, y *FloatPoint) bool {
+	if xTags, yTags := itr.tagsID(x), itr.tagsID(y); xTags != yTags {
+		if itr.ascending {
+			return xTags < yTags
+		}
+		return xTags > yTags
+	}
+	if itr.ascending {
+		return x.Time < y.Time
+	}
+	return x.Time > y.Time
+}
+
+// tagsID returns the identifier of the grouped tags of a point.
+func (itr *floatJoinIterator) tagsID(p *FloatPoint) string {
+	return p.Tags.Subset(itr.dimensions).ID()
+}
+
+// isNilAux returns true if an auxiliary value is missing. A missing value is
+// either nil or a nil pointer of the type of the field.
+func isNilAux(v interface{}) bool {
+	switch v.(type) {
+	case nil, *float64, *int64, *uint64, *string, *bool:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,284 @@
+package query
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/influxdata/influxdb/influxql"
+)
+
+// buildMathIterator creates an iterator that applies a math function to each
+// point of its arguments. The build function creates the iterator for an
+// argument that is not a literal.
+func buildMathIterator(expr *influxql.Call, build func(influxql.Expr) (Iterator, error), opt IteratorOptions) (Iterator, error) {
+	if expr.Name == "if" {
+		return buildIfIterator(expr, build, opt)
+	}
+
+	input, err := build(expr.Args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if fn, ok := lookupScalarFunction(expr.Name); ok {
+		return buildScalarFunctionIterator(expr, fn, input)
+	}
+
+	switch expr.Name {
+	case "abs", "ceil", "floor", "round":
+		switch itr := input.(type) {
+		case IntegerIterator:
+			// Integers are already rounded so only abs() modifies them.
+			if expr.Name == "abs" {
+				return &integerTransformIterator{
+					input: itr,
+					fn: func(p *IntegerPoint) *IntegerPoint {
+						if p.Value < 0 {
+							p.Value = -p.Value
+						}
+						return p
+					},
+				}, nil
+			}
+			return itr, nil
+		case UnsignedIterator:
+			return itr, nil
+		}
+	}
+
+	itr, err := newFloatMathInputIterator(input, expr.Name)
+	if err != nil {
+		input.Close()
+		return nil, err
+	}
+
+	var fn func(float64) float64
+	switch expr.Name {
+	case "abs":
+		fn = math.Abs
+	case "ceil":
+		fn = math.Ceil
+	case "floor":
+		fn = math.Floor
+	case "round":
+		fn = round
+	case "sqrt":
+		fn = math.Sqrt
+	case "log":
+		fn = math.Log
+		if len(expr.Args) == 2 {
+			base, _ := numberLiteral(expr.Args[1])
+			fn = func(v float64) float64 { return math.Log(v) / math.Log(base) }
+		}
+	case "pow":
+		exp, _ := numberLiteral(expr.Args[1])
+		fn = func(v float64) float64 { return math.Pow(v, exp) }
+	default:
+		itr.Close()
+		return nil, fmt.Errorf("undefined function %s()", expr.Name)
+	}
+
+	return &floatTransformIterator{
+		input: itr,
+		fn: func(p *FloatPoint) *FloatPoint {
+			if !p.Nil {
+				p.Value = fn(p.Value)
+
+				// Values outside of the domain of the function, such as the
+				// square root of a negative number, have no result.
+				if math.IsNaN(p.Value) || math.IsInf(p.Value, 0) {
+					p.Value, p.Nil = 0, true
+				}
+			}
+			return p
+		},
+	}, nil
+}
+
+// buildIfIterator creates an iterator that selects the second argument of
+// if() when the condition in the first argument is true and the third
+// argument otherwise. The result of if() is always a float.
+func buildIfIterator(expr *influxql.Call, build func(influxql.Expr) (Iterator, error), opt IteratorOptions) (Iterator, error) {
+	input, err := build(expr.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	cond, ok := input.(BooleanIterator)
+	if !ok {
+		input.Close()
+		return nil, fmt.Errorf("expected boolean condition in if(), got %s", iteratorDataType(input))
+	}
+
+	itr := &floatIfIterator{cond: cond, ascending: opt.Ascending}
+	for i, arg := range expr.Args[1:] {
+		branch := &itr.branches[i]
+		if v, ok := numberLiteral(arg); ok {
+			branch.value = v
+			continue
+		}
+
+		input, err := build(arg)
+		if err != nil {
+			itr.Close()
+			return nil, err
+		}
+		fitr, err := newFloatMathInputIterator(input, expr.Name)
+		if err != nil {
+			input.Close()
+			itr.Close()
+			return nil, err
+		}
+		branch.input = newBufFloatIterator(fitr)
+	}
+	return itr, nil
+}
+
+// newFloatMathInputIterator casts a numeric iterator to a FloatIterator.
+func newFloatMathInputIterator(input Iterator, name string) (FloatIterator, error) {
+	switch input := input.(type) {
+	case FloatIterator:
+		return input, nil
+	case IntegerIterator:
+		return &integerFloatCastIterator{input: input}, nil
+	case UnsignedIterator:
+		return &unsignedFloatCastIterator{input: input}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type in %s(): %s", name, iteratorDataType(input))
+	}
+}
+
+// numberLiteral returns the value of a numeric literal as a float.
+func numberLiteral(expr influxql.Expr) (float64, bool) {
+	switch expr := expr.(type) {
+	case *influxql.NumberLiteral:
+		return expr.Val, true
+	case *influxql.IntegerLiteral:
+		return float64(expr.Val), true
+	case *influxql.UnsignedLiteral:
+		return float64(expr.Val), true
+	}
+	return 0, false
+}
+
+// round rounds half away from zero.
+func round(v float64) float64 {
+	if v < 0 {
+		return math.Ceil(v - 0.5)
+	}
+	return math.Floor(v + 0.5)
+}
+
+// floatIfIterator returns a value from one of two branches for each point of
+// a boolean condition.
+type floatIfIterator struct {
+	cond      BooleanIterator
+	branches  [2]floatIfBranch
+	ascending bool
+}
+
+// floatIfBranch is either an iterator or a literal value.
+type floatIfBranch struct {
+	input *bufFloatIterator
+	value float64
+}
+
+func (itr *floatIfIterator) Stats() IteratorStats {
+	stats := itr.cond.Stats()
+	for _, b := range itr.branches {
+		if b.input != nil {
+			stats.Add(b.input.Stats())
+		}
+	}
+	return stats
+}
+
+func (itr *floatIfIterator) Close() error {
+	itr.cond.Close()
+	for _, b := range itr.branches {
+		if b.input != nil {
+			b.input.Close()
+		}
+	}
+	return nil
+}
+
+func (itr *floatIfIterator) Next() (*FloatPoint, error) {
+	p, err := itr.cond.Next()
+	if p == nil || err != nil {
+		return nil, err
+	}
+
+	// Read the matching point from both branches so they stay aligned
+	// with the condition.
+	var values [2]*float64
+	for i := range itr.branches {
+		v, err := itr.branches[i].next(p, itr.ascending)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	out := &FloatPoint{
+		Name:       p.Name,
+		Tags:       p.Tags,
+		Time:       p.Time,
+		Aggregated: p.Aggregated,
+		Nil:        true,
+	}
+	if !p.Nil {
+		v := values[1]
+		if p.Value {
+			v = values[0]
+		}
+		if v != nil {
+			out.Value, out.Nil = *v, false
+		}
+	}
+	return out, nil
+}
+
+// next returns the value of the branch for the condition point. Points in
+// the branch that precede the condition are discarded. A nil value is
+// returned if the branch has no point for the condition.
+func (b *floatIfBranch) next(cond *BooleanPoint, ascending bool) (*float64, error) {
+	if b.input == nil {
+		v := b.value
+		return &v, nil
+	}
+
+	for {
+		p, err := b.input.Next()
+		if p == nil || err != nil {
+			return nil, err
+		}
+
+		if p.Name != cond.Name {
+			if p.Name < cond.Name {
+				continue
+			}
+			b.input.unread(p)
+			return nil, nil
+		}
+		if ptags, ctags := p.Tags.ID(), cond.Tags.ID(); ptags != ctags {
+			if ptags < ctags {
+				continue
+			}
+			b.input.unread(p)
+			return nil, nil
+		}
+		if p.Time != cond.Time {
+			if (p.Time < cond.Time) == ascending {
+				continue
+			}
+			b.input.unread(p)
+			return nil, nil
+		}
+
+		if p.Nil {
+			return nil, nil
+		}
+		v := p.Value
+		return &v, nil
+	}
+}
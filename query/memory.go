@@ -0,0 +1,79 @@
+package query
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Estimates of the memory, in bytes, held by the iterators of a query.
+const (
+	// reducePointSize is the overhead of one name/tag combination in a
+	// window of a reduce iterator.
+	reducePointSize = 128
+
+	// bufferedPointSize is the size of a point held by a reducer that
+	// buffers every point it aggregates.
+	bufferedPointSize = 96
+
+	// sortedRowSize is the overhead of the values of a row at a point in
+	// time held by the emitter to sort a series.
+	sortedRowSize = 48
+
+	// sortedValueSize is the size of one of those values.
+	sortedValueSize = 24
+)
+
+// ErrMaxMemoryLimitExceeded is an error when a query holds more memory than allowed.
+func ErrMaxMemoryLimitExceeded(n, limit int64) error {
+	return fmt.Errorf("max-memory-per-query limit exceeded: (%d/%d)", n, limit)
+}
+
+// MemoryAccountant tracks an estimate of the memory held by the iterators of
+// a query and enforces a limit on it. A nil MemoryAccountant tracks nothing.
+type MemoryAccountant struct {
+	n     int64 // accessed atomically
+	limit int64
+}
+
+// NewMemoryAccountant returns a MemoryAccountant with a limit in bytes.
+// A limit of zero makes the memory unlimited.
+func NewMemoryAccountant(limit int64) *MemoryAccountant {
+	return &MemoryAccountant{limit: limit}
+}
+
+// Grow adds n bytes to the memory held by the query. It returns an error,
+// without adding the bytes, if they would exceed the limit.
+func (a *MemoryAccountant) Grow(n int) error {
+	if a == nil {
+		return nil
+	}
+
+	used := atomic.AddInt64(&a.n, int64(n))
+	if a.limit > 0 && used > a.limit {
+		atomic.AddInt64(&a.n, -int64(n))
+		return ErrMaxMemoryLimitExceeded(used, a.limit)
+	}
+	return nil
+}
+
+// Shrink removes n bytes from the memory held by the query.
+func (a *MemoryAccountant) Shrink(n int) {
+	if a == nil {
+		return
+	}
+	atomic.AddInt64(&a.n, -int64(n))
+}
+
+// Used returns the number of bytes currently held by the query.
+func (a *MemoryAccountant) Used() int64 {
+	if a == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&a.n)
+}
+
+// pointBuffer is implemented by reducers that hold every point they
+// aggregate in memory until they emit.
+type pointBuffer interface {
+	bufferPoints()
+}
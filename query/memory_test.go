@@ -0,0 +1,82 @@
+package query_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/query"
+)
+
+func TestMemoryAccountant(t *testing.T) {
+	a := query.NewMemoryAccountant(100)
+	if err := a.Grow(60); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := a.Grow(50); err == nil || err.Error() != "max-memory-per-query limit exceeded: (110/100)" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if got, want := a.Used(), int64(60); got != want {
+		t.Fatalf("unexpected used: got=%d want=%d", got, want)
+	}
+
+	a.Shrink(60)
+	if err := a.Grow(100); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// A nil accountant tracks nothing.
+	var nilA *query.MemoryAccountant
+	if err := nilA.Grow(1 << 30); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	nilA.Shrink(1 << 30)
+}
+
+// Ensure the points buffered by a reducer count against the memory limit.
+func TestCallIterator_MaxMemory_BufferedPoints(t *testing.T) {
+	input := func() query.Iterator {
+		return &FloatIterator{Points: []query.FloatPoint{
+			{Name: "cpu", Time: 0, Value: 1, Tags: ParseTags("host=hostA")},
+			{Name: "cpu", Time: 1, Value: 2, Tags: ParseTags("host=hostA")},
+			{Name: "cpu", Time: 2, Value: 3, Tags: ParseTags("host=hostA")},
+			{Name: "cpu", Time: 3, Value: 4, Tags: ParseTags("host=hostA")},
+		}}
+	}
+
+	for _, tt := range []struct {
+		expr   string
+		create func(query.Iterator, query.IteratorOptions) (query.Iterator, error)
+		err    bool
+	}{
+		{expr: `count("value")`, create: query.NewCallIterator},
+		{expr: `mode("value")`, create: query.NewModeIterator, err: true},
+	} {
+		mem := query.NewMemoryAccountant(400)
+		itr, err := tt.create(input(), query.IteratorOptions{
+			Expr:       MustParseExpr(tt.expr),
+			Dimensions: []string{"host"},
+			StartTime:  influxql.MinTime,
+			EndTime:    influxql.MaxTime,
+			Ordered:    true,
+			Ascending:  true,
+			Memory:     mem,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = Iterators([]query.Iterator{itr}).ReadAll()
+		if tt.err {
+			if err == nil || !strings.HasPrefix(err.Error(), "max-memory-per-query limit exceeded") {
+				t.Errorf("%s: unexpected error: %v", tt.expr, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.expr, err)
+		}
+
+		// The memory of the window is released once it is emitted.
+		if got := mem.Used(); got != 0 {
+			t.Errorf("%s: memory not released: %d", tt.expr, got)
+		}
+	}
+}
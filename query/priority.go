@@ -0,0 +1,110 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Priority is the class of a query that orders the queries waiting for the
+// concurrency limit of the TaskManager.  Queries of a higher class are
+// admitted before the queries of a lower class, in their order of arrival
+// within a class.
+type Priority int
+
+const (
+	// PriorityDefault is the priority of queries that don't request one.
+	// It resolves to the priority of their user, or PriorityInteractive.
+	PriorityDefault Priority = iota
+
+	// PriorityBackground is for queries nobody is waiting on, such as
+	// continuous queries and exports.
+	PriorityBackground
+
+	// PriorityInteractive is for queries answered to users and dashboards.
+	PriorityInteractive
+
+	// PrioritySystem is for the queries of administrators and of the
+	// system itself.
+	PrioritySystem
+)
+
+// ParsePriority returns the priority of the name of its class.
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(s) {
+	case "background":
+		return PriorityBackground, nil
+	case "interactive":
+		return PriorityInteractive, nil
+	case "system":
+		return PrioritySystem, nil
+	}
+	return PriorityDefault, fmt.Errorf("invalid query priority %q: must be background, interactive or system", s)
+}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityDefault:
+		return "default"
+	case PriorityBackground:
+		return "background"
+	case PriorityInteractive:
+		return "interactive"
+	case PrioritySystem:
+		return "system"
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+// ParseUserPriorities returns the priorities of users by the names of their
+// classes.
+func ParseUserPriorities(m map[string]string) (map[string]Priority, error) {
+	priorities := make(map[string]Priority, len(m))
+	for user, s := range m {
+		p, err := ParsePriority(s)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: %s", user, err)
+		}
+		priorities[user] = p
+	}
+	return priorities, nil
+}
+
+// queuedQuery is a query waiting for the concurrency limit.
+type queuedQuery struct {
+	priority Priority
+
+	// ready is closed once the query is admitted, or rejected with err.
+	ready    chan struct{}
+	admitted bool
+	err      error
+}
+
+// queryQueue holds the queued queries by decreasing priority.
+type queryQueue []*queuedQuery
+
+// push adds a query after the queries of its priority or higher.
+func (q *queryQueue) push(w *queuedQuery) {
+	i := sort.Search(len(*q), func(i int) bool { return (*q)[i].priority < w.priority })
+	*q = append(*q, nil)
+	copy((*q)[i+1:], (*q)[i:])
+	(*q)[i] = w
+}
+
+// pop removes and returns the query with the highest priority.
+func (q *queryQueue) pop() *queuedQuery {
+	w := (*q)[0]
+	(*q)[0] = nil
+	*q = (*q)[1:]
+	return w
+}
+
+// remove removes a query from the queue, if it is in it.
+func (q *queryQueue) remove(w *queuedQuery) {
+	for i := range *q {
+		if (*q)[i] == w {
+			*q = append((*q)[:i], (*q)[i+1:]...)
+			return
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/tracing"
 	"github.com/uber-go/zap"
 )
 
@@ -48,6 +49,7 @@ const (
 	statQueriesFinished        = "queriesFinished" // Number of queries that have finished.
 	statQueryExecutionDuration = "queryDurationNs" // Total (wall) time spent executing queries.
 	statRecoveredPanics        = "recoveredPanics" // Number of panics recovered by Query Executor.
+	statQueriesQueued          = "queriesQueued"   // Number of queries waiting for the concurrency limit.
 
 	// PanicCrashEnv is the environment variable that, when set, will prevent
 	// the handler from recovering any panics.
@@ -59,13 +61,13 @@ func ErrDatabaseNotFound(name string) error { return fmt.Errorf("database not fo
 
 // ErrMaxSelectPointsLimitExceeded is an error when a query hits the maximum number of points.
 func ErrMaxSelectPointsLimitExceeded(n, limit int) error {
-	return fmt.Errorf("max-select-point limit exceeed: (%d/%d)", n, limit)
+	return fmt.Errorf("max-select-point limit exceeded: (%d/%d)", n, limit)
 }
 
 // ErrMaxConcurrentQueriesLimitExceeded is an error when a query cannot be run
 // because the maximum number of queries has been reached.
 func ErrMaxConcurrentQueriesLimitExceeded(n, limit int) error {
-	return fmt.Errorf("max-concurrent-queries limit exceeded(%d, %d)", n, limit)
+	return fmt.Errorf("max-concurrent-queries limit exceeded: (%d/%d)", n, limit)
 }
 
 // Authorizer reports whether certain operations are authorized.
@@ -120,11 +122,30 @@ type ExecutionOptions struct {
 	// Node to execute on.
 	NodeID uint64
 
+	// The user and remote address the query was received from, if known.
+	User       string
+	RemoteAddr string
+
 	// Quiet suppresses non-essential output from the query executor.
 	Quiet bool
 
+	// Priority orders the query among the queries waiting for the
+	// concurrency limit.
+	Priority Priority
+
+	// Limits for this query. A value of zero uses the limit configured
+	// for the query executor.
+	QueryTimeout      time.Duration
+	MaxSelectPointN   int
+	MaxSelectSeriesN  int
+	MaxSelectBucketsN int
+
 	// AbortCh is a channel that signals when results are no longer desired by the caller.
 	AbortCh <-chan struct{}
+
+	// Span, if set, is the span of the traced request the query was
+	// received in. The execution of each statement is traced as its child.
+	Span *tracing.Span
 }
 
 // ExecutionContext contains state that the query is currently executing with.
@@ -209,11 +230,32 @@ type StatementNormalizer interface {
 	NormalizeStatement(stmt influxql.Statement, database string) error
 }
 
+// StatementAuditor records the statements executed by the QueryExecutor.
+type StatementAuditor interface {
+	// AuditStatement is called after stmt is executed, with the error it
+	// failed with, if any.
+	AuditStatement(stmt influxql.Statement, opt ExecutionOptions, err error)
+}
+
+// SlowQueryLogger records the queries executed by the QueryExecutor that
+// were slower than a threshold.
+type SlowQueryLogger interface {
+	// LogQuery is called after every query finishes, and records it if
+	// it was slow.
+	LogQuery(q QueryInfo)
+}
+
 // QueryExecutor executes every statement in an Query.
 type QueryExecutor struct {
 	// Used for executing a statement in the query.
 	StatementExecutor StatementExecutor
 
+	// Used for recording executed statements, if set.
+	Auditor StatementAuditor
+
+	// Used for recording slow queries, if set.
+	SlowQueryLog SlowQueryLogger
+
 	// Used for tracking running queries.
 	TaskManager *TaskManager
 
@@ -254,6 +296,7 @@ func (e *QueryExecutor) Statistics(tags map[string]string) []models.Statistic {
 			statQueriesFinished:        atomic.LoadInt64(&e.stats.FinishedQueries),
 			statQueryExecutionDuration: atomic.LoadInt64(&e.stats.QueryExecutionDuration),
 			statRecoveredPanics:        atomic.LoadInt64(&e.stats.RecoveredPanics),
+			statQueriesQueued:          int64(e.TaskManager.QueuedQueries()),
 		},
 	}}
 }
@@ -263,6 +306,13 @@ func (e *QueryExecutor) Close() error {
 	return e.TaskManager.Close()
 }
 
+// Drain stops the execution of new queries and waits for the running queries
+// to finish, for at most timeout.  It returns false if queries are still
+// running once the timeout elapses.
+func (e *QueryExecutor) Drain(timeout time.Duration) bool {
+	return e.TaskManager.Drain(timeout)
+}
+
 // SetLogOutput sets the writer to which all logs are written. It must not be
 // called after Open is called.
 func (e *QueryExecutor) WithLogger(log zap.Logger) {
@@ -289,7 +339,7 @@ func (e *QueryExecutor) executeQuery(query *influxql.Query, opt ExecutionOptions
 		atomic.AddInt64(&e.stats.QueryExecutionDuration, time.Since(start).Nanoseconds())
 	}(time.Now())
 
-	qid, task, err := e.TaskManager.AttachQuery(query, opt.Database, closing)
+	qid, task, err := e.TaskManager.AttachQuery(query, opt, closing)
 	if err != nil {
 		select {
 		case results <- &Result{Err: err}:
@@ -298,6 +348,9 @@ func (e *QueryExecutor) executeQuery(query *influxql.Query, opt ExecutionOptions
 		return
 	}
 	defer e.TaskManager.DetachQuery(qid)
+	if e.SlowQueryLog != nil {
+		defer func() { e.SlowQueryLog.LogQuery(task.info(qid, time.Now())) }()
+	}
 
 	// Setup the execution context that will be used when executing statements.
 	ctx := ExecutionContext{
@@ -378,7 +431,19 @@ LOOP:
 		}
 
 		// Send any other statements to the underlying statement executor.
+		var span *tracing.Span
+		if opt.Span != nil {
+			span = opt.Span.StartSpan("statement")
+			span.SetLabels("statement", stmt.String())
+			ctx.Span = span
+		}
 		err = e.StatementExecutor.ExecuteStatement(stmt, ctx)
+		if span != nil {
+			if err != nil {
+				span.MergeLabels("error", err.Error())
+			}
+			span.Finish()
+		}
 		if err == ErrQueryInterrupted {
 			// Query was interrupted so retrieve the real interrupt error from
 			// the query task if there is one.
@@ -387,6 +452,10 @@ LOOP:
 			}
 		}
 
+		if e.Auditor != nil {
+			e.Auditor.AuditStatement(stmt, ctx.ExecutionOptions, err)
+		}
+
 		// Send an error for this result if it failed for some reason.
 		if err != nil {
 			if err := ctx.send(&Result{
@@ -463,8 +532,16 @@ type QueryMonitorFunc func(<-chan struct{}) error
 // QueryTask is the internal data structure for managing queries.
 // For the public use data structure that gets returned, see QueryTask.
 type QueryTask struct {
+	// Number of rows emitted and an estimate of the memory, in bytes, of
+	// the values in those rows, and number of series read. Accessed
+	// atomically.
+	rowN    int64
+	memory  int64
+	seriesN int64
+
 	query     string
 	database  string
+	user      string
 	status    TaskStatus
 	startTime time.Time
 	closing   chan struct{}
@@ -489,6 +566,77 @@ func (q *QueryTask) Error() error {
 	return q.err
 }
 
+// TrackRow adds a row emitted by the query to the counters reported by
+// SHOW QUERIES.
+func (q *QueryTask) TrackRow(row *models.Row) {
+	atomic.AddInt64(&q.rowN, int64(len(row.Values)))
+	atomic.AddInt64(&q.memory, int64(rowSize(row)))
+}
+
+// RowN returns the number of rows emitted by the query.
+func (q *QueryTask) RowN() int64 {
+	return atomic.LoadInt64(&q.rowN)
+}
+
+// Memory returns an estimate of the memory, in bytes, of the rows emitted
+// by the query.
+func (q *QueryTask) Memory() int64 {
+	return atomic.LoadInt64(&q.memory)
+}
+
+// TrackSeries adds series read by the query to the counters reported by the
+// slow query log.
+func (q *QueryTask) TrackSeries(n int) {
+	atomic.AddInt64(&q.seriesN, int64(n))
+}
+
+// SeriesN returns the number of series read by the query.
+func (q *QueryTask) SeriesN() int64 {
+	return atomic.LoadInt64(&q.seriesN)
+}
+
+// info returns the information of the query with the given id at time now.
+func (q *QueryTask) info(id uint64, now time.Time) QueryInfo {
+	q.mu.Lock()
+	status := q.status
+	q.mu.Unlock()
+
+	return QueryInfo{
+		ID:       id,
+		Query:    q.query,
+		Database: q.database,
+		User:     q.user,
+		Duration: now.Sub(q.startTime),
+		Status:   status.String(),
+		Rows:     q.RowN(),
+		Memory:   q.Memory(),
+		SeriesN:  q.SeriesN(),
+	}
+}
+
+// rowSize returns an estimate of the number of bytes used by the values
+// of a row.
+func rowSize(row *models.Row) int {
+	const ifaceSize = 16
+	var n int
+	for _, values := range row.Values {
+		n += len(values) * ifaceSize
+		for _, v := range values {
+			switch v := v.(type) {
+			case float64, int64, uint64:
+				n += 8
+			case bool:
+				n++
+			case string:
+				n += len(v)
+			case time.Time:
+				n += 24
+			}
+		}
+	}
+	return n
+}
+
 func (q *QueryTask) setError(err error) {
 	q.mu.Lock()
 	q.err = err
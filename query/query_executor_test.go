@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/query"
 )
 
@@ -339,6 +340,54 @@ func TestQueryExecutor_ShowQueries(t *testing.T) {
 	}
 }
 
+func TestQueryExecutor_ShowQueries_TrackRows(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu; SHOW QUERIES`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			switch stmt.(type) {
+			case *influxql.ShowQueriesStatement:
+				return e.TaskManager.ExecuteStatement(stmt, ctx)
+			}
+
+			ctx.Query.TrackRow(&models.Row{
+				Name:    "cpu",
+				Columns: []string{"time", "count"},
+				Values: [][]interface{}{
+					{time.Unix(0, 0), int64(1)},
+					{time.Unix(1, 0), int64(2)},
+				},
+			})
+			return ctx.Send(&query.Result{StatementID: ctx.StatementID})
+		},
+	}
+
+	results := e.ExecuteQuery(q, query.ExecutionOptions{}, nil)
+	<-results
+	result := <-results
+	if result.Err != nil {
+		t.Fatalf("unexpected error: %s", result.Err)
+	} else if len(result.Series) != 1 || len(result.Series[0].Values) != 1 {
+		t.Fatalf("unexpected series: %v", result.Series)
+	}
+
+	row := result.Series[0]
+	if got, want := strings.Join(row.Columns, ","), "qid,query,database,duration,status,rows,memory"; got != want {
+		t.Errorf("unexpected columns: got=%s want=%s", got, want)
+	}
+	if got, want := row.Values[0][5], int64(2); got != want {
+		t.Errorf("unexpected rows: got=%v want=%v", got, want)
+	}
+	// Four values of 16 bytes, two times of 24 bytes and two int64s.
+	if got, want := row.Values[0][6], int64(4*16+2*24+2*8); got != want {
+		t.Errorf("unexpected memory: got=%v want=%v", got, want)
+	}
+}
+
 func TestQueryExecutor_Limit_Timeout(t *testing.T) {
 	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
 	if err != nil {
@@ -366,6 +415,33 @@ func TestQueryExecutor_Limit_Timeout(t *testing.T) {
 	}
 }
 
+func TestQueryExecutor_Limit_Timeout_Override(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			select {
+			case <-ctx.InterruptCh:
+				return query.ErrQueryInterrupted
+			case <-time.After(time.Second):
+				t.Errorf("timeout has not killed the query")
+				return errUnexpected
+			}
+		},
+	}
+	e.TaskManager.QueryTimeout = time.Hour
+
+	results := e.ExecuteQuery(q, query.ExecutionOptions{QueryTimeout: time.Nanosecond}, nil)
+	result := <-results
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "query-timeout") {
+		t.Errorf("unexpected error: %s", result.Err)
+	}
+}
+
 func TestQueryExecutor_Limit_ConcurrentQueries(t *testing.T) {
 	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
 	if err != nil {
@@ -405,6 +481,182 @@ func TestQueryExecutor_Limit_ConcurrentQueries(t *testing.T) {
 	}
 }
 
+// Ensure queries over the concurrency limit wait and are admitted by priority.
+func TestTaskManager_QueuedQueries(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tm := query.NewTaskManager()
+	tm.MaxConcurrentQueries = 1
+	tm.MaxQueuedQueries = 2
+	tm.UserPriorities = map[string]query.Priority{"cq": query.PriorityBackground}
+	defer tm.Close()
+
+	qid, _, err := tm.AttachQuery(q, query.ExecutionOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admitted := make(chan string, 2)
+	attach := func(name string, opt query.ExecutionOptions, queued int) {
+		go func() {
+			qid, _, err := tm.AttachQuery(q, opt, nil)
+			if err != nil {
+				admitted <- err.Error()
+				return
+			}
+			admitted <- name
+			tm.DetachQuery(qid)
+		}()
+		for deadline := time.Now().Add(5 * time.Second); tm.QueuedQueries() != queued; time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d queued queries", queued)
+			}
+		}
+	}
+	attach("background", query.ExecutionOptions{User: "cq"}, 1)
+	attach("interactive", query.ExecutionOptions{}, 2)
+
+	// The queue is full.
+	if _, _, err := tm.AttachQuery(q, query.ExecutionOptions{}, nil); err == nil || !strings.Contains(err.Error(), "max-concurrent-queries") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tm.DetachQuery(qid)
+	for _, exp := range []string{"interactive", "background"} {
+		select {
+		case name := <-admitted:
+			if name != exp {
+				t.Fatalf("unexpected admitted query: %s, expected %s", name, exp)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for the %s query", exp)
+		}
+	}
+}
+
+// Ensure an interrupted query leaves the queue.
+func TestTaskManager_QueuedQueries_Interrupt(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tm := query.NewTaskManager()
+	tm.MaxConcurrentQueries = 1
+	tm.MaxQueuedQueries = 1
+	defer tm.Close()
+
+	if _, _, err := tm.AttachQuery(q, query.ExecutionOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	interrupt := make(chan struct{})
+	close(interrupt)
+	if _, _, err := tm.AttachQuery(q, query.ExecutionOptions{}, interrupt); err != query.ErrQueryInterrupted {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n := tm.QueuedQueries(); n != 0 {
+		t.Fatalf("unexpected queued queries: %d", n)
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for s, exp := range map[string]query.Priority{
+		"background":  query.PriorityBackground,
+		"Interactive": query.PriorityInteractive,
+		"SYSTEM":      query.PrioritySystem,
+	} {
+		if p, err := query.ParsePriority(s); err != nil {
+			t.Fatal(err)
+		} else if p != exp {
+			t.Fatalf("unexpected priority for %q: %s", s, p)
+		}
+	}
+	if _, err := query.ParsePriority("urgent"); err == nil {
+		t.Fatal("expected error for unknown priority")
+	}
+}
+
+// Ensure the auditor is called with each executed statement and its error.
+func TestQueryExecutor_Auditor(t *testing.T) {
+	q, err := influxql.ParseQuery(`DROP DATABASE db0; DROP DATABASE db1; DROP DATABASE db2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			if stmt.(*influxql.DropDatabaseStatement).Name == "db1" {
+				return errUnexpected
+			}
+			return ctx.Send(&query.Result{StatementID: ctx.StatementID})
+		},
+	}
+
+	var audited []string
+	e.Auditor = StatementAuditorFunc(func(stmt influxql.Statement, opt query.ExecutionOptions, err error) {
+		if opt.User != "fred" {
+			t.Errorf("unexpected user: %s", opt.User)
+		}
+		audited = append(audited, fmt.Sprintf("%s: %v", stmt, err))
+	})
+
+	discardOutput(e.ExecuteQuery(q, query.ExecutionOptions{User: "fred"}, nil))
+
+	// Execution stops after the first error.
+	if exp := []string{"DROP DATABASE db0: <nil>", "DROP DATABASE db1: unexpected error"}; fmt.Sprint(audited) != fmt.Sprint(exp) {
+		t.Fatalf("unexpected audited statements: %q", audited)
+	}
+}
+
+// StatementAuditorFunc is a function that implements query.StatementAuditor.
+type StatementAuditorFunc func(stmt influxql.Statement, opt query.ExecutionOptions, err error)
+
+func (fn StatementAuditorFunc) AuditStatement(stmt influxql.Statement, opt query.ExecutionOptions, err error) {
+	fn(stmt, opt, err)
+}
+
+// Ensure the slow query log is passed each finished query with its counters.
+func TestQueryExecutor_SlowQueryLog(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			ctx.Query.TrackSeries(3)
+			row := &models.Row{Name: "cpu", Columns: []string{"time", "count"}, Values: [][]interface{}{{time.Unix(0, 0), int64(5)}}}
+			ctx.Query.TrackRow(row)
+			return ctx.Send(&query.Result{StatementID: ctx.StatementID, Series: models.Rows{row}})
+		},
+	}
+
+	var logged []query.QueryInfo
+	e.SlowQueryLog = SlowQueryLoggerFunc(func(q query.QueryInfo) {
+		logged = append(logged, q)
+	})
+
+	discardOutput(e.ExecuteQuery(q, query.ExecutionOptions{Database: "db0", User: "fred"}, nil))
+
+	if len(logged) != 1 {
+		t.Fatalf("unexpected logged queries: %+v", logged)
+	} else if info := logged[0]; info.Query != "SELECT count(value) FROM cpu" || info.Database != "db0" || info.User != "fred" || info.SeriesN != 3 || info.Rows != 1 || info.Memory == 0 {
+		t.Fatalf("unexpected logged query: %+v", info)
+	}
+}
+
+// SlowQueryLoggerFunc is a function that implements query.SlowQueryLogger.
+type SlowQueryLoggerFunc func(q query.QueryInfo)
+
+func (fn SlowQueryLoggerFunc) LogQuery(q query.QueryInfo) {
+	fn(q)
+}
+
 func TestQueryExecutor_Close(t *testing.T) {
 	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
 	if err != nil {
@@ -455,6 +707,94 @@ func TestQueryExecutor_Close(t *testing.T) {
 	}
 }
 
+func TestQueryExecutor_Drain(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			close(started)
+			<-release
+			return nil
+		},
+	}
+
+	results := e.ExecuteQuery(q, query.ExecutionOptions{}, nil)
+	go func() {
+		for range results {
+		}
+	}()
+
+	// Wait for the statement to start executing.
+	<-started
+
+	if e.Drain(10 * time.Millisecond) {
+		t.Fatal("expected the drain to time out")
+	}
+
+	// New queries are rejected once draining.
+	if result := <-e.ExecuteQuery(q, query.ExecutionOptions{}, nil); result.Err != query.ErrQueryEngineShutdown {
+		t.Fatalf("unexpected error: %v", result.Err)
+	}
+
+	drained := make(chan bool)
+	go func() { drained <- e.Drain(time.Second) }()
+
+	select {
+	case <-drained:
+		t.Fatal("drain returned before the query finished")
+	default:
+	}
+
+	// Let the running query finish.
+	close(release)
+	select {
+	case ok := <-drained:
+		if !ok {
+			t.Fatal("expected the queries to be drained")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("drain did not return after the query finished")
+	}
+}
+
+func TestQueryExecutor_Drain_Timeout(t *testing.T) {
+	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			close(started)
+			<-ctx.InterruptCh
+			return query.ErrQueryInterrupted
+		},
+	}
+
+	results := e.ExecuteQuery(q, query.ExecutionOptions{}, nil)
+	<-started
+
+	if e.Drain(10 * time.Millisecond) {
+		t.Fatal("expected the drain to time out")
+	}
+
+	// Closing the query executor kills the query left running.
+	e.Close()
+	if result := <-results; result.Err != query.ErrQueryEngineShutdown {
+		t.Fatalf("unexpected error: %v", result.Err)
+	}
+}
+
 func TestQueryExecutor_Panic(t *testing.T) {
 	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
 	if err != nil {
@@ -30,6 +30,9 @@ type SelectOptions struct {
 
 	// Maximum number of buckets for a statement.
 	MaxBucketsN int
+
+	// Tracks and limits the memory held by the iterators of the statement.
+	Memory *MemoryAccountant
 }
 
 // ShardMapper retrieves and maps shards into an IteratorCreator that can later be
@@ -313,6 +316,13 @@ func buildAuxIterator(expr influxql.Expr, aitr AuxIterator, opt IteratorOptions)
 			}
 			return buildTransformIterator(lhs, rhs, expr.Op, opt)
 		}
+	case *influxql.Call:
+		if !influxql.IsMathFunction(expr) {
+			return nil, fmt.Errorf("invalid expression type: %T", expr)
+		}
+		return buildMathIterator(expr, func(expr influxql.Expr) (Iterator, error) {
+			return buildAuxIterator(expr, aitr, opt)
+		}, opt)
 	case *influxql.ParenExpr:
 		return buildAuxIterator(expr.Expr, aitr, opt)
 	case *influxql.NilLiteral:
@@ -421,6 +431,9 @@ func buildExprIterator(ctx context.Context, expr influxql.Expr, ic IteratorCreat
 	case *influxql.VarRef:
 		return b.buildVarRefIterator(ctx, expr)
 	case *influxql.Call:
+		if influxql.IsMathFunction(expr) {
+			return b.buildMathIterator(ctx, expr)
+		}
 		return b.buildCallIterator(ctx, expr)
 	case *influxql.BinaryExpr:
 		return b.buildBinaryExprIterator(ctx, expr)
@@ -745,7 +758,28 @@ func (b *exprIteratorBuilder) buildCallIterator(ctx context.Context, expr *influ
 				percentile = float64(arg.Val)
 			}
 			return newPercentileIterator(input, opt, percentile)
+		case "count_hll":
+			input, err := b.callIterator(ctx, expr, opt)
+			if err != nil {
+				return nil, err
+			}
+			return newHLLEstimateIterator(input)
+		case "percentile_tdigest":
+			input, err := b.callIterator(ctx, expr, opt)
+			if err != nil {
+				return nil, err
+			}
+			percentile, _ := numberLiteral(expr.Args[1])
+			return newTDigestQuantileIterator(input, percentile)
 		default:
+			if fn, ok := lookupAggregateFunction(expr.Name); ok {
+				opt.Ordered = true
+				input, err := buildExprIterator(ctx, expr.Args[0].(*influxql.VarRef), b.ic, b.sources, opt, false, false)
+				if err != nil {
+					return nil, err
+				}
+				return newAggregateFunctionIterator(input, opt, expr, fn)
+			}
 			return nil, fmt.Errorf("unsupported call: %s", expr.Name)
 		}
 	}()
@@ -800,6 +834,14 @@ func (b *exprIteratorBuilder) buildBinaryExprIterator(ctx context.Context, expr
 	}
 }
 
+func (b *exprIteratorBuilder) buildMathIterator(ctx context.Context, expr *influxql.Call) (Iterator, error) {
+	// The selector time is only kept when the function applies to a single iterator.
+	selector := b.selector && expr.Name != "if"
+	return buildMathIterator(expr, func(arg influxql.Expr) (Iterator, error) {
+		return buildExprIterator(ctx, arg, b.ic, b.sources, b.opt, selector, false)
+	}, b.opt)
+}
+
 func (b *exprIteratorBuilder) callIterator(ctx context.Context, expr *influxql.Call, opt IteratorOptions) (Iterator, error) {
 	inputs := make([]Iterator, 0, len(b.sources))
 	if err := func() error {
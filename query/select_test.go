@@ -3,6 +3,7 @@ package query_test
 import (
 	"context"
 	"fmt"
+	"math"
 	"math/rand"
 	"reflect"
 	"testing"
@@ -1675,6 +1676,83 @@ func TestSelect(t *testing.T) {
 				{&query.UnsignedPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 50 * Second, Value: 4}},
 			},
 		},
+		{
+			name: "CountHLL_Float",
+			q:    `SELECT count_hll(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
+			typ:  influxql.Float,
+			expr: `count_hll(value::float)`,
+			itrs: []query.Iterator{
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: 20},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 1 * Second, Value: 19},
+				}},
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 5 * Second, Value: 10},
+				}},
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 9 * Second, Value: 19},
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 10 * Second, Value: 2},
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 11 * Second, Value: 2},
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 12 * Second, Value: 3},
+				}},
+			},
+			points: [][]query.Point{
+				{&query.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 2, Aggregated: 3}},
+				{&query.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 2, Aggregated: 3}},
+				{&query.IntegerPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 0 * Second, Value: 1, Aggregated: 1}},
+			},
+		},
+		{
+			name: "CountHLL_String",
+			q:    `SELECT count_hll(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
+			typ:  influxql.String,
+			itrs: []query.Iterator{
+				&StringIterator{Points: []query.StringPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: "a"},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 1 * Second, Value: "b"},
+				}},
+				&StringIterator{Points: []query.StringPoint{
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 9 * Second, Value: "b"},
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 10 * Second, Value: "c"},
+				}},
+			},
+			points: [][]query.Point{
+				{&query.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 2, Aggregated: 3}},
+				{&query.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 1, Aggregated: 1}},
+			},
+		},
+		{
+			name: "PercentileTDigest_Float",
+			q:    `SELECT percentile_tdigest(value, 90) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
+			typ:  influxql.Float,
+			expr: `percentile_tdigest(value::float, 90)`,
+			itrs: []query.Iterator{
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: 20},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 11 * Second, Value: 3},
+				}},
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 50 * Second, Value: 10},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 51 * Second, Value: 9},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 52 * Second, Value: 8},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 53 * Second, Value: 7},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 54 * Second, Value: 6},
+				}},
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 9 * Second, Value: 19},
+					{Name: "cpu", Tags: ParseTags("region=east,host=B"), Time: 55 * Second, Value: 5},
+					{Name: "cpu", Tags: ParseTags("region=east,host=B"), Time: 56 * Second, Value: 4},
+					{Name: "cpu", Tags: ParseTags("region=east,host=B"), Time: 57 * Second, Value: 3},
+					{Name: "cpu", Tags: ParseTags("region=east,host=B"), Time: 58 * Second, Value: 2},
+					{Name: "cpu", Tags: ParseTags("region=east,host=B"), Time: 59 * Second, Value: 1},
+				}},
+			},
+			points: [][]query.Point{
+				{&query.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 20, Aggregated: 2}},
+				{&query.FloatPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 3, Aggregated: 1}},
+				{&query.FloatPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 50 * Second, Value: 9.5, Aggregated: 10}},
+			},
+		},
 		{
 			name: "Percentile_Float",
 			q:    `SELECT percentile(value, 90) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
@@ -3932,6 +4010,371 @@ func TestSelect_BinaryExpr_NilValues(t *testing.T) {
 	}
 }
 
+// Ensure math and conditional functions are applied to each point.
+func TestSelect_MathFunctions(t *testing.T) {
+	shardMapper := ShardMapper{
+		MapShardsFn: func(sources influxql.Sources, _ influxql.TimeRange) query.ShardGroup {
+			return &ShardGroup{
+				Fields: map[string]influxql.DataType{
+					"n":     influxql.Integer,
+					"value": influxql.Float,
+				},
+				CreateIteratorFn: func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) (query.Iterator, error) {
+					if m.Name != "cpu" {
+						t.Fatalf("unexpected source: %s", m.Name)
+					}
+					values := map[string][]interface{}{
+						"n":     {int64(-3), int64(4), int64(10)},
+						"value": {float64(-2.5), float64(4), float64(9.2)},
+					}
+					points := make([]query.FloatPoint, 3)
+					for i := range points {
+						points[i] = query.FloatPoint{Name: "cpu", Time: int64(i) * 5 * Second, Value: values["value"][i].(float64)}
+						for _, ref := range opt.Aux {
+							points[i].Aux = append(points[i].Aux, values[ref.Val][i])
+						}
+					}
+
+					itr := &FloatIterator{Points: points}
+					if opt.Expr != nil {
+						return query.NewCallIterator(itr, opt)
+					}
+					return itr, nil
+				},
+			}
+		},
+	}
+
+	for _, test := range []struct {
+		Name      string
+		Statement string
+		Points    [][]query.Point
+	}{
+		{
+			Name:      "Abs_Float",
+			Statement: `SELECT abs(value) FROM cpu`,
+			Points: [][]query.Point{
+				{&query.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 2.5}},
+				{&query.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 4}},
+				{&query.FloatPoint{Name: "cpu", Time: 10 * Second, Value: 9.2}},
+			},
+		},
+		{
+			Name:      "Abs_Integer",
+			Statement: `SELECT abs(n) FROM cpu`,
+			Points: [][]query.Point{
+				{&query.IntegerPoint{Name: "cpu", Time: 0 * Second, Value: 3}},
+				{&query.IntegerPoint{Name: "cpu", Time: 5 * Second, Value: 4}},
+				{&query.IntegerPoint{Name: "cpu", Time: 10 * Second, Value: 10}},
+			},
+		},
+		{
+			Name:      "Round",
+			Statement: `SELECT round(value), floor(value), ceil(value) FROM cpu`,
+			Points: [][]query.Point{
+				{
+					&query.FloatPoint{Name: "cpu", Time: 0 * Second, Value: -3},
+					&query.FloatPoint{Name: "cpu", Time: 0 * Second, Value: -3},
+					&query.FloatPoint{Name: "cpu", Time: 0 * Second, Value: -2},
+				},
+				{
+					&query.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 4},
+					&query.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 4},
+					&query.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 4},
+				},
+				{
+					&query.FloatPoint{Name: "cpu", Time: 10 * Second, Value: 9},
+					&query.FloatPoint{Name: "cpu", Time: 10 * Second, Value: 9},
+					&query.FloatPoint{Name: "cpu", Time: 10 * Second, Value: 10},
+				},
+			},
+		},
+		{
+			Name:      "Sqrt",
+			Statement: `SELECT sqrt(value) FROM cpu`,
+			Points: [][]query.Point{
+				{&query.FloatPoint{Name: "cpu", Time: 0 * Second, Nil: true}},
+				{&query.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 2}},
+				{&query.FloatPoint{Name: "cpu", Time: 10 * Second, Value: math.Sqrt(9.2)}},
+			},
+		},
+		{
+			Name:      "Log",
+			Statement: `SELECT log(value, 2) FROM cpu`,
+			Points: [][]query.Point{
+				{&query.FloatPoint{Name: "cpu", Time: 0 * Second, Nil: true}},
+				{&query.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 2}},
+				{&query.FloatPoint{Name: "cpu", Time: 10 * Second, Value: math.Log(9.2) / math.Log(2)}},
+			},
+		},
+		{
+			Name:      "Pow",
+			Statement: `SELECT pow(n, 2) FROM cpu`,
+			Points: [][]query.Point{
+				{&query.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 9}},
+				{&query.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 16}},
+				{&query.FloatPoint{Name: "cpu", Time: 10 * Second, Value: 100}},
+			},
+		},
+		{
+			Name:      "If",
+			Statement: `SELECT if(value > 0, n, 0) FROM cpu`,
+			Points: [][]query.Point{
+				{&query.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 0}},
+				{&query.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 4}},
+				{&query.FloatPoint{Name: "cpu", Time: 10 * Second, Value: 10}},
+			},
+		},
+		{
+			Name:      "Abs_Aggregate",
+			Statement: `SELECT abs(min(value)) FROM cpu`,
+			Points: [][]query.Point{
+				{&query.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 2.5, Aggregated: 3}},
+			},
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			stmt := MustParseSelectStatement(test.Statement)
+			itrs, _, err := query.Select(context.Background(), stmt, &shardMapper, query.SelectOptions{})
+			if err != nil {
+				t.Errorf("%s: parse error: %s", test.Name, err)
+			} else if a, err := Iterators(itrs).ReadAll(); err != nil {
+				t.Fatalf("%s: unexpected error: %s", test.Name, err)
+			} else if diff := cmp.Diff(a, test.Points); diff != "" {
+				t.Errorf("%s: unexpected points:\n%s", test.Name, diff)
+			}
+		})
+	}
+}
+
+func TestSelect_UserFunctions(t *testing.T) {
+	if err := query.RegisterScalarFunction("scale", query.ScalarFunctionFunc(func(value interface{}, args []interface{}) (interface{}, error) {
+		return value.(float64) * args[0].(float64), nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	defer query.UnregisterFunction("scale")
+
+	if err := query.RegisterAggregateFunction("product", query.AggregateFunctionFunc(func(values []interface{}, args []interface{}) (interface{}, error) {
+		product := 1.0
+		for _, v := range values {
+			product *= v.(float64)
+		}
+		return product, nil
+	})); err != nil {
+		t.Fatal(err)
+	}
+	defer query.UnregisterFunction("product")
+
+	batch := &batchScaleFunction{}
+	if err := query.RegisterScalarFunction("scale_batch", batch); err != nil {
+		t.Fatal(err)
+	}
+	defer query.UnregisterFunction("scale_batch")
+
+	shardMapper := ShardMapper{
+		MapShardsFn: func(sources influxql.Sources, _ influxql.TimeRange) query.ShardGroup {
+			return &ShardGroup{
+				Fields: map[string]influxql.DataType{
+					"value": influxql.Float,
+				},
+				CreateIteratorFn: func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) (query.Iterator, error) {
+					if _, ok := opt.Expr.(*influxql.Call); ok {
+						t.Fatalf("unexpected call: %s", opt.Expr)
+					}
+					points := []query.FloatPoint{
+						{Name: "cpu", Time: 0 * Second, Value: 2},
+						{Name: "cpu", Time: 5 * Second, Value: 3},
+						{Name: "cpu", Time: 10 * Second, Value: 4},
+					}
+					for i := range points {
+						for range opt.Aux {
+							points[i].Aux = append(points[i].Aux, points[i].Value)
+						}
+					}
+					return &FloatIterator{Points: points}, nil
+				},
+			}
+		},
+	}
+
+	for _, test := range []struct {
+		Name      string
+		Statement string
+		Points    [][]query.Point
+		Err       string
+	}{
+		{
+			Name:      "Scalar",
+			Statement: `SELECT scale(value, 0.5) FROM cpu`,
+			Points: [][]query.Point{
+				{&query.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 1}},
+				{&query.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 1.5}},
+				{&query.FloatPoint{Name: "cpu", Time: 10 * Second, Value: 2}},
+			},
+		},
+		{
+			Name:      "ScalarBatch",
+			Statement: `SELECT scale_batch(value, 0.5) FROM cpu`,
+			Points: [][]query.Point{
+				{&query.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 1}},
+				{&query.FloatPoint{Name: "cpu", Time: 5 * Second, Value: 1.5}},
+				{&query.FloatPoint{Name: "cpu", Time: 10 * Second, Value: 2}},
+			},
+		},
+		{
+			Name:      "Aggregate",
+			Statement: `SELECT product(value) FROM cpu WHERE time >= 0s AND time < 20s GROUP BY time(10s)`,
+			Points: [][]query.Point{
+				{&query.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 6}},
+				{&query.FloatPoint{Name: "cpu", Time: 10 * Second, Value: 4}},
+			},
+		},
+		{
+			Name:      "Scalar_Aggregate",
+			Statement: `SELECT scale(product(value), 2.5) FROM cpu`,
+			Points: [][]query.Point{
+				{&query.FloatPoint{Name: "cpu", Time: 0 * Second, Value: 60}},
+			},
+		},
+		{
+			Name:      "FieldArgument",
+			Statement: `SELECT scale(value, value) FROM cpu`,
+			Err:       `expected literal argument in scale(), got value`,
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			stmt := MustParseSelectStatement(test.Statement)
+			itrs, _, err := query.Select(context.Background(), stmt, &shardMapper, query.SelectOptions{})
+			if test.Err != "" {
+				if err == nil || err.Error() != test.Err {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			} else if a, err := Iterators(itrs).ReadAll(); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			} else if diff := cmp.Diff(a, test.Points); diff != "" {
+				t.Errorf("unexpected points:\n%s", diff)
+			}
+		})
+	}
+
+	// The points are passed to a batch function in a single call.
+	if !reflect.DeepEqual(batch.sizes, []int{3}) {
+		t.Fatalf("unexpected batches: %v", batch.sizes)
+	}
+}
+
+// batchScaleFunction multiplies the values of each batch by the first
+// argument, and records the size of each batch.
+type batchScaleFunction struct {
+	sizes []int
+}
+
+func (fn *batchScaleFunction) Call(value interface{}, args []interface{}) (interface{}, error) {
+	return nil, fmt.Errorf("unexpected call")
+}
+
+func (fn *batchScaleFunction) CallBatch(values []interface{}, args []interface{}) ([]interface{}, error) {
+	fn.sizes = append(fn.sizes, len(values))
+	results := make([]interface{}, len(values))
+	for i, v := range values {
+		results[i] = v.(float64) * args[0].(float64)
+	}
+	return results, nil
+}
+
+func TestSelect_Join(t *testing.T) {
+	shardMapper := ShardMapper{
+		MapShardsFn: func(sources influxql.Sources, _ influxql.TimeRange) query.ShardGroup {
+			return &ShardGroup{
+				Fields: map[string]influxql.DataType{
+					"value": influxql.Float,
+				},
+				Dimensions: []string{"host"},
+				CreateIteratorFn: func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) (query.Iterator, error) {
+					var points []query.FloatPoint
+					switch m.Name {
+					case "errors":
+						points = []query.FloatPoint{
+							{Name: "errors", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 1},
+							{Name: "errors", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 3},
+							{Name: "errors", Tags: ParseTags("host=B"), Time: 0 * Second, Value: 2},
+						}
+					case "requests":
+						points = []query.FloatPoint{
+							{Name: "requests", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 10},
+							{Name: "requests", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 20},
+							{Name: "requests", Tags: ParseTags("host=B"), Time: 0 * Second, Value: 40},
+							{Name: "requests", Tags: ParseTags("host=B"), Time: 10 * Second, Value: 50},
+						}
+					default:
+						t.Fatalf("unexpected source: %s", m.Name)
+					}
+
+					for i := range points {
+						for _, ref := range opt.Aux {
+							switch ref.Val {
+							case "value":
+								points[i].Aux = append(points[i].Aux, points[i].Value)
+							case "host":
+								points[i].Aux = append(points[i].Aux, points[i].Tags.Value("host"))
+							default:
+								points[i].Aux = append(points[i].Aux, (*float64)(nil))
+							}
+						}
+					}
+
+					itr := &FloatIterator{Points: points}
+					if opt.Expr != nil {
+						return query.NewCallIterator(itr, opt)
+					}
+					return itr, nil
+				},
+			}
+		},
+	}
+
+	for _, test := range []struct {
+		Name      string
+		Statement string
+		Points    [][]query.Point
+	}{
+		{
+			Name:      "Raw",
+			Statement: `SELECT errors.value / requests.value FROM errors JOIN requests GROUP BY host`,
+			Points: [][]query.Point{
+				{&query.FloatPoint{Name: "errors_requests", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 0.1}},
+				{&query.FloatPoint{Name: "errors_requests", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 0.15}},
+				{&query.FloatPoint{Name: "errors_requests", Tags: ParseTags("host=B"), Time: 0 * Second, Value: 0.05}},
+				{&query.FloatPoint{Name: "errors_requests", Tags: ParseTags("host=B"), Time: 10 * Second, Nil: true}},
+			},
+		},
+		{
+			Name:      "Aggregate",
+			Statement: `SELECT sum(errors.value) / sum(requests.value) FROM errors JOIN requests WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:20Z' GROUP BY time(20s), host`,
+			Points: [][]query.Point{
+				{&query.FloatPoint{Name: "errors_requests", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 4.0 / 30, Aggregated: 2}},
+				{&query.FloatPoint{Name: "errors_requests", Tags: ParseTags("host=B"), Time: 0 * Second, Value: 2.0 / 90, Aggregated: 1}},
+			},
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			stmt := MustParseSelectStatement(test.Statement)
+			itrs, _, err := query.Select(context.Background(), stmt, &shardMapper, query.SelectOptions{})
+			if err != nil {
+				t.Errorf("%s: parse error: %s", test.Name, err)
+			} else if a, err := Iterators(itrs).ReadAll(); err != nil {
+				t.Fatalf("%s: unexpected error: %s", test.Name, err)
+			} else if diff := cmp.Diff(a, test.Points); diff != "" {
+				t.Errorf("%s: unexpected points:\n%s", test.Name, diff)
+			}
+		})
+	}
+}
+
 type ShardMapper struct {
 	MapShardsFn func(sources influxql.Sources, t influxql.TimeRange) query.ShardGroup
 }
@@ -3943,6 +4386,7 @@ func (m *ShardMapper) MapShards(sources influxql.Sources, t influxql.TimeRange,
 
 type ShardGroup struct {
 	CreateIteratorFn func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) (query.Iterator, error)
+	IteratorCostFn   func(m *influxql.Measurement, opt query.IteratorOptions) (query.IteratorCost, error)
 	Fields           map[string]influxql.DataType
 	Dimensions       []string
 }
@@ -3952,6 +4396,9 @@ func (sh *ShardGroup) CreateIterator(ctx context.Context, m *influxql.Measuremen
 }
 
 func (sh *ShardGroup) IteratorCost(m *influxql.Measurement, opt query.IteratorOptions) (query.IteratorCost, error) {
+	if sh.IteratorCostFn != nil {
+		return sh.IteratorCostFn(m, opt)
+	}
 	return query.IteratorCost{}, nil
 }
 
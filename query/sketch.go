@@ -0,0 +1,358 @@
+package query
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/influxdata/influxdb/pkg/estimator/hll"
+	"github.com/influxdata/influxdb/pkg/tdigest"
+)
+
+// The approximate functions count_hll() and percentile_tdigest() aggregate
+// their input into a sketch. The sketch is emitted as a string point so the
+// sketches from each shard can be merged before the estimate is calculated
+// by the query.
+
+// sketchMergeFunctions maps the approximate functions to the function that
+// merges their sketches.
+var sketchMergeFunctions = map[string]string{
+	"count_hll":          "merge_hll",
+	"percentile_tdigest": "merge_tdigest",
+}
+
+// HLLReducer adds the aggregated points to a HyperLogLog sketch.
+type HLLReducer struct {
+	sketch *hll.Plus
+	count  uint32
+	buf    [8]byte
+}
+
+// NewHLLReducer creates a new HLLReducer.
+func NewHLLReducer() *HLLReducer {
+	return &HLLReducer{sketch: hll.NewDefaultPlus()}
+}
+
+// AggregateFloat aggregates a point into the reducer.
+func (r *HLLReducer) AggregateFloat(p *FloatPoint) {
+	r.addUint64(math.Float64bits(p.Value))
+}
+
+// AggregateInteger aggregates a point into the reducer.
+func (r *HLLReducer) AggregateInteger(p *IntegerPoint) {
+	r.addUint64(uint64(p.Value))
+}
+
+// AggregateUnsigned aggregates a point into the reducer.
+func (r *HLLReducer) AggregateUnsigned(p *UnsignedPoint) {
+	r.addUint64(p.Value)
+}
+
+// AggregateString aggregates a point into the reducer.
+func (r *HLLReducer) AggregateString(p *StringPoint) {
+	r.sketch.Add([]byte(p.Value))
+	r.count++
+}
+
+// AggregateBoolean aggregates a point into the reducer.
+func (r *HLLReducer) AggregateBoolean(p *BooleanPoint) {
+	var v uint64
+	if p.Value {
+		v = 1
+	}
+	r.addUint64(v)
+}
+
+func (r *HLLReducer) addUint64(v uint64) {
+	binary.BigEndian.PutUint64(r.buf[:], v)
+	r.sketch.Add(r.buf[:])
+	r.count++
+}
+
+// Emit emits the sketch as a single point.
+func (r *HLLReducer) Emit() []StringPoint {
+	return emitHLL(r.sketch, r.count)
+}
+
+// HLLMergeReducer merges the HyperLogLog sketches of the aggregated points.
+type HLLMergeReducer struct {
+	sketch *hll.Plus
+	count  uint32
+}
+
+// NewHLLMergeReducer creates a new HLLMergeReducer.
+func NewHLLMergeReducer() *HLLMergeReducer {
+	return &HLLMergeReducer{sketch: hll.NewDefaultPlus()}
+}
+
+// AggregateString aggregates a point into the reducer. Points that do not
+// hold a valid sketch are ignored.
+func (r *HLLMergeReducer) AggregateString(p *StringPoint) {
+	sketch, err := decodeHLL(p.Value)
+	if err != nil {
+		return
+	}
+	if err := r.sketch.Merge(sketch); err != nil {
+		return
+	}
+	r.count += p.Aggregated
+}
+
+// Emit emits the merged sketch as a single point.
+func (r *HLLMergeReducer) Emit() []StringPoint {
+	return emitHLL(r.sketch, r.count)
+}
+
+func emitHLL(sketch *hll.Plus, count uint32) []StringPoint {
+	data, err := sketch.MarshalBinary()
+	if err != nil {
+		return nil
+	}
+	return []StringPoint{{
+		Time:       ZeroTime,
+		Value:      string(data),
+		Aggregated: count,
+	}}
+}
+
+func decodeHLL(s string) (*hll.Plus, error) {
+	if len(s) < 2 {
+		return nil, fmt.Errorf("invalid count_hll() sketch")
+	}
+	var sketch hll.Plus
+	if err := sketch.UnmarshalBinary([]byte(s)); err != nil {
+		return nil, err
+	}
+	return &sketch, nil
+}
+
+// TDigestReducer adds the aggregated points to a t-digest.
+type TDigestReducer struct {
+	digest *tdigest.TDigest
+}
+
+// NewTDigestReducer creates a new TDigestReducer.
+func NewTDigestReducer() *TDigestReducer {
+	return &TDigestReducer{digest: tdigest.New()}
+}
+
+// AggregateFloat aggregates a point into the reducer.
+func (r *TDigestReducer) AggregateFloat(p *FloatPoint) {
+	r.digest.Add(p.Value, 1)
+}
+
+// AggregateInteger aggregates a point into the reducer.
+func (r *TDigestReducer) AggregateInteger(p *IntegerPoint) {
+	r.digest.Add(float64(p.Value), 1)
+}
+
+// AggregateUnsigned aggregates a point into the reducer.
+func (r *TDigestReducer) AggregateUnsigned(p *UnsignedPoint) {
+	r.digest.Add(float64(p.Value), 1)
+}
+
+// AggregateString merges the digest of a point into the reducer. Points that
+// do not hold a valid digest are ignored.
+func (r *TDigestReducer) AggregateString(p *StringPoint) {
+	var digest tdigest.TDigest
+	if err := digest.UnmarshalBinary([]byte(p.Value)); err != nil {
+		return
+	}
+	r.digest.Merge(&digest)
+}
+
+// Emit emits the digest as a single point.
+func (r *TDigestReducer) Emit() []StringPoint {
+	data, err := r.digest.MarshalBinary()
+	if err != nil {
+		return nil
+	}
+	return []StringPoint{{
+		Time:       ZeroTime,
+		Value:      string(data),
+		Aggregated: uint32(r.digest.Count()),
+	}}
+}
+
+// newCountHLLIterator returns an iterator for operating on a count_hll() call.
+func newCountHLLIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
+	switch input := input.(type) {
+	case FloatIterator:
+		createFn := func() (FloatPointAggregator, StringPointEmitter) {
+			fn := NewHLLReducer()
+			return fn, fn
+		}
+		return newFloatReduceStringIterator(input, opt, createFn), nil
+	case IntegerIterator:
+		createFn := func() (IntegerPointAggregator, StringPointEmitter) {
+			fn := NewHLLReducer()
+			return fn, fn
+		}
+		return newIntegerReduceStringIterator(input, opt, createFn), nil
+	case UnsignedIterator:
+		createFn := func() (UnsignedPointAggregator, StringPointEmitter) {
+			fn := NewHLLReducer()
+			return fn, fn
+		}
+		return newUnsignedReduceStringIterator(input, opt, createFn), nil
+	case StringIterator:
+		createFn := func() (StringPointAggregator, StringPointEmitter) {
+			fn := NewHLLReducer()
+			return fn, fn
+		}
+		return newStringReduceStringIterator(input, opt, createFn), nil
+	case BooleanIterator:
+		createFn := func() (BooleanPointAggregator, StringPointEmitter) {
+			fn := NewHLLReducer()
+			return fn, fn
+		}
+		return newBooleanReduceStringIterator(input, opt, createFn), nil
+	default:
+		return nil, fmt.Errorf("unsupported count_hll iterator type: %T", input)
+	}
+}
+
+// newMergeHLLIterator returns an iterator that merges the sketches of count_hll().
+func newMergeHLLIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
+	switch input := input.(type) {
+	case StringIterator:
+		createFn := func() (StringPointAggregator, StringPointEmitter) {
+			fn := NewHLLMergeReducer()
+			return fn, fn
+		}
+		return newStringReduceStringIterator(input, opt, createFn), nil
+	default:
+		return nil, fmt.Errorf("unsupported merge_hll iterator type: %T", input)
+	}
+}
+
+// newPercentileTDigestIterator returns an iterator for operating on a
+// percentile_tdigest() call.
+func newPercentileTDigestIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
+	switch input := input.(type) {
+	case FloatIterator:
+		createFn := func() (FloatPointAggregator, StringPointEmitter) {
+			fn := NewTDigestReducer()
+			return fn, fn
+		}
+		return newFloatReduceStringIterator(input, opt, createFn), nil
+	case IntegerIterator:
+		createFn := func() (IntegerPointAggregator, StringPointEmitter) {
+			fn := NewTDigestReducer()
+			return fn, fn
+		}
+		return newIntegerReduceStringIterator(input, opt, createFn), nil
+	case UnsignedIterator:
+		createFn := func() (UnsignedPointAggregator, StringPointEmitter) {
+			fn := NewTDigestReducer()
+			return fn, fn
+		}
+		return newUnsignedReduceStringIterator(input, opt, createFn), nil
+	default:
+		return nil, fmt.Errorf("unsupported percentile_tdigest iterator type: %T", input)
+	}
+}
+
+// newMergeTDigestIterator returns an iterator that merges the digests of
+// percentile_tdigest().
+func newMergeTDigestIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
+	switch input := input.(type) {
+	case StringIterator:
+		createFn := func() (StringPointAggregator, StringPointEmitter) {
+			fn := NewTDigestReducer()
+			return fn, fn
+		}
+		return newStringReduceStringIterator(input, opt, createFn), nil
+	default:
+		return nil, fmt.Errorf("unsupported merge_tdigest iterator type: %T", input)
+	}
+}
+
+// newHLLEstimateIterator returns an iterator that emits the estimated count
+// of each count_hll() sketch.
+func newHLLEstimateIterator(input Iterator) (Iterator, error) {
+	switch input := input.(type) {
+	case StringIterator:
+		return &hllEstimateIterator{input: input}, nil
+	case *nilFloatIterator:
+		return input, nil
+	default:
+		return nil, fmt.Errorf("unsupported count_hll iterator type: %T", input)
+	}
+}
+
+type hllEstimateIterator struct {
+	input StringIterator
+}
+
+func (itr *hllEstimateIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *hllEstimateIterator) Close() error         { return itr.input.Close() }
+
+func (itr *hllEstimateIterator) Next() (*IntegerPoint, error) {
+	p, err := itr.input.Next()
+	if p == nil || err != nil {
+		return nil, err
+	}
+
+	out := &IntegerPoint{
+		Name:       p.Name,
+		Tags:       p.Tags,
+		Time:       p.Time,
+		Aggregated: p.Aggregated,
+		Nil:        p.Nil,
+	}
+	if !p.Nil {
+		sketch, err := decodeHLL(p.Value)
+		if err != nil {
+			return nil, err
+		}
+		out.Value = int64(sketch.Count())
+	}
+	return out, nil
+}
+
+// newTDigestQuantileIterator returns an iterator that emits the estimated
+// percentile of each percentile_tdigest() digest.
+func newTDigestQuantileIterator(input Iterator, percentile float64) (Iterator, error) {
+	switch input := input.(type) {
+	case StringIterator:
+		return &tdigestQuantileIterator{input: input, q: percentile / 100}, nil
+	case *nilFloatIterator:
+		return input, nil
+	default:
+		return nil, fmt.Errorf("unsupported percentile_tdigest iterator type: %T", input)
+	}
+}
+
+type tdigestQuantileIterator struct {
+	input StringIterator
+	q     float64
+}
+
+func (itr *tdigestQuantileIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *tdigestQuantileIterator) Close() error         { return itr.input.Close() }
+
+func (itr *tdigestQuantileIterator) Next() (*FloatPoint, error) {
+	p, err := itr.input.Next()
+	if p == nil || err != nil {
+		return nil, err
+	}
+
+	out := &FloatPoint{
+		Name:       p.Name,
+		Tags:       p.Tags,
+		Time:       p.Time,
+		Aggregated: p.Aggregated,
+		Nil:        true,
+	}
+	if !p.Nil {
+		var digest tdigest.TDigest
+		if err := digest.UnmarshalBinary([]byte(p.Value)); err != nil {
+			return nil, err
+		}
+		if v := digest.Quantile(itr.q); !math.IsNaN(v) {
+			out.Value, out.Nil = v, false
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,267 @@
+package query
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+)
+
+// SortKey is a column that the values of a row are sorted by.
+type SortKey struct {
+	// Index of the column in the values of the row.
+	Index int
+
+	// Sort order.
+	Ascending bool
+}
+
+// SortKeys returns the columns the rows of a statement are sorted by. It
+// returns nil if the rows are only sorted by time, which is the order the
+// values of a row are read in.
+func SortKeys(stmt *influxql.SelectStatement, columns []string) ([]SortKey, error) {
+	if stmt.SortedByTime() {
+		return nil, nil
+	}
+
+	keys := make([]SortKey, 0, len(stmt.SortFields))
+	seen := make(map[int]struct{}, len(stmt.SortFields))
+	for _, f := range stmt.SortFields {
+		index := -1
+		if f.IsTime() && !stmt.OmitTime {
+			index = 0
+		} else {
+			for i, name := range columns {
+				if name == f.Name {
+					index = i
+					break
+				}
+			}
+		}
+
+		if index < 0 {
+			return nil, fmt.Errorf("cannot ORDER BY %s: field is not selected", f.Name)
+		} else if _, ok := seen[index]; ok {
+			return nil, fmt.Errorf("duplicate ORDER BY field: %s", f.Name)
+		}
+		seen[index] = struct{}{}
+		keys = append(keys, SortKey{Index: index, Ascending: f.Ascending})
+	}
+	return keys, nil
+}
+
+// validateSortFields checks that the rows of a subquery are only sorted by
+// time. The rows of a subquery are read as points in time order.
+func validateSortFields(stmt *influxql.SelectStatement) error {
+	if !stmt.SortedByTime() {
+		return errors.New("subqueries can only be sorted by time")
+	}
+	return nil
+}
+
+// rowSorter sorts the values of a row by the sort keys. If limit is positive,
+// only the limit values that sort first are kept in a bounded heap. The
+// values held are accounted to memory.
+type rowSorter struct {
+	name   string
+	tags   Tags
+	keys   []SortKey
+	limit  int
+	items  []sortItem
+	seq    int
+	memory *MemoryAccountant
+	size   int // bytes accounted to memory
+}
+
+// sortItem is the values of a row at a point in time.
+type sortItem struct {
+	values []interface{}
+
+	// seq is the order the values were read in. It orders values that
+	// sort equally.
+	seq int
+}
+
+func newRowSorter(name string, tags Tags, keys []SortKey, limit int, memory *MemoryAccountant) *rowSorter {
+	return &rowSorter{
+		name:   name,
+		tags:   tags,
+		keys:   keys,
+		limit:  limit,
+		memory: memory,
+	}
+}
+
+// add adds the values of a row to the sorter. It returns an error if holding
+// the values would exceed the memory limit of the query.
+func (s *rowSorter) add(values []interface{}) error {
+	item := sortItem{values: values, seq: s.seq}
+	s.seq++
+
+	if s.limit > 0 && len(s.items) >= s.limit {
+		// Replace the values that sort last. The values of every row have
+		// the same size, so the memory held doesn't change.
+		if s.less(item, s.items[0]) {
+			s.items[0] = item
+			heap.Fix(s, 0)
+		}
+		return nil
+	}
+
+	n := sortedRowSize + len(values)*sortedValueSize
+	if err := s.memory.Grow(n); err != nil {
+		return err
+	}
+	s.size += n
+
+	if s.limit <= 0 {
+		s.items = append(s.items, item)
+	} else {
+		heap.Push(s, item)
+	}
+	return nil
+}
+
+// release removes the values held by the sorter from its memory.
+func (s *rowSorter) release() {
+	s.memory.Shrink(s.size)
+	s.size = 0
+}
+
+// sorted returns the values added to the sorter in sorted order.
+func (s *rowSorter) sorted() [][]interface{} {
+	sort.Slice(s.items, func(i, j int) bool { return s.less(s.items[i], s.items[j]) })
+
+	values := make([][]interface{}, len(s.items))
+	for i, item := range s.items {
+		values[i] = item.values
+	}
+	return values
+}
+
+// less returns true if the values of x sort before the values of y.
+func (s *rowSorter) less(x, y sortItem) bool {
+	for _, k := range s.keys {
+		if c := compareSortValues(x.values[k.Index], y.values[k.Index], k.Ascending); c != 0 {
+			return c < 0
+		}
+	}
+	return x.seq < y.seq
+}
+
+// Len, Less, Swap, Push, and Pop implement heap.Interface. The values that
+// sort last are at the top of the heap so they can be replaced.
+func (s *rowSorter) Len() int           { return len(s.items) }
+func (s *rowSorter) Less(i, j int) bool { return s.less(s.items[j], s.items[i]) }
+func (s *rowSorter) Swap(i, j int)      { s.items[i], s.items[j] = s.items[j], s.items[i] }
+
+func (s *rowSorter) Push(x interface{}) {
+	s.items = append(s.items, x.(sortItem))
+}
+
+func (s *rowSorter) Pop() interface{} {
+	item := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return item
+}
+
+// compareSortValues compares two values of a column. Null values sort after
+// every other value in either order.
+func compareSortValues(a, b interface{}, ascending bool) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return 1
+		default:
+			return -1
+		}
+	}
+
+	c := compareValues(a, b)
+	if !ascending {
+		c = -c
+	}
+	return c
+}
+
+// compareValues compares two non-null values. Numbers of different types
+// are compared by value. Values of other different types are ordered by type.
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int64:
+		if b, ok := b.(int64); ok {
+			return compareResult(a < b, a > b)
+		}
+	case uint64:
+		if b, ok := b.(uint64); ok {
+			return compareResult(a < b, a > b)
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b)
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			return compareResult(!a && b, a && !b)
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return compareResult(a.Before(b), a.After(b))
+		}
+	}
+
+	if x, ok := sortNumber(a); ok {
+		if y, ok := sortNumber(b); ok {
+			return compareResult(x < y, x > y)
+		}
+	}
+	return sortTypeRank(a) - sortTypeRank(b)
+}
+
+// compareResult returns the result of a comparison as -1, 0, or 1.
+func compareResult(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// sortNumber returns the value of a number as a float.
+func sortNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// sortTypeRank returns the order values of different types are sorted in.
+func sortTypeRank(v interface{}) int {
+	switch v.(type) {
+	case float64, int64, uint64:
+		return 0
+	case string:
+		return 1
+	case bool:
+		return 2
+	case time.Time:
+		return 3
+	default:
+		return 4
+	}
+}
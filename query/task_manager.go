@@ -49,6 +49,14 @@ type TaskManager struct {
 	// Maximum number of concurrent queries.
 	MaxConcurrentQueries int
 
+	// Maximum number of queries waiting for the concurrency limit.  Queries
+	// over the limit are rejected when no more can wait.
+	MaxQueuedQueries int
+
+	// UserPriorities are the priorities of the queries of users that don't
+	// request one.
+	UserPriorities map[string]Priority
+
 	// Logger to use for all logging.
 	// Defaults to discarding all log output.
 	Logger zap.Logger
@@ -58,6 +66,14 @@ type TaskManager struct {
 	nextID   uint64
 	mu       sync.RWMutex
 	shutdown bool
+
+	// Queries waiting for the concurrency limit, and the number of queries
+	// admitted from the queue that aren't attached yet.
+	queue    queryQueue
+	admitted int
+
+	// Closed once the last query is detached after a call to Drain.
+	drained chan struct{}
 }
 
 // NewTaskManager creates a new TaskManager.
@@ -70,6 +86,20 @@ func NewTaskManager() *TaskManager {
 	}
 }
 
+// SetLimits changes the query timeout, the slow query threshold, the
+// maximum number of concurrent and queued queries, and the priorities of
+// users.  Queries already running keep the timeout they started with.
+func (t *TaskManager) SetLimits(queryTimeout, logQueriesAfter time.Duration, maxConcurrentQueries, maxQueuedQueries int, userPriorities map[string]Priority) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.QueryTimeout = queryTimeout
+	t.LogQueriesAfter = logQueriesAfter
+	t.MaxConcurrentQueries = maxConcurrentQueries
+	t.MaxQueuedQueries = maxQueuedQueries
+	t.UserPriorities = userPriorities
+	t.admitQueued()
+}
+
 // ExecuteStatement executes a statement containing one of the task management queries.
 func (t *TaskManager) ExecuteStatement(stmt influxql.Statement, ctx ExecutionContext) error {
 	switch stmt := stmt.(type) {
@@ -125,11 +155,11 @@ func (t *TaskManager) executeShowQueriesStatement(q *influxql.ShowQueriesStateme
 			d = d - (d % time.Microsecond)
 		}
 
-		values = append(values, []interface{}{id, qi.query, qi.database, d.String(), qi.status.String()})
+		values = append(values, []interface{}{id, qi.query, qi.database, d.String(), qi.status.String(), qi.RowN(), qi.Memory()})
 	}
 
 	return []*models.Row{{
-		Columns: []string{"qid", "query", "database", "duration", "status"},
+		Columns: []string{"qid", "query", "database", "duration", "status", "rows", "memory"},
 		Values:  values,
 	}}, nil
 }
@@ -150,7 +180,12 @@ func (t *TaskManager) queryError(qid uint64, err error) {
 // query finishes running.
 //
 // After a query finishes running, the system is free to reuse a query id.
-func (t *TaskManager) AttachQuery(q *influxql.Query, database string, interrupt <-chan struct{}) (uint64, *QueryTask, error) {
+//
+// The query timeout in opt overrides the QueryTimeout of the TaskManager.
+//
+// Queries over the concurrency limit wait for a running query to finish, by
+// priority, if MaxQueuedQueries allows it.
+func (t *TaskManager) AttachQuery(q *influxql.Query, opt ExecutionOptions, interrupt <-chan struct{}) (uint64, *QueryTask, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -158,14 +193,20 @@ func (t *TaskManager) AttachQuery(q *influxql.Query, database string, interrupt
 		return 0, nil, ErrQueryEngineShutdown
 	}
 
-	if t.MaxConcurrentQueries > 0 && len(t.queries) >= t.MaxConcurrentQueries {
-		return 0, nil, ErrMaxConcurrentQueriesLimitExceeded(len(t.queries), t.MaxConcurrentQueries)
+	if t.full() || len(t.queue) > 0 {
+		if len(t.queue) >= t.MaxQueuedQueries {
+			return 0, nil, ErrMaxConcurrentQueriesLimitExceeded(len(t.queries), t.MaxConcurrentQueries)
+		}
+		if err := t.waitForAdmission(t.priority(opt), interrupt, opt.AbortCh); err != nil {
+			return 0, nil, err
+		}
 	}
 
 	qid := t.nextID
 	query := &QueryTask{
 		query:     q.String(),
-		database:  database,
+		database:  opt.Database,
+		user:      opt.User,
 		status:    RunningTask,
 		startTime: time.Now(),
 		closing:   make(chan struct{}),
@@ -173,16 +214,20 @@ func (t *TaskManager) AttachQuery(q *influxql.Query, database string, interrupt
 	}
 	t.queries[qid] = query
 
-	go t.waitForQuery(qid, query.closing, interrupt, query.monitorCh)
-	if t.LogQueriesAfter != 0 {
+	timeout := t.QueryTimeout
+	if opt.QueryTimeout > 0 {
+		timeout = opt.QueryTimeout
+	}
+	go t.waitForQuery(qid, timeout, query.closing, interrupt, query.monitorCh)
+	if logQueriesAfter := t.LogQueriesAfter; logQueriesAfter != 0 {
 		go query.monitor(func(closing <-chan struct{}) error {
-			timer := time.NewTimer(t.LogQueriesAfter)
+			timer := time.NewTimer(logQueriesAfter)
 			defer timer.Stop()
 
 			select {
 			case <-timer.C:
 				t.Logger.Warn(fmt.Sprintf("Detected slow query: %s (qid: %d, database: %s, threshold: %s)",
-					query.query, qid, query.database, t.LogQueriesAfter))
+					query.query, qid, query.database, logQueriesAfter))
 			case <-closing:
 			}
 			return nil
@@ -192,6 +237,85 @@ func (t *TaskManager) AttachQuery(q *influxql.Query, database string, interrupt
 	return qid, query, nil
 }
 
+// full returns true if no more queries can run.
+func (t *TaskManager) full() bool {
+	return t.MaxConcurrentQueries > 0 && len(t.queries)+t.admitted >= t.MaxConcurrentQueries
+}
+
+// priority returns the priority of a query: the priority it requests, or
+// the priority of its user.
+func (t *TaskManager) priority(opt ExecutionOptions) Priority {
+	if opt.Priority != PriorityDefault {
+		return opt.Priority
+	}
+	if p, ok := t.UserPriorities[opt.User]; ok && p != PriorityDefault {
+		return p
+	}
+	return PriorityInteractive
+}
+
+// waitForAdmission queues a query until it is admitted, interrupted or
+// aborted.  It must be called with the lock held, which it releases while
+// the query waits.
+func (t *TaskManager) waitForAdmission(p Priority, interrupt, abort <-chan struct{}) error {
+	w := &queuedQuery{priority: p, ready: make(chan struct{})}
+	t.queue.push(w)
+
+	t.mu.Unlock()
+	var err error
+	select {
+	case <-w.ready:
+	case <-interrupt:
+		err = ErrQueryInterrupted
+	case <-abort:
+		err = ErrQueryAborted
+	}
+	t.mu.Lock()
+
+	if !w.admitted {
+		if w.err != nil {
+			return w.err
+		}
+		t.queue.remove(w)
+		return err
+	}
+
+	t.admitted--
+	if t.shutdown {
+		return ErrQueryEngineShutdown
+	}
+	return nil
+}
+
+// admitQueued admits the queued queries, by priority, while the concurrency
+// limit allows it.  It must be called with the lock held.
+func (t *TaskManager) admitQueued() {
+	for len(t.queue) > 0 && !t.full() {
+		w := t.queue.pop()
+		w.admitted = true
+		t.admitted++
+		close(w.ready)
+	}
+}
+
+// rejectQueued rejects the queued queries with err.  It must be called with
+// the lock held.
+func (t *TaskManager) rejectQueued(err error) {
+	for len(t.queue) > 0 {
+		w := t.queue.pop()
+		w.err = err
+		close(w.ready)
+	}
+}
+
+// QueuedQueries returns the number of queries waiting for the concurrency
+// limit.
+func (t *TaskManager) QueuedQueries() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.queue)
+}
+
 // KillQuery enters a query into the killed state and closes the channel
 // from the TaskManager. This method can be used to forcefully terminate a
 // running query.
@@ -219,6 +343,11 @@ func (t *TaskManager) DetachQuery(qid uint64) error {
 
 	query.close()
 	delete(t.queries, qid)
+	t.admitQueued()
+	if t.drained != nil && len(t.queries) == 0 {
+		close(t.drained)
+		t.drained = nil
+	}
 	return nil
 }
 
@@ -227,7 +356,12 @@ type QueryInfo struct {
 	ID       uint64        `json:"id"`
 	Query    string        `json:"query"`
 	Database string        `json:"database"`
+	User     string        `json:"user,omitempty"`
 	Duration time.Duration `json:"duration"`
+	Status   string        `json:"status"`
+	Rows     int64         `json:"rows"`
+	Memory   int64         `json:"memory"`
+	SeriesN  int64         `json:"series"`
 }
 
 // Queries returns a list of all running queries with information about them.
@@ -238,20 +372,15 @@ func (t *TaskManager) Queries() []QueryInfo {
 	now := time.Now()
 	queries := make([]QueryInfo, 0, len(t.queries))
 	for id, qi := range t.queries {
-		queries = append(queries, QueryInfo{
-			ID:       id,
-			Query:    qi.query,
-			Database: qi.database,
-			Duration: now.Sub(qi.startTime),
-		})
+		queries = append(queries, qi.info(id, now))
 	}
 	return queries
 }
 
-func (t *TaskManager) waitForQuery(qid uint64, interrupt <-chan struct{}, closing <-chan struct{}, monitorCh <-chan error) {
+func (t *TaskManager) waitForQuery(qid uint64, timeout time.Duration, interrupt <-chan struct{}, closing <-chan struct{}, monitorCh <-chan error) {
 	var timerCh <-chan time.Time
-	if t.QueryTimeout != 0 {
-		timer := time.NewTimer(t.QueryTimeout)
+	if timeout != 0 {
+		timer := time.NewTimer(timeout)
 		timerCh = timer.C
 		defer timer.Stop()
 	}
@@ -274,16 +403,49 @@ func (t *TaskManager) waitForQuery(qid uint64, interrupt <-chan struct{}, closin
 	t.KillQuery(qid)
 }
 
+// Drain prevents new queries from being attached and waits for the running
+// queries to finish, for at most timeout.  It returns false if queries are
+// still running once the timeout elapses.
+func (t *TaskManager) Drain(timeout time.Duration) bool {
+	t.mu.Lock()
+	t.shutdown = true
+	t.rejectQueued(ErrQueryEngineShutdown)
+	if len(t.queries) == 0 {
+		t.mu.Unlock()
+		return true
+	}
+	if t.drained == nil {
+		t.drained = make(chan struct{})
+	}
+	drained := t.drained
+	t.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-drained:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 // Close kills all running queries and prevents new queries from being attached.
 func (t *TaskManager) Close() error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
 	t.shutdown = true
+	t.rejectQueued(ErrQueryEngineShutdown)
 	for _, query := range t.queries {
 		query.setError(ErrQueryEngineShutdown)
 		query.close()
 	}
 	t.queries = nil
+	if t.drained != nil {
+		close(t.drained)
+		t.drained = nil
+	}
 	return nil
 }
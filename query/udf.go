@@ -0,0 +1,475 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/influxdata/influxdb/influxql"
+)
+
+// ScalarFunction is a user-defined function that is applied to each point of
+// the first argument of a call. The remaining arguments of the call must be
+// literals.
+type ScalarFunction interface {
+	// Call returns the result of the function for the value of a point. The
+	// value is a float64, int64, uint64, string, or bool. A nil result means
+	// the point has no value.
+	Call(value interface{}, args []interface{}) (interface{}, error)
+}
+
+// BatchScalarFunction is a ScalarFunction that can be applied to the values
+// of several points at once, such as a function with a costly round trip for
+// each call.
+type BatchScalarFunction interface {
+	ScalarFunction
+
+	// CallBatch returns the results of the function for the values of
+	// several points, in the same order as the values.
+	CallBatch(values []interface{}, args []interface{}) ([]interface{}, error)
+}
+
+// AggregateFunction is a user-defined function that reduces the points of
+// each interval of the first argument of a call to a single value. The
+// remaining arguments of the call must be literals.
+type AggregateFunction interface {
+	// Reduce returns the result of the function for the values of the points
+	// within an interval. A nil result means the interval has no value.
+	Reduce(values []interface{}, args []interface{}) (interface{}, error)
+}
+
+// ScalarFunctionFunc is an adapter to allow the use of ordinary functions as
+// a ScalarFunction.
+type ScalarFunctionFunc func(value interface{}, args []interface{}) (interface{}, error)
+
+// Call calls fn(value, args).
+func (fn ScalarFunctionFunc) Call(value interface{}, args []interface{}) (interface{}, error) {
+	return fn(value, args)
+}
+
+// AggregateFunctionFunc is an adapter to allow the use of ordinary functions
+// as an AggregateFunction.
+type AggregateFunctionFunc func(values []interface{}, args []interface{}) (interface{}, error)
+
+// Reduce calls fn(values, args).
+func (fn AggregateFunctionFunc) Reduce(values []interface{}, args []interface{}) (interface{}, error) {
+	return fn(values, args)
+}
+
+// scalarFunctionBatchSize is the number of points a BatchScalarFunction is
+// applied to in each call.
+const scalarFunctionBatchSize = 1000
+
+// builtinFunctions are the names of the functions built into the query
+// engine. User-defined functions cannot replace them.
+var builtinFunctions = map[string]struct{}{
+	"count": {}, "count_hll": {}, "distinct": {}, "sum": {}, "mean": {}, "median": {}, "mode": {},
+	"stddev": {}, "spread": {}, "integral": {}, "min": {}, "max": {}, "first": {}, "last": {},
+	"percentile": {}, "percentile_tdigest": {}, "sample": {}, "top": {}, "bottom": {},
+	"derivative": {}, "non_negative_derivative": {}, "difference": {}, "non_negative_difference": {},
+	"cumulative_sum": {}, "moving_average": {}, "elapsed": {}, "holt_winters": {}, "holt_winters_with_fit": {},
+	"abs": {}, "ceil": {}, "floor": {}, "round": {}, "sqrt": {}, "log": {}, "pow": {}, "if": {},
+	"merge_hll": {}, "merge_tdigest": {},
+}
+
+// userFunctions holds the registered user-defined functions by name.
+var userFunctions = struct {
+	sync.RWMutex
+	scalar    map[string]ScalarFunction
+	aggregate map[string]AggregateFunction
+}{
+	scalar:    make(map[string]ScalarFunction),
+	aggregate: make(map[string]AggregateFunction),
+}
+
+// RegisterScalarFunction registers a user-defined scalar function so it can
+// be called by name in queries.
+func RegisterScalarFunction(name string, fn ScalarFunction) error {
+	userFunctions.Lock()
+	defer userFunctions.Unlock()
+	if err := validateUserFunctionName(name); err != nil {
+		return err
+	}
+	userFunctions.scalar[name] = fn
+	influxql.RegisterUserFunction(name, false)
+	return nil
+}
+
+// RegisterAggregateFunction registers a user-defined aggregate function so it
+// can be called by name in queries.
+func RegisterAggregateFunction(name string, fn AggregateFunction) error {
+	userFunctions.Lock()
+	defer userFunctions.Unlock()
+	if err := validateUserFunctionName(name); err != nil {
+		return err
+	}
+	userFunctions.aggregate[name] = fn
+	influxql.RegisterUserFunction(name, true)
+	return nil
+}
+
+// validateUserFunctionName returns an error if a function cannot be
+// registered with name. The lock on userFunctions must be held.
+func validateUserFunctionName(name string) error {
+	if name == "" {
+		return errors.New("function name must not be empty")
+	} else if _, ok := builtinFunctions[name]; ok {
+		return fmt.Errorf("cannot register function %s(): function is built in", name)
+	} else if _, ok := userFunctions.scalar[name]; ok {
+		return fmt.Errorf("function %s() is already registered", name)
+	} else if _, ok := userFunctions.aggregate[name]; ok {
+		return fmt.Errorf("function %s() is already registered", name)
+	}
+	return nil
+}
+
+// UnregisterFunction removes a user-defined function.
+func UnregisterFunction(name string) {
+	userFunctions.Lock()
+	defer userFunctions.Unlock()
+	_, scalar := userFunctions.scalar[name]
+	_, aggregate := userFunctions.aggregate[name]
+	if scalar || aggregate {
+		delete(userFunctions.scalar, name)
+		delete(userFunctions.aggregate, name)
+		influxql.UnregisterUserFunction(name)
+	}
+}
+
+// lookupScalarFunction returns the user-defined scalar function registered
+// with name.
+func lookupScalarFunction(name string) (ScalarFunction, bool) {
+	userFunctions.RLock()
+	fn, ok := userFunctions.scalar[name]
+	userFunctions.RUnlock()
+	return fn, ok
+}
+
+// lookupAggregateFunction returns the user-defined aggregate function
+// registered with name.
+func lookupAggregateFunction(name string) (AggregateFunction, bool) {
+	userFunctions.RLock()
+	fn, ok := userFunctions.aggregate[name]
+	userFunctions.RUnlock()
+	return fn, ok
+}
+
+// userFunctionArgs returns the values of the literal arguments of a call to
+// a user-defined function. The first argument is what the function is
+// applied to and is not included.
+func userFunctionArgs(expr *influxql.Call) ([]interface{}, error) {
+	args := make([]interface{}, 0, len(expr.Args)-1)
+	for _, arg := range expr.Args[1:] {
+		switch arg := arg.(type) {
+		case *influxql.NumberLiteral:
+			args = append(args, arg.Val)
+		case *influxql.IntegerLiteral:
+			args = append(args, arg.Val)
+		case *influxql.UnsignedLiteral:
+			args = append(args, arg.Val)
+		case *influxql.StringLiteral:
+			args = append(args, arg.Val)
+		case *influxql.BooleanLiteral:
+			args = append(args, arg.Val)
+		case *influxql.DurationLiteral:
+			args = append(args, int64(arg.Val))
+		default:
+			return nil, fmt.Errorf("expected literal argument in %s(), got %s", expr.Name, arg)
+		}
+	}
+	return args, nil
+}
+
+// userFunctionResult converts the result of a user-defined function to a
+// float. It returns false if the result has no value.
+func userFunctionResult(name string, v interface{}) (float64, bool, error) {
+	var f float64
+	switch v := v.(type) {
+	case nil:
+		return 0, false, nil
+	case float64:
+		f = v
+	case float32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case int:
+		f = float64(v)
+	case uint64:
+		f = float64(v)
+	default:
+		return 0, false, fmt.Errorf("unsupported result type in %s(): %T", name, v)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false, nil
+	}
+	return f, true, nil
+}
+
+// buildScalarFunctionIterator creates an iterator that applies a
+// user-defined scalar function to each point of its input.
+func buildScalarFunctionIterator(expr *influxql.Call, fn ScalarFunction, input Iterator) (Iterator, error) {
+	args, err := userFunctionArgs(expr)
+	if err != nil {
+		input.Close()
+		return nil, err
+	}
+
+	itr := &scalarFunctionIterator{name: expr.Name, fn: fn, args: args, input: input}
+	switch input := input.(type) {
+	case FloatIterator:
+		itr.next = func() (*FloatPoint, interface{}, error) {
+			p, err := input.Next()
+			if p == nil || err != nil {
+				return nil, nil, err
+			}
+			return &FloatPoint{Name: p.Name, Tags: p.Tags, Time: p.Time, Aggregated: p.Aggregated, Nil: p.Nil}, p.Value, nil
+		}
+	case IntegerIterator:
+		itr.next = func() (*FloatPoint, interface{}, error) {
+			p, err := input.Next()
+			if p == nil || err != nil {
+				return nil, nil, err
+			}
+			return &FloatPoint{Name: p.Name, Tags: p.Tags, Time: p.Time, Aggregated: p.Aggregated, Nil: p.Nil}, p.Value, nil
+		}
+	case UnsignedIterator:
+		itr.next = func() (*FloatPoint, interface{}, error) {
+			p, err := input.Next()
+			if p == nil || err != nil {
+				return nil, nil, err
+			}
+			return &FloatPoint{Name: p.Name, Tags: p.Tags, Time: p.Time, Aggregated: p.Aggregated, Nil: p.Nil}, p.Value, nil
+		}
+	case StringIterator:
+		itr.next = func() (*FloatPoint, interface{}, error) {
+			p, err := input.Next()
+			if p == nil || err != nil {
+				return nil, nil, err
+			}
+			return &FloatPoint{Name: p.Name, Tags: p.Tags, Time: p.Time, Aggregated: p.Aggregated, Nil: p.Nil}, p.Value, nil
+		}
+	case BooleanIterator:
+		itr.next = func() (*FloatPoint, interface{}, error) {
+			p, err := input.Next()
+			if p == nil || err != nil {
+				return nil, nil, err
+			}
+			return &FloatPoint{Name: p.Name, Tags: p.Tags, Time: p.Time, Aggregated: p.Aggregated, Nil: p.Nil}, p.Value, nil
+		}
+	default:
+		input.Close()
+		return nil, fmt.Errorf("unsupported type in %s(): %s", expr.Name, iteratorDataType(input))
+	}
+	return itr, nil
+}
+
+// scalarFunctionIterator applies a user-defined scalar function to each point
+// of an iterator of any type. A BatchScalarFunction is applied to batches of
+// points read ahead of the output.
+type scalarFunctionIterator struct {
+	name  string
+	fn    ScalarFunction
+	args  []interface{}
+	input Iterator
+
+	// next reads the next point of the input. It returns the point to
+	// output and the value the function is applied to.
+	next func() (*FloatPoint, interface{}, error)
+
+	// buf holds the points of the current batch, and i the next one to
+	// output.
+	buf []*FloatPoint
+	i   int
+}
+
+func (itr *scalarFunctionIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *scalarFunctionIterator) Close() error         { return itr.input.Close() }
+
+func (itr *scalarFunctionIterator) Next() (*FloatPoint, error) {
+	if fn, ok := itr.fn.(BatchScalarFunction); ok {
+		if itr.i >= len(itr.buf) {
+			if err := itr.readBatch(fn); err != nil {
+				return nil, err
+			} else if len(itr.buf) == 0 {
+				return nil, nil
+			}
+		}
+		p := itr.buf[itr.i]
+		itr.i++
+		return p, nil
+	}
+
+	p, value, err := itr.next()
+	if p == nil || err != nil || p.Nil {
+		return p, err
+	}
+
+	v, err := itr.fn.Call(value, itr.args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %s", itr.name, err)
+	}
+	f, ok, err := userFunctionResult(itr.name, v)
+	if err != nil {
+		return nil, err
+	}
+	p.Value, p.Nil = f, !ok
+	return p, nil
+}
+
+// readBatch reads the next batch of points of the input into buf, and applies
+// the function to the values of the points in a single call.
+func (itr *scalarFunctionIterator) readBatch(fn BatchScalarFunction) error {
+	itr.buf, itr.i = itr.buf[:0], 0
+
+	var points []*FloatPoint
+	var values []interface{}
+	for len(itr.buf) < scalarFunctionBatchSize {
+		p, value, err := itr.next()
+		if err != nil {
+			return err
+		} else if p == nil {
+			break
+		}
+		itr.buf = append(itr.buf, p)
+		if !p.Nil {
+			points = append(points, p)
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	results, err := fn.CallBatch(values, itr.args)
+	if err != nil {
+		return fmt.Errorf("%s(): %s", itr.name, err)
+	} else if len(results) != len(values) {
+		return fmt.Errorf("%s(): expected %d results, got %d", itr.name, len(values), len(results))
+	}
+	for i, v := range results {
+		f, ok, err := userFunctionResult(itr.name, v)
+		if err != nil {
+			return err
+		}
+		points[i].Value, points[i].Nil = f, !ok
+	}
+	return nil
+}
+
+// newAggregateFunctionIterator returns an iterator that reduces the points of
+// each interval of its input with a user-defined aggregate function.
+func newAggregateFunctionIterator(input Iterator, opt IteratorOptions, expr *influxql.Call, fn AggregateFunction) (Iterator, error) {
+	args, err := userFunctionArgs(expr)
+	if err != nil {
+		input.Close()
+		return nil, err
+	}
+
+	// reduce applies the function to the values of the points of an
+	// interval. The points of an interval have no value when there are no
+	// points or the function returns no value.
+	var reduceErr error
+	reduce := func(values []interface{}) []FloatPoint {
+		if reduceErr != nil {
+			return nil
+		}
+		v, err := fn.Reduce(values, args)
+		if err != nil {
+			reduceErr = fmt.Errorf("%s(): %s", expr.Name, err)
+			return nil
+		}
+		f, ok, err := userFunctionResult(expr.Name, v)
+		if err != nil {
+			reduceErr = err
+			return nil
+		} else if !ok {
+			return []FloatPoint{{Time: ZeroTime, Nil: true}}
+		}
+		return []FloatPoint{{Time: ZeroTime, Value: f}}
+	}
+
+	var itr FloatIterator
+	switch input := input.(type) {
+	case FloatIterator:
+		createFn := func() (FloatPointAggregator, FloatPointEmitter) {
+			fn := NewFloatSliceFuncReducer(func(a []FloatPoint) []FloatPoint {
+				values := make([]interface{}, len(a))
+				for i := range a {
+					values[i] = a[i].Value
+				}
+				return reduce(values)
+			})
+			return fn, fn
+		}
+		itr = newFloatReduceFloatIterator(input, opt, createFn)
+	case IntegerIterator:
+		createFn := func() (IntegerPointAggregator, FloatPointEmitter) {
+			fn := NewIntegerSliceFuncFloatReducer(func(a []IntegerPoint) []FloatPoint {
+				values := make([]interface{}, len(a))
+				for i := range a {
+					values[i] = a[i].Value
+				}
+				return reduce(values)
+			})
+			return fn, fn
+		}
+		itr = newIntegerReduceFloatIterator(input, opt, createFn)
+	case UnsignedIterator:
+		createFn := func() (UnsignedPointAggregator, FloatPointEmitter) {
+			fn := NewUnsignedSliceFuncFloatReducer(func(a []UnsignedPoint) []FloatPoint {
+				values := make([]interface{}, len(a))
+				for i := range a {
+					values[i] = a[i].Value
+				}
+				return reduce(values)
+			})
+			return fn, fn
+		}
+		itr = newUnsignedReduceFloatIterator(input, opt, createFn)
+	case StringIterator:
+		createFn := func() (StringPointAggregator, FloatPointEmitter) {
+			fn := NewStringSliceFuncFloatReducer(func(a []StringPoint) []FloatPoint {
+				values := make([]interface{}, len(a))
+				for i := range a {
+					values[i] = a[i].Value
+				}
+				return reduce(values)
+			})
+			return fn, fn
+		}
+		itr = newStringReduceFloatIterator(input, opt, createFn)
+	case BooleanIterator:
+		createFn := func() (BooleanPointAggregator, FloatPointEmitter) {
+			fn := NewBooleanSliceFuncFloatReducer(func(a []BooleanPoint) []FloatPoint {
+				values := make([]interface{}, len(a))
+				for i := range a {
+					values[i] = a[i].Value
+				}
+				return reduce(values)
+			})
+			return fn, fn
+		}
+		itr = newBooleanReduceFloatIterator(input, opt, createFn)
+	default:
+		input.Close()
+		return nil, fmt.Errorf("unsupported type in %s(): %s", expr.Name, iteratorDataType(input))
+	}
+	return &aggregateFunctionIterator{FloatIterator: itr, err: &reduceErr}, nil
+}
+
+// aggregateFunctionIterator returns the error of a user-defined aggregate
+// function once the reducer fails.
+type aggregateFunctionIterator struct {
+	FloatIterator
+	err *error
+}
+
+func (itr *aggregateFunctionIterator) Next() (*FloatPoint, error) {
+	p, err := itr.FloatIterator.Next()
+	if *itr.err != nil {
+		return nil, *itr.err
+	}
+	return p, err
+}
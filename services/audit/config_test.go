@@ -0,0 +1,40 @@
+package audit_test
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/audit"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	c := audit.NewConfig()
+	if _, err := toml.Decode(`
+enabled = true
+path = "/var/log/influxdb/audit.log"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.Enabled {
+		t.Fatalf("unexpected enabled state: %v", c.Enabled)
+	} else if c.Path != "/var/log/influxdb/audit.log" {
+		t.Fatalf("unexpected path: %s", c.Path)
+	} else if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail: %s", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := audit.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail from NewConfig: %s", err)
+	}
+
+	c.Enabled = true
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for empty path, got nil")
+	}
+}
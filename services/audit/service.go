@@ -0,0 +1,308 @@
+// Package audit provides a service that records administrative and
+// data-modifying actions to an append-only audit log.
+package audit // import "github.com/influxdata/influxdb/services/audit"
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/uber-go/zap"
+)
+
+// Statistics for the audit service.
+const (
+	statEntriesWritten = "entriesWritten"
+	statWriteErr       = "writeErr"
+)
+
+// Actions recorded in the audit log.
+const (
+	// ActionStatement is recorded when a statement is executed.
+	ActionStatement = "statement"
+
+	// ActionBackup is recorded when a backup of the meta store or of a shard
+	// is taken.
+	ActionBackup = "backup"
+
+	// ActionRestore is recorded when a backup is restored by influxd restore.
+	ActionRestore = "restore"
+
+	// ActionMetaImport is recorded when an export is imported into the meta
+	// store by influxd meta-import.
+	ActionMetaImport = "meta-import"
+
+	// ActionSchema is recorded when the schema of a database is set or
+	// removed.
+	ActionSchema = "schema"
+
+	// ActionConfigReload is recorded when the configuration file is reloaded.
+	ActionConfigReload = "config-reload"
+
+	// ActionLogLevel is recorded when the level of the logs is changed.
+	ActionLogLevel = "log-level"
+
+	// ActionKillQuery is recorded when a query is killed over HTTP.
+	ActionKillQuery = "kill-query"
+)
+
+// Entry is a single record of the audit log.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+
+	// User and remote address the action was requested by, if known.
+	User string `json:"user,omitempty"`
+	Addr string `json:"addr,omitempty"`
+
+	Database  string `json:"database,omitempty"`
+	Statement string `json:"statement,omitempty"`
+
+	// Target is what the action was taken on, such as the shard backed up or
+	// the database whose schema changed.
+	Target string `json:"target,omitempty"`
+
+	// Error is set if the action failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Service appends an entry to the audit log for every administrative or
+// data-modifying statement executed, for every backup taken and for the
+// other administrative actions of the server.  Entries are
+// written as JSON, one per line, and synced to disk before the service
+// returns.
+type Service struct {
+	config Config
+
+	mu sync.Mutex
+	f  *os.File
+
+	stats  *Statistics
+	Logger zap.Logger
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		config: c,
+		stats:  &Statistics{},
+		Logger: zap.New(zap.NullEncoder()),
+	}
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log zap.Logger) {
+	s.Logger = log.With(zap.String("service", "audit"))
+}
+
+// Open opens the audit log for appending.
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f != nil {
+		return nil
+	}
+
+	s.Logger.Info(fmt.Sprintf("Starting audit service, logging to %s", s.config.Path))
+
+	if err := os.MkdirAll(filepath.Dir(s.config.Path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.config.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	s.f = f
+	return nil
+}
+
+// Close closes the audit log.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// Statistics maintains the statistics for the audit service.
+type Statistics struct {
+	EntriesWritten int64
+	WriteErr       int64
+}
+
+// Statistics returns statistics for periodic monitoring.
+func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	return []models.Statistic{{
+		Name: "audit",
+		Tags: tags,
+		Values: map[string]interface{}{
+			statEntriesWritten: atomic.LoadInt64(&s.stats.EntriesWritten),
+			statWriteErr:       atomic.LoadInt64(&s.stats.WriteErr),
+		},
+	}}
+}
+
+// AuditStatement records the execution of stmt, if it is an administrative
+// or data-modifying statement.
+func (s *Service) AuditStatement(stmt influxql.Statement, opt query.ExecutionOptions, err error) {
+	if !Auditable(stmt) {
+		return
+	}
+
+	e := Entry{
+		Action:    ActionStatement,
+		User:      opt.User,
+		Addr:      opt.RemoteAddr,
+		Database:  opt.Database,
+		Statement: stmt.String(),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	s.Record(e)
+}
+
+// AuditBackup records a backup of target requested from addr.
+func (s *Service) AuditBackup(addr, target string, err error) {
+	e := Entry{
+		Action: ActionBackup,
+		Addr:   addr,
+		Target: target,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	s.Record(e)
+}
+
+// AuditAction records action, taken on target by user from addr.
+func (s *Service) AuditAction(action, user, addr, target string, err error) {
+	e := Entry{
+		Action: action,
+		User:   user,
+		Addr:   addr,
+		Target: target,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	s.Record(e)
+}
+
+// Record appends e to the audit log.  The time of e is set if it is zero.
+func (s *Service) Record(e Entry) {
+	b, err := encodeEntry(e)
+	if err != nil {
+		s.writeErr(err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		s.writeErr(fmt.Errorf("audit log closed"))
+		return
+	}
+	if _, err := s.f.Write(b); err != nil {
+		s.writeErr(err)
+		return
+	} else if err := s.f.Sync(); err != nil {
+		s.writeErr(err)
+		return
+	}
+	atomic.AddInt64(&s.stats.EntriesWritten, 1)
+}
+
+// Append appends e to the audit log at path, for commands that run while the
+// server, and so the service, is stopped.  The time of e is set if it is
+// zero.
+func Append(path string, e Entry) error {
+	b, err := encodeEntry(e)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	} else if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// encodeEntry returns the line of e in the audit log.
+func encodeEntry(e Entry) ([]byte, error) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// writeErr logs and counts a failure to write an entry.
+func (s *Service) writeErr(err error) {
+	atomic.AddInt64(&s.stats.WriteErr, 1)
+	s.Logger.Info(fmt.Sprintf("WARN: unable to write audit entry: %s", err))
+}
+
+// Auditable returns true if stmt manages users, privileges, databases,
+// quotas, retention policies, continuous queries, subscriptions or queries, or
+// deletes data.
+func Auditable(stmt influxql.Statement) bool {
+	switch stmt.(type) {
+	case *influxql.CreateUserStatement,
+		*influxql.DropUserStatement,
+		*influxql.SetPasswordUserStatement,
+		*influxql.CreateTokenStatement,
+		*influxql.DropTokenStatement,
+		*influxql.GrantStatement,
+		*influxql.GrantAdminStatement,
+		*influxql.RevokeStatement,
+		*influxql.RevokeAdminStatement,
+		*influxql.CreateDatabaseStatement,
+		*influxql.DropDatabaseStatement,
+		*influxql.AlterDatabaseStatement,
+		*influxql.CreateRetentionPolicyStatement,
+		*influxql.AlterRetentionPolicyStatement,
+		*influxql.DropRetentionPolicyStatement,
+		*influxql.CreateContinuousQueryStatement,
+		*influxql.DropContinuousQueryStatement,
+		*influxql.CreateSubscriptionStatement,
+		*influxql.DropSubscriptionStatement,
+		*influxql.DeleteStatement,
+		*influxql.DeleteSeriesStatement,
+		*influxql.DropSeriesStatement,
+		*influxql.DropMeasurementStatement,
+		*influxql.DropShardStatement,
+		*influxql.KillQueryStatement:
+		return true
+	}
+	return false
+}
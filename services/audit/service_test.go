@@ -0,0 +1,106 @@
+package audit_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/audit"
+)
+
+func TestService_Audit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := audit.NewConfig()
+	c.Enabled = true
+	c.Path = filepath.Join(dir, "log", "audit.log")
+	s := audit.NewService(c)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	opt := query.ExecutionOptions{Database: "db0", User: "fred", RemoteAddr: "127.0.0.1:1234"}
+	for _, q := range []string{
+		`DROP DATABASE db0`,
+		`SELECT * FROM cpu`,
+		`CREATE USER wilma WITH PASSWORD 'secret'`,
+	} {
+		s.AuditStatement(influxql.MustParseStatement(q), opt, nil)
+	}
+	s.AuditStatement(influxql.MustParseStatement(`DROP MEASUREMENT cpu`), opt, errors.New("measurement not found"))
+	s.AuditBackup("127.0.0.1:5678", "meta", nil)
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries are appended to the log when it is opened again.
+	s = audit.NewService(c)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	s.AuditBackup("127.0.0.1:5678", "shard 1", nil)
+	s.AuditAction(audit.ActionSchema, "fred", "127.0.0.1:1234", "db0", nil)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Commands run while the server is stopped append to the same log.
+	if err := audit.Append(c.Path, audit.Entry{Action: audit.ActionRestore, Target: "database db0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(c.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []audit.Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e audit.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 7 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if e := entries[0]; e.Action != audit.ActionStatement || e.User != "fred" || e.Addr != "127.0.0.1:1234" || e.Database != "db0" || e.Statement != "DROP DATABASE db0" || e.Time.IsZero() {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e := entries[1]; e.Statement != "CREATE USER wilma WITH PASSWORD [REDACTED]" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e := entries[2]; e.Statement != "DROP MEASUREMENT cpu" || e.Error != "measurement not found" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e := entries[3]; e.Action != audit.ActionBackup || e.Addr != "127.0.0.1:5678" || e.Target != "meta" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e := entries[4]; e.Target != "shard 1" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e := entries[5]; e.Action != audit.ActionSchema || e.User != "fred" || e.Addr != "127.0.0.1:1234" || e.Target != "db0" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e := entries[6]; e.Action != audit.ActionRestore || e.Target != "database db0" || e.Time.IsZero() {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+}
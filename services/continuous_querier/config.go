@@ -2,6 +2,8 @@ package continuous_querier
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/influxdata/influxdb/monitor/diagnostics"
@@ -12,6 +14,13 @@ import (
 const (
 	// The default value of how often to check whether any CQs need to be run.
 	DefaultRunInterval = time.Second
+
+	// DefaultAlertTimeout is the default timeout for posting an alert to the webhook.
+	DefaultAlertTimeout = 10 * time.Second
+
+	// DefaultMaxConcurrentQueries is the default number of CQs that can run at
+	// the same time.
+	DefaultMaxConcurrentQueries = 1
 )
 
 // Config represents a configuration for the continuous query service.
@@ -31,15 +40,33 @@ type Config struct {
 	// every minute, this should be set to 1 minute. The default is set to '1s' so the interval
 	// is compatible with most aggregations.
 	RunInterval toml.Duration `toml:"run-interval"`
+
+	// MaxJitter is the maximum duration each CQ is delayed by. Every CQ is
+	// delayed by its own fixed amount up to this duration, and no more than its
+	// resample interval, so CQs on the same interval do not all run at once.
+	MaxJitter toml.Duration `toml:"max-jitter"`
+
+	// MaxConcurrentQueries is the maximum number of CQs that can run at the
+	// same time. Zero means no limit.
+	MaxConcurrentQueries int `toml:"max-concurrent-queries"`
+
+	// AlertWebhookURL is the URL an alert is posted to when a continuous query fails.
+	// Alerts are disabled if it is empty.
+	AlertWebhookURL string `toml:"alert-webhook-url"`
+
+	// AlertTimeout is the timeout for posting an alert to the webhook.
+	AlertTimeout toml.Duration `toml:"alert-timeout"`
 }
 
 // NewConfig returns a new instance of Config with defaults.
 func NewConfig() Config {
 	return Config{
-		LogEnabled:        true,
-		Enabled:           true,
-		QueryStatsEnabled: false,
-		RunInterval:       toml.Duration(DefaultRunInterval),
+		LogEnabled:           true,
+		Enabled:              true,
+		QueryStatsEnabled:    false,
+		RunInterval:          toml.Duration(DefaultRunInterval),
+		MaxConcurrentQueries: DefaultMaxConcurrentQueries,
+		AlertTimeout:         toml.Duration(DefaultAlertTimeout),
 	}
 }
 
@@ -55,6 +82,24 @@ func (c Config) Validate() error {
 		return errors.New("run-interval must be positive")
 	}
 
+	if c.MaxJitter < 0 {
+		return errors.New("max-jitter must not be negative")
+	}
+	if c.MaxConcurrentQueries < 0 {
+		return errors.New("max-concurrent-queries must not be negative")
+	}
+
+	if c.AlertWebhookURL != "" {
+		if u, err := url.Parse(c.AlertWebhookURL); err != nil {
+			return fmt.Errorf("invalid alert-webhook-url: %s", err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return errors.New("alert-webhook-url scheme must be http or https")
+		}
+	}
+	if c.AlertTimeout < 0 {
+		return errors.New("alert-timeout must not be negative")
+	}
+
 	return nil
 }
 
@@ -67,8 +112,11 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 	}
 
 	return diagnostics.RowFromMap(map[string]interface{}{
-		"enabled":             true,
-		"query-stats-enabled": c.QueryStatsEnabled,
-		"run-interval":        c.RunInterval,
+		"enabled":                true,
+		"query-stats-enabled":    c.QueryStatsEnabled,
+		"run-interval":           c.RunInterval,
+		"max-jitter":             c.MaxJitter,
+		"max-concurrent-queries": c.MaxConcurrentQueries,
+		"alert-webhook":          c.AlertWebhookURL != "",
 	}), nil
 }
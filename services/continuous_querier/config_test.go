@@ -43,4 +43,29 @@ func TestConfig_Validate(t *testing.T) {
 	if err := c.Validate(); err == nil {
 		t.Fatal("expected error for negative run-interval, got nil")
 	}
+
+	c = continuous_querier.NewConfig()
+	c.MaxJitter = -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for negative max-jitter, got nil")
+	}
+
+	c = continuous_querier.NewConfig()
+	c.MaxConcurrentQueries = -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for negative max-concurrent-queries, got nil")
+	}
+
+	c = continuous_querier.NewConfig()
+	c.AlertWebhookURL = "ftp://example.com/alert"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for non-http alert-webhook-url, got nil")
+	}
+
+	c = continuous_querier.NewConfig()
+	c.AlertWebhookURL = "http://example.com/alert"
+	c.AlertTimeout *= -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for negative alert-timeout, got nil")
+	}
 }
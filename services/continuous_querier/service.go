@@ -4,6 +4,8 @@ package continuous_querier // import "github.com/influxdata/influxdb/services/co
 import (
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"net/http"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -31,6 +33,12 @@ const (
 const (
 	statQueryOK   = "queryOk"
 	statQueryFail = "queryFail"
+
+	statLastRun       = "lastRun"
+	statDuration      = "durationNs"
+	statPointsWritten = "pointsWritten"
+	statRuns          = "runs"
+	statFailures      = "failures"
 )
 
 // ContinuousQuerier represents a service that executes continuous queries.
@@ -96,6 +104,23 @@ type Service struct {
 	lastRuns map[string]time.Time
 	stop     chan struct{}
 	wg       *sync.WaitGroup
+
+	// configMu guards the settings that can be changed by SetConfig while
+	// the service runs.
+	configMu sync.RWMutex
+
+	// statuses maps CQ name to its execution status.
+	statusMu sync.Mutex
+	statuses map[string]*Status
+
+	// alerts tracks the alerts being posted to the webhook.
+	alerts     sync.WaitGroup
+	httpClient *http.Client
+
+	// ReadOnly, if set, reports whether the node is a read-only replica.
+	// A replica doesn't run continuous queries, their results are
+	// replicated from the primary instead.
+	ReadOnly func() bool
 }
 
 // NewService returns a new instance of Service.
@@ -110,11 +135,48 @@ func NewService(c Config) *Service {
 		Logger:            zap.New(zap.NullEncoder()),
 		stats:             &Statistics{},
 		lastRuns:          map[string]time.Time{},
+		statuses:          map[string]*Status{},
+		httpClient:        &http.Client{Timeout: time.Duration(c.AlertTimeout)},
 	}
 
 	return s
 }
 
+// SetConfig applies the settings of c that can be changed while the service
+// runs: logging, query statistics, the run interval, the max jitter, the max
+// concurrent queries and the alert webhook.
+func (s *Service) SetConfig(c Config) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	s.Config.LogEnabled = c.LogEnabled
+	s.Config.QueryStatsEnabled = c.QueryStatsEnabled
+	s.Config.RunInterval = c.RunInterval
+	s.Config.MaxJitter = c.MaxJitter
+	s.Config.MaxConcurrentQueries = c.MaxConcurrentQueries
+	s.Config.AlertWebhookURL = c.AlertWebhookURL
+	s.Config.AlertTimeout = c.AlertTimeout
+
+	s.RunInterval = time.Duration(c.RunInterval)
+	s.loggingEnabled = c.LogEnabled
+	s.queryStatsEnabled = c.QueryStatsEnabled
+	s.httpClient = &http.Client{Timeout: time.Duration(c.AlertTimeout)}
+}
+
+// config returns a copy of the config of the service.
+func (s *Service) config() Config {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return *s.Config
+}
+
+// runInterval returns how often the service checks for CQs to run.
+func (s *Service) runInterval() time.Duration {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.RunInterval
+}
+
 // Open starts the service.
 func (s *Service) Open() error {
 	s.Logger.Info("Starting continuous query service")
@@ -140,6 +202,7 @@ func (s *Service) Close() error {
 	}
 	close(s.stop)
 	s.wg.Wait()
+	s.alerts.Wait()
 	s.wg = nil
 	s.stop = nil
 	return nil
@@ -163,7 +226,7 @@ type statistic struct {
 
 // Statistics returns statistics for periodic monitoring.
 func (s *Service) Statistics(tags map[string]string) []models.Statistic {
-	return []models.Statistic{{
+	statistics := []models.Statistic{{
 		Name: "cq",
 		Tags: tags,
 		Values: map[string]interface{}{
@@ -171,6 +234,7 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 			statQueryFail: atomic.LoadInt64(&s.stats.QueryFail),
 		},
 	}}
+	return append(statistics, s.statusStatistics(tags)...)
 }
 
 // Run runs the specified continuous query, or all CQs if none is specified.
@@ -212,7 +276,7 @@ func (s *Service) Run(database, name string, t time.Time) error {
 // backgroundLoop runs on a go routine and periodically executes CQs.
 func (s *Service) backgroundLoop() {
 	leaseName := "continuous_querier"
-	t := time.NewTimer(s.RunInterval)
+	t := time.NewTimer(s.runInterval())
 	defer t.Stop()
 	defer s.wg.Done()
 	for {
@@ -230,13 +294,13 @@ func (s *Service) backgroundLoop() {
 			}
 		case <-t.C:
 			if !s.hasContinuousQueries() {
-				t.Reset(s.RunInterval)
+				t.Reset(s.runInterval())
 				continue
 			}
 			if _, err := s.MetaClient.AcquireLease(leaseName); err == nil {
 				s.runContinuousQueries(&RunRequest{Now: time.Now()})
 			}
-			t.Reset(s.RunInterval)
+			t.Reset(s.runInterval())
 		}
 	}
 }
@@ -256,34 +320,72 @@ func (s *Service) hasContinuousQueries() bool {
 
 // runContinuousQueries gets CQs from the meta store and runs them.
 func (s *Service) runContinuousQueries(req *RunRequest) {
+	if s.ReadOnly != nil && s.ReadOnly() {
+		return
+	}
+
 	// Get list of all databases.
 	dbs := s.MetaClient.Databases()
+	// Limit the number of CQs running at the same time.
+	var sem chan struct{}
+	if n := s.config().MaxConcurrentQueries; n > 0 {
+		sem = make(chan struct{}, n)
+	}
+
 	// Loop through all databases executing CQs.
-	for _, db := range dbs {
+	var wg sync.WaitGroup
+	for i := range dbs {
+		db := &dbs[i]
 		// TODO: distribute across nodes
-		for _, cq := range db.ContinuousQueries {
-			if !req.matches(&cq) {
+		for j := range db.ContinuousQueries {
+			cq := &db.ContinuousQueries[j]
+			if !req.matches(cq) {
 				continue
 			}
-			if ok, err := s.ExecuteContinuousQuery(&db, &cq, req.Now); err != nil {
-				s.Logger.Info(fmt.Sprintf("error executing query: %s: err = %s", cq.Query, err))
-				atomic.AddInt64(&s.stats.QueryFail, 1)
-			} else if ok {
-				atomic.AddInt64(&s.stats.QueryOK, 1)
+			if sem != nil {
+				sem <- struct{}{}
 			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if sem != nil {
+					defer func() { <-sem }()
+				}
+				if ok, err := s.ExecuteContinuousQuery(db, cq, req.Now); err != nil {
+					s.Logger.Info(fmt.Sprintf("error executing query: %s: err = %s", cq.Query, err))
+					atomic.AddInt64(&s.stats.QueryFail, 1)
+				} else if ok {
+					atomic.AddInt64(&s.stats.QueryOK, 1)
+				}
+			}()
 		}
 	}
+	wg.Wait()
+	s.pruneStatuses(dbs)
 }
 
 // ExecuteContinuousQuery may execute a single CQ. This will return false if there were no errors and the CQ was not run.
 func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.ContinuousQueryInfo, now time.Time) (bool, error) {
+	start := time.Now()
+	ok, written, err := s.executeContinuousQuery(dbi, cqi, now)
+	if err != nil {
+		s.recordFailure(dbi.Name, cqi, start, err)
+	} else if ok {
+		s.recordRun(dbi.Name, cqi.Name, start, time.Since(start), written)
+	}
+	return ok, err
+}
+
+// executeContinuousQuery may execute a single CQ. It returns the number of
+// points written if the CQ was run.
+func (s *Service) executeContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.ContinuousQueryInfo, now time.Time) (bool, int64, error) {
 	// TODO: re-enable stats
 	//s.stats.Inc("continuousQueryExecuted")
 
 	// Local wrapper / helper.
 	cq, err := NewContinuousQuery(dbi.Name, cqi)
 	if err != nil {
-		return false, err
+		return false, 0, err
 	}
 
 	// Set the time zone on the now time if the CQ has one. Otherwise, force UTC.
@@ -293,10 +395,10 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 	}
 
 	// Get the last time this CQ was run from the service's cache.
-	s.mu.Lock()
-	defer s.mu.Unlock()
 	id := fmt.Sprintf("%s%s%s", dbi.Name, idDelimiter, cqi.Name)
+	s.mu.RLock()
 	cq.LastRun, cq.HasRun = s.lastRuns[id]
+	s.mu.RUnlock()
 
 	// Set the retention policy to default if it wasn't specified in the query.
 	if cq.intoRP() == "" {
@@ -306,34 +408,40 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 	// Get the group by interval.
 	interval, err := cq.q.GroupByInterval()
 	if err != nil {
-		return false, err
+		return false, 0, err
 	} else if interval == 0 {
-		return false, nil
+		return false, 0, nil
 	}
 
 	// Get the group by offset.
 	offset, err := cq.q.GroupByOffset()
 	if err != nil {
-		return false, err
+		return false, 0, err
 	}
 
+	resampleEvery := interval
+	if cq.Resample.Every != 0 {
+		resampleEvery = cq.Resample.Every
+	}
+
+	// Delay the run so it computes the same time range as if it had run on
+	// schedule. This spreads out CQs with the same interval.
+	now = now.Add(-s.delay(id, cq, resampleEvery))
+
 	// See if this query needs to be run.
 	run, nextRun, err := cq.shouldRunContinuousQuery(now, interval)
 	if err != nil {
-		return false, err
+		return false, 0, err
 	} else if !run {
-		return false, nil
-	}
-
-	resampleEvery := interval
-	if cq.Resample.Every != 0 {
-		resampleEvery = cq.Resample.Every
+		return false, 0, nil
 	}
 
 	// We're about to run the query so store the current time closest to the nearest interval.
 	// If all is going well, this time should be the same as nextRun.
 	cq.LastRun = truncate(now.Add(-offset), resampleEvery).Add(offset)
+	s.mu.Lock()
 	s.lastRuns[id] = cq.LastRun
+	s.mu.Unlock()
 
 	// Retrieve the oldest interval we should calculate based on the next time
 	// interval. We do this instead of using the current time just in case any
@@ -357,20 +465,24 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 	endTime := truncate(now.Add(interval-resampleEvery-offset), interval).Add(offset)
 	if !endTime.After(startTime) {
 		// Exit early since there is no time interval.
-		return false, nil
+		return false, 0, nil
 	}
 
 	if err := cq.q.SetTimeRange(startTime, endTime); err != nil {
 		s.Logger.Info(fmt.Sprintf("error setting time range: %s\n", err))
-		return false, err
+		return false, 0, err
 	}
 
+	s.configMu.RLock()
+	loggingEnabled, queryStatsEnabled := s.loggingEnabled, s.queryStatsEnabled
+	s.configMu.RUnlock()
+
 	var start time.Time
-	if s.loggingEnabled || s.queryStatsEnabled {
+	if loggingEnabled || queryStatsEnabled {
 		start = time.Now()
 	}
 
-	if s.loggingEnabled {
+	if loggingEnabled {
 		s.Logger.Info(fmt.Sprintf("executing continuous query %s (%v to %v)", cq.Info.Name, startTime, endTime))
 	}
 
@@ -378,11 +490,11 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 	res := s.runContinuousQueryAndWriteResult(cq)
 	if res.Err != nil {
 		s.Logger.Info(fmt.Sprintf("error: %s. running: %s\n", res.Err, cq.q.String()))
-		return false, res.Err
+		return false, 0, res.Err
 	}
 
 	var execDuration time.Duration
-	if s.loggingEnabled || s.queryStatsEnabled {
+	if loggingEnabled || queryStatsEnabled {
 		execDuration = time.Since(start)
 	}
 
@@ -393,18 +505,36 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 		written = s.Values[0][1].(int64)
 	}
 
-	if s.loggingEnabled {
+	if loggingEnabled {
 		s.Logger.Info(fmt.Sprintf("finished continuous query %s, %d points(s) written (%v to %v) in %s", cq.Info.Name, written, startTime, endTime, execDuration))
 	}
 
-	if s.queryStatsEnabled && s.Monitor.Enabled() {
+	if queryStatsEnabled && s.Monitor.Enabled() {
 		tags := map[string]string{"db": dbi.Name, "cq": cq.Info.Name}
 		fields := map[string]interface{}{"durationNs": int64(execDuration), "pointsWrittenOK": written, "startTime": startTime.UnixNano(), "endTime": endTime.UnixNano()}
 		p, _ := models.NewPoint("cq_query", models.NewTags(tags), fields, time.Now())
 		s.Monitor.WritePoints(models.Points{p})
 	}
 
-	return true, nil
+	return true, written, nil
+}
+
+// delay returns how long each run of a CQ is delayed by. This is the resample
+// offset of the CQ plus a jitter, up to the max jitter or the resample
+// interval, that is derived from the id of the CQ so it is the same every run.
+func (s *Service) delay(id string, cq *ContinuousQuery, every time.Duration) time.Duration {
+	d := cq.Resample.Offset
+
+	max := time.Duration(s.config().MaxJitter)
+	if max > every {
+		max = every
+	}
+	if max > 0 {
+		h := fnv.New64a()
+		h.Write([]byte(id))
+		d += time.Duration(h.Sum64() % uint64(max))
+	}
+	return d
 }
 
 // runContinuousQueryAndWriteResult will run the query against the cluster and write the results back in
@@ -420,6 +550,7 @@ func (s *Service) runContinuousQueryAndWriteResult(cq *ContinuousQuery) *query.R
 	// Execute the SELECT.
 	ch := s.QueryExecutor.ExecuteQuery(q, query.ExecutionOptions{
 		Database: cq.Database,
+		Priority: query.PriorityBackground,
 	}, closing)
 
 	// There is only one statement, so we will only ever receive one result
@@ -450,6 +581,10 @@ type ResampleOptions struct {
 	// interval is set to the group by interval.
 	Every time.Duration
 
+	// Each run of the query is delayed by this duration. The time range of the
+	// query is the same as if it had not been delayed.
+	Offset time.Duration
+
 	// The query will continue being resampled for this time duration. If this
 	// option is not given, the resample duration is the same as the group by
 	// interval. A bucket's time is calculated based on the bucket's start time,
@@ -474,8 +609,9 @@ func NewContinuousQuery(database string, cqi *meta.ContinuousQueryInfo) (*Contin
 		Database: database,
 		Info:     cqi,
 		Resample: ResampleOptions{
-			Every: q.ResampleEvery,
-			For:   q.ResampleFor,
+			Every:  q.ResampleEvery,
+			Offset: q.ResampleOffset,
+			For:    q.ResampleFor,
 		},
 		q: q.Source,
 	}
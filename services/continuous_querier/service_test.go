@@ -1,10 +1,14 @@
 package continuous_querier
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -12,6 +16,7 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
 	"github.com/uber-go/zap"
 )
 
@@ -186,6 +191,132 @@ func TestContinuousQueryService_ResampleOptions(t *testing.T) {
 	}
 }
 
+func TestContinuousQueryService_ResampleOffset(t *testing.T) {
+	s := NewTestService(t)
+	mc := NewMetaClient(t)
+	mc.CreateDatabase("db", "")
+	mc.CreateContinuousQuery("db", "cq", `CREATE CONTINUOUS QUERY cq ON db RESAMPLE EVERY 1m OFFSET 10s BEGIN SELECT mean(value) INTO cpu_mean FROM cpu GROUP BY time(1m) END`)
+	s.MetaClient = mc
+
+	var min, max time.Time
+	s.QueryExecutor.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			s := stmt.(*influxql.SelectStatement)
+			valuer := &influxql.NowValuer{Location: s.Location}
+			_, timeRange, err := influxql.ConditionExpr(s.Condition, valuer)
+			if err != nil {
+				t.Errorf("unexpected error parsing time range: %s", err)
+			}
+			min, max = timeRange.Min, timeRange.Max
+			ctx.Results <- &query.Result{}
+			return nil
+		},
+	}
+
+	db := s.MetaClient.Database("db")
+	cq := &db.ContinuousQueries[0]
+
+	// The first run is delayed by the offset but queries the same time range
+	// as a run at the start of the interval.
+	now := time.Now().UTC().Truncate(10 * time.Minute)
+	if ok, err := s.ExecuteContinuousQuery(db, cq, now.Add(10*time.Second)); !ok || err != nil {
+		t.Fatalf("ExecuteContinuousQuery failed, ok=%t, err=%v", ok, err)
+	} else if !min.Equal(now.Add(-time.Minute)) || !max.Equal(now.Add(-1)) {
+		t.Fatalf("mismatched time range: got=(%s, %s) exp=(%s, %s)", min, max, now.Add(-time.Minute), now.Add(-1))
+	}
+
+	// The next run does not happen until the offset has passed.
+	if ok, err := s.ExecuteContinuousQuery(db, cq, now.Add(time.Minute+5*time.Second)); ok || err != nil {
+		t.Fatalf("unexpected run, ok=%t, err=%v", ok, err)
+	}
+	if ok, err := s.ExecuteContinuousQuery(db, cq, now.Add(time.Minute+10*time.Second)); !ok || err != nil {
+		t.Fatalf("ExecuteContinuousQuery failed, ok=%t, err=%v", ok, err)
+	} else if !min.Equal(now) || !max.Equal(now.Add(time.Minute-1)) {
+		t.Fatalf("mismatched time range: got=(%s, %s) exp=(%s, %s)", min, max, now, now.Add(time.Minute-1))
+	}
+}
+
+func TestContinuousQueryService_Jitter(t *testing.T) {
+	s := NewTestService(t)
+	s.Config.MaxJitter = toml.Duration(time.Minute)
+
+	cq := &ContinuousQuery{Resample: ResampleOptions{Offset: 5 * time.Second}}
+	ids := []string{"db" + idDelimiter + "cq", "db" + idDelimiter + "cq2", "db2" + idDelimiter + "cq"}
+	delays := make(map[time.Duration]struct{})
+	for _, id := range ids {
+		d := s.delay(id, cq, time.Hour)
+		if d < 5*time.Second || d >= time.Minute+5*time.Second {
+			t.Fatalf("delay out of range: %s", d)
+		} else if d2 := s.delay(id, cq, time.Hour); d2 != d {
+			t.Fatalf("delay not stable: %s != %s", d, d2)
+		}
+		delays[d] = struct{}{}
+
+		// The jitter is limited to the resample interval.
+		if d := s.delay(id, cq, 10*time.Second); d >= 15*time.Second {
+			t.Fatalf("delay exceeds resample interval: %s", d)
+		}
+	}
+	if len(delays) == 1 {
+		t.Fatal("expected CQs to be delayed by different amounts")
+	}
+
+	s.Config.MaxJitter = 0
+	if d := s.delay(ids[0], cq, time.Hour); d != 5*time.Second {
+		t.Fatalf("unexpected delay without jitter: %s", d)
+	}
+}
+
+func TestContinuousQueryService_SetConfig(t *testing.T) {
+	s := NewTestService(t)
+	s.Config.MaxJitter = toml.Duration(time.Minute)
+
+	c := NewConfig()
+	c.RunInterval = toml.Duration(5 * time.Second)
+	c.MaxJitter = 0
+	c.QueryStatsEnabled = true
+	s.SetConfig(c)
+
+	cq := &ContinuousQuery{Resample: ResampleOptions{Offset: 5 * time.Second}}
+	if d := s.delay("db"+idDelimiter+"cq", cq, time.Hour); d != 5*time.Second {
+		t.Fatalf("unexpected delay without jitter: %s", d)
+	} else if d := s.runInterval(); d != 5*time.Second {
+		t.Fatalf("unexpected run interval: %s", d)
+	} else if !s.queryStatsEnabled {
+		t.Fatal("expected query stats to be enabled")
+	}
+}
+
+func TestContinuousQueryService_MaxConcurrentQueries(t *testing.T) {
+	s := NewTestService(t)
+	s.Config.MaxConcurrentQueries = 2
+
+	var running, peak, n int64
+	s.QueryExecutor.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			cur := atomic.AddInt64(&running, 1)
+			for {
+				p := atomic.LoadInt64(&peak)
+				if cur <= p || atomic.CompareAndSwapInt64(&peak, p, cur) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt64(&running, -1)
+			atomic.AddInt64(&n, 1)
+			ctx.Results <- &query.Result{}
+			return nil
+		},
+	}
+
+	s.runContinuousQueries(&RunRequest{Now: time.Now().Truncate(10 * time.Minute)})
+	if n != 3 {
+		t.Fatalf("unexpected number of queries executed: %d", n)
+	} else if peak != 2 {
+		t.Fatalf("unexpected number of concurrent queries: %d", peak)
+	}
+}
+
 func TestContinuousQueryService_EveryHigherThanInterval(t *testing.T) {
 	s := NewTestService(t)
 	ms := NewMetaClient(t)
@@ -332,6 +463,30 @@ func TestContinuousQueryService_NotLeader(t *testing.T) {
 	s.Close()
 }
 
+// Test that CQs aren't run on a read-only replica.
+func TestContinuousQueryService_ReadOnly(t *testing.T) {
+	s := NewTestService(t)
+	s.RunInterval = 10 * time.Second
+	s.ReadOnly = func() bool { return true }
+
+	done := make(chan struct{})
+	// Set a callback for ExecuteStatement. Shouldn't get called because the node is read-only.
+	s.QueryExecutor.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			done <- struct{}{}
+			ctx.Results <- &query.Result{Err: errUnexpected}
+			return nil
+		},
+	}
+
+	s.Open()
+	s.RunCh <- &RunRequest{Now: time.Now()}
+	if err := wait(done, 100*time.Millisecond); err == nil {
+		t.Error("query executed on a read-only node")
+	}
+	s.Close()
+}
+
 // Test ExecuteContinuousQuery with invalid queries.
 func TestExecuteContinuousQuery_InvalidQueries(t *testing.T) {
 	s := NewTestService(t)
@@ -681,6 +836,111 @@ func TestService_ExecuteContinuousQuery_LogToMonitor_DisabledByDefault(t *testin
 	}
 }
 
+func TestService_ExecuteContinuousQuery_Status(t *testing.T) {
+	s := NewTestService(t)
+	s.QueryExecutor.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			ctx.Results <- &query.Result{
+				Series: []*models.Row{{
+					Name:    "result",
+					Columns: []string{"time", "written"},
+					Values:  [][]interface{}{{time.Time{}, int64(10)}},
+				}},
+			}
+			return nil
+		},
+	}
+
+	dbis := s.MetaClient.Databases()
+	dbi := dbis[0]
+	cqi := dbi.ContinuousQueries[0]
+
+	now := time.Now().Truncate(10 * time.Minute)
+	if ok, err := s.ExecuteContinuousQuery(&dbi, &cqi, now); !ok || err != nil {
+		t.Fatalf("ExecuteContinuousQuery failed, ok=%t, err=%v", ok, err)
+	}
+
+	statuses := s.Statuses()
+	if len(statuses) != 1 {
+		t.Fatalf("unexpected number of statuses: %d", len(statuses))
+	} else if st := statuses[0]; st.Database != "db" || st.Name != "cq" {
+		t.Fatalf("unexpected status: %s.%s", st.Database, st.Name)
+	} else if st.Runs != 1 || st.PointsWritten != 10 || st.LastRun.IsZero() {
+		t.Fatalf("unexpected status: runs=%d written=%d last run=%s", st.Runs, st.PointsWritten, st.LastRun)
+	} else if st.Failures != 0 || st.LastError != "" {
+		t.Fatalf("unexpected failure: %d %q", st.Failures, st.LastError)
+	}
+
+	var found bool
+	for _, stat := range s.Statistics(nil) {
+		if stat.Name == "cq_status" && stat.Tags["db"] == "db" && stat.Tags["cq"] == "cq" {
+			found = true
+			if got := stat.Values[statRuns]; got != int64(1) {
+				t.Fatalf("unexpected runs statistic: %v", got)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("expected cq_status statistic")
+	}
+
+	// The status is discarded once the CQ is dropped.
+	s.pruneStatuses(nil)
+	if statuses := s.Statuses(); len(statuses) != 0 {
+		t.Fatalf("unexpected statuses after prune: %d", len(statuses))
+	}
+}
+
+func TestService_ExecuteContinuousQuery_AlertWebhook(t *testing.T) {
+	alerts := make(chan Alert, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("unexpected error decoding alert: %s", err)
+		}
+		alerts <- alert
+	}))
+	defer ts.Close()
+
+	s := NewTestService(t)
+	s.Config.AlertWebhookURL = ts.URL
+	s.QueryExecutor.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			return errExpected
+		},
+	}
+
+	dbis := s.MetaClient.Databases()
+	dbi := dbis[0]
+	cqi := dbi.ContinuousQueries[0]
+
+	// The same error twice in a row is only alerted once.
+	now := time.Now().Truncate(10 * time.Minute)
+	for i := 0; i < 2; i++ {
+		if _, err := s.ExecuteContinuousQuery(&dbi, &cqi, now.Add(time.Duration(i)*time.Second)); err != errExpected {
+			t.Fatalf("exp = %s, got = %v", errExpected, err)
+		}
+	}
+	s.alerts.Wait()
+
+	if len(alerts) != 1 {
+		t.Fatalf("unexpected number of alerts: %d", len(alerts))
+	}
+	alert := <-alerts
+	if alert.Database != "db" || alert.Name != "cq" || alert.Query != cqi.Query || alert.Error != errExpected.Error() {
+		t.Fatalf("unexpected alert: %+v", alert)
+	}
+
+	statuses := s.Statuses()
+	if len(statuses) != 1 {
+		t.Fatalf("unexpected number of statuses: %d", len(statuses))
+	} else if st := statuses[0]; st.Failures != 2 || st.LastError != errExpected.Error() || st.LastErrorTime.IsZero() {
+		t.Fatalf("unexpected status: failures=%d error=%q", st.Failures, st.LastError)
+	} else if st.Runs != 0 {
+		t.Fatalf("unexpected runs: %d", st.Runs)
+	}
+}
+
 // NewTestService returns a new *Service with default mock object members.
 func NewTestService(t *testing.T) *Service {
 	s := NewService(NewConfig())
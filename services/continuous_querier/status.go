@@ -0,0 +1,185 @@
+package continuous_querier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// Status is the execution status of a continuous query since the service
+// started.
+type Status struct {
+	Database string
+	Name     string
+
+	// LastRun is when the query last ran successfully, Duration is how long
+	// the run took, and PointsWritten is the number of points it wrote.
+	LastRun       time.Time
+	Duration      time.Duration
+	PointsWritten int64
+
+	// Runs is the number of successful runs and Failures is the number of
+	// failed runs.
+	Runs     int64
+	Failures int64
+
+	// LastError is the error of the last failed run and LastErrorTime is
+	// when it failed.
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// Alert is the body posted to the alert webhook when a continuous query fails.
+type Alert struct {
+	Database string    `json:"database"`
+	Name     string    `json:"name"`
+	Query    string    `json:"query"`
+	Error    string    `json:"error"`
+	Time     time.Time `json:"time"`
+}
+
+// Statuses returns the status of each continuous query that has run, sorted
+// by database and name.
+func (s *Service) Statuses() []Status {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	statuses := make([]Status, 0, len(s.statuses))
+	for _, st := range s.statuses {
+		statuses = append(statuses, *st)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Database != statuses[j].Database {
+			return statuses[i].Database < statuses[j].Database
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// status returns the status of a continuous query. The lock must be held.
+func (s *Service) status(database, name string) *Status {
+	id := database + idDelimiter + name
+	st, ok := s.statuses[id]
+	if !ok {
+		st = &Status{Database: database, Name: name}
+		s.statuses[id] = st
+	}
+	return st
+}
+
+// recordRun records a successful run of a continuous query.
+func (s *Service) recordRun(database, name string, start time.Time, d time.Duration, written int64) {
+	if written < 0 {
+		written = 0
+	}
+
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	st := s.status(database, name)
+	st.LastRun, st.Duration, st.PointsWritten = start, d, written
+	st.Runs++
+}
+
+// recordFailure records a failed run of a continuous query. An alert is
+// posted to the webhook, if one is configured, when the query starts failing
+// or fails with a different error so a query that keeps failing is only
+// reported once.
+func (s *Service) recordFailure(database string, cqi *meta.ContinuousQueryInfo, t time.Time, err error) {
+	s.statusMu.Lock()
+	st := s.status(database, cqi.Name)
+	changed := st.LastError != err.Error() || st.LastRun.After(st.LastErrorTime)
+	st.LastError, st.LastErrorTime = err.Error(), t
+	st.Failures++
+	s.statusMu.Unlock()
+
+	if s.config().AlertWebhookURL == "" || !changed {
+		return
+	}
+
+	alert := Alert{
+		Database: database,
+		Name:     cqi.Name,
+		Query:    cqi.Query,
+		Error:    err.Error(),
+		Time:     t.UTC(),
+	}
+	s.alerts.Add(1)
+	go func() {
+		defer s.alerts.Done()
+		if err := s.postAlert(alert); err != nil {
+			s.Logger.Info(fmt.Sprintf("failed to post alert for continuous query %s: %s", alert.Name, err))
+		}
+	}()
+}
+
+// postAlert posts an alert to the webhook.
+func (s *Service) postAlert(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	s.configMu.RLock()
+	client, url := s.httpClient, s.Config.AlertWebhookURL
+	s.configMu.RUnlock()
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// pruneStatuses discards the status of continuous queries that no longer
+// exist.
+func (s *Service) pruneStatuses(dbs []meta.DatabaseInfo) {
+	ids := make(map[string]struct{})
+	for _, db := range dbs {
+		for _, cq := range db.ContinuousQueries {
+			ids[db.Name+idDelimiter+cq.Name] = struct{}{}
+		}
+	}
+
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	for id := range s.statuses {
+		if _, ok := ids[id]; !ok {
+			delete(s.statuses, id)
+		}
+	}
+}
+
+// statusStatistics returns a statistic for the status of each continuous query.
+func (s *Service) statusStatistics(tags map[string]string) []models.Statistic {
+	statuses := s.Statuses()
+	statistics := make([]models.Statistic, 0, len(statuses))
+	for _, st := range statuses {
+		var lastRun int64
+		if !st.LastRun.IsZero() {
+			lastRun = st.LastRun.UnixNano()
+		}
+		statistics = append(statistics, models.Statistic{
+			Name: "cq_status",
+			Tags: models.StatisticTags{"db": st.Database, "cq": st.Name}.Merge(tags),
+			Values: map[string]interface{}{
+				statLastRun:       lastRun,
+				statDuration:      int64(st.Duration),
+				statPointsWritten: st.PointsWritten,
+				statRuns:          st.Runs,
+				statFailures:      st.Failures,
+			},
+		})
+	}
+	return statistics
+}
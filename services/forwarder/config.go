@@ -0,0 +1,193 @@
+package forwarder
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	// DropOldest discards the oldest queued writes when a queue is full.
+	DropOldest = "oldest"
+
+	// DropNewest discards incoming writes when a queue is full.
+	DropNewest = "newest"
+
+	// DefaultMaxQueueSize is the default maximum size of each destination's queue.
+	DefaultMaxQueueSize = 1024 * 1024 * 1024
+
+	// DefaultMaxSegmentSize is the default size of each queue segment file.
+	DefaultMaxSegmentSize = 10 * 1024 * 1024
+
+	// DefaultWriteBufferSize is the default number of writes buffered for
+	// each destination before they're queued on disk.
+	DefaultWriteBufferSize = 1000
+
+	// DefaultSyncInterval is the default interval at which queues are synced to disk.
+	DefaultSyncInterval = time.Second
+
+	// DefaultDropPolicy is the default policy applied when a queue is full.
+	DefaultDropPolicy = DropOldest
+
+	// DefaultRetryInterval is the default initial delay before retrying a failed write.
+	DefaultRetryInterval = time.Second
+
+	// DefaultRetryMaxInterval is the default maximum delay between retries.
+	DefaultRetryMaxInterval = time.Minute
+
+	// DefaultHTTPTimeout is the default timeout of writes to a destination.
+	DefaultHTTPTimeout = 30 * time.Second
+)
+
+// Config represents the configuration for the write forwarder.
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// Dir is where the queues of unforwarded writes are stored.
+	Dir string `toml:"dir"`
+
+	// URLs are the InfluxDB instances writes are forwarded to.
+	URLs []string `toml:"urls"`
+
+	// Credentials used to authenticate with the destinations.
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+
+	// InsecureSkipVerify skips https certificate verification of the destinations.
+	InsecureSkipVerify bool `toml:"insecure-skip-verify"`
+
+	HTTPTimeout toml.Duration `toml:"http-timeout"`
+
+	// WriteBufferSize is the number of writes buffered for each destination
+	// while they're queued on disk. Writes are dropped if the buffer is full.
+	WriteBufferSize int `toml:"write-buffer-size"`
+
+	// MaxQueueSize is the maximum size on disk of each destination's queue.
+	MaxQueueSize toml.Size `toml:"max-queue-size"`
+
+	// MaxSegmentSize is the size at which a queue starts a new segment file.
+	MaxSegmentSize toml.Size `toml:"max-segment-size"`
+
+	// SyncInterval is how often queued writes are synced to disk. Queues are
+	// also synced when they start a new segment file.
+	SyncInterval toml.Duration `toml:"sync-interval"`
+
+	// DropPolicy determines which writes are discarded when a queue is full.
+	DropPolicy string `toml:"drop-policy"`
+
+	// RetryInterval is the delay before the first retry of a failed write. The
+	// delay doubles with each consecutive failure up to RetryMaxInterval.
+	RetryInterval    toml.Duration `toml:"retry-interval"`
+	RetryMaxInterval toml.Duration `toml:"retry-max-interval"`
+}
+
+// NewConfig returns a new Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Enabled:          false,
+		HTTPTimeout:      toml.Duration(DefaultHTTPTimeout),
+		WriteBufferSize:  DefaultWriteBufferSize,
+		MaxQueueSize:     toml.Size(DefaultMaxQueueSize),
+		MaxSegmentSize:   toml.Size(DefaultMaxSegmentSize),
+		SyncInterval:     toml.Duration(DefaultSyncInterval),
+		DropPolicy:       DefaultDropPolicy,
+		RetryInterval:    toml.Duration(DefaultRetryInterval),
+		RetryMaxInterval: toml.Duration(DefaultRetryMaxInterval),
+	}
+}
+
+// Validate returns an error if the Config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Dir == "" {
+		return errors.New("forwarder dir must be specified")
+	}
+
+	if len(c.URLs) == 0 {
+		return errors.New("at least one forwarder url must be specified")
+	}
+	for _, s := range c.URLs {
+		u, err := url.Parse(s)
+		if err != nil {
+			return fmt.Errorf("invalid forwarder url %q: %s", s, err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid forwarder url %q: scheme must be http or https", s)
+		}
+	}
+
+	switch c.DropPolicy {
+	case DropOldest, DropNewest:
+	default:
+		return fmt.Errorf("unknown drop-policy: %q", c.DropPolicy)
+	}
+
+	if c.MaxSegmentSize <= 0 {
+		return errors.New("max-segment-size must be greater than 0")
+	} else if c.MaxQueueSize < c.MaxSegmentSize {
+		return errors.New("max-queue-size must not be less than max-segment-size")
+	}
+
+	if c.WriteBufferSize <= 0 {
+		return errors.New("write-buffer-size must be greater than 0")
+	}
+
+	if c.SyncInterval < 0 {
+		return errors.New("sync-interval must not be negative")
+	}
+
+	if c.HTTPTimeout <= 0 {
+		return errors.New("http-timeout must be greater than 0")
+	}
+
+	if c.RetryInterval <= 0 {
+		return errors.New("retry-interval must be greater than 0")
+	} else if c.RetryMaxInterval < c.RetryInterval {
+		return errors.New("retry-max-interval must not be less than retry-interval")
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":            true,
+		"dir":                c.Dir,
+		"urls":               strings.Join(redactURLs(c.URLs), ","),
+		"write-buffer-size":  c.WriteBufferSize,
+		"max-queue-size":     c.MaxQueueSize,
+		"sync-interval":      c.SyncInterval,
+		"drop-policy":        c.DropPolicy,
+		"retry-interval":     c.RetryInterval,
+		"retry-max-interval": c.RetryMaxInterval,
+	}), nil
+}
+
+// redactURLs returns urls with any passwords removed.
+func redactURLs(urls []string) []string {
+	a := make([]string, 0, len(urls))
+	for _, s := range urls {
+		u, err := url.Parse(s)
+		if err != nil {
+			continue
+		}
+		if u.User != nil {
+			u.User = url.User(u.User.Username())
+		}
+		a = append(a, u.String())
+	}
+	return a
+}
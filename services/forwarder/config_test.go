@@ -0,0 +1,95 @@
+package forwarder_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/forwarder"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	c := forwarder.NewConfig()
+	if _, err := toml.Decode(`
+enabled = true
+dir = "/tmp/forwarder"
+urls = ["http://spare:8086"]
+drop-policy = "newest"
+max-queue-size = "100m"
+retry-max-interval = "30s"
+sync-interval = "5s"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.Enabled {
+		t.Fatalf("unexpected enabled state: %v", c.Enabled)
+	} else if c.Dir != "/tmp/forwarder" {
+		t.Fatalf("unexpected dir: %s", c.Dir)
+	} else if len(c.URLs) != 1 || c.URLs[0] != "http://spare:8086" {
+		t.Fatalf("unexpected urls: %v", c.URLs)
+	} else if c.DropPolicy != forwarder.DropNewest {
+		t.Fatalf("unexpected drop policy: %s", c.DropPolicy)
+	} else if c.MaxQueueSize != 100*1024*1024 {
+		t.Fatalf("unexpected max queue size: %d", c.MaxQueueSize)
+	} else if time.Duration(c.RetryMaxInterval) != 30*time.Second {
+		t.Fatalf("unexpected retry max interval: %s", c.RetryMaxInterval)
+	} else if time.Duration(c.SyncInterval) != 5*time.Second {
+		t.Fatalf("unexpected sync interval: %s", c.SyncInterval)
+	} else if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail: %s", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := forwarder.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail from NewConfig: %s", err)
+	}
+
+	valid := func() forwarder.Config {
+		c := forwarder.NewConfig()
+		c.Enabled = true
+		c.Dir = "/tmp/forwarder"
+		c.URLs = []string{"http://spare:8086"}
+		return c
+	}
+
+	c = valid()
+	c.Dir = ""
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for empty dir, got nil")
+	}
+
+	c = valid()
+	c.URLs = nil
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for no urls, got nil")
+	}
+
+	c = valid()
+	c.URLs = []string{"udp://spare:8089"}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for udp url, got nil")
+	}
+
+	c = valid()
+	c.DropPolicy = "random"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for unknown drop-policy, got nil")
+	}
+
+	c = valid()
+	c.MaxQueueSize = c.MaxSegmentSize - 1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for max-queue-size < max-segment-size, got nil")
+	}
+
+	c = valid()
+	c.RetryMaxInterval = c.RetryInterval / 2
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for retry-max-interval < retry-interval, got nil")
+	}
+}
@@ -0,0 +1,431 @@
+// Package forwarder provides a service that durably forwards local writes to
+// other InfluxDB instances.
+package forwarder // import "github.com/influxdata/influxdb/services/forwarder"
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/diskqueue"
+	"github.com/uber-go/zap"
+)
+
+// Statistics for the forwarder service.
+const (
+	statWritesQueued    = "writesQueued"
+	statWritesDropped   = "writesDropped"
+	statBytesDropped    = "bytesDropped"
+	statWritesForwarded = "writesForwarded"
+	statWritesRejected  = "writesRejected"
+	statWriteErr        = "writeErr"
+	statQueueBytes      = "queueBytes"
+)
+
+// Service queues every successful local write on disk and replays the queue
+// to each destination, retrying with backoff while a destination is down.
+// Writes are buffered in memory and queued in the background, so a slow disk
+// doesn't delay local writes.
+type Service struct {
+	config Config
+
+	mu      sync.RWMutex
+	wg      sync.WaitGroup
+	closing chan struct{}
+
+	destinations []*destination
+
+	// InternalDatabase is the database the monitor stores this node's
+	// statistics in. Its writes aren't forwarded.
+	InternalDatabase string
+
+	Logger zap.Logger
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		config: c,
+		Logger: zap.New(zap.NullEncoder()),
+	}
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log zap.Logger) {
+	s.Logger = log.With(zap.String("service", "forwarder"))
+}
+
+// Open opens the queues and starts replaying them to the destinations.
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing != nil {
+		return nil
+	}
+
+	s.Logger.Info("Starting write forwarder service")
+
+	var destinations []*destination
+	for _, rawurl := range s.config.URLs {
+		d, err := newDestination(rawurl, s.config)
+		if err != nil {
+			for _, d := range destinations {
+				d.queue.Close()
+			}
+			return err
+		}
+		destinations = append(destinations, d)
+	}
+
+	s.closing = make(chan struct{})
+	s.destinations = destinations
+	for _, d := range s.destinations {
+		s.wg.Add(2)
+		go s.enqueue(d)
+		go s.replay(d)
+	}
+	return nil
+}
+
+// Close stops replaying and closes the queues. Queued writes are replayed
+// once the service is reopened.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	if s.closing == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	close(s.closing)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.destinations {
+		d.queue.Close()
+	}
+	s.destinations = nil
+	s.closing = nil
+	return nil
+}
+
+// Forward buffers a write for each destination. The write is dropped for
+// destinations whose buffer is full.
+func (s *Service) Forward(p *coordinator.WritePointsRequest) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closing == nil || (s.InternalDatabase != "" && p.Database == s.InternalDatabase) {
+		return
+	}
+
+	b := encodeEntry(p)
+	for _, d := range s.destinations {
+		select {
+		case d.pending <- b:
+		default:
+			atomic.AddInt64(&d.stats.WritesDropped, 1)
+		}
+	}
+}
+
+// enqueue appends buffered writes to a destination's queue until the service
+// is closed.
+func (s *Service) enqueue(d *destination) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case b := <-d.pending:
+			s.append(d, b)
+		case <-s.closing:
+			// Queue the writes buffered before the service was closed.
+			for {
+				select {
+				case b := <-d.pending:
+					s.append(d, b)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// append appends a write to a destination's queue.
+func (s *Service) append(d *destination, b []byte) {
+	dropped, err := d.queue.Append(b, s.config.DropPolicy == DropOldest)
+	if dropped > 0 {
+		atomic.AddInt64(&d.stats.BytesDropped, dropped)
+	}
+	if err == diskqueue.ErrQueueFull {
+		atomic.AddInt64(&d.stats.WritesDropped, 1)
+		return
+	} else if err != nil {
+		atomic.AddInt64(&d.stats.WritesDropped, 1)
+		s.Logger.Info(fmt.Sprintf("failed to queue write for %s: %s", d.name, err))
+		return
+	}
+	atomic.AddInt64(&d.stats.WritesQueued, 1)
+
+	// Wake the replay goroutine if it is waiting for writes.
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Statistics maintains statistics for a forwarding destination.
+type Statistics struct {
+	WritesQueued    int64
+	WritesDropped   int64
+	BytesDropped    int64
+	WritesForwarded int64
+	WritesRejected  int64
+	WriteErr        int64
+}
+
+// Statistics returns statistics for periodic monitoring.
+func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statistics := make([]models.Statistic, 0, len(s.destinations))
+	for _, d := range s.destinations {
+		statistics = append(statistics, models.Statistic{
+			Name: "forwarder",
+			Tags: models.StatisticTags{"destination": d.name}.Merge(tags),
+			Values: map[string]interface{}{
+				statWritesQueued:    atomic.LoadInt64(&d.stats.WritesQueued),
+				statWritesDropped:   atomic.LoadInt64(&d.stats.WritesDropped),
+				statBytesDropped:    atomic.LoadInt64(&d.stats.BytesDropped),
+				statWritesForwarded: atomic.LoadInt64(&d.stats.WritesForwarded),
+				statWritesRejected:  atomic.LoadInt64(&d.stats.WritesRejected),
+				statWriteErr:        atomic.LoadInt64(&d.stats.WriteErr),
+				statQueueBytes:      d.queue.DiskSize(),
+			},
+		})
+	}
+	return statistics
+}
+
+// replay writes queued entries to a destination until the service is closed.
+func (s *Service) replay(d *destination) {
+	defer s.wg.Done()
+
+	retryInterval := time.Duration(s.config.RetryInterval)
+	backoff := retryInterval
+	for {
+		b, err := d.queue.Current()
+		if err == io.EOF {
+			select {
+			case <-s.closing:
+				return
+			case <-d.notify:
+			}
+			continue
+		} else if _, ok := err.(*diskqueue.CorruptSegmentError); ok {
+			// The corrupt segment was quarantined, so the next entry can be read.
+			s.Logger.Info(fmt.Sprintf("skipping corrupt queue segment for %s: %s", d.name, err))
+			continue
+		} else if err != nil {
+			s.Logger.Info(fmt.Sprintf("failed to read queue for %s, retrying in %s: %s", d.name, backoff, err))
+		} else if err = d.write(b); err == nil {
+			atomic.AddInt64(&d.stats.WritesForwarded, 1)
+			backoff = retryInterval
+			if err = d.queue.Advance(); err == nil {
+				continue
+			}
+			s.Logger.Info(fmt.Sprintf("failed to advance queue for %s, retrying in %s: %s", d.name, backoff, err))
+		} else if _, ok := err.(rejectedError); ok {
+			// Retrying a write the destination rejected would block the queue forever.
+			atomic.AddInt64(&d.stats.WritesRejected, 1)
+			s.Logger.Info(fmt.Sprintf("write rejected by %s, dropping: %s", d.name, err))
+			if err = d.queue.Advance(); err == nil {
+				continue
+			}
+			s.Logger.Info(fmt.Sprintf("failed to advance queue for %s, retrying in %s: %s", d.name, backoff, err))
+		} else {
+			atomic.AddInt64(&d.stats.WriteErr, 1)
+			s.Logger.Info(fmt.Sprintf("failed to forward write to %s, retrying in %s: %s", d.name, backoff, err))
+		}
+
+		select {
+		case <-s.closing:
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if max := time.Duration(s.config.RetryMaxInterval); backoff > max {
+			backoff = max
+		}
+	}
+}
+
+// destination is an InfluxDB instance writes are forwarded to.
+type destination struct {
+	name   string
+	url    url.URL
+	queue  *diskqueue.Queue
+	client *http.Client
+
+	// pending buffers writes until they're queued. notify wakes the replay
+	// goroutine once they have been.
+	pending chan []byte
+	notify  chan struct{}
+
+	username string
+	password string
+
+	stats *Statistics
+}
+
+// newDestination opens the queue for rawurl.
+func newDestination(rawurl string, c Config) (*destination, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &destination{
+		name:     u.Host,
+		url:      *u,
+		username: c.Username,
+		password: c.Password,
+		pending:  make(chan []byte, c.WriteBufferSize),
+		notify:   make(chan struct{}, 1),
+		stats:    &Statistics{},
+		client: &http.Client{
+			Timeout: time.Duration(c.HTTPTimeout),
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify},
+			},
+		},
+	}
+
+	if u.User != nil {
+		d.username = u.User.Username()
+		d.password, _ = u.User.Password()
+		d.url.User = nil
+	}
+	d.url.Path = strings.TrimSuffix(d.url.Path, "/") + "/write"
+
+	d.queue = diskqueue.New(filepath.Join(c.Dir, queueDirName(u)), int64(c.MaxQueueSize), int64(c.MaxSegmentSize))
+	d.queue.SyncInterval = time.Duration(c.SyncInterval)
+	if err := d.queue.Open(); err != nil {
+		return nil, fmt.Errorf("open queue for %s: %s", d.name, err)
+	}
+	return d, nil
+}
+
+// write sends a queued entry to the destination.
+func (d *destination) write(b []byte) error {
+	database, retentionPolicy, body, err := decodeEntry(b)
+	if err != nil {
+		return rejectedError{err}
+	}
+
+	u := d.url
+	params := url.Values{}
+	params.Set("db", database)
+	if retentionPolicy != "" {
+		params.Set("rp", retentionPolicy)
+	}
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if d.username != "" {
+		req.SetBasicAuth(d.username, d.password)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+
+	// Client errors other than timeouts and throttling will never succeed.
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return err
+	}
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return rejectedError{err}
+	}
+	return err
+}
+
+// rejectedError is returned when the destination permanently rejects a write.
+type rejectedError struct {
+	error
+}
+
+// queueDirName returns the name of the queue directory for a destination.
+func queueDirName(u *url.URL) string {
+	r := strings.NewReplacer(":", "_", "/", "_", "\\", "_")
+	return r.Replace(u.Host + strings.TrimSuffix(u.Path, "/"))
+}
+
+// encodeEntry encodes a write as the database and retention policy, each
+// prefixed by their length, followed by the points in line protocol.
+func encodeEntry(p *coordinator.WritePointsRequest) []byte {
+	var buf bytes.Buffer
+	var n [binary.MaxVarintLen64]byte
+
+	buf.Write(n[:binary.PutUvarint(n[:], uint64(len(p.Database)))])
+	buf.WriteString(p.Database)
+	buf.Write(n[:binary.PutUvarint(n[:], uint64(len(p.RetentionPolicy)))])
+	buf.WriteString(p.RetentionPolicy)
+	for _, pt := range p.Points {
+		buf.WriteString(pt.String())
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// decodeEntry decodes an entry encoded by encodeEntry.
+func decodeEntry(b []byte) (database, retentionPolicy string, points []byte, err error) {
+	readString := func() (string, error) {
+		n, sz := binary.Uvarint(b)
+		if sz <= 0 || uint64(len(b)-sz) < n {
+			return "", errors.New("corrupt forwarder entry")
+		}
+		v := string(b[sz : sz+int(n)])
+		b = b[sz+int(n):]
+		return v, nil
+	}
+
+	if database, err = readString(); err != nil {
+		return "", "", nil, err
+	} else if retentionPolicy, err = readString(); err != nil {
+		return "", "", nil, err
+	}
+	return database, retentionPolicy, b, nil
+}
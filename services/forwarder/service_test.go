@@ -0,0 +1,126 @@
+package forwarder_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/forwarder"
+	"github.com/influxdata/influxdb/toml"
+)
+
+func TestService_Forward(t *testing.T) {
+	var fail = true
+	writes := make(chan string, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Simulate an outage for the first write.
+		if fail {
+			fail = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if r.URL.Path != "/write" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		} else if db, rp := r.URL.Query().Get("db"), r.URL.Query().Get("rp"); db != "db0" || rp != "rp0" {
+			t.Errorf("unexpected db/rp: %s/%s", db, rp)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		writes <- string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "forwarder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := forwarder.NewConfig()
+	c.Enabled = true
+	c.Dir = dir
+	c.URLs = []string{ts.URL}
+	c.RetryInterval = toml.Duration(10 * time.Millisecond)
+
+	s := forwarder.NewService(c)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	points, err := models.ParsePointsString("cpu value=1 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Forward(&coordinator.WritePointsRequest{
+		Database:        "db0",
+		RetentionPolicy: "rp0",
+		Points:          points,
+	})
+
+	select {
+	case body := <-writes:
+		if exp := "cpu value=1 10\n"; body != exp {
+			t.Fatalf("unexpected body: got %q, exp %q", body, exp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for forwarded write")
+	}
+
+	stats := s.Statistics(nil)
+	if len(stats) != 1 {
+		t.Fatalf("unexpected statistics: %v", stats)
+	} else if v := stats[0].Values["writeErr"]; v != int64(1) {
+		t.Fatalf("unexpected writeErr: %v", v)
+	}
+}
+
+func TestService_Forward_InternalDatabase(t *testing.T) {
+	writes := make(chan string, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writes <- r.URL.Query().Get("db")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "forwarder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := forwarder.NewConfig()
+	c.Enabled = true
+	c.Dir = dir
+	c.URLs = []string{ts.URL}
+
+	s := forwarder.NewService(c)
+	s.InternalDatabase = "_internal"
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	points, err := models.ParsePointsString("cpu value=1 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, db := range []string{"_internal", "db0"} {
+		s.Forward(&coordinator.WritePointsRequest{Database: db, Points: points})
+	}
+
+	select {
+	case db := <-writes:
+		if db != "db0" {
+			t.Fatalf("unexpected database forwarded: %s", db)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for forwarded write")
+	}
+}
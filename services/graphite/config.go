@@ -1,6 +1,7 @@
 package graphite
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -36,6 +37,9 @@ const (
 	// DefaultBatchTimeout is the default Graphite batch timeout.
 	DefaultBatchTimeout = time.Second
 
+	// DefaultCertificate is the default location of the certificate used when TLS is enabled.
+	DefaultCertificate = "/etc/ssl/influxdb.pem"
+
 	// DefaultUDPReadBuffer is the default buffer size for the UDP listener.
 	// Sets the size of the operating system's receive buffer associated with
 	// the UDP traffic. Keep in mind that the OS must be able
@@ -65,6 +69,16 @@ type Config struct {
 	Tags             []string      `toml:"tags"`
 	Separator        string        `toml:"separator"`
 	UDPReadBuffer    int           `toml:"udp-read-buffer"`
+
+	// TLS and authentication of TCP connections.  Connections authenticate
+	// by sending "auth <shared-secret>" or "auth <username> <password>" of a
+	// user with write privilege on the database as their first line.
+	TLSEnabled   bool   `toml:"tls-enabled"`
+	Certificate  string `toml:"certificate"`
+	PrivateKey   string `toml:"private-key"`
+	ClientCA     string `toml:"client-ca"`
+	AuthEnabled  bool   `toml:"auth-enabled"`
+	SharedSecret string `toml:"shared-secret"`
 }
 
 // NewConfig returns a new instance of Config with defaults.
@@ -78,6 +92,7 @@ func NewConfig() Config {
 		BatchTimeout:     toml.Duration(DefaultBatchTimeout),
 		ConsistencyLevel: DefaultConsistencyLevel,
 		Separator:        DefaultSeparator,
+		Certificate:      DefaultCertificate,
 	}
 }
 
@@ -112,6 +127,9 @@ func (c *Config) WithDefaults() *Config {
 	if d.UDPReadBuffer == 0 {
 		d.UDPReadBuffer = DefaultUDPReadBuffer
 	}
+	if d.Certificate == "" {
+		d.Certificate = DefaultCertificate
+	}
 	return &d
 }
 
@@ -135,9 +153,19 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if (c.TLSEnabled || c.AuthEnabled) && strings.ToLower(c.Protocol) == "udp" {
+		return errors.New("tls-enabled and auth-enabled require the tcp protocol")
+	}
+
 	return nil
 }
 
+// ValidateTemplates returns an error if any of templates is invalid.
+func ValidateTemplates(templates []string) error {
+	c := Config{Templates: templates}
+	return c.validateTemplates()
+}
+
 func (c *Config) validateTemplates() error {
 	// map to keep track of filters we see
 	filters := map[string]struct{}{}
@@ -261,7 +289,7 @@ type Configs []Config
 // Diagnostics returns one set of diagnostics for all of the Configs.
 func (c Configs) Diagnostics() (*diagnostics.Diagnostics, error) {
 	d := &diagnostics.Diagnostics{
-		Columns: []string{"enabled", "bind-address", "protocol", "database", "retention-policy", "batch-size", "batch-pending", "batch-timeout"},
+		Columns: []string{"enabled", "bind-address", "protocol", "database", "retention-policy", "batch-size", "batch-pending", "batch-timeout", "tls-enabled", "auth-enabled"},
 	}
 
 	for _, cc := range c {
@@ -270,7 +298,7 @@ func (c Configs) Diagnostics() (*diagnostics.Diagnostics, error) {
 			continue
 		}
 
-		r := []interface{}{true, cc.BindAddress, cc.Protocol, cc.Database, cc.RetentionPolicy, cc.BatchSize, cc.BatchPending, cc.BatchTimeout}
+		r := []interface{}{true, cc.BindAddress, cc.Protocol, cc.Database, cc.RetentionPolicy, cc.BatchSize, cc.BatchPending, cc.BatchTimeout, cc.TLSEnabled, cc.AuthEnabled}
 		d.AddRow(r)
 	}
 
@@ -168,3 +168,20 @@ func TestConfigValidateFilterDuplicates(t *testing.T) {
 	}
 
 }
+
+func TestConfigValidateTLSAuthProtocol(t *testing.T) {
+	c := &graphite.Config{Protocol: "tcp", TLSEnabled: true, AuthEnabled: true}
+	if err := c.Validate(); err != nil {
+		t.Errorf("config validate expected success, got %v", err)
+	}
+
+	c.Protocol = "udp"
+	if err := c.Validate(); err == nil {
+		t.Errorf("config validate expected error. got nil")
+	}
+
+	c.TLSEnabled, c.AuthEnabled = false, false
+	if err := c.Validate(); err != nil {
+		t.Errorf("config validate expected success, got %v", err)
+	}
+}
@@ -3,6 +3,9 @@ package graphite // import "github.com/influxdata/influxdb/services/graphite"
 
 import (
 	"bufio"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"math"
 	"net"
@@ -11,8 +14,10 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/pkg/tlsconfig"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/uber-go/zap"
@@ -20,6 +25,12 @@ import (
 
 const udpBufferSize = 65536
 
+// authTimeout is the time TCP connections have to authenticate.
+const authTimeout = 10 * time.Second
+
+// ErrAuthenticate is returned when a connection fails to authenticate.
+var ErrAuthenticate = errors.New("authentication failed")
+
 // statistics gathered by the graphite package.
 const (
 	statPointsReceived      = "pointsRx"
@@ -31,6 +42,7 @@ const (
 	statBatchesTransmitFail = "batchesTxFail"
 	statConnectionsActive   = "connsActive"
 	statConnectionsHandled  = "connsHandled"
+	statAuthFail            = "authFail"
 )
 
 type tcpConnection struct {
@@ -53,8 +65,20 @@ type Service struct {
 	batchTimeout    time.Duration
 	udpReadBuffer   int
 
+	tlsEnabled   bool
+	certificate  string
+	privateKey   string
+	clientCA     string
+	authEnabled  bool
+	sharedSecret string
+
 	batcher *tsdb.PointBatcher
-	parser  *Parser
+
+	parserMu   sync.RWMutex
+	parser     *Parser
+	templates  []string
+	parserTags models.Tags
+	separator  string
 
 	logger      zap.Logger
 	stats       *Statistics
@@ -86,6 +110,7 @@ type Service struct {
 		CreateRetentionPolicy(database string, spec *meta.RetentionPolicySpec, makeDefault bool) (*meta.RetentionPolicyInfo, error)
 		Database(name string) *meta.DatabaseInfo
 		RetentionPolicy(database, name string) (*meta.RetentionPolicyInfo, error)
+		Authenticate(username, password string) (meta.User, error)
 	}
 }
 
@@ -103,6 +128,12 @@ func NewService(c Config) (*Service, error) {
 		batchPending:    d.BatchPending,
 		udpReadBuffer:   d.UDPReadBuffer,
 		batchTimeout:    time.Duration(d.BatchTimeout),
+		tlsEnabled:      d.TLSEnabled,
+		certificate:     d.Certificate,
+		privateKey:      d.PrivateKey,
+		clientCA:        d.ClientCA,
+		authEnabled:     d.AuthEnabled,
+		sharedSecret:    d.SharedSecret,
 		logger:          zap.New(zap.NullEncoder()),
 		stats:           &Statistics{},
 		defaultTags:     models.StatisticTags{"proto": d.Protocol, "bind": d.BindAddress},
@@ -110,19 +141,81 @@ func NewService(c Config) (*Service, error) {
 		diagsKey:        strings.Join([]string{"graphite", d.Protocol, d.BindAddress}, ":"),
 	}
 
-	parser, err := NewParserWithOptions(Options{
-		Templates:   d.Templates,
-		DefaultTags: d.DefaultTags(),
-		Separator:   d.Separator})
+	s.parserTags = d.DefaultTags()
+	s.separator = d.Separator
 
+	parser, err := s.newParser(d.Templates)
 	if err != nil {
 		return nil, err
 	}
 	s.parser = parser
+	s.templates = d.Templates
 
 	return &s, nil
 }
 
+// newParser returns a parser using templates, with the default tags and
+// separator of the service.
+func (s *Service) newParser(templates []string) (*Parser, error) {
+	return NewParserWithOptions(Options{
+		Templates:   templates,
+		DefaultTags: s.parserTags,
+		Separator:   s.separator,
+	})
+}
+
+// Templates returns the templates currently used to parse metrics.
+func (s *Service) Templates() []string {
+	s.parserMu.RLock()
+	defer s.parserMu.RUnlock()
+	return s.templates
+}
+
+// SetTemplates replaces the templates used to parse metrics.  Metrics
+// received after it returns are parsed with the new templates.  The current
+// templates are kept if any of the new ones is invalid.
+func (s *Service) SetTemplates(templates []string) error {
+	parser, err := s.templatesParser(templates)
+	if err != nil {
+		return err
+	}
+
+	s.parserMu.Lock()
+	s.parser, s.templates = parser, templates
+	s.parserMu.Unlock()
+
+	s.logger.Info(fmt.Sprintf("Loaded %d templates", len(templates)))
+	return nil
+}
+
+// TestTemplates parses line with templates, or with the current templates
+// of the service if templates is nil, without writing the point.
+func (s *Service) TestTemplates(templates []string, line string) (models.Point, error) {
+	parser := s.currentParser()
+	if templates != nil {
+		var err error
+		if parser, err = s.templatesParser(templates); err != nil {
+			return nil, err
+		}
+	}
+	return parser.Parse(line)
+}
+
+// templatesParser validates templates and returns a parser using them.
+func (s *Service) templatesParser(templates []string) (*Parser, error) {
+	if err := ValidateTemplates(templates); err != nil {
+		return nil, err
+	}
+	return s.newParser(templates)
+}
+
+// currentParser returns the parser of the current templates.
+func (s *Service) currentParser() *Parser {
+	s.parserMu.RLock()
+	defer s.parserMu.RUnlock()
+	return s.parser
+}
+
 // Open starts the Graphite input processing data.
 func (s *Service) Open() error {
 	s.mu.Lock()
@@ -277,6 +370,7 @@ type Statistics struct {
 	BatchesTransmitFail int64
 	ActiveConnections   int64
 	HandledConnections  int64
+	AuthFail            int64
 }
 
 // Statistics returns statistics for periodic monitoring.
@@ -294,6 +388,7 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 			statBatchesTransmitFail: atomic.LoadInt64(&s.stats.BatchesTransmitFail),
 			statConnectionsActive:   atomic.LoadInt64(&s.stats.ActiveConnections),
 			statConnectionsHandled:  atomic.LoadInt64(&s.stats.HandledConnections),
+			statAuthFail:            atomic.LoadInt64(&s.stats.AuthFail),
 		},
 	}}
 }
@@ -309,6 +404,15 @@ func (s *Service) openTCPServer() (net.Addr, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	if s.tlsEnabled {
+		config, err := tlsconfig.Server(s.certificate, s.privateKey, s.clientCA)
+		if err != nil {
+			ln.Close()
+			return nil, err
+		}
+		ln = tls.NewListener(ln, config)
+	}
 	s.ln = ln
 
 	s.wg.Add(1)
@@ -344,6 +448,20 @@ func (s *Service) handleTCPConnection(conn net.Conn) {
 
 	reader := bufio.NewReader(conn)
 
+	if s.authEnabled {
+		conn.SetReadDeadline(time.Now().Add(authTimeout))
+		buf, err := reader.ReadBytes('\n')
+		if err == nil {
+			err = s.authenticate(strings.TrimSpace(string(buf)))
+		}
+		if err != nil {
+			atomic.AddInt64(&s.stats.AuthFail, 1)
+			s.logger.Info(fmt.Sprintf("failed to authenticate connection from %s: %s", conn.RemoteAddr(), err))
+			return
+		}
+		conn.SetReadDeadline(time.Time{})
+	}
+
 	for {
 		// Read up to the next newline.
 		buf, err := reader.ReadBytes('\n')
@@ -360,6 +478,32 @@ func (s *Service) handleTCPConnection(conn net.Conn) {
 	}
 }
 
+// authenticate returns nil if line is an auth command with the shared secret,
+// or with the credentials of a user allowed to write to the database.
+func (s *Service) authenticate(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "auth" {
+		return errors.New("auth command required")
+	}
+
+	switch len(fields) {
+	case 2:
+		if s.sharedSecret != "" && subtle.ConstantTimeCompare([]byte(fields[1]), []byte(s.sharedSecret)) == 1 {
+			return nil
+		}
+	case 3:
+		u, err := s.MetaClient.Authenticate(fields[1], fields[2])
+		if err != nil {
+			return ErrAuthenticate
+		}
+		if !u.AuthorizeDatabase(influxql.WritePrivilege, s.database) {
+			return fmt.Errorf("%q user is not authorized to write to database %q", u.ID(), s.database)
+		}
+		return nil
+	}
+	return ErrAuthenticate
+}
+
 func (s *Service) trackConnection(c net.Conn) {
 	s.tcpConnectionsMu.Lock()
 	defer s.tcpConnectionsMu.Unlock()
@@ -422,7 +566,7 @@ func (s *Service) handleLine(line string) {
 	}
 
 	// Parse it.
-	point, err := s.parser.Parse(line)
+	point, err := s.currentParser().Parse(line)
 	if err != nil {
 		switch err := err.(type) {
 		case *UnsupportedValueError:
@@ -1,14 +1,26 @@
 package graphite
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"math/big"
 	"net"
 	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/internal"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
@@ -194,6 +206,52 @@ func Test_Service_TCP(t *testing.T) {
 	wg.Wait()
 }
 
+// Ensure templates can be replaced and tested while the service is running.
+func TestService_SetTemplates(t *testing.T) {
+	t.Parallel()
+
+	config := Config{BindAddress: "127.0.0.1:0", Templates: []string{"measurement*"}}
+	service := NewTestService(&config)
+	if err := service.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer service.Service.Close()
+
+	const line = "servers.localhost.cpu 1.5 1500000000"
+	if pt, err := service.Service.TestTemplates(nil, line); err != nil {
+		t.Fatal(err)
+	} else if got, exp := pt.String(), "servers.localhost.cpu value=1.5 1500000000000000000"; got != exp {
+		t.Fatalf("unexpected point:\ngot %s\nexp %s", got, exp)
+	}
+
+	// Testing templates doesn't change the templates of the service.
+	if pt, err := service.Service.TestTemplates([]string{".host.measurement"}, line); err != nil {
+		t.Fatal(err)
+	} else if got, exp := pt.String(), "cpu,host=localhost value=1.5 1500000000000000000"; got != exp {
+		t.Fatalf("unexpected point:\ngot %s\nexp %s", got, exp)
+	} else if got := service.Service.Templates(); len(got) != 1 || got[0] != "measurement*" {
+		t.Fatalf("unexpected templates: %q", got)
+	}
+
+	if err := service.Service.SetTemplates([]string{".host.measurement"}); err != nil {
+		t.Fatal(err)
+	} else if pt, err := service.Service.TestTemplates(nil, line); err != nil {
+		t.Fatal(err)
+	} else if got, exp := pt.String(), "cpu,host=localhost value=1.5 1500000000000000000"; got != exp {
+		t.Fatalf("unexpected point:\ngot %s\nexp %s", got, exp)
+	}
+
+	// Invalid templates are rejected and the current ones are kept.
+	if err := service.Service.SetTemplates([]string{"host.cpu"}); err == nil {
+		t.Fatal("expected error")
+	} else if got := service.Service.Templates(); len(got) != 1 || got[0] != ".host.measurement" {
+		t.Fatalf("unexpected templates: %q", got)
+	}
+	if _, err := service.Service.TestTemplates([]string{"host.cpu"}, line); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
 func Test_Service_UDP(t *testing.T) {
 	t.Parallel()
 
@@ -252,6 +310,115 @@ func Test_Service_UDP(t *testing.T) {
 	conn.Close()
 }
 
+func Test_Service_TCP_TLSAuth(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "graphite-tls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := Config{}
+	config.Database = "graphitedb"
+	config.BatchSize = 0 // No batching.
+	config.BatchTimeout = toml.Duration(time.Second)
+	config.BindAddress = "127.0.0.1:0"
+	config.TLSEnabled = true
+	config.Certificate, config.PrivateKey = MustWriteCertificate(dir)
+	config.AuthEnabled = true
+	config.SharedSecret = "secret"
+
+	service := NewTestService(&config)
+
+	written := make(chan []models.Point, 1)
+	service.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+		written <- points
+		return nil
+	}
+
+	if err := service.Service.Open(); err != nil {
+		t.Fatalf("failed to open Graphite service: %s", err.Error())
+	}
+	defer service.Service.Close()
+
+	send := func(data string) *tls.Conn {
+		conn, err := tls.Dial("tcp", service.Service.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+
+	// A connection with the wrong secret is closed before its points are read.
+	conn := send("auth wrong\ncpu 1 1500000000\n")
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := ioutil.ReadAll(conn); err != nil {
+		t.Fatalf("connection should have been closed: %s", err)
+	}
+	conn.Close()
+
+	conn = send("auth secret\ncpu 2 1500000000\n")
+	defer conn.Close()
+
+	select {
+	case points := <-written:
+		if len(points) != 1 || points[0].String() != "cpu value=2 1500000000000000000" {
+			t.Fatalf("unexpected points: %v", points)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for points")
+	}
+
+	if n := atomic.LoadInt64(&service.Service.stats.AuthFail); n != 1 {
+		t.Fatalf("unexpected auth failures: %d", n)
+	}
+}
+
+func TestService_Authenticate(t *testing.T) {
+	config := Config{Database: "graphitedb", AuthEnabled: true, SharedSecret: "secret"}
+	service := NewTestService(&config)
+	service.MetaClient.AuthenticateFn = func(username, password string) (meta.User, error) {
+		if password != "password" {
+			return nil, meta.ErrAuthenticate
+		}
+		u := &meta.UserInfo{Name: username, Privileges: map[string]influxql.Privilege{}}
+		if username == "writer" {
+			u.Privileges["graphitedb"] = influxql.WritePrivilege
+		}
+		return u, nil
+	}
+
+	for _, tt := range []struct {
+		line string
+		err  string
+	}{
+		{line: "auth secret"},
+		{line: "auth writer password"},
+		{line: "cpu 1 1500000000", err: "auth command required"},
+		{line: "auth wrong", err: "authentication failed"},
+		{line: "auth writer wrong", err: "authentication failed"},
+		{line: "auth reader password", err: `"reader" user is not authorized to write to database "graphitedb"`},
+	} {
+		err := service.Service.authenticate(tt.line)
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.line, err)
+		} else if tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%s: unexpected error: got %v, exp %s", tt.line, err, tt.err)
+		}
+	}
+
+	// The shared secret is optional.
+	config.SharedSecret = ""
+	service = NewTestService(&config)
+	if err := service.Service.authenticate("auth "); err == nil {
+		t.Fatal("expected error for empty secret")
+	}
+}
+
 type TestService struct {
 	Service       *Service
 	MetaClient    *internal.MetaClientMock
@@ -304,6 +471,42 @@ func NewTestService(c *Config) *TestService {
 	return service
 }
 
+// MustWriteCertificate writes a self-signed certificate for 127.0.0.1 and its
+// private key to PEM files in dir, and returns their paths.
+func MustWriteCertificate(dir string) (cert, key string) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "graphite"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		panic(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		panic(err)
+	}
+
+	cert, key = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		panic(err)
+	}
+	if err := ioutil.WriteFile(key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		panic(err)
+	}
+	return cert, key
+}
+
 func (s *TestService) WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
 	return s.WritePointsFn(database, retentionPolicy, consistencyLevel, points)
 }
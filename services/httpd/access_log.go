@@ -0,0 +1,127 @@
+package httpd
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+const (
+	// AccessLogFormatCommon logs requests in the Common Log Format.
+	AccessLogFormatCommon = "common"
+
+	// AccessLogFormatJSON logs requests as JSON objects, one per line.
+	AccessLogFormatJSON = "json"
+)
+
+// accessLogKey is the context key of the access log entry of a request.
+type accessLogKey struct{}
+
+// accessLogEntry is an entry of the JSON access log.  The handlers of the
+// endpoints add what they know about a request, such as its database or
+// query, to the entry in the context of the request.  All of its methods
+// can be called on a nil entry.
+type accessLogEntry struct {
+	Time           string   `json:"time"`
+	RequestID      string   `json:"requestId,omitempty"`
+	ClientIP       string   `json:"clientIp"`
+	ForwardedFor   string   `json:"forwardedFor,omitempty"`
+	User           string   `json:"user,omitempty"`
+	Method         string   `json:"method"`
+	Path           string   `json:"path"`
+	Endpoint       string   `json:"endpoint"`
+	Database       string   `json:"database,omitempty"`
+	StatementTypes []string `json:"statementTypes,omitempty"`
+	Query          string   `json:"query,omitempty"`
+	Points         int      `json:"points,omitempty"`
+	Status         int      `json:"status"`
+	Error          string   `json:"error,omitempty"`
+	DurationUs     int64    `json:"durationUs"`
+	Rows           int      `json:"rows"`
+	Bytes          int      `json:"bytes"`
+	UserAgent      string   `json:"userAgent,omitempty"`
+}
+
+// newAccessLogEntry returns an entry for a request to the endpoint.
+func newAccessLogEntry(r *http.Request, endpoint string, start time.Time) *accessLogEntry {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return &accessLogEntry{
+		Time:         start.UTC().Format(time.RFC3339Nano),
+		ClientIP:     host,
+		ForwardedFor: strings.Join(r.Header["X-Forwarded-For"], ","),
+		User:         parseUsername(r),
+		Method:       r.Method,
+		Path:         r.URL.Path,
+		Endpoint:     endpoint,
+		UserAgent:    r.UserAgent(),
+	}
+}
+
+// accessLogFromContext returns the access log entry of a request, or nil if
+// the request isn't logged as JSON.
+func accessLogFromContext(ctx context.Context) *accessLogEntry {
+	e, _ := ctx.Value(accessLogKey{}).(*accessLogEntry)
+	return e
+}
+
+// setUser sets the authenticated user of the request.
+func (e *accessLogEntry) setUser(user meta.User) {
+	if e != nil && user != nil {
+		e.User = user.ID()
+	}
+}
+
+// setQuery sets the database and the statements of a query.  The query
+// text is normalized to its canonical form, with the passwords redacted.
+func (e *accessLogEntry) setQuery(database string, q *influxql.Query) {
+	if e == nil {
+		return
+	}
+	e.Database = database
+	e.Query = q.String()
+	e.StatementTypes = make([]string, len(q.Statements))
+	for i, stmt := range q.Statements {
+		e.StatementTypes[i] = reflect.Indirect(reflect.ValueOf(stmt)).Type().Name()
+	}
+}
+
+// setWrite sets the database and the number of points of a write.
+func (e *accessLogEntry) setWrite(database string, points int) {
+	if e != nil {
+		e.Database = database
+		e.Points = points
+	}
+}
+
+// addRows adds the rows of the results to the rows returned.
+func (e *accessLogEntry) addRows(results ...*query.Result) {
+	if e == nil {
+		return
+	}
+	for _, r := range results {
+		for _, s := range r.Series {
+			e.Rows += len(s.Values)
+		}
+	}
+}
+
+// finish completes the entry with the response and returns it as JSON.
+func (e *accessLogEntry) finish(l *responseLogger, r *http.Request, start time.Time) ([]byte, error) {
+	e.RequestID = r.Header.Get("Request-Id")
+	e.Status = l.Status()
+	e.Error = l.Header().Get("X-InfluxDB-Error")
+	e.DurationUs = int64(time.Since(start) / time.Microsecond)
+	e.Bytes = l.Size()
+	return json.Marshal(e)
+}
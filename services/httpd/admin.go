@@ -0,0 +1,650 @@
+package httpd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/audit"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// adminDatabase is a database in the admin API.
+type adminDatabase struct {
+	Name            string                `json:"name"`
+	RetentionPolicy *adminRetentionPolicy `json:"retentionPolicy,omitempty"`
+}
+
+// adminRetentionPolicy is a retention policy in the admin API.  Durations are
+// InfluxQL duration literals, or INF.
+type adminRetentionPolicy struct {
+	Database           string `json:"database,omitempty"`
+	Name               string `json:"name"`
+	Duration           string `json:"duration"`
+	ShardGroupDuration string `json:"shardGroupDuration,omitempty"`
+	ReplicaN           int    `json:"replicaN"`
+	Default            bool   `json:"default"`
+}
+
+// adminUser is a user in the admin API.  The password is only read.
+type adminUser struct {
+	Name     string `json:"name"`
+	Password string `json:"password,omitempty"`
+	Admin    bool   `json:"admin"`
+}
+
+// serveAdminDatabases lists the databases the user may read or write, like
+// SHOW DATABASES.
+func (h *Handler) serveAdminDatabases(w http.ResponseWriter, r *http.Request, user meta.User) {
+	row, ok := h.executeAdminStatement(w, r, user, &influxql.ShowDatabasesStatement{})
+	if !ok {
+		return
+	}
+
+	databases := []adminDatabase{}
+	for _, v := range row.Values {
+		databases = append(databases, adminDatabase{Name: v[0].(string)})
+	}
+	h.writeAdminJSON(w, http.StatusOK, struct {
+		Databases []adminDatabase `json:"databases"`
+	}{Databases: databases})
+}
+
+// serveAdminCreateDatabase creates the database in the request body, with a
+// retention policy if it has one, like CREATE DATABASE.
+func (h *Handler) serveAdminCreateDatabase(w http.ResponseWriter, r *http.Request, user meta.User) {
+	var db adminDatabase
+	if !h.decodeAdminJSON(w, r, &db) {
+		return
+	} else if db.Name == "" {
+		h.httpError(w, meta.ErrDatabaseNameRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stmt := &influxql.CreateDatabaseStatement{Name: db.Name}
+	if rp := db.RetentionPolicy; rp != nil {
+		stmt.RetentionPolicyCreate = true
+		stmt.RetentionPolicyName = rp.Name
+		if rp.Duration != "" {
+			d, err := parseAdminDuration(rp.Duration)
+			if err != nil {
+				h.httpError(w, "invalid duration: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			stmt.RetentionPolicyDuration = &d
+		}
+		if rp.ShardGroupDuration != "" {
+			d, err := parseAdminDuration(rp.ShardGroupDuration)
+			if err != nil {
+				h.httpError(w, "invalid shard group duration: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			stmt.RetentionPolicyShardGroupDuration = d
+		}
+		if rp.ReplicaN != 0 {
+			stmt.RetentionPolicyReplication = &rp.ReplicaN
+		}
+	}
+
+	if _, ok := h.executeAdminStatement(w, r, user, stmt); ok {
+		h.writeHeader(w, http.StatusCreated)
+	}
+}
+
+// serveAdminDropDatabase drops the database in the URL, like DROP DATABASE.
+func (h *Handler) serveAdminDropDatabase(w http.ResponseWriter, r *http.Request, user meta.User) {
+	name := r.URL.Query().Get(":name")
+	if h.MetaClient.Database(name) == nil {
+		h.httpError(w, influxdb.ErrDatabaseNotFound(name).Error(), http.StatusNotFound)
+		return
+	}
+
+	if _, ok := h.executeAdminStatement(w, r, user, &influxql.DropDatabaseStatement{Name: name}); ok {
+		h.writeHeader(w, http.StatusNoContent)
+	}
+}
+
+// serveAdminUsers lists the users, like SHOW USERS.
+func (h *Handler) serveAdminUsers(w http.ResponseWriter, r *http.Request, user meta.User) {
+	row, ok := h.executeAdminStatement(w, r, user, &influxql.ShowUsersStatement{})
+	if !ok {
+		return
+	}
+
+	users := []adminUser{}
+	for _, v := range row.Values {
+		users = append(users, adminUser{Name: v[0].(string), Admin: v[1].(bool)})
+	}
+	h.writeAdminJSON(w, http.StatusOK, struct {
+		Users []adminUser `json:"users"`
+	}{Users: users})
+}
+
+// serveAdminCreateUser creates the user in the request body, like CREATE USER.
+func (h *Handler) serveAdminCreateUser(w http.ResponseWriter, r *http.Request, user meta.User) {
+	var u adminUser
+	if !h.decodeAdminJSON(w, r, &u) {
+		return
+	} else if u.Name == "" {
+		h.httpError(w, meta.ErrUsernameRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stmt := &influxql.CreateUserStatement{Name: u.Name, Password: u.Password, Admin: u.Admin}
+	if _, ok := h.executeAdminStatement(w, r, user, stmt); ok {
+		h.writeHeader(w, http.StatusCreated)
+	}
+}
+
+// serveAdminDropUser drops the user in the URL, like DROP USER.
+func (h *Handler) serveAdminDropUser(w http.ResponseWriter, r *http.Request, user meta.User) {
+	stmt := &influxql.DropUserStatement{Name: r.URL.Query().Get(":name")}
+	if _, ok := h.executeAdminStatement(w, r, user, stmt); ok {
+		h.writeHeader(w, http.StatusNoContent)
+	}
+}
+
+// serveAdminRetentionPolicies lists the retention policies of the database in
+// the db parameter, like SHOW RETENTION POLICIES.
+func (h *Handler) serveAdminRetentionPolicies(w http.ResponseWriter, r *http.Request, user meta.User) {
+	db := r.URL.Query().Get("db")
+	if !h.checkAdminDatabase(w, db) {
+		return
+	}
+
+	row, ok := h.executeAdminStatement(w, r, user, &influxql.ShowRetentionPoliciesStatement{Database: db})
+	if !ok {
+		return
+	}
+
+	rps := []adminRetentionPolicy{}
+	for _, v := range row.Values {
+		rps = append(rps, adminRetentionPolicy{
+			Name:               v[0].(string),
+			Duration:           v[1].(string),
+			ShardGroupDuration: v[2].(string),
+			ReplicaN:           v[3].(int),
+			Default:            v[4].(bool),
+		})
+	}
+	h.writeAdminJSON(w, http.StatusOK, struct {
+		RetentionPolicies []adminRetentionPolicy `json:"retentionPolicies"`
+	}{RetentionPolicies: rps})
+}
+
+// serveAdminCreateRetentionPolicy creates the retention policy in the request
+// body, like CREATE RETENTION POLICY.  The replication factor defaults to 1.
+func (h *Handler) serveAdminCreateRetentionPolicy(w http.ResponseWriter, r *http.Request, user meta.User) {
+	var rp adminRetentionPolicy
+	if !h.decodeAdminJSON(w, r, &rp) {
+		return
+	} else if !h.checkAdminDatabase(w, rp.Database) {
+		return
+	} else if rp.Name == "" {
+		h.httpError(w, meta.ErrRetentionPolicyNameRequired.Error(), http.StatusBadRequest)
+		return
+	} else if rp.Duration == "" {
+		h.httpError(w, "retention policy duration required", http.StatusBadRequest)
+		return
+	}
+
+	stmt := &influxql.CreateRetentionPolicyStatement{
+		Database:    rp.Database,
+		Name:        rp.Name,
+		Replication: rp.ReplicaN,
+		Default:     rp.Default,
+	}
+	if stmt.Replication == 0 {
+		stmt.Replication = 1
+	}
+
+	var err error
+	if stmt.Duration, err = parseAdminDuration(rp.Duration); err != nil {
+		h.httpError(w, "invalid duration: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if rp.ShardGroupDuration != "" {
+		if stmt.ShardGroupDuration, err = parseAdminDuration(rp.ShardGroupDuration); err != nil {
+			h.httpError(w, "invalid shard group duration: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, ok := h.executeAdminStatement(w, r, user, stmt); ok {
+		h.writeHeader(w, http.StatusCreated)
+	}
+}
+
+// serveAdminDropRetentionPolicy drops the retention policy in the URL from the
+// database in the db parameter, like DROP RETENTION POLICY.
+func (h *Handler) serveAdminDropRetentionPolicy(w http.ResponseWriter, r *http.Request, user meta.User) {
+	db, name := r.URL.Query().Get("db"), r.URL.Query().Get(":name")
+	if !h.checkAdminDatabase(w, db) {
+		return
+	} else if h.MetaClient.Database(db).RetentionPolicy(name) == nil {
+		h.httpError(w, influxdb.ErrRetentionPolicyNotFound(name).Error(), http.StatusNotFound)
+		return
+	}
+
+	stmt := &influxql.DropRetentionPolicyStatement{Database: db, Name: name}
+	if _, ok := h.executeAdminStatement(w, r, user, stmt); ok {
+		h.writeHeader(w, http.StatusNoContent)
+	}
+}
+
+// adminSchema is the schema of a database in the admin API.
+type adminSchema struct {
+	Database string           `json:"database"`
+	Schema   *meta.SchemaInfo `json:"schema"`
+}
+
+// serveAdminSchemas lists the schemas of the databases that have one.
+func (h *Handler) serveAdminSchemas(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.checkAdminSchemas(w, user) {
+		return
+	}
+
+	schemas := []adminSchema{}
+	for _, di := range h.MetaClient.Databases() {
+		if di.Schema != nil {
+			schemas = append(schemas, adminSchema{Database: di.Name, Schema: di.Schema})
+		}
+	}
+	h.writeAdminJSON(w, http.StatusOK, struct {
+		Schemas []adminSchema `json:"schemas"`
+	}{Schemas: schemas})
+}
+
+// serveAdminSetSchema sets the schema of the database in the request body,
+// replacing its current schema.
+func (h *Handler) serveAdminSetSchema(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.checkAdminSchemas(w, user) {
+		return
+	}
+
+	var s adminSchema
+	if !h.decodeAdminJSON(w, r, &s) {
+		return
+	} else if !h.checkAdminDatabase(w, s.Database) {
+		return
+	} else if s.Schema == nil {
+		h.httpError(w, "schema required", http.StatusBadRequest)
+		return
+	} else if err := s.Schema.Validate(); err != nil {
+		h.httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := h.MetaClient.SetSchema(s.Database, s.Schema)
+	h.audit(r, user, audit.ActionSchema, s.Database, err)
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.Logger.Info(fmt.Sprintf("schema of database %q set, mode: %s, measurements: %d", s.Database, s.Schema.Mode, len(s.Schema.Measurements)))
+	h.writeHeader(w, http.StatusNoContent)
+}
+
+// serveAdminDropSchema removes the schema of the database in the URL, so any
+// point may be written to it.
+func (h *Handler) serveAdminDropSchema(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.checkAdminSchemas(w, user) {
+		return
+	}
+
+	name := r.URL.Query().Get(":name")
+	if !h.checkAdminDatabase(w, name) {
+		return
+	} else if h.MetaClient.Database(name).Schema == nil {
+		h.httpError(w, "schema not found", http.StatusNotFound)
+		return
+	}
+
+	err := h.MetaClient.SetSchema(name, nil)
+	h.audit(r, user, audit.ActionSchema, name, err)
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.Logger.Info(fmt.Sprintf("schema of database %q removed", name))
+	h.writeHeader(w, http.StatusNoContent)
+}
+
+// checkAdminSchemas checks that the user is an admin. It writes an error and
+// returns false if not.
+func (h *Handler) checkAdminSchemas(w http.ResponseWriter, user meta.User) bool {
+	if h.Config.AuthEnabled && (user == nil || !user.IsAdmin()) {
+		h.httpError(w, "schemas require admin privileges", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// adminGraphiteTest is a graphite metric line to parse with templates.  The
+// current templates of the input bound to BindAddress are used if Templates
+// is null.
+type adminGraphiteTest struct {
+	BindAddress string   `json:"bindAddress,omitempty"`
+	Templates   []string `json:"templates,omitempty"`
+	Line        string   `json:"line"`
+}
+
+// adminGraphitePoint is the point a graphite metric line is parsed to.
+type adminGraphitePoint struct {
+	Measurement string                 `json:"measurement"`
+	Tags        map[string]string      `json:"tags"`
+	Fields      map[string]interface{} `json:"fields"`
+	Time        time.Time              `json:"time"`
+}
+
+// serveAdminGraphiteReload reloads the templates of the graphite inputs from
+// the configuration file.
+func (h *Handler) serveAdminGraphiteReload(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.checkAdminGraphite(w, user) {
+		return
+	}
+
+	if err := h.Graphite.ReloadGraphiteTemplates(); err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeHeader(w, http.StatusNoContent)
+}
+
+// serveAdminGraphiteTestTemplate returns the point the graphite metric line in
+// the request body is parsed to, without writing it.
+func (h *Handler) serveAdminGraphiteTestTemplate(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.checkAdminGraphite(w, user) {
+		return
+	}
+
+	var test adminGraphiteTest
+	if !h.decodeAdminJSON(w, r, &test) {
+		return
+	} else if test.Line == "" {
+		h.httpError(w, "line required", http.StatusBadRequest)
+		return
+	}
+
+	pt, err := h.Graphite.TestGraphiteTemplates(test.BindAddress, test.Templates, test.Line)
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fields, err := pt.Fields()
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeAdminJSON(w, http.StatusOK, adminGraphitePoint{
+		Measurement: string(pt.Name()),
+		Tags:        pt.Tags().Map(),
+		Fields:      fields,
+		Time:        pt.Time().UTC(),
+	})
+}
+
+// checkAdminGraphite checks that graphite templates are supported and that the
+// user is an admin. It writes an error and returns false if not.
+func (h *Handler) checkAdminGraphite(w http.ResponseWriter, user meta.User) bool {
+	if h.Graphite == nil {
+		h.httpError(w, "graphite templates are not supported", http.StatusNotImplemented)
+		return false
+	} else if h.Config.AuthEnabled && (user == nil || !user.IsAdmin()) {
+		h.httpError(w, "graphite templates require admin privileges", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// adminLogLevels are the levels of the logs.
+type adminLogLevels struct {
+	Level    string            `json:"level"`
+	Services map[string]string `json:"services"`
+}
+
+// adminLogLevel is a change of the level of the logs of a service, or of the
+// default level if Service is empty.  An empty Level removes the level of the
+// service so its logs are written at the default level.
+type adminLogLevel struct {
+	Service string `json:"service,omitempty"`
+	Level   string `json:"level"`
+}
+
+// serveAdminLogLevels returns the default level of the logs and the levels of
+// the services overriding it.
+func (h *Handler) serveAdminLogLevels(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.checkAdminLogLevels(w, user) {
+		return
+	}
+	h.writeAdminJSON(w, http.StatusOK, adminLogLevels{
+		Level:    h.LogLevels.Level(),
+		Services: h.LogLevels.ServiceLevels(),
+	})
+}
+
+// serveAdminSetLogLevel changes the level of the logs of a service, or the
+// default level, until the server restarts.
+func (h *Handler) serveAdminSetLogLevel(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.checkAdminLogLevels(w, user) {
+		return
+	}
+
+	var level adminLogLevel
+	if !h.decodeAdminJSON(w, r, &level) {
+		return
+	}
+
+	// The target of the audit entry is the service, or the default level,
+	// and the level it is set to.
+	target := level.Service
+	if target == "" {
+		target = "default"
+	}
+	if level.Level == "" {
+		if level.Service == "" {
+			h.httpError(w, "level required", http.StatusBadRequest)
+			return
+		}
+		h.LogLevels.ResetLevel(level.Service)
+		h.audit(r, user, audit.ActionLogLevel, target+"=default", nil)
+	} else {
+		err := h.LogLevels.SetLevel(level.Service, level.Level)
+		h.audit(r, user, audit.ActionLogLevel, target+"="+level.Level, err)
+		if err != nil {
+			h.httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	h.Logger.Info(fmt.Sprintf("log level of %q set to %q", level.Service, level.Level))
+
+	h.writeAdminJSON(w, http.StatusOK, adminLogLevels{
+		Level:    h.LogLevels.Level(),
+		Services: h.LogLevels.ServiceLevels(),
+	})
+}
+
+// checkAdminLogLevels checks that the levels of the logs can be changed and
+// that the user is an admin. It writes an error and returns false if not.
+func (h *Handler) checkAdminLogLevels(w http.ResponseWriter, user meta.User) bool {
+	if h.LogLevels == nil {
+		h.httpError(w, "log levels are not supported", http.StatusNotImplemented)
+		return false
+	} else if h.Config.AuthEnabled && (user == nil || !user.IsAdmin()) {
+		h.httpError(w, "log levels require admin privileges", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// adminConfigReload reports the settings changed by a reload of the
+// configuration file.
+type adminConfigReload struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// serveAdminConfigReload reads the configuration file again, applying the
+// settings that can change while the server runs, and returns the changed
+// settings that were applied and those that require a restart.
+func (h *Handler) serveAdminConfigReload(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if h.ConfigReloader == nil {
+		h.httpError(w, "configuration reload is not supported", http.StatusNotImplemented)
+		return
+	} else if h.Config.AuthEnabled && (user == nil || !user.IsAdmin()) {
+		h.httpError(w, "configuration reload requires admin privileges", http.StatusForbidden)
+		return
+	}
+
+	applied, restartRequired, err := h.ConfigReloader.Reload()
+	h.audit(r, user, audit.ActionConfigReload, "", err)
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.Logger.Info(fmt.Sprintf("configuration reloaded, applied: %v, restart required: %v", applied, restartRequired))
+
+	res := adminConfigReload{Applied: applied, RestartRequired: restartRequired}
+	if res.Applied == nil {
+		res.Applied = []string{}
+	}
+	if res.RestartRequired == nil {
+		res.RestartRequired = []string{}
+	}
+	h.writeAdminJSON(w, http.StatusOK, res)
+}
+
+// audit records action, taken on target by the user of r, if the handler has
+// an auditor.
+func (h *Handler) audit(r *http.Request, user meta.User, action, target string, err error) {
+	if h.Auditor == nil {
+		return
+	}
+	var name string
+	if user != nil {
+		name = user.ID()
+	}
+	h.Auditor.AuditAction(action, name, r.RemoteAddr, target, err)
+}
+
+// checkAdminDatabase checks that the named database exists. It writes an
+// error and returns false if not.
+func (h *Handler) checkAdminDatabase(w http.ResponseWriter, name string) bool {
+	if name == "" {
+		h.httpError(w, meta.ErrDatabaseNameRequired.Error(), http.StatusBadRequest)
+		return false
+	} else if h.MetaClient.Database(name) == nil {
+		h.httpError(w, influxdb.ErrDatabaseNotFound(name).Error(), http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// executeAdminStatement authorizes and executes an admin statement as the
+// user, so it is logged and audited like the same statement sent to /query.
+// It returns the first row of the result, or writes an error and returns
+// false if the statement fails.
+func (h *Handler) executeAdminStatement(w http.ResponseWriter, r *http.Request, user meta.User, stmt influxql.Statement) (*models.Row, bool) {
+	if !h.authorizeStatement(w, user, stmt) {
+		return nil, false
+	}
+
+	opts := query.ExecutionOptions{RemoteAddr: r.RemoteAddr}
+	if user != nil {
+		opts.User = user.ID()
+	}
+	if h.Config.AuthEnabled {
+		opts.Authorizer = user
+	} else {
+		opts.Authorizer = query.OpenAuthorizer{}
+	}
+
+	q := &influxql.Query{Statements: influxql.Statements{stmt}}
+	if h.QueryCache != nil && queryModifiesData(q) {
+		defer h.QueryCache.Purge()
+	}
+
+	row := &models.Row{}
+	for result := range h.QueryExecutor.ExecuteQuery(q, opts, nil) {
+		if result.Err != nil {
+			h.httpError(w, result.Err.Error(), adminErrorStatus(result.Err))
+			return nil, false
+		}
+		if len(result.Series) > 0 {
+			row = result.Series[0]
+		}
+	}
+	return row, true
+}
+
+// adminErrorStatus returns the status code of an error returned by an admin
+// statement.
+func adminErrorStatus(err error) int {
+	switch err {
+	case meta.ErrDatabaseNameRequired,
+		meta.ErrInvalidName,
+		meta.ErrRetentionPolicyNameRequired,
+		meta.ErrRetentionPolicyDurationTooLow,
+		meta.ErrIncompatibleDurations,
+		meta.ErrReplicationFactorTooLow,
+		meta.ErrUsernameRequired:
+		return http.StatusBadRequest
+	case meta.ErrDatabaseNotExists,
+		meta.ErrRetentionPolicyNotFound,
+		meta.ErrUserNotFound:
+		return http.StatusNotFound
+	case meta.ErrDatabaseExists,
+		meta.ErrRetentionPolicyExists,
+		meta.ErrRetentionPolicyNameExists,
+		meta.ErrRetentionPolicyConflict,
+		meta.ErrUserExists:
+		return http.StatusConflict
+	}
+	if influxdb.IsAuthorizationError(err) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// decodeAdminJSON decodes the JSON request body into v. It writes an error and
+// returns false if the body is invalid.
+func (h *Handler) decodeAdminJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Body == nil {
+		h.httpError(w, "request body required", http.StatusBadRequest)
+		return false
+	}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(v); err != nil {
+		h.httpError(w, fmt.Sprintf("invalid request body: %s", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writeAdminJSON writes v as the JSON response body with the status code.
+func (h *Handler) writeAdminJSON(w http.ResponseWriter, code int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	h.writeHeader(w, code)
+	w.Write(b)
+}
+
+// parseAdminDuration parses an InfluxQL duration literal, or INF for an
+// infinite duration.
+func parseAdminDuration(s string) (time.Duration, error) {
+	if strings.EqualFold(s, "INF") {
+		return 0, nil
+	}
+	return influxql.ParseDuration(s)
+}
@@ -0,0 +1,509 @@
+package httpd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// arrowFormatter writes responses as Apache Arrow IPC streams. Each
+// statement of a response is written as a separate stream, starting with a
+// schema message and ending with an end-of-stream marker, so chunked
+// responses are a sequence of streams that clients read until the body ends.
+//
+// The columns of a stream are "name" and "tags", formatted as in CSV
+// responses, followed by the columns of the statement's series. Column types
+// are inferred from the first value of each column that is not null. Values
+// that cannot be converted to the type of their column are null.
+//
+// Errors are written as a stream with a single "error" column.
+type arrowFormatter struct {
+	io.Writer
+}
+
+func (f *arrowFormatter) WriteResponse(resp Response) (n int, err error) {
+	var buf bytes.Buffer
+	if resp.Err != nil {
+		writeArrowError(&buf, resp.Err)
+	}
+	for _, result := range resp.Results {
+		if result.Err != nil {
+			writeArrowError(&buf, result.Err)
+		} else if len(result.Series) > 0 {
+			writeArrowRows(&buf, result.Series)
+		}
+	}
+	return f.Write(buf.Bytes())
+}
+
+// writeArrowError writes err as a stream of one row with an "error" column.
+func writeArrowError(buf *bytes.Buffer, err error) {
+	cols := []arrowColumn{{name: "error", typ: arrowUtf8, values: []interface{}{err.Error()}}}
+	writeArrowStream(buf, cols, 1)
+}
+
+// writeArrowRows writes the rows of series as a stream with one record
+// batch.
+func writeArrowRows(buf *bytes.Buffer, series models.Rows) {
+	// The series of a statement can have different columns, such as when
+	// selecting from several measurements, so use the union of the columns.
+	var names []string
+	index := make(map[string]int)
+	var nrows int
+	for _, row := range series {
+		for _, c := range row.Columns {
+			if _, ok := index[c]; !ok {
+				index[c] = len(names)
+				names = append(names, c)
+			}
+		}
+		nrows += len(row.Values)
+	}
+
+	cols := make([]arrowColumn, 2+len(names))
+	cols[0] = arrowColumn{name: "name", typ: arrowUtf8, values: make([]interface{}, 0, nrows)}
+	cols[1] = arrowColumn{name: "tags", typ: arrowUtf8, values: make([]interface{}, 0, nrows)}
+	for i, name := range names {
+		cols[i+2] = arrowColumn{name: name, typ: arrowNull, values: make([]interface{}, nrows)}
+	}
+
+	var r int
+	for _, row := range series {
+		var tags string
+		if len(row.Tags) > 0 {
+			tags = string(models.NewTags(row.Tags).HashKey()[1:])
+		}
+		for _, values := range row.Values {
+			cols[0].values = append(cols[0].values, row.Name)
+			cols[1].values = append(cols[1].values, tags)
+			for i, v := range values {
+				if i >= len(row.Columns) {
+					break
+				}
+				col := &cols[2+index[row.Columns[i]]]
+				if col.typ == arrowNull {
+					col.typ = arrowTypeOf(v)
+				}
+				col.values[r] = v
+			}
+			r++
+		}
+	}
+
+	// Columns with only null values have no type to infer.
+	for i := range cols {
+		if cols[i].typ == arrowNull {
+			cols[i].typ = arrowUtf8
+		}
+	}
+	writeArrowStream(buf, cols, nrows)
+}
+
+// writeArrowStream writes a stream with a schema for cols and a record batch
+// of their values.
+func writeArrowStream(buf *bytes.Buffer, cols []arrowColumn, nrows int) {
+	fields := make(fbVector, len(cols))
+	for i, col := range cols {
+		fields[i] = col.field()
+	}
+	schema := fbTable{
+		{size: 2, value: 0}, // endianness: little
+		{ref: fields},
+	}
+	writeArrowMessage(buf, arrowHeaderSchema, schema, nil)
+
+	var body []byte
+	var nodes, buffers fbStructVector
+	for _, col := range cols {
+		bufs, nulls := col.encode(nrows)
+		nodes = append(nodes, [2]int64{int64(nrows), int64(nulls)})
+		for _, b := range bufs {
+			buffers = append(buffers, [2]int64{int64(len(body)), int64(len(b))})
+			body = append(body, b...)
+			body = append(body, make([]byte, arrowPadding(len(body)))...)
+		}
+	}
+	batch := fbTable{
+		{size: 8, value: uint64(nrows)},
+		{ref: nodes},
+		{ref: buffers},
+	}
+	writeArrowMessage(buf, arrowHeaderRecordBatch, batch, body)
+
+	// End-of-stream marker.
+	buf.Write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0})
+}
+
+const (
+	// arrowMetadataV5 is the Arrow metadata version written.
+	arrowMetadataV5 = 4
+
+	// Message header types.
+	arrowHeaderSchema      = 1
+	arrowHeaderRecordBatch = 3
+)
+
+// writeArrowMessage writes an encapsulated IPC message with a header and
+// body. The body must be padded to a multiple of 8 bytes.
+func writeArrowMessage(buf *bytes.Buffer, headerType byte, header fbObject, body []byte) {
+	meta := fbFinish(fbTable{
+		{size: 2, value: arrowMetadataV5},
+		{size: 1, value: uint64(headerType)},
+		{ref: header},
+		{size: 8, value: uint64(len(body))},
+	})
+
+	// The metadata is padded so that the body starts at a multiple of 8.
+	pad := arrowPadding(len(meta))
+	var prefix [8]byte
+	binary.LittleEndian.PutUint32(prefix[0:], 0xffffffff)
+	binary.LittleEndian.PutUint32(prefix[4:], uint32(len(meta)+pad))
+	buf.Write(prefix[:])
+	buf.Write(meta)
+	buf.Write(make([]byte, pad))
+	buf.Write(body)
+}
+
+// arrowPadding returns the padding needed after n bytes to reach a multiple
+// of 8.
+func arrowPadding(n int) int {
+	return (8 - n%8) % 8
+}
+
+// arrowType is the type of an Arrow column.
+type arrowType int
+
+const (
+	arrowNull arrowType = iota
+	arrowUtf8
+	arrowInt64
+	arrowUint64
+	arrowFloat64
+	arrowBool
+	arrowTimestamp
+)
+
+// arrowTypeOf returns the column type for the value v, or arrowNull if v is
+// nil.
+func arrowTypeOf(v interface{}) arrowType {
+	switch v.(type) {
+	case nil:
+		return arrowNull
+	case float64:
+		return arrowFloat64
+	case int64:
+		return arrowInt64
+	case uint64:
+		return arrowUint64
+	case bool:
+		return arrowBool
+	case time.Time:
+		return arrowTimestamp
+	default:
+		return arrowUtf8
+	}
+}
+
+// arrowColumn holds the values of a column of a record batch.
+type arrowColumn struct {
+	name   string
+	typ    arrowType
+	values []interface{}
+}
+
+// field returns the schema field of the column.
+func (c *arrowColumn) field() fbTable {
+	var typeID uint64
+	var typ fbTable
+	switch c.typ {
+	case arrowInt64:
+		typeID, typ = 2, fbTable{{size: 4, value: 64}, {size: 1, value: 1}}
+	case arrowUint64:
+		typeID, typ = 2, fbTable{{size: 4, value: 64}, {size: 1, value: 0}}
+	case arrowFloat64:
+		typeID, typ = 3, fbTable{{size: 2, value: 2}} // double precision
+	case arrowBool:
+		typeID, typ = 6, fbTable{}
+	case arrowTimestamp:
+		typeID, typ = 10, fbTable{{size: 2, value: 3}, {ref: fbString("UTC")}} // nanoseconds
+	default:
+		typeID, typ = 5, fbTable{} // utf8
+	}
+
+	return fbTable{
+		{ref: fbString(c.name)},
+		{size: 1, value: 1}, // nullable
+		{size: 1, value: typeID},
+		{ref: typ},
+		{},                // dictionary
+		{ref: fbVector{}}, // children
+	}
+}
+
+// encode returns the buffers of the first n values of the column and the
+// number of nulls.
+func (c *arrowColumn) encode(n int) (buffers [][]byte, nulls int) {
+	validity := make([]byte, (n+7)/8)
+	var data, offsets []byte
+	switch c.typ {
+	case arrowUtf8:
+		offsets = make([]byte, 4*(n+1))
+	case arrowBool:
+		data = make([]byte, (n+7)/8)
+	default:
+		data = make([]byte, 8*n)
+	}
+
+	for i := 0; i < n; i++ {
+		var v interface{}
+		if i < len(c.values) {
+			v = c.convert(c.values[i])
+		}
+		if v == nil {
+			nulls++
+		} else {
+			validity[i/8] |= 1 << uint(i%8)
+		}
+
+		switch c.typ {
+		case arrowUtf8:
+			if s, ok := v.(string); ok {
+				data = append(data, s...)
+			}
+			binary.LittleEndian.PutUint32(offsets[4*(i+1):], uint32(len(data)))
+		case arrowBool:
+			if b, ok := v.(bool); ok && b {
+				data[i/8] |= 1 << uint(i%8)
+			}
+		default:
+			if u, ok := v.(uint64); ok {
+				binary.LittleEndian.PutUint64(data[8*i:], u)
+			}
+		}
+	}
+
+	if c.typ == arrowUtf8 {
+		return [][]byte{validity, offsets, data}, nulls
+	}
+	return [][]byte{validity, data}, nulls
+}
+
+// convert converts v to the representation stored for the column's type:
+// a string for utf8, a bool for bool, and the bits of the value as a uint64
+// for other types. It returns nil if v is nil or cannot be converted.
+func (c *arrowColumn) convert(v interface{}) interface{} {
+	switch c.typ {
+	case arrowUtf8:
+		switch v := v.(type) {
+		case nil:
+			return nil
+		case string:
+			return v
+		case time.Time:
+			return v.UTC().Format(time.RFC3339Nano)
+		default:
+			return fmt.Sprint(v)
+		}
+	case arrowBool:
+		if v, ok := v.(bool); ok {
+			return v
+		}
+	case arrowFloat64:
+		switch v := v.(type) {
+		case float64:
+			return math.Float64bits(v)
+		case int64:
+			return math.Float64bits(float64(v))
+		case uint64:
+			return math.Float64bits(float64(v))
+		}
+	case arrowInt64:
+		if v, ok := v.(int64); ok {
+			return uint64(v)
+		}
+	case arrowUint64:
+		if v, ok := v.(uint64); ok {
+			return v
+		}
+	case arrowTimestamp:
+		if v, ok := v.(time.Time); ok {
+			return uint64(v.UnixNano())
+		}
+	}
+	return nil
+}
+
+// fbObject is an object in a flatbuffer that offsets refer to.
+type fbObject interface {
+	// writeTo writes the object and returns the position offsets refer to.
+	writeTo(b *fbBuilder) int
+}
+
+// fbBuilder builds a flatbuffer front to back. Objects are written after
+// the tables that refer to them, whose offsets are patched once the objects
+// are written, so all offsets point forward as required.
+type fbBuilder struct {
+	buf []byte
+}
+
+// fbFinish returns a flatbuffer with the root table root.
+func fbFinish(root fbObject) []byte {
+	b := &fbBuilder{buf: make([]byte, 4)}
+	pos := root.writeTo(b)
+	binary.LittleEndian.PutUint32(b.buf, uint32(pos))
+	return b.buf
+}
+
+func (b *fbBuilder) align(n int) {
+	for len(b.buf)%n != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *fbBuilder) grow(n int) int {
+	pos := len(b.buf)
+	b.buf = append(b.buf, make([]byte, n)...)
+	return pos
+}
+
+// patch sets the offset at pos to refer to obj, writing obj.
+func (b *fbBuilder) patch(pos int, obj fbObject) {
+	// Write obj first, as it may reallocate the buffer.
+	p := obj.writeTo(b)
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(p-pos))
+}
+
+// fbTable is a flatbuffer table, indexed by field ID.
+type fbTable []fbField
+
+// fbField is a table field. Fields with neither a size nor a ref are absent.
+type fbField struct {
+	size  int    // size of a scalar value: 1, 2, 4 or 8
+	value uint64 // scalar value
+	ref   fbObject
+}
+
+func (t fbTable) writeTo(b *fbBuilder) int {
+	// Lay out the fields after the vtable offset, largest first so that
+	// they are aligned.
+	type slot struct {
+		id, size, off int
+	}
+	var slots []slot
+	var has8 bool
+	for id, f := range t {
+		size := f.size
+		if f.ref != nil {
+			size = 4
+		}
+		if size == 0 {
+			continue
+		}
+		has8 = has8 || size == 8
+		slots = append(slots, slot{id: id, size: size})
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].size > slots[j].size })
+
+	off := 4
+	offs := make([]int, len(t))
+	for i := range slots {
+		if slots[i].size < 8 {
+			off = (off + slots[i].size - 1) / slots[i].size * slots[i].size
+		}
+		slots[i].off = off
+		offs[slots[i].id] = off
+		off += slots[i].size
+	}
+
+	// Write the vtable.
+	b.align(2)
+	vt := b.grow(4 + 2*len(t))
+	binary.LittleEndian.PutUint16(b.buf[vt:], uint16(4+2*len(t)))
+	binary.LittleEndian.PutUint16(b.buf[vt+2:], uint16(off))
+	for id, o := range offs {
+		binary.LittleEndian.PutUint16(b.buf[vt+4+2*id:], uint16(o))
+	}
+
+	// Write the table, aligning 8 byte fields, which start 4 bytes in.
+	if has8 {
+		for (len(b.buf)+4)%8 != 0 {
+			b.buf = append(b.buf, 0)
+		}
+	} else {
+		b.align(4)
+	}
+	pos := b.grow(off)
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(pos-vt))
+	for _, s := range slots {
+		f := t[s.id]
+		if f.ref != nil {
+			continue
+		}
+		switch s.size {
+		case 1:
+			b.buf[pos+s.off] = byte(f.value)
+		case 2:
+			binary.LittleEndian.PutUint16(b.buf[pos+s.off:], uint16(f.value))
+		case 4:
+			binary.LittleEndian.PutUint32(b.buf[pos+s.off:], uint32(f.value))
+		case 8:
+			binary.LittleEndian.PutUint64(b.buf[pos+s.off:], f.value)
+		}
+	}
+
+	for _, s := range slots {
+		if ref := t[s.id].ref; ref != nil {
+			b.patch(pos+s.off, ref)
+		}
+	}
+	return pos
+}
+
+// fbString is a flatbuffer string.
+type fbString string
+
+func (s fbString) writeTo(b *fbBuilder) int {
+	b.align(4)
+	pos := b.grow(4)
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(len(s)))
+	b.buf = append(b.buf, s...)
+	b.buf = append(b.buf, 0)
+	return pos
+}
+
+// fbVector is a flatbuffer vector of tables.
+type fbVector []fbTable
+
+func (v fbVector) writeTo(b *fbBuilder) int {
+	b.align(4)
+	pos := b.grow(4 + 4*len(v))
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(len(v)))
+	for i, t := range v {
+		b.patch(pos+4+4*i, t)
+	}
+	return pos
+}
+
+// fbStructVector is a flatbuffer vector of structs of two longs, such as
+// the FieldNode and Buffer structs of a record batch.
+type fbStructVector [][2]int64
+
+func (v fbStructVector) writeTo(b *fbBuilder) int {
+	// The elements are 8 byte aligned.
+	for (len(b.buf)+4)%8 != 0 {
+		b.buf = append(b.buf, 0)
+	}
+	pos := b.grow(4 + 16*len(v))
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(len(v)))
+	for i, s := range v {
+		binary.LittleEndian.PutUint64(b.buf[pos+4+16*i:], uint64(s[0]))
+		binary.LittleEndian.PutUint64(b.buf[pos+12+16*i:], uint64(s[1]))
+	}
+	return pos
+}
@@ -0,0 +1,293 @@
+package httpd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+)
+
+func TestArrowFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	f := &arrowFormatter{Writer: &buf}
+	f.WriteResponse(Response{
+		Results: []*query.Result{
+			{
+				StatementID: 0,
+				Series: []*models.Row{
+					{
+						Name:    "cpu",
+						Tags:    map[string]string{"host": "server01", "region": "uswest"},
+						Columns: []string{"time", "value", "ok"},
+						Values: [][]interface{}{
+							{time.Unix(0, 10), float64(2.5), true},
+							{time.Unix(0, 20), int64(5), nil},
+							{time.Unix(0, 30), nil, false},
+						},
+					},
+					{
+						Name:    "mem",
+						Columns: []string{"time", "free"},
+						Values: [][]interface{}{
+							{time.Unix(0, 40), "a lot"},
+						},
+					},
+				},
+			},
+			{StatementID: 1, Err: errors.New("bad statement")},
+		},
+	})
+
+	streams := readArrowStreams(t, buf.Bytes())
+	if len(streams) != 2 {
+		t.Fatalf("unexpected stream count: %d", len(streams))
+	}
+
+	exp := arrowTestStream{
+		fields: []arrowTestField{
+			{"name", 5}, {"tags", 5}, {"time", 10}, {"value", 3}, {"ok", 6}, {"free", 5},
+		},
+		length: 4,
+		columns: [][]interface{}{
+			{"cpu", "cpu", "cpu", "mem"},
+			{"host=server01,region=uswest", "host=server01,region=uswest", "host=server01,region=uswest", ""},
+			{int64(10), int64(20), int64(30), int64(40)},
+			{2.5, 5.0, nil, nil},
+			{true, nil, false, nil},
+			{nil, nil, nil, "a lot"},
+		},
+	}
+	if !reflect.DeepEqual(streams[0], exp) {
+		t.Fatalf("unexpected stream:\ngot: %#v\nexp: %#v", streams[0], exp)
+	}
+
+	exp = arrowTestStream{
+		fields:  []arrowTestField{{"error", 5}},
+		length:  1,
+		columns: [][]interface{}{{"bad statement"}},
+	}
+	if !reflect.DeepEqual(streams[1], exp) {
+		t.Fatalf("unexpected error stream:\ngot: %#v\nexp: %#v", streams[1], exp)
+	}
+}
+
+type arrowTestField struct {
+	name   string
+	typeID byte
+}
+
+type arrowTestStream struct {
+	fields  []arrowTestField
+	length  int64
+	columns [][]interface{}
+}
+
+// readArrowStreams decodes the streams written by arrowFormatter, checking
+// the framing and alignment required by the Arrow IPC format.
+func readArrowStreams(t *testing.T, b []byte) []arrowTestStream {
+	var streams []arrowTestStream
+	var cur *arrowTestStream
+	for len(b) > 0 {
+		if len(b) < 8 || binary.LittleEndian.Uint32(b) != 0xffffffff {
+			t.Fatalf("missing continuation marker")
+		}
+		size := int(binary.LittleEndian.Uint32(b[4:]))
+		b = b[8:]
+		if size == 0 {
+			if cur == nil {
+				t.Fatal("unexpected end of stream")
+			}
+			streams = append(streams, *cur)
+			cur = nil
+			continue
+		} else if size%8 != 0 {
+			t.Fatalf("metadata size %d not a multiple of 8", size)
+		}
+
+		msg := fbTestRoot(b[:size])
+		b = b[size:]
+		if v := msg.uint16(0); v != arrowMetadataV5 {
+			t.Fatalf("unexpected metadata version: %d", v)
+		}
+		bodyLength := int(msg.uint64(3))
+		if bodyLength%8 != 0 {
+			t.Fatalf("body length %d not a multiple of 8", bodyLength)
+		}
+		body := b[:bodyLength]
+		b = b[bodyLength:]
+
+		header := msg.table(2)
+		switch msg.uint8(1) {
+		case arrowHeaderSchema:
+			if cur != nil {
+				t.Fatal("unexpected schema")
+			}
+			cur = &arrowTestStream{}
+			fields := header.vector(1)
+			for i := 0; i < fields.len(); i++ {
+				field := fields.table(i)
+				if field.vector(5).len() != 0 {
+					t.Fatal("expected no children")
+				}
+				cur.fields = append(cur.fields, arrowTestField{name: field.string(0), typeID: field.uint8(2)})
+			}
+
+		case arrowHeaderRecordBatch:
+			if cur == nil {
+				t.Fatal("record batch before schema")
+			}
+			cur.length = int64(header.uint64(0))
+			nodes, buffers := header.vector(1), header.vector(2)
+			if nodes.len() != len(cur.fields) {
+				t.Fatalf("unexpected node count: %d", nodes.len())
+			}
+
+			bufs := make([][]byte, buffers.len())
+			for i := range bufs {
+				off, n := buffers.long(i, 0), buffers.long(i, 1)
+				if off%8 != 0 {
+					t.Fatalf("buffer offset %d not aligned", off)
+				}
+				bufs[i] = body[off : off+n]
+			}
+
+			for i, field := range cur.fields {
+				if n := nodes.long(i, 0); n != int(cur.length) {
+					t.Fatalf("unexpected node length: %d", n)
+				}
+				validity := bufs[0]
+				col := make([]interface{}, cur.length)
+				var nulls int
+				for j := range col {
+					if validity[j/8]&(1<<uint(j%8)) == 0 {
+						nulls++
+						continue
+					}
+					switch field.typeID {
+					case 5:
+						offsets := bufs[1]
+						start, end := binary.LittleEndian.Uint32(offsets[4*j:]), binary.LittleEndian.Uint32(offsets[4*j+4:])
+						col[j] = string(bufs[2][start:end])
+					case 6:
+						col[j] = bufs[1][j/8]&(1<<uint(j%8)) != 0
+					case 3:
+						col[j] = math.Float64frombits(binary.LittleEndian.Uint64(bufs[1][8*j:]))
+					default:
+						col[j] = int64(binary.LittleEndian.Uint64(bufs[1][8*j:]))
+					}
+				}
+				if n := nodes.long(i, 1); n != nulls {
+					t.Fatalf("unexpected null count: got %d, exp %d", n, nulls)
+				}
+				if field.typeID == 5 {
+					bufs = bufs[3:]
+				} else {
+					bufs = bufs[2:]
+				}
+				cur.columns = append(cur.columns, col)
+			}
+		default:
+			t.Fatalf("unexpected header type: %d", msg.uint8(1))
+		}
+	}
+	if cur != nil {
+		t.Fatal("missing end of stream")
+	}
+	return streams
+}
+
+// fbTestTable reads the fields of a flatbuffer table.
+type fbTestTable struct {
+	buf []byte
+	pos int
+}
+
+func fbTestRoot(buf []byte) fbTestTable {
+	return fbTestTable{buf: buf, pos: int(binary.LittleEndian.Uint32(buf))}
+}
+
+// field returns the position of field id, or 0 if absent.
+func (t fbTestTable) field(id int) int {
+	vt := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	if 4+2*id >= int(binary.LittleEndian.Uint16(t.buf[vt:])) {
+		return 0
+	}
+	off := int(binary.LittleEndian.Uint16(t.buf[vt+4+2*id:]))
+	if off == 0 {
+		return 0
+	}
+	return t.pos + off
+}
+
+func (t fbTestTable) uint8(id int) byte {
+	if p := t.field(id); p != 0 {
+		return t.buf[p]
+	}
+	return 0
+}
+
+func (t fbTestTable) uint16(id int) uint16 {
+	if p := t.field(id); p != 0 {
+		return binary.LittleEndian.Uint16(t.buf[p:])
+	}
+	return 0
+}
+
+func (t fbTestTable) uint64(id int) uint64 {
+	if p := t.field(id); p != 0 {
+		if p%8 != 0 {
+			panic("unaligned long")
+		}
+		return binary.LittleEndian.Uint64(t.buf[p:])
+	}
+	return 0
+}
+
+func (t fbTestTable) deref(id int) int {
+	p := t.field(id)
+	return p + int(binary.LittleEndian.Uint32(t.buf[p:]))
+}
+
+func (t fbTestTable) table(id int) fbTestTable {
+	return fbTestTable{buf: t.buf, pos: t.deref(id)}
+}
+
+func (t fbTestTable) string(id int) string {
+	p := t.deref(id)
+	n := int(binary.LittleEndian.Uint32(t.buf[p:]))
+	return string(t.buf[p+4 : p+4+n])
+}
+
+func (t fbTestTable) vector(id int) fbTestVector {
+	return fbTestVector{buf: t.buf, pos: t.deref(id)}
+}
+
+// fbTestVector reads the elements of a flatbuffer vector.
+type fbTestVector struct {
+	buf []byte
+	pos int
+}
+
+func (v fbTestVector) len() int {
+	return int(binary.LittleEndian.Uint32(v.buf[v.pos:]))
+}
+
+func (v fbTestVector) table(i int) fbTestTable {
+	p := v.pos + 4 + 4*i
+	return fbTestTable{buf: v.buf, pos: p + int(binary.LittleEndian.Uint32(v.buf[p:]))}
+}
+
+// long returns long j of struct i of a vector of structs of two longs.
+func (v fbTestVector) long(i, j int) int {
+	p := v.pos + 4 + 16*i + 8*j
+	if p%8 != 0 {
+		panic("unaligned struct")
+	}
+	return int(binary.LittleEndian.Uint64(v.buf[p:]))
+}
@@ -0,0 +1,220 @@
+package httpd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// responseEncodings are the encodings of the compressed responses, in order
+// of preference.  zstd and snappy use less CPU than gzip.
+var responseEncodings = []string{"zstd", "snappy", "gzip"}
+
+// snappyStreamHeader starts the bodies in the snappy framing format.
+var snappyStreamHeader = []byte("\xff\x06\x00\x00sNaPpY")
+
+// compressWriter is a compressor of a response that can be reused.
+type compressWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var compressWriterPools = map[string]*sync.Pool{
+	"gzip": {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+	"zstd": {New: func() interface{} {
+		zw, _ := zstd.NewWriter(nil)
+		return zw
+	}},
+	"snappy": {New: func() interface{} {
+		return snappy.NewBufferedWriter(nil)
+	}},
+}
+
+func getCompressWriter(encoding string, w io.Writer) compressWriter {
+	cw := compressWriterPools[encoding].Get().(compressWriter)
+	cw.Reset(w)
+	return cw
+}
+
+func putCompressWriter(encoding string, cw compressWriter) {
+	cw.Close()
+	compressWriterPools[encoding].Put(cw)
+}
+
+type lazyCompressResponseWriter struct {
+	io.Writer
+	http.ResponseWriter
+	http.Flusher
+	http.CloseNotifier
+	encoding    string
+	compressor  compressWriter
+	wroteHeader bool
+}
+
+// compressFilter determines the encodings the client accepts for the
+// response, and compresses it with the preferred one: zstd, snappy (in the
+// framing format) or gzip.
+func compressFilter(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			inner.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &lazyCompressResponseWriter{ResponseWriter: w, Writer: w, encoding: encoding}
+
+		if f, ok := w.(http.Flusher); ok {
+			cw.Flusher = f
+		}
+
+		if cn, ok := w.(http.CloseNotifier); ok {
+			cw.CloseNotifier = cn
+		}
+
+		defer cw.Close()
+
+		inner.ServeHTTP(cw, r)
+	})
+}
+
+// acceptedEncoding returns the preferred response encoding of those in the
+// Accept-Encoding header, or an empty string if none is accepted.
+func acceptedEncoding(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	accepted := make(map[string]bool)
+	for _, s := range strings.Split(header, ",") {
+		params := strings.Split(s, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		accepted[name] = true
+		for _, p := range params[1:] {
+			// A quality of zero refuses the encoding.
+			if p = strings.TrimSpace(p); strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil && q == 0 {
+					accepted[name] = false
+				}
+			}
+		}
+	}
+
+	for _, encoding := range responseEncodings {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+func (w *lazyCompressResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+	// Responses already encoded by the handler are sent as is.
+	if code == http.StatusOK && w.Header().Get("Content-Encoding") == "" {
+		w.Header().Set("Content-Encoding", w.encoding)
+		// Add compressor
+		if w.compressor == nil {
+			w.compressor = getCompressWriter(w.encoding, w.Writer)
+			w.Writer = w.compressor
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *lazyCompressResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.Writer.Write(p)
+}
+
+func (w *lazyCompressResponseWriter) Flush() {
+	// Flush writer, if supported
+	if w.compressor != nil {
+		w.compressor.Flush()
+	}
+
+	// Flush the HTTP response
+	if w.Flusher != nil {
+		w.Flusher.Flush()
+	}
+}
+
+func (w *lazyCompressResponseWriter) Close() error {
+	if w.compressor != nil {
+		putCompressWriter(w.encoding, w.compressor)
+	}
+
+	return nil
+}
+
+// decodeBody returns a reader of the body decoded from its Content-Encoding:
+// gzip, zstd, or snappy in either the framing or the block format.  A body
+// with another encoding is read as is.  maxSize, if positive, bounds the
+// size of a snappy block once decoded.
+func decodeBody(encoding string, body io.Reader, maxSize int) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip":
+		gr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return gr, nil
+	case "zstd":
+		zr, err := zstd.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return zstdReader{zr}, nil
+	case "snappy":
+		br := bufio.NewReader(body)
+		if b, err := br.Peek(len(snappyStreamHeader)); err == nil && bytes.Equal(b, snappyStreamHeader) {
+			return ioutil.NopCloser(snappy.NewReader(br)), nil
+		}
+
+		// The block format, as sent by Prometheus, is decoded at once.
+		b, err := ioutil.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := snappy.DecodedLen(b); err != nil {
+			return nil, err
+		} else if maxSize > 0 && n > maxSize {
+			return nil, errTruncated
+		}
+		b, err = snappy.Decode(nil, b)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(b)), nil
+	}
+	return ioutil.NopCloser(body), nil
+}
+
+// zstdReader releases the resources of a zstd decoder when closed.
+type zstdReader struct {
+	*zstd.Decoder
+}
+
+func (r zstdReader) Close() error {
+	r.Decoder.Close()
+	return nil
+}
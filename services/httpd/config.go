@@ -1,6 +1,16 @@
 package httpd
 
-import "github.com/influxdata/influxdb/monitor/diagnostics"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/prometheus"
+	"github.com/influxdata/influxdb/toml"
+)
 
 const (
 	// DefaultBindAddress is the default address to bind to.
@@ -12,8 +22,65 @@ const (
 	// DefaultBindSocket is the default unix socket to bind to.
 	DefaultBindSocket = "/var/run/influxdb.sock"
 
+	// DefaultUnixSocketPermissions is the default permissions of the unix socket.
+	DefaultUnixSocketPermissions = 0777
+
 	// DefaultMaxBodySize is the default maximum size of a client request body, in bytes. Specify 0 for no limit.
 	DefaultMaxBodySize = 25e6
+
+	// DefaultQueryCacheTTL is the default time the results of a query are cached for.
+	DefaultQueryCacheTTL = 10 * time.Second
+
+	// DefaultJWKSRefreshInterval is the default interval the keys of a JWKS URL are fetched at.
+	DefaultJWKSRefreshInterval = time.Hour
+
+	// DefaultHealthMinDiskFree is the default free space of the data and WAL
+	// directories below which the health check of the disk fails.
+	DefaultHealthMinDiskFree = 1 << 30
+
+	// DefaultJWTUsernameClaim is the default claim of a JWT holding the username.
+	DefaultJWTUsernameClaim = "username"
+
+	// DefaultReadHeaderTimeout is the default longest time to read the
+	// headers of a request.
+	DefaultReadHeaderTimeout = 10 * time.Second
+
+	// DefaultIdleTimeout is the default longest time a keep-alive connection
+	// waits for the next request.
+	DefaultIdleTimeout = 3 * time.Minute
+
+	// DefaultHTTPSCertificateReloadInterval is the default interval the
+	// HTTPS certificate and key files are checked for changes at.
+	DefaultHTTPSCertificateReloadInterval = time.Minute
+
+	// DefaultACMEDirectoryURL is the default directory of the ACME server
+	// certificates are obtained from.  It is Let's Encrypt's ACMEv1
+	// directory, as that is the protocol the ACME client speaks.
+	DefaultACMEDirectoryURL = "https://acme-v01.api.letsencrypt.org/directory"
+
+	// DefaultACMEBindAddress is the default address the HTTP-01 challenges
+	// of the ACME server are answered on.
+	DefaultACMEBindAddress = ":80"
+)
+
+var (
+	// DefaultCORSAllowedOrigins are the default origins allowed to make
+	// cross-origin requests.  "*" allows any origin.
+	DefaultCORSAllowedOrigins = []string{"*"}
+
+	// DefaultCORSAllowedMethods are the default methods of cross-origin requests.
+	DefaultCORSAllowedMethods = []string{"DELETE", "GET", "OPTIONS", "POST", "PUT"}
+
+	// DefaultCORSAllowedHeaders are the default headers of cross-origin requests.
+	DefaultCORSAllowedHeaders = []string{
+		"Accept",
+		"Accept-Encoding",
+		"Authorization",
+		"Content-Length",
+		"Content-Type",
+		"X-CSRF-Token",
+		"X-HTTP-Method-Override",
+	}
 )
 
 // Config represents a configuration for a HTTP service.
@@ -34,6 +101,151 @@ type Config struct {
 	UnixSocketEnabled  bool   `toml:"unix-socket-enabled"`
 	BindSocket         string `toml:"bind-socket"`
 	MaxBodySize        int    `toml:"max-body-size"`
+
+	// AccessLogFormat is the format of the access log, either "common" for
+	// the Common Log Format or "json" for one JSON object per request.
+	// AccessLogDisabledEndpoints are the names of the endpoints, such as
+	// "ping" or "write", whose requests are not logged.
+	AccessLogFormat            string   `toml:"access-log-format"`
+	AccessLogDisabledEndpoints []string `toml:"access-log-disabled-endpoints"`
+
+	// UnixSocketPermissions and UnixSocketGroup are the permissions and the
+	// group, by name or ID, of the unix socket.  Only the users with write
+	// permission on the socket can connect to it.  The socket keeps the
+	// group of the process if UnixSocketGroup is empty.
+	UnixSocketPermissions toml.FileMode `toml:"unix-socket-permissions"`
+	UnixSocketGroup       string        `toml:"unix-socket-group"`
+
+	// HTTPSCertificateReloadInterval is the interval the certificate and
+	// key files are checked for changes at.  Changed files are reloaded
+	// without restarting the listener.  Zero disables the reloading.
+	HTTPSCertificateReloadInterval toml.Duration `toml:"https-certificate-reload-interval"`
+
+	// HTTP2Enabled enables HTTP/2 on the HTTPS listener.  Clients that don't
+	// negotiate HTTP/2 keep using HTTP/1.1.
+	HTTP2Enabled bool `toml:"http2-enabled"`
+
+	// ReadHeaderTimeout, WriteTimeout and IdleTimeout are the longest time
+	// to read the headers of a request, to write a response and to wait for
+	// the next request on a keep-alive connection.  Zero disables a timeout.
+	// The write timeout applies to the whole response, including chunked
+	// query responses.
+	ReadHeaderTimeout toml.Duration `toml:"read-header-timeout"`
+	WriteTimeout      toml.Duration `toml:"write-timeout"`
+	IdleTimeout       toml.Duration `toml:"idle-timeout"`
+
+	// ClockSkewThreshold is the difference between the timestamps a client
+	// writes and server time beyond which a warning is logged. Zero disables
+	// the warning; skew is always tracked in the clockSkew statistics.
+	ClockSkewThreshold toml.Duration `toml:"clock-skew-threshold"`
+
+	// PrometheusMeasurement is the measurement Prometheus remote writes are
+	// written to. If empty, each metric is written to a measurement named
+	// after it, with PrometheusMeasurementPrefix prepended.
+	PrometheusMeasurement       string `toml:"prometheus-measurement"`
+	PrometheusMeasurementPrefix string `toml:"prometheus-measurement-prefix"`
+
+	// PrometheusField is the field Prometheus sample values are written to.
+	PrometheusField string `toml:"prometheus-field"`
+
+	// PrometheusDropLabels are Prometheus labels that are not written as tags.
+	PrometheusDropLabels []string `toml:"prometheus-drop-labels"`
+
+	// QueryCacheSize is the number of query results that are cached. Zero
+	// disables the cache.
+	QueryCacheSize int `toml:"query-cache-size"`
+
+	// QueryCacheTTL is the longest time the results of a query are cached.
+	QueryCacheTTL toml.Duration `toml:"query-cache-ttl"`
+
+	// JWKSURL is the JSON Web Key Set the keys of RS256 signed JWTs are
+	// fetched from, every JWKSRefreshInterval. HS256 signed JWTs are
+	// verified with SharedSecret.
+	JWKSURL             string        `toml:"jwks-url"`
+	JWKSRefreshInterval toml.Duration `toml:"jwks-refresh-interval"`
+
+	// JWTUsernameClaim is the claim of a JWT holding the username.
+	JWTUsernameClaim string `toml:"jwt-username-claim"`
+
+	// JWTPrivilegesClaim is the claim of a JWT holding the privileges of its
+	// user, as an object of database names to READ, WRITE or ALL. JWTs with
+	// the claim authenticate users that don't need to exist in the meta
+	// store, with those privileges and, if the JWTAdminClaim claim is true,
+	// as an admin.
+	JWTPrivilegesClaim string `toml:"jwt-privileges-claim"`
+	JWTAdminClaim      string `toml:"jwt-admin-claim"`
+
+	// The rate limits of the requests of each authenticated user and to
+	// each database: queries per second, concurrent queries and points
+	// written per second.  Zero disables a limit.  Requests over a limit
+	// are rejected with 429 Too Many Requests.
+	UserQueryRateLimit            int `toml:"user-query-rate-limit"`
+	UserQueryConcurrencyLimit     int `toml:"user-query-concurrency-limit"`
+	UserWriteRateLimit            int `toml:"user-write-rate-limit"`
+	DatabaseQueryRateLimit        int `toml:"database-query-rate-limit"`
+	DatabaseQueryConcurrencyLimit int `toml:"database-query-concurrency-limit"`
+	DatabaseWriteRateLimit        int `toml:"database-write-rate-limit"`
+
+	// HealthMinDiskFree is the free space of the data and WAL directories
+	// below which the /health and /ready endpoints report a failure.  Zero
+	// disables the check.
+	HealthMinDiskFree toml.Size `toml:"health-min-disk-free"`
+
+	CORS CORSConfig `toml:"cors"`
+	ACME ACMEConfig `toml:"acme"`
+}
+
+// CORSConfig controls the cross-origin requests browsers are allowed to make
+// to the API.  Requests from other origins are served without the CORS
+// headers, so browsers don't expose their responses.
+type CORSConfig struct {
+	// AllowedOrigins are the origins, such as "https://grafana.example.com",
+	// allowed to make cross-origin requests.  "*" allows any origin, and an
+	// empty list disables cross-origin requests.
+	AllowedOrigins []string `toml:"allowed-origins"`
+
+	// AllowedMethods and AllowedHeaders are the methods and the headers of
+	// the cross-origin requests.
+	AllowedMethods []string `toml:"allowed-methods"`
+	AllowedHeaders []string `toml:"allowed-headers"`
+
+	// MaxAge is how long browsers may cache the result of a preflight
+	// request.  Zero leaves it to the browser.
+	MaxAge toml.Duration `toml:"max-age"`
+}
+
+// ACMEConfig controls obtaining and renewing the HTTPS certificate from an
+// ACME server, such as Let's Encrypt, instead of reading it from files.
+type ACMEConfig struct {
+	Enabled bool `toml:"enabled"`
+
+	// Hosts are the host names certificates are obtained for.  Requests for
+	// other names are refused.
+	Hosts []string `toml:"hosts"`
+
+	// Email is the contact address of the ACME account.
+	Email string `toml:"email"`
+
+	// DirectoryURL is the directory of the ACME server.
+	DirectoryURL string `toml:"directory-url"`
+
+	// CacheDir is the directory the account key and the certificates are
+	// stored in, so they survive restarts.
+	CacheDir string `toml:"cache-dir"`
+
+	// BindAddress is the address the HTTP-01 challenges are answered on.
+	// Other requests to it are redirected to HTTPS.
+	BindAddress string `toml:"bind-address"`
+}
+
+// allowsOrigin returns true if the origin may make cross-origin requests.
+func (c CORSConfig) allowsOrigin(origin string) bool {
+	for _, o := range c.AllowedOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
 }
 
 // NewConfig returns a new Config with default settings.
@@ -50,6 +262,141 @@ func NewConfig() Config {
 		UnixSocketEnabled: false,
 		BindSocket:        DefaultBindSocket,
 		MaxBodySize:       DefaultMaxBodySize,
+		AccessLogFormat:   AccessLogFormatCommon,
+
+		UnixSocketPermissions: DefaultUnixSocketPermissions,
+
+		HTTPSCertificateReloadInterval: toml.Duration(DefaultHTTPSCertificateReloadInterval),
+
+		HTTP2Enabled:      true,
+		ReadHeaderTimeout: toml.Duration(DefaultReadHeaderTimeout),
+		IdleTimeout:       toml.Duration(DefaultIdleTimeout),
+
+		PrometheusMeasurement: prometheus.DefaultSchema.Measurement,
+		PrometheusField:       prometheus.DefaultSchema.Field,
+
+		QueryCacheTTL: toml.Duration(DefaultQueryCacheTTL),
+
+		JWKSRefreshInterval: toml.Duration(DefaultJWKSRefreshInterval),
+		JWTUsernameClaim:    DefaultJWTUsernameClaim,
+
+		HealthMinDiskFree: DefaultHealthMinDiskFree,
+
+		CORS: CORSConfig{
+			AllowedOrigins: DefaultCORSAllowedOrigins,
+			AllowedMethods: DefaultCORSAllowedMethods,
+			AllowedHeaders: DefaultCORSAllowedHeaders,
+		},
+
+		ACME: ACMEConfig{
+			DirectoryURL: DefaultACMEDirectoryURL,
+			BindAddress:  DefaultACMEBindAddress,
+		},
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if c.UnixSocketEnabled && c.BindSocket == "" {
+		return errors.New("bind-socket must be set when the unix socket is enabled")
+	}
+
+	switch c.AccessLogFormat {
+	case "", AccessLogFormatCommon, AccessLogFormatJSON:
+	default:
+		return fmt.Errorf("invalid access-log-format %q: must be %q or %q", c.AccessLogFormat, AccessLogFormatCommon, AccessLogFormatJSON)
+	}
+
+	for _, limit := range []struct {
+		name  string
+		value int
+	}{
+		{"user-query-rate-limit", c.UserQueryRateLimit},
+		{"user-query-concurrency-limit", c.UserQueryConcurrencyLimit},
+		{"user-write-rate-limit", c.UserWriteRateLimit},
+		{"database-query-rate-limit", c.DatabaseQueryRateLimit},
+		{"database-query-concurrency-limit", c.DatabaseQueryConcurrencyLimit},
+		{"database-write-rate-limit", c.DatabaseWriteRateLimit},
+	} {
+		if limit.value < 0 {
+			return fmt.Errorf("%s must be non-negative", limit.name)
+		}
+	}
+
+	for _, timeout := range []struct {
+		name  string
+		value toml.Duration
+	}{
+		{"read-header-timeout", c.ReadHeaderTimeout},
+		{"write-timeout", c.WriteTimeout},
+		{"idle-timeout", c.IdleTimeout},
+		{"cors.max-age", c.CORS.MaxAge},
+		{"https-certificate-reload-interval", c.HTTPSCertificateReloadInterval},
+	} {
+		if timeout.value < 0 {
+			return fmt.Errorf("%s must be non-negative", timeout.name)
+		}
+	}
+
+	if c.ACME.Enabled {
+		if !c.HTTPSEnabled {
+			return errors.New("https-enabled must be true when acme is enabled")
+		} else if len(c.ACME.Hosts) == 0 {
+			return errors.New("acme.hosts must be set when acme is enabled")
+		} else if c.ACME.CacheDir == "" {
+			return errors.New("acme.cache-dir must be set when acme is enabled")
+		} else if c.ACME.BindAddress == "" {
+			return errors.New("acme.bind-address must be set when acme is enabled")
+		}
+	}
+
+	if c.MaxConnectionLimit < 0 {
+		return errors.New("max-connection-limit must be non-negative")
+	}
+
+	if c.HealthMinDiskFree < 0 {
+		return errors.New("health-min-disk-free must be non-negative")
+	}
+
+	if c.QueryCacheSize < 0 {
+		return errors.New("query-cache-size must be non-negative")
+	} else if c.QueryCacheSize > 0 && c.QueryCacheTTL <= 0 {
+		return errors.New("query-cache-ttl must be positive when the query cache is enabled")
+	}
+
+	if c.JWKSURL != "" {
+		if u, err := url.Parse(c.JWKSURL); err != nil {
+			return fmt.Errorf("invalid jwks-url %q: %s", c.JWKSURL, err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid jwks-url %q: scheme must be http or https", c.JWKSURL)
+		} else if c.JWKSRefreshInterval <= 0 {
+			return errors.New("jwks-refresh-interval must be positive when a jwks-url is set")
+		}
+	}
+
+	schema := c.PrometheusSchema()
+	return schema.Validate()
+}
+
+// accessLogDisabled returns true if the requests to the endpoint are not
+// logged.
+func (c Config) accessLogDisabled(endpoint string) bool {
+	for _, e := range c.AccessLogDisabledEndpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// PrometheusSchema returns the rules for mapping Prometheus time series to
+// InfluxDB series.
+func (c Config) PrometheusSchema() prometheus.Schema {
+	return prometheus.Schema{
+		Measurement:       c.PrometheusMeasurement,
+		MeasurementPrefix: c.PrometheusMeasurementPrefix,
+		Field:             c.PrometheusField,
+		DropLabels:        c.PrometheusDropLabels,
 	}
 }
 
@@ -62,10 +409,23 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 	}
 
 	return diagnostics.RowFromMap(map[string]interface{}{
-		"enabled":              true,
-		"bind-address":         c.BindAddress,
-		"https-enabled":        c.HTTPSEnabled,
-		"max-row-limit":        c.MaxRowLimit,
-		"max-connection-limit": c.MaxConnectionLimit,
+		"enabled":                true,
+		"bind-address":           c.BindAddress,
+		"access-log-format":      c.AccessLogFormat,
+		"https-enabled":          c.HTTPSEnabled,
+		"acme-enabled":           c.ACME.Enabled,
+		"http2-enabled":          c.HTTP2Enabled,
+		"max-row-limit":          c.MaxRowLimit,
+		"max-connection-limit":   c.MaxConnectionLimit,
+		"read-header-timeout":    c.ReadHeaderTimeout,
+		"write-timeout":          c.WriteTimeout,
+		"idle-timeout":           c.IdleTimeout,
+		"unix-socket-enabled":    c.UnixSocketEnabled,
+		"bind-socket":            c.BindSocket,
+		"prometheus-measurement": c.PrometheusMeasurement,
+		"prometheus-field":       c.PrometheusField,
+		"query-cache-size":       c.QueryCacheSize,
+		"query-cache-ttl":        c.QueryCacheTTL,
+		"jwks-url":               c.JWKSURL,
 	}), nil
 }
@@ -2,6 +2,7 @@ package httpd_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/influxdata/influxdb/services/httpd"
@@ -20,7 +21,27 @@ https-enabled = true
 https-certificate = "/dev/null"
 unix-socket-enabled = true
 bind-socket = "/var/run/influxdb.sock"
+unix-socket-permissions = "0770"
+unix-socket-group = "influxdb"
+http2-enabled = false
+read-header-timeout = "5s"
+write-timeout = "1m"
+idle-timeout = "2m"
 max-body-size = 100
+clock-skew-threshold = "5m"
+prometheus-measurement = ""
+prometheus-measurement-prefix = "prom_"
+prometheus-field = "value"
+prometheus-drop-labels = ["job", "instance"]
+query-cache-size = 500
+query-cache-ttl = "30s"
+jwks-url = "https://example.com/.well-known/jwks.json"
+jwks-refresh-interval = "10m"
+jwt-privileges-claim = "influxdb_privileges"
+
+[cors]
+allowed-origins = ["https://grafana.example.com"]
+max-age = "10m"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -44,8 +65,149 @@ max-body-size = 100
 		t.Fatalf("unexpected unix socket enabled: %v", c.UnixSocketEnabled)
 	} else if c.BindSocket != "/var/run/influxdb.sock" {
 		t.Fatalf("unexpected bind unix socket: %v", c.BindSocket)
+	} else if c.UnixSocketPermissions != 0770 {
+		t.Fatalf("unexpected unix socket permissions: %o", c.UnixSocketPermissions)
+	} else if c.UnixSocketGroup != "influxdb" {
+		t.Fatalf("unexpected unix socket group: %v", c.UnixSocketGroup)
+	} else if c.HTTP2Enabled {
+		t.Fatalf("unexpected http2-enabled: %v", c.HTTP2Enabled)
+	} else if time.Duration(c.ReadHeaderTimeout) != 5*time.Second {
+		t.Fatalf("unexpected read-header-timeout: %v", c.ReadHeaderTimeout)
+	} else if time.Duration(c.WriteTimeout) != time.Minute {
+		t.Fatalf("unexpected write-timeout: %v", c.WriteTimeout)
+	} else if time.Duration(c.IdleTimeout) != 2*time.Minute {
+		t.Fatalf("unexpected idle-timeout: %v", c.IdleTimeout)
 	} else if c.MaxBodySize != 100 {
 		t.Fatalf("unexpected max-body-size: %v", c.MaxBodySize)
+	} else if time.Duration(c.ClockSkewThreshold) != 5*time.Minute {
+		t.Fatalf("unexpected clock-skew-threshold: %v", c.ClockSkewThreshold)
+	} else if c.PrometheusMeasurement != "" {
+		t.Fatalf("unexpected prometheus-measurement: %v", c.PrometheusMeasurement)
+	} else if c.PrometheusMeasurementPrefix != "prom_" {
+		t.Fatalf("unexpected prometheus-measurement-prefix: %v", c.PrometheusMeasurementPrefix)
+	} else if c.PrometheusField != "value" {
+		t.Fatalf("unexpected prometheus-field: %v", c.PrometheusField)
+	} else if len(c.PrometheusDropLabels) != 2 || c.PrometheusDropLabels[1] != "instance" {
+		t.Fatalf("unexpected prometheus-drop-labels: %v", c.PrometheusDropLabels)
+	} else if c.QueryCacheSize != 500 {
+		t.Fatalf("unexpected query-cache-size: %v", c.QueryCacheSize)
+	} else if time.Duration(c.QueryCacheTTL) != 30*time.Second {
+		t.Fatalf("unexpected query-cache-ttl: %v", c.QueryCacheTTL)
+	} else if c.JWKSURL != "https://example.com/.well-known/jwks.json" {
+		t.Fatalf("unexpected jwks-url: %v", c.JWKSURL)
+	} else if time.Duration(c.JWKSRefreshInterval) != 10*time.Minute {
+		t.Fatalf("unexpected jwks-refresh-interval: %v", c.JWKSRefreshInterval)
+	} else if c.JWTPrivilegesClaim != "influxdb_privileges" {
+		t.Fatalf("unexpected jwt-privileges-claim: %v", c.JWTPrivilegesClaim)
+	} else if len(c.CORS.AllowedOrigins) != 1 || c.CORS.AllowedOrigins[0] != "https://grafana.example.com" {
+		t.Fatalf("unexpected cors allowed-origins: %v", c.CORS.AllowedOrigins)
+	} else if time.Duration(c.CORS.MaxAge) != 10*time.Minute {
+		t.Fatalf("unexpected cors max-age: %v", c.CORS.MaxAge)
+	} else if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfig_Validate_Prometheus(t *testing.T) {
+	c := httpd.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.PrometheusField = ""
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for empty prometheus-field")
+	}
+
+	c = httpd.NewConfig()
+	c.PrometheusMeasurementPrefix = "prom_"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for prefix with a fixed measurement")
+	}
+}
+
+func TestConfig_Validate_AccessLogFormat(t *testing.T) {
+	c := httpd.NewConfig()
+	c.AccessLogFormat = httpd.AccessLogFormatJSON
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.AccessLogFormat = "xml"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for unknown access-log-format")
+	}
+}
+
+func TestConfig_Validate_ACME(t *testing.T) {
+	c := httpd.NewConfig()
+	c.ACME.Enabled = true
+	c.ACME.Hosts = []string{"influxdb.example.com"}
+	c.ACME.CacheDir = "/var/lib/influxdb/acme"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for acme without https")
+	}
+
+	c.HTTPSEnabled = true
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.ACME.Hosts = nil
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for acme without hosts")
+	}
+}
+
+func TestConfig_Validate_QueryCache(t *testing.T) {
+	c := httpd.NewConfig()
+	c.QueryCacheSize = 100
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.QueryCacheSize = -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for negative query-cache-size")
+	}
+
+	c.QueryCacheSize = 100
+	c.QueryCacheTTL = 0
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for zero query-cache-ttl")
+	}
+}
+
+func TestConfig_Validate_Timeouts(t *testing.T) {
+	c := httpd.NewConfig()
+	c.IdleTimeout = -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for negative idle-timeout")
+	}
+
+	c = httpd.NewConfig()
+	c.MaxConnectionLimit = -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for negative max-connection-limit")
+	}
+}
+
+func TestConfig_Validate_JWKS(t *testing.T) {
+	c := httpd.NewConfig()
+	c.JWKSURL = "https://example.com/.well-known/jwks.json"
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.JWKSURL = "ftp://example.com/jwks.json"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for jwks-url scheme")
+	}
+
+	c.JWKSURL = "https://example.com/.well-known/jwks.json"
+	c.JWKSRefreshInterval = 0
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for zero jwks-refresh-interval")
 	}
 }
 
@@ -0,0 +1,321 @@
+package httpd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+)
+
+// nextCursorHeader is the response header that holds the cursor of the next
+// page of a paginated query.
+const nextCursorHeader = "X-Influxdb-Next-Cursor"
+
+// queryCursor is the position a page of query results starts at. It is
+// handed to clients as an opaque token.
+//
+// A page that ends within a statement records the series of the last value
+// on the page, and the time of the value if the values of the series are in
+// time order, or else the number of values of the series returned. The next
+// page resumes after that value, so values written meanwhile before the
+// position don't shift the pages.
+type queryCursor struct {
+	// Query identifies the query and database the cursor belongs to.
+	Query string `json:"q"`
+
+	// Statement is the index of the statement the page starts in.
+	Statement int `json:"s"`
+
+	// InSeries is set if the page starts within the results of the statement,
+	// after a value of the series identified by Name and Tags.
+	InSeries bool   `json:"i,omitempty"`
+	Name     string `json:"n,omitempty"`
+	Tags     string `json:"t,omitempty"`
+
+	// Time is the time of the last value of the series returned by previous
+	// pages, if the values of the series are in time order.
+	Time *int64 `json:"tm,omitempty"`
+
+	// Offset is the number of values of the series returned by previous
+	// pages, if the values of the series are not in time order.
+	Offset int `json:"o,omitempty"`
+}
+
+// queryCursorID returns the identifier of a query run against database.
+func queryCursorID(q *influxql.Query, database string) string {
+	h := fnv.New64a()
+	h.Write([]byte(q.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(database))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// decodeQueryCursor decodes a cursor token.
+func decodeQueryCursor(s string) (*queryCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	var c queryCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Statement < 0 || c.Offset < 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	return &c, nil
+}
+
+// String returns the token of the cursor.
+func (c *queryCursor) String() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// resume returns the statements of q from the cursor onward. A SELECT of a
+// single series that is resumed after a value is bounded by the time of the
+// value, so the values returned by previous pages aren't read again.
+func (c *queryCursor) resume(q *influxql.Query) *influxql.Query {
+	stmts := q.Statements[c.Statement:]
+	if s, ok := stmts[0].(*influxql.SelectStatement); ok && c.InSeries && c.Time != nil && selectsSingleSeries(s) {
+		op := influxql.GT
+		if !s.TimeAscending() {
+			op = influxql.LT
+		}
+		var cond influxql.Expr = &influxql.BinaryExpr{
+			Op:  op,
+			LHS: &influxql.VarRef{Val: "time"},
+			RHS: &influxql.IntegerLiteral{Val: *c.Time},
+		}
+
+		s = s.Clone()
+		if s.Condition != nil {
+			cond = &influxql.BinaryExpr{Op: influxql.AND, LHS: &influxql.ParenExpr{Expr: s.Condition}, RHS: cond}
+		}
+		s.Condition = cond
+		stmts = append([]influxql.Statement{s}, stmts[1:]...)
+	}
+	return &influxql.Query{Statements: stmts}
+}
+
+// selectsSingleSeries returns true if s returns the raw values of a single
+// series in time order, which a time bound doesn't change other than by
+// dropping the values outside of it.
+func selectsSingleSeries(s *influxql.SelectStatement) bool {
+	if !s.IsRawQuery || len(s.Dimensions) > 0 || len(s.Sources) != 1 || s.Target != nil {
+		return false
+	} else if s.Limit > 0 || s.Offset > 0 || s.SLimit > 0 || s.SOffset > 0 {
+		return false
+	} else if !s.SortedByTime() || s.OmitTime {
+		return false
+	}
+	m, ok := s.Sources[0].(*influxql.Measurement)
+	return ok && m.Regex == nil
+}
+
+// pageOrder is the order of the results of a statement.
+type pageOrder struct {
+	// series is 1 or -1 if the series are in ascending or descending order of
+	// their names and tags, or 0 if their order is unknown.
+	series int
+
+	// byTime is set if the values of each series are in time order.
+	byTime bool
+}
+
+// statementPageOrder returns the order of the results of stmt.
+func statementPageOrder(stmt influxql.Statement) pageOrder {
+	s, ok := stmt.(*influxql.SelectStatement)
+	if !ok {
+		return pageOrder{}
+	} else if !s.TimeAscending() {
+		return pageOrder{series: -1, byTime: s.SortedByTime() && !s.OmitTime}
+	}
+	return pageOrder{series: 1, byTime: s.SortedByTime() && !s.OmitTime}
+}
+
+// seriesTags returns the identifier of the tags of row, which orders series
+// the way the query engine does.
+func seriesTags(row *models.Row) string {
+	return query.NewTags(row.Tags).ID()
+}
+
+// valueTime returns the time of the values of a row at a point in time.
+func valueTime(values []interface{}) (int64, bool) {
+	if len(values) == 0 {
+		return 0, false
+	}
+	t, ok := values[0].(time.Time)
+	return t.UnixNano(), ok
+}
+
+// readQueryPage reads the page of results that starts at cursor. The results
+// must be of the statements from the cursor onward, and stmts are the
+// statements of the whole query. A page holds at most size values. It returns
+// the cursor of the next page, or nil once the results are exhausted.
+func readQueryPage(results <-chan *query.Result, stmts []influxql.Statement, cursor *queryCursor, size int) (Response, *queryCursor) {
+	resp := Response{Results: make([]*query.Result, 0)}
+	stmt := -1 // statement being read
+	n := 0     // values on the page
+
+	var order pageOrder
+	var found bool        // whether the series of the cursor was read
+	var skipped int       // values of the series of the cursor skipped by offset
+	var last *queryCursor // position after the last value on the page
+
+	for r := range results {
+		if r == nil {
+			continue
+		}
+
+		// The statements before the cursor were not executed.
+		r.StatementID += cursor.Statement
+		if r.StatementID != stmt {
+			if n >= size {
+				return resp, &queryCursor{Query: cursor.Query, Statement: r.StatementID}
+			}
+			stmt, last = r.StatementID, nil
+			if stmt < len(stmts) {
+				order = statementPageOrder(stmts[stmt])
+			}
+		}
+
+		if r.Err != nil {
+			appendPageResult(&resp, r)
+			return resp, nil
+		}
+
+		series := make([]*models.Row, 0, len(r.Series))
+		for _, row := range r.Series {
+			values := row.Values
+			name, tags := row.Name, seriesTags(row)
+
+			// Skip the values returned by previous pages.
+			if stmt == cursor.Statement && cursor.InSeries {
+				c := cursor.compareSeries(name, tags, order, found)
+				if c < 0 {
+					continue
+				} else if c == 0 {
+					found = true
+					values = cursor.skip(values, order, &skipped)
+				}
+			}
+			if len(values) == 0 {
+				continue
+			}
+
+			if n >= size {
+				appendPageResult(&resp, &query.Result{StatementID: stmt, Series: series, Messages: r.Messages})
+				return resp, last
+			}
+
+			page := values
+			if len(page) > size-n {
+				page = page[:size-n]
+			}
+			n += len(page)
+			last = cursor.advance(last, stmt, name, tags, page, order)
+
+			other := *row
+			other.Values = page
+			other.Partial = row.Partial || len(page) < len(values)
+			series = append(series, &other)
+
+			if len(page) < len(values) {
+				appendPageResult(&resp, &query.Result{StatementID: stmt, Series: series, Messages: r.Messages})
+				return resp, last
+			}
+		}
+		appendPageResult(&resp, &query.Result{StatementID: stmt, Series: series, Messages: r.Messages})
+	}
+	return resp, nil
+}
+
+// compareSeries returns -1, 0 or 1 if the series of name and tags is before,
+// the same as, or after the series of the cursor in the results of a
+// statement in order. If the order of the series is unknown, series are
+// before the series of the cursor until it is found.
+func (c *queryCursor) compareSeries(name, tags string, order pageOrder, found bool) int {
+	if name == c.Name && tags == c.Tags {
+		return 0
+	} else if order.series == 0 {
+		if found {
+			return 1
+		}
+		return -1
+	}
+
+	if name < c.Name || (name == c.Name && tags < c.Tags) {
+		return -order.series
+	}
+	return order.series
+}
+
+// skip returns values of the series of the cursor without the values returned
+// by previous pages. skipped counts the values skipped by offset.
+func (c *queryCursor) skip(values [][]interface{}, order pageOrder, skipped *int) [][]interface{} {
+	if order.byTime && c.Time != nil {
+		for len(values) > 0 {
+			t, ok := valueTime(values[0])
+			if !ok || (order.series >= 0 && t > *c.Time) || (order.series < 0 && t < *c.Time) {
+				break
+			}
+			values = values[1:]
+		}
+		return values
+	}
+
+	k := c.Offset - *skipped
+	if k > len(values) {
+		k = len(values)
+	}
+	*skipped += k
+	return values[k:]
+}
+
+// advance returns the position after page, the values of a series of stmt
+// added to a page following the position last.
+func (c *queryCursor) advance(last *queryCursor, stmt int, name, tags string, page [][]interface{}, order pageOrder) *queryCursor {
+	next := &queryCursor{Query: c.Query, Statement: stmt, InSeries: true, Name: name, Tags: tags}
+	if order.byTime {
+		if t, ok := valueTime(page[len(page)-1]); ok {
+			next.Time = &t
+			return next
+		}
+	}
+
+	// Count the values of the series returned by this and previous pages.
+	if last != nil && last.Name == name && last.Tags == tags {
+		next.Offset = last.Offset
+	} else if stmt == c.Statement && c.InSeries && c.Name == name && c.Tags == tags {
+		next.Offset = c.Offset
+	}
+	next.Offset += len(page)
+	return next
+}
+
+// appendPageResult adds a result to a page. The values of a series that was
+// split into several results are combined into a single row.
+func appendPageResult(resp *Response, r *query.Result) {
+	l := len(resp.Results)
+	if l == 0 || resp.Results[l-1].StatementID != r.StatementID {
+		resp.Results = append(resp.Results, r)
+		return
+	}
+
+	last := resp.Results[l-1]
+	for _, row := range r.Series {
+		if k := len(last.Series); k > 0 && last.Series[k-1].SameSeries(row) {
+			last.Series[k-1].Values = append(last.Series[k-1].Values, row.Values...)
+			last.Series[k-1].Partial = row.Partial
+			continue
+		}
+		last.Series = append(last.Series, row)
+	}
+	last.Messages = append(last.Messages, r.Messages...)
+	last.Err = r.Err
+}
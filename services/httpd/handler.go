@@ -2,7 +2,7 @@ package httpd
 
 import (
 	"bytes"
-	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
 	"expvar"
@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"log"
 	"math"
+	"net"
 	"net/http"
 	"os"
 	"runtime/debug"
@@ -28,6 +29,8 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor"
 	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/pkg/tracing"
+	"github.com/influxdata/influxdb/pkg/tracing/fields"
 	"github.com/influxdata/influxdb/prometheus"
 	"github.com/influxdata/influxdb/prometheus/remote"
 	"github.com/influxdata/influxdb/query"
@@ -59,6 +62,9 @@ const (
 
 	// Authenticate with jwt.
 	BearerAuthentication
+
+	// Authenticate with an API token.
+	TokenAuthentication
 )
 
 // TODO: Check HTTP response codes: 400, 401, 403, 409.
@@ -83,8 +89,16 @@ type Handler struct {
 		Database(name string) *meta.DatabaseInfo
 		Databases() []meta.DatabaseInfo
 		Authenticate(username, password string) (ui meta.User, err error)
+		AuthenticateToken(token string) (meta.User, error)
 		User(username string) (meta.User, error)
 		AdminUserExists() bool
+		SetSchema(database string, schema *meta.SchemaInfo) error
+	}
+
+	// Authenticator authenticates users with a username and password.  The
+	// meta store is used if it isn't set.
+	Authenticator interface {
+		Authenticate(username, password string) (meta.User, error)
 	}
 
 	QueryAuthorizer interface {
@@ -106,12 +120,74 @@ type Handler struct {
 		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error
 	}
 
-	Config    *Config
-	Logger    zap.Logger
-	CLFLogger *log.Logger
-	stats     *Statistics
+	Store interface {
+		ShardsStorage() []tsdb.ShardStorage
+		DatabasesStorage() ([]tsdb.DatabaseStorage, error)
+	}
+
+	// QueryCache, if set, holds the results of recent SELECT queries.
+	QueryCache *QueryCache
+
+	// Graphite reloads and tests the templates of the graphite inputs.
+	Graphite interface {
+		ReloadGraphiteTemplates() error
+		TestGraphiteTemplates(bindAddress string, templates []string, line string) (models.Point, error)
+	}
+
+	// Tracer, if set, traces a sample of the requests.
+	Tracer interface {
+		// StartTrace returns the trace and root span of a request with the
+		// given headers, or nil if the request is not traced.
+		StartTrace(name string, header http.Header) (*tracing.Trace, *tracing.Span)
+
+		// Report is called with the trace once the request is served.
+		Report(t *tracing.Trace)
+	}
+
+	// ConfigReloader reads the configuration file again and applies the
+	// settings that can change while the server runs.
+	ConfigReloader interface {
+		Reload() (applied, restartRequired []string, err error)
+	}
+
+	// Health checks the subsystems of the server for the /health and /ready
+	// endpoints.
+	Health interface {
+		// HealthChecks returns the status of the subsystems storing data
+		// and, if ready is true, whether the server is ready for traffic.
+		HealthChecks(ready bool) []HealthCheck
+	}
+
+	// LogLevels changes the levels of the logs while the server runs.
+	LogLevels interface {
+		Level() string
+		ServiceLevels() map[string]string
+		SetLevel(service, level string) error
+		ResetLevel(service string)
+	}
+
+	// Auditor, if set, records the administrative actions taken over HTTP
+	// that don't execute a statement.
+	Auditor interface {
+		AuditAction(action, user, addr, target string, err error)
+	}
+
+	Config     *Config
+	Logger     zap.Logger
+	CLFLogger  *log.Logger
+	JSONLogger *log.Logger
+	stats      *Statistics
 
 	requestTracker *RequestTracker
+	clockSkew      *clockSkewTracker
+	promSchema     prometheus.Schema
+	jwks           *jwks
+
+	// The rate limiters of the queries and writes, by user and database.
+	userQueries     *rateLimiter
+	userWrites      *rateLimiter
+	databaseQueries *rateLimiter
+	databaseWrites  *rateLimiter
 }
 
 // NewHandler returns a new instance of handler with routes.
@@ -121,8 +197,22 @@ func NewHandler(c Config) *Handler {
 		Config:         &c,
 		Logger:         zap.New(zap.NullEncoder()),
 		CLFLogger:      log.New(os.Stderr, "[httpd] ", 0),
+		JSONLogger:     log.New(os.Stderr, "", 0),
 		stats:          &Statistics{},
 		requestTracker: NewRequestTracker(),
+		clockSkew:      newClockSkewTracker(time.Duration(c.ClockSkewThreshold)),
+		promSchema:     c.PrometheusSchema(),
+
+		userQueries:     newRateLimiter("user", "query", c.UserQueryRateLimit, c.UserQueryConcurrencyLimit),
+		userWrites:      newRateLimiter("user", "write", c.UserWriteRateLimit, 0),
+		databaseQueries: newRateLimiter("database", "query", c.DatabaseQueryRateLimit, c.DatabaseQueryConcurrencyLimit),
+		databaseWrites:  newRateLimiter("database", "write", c.DatabaseWriteRateLimit, 0),
+	}
+	if c.QueryCacheSize > 0 {
+		h.QueryCache = NewQueryCache(c.QueryCacheSize, time.Duration(c.QueryCacheTTL))
+	}
+	if c.JWKSURL != "" {
+		h.jwks = newJWKS(c.JWKSURL, time.Duration(c.JWKSRefreshInterval))
 	}
 
 	h.AddRoutes([]Route{
@@ -138,6 +228,86 @@ func NewHandler(c Config) *Handler {
 			"query", // Query serving route.
 			"POST", "/query", true, true, h.serveQuery,
 		},
+		Route{
+			"queries", // Running queries.
+			"GET", "/queries", true, true, h.serveQueries,
+		},
+		Route{
+			"kill-query", // Kill a running query.
+			"DELETE", "/queries/:id", false, true, h.serveKillQuery,
+		},
+		Route{
+			"storage", // Storage used by databases and shards.
+			"GET", "/storage", true, true, h.serveStorage,
+		},
+		Route{
+			"admin-databases", // Databases.
+			"GET", "/api/admin/databases", true, true, h.serveAdminDatabases,
+		},
+		Route{
+			"admin-create-database", // Create a database.
+			"POST", "/api/admin/databases", false, true, h.serveAdminCreateDatabase,
+		},
+		Route{
+			"admin-drop-database", // Drop a database.
+			"DELETE", "/api/admin/databases/:name", false, true, h.serveAdminDropDatabase,
+		},
+		Route{
+			"admin-users", // Users.
+			"GET", "/api/admin/users", true, true, h.serveAdminUsers,
+		},
+		Route{
+			"admin-create-user", // Create a user.
+			"POST", "/api/admin/users", false, true, h.serveAdminCreateUser,
+		},
+		Route{
+			"admin-drop-user", // Drop a user.
+			"DELETE", "/api/admin/users/:name", false, true, h.serveAdminDropUser,
+		},
+		Route{
+			"admin-retention-policies", // Retention policies of a database.
+			"GET", "/api/admin/retention-policies", true, true, h.serveAdminRetentionPolicies,
+		},
+		Route{
+			"admin-create-retention-policy", // Create a retention policy.
+			"POST", "/api/admin/retention-policies", false, true, h.serveAdminCreateRetentionPolicy,
+		},
+		Route{
+			"admin-drop-retention-policy", // Drop a retention policy.
+			"DELETE", "/api/admin/retention-policies/:name", false, true, h.serveAdminDropRetentionPolicy,
+		},
+		Route{
+			"admin-schemas", // Schemas of the databases.
+			"GET", "/api/admin/schemas", true, true, h.serveAdminSchemas,
+		},
+		Route{
+			"admin-set-schema", // Set the schema of a database.
+			"POST", "/api/admin/schemas", false, true, h.serveAdminSetSchema,
+		},
+		Route{
+			"admin-drop-schema", // Remove the schema of a database.
+			"DELETE", "/api/admin/schemas/:name", false, true, h.serveAdminDropSchema,
+		},
+		Route{
+			"admin-graphite-reload", // Reload the graphite templates.
+			"POST", "/api/admin/graphite/reload", false, true, h.serveAdminGraphiteReload,
+		},
+		Route{
+			"admin-graphite-test-template", // Parse a graphite metric with templates.
+			"POST", "/api/admin/graphite/test-template", false, true, h.serveAdminGraphiteTestTemplate,
+		},
+		Route{
+			"admin-log-levels", // Levels of the logs.
+			"GET", "/api/admin/log-levels", true, true, h.serveAdminLogLevels,
+		},
+		Route{
+			"admin-set-log-level", // Change the level of the logs.
+			"POST", "/api/admin/log-levels", false, true, h.serveAdminSetLogLevel,
+		},
+		Route{
+			"admin-config-reload", // Reload the configuration file.
+			"POST", "/api/admin/config/reload", false, true, h.serveAdminConfigReload,
+		},
 		Route{
 			"write-options", // Satisfy CORS checks.
 			"OPTIONS", "/write", false, true, h.serveOptions,
@@ -162,6 +332,22 @@ func NewHandler(c Config) *Handler {
 			"ping-head",
 			"HEAD", "/ping", false, true, h.servePing,
 		},
+		Route{ // Health of the subsystems
+			"health",
+			"GET", "/health", false, false, h.serveHealth,
+		},
+		Route{ // Health of the subsystems
+			"health-head",
+			"HEAD", "/health", false, false, h.serveHealth,
+		},
+		Route{ // Readiness for traffic
+			"ready",
+			"GET", "/ready", false, false, h.serveReady,
+		},
+		Route{ // Readiness for traffic
+			"ready-head",
+			"HEAD", "/ready", false, false, h.serveReady,
+		},
 		Route{ // Ping w/ status
 			"status",
 			"GET", "/status", false, true, h.serveStatus,
@@ -170,6 +356,10 @@ func NewHandler(c Config) *Handler {
 			"status-head",
 			"HEAD", "/status", false, true, h.serveStatus,
 		},
+		Route{ // Prometheus metrics
+			"metrics",
+			"GET", "/metrics", true, false, h.serveMetrics,
+		},
 	}...)
 
 	return h
@@ -199,11 +389,19 @@ type Statistics struct {
 	RecoveredPanics              int64
 	PromWriteRequests            int64
 	PromReadRequests             int64
+	QueryCacheHits               int64
+	QueryCacheMisses             int64
+	QueryRequestsRateLimited     int64
+	WriteRequestsRateLimited     int64
+	Connections                  int64
+	IdleConnections              int64
+	ConnectionsAccepted          int64
+	ConnectionsRejected          int64
 }
 
 // Statistics returns statistics for periodic monitoring.
 func (h *Handler) Statistics(tags map[string]string) []models.Statistic {
-	return []models.Statistic{{
+	statistics := append([]models.Statistic{{
 		Name: "httpd",
 		Tags: tags,
 		Values: map[string]interface{}{
@@ -228,8 +426,20 @@ func (h *Handler) Statistics(tags map[string]string) []models.Statistic {
 			statRecoveredPanics:              atomic.LoadInt64(&h.stats.RecoveredPanics),
 			statPromWriteRequest:             atomic.LoadInt64(&h.stats.PromWriteRequests),
 			statPromReadRequest:              atomic.LoadInt64(&h.stats.PromReadRequests),
+			statQueryCacheHit:                atomic.LoadInt64(&h.stats.QueryCacheHits),
+			statQueryCacheMiss:               atomic.LoadInt64(&h.stats.QueryCacheMisses),
+			statQueryRequestRateLimited:      atomic.LoadInt64(&h.stats.QueryRequestsRateLimited),
+			statWriteRequestRateLimited:      atomic.LoadInt64(&h.stats.WriteRequestsRateLimited),
+			statConnectionsActive:            atomic.LoadInt64(&h.stats.Connections),
+			statConnectionsIdle:              atomic.LoadInt64(&h.stats.IdleConnections),
+			statConnectionsTotal:             atomic.LoadInt64(&h.stats.ConnectionsAccepted),
+			statConnectionsRejected:          atomic.LoadInt64(&h.stats.ConnectionsRejected),
 		},
-	}}
+	}}, h.clockSkew.statistics(tags)...)
+	for _, l := range []*rateLimiter{h.userQueries, h.userWrites, h.databaseQueries, h.databaseWrites} {
+		statistics = append(statistics, l.statistics(tags)...)
+	}
+	return statistics
 }
 
 // AddRoutes sets the provided routes on the handler.
@@ -249,11 +459,12 @@ func (h *Handler) AddRoutes(routes ...Route) {
 
 		handler = h.responseWriter(handler)
 		if r.Gzipped {
-			handler = gzipFilter(handler)
+			handler = compressFilter(handler)
 		}
-		handler = cors(handler)
+		handler = h.cors(handler)
+		handler = h.tracing(handler, r.Name)
 		handler = requestID(handler)
-		if h.Config.LogEnabled && r.LoggingEnabled {
+		if h.Config.LogEnabled && r.LoggingEnabled && !h.Config.accessLogDisabled(r.Name) {
 			handler = h.logging(handler, r.Name)
 		}
 		handler = h.recovery(handler, r.Name) // make sure recovery is always last
@@ -382,6 +593,8 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user meta.U
 		h.httpError(rw, "error parsing query: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	accessLog := accessLogFromContext(r.Context())
+	accessLog.setQuery(db, q)
 
 	// Check authorization.
 	if h.Config.AuthEnabled {
@@ -394,23 +607,56 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user meta.U
 		}
 	}
 
+	release, ok := h.rateLimit(rw, h.userQueries, h.databaseQueries, user, db, 1)
+	if !ok {
+		atomic.AddInt64(&h.stats.QueryRequestsRateLimited, 1)
+		return
+	}
+	defer release()
+
+	// Parse the cursor of a paginated query. Each page holds at most the
+	// chunk size values and the cursor of the next page is returned in the
+	// X-Influxdb-Next-Cursor header.
+	var cursor *queryCursor
+	if v := r.FormValue("cursor"); v != "" {
+		if cursor, err = decodeQueryCursor(v); err != nil {
+			h.httpError(rw, err.Error(), http.StatusBadRequest)
+			return
+		} else if cursor.Query != queryCursorID(q, db) || cursor.Statement >= len(q.Statements) {
+			h.httpError(rw, "cursor does not match the query", http.StatusBadRequest)
+			return
+		}
+	} else if r.FormValue("paginate") == "true" {
+		cursor = &queryCursor{Query: queryCursorID(q, db)}
+	}
+
 	// Parse chunk size. Use default if not provided or unparsable.
-	chunked := r.FormValue("chunked") == "true"
+	// Pages are never streamed in chunks.
+	chunked := r.FormValue("chunked") == "true" && cursor == nil
 	chunkSize := DefaultChunkSize
-	if chunked {
+	if chunked || cursor != nil {
 		if n, err := strconv.ParseInt(r.FormValue("chunk_size"), 10, 64); err == nil && int(n) > 0 {
 			chunkSize = int(n)
 		}
 	}
 
-	// Parse whether this is an async command.
-	async := r.FormValue("async") == "true"
+	// Parse whether this is an async command. Paginated queries return their
+	// results so they are never async.
+	async := r.FormValue("async") == "true" && cursor == nil
 
 	opts := query.ExecutionOptions{
-		Database:  db,
-		ChunkSize: chunkSize,
-		ReadOnly:  r.Method == "GET",
-		NodeID:    nodeID,
+		Database:   db,
+		ChunkSize:  chunkSize,
+		ReadOnly:   r.Method == "GET",
+		NodeID:     nodeID,
+		RemoteAddr: r.RemoteAddr,
+	}
+	if user != nil {
+		opts.User = user.ID()
+	}
+	if !async {
+		// Async queries outlive the request, and so its trace.
+		opts.Span = tracing.SpanFromContext(r.Context())
 	}
 
 	if h.Config.AuthEnabled {
@@ -421,6 +667,52 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user meta.U
 		opts.Authorizer = query.OpenAuthorizer{}
 	}
 
+	// Apply the limits requested by trusted clients.
+	limited, err := parseQueryLimits(r, &opts)
+	if err != nil {
+		h.httpError(rw, err.Error(), http.StatusBadRequest)
+		return
+	} else if limited && h.Config.AuthEnabled && (user == nil || !user.IsAdmin()) {
+		h.httpError(rw, "query limit headers require admin privileges", http.StatusForbidden)
+		return
+	}
+
+	// Any client may lower the priority of its queries, but only admins
+	// may raise it.
+	if v := r.Header.Get(queryPriorityHeader); v != "" {
+		if opts.Priority, err = query.ParsePriority(v); err != nil {
+			h.httpError(rw, err.Error(), http.StatusBadRequest)
+			return
+		} else if opts.Priority != query.PriorityBackground && h.Config.AuthEnabled && (user == nil || !user.IsAdmin()) {
+			h.httpError(rw, "query priorities above background require admin privileges", http.StatusForbidden)
+			return
+		}
+	}
+
+	// Answer repeated queries with the results that were cached for them.
+	// Queries run with limits of their own are always executed.
+	var cacheEntry *queryCacheEntry
+	if h.QueryCache != nil && !chunked && !async && !limited && cursor == nil && queryCacheable(q) {
+		var userID string
+		if user != nil {
+			userID = user.ID()
+		}
+
+		results, entry := h.QueryCache.lookup(q, db, epoch, userID)
+		if entry == nil {
+			atomic.AddInt64(&h.stats.QueryCacheHits, 1)
+			accessLog.addRows(results...)
+			h.writeHeader(rw, http.StatusOK)
+			n, _ := rw.WriteResponse(Response{Results: results})
+			atomic.AddInt64(&h.stats.QueryRequestBytesTransmitted, int64(n))
+			return
+		}
+		atomic.AddInt64(&h.stats.QueryCacheMisses, 1)
+		cacheEntry = entry
+	} else if h.QueryCache != nil && !async && queryModifiesData(q) {
+		defer h.QueryCache.Purge()
+	}
+
 	// Make sure if the client disconnects we signal the query to abort
 	var closing chan struct{}
 	if !async {
@@ -448,6 +740,12 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user meta.U
 		}
 	}
 
+	// Only the statements from the cursor onward are executed.
+	stmts := q.Statements
+	if cursor != nil {
+		q = cursor.resume(q)
+	}
+
 	// Execute query.
 	results := h.QueryExecutor.ExecuteQuery(q, opts, closing)
 
@@ -459,6 +757,25 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user meta.U
 		return
 	}
 
+	// Read a single page of a paginated query. The query is aborted once the
+	// page is full.
+	if cursor != nil {
+		resp, next := readQueryPage(results, stmts, cursor, chunkSize)
+		if epoch != "" {
+			for _, r := range resp.Results {
+				convertToEpoch(r, epoch)
+			}
+		}
+		if next != nil {
+			rw.Header().Set(nextCursorHeader, next.String())
+		}
+		accessLog.addRows(resp.Results...)
+		h.writeHeader(rw, http.StatusOK)
+		n, _ := rw.WriteResponse(resp)
+		atomic.AddInt64(&h.stats.QueryRequestBytesTransmitted, int64(n))
+		return
+	}
+
 	// if we're not chunking, this will be the in memory buffer for all results before sending to client
 	resp := Response{Results: make([]*query.Result, 0)}
 
@@ -485,6 +802,7 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user meta.U
 
 		// Write out result immediately if chunked.
 		if chunked {
+			accessLog.addRows(r)
 			n, _ := rw.WriteResponse(Response{
 				Results: []*query.Result{r},
 			})
@@ -575,8 +893,13 @@ func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request, user meta.U
 		}
 	}
 
+	if cacheEntry != nil {
+		h.QueryCache.store(cacheEntry, resp.Results)
+	}
+
 	// If it's not chunked we buffered everything in memory, so write it out
 	if !chunked {
+		accessLog.addRows(resp.Results...)
 		n, _ := rw.WriteResponse(resp)
 		atomic.AddInt64(&h.stats.QueryRequestBytesTransmitted, int64(n))
 	}
@@ -596,6 +919,10 @@ func (h *Handler) async(q *influxql.Query, results <-chan *query.Result) {
 			h.Logger.Info(fmt.Sprintf("error while running async query: %s: %s", q, r.Err))
 		}
 	}
+
+	if h.QueryCache != nil && queryModifiesData(q) {
+		h.QueryCache.Purge()
+	}
 }
 
 // serveWrite receives incoming series data in line protocol format and writes it to the database.
@@ -625,28 +952,27 @@ func (h *Handler) serveWrite(w http.ResponseWriter, r *http.Request, user meta.U
 			return
 		}
 
-		if err := h.WriteAuthorizer.AuthorizeWrite(user.ID(), database); err != nil {
+		if err := h.authorizeWrite(user, database); err != nil {
 			h.httpError(w, fmt.Sprintf("%q user is not authorized to write to database %q", user.ID(), database), http.StatusForbidden)
 			return
 		}
 	}
 
-	body := r.Body
+	// Decode the body from gzip, zstd or snappy.  The maximum body size
+	// applies to the decoded body.
+	body, err := decodeBody(r.Header.Get("Content-Encoding"), r.Body, h.Config.MaxBodySize)
+	if err == errTruncated {
+		h.httpError(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		h.httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer body.Close()
 	if h.Config.MaxBodySize > 0 {
 		body = truncateReader(body, int64(h.Config.MaxBodySize))
 	}
 
-	// Handle gzip decoding of the body
-	if r.Header.Get("Content-Encoding") == "gzip" {
-		b, err := gzip.NewReader(r.Body)
-		if err != nil {
-			h.httpError(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-		defer b.Close()
-		body = b
-	}
-
 	var bs []byte
 	if r.ContentLength > 0 {
 		if h.Config.MaxBodySize > 0 && r.ContentLength > int64(h.Config.MaxBodySize) {
@@ -654,13 +980,13 @@ func (h *Handler) serveWrite(w http.ResponseWriter, r *http.Request, user meta.U
 			return
 		}
 
-		// This will just be an initial hint for the gzip reader, as the
+		// This will just be an initial hint for the decoded body, as the
 		// bytes.Buffer will grow as needed when ReadFrom is called
 		bs = make([]byte, 0, r.ContentLength)
 	}
 	buf := bytes.NewBuffer(bs)
 
-	_, err := buf.ReadFrom(body)
+	_, err = buf.ReadFrom(body)
 	if err != nil {
 		if err == errTruncated {
 			h.httpError(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
@@ -679,16 +1005,40 @@ func (h *Handler) serveWrite(w http.ResponseWriter, r *http.Request, user meta.U
 		h.Logger.Info(fmt.Sprintf("Write body received by handler: %s", buf.Bytes()))
 	}
 
-	points, parseError := models.ParsePointsWithPrecision(buf.Bytes(), time.Now().UTC(), r.URL.Query().Get("precision"))
-	// Not points parsed correctly so return the error now
-	if parseError != nil && len(points) == 0 {
-		if parseError.Error() == "EOF" {
-			h.writeHeader(w, http.StatusOK)
+	now, precision := time.Now().UTC(), r.URL.Query().Get("precision")
+	var points []models.Point
+	var parseError error
+	if isJSONWrite(r) {
+		// JSON points are validated as a whole so none of them are written
+		// if any of them is invalid.
+		if points, err = parseJSONPoints(buf.Bytes(), now, precision); err != nil {
+			h.httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if len(points) == 0 {
+			h.writeHeader(w, http.StatusNoContent)
+			return
+		}
+	} else {
+		points, parseError = models.ParsePointsWithPrecision(buf.Bytes(), now, precision)
+		// Not points parsed correctly so return the error now
+		if parseError != nil && len(points) == 0 {
+			if parseError.Error() == "EOF" {
+				h.writeHeader(w, http.StatusOK)
+				return
+			}
+			h.httpError(w, parseError.Error(), http.StatusBadRequest)
 			return
 		}
-		h.httpError(w, parseError.Error(), http.StatusBadRequest)
+	}
+	h.recordClockSkew(r, user, database, points, now, precision)
+	accessLogFromContext(r.Context()).setWrite(database, len(points))
+
+	release, ok := h.rateLimit(w, h.userWrites, h.databaseWrites, user, database, len(points))
+	if !ok {
+		atomic.AddInt64(&h.stats.WriteRequestsRateLimited, 1)
 		return
 	}
+	defer release()
 
 	// Determine required consistency level.
 	level := r.URL.Query().Get("consistency")
@@ -703,7 +1053,7 @@ func (h *Handler) serveWrite(w http.ResponseWriter, r *http.Request, user meta.U
 	}
 
 	// Write points.
-	if err := h.PointsWriter.WritePoints(database, r.URL.Query().Get("rp"), consistency, user, points); influxdb.IsClientError(err) {
+	if err := h.writePoints(r, database, r.URL.Query().Get("rp"), consistency, user, points); influxdb.IsClientError(err) {
 		atomic.AddInt64(&h.stats.PointsWrittenFail, int64(len(points)))
 		h.httpError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -711,6 +1061,10 @@ func (h *Handler) serveWrite(w http.ResponseWriter, r *http.Request, user meta.U
 		atomic.AddInt64(&h.stats.PointsWrittenFail, int64(len(points)))
 		h.httpError(w, err.Error(), http.StatusForbidden)
 		return
+	} else if qerr, ok := err.(influxdb.QuotaError); ok {
+		atomic.AddInt64(&h.stats.PointsWrittenFail, int64(len(points)))
+		h.quotaError(w, qerr)
+		return
 	} else if werr, ok := err.(tsdb.PartialWriteError); ok {
 		atomic.AddInt64(&h.stats.PointsWrittenOK, int64(len(points)-werr.Dropped))
 		atomic.AddInt64(&h.stats.PointsWrittenDropped, int64(werr.Dropped))
@@ -733,6 +1087,75 @@ func (h *Handler) serveWrite(w http.ResponseWriter, r *http.Request, user meta.U
 	h.writeHeader(w, http.StatusNoContent)
 }
 
+// authorizeWrite returns an error if user isn't authorized to write to
+// database.  Users authenticated with an API token are also limited to the
+// privileges of the token.
+func (h *Handler) authorizeWrite(user meta.User, database string) error {
+	// Users authenticated by an external identity provider don't exist in
+	// the meta store.
+	if _, ok := user.(*meta.ExternalUser); ok {
+		if !user.AuthorizeDatabase(influxql.WritePrivilege, database) {
+			return &meta.ErrAuthorize{
+				Database: database,
+				Message:  fmt.Sprintf("%s not authorized to write to %s", user.ID(), database),
+			}
+		}
+		return nil
+	}
+
+	if err := h.WriteAuthorizer.AuthorizeWrite(user.ID(), database); err != nil {
+		return err
+	}
+	if _, ok := user.(*meta.TokenUser); ok && !user.AuthorizeDatabase(influxql.WritePrivilege, database) {
+		return &meta.ErrAuthorize{
+			Database: database,
+			Message:  fmt.Sprintf("token of %s not authorized to write to %s", user.ID(), database),
+		}
+	}
+	return nil
+}
+
+// recordClockSkew tracks the skew between the timestamps of a write and the
+// time it was received, logging a warning if it exceeds the threshold.
+func (h *Handler) recordClockSkew(r *http.Request, user meta.User, database string, points []models.Point, now time.Time, precision string) {
+	skew, ok := writeSkew(points, now, precision)
+	if !ok {
+		return
+	}
+
+	client := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(client); err == nil {
+		client = host
+	}
+	if user != nil {
+		client = user.ID() + "@" + client
+	}
+
+	if h.clockSkew.add(database, client, skew, now) {
+		direction := "ahead of"
+		if skew < 0 {
+			direction, skew = "behind", -skew
+		}
+		h.Logger.Warn(fmt.Sprintf("Detected clock skew: client %s writing to %s is %s %s server time (threshold: %s)",
+			client, database, skew, direction, time.Duration(h.Config.ClockSkewThreshold)))
+	}
+}
+
+// contextPointsWriter is implemented by points writers tracing the writes in
+// the span of the context.
+type contextPointsWriter interface {
+	WritePointsContext(ctx context.Context, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error
+}
+
+// writePoints writes the points of the request, in the trace of the request
+// if the points writer supports it.
+func (h *Handler) writePoints(r *http.Request, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+	if pw, ok := h.PointsWriter.(contextPointsWriter); ok {
+		return pw.WritePointsContext(r.Context(), database, retentionPolicy, consistencyLevel, user, points)
+	}
+	return h.PointsWriter.WritePoints(database, retentionPolicy, consistencyLevel, user, points)
+}
+
 // serveOptions returns an empty response to comply with OPTIONS pre-flight requests
 func (h *Handler) serveOptions(w http.ResponseWriter, r *http.Request) {
 	h.writeHeader(w, http.StatusNoContent)
@@ -751,6 +1174,46 @@ func (h *Handler) serveStatus(w http.ResponseWriter, r *http.Request) {
 	h.writeHeader(w, http.StatusNoContent)
 }
 
+// Headers that override the query limits of the server for a request.
+const (
+	queryTimeoutHeader     = "X-Influxdb-Query-Timeout"
+	maxSelectPointHeader   = "X-Influxdb-Max-Select-Point"
+	maxSelectSeriesHeader  = "X-Influxdb-Max-Select-Series"
+	maxSelectBucketsHeader = "X-Influxdb-Max-Select-Buckets"
+	queryPriorityHeader    = "X-Influxdb-Query-Priority"
+)
+
+// parseQueryLimits sets the query limits in opts from the request headers.
+// It returns true if any of the headers are set.
+func parseQueryLimits(r *http.Request, opts *query.ExecutionOptions) (bool, error) {
+	var set bool
+	if v := r.Header.Get(queryTimeoutHeader); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return false, fmt.Errorf("invalid %s header: %q", queryTimeoutHeader, v)
+		}
+		opts.QueryTimeout, set = d, true
+	}
+
+	for _, limit := range []struct {
+		header string
+		n      *int
+	}{
+		{maxSelectPointHeader, &opts.MaxSelectPointN},
+		{maxSelectSeriesHeader, &opts.MaxSelectSeriesN},
+		{maxSelectBucketsHeader, &opts.MaxSelectBucketsN},
+	} {
+		if v := r.Header.Get(limit.header); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return false, fmt.Errorf("invalid %s header: %q", limit.header, v)
+			}
+			*limit.n, set = n, true
+		}
+	}
+	return set, nil
+}
+
 // convertToEpoch converts result timestamps from time.Time to the specified epoch.
 func convertToEpoch(r *query.Result, epoch string) {
 	divisor := int64(1)
@@ -806,7 +1269,7 @@ func (h *Handler) servePromWrite(w http.ResponseWriter, r *http.Request, user me
 			return
 		}
 
-		if err := h.WriteAuthorizer.AuthorizeWrite(user.ID(), database); err != nil {
+		if err := h.authorizeWrite(user, database); err != nil {
 			h.httpError(w, fmt.Sprintf("%q user is not authorized to write to database %q", user.ID(), database), http.StatusForbidden)
 			return
 		}
@@ -862,7 +1325,7 @@ func (h *Handler) servePromWrite(w http.ResponseWriter, r *http.Request, user me
 		return
 	}
 
-	points, err := prometheus.WriteRequestToPoints(&req)
+	points, err := h.promSchema.WriteRequestToPoints(&req)
 	if err != nil {
 		if h.Config.WriteTracing {
 			h.Logger.Info(fmt.Sprintf("Prom write handler: %s", err.Error()))
@@ -873,6 +1336,7 @@ func (h *Handler) servePromWrite(w http.ResponseWriter, r *http.Request, user me
 			return
 		}
 	}
+	h.recordClockSkew(r, user, database, points, time.Now().UTC(), "")
 
 	// Determine required consistency level.
 	level := r.URL.Query().Get("consistency")
@@ -886,7 +1350,7 @@ func (h *Handler) servePromWrite(w http.ResponseWriter, r *http.Request, user me
 	}
 
 	// Write points.
-	if err := h.PointsWriter.WritePoints(database, r.URL.Query().Get("rp"), consistency, user, points); influxdb.IsClientError(err) {
+	if err := h.writePoints(r, database, r.URL.Query().Get("rp"), consistency, user, points); influxdb.IsClientError(err) {
 		atomic.AddInt64(&h.stats.PointsWrittenFail, int64(len(points)))
 		h.httpError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -894,6 +1358,10 @@ func (h *Handler) servePromWrite(w http.ResponseWriter, r *http.Request, user me
 		atomic.AddInt64(&h.stats.PointsWrittenFail, int64(len(points)))
 		h.httpError(w, err.Error(), http.StatusForbidden)
 		return
+	} else if qerr, ok := err.(influxdb.QuotaError); ok {
+		atomic.AddInt64(&h.stats.PointsWrittenFail, int64(len(points)))
+		h.quotaError(w, qerr)
+		return
 	} else if werr, ok := err.(tsdb.PartialWriteError); ok {
 		atomic.AddInt64(&h.stats.PointsWrittenOK, int64(len(points)-werr.Dropped))
 		atomic.AddInt64(&h.stats.PointsWrittenDropped, int64(werr.Dropped))
@@ -932,7 +1400,7 @@ func (h *Handler) servePromRead(w http.ResponseWriter, r *http.Request, user met
 
 	// Query the DB and create a ReadResponse for Prometheus
 	db := r.FormValue("db")
-	q, err := prometheus.ReadRequestToInfluxQLQuery(&req, db, r.FormValue("rp"))
+	q, err := h.promSchema.ReadRequestToInfluxQLQuery(&req, db, r.FormValue("rp"))
 	if err != nil {
 		h.httpError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -963,6 +1431,15 @@ func (h *Handler) servePromRead(w http.ResponseWriter, r *http.Request, user met
 		opts.Authorizer = query.OpenAuthorizer{}
 	}
 
+	// Apply the limits requested by trusted clients.
+	if ok, err := parseQueryLimits(r, &opts); err != nil {
+		h.httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if ok && h.Config.AuthEnabled && (user == nil || !user.IsAdmin()) {
+		h.httpError(w, "query limit headers require admin privileges", http.StatusForbidden)
+		return
+	}
+
 	// Make sure if the client disconnects we signal the query to abort
 	var closing chan struct{}
 	closing = make(chan struct{})
@@ -1005,7 +1482,7 @@ func (h *Handler) servePromRead(w http.ResponseWriter, r *http.Request, user met
 		// read the series data and convert into Prometheus samples
 		for _, s := range r.Series {
 			ts := &remote.TimeSeries{
-				Labels: prometheus.TagsToLabelPairs(s.Tags),
+				Labels: h.promSchema.LabelPairs(s.Name, s.Tags),
 			}
 
 			for _, v := range s.Values {
@@ -1269,6 +1746,20 @@ func (h *Handler) httpError(w http.ResponseWriter, errmsg string, code int) {
 	w.Write(b)
 }
 
+// quotaError writes a write rejected by a database quota to the client.  Each
+// quota is reported with its own status code so clients can tell them apart.
+func (h *Handler) quotaError(w http.ResponseWriter, err influxdb.QuotaError) {
+	code := http.StatusForbidden
+	switch err.Quota {
+	case influxdb.QuotaSeries:
+		code = http.StatusUnprocessableEntity
+	case influxdb.QuotaWrites:
+		code = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", "1")
+	}
+	h.httpError(w, err.Error(), code)
+}
+
 // Filters and filter helpers
 
 type credentials struct {
@@ -1307,6 +1798,14 @@ func parseCredentials(r *http.Request) (*credentials, error) {
 			}, nil
 		}
 
+		// Check for API token.
+		if len(strs) == 2 && strs[0] == "Token" {
+			return &credentials{
+				Method: TokenAuthentication,
+				Token:  strs[1],
+			}, nil
+		}
+
 		// Check for basic auth.
 		if u, p, ok := r.BasicAuth(); ok {
 			return &credentials{
@@ -1351,23 +1850,19 @@ func authenticate(inner func(http.ResponseWriter, *http.Request, meta.User), h *
 					return
 				}
 
-				user, err = h.MetaClient.Authenticate(creds.Username, creds.Password)
+				if h.Authenticator != nil {
+					user, err = h.Authenticator.Authenticate(creds.Username, creds.Password)
+				} else {
+					user, err = h.MetaClient.Authenticate(creds.Username, creds.Password)
+				}
 				if err != nil {
 					atomic.AddInt64(&h.stats.AuthenticationFailures, 1)
 					h.httpError(w, "authorization failed", http.StatusUnauthorized)
 					return
 				}
 			case BearerAuthentication:
-				keyLookupFn := func(token *jwt.Token) (interface{}, error) {
-					// Check for expected signing method.
-					if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-						return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-					}
-					return []byte(h.Config.SharedSecret), nil
-				}
-
 				// Parse and validate the token.
-				token, err := jwt.Parse(creds.Token, keyLookupFn)
+				token, err := jwt.Parse(creds.Token, h.jwtKey)
 				if err != nil {
 					h.httpError(w, err.Error(), http.StatusUnauthorized)
 					return
@@ -1390,7 +1885,11 @@ func authenticate(inner func(http.ResponseWriter, *http.Request, meta.User), h *
 				}
 
 				// Get the username from the token.
-				username, ok := claims["username"].(string)
+				usernameClaim := h.Config.JWTUsernameClaim
+				if usernameClaim == "" {
+					usernameClaim = DefaultJWTUsernameClaim
+				}
+				username, ok := claims[usernameClaim].(string)
 				if !ok {
 					h.httpError(w, "username in token must be a string", http.StatusUnauthorized)
 					return
@@ -1399,52 +1898,58 @@ func authenticate(inner func(http.ResponseWriter, *http.Request, meta.User), h *
 					return
 				}
 
-				// Lookup user in the metastore.
-				if user, err = h.MetaClient.User(username); err != nil {
+				// Take the privileges of the user from the token, if it has them,
+				// or else lookup the user in the metastore.
+				if c := h.Config.JWTPrivilegesClaim; c != "" && claims[c] != nil {
+					if user, err = newJWTUser(username, claims[c], claims[h.Config.JWTAdminClaim]); err != nil {
+						h.httpError(w, err.Error(), http.StatusUnauthorized)
+						return
+					}
+				} else if user, err = h.MetaClient.User(username); err != nil {
 					h.httpError(w, err.Error(), http.StatusUnauthorized)
 					return
 				} else if user == nil {
 					h.httpError(w, meta.ErrUserNotFound.Error(), http.StatusUnauthorized)
 					return
 				}
+			case TokenAuthentication:
+				user, err = h.MetaClient.AuthenticateToken(creds.Token)
+				if err != nil {
+					atomic.AddInt64(&h.stats.AuthenticationFailures, 1)
+					h.httpError(w, "authorization failed", http.StatusUnauthorized)
+					return
+				}
 			default:
 				h.httpError(w, "unsupported authentication", http.StatusUnauthorized)
 			}
 
 		}
+		accessLogFromContext(r.Context()).setUser(user)
 		inner(w, r, user)
 	})
 }
 
-// cors responds to incoming requests and adds the appropriate cors headers
-// TODO: corylanou: add the ability to configure this in our config
-func cors(inner http.Handler) http.Handler {
+// cors adds the CORS headers to the responses to the allowed origins, and
+// responds to preflight requests.
+func (h *Handler) cors(inner http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := h.Config.CORS
 		if origin := r.Header.Get("Origin"); origin != "" {
-			w.Header().Set(`Access-Control-Allow-Origin`, origin)
-			w.Header().Set(`Access-Control-Allow-Methods`, strings.Join([]string{
-				`DELETE`,
-				`GET`,
-				`OPTIONS`,
-				`POST`,
-				`PUT`,
-			}, ", "))
-
-			w.Header().Set(`Access-Control-Allow-Headers`, strings.Join([]string{
-				`Accept`,
-				`Accept-Encoding`,
-				`Authorization`,
-				`Content-Length`,
-				`Content-Type`,
-				`X-CSRF-Token`,
-				`X-HTTP-Method-Override`,
-			}, ", "))
-
-			w.Header().Set(`Access-Control-Expose-Headers`, strings.Join([]string{
-				`Date`,
-				`X-InfluxDB-Version`,
-				`X-InfluxDB-Build`,
-			}, ", "))
+			w.Header().Add("Vary", "Origin")
+			if c.allowsOrigin(origin) {
+				w.Header().Set(`Access-Control-Allow-Origin`, origin)
+				w.Header().Set(`Access-Control-Allow-Methods`, strings.Join(c.AllowedMethods, ", "))
+				w.Header().Set(`Access-Control-Allow-Headers`, strings.Join(c.AllowedHeaders, ", "))
+				w.Header().Set(`Access-Control-Expose-Headers`, strings.Join([]string{
+					`Date`,
+					`X-InfluxDB-Version`,
+					`X-InfluxDB-Build`,
+					nextCursorHeader,
+				}, ", "))
+				if r.Method == "OPTIONS" && c.MaxAge > 0 {
+					w.Header().Set(`Access-Control-Max-Age`, strconv.Itoa(int(time.Duration(c.MaxAge)/time.Second)))
+				}
+			}
 		}
 
 		if r.Method == "OPTIONS" {
@@ -1490,8 +1995,19 @@ func (h *Handler) logging(inner http.Handler, name string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		l := &responseLogger{w: w}
-		inner.ServeHTTP(l, r)
-		h.CLFLogger.Println(buildLogLine(l, r, start))
+		if h.Config.AccessLogFormat == AccessLogFormatJSON {
+			entry := newAccessLogEntry(r, name, start)
+			r = r.WithContext(context.WithValue(r.Context(), accessLogKey{}, entry))
+			inner.ServeHTTP(l, r)
+			if b, err := entry.finish(l, r, start); err != nil {
+				h.Logger.Info(fmt.Sprintf("unable to encode access log entry: %s", err))
+			} else {
+				h.JSONLogger.Println(string(b))
+			}
+		} else {
+			inner.ServeHTTP(l, r)
+			h.CLFLogger.Println(buildLogLine(l, r, start))
+		}
 
 		// Log server errors.
 		if l.Status()/100 == 5 {
@@ -1503,6 +2019,33 @@ func (h *Handler) logging(inner http.Handler, name string) http.Handler {
 	})
 }
 
+// tracing traces the request if the tracer samples it, passing its span to
+// the inner handler in the context of the request.
+func (h *Handler) tracing(inner http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.Tracer == nil {
+			inner.ServeHTTP(w, r)
+			return
+		}
+
+		t, span := h.Tracer.StartTrace(name, r.Header)
+		if span == nil {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		span.SetLabels("http.method", r.Method, "http.path", r.URL.Path, "request_id", r.Header.Get("Request-Id"))
+
+		ctx := tracing.NewContextWithTrace(r.Context(), t)
+		ctx = tracing.NewContextWithSpan(ctx, span)
+		l := &responseLogger{w: w}
+		inner.ServeHTTP(l, r.WithContext(ctx))
+
+		span.SetFields(fields.New(fields.Int64("http.status_code", int64(l.Status()))))
+		span.Finish()
+		h.Tracer.Report(t)
+	})
+}
+
 func (h *Handler) responseWriter(inner http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w = NewResponseWriter(w, r)
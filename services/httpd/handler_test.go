@@ -2,11 +2,18 @@ package httpd_test
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"math"
+	"math/big"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
@@ -19,13 +26,19 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
+	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/internal"
+	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/tracing"
 	"github.com/influxdata/influxdb/prometheus/remote"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/services/httpd"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/klauspost/compress/zstd"
 )
 
 // Ensure the handler returns results from a query (including nil results).
@@ -51,6 +64,116 @@ func TestHandler_Query(t *testing.T) {
 	}
 }
 
+// Ensure the handler compresses the results with the preferred encoding the
+// client accepts.
+func TestHandler_Query_AcceptEncoding(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		ctx.Results <- &query.Result{StatementID: 0, Series: models.Rows([]*models.Row{{Name: "series0"}})}
+		return nil
+	}
+
+	for _, tt := range []struct {
+		accept   string
+		encoding string
+		decode   func(r io.Reader) (io.Reader, error)
+	}{
+		{accept: "gzip", encoding: "gzip", decode: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{accept: "gzip, zstd", encoding: "zstd", decode: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) }},
+		{accept: "gzip, snappy", encoding: "snappy", decode: func(r io.Reader) (io.Reader, error) { return snappy.NewReader(r), nil }},
+		{accept: "gzip, zstd;q=0", encoding: "gzip", decode: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{accept: "br", encoding: "", decode: func(r io.Reader) (io.Reader, error) { return r, nil }},
+	} {
+		req := MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+		req.Header.Set("Accept-Encoding", tt.accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: unexpected status: %d", tt.accept, w.Code)
+		} else if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Fatalf("%s: unexpected content encoding: %q", tt.accept, got)
+		}
+
+		r, err := tt.decode(w.Body)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.accept, err)
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.accept, err)
+		} else if body := strings.TrimSpace(string(b)); body != `{"results":[{"statement_id":0,"series":[{"name":"series0"}]}]}` {
+			t.Fatalf("%s: unexpected body: %s", tt.accept, body)
+		}
+	}
+}
+
+// Ensure the handler answers repeated queries from the query cache until a
+// write invalidates the results.
+func TestHandler_Query_Cache(t *testing.T) {
+	config := httpd.NewConfig()
+	config.QueryCacheSize = 10
+	h := NewHandlerWithConfig(config)
+
+	var n int
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		n++
+		ctx.Results <- &query.Result{StatementID: 0, Series: models.Rows([]*models.Row{{Name: "series0"}})}
+		return nil
+	}
+
+	exp := `{"results":[{"statement_id":0,"series":[{"name":"series0"}]}]}`
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status: %d", w.Code)
+		} else if body := strings.TrimSpace(w.Body.String()); body != exp {
+			t.Fatalf("unexpected body: %s", body)
+		}
+
+		if i == 1 {
+			h.QueryCache.Invalidate("foo", "autogen", []models.Point{models.MustNewPoint("bar", nil, models.Fields{"value": 1.0}, time.Now())})
+		}
+	}
+
+	if n != 2 {
+		t.Fatalf("unexpected number of executions: %d", n)
+	}
+}
+
+// Ensure the handler binds the query parameters into the statement.
+func TestHandler_Query_Params(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		if stmt.String() != `SELECT * FROM bar WHERE host = 'server01' AND value > 1.500 AND n < 10` {
+			t.Fatalf("unexpected query: %s", stmt.String())
+		}
+		ctx.Results <- &query.Result{StatementID: 0}
+		return nil
+	}
+
+	form := url.Values{
+		"q":      {`SELECT * FROM bar WHERE host = $host AND value > $min AND n < $max`},
+		"params": {`{"host": "server01", "min": 1.5, "max": 10}`},
+	}
+	r := MustNewJSONRequest("POST", "/query?db=foo", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	// A parameter that is not bound is an error.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q="+url.QueryEscape(`SELECT * FROM bar WHERE host = $host`)+"&params="+url.QueryEscape(`{}`), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"error":"error parsing query: missing parameter: host"}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
 // Ensure the handler returns results from a query passed as a file.
 func TestHandler_Query_File(t *testing.T) {
 	h := NewHandler(false)
@@ -311,6 +434,138 @@ func TestHandler_Query_Chunked(t *testing.T) {
 	}
 }
 
+// Ensure the handler returns the results of a paginated query in pages that
+// resume where the previous page ended.
+func TestHandler_Query_Paginate(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		switch stmt.String() {
+		case `SELECT * FROM foo`:
+			ctx.Send(&query.Result{StatementID: ctx.StatementID, Series: models.Rows{{Name: "foo", Values: [][]interface{}{{1}, {2}}, Partial: true}}})
+			ctx.Send(&query.Result{StatementID: ctx.StatementID, Series: models.Rows{{Name: "foo", Values: [][]interface{}{{3}}}}})
+		case `SELECT * FROM bar`:
+			ctx.Send(&query.Result{StatementID: ctx.StatementID, Series: models.Rows{{Name: "bar", Values: [][]interface{}{{4}}}}})
+		default:
+			t.Fatalf("unexpected query: %s", stmt.String())
+		}
+		return nil
+	}
+
+	form := url.Values{
+		"db":         {"db0"},
+		"q":          {"SELECT * FROM foo; SELECT * FROM bar"},
+		"paginate":   {"true"},
+		"chunk_size": {"2"},
+	}
+	var cursors []string
+	for i, exp := range []string{
+		`{"results":[{"statement_id":0,"series":[{"name":"foo","values":[[1],[2]],"partial":true}]}]}`,
+		`{"results":[{"statement_id":0,"series":[{"name":"foo","values":[[3]]}]},{"statement_id":1,"series":[{"name":"bar","values":[[4]]}]}]}`,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?"+form.Encode(), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status(%d): %d", i, w.Code)
+		} else if body := strings.TrimSpace(w.Body.String()); body != exp {
+			t.Fatalf("unexpected body(%d): %s", i, body)
+		}
+
+		cursor := w.Header().Get("X-Influxdb-Next-Cursor")
+		cursors = append(cursors, cursor)
+		form.Set("cursor", cursor)
+	}
+
+	// The last page has no cursor.
+	if cursors[1] != "" {
+		t.Fatalf("unexpected cursor: %s", cursors[1])
+	}
+
+	// A cursor of another query is rejected.
+	form.Set("q", "SELECT * FROM bar")
+	form.Set("cursor", cursors[0])
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?"+form.Encode(), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the pages of a paginated query resume after the series and time of
+// the last value of the previous page, so values written meanwhile before it
+// don't shift the pages.
+func TestHandler_Query_Paginate_Resume(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		q     string
+		rows  [2]models.Rows // rows of the first and second requests
+		stmts [2]string      // statements executed by the requests
+		exp   [2]string
+	}{
+		{
+			name: "time bound",
+			q:    "SELECT * FROM cpu",
+			rows: [2]models.Rows{
+				{{Name: "cpu", Columns: []string{"time", "value"}, Values: [][]interface{}{{time.Unix(0, 10), 1}, {time.Unix(0, 20), 2}, {time.Unix(0, 30), 3}}}},
+				{{Name: "cpu", Columns: []string{"time", "value"}, Values: [][]interface{}{{time.Unix(0, 5), 0}, {time.Unix(0, 30), 3}}}},
+			},
+			stmts: [2]string{`SELECT * FROM cpu`, `SELECT * FROM cpu WHERE time > 20`},
+			exp: [2]string{
+				`{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","value"],"values":[["1970-01-01T00:00:00.00000001Z",1],["1970-01-01T00:00:00.00000002Z",2]],"partial":true}]}]}`,
+				`{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","value"],"values":[["1970-01-01T00:00:00.00000003Z",3]]}]}]}`,
+			},
+		},
+		{
+			name: "series",
+			q:    "SELECT * FROM cpu GROUP BY host",
+			rows: [2]models.Rows{
+				{
+					{Name: "cpu", Tags: map[string]string{"host": "b"}, Columns: []string{"time", "value"}, Values: [][]interface{}{{time.Unix(0, 10), 1}, {time.Unix(0, 20), 2}}},
+					{Name: "cpu", Tags: map[string]string{"host": "c"}, Columns: []string{"time", "value"}, Values: [][]interface{}{{time.Unix(0, 10), 3}}},
+				},
+				{
+					{Name: "cpu", Tags: map[string]string{"host": "a"}, Columns: []string{"time", "value"}, Values: [][]interface{}{{time.Unix(0, 10), 0}}},
+					{Name: "cpu", Tags: map[string]string{"host": "b"}, Columns: []string{"time", "value"}, Values: [][]interface{}{{time.Unix(0, 10), 1}, {time.Unix(0, 20), 2}}},
+					{Name: "cpu", Tags: map[string]string{"host": "c"}, Columns: []string{"time", "value"}, Values: [][]interface{}{{time.Unix(0, 10), 3}}},
+				},
+			},
+			stmts: [2]string{`SELECT * FROM cpu GROUP BY host`, `SELECT * FROM cpu GROUP BY host`},
+			exp: [2]string{
+				`{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"b"},"columns":["time","value"],"values":[["1970-01-01T00:00:00.00000001Z",1],["1970-01-01T00:00:00.00000002Z",2]]}]}]}`,
+				`{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"c"},"columns":["time","value"],"values":[["1970-01-01T00:00:00.00000001Z",3]]}]}]}`,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var i int
+			h := NewHandler(false)
+			h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+				if got, exp := stmt.String(), tt.stmts[i]; got != exp {
+					t.Errorf("unexpected statement(%d): %s", i, got)
+				}
+				ctx.Send(&query.Result{StatementID: ctx.StatementID, Series: tt.rows[i]})
+				return nil
+			}
+
+			form := url.Values{
+				"db":         {"db0"},
+				"q":          {tt.q},
+				"paginate":   {"true"},
+				"chunk_size": {"2"},
+			}
+			for i = range tt.exp {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?"+form.Encode(), nil))
+				if w.Code != http.StatusOK {
+					t.Fatalf("unexpected status(%d): %d", i, w.Code)
+				} else if body := strings.TrimSpace(w.Body.String()); body != tt.exp[i] {
+					t.Fatalf("unexpected body(%d): %s", i, body)
+				}
+				form.Set("cursor", w.Header().Get("X-Influxdb-Next-Cursor"))
+			}
+		})
+	}
+}
+
 // Ensure the handler can accept an async query.
 func TestHandler_Query_Async(t *testing.T) {
 	done := make(chan struct{})
@@ -358,156 +613,851 @@ func TestHandler_Query_ErrQueryRequired(t *testing.T) {
 	}
 }
 
-// Ensure the handler returns a status 400 if the query cannot be parsed.
-func TestHandler_Query_ErrInvalidQuery(t *testing.T) {
+// Ensure the handler lists running queries and can kill them.
+func TestHandler_Queries_Kill(t *testing.T) {
 	h := NewHandler(false)
+	started := make(chan struct{})
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		close(started)
+		<-ctx.InterruptCh
+		return query.ErrQueryInterrupted
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil))
+		done <- w
+	}()
+	<-started
+
 	w := httptest.NewRecorder()
-	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?q=SELECT", nil))
-	if w.Code != http.StatusBadRequest {
+	h.ServeHTTP(w, MustNewRequest("GET", "/queries", nil))
+	if w.Code != http.StatusOK {
 		t.Fatalf("unexpected status: %d", w.Code)
-	} else if body := strings.TrimSpace(w.Body.String()); body != `{"error":"error parsing query: found EOF, expected identifier, string, number, bool at line 1, char 8"}` {
+	}
+	var resp struct {
+		Queries []query.QueryInfo `json:"queries"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	} else if len(resp.Queries) != 1 {
+		t.Fatalf("unexpected queries: %s", w.Body.String())
+	} else if got, want := resp.Queries[0].Query, "SELECT * FROM bar"; got != want {
+		t.Fatalf("unexpected query: got=%s want=%s", got, want)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", fmt.Sprintf("/queries/%d", resp.Queries[0].ID), nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	w = <-done
+	if body := strings.TrimSpace(w.Body.String()); body != `{"results":[{"statement_id":0,"error":"query interrupted"}]}` {
 		t.Fatalf("unexpected body: %s", body)
 	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", fmt.Sprintf("/queries/%d", resp.Queries[0].ID), nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
 }
 
-// Ensure the handler returns an appropriate 401 or 403 status when authentication or authorization fails.
-func TestHandler_Query_ErrAuthorize(t *testing.T) {
+// Ensure killing a query over HTTP requires the KILL QUERY privileges.
+func TestHandler_Queries_Kill_ErrAuthorize(t *testing.T) {
 	h := NewHandler(true)
 	h.QueryAuthorizer.AuthorizeQueryFn = func(u meta.User, q *influxql.Query, db string) error {
+		if _, ok := q.Statements[0].(*influxql.KillQueryStatement); !ok {
+			t.Errorf("unexpected statement: %s", q)
+		}
 		return errors.New("marker")
 	}
-	h.MetaClient.AdminUserExistsFn = func() bool { return true }
-	h.MetaClient.AuthenticateFn = func(u, p string) (meta.User, error) {
-
-		users := []meta.UserInfo{
-			{
-				Name:  "admin",
-				Hash:  "admin",
-				Admin: true,
-			},
-			{
-				Name: "user1",
-				Hash: "abcd",
-				Privileges: map[string]influxql.Privilege{
-					"db0": influxql.ReadPrivilege,
-				},
-			},
-		}
+	h.MetaClient.AdminUserExistsFn = func() bool { return false }
 
-		for _, user := range users {
-			if u == user.Name {
-				if p == user.Hash {
-					return &user, nil
-				}
-				return nil, meta.ErrAuthenticate
-			}
-		}
-		return nil, meta.ErrUserNotFound
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", "/queries/1", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d", w.Code)
 	}
+}
 
-	for i, tt := range []struct {
-		user     string
-		password string
-		query    string
-		code     int
-	}{
-		{
-			query: "/query?q=SHOW+DATABASES",
-			code:  http.StatusUnauthorized,
-		},
-		{
-			user:     "user1",
-			password: "abcd",
-			query:    "/query?q=SHOW+DATABASES",
-			code:     http.StatusForbidden,
+// Ensure the handler lists the storage used by databases and shards.
+func TestHandler_Storage(t *testing.T) {
+	h := NewHandler(false)
+	h.Handler.Store = &HandlerStore{
+		ShardsStorageFn: func() []tsdb.ShardStorage {
+			return []tsdb.ShardStorage{
+				{ID: 1, Database: "db0", RetentionPolicy: "rp0", DiskBytes: 100, TSMFiles: 2},
+				{ID: 2, Database: "db1", RetentionPolicy: "rp0", DiskBytes: 50, TSMFiles: 1},
+			}
 		},
-		{
-			user:     "user2",
-			password: "abcd",
-			query:    "/query?q=SHOW+DATABASES",
-			code:     http.StatusUnauthorized,
+		DatabasesStorageFn: func() ([]tsdb.DatabaseStorage, error) {
+			return []tsdb.DatabaseStorage{
+				{Name: "db0", ShardN: 1, DiskBytes: 100, TSMFiles: 2, SeriesN: 10},
+				{Name: "db1", ShardN: 1, DiskBytes: 50, TSMFiles: 1, SeriesN: 5},
+			}, nil
 		},
-	} {
-		w := httptest.NewRecorder()
-		r := MustNewJSONRequest("GET", tt.query, nil)
-		params := r.URL.Query()
-		if tt.user != "" {
-			params.Set("u", tt.user)
-		}
-		if tt.password != "" {
-			params.Set("p", tt.password)
-		}
-		r.URL.RawQuery = params.Encode()
+	}
 
-		h.ServeHTTP(w, r)
-		if w.Code != tt.code {
-			t.Errorf("%d. unexpected status: got=%d exp=%d\noutput: %s", i, w.Code, tt.code, w.Body.String())
-		}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/storage?db=db1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Databases []tsdb.DatabaseStorage `json:"databases"`
+		Shards    []tsdb.ShardStorage    `json:"shards"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	} else if len(resp.Databases) != 1 || resp.Databases[0].Name != "db1" || resp.Databases[0].SeriesN != 5 {
+		t.Fatalf("unexpected databases: %s", w.Body.String())
+	} else if len(resp.Shards) != 1 || resp.Shards[0].ID != 2 || resp.Shards[0].DiskBytes != 50 {
+		t.Fatalf("unexpected shards: %s", w.Body.String())
 	}
 }
 
-// Ensure the handler returns a status 200 if an error is returned in the result.
-func TestHandler_Query_ErrResult(t *testing.T) {
-	h := NewHandler(false)
-	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
-		return errors.New("measurement not found")
+// Ensure listing storage over HTTP requires the SHOW SHARDS privileges.
+func TestHandler_Storage_ErrAuthorize(t *testing.T) {
+	h := NewHandler(true)
+	h.QueryAuthorizer.AuthorizeQueryFn = func(u meta.User, q *influxql.Query, db string) error {
+		if _, ok := q.Statements[0].(*influxql.ShowShardsStatement); !ok {
+			t.Errorf("unexpected statement: %s", q)
+		}
+		return errors.New("marker")
 	}
+	h.MetaClient.AdminUserExistsFn = func() bool { return false }
 
 	w := httptest.NewRecorder()
-	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SHOW+SERIES+from+bin", nil))
-	if w.Code != http.StatusOK {
+	h.ServeHTTP(w, MustNewRequest("GET", "/storage", nil))
+	if w.Code != http.StatusForbidden {
 		t.Fatalf("unexpected status: %d", w.Code)
-	} else if body := strings.TrimSpace(w.Body.String()); body != `{"results":[{"statement_id":0,"error":"measurement not found"}]}` {
-		t.Fatalf("unexpected body: %s", body)
 	}
 }
 
-// Ensure that closing the HTTP connection causes the query to be interrupted.
-func TestHandler_Query_CloseNotify(t *testing.T) {
-	// Avoid leaking a goroutine when this fails.
-	done := make(chan struct{})
-	defer close(done)
-
-	interrupted := make(chan struct{})
+// Ensure the admin API creates, lists and drops databases.
+func TestHandler_Admin_Databases(t *testing.T) {
 	h := NewHandler(false)
-	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
-		select {
-		case <-ctx.InterruptCh:
-		case <-done:
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		if name == "db0" {
+			return &meta.DatabaseInfo{Name: name}
 		}
-		close(interrupted)
 		return nil
 	}
 
-	s := httptest.NewServer(h)
-	defer s.Close()
-
-	// Parse the URL and generate a query request.
-	u, err := url.Parse(s.URL)
-	if err != nil {
-		t.Fatal(err)
+	var stmts []string
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		stmts = append(stmts, stmt.String())
+		if _, ok := stmt.(*influxql.ShowDatabasesStatement); ok {
+			ctx.Results <- &query.Result{Series: models.Rows{{Name: "databases", Columns: []string{"name"}, Values: [][]interface{}{{"db0"}}}}}
+		}
+		return nil
 	}
-	u.Path = "/query"
-
-	values := url.Values{}
-	values.Set("q", "SELECT * FROM cpu")
-	values.Set("db", "db0")
-	values.Set("rp", "rp0")
-	values.Set("chunked", "true")
-	u.RawQuery = values.Encode()
 
-	req, err := http.NewRequest("GET", u.String(), nil)
-	if err != nil {
-		t.Fatal(err)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/databases", strings.NewReader(`{"name":"db1","retentionPolicy":{"name":"rp0","duration":"1d","replicaN":1}}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
 	}
 
-	// Perform the request and retrieve the response.
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		t.Fatal(err)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/api/admin/databases", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"databases":[{"name":"db0"}]}` {
+		t.Fatalf("unexpected body: %s", body)
 	}
 
-	// Validate that the interrupted channel has NOT been closed yet.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", "/api/admin/databases/db0", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	// Unknown databases and invalid bodies aren't sent to the executor.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", "/api/admin/databases/db1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/databases", strings.NewReader(`{"name":"db2","retentionPolicy":{"duration":"1x"}}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	exp := []string{
+		`CREATE DATABASE db1 WITH DURATION 24h0m0s REPLICATION 1 NAME rp0`,
+		`SHOW DATABASES`,
+		`DROP DATABASE db0`,
+	}
+	if !reflect.DeepEqual(stmts, exp) {
+		t.Fatalf("unexpected statements:\ngot %q\nexp %q", stmts, exp)
+	}
+}
+
+// Ensure the admin API maps the errors of user statements to status codes.
+func TestHandler_Admin_Users(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		switch stmt := stmt.(type) {
+		case *influxql.CreateUserStatement:
+			if stmt.Name != "susy" || stmt.Password != "pass" || !stmt.Admin {
+				t.Fatalf("unexpected statement: %#v", stmt)
+			}
+			return meta.ErrUserExists
+		case *influxql.DropUserStatement:
+			return meta.ErrUserNotFound
+		case *influxql.ShowUsersStatement:
+			ctx.Results <- &query.Result{Series: models.Rows{{Columns: []string{"user", "admin"}, Values: [][]interface{}{{"susy", true}}}}}
+		}
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/users", strings.NewReader(`{"name":"susy","password":"pass","admin":true}`)))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"error":"user already exists"}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/api/admin/users", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"users":[{"name":"susy","admin":true}]}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", "/api/admin/users/bob", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// Ensure the admin API creates, lists and drops retention policies.
+func TestHandler_Admin_RetentionPolicies(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		if name == "db0" {
+			return &meta.DatabaseInfo{Name: name, RetentionPolicies: []meta.RetentionPolicyInfo{{Name: "rp0"}}}
+		}
+		return nil
+	}
+
+	var stmts []string
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		stmts = append(stmts, stmt.String())
+		if _, ok := stmt.(*influxql.ShowRetentionPoliciesStatement); ok {
+			ctx.Results <- &query.Result{Series: models.Rows{{
+				Columns: []string{"name", "duration", "shardGroupDuration", "replicaN", "default"},
+				Values:  [][]interface{}{{"rp0", "168h0m0s", "24h0m0s", 1, true}},
+			}}}
+		}
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/retention-policies", strings.NewReader(`{"database":"db0","name":"rp1","duration":"INF","shardGroupDuration":"1w","default":true}`)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/api/admin/retention-policies?db=db0", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"retentionPolicies":[{"name":"rp0","duration":"168h0m0s","shardGroupDuration":"24h0m0s","replicaN":1,"default":true}]}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", "/api/admin/retention-policies/rp0?db=db0", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, tt := range []struct {
+		method, url, body string
+		code              int
+	}{
+		{method: "GET", url: "/api/admin/retention-policies", code: http.StatusBadRequest},
+		{method: "GET", url: "/api/admin/retention-policies?db=db1", code: http.StatusNotFound},
+		{method: "DELETE", url: "/api/admin/retention-policies/rp1?db=db0", code: http.StatusNotFound},
+		{method: "POST", url: "/api/admin/retention-policies", body: `{"database":"db0","name":"rp1"}`, code: http.StatusBadRequest},
+	} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+		if w.Code != tt.code {
+			t.Fatalf("%s %s: unexpected status: %d: %s", tt.method, tt.url, w.Code, w.Body.String())
+		}
+	}
+
+	exp := []string{
+		`CREATE RETENTION POLICY rp1 ON db0 DURATION 0s REPLICATION 1 SHARD DURATION 1w DEFAULT`,
+		`SHOW RETENTION POLICIES ON db0`,
+		`DROP RETENTION POLICY rp0 ON db0`,
+	}
+	if !reflect.DeepEqual(stmts, exp) {
+		t.Fatalf("unexpected statements:\ngot %q\nexp %q", stmts, exp)
+	}
+}
+
+// Ensure the admin API requires the privileges of the statements.
+func TestHandler_Admin_ErrAuthorize(t *testing.T) {
+	h := NewHandler(true)
+	h.QueryAuthorizer.AuthorizeQueryFn = func(u meta.User, q *influxql.Query, db string) error {
+		if _, ok := q.Statements[0].(*influxql.DropUserStatement); !ok {
+			t.Errorf("unexpected statement: %s", q)
+		}
+		return errors.New("marker")
+	}
+	h.MetaClient.AdminUserExistsFn = func() bool { return false }
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", "/api/admin/users/susy", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the admin API reloads and tests graphite templates.
+func TestHandler_Admin_Graphite(t *testing.T) {
+	h := NewHandler(false)
+
+	var reloaded bool
+	h.Handler.Graphite = &HandlerGraphite{
+		ReloadGraphiteTemplatesFn: func() error {
+			reloaded = true
+			return nil
+		},
+		TestGraphiteTemplatesFn: func(bindAddress string, templates []string, line string) (models.Point, error) {
+			if bindAddress != ":2003" || !reflect.DeepEqual(templates, []string{"host.measurement"}) {
+				t.Fatalf("unexpected test: %q %q", bindAddress, templates)
+			} else if line == "bad" {
+				return nil, errors.New("marker")
+			}
+			return models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "server01"}), models.Fields{"value": 1.5}, time.Unix(1500000000, 0)), nil
+		},
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/graphite/reload", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if !reloaded {
+		t.Fatal("templates not reloaded")
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/graphite/test-template", strings.NewReader(`{"bindAddress":":2003","templates":["host.measurement"],"line":"server01.cpu 1.5 1500000000"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"measurement":"cpu","tags":{"host":"server01"},"fields":{"value":1.5},"time":"2017-07-14T02:40:00Z"}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	for _, body := range []string{`{"bindAddress":":2003","templates":["host.measurement"],"line":"bad"}`, `{}`} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/graphite/test-template", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: unexpected status: %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+// Ensure the graphite admin API requires an admin user.
+func TestHandler_Admin_Graphite_ErrAuthorize(t *testing.T) {
+	h := NewHandler(true)
+	h.Handler.Graphite = &HandlerGraphite{
+		ReloadGraphiteTemplatesFn: func() error {
+			t.Fatal("unexpected reload")
+			return nil
+		},
+	}
+	h.MetaClient.AdminUserExistsFn = func() bool { return false }
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/graphite/reload", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the admin API reloads the configuration and reports the changed
+// settings.
+func TestHandler_Admin_ConfigReload(t *testing.T) {
+	h := NewHandler(false)
+
+	var err error
+	h.Handler.ConfigReloader = &HandlerConfigReloader{
+		ReloadFn: func() ([]string, []string, error) {
+			return []string{"logging.level"}, nil, err
+		},
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/config/reload", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"applied":["logging.level"],"restartRequired":[]}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	err = errors.New("invalid config")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/config/reload", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// Ensure reloading the configuration requires an admin user.
+func TestHandler_Admin_ConfigReload_ErrAuthorize(t *testing.T) {
+	h := NewHandler(true)
+	h.Handler.ConfigReloader = &HandlerConfigReloader{
+		ReloadFn: func() ([]string, []string, error) {
+			t.Fatal("unexpected reload")
+			return nil, nil, nil
+		},
+	}
+	h.MetaClient.AdminUserExistsFn = func() bool { return false }
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/config/reload", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the admin API returns and changes the levels of the logs.
+func TestHandler_Admin_LogLevels(t *testing.T) {
+	h := NewHandler(false)
+	levels := &HandlerLogLevels{level: "info", services: map[string]string{"httpd": "warn"}}
+	h.Handler.LogLevels = levels
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/api/admin/log-levels", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"level":"info","services":{"httpd":"warn"}}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/log-levels", strings.NewReader(`{"service":"tsm1","level":"debug"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"level":"info","services":{"httpd":"warn","tsm1":"debug"}}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/log-levels", strings.NewReader(`{"service":"httpd"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if _, ok := levels.services["httpd"]; ok {
+		t.Fatal("httpd level not reset")
+	}
+
+	for _, body := range []string{`{"level":"verbose"}`, `{}`} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/log-levels", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: unexpected status: %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+}
+
+// Ensure the log levels admin API requires an admin user.
+func TestHandler_Admin_LogLevels_ErrAuthorize(t *testing.T) {
+	h := NewHandler(true)
+	h.Handler.LogLevels = &HandlerLogLevels{level: "info"}
+	h.MetaClient.AdminUserExistsFn = func() bool { return false }
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/log-levels", strings.NewReader(`{"level":"debug"}`)))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure the admin API returns, sets and removes the schemas of databases.
+func TestHandler_Admin_Schemas(t *testing.T) {
+	h := NewHandler(false)
+	var schema *meta.SchemaInfo
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		if name != "db0" {
+			return nil
+		}
+		return &meta.DatabaseInfo{Name: "db0", Schema: schema}
+	}
+	h.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		return []meta.DatabaseInfo{{Name: "db0", Schema: schema}, {Name: "db1"}}
+	}
+	h.MetaClient.SetSchemaFn = func(database string, s *meta.SchemaInfo) error {
+		schema = s
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/schemas", strings.NewReader(`{"database":"db0","schema":{"mode":"strict","measurements":[{"name":"cpu","tagKeys":["host"],"fields":{"value":"float"}}]}}`)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if schema == nil || schema.Mode != meta.SchemaStrict || schema.Measurement("cpu").Field("value").Type != influxql.Float {
+		t.Fatalf("unexpected schema: %+v", schema)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/api/admin/schemas", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"schemas":[{"database":"db0","schema":{"mode":"strict","measurements":[{"name":"cpu","tagKeys":["host"],"fields":{"value":"float"}}]}}]}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	for _, tt := range []struct {
+		body string
+		code int
+	}{
+		{body: `{"database":"db1","schema":{"mode":"strict"}}`, code: http.StatusNotFound},
+		{body: `{"database":"db0"}`, code: http.StatusBadRequest},
+		{body: `{"database":"db0","schema":{"mode":"loose"}}`, code: http.StatusBadRequest},
+		{body: `{"database":"db0","schema":{"mode":"warn","measurements":[{"name":"cpu","fields":{"value":"double"}}]}}`, code: http.StatusBadRequest},
+	} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest("POST", "/api/admin/schemas", strings.NewReader(tt.body)))
+		if w.Code != tt.code {
+			t.Fatalf("%s: unexpected status: %d: %s", tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", "/api/admin/schemas/db0", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if schema != nil {
+		t.Fatalf("unexpected schema: %+v", schema)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", "/api/admin/schemas/db0", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// Ensure the schemas admin API requires an admin user.
+func TestHandler_Admin_Schemas_ErrAuthorize(t *testing.T) {
+	h := NewHandler(true)
+	h.MetaClient.AdminUserExistsFn = func() bool { return false }
+	h.MetaClient.SetSchemaFn = func(database string, s *meta.SchemaInfo) error {
+		t.Fatal("unexpected schema change")
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("DELETE", "/api/admin/schemas/db0", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure schema changes, log level changes and query kills are audited.
+func TestHandler_Admin_Audit(t *testing.T) {
+	h := NewHandler(false)
+	auditor := &HandlerAuditor{}
+	h.Handler.Auditor = auditor
+	h.Handler.LogLevels = &HandlerLogLevels{level: "info", services: map[string]string{}}
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{Name: name, Schema: &meta.SchemaInfo{Mode: meta.SchemaWarn}}
+	}
+	h.MetaClient.SetSchemaFn = func(database string, s *meta.SchemaInfo) error { return nil }
+
+	for _, tt := range []struct {
+		method, path, body string
+	}{
+		{method: "POST", path: "/api/admin/log-levels", body: `{"service":"tsm1","level":"debug"}`},
+		{method: "POST", path: "/api/admin/log-levels", body: `{"service":"tsm1"}`},
+		{method: "POST", path: "/api/admin/log-levels", body: `{"level":"verbose"}`},
+		{method: "DELETE", path: "/api/admin/schemas/db0"},
+		{method: "DELETE", path: "/queries/12"},
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), MustNewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+	}
+
+	if exp := []string{
+		"log-level tsm1=debug",
+		"log-level tsm1=default",
+		`log-level default=verbose: unknown logging level: "verbose"`,
+		"schema db0",
+		"kill-query query 12: no such query id: 12",
+	}; !reflect.DeepEqual(auditor.entries, exp) {
+		t.Fatalf("unexpected entries:\n%s", strings.Join(auditor.entries, "\n"))
+	}
+}
+
+// Ensure a traced query passes its span to the statements and is reported.
+func TestHandler_Query_Tracer(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		if ctx.Span == nil {
+			t.Error("expected statement span")
+		}
+		ctx.Results <- &query.Result{StatementID: 0}
+		return nil
+	}
+
+	var reported *tracing.Trace
+	h.Handler.Tracer = &HandlerTracer{
+		StartTraceFn: func(name string, header http.Header) (*tracing.Trace, *tracing.Span) {
+			if name != "query" {
+				t.Fatalf("unexpected name: %s", name)
+			}
+			return tracing.NewTrace(name)
+		},
+		ReportFn: func(trace *tracing.Trace) { reported = trace },
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if reported == nil {
+		t.Fatal("trace not reported")
+	}
+
+	var names []string
+	for _, span := range reported.Spans() {
+		names = append(names, span.Name)
+	}
+	if exp := []string{"query", "statement"}; !reflect.DeepEqual(names, exp) {
+		t.Fatalf("unexpected spans: %v", names)
+	}
+}
+
+// Ensure the handler passes the query limit headers to the query executor.
+func TestHandler_Query_LimitHeaders(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		if got, want := ctx.QueryTimeout, 30*time.Second; got != want {
+			t.Errorf("unexpected query timeout: got=%s want=%s", got, want)
+		}
+		if ctx.MaxSelectPointN != 0 || ctx.MaxSelectSeriesN != 10 || ctx.MaxSelectBucketsN != 20 {
+			t.Errorf("unexpected limits: %d, %d, %d", ctx.MaxSelectPointN, ctx.MaxSelectSeriesN, ctx.MaxSelectBucketsN)
+		}
+		ctx.Results <- &query.Result{StatementID: 0}
+		return nil
+	}
+
+	req := MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+	req.Header.Set("X-Influxdb-Query-Timeout", "30s")
+	req.Header.Set("X-Influxdb-Max-Select-Series", "10")
+	req.Header.Set("X-Influxdb-Max-Select-Buckets", "20")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	req = MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+	req.Header.Set("X-Influxdb-Max-Select-Point", "-1")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"error":"invalid X-Influxdb-Max-Select-Point header: \"-1\""}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure only admin users can set the query limit headers.
+func TestHandler_Query_LimitHeaders_ErrAuthorize(t *testing.T) {
+	h := NewHandler(true)
+	h.QueryAuthorizer.AuthorizeQueryFn = func(u meta.User, q *influxql.Query, db string) error {
+		return nil
+	}
+	h.MetaClient.AdminUserExistsFn = func() bool { return true }
+	h.MetaClient.AuthenticateFn = func(u, p string) (meta.User, error) {
+		return &meta.UserInfo{Name: u, Admin: u == "admin"}, nil
+	}
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		ctx.Results <- &query.Result{StatementID: 0}
+		return nil
+	}
+
+	for _, tt := range []struct {
+		user string
+		code int
+	}{
+		{user: "admin", code: http.StatusOK},
+		{user: "user1", code: http.StatusForbidden},
+	} {
+		req := MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+		req.SetBasicAuth(tt.user, "password")
+		req.Header.Set("X-Influxdb-Query-Timeout", "1h")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: unexpected status: got=%d want=%d", tt.user, w.Code, tt.code)
+		}
+	}
+}
+
+// Ensure the handler returns a status 400 if the query cannot be parsed.
+func TestHandler_Query_ErrInvalidQuery(t *testing.T) {
+	h := NewHandler(false)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?q=SELECT", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"error":"error parsing query: found EOF, expected identifier, string, number, bool at line 1, char 8"}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure the handler returns an appropriate 401 or 403 status when authentication or authorization fails.
+func TestHandler_Query_ErrAuthorize(t *testing.T) {
+	h := NewHandler(true)
+	h.QueryAuthorizer.AuthorizeQueryFn = func(u meta.User, q *influxql.Query, db string) error {
+		return errors.New("marker")
+	}
+	h.MetaClient.AdminUserExistsFn = func() bool { return true }
+	h.MetaClient.AuthenticateFn = func(u, p string) (meta.User, error) {
+
+		users := []meta.UserInfo{
+			{
+				Name:  "admin",
+				Hash:  "admin",
+				Admin: true,
+			},
+			{
+				Name: "user1",
+				Hash: "abcd",
+				Privileges: map[string]influxql.Privilege{
+					"db0": influxql.ReadPrivilege,
+				},
+			},
+		}
+
+		for _, user := range users {
+			if u == user.Name {
+				if p == user.Hash {
+					return &user, nil
+				}
+				return nil, meta.ErrAuthenticate
+			}
+		}
+		return nil, meta.ErrUserNotFound
+	}
+
+	for i, tt := range []struct {
+		user     string
+		password string
+		query    string
+		code     int
+	}{
+		{
+			query: "/query?q=SHOW+DATABASES",
+			code:  http.StatusUnauthorized,
+		},
+		{
+			user:     "user1",
+			password: "abcd",
+			query:    "/query?q=SHOW+DATABASES",
+			code:     http.StatusForbidden,
+		},
+		{
+			user:     "user2",
+			password: "abcd",
+			query:    "/query?q=SHOW+DATABASES",
+			code:     http.StatusUnauthorized,
+		},
+	} {
+		w := httptest.NewRecorder()
+		r := MustNewJSONRequest("GET", tt.query, nil)
+		params := r.URL.Query()
+		if tt.user != "" {
+			params.Set("u", tt.user)
+		}
+		if tt.password != "" {
+			params.Set("p", tt.password)
+		}
+		r.URL.RawQuery = params.Encode()
+
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("%d. unexpected status: got=%d exp=%d\noutput: %s", i, w.Code, tt.code, w.Body.String())
+		}
+	}
+}
+
+// Ensure the handler returns a status 200 if an error is returned in the result.
+func TestHandler_Query_ErrResult(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		return errors.New("measurement not found")
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SHOW+SERIES+from+bin", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"results":[{"statement_id":0,"error":"measurement not found"}]}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure that closing the HTTP connection causes the query to be interrupted.
+func TestHandler_Query_CloseNotify(t *testing.T) {
+	// Avoid leaking a goroutine when this fails.
+	done := make(chan struct{})
+	defer close(done)
+
+	interrupted := make(chan struct{})
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		select {
+		case <-ctx.InterruptCh:
+		case <-done:
+		}
+		close(interrupted)
+		return nil
+	}
+
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	// Parse the URL and generate a query request.
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Path = "/query"
+
+	values := url.Values{}
+	values.Set("q", "SELECT * FROM cpu")
+	values.Set("db", "db0")
+	values.Set("rp", "rp0")
+	values.Set("chunked", "true")
+	u.RawQuery = values.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Perform the request and retrieve the response.
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate that the interrupted channel has NOT been closed yet.
 	timer := time.NewTimer(100 * time.Millisecond)
 	select {
 	case <-interrupted:
@@ -660,6 +1610,97 @@ func TestHandler_PromRead(t *testing.T) {
 	}
 }
 
+// Ensure Prometheus metrics can be written to and read from measurements named after them.
+func TestHandler_PromWriteRead_MetricMeasurements(t *testing.T) {
+	config := httpd.NewConfig()
+	config.PrometheusMeasurement = ""
+	config.PrometheusMeasurementPrefix = "prom_"
+	config.PrometheusField = "value"
+	config.PrometheusDropLabels = []string{"job"}
+	h := NewHandlerWithConfig(config)
+
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	var written []models.Point
+	h.PointsWriter.WritePointsFn = func(db, rp string, _ models.ConsistencyLevel, _ meta.User, points []models.Point) error {
+		written = points
+		return nil
+	}
+
+	data, err := proto.Marshal(&remote.WriteRequest{
+		Timeseries: []*remote.TimeSeries{{
+			Labels: []*remote.LabelPair{
+				{Name: "__name__", Value: "http_requests"},
+				{Name: "host", Value: "a"},
+				{Name: "job", Value: "web"},
+			},
+			Samples: []*remote.Sample{{TimestampMs: 1, Value: 1.2}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/api/v1/prom/write?db=foo", bytes.NewReader(snappy.Encode(nil, data))))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if len(written) != 1 {
+		t.Fatalf("unexpected points: %v", written)
+	} else if got, exp := written[0].String(), "prom_http_requests,host=a value=1.2 1000000"; got != exp {
+		t.Fatalf("unexpected point\n\texp: %s\n\tgot: %s", exp, got)
+	}
+
+	data, err = proto.Marshal(&remote.ReadRequest{
+		Queries: []*remote.Query{{
+			Matchers: []*remote.LabelMatcher{
+				{Type: remote.MatchType_REGEX_MATCH, Name: "__name__", Value: "http_.*"},
+				{Type: remote.MatchType_EQUAL, Name: "host", Value: "a"},
+			},
+			StartTimestampMs: 1,
+			EndTimestampMs:   2,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		if stmt.String() != `SELECT value FROM foo../^prom_(?:http_.*)$/ WHERE host = 'a' AND time >= '1970-01-01T00:00:00.001Z' AND time <= '1970-01-01T00:00:00.002Z' GROUP BY *` {
+			t.Fatalf("unexpected query: %s", stmt.String())
+		}
+		row := &models.Row{
+			Name:    "prom_http_requests",
+			Tags:    map[string]string{"host": "a"},
+			Columns: []string{"time", "value"},
+			Values:  [][]interface{}{{time.Unix(23, 0), 1.2}},
+		}
+		ctx.Results <- &query.Result{StatementID: 1, Series: models.Rows([]*models.Row{row})}
+		return nil
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("POST", "/api/v1/prom/read?db=foo", bytes.NewReader(snappy.Encode(nil, data))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	respBuf, err := snappy.Decode(nil, w.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp remote.ReadResponse
+	if err := proto.Unmarshal(respBuf, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	expLabels := []*remote.LabelPair{{Name: "host", Value: "a"}, {Name: "__name__", Value: "http_requests"}}
+	if got := resp.Results[0].Timeseries[0].Labels; !reflect.DeepEqual(expLabels, got) {
+		t.Fatalf("unexpected labels\n\texp: %v\n\tgot: %v", expLabels, got)
+	}
+}
+
 // Ensure the handler handles ping requests correctly.
 // TODO: This should be expanded to verify the MetaClient check in servePing is working correctly
 func TestHandler_Ping(t *testing.T) {
@@ -675,6 +1716,50 @@ func TestHandler_Ping(t *testing.T) {
 	}
 }
 
+// Ensure the health and readiness endpoints report the checks of the
+// subsystems, failing if any check fails.
+func TestHandler_Health(t *testing.T) {
+	h := NewHandler(false)
+
+	var ready bool
+	checks := []httpd.HealthCheck{{Name: "tsdb", Status: httpd.HealthPass, Message: "2 shards open"}}
+	h.Handler.Health = &HandlerHealth{
+		HealthChecksFn: func(r bool) []httpd.HealthCheck {
+			ready = r
+			return checks
+		},
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if ready {
+		t.Fatal("unexpected readiness checks")
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"status":"pass","checks":[{"name":"tsdb","status":"pass","message":"2 shards open"}]}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	checks = append(checks, httpd.HealthCheck{Name: "server", Status: httpd.HealthFail, Message: "shutting down"})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/ready", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if !ready {
+		t.Fatal("expected readiness checks")
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"status":"fail","checks":[{"name":"tsdb","status":"pass","message":"2 shards open"},{"name":"server","status":"fail","message":"shutting down"}]}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("HEAD", "/ready", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Body.Len() != 0 {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
 // Ensure the handler returns the version correctly from the different endpoints.
 func TestHandler_Version(t *testing.T) {
 	h := NewHandler(false)
@@ -708,49 +1793,210 @@ func TestHandler_Version(t *testing.T) {
 		},
 	}
 
-	for _, test := range tests {
-		w := httptest.NewRecorder()
-		h.ServeHTTP(w, MustNewRequest(test.method, test.endpoint, test.body))
-		if v := w.HeaderMap["X-Influxdb-Version"]; len(v) > 0 {
-			if v[0] != "0.0.0" {
-				t.Fatalf("unexpected version: %s", v)
-			}
-		} else {
-			t.Fatalf("Header entry 'X-Influxdb-Version' not present")
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest(test.method, test.endpoint, test.body))
+		if v := w.HeaderMap["X-Influxdb-Version"]; len(v) > 0 {
+			if v[0] != "0.0.0" {
+				t.Fatalf("unexpected version: %s", v)
+			}
+		} else {
+			t.Fatalf("Header entry 'X-Influxdb-Version' not present")
+		}
+
+		if v := w.HeaderMap["X-Influxdb-Build"]; len(v) > 0 {
+			if v[0] != "OSS" {
+				t.Fatalf("unexpected BuildType: %s", v)
+			}
+		} else {
+			t.Fatalf("Header entry 'X-Influxdb-Build' not present")
+		}
+	}
+}
+
+// Ensure the handler handles status requests correctly.
+func TestHandler_Status(t *testing.T) {
+	h := NewHandler(false)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/status", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+	h.ServeHTTP(w, MustNewRequest("HEAD", "/status", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure write endpoint can handle bad requests
+func TestHandler_HandleBadRequestBody(t *testing.T) {
+	b := bytes.NewReader(make([]byte, 10))
+	h := NewHandler(false)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write", b))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure API tokens authenticate as their user, limited to the privileges of the token.
+func TestHandler_TokenAuth(t *testing.T) {
+	h := NewHandler(true)
+	h.MetaClient.AdminUserExistsFn = func() bool { return true }
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	h.MetaClient.AuthenticateTokenFn = func(token string) (meta.User, error) {
+		if token != "abc.secret" {
+			return nil, meta.ErrAuthenticate
+		}
+		return &meta.TokenUser{
+			UserInfo: &meta.UserInfo{Name: "user1", Admin: true},
+			Token:    meta.TokenInfo{ID: "abc", User: "user1", Database: "foo", Privilege: influxql.ReadPrivilege},
+		}, nil
+	}
+	h.QueryAuthorizer.AuthorizeQueryFn = func(u meta.User, query *influxql.Query, database string) error {
+		if u == nil || u.ID() != "user1" {
+			t.Fatalf("unexpected user: %v", u)
+		}
+		return nil
+	}
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		ctx.Results <- &query.Result{StatementID: 0, Series: models.Rows([]*models.Row{{Name: "series0"}})}
+		return nil
+	}
+	h.Handler.WriteAuthorizer = &HandlerWriteAuthorizer{
+		AuthorizeWriteFn: func(username, database string) error { return nil },
+	}
+	h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, _ []models.Point) error {
+		t.Fatal("unexpected write")
+		return nil
+	}
+
+	// A valid token authenticates the query.
+	req := MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+	req.Header.Set("Authorization", "Token abc.secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	// An invalid token doesn't.
+	req.Header.Set("Authorization", "Token abc.wrong")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	// A read token can't write, even though its user can.
+	req = MustNewRequest("POST", "/write?db=foo", strings.NewReader("cpu value=1"))
+	req.Header.Set("Authorization", "Token abc.secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandler_JWT_JWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"use": "sig",
+				"kid": "key1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer jwks.Close()
+
+	config := httpd.NewConfig()
+	config.AuthEnabled = true
+	config.JWKSURL = jwks.URL
+	config.JWTPrivilegesClaim = "influxdb_privileges"
+	h := NewHandlerWithConfig(config)
+	h.MetaClient.AdminUserExistsFn = func() bool { return true }
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	h.MetaClient.UserFn = func(username string) (meta.User, error) {
+		t.Fatalf("unexpected user lookup: %s", username)
+		return nil, nil
+	}
+	h.QueryAuthorizer.AuthorizeQueryFn = func(u meta.User, query *influxql.Query, database string) error {
+		if u == nil || u.ID() != "user1" || !u.AuthorizeDatabase(influxql.ReadPrivilege, "foo") {
+			t.Fatalf("unexpected user: %v", u)
 		}
+		return nil
+	}
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		ctx.Results <- &query.Result{StatementID: 0, Series: models.Rows([]*models.Row{{Name: "series0"}})}
+		return nil
+	}
+	h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, _ []models.Point) error {
+		return nil
+	}
 
-		if v := w.HeaderMap["X-Influxdb-Build"]; len(v) > 0 {
-			if v[0] != "OSS" {
-				t.Fatalf("unexpected BuildType: %s", v)
-			}
-		} else {
-			t.Fatalf("Header entry 'X-Influxdb-Build' not present")
-		}
+	token := jwt.New(jwt.SigningMethodRS256)
+	token.Header["kid"] = "key1"
+	token.Claims = jwt.MapClaims{
+		"username":            "user1",
+		"exp":                 time.Now().Add(time.Minute).Unix(),
+		"influxdb_privileges": map[string]string{"foo": "read", "bar": "write"},
+	}
+	signedToken, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
 	}
-}
 
-// Ensure the handler handles status requests correctly.
-func TestHandler_Status(t *testing.T) {
-	h := NewHandler(false)
+	// A token signed with a key of the JWKS authenticates the query.
+	req := MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", signedToken))
 	w := httptest.NewRecorder()
-	h.ServeHTTP(w, MustNewRequest("GET", "/status", nil))
-	if w.Code != http.StatusNoContent {
-		t.Fatalf("unexpected status: %d", w.Code)
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
 	}
-	h.ServeHTTP(w, MustNewRequest("HEAD", "/status", nil))
+
+	// The privileges of the token allow writes to bar, but not to foo.
+	req = MustNewRequest("POST", "/write?db=bar", strings.NewReader("cpu value=1"))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", signedToken))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
 	if w.Code != http.StatusNoContent {
-		t.Fatalf("unexpected status: %d", w.Code)
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
 	}
-}
 
-// Ensure write endpoint can handle bad requests
-func TestHandler_HandleBadRequestBody(t *testing.T) {
-	b := bytes.NewReader(make([]byte, 10))
-	h := NewHandler(false)
-	w := httptest.NewRecorder()
-	h.ServeHTTP(w, MustNewRequest("POST", "/write", b))
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("unexpected status: %d", w.Code)
+	req = MustNewRequest("POST", "/write?db=foo", strings.NewReader("cpu value=1"))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", signedToken))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	}
+
+	// A token signed with another key is rejected.
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signedToken, err = token.SignedString(other); err != nil {
+		t.Fatal(err)
+	}
+	req = MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", signedToken))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
 	}
 }
 
@@ -793,6 +2039,93 @@ func TestHandler_Write_EntityTooLarge_NoContentLength(t *testing.T) {
 	}
 }
 
+// Ensure the handler decodes write bodies compressed with gzip, zstd or snappy.
+func TestHandler_Write_ContentEncoding(t *testing.T) {
+	data := []byte("cpu value=1 0\ncpu value=2 10\n")
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write(data)
+	gw.Close()
+
+	var zs bytes.Buffer
+	zw, err := zstd.NewWriter(&zs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Write(data)
+	zw.Close()
+
+	var sn bytes.Buffer
+	sw := snappy.NewBufferedWriter(&sn)
+	sw.Write(data)
+	sw.Close()
+
+	for _, tt := range []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{name: "gzip", encoding: "gzip", body: gz.Bytes()},
+		{name: "zstd", encoding: "zstd", body: zs.Bytes()},
+		{name: "snappy framing", encoding: "snappy", body: sn.Bytes()},
+		{name: "snappy block", encoding: "snappy", body: snappy.Encode(nil, data)},
+	} {
+		h := NewHandler(false)
+		h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+			return &meta.DatabaseInfo{}
+		}
+		var n int
+		h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, points []models.Point) error {
+			n = len(points)
+			return nil
+		}
+
+		req := MustNewRequest("POST", "/write?db=foo", bytes.NewReader(tt.body))
+		req.Header.Set("Content-Encoding", tt.encoding)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("%s: unexpected status: %d: %s", tt.name, w.Code, w.Body.String())
+		} else if n != 2 {
+			t.Fatalf("%s: unexpected points written: %d", tt.name, n)
+		}
+	}
+}
+
+// Ensure the maximum body size applies to the decoded body of a write.
+func TestHandler_Write_ContentEncoding_EntityTooLarge(t *testing.T) {
+	var zs bytes.Buffer
+	zw, err := zstd.NewWriter(&zs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Write(make([]byte, 1000))
+	zw.Close()
+
+	for _, tt := range []struct {
+		encoding string
+		body     []byte
+	}{
+		{encoding: "zstd", body: zs.Bytes()},
+		{encoding: "snappy", body: snappy.Encode(nil, make([]byte, 1000))},
+	} {
+		h := NewHandler(false)
+		h.Config.MaxBodySize = 100
+		h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+			return &meta.DatabaseInfo{}
+		}
+
+		req := MustNewRequest("POST", "/write?db=foo", bytes.NewReader(tt.body))
+		req.Header.Set("Content-Encoding", tt.encoding)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Fatalf("%s: unexpected status: %d", tt.encoding, w.Code)
+		}
+	}
+}
+
 // TestHandler_Write_NegativeMaxBodySize verifies no error occurs if MaxBodySize is < 0
 func TestHandler_Write_NegativeMaxBodySize(t *testing.T) {
 	b := bytes.NewReader([]byte(`foo n=1`))
@@ -817,6 +2150,218 @@ func TestHandler_Write_NegativeMaxBodySize(t *testing.T) {
 	}
 }
 
+// Ensure points can be written as JSON.
+func TestHandler_Write_JSON(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	var written []string
+	h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, points []models.Point) error {
+		for _, p := range points {
+			written = append(written, p.String())
+		}
+		return nil
+	}
+
+	body := `[
+		{"measurement": "cpu", "tags": {"host": "a b"}, "fields": {"value": 1.5, "count": 2, "ok": true, "msg": "a \"b\""}, "time": 1},
+		{"measurement": "mem", "fields": {"value": 1.0}, "time": "1970-01-01T00:00:02Z"}
+	]`
+	req := MustNewRequest("POST", "/write?db=foo&precision=s", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if exp := []string{
+		`cpu,host=a\ b count=2i,msg="a \"b\"",ok=true,value=1.5 1000000000`,
+		`mem value=1 2000000000`,
+	}; !reflect.DeepEqual(written, exp) {
+		t.Fatalf("unexpected points written:\ngot %q\nexp %q", written, exp)
+	}
+
+	for _, tt := range []struct {
+		body string
+		err  string
+	}{
+		{body: `{"measurement": "cpu"}`, err: `expected a JSON array of points`},
+		{body: `[{"measurement": "cpu", "fields": {"value": 1}]`, err: `unable to parse JSON points`},
+		{body: `[] []`, err: `unexpected data after the JSON array of points`},
+		{body: `[1]`, err: `point 0: expected an object`},
+		{body: `[{"fields": {"value": 1}}]`, err: `point 0: measurement is required`},
+		{body: `[{"measurement": 1, "fields": {"value": 1}}]`, err: `point 0: measurement must be a string`},
+		{body: `[{"measurement": "cpu", "fields": {"value": 1}, "field": {}}]`, err: `point 0: unknown key "field"`},
+		{body: `[{"measurement": "cpu", "fields": {"value": 1}}, {"measurement": "cpu"}]`, err: `point 1: fields are required`},
+		{body: `[{"measurement": "cpu", "fields": {"value": null}}]`, err: `point 0: field "value" must not be null`},
+		{body: `[{"measurement": "cpu", "fields": {"value": [1]}}]`, err: `point 0: field "value" must be a number, string or boolean`},
+		{body: `[{"measurement": "cpu", "fields": {"value": 9223372036854775808}}]`, err: `point 0: field "value": integer 9223372036854775808 out of range`},
+		{body: `[{"measurement": "cpu", "tags": {"host": 1}, "fields": {"value": 1}}]`, err: `point 0: tag "host" must have a string value`},
+		{body: `[{"measurement": "cpu", "tags": {"host": ""}, "fields": {"value": 1}}]`, err: `point 0: tag "host" must not have an empty value`},
+		{body: `[{"measurement": "cpu", "fields": {"value": 1}, "time": 1.5}]`, err: `point 0: time must be an integer: 1.5`},
+		{body: `[{"measurement": "cpu", "fields": {"value": 1}, "time": "yesterday"}]`, err: `point 0: time must be an RFC3339 string`},
+	} {
+		written = nil
+		req := MustNewRequest("POST", "/write?db=foo", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		var resp struct {
+			Err string `json:"error"`
+		}
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: unexpected status: %d", tt.body, w.Code)
+		} else if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: unexpected body: %s", tt.body, w.Body.String())
+		} else if !strings.Contains(resp.Err, tt.err) {
+			t.Fatalf("%s: unexpected error: %s", tt.body, resp.Err)
+		} else if len(written) != 0 {
+			t.Fatalf("%s: unexpected points written: %q", tt.body, written)
+		}
+	}
+}
+
+// Ensure writes rejected by a quota return the status code of the quota.
+func TestHandler_Write_Quota(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+
+	for _, tt := range []struct {
+		quota string
+		code  int
+	}{
+		{quota: influxdb.QuotaDisk, code: http.StatusForbidden},
+		{quota: influxdb.QuotaSeries, code: http.StatusUnprocessableEntity},
+		{quota: influxdb.QuotaWrites, code: http.StatusTooManyRequests},
+	} {
+		h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, _ []models.Point) error {
+			return influxdb.QuotaError{Database: "foo", Quota: tt.quota, Limit: 10}
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo", strings.NewReader("cpu value=1")))
+		if w.Code != tt.code {
+			t.Fatalf("%s: unexpected status: %d", tt.quota, w.Code)
+		} else if !strings.Contains(w.Body.String(), "quota exceeded") {
+			t.Fatalf("%s: unexpected body: %s", tt.quota, w.Body.String())
+		}
+	}
+}
+
+// Ensure writes over the rate limit of their database are rejected with a
+// Retry-After header.
+func TestHandler_Write_RateLimit(t *testing.T) {
+	config := httpd.NewConfig()
+	config.DatabaseWriteRateLimit = 2
+	h := NewHandlerWithConfig(config)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, _ []models.Point) error {
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo", strings.NewReader("cpu value=1\ncpu value=2 1\ncpu value=3 2")))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo", strings.NewReader("cpu value=1")))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("unexpected Retry-After: %q", w.Header().Get("Retry-After"))
+	}
+
+	var found bool
+	for _, st := range h.Statistics(nil) {
+		if st.Name == "httpd" && st.Values["writeReqRateLimited"] != int64(1) {
+			t.Fatalf("unexpected rate limited writes: %v", st.Values["writeReqRateLimited"])
+		} else if st.Name == "rateLimit" && st.Tags["database"] == "foo" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected rate limit statistics for the database")
+	}
+}
+
+// Ensure the skew between written timestamps and server time is reported.
+func TestHandler_Write_ClockSkew(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, _ []models.Point) error {
+		return nil
+	}
+
+	// The newest timestamp of each write is sampled; points without a
+	// timestamp are ignored.
+	ahead := time.Now().Add(time.Hour).Unix()
+	for _, body := range []string{
+		fmt.Sprintf("cpu value=1 %d\ncpu value=2 %d", ahead-3600, ahead),
+		"cpu value=3",
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&precision=s", strings.NewReader(body)))
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("unexpected status: %d", w.Code)
+		}
+	}
+
+	var found bool
+	for _, s := range h.Statistics(nil) {
+		if s.Name != "clockSkew" {
+			continue
+		}
+		found = true
+
+		if s.Tags["database"] != "foo" {
+			t.Fatalf("unexpected tags: %v", s.Tags)
+		} else if n := s.Values["samples"].(int64); n != 1 {
+			t.Fatalf("unexpected samples: %d", n)
+		} else if p50 := time.Duration(s.Values["p50"].(int64)); p50 < 59*time.Minute || p50 > time.Hour {
+			t.Fatalf("unexpected p50: %s", p50)
+		}
+	}
+	if !found {
+		t.Fatal("expected clockSkew statistics")
+	}
+}
+
+// Ensure the CORS headers are only sent to the allowed origins.
+func TestHandler_CORS(t *testing.T) {
+	config := httpd.NewConfig()
+	config.CORS.AllowedOrigins = []string{"https://grafana.example.com"}
+	config.CORS.MaxAge = toml.Duration(10 * time.Minute)
+	h := NewHandlerWithConfig(config)
+
+	req := MustNewRequest("OPTIONS", "/query", nil)
+	req.Header.Set("Origin", "https://grafana.example.com")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://grafana.example.com" {
+		t.Fatalf("unexpected allowed origin: %q", got)
+	} else if got := w.Header().Get("Access-Control-Allow-Methods"); got != "DELETE, GET, OPTIONS, POST, PUT" {
+		t.Fatalf("unexpected allowed methods: %q", got)
+	} else if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Fatalf("unexpected max age: %q", got)
+	}
+
+	req = MustNewRequest("OPTIONS", "/query", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("unexpected allowed origin: %q", got)
+	}
+}
+
 // Ensure X-Forwarded-For header writes the correct log message.
 func TestHandler_XForwardedFor(t *testing.T) {
 	var buf bytes.Buffer
@@ -900,6 +2445,61 @@ func TestHandler_XRequestId(t *testing.T) {
 	}
 }
 
+// Ensure the JSON access log records the query and its outcome, and skips
+// the disabled endpoints.
+func TestHandler_AccessLogJSON(t *testing.T) {
+	var buf bytes.Buffer
+	config := httpd.NewConfig()
+	config.AccessLogFormat = httpd.AccessLogFormatJSON
+	config.AccessLogDisabledEndpoints = []string{"ping"}
+	h := NewHandlerWithConfig(config)
+	h.JSONLogger = log.New(&buf, "", 0)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		ctx.Results <- &query.Result{StatementID: 0, Series: models.Rows([]*models.Row{{Name: "cpu", Columns: []string{"time", "value"}, Values: [][]interface{}{{1, 2}, {3, 4}}}})}
+		return nil
+	}
+
+	req := MustNewRequest("GET", "/query?db=foo&q=select+*+from+cpu", nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry struct {
+		ClientIP       string   `json:"clientIp"`
+		Endpoint       string   `json:"endpoint"`
+		Database       string   `json:"database"`
+		StatementTypes []string `json:"statementTypes"`
+		Query          string   `json:"query"`
+		Status         int      `json:"status"`
+		Rows           int      `json:"rows"`
+		Bytes          int      `json:"bytes"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("unexpected log line %q: %s", buf.String(), err)
+	} else if entry.ClientIP != "127.0.0.1" {
+		t.Fatalf("unexpected client ip: %s", entry.ClientIP)
+	} else if entry.Endpoint != "query" {
+		t.Fatalf("unexpected endpoint: %s", entry.Endpoint)
+	} else if entry.Database != "foo" {
+		t.Fatalf("unexpected database: %s", entry.Database)
+	} else if len(entry.StatementTypes) != 1 || entry.StatementTypes[0] != "SelectStatement" {
+		t.Fatalf("unexpected statement types: %v", entry.StatementTypes)
+	} else if entry.Query != "SELECT * FROM cpu" {
+		t.Fatalf("unexpected query: %s", entry.Query)
+	} else if entry.Status != http.StatusOK {
+		t.Fatalf("unexpected status: %d", entry.Status)
+	} else if entry.Rows != 2 {
+		t.Fatalf("unexpected rows: %d", entry.Rows)
+	} else if entry.Bytes == 0 {
+		t.Fatal("expected bytes to be logged")
+	}
+
+	buf.Reset()
+	h.ServeHTTP(httptest.NewRecorder(), MustNewRequest("GET", "/ping", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected log line for disabled endpoint: %s", buf.String())
+	}
+}
+
 // NewHandler represents a test wrapper for httpd.Handler.
 type Handler struct {
 	*httpd.Handler
@@ -913,6 +2513,11 @@ type Handler struct {
 func NewHandler(requireAuthentication bool) *Handler {
 	config := httpd.NewConfig()
 	config.AuthEnabled = requireAuthentication
+	return NewHandlerWithConfig(config)
+}
+
+// NewHandlerWithConfig returns a new instance of Handler with the given config.
+func NewHandlerWithConfig(config httpd.Config) *Handler {
 	config.SharedSecret = "super secret key"
 
 	h := &Handler{
@@ -931,6 +2536,20 @@ func NewHandler(requireAuthentication bool) *Handler {
 	return h
 }
 
+// HandlerStore is a mock implementation of Handler.Store.
+type HandlerStore struct {
+	ShardsStorageFn    func() []tsdb.ShardStorage
+	DatabasesStorageFn func() ([]tsdb.DatabaseStorage, error)
+}
+
+func (s *HandlerStore) ShardsStorage() []tsdb.ShardStorage {
+	return s.ShardsStorageFn()
+}
+
+func (s *HandlerStore) DatabasesStorage() ([]tsdb.DatabaseStorage, error) {
+	return s.DatabasesStorageFn()
+}
+
 // HandlerStatementExecutor is a mock implementation of Handler.StatementExecutor.
 type HandlerStatementExecutor struct {
 	ExecuteStatementFn func(stmt influxql.Statement, ctx query.ExecutionContext) error
@@ -940,6 +2559,15 @@ func (e *HandlerStatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx
 	return e.ExecuteStatementFn(stmt, ctx)
 }
 
+// HandlerWriteAuthorizer is a mock implementation of Handler.WriteAuthorizer.
+type HandlerWriteAuthorizer struct {
+	AuthorizeWriteFn func(username, database string) error
+}
+
+func (a *HandlerWriteAuthorizer) AuthorizeWrite(username, database string) error {
+	return a.AuthorizeWriteFn(username, database)
+}
+
 // HandlerQueryAuthorizer is a mock implementation of Handler.QueryAuthorizer.
 type HandlerQueryAuthorizer struct {
 	AuthorizeQueryFn func(u meta.User, query *influxql.Query, database string) error
@@ -949,6 +2577,87 @@ func (a *HandlerQueryAuthorizer) AuthorizeQuery(u meta.User, query *influxql.Que
 	return a.AuthorizeQueryFn(u, query, database)
 }
 
+// HandlerGraphite is a mock implementation of Handler.Graphite.
+type HandlerGraphite struct {
+	ReloadGraphiteTemplatesFn func() error
+	TestGraphiteTemplatesFn   func(bindAddress string, templates []string, line string) (models.Point, error)
+}
+
+func (g *HandlerGraphite) ReloadGraphiteTemplates() error {
+	return g.ReloadGraphiteTemplatesFn()
+}
+
+func (g *HandlerGraphite) TestGraphiteTemplates(bindAddress string, templates []string, line string) (models.Point, error) {
+	return g.TestGraphiteTemplatesFn(bindAddress, templates, line)
+}
+
+// HandlerConfigReloader is a mock implementation of Handler.ConfigReloader.
+type HandlerConfigReloader struct {
+	ReloadFn func() (applied, restartRequired []string, err error)
+}
+
+func (r *HandlerConfigReloader) Reload() (applied, restartRequired []string, err error) {
+	return r.ReloadFn()
+}
+
+// HandlerHealth is a mock implementation of Handler.Health.
+type HandlerHealth struct {
+	HealthChecksFn func(ready bool) []httpd.HealthCheck
+}
+
+func (h *HandlerHealth) HealthChecks(ready bool) []httpd.HealthCheck {
+	return h.HealthChecksFn(ready)
+}
+
+// HandlerTracer is a mock implementation of Handler.Tracer.
+type HandlerTracer struct {
+	StartTraceFn func(name string, header http.Header) (*tracing.Trace, *tracing.Span)
+	ReportFn     func(t *tracing.Trace)
+}
+
+func (t *HandlerTracer) StartTrace(name string, header http.Header) (*tracing.Trace, *tracing.Span) {
+	return t.StartTraceFn(name, header)
+}
+
+func (t *HandlerTracer) Report(trace *tracing.Trace) { t.ReportFn(trace) }
+
+// HandlerAuditor is a mock implementation of Handler.Auditor that records
+// the actions audited.
+type HandlerAuditor struct {
+	entries []string
+}
+
+func (a *HandlerAuditor) AuditAction(action, user, addr, target string, err error) {
+	e := action + " " + target
+	if err != nil {
+		e += ": " + err.Error()
+	}
+	a.entries = append(a.entries, e)
+}
+
+// HandlerLogLevels is a mock implementation of Handler.LogLevels.
+type HandlerLogLevels struct {
+	level    string
+	services map[string]string
+}
+
+func (l *HandlerLogLevels) Level() string { return l.level }
+
+func (l *HandlerLogLevels) ServiceLevels() map[string]string { return l.services }
+
+func (l *HandlerLogLevels) SetLevel(service, level string) error {
+	if _, err := logger.ParseLevel(level); err != nil {
+		return err
+	} else if service == "" {
+		l.level = level
+	} else {
+		l.services[service] = level
+	}
+	return nil
+}
+
+func (l *HandlerLogLevels) ResetLevel(service string) { delete(l.services, service) }
+
 type HandlerPointsWriter struct {
 	WritePointsFn func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error
 }
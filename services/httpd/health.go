@@ -0,0 +1,59 @@
+package httpd
+
+import (
+	"net/http"
+)
+
+// Statuses of a health check.
+const (
+	HealthPass = "pass"
+	HealthFail = "fail"
+)
+
+// HealthCheck is the status of a subsystem of the server.
+type HealthCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// health is the response of the /health and /ready endpoints.
+type health struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// serveHealth reports the status of the subsystems storing data.  It returns
+// 503 if any of them fails.
+func (h *Handler) serveHealth(w http.ResponseWriter, r *http.Request) {
+	h.writeHealth(w, r, false)
+}
+
+// serveReady reports whether the server is ready for traffic: it is started
+// and not shutting down, its services are running and its subsystems are
+// healthy.  It returns 503 if not, so load balancers can stop sending
+// requests to the server.
+func (h *Handler) serveReady(w http.ResponseWriter, r *http.Request) {
+	h.writeHealth(w, r, true)
+}
+
+func (h *Handler) writeHealth(w http.ResponseWriter, r *http.Request, ready bool) {
+	res := health{Status: HealthPass, Checks: []HealthCheck{}}
+	if h.Health != nil {
+		res.Checks = append(res.Checks, h.Health.HealthChecks(ready)...)
+	}
+
+	code := http.StatusOK
+	for _, c := range res.Checks {
+		if c.Status == HealthFail {
+			res.Status, code = HealthFail, http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	if r.Method == "HEAD" {
+		h.writeHeader(w, code)
+		return
+	}
+	h.writeAdminJSON(w, code, res)
+}
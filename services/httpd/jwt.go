@@ -0,0 +1,221 @@
+package httpd
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+const (
+	// jwksMinRefetchInterval is the shortest time between fetches of the keys
+	// of a JSON Web Key Set when a JWT is signed with an unknown key.
+	jwksMinRefetchInterval = time.Minute
+
+	// jwksTimeout is the timeout of requests for the keys of a JSON Web Key Set.
+	jwksTimeout = 10 * time.Second
+)
+
+// jwtKey returns the key the signature of token is verified with: the
+// shared secret for HMAC signed tokens and a key of the JWKS URL for RSA
+// signed tokens.
+func (h *Handler) jwtKey(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if h.Config.SharedSecret == "" {
+			return nil, errors.New("shared-secret required to verify HMAC signed tokens")
+		}
+		return []byte(h.Config.SharedSecret), nil
+	case *jwt.SigningMethodRSA:
+		if h.jwks == nil {
+			return nil, errors.New("jwks-url required to verify RSA signed tokens")
+		}
+		kid, _ := token.Header["kid"].(string)
+		return h.jwks.Key(kid)
+	default:
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+}
+
+// newJWTUser returns the user named username with the privileges of the
+// privileges claim of a JWT, an object of database names to privileges, and
+// the admin claim.
+func newJWTUser(username string, privileges, admin interface{}) (*meta.ExternalUser, error) {
+	m, ok := privileges.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("privileges in token must be an object")
+	}
+
+	u := &meta.UserInfo{
+		Name:       username,
+		Privileges: make(map[string]influxql.Privilege, len(m)),
+	}
+	u.Admin, _ = admin.(bool)
+	for db, v := range m {
+		s, _ := v.(string)
+		p, err := influxql.ParsePrivilege(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid privilege in token for database %q: %v", db, v)
+		}
+		u.Privileges[db] = p
+	}
+	return &meta.ExternalUser{UserInfo: u}, nil
+}
+
+// jwks holds the RSA keys of a JSON Web Key Set, fetched from a URL.
+type jwks struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+
+	// fetching is closed when the fetch in progress completes, with err set
+	// to its error.  It is nil when no fetch is in progress.
+	fetching chan struct{}
+	err      error
+}
+
+// newJWKS returns a JSON Web Key Set fetched from url every refresh.
+func newJWKS(url string, refresh time.Duration) *jwks {
+	return &jwks{
+		url:     url,
+		refresh: refresh,
+		client:  &http.Client{Timeout: jwksTimeout},
+	}
+}
+
+// Key returns the key with the given ID. An empty ID matches the only key of
+// a set with a single key. The keys are fetched again when they are older
+// than the refresh interval, or when the key is unknown and they weren't
+// fetched within the last minute. Keys that were fetched before are kept
+// while the URL is unavailable, and returned while they are fetched again.
+func (s *jwks) Key(kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	age := time.Since(s.fetched)
+	key := s.key(kid)
+	if s.keys == nil || age >= s.refresh || (key == nil && age >= jwksMinRefetchInterval) {
+		fetching := s.startFetch()
+		if key != nil {
+			return key, nil
+		}
+
+		// Wait for the fetch of an unknown key, without holding the lock.
+		s.mu.Unlock()
+		<-fetching
+		s.mu.Lock()
+
+		if key = s.key(kid); key == nil && s.err != nil {
+			return nil, s.err
+		}
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("unknown jwks key %q", kid)
+	}
+	return key, nil
+}
+
+// startFetch fetches the keys of the set in the background, unless a fetch
+// is already in progress, and returns the channel closed when the fetch
+// completes.  s.mu must be held.
+func (s *jwks) startFetch() <-chan struct{} {
+	if s.fetching != nil {
+		return s.fetching
+	}
+
+	fetching := make(chan struct{})
+	s.fetching = fetching
+	go func() {
+		keys, err := s.fetch()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err == nil {
+			s.keys, s.fetched = keys, time.Now()
+		}
+		s.err, s.fetching = err, nil
+		close(fetching)
+	}()
+	return fetching
+}
+
+func (s *jwks) key(kid string) *rsa.PublicKey {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key
+		}
+	}
+	return s.keys[kid]
+}
+
+// fetch fetches the RSA signing keys of the set.
+func (s *jwks) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch jwks: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch jwks: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("unable to decode jwks: %s", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := decodeBase64URLInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of jwks key %q: %s", k.Kid, err)
+		}
+		e, err := decodeBase64URLInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of jwks key %q: %s", k.Kid, err)
+		} else if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid exponent of jwks key %q", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	}
+	return keys, nil
+}
+
+// decodeBase64URLInt decodes a big-endian integer encoded as unpadded
+// base64url, as the integers of JSON Web Keys are.
+func decodeBase64URLInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	} else if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
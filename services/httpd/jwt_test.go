@@ -0,0 +1,65 @@
+package httpd
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Ensure cached keys are returned while the keys are fetched again.
+func TestJWKS_Key_Refresh(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int64
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&requests, 1) > 1 {
+			<-release
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	s := newJWKS(ts.URL, time.Hour)
+	if k, err := s.Key("key1"); err != nil || k.N.Cmp(key.N) != 0 {
+		t.Fatalf("unexpected key: %v, %v", k, err)
+	}
+
+	// The keys are stale, so they are fetched again in the background.
+	s.mu.Lock()
+	s.fetched = s.fetched.Add(-time.Hour)
+	s.mu.Unlock()
+	for i := 0; i < 2; i++ {
+		if k, err := s.Key("key1"); err != nil || k.N.Cmp(key.N) != 0 {
+			t.Fatalf("unexpected key: %v, %v", k, err)
+		}
+	}
+
+	// A single fetch is in progress.
+	s.mu.Lock()
+	fetching := s.fetching
+	s.mu.Unlock()
+	if fetching == nil {
+		t.Fatal("expected a fetch in progress")
+	} else if n := atomic.LoadInt64(&requests); n > 2 {
+		t.Fatalf("unexpected number of requests: %d", n)
+	}
+}
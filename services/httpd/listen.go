@@ -8,14 +8,21 @@ import (
 // LimitListener returns a Listener that accepts at most n simultaneous
 // connections from the provided Listener and will drop extra connections.
 func LimitListener(l net.Listener, n int) net.Listener {
-	return &limitListener{Listener: l, sem: make(chan struct{}, n)}
+	return newLimitListener(l, n, nil)
+}
+
+// newLimitListener returns a limit listener calling rejected, if not nil,
+// for each connection it drops.
+func newLimitListener(l net.Listener, n int, rejected func()) net.Listener {
+	return &limitListener{Listener: l, sem: make(chan struct{}, n), rejected: rejected}
 }
 
 // limitListener is a listener that limits the number of active connections
 // at any given time.
 type limitListener struct {
 	net.Listener
-	sem chan struct{}
+	sem      chan struct{}
+	rejected func()
 }
 
 func (l *limitListener) release() {
@@ -34,6 +41,9 @@ func (l *limitListener) Accept() (net.Conn, error) {
 			return &limitListenerConn{Conn: c, release: l.release}, nil
 		default:
 			c.Close()
+			if l.rejected != nil {
+				l.rejected()
+			}
 		}
 	}
 }
@@ -0,0 +1,166 @@
+package httpd
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/influxdata/influxdb/monitor"
+)
+
+// metricsNamespace is the prefix of the names of all metrics served on /metrics.
+const metricsNamespace = "influxdb"
+
+// serveMetrics serves the monitor statistics in the Prometheus text
+// exposition format. Each numeric value of a statistic is a metric named
+// after the statistic and value, such as influxdb_httpd_points_written_ok,
+// labeled with the statistic's tags.
+func (h *Handler) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.Monitor.Statistics(nil)
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	writeMetrics(w, stats)
+}
+
+// metricSample is a sample of a metric, with its labels formatted.
+type metricSample struct {
+	labels string
+	value  string
+}
+
+// writeMetrics writes stats to w in the Prometheus text exposition format.
+// The samples of each metric are written together, as the format requires.
+func writeMetrics(w io.Writer, stats []*monitor.Statistic) {
+	metrics := make(map[string][]metricSample)
+	seen := make(map[string]bool)
+	for _, s := range stats {
+		labels := formatMetricLabels(s.Tags)
+		for _, k := range s.ValueNames() {
+			value, ok := formatMetricValue(s.Values[k])
+			if !ok {
+				continue
+			}
+
+			name := metricsNamespace + "_" + metricName(s.Name) + "_" + metricName(k)
+			if key := name + labels; seen[key] {
+				// Skip duplicate series, which scrapers reject.
+				continue
+			} else {
+				seen[key] = true
+			}
+			metrics[name] = append(metrics[name], metricSample{labels: labels, value: value})
+		}
+	}
+
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		// Statistics do not say whether they are counters or gauges.
+		bw.WriteString("# TYPE " + name + " untyped\n")
+		for _, s := range metrics[name] {
+			bw.WriteString(name)
+			bw.WriteString(s.labels)
+			bw.WriteByte(' ')
+			bw.WriteString(s.value)
+			bw.WriteByte('\n')
+		}
+	}
+	bw.Flush()
+}
+
+// metricName converts a camel case statistic or value name, such as
+// "pointsWrittenOK", to a snake case metric name, such as
+// "points_written_ok". Characters not allowed in metric names are replaced
+// with underscores.
+func metricName(s string) string {
+	runes := []rune(s)
+	buf := make([]rune, 0, len(runes)+4)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			acronymEnd := unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || acronymEnd {
+				buf = append(buf, '_')
+			}
+		}
+
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			buf = append(buf, unicode.ToLower(r))
+		default:
+			buf = append(buf, '_')
+		}
+	}
+	return string(buf)
+}
+
+// formatMetricLabels formats tags as sorted metric labels, such as
+// {database="db0",engine="tsm1"}.
+func formatMetricLabels(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name := metricName(k)
+		if name != "" && unicode.IsDigit(rune(name[0])) {
+			name = "_" + name
+		}
+		buf.WriteString(name)
+		buf.WriteString(`="`)
+		buf.WriteString(metricLabelEscaper.Replace(tags[k]))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatMetricValue formats a numeric or boolean statistic value. It returns
+// false for other values, which are not exposed.
+func formatMetricValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case int:
+		return strconv.Itoa(v), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case bool:
+		if v {
+			return "1", true
+		}
+		return "0", true
+	default:
+		return "", false
+	}
+}
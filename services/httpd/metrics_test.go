@@ -0,0 +1,63 @@
+package httpd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/monitor"
+)
+
+func TestMetricName(t *testing.T) {
+	for _, tt := range []struct {
+		in, exp string
+	}{
+		{"httpd", "httpd"},
+		{"pointsWrittenOK", "points_written_ok"},
+		{"reqDurationNs", "req_duration_ns"},
+		{"WALCompactionTimeMs", "wal_compaction_time_ms"},
+		{"HeapAlloc", "heap_alloc"},
+		{"tsm1_engine", "tsm1_engine"},
+		{"cache-age", "cache_age"},
+	} {
+		if got := metricName(tt.in); got != tt.exp {
+			t.Errorf("metricName(%q): got %q, exp %q", tt.in, got, tt.exp)
+		}
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	stats := []*monitor.Statistic{
+		{Statistic: models.Statistic{
+			Name:   "shard",
+			Tags:   map[string]string{"id": "2", "database": "db0", "path": `C:\data "2"`},
+			Values: map[string]interface{}{"writeReq": int64(3), "diskBytes": int64(1024)},
+		}},
+		{Statistic: models.Statistic{
+			Name:   "shard",
+			Tags:   map[string]string{"id": "1", "database": "db0", "path": "/data/1"},
+			Values: map[string]interface{}{"writeReq": int64(5), "note": "skipped"},
+		}},
+		{Statistic: models.Statistic{
+			Name:   "runtime",
+			Values: map[string]interface{}{"HeapAlloc": int64(100), "GCFraction": 0.5},
+		}},
+	}
+
+	var buf bytes.Buffer
+	writeMetrics(&buf, stats)
+
+	exp := `# TYPE influxdb_runtime_gc_fraction untyped
+influxdb_runtime_gc_fraction 0.5
+# TYPE influxdb_runtime_heap_alloc untyped
+influxdb_runtime_heap_alloc 100
+# TYPE influxdb_shard_disk_bytes untyped
+influxdb_shard_disk_bytes{database="db0",id="2",path="C:\\data \"2\""} 1024
+# TYPE influxdb_shard_write_req untyped
+influxdb_shard_write_req{database="db0",id="2",path="C:\\data \"2\""} 3
+influxdb_shard_write_req{database="db0",id="1",path="/data/1"} 5
+`
+	if got := buf.String(); got != exp {
+		t.Fatalf("unexpected metrics:\ngot:\n%s\nexp:\n%s", got, exp)
+	}
+}
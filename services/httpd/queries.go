@@ -0,0 +1,76 @@
+package httpd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/audit"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// serveQueries lists the running queries as JSON. It requires the same
+// privileges as SHOW QUERIES.
+func (h *Handler) serveQueries(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.authorizeStatement(w, user, &influxql.ShowQueriesStatement{}) {
+		return
+	}
+
+	queries := h.QueryExecutor.TaskManager.Queries()
+	sort.Slice(queries, func(i, j int) bool { return queries[i].ID < queries[j].ID })
+
+	b, err := json.Marshal(struct {
+		Queries []query.QueryInfo `json:"queries"`
+	}{Queries: queries})
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	h.writeHeader(w, http.StatusOK)
+	w.Write(b)
+}
+
+// serveKillQuery kills the running query with the id in the URL. It requires
+// the same privileges as KILL QUERY.
+func (h *Handler) serveKillQuery(w http.ResponseWriter, r *http.Request, user meta.User) {
+	qid, err := strconv.ParseUint(r.URL.Query().Get(":id"), 10, 64)
+	if err != nil {
+		h.httpError(w, fmt.Sprintf("invalid query id: %q", r.URL.Query().Get(":id")), http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeStatement(w, user, &influxql.KillQueryStatement{QueryID: qid}) {
+		return
+	}
+
+	err = h.QueryExecutor.TaskManager.KillQuery(qid)
+	h.audit(r, user, audit.ActionKillQuery, fmt.Sprintf("query %d", qid), err)
+	if err == query.ErrAlreadyKilled {
+		h.httpError(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		h.httpError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	h.writeHeader(w, http.StatusNoContent)
+}
+
+// authorizeStatement checks that the user may execute a statement when
+// authentication is enabled. It writes an error and returns false if not.
+func (h *Handler) authorizeStatement(w http.ResponseWriter, user meta.User, stmt influxql.Statement) bool {
+	if !h.Config.AuthEnabled {
+		return true
+	}
+
+	q := &influxql.Query{Statements: influxql.Statements{stmt}}
+	if err := h.QueryAuthorizer.AuthorizeQuery(user, q, ""); err != nil {
+		h.httpError(w, "error authorizing query: "+err.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
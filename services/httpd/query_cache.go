@@ -0,0 +1,266 @@
+package httpd
+
+import (
+	"container/list"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+)
+
+// QueryCache holds the results of recent queries so identical queries, such
+// as the ones dashboards re-issue every few seconds, are answered without
+// executing them again.
+//
+// Queries are cached by their normalized text, which includes any retention
+// policy they read from, the database and epoch of the request, and the
+// window of the TTL the query was made in. Results are discarded once the
+// window ends or when points are written within the time range the query
+// read from.
+type QueryCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	lru     *list.List // most recently used at the front
+
+	// pending holds the entries of queries that are executing. A write that
+	// would invalidate one prevents its results from being stored.
+	pending map[*queryCacheEntry]struct{}
+
+	now func() time.Time
+}
+
+// queryCacheEntry holds the results of a query and the data they were read from.
+type queryCacheEntry struct {
+	key     string
+	sources []queryCacheSource
+	min     int64
+	max     int64
+	expires time.Time
+	stale   bool
+	results []*query.Result
+}
+
+// queryCacheSource is a database and retention policy read by a query. An
+// empty retention policy matches any retention policy.
+type queryCacheSource struct {
+	database        string
+	retentionPolicy string
+}
+
+// NewQueryCache returns a new QueryCache holding at most size queries for
+// at most ttl.
+func NewQueryCache(size int, ttl time.Duration) *QueryCache {
+	return &QueryCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		pending: make(map[*queryCacheEntry]struct{}),
+		now:     time.Now,
+	}
+}
+
+// queryCacheable returns true if the results of q can be cached. Only
+// queries made up of SELECT statements that do not write their results are
+// cached.
+func queryCacheable(q *influxql.Query) bool {
+	if len(q.Statements) == 0 {
+		return false
+	}
+	for _, stmt := range q.Statements {
+		if stmt, ok := stmt.(*influxql.SelectStatement); !ok || stmt.Target != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// queryModifiesData returns true if q contains a statement that may change
+// the results of a cached query without writing points.
+func queryModifiesData(q *influxql.Query) bool {
+	for _, stmt := range q.Statements {
+		switch stmt.(type) {
+		case *influxql.DeleteSeriesStatement,
+			*influxql.DropSeriesStatement,
+			*influxql.DropMeasurementStatement,
+			*influxql.DropShardStatement,
+			*influxql.DropRetentionPolicyStatement,
+			*influxql.AlterRetentionPolicyStatement,
+			*influxql.DropDatabaseStatement:
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the cached results of q run against database by user. On a
+// miss it returns the entry the results must be stored in with store once
+// the query has executed.
+func (c *QueryCache) lookup(q *influxql.Query, database, epoch, user string) ([]*query.Result, *queryCacheEntry) {
+	now := c.now()
+	window := now.Truncate(c.ttl)
+	key := strings.Join([]string{
+		q.String(),
+		database,
+		epoch,
+		user,
+		strconv.FormatInt(window.UnixNano(), 10),
+	}, "\x00")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*queryCacheEntry)
+		if now.Before(entry.expires) {
+			c.lru.MoveToFront(elem)
+			return entry.results, nil
+		}
+		c.remove(elem)
+	}
+
+	entry := &queryCacheEntry{
+		key:     key,
+		min:     influxql.MaxTime,
+		max:     influxql.MinTime,
+		expires: window.Add(c.ttl),
+	}
+
+	valuer := &influxql.NowValuer{Now: now}
+	for _, stmt := range q.Statements {
+		influxql.WalkFunc(stmt, func(n influxql.Node) {
+			if m, ok := n.(*influxql.Measurement); ok {
+				source := queryCacheSource{database: m.Database, retentionPolicy: m.RetentionPolicy}
+				if source.database == "" {
+					source.database = database
+				}
+				entry.sources = append(entry.sources, source)
+			}
+		})
+
+		// Statements with a time range that cannot be determined read from
+		// any time.
+		min, max := influxql.MinTime, influxql.MaxTime
+		if stmt, ok := stmt.(*influxql.SelectStatement); ok {
+			if _, tr, err := influxql.ConditionExpr(stmt.Condition, valuer); err == nil {
+				min, max = tr.MinTime(), tr.MaxTime()
+			}
+		}
+		if min < entry.min {
+			entry.min = min
+		}
+		if max > entry.max {
+			entry.max = max
+		}
+	}
+
+	c.pending[entry] = struct{}{}
+	return nil, entry
+}
+
+// store caches the results of the query of a pending entry. Results are not
+// cached if they contain an error or a write invalidated them while the
+// query was executing. Passing nil results discards the entry.
+func (c *QueryCache) store(entry *queryCacheEntry, results []*query.Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pending, entry)
+	if entry.stale || results == nil || !c.now().Before(entry.expires) {
+		return
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			return
+		}
+	}
+
+	if elem, ok := c.entries[entry.key]; ok {
+		c.remove(elem)
+	}
+	entry.results = results
+	c.entries[entry.key] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Invalidate discards the cached results of queries that read from the
+// database and retention policy within the time range of points.
+func (c *QueryCache) Invalidate(database, retentionPolicy string, points []models.Point) {
+	if len(points) == 0 {
+		return
+	}
+	min, max := points[0].UnixNano(), points[0].UnixNano()
+	for _, p := range points[1:] {
+		if t := p.UnixNano(); t < min {
+			min = t
+		} else if t > max {
+			max = t
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for entry := range c.pending {
+		if entry.reads(database, retentionPolicy, min, max) {
+			entry.stale = true
+		}
+	}
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*queryCacheEntry).reads(database, retentionPolicy, min, max) {
+			c.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// Purge discards every cached result.
+func (c *QueryCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for entry := range c.pending {
+		entry.stale = true
+	}
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// Len returns the number of cached queries.
+func (c *QueryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *QueryCache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*queryCacheEntry).key)
+}
+
+// reads returns true if the query of the entry read from the database and
+// retention policy between min and max.
+func (e *queryCacheEntry) reads(database, retentionPolicy string, min, max int64) bool {
+	if max < e.min || min > e.max {
+		return false
+	}
+	for _, s := range e.sources {
+		if s.database != database {
+			continue
+		}
+		if s.retentionPolicy == "" || retentionPolicy == "" || s.retentionPolicy == retentionPolicy {
+			return true
+		}
+	}
+	return false
+}
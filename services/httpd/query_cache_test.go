@@ -0,0 +1,133 @@
+package httpd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+)
+
+func mustParseQuery(t *testing.T, s string) *influxql.Query {
+	q, err := influxql.ParseQuery(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func mustParsePoints(t *testing.T, s string) []models.Point {
+	points, err := models.ParsePointsString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return points
+}
+
+func TestQueryCache_Lookup(t *testing.T) {
+	c := NewQueryCache(10, 10*time.Second)
+	now := time.Unix(100, 0)
+	c.now = func() time.Time { return now }
+
+	q := mustParseQuery(t, `SELECT mean(value) FROM cpu WHERE time >= now() - 1m GROUP BY time(10s)`)
+	if _, entry := c.lookup(q, "db0", "", ""); entry == nil {
+		t.Fatal("unexpected hit")
+	} else {
+		c.store(entry, []*query.Result{{StatementID: 0}})
+	}
+
+	now = now.Add(5 * time.Second)
+	if results, entry := c.lookup(q, "db0", "", ""); entry != nil {
+		t.Fatal("expected hit")
+	} else if len(results) != 1 {
+		t.Fatalf("unexpected results: %v", results)
+	}
+
+	// Queries against another database or for another epoch are not shared.
+	if _, entry := c.lookup(q, "db1", "", ""); entry == nil {
+		t.Fatal("unexpected hit for another database")
+	} else if _, entry := c.lookup(q, "db0", "s", ""); entry == nil {
+		t.Fatal("unexpected hit for another epoch")
+	}
+
+	// Results expire at the end of the window they were cached in.
+	now = now.Add(5 * time.Second)
+	if _, entry := c.lookup(q, "db0", "", ""); entry == nil {
+		t.Fatal("unexpected hit after ttl")
+	}
+}
+
+func TestQueryCache_Store_Error(t *testing.T) {
+	c := NewQueryCache(10, time.Minute)
+	q := mustParseQuery(t, `SELECT value FROM cpu`)
+
+	_, entry := c.lookup(q, "db0", "", "")
+	c.store(entry, []*query.Result{{Err: query.ErrQueryInterrupted}})
+	if n := c.Len(); n != 0 {
+		t.Fatalf("unexpected cached queries: %d", n)
+	}
+}
+
+func TestQueryCache_Evict(t *testing.T) {
+	c := NewQueryCache(2, time.Minute)
+	for _, s := range []string{`SELECT a FROM cpu`, `SELECT b FROM cpu`, `SELECT c FROM cpu`} {
+		_, entry := c.lookup(mustParseQuery(t, s), "db0", "", "")
+		c.store(entry, []*query.Result{})
+	}
+
+	if n := c.Len(); n != 2 {
+		t.Fatalf("unexpected cached queries: %d", n)
+	} else if _, entry := c.lookup(mustParseQuery(t, `SELECT a FROM cpu`), "db0", "", ""); entry == nil {
+		t.Fatal("expected least recently used query to be evicted")
+	}
+}
+
+func TestQueryCache_Invalidate(t *testing.T) {
+	c := NewQueryCache(10, time.Minute)
+	c.now = func() time.Time { return time.Unix(0, 0) }
+
+	queries := []*influxql.Query{
+		mustParseQuery(t, `SELECT value FROM cpu WHERE time >= 10s AND time < 20s`),
+		mustParseQuery(t, `SELECT value FROM rp1.cpu WHERE time >= 10s AND time < 20s`),
+		mustParseQuery(t, `SELECT value FROM db1..cpu WHERE time >= 10s AND time < 20s`),
+	}
+	for _, q := range queries {
+		_, entry := c.lookup(q, "db0", "", "")
+		c.store(entry, []*query.Result{})
+	}
+
+	// Points outside of the time range of the queries.
+	c.Invalidate("db0", "rp0", mustParsePoints(t, "cpu value=1 30000000000"))
+	if n := c.Len(); n != 3 {
+		t.Fatalf("unexpected cached queries: %d", n)
+	}
+
+	// Points in another retention policy only invalidate the query of the
+	// default retention policy.
+	c.Invalidate("db0", "rp0", mustParsePoints(t, "cpu value=1 0\ncpu value=2 15000000000"))
+	if n := c.Len(); n != 2 {
+		t.Fatalf("unexpected cached queries: %d", n)
+	} else if _, entry := c.lookup(queries[0], "db0", "", ""); entry == nil {
+		t.Fatal("expected query to be invalidated")
+	}
+
+	c.Invalidate("db1", "rp0", mustParsePoints(t, "mem value=1 15000000000"))
+	if n := c.Len(); n != 1 {
+		t.Fatalf("unexpected cached queries: %d", n)
+	}
+}
+
+// Ensure the results of a query are not cached if a write invalidates them
+// while it executes.
+func TestQueryCache_Invalidate_Pending(t *testing.T) {
+	c := NewQueryCache(10, time.Minute)
+	q := mustParseQuery(t, `SELECT value FROM cpu`)
+
+	_, entry := c.lookup(q, "db0", "", "")
+	c.Invalidate("db0", "rp0", mustParsePoints(t, "cpu value=1"))
+	c.store(entry, []*query.Result{})
+	if n := c.Len(); n != 0 {
+		t.Fatalf("unexpected cached queries: %d", n)
+	}
+}
@@ -0,0 +1,208 @@
+package httpd
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// rateLimitIdleTime is the time after which the state of an idle key is
+// forgotten.
+const rateLimitIdleTime = time.Minute
+
+// rateLimiter limits the rate and the number of concurrent requests of each
+// key, such as a user or a database.  The rate is limited with a bucket
+// holding a second's worth of tokens: a request is allowed while tokens are
+// left, even if it takes more than are left, and the next requests wait for
+// the bucket to refill.
+type rateLimiter struct {
+	// tag and request name the key and the requests in the statistics.
+	tag     string
+	request string
+
+	rate        float64
+	concurrency int
+
+	mu        sync.Mutex
+	keys      map[string]*rateLimitKey
+	lastSweep time.Time
+	now       func() time.Time
+
+	// rejected is the number of requests rejected for the forgotten keys.
+	rejected int64
+}
+
+// rateLimitKey is the state of the requests of a key.
+type rateLimitKey struct {
+	tokens   float64
+	last     time.Time
+	active   int
+	rejected int64
+}
+
+// newRateLimiter returns a limiter of rate tokens per second and of
+// concurrency requests at once for each key, or nil if both are zero.
+func newRateLimiter(tag, request string, rate, concurrency int) *rateLimiter {
+	if rate <= 0 && concurrency <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		tag:         tag,
+		request:     request,
+		rate:        float64(rate),
+		concurrency: concurrency,
+		keys:        make(map[string]*rateLimitKey),
+		now:         time.Now,
+	}
+}
+
+// take takes n tokens and a concurrent request for key.  It returns false,
+// and the time to wait before retrying, if the request is over a limit.
+// release must be called once an allowed request is done.  A nil limiter
+// allows every request.
+func (l *rateLimiter) take(key string, n int) (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	k := l.keys[key]
+	if k == nil {
+		k = &rateLimitKey{tokens: l.rate, last: now}
+		l.keys[key] = k
+	}
+
+	elapsed := now.Sub(k.last)
+	k.last = now
+
+	if l.rate > 0 {
+		if k.tokens += elapsed.Seconds() * l.rate; k.tokens > l.rate {
+			k.tokens = l.rate
+		}
+		if k.tokens <= 0 {
+			k.rejected++
+			wait := time.Duration((-k.tokens/l.rate + 1/l.rate) * float64(time.Second))
+			return wait, false
+		}
+	}
+	if l.concurrency > 0 && k.active >= l.concurrency {
+		k.rejected++
+		return time.Second, false
+	}
+
+	k.tokens -= float64(n)
+	k.active++
+	return 0, true
+}
+
+// release ends a request of key allowed by take.
+func (l *rateLimiter) release(key string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if k := l.keys[key]; k != nil && k.active > 0 {
+		k.active--
+	}
+}
+
+// sweep forgets the keys idle for a while, adding the requests rejected for
+// them to the total of the limiter.  l.mu must be held.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitIdleTime {
+		return
+	}
+	l.lastSweep = now
+
+	for key, k := range l.keys {
+		if k.active == 0 && now.Sub(k.last) >= rateLimitIdleTime {
+			l.rejected += k.rejected
+			delete(l.keys, key)
+		}
+	}
+}
+
+// statistics returns the number of requests rejected for each key, and for
+// the forgotten keys without the tag of the key.
+func (l *rateLimiter) statistics(tags map[string]string) []models.Statistic {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var statistics []models.Statistic
+	if l.rejected > 0 {
+		statistics = append(statistics, models.Statistic{
+			Name: "rateLimit",
+			Tags: models.StatisticTags{"request": l.request}.Merge(tags),
+			Values: map[string]interface{}{
+				statRateLimitRejected: l.rejected,
+			},
+		})
+	}
+	for key, k := range l.keys {
+		if k.rejected == 0 {
+			continue
+		}
+		statistics = append(statistics, models.Statistic{
+			Name: "rateLimit",
+			Tags: models.StatisticTags{l.tag: key, "request": l.request}.Merge(tags),
+			Values: map[string]interface{}{
+				statRateLimitRejected: k.rejected,
+			},
+		})
+	}
+	return statistics
+}
+
+// rateLimit takes n tokens and a concurrent request from the limiters of
+// the user and the database of a request.  Requests of anonymous users are
+// only limited by database.  If the request is over a limit, a 429 response
+// is written and false returned.  Otherwise the returned function must be
+// called once the request is done.
+func (h *Handler) rateLimit(w http.ResponseWriter, byUser, byDatabase *rateLimiter, user meta.User, database string, n int) (func(), bool) {
+	var userID string
+	if user != nil {
+		userID = user.ID()
+	}
+
+	var released []func()
+	release := func() {
+		for _, fn := range released {
+			fn()
+		}
+	}
+
+	for _, lk := range []struct {
+		l   *rateLimiter
+		key string
+	}{{byUser, userID}, {byDatabase, database}} {
+		if lk.l == nil || lk.key == "" {
+			continue
+		}
+
+		if wait, ok := lk.l.take(lk.key, n); !ok {
+			release()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			h.httpError(w, "rate limit exceeded for "+lk.l.tag+" "+strconv.Quote(lk.key), http.StatusTooManyRequests)
+			return nil, false
+		}
+		l, key := lk.l, lk.key
+		released = append(released, func() { l.release(key) })
+	}
+	return release, true
+}
@@ -0,0 +1,99 @@
+package httpd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Rate(t *testing.T) {
+	l := newRateLimiter("database", "write", 10, 0)
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+
+	// A second's worth of tokens may be taken at once, and more by the
+	// request that empties the bucket.
+	if _, ok := l.take("db0", 8); !ok {
+		t.Fatal("expected write to be allowed")
+	} else if _, ok := l.take("db0", 5); !ok {
+		t.Fatal("expected write to be allowed while tokens are left")
+	}
+	if wait, ok := l.take("db0", 1); ok {
+		t.Fatal("expected write to be rejected")
+	} else if wait <= 0 || wait > time.Second {
+		t.Fatalf("unexpected wait: %s", wait)
+	} else if _, ok := l.take("db1", 1); !ok {
+		t.Fatal("expected write to another database to be allowed")
+	}
+
+	// The bucket refills at the rate.
+	now = now.Add(500 * time.Millisecond)
+	if _, ok := l.take("db0", 1); !ok {
+		t.Fatal("expected write to be allowed once refilled")
+	}
+
+	stats := l.statistics(nil)
+	if len(stats) != 1 {
+		t.Fatalf("unexpected statistics: %v", stats)
+	} else if stats[0].Tags["database"] != "db0" || stats[0].Tags["request"] != "write" || stats[0].Values[statRateLimitRejected] != int64(1) {
+		t.Fatalf("unexpected statistic: %v", stats[0])
+	}
+}
+
+func TestRateLimiter_Concurrency(t *testing.T) {
+	l := newRateLimiter("user", "query", 0, 2)
+
+	for i := 0; i < 2; i++ {
+		if _, ok := l.take("alice", 1); !ok {
+			t.Fatalf("expected query %d to be allowed", i)
+		}
+	}
+	if _, ok := l.take("alice", 1); ok {
+		t.Fatal("expected query to be rejected")
+	}
+
+	l.release("alice")
+	if _, ok := l.take("alice", 1); !ok {
+		t.Fatal("expected query to be allowed once released")
+	}
+}
+
+func TestRateLimiter_Sweep(t *testing.T) {
+	l := newRateLimiter("user", "query", 0, 1)
+	now := time.Unix(0, 0)
+	l.now = func() time.Time { return now }
+
+	l.take("alice", 1)
+	l.release("alice")
+	l.take("bob", 1)
+	l.take("bob", 1)
+	l.release("bob")
+
+	// Idle keys are forgotten, and their rejected requests are kept in the
+	// total of the limiter.
+	now = now.Add(rateLimitIdleTime)
+	l.take("carol", 1)
+	if _, ok := l.keys["alice"]; ok {
+		t.Fatal("expected idle key to be forgotten")
+	} else if _, ok := l.keys["bob"]; ok {
+		t.Fatal("expected idle key with rejected requests to be forgotten")
+	} else if _, ok := l.keys["carol"]; !ok {
+		t.Fatal("expected active key to be kept")
+	}
+
+	stats := l.statistics(nil)
+	if len(stats) != 1 {
+		t.Fatalf("unexpected statistics: %v", stats)
+	} else if _, ok := stats[0].Tags["user"]; ok || stats[0].Values[statRateLimitRejected] != int64(1) {
+		t.Fatalf("unexpected statistic: %v", stats[0])
+	}
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	l := newRateLimiter("user", "query", 0, 0)
+	if l != nil {
+		t.Fatal("expected no limiter")
+	} else if _, ok := l.take("alice", 1); !ok {
+		t.Fatal("expected requests to be allowed")
+	}
+	l.release("alice")
+}
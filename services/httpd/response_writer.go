@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/influxdb/models"
@@ -21,17 +22,22 @@ type ResponseWriter interface {
 }
 
 // NewResponseWriter creates a new ResponseWriter based on the Accept header
-// in the request that wraps the ResponseWriter.
+// in the request that wraps the ResponseWriter. The "format" query parameter,
+// one of "json", "csv", "msgpack" or "arrow", overrides the Accept header.
 func NewResponseWriter(w http.ResponseWriter, r *http.Request) ResponseWriter {
-	pretty := r.URL.Query().Get("pretty") == "true"
+	q := r.URL.Query()
+	pretty := q.Get("pretty") == "true"
 	rw := &responseWriter{ResponseWriter: w}
-	switch r.Header.Get("Accept") {
+	switch responseFormat(r) {
 	case "application/csv", "text/csv":
 		w.Header().Add("Content-Type", "text/csv")
-		rw.formatter = &csvFormatter{statementID: -1, Writer: w}
+		rw.formatter = &csvFormatter{statementID: -1, Writer: w, rfc3339: q.Get("time_format") == "rfc3339"}
 	case "application/x-msgpack":
 		w.Header().Add("Content-Type", "application/x-msgpack")
 		rw.formatter = &msgpackFormatter{Writer: w}
+	case "application/vnd.apache.arrow.stream":
+		w.Header().Add("Content-Type", "application/vnd.apache.arrow.stream")
+		rw.formatter = &arrowFormatter{Writer: w}
 	case "application/json":
 		fallthrough
 	default:
@@ -41,6 +47,35 @@ func NewResponseWriter(w http.ResponseWriter, r *http.Request) ResponseWriter {
 	return rw
 }
 
+// responseFormats maps the values of the "format" query parameter to the
+// media types of the formats.
+var responseFormats = map[string]string{
+	"json":    "application/json",
+	"csv":     "text/csv",
+	"msgpack": "application/x-msgpack",
+	"arrow":   "application/vnd.apache.arrow.stream",
+}
+
+// responseFormat returns the media type of the format requested by the
+// "format" query parameter, or else the first supported media type in the
+// Accept header. Media type parameters, such as "charset", are ignored.
+func responseFormat(r *http.Request) string {
+	if mt, ok := responseFormats[r.URL.Query().Get("format")]; ok {
+		return mt
+	}
+
+	for _, mt := range strings.Split(r.Header.Get("Accept"), ",") {
+		if i := strings.IndexByte(mt, ';'); i >= 0 {
+			mt = mt[:i]
+		}
+		switch mt = strings.TrimSpace(mt); mt {
+		case "application/json", "application/csv", "text/csv", "application/x-msgpack", "application/vnd.apache.arrow.stream":
+			return mt
+		}
+	}
+	return ""
+}
+
 // WriteError is a convenience function for writing an error response to the ResponseWriter.
 func WriteError(w ResponseWriter, err error) (int, error) {
 	return w.WriteResponse(Response{Err: err})
@@ -99,10 +134,18 @@ func (w *jsonFormatter) WriteResponse(resp Response) (n int, err error) {
 	return n, err
 }
 
+// csvFormatter writes responses as CSV. Each statement is written as a
+// header followed by a row for each value, and statements are separated by
+// a blank line. A series with different columns than the previous series of
+// its statement starts a new header, also after a blank line.
 type csvFormatter struct {
 	io.Writer
 	statementID int
+	header      []string
 	columns     []string
+
+	// rfc3339 writes times in RFC3339 format instead of as epoch nanoseconds.
+	rfc3339 bool
 }
 
 func (w *csvFormatter) WriteResponse(resp Response) (n int, err error) {
@@ -115,39 +158,40 @@ func (w *csvFormatter) WriteResponse(resp Response) (n int, err error) {
 	}
 
 	for _, result := range resp.Results {
-		if result.StatementID != w.statementID {
-			// If there are no series in the result, skip past this result.
-			if len(result.Series) == 0 {
-				continue
-			}
+		// If there are no series in the result, skip past this result.
+		if len(result.Series) == 0 {
+			continue
+		}
 
-			// Set the statement id and print out a newline if this is not the first statement.
-			if w.statementID >= 0 {
-				// Flush the csv writer and write a newline.
-				csv.Flush()
-				if err := csv.Error(); err != nil {
-					return n, err
+		for _, row := range result.Series {
+			if result.StatementID != w.statementID || !stringsEqual(row.Columns, w.header) {
+				// Print out a newline if this is not the first header.
+				if w.statementID >= 0 {
+					// Flush the csv writer and write a newline.
+					csv.Flush()
+					if err := csv.Error(); err != nil {
+						return n, err
+					}
+
+					out, err := io.WriteString(w, "\n")
+					if err != nil {
+						return n, err
+					}
+					n += out
 				}
+				w.statementID = result.StatementID
 
-				out, err := io.WriteString(w, "\n")
-				if err != nil {
+				// Print out the column headers from the series.
+				w.header = row.Columns
+				w.columns = make([]string, 2+len(row.Columns))
+				w.columns[0] = "name"
+				w.columns[1] = "tags"
+				copy(w.columns[2:], row.Columns)
+				if err := csv.Write(w.columns); err != nil {
 					return n, err
 				}
-				n += out
-			}
-			w.statementID = result.StatementID
-
-			// Print out the column headers from the first series.
-			w.columns = make([]string, 2+len(result.Series[0].Columns))
-			w.columns[0] = "name"
-			w.columns[1] = "tags"
-			copy(w.columns[2:], result.Series[0].Columns)
-			if err := csv.Write(w.columns); err != nil {
-				return n, err
 			}
-		}
 
-		for _, row := range result.Series {
 			w.columns[0] = row.Name
 			if len(row.Tags) > 0 {
 				w.columns[1] = string(models.NewTags(row.Tags).HashKey()[1:])
@@ -155,32 +199,12 @@ func (w *csvFormatter) WriteResponse(resp Response) (n int, err error) {
 				w.columns[1] = ""
 			}
 			for _, values := range row.Values {
-				for i, value := range values {
-					if value == nil {
-						w.columns[i+2] = ""
-						continue
-					}
-
-					switch v := value.(type) {
-					case float64:
-						w.columns[i+2] = strconv.FormatFloat(v, 'f', -1, 64)
-					case int64:
-						w.columns[i+2] = strconv.FormatInt(v, 10)
-					case uint64:
-						w.columns[i+2] = strconv.FormatUint(v, 10)
-					case string:
-						w.columns[i+2] = v
-					case bool:
-						if v {
-							w.columns[i+2] = "true"
-						} else {
-							w.columns[i+2] = "false"
-						}
-					case time.Time:
-						w.columns[i+2] = strconv.FormatInt(v.UnixNano(), 10)
-					case *float64, *int64, *string, *bool:
-						w.columns[i+2] = ""
+				for i := range w.columns[2:] {
+					var value interface{}
+					if i < len(values) {
+						value = values[i]
 					}
+					w.columns[i+2] = w.format(value)
 				}
 				csv.Write(w.columns)
 			}
@@ -193,6 +217,46 @@ func (w *csvFormatter) WriteResponse(resp Response) (n int, err error) {
 	return n, nil
 }
 
+// format returns the CSV representation of a value.
+func (w *csvFormatter) format(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case string:
+		return v
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case time.Time:
+		if w.rfc3339 {
+			return v.UTC().Format(time.RFC3339Nano)
+		}
+		return strconv.FormatInt(v.UnixNano(), 10)
+	default:
+		// nil and *float64, *int64, *string and *bool.
+		return ""
+	}
+}
+
+// stringsEqual returns true if a and b hold the same strings.
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 type msgpackFormatter struct {
 	io.Writer
 }
@@ -208,7 +272,7 @@ func (f *msgpackFormatter) WriteResponse(resp Response) (n int, err error) {
 	enc.WriteMapHeader(1)
 	if resp.Err != nil {
 		enc.WriteString("error")
-		enc.WriteString(err.Error())
+		enc.WriteString(resp.Err.Error())
 		return 0, nil
 	} else {
 		enc.WriteString("results")
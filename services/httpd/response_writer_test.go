@@ -68,6 +68,108 @@ cpu,"host=server01,region=uswest",70,9223372036854775808
 	}
 }
 
+func TestResponseWriter_CSV_FormatParam(t *testing.T) {
+	header := make(http.Header)
+	header.Set("Accept", "application/json")
+	r := &http.Request{
+		Header: header,
+		URL:    &url.URL{RawQuery: "format=csv&time_format=rfc3339"},
+	}
+	w := httptest.NewRecorder()
+
+	writer := httpd.NewResponseWriter(w, r)
+	writer.WriteResponse(httpd.Response{
+		Results: []*query.Result{
+			{
+				StatementID: 0,
+				Series: []*models.Row{
+					{
+						Name:    "cpu",
+						Columns: []string{"time", "value"},
+						Values: [][]interface{}{
+							{time.Unix(0, 10), "a \"quoted\" value"},
+							{time.Unix(1, 0), "multi\nline"},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	if got, want := w.Header().Get("Content-Type"), "text/csv"; got != want {
+		t.Errorf("unexpected content type: got=%s want=%s", got, want)
+	}
+	if got, want := w.Body.String(), `name,tags,time,value
+cpu,,1970-01-01T00:00:00.00000001Z,"a ""quoted"" value"
+cpu,,1970-01-01T00:00:01Z,"multi
+line"
+`; got != want {
+		t.Errorf("unexpected output:\n\ngot=%v\nwant=%s", got, want)
+	}
+}
+
+func TestResponseWriter_CSV_ChangingColumns(t *testing.T) {
+	header := make(http.Header)
+	header.Set("Accept", "text/csv; charset=utf-8")
+	r := &http.Request{
+		Header: header,
+		URL:    &url.URL{},
+	}
+	w := httptest.NewRecorder()
+
+	writer := httpd.NewResponseWriter(w, r)
+	for _, resp := range []httpd.Response{
+		{
+			Results: []*query.Result{
+				{
+					StatementID: 0,
+					Series: []*models.Row{
+						{
+							Name:    "cpu",
+							Columns: []string{"time", "value"},
+							Values:  [][]interface{}{{time.Unix(0, 10), int64(1)}},
+						},
+						{
+							Name:    "mem",
+							Columns: []string{"time", "free", "used"},
+							Values:  [][]interface{}{{time.Unix(0, 20), int64(2), int64(3)}},
+						},
+					},
+				},
+			},
+		},
+		// A second chunk of the same statement does not repeat the header.
+		{
+			Results: []*query.Result{
+				{
+					StatementID: 0,
+					Series: []*models.Row{
+						{
+							Name:    "mem",
+							Columns: []string{"time", "free", "used"},
+							Values:  [][]interface{}{{time.Unix(0, 30), int64(4)}},
+						},
+					},
+				},
+			},
+		},
+	} {
+		if _, err := writer.WriteResponse(resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := w.Body.String(), `name,tags,time,value
+cpu,,10,1
+
+name,tags,time,free,used
+mem,,20,2,3
+mem,,30,4,
+`; got != want {
+		t.Errorf("unexpected output:\n\ngot=%v\nwant=%s", got, want)
+	}
+}
+
 func TestResponseWriter_MessagePack(t *testing.T) {
 	header := make(http.Header)
 	header.Set("Accept", "application/x-msgpack")
@@ -129,3 +231,26 @@ func TestResponseWriter_MessagePack(t *testing.T) {
 		t.Fatalf("unexpected output: %s != %s", have, want)
 	}
 }
+
+func TestResponseWriter_MessagePack_Error(t *testing.T) {
+	header := make(http.Header)
+	header.Set("Accept", "application/x-msgpack")
+	r := &http.Request{
+		Header: header,
+		URL:    &url.URL{},
+	}
+	w := httptest.NewRecorder()
+
+	writer := httpd.NewResponseWriter(w, r)
+	httpd.WriteError(writer, fmt.Errorf("test error"))
+
+	reader := msgp.NewReader(w.Body)
+	var buf bytes.Buffer
+	if _, err := reader.WriteToJSON(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := `{"error":"test error"}`
+	if have := strings.TrimSpace(buf.String()); have != want {
+		t.Fatalf("unexpected output: %s != %s", have, want)
+	}
+}
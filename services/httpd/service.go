@@ -7,14 +7,20 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/user"
 	"path"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/influxdata/influxdb/models"
 	"github.com/uber-go/zap"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 // statistics gathered by the httpd package.
@@ -42,6 +48,29 @@ const (
 	// Prometheus stats
 	statPromWriteRequest = "promWriteReq" // Number of write requests to the promtheus endpoint
 	statPromReadRequest  = "promReadReq"  // Number of read requests to the prometheus endpoint
+
+	// Query cache stats
+	statQueryCacheHit  = "queryCacheHit"  // Number of queries answered from the query cache
+	statQueryCacheMiss = "queryCacheMiss" // Number of cacheable queries that were executed
+
+	// Clock skew stats
+	statClockSkewP50     = "p50"     // Median absolute clock skew of recent writes, in nanoseconds
+	statClockSkewP90     = "p90"     // 90th percentile absolute clock skew of recent writes, in nanoseconds
+	statClockSkewP99     = "p99"     // 99th percentile absolute clock skew of recent writes, in nanoseconds
+	statClockSkewMax     = "max"     // Maximum absolute clock skew of recent writes, in nanoseconds
+	statClockSkewSamples = "samples" // Number of writes sampled for clock skew
+	statClockSkewAlerts  = "alerts"  // Number of writes with clock skew beyond the threshold
+
+	// Rate limit stats
+	statQueryRequestRateLimited = "queryReqRateLimited" // Number of query requests rejected by a rate limit
+	statWriteRequestRateLimited = "writeReqRateLimited" // Number of write requests rejected by a rate limit
+	statRateLimitRejected       = "rejected"            // Number of requests of a user or to a database rejected by a rate limit
+
+	// Connection stats
+	statConnectionsActive   = "connActive"   // Number of open connections
+	statConnectionsIdle     = "connIdle"     // Number of open keep-alive connections waiting for a request
+	statConnectionsTotal    = "connTotal"    // Number of connections accepted
+	statConnectionsRejected = "connRejected" // Number of connections dropped by the connection limit
 )
 
 // Service manages the listener and handler for an HTTP endpoint.
@@ -52,10 +81,23 @@ type Service struct {
 	cert  string
 	key   string
 	limit int
+	http2 bool
 	err   chan error
 
+	certReloadInterval time.Duration
+	acme               ACMEConfig
+	acmeServer         *http.Server
+	closing            chan struct{}
+
+	server *http.Server
+
+	mu    sync.Mutex
+	conns map[net.Conn]http.ConnState
+
 	unixSocket         bool
 	bindSocket         string
+	unixSocketPerm     os.FileMode
+	unixSocketGroup    string
 	unixSocketListener net.Listener
 
 	Handler *Handler
@@ -71,15 +113,34 @@ func NewService(c Config) *Service {
 		cert:       c.HTTPSCertificate,
 		key:        c.HTTPSPrivateKey,
 		limit:      c.MaxConnectionLimit,
+		http2:      c.HTTP2Enabled,
 		err:        make(chan error),
+		conns:      make(map[net.Conn]http.ConnState),
 		unixSocket: c.UnixSocketEnabled,
 		bindSocket: c.BindSocket,
 		Handler:    NewHandler(c),
 		Logger:     zap.New(zap.NullEncoder()),
+
+		unixSocketPerm:  os.FileMode(c.UnixSocketPermissions),
+		unixSocketGroup: c.UnixSocketGroup,
+
+		certReloadInterval: time.Duration(c.HTTPSCertificateReloadInterval),
+		acme:               c.ACME,
 	}
 	if s.key == "" {
 		s.key = s.cert
 	}
+	s.server = &http.Server{
+		Handler:           s.Handler,
+		ReadHeaderTimeout: time.Duration(c.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(c.WriteTimeout),
+		IdleTimeout:       time.Duration(c.IdleTimeout),
+		ConnState:         s.trackConn,
+	}
+	if !s.http2 {
+		// A non-nil map disables the automatic HTTP/2 support of the server.
+		s.server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
 	s.Handler.Logger = s.Logger
 	return s
 }
@@ -90,19 +151,32 @@ func (s *Service) Open() error {
 	s.Logger.Info(fmt.Sprint("Authentication enabled:", s.Handler.Config.AuthEnabled))
 
 	// Open listener.
+	s.closing = make(chan struct{})
 	if s.https {
-		cert, err := tls.LoadX509KeyPair(s.cert, s.key)
-		if err != nil {
+		config := &tls.Config{}
+		if s.http2 {
+			config.NextProtos = []string{"h2", "http/1.1"}
+		}
+		if err := s.setCertificate(config); err != nil {
 			return err
 		}
 
-		listener, err := tls.Listen("tcp", s.addr, &tls.Config{
-			Certificates: []tls.Certificate{cert},
-		})
+		listener, err := tls.Listen("tcp", s.addr, config)
 		if err != nil {
 			return err
 		}
 
+		// Answer the HTTP-01 challenges of the ACME server.
+		if s.acmeServer != nil {
+			acmeListener, err := net.Listen("tcp", s.acme.BindAddress)
+			if err != nil {
+				listener.Close()
+				return err
+			}
+			s.Logger.Info(fmt.Sprint("Listening for ACME challenges on HTTP:", acmeListener.Addr().String()))
+			go s.serve(s.acmeServer, acmeListener)
+		}
+
 		s.Logger.Info(fmt.Sprint("Listening on HTTPS:", listener.Addr().String()))
 		s.ln = listener
 	} else {
@@ -131,6 +205,10 @@ func (s *Service) Open() error {
 		if err != nil {
 			return err
 		}
+		if err := s.setUnixSocketAccess(); err != nil {
+			listener.Close()
+			return err
+		}
 
 		s.Logger.Info(fmt.Sprint("Listening on unix socket:", listener.Addr().String()))
 		s.unixSocketListener = listener
@@ -140,7 +218,9 @@ func (s *Service) Open() error {
 
 	// Enforce a connection limit if one has been given.
 	if s.limit > 0 {
-		s.ln = LimitListener(s.ln, s.limit)
+		s.ln = newLimitListener(s.ln, s.limit, func() {
+			atomic.AddInt64(&s.Handler.stats.ConnectionsRejected, 1)
+		})
 	}
 
 	// wait for the listeners to start
@@ -161,8 +241,52 @@ func (s *Service) Open() error {
 	return nil
 }
 
+// setCertificate sets the source of the certificate of the HTTPS listener:
+// an ACME server, or the certificate files, reloaded when they change.  The
+// server answering the challenges of the ACME server is served by Open.
+func (s *Service) setCertificate(config *tls.Config) error {
+	if s.acme.Enabled {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(s.acme.Hosts...),
+			Cache:      autocert.DirCache(s.acme.CacheDir),
+			Email:      s.acme.Email,
+			Client:     &acme.Client{DirectoryURL: s.acme.DirectoryURL},
+		}
+		config.GetCertificate = m.GetCertificate
+		s.acmeServer = &http.Server{
+			Handler:           m.HTTPHandler(nil),
+			ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		}
+		return nil
+	}
+
+	reloader, err := newCertReloader(s.cert, s.key)
+	if err != nil {
+		return err
+	}
+	reloader.Logger = s.Logger
+	config.GetCertificate = reloader.GetCertificate
+	if s.certReloadInterval > 0 {
+		go reloader.watch(s.certReloadInterval, s.closing)
+	}
+	return nil
+}
+
 // Close closes the underlying listener.
 func (s *Service) Close() error {
+	if s.closing != nil {
+		select {
+		case <-s.closing:
+		default:
+			close(s.closing)
+		}
+	}
+	if s.acmeServer != nil {
+		if err := s.acmeServer.Close(); err != nil {
+			return err
+		}
+	}
 	if s.ln != nil {
 		if err := s.ln.Close(); err != nil {
 			return err
@@ -206,20 +330,77 @@ func (s *Service) BoundHTTPAddr() string {
 
 // serveTCP serves the handler from the TCP listener.
 func (s *Service) serveTCP() {
-	s.serve(s.ln)
+	s.serve(s.server, s.ln)
 }
 
 // serveUnixSocket serves the handler from the unix socket listener.
 func (s *Service) serveUnixSocket() {
-	s.serve(s.unixSocketListener)
+	s.serve(s.server, s.unixSocketListener)
+}
+
+// setUnixSocketAccess sets the permissions and the group of the unix socket,
+// which control the users allowed to connect to it.
+func (s *Service) setUnixSocketAccess() error {
+	if s.unixSocketGroup != "" {
+		gid, err := lookupGroup(s.unixSocketGroup)
+		if err != nil {
+			return err
+		}
+		if err := os.Chown(s.bindSocket, -1, gid); err != nil {
+			return err
+		}
+	}
+	if s.unixSocketPerm != 0 {
+		if err := os.Chmod(s.bindSocket, s.unixSocketPerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupGroup returns the ID of the group with the given name or ID.
+func lookupGroup(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// trackConn counts the open and idle connections of the service as their
+// state changes.
+func (s *Service) trackConn(c net.Conn, state http.ConnState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.Handler.stats
+	if prev, ok := s.conns[c]; ok && prev == http.StateIdle {
+		atomic.AddInt64(&stats.IdleConnections, -1)
+	}
+
+	switch state {
+	case http.StateNew:
+		atomic.AddInt64(&stats.Connections, 1)
+		atomic.AddInt64(&stats.ConnectionsAccepted, 1)
+	case http.StateIdle:
+		atomic.AddInt64(&stats.IdleConnections, 1)
+	case http.StateHijacked, http.StateClosed:
+		atomic.AddInt64(&stats.Connections, -1)
+		delete(s.conns, c)
+		return
+	}
+	s.conns[c] = state
 }
 
-// serve serves the handler from the listener.
-func (s *Service) serve(listener net.Listener) {
+// serve serves the handler of the server from the listener.
+func (s *Service) serve(server *http.Server, listener net.Listener) {
 	// The listener was closed so exit
 	// See https://github.com/golang/go/issues/4373
-	err := http.Serve(listener, s.Handler)
+	err := server.Serve(listener)
 	if err != nil && !strings.Contains(err.Error(), "closed") {
-		s.err <- fmt.Errorf("listener failed: addr=%s, err=%s", s.Addr(), err)
+		s.err <- fmt.Errorf("listener failed: addr=%s, err=%s", listener.Addr(), err)
 	}
 }
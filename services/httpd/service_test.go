@@ -0,0 +1,71 @@
+package httpd_test
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/httpd"
+)
+
+// Ensure the service reports its open, idle and rejected connections.
+func TestService_Connections(t *testing.T) {
+	c := httpd.NewConfig()
+	c.BindAddress = "127.0.0.1:0"
+	c.MaxConnectionLimit = 1
+	s := httpd.NewService(c)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("GET /ping HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+
+	// A second connection is over the limit and dropped.
+	rejected, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rejected.Close()
+	rejected.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := rejected.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expected the connection over the limit to be closed")
+	}
+
+	exp := map[string]int64{"connActive": 1, "connIdle": 1, "connTotal": 1, "connRejected": 1}
+	var values map[string]interface{}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		values = s.Statistics(nil)[0].Values
+		if connStatsEqual(values, exp) {
+			return
+		}
+	}
+	t.Fatalf("unexpected connection statistics: %v", values)
+}
+
+func connStatsEqual(values map[string]interface{}, exp map[string]int64) bool {
+	for k, v := range exp {
+		if values[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package httpd
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+const (
+	// clockSkewSamples is the number of recent samples kept for each database.
+	clockSkewSamples = 1024
+
+	// clockSkewAlertInterval is the minimum time between alerts for a client.
+	clockSkewAlertInterval = time.Minute
+)
+
+// clockSkewTracker tracks the difference between the timestamps clients
+// write and the time the server received them.
+type clockSkewTracker struct {
+	mu        sync.Mutex
+	threshold time.Duration
+	databases map[string]*clockSkewStats
+
+	// alerted holds the last time an alert was raised for each database and
+	// client, within the alert interval before lastPrune.
+	alerted   map[string]time.Time
+	lastPrune time.Time
+}
+
+// clockSkewStats holds the recent skew samples of a database.
+type clockSkewStats struct {
+	samples []int64
+	next    int
+	total   int64
+	alerts  int64
+}
+
+func newClockSkewTracker(threshold time.Duration) *clockSkewTracker {
+	return &clockSkewTracker{
+		threshold: threshold,
+		databases: make(map[string]*clockSkewStats),
+		alerted:   make(map[string]time.Time),
+	}
+}
+
+// add records the skew of a write by client to database, received at now.
+// It returns true if the skew exceeds the threshold and no alert has been
+// raised for the client recently.
+func (t *clockSkewTracker) add(database, client string, skew time.Duration, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.databases[database]
+	if s == nil {
+		s = &clockSkewStats{samples: make([]int64, 0, clockSkewSamples)}
+		t.databases[database] = s
+	}
+
+	if len(s.samples) < clockSkewSamples {
+		s.samples = append(s.samples, int64(skew))
+	} else {
+		s.samples[s.next] = int64(skew)
+		s.next = (s.next + 1) % clockSkewSamples
+	}
+	s.total++
+	t.prune(now)
+
+	if t.threshold <= 0 || (skew <= t.threshold && skew >= -t.threshold) {
+		return false
+	}
+	s.alerts++
+
+	key := database + "\x00" + client
+	if last, ok := t.alerted[key]; ok && now.Sub(last) < clockSkewAlertInterval {
+		return false
+	}
+	t.alerted[key] = now
+	return true
+}
+
+// prune forgets the alerts raised more than the alert interval ago, at most
+// once per interval.  t.mu must be held.
+func (t *clockSkewTracker) prune(now time.Time) {
+	if now.Sub(t.lastPrune) < clockSkewAlertInterval {
+		return
+	}
+	t.lastPrune = now
+
+	for key, last := range t.alerted {
+		if now.Sub(last) >= clockSkewAlertInterval {
+			delete(t.alerted, key)
+		}
+	}
+}
+
+// statistics returns the skew percentiles of each database. Percentiles are
+// of the absolute skew, in nanoseconds.
+func (t *clockSkewTracker) statistics(tags map[string]string) []models.Statistic {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statistics := make([]models.Statistic, 0, len(t.databases))
+	for database, s := range t.databases {
+		a := make([]int64, len(s.samples))
+		for i, v := range s.samples {
+			if v < 0 {
+				v = -v
+			}
+			a[i] = v
+		}
+		sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+
+		statistics = append(statistics, models.Statistic{
+			Name: "clockSkew",
+			Tags: models.StatisticTags{"database": database}.Merge(tags),
+			Values: map[string]interface{}{
+				statClockSkewP50:     percentile(a, 50),
+				statClockSkewP90:     percentile(a, 90),
+				statClockSkewP99:     percentile(a, 99),
+				statClockSkewMax:     percentile(a, 100),
+				statClockSkewSamples: s.total,
+				statClockSkewAlerts:  s.alerts,
+			},
+		})
+	}
+	return statistics
+}
+
+// percentile returns the pth percentile of the sorted values in a.
+func percentile(a []int64, p int) int64 {
+	if len(a) == 0 {
+		return 0
+	}
+	i := (len(a)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return a[i]
+}
+
+// writeSkew returns the difference between the newest timestamp in points
+// and now. Points without a timestamp are given now, truncated to precision,
+// by the parser and are ignored. false is returned if no points had a timestamp.
+func writeSkew(points []models.Point, now time.Time, precision string) (time.Duration, bool) {
+	ns := now.UnixNano()
+	defaultTime := ns - ns%models.GetPrecisionMultiplier(precision)
+
+	var newest int64
+	var ok bool
+	for _, p := range points {
+		if ts := p.UnixNano(); ts != defaultTime && (!ok || ts > newest) {
+			newest, ok = ts, true
+		}
+	}
+	return time.Duration(newest - ns), ok
+}
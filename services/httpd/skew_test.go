@@ -0,0 +1,93 @@
+package httpd
+
+import (
+	"testing"
+	"time"
+)
+
+// Ensure alerts are raised once per interval for a client, and old alerts are forgotten.
+func TestClockSkewTracker_Alerts(t *testing.T) {
+	tr := newClockSkewTracker(time.Minute)
+	now := time.Unix(0, 0)
+
+	if !tr.add("db0", "alice", time.Hour, now) {
+		t.Fatal("expected alert")
+	} else if tr.add("db0", "alice", time.Hour, now.Add(time.Second)) {
+		t.Fatal("unexpected alert within the alert interval")
+	} else if tr.add("db0", "bob", time.Second, now.Add(time.Second)) {
+		t.Fatal("unexpected alert below the threshold")
+	}
+
+	now = now.Add(clockSkewAlertInterval)
+	if tr.add("db0", "bob", time.Second, now) {
+		t.Fatal("unexpected alert below the threshold")
+	} else if _, ok := tr.alerted["db0\x00alice"]; ok {
+		t.Fatal("expected old alert to be forgotten")
+	} else if !tr.add("db0", "alice", -time.Hour, now) {
+		t.Fatal("expected alert after the alert interval")
+	}
+
+	// Every write beyond the threshold is counted, including those whose
+	// alerts were suppressed.
+	stats := tr.statistics(nil)
+	if len(stats) != 1 {
+		t.Fatalf("unexpected statistics: %v", stats)
+	}
+	if v := stats[0].Values[statClockSkewAlerts]; v != int64(3) {
+		t.Fatalf("unexpected alerts: %v", v)
+	} else if v := stats[0].Values[statClockSkewSamples]; v != int64(5) {
+		t.Fatalf("unexpected samples: %v", v)
+	}
+}
+
+// Ensure the percentiles are of the absolute skew of the recent writes.
+func TestClockSkewTracker_Statistics(t *testing.T) {
+	tr := newClockSkewTracker(time.Minute)
+	now := time.Unix(0, 0)
+
+	if tr.add("db0", "a", 30*time.Second, now) {
+		t.Fatal("unexpected alert within threshold")
+	} else if !tr.add("db0", "a", -2*time.Minute, now) {
+		t.Fatal("expected alert beyond threshold")
+	} else if tr.add("db0", "a", 2*time.Minute, now.Add(time.Second)) {
+		t.Fatal("expected repeated alert to be suppressed")
+	} else if !tr.add("db0", "b", 2*time.Minute, now.Add(time.Second)) {
+		t.Fatal("expected alert for another client")
+	} else if !tr.add("db0", "a", 2*time.Minute, now.Add(clockSkewAlertInterval)) {
+		t.Fatal("expected alert after interval")
+	}
+	for i := 0; i < 5; i++ {
+		tr.add("db1", "a", time.Duration(i+1)*time.Second, now)
+	}
+
+	stats := tr.statistics(nil)
+	if len(stats) != 2 {
+		t.Fatalf("unexpected statistics: %v", stats)
+	}
+	for _, s := range stats {
+		switch s.Tags["database"] {
+		case "db0":
+			if v := s.Values[statClockSkewAlerts]; v != int64(4) {
+				t.Fatalf("unexpected alerts: %v", v)
+			} else if v := s.Values[statClockSkewP50]; v != int64(2*time.Minute) {
+				t.Fatalf("unexpected p50: %v", v)
+			} else if v := s.Values[statClockSkewMax]; v != int64(2*time.Minute) {
+				t.Fatalf("unexpected max: %v", v)
+			}
+		case "db1":
+			if v := s.Values[statClockSkewAlerts]; v != int64(0) {
+				t.Fatalf("unexpected alerts: %v", v)
+			} else if v := s.Values[statClockSkewP50]; v != int64(3*time.Second) {
+				t.Fatalf("unexpected p50: %v", v)
+			} else if v := s.Values[statClockSkewP90]; v != int64(5*time.Second) {
+				t.Fatalf("unexpected p90: %v", v)
+			} else if v := s.Values[statClockSkewP99]; v != int64(5*time.Second) {
+				t.Fatalf("unexpected p99: %v", v)
+			} else if v := s.Values[statClockSkewMax]; v != int64(5*time.Second) {
+				t.Fatalf("unexpected max: %v", v)
+			}
+		default:
+			t.Fatalf("unexpected statistics: %v", s)
+		}
+	}
+}
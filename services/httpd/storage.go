@@ -0,0 +1,68 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// serveStorage lists the storage used by each database and shard of the node
+// as JSON, optionally for the database in the db parameter only. It requires
+// the same privileges as SHOW SHARDS.
+func (h *Handler) serveStorage(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.authorizeStatement(w, user, &influxql.ShowShardsStatement{}) {
+		return
+	}
+
+	if h.Store == nil {
+		h.httpError(w, "storage statistics are not available", http.StatusNotFound)
+		return
+	}
+
+	databases, err := h.Store.DatabasesStorage()
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	shards := h.Store.ShardsStorage()
+
+	if db := r.URL.Query().Get("db"); db != "" {
+		filtered := databases[:0]
+		for _, d := range databases {
+			if d.Name == db {
+				filtered = append(filtered, d)
+			}
+		}
+		databases = filtered
+
+		var filteredShards []tsdb.ShardStorage
+		for _, sh := range shards {
+			if sh.Database == db {
+				filteredShards = append(filteredShards, sh)
+			}
+		}
+		shards = filteredShards
+	}
+
+	if databases == nil {
+		databases = []tsdb.DatabaseStorage{}
+	}
+	if shards == nil {
+		shards = []tsdb.ShardStorage{}
+	}
+
+	b, err := json.Marshal(struct {
+		Databases []tsdb.DatabaseStorage `json:"databases"`
+		Shards    []tsdb.ShardStorage    `json:"shards"`
+	}{Databases: databases, Shards: shards})
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	h.writeHeader(w, http.StatusOK)
+	w.Write(b)
+}
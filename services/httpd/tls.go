@@ -0,0 +1,110 @@
+package httpd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/uber-go/zap"
+)
+
+// certReloader serves the certificate of the HTTPS listener and reloads it
+// from its files when they change, so certificates can be rotated without
+// restarting the service.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+
+	Logger zap.Logger
+}
+
+// newCertReloader returns a reloader of the certificate and key files,
+// which must hold a valid key pair.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		Logger:   zap.New(zap.NullEncoder()),
+	}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate.  It is used as the
+// GetCertificate callback of the TLS configuration of the listener.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// reload loads the key pair if either of its files changed since it was
+// last loaded, and returns true if it did.  The current certificate is kept
+// if the files don't hold a valid key pair, such as while they are being
+// replaced.
+func (r *certReloader) reload() (bool, error) {
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	unchanged := r.cert != nil && modTime.Equal(r.modTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	r.cert, r.modTime = &cert, modTime
+	r.mu.Unlock()
+	return true, nil
+}
+
+// filesModTime returns the latest modification time of the files.
+func (r *certReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// watch checks the files for changes every interval until closing is
+// closed.
+func (r *certReloader) watch(interval time.Duration, closing <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closing:
+			return
+		case <-ticker.C:
+			if reloaded, err := r.reload(); err != nil {
+				r.Logger.Info(fmt.Sprintf("unable to reload HTTPS certificate %s: %s", r.certFile, err))
+			} else if reloaded {
+				r.Logger.Info(fmt.Sprintf("Reloaded HTTPS certificate %s", r.certFile))
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package httpd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCertReloader_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpd-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeKeyPair(t, certFile, keyFile, "a.example.com", time.Now().Add(-time.Hour))
+
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cn := certCommonName(t, r); cn != "a.example.com" {
+		t.Fatalf("unexpected certificate: %s", cn)
+	}
+
+	// Unchanged files aren't reloaded.
+	if reloaded, err := r.reload(); err != nil {
+		t.Fatal(err)
+	} else if reloaded {
+		t.Fatal("unexpected reload of unchanged files")
+	}
+
+	// An invalid key pair keeps the current certificate.
+	if err := ioutil.WriteFile(certFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	} else if _, err := r.reload(); err == nil {
+		t.Fatal("expected error for invalid certificate")
+	} else if cn := certCommonName(t, r); cn != "a.example.com" {
+		t.Fatalf("unexpected certificate: %s", cn)
+	}
+
+	writeKeyPair(t, certFile, keyFile, "b.example.com", time.Now())
+	if reloaded, err := r.reload(); err != nil {
+		t.Fatal(err)
+	} else if !reloaded {
+		t.Fatal("expected reload of changed files")
+	} else if cn := certCommonName(t, r); cn != "b.example.com" {
+		t.Fatalf("unexpected certificate: %s", cn)
+	}
+}
+
+// certCommonName returns the common name of the current certificate of r.
+func certCommonName(t *testing.T, r *certReloader) string {
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+// writeKeyPair writes a self-signed certificate for the common name, and its
+// key, with the modification time.
+func writeKeyPair(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{certFile, keyFile} {
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
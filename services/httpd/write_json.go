@@ -0,0 +1,206 @@
+package httpd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// isJSONWrite returns true if the body of a write request is a JSON array of
+// points rather than line protocol.
+func isJSONWrite(r *http.Request) bool {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mt == "application/json"
+}
+
+// parseJSONPoints parses a JSON array of points in the form:
+//
+//	[{"measurement": "cpu", "tags": {"host": "a"}, "fields": {"value": 1.5}, "time": 1500000000}]
+//
+// Tags are optional and must have string values.  Field values may be
+// numbers, strings or booleans.  Numbers without a fraction or exponent are
+// integers, all other numbers are floats.  The time is optional and is either
+// an integer in the units of precision or an RFC3339 string.  Points without
+// a time are given now.
+//
+// Unlike line protocol, the points are validated as a whole and an error is
+// returned if any of them is invalid.
+func parseJSONPoints(buf []byte, now time.Time, precision string) ([]models.Point, error) {
+	if buf = bytes.TrimSpace(buf); len(buf) == 0 {
+		return nil, nil
+	} else if buf[0] != '[' {
+		return nil, errors.New("expected a JSON array of points")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+
+	var values []interface{}
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("unable to parse JSON points: %s", err)
+	} else if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON array of points")
+	}
+
+	points := make([]models.Point, 0, len(values))
+	for i, v := range values {
+		p, err := parseJSONPoint(v, now, precision)
+		if err != nil {
+			return nil, fmt.Errorf("point %d: %s", i, err)
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// parseJSONPoint parses a single point of a JSON write.
+func parseJSONPoint(v interface{}, now time.Time, precision string) (models.Point, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("expected an object")
+	}
+	for k := range obj {
+		switch k {
+		case "measurement", "tags", "fields", "time":
+		default:
+			return nil, fmt.Errorf("unknown key %q", k)
+		}
+	}
+
+	name, ok := obj["measurement"].(string)
+	if !ok {
+		if obj["measurement"] != nil {
+			return nil, errors.New("measurement must be a string")
+		}
+		return nil, errors.New("measurement is required")
+	} else if name == "" {
+		return nil, errors.New("measurement is required")
+	}
+
+	tags, err := parseJSONTags(obj["tags"])
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := parseJSONFields(obj["fields"])
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := parseJSONTime(obj["time"], now, precision)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewPoint(name, models.NewTags(tags), fields, t)
+}
+
+// parseJSONTags parses the tags of a point.
+func parseJSONTags(v interface{}) (map[string]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("tags must be an object")
+	}
+
+	tags := make(map[string]string, len(obj))
+	for k, v := range obj {
+		if k == "" {
+			return nil, errors.New("tag keys must not be empty")
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("tag %q must have a string value", k)
+		} else if s == "" {
+			return nil, fmt.Errorf("tag %q must not have an empty value", k)
+		}
+		tags[k] = s
+	}
+	return tags, nil
+}
+
+// parseJSONFields parses the fields of a point.
+func parseJSONFields(v interface{}) (models.Fields, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		if v != nil {
+			return nil, errors.New("fields must be an object")
+		}
+		return nil, errors.New("fields are required")
+	} else if len(obj) == 0 {
+		return nil, errors.New("fields are required")
+	}
+
+	fields := make(models.Fields, len(obj))
+	for k, v := range obj {
+		if k == "" {
+			return nil, errors.New("field keys must not be empty")
+		}
+		switch v := v.(type) {
+		case json.Number:
+			if strings.ContainsAny(string(v), ".eE") {
+				f, err := v.Float64()
+				if err != nil {
+					return nil, fmt.Errorf("field %q: invalid float %s", k, v)
+				}
+				fields[k] = f
+			} else {
+				n, err := v.Int64()
+				if err != nil {
+					return nil, fmt.Errorf("field %q: integer %s out of range", k, v)
+				}
+				fields[k] = n
+			}
+		case string:
+			fields[k] = v
+		case bool:
+			fields[k] = v
+		case nil:
+			return nil, fmt.Errorf("field %q must not be null", k)
+		default:
+			return nil, fmt.Errorf("field %q must be a number, string or boolean", k)
+		}
+	}
+	return fields, nil
+}
+
+// parseJSONTime parses the time of a point.
+func parseJSONTime(v interface{}, now time.Time, precision string) (time.Time, error) {
+	switch v := v.(type) {
+	case nil:
+		return now, nil
+	case json.Number:
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("time must be an integer: %s", v)
+		}
+		t, err := models.SafeCalcTime(n, precision)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return t, nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("time must be an RFC3339 string: %s", err)
+		}
+		t = t.UTC()
+		if err := models.CheckTime(t); err != nil {
+			return time.Time{}, err
+		}
+		return t, nil
+	default:
+		return time.Time{}, errors.New("time must be an integer or a string")
+	}
+}
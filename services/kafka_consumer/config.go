@@ -0,0 +1,216 @@
+package kafka_consumer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	// DefaultConsumerGroup is the default Kafka consumer group of the service.
+	DefaultConsumerGroup = "influxdb"
+
+	// DefaultOffset is the default offset a new consumer group starts
+	// consuming topics from.
+	DefaultOffset = "oldest"
+
+	// DefaultKafkaVersion is the default version of the Kafka protocol used.
+	// Consumer groups require at least 0.10.2.0.
+	DefaultKafkaVersion = "0.10.2.0"
+
+	// DefaultDatabase is the default database for Kafka messages.
+	DefaultDatabase = "kafka"
+
+	// DefaultRetentionPolicy is the default retention policy used for writes.
+	DefaultRetentionPolicy = ""
+
+	// DefaultDataFormat is the default format of Kafka messages.
+	DefaultDataFormat = "line-protocol"
+
+	// DefaultPrecision is the default time precision of line protocol messages.
+	DefaultPrecision = "n"
+
+	// DefaultBatchSize is the default number of points written at once.
+	DefaultBatchSize = 5000
+
+	// DefaultBatchTimeout is the default time messages are batched for.
+	DefaultBatchTimeout = time.Second
+
+	// DefaultRetryInterval is the default time between attempts to write a
+	// batch that failed to be written.
+	DefaultRetryInterval = time.Second
+
+	// DefaultJSONTimeFormat is the default format of the times of JSON
+	// messages.
+	DefaultJSONTimeFormat = "rfc3339"
+)
+
+// Config holds the configuration of a Kafka consumer.
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	Brokers       []string `toml:"brokers"`
+	Topics        []string `toml:"topics"`
+	ConsumerGroup string   `toml:"consumer-group"`
+	Offset        string   `toml:"offset"`
+	KafkaVersion  string   `toml:"kafka-version"`
+
+	TLSEnabled         bool   `toml:"tls-enabled"`
+	InsecureSkipVerify bool   `toml:"insecure-skip-verify"`
+	SASLUsername       string `toml:"sasl-username"`
+	SASLPassword       string `toml:"sasl-password"`
+
+	Database        string     `toml:"database"`
+	RetentionPolicy string     `toml:"retention-policy"`
+	DataFormat      string     `toml:"data-format"`
+	Precision       string     `toml:"precision"`
+	JSON            JSONConfig `toml:"json"`
+
+	BatchSize     int           `toml:"batch-size"`
+	BatchTimeout  toml.Duration `toml:"batch-timeout"`
+	RetryInterval toml.Duration `toml:"retry-interval"`
+}
+
+// JSONConfig maps JSON messages to points.  A message is an object or an
+// array of objects, whose nested objects are flattened by joining their keys
+// with underscores.
+type JSONConfig struct {
+	// Measurement is the measurement of the points, unless MeasurementKey is
+	// set and the object has a string value for it.
+	Measurement    string `toml:"measurement"`
+	MeasurementKey string `toml:"measurement-key"`
+
+	// TimeKey is the key of the time of the points, in TimeFormat: rfc3339,
+	// unix, unix_ms, unix_us, unix_ns or a Go time layout.  Points without a
+	// time have the timestamp of their message.
+	TimeKey    string `toml:"time-key"`
+	TimeFormat string `toml:"time-format"`
+
+	// TagKeys are the keys of the tags of the points.  FieldKeys are the keys
+	// of their fields, or every other number, boolean and string if empty.
+	// Numbers are always float fields.
+	TagKeys   []string `toml:"tag-keys"`
+	FieldKeys []string `toml:"field-keys"`
+}
+
+// NewConfig returns a new instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		ConsumerGroup:   DefaultConsumerGroup,
+		Offset:          DefaultOffset,
+		KafkaVersion:    DefaultKafkaVersion,
+		Database:        DefaultDatabase,
+		RetentionPolicy: DefaultRetentionPolicy,
+		DataFormat:      DefaultDataFormat,
+		Precision:       DefaultPrecision,
+		JSON:            JSONConfig{TimeFormat: DefaultJSONTimeFormat},
+		BatchSize:       DefaultBatchSize,
+		BatchTimeout:    toml.Duration(DefaultBatchTimeout),
+		RetryInterval:   toml.Duration(DefaultRetryInterval),
+	}
+}
+
+// WithDefaults takes the given config and returns a new config with any required
+// default values set.
+func (c *Config) WithDefaults() *Config {
+	d := *c
+	if d.ConsumerGroup == "" {
+		d.ConsumerGroup = DefaultConsumerGroup
+	}
+	if d.Offset == "" {
+		d.Offset = DefaultOffset
+	}
+	if d.KafkaVersion == "" {
+		d.KafkaVersion = DefaultKafkaVersion
+	}
+	if d.Database == "" {
+		d.Database = DefaultDatabase
+	}
+	if d.DataFormat == "" {
+		d.DataFormat = DefaultDataFormat
+	}
+	if d.Precision == "" {
+		d.Precision = DefaultPrecision
+	}
+	if d.JSON.TimeFormat == "" {
+		d.JSON.TimeFormat = DefaultJSONTimeFormat
+	}
+	if d.BatchSize == 0 {
+		d.BatchSize = DefaultBatchSize
+	}
+	if d.BatchTimeout == 0 {
+		d.BatchTimeout = toml.Duration(DefaultBatchTimeout)
+	}
+	if d.RetryInterval == 0 {
+		d.RetryInterval = toml.Duration(DefaultRetryInterval)
+	}
+	return &d
+}
+
+// Validate returns an error if the config is invalid.
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	d := c.WithDefaults()
+	if len(d.Brokers) == 0 {
+		return errors.New("brokers must be specified")
+	} else if len(d.Topics) == 0 {
+		return errors.New("topics must be specified")
+	} else if d.Offset != "oldest" && d.Offset != "newest" {
+		return fmt.Errorf("unknown offset %q, must be oldest or newest", d.Offset)
+	} else if _, err := sarama.ParseKafkaVersion(d.KafkaVersion); err != nil {
+		return fmt.Errorf("invalid kafka-version: %s", err)
+	} else if d.BatchSize < 0 {
+		return errors.New("batch-size must not be negative")
+	}
+
+	switch d.DataFormat {
+	case "line-protocol":
+	case "json":
+		if d.JSON.Measurement == "" && d.JSON.MeasurementKey == "" {
+			return errors.New("json.measurement or json.measurement-key must be specified")
+		}
+	default:
+		return fmt.Errorf("unknown data-format %q, must be line-protocol or json", d.DataFormat)
+	}
+	return nil
+}
+
+// Configs wraps a slice of Config to aggregate diagnostics.
+type Configs []Config
+
+// Diagnostics returns one set of diagnostics for all of the Configs.
+func (c Configs) Diagnostics() (*diagnostics.Diagnostics, error) {
+	d := &diagnostics.Diagnostics{
+		Columns: []string{"enabled", "brokers", "topics", "consumer-group", "database", "retention-policy", "data-format", "batch-size", "batch-timeout"},
+	}
+
+	for _, cc := range c {
+		if !cc.Enabled {
+			d.AddRow([]interface{}{false})
+			continue
+		}
+
+		r := []interface{}{true, strings.Join(cc.Brokers, ","), strings.Join(cc.Topics, ","), cc.ConsumerGroup, cc.Database, cc.RetentionPolicy, cc.DataFormat, cc.BatchSize, cc.BatchTimeout}
+		d.AddRow(r)
+	}
+
+	return d, nil
+}
+
+// Enabled returns true if any underlying Config is Enabled.
+func (c Configs) Enabled() bool {
+	for _, cc := range c {
+		if cc.Enabled {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package kafka_consumer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/kafka_consumer"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	var c kafka_consumer.Config
+	if _, err := toml.Decode(`
+enabled = true
+brokers = ["kafka1:9092", "kafka2:9092"]
+topics = ["metrics"]
+consumer-group = "influx"
+offset = "newest"
+database = "awesomedb"
+retention-policy = "awesomerp"
+data-format = "json"
+batch-size = 100
+batch-timeout = "10ms"
+
+[json]
+measurement-key = "name"
+time-key = "ts"
+time-format = "unix_ms"
+tag-keys = ["host"]
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if c.Enabled != true {
+		t.Fatalf("unexpected enabled: %v", c.Enabled)
+	} else if len(c.Brokers) != 2 || c.Brokers[1] != "kafka2:9092" {
+		t.Fatalf("unexpected brokers: %v", c.Brokers)
+	} else if len(c.Topics) != 1 || c.Topics[0] != "metrics" {
+		t.Fatalf("unexpected topics: %v", c.Topics)
+	} else if c.ConsumerGroup != "influx" {
+		t.Fatalf("unexpected consumer group: %s", c.ConsumerGroup)
+	} else if c.Offset != "newest" {
+		t.Fatalf("unexpected offset: %s", c.Offset)
+	} else if c.Database != "awesomedb" {
+		t.Fatalf("unexpected database: %s", c.Database)
+	} else if c.RetentionPolicy != "awesomerp" {
+		t.Fatalf("unexpected retention policy: %s", c.RetentionPolicy)
+	} else if c.DataFormat != "json" {
+		t.Fatalf("unexpected data format: %s", c.DataFormat)
+	} else if c.BatchSize != 100 {
+		t.Fatalf("unexpected batch size: %d", c.BatchSize)
+	} else if time.Duration(c.BatchTimeout) != (10 * time.Millisecond) {
+		t.Fatalf("unexpected batch timeout: %v", c.BatchTimeout)
+	} else if c.JSON.MeasurementKey != "name" {
+		t.Fatalf("unexpected json measurement key: %s", c.JSON.MeasurementKey)
+	} else if c.JSON.TimeKey != "ts" || c.JSON.TimeFormat != "unix_ms" {
+		t.Fatalf("unexpected json time key: %s %s", c.JSON.TimeKey, c.JSON.TimeFormat)
+	} else if len(c.JSON.TagKeys) != 1 || c.JSON.TagKeys[0] != "host" {
+		t.Fatalf("unexpected json tag keys: %v", c.JSON.TagKeys)
+	}
+
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		fn   func(c *kafka_consumer.Config)
+		err  string
+	}{
+		{
+			name: "no brokers",
+			fn:   func(c *kafka_consumer.Config) { c.Brokers = nil },
+			err:  "brokers must be specified",
+		},
+		{
+			name: "no topics",
+			fn:   func(c *kafka_consumer.Config) { c.Topics = nil },
+			err:  "topics must be specified",
+		},
+		{
+			name: "unknown offset",
+			fn:   func(c *kafka_consumer.Config) { c.Offset = "latest" },
+			err:  `unknown offset "latest", must be oldest or newest`,
+		},
+		{
+			name: "unknown data format",
+			fn:   func(c *kafka_consumer.Config) { c.DataFormat = "csv" },
+			err:  `unknown data-format "csv", must be line-protocol or json`,
+		},
+		{
+			name: "json without measurement",
+			fn:   func(c *kafka_consumer.Config) { c.DataFormat = "json" },
+			err:  "json.measurement or json.measurement-key must be specified",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := kafka_consumer.NewConfig()
+			c.Enabled = true
+			c.Brokers = []string{"localhost:9092"}
+			c.Topics = []string{"metrics"}
+			tt.fn(&c)
+
+			if err := c.Validate(); err == nil || err.Error() != tt.err {
+				t.Fatalf("unexpected error: got %v, exp %s", err, tt.err)
+			}
+		})
+	}
+
+	// Disabled configs aren't validated.
+	c := kafka_consumer.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,188 @@
+package kafka_consumer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// parser converts the value of a Kafka message to points.  Points without a
+// time get the timestamp of their message, so replaying a topic writes the
+// same points again.
+type parser interface {
+	parse(b []byte, t time.Time) ([]models.Point, error)
+}
+
+// newParser returns a parser for the data format of the config.
+func newParser(c *Config) parser {
+	if c.DataFormat == "json" {
+		return &jsonParser{config: c.JSON}
+	}
+	return &lineProtocolParser{precision: c.Precision}
+}
+
+// lineProtocolParser parses line protocol messages.
+type lineProtocolParser struct {
+	precision string
+}
+
+func (p *lineProtocolParser) parse(b []byte, t time.Time) ([]models.Point, error) {
+	return models.ParsePointsWithPrecision(b, t, p.precision)
+}
+
+// jsonParser parses JSON messages with the mapping of a JSONConfig.
+type jsonParser struct {
+	config JSONConfig
+}
+
+func (p *jsonParser) parse(b []byte, t time.Time) ([]models.Point, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	var objs []interface{}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		objs = []interface{}{v}
+	case []interface{}:
+		objs = v
+	default:
+		return nil, errors.New("message must be a JSON object or an array of objects")
+	}
+
+	points := make([]models.Point, 0, len(objs))
+	for _, obj := range objs {
+		obj, ok := obj.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("message must be a JSON object or an array of objects")
+		}
+
+		values := make(map[string]interface{})
+		flattenJSON("", obj, values)
+
+		pt, err := p.point(values, t)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, pt)
+	}
+	return points, nil
+}
+
+// point returns the point of the flattened values of a JSON object.
+func (p *jsonParser) point(values map[string]interface{}, t time.Time) (models.Point, error) {
+	name := p.config.Measurement
+	if s, ok := values[p.config.MeasurementKey].(string); ok && p.config.MeasurementKey != "" {
+		name = s
+	}
+	if name == "" {
+		return nil, fmt.Errorf("missing measurement key %q", p.config.MeasurementKey)
+	}
+
+	if v, ok := values[p.config.TimeKey]; ok && p.config.TimeKey != "" {
+		var err error
+		if t, err = parseJSONTime(v, p.config.TimeFormat); err != nil {
+			return nil, fmt.Errorf("invalid time key %q: %s", p.config.TimeKey, err)
+		}
+	}
+
+	tags := make(map[string]string, len(p.config.TagKeys))
+	for _, k := range p.config.TagKeys {
+		if v, ok := values[k]; ok {
+			tags[k] = fmt.Sprint(v)
+		}
+	}
+
+	fields := make(models.Fields)
+	addField := func(k string, v interface{}) {
+		switch v := v.(type) {
+		case json.Number:
+			if f, err := v.Float64(); err == nil {
+				fields[k] = f
+			}
+		case bool, string:
+			fields[k] = v
+		}
+	}
+	if len(p.config.FieldKeys) > 0 {
+		for _, k := range p.config.FieldKeys {
+			addField(k, values[k])
+		}
+	} else {
+		for k, v := range values {
+			if _, ok := tags[k]; ok || k == p.config.MeasurementKey || k == p.config.TimeKey {
+				continue
+			}
+			addField(k, v)
+		}
+	}
+
+	return models.NewPoint(name, models.NewTags(tags), fields, t)
+}
+
+// flattenJSON adds the values of obj to values, with the keys of nested
+// objects joined to their parents' keys with underscores.  Arrays and nulls
+// are skipped.
+func flattenJSON(prefix string, obj map[string]interface{}, values map[string]interface{}) {
+	for k, v := range obj {
+		if prefix != "" {
+			k = prefix + "_" + k
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			flattenJSON(k, v, values)
+		case json.Number, bool, string:
+			values[k] = v
+		}
+	}
+}
+
+// parseJSONTime parses the JSON value of a time in format.
+func parseJSONTime(v interface{}, format string) (time.Time, error) {
+	switch format {
+	case "unix", "unix_ms", "unix_us", "unix_ns":
+		n, ok := v.(json.Number)
+		if !ok {
+			return time.Time{}, errors.New("time must be a number")
+		}
+
+		var unit float64
+		switch format {
+		case "unix":
+			unit = float64(time.Second)
+		case "unix_ms":
+			unit = float64(time.Millisecond)
+		case "unix_us":
+			unit = float64(time.Microsecond)
+		default:
+			unit = float64(time.Nanosecond)
+		}
+
+		// Integers are converted exactly, fractions to the nearest nanosecond.
+		if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+			return time.Unix(0, i*int64(unit)).UTC(), nil
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, int64(f*unit)).UTC(), nil
+	case "rfc3339":
+		format = time.RFC3339Nano
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, errors.New("time must be a string")
+	}
+	return time.Parse(format, s)
+}
@@ -0,0 +1,151 @@
+package kafka_consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+func TestParser_LineProtocol(t *testing.T) {
+	c := NewConfig()
+	c.Precision = "s"
+	p := newParser(&c)
+
+	now := time.Unix(100, 0).UTC()
+	points, err := p.parse([]byte("cpu,host=a value=1 10\ncpu,host=b value=2"), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := pointStrings(points), []string{
+		"cpu,host=a value=1 10000000000",
+		"cpu,host=b value=2 100000000000",
+	}; !equalStrings(got, exp) {
+		t.Fatalf("unexpected points:\n\tgot = %v\n\texp = %v", got, exp)
+	}
+
+	if _, err := p.parse([]byte("cpu value="), now); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+func TestParser_JSON(t *testing.T) {
+	now := time.Unix(100, 0).UTC()
+
+	for _, tt := range []struct {
+		name   string
+		config JSONConfig
+		msg    string
+		exp    []string
+		err    string
+	}{
+		{
+			name:   "object",
+			config: JSONConfig{Measurement: "cpu", TagKeys: []string{"host"}},
+			msg:    `{"host": "a", "value": 1.5, "ok": true, "state": "up", "list": [1, 2], "none": null}`,
+			exp:    []string{`cpu,host=a ok=true,state="up",value=1.5 100000000000`},
+		},
+		{
+			name:   "array",
+			config: JSONConfig{Measurement: "cpu"},
+			msg:    `[{"value": 1}, {"value": 2}]`,
+			exp:    []string{"cpu value=1 100000000000", "cpu value=2 100000000000"},
+		},
+		{
+			name:   "nested",
+			config: JSONConfig{Measurement: "cpu", TagKeys: []string{"meta_host"}, FieldKeys: []string{"stats_idle"}},
+			msg:    `{"meta": {"host": "a"}, "stats": {"idle": 90, "user": 10}}`,
+			exp:    []string{"cpu,meta_host=a stats_idle=90 100000000000"},
+		},
+		{
+			name:   "measurement key",
+			config: JSONConfig{Measurement: "default", MeasurementKey: "name"},
+			msg:    `[{"name": "mem", "value": 1}, {"value": 2}]`,
+			exp:    []string{"mem value=1 100000000000", "default value=2 100000000000"},
+		},
+		{
+			name:   "rfc3339 time",
+			config: JSONConfig{Measurement: "cpu", TimeKey: "time", TimeFormat: "rfc3339"},
+			msg:    `{"time": "1970-01-01T00:00:01.5Z", "value": 1}`,
+			exp:    []string{"cpu value=1 1500000000"},
+		},
+		{
+			name:   "unix time",
+			config: JSONConfig{Measurement: "cpu", TimeKey: "time", TimeFormat: "unix"},
+			msg:    `{"time": 1.5, "value": 1}`,
+			exp:    []string{"cpu value=1 1500000000"},
+		},
+		{
+			name:   "unix_ms time",
+			config: JSONConfig{Measurement: "cpu", TimeKey: "time", TimeFormat: "unix_ms"},
+			msg:    `{"time": 1500, "value": 1}`,
+			exp:    []string{"cpu value=1 1500000000"},
+		},
+		{
+			name:   "layout time",
+			config: JSONConfig{Measurement: "cpu", TimeKey: "time", TimeFormat: "2006-01-02 15:04:05"},
+			msg:    `{"time": "1970-01-01 00:00:02", "value": 1}`,
+			exp:    []string{"cpu value=1 2000000000"},
+		},
+		{
+			name:   "invalid time",
+			config: JSONConfig{Measurement: "cpu", TimeKey: "time", TimeFormat: "unix"},
+			msg:    `{"time": "now", "value": 1}`,
+			err:    `invalid time key "time": time must be a number`,
+		},
+		{
+			name:   "missing measurement",
+			config: JSONConfig{MeasurementKey: "name"},
+			msg:    `{"value": 1}`,
+			err:    `missing measurement key "name"`,
+		},
+		{
+			name:   "not an object",
+			config: JSONConfig{Measurement: "cpu"},
+			msg:    `[1]`,
+			err:    "message must be a JSON object or an array of objects",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			c.DataFormat = "json"
+			c.JSON = tt.config
+			p := newParser(&c)
+
+			points, err := p.parse([]byte(tt.msg), now)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("unexpected error: got %v, exp %s", err, tt.err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := pointStrings(points); !equalStrings(got, tt.exp) {
+				t.Fatalf("unexpected points:\n\tgot = %v\n\texp = %v", got, tt.exp)
+			}
+		})
+	}
+}
+
+func pointStrings(points []models.Point) []string {
+	a := make([]string, len(points))
+	for i, pt := range points {
+		a[i] = pt.String()
+	}
+	return a
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
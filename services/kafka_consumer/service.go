@@ -0,0 +1,381 @@
+// Package kafka_consumer provides a service that consumes points from Kafka
+// topics.
+package kafka_consumer // import "github.com/influxdata/influxdb/services/kafka_consumer"
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/uber-go/zap"
+)
+
+// statistics gathered by the Kafka consumer package.
+const (
+	statMessagesReceived    = "messagesRx"
+	statBytesReceived       = "bytesRx"
+	statPointsReceived      = "pointsRx"
+	statMessagesParseFail   = "messagesParseFail"
+	statBatchesTransmitted  = "batchesTx"
+	statPointsTransmitted   = "pointsTx"
+	statBatchesTransmitFail = "batchesTxFail"
+	statPointsDropped       = "pointsDropped"
+)
+
+// Service consumes messages from Kafka topics as a member of a consumer group
+// and writes their points in batches.  The offset of a message is only
+// committed once the batch with its points has been written, so messages are
+// delivered at least once.
+type Service struct {
+	config Config
+	parser parser
+
+	group  sarama.ConsumerGroup
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu    sync.RWMutex
+	ready bool // Has the required database been created?
+
+	PointsWriter interface {
+		WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+
+	MetaClient interface {
+		CreateDatabase(name string) (*meta.DatabaseInfo, error)
+	}
+
+	Logger      zap.Logger
+	stats       *Statistics
+	defaultTags models.StatisticTags
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	d := *c.WithDefaults()
+	return &Service{
+		config: d,
+		parser: newParser(&d),
+		Logger: zap.New(zap.NullEncoder()),
+		stats:  &Statistics{},
+		defaultTags: models.StatisticTags{
+			"group":  d.ConsumerGroup,
+			"topics": strings.Join(d.Topics, ","),
+		},
+	}
+}
+
+// Open starts consuming the topics of the service.
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.group != nil {
+		return nil // Already open.
+	}
+
+	config, err := s.saramaConfig()
+	if err != nil {
+		return err
+	}
+
+	group, err := sarama.NewConsumerGroup(s.config.Brokers, s.config.ConsumerGroup, config)
+	if err != nil {
+		s.Logger.Info(fmt.Sprintf("Failed to join consumer group %s: %s", s.config.ConsumerGroup, err))
+		return err
+	}
+	s.group = group
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.Logger.Info(fmt.Sprintf("Started consuming topics %s as consumer group %s",
+		strings.Join(s.config.Topics, ","), s.config.ConsumerGroup))
+
+	s.wg.Add(2)
+	go s.consume(ctx, group)
+	go s.logErrors(group)
+
+	return nil
+}
+
+// saramaConfig returns the configuration of the Kafka client.
+func (s *Service) saramaConfig() (*sarama.Config, error) {
+	version, err := sarama.ParseKafkaVersion(s.config.KafkaVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	config := sarama.NewConfig()
+	config.ClientID = "influxdb"
+	config.Version = version
+	config.Consumer.Return.Errors = true
+
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	if s.config.Offset == "newest" {
+		config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	}
+
+	if s.config.TLSEnabled {
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = &tls.Config{
+			InsecureSkipVerify: s.config.InsecureSkipVerify,
+		}
+	}
+
+	if s.config.SASLUsername != "" {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.User = s.config.SASLUsername
+		config.Net.SASL.Password = s.config.SASLPassword
+	}
+	return config, nil
+}
+
+// consume consumes the topics until ctx is canceled.  Consume returns
+// whenever the partitions of the group are rebalanced, so it is called again
+// to join the next generation of the group.
+func (s *Service) consume(ctx context.Context, group sarama.ConsumerGroup) {
+	defer s.wg.Done()
+
+	for {
+		if err := group.Consume(ctx, s.config.Topics, s); err != nil {
+			s.Logger.Info(fmt.Sprintf("Failed to consume topics %s: %s", strings.Join(s.config.Topics, ","), err))
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(s.config.RetryInterval)):
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// logErrors logs the errors of the consumer group until it is closed.
+func (s *Service) logErrors(group sarama.ConsumerGroup) {
+	defer s.wg.Done()
+
+	for err := range group.Errors() {
+		s.Logger.Info(fmt.Sprintf("Kafka consumer error: %s", err))
+	}
+}
+
+// Setup is called at the start of each session of the consumer group.
+func (s *Service) Setup(session sarama.ConsumerGroupSession) error {
+	s.Logger.Info(fmt.Sprintf("Joined generation %d of consumer group %s", session.GenerationID(), s.config.ConsumerGroup))
+	return nil
+}
+
+// Cleanup is called at the end of each session of the consumer group.
+func (s *Service) Cleanup(session sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim consumes the messages of a partition claimed in session,
+// until the session ends.
+func (s *Service) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	var (
+		batch []models.Point
+		last  *sarama.ConsumerMessage
+		timer *time.Timer
+		timeC <-chan time.Time
+	)
+
+	// flush writes the batch and marks the messages of its points as consumed.
+	// It returns false if the session ended before the batch was written.
+	flush := func() bool {
+		if timer != nil {
+			timer.Stop()
+			timer, timeC = nil, nil
+		}
+		if last == nil {
+			return true
+		}
+
+		if !s.writeBatch(session.Context(), batch) {
+			return false
+		}
+		session.MarkMessage(last, "")
+		batch, last = nil, nil
+		return true
+	}
+
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				flush()
+				return nil
+			}
+			atomic.AddInt64(&s.stats.MessagesReceived, 1)
+			atomic.AddInt64(&s.stats.BytesReceived, int64(len(msg.Value)))
+
+			t := msg.Timestamp
+			if t.IsZero() {
+				t = time.Now().UTC()
+			}
+
+			// Messages that can't be parsed will never be, so they are
+			// consumed to not block the partition.
+			points, err := s.parser.parse(msg.Value, t)
+			if err != nil {
+				atomic.AddInt64(&s.stats.MessagesParseFail, 1)
+				s.Logger.Info(fmt.Sprintf("Failed to parse message at offset %d of %s/%d: %s",
+					msg.Offset, msg.Topic, msg.Partition, err))
+			}
+			atomic.AddInt64(&s.stats.PointsReceived, int64(len(points)))
+
+			batch = append(batch, points...)
+			last = msg
+
+			if len(batch) >= s.config.BatchSize {
+				if !flush() {
+					return nil
+				}
+			} else if timer == nil {
+				timer = time.NewTimer(time.Duration(s.config.BatchTimeout))
+				timeC = timer.C
+			}
+
+		case <-timeC:
+			timer, timeC = nil, nil
+			if !flush() {
+				return nil
+			}
+
+		case <-session.Context().Done():
+			// The messages of the pending batch are consumed again by the
+			// next owner of the partition.
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil
+		}
+	}
+}
+
+// writeBatch writes the points of a batch, retrying until they are written or
+// ctx is done.  It returns false if ctx is done first.  Points rejected by the
+// database are dropped, since writing them again would fail again.
+func (s *Service) writeBatch(ctx context.Context, batch []models.Point) bool {
+	if len(batch) == 0 {
+		return true
+	}
+
+	for {
+		err := s.createInternalStorage()
+		if err != nil {
+			s.Logger.Info(fmt.Sprintf("Required database %s does not yet exist: %s", s.config.Database, err.Error()))
+		} else if err = s.PointsWriter.WritePointsPrivileged(s.config.Database, s.config.RetentionPolicy, models.ConsistencyLevelAny, batch); err == nil {
+			atomic.AddInt64(&s.stats.BatchesTransmitted, 1)
+			atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)))
+			return true
+		} else if perr, ok := err.(tsdb.PartialWriteError); ok {
+			atomic.AddInt64(&s.stats.BatchesTransmitted, 1)
+			atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)-perr.Dropped))
+			atomic.AddInt64(&s.stats.PointsDropped, int64(perr.Dropped))
+			s.Logger.Info(fmt.Sprintf("Dropped points writing batch to database %q: %s", s.config.Database, err))
+			return true
+		} else if influxdb.IsClientError(err) {
+			atomic.AddInt64(&s.stats.BatchesTransmitFail, 1)
+			atomic.AddInt64(&s.stats.PointsDropped, int64(len(batch)))
+			s.Logger.Info(fmt.Sprintf("Dropped batch rejected by database %q: %s", s.config.Database, err))
+			return true
+		} else {
+			atomic.AddInt64(&s.stats.BatchesTransmitFail, 1)
+			s.Logger.Info(fmt.Sprintf("failed to write point batch to database %q: %s", s.config.Database, err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(time.Duration(s.config.RetryInterval)):
+		}
+	}
+}
+
+// Close stops consuming and leaves the consumer group.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	group, cancel := s.group, s.cancel
+	s.group, s.cancel = nil, nil
+	s.mu.Unlock()
+
+	if group == nil {
+		return nil // Already closed.
+	}
+
+	cancel()
+	err := group.Close()
+	s.wg.Wait()
+
+	s.Logger.Info("Service closed")
+
+	return err
+}
+
+// Statistics maintains statistics for the Kafka consumer service.
+type Statistics struct {
+	MessagesReceived    int64
+	BytesReceived       int64
+	PointsReceived      int64
+	MessagesParseFail   int64
+	BatchesTransmitted  int64
+	PointsTransmitted   int64
+	BatchesTransmitFail int64
+	PointsDropped       int64
+}
+
+// Statistics returns statistics for periodic monitoring.
+func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	return []models.Statistic{{
+		Name: "kafka_consumer",
+		Tags: s.defaultTags.Merge(tags),
+		Values: map[string]interface{}{
+			statMessagesReceived:    atomic.LoadInt64(&s.stats.MessagesReceived),
+			statBytesReceived:       atomic.LoadInt64(&s.stats.BytesReceived),
+			statPointsReceived:      atomic.LoadInt64(&s.stats.PointsReceived),
+			statMessagesParseFail:   atomic.LoadInt64(&s.stats.MessagesParseFail),
+			statBatchesTransmitted:  atomic.LoadInt64(&s.stats.BatchesTransmitted),
+			statPointsTransmitted:   atomic.LoadInt64(&s.stats.PointsTransmitted),
+			statBatchesTransmitFail: atomic.LoadInt64(&s.stats.BatchesTransmitFail),
+			statPointsDropped:       atomic.LoadInt64(&s.stats.PointsDropped),
+		},
+	}}
+}
+
+// createInternalStorage ensures that the required database has been created.
+func (s *Service) createInternalStorage() error {
+	s.mu.RLock()
+	ready := s.ready
+	s.mu.RUnlock()
+	if ready {
+		return nil
+	}
+
+	if _, err := s.MetaClient.CreateDatabase(s.config.Database); err != nil {
+		return err
+	}
+
+	// The service is now ready.
+	s.mu.Lock()
+	s.ready = true
+	s.mu.Unlock()
+	return nil
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log zap.Logger) {
+	s.Logger = log.With(zap.String("service", "kafka_consumer"))
+}
@@ -0,0 +1,320 @@
+package kafka_consumer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/influxdata/influxdb/internal"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/uber-go/zap"
+)
+
+func TestService_ConsumeClaim_BatchSize(t *testing.T) {
+	c := NewConfig()
+	c.BatchSize = 2
+	s := NewTestService(&c)
+
+	var batches [][]models.Point
+	s.WritePointsFn = func(database, rp string, _ models.ConsistencyLevel, points []models.Point) error {
+		if database != DefaultDatabase {
+			t.Errorf("unexpected database: %s", database)
+		}
+		batches = append(batches, points)
+		return nil
+	}
+
+	session, claim := NewTestSession(), NewTestClaim()
+	claim.Send("cpu value=1", "cpu value=2\ncpu value=3", "cpu value=4")
+	close(claim.messages)
+
+	if err := s.Service.ConsumeClaim(session, claim); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first batch is written as soon as it's full, the rest when the
+	// claim ends.
+	if len(batches) != 2 || len(batches[0]) != 3 || len(batches[1]) != 1 {
+		t.Fatalf("unexpected batches: %v", batches)
+	} else if got, exp := session.Marked(), []int64{1, 2}; !equalOffsets(got, exp) {
+		t.Fatalf("unexpected marked offsets: got %v, exp %v", got, exp)
+	}
+}
+
+func TestService_ConsumeClaim_BatchTimeout(t *testing.T) {
+	s := NewTestService(nil)
+	s.Service.config.BatchTimeout = 0 // Flush immediately.
+
+	written := make(chan []models.Point, 1)
+	s.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, points []models.Point) error {
+		written <- points
+		return nil
+	}
+
+	session, claim := NewTestSession(), NewTestClaim()
+	done := make(chan error)
+	go func() { done <- s.Service.ConsumeClaim(session, claim) }()
+
+	claim.Send("cpu value=1")
+	select {
+	case points := <-written:
+		if len(points) != 1 {
+			t.Fatalf("unexpected points: %v", points)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch should have been written")
+	}
+
+	session.cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := session.Marked(), []int64{0}; !equalOffsets(got, exp) {
+		t.Fatalf("unexpected marked offsets: got %v, exp %v", got, exp)
+	}
+}
+
+func TestService_ConsumeClaim_RetryWrite(t *testing.T) {
+	s := NewTestService(nil)
+	s.Service.config.RetryInterval = 0
+
+	var attempts int
+	s.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, points []models.Point) error {
+		if attempts++; attempts < 3 {
+			return errors.New("timeout")
+		}
+		return nil
+	}
+
+	session, claim := NewTestSession(), NewTestClaim()
+	claim.Send("cpu value=1")
+	close(claim.messages)
+
+	if err := s.Service.ConsumeClaim(session, claim); err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != 3 {
+		t.Fatalf("unexpected attempts: %d", attempts)
+	} else if got, exp := session.Marked(), []int64{0}; !equalOffsets(got, exp) {
+		t.Fatalf("unexpected marked offsets: got %v, exp %v", got, exp)
+	} else if n := s.Service.stats.BatchesTransmitFail; n != 2 {
+		t.Fatalf("unexpected failed batches: %d", n)
+	}
+}
+
+func TestService_ConsumeClaim_SessionEnd(t *testing.T) {
+	s := NewTestService(nil)
+
+	attempted := make(chan struct{}, 1)
+	s.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, points []models.Point) error {
+		select {
+		case attempted <- struct{}{}:
+		default:
+		}
+		return errors.New("timeout")
+	}
+
+	session, claim := NewTestSession(), NewTestClaim()
+	claim.Send("cpu value=1")
+	close(claim.messages)
+
+	done := make(chan error)
+	go func() { done <- s.Service.ConsumeClaim(session, claim) }()
+
+	<-attempted
+	session.cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// The offset of a batch that wasn't written must not be committed.
+	if got := session.Marked(); len(got) != 0 {
+		t.Fatalf("unexpected marked offsets: %v", got)
+	}
+}
+
+func TestService_ConsumeClaim_Dropped(t *testing.T) {
+	s := NewTestService(nil)
+	s.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, points []models.Point) error {
+		return tsdb.PartialWriteError{Reason: "field type conflict", Dropped: 1}
+	}
+
+	session, claim := NewTestSession(), NewTestClaim()
+	claim.Send("cpu value=1\ncpu value=2", "cpu value=", "")
+	close(claim.messages)
+
+	if err := s.Service.ConsumeClaim(session, claim); err != nil {
+		t.Fatal(err)
+	}
+
+	// Points that can't be parsed or written are never retried.
+	if got, exp := session.Marked(), []int64{2}; !equalOffsets(got, exp) {
+		t.Fatalf("unexpected marked offsets: got %v, exp %v", got, exp)
+	} else if n := s.Service.stats.MessagesParseFail; n != 1 {
+		t.Fatalf("unexpected parse failures: %d", n)
+	} else if n := s.Service.stats.PointsTransmitted; n != 1 {
+		t.Fatalf("unexpected points transmitted: %d", n)
+	} else if n := s.Service.stats.PointsDropped; n != 1 {
+		t.Fatalf("unexpected points dropped: %d", n)
+	}
+}
+
+func TestService_CreatesDatabase(t *testing.T) {
+	s := NewTestService(nil)
+	s.Service.config.RetryInterval = 0
+
+	var created int
+	s.MetaClient.CreateDatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		if name != s.Config.Database {
+			t.Errorf("\n\texp = %s\n\tgot = %s\n", s.Config.Database, name)
+		}
+		if created++; created == 1 {
+			return nil, errors.New("an error")
+		}
+		return nil, nil
+	}
+	s.WritePointsFn = func(string, string, models.ConsistencyLevel, []models.Point) error {
+		return nil
+	}
+
+	session, claim := NewTestSession(), NewTestClaim()
+	claim.Send("cpu value=1")
+	close(claim.messages)
+
+	if err := s.Service.ConsumeClaim(session, claim); err != nil {
+		t.Fatal(err)
+	}
+
+	if created != 2 {
+		t.Fatalf("unexpected database creations: %d", created)
+	} else if !s.Service.ready {
+		t.Fatal("service should be ready")
+	}
+}
+
+type TestService struct {
+	Service       *Service
+	Config        Config
+	MetaClient    *internal.MetaClientMock
+	WritePointsFn func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+}
+
+func NewTestService(c *Config) *TestService {
+	if c == nil {
+		defaultC := NewConfig()
+		c = &defaultC
+	}
+
+	service := &TestService{
+		Service: NewService(*c),
+		Config:  *c,
+		MetaClient: &internal.MetaClientMock{
+			CreateDatabaseFn: func(name string) (*meta.DatabaseInfo, error) { return nil, nil },
+		},
+	}
+
+	if testing.Verbose() {
+		service.Service.WithLogger(zap.New(
+			zap.NewTextEncoder(),
+			zap.Output(os.Stderr),
+		))
+	}
+
+	service.Service.MetaClient = service.MetaClient
+	service.Service.PointsWriter = service
+	return service
+}
+
+func (s *TestService) WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	return s.WritePointsFn(database, retentionPolicy, consistencyLevel, points)
+}
+
+// TestSession is a consumer group session that records marked offsets.
+type TestSession struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	marked []int64
+}
+
+func NewTestSession() *TestSession {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &TestSession{ctx: ctx, cancel: cancel}
+}
+
+func (s *TestSession) Claims() map[string][]int32 { return nil }
+func (s *TestSession) MemberID() string           { return "test" }
+func (s *TestSession) GenerationID() int32        { return 1 }
+func (s *TestSession) Context() context.Context   { return s.ctx }
+
+func (s *TestSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marked = append(s.marked, offset)
+}
+
+func (s *TestSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {}
+
+func (s *TestSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+
+// Marked returns the offsets of the marked messages.
+func (s *TestSession) Marked() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := make([]int64, len(s.marked))
+	for i, offset := range s.marked {
+		a[i] = offset - 1
+	}
+	return a
+}
+
+// TestClaim is a claim of a partition whose messages are sent by the test.
+type TestClaim struct {
+	messages chan *sarama.ConsumerMessage
+	offset   int64
+}
+
+func NewTestClaim() *TestClaim {
+	return &TestClaim{messages: make(chan *sarama.ConsumerMessage, 100)}
+}
+
+func (c *TestClaim) Topic() string                            { return "metrics" }
+func (c *TestClaim) Partition() int32                         { return 0 }
+func (c *TestClaim) InitialOffset() int64                     { return 0 }
+func (c *TestClaim) HighWaterMarkOffset() int64               { return c.offset }
+func (c *TestClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+// Send sends messages with the values to the claim.
+func (c *TestClaim) Send(values ...string) {
+	for _, v := range values {
+		c.messages <- &sarama.ConsumerMessage{
+			Topic:     "metrics",
+			Value:     []byte(v),
+			Offset:    c.offset,
+			Timestamp: time.Unix(100, 0),
+		}
+		c.offset++
+	}
+}
+
+func equalOffsets(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
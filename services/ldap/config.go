@@ -0,0 +1,134 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	// DefaultUserSearchFilter is the default filter users are searched for
+	// with.  The username replaces %s.
+	DefaultUserSearchFilter = "(uid=%s)"
+
+	// DefaultGroupMemberAttribute is the default attribute of a group holding
+	// the DNs of its members.
+	DefaultGroupMemberAttribute = "member"
+
+	// DefaultGroupRefreshInterval is the default interval at which the members
+	// of the groups are refreshed.
+	DefaultGroupRefreshInterval = 5 * time.Minute
+
+	// DefaultTimeout is the default timeout of requests to the LDAP server.
+	DefaultTimeout = 10 * time.Second
+)
+
+// Config represents the configuration for LDAP authentication.
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// URL of the LDAP server, with the ldap or ldaps scheme.
+	URL                string        `toml:"url"`
+	StartTLS           bool          `toml:"start-tls"`
+	InsecureSkipVerify bool          `toml:"insecure-skip-verify"`
+	Timeout            toml.Duration `toml:"timeout"`
+
+	// DN and password the server is searched with.  The search is anonymous
+	// if BindDN is empty.
+	BindDN       string `toml:"bind-dn"`
+	BindPassword string `toml:"bind-password"`
+
+	// Users are searched for under UserSearchBase with UserSearchFilter.
+	UserSearchBase   string `toml:"user-search-base"`
+	UserSearchFilter string `toml:"user-search-filter"`
+
+	GroupMemberAttribute string        `toml:"group-member-attribute"`
+	GroupRefreshInterval toml.Duration `toml:"group-refresh-interval"`
+
+	// Groups maps the members of LDAP groups to privileges.
+	Groups []GroupConfig `toml:"group"`
+}
+
+// GroupConfig maps the members of an LDAP group to privileges.
+type GroupConfig struct {
+	DN    string `toml:"dn"`
+	Admin bool   `toml:"admin"`
+
+	// Privileges maps database names to the READ, WRITE or ALL privilege.
+	Privileges map[string]string `toml:"privileges"`
+}
+
+// NewConfig returns a new Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Enabled:              false,
+		Timeout:              toml.Duration(DefaultTimeout),
+		UserSearchFilter:     DefaultUserSearchFilter,
+		GroupMemberAttribute: DefaultGroupMemberAttribute,
+		GroupRefreshInterval: toml.Duration(DefaultGroupRefreshInterval),
+	}
+}
+
+// Validate returns an error if the Config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid ldap url %q: %s", c.URL, err)
+	} else if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return fmt.Errorf("invalid ldap url %q: scheme must be ldap or ldaps", c.URL)
+	} else if u.Scheme == "ldaps" && c.StartTLS {
+		return errors.New("start-tls can't be used with an ldaps url")
+	}
+
+	if c.UserSearchBase == "" {
+		return errors.New("user-search-base must be specified")
+	} else if !strings.Contains(c.UserSearchFilter, "%s") {
+		return errors.New("user-search-filter must contain %s")
+	}
+
+	if c.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	} else if c.GroupRefreshInterval <= 0 {
+		return errors.New("group-refresh-interval must be positive")
+	}
+
+	for _, g := range c.Groups {
+		if g.DN == "" {
+			return errors.New("group dn must be specified")
+		}
+		for db, p := range g.Privileges {
+			if _, err := influxql.ParsePrivilege(p); err != nil {
+				return fmt.Errorf("group %q, database %q: %s", g.DN, db, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":                true,
+		"url":                    c.URL,
+		"start-tls":              c.StartTLS,
+		"user-search-base":       c.UserSearchBase,
+		"group-refresh-interval": c.GroupRefreshInterval,
+		"groups":                 len(c.Groups),
+	}), nil
+}
@@ -0,0 +1,78 @@
+package ldap_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/ldap"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	c := ldap.NewConfig()
+	if _, err := toml.Decode(`
+enabled = true
+url = "ldaps://ldap.example.com"
+bind-dn = "cn=influxdb,dc=example,dc=com"
+bind-password = "secret"
+user-search-base = "ou=users,dc=example,dc=com"
+user-search-filter = "(sAMAccountName=%s)"
+group-refresh-interval = "1m"
+
+[[group]]
+  dn = "cn=admins,ou=groups,dc=example,dc=com"
+  admin = true
+
+[[group]]
+  dn = "cn=readers,ou=groups,dc=example,dc=com"
+  privileges = { telegraf = "READ" }
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.Enabled {
+		t.Fatalf("unexpected enabled state: %v", c.Enabled)
+	} else if c.URL != "ldaps://ldap.example.com" {
+		t.Fatalf("unexpected url: %s", c.URL)
+	} else if c.BindDN != "cn=influxdb,dc=example,dc=com" {
+		t.Fatalf("unexpected bind dn: %s", c.BindDN)
+	} else if c.UserSearchFilter != "(sAMAccountName=%s)" {
+		t.Fatalf("unexpected user search filter: %s", c.UserSearchFilter)
+	} else if c.GroupMemberAttribute != ldap.DefaultGroupMemberAttribute {
+		t.Fatalf("unexpected group member attribute: %s", c.GroupMemberAttribute)
+	} else if time.Duration(c.GroupRefreshInterval) != time.Minute {
+		t.Fatalf("unexpected group refresh interval: %s", c.GroupRefreshInterval)
+	} else if len(c.Groups) != 2 || !c.Groups[0].Admin || c.Groups[1].Privileges["telegraf"] != "READ" {
+		t.Fatalf("unexpected groups: %+v", c.Groups)
+	} else if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail: %s", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := ldap.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail from NewConfig: %s", err)
+	}
+
+	c.Enabled = true
+	c.URL = "http://ldap.example.com"
+	c.UserSearchBase = "ou=users,dc=example,dc=com"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for url scheme, got nil")
+	}
+
+	c.URL = "ldap://ldap.example.com"
+	c.UserSearchFilter = "(uid=admin)"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for user search filter, got nil")
+	}
+
+	c.UserSearchFilter = ldap.DefaultUserSearchFilter
+	c.Groups = []ldap.GroupConfig{{DN: "cn=admins", Privileges: map[string]string{"db0": "SOME"}}}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for group privilege, got nil")
+	}
+}
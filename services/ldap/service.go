@@ -0,0 +1,380 @@
+// Package ldap provides a service that authenticates users against an LDAP or
+// Active Directory server and maps the groups they are members of to
+// privileges.
+package ldap // import "github.com/influxdata/influxdb/services/ldap"
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/uber-go/zap"
+	goldap "gopkg.in/ldap.v2"
+)
+
+// Statistics for the LDAP service.
+const (
+	statAuthSuccess     = "authSuccess"
+	statAuthFail        = "authFail"
+	statAuthFallback    = "authFallback"
+	statGroupRefresh    = "groupRefresh"
+	statGroupRefreshErr = "groupRefreshErr"
+)
+
+// conn is the subset of *ldap.Conn used by the service.
+type conn interface {
+	Bind(username, password string) error
+	Search(req *goldap.SearchRequest) (*goldap.SearchResult, error)
+	Close()
+}
+
+// unreachableError is returned when the LDAP server can't be reached.
+type unreachableError struct {
+	err error
+}
+
+func (e unreachableError) Error() string {
+	return fmt.Sprintf("ldap server unreachable: %s", e.err)
+}
+
+// unreachable returns true if err is a failure to reach the LDAP server, or a
+// loss of the connection to it.
+func unreachable(err error) bool {
+	if _, ok := err.(unreachableError); ok {
+		return true
+	}
+	return goldap.IsErrorWithCode(err, goldap.ErrorNetwork)
+}
+
+// Service authenticates users by binding to an LDAP server with their DN and
+// password.  Users are given the privileges of the groups they are members
+// of, which are refreshed on an interval.  Users are authenticated against
+// the meta store while the LDAP server is unreachable.
+type Service struct {
+	config Config
+
+	MetaClient interface {
+		Authenticate(username, password string) (meta.User, error)
+	}
+
+	// members maps the lowercased DNs of users to the indexes of the groups
+	// of the config they are members of.
+	mu      sync.RWMutex
+	members map[string][]int
+
+	dial func() (conn, error)
+
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	stats  *Statistics
+	Logger zap.Logger
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	s := &Service{
+		config: c,
+		stats:  &Statistics{},
+		Logger: zap.New(zap.NullEncoder()),
+	}
+	s.dial = s.dialLDAP
+	return s
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log zap.Logger) {
+	s.Logger = log.With(zap.String("service", "ldap"))
+}
+
+// Open starts refreshing the members of the groups.
+func (s *Service) Open() error {
+	if s.done != nil {
+		return nil
+	}
+
+	s.Logger.Info(fmt.Sprintf("Starting LDAP authentication service with %s", s.config.URL))
+
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go s.runRefresh()
+	return nil
+}
+
+// Close stops refreshing the members of the groups.
+func (s *Service) Close() error {
+	if s.done == nil {
+		return nil
+	}
+
+	close(s.done)
+	s.wg.Wait()
+	s.done = nil
+	return nil
+}
+
+// Statistics maintains the statistics for the LDAP service.
+type Statistics struct {
+	AuthSuccess     int64
+	AuthFail        int64
+	AuthFallback    int64
+	GroupRefresh    int64
+	GroupRefreshErr int64
+}
+
+// Statistics returns statistics for periodic monitoring.
+func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	return []models.Statistic{{
+		Name: "ldap",
+		Tags: tags,
+		Values: map[string]interface{}{
+			statAuthSuccess:     atomic.LoadInt64(&s.stats.AuthSuccess),
+			statAuthFail:        atomic.LoadInt64(&s.stats.AuthFail),
+			statAuthFallback:    atomic.LoadInt64(&s.stats.AuthFallback),
+			statGroupRefresh:    atomic.LoadInt64(&s.stats.GroupRefresh),
+			statGroupRefreshErr: atomic.LoadInt64(&s.stats.GroupRefreshErr),
+		},
+	}}
+}
+
+// Authenticate authenticates the user with the LDAP server and returns it with
+// the privileges of its groups.  If the LDAP server is unreachable, the user
+// is authenticated against the meta store instead.
+func (s *Service) Authenticate(username, password string) (meta.User, error) {
+	// An empty password would bind anonymously, which most servers allow.
+	if password == "" {
+		atomic.AddInt64(&s.stats.AuthFail, 1)
+		return nil, meta.ErrAuthenticate
+	}
+
+	u, err := s.authenticate(username, password)
+	if err != nil && unreachable(err) {
+		atomic.AddInt64(&s.stats.AuthFallback, 1)
+		s.Logger.Info(fmt.Sprintf("WARN: authenticating %s with local users: %s", username, err))
+		return s.MetaClient.Authenticate(username, password)
+	} else if err != nil {
+		atomic.AddInt64(&s.stats.AuthFail, 1)
+		return nil, err
+	}
+	atomic.AddInt64(&s.stats.AuthSuccess, 1)
+	return u, nil
+}
+
+func (s *Service) authenticate(username, password string) (meta.User, error) {
+	c, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	dn, err := s.userDN(c, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Bind(dn, password); goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+		return nil, meta.ErrAuthenticate
+	} else if err != nil {
+		return nil, err
+	}
+	return &meta.ExternalUser{UserInfo: s.userInfo(username, dn)}, nil
+}
+
+// userDN returns the DN of the user named username.
+func (s *Service) userDN(c conn, username string) (string, error) {
+	if err := s.bind(c); err != nil {
+		return "", err
+	}
+
+	res, err := c.Search(goldap.NewSearchRequest(
+		s.config.UserSearchBase, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases,
+		2, s.timeLimit(), false,
+		fmt.Sprintf(s.config.UserSearchFilter, goldap.EscapeFilter(username)),
+		[]string{"dn"}, nil,
+	))
+	if goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded) {
+		return "", meta.ErrAuthenticate
+	} else if err != nil {
+		return "", err
+	} else if len(res.Entries) != 1 {
+		// The user doesn't exist, or the filter isn't unique.
+		return "", meta.ErrAuthenticate
+	}
+	return res.Entries[0].DN, nil
+}
+
+// userInfo returns the user named username, with DN dn, with the privileges
+// of the groups it is a member of.
+func (s *Service) userInfo(username, dn string) *meta.UserInfo {
+	s.mu.RLock()
+	groups := s.members[strings.ToLower(dn)]
+	s.mu.RUnlock()
+
+	u := &meta.UserInfo{
+		Name:       username,
+		Privileges: make(map[string]influxql.Privilege),
+	}
+	for _, i := range groups {
+		g := s.config.Groups[i]
+		if g.Admin {
+			u.Admin = true
+		}
+		for db, name := range g.Privileges {
+			p, _ := influxql.ParsePrivilege(name)
+			u.Privileges[db] = mergePrivileges(u.Privileges[db], p)
+		}
+	}
+	return u
+}
+
+// mergePrivileges returns the privilege granting both a and b.
+func mergePrivileges(a, b influxql.Privilege) influxql.Privilege {
+	if a == b || b == influxql.NoPrivileges {
+		return a
+	} else if a == influxql.NoPrivileges {
+		return b
+	}
+	return influxql.AllPrivileges
+}
+
+// runRefresh refreshes the members of the groups every refresh interval.
+func (s *Service) runRefresh() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(s.config.GroupRefreshInterval))
+	defer ticker.Stop()
+
+	for {
+		if err := s.refresh(); err != nil {
+			atomic.AddInt64(&s.stats.GroupRefreshErr, 1)
+			s.Logger.Info(fmt.Sprintf("WARN: unable to refresh LDAP groups: %s", err))
+		} else {
+			atomic.AddInt64(&s.stats.GroupRefresh, 1)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// refresh reads the members of the groups from the LDAP server.  Groups that
+// don't exist are skipped.  The previous members are kept if it fails.
+func (s *Service) refresh() error {
+	if len(s.config.Groups) == 0 {
+		return nil
+	}
+
+	c, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := s.bind(c); err != nil {
+		return err
+	}
+
+	attr := s.config.GroupMemberAttribute
+	members := make(map[string][]int)
+	for i, g := range s.config.Groups {
+		res, err := c.Search(goldap.NewSearchRequest(
+			g.DN, goldap.ScopeBaseObject, goldap.NeverDerefAliases,
+			0, s.timeLimit(), false,
+			"(objectClass=*)", []string{attr}, nil,
+		))
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultNoSuchObject) {
+			s.Logger.Info(fmt.Sprintf("WARN: LDAP group %s does not exist", g.DN))
+			continue
+		} else if err != nil {
+			return fmt.Errorf("group %s: %s", g.DN, err)
+		}
+
+		for _, e := range res.Entries {
+			for _, dn := range e.GetAttributeValues(attr) {
+				dn = strings.ToLower(dn)
+				members[dn] = append(members[dn], i)
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.members = members
+	s.mu.Unlock()
+	return nil
+}
+
+// bind binds c with the bind DN, if one is configured.
+func (s *Service) bind(c conn) error {
+	if s.config.BindDN == "" {
+		return nil
+	}
+	return c.Bind(s.config.BindDN, s.config.BindPassword)
+}
+
+// timeLimit returns the time limit of searches, in seconds.
+func (s *Service) timeLimit() int {
+	return int(time.Duration(s.config.Timeout) / time.Second)
+}
+
+// dialLDAP connects to the LDAP server, over TLS for ldaps URLs or if
+// configured to start TLS.
+func (s *Service) dialLDAP() (conn, error) {
+	u, err := url.Parse(s.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	isTLS := u.Scheme == "ldaps"
+
+	addr := u.Host
+	if u.Port() == "" {
+		port := "389"
+		if isTLS {
+			port = "636"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	timeout := time.Duration(s.config.Timeout)
+	nc, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, unreachableError{err: err}
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: s.config.InsecureSkipVerify,
+	}
+	if isTLS {
+		tc := tls.Client(nc, tlsConfig)
+		tc.SetDeadline(time.Now().Add(timeout))
+		if err := tc.Handshake(); err != nil {
+			nc.Close()
+			return nil, unreachableError{err: err}
+		}
+		tc.SetDeadline(time.Time{})
+		nc = tc
+	}
+
+	c := goldap.NewConn(nc, isTLS)
+	c.Start()
+	c.SetTimeout(timeout)
+
+	if s.config.StartTLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, unreachableError{err: err}
+		}
+	}
+	return c, nil
+}
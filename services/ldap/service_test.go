@@ -0,0 +1,161 @@
+package ldap
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/services/meta"
+	goldap "gopkg.in/ldap.v2"
+)
+
+// testConn is a mock implementation of conn.
+type testConn struct {
+	BindFn   func(username, password string) error
+	SearchFn func(req *goldap.SearchRequest) (*goldap.SearchResult, error)
+}
+
+func (c *testConn) Bind(username, password string) error { return c.BindFn(username, password) }
+func (c *testConn) Search(req *goldap.SearchRequest) (*goldap.SearchResult, error) {
+	return c.SearchFn(req)
+}
+func (c *testConn) Close() {}
+
+// testMetaClient is a mock implementation of Service.MetaClient.
+type testMetaClient struct {
+	AuthenticateFn func(username, password string) (meta.User, error)
+}
+
+func (c *testMetaClient) Authenticate(username, password string) (meta.User, error) {
+	return c.AuthenticateFn(username, password)
+}
+
+func NewTestService() *Service {
+	c := NewConfig()
+	c.Enabled = true
+	c.URL = "ldap://ldap.example.com"
+	c.BindDN = "cn=influxdb,dc=example,dc=com"
+	c.BindPassword = "secret"
+	c.UserSearchBase = "ou=users,dc=example,dc=com"
+	c.Groups = []GroupConfig{
+		{DN: "cn=admins,dc=example,dc=com", Admin: true},
+		{DN: "cn=readers,dc=example,dc=com", Privileges: map[string]string{"db0": "READ"}},
+		{DN: "cn=writers,dc=example,dc=com", Privileges: map[string]string{"db0": "WRITE", "db1": "WRITE"}},
+	}
+
+	s := NewService(c)
+	s.MetaClient = &testMetaClient{
+		AuthenticateFn: func(username, password string) (meta.User, error) {
+			return nil, errors.New("unexpected local authentication")
+		},
+	}
+
+	members := map[string][]string{
+		"cn=admins,dc=example,dc=com":  {"uid=alice,ou=users,dc=example,dc=com"},
+		"cn=readers,dc=example,dc=com": {"uid=bob,ou=users,dc=example,dc=com"},
+		"cn=writers,dc=example,dc=com": {"UID=Bob,ou=users,dc=example,dc=com"},
+	}
+	passwords := map[string]string{
+		"cn=influxdb,dc=example,dc=com":        "secret",
+		"uid=alice,ou=users,dc=example,dc=com": "alice",
+		"uid=bob,ou=users,dc=example,dc=com":   "bob",
+	}
+	s.dial = func() (conn, error) {
+		return &testConn{
+			BindFn: func(username, password string) error {
+				if passwords[username] != password {
+					return goldap.NewError(goldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+				}
+				return nil
+			},
+			SearchFn: func(req *goldap.SearchRequest) (*goldap.SearchResult, error) {
+				if req.BaseDN == c.UserSearchBase {
+					var entries []*goldap.Entry
+					for _, name := range []string{"alice", "bob"} {
+						if req.Filter == "(uid="+name+")" {
+							entries = append(entries, goldap.NewEntry("uid="+name+",ou=users,dc=example,dc=com", nil))
+						}
+					}
+					return &goldap.SearchResult{Entries: entries}, nil
+				}
+				dns, ok := members[req.BaseDN]
+				if !ok {
+					return nil, goldap.NewError(goldap.LDAPResultNoSuchObject, errors.New("no such object"))
+				}
+				return &goldap.SearchResult{Entries: []*goldap.Entry{
+					goldap.NewEntry(req.BaseDN, map[string][]string{"member": dns}),
+				}}, nil
+			},
+		}, nil
+	}
+	return s
+}
+
+func TestService_Authenticate(t *testing.T) {
+	s := NewTestService()
+	if err := s.refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Members of the admins group are admins.
+	if u, err := s.Authenticate("alice", "alice"); err != nil {
+		t.Fatal(err)
+	} else if u.ID() != "alice" || !u.IsAdmin() {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+
+	// The privileges of the groups are merged, and DNs are compared ignoring case.
+	u, err := s.Authenticate("bob", "bob")
+	if err != nil {
+		t.Fatal(err)
+	} else if u.IsAdmin() {
+		t.Fatal("unexpected admin")
+	} else if !u.AuthorizeDatabase(influxql.ReadPrivilege, "db0") || !u.AuthorizeDatabase(influxql.WritePrivilege, "db0") {
+		t.Fatalf("expected all privileges on db0: %+v", u)
+	} else if u.AuthorizeDatabase(influxql.ReadPrivilege, "db1") || !u.AuthorizeDatabase(influxql.WritePrivilege, "db1") {
+		t.Fatalf("expected write privilege on db1: %+v", u)
+	} else if _, ok := u.(*meta.ExternalUser); !ok {
+		t.Fatalf("unexpected user type: %T", u)
+	}
+
+	// Wrong passwords, empty passwords and unknown users are rejected.
+	if _, err := s.Authenticate("bob", "alice"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := s.Authenticate("bob", ""); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := s.Authenticate("carol", "carol"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestService_Authenticate_Unreachable(t *testing.T) {
+	s := NewTestService()
+	s.dial = func() (conn, error) {
+		return nil, unreachableError{err: errors.New("connection refused")}
+	}
+	s.MetaClient = &testMetaClient{
+		AuthenticateFn: func(username, password string) (meta.User, error) {
+			if username != "admin" || password != "admin" {
+				return nil, meta.ErrAuthenticate
+			}
+			return &meta.UserInfo{Name: "admin", Admin: true}, nil
+		},
+	}
+
+	// Users are authenticated with local users while LDAP is unreachable.
+	if u, err := s.Authenticate("admin", "admin"); err != nil {
+		t.Fatal(err)
+	} else if _, ok := u.(*meta.UserInfo); !ok || !u.IsAdmin() {
+		t.Fatalf("unexpected user: %+v", u)
+	} else if _, err := s.Authenticate("admin", "wrong"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A failed refresh keeps the previous members.
+	s.members = map[string][]int{"uid=alice,ou=users,dc=example,dc=com": {0}}
+	if err := s.refresh(); err == nil {
+		t.Fatal("expected refresh error")
+	} else if len(s.members) != 1 {
+		t.Fatalf("unexpected members: %v", s.members)
+	}
+}
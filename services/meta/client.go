@@ -6,6 +6,8 @@ import (
 	"bytes"
 	crand "crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -15,6 +17,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -29,6 +32,11 @@ const (
 	// SaltBytes is the number of bytes used for salts.
 	SaltBytes = 32
 
+	// TokenIDBytes and TokenSecretBytes are the number of random bytes of
+	// the ID and of the secret of API tokens.
+	TokenIDBytes     = 8
+	TokenSecretBytes = 32
+
 	metaFile = "meta.db"
 
 	// ShardGroupDeletedExpiration is the amount of time before a shard group info will be removed from cached
@@ -57,6 +65,12 @@ type Client struct {
 	// Authentication cache.
 	authCache map[string]authUser
 
+	// Password hashing and policy.
+	hasher            *passwordHasher
+	passwordMinLength int
+	passwordHistory   int
+	passwordMaxAge    time.Duration
+
 	path string
 
 	retentionAutoCreate bool
@@ -79,6 +93,10 @@ func NewClient(config *Config) *Client {
 		changed:             make(chan struct{}),
 		logger:              zap.New(zap.NullEncoder()),
 		authCache:           make(map[string]authUser, 0),
+		hasher:              newPasswordHasher(config),
+		passwordMinLength:   config.PasswordMinLength,
+		passwordHistory:     config.PasswordHistory,
+		passwordMaxAge:      time.Duration(config.PasswordMaxAge),
 		path:                config.Dir,
 		retentionAutoCreate: config.RetentionAutoCreate,
 	}
@@ -356,6 +374,60 @@ func (c *Client) UpdateRetentionPolicy(database, name string, rpu *RetentionPoli
 	return nil
 }
 
+// UpdateQuota updates the quotas of a database.
+func (c *Client) UpdateQuota(database string, qu *QuotaUpdate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.UpdateQuota(database, qu); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateWriteLimits updates the write limits of a database.
+func (c *Client) UpdateWriteLimits(database string, wlu *WriteLimitsUpdate) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.UpdateWriteLimits(database, wlu); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetSchema sets the schema of a database, or removes it if schema is nil.
+func (c *Client) SetSchema(database string, schema *SchemaInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.SetSchema(database, schema); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // Users returns a slice of UserInfo representing the currently known users.
 func (c *Client) Users() []UserInfo {
 	c.mu.RLock()
@@ -383,8 +455,9 @@ func (c *Client) User(name string) (User, error) {
 	return nil, ErrUserNotFound
 }
 
-// bcryptCost is the cost associated with generating password with bcrypt.
-// This setting is lowered during testing to improve test suite performance.
+// bcryptCost is the default cost associated with generating password with
+// bcrypt.  This setting is lowered during testing to improve test suite
+// performance.
 var bcryptCost = bcrypt.DefaultCost
 
 // hashWithSalt returns a salted hash of password using salt.
@@ -414,23 +487,28 @@ func (c *Client) CreateUser(name, password string, admin bool) (User, error) {
 
 	// See if the user already exists.
 	if u := data.user(name); u != nil {
-		if err := bcrypt.CompareHashAndPassword([]byte(u.Hash), []byte(password)); err != nil || u.Admin != admin {
+		if err := c.hasher.compare(u.Hash, password); err != nil || u.Admin != admin {
 			return nil, ErrUserExists
 		}
 		return u, nil
 	}
 
+	if len(password) < c.passwordMinLength {
+		return nil, ErrPasswordTooShort(c.passwordMinLength)
+	}
+
 	// Hash the password before serializing it.
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	hash, err := c.hasher.hash(password)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := data.CreateUser(name, string(hash), admin); err != nil {
+	if err := data.CreateUser(name, hash, admin); err != nil {
 		return nil, err
 	}
 
 	u := data.user(name)
+	u.PasswordChanged = time.Now().UTC()
 
 	if err := c.commit(data); err != nil {
 		return nil, err
@@ -439,30 +517,74 @@ func (c *Client) CreateUser(name, password string, admin bool) (User, error) {
 	return u, nil
 }
 
-// UpdateUser updates the password of an existing user.
+// UpdateUser updates the password of an existing user.  The password must
+// meet the password policy.
 func (c *Client) UpdateUser(name, password string) error {
+	// Comparing and hashing passwords is slow, so it is done without holding
+	// the lock, and done again if the password is changed meanwhile.
+	for {
+		c.mu.RLock()
+		ui := c.cacheData.user(name)
+		c.mu.RUnlock()
+		if ui == nil {
+			return ErrUserNotFound
+		} else if len(password) < c.passwordMinLength {
+			return ErrPasswordTooShort(c.passwordMinLength)
+		}
+
+		// The current password and the most recent ones can't be reused.
+		if c.passwordHistory > 0 {
+			hashes := append([]string{ui.Hash}, ui.PasswordHistory...)
+			if len(hashes) > c.passwordHistory {
+				hashes = hashes[:c.passwordHistory]
+			}
+			for _, hash := range hashes {
+				if c.hasher.compare(hash, password) == nil {
+					return ErrPasswordReused
+				}
+			}
+		}
+
+		// Hash the password before serializing it.
+		hash, err := c.hasher.hash(password)
+		if err != nil {
+			return err
+		}
+
+		if ok, err := c.setPassword(name, ui.Hash, hash); err != nil {
+			return err
+		} else if ok {
+			return nil
+		}
+	}
+}
+
+// setPassword sets the password hash of a user, unless its password was
+// changed since it was checked against oldHash.  It returns false if the
+// password was changed.
+func (c *Client) setPassword(name, oldHash, hash string) (bool, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	data := c.cacheData.Clone()
 
-	// Hash the password before serializing it.
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
-	if err != nil {
-		return err
+	if ui := data.user(name); ui == nil {
+		return false, ErrUserNotFound
+	} else if ui.Hash != oldHash {
+		return false, nil
 	}
 
-	if err := data.UpdateUser(name, string(hash)); err != nil {
-		return err
+	if err := data.SetPassword(name, hash, time.Now().UTC(), c.passwordHistory); err != nil {
+		return false, err
 	}
 
 	delete(c.authCache, name)
 
 	if err := c.commit(data); err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	return true, nil
 }
 
 // DropUser removes the user with the given name.
@@ -550,7 +672,8 @@ func (c *Client) AdminUserExists() bool {
 	return c.cacheData.AdminUserExists()
 }
 
-// Authenticate returns a UserInfo if the username and password match an existing entry.
+// Authenticate returns a UserInfo if the username and password match an
+// existing entry.  Passwords hashed differently than configured are rehashed.
 func (c *Client) Authenticate(username, password string) (User, error) {
 	// Find user.
 	c.mu.RLock()
@@ -567,15 +690,24 @@ func (c *Client) Authenticate(username, password string) (User, error) {
 	if ok {
 		// verify the password using the cached salt and hash
 		if bytes.Equal(c.hashWithSalt(au.salt, password), au.hash) {
+			if err := c.checkPasswordAge(userInfo); err != nil {
+				return nil, err
+			}
 			return userInfo, nil
 		}
 
-		// fall through to requiring a full bcrypt hash for invalid passwords
+		// fall through to requiring a full hash for invalid passwords
 	}
 
 	// Compare password with user hash.
-	if err := bcrypt.CompareHashAndPassword([]byte(userInfo.Hash), []byte(password)); err != nil {
+	if err := c.hasher.compare(userInfo.Hash, password); err != nil {
 		return nil, ErrAuthenticate
+	} else if err := c.checkPasswordAge(userInfo); err != nil {
+		return nil, err
+	}
+
+	if c.hasher.needsRehash(userInfo.Hash) {
+		c.rehashPassword(username, userInfo.Hash, password)
 	}
 
 	// generate a salt and hash of the password for the cache
@@ -589,6 +721,151 @@ func (c *Client) Authenticate(username, password string) (User, error) {
 	return userInfo, nil
 }
 
+// checkPasswordAge returns ErrPasswordExpired if the password of the user is
+// older than the maximum password age.  The passwords of admins don't expire,
+// so that they can always set the expired passwords of other users.  The age
+// of passwords set before it was recorded counts from their first use.
+func (c *Client) checkPasswordAge(ui *UserInfo) error {
+	if c.passwordMaxAge <= 0 {
+		return nil
+	} else if ui.PasswordChanged.IsZero() {
+		c.stampPasswordChanged(ui.Name)
+		return nil
+	} else if !ui.Admin && time.Since(ui.PasswordChanged) > c.passwordMaxAge {
+		return ErrPasswordExpired
+	}
+	return nil
+}
+
+// stampPasswordChanged records the current time as the time the password of
+// a user was changed, unless it is already recorded.
+func (c *Client) stampPasswordChanged(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+	ui := data.user(name)
+	if ui == nil || !ui.PasswordChanged.IsZero() {
+		return
+	}
+	ui.PasswordChanged = time.Now().UTC()
+
+	if err := c.commit(data); err != nil {
+		c.logger.Info(fmt.Sprintf("WARN: failed to record password change time of user %s: %s", name, err))
+	}
+}
+
+// rehashPassword replaces the hash of the password of a user with one made
+// with the configured algorithm and parameters, unless the password was
+// changed since it was checked against hash.
+func (c *Client) rehashPassword(name, hash, password string) {
+	newHash, err := c.hasher.hash(password)
+	if err != nil {
+		c.logger.Info(fmt.Sprintf("WARN: failed to rehash password of user %s: %s", name, err))
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+	if ui := data.user(name); ui == nil || ui.Hash != hash {
+		return
+	} else if err := data.UpdateUser(name, newHash); err != nil {
+		return
+	} else if err := c.commit(data); err != nil {
+		c.logger.Info(fmt.Sprintf("WARN: failed to rehash password of user %s: %s", name, err))
+	}
+}
+
+// CreateToken creates an API token for a user, limited to privilege p on
+// database if database is set, and expiring at expires if it is not zero.
+// It returns the token info and the token, which is only ever returned here;
+// the meta store keeps a hash of its secret.
+func (c *Client) CreateToken(username, database string, p influxql.Privilege, expires time.Time) (*TokenInfo, string, error) {
+	b := make([]byte, TokenIDBytes+TokenSecretBytes)
+	if _, err := io.ReadFull(crand.Reader, b); err != nil {
+		return nil, "", err
+	}
+	id, secret := hex.EncodeToString(b[:TokenIDBytes]), hex.EncodeToString(b[TokenIDBytes:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.CreateToken(id, username, hashTokenSecret(secret), database, p, expires); err != nil {
+		return nil, "", err
+	}
+
+	ti := *data.token(id)
+
+	if err := c.commit(data); err != nil {
+		return nil, "", err
+	}
+
+	return &ti, id + "." + secret, nil
+}
+
+// DropToken removes the API token with the given ID.
+func (c *Client) DropToken(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+
+	if err := data.DropToken(id); err != nil {
+		return err
+	}
+
+	if err := c.commit(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Tokens returns the API tokens of all users.
+func (c *Client) Tokens() []TokenInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.cacheData.CloneTokens()
+}
+
+// AuthenticateToken returns the user an API token authenticates as, with
+// the privileges of the user limited to those of the token.
+func (c *Client) AuthenticateToken(token string) (User, error) {
+	i := strings.IndexByte(token, '.')
+	if i < 0 {
+		return nil, ErrAuthenticate
+	}
+	id, secret := token[:i], token[i+1:]
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ti := c.cacheData.token(id)
+	if ti == nil || ti.Expired(time.Now()) {
+		return nil, ErrAuthenticate
+	} else if subtle.ConstantTimeCompare([]byte(hashTokenSecret(secret)), []byte(ti.Hash)) != 1 {
+		return nil, ErrAuthenticate
+	}
+
+	ui := c.cacheData.user(ti.User)
+	if ui == nil {
+		return nil, ErrAuthenticate
+	}
+	return &TokenUser{UserInfo: ui, Token: *ti}, nil
+}
+
+// hashTokenSecret returns the hash of the secret of an API token.  Secrets
+// are random, so they are hashed without a salt.
+func hashTokenSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
 // UserCount returns the number of users stored.
 func (c *Client) UserCount() int {
 	c.mu.RLock()
@@ -880,13 +1157,13 @@ func (c *Client) DropContinuousQuery(database, name string) error {
 }
 
 // CreateSubscription creates a subscription against the given database and retention policy.
-func (c *Client) CreateSubscription(database, rp, name, mode string, destinations []string) error {
+func (c *Client) CreateSubscription(database, rp, name, mode string, destinations []string, filter string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	data := c.cacheData.Clone()
 
-	if err := data.CreateSubscription(database, rp, name, mode, destinations); err != nil {
+	if err := data.CreateSubscription(database, rp, name, mode, destinations, filter); err != nil {
 		return err
 	}
 
@@ -14,6 +14,7 @@ import (
 
 	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
 )
 
 func TestMetaClient_CreateDatabaseOnly(t *testing.T) {
@@ -759,6 +760,212 @@ func TestMetaClient_UpdateUser(t *testing.T) {
 	}
 }
 
+func TestMetaClient_PasswordHash(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.PasswordHash = meta.PasswordHashArgon2id
+	cfg.Argon2Memory = 64 * 1024
+
+	c := meta.NewClient(cfg)
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateUser("fred", "supersecure", false); err != nil {
+		t.Fatal(err)
+	} else if u, err := c.Authenticate("fred", "supersecure"); err != nil {
+		t.Fatal(err)
+	} else if hash := u.(*meta.UserInfo).Hash; !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=4$") {
+		t.Fatalf("unexpected hash: %s", hash)
+	} else if _, err := c.Authenticate("fred", "wrong"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.Close()
+
+	// The password is rehashed with bcrypt once the user authenticates.
+	dir := cfg.Dir
+	cfg = meta.NewConfig()
+	cfg.Dir = dir
+	c = meta.NewClient(cfg)
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Authenticate("fred", "supersecure"); err != nil {
+		t.Fatal(err)
+	} else if u, err := c.User("fred"); err != nil {
+		t.Fatal(err)
+	} else if hash := u.(*meta.UserInfo).Hash; !strings.HasPrefix(hash, "$2a$") {
+		t.Fatalf("unexpected hash: %s", hash)
+	} else if _, err := c.Authenticate("fred", "supersecure"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMetaClient_PasswordPolicy(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.PasswordMinLength = 8
+	cfg.PasswordHistory = 2
+	cfg.PasswordMaxAge = toml.Duration(time.Hour)
+
+	c := meta.NewClient(cfg)
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.CreateUser("fred", "short", false); err == nil || err.Error() != "password must be at least 8 characters" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := c.CreateUser("fred", "password0", false); err != nil {
+		t.Fatal(err)
+	} else if err := c.UpdateUser("fred", "short"); err == nil {
+		t.Fatal("expected error for short password")
+	}
+
+	// The two most recent passwords can't be reused.
+	for _, tt := range []struct {
+		password string
+		err      error
+	}{
+		{password: "password0", err: meta.ErrPasswordReused},
+		{password: "password1"},
+		{password: "password0", err: meta.ErrPasswordReused},
+		{password: "password2"},
+		{password: "password1", err: meta.ErrPasswordReused},
+		{password: "password0"},
+	} {
+		if err := c.UpdateUser("fred", tt.password); err != tt.err {
+			t.Fatalf("%s: unexpected error: got %v, exp %v", tt.password, err, tt.err)
+		}
+	}
+
+	// Passwords older than the maximum age are rejected until they are set.
+	data := c.Data()
+	data.Users[0].PasswordChanged = time.Now().Add(-2 * time.Hour)
+	if err := c.SetData(&data); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.Authenticate("fred", "password0"); err != meta.ErrPasswordExpired {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := c.Authenticate("fred", "wrong"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := c.UpdateUser("fred", "password3"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.Authenticate("fred", "password3"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The passwords of admins don't expire.
+	if _, err := c.CreateUser("admin", "password0", true); err != nil {
+		t.Fatal(err)
+	}
+	data = c.Data()
+	for i := range data.Users {
+		data.Users[i].PasswordChanged = time.Now().Add(-2 * time.Hour)
+	}
+	if err := c.SetData(&data); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.Authenticate("admin", "password0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The age of passwords set before it was recorded counts from their first use.
+	data = c.Data()
+	for i := range data.Users {
+		data.Users[i].PasswordChanged = time.Time{}
+	}
+	if err := c.SetData(&data); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.Authenticate("fred", "password3"); err != nil {
+		t.Fatal(err)
+	} else if data = c.Data(); time.Since(data.Users[0].PasswordChanged) > time.Minute {
+		t.Fatalf("unexpected password change time: %s", data.Users[0].PasswordChanged)
+	}
+}
+
+func TestMetaClient_Tokens(t *testing.T) {
+	t.Parallel()
+
+	d, c := newClient()
+	defer os.RemoveAll(d)
+	defer c.Close()
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateDatabase("db1"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateUser("fred", "supersecure", true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Creating a token for a user that doesn't exist should return an error.
+	if _, _, err := c.CreateToken("wilma", "", influxql.NoPrivileges, time.Time{}); err != meta.ErrUserNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ti, token, err := c.CreateToken("fred", "db0", influxql.ReadPrivilege, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.HasPrefix(token, ti.ID+".") {
+		t.Fatalf("unexpected token %q for id %q", token, ti.ID)
+	} else if ti.Hash == "" || strings.Contains(token, ti.Hash) {
+		t.Fatalf("unexpected token hash %q", ti.Hash)
+	}
+
+	// The token authenticates as the user, limited to the token's privilege.
+	u, err := c.AuthenticateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	} else if u.ID() != "fred" {
+		t.Fatalf("unexpected user: %s", u.ID())
+	} else if u.IsAdmin() {
+		t.Fatal("expected scoped token not to be admin")
+	} else if !u.AuthorizeDatabase(influxql.ReadPrivilege, "db0") {
+		t.Fatal("expected read on db0 to be authorized")
+	} else if u.AuthorizeDatabase(influxql.WritePrivilege, "db0") {
+		t.Fatal("expected write on db0 not to be authorized")
+	} else if u.AuthorizeDatabase(influxql.ReadPrivilege, "db1") {
+		t.Fatal("expected read on db1 not to be authorized")
+	}
+
+	// A wrong secret doesn't authenticate.
+	if _, err := c.AuthenticateToken(ti.ID + ".bad"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := c.AuthenticateToken("bad"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An expired token doesn't authenticate.
+	_, expired, err := c.CreateToken("fred", "", influxql.NoPrivileges, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := c.AuthenticateToken(expired); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := len(c.Tokens()); got != 2 {
+		t.Fatalf("unexpected token count: %d", got)
+	}
+
+	if err := c.DropToken(ti.ID); err != nil {
+		t.Fatal(err)
+	} else if err := c.DropToken(ti.ID); err != meta.ErrTokenNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := c.AuthenticateToken(token); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Dropping the user drops its tokens.
+	if err := c.DropUser("fred"); err != nil {
+		t.Fatal(err)
+	} else if got := len(c.Tokens()); got != 0 {
+		t.Fatalf("unexpected token count: %d", got)
+	}
+}
+
 func TestMetaClient_ContinuousQueries(t *testing.T) {
 	t.Parallel()
 
@@ -834,42 +1041,70 @@ func TestMetaClient_Subscriptions_Create(t *testing.T) {
 	}
 
 	// Create a subscription
-	if err := c.CreateSubscription("db0", "autogen", "sub0", "ALL", []string{"udp://example.com:9090"}); err != nil {
+	if err := c.CreateSubscription("db0", "autogen", "sub0", "ALL", []string{"udp://example.com:9090"}, ""); err != nil {
 		t.Fatal(err)
 	}
 
 	// Re-create a subscription
-	err := c.CreateSubscription("db0", "autogen", "sub0", "ALL", []string{"udp://example.com:9090"})
+	err := c.CreateSubscription("db0", "autogen", "sub0", "ALL", []string{"udp://example.com:9090"}, "")
 	if err == nil || err.Error() != `subscription already exists` {
 		t.Fatalf("unexpected error: %s", err)
 	}
 
 	// Create another subscription.
-	if err := c.CreateSubscription("db0", "autogen", "sub1", "ALL", []string{"udp://example.com:6060"}); err != nil {
+	if err := c.CreateSubscription("db0", "autogen", "sub1", "ALL", []string{"udp://example.com:6060"}, ""); err != nil {
 		t.Fatal(err)
 	}
 
 	// Create a subscription with invalid scheme
-	err = c.CreateSubscription("db0", "autogen", "sub2", "ALL", []string{"bad://example.com:9191"})
+	err = c.CreateSubscription("db0", "autogen", "sub2", "ALL", []string{"bad://example.com:9191"}, "")
 	if err == nil || !strings.HasPrefix(err.Error(), "invalid subscription URL") {
 		t.Fatalf("unexpected error: %s", err)
 	}
 
 	// Create a subscription without port number
-	err = c.CreateSubscription("db0", "autogen", "sub2", "ALL", []string{"udp://example.com"})
+	err = c.CreateSubscription("db0", "autogen", "sub2", "ALL", []string{"udp://example.com"}, "")
 	if err == nil || !strings.HasPrefix(err.Error(), "invalid subscription URL") {
 		t.Fatalf("unexpected error: %s", err)
 	}
 
 	// Create an HTTP subscription.
-	if err := c.CreateSubscription("db0", "autogen", "sub3", "ALL", []string{"http://example.com:9092"}); err != nil {
+	if err := c.CreateSubscription("db0", "autogen", "sub3", "ALL", []string{"http://example.com:9092"}, ""); err != nil {
 		t.Fatal(err)
 	}
 
 	// Create an HTTPS subscription.
-	if err := c.CreateSubscription("db0", "autogen", "sub4", "ALL", []string{"https://example.com:9092"}); err != nil {
+	if err := c.CreateSubscription("db0", "autogen", "sub4", "ALL", []string{"https://example.com:9092"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create Kafka and NATS subscriptions.
+	if err := c.CreateSubscription("db0", "autogen", "sub5", "ALL", []string{"kafka://example.com:9092/metrics", "nats://example.com:4222/metrics.db0"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a Kafka subscription without a topic
+	err = c.CreateSubscription("db0", "autogen", "sub6", "ALL", []string{"kafka://example.com:9092"}, "")
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid subscription URL") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Create a subscription with a filter
+	if err := c.CreateSubscription("db0", "autogen", "sub7", "ALL", []string{"udp://example.com:9090"}, `"measurement" =~ /^api_/ AND region = 'us-east'`); err != nil {
 		t.Fatal(err)
 	}
+	rp, err := c.RetentionPolicy("db0", "autogen")
+	if err != nil {
+		t.Fatal(err)
+	} else if got, exp := rp.Subscriptions[len(rp.Subscriptions)-1].Filter, `"measurement" =~ /^api_/ AND region = 'us-east'`; got != exp {
+		t.Fatalf("unexpected filter: got %q, exp %q", got, exp)
+	}
+
+	// Create a subscription with a filter that cannot be parsed
+	err = c.CreateSubscription("db0", "autogen", "sub8", "ALL", []string{"udp://example.com:9090"}, "region =")
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid subscription filter") {
+		t.Fatalf("unexpected error: %s", err)
+	}
 }
 
 func TestMetaClient_Subscriptions_Drop(t *testing.T) {
@@ -892,7 +1127,7 @@ func TestMetaClient_Subscriptions_Drop(t *testing.T) {
 	}
 
 	// Create a subscription.
-	if err := c.CreateSubscription("db0", "autogen", "sub0", "ALL", []string{"udp://example.com:9090"}); err != nil {
+	if err := c.CreateSubscription("db0", "autogen", "sub0", "ALL", []string{"udp://example.com:9090"}, ""); err != nil {
 		t.Fatal(err)
 	}
 
@@ -2,9 +2,12 @@ package meta
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
@@ -13,6 +16,19 @@ const (
 
 	// DefaultLoggingEnabled determines if log messages are printed for the meta service.
 	DefaultLoggingEnabled = true
+
+	// DefaultPasswordHash is the default algorithm passwords are hashed with.
+	DefaultPasswordHash = PasswordHashBcrypt
+
+	// DefaultArgon2Time is the default number of passes over the memory of
+	// argon2id hashes.
+	DefaultArgon2Time = 1
+
+	// DefaultArgon2Memory is the default memory used by argon2id hashes.
+	DefaultArgon2Memory = 64 * 1024 * 1024
+
+	// DefaultArgon2Threads is the default number of threads of argon2id hashes.
+	DefaultArgon2Threads = 4
 )
 
 // Config represents the meta configuration.
@@ -21,6 +37,19 @@ type Config struct {
 
 	RetentionAutoCreate bool `toml:"retention-autocreate"`
 	LoggingEnabled      bool `toml:"logging-enabled"`
+
+	// The algorithm new password hashes use and its parameters.  Passwords
+	// are rehashed when users authenticate with hashes made differently.
+	PasswordHash  string    `toml:"password-hash"`
+	BcryptCost    int       `toml:"bcrypt-cost"`
+	Argon2Time    uint32    `toml:"argon2-time"`
+	Argon2Memory  toml.Size `toml:"argon2-memory"`
+	Argon2Threads uint8     `toml:"argon2-threads"`
+
+	// Password policy.  Zero values disable the checks.
+	PasswordMinLength int           `toml:"password-min-length"`
+	PasswordHistory   int           `toml:"password-history"`
+	PasswordMaxAge    toml.Duration `toml:"password-max-age"`
 }
 
 // NewConfig builds a new configuration with default values.
@@ -28,6 +57,11 @@ func NewConfig() *Config {
 	return &Config{
 		RetentionAutoCreate: true,
 		LoggingEnabled:      DefaultLoggingEnabled,
+		PasswordHash:        DefaultPasswordHash,
+		BcryptCost:          bcryptCost,
+		Argon2Time:          DefaultArgon2Time,
+		Argon2Memory:        DefaultArgon2Memory,
+		Argon2Threads:       DefaultArgon2Threads,
 	}
 }
 
@@ -36,12 +70,41 @@ func (c *Config) Validate() error {
 	if c.Dir == "" {
 		return errors.New("Meta.Dir must be specified")
 	}
+
+	switch c.PasswordHash {
+	case PasswordHashBcrypt:
+		if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("meta.bcrypt-cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case PasswordHashArgon2id:
+		if c.Argon2Time == 0 {
+			return errors.New("meta.argon2-time must be positive")
+		} else if c.Argon2Threads == 0 {
+			return errors.New("meta.argon2-threads must be positive")
+		} else if c.Argon2Memory < toml.Size(8*1024*int(c.Argon2Threads)) {
+			return errors.New("meta.argon2-memory must be at least 8KiB per thread")
+		}
+	default:
+		return fmt.Errorf("unknown meta.password-hash %q, must be %s or %s", c.PasswordHash, PasswordHashBcrypt, PasswordHashArgon2id)
+	}
+
+	if c.PasswordMinLength < 0 {
+		return errors.New("meta.password-min-length must not be negative")
+	} else if c.PasswordHistory < 0 {
+		return errors.New("meta.password-history must not be negative")
+	} else if c.PasswordMaxAge < 0 {
+		return errors.New("meta.password-max-age must not be negative")
+	}
 	return nil
 }
 
 // Diagnostics returns a diagnostics representation of a subset of the Config.
 func (c *Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 	return diagnostics.RowFromMap(map[string]interface{}{
-		"dir": c.Dir,
+		"dir":                 c.Dir,
+		"password-hash":       c.PasswordHash,
+		"password-min-length": c.PasswordMinLength,
+		"password-history":    c.PasswordHistory,
+		"password-max-age":    c.PasswordMaxAge,
 	}), nil
 }
@@ -2,9 +2,11 @@ package meta_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/influxdata/influxdb/services/meta"
+	itoml "github.com/influxdata/influxdb/toml"
 )
 
 func TestConfig_Parse(t *testing.T) {
@@ -13,6 +15,11 @@ func TestConfig_Parse(t *testing.T) {
 	if _, err := toml.Decode(`
 dir = "/tmp/foo"
 logging-enabled = false
+password-hash = "argon2id"
+argon2-memory = "32m"
+password-min-length = 12
+password-history = 5
+password-max-age = "2160h"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -22,5 +29,38 @@ logging-enabled = false
 		t.Fatalf("unexpected dir: %s", c.Dir)
 	} else if c.LoggingEnabled {
 		t.Fatalf("unexpected logging enabled: %v", c.LoggingEnabled)
+	} else if c.PasswordHash != meta.PasswordHashArgon2id {
+		t.Fatalf("unexpected password hash: %s", c.PasswordHash)
+	} else if c.Argon2Memory != 32*1024*1024 {
+		t.Fatalf("unexpected argon2 memory: %d", c.Argon2Memory)
+	} else if c.PasswordMinLength != 12 {
+		t.Fatalf("unexpected password min length: %d", c.PasswordMinLength)
+	} else if c.PasswordHistory != 5 {
+		t.Fatalf("unexpected password history: %d", c.PasswordHistory)
+	} else if time.Duration(c.PasswordMaxAge) != 90*24*time.Hour {
+		t.Fatalf("unexpected password max age: %s", c.PasswordMaxAge)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := meta.NewConfig()
+	c.Dir = "/tmp/foo"
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, fn := range []func(c *meta.Config){
+		func(c *meta.Config) { c.PasswordHash = "md5" },
+		func(c *meta.Config) { c.BcryptCost = 100 },
+		func(c *meta.Config) { c.PasswordHash = meta.PasswordHashArgon2id; c.Argon2Time = 0 },
+		func(c *meta.Config) { c.PasswordHash = meta.PasswordHashArgon2id; c.Argon2Memory = itoml.Size(1024) },
+		func(c *meta.Config) { c.PasswordHistory = -1 },
+	} {
+		c := meta.NewConfig()
+		c.Dir = "/tmp/foo"
+		fn(c)
+		if err := c.Validate(); err == nil {
+			t.Fatalf("expected error for %+v", c)
+		}
 	}
 }
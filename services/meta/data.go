@@ -2,6 +2,7 @@ package meta
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"net/url"
 	"sort"
@@ -41,6 +42,7 @@ type Data struct {
 	ClusterID uint64
 	Databases []DatabaseInfo
 	Users     []UserInfo
+	Tokens    []TokenInfo
 
 	// adminUserExists provides a constant time mechanism for determining
 	// if there is at least one admin user.
@@ -98,6 +100,9 @@ func (data *Data) DropDatabase(name string) error {
 			for i := range data.Users {
 				delete(data.Users[i].Privileges, name)
 			}
+
+			// Remove all tokens scoped to this database.
+			data.dropTokens(func(ti *TokenInfo) bool { return ti.Database == name })
 			break
 		}
 	}
@@ -140,13 +145,18 @@ func (data *Data) CreateRetentionPolicy(database string, rpi *RetentionPolicyInf
 		return ErrIncompatibleDurations
 	}
 
+	if err := validateMeasurementDurations(rpi.MeasurementDurations, rpi.Duration); err != nil {
+		return err
+	}
+
 	// Find database.
 	di := data.Database(database)
 	if di == nil {
 		return influxdb.ErrDatabaseNotFound(database)
 	} else if rp := di.RetentionPolicy(rpi.Name); rp != nil {
 		// RP with that name already exists. Make sure they're the same.
-		if rp.ReplicaN != rpi.ReplicaN || rp.Duration != rpi.Duration || rp.ShardGroupDuration != rpi.ShardGroupDuration {
+		if rp.ReplicaN != rpi.ReplicaN || rp.Duration != rpi.Duration || rp.ShardGroupDuration != rpi.ShardGroupDuration ||
+			!measurementDurationsEqual(rp.MeasurementDurations, rpi.MeasurementDurations) || rp.Rollup != rpi.Rollup {
 			return ErrRetentionPolicyExists
 		}
 		// if they want to make it default, and it's not the default, it's not an identical command so it's an error
@@ -193,6 +203,13 @@ type RetentionPolicyUpdate struct {
 	Duration           *time.Duration
 	ReplicaN           *int
 	ShardGroupDuration *time.Duration
+
+	// MeasurementDurations are the measurement durations to set. A zero
+	// duration removes the duration of the measurement.
+	MeasurementDurations map[string]time.Duration
+
+	// Rollup is the rollup query to set. An empty query removes the rollup.
+	Rollup *string
 }
 
 // SetName sets the RetentionPolicyUpdate.Name.
@@ -207,6 +224,17 @@ func (rpu *RetentionPolicyUpdate) SetReplicaN(v int) { rpu.ReplicaN = &v }
 // SetShardGroupDuration sets the RetentionPolicyUpdate.ShardGroupDuration.
 func (rpu *RetentionPolicyUpdate) SetShardGroupDuration(v time.Duration) { rpu.ShardGroupDuration = &v }
 
+// SetMeasurementDuration sets the duration of a measurement in RetentionPolicyUpdate.MeasurementDurations.
+func (rpu *RetentionPolicyUpdate) SetMeasurementDuration(name string, v time.Duration) {
+	if rpu.MeasurementDurations == nil {
+		rpu.MeasurementDurations = make(map[string]time.Duration)
+	}
+	rpu.MeasurementDurations[name] = v
+}
+
+// SetRollup sets the RetentionPolicyUpdate.Rollup.
+func (rpu *RetentionPolicyUpdate) SetRollup(v string) { rpu.Rollup = &v }
+
 // UpdateRetentionPolicy updates an existing retention policy.
 func (data *Data) UpdateRetentionPolicy(database, name string, rpu *RetentionPolicyUpdate, makeDefault bool) error {
 	// Find database.
@@ -240,6 +268,32 @@ func (data *Data) UpdateRetentionPolicy(database, name string, rpu *RetentionPol
 		return ErrIncompatibleDurations
 	}
 
+	// Enforce measurement durations are less than the policy duration.
+	duration := rpi.Duration
+	if rpu.Duration != nil {
+		duration = *rpu.Duration
+	}
+	mds := rpi.MeasurementDurations
+	if len(rpu.MeasurementDurations) > 0 {
+		mds = make(map[string]time.Duration, len(rpi.MeasurementDurations)+len(rpu.MeasurementDurations))
+		for name, d := range rpi.MeasurementDurations {
+			mds[name] = d
+		}
+		for name, d := range rpu.MeasurementDurations {
+			if d == 0 {
+				delete(mds, name)
+				continue
+			}
+			mds[name] = d
+		}
+		if len(mds) == 0 {
+			mds = nil
+		}
+	}
+	if err := validateMeasurementDurations(mds, duration); err != nil {
+		return err
+	}
+
 	// Update fields.
 	if rpu.Name != nil {
 		rpi.Name = *rpu.Name
@@ -253,6 +307,10 @@ func (data *Data) UpdateRetentionPolicy(database, name string, rpu *RetentionPol
 	if rpu.ShardGroupDuration != nil {
 		rpi.ShardGroupDuration = normalisedShardDuration(*rpu.ShardGroupDuration, rpi.Duration)
 	}
+	rpi.MeasurementDurations = mds
+	if rpu.Rollup != nil {
+		rpi.Rollup = *rpu.Rollup
+	}
 
 	if di.DefaultRetentionPolicy != rpi.Name && makeDefault {
 		di.DefaultRetentionPolicy = rpi.Name
@@ -261,6 +319,110 @@ func (data *Data) UpdateRetentionPolicy(database, name string, rpu *RetentionPol
 	return nil
 }
 
+// QuotaUpdate represents the quotas of a database to update.  A nil quota
+// is left unchanged, and a zero quota removes it.
+type QuotaUpdate struct {
+	DiskBytes       *int64
+	SeriesN         *int64
+	WritesPerSecond *int64
+}
+
+// SetDiskBytes sets the QuotaUpdate.DiskBytes.
+func (qu *QuotaUpdate) SetDiskBytes(v int64) { qu.DiskBytes = &v }
+
+// SetSeriesN sets the QuotaUpdate.SeriesN.
+func (qu *QuotaUpdate) SetSeriesN(v int64) { qu.SeriesN = &v }
+
+// SetWritesPerSecond sets the QuotaUpdate.WritesPerSecond.
+func (qu *QuotaUpdate) SetWritesPerSecond(v int64) { qu.WritesPerSecond = &v }
+
+// UpdateQuota updates the quotas of a database.
+func (data *Data) UpdateQuota(database string, qu *QuotaUpdate) error {
+	di := data.Database(database)
+	if di == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	}
+
+	for _, v := range []*int64{qu.DiskBytes, qu.SeriesN, qu.WritesPerSecond} {
+		if v != nil && *v < 0 {
+			return ErrQuotaNegative
+		}
+	}
+
+	if qu.DiskBytes != nil {
+		di.Quota.DiskBytes = *qu.DiskBytes
+	}
+	if qu.SeriesN != nil {
+		di.Quota.SeriesN = *qu.SeriesN
+	}
+	if qu.WritesPerSecond != nil {
+		di.Quota.WritesPerSecond = *qu.WritesPerSecond
+	}
+	return nil
+}
+
+// WriteLimitsUpdate represents the write limits of a database to update.  A
+// nil limit is left unchanged, and a zero limit removes it.
+type WriteLimitsUpdate struct {
+	Future *time.Duration
+	Past   *time.Duration
+	Clamp  *bool
+}
+
+// SetFuture sets the WriteLimitsUpdate.Future.
+func (wlu *WriteLimitsUpdate) SetFuture(v time.Duration) { wlu.Future = &v }
+
+// SetPast sets the WriteLimitsUpdate.Past.
+func (wlu *WriteLimitsUpdate) SetPast(v time.Duration) { wlu.Past = &v }
+
+// SetClamp sets the WriteLimitsUpdate.Clamp.
+func (wlu *WriteLimitsUpdate) SetClamp(v bool) { wlu.Clamp = &v }
+
+// UpdateWriteLimits updates the write limits of a database.
+func (data *Data) UpdateWriteLimits(database string, wlu *WriteLimitsUpdate) error {
+	di := data.Database(database)
+	if di == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	}
+
+	for _, v := range []*time.Duration{wlu.Future, wlu.Past} {
+		if v != nil && *v < 0 {
+			return ErrWriteLimitNegative
+		}
+	}
+
+	if wlu.Future != nil {
+		di.WriteLimits.Future = *wlu.Future
+	}
+	if wlu.Past != nil {
+		di.WriteLimits.Past = *wlu.Past
+	}
+	if wlu.Clamp != nil {
+		di.WriteLimits.Clamp = *wlu.Clamp
+	}
+	return nil
+}
+
+// SetSchema sets the schema of a database, or removes it if schema is nil.
+func (data *Data) SetSchema(database string, schema *SchemaInfo) error {
+	di := data.Database(database)
+	if di == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	}
+
+	if schema == nil {
+		di.Schema = nil
+		return nil
+	}
+
+	if err := schema.Validate(); err != nil {
+		return err
+	}
+	other := schema.clone()
+	di.Schema = &other
+	return nil
+}
+
 // DropShard removes a shard by ID.
 //
 // DropShard won't return an error if the shard can't be found, which
@@ -406,6 +568,79 @@ func (data *Data) DeleteShardGroup(database, policy string, id uint64) error {
 	return ErrShardGroupNotFound
 }
 
+// ReplaceShardGroups deletes the shard groups with the given IDs and creates a
+// shard group, with a single new shard, for the time range of each of groups
+// in their place.  The new shard groups may not overlap each other or any shard
+// group that is not replaced.  The new shard groups are returned.
+func (data *Data) ReplaceShardGroups(database, policy string, ids []uint64, groups []ShardGroupInfo) ([]ShardGroupInfo, error) {
+	// Find retention policy.
+	rpi, err := data.RetentionPolicy(database, policy)
+	if err != nil {
+		return nil, err
+	} else if rpi == nil {
+		return nil, influxdb.ErrRetentionPolicyNotFound(policy)
+	}
+
+	replaced := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		replaced[id] = false
+	}
+	for _, sgi := range rpi.ShardGroups {
+		if _, ok := replaced[sgi.ID]; ok && !sgi.Deleted() {
+			replaced[sgi.ID] = true
+		}
+	}
+	for _, found := range replaced {
+		if !found {
+			return nil, ErrShardGroupNotFound
+		}
+	}
+
+	for i, g := range groups {
+		if !g.StartTime.Before(g.EndTime) {
+			return nil, fmt.Errorf("invalid shard group time range: %s to %s", g.StartTime, g.EndTime)
+		}
+		for _, other := range groups[:i] {
+			if g.StartTime.Before(other.EndTime) && other.StartTime.Before(g.EndTime) {
+				return nil, ErrShardGroupExists
+			}
+		}
+		for _, sgi := range rpi.ShardGroups {
+			if sgi.Deleted() || replaced[sgi.ID] {
+				continue
+			}
+			if g.StartTime.Before(sgi.EndTime) && sgi.StartTime.Before(g.EndTime) {
+				return nil, ErrShardGroupExists
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	for i := range rpi.ShardGroups {
+		if replaced[rpi.ShardGroups[i].ID] {
+			rpi.ShardGroups[i].DeletedAt = now
+		}
+	}
+
+	created := make([]ShardGroupInfo, 0, len(groups))
+	for _, g := range groups {
+		data.MaxShardGroupID++
+		data.MaxShardID++
+		created = append(created, ShardGroupInfo{
+			ID:        data.MaxShardGroupID,
+			StartTime: g.StartTime.UTC(),
+			EndTime:   g.EndTime.UTC(),
+			Shards:    []ShardInfo{{ID: data.MaxShardID}},
+		})
+	}
+
+	// Shard groups must be stored in sorted order.
+	rpi.ShardGroups = append(rpi.ShardGroups, created...)
+	sort.Sort(ShardGroupInfos(rpi.ShardGroups))
+
+	return created, nil
+}
+
 // CreateContinuousQuery adds a named continuous query to a database.
 func (data *Data) CreateContinuousQuery(database, name, query string) error {
 	di := data.Database(database)
@@ -451,14 +686,21 @@ func (data *Data) DropContinuousQuery(database, name string) error {
 	return ErrContinuousQueryNotFound
 }
 
-// validateURL returns an error if the URL does not have a port or uses a scheme other than UDP or HTTP.
+// validateURL returns an error if the URL does not have a port or uses a scheme other than UDP, HTTP,
+// Kafka or NATS.  Kafka and NATS URLs must also have a topic or subject as their path.
 func validateURL(input string) error {
 	u, err := url.Parse(input)
 	if err != nil {
 		return ErrInvalidSubscriptionURL(input)
 	}
 
-	if u.Scheme != "udp" && u.Scheme != "http" && u.Scheme != "https" {
+	switch u.Scheme {
+	case "udp", "http", "https":
+	case "kafka", "nats":
+		if strings.Trim(u.Path, "/") == "" {
+			return ErrInvalidSubscriptionURL(input)
+		}
+	default:
 		return ErrInvalidSubscriptionURL(input)
 	}
 
@@ -471,12 +713,18 @@ func validateURL(input string) error {
 }
 
 // CreateSubscription adds a named subscription to a database and retention policy.
-func (data *Data) CreateSubscription(database, rp, name, mode string, destinations []string) error {
+// Only the points matching filter are forwarded, unless it is empty.
+func (data *Data) CreateSubscription(database, rp, name, mode string, destinations []string, filter string) error {
 	for _, d := range destinations {
 		if err := validateURL(d); err != nil {
 			return err
 		}
 	}
+	if filter != "" {
+		if _, err := influxql.ParseExpr(filter); err != nil {
+			return ErrInvalidSubscriptionFilter(filter)
+		}
+	}
 
 	rpi, err := data.RetentionPolicy(database, rp)
 	if err != nil {
@@ -497,6 +745,7 @@ func (data *Data) CreateSubscription(database, rp, name, mode string, destinatio
 		Name:         name,
 		Mode:         mode,
 		Destinations: destinations,
+		Filter:       filter,
 	})
 
 	return nil
@@ -570,6 +819,9 @@ func (data *Data) DropUser(name string) error {
 			wasAdmin := data.Users[i].Admin
 			data.Users = append(data.Users[:i], data.Users[i+1:]...)
 
+			// Remove all tokens of the user.
+			data.dropTokens(func(ti *TokenInfo) bool { return ti.User == name })
+
 			// Maybe we dropped the only admin user?
 			if wasAdmin {
 				data.adminUserExists = data.hasAdminUser()
@@ -592,6 +844,28 @@ func (data *Data) UpdateUser(name, hash string) error {
 	return ErrUserNotFound
 }
 
+// SetPassword sets the password hash of an existing user, changed at t, and
+// keeps up to history hashes of its previous passwords.
+func (data *Data) SetPassword(name, hash string, t time.Time, history int) error {
+	ui := data.user(name)
+	if ui == nil {
+		return ErrUserNotFound
+	}
+
+	if history > 0 {
+		ui.PasswordHistory = append([]string{ui.Hash}, ui.PasswordHistory...)
+	}
+	if len(ui.PasswordHistory) > history {
+		ui.PasswordHistory = ui.PasswordHistory[:history]
+	}
+	if len(ui.PasswordHistory) == 0 {
+		ui.PasswordHistory = nil
+	}
+	ui.Hash = hash
+	ui.PasswordChanged = t
+	return nil
+}
+
 // CloneUsers returns a copy of the user infos.
 func (data *Data) CloneUsers() []UserInfo {
 	if len(data.Users) == 0 {
@@ -670,12 +944,78 @@ func (data *Data) UserPrivilege(name, database string) (*influxql.Privilege, err
 	return influxql.NewPrivilege(influxql.NoPrivileges), nil
 }
 
+// token returns the token with the given ID.
+func (data *Data) token(id string) *TokenInfo {
+	for i := range data.Tokens {
+		if data.Tokens[i].ID == id {
+			return &data.Tokens[i]
+		}
+	}
+	return nil
+}
+
+// CreateToken creates a new API token of a user.  If database is set, the
+// token is limited to privilege p on it.  A zero expires creates a token that
+// doesn't expire.
+func (data *Data) CreateToken(id, username, hash, database string, p influxql.Privilege, expires time.Time) error {
+	if id == "" {
+		return ErrTokenIDRequired
+	} else if data.token(id) != nil {
+		return ErrTokenExists
+	} else if data.user(username) == nil {
+		return ErrUserNotFound
+	} else if database != "" && data.Database(database) == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	}
+
+	data.Tokens = append(data.Tokens, TokenInfo{
+		ID:        id,
+		User:      username,
+		Hash:      hash,
+		Database:  database,
+		Privilege: p,
+		Expires:   expires,
+	})
+	return nil
+}
+
+// DropToken removes an existing token by ID.
+func (data *Data) DropToken(id string) error {
+	if data.token(id) == nil {
+		return ErrTokenNotFound
+	}
+	data.dropTokens(func(ti *TokenInfo) bool { return ti.ID == id })
+	return nil
+}
+
+// dropTokens removes the tokens fn returns true for.
+func (data *Data) dropTokens(fn func(ti *TokenInfo) bool) {
+	tokens := data.Tokens[:0]
+	for i := range data.Tokens {
+		if !fn(&data.Tokens[i]) {
+			tokens = append(tokens, data.Tokens[i])
+		}
+	}
+	data.Tokens = tokens
+}
+
+// CloneTokens returns a copy of the token infos.
+func (data *Data) CloneTokens() []TokenInfo {
+	if len(data.Tokens) == 0 {
+		return nil
+	}
+	tokens := make([]TokenInfo, len(data.Tokens))
+	copy(tokens, data.Tokens)
+	return tokens
+}
+
 // Clone returns a copy of data with a new version.
 func (data *Data) Clone() *Data {
 	other := *data
 
 	other.Databases = data.CloneDatabases()
 	other.Users = data.CloneUsers()
+	other.Tokens = data.CloneTokens()
 
 	return &other
 }
@@ -704,6 +1044,11 @@ func (data *Data) marshal() *internal.Data {
 		pb.Users[i] = data.Users[i].marshal()
 	}
 
+	pb.Tokens = make([]*internal.TokenInfo, len(data.Tokens))
+	for i := range data.Tokens {
+		pb.Tokens[i] = data.Tokens[i].marshal()
+	}
+
 	return pb
 }
 
@@ -726,6 +1071,14 @@ func (data *Data) unmarshal(pb *internal.Data) {
 		data.Users[i].unmarshal(x)
 	}
 
+	data.Tokens = nil
+	if len(pb.GetTokens()) > 0 {
+		data.Tokens = make([]TokenInfo, len(pb.GetTokens()))
+		for i, x := range pb.GetTokens() {
+			data.Tokens[i].unmarshal(x)
+		}
+	}
+
 	// Exhaustively determine if there is an admin user. The marshalled cache
 	// value may not be correct.
 	data.adminUserExists = data.hasAdminUser()
@@ -782,6 +1135,224 @@ type DatabaseInfo struct {
 	DefaultRetentionPolicy string
 	RetentionPolicies      []RetentionPolicyInfo
 	ContinuousQueries      []ContinuousQueryInfo
+	Quota                  QuotaInfo
+
+	// Schema, if set, is the schema of the points written to the database.
+	Schema *SchemaInfo
+
+	WriteLimits WriteLimitsInfo
+}
+
+// QuotaInfo represents the quotas of a database.  A zero quota is unlimited.
+type QuotaInfo struct {
+	// DiskBytes is the maximum size of the shards of the database on disk.
+	DiskBytes int64
+
+	// SeriesN is the maximum number of series in the database.
+	SeriesN int64
+
+	// WritesPerSecond is the maximum rate points are written to the database.
+	WritesPerSecond int64
+}
+
+// IsZero returns true if no quota is set.
+func (qi QuotaInfo) IsZero() bool {
+	return qi == QuotaInfo{}
+}
+
+// marshal serializes to a protobuf representation.
+func (qi QuotaInfo) marshal() *internal.QuotaInfo {
+	return &internal.QuotaInfo{
+		DiskBytes:       proto.Int64(qi.DiskBytes),
+		SeriesN:         proto.Int64(qi.SeriesN),
+		WritesPerSecond: proto.Int64(qi.WritesPerSecond),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (qi *QuotaInfo) unmarshal(pb *internal.QuotaInfo) {
+	qi.DiskBytes = pb.GetDiskBytes()
+	qi.SeriesN = pb.GetSeriesN()
+	qi.WritesPerSecond = pb.GetWritesPerSecond()
+}
+
+// WriteLimitsInfo represents how far from the current time the timestamps of
+// the points written to a database may be.  A zero limit is unlimited.
+type WriteLimitsInfo struct {
+	// Future is the maximum time a point may be ahead of the current time.
+	Future time.Duration
+
+	// Past is the maximum time a point may be behind the current time.
+	Past time.Duration
+
+	// Clamp is true if the timestamps of points beyond a limit are moved to
+	// the limit, rather than the points rejected.
+	Clamp bool
+}
+
+// IsZero returns true if no write limit is set.
+func (wli WriteLimitsInfo) IsZero() bool {
+	return wli.Future == 0 && wli.Past == 0
+}
+
+// marshal serializes to a protobuf representation.
+func (wli WriteLimitsInfo) marshal() *internal.WriteLimitsInfo {
+	return &internal.WriteLimitsInfo{
+		Future: proto.Int64(int64(wli.Future)),
+		Past:   proto.Int64(int64(wli.Past)),
+		Clamp:  proto.Bool(wli.Clamp),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (wli *WriteLimitsInfo) unmarshal(pb *internal.WriteLimitsInfo) {
+	wli.Future = time.Duration(pb.GetFuture())
+	wli.Past = time.Duration(pb.GetPast())
+	wli.Clamp = pb.GetClamp()
+}
+
+// Schema modes.
+const (
+	// SchemaStrict rejects the points that don't conform to the schema.
+	SchemaStrict = "strict"
+
+	// SchemaWarn writes the points that don't conform to the schema, only
+	// counting them.
+	SchemaWarn = "warn"
+)
+
+// SchemaInfo represents the measurements, tag keys and fields allowed in the
+// points written to a database.
+type SchemaInfo struct {
+	Mode         string
+	Measurements []MeasurementSchemaInfo
+}
+
+// MeasurementSchemaInfo represents the tag keys and fields allowed in the
+// points of a measurement.  A point may omit any of them.
+type MeasurementSchemaInfo struct {
+	Name    string
+	TagKeys []string
+	Fields  []FieldSchemaInfo
+}
+
+// FieldSchemaInfo represents a field and its type.
+type FieldSchemaInfo struct {
+	Name string
+	Type influxql.DataType
+}
+
+// Measurement returns the schema of a measurement by name, or nil if the
+// measurement isn't allowed.
+func (si *SchemaInfo) Measurement(name string) *MeasurementSchemaInfo {
+	for i := range si.Measurements {
+		if si.Measurements[i].Name == name {
+			return &si.Measurements[i]
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if the schema is invalid.
+func (si *SchemaInfo) Validate() error {
+	if si.Mode != SchemaStrict && si.Mode != SchemaWarn {
+		return ErrSchemaModeInvalid
+	}
+
+	measurements := make(map[string]struct{}, len(si.Measurements))
+	for _, msi := range si.Measurements {
+		if msi.Name == "" {
+			return ErrSchemaMeasurementNameRequired
+		} else if _, ok := measurements[msi.Name]; ok {
+			return fmt.Errorf("schema measurement %q is duplicated", msi.Name)
+		}
+		measurements[msi.Name] = struct{}{}
+
+		for _, f := range msi.Fields {
+			switch {
+			case f.Name == "":
+				return ErrSchemaFieldNameRequired
+			case f.Type != influxql.Float && f.Type != influxql.Integer && f.Type != influxql.Unsigned &&
+				f.Type != influxql.String && f.Type != influxql.Boolean:
+				return fmt.Errorf("schema field %q of measurement %q has an invalid type", f.Name, msi.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// HasTagKey returns true if the tag key is allowed.
+func (msi *MeasurementSchemaInfo) HasTagKey(key string) bool {
+	for _, k := range msi.TagKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Field returns a field by name, or nil if the field isn't allowed.
+func (msi *MeasurementSchemaInfo) Field(name string) *FieldSchemaInfo {
+	for i := range msi.Fields {
+		if msi.Fields[i].Name == name {
+			return &msi.Fields[i]
+		}
+	}
+	return nil
+}
+
+// clone returns a deep copy of si.
+func (si SchemaInfo) clone() SchemaInfo {
+	other := si
+	other.Measurements = make([]MeasurementSchemaInfo, len(si.Measurements))
+	for i, msi := range si.Measurements {
+		other.Measurements[i] = MeasurementSchemaInfo{
+			Name:    msi.Name,
+			TagKeys: append([]string(nil), msi.TagKeys...),
+			Fields:  append([]FieldSchemaInfo(nil), msi.Fields...),
+		}
+	}
+	return other
+}
+
+// marshal serializes to a protobuf representation.
+func (si SchemaInfo) marshal() *internal.SchemaInfo {
+	pb := &internal.SchemaInfo{
+		Mode:         proto.String(si.Mode),
+		Measurements: make([]*internal.MeasurementSchemaInfo, len(si.Measurements)),
+	}
+	for i, msi := range si.Measurements {
+		m := &internal.MeasurementSchemaInfo{
+			Name:    proto.String(msi.Name),
+			TagKeys: msi.TagKeys,
+			Fields:  make([]*internal.FieldSchemaInfo, len(msi.Fields)),
+		}
+		for j, f := range msi.Fields {
+			m.Fields[j] = &internal.FieldSchemaInfo{
+				Name: proto.String(f.Name),
+				Type: proto.Int32(int32(f.Type)),
+			}
+		}
+		pb.Measurements[i] = m
+	}
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (si *SchemaInfo) unmarshal(pb *internal.SchemaInfo) {
+	si.Mode = pb.GetMode()
+	si.Measurements = make([]MeasurementSchemaInfo, len(pb.GetMeasurements()))
+	for i, m := range pb.GetMeasurements() {
+		msi := MeasurementSchemaInfo{
+			Name:    m.GetName(),
+			TagKeys: m.GetTagKeys(),
+			Fields:  make([]FieldSchemaInfo, len(m.GetFields())),
+		}
+		for j, f := range m.GetFields() {
+			msi.Fields[j] = FieldSchemaInfo{Name: f.GetName(), Type: influxql.DataType(f.GetType())}
+		}
+		si.Measurements[i] = msi
+	}
 }
 
 // RetentionPolicy returns a retention policy by name.
@@ -845,6 +1416,11 @@ func (di DatabaseInfo) clone() DatabaseInfo {
 		}
 	}
 
+	if di.Schema != nil {
+		schema := di.Schema.clone()
+		other.Schema = &schema
+	}
+
 	return other
 }
 
@@ -863,6 +1439,18 @@ func (di DatabaseInfo) marshal() *internal.DatabaseInfo {
 	for i := range di.ContinuousQueries {
 		pb.ContinuousQueries[i] = di.ContinuousQueries[i].marshal()
 	}
+
+	if !di.Quota.IsZero() {
+		pb.Quota = di.Quota.marshal()
+	}
+
+	if di.Schema != nil {
+		pb.Schema = di.Schema.marshal()
+	}
+
+	if di.WriteLimits != (WriteLimitsInfo{}) {
+		pb.WriteLimits = di.WriteLimits.marshal()
+	}
 	return pb
 }
 
@@ -884,14 +1472,29 @@ func (di *DatabaseInfo) unmarshal(pb *internal.DatabaseInfo) {
 			di.ContinuousQueries[i].unmarshal(x)
 		}
 	}
+
+	if pb.Quota != nil {
+		di.Quota.unmarshal(pb.GetQuota())
+	}
+
+	if pb.Schema != nil {
+		di.Schema = &SchemaInfo{}
+		di.Schema.unmarshal(pb.GetSchema())
+	}
+
+	if pb.WriteLimits != nil {
+		di.WriteLimits.unmarshal(pb.GetWriteLimits())
+	}
 }
 
 // RetentionPolicySpec represents the specification for a new retention policy.
 type RetentionPolicySpec struct {
-	Name               string
-	ReplicaN           *int
-	Duration           *time.Duration
-	ShardGroupDuration time.Duration
+	Name                 string
+	ReplicaN             *int
+	Duration             *time.Duration
+	ShardGroupDuration   time.Duration
+	MeasurementDurations map[string]time.Duration
+	Rollup               string
 }
 
 // NewRetentionPolicyInfo creates a new retention policy info from the specification.
@@ -910,6 +1513,10 @@ func (s *RetentionPolicySpec) Matches(rpi *RetentionPolicyInfo) bool {
 		return false
 	} else if s.ReplicaN != nil && *s.ReplicaN != rpi.ReplicaN {
 		return false
+	} else if s.MeasurementDurations != nil && !measurementDurationsEqual(s.MeasurementDurations, rpi.MeasurementDurations) {
+		return false
+	} else if s.Rollup != "" && s.Rollup != rpi.Rollup {
+		return false
 	}
 
 	// Normalise ShardDuration before comparing to any existing retention policies.
@@ -978,6 +1585,14 @@ type RetentionPolicyInfo struct {
 	ShardGroupDuration time.Duration
 	ShardGroups        []ShardGroupInfo
 	Subscriptions      []SubscriptionInfo
+
+	// MeasurementDurations are the durations of measurements that expire
+	// before the policy duration.
+	MeasurementDurations map[string]time.Duration
+
+	// Rollup is a SELECT INTO statement run over each shard group before it
+	// is deleted.
+	Rollup string
 }
 
 // NewRetentionPolicyInfo returns a new instance of RetentionPolicyInfo
@@ -1014,6 +1629,13 @@ func (rpi *RetentionPolicyInfo) Apply(spec *RetentionPolicySpec) *RetentionPolic
 		rp.Duration = *spec.Duration
 	}
 	rp.ShardGroupDuration = normalisedShardDuration(spec.ShardGroupDuration, rp.Duration)
+	if len(spec.MeasurementDurations) > 0 {
+		rp.MeasurementDurations = make(map[string]time.Duration, len(spec.MeasurementDurations))
+		for name, d := range spec.MeasurementDurations {
+			rp.MeasurementDurations[name] = d
+		}
+	}
+	rp.Rollup = spec.Rollup
 	return rp
 }
 
@@ -1074,6 +1696,22 @@ func (rpi *RetentionPolicyInfo) marshal() *internal.RetentionPolicyInfo {
 		pb.Subscriptions[i] = sub.marshal()
 	}
 
+	names := make([]string, 0, len(rpi.MeasurementDurations))
+	for name := range rpi.MeasurementDurations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pb.MeasurementDurations = append(pb.MeasurementDurations, &internal.MeasurementDuration{
+			Name:     proto.String(name),
+			Duration: proto.Int64(int64(rpi.MeasurementDurations[name])),
+		})
+	}
+
+	if rpi.Rollup != "" {
+		pb.Rollup = proto.String(rpi.Rollup)
+	}
+
 	return pb
 }
 
@@ -1096,6 +1734,13 @@ func (rpi *RetentionPolicyInfo) unmarshal(pb *internal.RetentionPolicyInfo) {
 			rpi.Subscriptions[i].unmarshal(x)
 		}
 	}
+	if len(pb.GetMeasurementDurations()) > 0 {
+		rpi.MeasurementDurations = make(map[string]time.Duration, len(pb.GetMeasurementDurations()))
+		for _, x := range pb.GetMeasurementDurations() {
+			rpi.MeasurementDurations[x.GetName()] = time.Duration(x.GetDuration())
+		}
+	}
+	rpi.Rollup = pb.GetRollup()
 }
 
 // clone returns a deep copy of rpi.
@@ -1109,6 +1754,13 @@ func (rpi RetentionPolicyInfo) clone() RetentionPolicyInfo {
 		}
 	}
 
+	if rpi.MeasurementDurations != nil {
+		other.MeasurementDurations = make(map[string]time.Duration, len(rpi.MeasurementDurations))
+		for name, d := range rpi.MeasurementDurations {
+			other.MeasurementDurations[name] = d
+		}
+	}
+
 	return other
 }
 
@@ -1127,6 +1779,32 @@ func (rpi *RetentionPolicyInfo) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// validateMeasurementDurations returns an error if a measurement duration is
+// too low or is not less than the duration of its retention policy.
+func validateMeasurementDurations(mds map[string]time.Duration, duration time.Duration) error {
+	for _, d := range mds {
+		if d < MinRetentionPolicyDuration {
+			return ErrMeasurementDurationTooLow
+		} else if duration > 0 && d >= duration {
+			return ErrIncompatibleMeasurementDuration
+		}
+	}
+	return nil
+}
+
+// measurementDurationsEqual returns true if a and b hold the same durations.
+func measurementDurationsEqual(a, b map[string]time.Duration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, d := range a {
+		if other, ok := b[name]; !ok || other != d {
+			return false
+		}
+	}
+	return true
+}
+
 // shardGroupDuration returns the default duration for a shard group based on a policy duration.
 func shardGroupDuration(d time.Duration) time.Duration {
 	if d >= 180*24*time.Hour || d == 0 { // 6 months or 0
@@ -1359,6 +2037,10 @@ type SubscriptionInfo struct {
 	Name         string
 	Mode         string
 	Destinations []string
+
+	// Filter is the condition that points must match to be forwarded.
+	// All points are forwarded if it is empty.
+	Filter string
 }
 
 // marshal serializes to a protobuf representation.
@@ -1367,6 +2049,9 @@ func (si SubscriptionInfo) marshal() *internal.SubscriptionInfo {
 		Name: proto.String(si.Name),
 		Mode: proto.String(si.Mode),
 	}
+	if si.Filter != "" {
+		pb.Filter = proto.String(si.Filter)
+	}
 
 	pb.Destinations = make([]string, len(si.Destinations))
 	for i := range si.Destinations {
@@ -1379,6 +2064,7 @@ func (si SubscriptionInfo) marshal() *internal.SubscriptionInfo {
 func (si *SubscriptionInfo) unmarshal(pb *internal.SubscriptionInfo) {
 	si.Name = pb.GetName()
 	si.Mode = pb.GetMode()
+	si.Filter = pb.GetFilter()
 
 	if len(pb.GetDestinations()) > 0 {
 		si.Destinations = make([]string, len(pb.GetDestinations()))
@@ -1448,6 +2134,13 @@ type UserInfo struct {
 
 	// Map of database name to granted privilege.
 	Privileges map[string]influxql.Privilege
+
+	// Hashes of the previous passwords of the user, most recent first.
+	PasswordHistory []string
+
+	// Time the password was last set.  It is zero for users whose password
+	// hasn't been set since it started being recorded.
+	PasswordChanged time.Time
 }
 
 type User interface {
@@ -1494,6 +2187,11 @@ func (ui UserInfo) clone() UserInfo {
 		}
 	}
 
+	if ui.PasswordHistory != nil {
+		other.PasswordHistory = make([]string, len(ui.PasswordHistory))
+		copy(other.PasswordHistory, ui.PasswordHistory)
+	}
+
 	return other
 }
 
@@ -1512,6 +2210,11 @@ func (ui UserInfo) marshal() *internal.UserInfo {
 		})
 	}
 
+	pb.PasswordHistory = ui.PasswordHistory
+	if !ui.PasswordChanged.IsZero() {
+		pb.PasswordChanged = proto.Int64(MarshalTime(ui.PasswordChanged))
+	}
+
 	return pb
 }
 
@@ -1525,6 +2228,107 @@ func (ui *UserInfo) unmarshal(pb *internal.UserInfo) {
 	for _, p := range pb.GetPrivileges() {
 		ui.Privileges[p.GetDatabase()] = influxql.Privilege(p.GetPrivilege())
 	}
+
+	ui.PasswordHistory = pb.GetPasswordHistory()
+	ui.PasswordChanged = UnmarshalTime(pb.GetPasswordChanged())
+}
+
+// TokenInfo represents metadata about an API token of a user.
+type TokenInfo struct {
+	// Public ID of the token.
+	ID string
+
+	// Name of the user the token authenticates as.
+	User string
+
+	// Hashed secret of the token.
+	Hash string
+
+	// Database the token is limited to, and the privilege it grants on it.
+	// An empty database grants all the privileges of the user.
+	Database  string
+	Privilege influxql.Privilege
+
+	// Time the token expires at.  A zero time never expires.
+	Expires time.Time
+}
+
+// Expired returns true if the token has expired at t.
+func (ti *TokenInfo) Expired(t time.Time) bool {
+	return !ti.Expires.IsZero() && !t.Before(ti.Expires)
+}
+
+// marshal serializes to a protobuf representation.
+func (ti TokenInfo) marshal() *internal.TokenInfo {
+	pb := &internal.TokenInfo{
+		ID:   proto.String(ti.ID),
+		User: proto.String(ti.User),
+		Hash: proto.String(ti.Hash),
+	}
+
+	if ti.Database != "" {
+		pb.Database = proto.String(ti.Database)
+		pb.Privilege = proto.Int32(int32(ti.Privilege))
+	}
+	if !ti.Expires.IsZero() {
+		pb.Expires = proto.Int64(MarshalTime(ti.Expires))
+	}
+
+	return pb
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ti *TokenInfo) unmarshal(pb *internal.TokenInfo) {
+	ti.ID = pb.GetID()
+	ti.User = pb.GetUser()
+	ti.Hash = pb.GetHash()
+	ti.Database = pb.GetDatabase()
+	ti.Privilege = influxql.Privilege(pb.GetPrivilege())
+	ti.Expires = UnmarshalTime(pb.GetExpires())
+}
+
+var _ User = (*TokenUser)(nil)
+
+// TokenUser is a user authenticated with an API token.  It has the
+// privileges of the user, limited to those of the token.
+type TokenUser struct {
+	*UserInfo
+	Token TokenInfo
+}
+
+// IsAdmin returns true if the user is an admin and the token isn't limited
+// to a database.
+func (u *TokenUser) IsAdmin() bool {
+	return u.Admin && u.Token.Database == ""
+}
+
+// AuthorizeDatabase returns true if both the user and the token are
+// authorized for the given privilege on the given database.
+func (u *TokenUser) AuthorizeDatabase(privilege influxql.Privilege, database string) bool {
+	if privilege == influxql.NoPrivileges {
+		return true
+	}
+	if t := u.Token; t.Database != "" {
+		if t.Database != database || (t.Privilege != privilege && t.Privilege != influxql.AllPrivileges) {
+			return false
+		}
+	}
+	return u.UserInfo.AuthorizeDatabase(privilege, database)
+}
+
+// AuthorizeQuery returns an error if the user or the token isn't authorized
+// to execute query.
+func (u *TokenUser) AuthorizeQuery(database string, query *influxql.Query) error {
+	return authorizeQuery(u, database, query)
+}
+
+var _ User = (*ExternalUser)(nil)
+
+// ExternalUser is a user authenticated, and given its privileges, by an
+// external identity provider rather than by the meta store.  It isn't stored
+// in the meta store.
+type ExternalUser struct {
+	*UserInfo
 }
 
 // Lease represents a lease held on a resource.
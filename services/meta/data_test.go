@@ -111,6 +111,126 @@ func Test_Data_CreateRetentionPolicy(t *testing.T) {
 	}
 }
 
+func Test_Data_RetentionPolicy_MeasurementDurations(t *testing.T) {
+	data := meta.Data{}
+	if err := data.CreateDatabase("foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Measurement durations must be less than the retention policy duration.
+	if err := data.CreateRetentionPolicy("foo", &meta.RetentionPolicyInfo{
+		Name:                 "bar",
+		ReplicaN:             1,
+		Duration:             24 * time.Hour,
+		MeasurementDurations: map[string]time.Duration{"cpu": 48 * time.Hour},
+	}, false); err != meta.ErrIncompatibleMeasurementDuration {
+		t.Fatalf("unexpected error.  got: %v, exp: %s", err, meta.ErrIncompatibleMeasurementDuration)
+	}
+
+	// Measurement durations must be at least the minimum duration.
+	if err := data.CreateRetentionPolicy("foo", &meta.RetentionPolicyInfo{
+		Name:                 "bar",
+		ReplicaN:             1,
+		Duration:             24 * time.Hour,
+		MeasurementDurations: map[string]time.Duration{"cpu": time.Minute},
+	}, false); err != meta.ErrMeasurementDurationTooLow {
+		t.Fatalf("unexpected error.  got: %v, exp: %s", err, meta.ErrMeasurementDurationTooLow)
+	}
+
+	if err := data.CreateRetentionPolicy("foo", &meta.RetentionPolicyInfo{
+		Name:                 "bar",
+		ReplicaN:             1,
+		Duration:             24 * time.Hour,
+		MeasurementDurations: map[string]time.Duration{"cpu": 2 * time.Hour, "mem": 6 * time.Hour},
+		Rollup:               `SELECT mean(value) INTO "rp1".:MEASUREMENT FROM /.*/ GROUP BY time(1h), *`,
+	}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Update a measurement duration and remove another.
+	rpu := &meta.RetentionPolicyUpdate{}
+	rpu.SetMeasurementDuration("cpu", 4*time.Hour)
+	rpu.SetMeasurementDuration("mem", 0)
+	if err := data.UpdateRetentionPolicy("foo", "bar", rpu, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shortening the retention policy below a measurement duration should fail.
+	rpu = &meta.RetentionPolicyUpdate{}
+	rpu.SetDuration(2 * time.Hour)
+	if err := data.UpdateRetentionPolicy("foo", "bar", rpu, false); err != meta.ErrIncompatibleMeasurementDuration {
+		t.Fatalf("unexpected error.  got: %v, exp: %s", err, meta.ErrIncompatibleMeasurementDuration)
+	}
+
+	// The measurement durations and rollup should survive a marshal round trip.
+	buf, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other meta.Data
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	rp, err := other.RetentionPolicy("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	} else if got, exp := rp.MeasurementDurations, map[string]time.Duration{"cpu": 4 * time.Hour}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected measurement durations.  got: %v, exp: %v", got, exp)
+	} else if got, exp := rp.Rollup, `SELECT mean(value) INTO "rp1".:MEASUREMENT FROM /.*/ GROUP BY time(1h), *`; got != exp {
+		t.Fatalf("unexpected rollup.  got: %s, exp: %s", got, exp)
+	}
+}
+
+func TestData_ReplaceShardGroups(t *testing.T) {
+	data := meta.Data{}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateRetentionPolicy("db0", &meta.RetentionPolicyInfo{
+		Name:               "rp0",
+		ReplicaN:           1,
+		ShardGroupDuration: time.Hour,
+	}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := data.CreateShardGroup("db0", "rp0", start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The new group may not overlap the group that is not replaced.
+	if _, err := data.ReplaceShardGroups("db0", "rp0", []uint64{1, 2}, []meta.ShardGroupInfo{
+		{StartTime: start, EndTime: start.Add(3 * time.Hour)},
+	}); err != meta.ErrShardGroupExists {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := data.ReplaceShardGroups("db0", "rp0", []uint64{1, 4}, []meta.ShardGroupInfo{
+		{StartTime: start, EndTime: start.Add(2 * time.Hour)},
+	}); err != meta.ErrShardGroupNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	created, err := data.ReplaceShardGroups("db0", "rp0", []uint64{1, 2}, []meta.ShardGroupInfo{
+		{StartTime: start, EndTime: start.Add(2 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(created) != 1 || created[0].ID != 4 || created[0].Shards[0].ID != 4 {
+		t.Fatalf("unexpected shard groups: %+v", created)
+	}
+
+	groups, err := data.ShardGroups("db0", "rp0")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(groups) != 2 || groups[0].ID != 4 || groups[1].ID != 3 {
+		t.Fatalf("unexpected shard groups: %+v", groups)
+	}
+}
+
 func TestData_AdminUserExists(t *testing.T) {
 	data := meta.Data{}
 
@@ -229,3 +349,187 @@ func TestUserInfo_AuthorizeDatabase(t *testing.T) {
 		t.Fatalf("expected admin to be authorized but it wasn't")
 	}
 }
+
+func TestData_Tokens(t *testing.T) {
+	data := &meta.Data{}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateUser("user1", "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	expires := time.Unix(0, 1000).UTC()
+	if err := data.CreateToken("a", "user1", "hash", "db0", influxql.WritePrivilege, expires); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateToken("b", "user1", "hash", "", influxql.NoPrivileges, time.Time{}); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateToken("a", "user1", "hash", "", influxql.NoPrivileges, time.Time{}); err != meta.ErrTokenExists {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := data.CreateToken("c", "user1", "hash", "db1", influxql.ReadPrivilege, time.Time{}); err == nil {
+		t.Fatal("expected error for a token of a database that doesn't exist")
+	}
+
+	buf, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other meta.Data
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(other.Tokens, data.Tokens) {
+		t.Fatalf("unexpected tokens: got %+v, exp %+v", other.Tokens, data.Tokens)
+	}
+
+	// Dropping the database drops the tokens scoped to it.
+	if err := data.DropDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if len(data.Tokens) != 1 || data.Tokens[0].ID != "b" {
+		t.Fatalf("unexpected tokens: %+v", data.Tokens)
+	}
+}
+
+func TestData_UpdateQuota(t *testing.T) {
+	data := &meta.Data{}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	qu := &meta.QuotaUpdate{}
+	qu.SetDiskBytes(1 << 30)
+	qu.SetSeriesN(1000)
+	if err := data.UpdateQuota("db0", qu); err != nil {
+		t.Fatal(err)
+	}
+
+	// Quotas not in the update are unchanged, and a zero quota is removed.
+	qu = &meta.QuotaUpdate{}
+	qu.SetSeriesN(0)
+	qu.SetWritesPerSecond(100)
+	if err := data.UpdateQuota("db0", qu); err != nil {
+		t.Fatal(err)
+	} else if got, exp := data.Database("db0").Quota, (meta.QuotaInfo{DiskBytes: 1 << 30, WritesPerSecond: 100}); got != exp {
+		t.Fatalf("unexpected quota: got %+v, exp %+v", got, exp)
+	}
+
+	buf, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other meta.Data
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	} else if got, exp := other.Database("db0").Quota, data.Database("db0").Quota; got != exp {
+		t.Fatalf("unexpected quota: got %+v, exp %+v", got, exp)
+	}
+
+	qu = &meta.QuotaUpdate{}
+	qu.SetDiskBytes(-1)
+	if err := data.UpdateQuota("db0", qu); err != meta.ErrQuotaNegative {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := data.UpdateQuota("db1", qu); err == nil {
+		t.Fatal("expected error for a database that doesn't exist")
+	}
+}
+
+func TestData_UpdateWriteLimits(t *testing.T) {
+	data := &meta.Data{}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	wlu := &meta.WriteLimitsUpdate{}
+	wlu.SetFuture(time.Hour)
+	wlu.SetPast(7 * 24 * time.Hour)
+	if err := data.UpdateWriteLimits("db0", wlu); err != nil {
+		t.Fatal(err)
+	}
+
+	// Limits not in the update are unchanged, and a zero limit is removed.
+	wlu = &meta.WriteLimitsUpdate{}
+	wlu.SetPast(0)
+	wlu.SetClamp(true)
+	if err := data.UpdateWriteLimits("db0", wlu); err != nil {
+		t.Fatal(err)
+	} else if got, exp := data.Database("db0").WriteLimits, (meta.WriteLimitsInfo{Future: time.Hour, Clamp: true}); got != exp {
+		t.Fatalf("unexpected write limits: got %+v, exp %+v", got, exp)
+	}
+
+	buf, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other meta.Data
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	} else if got, exp := other.Database("db0").WriteLimits, data.Database("db0").WriteLimits; got != exp {
+		t.Fatalf("unexpected write limits: got %+v, exp %+v", got, exp)
+	}
+
+	wlu = &meta.WriteLimitsUpdate{}
+	wlu.SetFuture(-time.Hour)
+	if err := data.UpdateWriteLimits("db0", wlu); err != meta.ErrWriteLimitNegative {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := data.UpdateWriteLimits("db1", wlu); err == nil {
+		t.Fatal("expected error for a database that doesn't exist")
+	}
+}
+
+func TestData_SetSchema(t *testing.T) {
+	data := &meta.Data{}
+	if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	schema := &meta.SchemaInfo{
+		Mode: meta.SchemaWarn,
+		Measurements: []meta.MeasurementSchemaInfo{{
+			Name:    "cpu",
+			TagKeys: []string{"host", "region"},
+			Fields:  []meta.FieldSchemaInfo{{Name: "value", Type: influxql.Float}, {Name: "count", Type: influxql.Integer}},
+		}},
+	}
+	if err := data.SetSchema("db0", schema); err != nil {
+		t.Fatal(err)
+	}
+
+	// The schema is copied.
+	schema.Measurements[0].TagKeys[0] = "hostname"
+	if got := data.Database("db0").Schema; got == nil || got.Measurement("cpu") == nil || !got.Measurement("cpu").HasTagKey("host") {
+		t.Fatalf("unexpected schema: %+v", got)
+	}
+
+	buf, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other meta.Data
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	} else if got, exp := other.Database("db0").Schema, data.Database("db0").Schema; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected schema: got %+v, exp %+v", got, exp)
+	} else if f := got.Measurement("cpu").Field("count"); f == nil || f.Type != influxql.Integer {
+		t.Fatalf("unexpected field: %+v", f)
+	}
+
+	for _, tt := range []struct {
+		schema *meta.SchemaInfo
+		err    string
+	}{
+		{schema: &meta.SchemaInfo{Mode: "loose"}, err: meta.ErrSchemaModeInvalid.Error()},
+		{schema: &meta.SchemaInfo{Mode: meta.SchemaStrict, Measurements: []meta.MeasurementSchemaInfo{{}}}, err: meta.ErrSchemaMeasurementNameRequired.Error()},
+		{schema: &meta.SchemaInfo{Mode: meta.SchemaStrict, Measurements: []meta.MeasurementSchemaInfo{{Name: "cpu"}, {Name: "cpu"}}}, err: `schema measurement "cpu" is duplicated`},
+		{schema: &meta.SchemaInfo{Mode: meta.SchemaStrict, Measurements: []meta.MeasurementSchemaInfo{{Name: "cpu", Fields: []meta.FieldSchemaInfo{{Name: "value", Type: influxql.Tag}}}}}, err: `schema field "value" of measurement "cpu" has an invalid type`},
+	} {
+		if err := data.SetSchema("db0", tt.schema); err == nil || err.Error() != tt.err {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := data.SetSchema("db0", nil); err != nil {
+		t.Fatal(err)
+	} else if data.Database("db0").Schema != nil {
+		t.Fatal("expected schema to be removed")
+	} else if err := data.SetSchema("db1", nil); err == nil {
+		t.Fatal("expected error for a database that doesn't exist")
+	}
+}
@@ -25,6 +25,27 @@ var (
 
 	// ErrInvalidName is returned when attempting to create a database or retention policy with an invalid name
 	ErrInvalidName = errors.New("invalid name")
+
+	// ErrQuotaNegative is returned when setting a negative database quota.
+	ErrQuotaNegative = errors.New("quota must not be negative")
+
+	// ErrWriteLimitNegative is returned when setting a negative database
+	// write limit.
+	ErrWriteLimitNegative = errors.New("write limit must not be negative")
+)
+
+var (
+	// ErrSchemaModeInvalid is returned when setting a database schema whose
+	// mode is neither strict nor warn.
+	ErrSchemaModeInvalid = errors.New("schema mode must be strict or warn")
+
+	// ErrSchemaMeasurementNameRequired is returned when setting a database
+	// schema with a measurement without a name.
+	ErrSchemaMeasurementNameRequired = errors.New("schema measurement name required")
+
+	// ErrSchemaFieldNameRequired is returned when setting a database schema
+	// with a field without a name.
+	ErrSchemaFieldNameRequired = errors.New("schema field name required")
 )
 
 var (
@@ -62,6 +83,15 @@ var (
 	// duration.
 	ErrIncompatibleDurations = errors.New("retention policy duration must be greater than the shard duration")
 
+	// ErrMeasurementDurationTooLow is returned when a measurement of a retention
+	// policy has a duration lower than the allowed minimum.
+	ErrMeasurementDurationTooLow = fmt.Errorf("measurement duration must be at least %s", MinRetentionPolicyDuration)
+
+	// ErrIncompatibleMeasurementDuration is returned when a measurement of a
+	// retention policy has a duration that is not less than the duration of
+	// the policy.
+	ErrIncompatibleMeasurementDuration = errors.New("measurement duration must be less than the retention policy duration")
+
 	// ErrReplicationFactorTooLow is returned when the replication factor is not in an
 	// acceptable range.
 	ErrReplicationFactorTooLow = errors.New("replication factor must be greater than 0")
@@ -100,6 +130,11 @@ func ErrInvalidSubscriptionURL(url string) error {
 	return fmt.Errorf("invalid subscription URL: %s", url)
 }
 
+// ErrInvalidSubscriptionFilter is returned when the subscription's filter cannot be parsed.
+func ErrInvalidSubscriptionFilter(filter string) error {
+	return fmt.Errorf("invalid subscription filter: %s", filter)
+}
+
 var (
 	// ErrUserExists is returned when creating an already existing user.
 	ErrUserExists = errors.New("user already exists")
@@ -112,4 +147,30 @@ var (
 
 	// ErrAuthenticate is returned when authentication fails.
 	ErrAuthenticate = errors.New("authentication failed")
+
+	// ErrPasswordReused is returned when setting a password a user had
+	// recently.
+	ErrPasswordReused = errors.New("password was used recently")
+
+	// ErrPasswordExpired is returned when authenticating with a password
+	// older than the maximum password age.
+	ErrPasswordExpired = errors.New("password expired")
+)
+
+// ErrPasswordTooShort is returned when setting a password shorter than the
+// minimum password length.
+func ErrPasswordTooShort(n int) error {
+	return fmt.Errorf("password must be at least %d characters", n)
+}
+
+var (
+	// ErrTokenExists is returned when creating a token with the ID of an
+	// existing token.
+	ErrTokenExists = errors.New("token already exists")
+
+	// ErrTokenNotFound is returned when dropping a token that doesn't exist.
+	ErrTokenNotFound = errors.New("token not found")
+
+	// ErrTokenIDRequired is returned when creating a token without an ID.
+	ErrTokenIDRequired = errors.New("token id required")
 )
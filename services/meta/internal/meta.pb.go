@@ -12,8 +12,14 @@ It has these top-level messages:
 	Data
 	NodeInfo
 	DatabaseInfo
+	QuotaInfo
+	SchemaInfo
+	MeasurementSchemaInfo
+	FieldSchemaInfo
+	WriteLimitsInfo
 	RetentionPolicySpec
 	RetentionPolicyInfo
+	MeasurementDuration
 	ShardGroupInfo
 	ShardInfo
 	SubscriptionInfo
@@ -21,6 +27,7 @@ It has these top-level messages:
 	ContinuousQueryInfo
 	UserInfo
 	UserPrivilege
+	TokenInfo
 	Command
 	CreateNodeCommand
 	DeleteNodeCommand
@@ -183,7 +190,7 @@ func (x *Command_Type) UnmarshalJSON(data []byte) error {
 	*x = Command_Type(value)
 	return nil
 }
-func (Command_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptorMeta, []int{12, 0} }
+func (Command_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptorMeta, []int{19, 0} }
 
 type Data struct {
 	Term            *uint64         `protobuf:"varint,1,req,name=Term" json:"Term,omitempty"`
@@ -196,9 +203,10 @@ type Data struct {
 	MaxShardGroupID *uint64         `protobuf:"varint,8,req,name=MaxShardGroupID" json:"MaxShardGroupID,omitempty"`
 	MaxShardID      *uint64         `protobuf:"varint,9,req,name=MaxShardID" json:"MaxShardID,omitempty"`
 	// added for 0.10.0
-	DataNodes        []*NodeInfo `protobuf:"bytes,10,rep,name=DataNodes" json:"DataNodes,omitempty"`
-	MetaNodes        []*NodeInfo `protobuf:"bytes,11,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
-	XXX_unrecognized []byte      `json:"-"`
+	DataNodes        []*NodeInfo  `protobuf:"bytes,10,rep,name=DataNodes" json:"DataNodes,omitempty"`
+	MetaNodes        []*NodeInfo  `protobuf:"bytes,11,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
+	Tokens           []*TokenInfo `protobuf:"bytes,12,rep,name=Tokens" json:"Tokens,omitempty"`
+	XXX_unrecognized []byte       `json:"-"`
 }
 
 func (m *Data) Reset()                    { *m = Data{} }
@@ -283,6 +291,13 @@ func (m *Data) GetMetaNodes() []*NodeInfo {
 	return nil
 }
 
+func (m *Data) GetTokens() []*TokenInfo {
+	if m != nil {
+		return m.Tokens
+	}
+	return nil
+}
+
 type NodeInfo struct {
 	ID               *uint64 `protobuf:"varint,1,req,name=ID" json:"ID,omitempty"`
 	Host             *string `protobuf:"bytes,2,req,name=Host" json:"Host,omitempty"`
@@ -321,6 +336,9 @@ type DatabaseInfo struct {
 	DefaultRetentionPolicy *string                `protobuf:"bytes,2,req,name=DefaultRetentionPolicy" json:"DefaultRetentionPolicy,omitempty"`
 	RetentionPolicies      []*RetentionPolicyInfo `protobuf:"bytes,3,rep,name=RetentionPolicies" json:"RetentionPolicies,omitempty"`
 	ContinuousQueries      []*ContinuousQueryInfo `protobuf:"bytes,4,rep,name=ContinuousQueries" json:"ContinuousQueries,omitempty"`
+	Quota                  *QuotaInfo             `protobuf:"bytes,5,opt,name=Quota" json:"Quota,omitempty"`
+	Schema                 *SchemaInfo            `protobuf:"bytes,6,opt,name=Schema" json:"Schema,omitempty"`
+	WriteLimits            *WriteLimitsInfo       `protobuf:"bytes,7,opt,name=WriteLimits" json:"WriteLimits,omitempty"`
 	XXX_unrecognized       []byte                 `json:"-"`
 }
 
@@ -357,6 +375,176 @@ func (m *DatabaseInfo) GetContinuousQueries() []*ContinuousQueryInfo {
 	return nil
 }
 
+func (m *DatabaseInfo) GetQuota() *QuotaInfo {
+	if m != nil {
+		return m.Quota
+	}
+	return nil
+}
+
+func (m *DatabaseInfo) GetSchema() *SchemaInfo {
+	if m != nil {
+		return m.Schema
+	}
+	return nil
+}
+
+func (m *DatabaseInfo) GetWriteLimits() *WriteLimitsInfo {
+	if m != nil {
+		return m.WriteLimits
+	}
+	return nil
+}
+
+type QuotaInfo struct {
+	DiskBytes        *int64 `protobuf:"varint,1,opt,name=DiskBytes" json:"DiskBytes,omitempty"`
+	SeriesN          *int64 `protobuf:"varint,2,opt,name=SeriesN" json:"SeriesN,omitempty"`
+	WritesPerSecond  *int64 `protobuf:"varint,3,opt,name=WritesPerSecond" json:"WritesPerSecond,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *QuotaInfo) Reset()                    { *m = QuotaInfo{} }
+func (m *QuotaInfo) String() string            { return proto.CompactTextString(m) }
+func (*QuotaInfo) ProtoMessage()               {}
+func (*QuotaInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{3} }
+
+func (m *QuotaInfo) GetDiskBytes() int64 {
+	if m != nil && m.DiskBytes != nil {
+		return *m.DiskBytes
+	}
+	return 0
+}
+
+func (m *QuotaInfo) GetSeriesN() int64 {
+	if m != nil && m.SeriesN != nil {
+		return *m.SeriesN
+	}
+	return 0
+}
+
+func (m *QuotaInfo) GetWritesPerSecond() int64 {
+	if m != nil && m.WritesPerSecond != nil {
+		return *m.WritesPerSecond
+	}
+	return 0
+}
+
+type SchemaInfo struct {
+	Mode             *string                  `protobuf:"bytes,1,req,name=Mode" json:"Mode,omitempty"`
+	Measurements     []*MeasurementSchemaInfo `protobuf:"bytes,2,rep,name=Measurements" json:"Measurements,omitempty"`
+	XXX_unrecognized []byte                   `json:"-"`
+}
+
+func (m *SchemaInfo) Reset()                    { *m = SchemaInfo{} }
+func (m *SchemaInfo) String() string            { return proto.CompactTextString(m) }
+func (*SchemaInfo) ProtoMessage()               {}
+func (*SchemaInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{4} }
+
+func (m *SchemaInfo) GetMode() string {
+	if m != nil && m.Mode != nil {
+		return *m.Mode
+	}
+	return ""
+}
+
+func (m *SchemaInfo) GetMeasurements() []*MeasurementSchemaInfo {
+	if m != nil {
+		return m.Measurements
+	}
+	return nil
+}
+
+type MeasurementSchemaInfo struct {
+	Name             *string            `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	TagKeys          []string           `protobuf:"bytes,2,rep,name=TagKeys" json:"TagKeys,omitempty"`
+	Fields           []*FieldSchemaInfo `protobuf:"bytes,3,rep,name=Fields" json:"Fields,omitempty"`
+	XXX_unrecognized []byte             `json:"-"`
+}
+
+func (m *MeasurementSchemaInfo) Reset()                    { *m = MeasurementSchemaInfo{} }
+func (m *MeasurementSchemaInfo) String() string            { return proto.CompactTextString(m) }
+func (*MeasurementSchemaInfo) ProtoMessage()               {}
+func (*MeasurementSchemaInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{5} }
+
+func (m *MeasurementSchemaInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *MeasurementSchemaInfo) GetTagKeys() []string {
+	if m != nil {
+		return m.TagKeys
+	}
+	return nil
+}
+
+func (m *MeasurementSchemaInfo) GetFields() []*FieldSchemaInfo {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+type FieldSchemaInfo struct {
+	Name             *string `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	Type             *int32  `protobuf:"varint,2,req,name=Type" json:"Type,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *FieldSchemaInfo) Reset()                    { *m = FieldSchemaInfo{} }
+func (m *FieldSchemaInfo) String() string            { return proto.CompactTextString(m) }
+func (*FieldSchemaInfo) ProtoMessage()               {}
+func (*FieldSchemaInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{6} }
+
+func (m *FieldSchemaInfo) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *FieldSchemaInfo) GetType() int32 {
+	if m != nil && m.Type != nil {
+		return *m.Type
+	}
+	return 0
+}
+
+type WriteLimitsInfo struct {
+	Future           *int64 `protobuf:"varint,1,opt,name=Future" json:"Future,omitempty"`
+	Past             *int64 `protobuf:"varint,2,opt,name=Past" json:"Past,omitempty"`
+	Clamp            *bool  `protobuf:"varint,3,opt,name=Clamp" json:"Clamp,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *WriteLimitsInfo) Reset()                    { *m = WriteLimitsInfo{} }
+func (m *WriteLimitsInfo) String() string            { return proto.CompactTextString(m) }
+func (*WriteLimitsInfo) ProtoMessage()               {}
+func (*WriteLimitsInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{7} }
+
+func (m *WriteLimitsInfo) GetFuture() int64 {
+	if m != nil && m.Future != nil {
+		return *m.Future
+	}
+	return 0
+}
+
+func (m *WriteLimitsInfo) GetPast() int64 {
+	if m != nil && m.Past != nil {
+		return *m.Past
+	}
+	return 0
+}
+
+func (m *WriteLimitsInfo) GetClamp() bool {
+	if m != nil && m.Clamp != nil {
+		return *m.Clamp
+	}
+	return false
+}
+
 type RetentionPolicySpec struct {
 	Name               *string `protobuf:"bytes,1,opt,name=Name" json:"Name,omitempty"`
 	Duration           *int64  `protobuf:"varint,2,opt,name=Duration" json:"Duration,omitempty"`
@@ -368,7 +556,7 @@ type RetentionPolicySpec struct {
 func (m *RetentionPolicySpec) Reset()                    { *m = RetentionPolicySpec{} }
 func (m *RetentionPolicySpec) String() string            { return proto.CompactTextString(m) }
 func (*RetentionPolicySpec) ProtoMessage()               {}
-func (*RetentionPolicySpec) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{3} }
+func (*RetentionPolicySpec) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{8} }
 
 func (m *RetentionPolicySpec) GetName() string {
 	if m != nil && m.Name != nil {
@@ -399,19 +587,21 @@ func (m *RetentionPolicySpec) GetReplicaN() uint32 {
 }
 
 type RetentionPolicyInfo struct {
-	Name               *string             `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
-	Duration           *int64              `protobuf:"varint,2,req,name=Duration" json:"Duration,omitempty"`
-	ShardGroupDuration *int64              `protobuf:"varint,3,req,name=ShardGroupDuration" json:"ShardGroupDuration,omitempty"`
-	ReplicaN           *uint32             `protobuf:"varint,4,req,name=ReplicaN" json:"ReplicaN,omitempty"`
-	ShardGroups        []*ShardGroupInfo   `protobuf:"bytes,5,rep,name=ShardGroups" json:"ShardGroups,omitempty"`
-	Subscriptions      []*SubscriptionInfo `protobuf:"bytes,6,rep,name=Subscriptions" json:"Subscriptions,omitempty"`
-	XXX_unrecognized   []byte              `json:"-"`
+	Name                 *string                `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	Duration             *int64                 `protobuf:"varint,2,req,name=Duration" json:"Duration,omitempty"`
+	ShardGroupDuration   *int64                 `protobuf:"varint,3,req,name=ShardGroupDuration" json:"ShardGroupDuration,omitempty"`
+	ReplicaN             *uint32                `protobuf:"varint,4,req,name=ReplicaN" json:"ReplicaN,omitempty"`
+	ShardGroups          []*ShardGroupInfo      `protobuf:"bytes,5,rep,name=ShardGroups" json:"ShardGroups,omitempty"`
+	Subscriptions        []*SubscriptionInfo    `protobuf:"bytes,6,rep,name=Subscriptions" json:"Subscriptions,omitempty"`
+	MeasurementDurations []*MeasurementDuration `protobuf:"bytes,7,rep,name=MeasurementDurations" json:"MeasurementDurations,omitempty"`
+	Rollup               *string                `protobuf:"bytes,8,opt,name=Rollup" json:"Rollup,omitempty"`
+	XXX_unrecognized     []byte                 `json:"-"`
 }
 
 func (m *RetentionPolicyInfo) Reset()                    { *m = RetentionPolicyInfo{} }
 func (m *RetentionPolicyInfo) String() string            { return proto.CompactTextString(m) }
 func (*RetentionPolicyInfo) ProtoMessage()               {}
-func (*RetentionPolicyInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{4} }
+func (*RetentionPolicyInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{9} }
 
 func (m *RetentionPolicyInfo) GetName() string {
 	if m != nil && m.Name != nil {
@@ -455,6 +645,45 @@ func (m *RetentionPolicyInfo) GetSubscriptions() []*SubscriptionInfo {
 	return nil
 }
 
+func (m *RetentionPolicyInfo) GetMeasurementDurations() []*MeasurementDuration {
+	if m != nil {
+		return m.MeasurementDurations
+	}
+	return nil
+}
+
+func (m *RetentionPolicyInfo) GetRollup() string {
+	if m != nil && m.Rollup != nil {
+		return *m.Rollup
+	}
+	return ""
+}
+
+type MeasurementDuration struct {
+	Name             *string `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
+	Duration         *int64  `protobuf:"varint,2,req,name=Duration" json:"Duration,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *MeasurementDuration) Reset()                    { *m = MeasurementDuration{} }
+func (m *MeasurementDuration) String() string            { return proto.CompactTextString(m) }
+func (*MeasurementDuration) ProtoMessage()               {}
+func (*MeasurementDuration) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{10} }
+
+func (m *MeasurementDuration) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *MeasurementDuration) GetDuration() int64 {
+	if m != nil && m.Duration != nil {
+		return *m.Duration
+	}
+	return 0
+}
+
 type ShardGroupInfo struct {
 	ID               *uint64      `protobuf:"varint,1,req,name=ID" json:"ID,omitempty"`
 	StartTime        *int64       `protobuf:"varint,2,req,name=StartTime" json:"StartTime,omitempty"`
@@ -468,7 +697,7 @@ type ShardGroupInfo struct {
 func (m *ShardGroupInfo) Reset()                    { *m = ShardGroupInfo{} }
 func (m *ShardGroupInfo) String() string            { return proto.CompactTextString(m) }
 func (*ShardGroupInfo) ProtoMessage()               {}
-func (*ShardGroupInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{5} }
+func (*ShardGroupInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{11} }
 
 func (m *ShardGroupInfo) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -522,7 +751,7 @@ type ShardInfo struct {
 func (m *ShardInfo) Reset()                    { *m = ShardInfo{} }
 func (m *ShardInfo) String() string            { return proto.CompactTextString(m) }
 func (*ShardInfo) ProtoMessage()               {}
-func (*ShardInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{6} }
+func (*ShardInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{12} }
 
 func (m *ShardInfo) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -549,13 +778,14 @@ type SubscriptionInfo struct {
 	Name             *string  `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
 	Mode             *string  `protobuf:"bytes,2,req,name=Mode" json:"Mode,omitempty"`
 	Destinations     []string `protobuf:"bytes,3,rep,name=Destinations" json:"Destinations,omitempty"`
+	Filter           *string  `protobuf:"bytes,4,opt,name=Filter" json:"Filter,omitempty"`
 	XXX_unrecognized []byte   `json:"-"`
 }
 
 func (m *SubscriptionInfo) Reset()                    { *m = SubscriptionInfo{} }
 func (m *SubscriptionInfo) String() string            { return proto.CompactTextString(m) }
 func (*SubscriptionInfo) ProtoMessage()               {}
-func (*SubscriptionInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{7} }
+func (*SubscriptionInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{13} }
 
 func (m *SubscriptionInfo) GetName() string {
 	if m != nil && m.Name != nil {
@@ -578,6 +808,13 @@ func (m *SubscriptionInfo) GetDestinations() []string {
 	return nil
 }
 
+func (m *SubscriptionInfo) GetFilter() string {
+	if m != nil && m.Filter != nil {
+		return *m.Filter
+	}
+	return ""
+}
+
 type ShardOwner struct {
 	NodeID           *uint64 `protobuf:"varint,1,req,name=NodeID" json:"NodeID,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
@@ -586,7 +823,7 @@ type ShardOwner struct {
 func (m *ShardOwner) Reset()                    { *m = ShardOwner{} }
 func (m *ShardOwner) String() string            { return proto.CompactTextString(m) }
 func (*ShardOwner) ProtoMessage()               {}
-func (*ShardOwner) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{8} }
+func (*ShardOwner) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{14} }
 
 func (m *ShardOwner) GetNodeID() uint64 {
 	if m != nil && m.NodeID != nil {
@@ -604,7 +841,7 @@ type ContinuousQueryInfo struct {
 func (m *ContinuousQueryInfo) Reset()                    { *m = ContinuousQueryInfo{} }
 func (m *ContinuousQueryInfo) String() string            { return proto.CompactTextString(m) }
 func (*ContinuousQueryInfo) ProtoMessage()               {}
-func (*ContinuousQueryInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{9} }
+func (*ContinuousQueryInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{15} }
 
 func (m *ContinuousQueryInfo) GetName() string {
 	if m != nil && m.Name != nil {
@@ -625,13 +862,15 @@ type UserInfo struct {
 	Hash             *string          `protobuf:"bytes,2,req,name=Hash" json:"Hash,omitempty"`
 	Admin            *bool            `protobuf:"varint,3,req,name=Admin" json:"Admin,omitempty"`
 	Privileges       []*UserPrivilege `protobuf:"bytes,4,rep,name=Privileges" json:"Privileges,omitempty"`
+	PasswordHistory  []string         `protobuf:"bytes,5,rep,name=PasswordHistory" json:"PasswordHistory,omitempty"`
+	PasswordChanged  *int64           `protobuf:"varint,6,opt,name=PasswordChanged" json:"PasswordChanged,omitempty"`
 	XXX_unrecognized []byte           `json:"-"`
 }
 
 func (m *UserInfo) Reset()                    { *m = UserInfo{} }
 func (m *UserInfo) String() string            { return proto.CompactTextString(m) }
 func (*UserInfo) ProtoMessage()               {}
-func (*UserInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{10} }
+func (*UserInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{16} }
 
 func (m *UserInfo) GetName() string {
 	if m != nil && m.Name != nil {
@@ -661,6 +900,20 @@ func (m *UserInfo) GetPrivileges() []*UserPrivilege {
 	return nil
 }
 
+func (m *UserInfo) GetPasswordHistory() []string {
+	if m != nil {
+		return m.PasswordHistory
+	}
+	return nil
+}
+
+func (m *UserInfo) GetPasswordChanged() int64 {
+	if m != nil && m.PasswordChanged != nil {
+		return *m.PasswordChanged
+	}
+	return 0
+}
+
 type UserPrivilege struct {
 	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
 	Privilege        *int32  `protobuf:"varint,2,req,name=Privilege" json:"Privilege,omitempty"`
@@ -670,7 +923,7 @@ type UserPrivilege struct {
 func (m *UserPrivilege) Reset()                    { *m = UserPrivilege{} }
 func (m *UserPrivilege) String() string            { return proto.CompactTextString(m) }
 func (*UserPrivilege) ProtoMessage()               {}
-func (*UserPrivilege) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{11} }
+func (*UserPrivilege) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{17} }
 
 func (m *UserPrivilege) GetDatabase() string {
 	if m != nil && m.Database != nil {
@@ -686,6 +939,63 @@ func (m *UserPrivilege) GetPrivilege() int32 {
 	return 0
 }
 
+type TokenInfo struct {
+	ID               *string `protobuf:"bytes,1,req,name=ID" json:"ID,omitempty"`
+	User             *string `protobuf:"bytes,2,req,name=User" json:"User,omitempty"`
+	Hash             *string `protobuf:"bytes,3,req,name=Hash" json:"Hash,omitempty"`
+	Database         *string `protobuf:"bytes,4,opt,name=Database" json:"Database,omitempty"`
+	Privilege        *int32  `protobuf:"varint,5,opt,name=Privilege" json:"Privilege,omitempty"`
+	Expires          *int64  `protobuf:"varint,6,opt,name=Expires" json:"Expires,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *TokenInfo) Reset()                    { *m = TokenInfo{} }
+func (m *TokenInfo) String() string            { return proto.CompactTextString(m) }
+func (*TokenInfo) ProtoMessage()               {}
+func (*TokenInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{18} }
+
+func (m *TokenInfo) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *TokenInfo) GetUser() string {
+	if m != nil && m.User != nil {
+		return *m.User
+	}
+	return ""
+}
+
+func (m *TokenInfo) GetHash() string {
+	if m != nil && m.Hash != nil {
+		return *m.Hash
+	}
+	return ""
+}
+
+func (m *TokenInfo) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *TokenInfo) GetPrivilege() int32 {
+	if m != nil && m.Privilege != nil {
+		return *m.Privilege
+	}
+	return 0
+}
+
+func (m *TokenInfo) GetExpires() int64 {
+	if m != nil && m.Expires != nil {
+		return *m.Expires
+	}
+	return 0
+}
+
 type Command struct {
 	Type                         *Command_Type `protobuf:"varint,1,req,name=type,enum=meta.Command_Type" json:"type,omitempty"`
 	proto.XXX_InternalExtensions `json:"-"`
@@ -695,7 +1005,7 @@ type Command struct {
 func (m *Command) Reset()                    { *m = Command{} }
 func (m *Command) String() string            { return proto.CompactTextString(m) }
 func (*Command) ProtoMessage()               {}
-func (*Command) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{12} }
+func (*Command) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{19} }
 
 var extRange_Command = []proto.ExtensionRange{
 	{Start: 100, End: 536870911},
@@ -723,7 +1033,7 @@ type CreateNodeCommand struct {
 func (m *CreateNodeCommand) Reset()                    { *m = CreateNodeCommand{} }
 func (m *CreateNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateNodeCommand) ProtoMessage()               {}
-func (*CreateNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{13} }
+func (*CreateNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{20} }
 
 func (m *CreateNodeCommand) GetHost() string {
 	if m != nil && m.Host != nil {
@@ -756,7 +1066,7 @@ type DeleteNodeCommand struct {
 func (m *DeleteNodeCommand) Reset()                    { *m = DeleteNodeCommand{} }
 func (m *DeleteNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*DeleteNodeCommand) ProtoMessage()               {}
-func (*DeleteNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{14} }
+func (*DeleteNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{21} }
 
 func (m *DeleteNodeCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -789,7 +1099,7 @@ type CreateDatabaseCommand struct {
 func (m *CreateDatabaseCommand) Reset()                    { *m = CreateDatabaseCommand{} }
 func (m *CreateDatabaseCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateDatabaseCommand) ProtoMessage()               {}
-func (*CreateDatabaseCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{15} }
+func (*CreateDatabaseCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{22} }
 
 func (m *CreateDatabaseCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -821,7 +1131,7 @@ type DropDatabaseCommand struct {
 func (m *DropDatabaseCommand) Reset()                    { *m = DropDatabaseCommand{} }
 func (m *DropDatabaseCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropDatabaseCommand) ProtoMessage()               {}
-func (*DropDatabaseCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{16} }
+func (*DropDatabaseCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{23} }
 
 func (m *DropDatabaseCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -848,7 +1158,7 @@ func (m *CreateRetentionPolicyCommand) Reset()         { *m = CreateRetentionPol
 func (m *CreateRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
 func (*CreateRetentionPolicyCommand) ProtoMessage()    {}
 func (*CreateRetentionPolicyCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{17}
+	return fileDescriptorMeta, []int{24}
 }
 
 func (m *CreateRetentionPolicyCommand) GetDatabase() string {
@@ -882,7 +1192,7 @@ type DropRetentionPolicyCommand struct {
 func (m *DropRetentionPolicyCommand) Reset()                    { *m = DropRetentionPolicyCommand{} }
 func (m *DropRetentionPolicyCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropRetentionPolicyCommand) ProtoMessage()               {}
-func (*DropRetentionPolicyCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{18} }
+func (*DropRetentionPolicyCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{25} }
 
 func (m *DropRetentionPolicyCommand) GetDatabase() string {
 	if m != nil && m.Database != nil {
@@ -916,7 +1226,7 @@ func (m *SetDefaultRetentionPolicyCommand) Reset()         { *m = SetDefaultRete
 func (m *SetDefaultRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
 func (*SetDefaultRetentionPolicyCommand) ProtoMessage()    {}
 func (*SetDefaultRetentionPolicyCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{19}
+	return fileDescriptorMeta, []int{26}
 }
 
 func (m *SetDefaultRetentionPolicyCommand) GetDatabase() string {
@@ -954,7 +1264,7 @@ func (m *UpdateRetentionPolicyCommand) Reset()         { *m = UpdateRetentionPol
 func (m *UpdateRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
 func (*UpdateRetentionPolicyCommand) ProtoMessage()    {}
 func (*UpdateRetentionPolicyCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{20}
+	return fileDescriptorMeta, []int{27}
 }
 
 func (m *UpdateRetentionPolicyCommand) GetDatabase() string {
@@ -1010,7 +1320,7 @@ type CreateShardGroupCommand struct {
 func (m *CreateShardGroupCommand) Reset()                    { *m = CreateShardGroupCommand{} }
 func (m *CreateShardGroupCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateShardGroupCommand) ProtoMessage()               {}
-func (*CreateShardGroupCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{21} }
+func (*CreateShardGroupCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{28} }
 
 func (m *CreateShardGroupCommand) GetDatabase() string {
 	if m != nil && m.Database != nil {
@@ -1051,7 +1361,7 @@ type DeleteShardGroupCommand struct {
 func (m *DeleteShardGroupCommand) Reset()                    { *m = DeleteShardGroupCommand{} }
 func (m *DeleteShardGroupCommand) String() string            { return proto.CompactTextString(m) }
 func (*DeleteShardGroupCommand) ProtoMessage()               {}
-func (*DeleteShardGroupCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{22} }
+func (*DeleteShardGroupCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{29} }
 
 func (m *DeleteShardGroupCommand) GetDatabase() string {
 	if m != nil && m.Database != nil {
@@ -1093,7 +1403,7 @@ func (m *CreateContinuousQueryCommand) Reset()         { *m = CreateContinuousQu
 func (m *CreateContinuousQueryCommand) String() string { return proto.CompactTextString(m) }
 func (*CreateContinuousQueryCommand) ProtoMessage()    {}
 func (*CreateContinuousQueryCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{23}
+	return fileDescriptorMeta, []int{30}
 }
 
 func (m *CreateContinuousQueryCommand) GetDatabase() string {
@@ -1134,7 +1444,7 @@ type DropContinuousQueryCommand struct {
 func (m *DropContinuousQueryCommand) Reset()                    { *m = DropContinuousQueryCommand{} }
 func (m *DropContinuousQueryCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropContinuousQueryCommand) ProtoMessage()               {}
-func (*DropContinuousQueryCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{24} }
+func (*DropContinuousQueryCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{31} }
 
 func (m *DropContinuousQueryCommand) GetDatabase() string {
 	if m != nil && m.Database != nil {
@@ -1168,7 +1478,7 @@ type CreateUserCommand struct {
 func (m *CreateUserCommand) Reset()                    { *m = CreateUserCommand{} }
 func (m *CreateUserCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateUserCommand) ProtoMessage()               {}
-func (*CreateUserCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{25} }
+func (*CreateUserCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{32} }
 
 func (m *CreateUserCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -1207,7 +1517,7 @@ type DropUserCommand struct {
 func (m *DropUserCommand) Reset()                    { *m = DropUserCommand{} }
 func (m *DropUserCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropUserCommand) ProtoMessage()               {}
-func (*DropUserCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{26} }
+func (*DropUserCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{33} }
 
 func (m *DropUserCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -1233,7 +1543,7 @@ type UpdateUserCommand struct {
 func (m *UpdateUserCommand) Reset()                    { *m = UpdateUserCommand{} }
 func (m *UpdateUserCommand) String() string            { return proto.CompactTextString(m) }
 func (*UpdateUserCommand) ProtoMessage()               {}
-func (*UpdateUserCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{27} }
+func (*UpdateUserCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{34} }
 
 func (m *UpdateUserCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -1267,7 +1577,7 @@ type SetPrivilegeCommand struct {
 func (m *SetPrivilegeCommand) Reset()                    { *m = SetPrivilegeCommand{} }
 func (m *SetPrivilegeCommand) String() string            { return proto.CompactTextString(m) }
 func (*SetPrivilegeCommand) ProtoMessage()               {}
-func (*SetPrivilegeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{28} }
+func (*SetPrivilegeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{35} }
 
 func (m *SetPrivilegeCommand) GetUsername() string {
 	if m != nil && m.Username != nil {
@@ -1306,7 +1616,7 @@ type SetDataCommand struct {
 func (m *SetDataCommand) Reset()                    { *m = SetDataCommand{} }
 func (m *SetDataCommand) String() string            { return proto.CompactTextString(m) }
 func (*SetDataCommand) ProtoMessage()               {}
-func (*SetDataCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{29} }
+func (*SetDataCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{36} }
 
 func (m *SetDataCommand) GetData() *Data {
 	if m != nil {
@@ -1332,7 +1642,7 @@ type SetAdminPrivilegeCommand struct {
 func (m *SetAdminPrivilegeCommand) Reset()                    { *m = SetAdminPrivilegeCommand{} }
 func (m *SetAdminPrivilegeCommand) String() string            { return proto.CompactTextString(m) }
 func (*SetAdminPrivilegeCommand) ProtoMessage()               {}
-func (*SetAdminPrivilegeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{30} }
+func (*SetAdminPrivilegeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{37} }
 
 func (m *SetAdminPrivilegeCommand) GetUsername() string {
 	if m != nil && m.Username != nil {
@@ -1365,7 +1675,7 @@ type UpdateNodeCommand struct {
 func (m *UpdateNodeCommand) Reset()                    { *m = UpdateNodeCommand{} }
 func (m *UpdateNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*UpdateNodeCommand) ProtoMessage()               {}
-func (*UpdateNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{31} }
+func (*UpdateNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{38} }
 
 func (m *UpdateNodeCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1401,7 +1711,7 @@ type CreateSubscriptionCommand struct {
 func (m *CreateSubscriptionCommand) Reset()                    { *m = CreateSubscriptionCommand{} }
 func (m *CreateSubscriptionCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateSubscriptionCommand) ProtoMessage()               {}
-func (*CreateSubscriptionCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{32} }
+func (*CreateSubscriptionCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{39} }
 
 func (m *CreateSubscriptionCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -1456,7 +1766,7 @@ type DropSubscriptionCommand struct {
 func (m *DropSubscriptionCommand) Reset()                    { *m = DropSubscriptionCommand{} }
 func (m *DropSubscriptionCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropSubscriptionCommand) ProtoMessage()               {}
-func (*DropSubscriptionCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{33} }
+func (*DropSubscriptionCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{40} }
 
 func (m *DropSubscriptionCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -1496,7 +1806,7 @@ type RemovePeerCommand struct {
 func (m *RemovePeerCommand) Reset()                    { *m = RemovePeerCommand{} }
 func (m *RemovePeerCommand) String() string            { return proto.CompactTextString(m) }
 func (*RemovePeerCommand) ProtoMessage()               {}
-func (*RemovePeerCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{34} }
+func (*RemovePeerCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{41} }
 
 func (m *RemovePeerCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1530,7 +1840,7 @@ type CreateMetaNodeCommand struct {
 func (m *CreateMetaNodeCommand) Reset()                    { *m = CreateMetaNodeCommand{} }
 func (m *CreateMetaNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateMetaNodeCommand) ProtoMessage()               {}
-func (*CreateMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{35} }
+func (*CreateMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{42} }
 
 func (m *CreateMetaNodeCommand) GetHTTPAddr() string {
 	if m != nil && m.HTTPAddr != nil {
@@ -1570,7 +1880,7 @@ type CreateDataNodeCommand struct {
 func (m *CreateDataNodeCommand) Reset()                    { *m = CreateDataNodeCommand{} }
 func (m *CreateDataNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateDataNodeCommand) ProtoMessage()               {}
-func (*CreateDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{36} }
+func (*CreateDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{43} }
 
 func (m *CreateDataNodeCommand) GetHTTPAddr() string {
 	if m != nil && m.HTTPAddr != nil {
@@ -1604,7 +1914,7 @@ type UpdateDataNodeCommand struct {
 func (m *UpdateDataNodeCommand) Reset()                    { *m = UpdateDataNodeCommand{} }
 func (m *UpdateDataNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*UpdateDataNodeCommand) ProtoMessage()               {}
-func (*UpdateDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{37} }
+func (*UpdateDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{44} }
 
 func (m *UpdateDataNodeCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1643,7 +1953,7 @@ type DeleteMetaNodeCommand struct {
 func (m *DeleteMetaNodeCommand) Reset()                    { *m = DeleteMetaNodeCommand{} }
 func (m *DeleteMetaNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*DeleteMetaNodeCommand) ProtoMessage()               {}
-func (*DeleteMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{38} }
+func (*DeleteMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{45} }
 
 func (m *DeleteMetaNodeCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1668,7 +1978,7 @@ type DeleteDataNodeCommand struct {
 func (m *DeleteDataNodeCommand) Reset()                    { *m = DeleteDataNodeCommand{} }
 func (m *DeleteDataNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*DeleteDataNodeCommand) ProtoMessage()               {}
-func (*DeleteDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{39} }
+func (*DeleteDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{46} }
 
 func (m *DeleteDataNodeCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1695,7 +2005,7 @@ type Response struct {
 func (m *Response) Reset()                    { *m = Response{} }
 func (m *Response) String() string            { return proto.CompactTextString(m) }
 func (*Response) ProtoMessage()               {}
-func (*Response) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{40} }
+func (*Response) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{47} }
 
 func (m *Response) GetOK() bool {
 	if m != nil && m.OK != nil {
@@ -1730,7 +2040,7 @@ type SetMetaNodeCommand struct {
 func (m *SetMetaNodeCommand) Reset()                    { *m = SetMetaNodeCommand{} }
 func (m *SetMetaNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*SetMetaNodeCommand) ProtoMessage()               {}
-func (*SetMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{41} }
+func (*SetMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{48} }
 
 func (m *SetMetaNodeCommand) GetHTTPAddr() string {
 	if m != nil && m.HTTPAddr != nil {
@@ -1769,7 +2079,7 @@ type DropShardCommand struct {
 func (m *DropShardCommand) Reset()                    { *m = DropShardCommand{} }
 func (m *DropShardCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropShardCommand) ProtoMessage()               {}
-func (*DropShardCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{42} }
+func (*DropShardCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{49} }
 
 func (m *DropShardCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1790,8 +2100,14 @@ func init() {
 	proto.RegisterType((*Data)(nil), "meta.Data")
 	proto.RegisterType((*NodeInfo)(nil), "meta.NodeInfo")
 	proto.RegisterType((*DatabaseInfo)(nil), "meta.DatabaseInfo")
+	proto.RegisterType((*QuotaInfo)(nil), "meta.QuotaInfo")
+	proto.RegisterType((*SchemaInfo)(nil), "meta.SchemaInfo")
+	proto.RegisterType((*MeasurementSchemaInfo)(nil), "meta.MeasurementSchemaInfo")
+	proto.RegisterType((*FieldSchemaInfo)(nil), "meta.FieldSchemaInfo")
+	proto.RegisterType((*WriteLimitsInfo)(nil), "meta.WriteLimitsInfo")
 	proto.RegisterType((*RetentionPolicySpec)(nil), "meta.RetentionPolicySpec")
 	proto.RegisterType((*RetentionPolicyInfo)(nil), "meta.RetentionPolicyInfo")
+	proto.RegisterType((*MeasurementDuration)(nil), "meta.MeasurementDuration")
 	proto.RegisterType((*ShardGroupInfo)(nil), "meta.ShardGroupInfo")
 	proto.RegisterType((*ShardInfo)(nil), "meta.ShardInfo")
 	proto.RegisterType((*SubscriptionInfo)(nil), "meta.SubscriptionInfo")
@@ -1799,6 +2115,7 @@ func init() {
 	proto.RegisterType((*ContinuousQueryInfo)(nil), "meta.ContinuousQueryInfo")
 	proto.RegisterType((*UserInfo)(nil), "meta.UserInfo")
 	proto.RegisterType((*UserPrivilege)(nil), "meta.UserPrivilege")
+	proto.RegisterType((*TokenInfo)(nil), "meta.TokenInfo")
 	proto.RegisterType((*Command)(nil), "meta.Command")
 	proto.RegisterType((*CreateNodeCommand)(nil), "meta.CreateNodeCommand")
 	proto.RegisterType((*DeleteNodeCommand)(nil), "meta.DeleteNodeCommand")
@@ -1865,107 +2182,123 @@ func init() {
 func init() { proto.RegisterFile("internal/meta.proto", fileDescriptorMeta) }
 
 var fileDescriptorMeta = []byte{
-	// 1617 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x9c, 0x58, 0x5b, 0x6f, 0x1b, 0xc5,
-	0x17, 0xd7, 0xda, 0x6b, 0xc7, 0x7b, 0x62, 0x27, 0xf6, 0x38, 0x97, 0x4d, 0x9b, 0xa4, 0xee, 0xe8,
-	0x7f, 0xf1, 0xff, 0x2f, 0x51, 0x24, 0x2b, 0x15, 0x42, 0x5c, 0xdb, 0xb8, 0xa5, 0x11, 0x4a, 0x1a,
-	0x62, 0x17, 0xde, 0xaa, 0x6e, 0xed, 0x49, 0xb3, 0x60, 0xef, 0x9a, 0xdd, 0x75, 0xd3, 0x50, 0x68,
-	0x03, 0x12, 0x42, 0x20, 0x21, 0xc1, 0x0b, 0x2f, 0x3c, 0xf1, 0xc6, 0x37, 0x40, 0x3c, 0xf0, 0x29,
-	0xf8, 0x42, 0x68, 0x66, 0xf6, 0x32, 0xbb, 0x3b, 0xb3, 0x69, 0xfb, 0x66, 0xcf, 0x39, 0x73, 0x7e,
-	0xbf, 0x39, 0xb7, 0x39, 0xb3, 0xd0, 0xb6, 0x9d, 0x80, 0x78, 0x8e, 0x35, 0x79, 0x7d, 0x4a, 0x02,
-	0xeb, 0xda, 0xcc, 0x73, 0x03, 0x17, 0xe9, 0xf4, 0x37, 0xfe, 0xad, 0x04, 0x7a, 0xdf, 0x0a, 0x2c,
-	0x54, 0x07, 0x7d, 0x48, 0xbc, 0xa9, 0xa9, 0x75, 0x4a, 0x5d, 0x1d, 0x35, 0xa0, 0xb2, 0xe7, 0x8c,
-	0xc9, 0x13, 0xb3, 0xc4, 0xfe, 0xb6, 0xc0, 0xd8, 0x9d, 0xcc, 0xfd, 0x80, 0x78, 0x7b, 0x7d, 0xb3,
-	0xcc, 0x96, 0xb6, 0xa0, 0x72, 0xe0, 0x8e, 0x89, 0x6f, 0xea, 0x9d, 0x72, 0x77, 0xb1, 0xb7, 0x74,
-	0x8d, 0x99, 0xa6, 0x4b, 0x7b, 0xce, 0xb1, 0x8b, 0xfe, 0x0d, 0x06, 0x35, 0xfb, 0xd0, 0xf2, 0x89,
-	0x6f, 0x56, 0x98, 0x0a, 0xe2, 0x2a, 0xd1, 0x32, 0x53, 0xdb, 0x82, 0xca, 0x3d, 0x9f, 0x78, 0xbe,
-	0x59, 0x15, 0xad, 0xd0, 0x25, 0x26, 0x6e, 0x81, 0xb1, 0x6f, 0x3d, 0x61, 0x46, 0xfb, 0xe6, 0x02,
-	0xc3, 0x5d, 0x87, 0xe5, 0x7d, 0xeb, 0xc9, 0xe0, 0xc4, 0xf2, 0xc6, 0x1f, 0x78, 0xee, 0x7c, 0xb6,
-	0xd7, 0x37, 0x6b, 0x4c, 0x80, 0x00, 0x22, 0xc1, 0x5e, 0xdf, 0x34, 0xd8, 0xda, 0x55, 0xce, 0x82,
-	0x13, 0x05, 0x29, 0xd1, 0xab, 0x60, 0xec, 0x93, 0x48, 0x65, 0x51, 0xa6, 0x82, 0xaf, 0x43, 0x2d,
-	0x56, 0x07, 0x28, 0xed, 0xf5, 0x43, 0x27, 0xd5, 0x41, 0xbf, 0xe3, 0xfa, 0x01, 0xf3, 0x91, 0x81,
-	0x96, 0x61, 0x61, 0xb8, 0x7b, 0xc8, 0x16, 0xca, 0x1d, 0xad, 0x6b, 0xe0, 0xdf, 0x35, 0xa8, 0xa7,
-	0x0e, 0x5b, 0x07, 0xfd, 0xc0, 0x9a, 0x12, 0xb6, 0xdb, 0x40, 0xdb, 0xb0, 0xd6, 0x27, 0xc7, 0xd6,
-	0x7c, 0x12, 0x1c, 0x91, 0x80, 0x38, 0x81, 0xed, 0x3a, 0x87, 0xee, 0xc4, 0x1e, 0x9d, 0x85, 0xf6,
-	0x76, 0xa0, 0x95, 0x16, 0xd8, 0xc4, 0x37, 0xcb, 0x8c, 0xe0, 0x06, 0x27, 0x98, 0xd9, 0xc7, 0x30,
-	0x76, 0xa0, 0xb5, 0xeb, 0x3a, 0x81, 0xed, 0xcc, 0xdd, 0xb9, 0xff, 0xd1, 0x9c, 0x78, 0x76, 0x1c,
-	0xa2, 0x70, 0x57, 0x5a, 0xcc, 0x76, 0xe1, 0x11, 0xb4, 0x33, 0xc6, 0x06, 0x33, 0x32, 0x12, 0x08,
-	0x6b, 0x5d, 0x03, 0x35, 0xa1, 0xd6, 0x9f, 0x7b, 0x16, 0xd5, 0x31, 0x4b, 0x1d, 0xad, 0x5b, 0x46,
-	0x97, 0x00, 0x25, 0x81, 0x88, 0x65, 0x65, 0x26, 0x6b, 0x42, 0xed, 0x88, 0xcc, 0x26, 0xf6, 0xc8,
-	0x3a, 0x30, 0xf5, 0x8e, 0xd6, 0x6d, 0xe0, 0xbf, 0xb4, 0x1c, 0x8a, 0xc4, 0x2d, 0x69, 0x94, 0x52,
-	0x01, 0x4a, 0x29, 0x87, 0x52, 0xea, 0x36, 0xd0, 0xff, 0x60, 0x31, 0xd1, 0x8e, 0x52, 0x6f, 0x85,
-	0x1f, 0x5d, 0xc8, 0x1a, 0x0a, 0xfc, 0x1a, 0x34, 0x06, 0xf3, 0x87, 0xfe, 0xc8, 0xb3, 0x67, 0xd4,
-	0x64, 0x94, 0x84, 0x6b, 0xa1, 0xb2, 0x20, 0x62, 0x4e, 0xfa, 0x5e, 0x83, 0xa5, 0x8c, 0x05, 0x31,
-	0x1b, 0x5a, 0x60, 0x0c, 0x02, 0xcb, 0x0b, 0x86, 0xf6, 0x94, 0x84, 0xcc, 0x97, 0x61, 0xe1, 0x96,
-	0x33, 0x66, 0x0b, 0x9c, 0x6e, 0x0b, 0x8c, 0x3e, 0x99, 0x90, 0x80, 0x8c, 0x6f, 0x04, 0x8c, 0x6f,
-	0x19, 0x5d, 0x81, 0x2a, 0x33, 0x1a, 0x51, 0x5d, 0x16, 0xa8, 0x32, 0x8c, 0x36, 0x2c, 0x0e, 0xbd,
-	0xb9, 0x33, 0xb2, 0xf8, 0xae, 0x2a, 0xf5, 0x2e, 0xbe, 0x0b, 0x46, 0xa2, 0x21, 0xb2, 0x58, 0x81,
-	0xda, 0xdd, 0x53, 0x87, 0xd6, 0xa9, 0x6f, 0x96, 0x3a, 0xe5, 0xae, 0x7e, 0xb3, 0x64, 0x6a, 0xa8,
-	0x03, 0x55, 0xb6, 0x1a, 0x25, 0x50, 0x53, 0x00, 0x61, 0x02, 0xdc, 0x87, 0x66, 0xf6, 0xc0, 0x99,
-	0xc0, 0xd4, 0x41, 0xdf, 0x77, 0xc7, 0x24, 0xcc, 0xce, 0x15, 0xa8, 0xf7, 0x89, 0x1f, 0xd8, 0x8e,
-	0xc5, 0x5d, 0x47, 0xed, 0x1a, 0x78, 0x13, 0x20, 0xb1, 0x89, 0x96, 0xa0, 0x1a, 0x96, 0x2e, 0xe3,
-	0x86, 0x7b, 0xd0, 0x96, 0x24, 0x5f, 0x06, 0xa6, 0x01, 0x15, 0x26, 0xe2, 0x38, 0xf8, 0x3e, 0xd4,
-	0xe2, 0x6e, 0x90, 0xe3, 0x73, 0xc7, 0xf2, 0x4f, 0x42, 0x3e, 0x0d, 0xa8, 0xdc, 0x18, 0x4f, 0x6d,
-	0x9e, 0x17, 0x35, 0xf4, 0x5f, 0x80, 0x43, 0xcf, 0x7e, 0x6c, 0x4f, 0xc8, 0xa3, 0x38, 0xff, 0xdb,
-	0x49, 0x73, 0x89, 0x65, 0x78, 0x07, 0x1a, 0xa9, 0x05, 0x96, 0x7f, 0x61, 0xd1, 0x86, 0x40, 0x2d,
-	0x30, 0x62, 0x31, 0x43, 0xab, 0xe0, 0xbf, 0xab, 0xb0, 0xb0, 0xeb, 0x4e, 0xa7, 0x96, 0x33, 0x46,
-	0x1d, 0xd0, 0x83, 0xb3, 0x19, 0x57, 0x5e, 0x8a, 0x9a, 0x5c, 0x28, 0xbc, 0x36, 0x3c, 0x9b, 0x11,
-	0xfc, 0x6b, 0x15, 0x74, 0xfa, 0x03, 0xad, 0x42, 0x6b, 0xd7, 0x23, 0x56, 0x40, 0xa8, 0x5b, 0x42,
-	0x95, 0xa6, 0x46, 0x97, 0x79, 0x56, 0x88, 0xcb, 0x25, 0xb4, 0x01, 0xab, 0x5c, 0x3b, 0xe2, 0x13,
-	0x89, 0xca, 0x68, 0x1d, 0xda, 0x7d, 0xcf, 0x9d, 0x65, 0x05, 0x3a, 0xea, 0xc0, 0x26, 0xdf, 0x93,
-	0x29, 0xb4, 0x48, 0xa3, 0x82, 0xb6, 0xe1, 0x12, 0xdd, 0xaa, 0x90, 0x57, 0xd1, 0xbf, 0xa0, 0x33,
-	0x20, 0x81, 0xbc, 0x33, 0x45, 0x5a, 0x0b, 0x14, 0xe7, 0xde, 0x6c, 0xac, 0xc6, 0xa9, 0xa1, 0xcb,
-	0xb0, 0xce, 0x99, 0x24, 0x25, 0x13, 0x09, 0x0d, 0x2a, 0xe4, 0x27, 0xce, 0x0b, 0x21, 0x39, 0x43,
-	0x26, 0x59, 0x22, 0x8d, 0xc5, 0xe8, 0x0c, 0x0a, 0x79, 0x3d, 0xf1, 0x33, 0x0d, 0x6d, 0xb4, 0xdc,
-	0x40, 0x6d, 0x58, 0xa6, 0xdb, 0xc4, 0xc5, 0x25, 0xaa, 0xcb, 0x4f, 0x22, 0x2e, 0x2f, 0x53, 0x0f,
-	0x0f, 0x48, 0x10, 0xc7, 0x3d, 0x12, 0x34, 0x11, 0x82, 0x25, 0xea, 0x1f, 0x2b, 0xb0, 0xa2, 0xb5,
-	0x16, 0xda, 0x04, 0x73, 0x40, 0x02, 0x96, 0x7f, 0xb9, 0x1d, 0x28, 0x41, 0x10, 0xc3, 0xdb, 0x46,
-	0x5b, 0xb0, 0x11, 0x3a, 0x48, 0xa8, 0xbb, 0x48, 0xbc, 0xca, 0x5c, 0xe4, 0xb9, 0x33, 0x99, 0x70,
-	0x8d, 0x9a, 0x3c, 0x22, 0x53, 0xf7, 0x31, 0x39, 0x24, 0x09, 0xe9, 0xf5, 0x24, 0x63, 0xa2, 0x1b,
-	0x2d, 0x12, 0x99, 0xe9, 0x64, 0x12, 0x45, 0x1b, 0x54, 0xc4, 0xf9, 0x65, 0x45, 0x97, 0xa8, 0x88,
-	0xc7, 0x29, 0x6b, 0xf0, 0x72, 0x22, 0xca, 0xee, 0xda, 0x44, 0x6b, 0x80, 0x06, 0x24, 0xc8, 0x6e,
-	0xd9, 0x42, 0x2b, 0xd0, 0x64, 0x47, 0xa2, 0x31, 0x8f, 0x56, 0xb7, 0xff, 0x5f, 0xab, 0x8d, 0x9b,
-	0xe7, 0xe7, 0xe7, 0xe7, 0x25, 0x7c, 0x22, 0x29, 0x8f, 0xf8, 0x92, 0x8d, 0x8b, 0xfe, 0xc8, 0x72,
-	0xc6, 0x7c, 0x2c, 0xe9, 0xbd, 0x01, 0x0b, 0xa3, 0x50, 0xad, 0x91, 0xaa, 0x3b, 0x93, 0x74, 0xb4,
-	0xee, 0x62, 0x6f, 0x3d, 0x5c, 0xcc, 0x1a, 0xc5, 0x8f, 0x24, 0x15, 0x97, 0x6a, 0xa3, 0x0d, 0xa8,
-	0xdc, 0x76, 0xbd, 0x11, 0xaf, 0xf7, 0x5a, 0x01, 0xd0, 0xb1, 0x08, 0x94, 0xb3, 0x89, 0x7f, 0xd1,
-	0x14, 0x45, 0x9c, 0x69, 0x66, 0x3d, 0x58, 0xce, 0x4f, 0x01, 0x5a, 0xe1, 0x55, 0xdf, 0x7b, 0x4b,
-	0x49, 0xea, 0x11, 0xdb, 0x7a, 0x59, 0x3c, 0x7d, 0x06, 0x1e, 0xdf, 0x97, 0x76, 0x90, 0x34, 0xab,
-	0xde, 0x9b, 0x4a, 0x84, 0x13, 0x91, 0x9c, 0xc4, 0x10, 0x1d, 0x7e, 0x0a, 0x3b, 0x91, 0xa4, 0xcf,
-	0x4a, 0x7d, 0x50, 0x2a, 0xf6, 0xc1, 0x4d, 0x25, 0x43, 0x9b, 0x31, 0xc4, 0xa2, 0x0f, 0xe4, 0x4c,
-	0xf0, 0xb3, 0xa2, 0x8e, 0x28, 0xe1, 0x19, 0xf9, 0x88, 0x5d, 0x3c, 0xbd, 0xf7, 0x95, 0x0c, 0x3e,
-	0x65, 0x0c, 0x3a, 0x89, 0x8f, 0x14, 0xf8, 0x3f, 0x68, 0x17, 0xb7, 0xdc, 0x0b, 0x69, 0xdc, 0x56,
-	0xd2, 0xf8, 0x8c, 0xd1, 0xf8, 0x4f, 0x78, 0xe3, 0x5f, 0x80, 0x83, 0xff, 0xd0, 0x8a, 0x3b, 0xfb,
-	0x45, 0x44, 0xe8, 0xcc, 0x73, 0x40, 0x4e, 0xd9, 0x42, 0x39, 0x37, 0x36, 0xea, 0xb9, 0xd1, 0xb0,
-	0x42, 0x47, 0xc3, 0x82, 0x30, 0x4e, 0xc4, 0x30, 0x16, 0x11, 0xc3, 0x3f, 0x6a, 0xca, 0x1b, 0x47,
-	0x42, 0x7a, 0x09, 0xaa, 0xa9, 0x69, 0xbb, 0x05, 0x06, 0x9d, 0xd3, 0xfc, 0xc0, 0x9a, 0xce, 0xf8,
-	0xb0, 0xd6, 0x7b, 0x47, 0x49, 0x6a, 0xca, 0x48, 0x6d, 0x89, 0xb9, 0x95, 0xc3, 0xc4, 0x3f, 0x69,
-	0xca, 0x4b, 0xee, 0x05, 0xf8, 0xac, 0x40, 0x3d, 0xf5, 0xc6, 0x61, 0x8f, 0xae, 0x02, 0x4a, 0x8e,
-	0x48, 0x49, 0x01, 0x8b, 0x7f, 0xd6, 0x8a, 0xaf, 0xd6, 0x0b, 0x83, 0x1b, 0x0f, 0x67, 0x65, 0x96,
-	0x74, 0xea, 0xb0, 0xb9, 0xf9, 0xea, 0x93, 0x43, 0x46, 0xd5, 0xf7, 0x6a, 0x84, 0x0a, 0xaa, 0x6f,
-	0x96, 0xad, 0x3e, 0x05, 0xfe, 0xa9, 0x64, 0x56, 0x78, 0x89, 0x49, 0xb3, 0xe0, 0x6a, 0xf8, 0x3c,
-	0x7f, 0x07, 0x09, 0x18, 0xf8, 0xe3, 0xdc, 0x34, 0x92, 0xe9, 0xbe, 0xd7, 0x95, 0x96, 0x3d, 0x66,
-	0x79, 0x35, 0x39, 0x9b, 0x68, 0xf7, 0x44, 0x32, 0xd0, 0x14, 0x1d, 0xa8, 0xe0, 0x04, 0xbe, 0x78,
-	0x82, 0x9c, 0x51, 0xfc, 0x9d, 0x26, 0x1d, 0x92, 0x68, 0xd0, 0xa8, 0x9a, 0x93, 0x7e, 0xd4, 0x45,
-	0x61, 0x2c, 0xe5, 0x87, 0x6a, 0xea, 0xc9, 0x4a, 0xc1, 0x6d, 0x13, 0x88, 0xb7, 0x8d, 0x04, 0x11,
-	0x3f, 0xc8, 0x0e, 0x65, 0xc8, 0xe4, 0x9f, 0x35, 0x18, 0xfe, 0x62, 0x0f, 0x92, 0x4f, 0x0f, 0xbd,
-	0x1d, 0x25, 0xcc, 0x9c, 0xc1, 0xac, 0x24, 0x9d, 0x32, 0xb1, 0x87, 0x9f, 0xaa, 0x47, 0x3c, 0xc9,
-	0x79, 0xe3, 0x1c, 0xe1, 0xe3, 0xc3, 0xbb, 0x4a, 0xc8, 0xc7, 0x0c, 0x72, 0x3b, 0x86, 0x94, 0x02,
-	0xe0, 0x63, 0xc9, 0x04, 0xa9, 0xfe, 0x12, 0x51, 0x10, 0xd0, 0xd3, 0x7c, 0x40, 0xc5, 0x69, 0xe5,
-	0x4f, 0xad, 0x60, 0x26, 0x95, 0xbc, 0xd3, 0xd3, 0x21, 0x5d, 0xcf, 0xdf, 0xdf, 0xe5, 0xd4, 0xcb,
-	0x51, 0x97, 0xbe, 0x1c, 0xe9, 0xb3, 0xd7, 0xe8, 0xbd, 0xa7, 0xe4, 0x7c, 0xc6, 0x38, 0x5f, 0x49,
-	0x35, 0xdb, 0x3c, 0x3b, 0xda, 0xdb, 0x54, 0x03, 0xf3, 0x2b, 0x33, 0x2f, 0xe8, 0xb7, 0x5f, 0xa4,
-	0xfa, 0xad, 0x1c, 0x97, 0xc6, 0x2d, 0x37, 0xa6, 0xc7, 0x71, 0xd3, 0x78, 0xdc, 0x6e, 0x8c, 0xc7,
-	0xde, 0x85, 0x71, 0x7b, 0x2a, 0xc6, 0x2d, 0x67, 0x12, 0x7f, 0xab, 0x29, 0x06, 0x7f, 0x7a, 0xd6,
-	0x3b, 0xc3, 0xe1, 0x21, 0x03, 0xd1, 0x84, 0xcf, 0x54, 0x09, 0x6a, 0x3c, 0x52, 0xf3, 0x1b, 0x46,
-	0x3d, 0x54, 0x7e, 0x99, 0x1f, 0x2a, 0x33, 0x68, 0xf8, 0x54, 0xf1, 0xc8, 0x78, 0x01, 0x1a, 0x05,
-	0xc0, 0x5f, 0xc9, 0xa7, 0x59, 0x11, 0xf8, 0xb9, 0xe2, 0x09, 0xf3, 0xa2, 0x9f, 0xeb, 0x8a, 0x09,
-	0x3c, 0x13, 0x09, 0x48, 0x71, 0xf0, 0x03, 0xc5, 0x43, 0x49, 0x24, 0x50, 0x80, 0xf0, 0x5c, 0x44,
-	0x90, 0x1a, 0xc2, 0x96, 0xe2, 0xbd, 0x95, 0x42, 0x78, 0x5b, 0x89, 0x70, 0xae, 0xe5, 0x21, 0xb2,
-	0x87, 0xd8, 0xa1, 0x73, 0x99, 0x3f, 0x73, 0x1d, 0x9f, 0x50, 0xab, 0x77, 0x3f, 0x64, 0x56, 0x6b,
-	0xb4, 0x9b, 0xdd, 0xf2, 0x3c, 0xd7, 0x63, 0x4f, 0x12, 0x23, 0xf9, 0x36, 0x4c, 0xe7, 0x3b, 0x1d,
-	0x9f, 0x6b, 0xb2, 0xe7, 0xde, 0xcb, 0x67, 0x9e, 0xba, 0xfd, 0x7f, 0xcd, 0xb9, 0x9b, 0x71, 0x97,
-	0xcc, 0xfa, 0xe6, 0x93, 0xfc, 0xc3, 0x32, 0xe5, 0x16, 0x75, 0x61, 0x7d, 0xc3, 0x4d, 0xaf, 0x09,
-	0x75, 0x2c, 0x18, 0xf9, 0x27, 0x00, 0x00, 0xff, 0xff, 0x5d, 0xc6, 0xc9, 0x45, 0x39, 0x17, 0x00,
-	0x00,
+	// 1879 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x9d, 0x59, 0x6d, 0x6f, 0x1b, 0x45,
+	0x10, 0xd6, 0xf9, 0x2d, 0xf6, 0x26, 0x8e, 0xdd, 0x73, 0x5e, 0x9c, 0x26, 0x6d, 0xc3, 0x8a, 0x97,
+	0x50, 0xa9, 0x45, 0x58, 0xa9, 0x2a, 0x04, 0x14, 0xda, 0xb8, 0x25, 0x55, 0x49, 0x9a, 0xc6, 0x29,
+	0xfd, 0x04, 0xe2, 0x6a, 0x6f, 0x92, 0xa3, 0xf6, 0x9d, 0xb9, 0x3b, 0x37, 0x0d, 0x85, 0xb6, 0x20,
+	0x01, 0x02, 0x09, 0x09, 0xbe, 0xf4, 0x0b, 0x7f, 0x80, 0x7f, 0x80, 0xf8, 0xca, 0x5f, 0xe0, 0x0f,
+	0x31, 0x3b, 0x7b, 0x2f, 0x7b, 0x77, 0x7b, 0x97, 0xb6, 0xf9, 0x94, 0xcc, 0xcc, 0xce, 0xf3, 0xec,
+	0xcc, 0xce, 0xec, 0xec, 0x85, 0xb4, 0x4c, 0xcb, 0x63, 0x8e, 0x65, 0x0c, 0xdf, 0x19, 0x31, 0xcf,
+	0xb8, 0x38, 0x76, 0x6c, 0xcf, 0xd6, 0x4b, 0xfc, 0x77, 0xfa, 0x6f, 0x81, 0x94, 0xba, 0x86, 0x67,
+	0xe8, 0x33, 0xa4, 0xb4, 0xc7, 0x9c, 0x51, 0x5b, 0x5b, 0x2d, 0xac, 0x95, 0xf4, 0x3a, 0x29, 0xdf,
+	0xb4, 0x06, 0xec, 0x51, 0xbb, 0x80, 0x7f, 0x9e, 0x22, 0xb5, 0x8d, 0xe1, 0xc4, 0x05, 0x27, 0x37,
+	0xbb, 0xed, 0x22, 0x8a, 0xce, 0x90, 0xf2, 0xb6, 0x3d, 0x60, 0x6e, 0xbb, 0xb4, 0x5a, 0x5c, 0x9b,
+	0xee, 0xcc, 0x5e, 0x44, 0xd7, 0x5c, 0x74, 0xd3, 0xda, 0xb7, 0xf5, 0x37, 0x48, 0x8d, 0xbb, 0xbd,
+	0x6f, 0xb8, 0x60, 0x52, 0x46, 0x13, 0x5d, 0x98, 0x04, 0x62, 0x34, 0x03, 0x2f, 0x77, 0x5d, 0xe6,
+	0xb8, 0xed, 0x8a, 0xec, 0x85, 0x8b, 0x50, 0x0d, 0xb8, 0x5b, 0xc6, 0x23, 0x74, 0xda, 0x6d, 0x4f,
+	0x21, 0xee, 0x22, 0x69, 0x80, 0xa8, 0x77, 0x68, 0x38, 0x83, 0x4f, 0x1c, 0x7b, 0x32, 0x06, 0x45,
+	0x15, 0x15, 0x3a, 0x21, 0x81, 0x02, 0x64, 0x35, 0x94, 0xbd, 0x26, 0x58, 0x08, 0xa2, 0x44, 0x49,
+	0x14, 0x4c, 0xb6, 0x58, 0x60, 0x32, 0xad, 0x34, 0x39, 0x47, 0x2a, 0x7b, 0xf6, 0x03, 0x66, 0xb9,
+	0xed, 0x19, 0xd4, 0x37, 0x84, 0x1e, 0x65, 0xdc, 0x80, 0x5e, 0x22, 0xd5, 0xd0, 0x98, 0x90, 0x02,
+	0xc0, 0x8b, 0x28, 0x42, 0x4c, 0x37, 0x6d, 0xd7, 0xc3, 0x20, 0xd6, 0xf4, 0x06, 0x99, 0xda, 0xdb,
+	0xd8, 0x41, 0x41, 0x71, 0x55, 0x5b, 0xab, 0xd1, 0xe7, 0x05, 0x32, 0x13, 0x8b, 0x06, 0xd8, 0x6f,
+	0x1b, 0x23, 0x86, 0xab, 0x6b, 0xfa, 0x59, 0xb2, 0xd0, 0x65, 0xfb, 0xc6, 0x64, 0xe8, 0xed, 0x32,
+	0x8f, 0x59, 0x9e, 0x69, 0x5b, 0x3b, 0xf6, 0xd0, 0xec, 0x1f, 0xfb, 0xfe, 0xd6, 0xc9, 0xa9, 0xb8,
+	0xc2, 0x84, 0x1d, 0x14, 0x91, 0xe1, 0x92, 0x60, 0x98, 0x58, 0x87, 0x18, 0xb0, 0x6a, 0xc3, 0x06,
+	0xa1, 0x35, 0xb1, 0x27, 0xee, 0x9d, 0x09, 0x73, 0xcc, 0x30, 0x87, 0xfe, 0xaa, 0xb8, 0x5a, 0xac,
+	0x3a, 0x4b, 0xca, 0x77, 0x26, 0xb6, 0x67, 0x40, 0x2a, 0xb5, 0x28, 0x02, 0x28, 0x42, 0xfd, 0x2a,
+	0xa9, 0xf4, 0xfa, 0x87, 0x6c, 0x64, 0x40, 0x22, 0xb9, 0x41, 0x53, 0x18, 0x08, 0x19, 0x5a, 0x9c,
+	0x27, 0xd3, 0xf7, 0x1c, 0xd3, 0x63, 0x9f, 0x9a, 0x23, 0xd3, 0x73, 0x21, 0x99, 0xdc, 0x6c, 0x5e,
+	0x98, 0x49, 0x0a, 0x8c, 0xe7, 0x26, 0xa9, 0x45, 0xae, 0xe1, 0x0c, 0x74, 0x4d, 0xf7, 0xc1, 0xb5,
+	0x63, 0x0f, 0x88, 0x6a, 0xb0, 0xac, 0xc8, 0x23, 0xd9, 0x43, 0xe2, 0xdb, 0x10, 0x0a, 0x2e, 0x80,
+	0x43, 0x81, 0x3e, 0xdc, 0x1d, 0xe6, 0xf4, 0x58, 0xdf, 0xb6, 0x06, 0x18, 0xe2, 0x22, 0xdd, 0x22,
+	0x44, 0xe2, 0x00, 0xf1, 0xdd, 0x82, 0x3c, 0xf9, 0xf1, 0x7d, 0x97, 0xcc, 0x6c, 0x31, 0xc3, 0x9d,
+	0x38, 0x6c, 0x04, 0x51, 0x72, 0xc1, 0x15, 0x0f, 0xc2, 0xb2, 0xa0, 0x24, 0x69, 0x22, 0x07, 0xf4,
+	0x73, 0x32, 0xaf, 0x54, 0x24, 0x32, 0xc7, 0x33, 0x6d, 0x1c, 0xdc, 0x62, 0xc7, 0xc2, 0x69, 0x0d,
+	0xaa, 0xa1, 0x72, 0xc3, 0x64, 0xc3, 0x41, 0x90, 0x1f, 0x7f, 0xdf, 0x28, 0x93, 0xdc, 0x5f, 0x20,
+	0x8d, 0x84, 0x28, 0xe1, 0x98, 0x17, 0xe9, 0xf1, 0x98, 0xe1, 0x01, 0x28, 0xd3, 0x2b, 0xfe, 0xae,
+	0xa3, 0xc8, 0xe9, 0xb3, 0x00, 0x34, 0xf1, 0x80, 0x9f, 0x1f, 0x29, 0x58, 0xb0, 0x63, 0xe0, 0x09,
+	0xe4, 0x7f, 0x41, 0x55, 0x6f, 0x0c, 0x8d, 0xd1, 0x18, 0x83, 0x53, 0xa5, 0x7d, 0xd2, 0x4a, 0x9c,
+	0x90, 0xde, 0x98, 0xf5, 0x25, 0x48, 0x38, 0xa4, 0x7a, 0x93, 0x54, 0xbb, 0x13, 0xc7, 0xe0, 0x36,
+	0xbe, 0x97, 0xd3, 0x44, 0x8f, 0xca, 0x2f, 0xd4, 0x61, 0xbc, 0xb9, 0xf5, 0x2e, 0x1b, 0x83, 0x2b,
+	0x63, 0x1b, 0x0e, 0x95, 0xb6, 0x56, 0xa7, 0x3f, 0x15, 0x52, 0x28, 0x8a, 0x8d, 0xc5, 0x51, 0x0a,
+	0x39, 0x28, 0x85, 0x14, 0x4a, 0x61, 0xad, 0xae, 0xbf, 0x4d, 0xa6, 0x23, 0xeb, 0xa0, 0xe1, 0xcc,
+	0xf9, 0x87, 0x30, 0xea, 0x15, 0x1c, 0xf8, 0x02, 0xa9, 0xf7, 0x26, 0xf7, 0xdd, 0xbe, 0x63, 0x8e,
+	0xb9, 0xcb, 0xa0, 0xf5, 0x2c, 0xf8, 0xc6, 0x92, 0x0a, 0xcd, 0x2f, 0x93, 0x39, 0x29, 0xe5, 0x01,
+	0x11, 0x7e, 0x80, 0xa5, 0x92, 0x51, 0x58, 0xf0, 0x54, 0xec, 0xda, 0xc3, 0xe1, 0x64, 0x0c, 0xfd,
+	0x89, 0x57, 0xfb, 0x25, 0xd2, 0x52, 0x99, 0x9d, 0x10, 0x07, 0xfa, 0x8b, 0x46, 0x66, 0x13, 0x3b,
+	0x90, 0x5b, 0x0c, 0x54, 0x47, 0xcf, 0x33, 0x1c, 0x6f, 0xcf, 0x1c, 0x31, 0x3f, 0x72, 0x70, 0xfa,
+	0xae, 0x5b, 0x03, 0x14, 0x88, 0x70, 0xf1, 0x0a, 0x62, 0x43, 0xc8, 0xc1, 0xe0, 0xaa, 0x87, 0xf1,
+	0x2a, 0xf2, 0x96, 0x86, 0x4e, 0x83, 0x50, 0x35, 0xa4, 0x50, 0x21, 0x46, 0x8b, 0x4c, 0xef, 0x39,
+	0x13, 0xab, 0x6f, 0x88, 0x55, 0x15, 0xac, 0xa6, 0xdb, 0x00, 0x16, 0x5a, 0xc8, 0x2c, 0xe6, 0x48,
+	0xf5, 0xf6, 0x91, 0xc5, 0x6f, 0x07, 0x71, 0xe2, 0x4b, 0xd7, 0x0a, 0x6d, 0x8d, 0x37, 0x05, 0x94,
+	0x06, 0xa7, 0xbe, 0x29, 0x81, 0xa0, 0x82, 0x76, 0x49, 0x33, 0x15, 0xf0, 0xd4, 0x89, 0xc7, 0x92,
+	0x15, 0x2d, 0x6f, 0x0e, 0x1a, 0x26, 0x73, 0xa1, 0x3b, 0xf9, 0x49, 0xe0, 0x7e, 0x6b, 0x74, 0x05,
+	0x8a, 0x3c, 0xf4, 0xc9, 0xe3, 0xee, 0x5f, 0x18, 0xc8, 0x8d, 0x76, 0x48, 0x4b, 0xd5, 0xd1, 0xe2,
+	0x30, 0x75, 0xde, 0xdf, 0x40, 0x25, 0x70, 0xe8, 0x17, 0xa4, 0x1a, 0xde, 0x41, 0x29, 0x3e, 0x9b,
+	0x86, 0x7b, 0xe8, 0xf3, 0x81, 0x65, 0x57, 0x07, 0x23, 0x53, 0x9c, 0xcb, 0xaa, 0xfe, 0x16, 0x21,
+	0x3b, 0x8e, 0xf9, 0xd0, 0x1c, 0xb2, 0x83, 0xb0, 0xa9, 0xb6, 0xa2, 0x2b, 0x2d, 0xd4, 0xd1, 0x75,
+	0x52, 0x8f, 0x09, 0x30, 0xef, 0xfe, 0x4d, 0xe0, 0x03, 0x41, 0xd2, 0x42, 0xb5, 0x5f, 0xef, 0x26,
+	0xa9, 0x85, 0x77, 0x8e, 0x14, 0x7e, 0x24, 0xc5, 0xdd, 0xf9, 0xa4, 0x02, 0x8a, 0xc5, 0xf0, 0x44,
+	0x05, 0x9e, 0x4b, 0x58, 0xd1, 0x31, 0xcf, 0xbc, 0x9f, 0x97, 0xf1, 0xc8, 0x3c, 0x1a, 0x9b, 0x0e,
+	0x73, 0xfd, 0x4c, 0xff, 0x57, 0x21, 0x53, 0x1b, 0xf6, 0x68, 0x64, 0x58, 0x03, 0x48, 0x63, 0xc9,
+	0xe3, 0x4d, 0x87, 0x63, 0xcd, 0x06, 0xb7, 0xb8, 0xaf, 0xbc, 0xc8, 0xdb, 0x11, 0xfd, 0xb3, 0x22,
+	0xfa, 0x92, 0x3e, 0x0f, 0x97, 0x8b, 0xc3, 0xe0, 0xcc, 0xf0, 0x0c, 0xf8, 0x26, 0x4d, 0x8d, 0x8b,
+	0xc5, 0x01, 0x94, 0xc5, 0x05, 0x7d, 0x89, 0xcc, 0x0b, 0xeb, 0x80, 0x60, 0xa0, 0xe2, 0x0d, 0xbd,
+	0xd5, 0x75, 0xec, 0x71, 0x52, 0x51, 0x02, 0x32, 0x2b, 0x62, 0x4d, 0xa2, 0xa7, 0x04, 0x16, 0x65,
+	0xb8, 0xaa, 0x4e, 0xf3, 0xa5, 0x19, 0xfa, 0x8a, 0xfe, 0x3a, 0x59, 0xed, 0x31, 0x4f, 0x7d, 0xb3,
+	0x06, 0x56, 0x53, 0x1c, 0xe7, 0xee, 0x78, 0x90, 0x8d, 0x53, 0xd5, 0x97, 0xc9, 0xa2, 0x60, 0x12,
+	0x55, 0x67, 0xa0, 0xac, 0x71, 0xa5, 0xd8, 0x71, 0x5a, 0x49, 0xa2, 0x3d, 0x24, 0xce, 0x65, 0x60,
+	0x31, 0x1d, 0xec, 0x21, 0x43, 0x3f, 0x13, 0xc5, 0x99, 0xa7, 0x3d, 0x10, 0xd7, 0xa1, 0x68, 0x1b,
+	0x7c, 0x99, 0x2c, 0x9c, 0xe5, 0xb6, 0x62, 0x27, 0xb2, 0xb8, 0xc1, 0x23, 0x0c, 0x61, 0x08, 0x0f,
+	0x42, 0xa0, 0x68, 0xc2, 0x1c, 0x35, 0xcb, 0xe3, 0x03, 0x91, 0x0f, 0x64, 0xa7, 0xf4, 0x15, 0xd2,
+	0x06, 0x19, 0x1e, 0xf5, 0xd4, 0x0a, 0x3d, 0x42, 0x90, 0xd3, 0xdb, 0x82, 0xd9, 0x6e, 0xc9, 0x0f,
+	0x90, 0x54, 0xe2, 0x81, 0x7a, 0x1e, 0x43, 0x04, 0x64, 0x55, 0xca, 0x05, 0xee, 0x72, 0x97, 0x8d,
+	0xec, 0x87, 0x6c, 0x87, 0x45, 0xa4, 0x17, 0xa3, 0x13, 0x13, 0x8c, 0x6c, 0x81, 0xaa, 0x1d, 0x3f,
+	0x4c, 0xb2, 0x6a, 0x89, 0xab, 0x04, 0xbf, 0xa4, 0xea, 0x34, 0x57, 0x89, 0x3c, 0x25, 0x1d, 0x2e,
+	0x47, 0xaa, 0xe4, 0xaa, 0x15, 0x7d, 0x01, 0xee, 0x26, 0xe6, 0x25, 0x97, 0x9c, 0x81, 0xf6, 0xd4,
+	0xc4, 0x2d, 0xf1, 0x9c, 0x07, 0xd2, 0xb3, 0xe7, 0xab, 0xd5, 0x41, 0xf3, 0x19, 0xfc, 0x14, 0xe8,
+	0xa1, 0xa2, 0x3c, 0xc2, 0x21, 0x31, 0x2c, 0xe5, 0x5d, 0x90, 0x8a, 0xb9, 0xbb, 0x73, 0x99, 0x4c,
+	0xf5, 0x7d, 0xb3, 0x7a, 0xac, 0xee, 0xda, 0x0c, 0xe7, 0xa7, 0x45, 0x5f, 0x98, 0x74, 0x4a, 0x0f,
+	0x14, 0x15, 0x17, 0xeb, 0xd8, 0xd0, 0xb9, 0x6e, 0xd8, 0x4e, 0x5f, 0xb4, 0x96, 0x6a, 0x0e, 0xd0,
+	0xbe, 0x0c, 0x94, 0xf2, 0x49, 0x9f, 0x6b, 0x19, 0x45, 0x9c, 0xe8, 0x9b, 0x1d, 0xd2, 0x48, 0x4f,
+	0xb1, 0x5a, 0xee, 0xa8, 0xda, 0x79, 0x3f, 0x93, 0xd4, 0x01, 0x2e, 0x5d, 0x96, 0x77, 0x9f, 0x80,
+	0x87, 0x16, 0xae, 0xea, 0x20, 0x71, 0x56, 0x9d, 0xf7, 0x32, 0x11, 0x0e, 0x65, 0x72, 0x0a, 0x47,
+	0xf4, 0x2f, 0x2d, 0xbf, 0x13, 0x29, 0x5a, 0xba, 0x32, 0x06, 0x85, 0xfc, 0x18, 0x5c, 0xcb, 0x64,
+	0x68, 0x22, 0x43, 0x2a, 0xc7, 0x40, 0xcd, 0x84, 0x3e, 0xc9, 0xeb, 0x88, 0x0a, 0x9e, 0x41, 0x8c,
+	0xf0, 0x3a, 0xe9, 0x7c, 0x9c, 0xc9, 0xe0, 0x2b, 0x64, 0xb0, 0x1a, 0xc5, 0x28, 0x03, 0xff, 0x57,
+	0xed, 0xe4, 0x96, 0x7b, 0x22, 0x8d, 0x1b, 0x99, 0x34, 0x1e, 0x20, 0x8d, 0x37, 0xfd, 0xe1, 0xe2,
+	0x04, 0x1c, 0xfa, 0xb7, 0x96, 0xdf, 0xd9, 0x4f, 0x22, 0xc2, 0xef, 0xca, 0x6d, 0x76, 0x84, 0x82,
+	0x62, 0x6a, 0x42, 0x2e, 0xa5, 0xa6, 0x60, 0x7e, 0xc1, 0xd6, 0x73, 0xd2, 0x38, 0x94, 0xd3, 0x98,
+	0x47, 0x8c, 0xfe, 0xa6, 0x65, 0xde, 0x38, 0x0a, 0xd2, 0x30, 0x06, 0xc5, 0x5e, 0x8b, 0x70, 0xeb,
+	0xf3, 0x91, 0xd0, 0xf5, 0xc4, 0xfc, 0x0f, 0x43, 0x60, 0xe7, 0xc3, 0x4c, 0x52, 0x23, 0x24, 0x75,
+	0x46, 0x3e, 0x5b, 0x29, 0x4c, 0xfa, 0xbb, 0x96, 0x79, 0xc9, 0xbd, 0x00, 0x1f, 0x18, 0xe5, 0x62,
+	0x8f, 0x78, 0xfc, 0xaa, 0x90, 0x43, 0xc9, 0x92, 0x29, 0x65, 0xc0, 0xd2, 0x3f, 0xb4, 0xfc, 0xab,
+	0xf5, 0xc4, 0xe4, 0x86, 0x73, 0x20, 0x0e, 0x4f, 0x39, 0x69, 0xb3, 0xd3, 0xd5, 0xa7, 0x86, 0x0c,
+	0xaa, 0xef, 0xd5, 0x08, 0xe5, 0x54, 0xdf, 0x38, 0x59, 0x7d, 0x19, 0xf8, 0x47, 0x8a, 0x59, 0xe1,
+	0x25, 0x86, 0xda, 0x9c, 0xab, 0xe1, 0xeb, 0xf4, 0x1d, 0x24, 0x61, 0xd0, 0xcf, 0x52, 0xd3, 0x48,
+	0xa2, 0xfb, 0x5e, 0xca, 0xf4, 0xec, 0xc8, 0x5f, 0x07, 0x12, 0x4e, 0xf8, 0x2d, 0x9a, 0x1a, 0x68,
+	0xf2, 0x36, 0x94, 0xb3, 0x03, 0x57, 0xde, 0x41, 0xca, 0x29, 0xfd, 0x59, 0x53, 0x0e, 0x49, 0x3c,
+	0x69, 0xdc, 0xcc, 0x8a, 0xbf, 0xdb, 0x82, 0x34, 0x16, 0xd2, 0xf3, 0x3b, 0x8f, 0x64, 0x39, 0xe7,
+	0xb6, 0xf1, 0xe4, 0xdb, 0x46, 0x81, 0x48, 0xbf, 0x4c, 0x0e, 0x65, 0x7a, 0x5b, 0x7c, 0xb7, 0x43,
+	0xfc, 0xe9, 0x0e, 0x89, 0xbe, 0xad, 0x75, 0xd6, 0x33, 0x61, 0x26, 0x08, 0x33, 0x17, 0x75, 0xca,
+	0xc8, 0x1f, 0x7d, 0x9c, 0x3d, 0xe2, 0x29, 0xf6, 0x1b, 0x9e, 0x11, 0x31, 0x3e, 0x5c, 0xc9, 0x84,
+	0x7c, 0x88, 0x90, 0x67, 0x43, 0x48, 0x25, 0x00, 0xdd, 0x57, 0x4c, 0x90, 0xd9, 0x5f, 0xd2, 0x72,
+	0x12, 0x7a, 0x94, 0x4e, 0xa8, 0x3c, 0xad, 0xfc, 0xa3, 0xe5, 0xcc, 0xa4, 0x8a, 0xa7, 0x78, 0x3c,
+	0xa5, 0x8b, 0xe9, 0xfb, 0xbb, 0x18, 0x7b, 0xa4, 0x96, 0x94, 0x8f, 0x54, 0xfe, 0xc2, 0xae, 0x75,
+	0x3e, 0xca, 0xe4, 0x7c, 0x8c, 0x9c, 0xcf, 0xc5, 0x9a, 0x6d, 0x9a, 0x1d, 0xef, 0x6d, 0x59, 0x03,
+	0xf3, 0x2b, 0x33, 0xcf, 0xe9, 0xb7, 0xdf, 0xc4, 0xfa, 0xad, 0x1a, 0x97, 0xe7, 0x2d, 0x35, 0xa6,
+	0x87, 0x79, 0xd3, 0x44, 0xde, 0xae, 0x0e, 0x06, 0xce, 0x89, 0x79, 0x7b, 0x2c, 0xe7, 0x2d, 0xe5,
+	0x92, 0xfe, 0xa8, 0x65, 0x0c, 0xfe, 0x7c, 0xaf, 0x9b, 0x7b, 0x7b, 0x3b, 0x08, 0xa2, 0x49, 0x9f,
+	0x59, 0x23, 0xd4, 0x70, 0xa4, 0x16, 0x37, 0x4c, 0xf6, 0x50, 0xf9, 0x6d, 0x7a, 0xa8, 0x4c, 0xa0,
+	0x41, 0x2f, 0x55, 0x3f, 0x32, 0x5e, 0x80, 0x46, 0x0e, 0xf0, 0x77, 0xea, 0x69, 0x56, 0x06, 0x7e,
+	0x9a, 0xf1, 0x84, 0x79, 0xd1, 0xcf, 0xcd, 0xf9, 0x04, 0x9e, 0xc8, 0x04, 0x94, 0x38, 0xd0, 0x80,
+	0xd4, 0x0f, 0x25, 0x99, 0x40, 0x0e, 0xc2, 0x53, 0x19, 0x41, 0xe9, 0x88, 0x1a, 0x19, 0xef, 0xad,
+	0x18, 0xc2, 0x07, 0x99, 0x08, 0xcf, 0xb4, 0x34, 0x44, 0x72, 0x13, 0xeb, 0x7c, 0x2e, 0x73, 0xc7,
+	0x50, 0x94, 0x8c, 0x7b, 0xbd, 0x7d, 0x0b, 0xbd, 0x56, 0x79, 0x37, 0xbb, 0xee, 0x38, 0xb6, 0x83,
+	0x4f, 0x92, 0x5a, 0xf4, 0xcf, 0x0f, 0x3e, 0xdf, 0x95, 0xe8, 0x33, 0x4d, 0xf5, 0xdc, 0x7b, 0xf9,
+	0x93, 0x97, 0xdd, 0xfe, 0xbf, 0x17, 0xdc, 0xdb, 0x61, 0x97, 0x4c, 0xc6, 0xe6, 0x5e, 0xfa, 0x61,
+	0x19, 0x0b, 0x4b, 0x76, 0x61, 0xfd, 0x20, 0x5c, 0x2f, 0x48, 0x75, 0x2c, 0x39, 0xf9, 0x1f, 0x93,
+	0x38, 0x22, 0xb0, 0x1a, 0x1a, 0x00, 0x00,
 }
@@ -0,0 +1,460 @@
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+)
+
+// The JSON representation of the metadata is meant to be read and edited by
+// people, and kept under version control.  Durations and privileges are
+// written as strings, and every list is sorted so the same metadata is always
+// encoded the same way.  The raft term and index are not included.
+
+type dataJSON struct {
+	ClusterID       uint64         `json:"clusterID"`
+	MaxShardGroupID uint64         `json:"maxShardGroupID"`
+	MaxShardID      uint64         `json:"maxShardID"`
+	Databases       []databaseJSON `json:"databases"`
+	Users           []userJSON     `json:"users"`
+	Tokens          []tokenJSON    `json:"tokens,omitempty"`
+}
+
+type databaseJSON struct {
+	Name                   string                `json:"name"`
+	DefaultRetentionPolicy string                `json:"defaultRetentionPolicy,omitempty"`
+	RetentionPolicies      []retentionPolicyJSON `json:"retentionPolicies"`
+	ContinuousQueries      []continuousQueryJSON `json:"continuousQueries,omitempty"`
+	Quota                  *quotaJSON            `json:"quota,omitempty"`
+	Schema                 *SchemaInfo           `json:"schema,omitempty"`
+	WriteLimits            *writeLimitsJSON      `json:"writeLimits,omitempty"`
+}
+
+type continuousQueryJSON struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+type quotaJSON struct {
+	DiskBytes       int64 `json:"diskBytes,omitempty"`
+	SeriesN         int64 `json:"seriesN,omitempty"`
+	WritesPerSecond int64 `json:"writesPerSecond,omitempty"`
+}
+
+type writeLimitsJSON struct {
+	Future string `json:"future,omitempty"`
+	Past   string `json:"past,omitempty"`
+	Clamp  bool   `json:"clamp,omitempty"`
+}
+
+type schemaJSON struct {
+	Mode         string                  `json:"mode"`
+	Measurements []measurementSchemaJSON `json:"measurements"`
+}
+
+type measurementSchemaJSON struct {
+	Name    string            `json:"name"`
+	TagKeys []string          `json:"tagKeys,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+type retentionPolicyJSON struct {
+	Name                 string             `json:"name"`
+	ReplicaN             int                `json:"replicaN"`
+	Duration             string             `json:"duration"`
+	ShardGroupDuration   string             `json:"shardGroupDuration"`
+	MeasurementDurations map[string]string  `json:"measurementDurations,omitempty"`
+	Rollup               string             `json:"rollup,omitempty"`
+	ShardGroups          []shardGroupJSON   `json:"shardGroups,omitempty"`
+	Subscriptions        []subscriptionJSON `json:"subscriptions,omitempty"`
+}
+
+type shardGroupJSON struct {
+	ID          uint64      `json:"id"`
+	StartTime   time.Time   `json:"startTime"`
+	EndTime     time.Time   `json:"endTime"`
+	DeletedAt   *time.Time  `json:"deletedAt,omitempty"`
+	TruncatedAt *time.Time  `json:"truncatedAt,omitempty"`
+	Shards      []shardJSON `json:"shards"`
+}
+
+type shardJSON struct {
+	ID     uint64   `json:"id"`
+	Owners []uint64 `json:"owners"`
+}
+
+type subscriptionJSON struct {
+	Name         string   `json:"name"`
+	Mode         string   `json:"mode"`
+	Destinations []string `json:"destinations"`
+	Filter       string   `json:"filter,omitempty"`
+}
+
+type userJSON struct {
+	Name            string            `json:"name"`
+	Hash            string            `json:"hash"`
+	Admin           bool              `json:"admin,omitempty"`
+	Privileges      map[string]string `json:"privileges,omitempty"`
+	PasswordHistory []string          `json:"passwordHistory,omitempty"`
+	PasswordChanged *time.Time        `json:"passwordChanged,omitempty"`
+}
+
+type tokenJSON struct {
+	ID        string     `json:"id"`
+	User      string     `json:"user"`
+	Hash      string     `json:"hash"`
+	Database  string     `json:"database,omitempty"`
+	Privilege string     `json:"privilege,omitempty"`
+	Expires   *time.Time `json:"expires,omitempty"`
+}
+
+// MarshalJSON encodes the metadata to its JSON representation.
+func (data *Data) MarshalJSON() ([]byte, error) {
+	v := dataJSON{
+		ClusterID:       data.ClusterID,
+		MaxShardGroupID: data.MaxShardGroupID,
+		MaxShardID:      data.MaxShardID,
+		Databases:       make([]databaseJSON, 0, len(data.Databases)),
+		Users:           make([]userJSON, 0, len(data.Users)),
+	}
+
+	for _, di := range data.Databases {
+		db := databaseJSON{
+			Name:                   di.Name,
+			DefaultRetentionPolicy: di.DefaultRetentionPolicy,
+			RetentionPolicies:      make([]retentionPolicyJSON, 0, len(di.RetentionPolicies)),
+		}
+		for _, rpi := range di.RetentionPolicies {
+			db.RetentionPolicies = append(db.RetentionPolicies, marshalRetentionPolicyJSON(rpi))
+		}
+		sort.Slice(db.RetentionPolicies, func(i, j int) bool { return db.RetentionPolicies[i].Name < db.RetentionPolicies[j].Name })
+
+		for _, cqi := range di.ContinuousQueries {
+			db.ContinuousQueries = append(db.ContinuousQueries, continuousQueryJSON{Name: cqi.Name, Query: cqi.Query})
+		}
+		sort.Slice(db.ContinuousQueries, func(i, j int) bool { return db.ContinuousQueries[i].Name < db.ContinuousQueries[j].Name })
+
+		if q := di.Quota; !q.IsZero() {
+			db.Quota = &quotaJSON{DiskBytes: q.DiskBytes, SeriesN: q.SeriesN, WritesPerSecond: q.WritesPerSecond}
+		}
+		db.Schema = di.Schema
+		if wl := di.WriteLimits; wl != (WriteLimitsInfo{}) {
+			db.WriteLimits = &writeLimitsJSON{Clamp: wl.Clamp}
+			if wl.Future != 0 {
+				db.WriteLimits.Future = wl.Future.String()
+			}
+			if wl.Past != 0 {
+				db.WriteLimits.Past = wl.Past.String()
+			}
+		}
+		v.Databases = append(v.Databases, db)
+	}
+	sort.Slice(v.Databases, func(i, j int) bool { return v.Databases[i].Name < v.Databases[j].Name })
+
+	for _, ui := range data.Users {
+		u := userJSON{Name: ui.Name, Hash: ui.Hash, Admin: ui.Admin, PasswordHistory: ui.PasswordHistory, PasswordChanged: timeJSON(ui.PasswordChanged)}
+		if len(ui.Privileges) > 0 {
+			u.Privileges = make(map[string]string, len(ui.Privileges))
+			for db, p := range ui.Privileges {
+				u.Privileges[db] = p.String()
+			}
+		}
+		v.Users = append(v.Users, u)
+	}
+	sort.Slice(v.Users, func(i, j int) bool { return v.Users[i].Name < v.Users[j].Name })
+
+	for _, ti := range data.Tokens {
+		t := tokenJSON{ID: ti.ID, User: ti.User, Hash: ti.Hash, Database: ti.Database, Expires: timeJSON(ti.Expires)}
+		if ti.Database != "" {
+			t.Privilege = ti.Privilege.String()
+		}
+		v.Tokens = append(v.Tokens, t)
+	}
+	sort.Slice(v.Tokens, func(i, j int) bool { return v.Tokens[i].ID < v.Tokens[j].ID })
+
+	return json.Marshal(v)
+}
+
+func marshalRetentionPolicyJSON(rpi RetentionPolicyInfo) retentionPolicyJSON {
+	rp := retentionPolicyJSON{
+		Name:               rpi.Name,
+		ReplicaN:           rpi.ReplicaN,
+		Duration:           rpi.Duration.String(),
+		ShardGroupDuration: rpi.ShardGroupDuration.String(),
+		Rollup:             rpi.Rollup,
+	}
+	if len(rpi.MeasurementDurations) > 0 {
+		rp.MeasurementDurations = make(map[string]string, len(rpi.MeasurementDurations))
+		for name, d := range rpi.MeasurementDurations {
+			rp.MeasurementDurations[name] = d.String()
+		}
+	}
+
+	for _, sgi := range rpi.ShardGroups {
+		sg := shardGroupJSON{
+			ID:          sgi.ID,
+			StartTime:   sgi.StartTime.UTC(),
+			EndTime:     sgi.EndTime.UTC(),
+			DeletedAt:   timeJSON(sgi.DeletedAt),
+			TruncatedAt: timeJSON(sgi.TruncatedAt),
+			Shards:      make([]shardJSON, 0, len(sgi.Shards)),
+		}
+		for _, si := range sgi.Shards {
+			sh := shardJSON{ID: si.ID, Owners: make([]uint64, 0, len(si.Owners))}
+			for _, so := range si.Owners {
+				sh.Owners = append(sh.Owners, so.NodeID)
+			}
+			sort.Slice(sh.Owners, func(i, j int) bool { return sh.Owners[i] < sh.Owners[j] })
+			sg.Shards = append(sg.Shards, sh)
+		}
+		sort.Slice(sg.Shards, func(i, j int) bool { return sg.Shards[i].ID < sg.Shards[j].ID })
+		rp.ShardGroups = append(rp.ShardGroups, sg)
+	}
+	sort.Slice(rp.ShardGroups, func(i, j int) bool { return rp.ShardGroups[i].ID < rp.ShardGroups[j].ID })
+
+	for _, sub := range rpi.Subscriptions {
+		destinations := append([]string{}, sub.Destinations...)
+		rp.Subscriptions = append(rp.Subscriptions, subscriptionJSON{Name: sub.Name, Mode: sub.Mode, Destinations: destinations, Filter: sub.Filter})
+	}
+	sort.Slice(rp.Subscriptions, func(i, j int) bool { return rp.Subscriptions[i].Name < rp.Subscriptions[j].Name })
+	return rp
+}
+
+// UnmarshalJSON decodes the metadata from its JSON representation.  The
+// maximum shard group and shard IDs are raised to the largest IDs in use.
+func (data *Data) UnmarshalJSON(b []byte) error {
+	var v dataJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	other := Data{
+		ClusterID:       v.ClusterID,
+		MaxShardGroupID: v.MaxShardGroupID,
+		MaxShardID:      v.MaxShardID,
+	}
+
+	for _, db := range v.Databases {
+		if db.Name == "" {
+			return ErrDatabaseNameRequired
+		} else if other.Database(db.Name) != nil {
+			return fmt.Errorf("database %s: %s", db.Name, ErrDatabaseExists)
+		}
+
+		di := DatabaseInfo{
+			Name:                   db.Name,
+			DefaultRetentionPolicy: db.DefaultRetentionPolicy,
+		}
+		for _, rp := range db.RetentionPolicies {
+			if di.RetentionPolicy(rp.Name) != nil {
+				return fmt.Errorf("retention policy %s.%s: %s", db.Name, rp.Name, ErrRetentionPolicyExists)
+			}
+			rpi, err := unmarshalRetentionPolicyJSON(rp, &other)
+			if err != nil {
+				return fmt.Errorf("retention policy %s.%s: %s", db.Name, rp.Name, err)
+			}
+			di.RetentionPolicies = append(di.RetentionPolicies, rpi)
+		}
+		if di.DefaultRetentionPolicy != "" && di.RetentionPolicy(di.DefaultRetentionPolicy) == nil {
+			return fmt.Errorf("database %s: default %s", db.Name, ErrRetentionPolicyNotFound)
+		}
+
+		for _, cq := range db.ContinuousQueries {
+			di.ContinuousQueries = append(di.ContinuousQueries, ContinuousQueryInfo{Name: cq.Name, Query: cq.Query})
+		}
+		if q := db.Quota; q != nil {
+			di.Quota = QuotaInfo{DiskBytes: q.DiskBytes, SeriesN: q.SeriesN, WritesPerSecond: q.WritesPerSecond}
+		}
+		if db.Schema != nil {
+			if err := db.Schema.Validate(); err != nil {
+				return fmt.Errorf("database %s: %s", db.Name, err)
+			}
+			di.Schema = db.Schema
+		}
+		if wl := db.WriteLimits; wl != nil {
+			di.WriteLimits.Clamp = wl.Clamp
+			for _, l := range []struct {
+				name string
+				s    string
+				d    *time.Duration
+			}{{"future", wl.Future, &di.WriteLimits.Future}, {"past", wl.Past, &di.WriteLimits.Past}} {
+				if l.s == "" {
+					continue
+				}
+				d, err := time.ParseDuration(l.s)
+				if err != nil {
+					return fmt.Errorf("database %s: %s write limit: %s", db.Name, l.name, err)
+				} else if d < 0 {
+					return fmt.Errorf("database %s: %s", db.Name, ErrWriteLimitNegative)
+				}
+				*l.d = d
+			}
+		}
+		other.Databases = append(other.Databases, di)
+	}
+
+	for _, u := range v.Users {
+		if other.user(u.Name) != nil {
+			return fmt.Errorf("user %s: %s", u.Name, ErrUserExists)
+		}
+
+		ui := UserInfo{Name: u.Name, Hash: u.Hash, Admin: u.Admin, PasswordHistory: u.PasswordHistory}
+		if u.PasswordChanged != nil {
+			ui.PasswordChanged = u.PasswordChanged.UTC()
+		}
+		if len(u.Privileges) > 0 {
+			ui.Privileges = make(map[string]influxql.Privilege, len(u.Privileges))
+			for db, name := range u.Privileges {
+				p, err := parsePrivilegeJSON(name)
+				if err != nil {
+					return fmt.Errorf("user %s: %s", u.Name, err)
+				}
+				ui.Privileges[db] = p
+			}
+		}
+		other.Users = append(other.Users, ui)
+	}
+
+	for _, t := range v.Tokens {
+		ti := TokenInfo{ID: t.ID, User: t.User, Hash: t.Hash, Database: t.Database}
+		if t.Database != "" {
+			p, err := parsePrivilegeJSON(t.Privilege)
+			if err != nil {
+				return fmt.Errorf("token %s: %s", t.ID, err)
+			}
+			ti.Privilege = p
+		}
+		if t.Expires != nil {
+			ti.Expires = t.Expires.UTC()
+		}
+		other.Tokens = append(other.Tokens, ti)
+	}
+
+	other.adminUserExists = other.hasAdminUser()
+	*data = other
+	return nil
+}
+
+func unmarshalRetentionPolicyJSON(rp retentionPolicyJSON, data *Data) (RetentionPolicyInfo, error) {
+	rpi := RetentionPolicyInfo{
+		Name:     rp.Name,
+		ReplicaN: rp.ReplicaN,
+		Rollup:   rp.Rollup,
+	}
+
+	var err error
+	if rpi.Duration, err = time.ParseDuration(rp.Duration); err != nil {
+		return rpi, fmt.Errorf("duration: %s", err)
+	} else if rpi.ShardGroupDuration, err = time.ParseDuration(rp.ShardGroupDuration); err != nil {
+		return rpi, fmt.Errorf("shard group duration: %s", err)
+	}
+	if len(rp.MeasurementDurations) > 0 {
+		rpi.MeasurementDurations = make(map[string]time.Duration, len(rp.MeasurementDurations))
+		for name, s := range rp.MeasurementDurations {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return rpi, fmt.Errorf("measurement %s duration: %s", name, err)
+			}
+			rpi.MeasurementDurations[name] = d
+		}
+	}
+
+	for _, sg := range rp.ShardGroups {
+		sgi := ShardGroupInfo{
+			ID:        sg.ID,
+			StartTime: sg.StartTime.UTC(),
+			EndTime:   sg.EndTime.UTC(),
+		}
+		if sg.DeletedAt != nil {
+			sgi.DeletedAt = sg.DeletedAt.UTC()
+		}
+		if sg.TruncatedAt != nil {
+			sgi.TruncatedAt = sg.TruncatedAt.UTC()
+		}
+		if sgi.ID > data.MaxShardGroupID {
+			data.MaxShardGroupID = sgi.ID
+		}
+
+		for _, sh := range sg.Shards {
+			si := ShardInfo{ID: sh.ID}
+			for _, id := range sh.Owners {
+				si.Owners = append(si.Owners, ShardOwner{NodeID: id})
+			}
+			if si.ID > data.MaxShardID {
+				data.MaxShardID = si.ID
+			}
+			sgi.Shards = append(sgi.Shards, si)
+		}
+		rpi.ShardGroups = append(rpi.ShardGroups, sgi)
+	}
+
+	for _, sub := range rp.Subscriptions {
+		rpi.Subscriptions = append(rpi.Subscriptions, SubscriptionInfo{Name: sub.Name, Mode: sub.Mode, Destinations: sub.Destinations, Filter: sub.Filter})
+	}
+	return rpi, nil
+}
+
+// MarshalJSON encodes the schema to its JSON representation, where the
+// fields of a measurement map their names to their types.
+func (si SchemaInfo) MarshalJSON() ([]byte, error) {
+	v := schemaJSON{Mode: si.Mode, Measurements: make([]measurementSchemaJSON, 0, len(si.Measurements))}
+	for _, msi := range si.Measurements {
+		m := measurementSchemaJSON{Name: msi.Name, TagKeys: append([]string(nil), msi.TagKeys...)}
+		sort.Strings(m.TagKeys)
+		if len(msi.Fields) > 0 {
+			m.Fields = make(map[string]string, len(msi.Fields))
+			for _, f := range msi.Fields {
+				m.Fields[f.Name] = f.Type.String()
+			}
+		}
+		v.Measurements = append(v.Measurements, m)
+	}
+	sort.Slice(v.Measurements, func(i, j int) bool { return v.Measurements[i].Name < v.Measurements[j].Name })
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes the schema from its JSON representation.  The schema
+// isn't validated.
+func (si *SchemaInfo) UnmarshalJSON(b []byte) error {
+	var v schemaJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	other := SchemaInfo{Mode: v.Mode, Measurements: make([]MeasurementSchemaInfo, 0, len(v.Measurements))}
+	for _, m := range v.Measurements {
+		msi := MeasurementSchemaInfo{Name: m.Name, TagKeys: m.TagKeys}
+		for name, typ := range m.Fields {
+			t := influxql.DataTypeFromString(typ)
+			if t == influxql.Unknown {
+				return fmt.Errorf("schema field %q of measurement %q has an invalid type %q", name, m.Name, typ)
+			}
+			msi.Fields = append(msi.Fields, FieldSchemaInfo{Name: name, Type: t})
+		}
+		sort.Slice(msi.Fields, func(i, j int) bool { return msi.Fields[i].Name < msi.Fields[j].Name })
+		other.Measurements = append(other.Measurements, msi)
+	}
+	*si = other
+	return nil
+}
+
+// timeJSON returns a pointer to t in UTC, or nil if t is zero.
+func timeJSON(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// parsePrivilegeJSON parses a privilege written by Privilege.String.
+func parsePrivilegeJSON(s string) (influxql.Privilege, error) {
+	if strings.EqualFold(s, influxql.NoPrivileges.String()) {
+		return influxql.NoPrivileges, nil
+	}
+	return influxql.ParsePrivilege(s)
+}
@@ -0,0 +1,136 @@
+package meta_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+func TestData_MarshalJSON(t *testing.T) {
+	data := &meta.Data{ClusterID: 42}
+	if err := data.CreateDatabase("db1"); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	rpi := &meta.RetentionPolicyInfo{
+		Name:                 "rp0",
+		ReplicaN:             1,
+		Duration:             7 * 24 * time.Hour,
+		ShardGroupDuration:   24 * time.Hour,
+		MeasurementDurations: map[string]time.Duration{"cpu": 48 * time.Hour},
+	}
+	if err := data.CreateRetentionPolicy("db0", rpi, true); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateShardGroup("db0", "rp0", time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateContinuousQuery("db0", "cq0", `CREATE CONTINUOUS QUERY cq0 ON db0 BEGIN SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1h) END`); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateSubscription("db0", "rp0", "sub0", "ANY", []string{"udp://localhost:9090"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	qu := &meta.QuotaUpdate{}
+	qu.SetSeriesN(1000)
+	if err := data.UpdateQuota("db0", qu); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetSchema("db0", &meta.SchemaInfo{
+		Mode: meta.SchemaStrict,
+		Measurements: []meta.MeasurementSchemaInfo{{
+			Name:    "cpu",
+			TagKeys: []string{"host"},
+			Fields:  []meta.FieldSchemaInfo{{Name: "value", Type: influxql.Float}},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	wlu := &meta.WriteLimitsUpdate{}
+	wlu.SetFuture(time.Hour)
+	if err := data.UpdateWriteLimits("db0", wlu); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := data.CreateUser("susy", "hash", false); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateUser("admin", "hash", true); err != nil {
+		t.Fatal(err)
+	} else if err := data.SetPrivilege("susy", "db0", influxql.ReadPrivilege); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateToken("abc", "susy", "hash", "db0", influxql.ReadPrivilege, time.Unix(3600, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`"duration":"168h0m0s"`,
+		`"measurementDurations":{"cpu":"48h0m0s"}`,
+		`"privileges":{"db0":"READ"}`,
+		`"quota":{"seriesN":1000}`,
+		`"writeLimits":{"future":"1h0m0s"}`,
+		`"schema":{"mode":"strict","measurements":[{"name":"cpu","tagKeys":["host"],"fields":{"value":"float"}}]}`,
+	} {
+		if !strings.Contains(string(b), s) {
+			t.Fatalf("expected %s in %s", s, b)
+		}
+	}
+	if i, j := strings.Index(string(b), `"db0"`), strings.Index(string(b), `"db1"`); i > j {
+		t.Fatalf("expected databases to be sorted: %s", b)
+	}
+
+	var other meta.Data
+	if err := json.Unmarshal(b, &other); err != nil {
+		t.Fatal(err)
+	} else if !other.AdminUserExists() {
+		t.Fatal("expected admin user to exist")
+	} else if got, exp := other.Database("db0"), data.Database("db0"); !reflect.DeepEqual(got.RetentionPolicy("rp0").MeasurementDurations, exp.RetentionPolicy("rp0").MeasurementDurations) {
+		t.Fatalf("unexpected retention policy: got %+v, exp %+v", got.RetentionPolicy("rp0"), exp.RetentionPolicy("rp0"))
+	} else if p, err := other.UserPrivilege("susy", "db0"); err != nil || *p != influxql.ReadPrivilege {
+		t.Fatalf("unexpected privilege: %v, %v", p, err)
+	}
+
+	// Re-encoding the decoded metadata gives the same JSON.
+	if b2, err := json.Marshal(&other); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(b, b2) {
+		t.Fatalf("unexpected JSON:\ngot %s\nexp %s", b2, b)
+	}
+}
+
+func TestData_UnmarshalJSON_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		s   string
+		err string
+	}{
+		{s: `{"databases":[{"name":"db0"},{"name":"db0"}]}`, err: "database db0: database already exists"},
+		{s: `{"databases":[{"name":"db0","defaultRetentionPolicy":"rp0"}]}`, err: "database db0: default retention policy not found"},
+		{s: `{"databases":[{"name":"db0","retentionPolicies":[{"name":"rp0","duration":"1x","shardGroupDuration":"1h"}]}]}`, err: "retention policy db0.rp0: duration: "},
+		{s: `{"databases":[{"name":"db0","schema":{"mode":"loose"}}]}`, err: "database db0: schema mode must be strict or warn"},
+		{s: `{"databases":[{"name":"db0","schema":{"mode":"warn","measurements":[{"name":"cpu","fields":{"value":"double"}}]}}]}`, err: `schema field "value" of measurement "cpu" has an invalid type "double"`},
+		{s: `{"databases":[{"name":"db0","writeLimits":{"past":"1y"}}]}`, err: "database db0: past write limit: "},
+		{s: `{"users":[{"name":"susy","privileges":{"db0":"OWNER"}}]}`, err: `user susy: invalid privilege: "OWNER"`},
+	} {
+		var data meta.Data
+		if err := json.Unmarshal([]byte(tt.s), &data); err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Fatalf("%s: unexpected error: %v", tt.s, err)
+		}
+	}
+
+	// Shard IDs are kept from being reused.
+	var data meta.Data
+	if err := json.Unmarshal([]byte(`{"databases":[{"name":"db0","retentionPolicies":[{"name":"rp0","duration":"0s","shardGroupDuration":"1h","shardGroups":[{"id":3,"shards":[{"id":7,"owners":[0]}]}]}]}]}`), &data); err != nil {
+		t.Fatal(err)
+	} else if data.MaxShardGroupID != 3 || data.MaxShardID != 7 {
+		t.Fatalf("unexpected max IDs: %d, %d", data.MaxShardGroupID, data.MaxShardID)
+	}
+}
@@ -0,0 +1,134 @@
+package meta
+
+import (
+	crand "crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hash algorithms.
+const (
+	PasswordHashBcrypt   = "bcrypt"
+	PasswordHashArgon2id = "argon2id"
+)
+
+const (
+	// argon2SaltBytes is the number of bytes of the salt of argon2id hashes.
+	argon2SaltBytes = 16
+
+	// argon2KeyBytes is the number of bytes of argon2id keys.
+	argon2KeyBytes = 32
+)
+
+// passwordHasher hashes passwords with the algorithm and parameters of the
+// configuration.  It compares passwords with hashes of any algorithm, so
+// existing hashes keep working when the configuration changes.
+type passwordHasher struct {
+	algorithm     string
+	bcryptCost    int
+	argon2Time    uint32
+	argon2Memory  uint32 // KiB
+	argon2Threads uint8
+}
+
+// newPasswordHasher returns a passwordHasher for the configuration.
+func newPasswordHasher(c *Config) *passwordHasher {
+	return &passwordHasher{
+		algorithm:     c.PasswordHash,
+		bcryptCost:    c.BcryptCost,
+		argon2Time:    c.Argon2Time,
+		argon2Memory:  uint32(c.Argon2Memory / 1024),
+		argon2Threads: c.Argon2Threads,
+	}
+}
+
+// hash returns a hash of password.
+func (h *passwordHasher) hash(password string) (string, error) {
+	if h.algorithm != PasswordHashArgon2id {
+		b, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		return string(b), err
+	}
+
+	salt := make([]byte, argon2SaltBytes)
+	if _, err := io.ReadFull(crand.Reader, salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.argon2Time, h.argon2Memory, h.argon2Threads, argon2KeyBytes)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.argon2Memory, h.argon2Time, h.argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// compare returns nil if hash is a hash of password.
+func (h *passwordHasher) compare(hash, password string) error {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	}
+
+	p, err := parseArgon2Hash(hash)
+	if err != nil {
+		return err
+	}
+	key := argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+	if subtle.ConstantTimeCompare(key, p.key) != 1 {
+		return ErrAuthenticate
+	}
+	return nil
+}
+
+// needsRehash returns true if hash wasn't made with the algorithm and
+// parameters of the configuration.
+func (h *passwordHasher) needsRehash(hash string) bool {
+	if h.algorithm != PasswordHashArgon2id {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.bcryptCost
+	}
+
+	p, err := parseArgon2Hash(hash)
+	return err != nil || p.time != h.argon2Time || p.memory != h.argon2Memory || p.threads != h.argon2Threads
+}
+
+// argon2Hash is a parsed argon2id hash.
+type argon2Hash struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// parseArgon2Hash parses an argon2id hash in the PHC string format.
+func parseArgon2Hash(s string) (*argon2Hash, error) {
+	a := strings.Split(s, "$")
+	if len(a) != 6 || a[1] != PasswordHashArgon2id {
+		return nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(a[2], "v=%d", &version); err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash version: %s", err)
+	} else if version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2id hash version: %d", version)
+	}
+
+	var p argon2Hash
+	if _, err := fmt.Sscanf(a[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash parameters: %s", err)
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(a[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash salt: %s", err)
+	} else if p.key, err = base64.RawStdEncoding.DecodeString(a[5]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash key: %s", err)
+	}
+	return &p, nil
+}
@@ -51,10 +51,16 @@ func (a *QueryAuthorizer) AuthorizeQuery(u User, query *influxql.Query, database
 	return u.AuthorizeQuery(database, query)
 }
 
+// AuthorizeQuery returns an error if the user isn't authorized to execute
+// query.
 func (u *UserInfo) AuthorizeQuery(database string, query *influxql.Query) error {
+	return authorizeQuery(u, database, query)
+}
 
+// authorizeQuery returns an error if u isn't authorized to execute query.
+func authorizeQuery(u User, database string, query *influxql.Query) error {
 	// Admin privilege allows the user to execute all statements.
-	if u.Admin {
+	if u.IsAdmin() {
 		return nil
 	}
 
@@ -74,7 +80,7 @@ func (u *UserInfo) AuthorizeQuery(database string, query *influxql.Query) error
 				// privilege cannot be run.
 				return &ErrAuthorize{
 					Query:    query,
-					User:     u.Name,
+					User:     u.ID(),
 					Database: database,
 					Message:  fmt.Sprintf("statement '%s', requires admin privilege", stmt),
 				}
@@ -90,7 +96,7 @@ func (u *UserInfo) AuthorizeQuery(database string, query *influxql.Query) error
 			if !u.AuthorizeDatabase(p.Privilege, db) {
 				return &ErrAuthorize{
 					Query:    query,
-					User:     u.Name,
+					User:     u.ID(),
 					Database: database,
 					Message:  fmt.Sprintf("statement '%s', requires %s on %s", stmt, p.Privilege.String(), db),
 				}
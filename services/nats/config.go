@@ -0,0 +1,200 @@
+package nats
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	// DefaultURL is the default URL of the NATS server.
+	DefaultURL = "nats://localhost:4222"
+
+	// DefaultQueueGroup is the default queue group the service subscribes
+	// with.  Messages are delivered to only one member of a queue group.
+	DefaultQueueGroup = "influxdb"
+
+	// DefaultDatabase is the default database for NATS messages.
+	DefaultDatabase = "nats"
+
+	// DefaultRetentionPolicy is the default retention policy used for writes.
+	DefaultRetentionPolicy = ""
+
+	// DefaultPrecision is the default time precision of line protocol messages.
+	DefaultPrecision = "n"
+
+	// DefaultReconnectWait is the default time between attempts to reconnect
+	// to the NATS servers.
+	DefaultReconnectWait = 2 * time.Second
+
+	// DefaultMaxReconnectWait is the default maximum time between attempts to
+	// connect to NATS servers that can't be reached at all.
+	DefaultMaxReconnectWait = 30 * time.Second
+
+	// DefaultPendingLimit is the default number of messages a subscription
+	// buffers before messages are dropped.
+	DefaultPendingLimit = 65536
+
+	// DefaultBatchSize is the default NATS batch size.
+	DefaultBatchSize = 5000
+
+	// DefaultBatchPending is the default number of pending NATS batches.
+	DefaultBatchPending = 10
+
+	// DefaultBatchTimeout is the default NATS batch timeout.
+	DefaultBatchTimeout = time.Second
+)
+
+// Config holds the configuration of a NATS subscriber.
+type Config struct {
+	Enabled bool     `toml:"enabled"`
+	URLs    []string `toml:"urls"`
+
+	Username           string `toml:"username"`
+	Password           string `toml:"password"`
+	Token              string `toml:"token"`
+	TLSEnabled         bool   `toml:"tls-enabled"`
+	InsecureSkipVerify bool   `toml:"insecure-skip-verify"`
+
+	QueueGroup       string          `toml:"queue-group"`
+	Subjects         []SubjectConfig `toml:"subjects"`
+	Database         string          `toml:"database"`
+	RetentionPolicy  string          `toml:"retention-policy"`
+	Precision        string          `toml:"precision"`
+	ReconnectWait    toml.Duration   `toml:"reconnect-wait"`
+	MaxReconnectWait toml.Duration   `toml:"max-reconnect-wait"`
+	PendingLimit     int             `toml:"pending-limit"`
+
+	BatchSize    int           `toml:"batch-size"`
+	BatchPending int           `toml:"batch-pending"`
+	BatchTimeout toml.Duration `toml:"batch-timeout"`
+}
+
+// SubjectConfig routes the messages of a subject to a database and retention
+// policy.  Subjects may contain the NATS wildcards * and >.  An empty database
+// or retention policy is the one of the Config.
+type SubjectConfig struct {
+	Subject         string `toml:"subject"`
+	Database        string `toml:"database"`
+	RetentionPolicy string `toml:"retention-policy"`
+}
+
+// NewConfig returns a new instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		URLs:             []string{DefaultURL},
+		QueueGroup:       DefaultQueueGroup,
+		Database:         DefaultDatabase,
+		RetentionPolicy:  DefaultRetentionPolicy,
+		Precision:        DefaultPrecision,
+		ReconnectWait:    toml.Duration(DefaultReconnectWait),
+		MaxReconnectWait: toml.Duration(DefaultMaxReconnectWait),
+		PendingLimit:     DefaultPendingLimit,
+		BatchSize:        DefaultBatchSize,
+		BatchPending:     DefaultBatchPending,
+		BatchTimeout:     toml.Duration(DefaultBatchTimeout),
+	}
+}
+
+// WithDefaults takes the given config and returns a new config with any required
+// default values set.
+func (c *Config) WithDefaults() *Config {
+	d := *c
+	if len(d.URLs) == 0 {
+		d.URLs = []string{DefaultURL}
+	}
+	if d.QueueGroup == "" {
+		d.QueueGroup = DefaultQueueGroup
+	}
+	if d.Database == "" {
+		d.Database = DefaultDatabase
+	}
+	if d.Precision == "" {
+		d.Precision = DefaultPrecision
+	}
+	if d.ReconnectWait == 0 {
+		d.ReconnectWait = toml.Duration(DefaultReconnectWait)
+	}
+	if d.MaxReconnectWait == 0 {
+		d.MaxReconnectWait = toml.Duration(DefaultMaxReconnectWait)
+	}
+	if d.PendingLimit == 0 {
+		d.PendingLimit = DefaultPendingLimit
+	}
+	if d.BatchSize == 0 {
+		d.BatchSize = DefaultBatchSize
+	}
+	if d.BatchPending == 0 {
+		d.BatchPending = DefaultBatchPending
+	}
+	if d.BatchTimeout == 0 {
+		d.BatchTimeout = toml.Duration(DefaultBatchTimeout)
+	}
+	return &d
+}
+
+// Validate returns an error if the config is invalid.
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if len(c.Subjects) == 0 {
+		return errors.New("subjects must be specified")
+	}
+	for _, sc := range c.Subjects {
+		if sc.Subject == "" {
+			return errors.New("subject must be specified")
+		} else if strings.ContainsAny(sc.Subject, " \t") {
+			return fmt.Errorf("invalid subject %q", sc.Subject)
+		}
+	}
+
+	if c.ReconnectWait < 0 || c.MaxReconnectWait < 0 {
+		return errors.New("reconnect-wait and max-reconnect-wait must not be negative")
+	} else if c.PendingLimit < 0 {
+		return errors.New("pending-limit must not be negative")
+	}
+	return nil
+}
+
+// Configs wraps a slice of Config to aggregate diagnostics.
+type Configs []Config
+
+// Diagnostics returns one set of diagnostics for all of the Configs.
+func (c Configs) Diagnostics() (*diagnostics.Diagnostics, error) {
+	d := &diagnostics.Diagnostics{
+		Columns: []string{"enabled", "urls", "queue-group", "subjects", "database", "retention-policy", "batch-size", "batch-pending", "batch-timeout"},
+	}
+
+	for _, cc := range c {
+		if !cc.Enabled {
+			d.AddRow([]interface{}{false})
+			continue
+		}
+
+		subjects := make([]string, len(cc.Subjects))
+		for i, sc := range cc.Subjects {
+			subjects[i] = sc.Subject
+		}
+
+		r := []interface{}{true, strings.Join(cc.URLs, ","), cc.QueueGroup, strings.Join(subjects, ","), cc.Database, cc.RetentionPolicy, cc.BatchSize, cc.BatchPending, cc.BatchTimeout}
+		d.AddRow(r)
+	}
+
+	return d, nil
+}
+
+// Enabled returns true if any underlying Config is Enabled.
+func (c Configs) Enabled() bool {
+	for _, cc := range c {
+		if cc.Enabled {
+			return true
+		}
+	}
+	return false
+}
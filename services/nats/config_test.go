@@ -0,0 +1,89 @@
+package nats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/nats"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	var c nats.Config
+	if _, err := toml.Decode(`
+enabled = true
+urls = ["nats://nats1:4222", "nats://nats2:4222"]
+queue-group = "influx"
+database = "awesomedb"
+retention-policy = "awesomerp"
+reconnect-wait = "5s"
+batch-size = 100
+batch-pending = 9
+batch-timeout = "10ms"
+
+[[subjects]]
+subject = "telegraf.>"
+
+[[subjects]]
+subject = "app.*.metrics"
+database = "app"
+retention-policy = "week"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if c.Enabled != true {
+		t.Fatalf("unexpected enabled: %v", c.Enabled)
+	} else if len(c.URLs) != 2 || c.URLs[1] != "nats://nats2:4222" {
+		t.Fatalf("unexpected urls: %v", c.URLs)
+	} else if c.QueueGroup != "influx" {
+		t.Fatalf("unexpected queue group: %s", c.QueueGroup)
+	} else if c.Database != "awesomedb" {
+		t.Fatalf("unexpected database: %s", c.Database)
+	} else if c.RetentionPolicy != "awesomerp" {
+		t.Fatalf("unexpected retention policy: %s", c.RetentionPolicy)
+	} else if time.Duration(c.ReconnectWait) != 5*time.Second {
+		t.Fatalf("unexpected reconnect wait: %v", c.ReconnectWait)
+	} else if c.BatchSize != 100 {
+		t.Fatalf("unexpected batch size: %d", c.BatchSize)
+	} else if c.BatchPending != 9 {
+		t.Fatalf("unexpected batch pending: %d", c.BatchPending)
+	} else if time.Duration(c.BatchTimeout) != (10 * time.Millisecond) {
+		t.Fatalf("unexpected batch timeout: %v", c.BatchTimeout)
+	} else if len(c.Subjects) != 2 {
+		t.Fatalf("unexpected subjects: %v", c.Subjects)
+	} else if c.Subjects[0].Subject != "telegraf.>" || c.Subjects[0].Database != "" {
+		t.Fatalf("unexpected subject: %v", c.Subjects[0])
+	} else if c.Subjects[1].Subject != "app.*.metrics" || c.Subjects[1].Database != "app" || c.Subjects[1].RetentionPolicy != "week" {
+		t.Fatalf("unexpected subject: %v", c.Subjects[1])
+	}
+
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := nats.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c.Enabled = true
+	if err := c.Validate(); err == nil || err.Error() != "subjects must be specified" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.Subjects = []nats.SubjectConfig{{Subject: "cpu metrics"}}
+	if err := c.Validate(); err == nil || err.Error() != `invalid subject "cpu metrics"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.Subjects = []nats.SubjectConfig{{Subject: "metrics"}}
+	c.PendingLimit = -1
+	if err := c.Validate(); err == nil || err.Error() != "pending-limit must not be negative" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,392 @@
+// Package nats provides a service that subscribes to NATS subjects for line
+// protocol.
+package nats // import "github.com/influxdata/influxdb/services/nats"
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+	gonats "github.com/nats-io/go-nats"
+	"github.com/uber-go/zap"
+)
+
+// statistics gathered by the NATS package.
+const (
+	statMessagesReceived    = "messagesRx"
+	statBytesReceived       = "bytesRx"
+	statPointsReceived      = "pointsRx"
+	statPointsParseFail     = "pointsParseFail"
+	statBatchesTransmitted  = "batchesTx"
+	statPointsTransmitted   = "pointsTx"
+	statBatchesTransmitFail = "batchesTxFail"
+	statDisconnects         = "disconnects"
+	statReconnects          = "reconnects"
+	statSlowConsumers       = "slowConsumers"
+)
+
+// route batches the points of the subjects written to a database and
+// retention policy.
+type route struct {
+	database        string
+	retentionPolicy string
+	batcher         *tsdb.PointBatcher
+}
+
+// subscription is a subject and the route of its messages.
+type subscription struct {
+	subject string
+	route   *route
+}
+
+// Service subscribes to NATS subjects as a member of a queue group and writes
+// the line protocol of their messages.  Like UDP, messages are delivered at
+// most once: messages published while the service is disconnected, or that
+// arrive faster than they can be written, are dropped.
+type Service struct {
+	config Config
+	wg     sync.WaitGroup
+
+	mu            sync.RWMutex
+	done          chan struct{}   // Is the service closing or closed?
+	created       map[string]bool // Databases that have been created.
+	routes        []*route
+	subscriptions []subscription
+
+	PointsWriter interface {
+		WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+
+	MetaClient interface {
+		CreateDatabase(name string) (*meta.DatabaseInfo, error)
+	}
+
+	Logger      zap.Logger
+	stats       *Statistics
+	defaultTags models.StatisticTags
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	d := *c.WithDefaults()
+	return &Service{
+		config:      d,
+		created:     make(map[string]bool),
+		Logger:      zap.New(zap.NullEncoder()),
+		stats:       &Statistics{},
+		defaultTags: models.StatisticTags{"url": strings.Join(d.URLs, ","), "queue_group": d.QueueGroup},
+	}
+}
+
+// Open starts the service.  The NATS servers are connected to in the
+// background, so the service opens even if they can't be reached yet.
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed() {
+		return nil // Already open.
+	}
+	s.done = make(chan struct{})
+
+	// Subjects written to the same database and retention policy share a
+	// batcher.
+	routes := make(map[string]*route)
+	s.routes, s.subscriptions = nil, nil
+	for _, sc := range s.config.Subjects {
+		db, rp := sc.Database, sc.RetentionPolicy
+		if db == "" {
+			db = s.config.Database
+		}
+		if rp == "" {
+			rp = s.config.RetentionPolicy
+		}
+
+		key := db + "\x00" + rp
+		r := routes[key]
+		if r == nil {
+			r = &route{
+				database:        db,
+				retentionPolicy: rp,
+				batcher:         tsdb.NewPointBatcher(s.config.BatchSize, s.config.BatchPending, time.Duration(s.config.BatchTimeout)),
+			}
+			routes[key] = r
+			s.routes = append(s.routes, r)
+		}
+		s.subscriptions = append(s.subscriptions, subscription{subject: sc.Subject, route: r})
+	}
+
+	for _, r := range s.routes {
+		r.batcher.Start()
+		s.wg.Add(1)
+		go s.writer(r, s.done)
+	}
+
+	s.wg.Add(1)
+	go s.run(s.subscriptions, s.done)
+
+	return nil
+}
+
+// run keeps the service connected to the NATS servers until done is closed.
+// The NATS client reconnects to servers it loses on its own; run only
+// connects when there is no connection, backing off while no server can be
+// reached.
+func (s *Service) run(subscriptions []subscription, done chan struct{}) {
+	defer s.wg.Done()
+
+	urls := strings.Join(s.config.URLs, ",")
+	wait := time.Duration(s.config.ReconnectWait)
+	for {
+		conn, closed, err := s.connect(subscriptions, done)
+		if err != nil {
+			s.Logger.Info(fmt.Sprintf("Failed to connect to NATS at %s, retrying in %s: %s", urls, wait, err))
+
+			select {
+			case <-done:
+				return
+			case <-time.After(wait):
+			}
+
+			if wait *= 2; wait > time.Duration(s.config.MaxReconnectWait) {
+				wait = time.Duration(s.config.MaxReconnectWait)
+			}
+			continue
+		}
+		wait = time.Duration(s.config.ReconnectWait)
+
+		s.Logger.Info(fmt.Sprintf("Connected to NATS at %s as queue group %s", conn.ConnectedUrl(), s.config.QueueGroup))
+
+		select {
+		case <-done:
+			conn.Close()
+			return
+		case <-closed:
+			s.Logger.Info("Connection to NATS closed, reconnecting")
+		}
+	}
+}
+
+// connect connects to the NATS servers and subscribes to the subjects.  The
+// returned channel is closed when the connection is closed for good.
+func (s *Service) connect(subscriptions []subscription, done chan struct{}) (*gonats.Conn, <-chan struct{}, error) {
+	closed := make(chan struct{})
+	opts := []gonats.Option{
+		gonats.Name("influxdb"),
+		gonats.MaxReconnects(-1),
+		gonats.ReconnectWait(time.Duration(s.config.ReconnectWait)),
+		gonats.DisconnectHandler(func(*gonats.Conn) {
+			atomic.AddInt64(&s.stats.Disconnects, 1)
+			s.Logger.Info("Disconnected from NATS")
+		}),
+		gonats.ReconnectHandler(func(conn *gonats.Conn) {
+			atomic.AddInt64(&s.stats.Reconnects, 1)
+			s.Logger.Info(fmt.Sprintf("Reconnected to NATS at %s", conn.ConnectedUrl()))
+		}),
+		gonats.ClosedHandler(func(*gonats.Conn) {
+			close(closed)
+		}),
+		gonats.ErrorHandler(func(_ *gonats.Conn, sub *gonats.Subscription, err error) {
+			if err == gonats.ErrSlowConsumer {
+				atomic.AddInt64(&s.stats.SlowConsumers, 1)
+			}
+			if sub != nil {
+				s.Logger.Info(fmt.Sprintf("NATS error on subject %s: %s", sub.Subject, err))
+				return
+			}
+			s.Logger.Info(fmt.Sprintf("NATS error: %s", err))
+		}),
+	}
+	if s.config.Username != "" {
+		opts = append(opts, gonats.UserInfo(s.config.Username, s.config.Password))
+	}
+	if s.config.Token != "" {
+		opts = append(opts, gonats.Token(s.config.Token))
+	}
+	if s.config.TLSEnabled {
+		opts = append(opts, gonats.Secure(&tls.Config{
+			InsecureSkipVerify: s.config.InsecureSkipVerify,
+		}))
+	}
+
+	conn, err := gonats.Connect(strings.Join(s.config.URLs, ","), opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, sub := range subscriptions {
+		r := sub.route
+		ns, err := conn.QueueSubscribe(sub.subject, s.config.QueueGroup, func(msg *gonats.Msg) {
+			s.handleMessage(r, msg.Data, done)
+		})
+		if err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("subscribe to %s: %s", sub.subject, err)
+		}
+
+		if err := ns.SetPendingLimits(s.config.PendingLimit, -1); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("set pending limits of %s: %s", sub.subject, err)
+		}
+	}
+	return conn, closed, nil
+}
+
+// handleMessage parses the line protocol of a message and batches its points.
+func (s *Service) handleMessage(r *route, b []byte, done chan struct{}) {
+	atomic.AddInt64(&s.stats.MessagesReceived, 1)
+	atomic.AddInt64(&s.stats.BytesReceived, int64(len(b)))
+
+	points, err := models.ParsePointsWithPrecision(b, time.Now().UTC(), s.config.Precision)
+	if err != nil {
+		atomic.AddInt64(&s.stats.PointsParseFail, 1)
+		s.Logger.Info(fmt.Sprintf("Failed to parse points: %s", err))
+		return
+	}
+
+	for _, point := range points {
+		select {
+		case r.batcher.In() <- point:
+		case <-done:
+			return
+		}
+	}
+	atomic.AddInt64(&s.stats.PointsReceived, int64(len(points)))
+}
+
+func (s *Service) writer(r *route, done chan struct{}) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case batch := <-r.batcher.Out():
+			// Will attempt to create database if not yet created.
+			if err := s.createInternalStorage(r.database); err != nil {
+				s.Logger.Info(fmt.Sprintf("Required database %s does not yet exist: %s", r.database, err.Error()))
+				continue
+			}
+
+			if err := s.PointsWriter.WritePointsPrivileged(r.database, r.retentionPolicy, models.ConsistencyLevelAny, batch); err == nil {
+				atomic.AddInt64(&s.stats.BatchesTransmitted, 1)
+				atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)))
+			} else {
+				s.Logger.Info(fmt.Sprintf("failed to write point batch to database %q: %s", r.database, err))
+				atomic.AddInt64(&s.stats.BatchesTransmitFail, 1)
+			}
+
+		case <-done:
+			return
+		}
+	}
+}
+
+// Close closes the connection to NATS and stops writing points.
+func (s *Service) Close() error {
+	if wait := func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.closed() {
+			return false // Already closed.
+		}
+		close(s.done)
+		return true
+	}(); !wait {
+		return nil
+	}
+	s.wg.Wait()
+
+	// Release all remaining resources.
+	s.mu.Lock()
+	for _, r := range s.routes {
+		r.batcher.Stop()
+	}
+	s.done = nil
+	s.routes, s.subscriptions = nil, nil
+	s.mu.Unlock()
+
+	s.Logger.Info("Service closed")
+
+	return nil
+}
+
+// Closed returns true if the service is currently closed.
+func (s *Service) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed()
+}
+
+func (s *Service) closed() bool {
+	select {
+	case <-s.done:
+		// Service is closing.
+		return true
+	default:
+	}
+	return s.done == nil
+}
+
+// Statistics maintains statistics for the NATS service.
+type Statistics struct {
+	MessagesReceived    int64
+	BytesReceived       int64
+	PointsReceived      int64
+	PointsParseFail     int64
+	BatchesTransmitted  int64
+	PointsTransmitted   int64
+	BatchesTransmitFail int64
+	Disconnects         int64
+	Reconnects          int64
+	SlowConsumers       int64
+}
+
+// Statistics returns statistics for periodic monitoring.
+func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	return []models.Statistic{{
+		Name: "nats",
+		Tags: s.defaultTags.Merge(tags),
+		Values: map[string]interface{}{
+			statMessagesReceived:    atomic.LoadInt64(&s.stats.MessagesReceived),
+			statBytesReceived:       atomic.LoadInt64(&s.stats.BytesReceived),
+			statPointsReceived:      atomic.LoadInt64(&s.stats.PointsReceived),
+			statPointsParseFail:     atomic.LoadInt64(&s.stats.PointsParseFail),
+			statBatchesTransmitted:  atomic.LoadInt64(&s.stats.BatchesTransmitted),
+			statPointsTransmitted:   atomic.LoadInt64(&s.stats.PointsTransmitted),
+			statBatchesTransmitFail: atomic.LoadInt64(&s.stats.BatchesTransmitFail),
+			statDisconnects:         atomic.LoadInt64(&s.stats.Disconnects),
+			statReconnects:          atomic.LoadInt64(&s.stats.Reconnects),
+			statSlowConsumers:       atomic.LoadInt64(&s.stats.SlowConsumers),
+		},
+	}}
+}
+
+// createInternalStorage ensures that the database has been created.
+func (s *Service) createInternalStorage(database string) error {
+	s.mu.RLock()
+	created := s.created[database]
+	s.mu.RUnlock()
+	if created {
+		return nil
+	}
+
+	if _, err := s.MetaClient.CreateDatabase(database); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.created[database] = true
+	s.mu.Unlock()
+	return nil
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log zap.Logger) {
+	s.Logger = log.With(zap.String("service", "nats"))
+}
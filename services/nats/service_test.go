@@ -0,0 +1,177 @@
+package nats
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/internal"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/uber-go/zap"
+)
+
+func TestService_OpenClose(t *testing.T) {
+	c := NewConfig()
+	c.URLs = []string{"nats://127.0.0.1:1"}
+	c.Subjects = []SubjectConfig{{Subject: "metrics"}}
+	service := NewTestService(&c)
+
+	// Closing a closed service is fine.
+	if err := service.Service.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The service opens while NATS can't be reached.
+	if err := service.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Opening an already open service is fine.
+	if err := service.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening a previously opened service is fine.
+	if err := service.Service.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tidy up.
+	if err := service.Service.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestService_Routes(t *testing.T) {
+	c := NewConfig()
+	c.URLs = []string{"nats://127.0.0.1:1"}
+	c.Database = "nats"
+	c.BatchSize = 1
+	c.Subjects = []SubjectConfig{
+		{Subject: "telegraf.>"},
+		{Subject: "app.*.metrics", Database: "app", RetentionPolicy: "week"},
+		{Subject: "app.*.events", Database: "app", RetentionPolicy: "week"},
+	}
+	s := NewTestService(&c)
+
+	var mu sync.Mutex
+	var created []string
+	s.MetaClient.CreateDatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		created = append(created, name)
+		return nil, nil
+	}
+
+	written := make(chan string, 3)
+	s.WritePointsFn = func(database, rp string, _ models.ConsistencyLevel, points []models.Point) error {
+		for _, p := range points {
+			written <- database + "." + rp + " " + p.String()
+		}
+		return nil
+	}
+
+	if err := s.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Service.Close()
+
+	// Subjects written to the same database and retention policy share a
+	// route.
+	subs := s.Service.subscriptions
+	if len(s.Service.routes) != 2 {
+		t.Fatalf("unexpected routes: %d", len(s.Service.routes))
+	} else if subs[1].route != subs[2].route {
+		t.Fatal("expected subjects to share a route")
+	}
+
+	done := s.Service.done
+	s.Service.handleMessage(subs[0].route, []byte("cpu value=1 10"), done)
+	s.Service.handleMessage(subs[1].route, []byte("mem value=2 20"), done)
+	s.Service.handleMessage(subs[2].route, []byte("event value=3 30"), done)
+	s.Service.handleMessage(subs[2].route, []byte("event value="), done)
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		select {
+		case w := <-written:
+			got = append(got, w)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for points, got %v", got)
+		}
+	}
+	sort.Strings(got)
+
+	if exp := []string{
+		"app.week event value=3 30",
+		"app.week mem value=2 20",
+		"nats. cpu value=1 10",
+	}; !equalStrings(got, exp) {
+		t.Fatalf("unexpected points:\n\tgot = %v\n\texp = %v", got, exp)
+	}
+
+	mu.Lock()
+	sort.Strings(created)
+	if exp := []string{"app", "nats"}; !equalStrings(created, exp) {
+		t.Fatalf("unexpected created databases: %v", created)
+	}
+	mu.Unlock()
+
+	if n := s.Service.stats.PointsParseFail; n != 1 {
+		t.Fatalf("unexpected parse failures: %d", n)
+	}
+}
+
+type TestService struct {
+	Service       *Service
+	Config        Config
+	MetaClient    *internal.MetaClientMock
+	WritePointsFn func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+}
+
+func NewTestService(c *Config) *TestService {
+	if c == nil {
+		defaultC := NewConfig()
+		c = &defaultC
+	}
+
+	service := &TestService{
+		Service:    NewService(*c),
+		Config:     *c,
+		MetaClient: &internal.MetaClientMock{},
+	}
+
+	if testing.Verbose() {
+		service.Service.WithLogger(zap.New(
+			zap.NewTextEncoder(),
+			zap.Output(os.Stderr),
+		))
+	}
+
+	service.Service.MetaClient = service.MetaClient
+	service.Service.PointsWriter = service
+	return service
+}
+
+func (s *TestService) WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	return s.WritePointsFn(database, retentionPolicy, consistencyLevel, points)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
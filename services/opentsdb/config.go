@@ -29,6 +29,10 @@ const (
 	// DefaultBatchPending is the default number of batches that can be in the queue.
 	DefaultBatchPending = 5
 
+	// DefaultMaxBodySize is the default maximum size of a decompressed HTTP
+	// request body, in bytes. Specify 0 for no limit.
+	DefaultMaxBodySize = 25e6
+
 	// DefaultCertificate is the default location of the certificate used when TLS is enabled.
 	DefaultCertificate = "/etc/ssl/influxdb.pem"
 )
@@ -42,10 +46,15 @@ type Config struct {
 	ConsistencyLevel string        `toml:"consistency-level"`
 	TLSEnabled       bool          `toml:"tls-enabled"`
 	Certificate      string        `toml:"certificate"`
+	PrivateKey       string        `toml:"private-key"`
+	ClientCA         string        `toml:"client-ca"`
+	AuthEnabled      bool          `toml:"auth-enabled"`
+	SharedSecret     string        `toml:"shared-secret"`
 	BatchSize        int           `toml:"batch-size"`
 	BatchPending     int           `toml:"batch-pending"`
 	BatchTimeout     toml.Duration `toml:"batch-timeout"`
 	LogPointErrors   bool          `toml:"log-point-errors"`
+	MaxBodySize      int           `toml:"max-body-size"`
 }
 
 // NewConfig returns a new config for the service.
@@ -61,6 +70,7 @@ func NewConfig() Config {
 		BatchPending:     DefaultBatchPending,
 		BatchTimeout:     toml.Duration(DefaultBatchTimeout),
 		LogPointErrors:   true,
+		MaxBodySize:      DefaultMaxBodySize,
 	}
 }
 
@@ -102,7 +112,7 @@ type Configs []Config
 // Diagnostics returns one set of diagnostics for all of the Configs.
 func (c Configs) Diagnostics() (*diagnostics.Diagnostics, error) {
 	d := &diagnostics.Diagnostics{
-		Columns: []string{"enabled", "bind-address", "database", "retention-policy", "batch-size", "batch-pending", "batch-timeout"},
+		Columns: []string{"enabled", "bind-address", "database", "retention-policy", "batch-size", "batch-pending", "batch-timeout", "tls-enabled", "auth-enabled"},
 	}
 
 	for _, cc := range c {
@@ -111,7 +121,7 @@ func (c Configs) Diagnostics() (*diagnostics.Diagnostics, error) {
 			continue
 		}
 
-		r := []interface{}{true, cc.BindAddress, cc.Database, cc.RetentionPolicy, cc.BatchSize, cc.BatchPending, cc.BatchTimeout}
+		r := []interface{}{true, cc.BindAddress, cc.Database, cc.RetentionPolicy, cc.BatchSize, cc.BatchPending, cc.BatchTimeout, cc.TLSEnabled, cc.AuthEnabled}
 		d.AddRow(r)
 	}
 
@@ -17,7 +17,12 @@ database = "xxx"
 consistency-level ="all"
 tls-enabled = true
 certificate = "/etc/ssl/cert.pem"
+private-key = "/etc/ssl/key.pem"
+client-ca = "/etc/ssl/ca.pem"
+auth-enabled = true
+shared-secret = "secret"
 log-point-errors = true
+max-body-size = 1000
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -35,7 +40,17 @@ log-point-errors = true
 		t.Fatalf("unexpected tls-enabled: %v", c.TLSEnabled)
 	} else if c.Certificate != "/etc/ssl/cert.pem" {
 		t.Fatalf("unexpected certificate: %s", c.Certificate)
+	} else if c.PrivateKey != "/etc/ssl/key.pem" {
+		t.Fatalf("unexpected private-key: %s", c.PrivateKey)
+	} else if c.ClientCA != "/etc/ssl/ca.pem" {
+		t.Fatalf("unexpected client-ca: %s", c.ClientCA)
+	} else if !c.AuthEnabled {
+		t.Fatalf("unexpected auth-enabled: %v", c.AuthEnabled)
+	} else if c.SharedSecret != "secret" {
+		t.Fatalf("unexpected shared-secret: %s", c.SharedSecret)
 	} else if !c.LogPointErrors {
 		t.Fatalf("unexpected log-point-errors: %v", c.LogPointErrors)
+	} else if c.MaxBodySize != 1000 {
+		t.Fatalf("unexpected max-body-size: %d", c.MaxBodySize)
 	}
 }
@@ -1,7 +1,7 @@
 package opentsdb
 
 import (
-	"bufio"
+	"bytes"
 	"compress/gzip"
 	"encoding/json"
 	"errors"
@@ -9,6 +9,8 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,6 +29,14 @@ type Handler struct {
 		WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
 	}
 
+	// Authenticate authenticates the basic auth credentials of requests, if
+	// set.
+	Authenticate func(username, password string) error
+
+	// MaxBodySize is the maximum size in bytes of a decompressed request
+	// body, or 0 for no limit.
+	MaxBodySize int
+
 	Logger zap.Logger
 
 	stats *Statistics
@@ -34,6 +44,18 @@ type Handler struct {
 
 // ServeHTTP handles an HTTP request of the OpenTSDB REST API.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Authenticate != nil {
+		username, password, _ := r.BasicAuth()
+		if err := h.Authenticate(username, password); err != nil {
+			if h.stats != nil {
+				atomic.AddInt64(&h.stats.AuthFail, 1)
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="InfluxDB"`)
+			h.httpError(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+
 	switch r.URL.Path {
 	case "/api/metadata/put":
 		w.WriteHeader(http.StatusNoContent)
@@ -45,98 +67,177 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 // servePut implements OpenTSDB's HTTP /api/put endpoint.
+//
+// Valid data points are written even if others in the request are invalid,
+// in which case a 400 is returned.  The "summary" and "details" query
+// parameters add the number of data points written and failed to the
+// response and, for "details", the error of each failed data point.
 func (h *Handler) servePut(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
 	// Require POST method.
 	if r.Method != "POST" {
-		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		h.httpError(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.MaxBodySize > 0 && r.ContentLength > int64(h.MaxBodySize) {
+		h.httpError(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
 		return
 	}
 
-	// Wrap reader if it's gzip encoded.
-	var br *bufio.Reader
-	if r.Header.Get("Content-Encoding") == "gzip" {
+	// Wrap reader if it's gzip encoded.  Chunked bodies are decoded by the
+	// HTTP server.
+	var body io.Reader = r.Body
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
 		zr, err := gzip.NewReader(r.Body)
 		if err != nil {
-			http.Error(w, "could not read gzip, "+err.Error(), http.StatusBadRequest)
+			h.httpError(w, "could not read gzip, "+err.Error(), http.StatusBadRequest)
 			return
 		}
-
-		br = bufio.NewReader(zr)
-	} else {
-		br = bufio.NewReader(r.Body)
+		defer zr.Close()
+		body = zr
 	}
 
-	// Lookahead at the first byte.
-	f, err := br.Peek(1)
-	if err != nil || len(f) != 1 {
-		http.Error(w, "peek error: "+err.Error(), http.StatusBadRequest)
-		return
+	// Limit the size of the decompressed body.
+	if h.MaxBodySize > 0 {
+		body = &io.LimitedReader{R: body, N: int64(h.MaxBodySize) + 1}
 	}
 
-	// Peek to see if this is a JSON array.
-	var multi bool
-	switch f[0] {
-	case '{':
-	case '[':
-		multi = true
-	default:
-		http.Error(w, "expected JSON array or hash", http.StatusBadRequest)
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(body); err != nil {
+		h.httpError(w, "could not read body, "+err.Error(), http.StatusBadRequest)
+		return
+	} else if h.MaxBodySize > 0 && buf.Len() > h.MaxBodySize {
+		h.httpError(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
 		return
 	}
+	data := bytes.TrimSpace(buf.Bytes())
 
-	// Decode JSON data into slice of points.
-	dps := make([]point, 1)
-	if dec := json.NewDecoder(br); multi {
-		if err = dec.Decode(&dps); err != nil {
-			http.Error(w, "json array decode error", http.StatusBadRequest)
-			return
-		}
-	} else {
-		if err = dec.Decode(&dps[0]); err != nil {
-			http.Error(w, "json object decode error", http.StatusBadRequest)
+	// Decode a JSON array or hash into the raw data points.
+	var raw []json.RawMessage
+	switch {
+	case len(data) == 0:
+		h.httpError(w, "missing data points", http.StatusBadRequest)
+		return
+	case data[0] == '[':
+		if err := json.Unmarshal(data, &raw); err != nil {
+			h.httpError(w, "json array decode error, "+err.Error(), http.StatusBadRequest)
 			return
 		}
+	case data[0] == '{':
+		raw = []json.RawMessage{data}
+	default:
+		h.httpError(w, "expected JSON array or hash", http.StatusBadRequest)
+		return
 	}
 
-	// Convert points into TSDB points.
-	points := make([]models.Point, 0, len(dps))
-	for i := range dps {
-		p := dps[i]
-
-		// Convert timestamp to Go time.
-		// If time value is over a billion then it's microseconds.
-		var ts time.Time
-		if p.Time < 10000000000 {
-			ts = time.Unix(p.Time, 0)
-		} else {
-			ts = time.Unix(p.Time/1000, (p.Time%1000)*1000)
-		}
-
-		pt, err := models.NewPoint(p.Metric, models.NewTags(p.Tags), map[string]interface{}{"value": p.Value}, ts)
+	// Convert data points into TSDB points.
+	var errs []putError
+	points := make([]models.Point, 0, len(raw))
+	for _, dp := range raw {
+		pt, err := parsePoint(dp)
 		if err != nil {
-			h.Logger.Info(fmt.Sprintf("Dropping point %v: %v", p.Metric, err))
+			h.Logger.Info(fmt.Sprintf("Dropping point %s: %v", dp, err))
 			if h.stats != nil {
 				atomic.AddInt64(&h.stats.InvalidDroppedPoints, 1)
 			}
+			errs = append(errs, putError{Datapoint: dp, Error: err.Error()})
 			continue
 		}
 		points = append(points, pt)
 	}
 
 	// Write points.
-	if err := h.PointsWriter.WritePointsPrivileged(h.Database, h.RetentionPolicy, models.ConsistencyLevelAny, points); influxdb.IsClientError(err) {
-		h.Logger.Info(fmt.Sprint("write series error: ", err))
-		http.Error(w, "write series error: "+err.Error(), http.StatusBadRequest)
-		return
-	} else if err != nil {
-		h.Logger.Info(fmt.Sprint("write series error: ", err))
-		http.Error(w, "write series error: "+err.Error(), http.StatusInternalServerError)
-		return
+	if len(points) > 0 {
+		if err := h.PointsWriter.WritePointsPrivileged(h.Database, h.RetentionPolicy, models.ConsistencyLevelAny, points); influxdb.IsClientError(err) {
+			h.Logger.Info(fmt.Sprint("write series error: ", err))
+			h.httpError(w, "write series error: "+err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			h.Logger.Info(fmt.Sprint("write series error: ", err))
+			h.httpError(w, "write series error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	q := r.URL.Query()
+	_, details := q["details"]
+	_, summary := q["summary"]
+
+	status := http.StatusOK
+	if len(errs) > 0 {
+		status = http.StatusBadRequest
+	}
+
+	switch {
+	case details:
+		if errs == nil {
+			errs = []putError{}
+		}
+		h.writeJSON(w, status, putDetails{Errors: errs, Failed: len(errs), Success: len(points)})
+	case summary:
+		h.writeJSON(w, status, putSummary{Failed: len(errs), Success: len(points)})
+	case len(errs) > 0:
+		h.httpError(w, `One or more data points had errors, append "details" to the put request for details`, http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// parsePoint converts a JSON data point into a TSDB point.
+func parsePoint(data []byte) (models.Point, error) {
+	var p point
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("unable to parse data point: %s", err)
+	}
+
+	if p.Metric == "" {
+		return nil, errors.New("metric name was empty")
+	}
+
+	t, err := p.Time.Int64()
+	if err != nil || t <= 0 {
+		return nil, errors.New("invalid timestamp")
+	}
+
+	value, err := p.Value.Float64()
+	if err != nil {
+		return nil, errors.New("unable to parse value to a number")
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	// Convert timestamp to Go time.
+	// If time value is over a billion then it's microseconds.
+	var ts time.Time
+	if t < 10000000000 {
+		ts = time.Unix(t, 0)
+	} else {
+		ts = time.Unix(t/1000, (t%1000)*1000)
+	}
+
+	return models.NewPoint(p.Metric, models.NewTags(p.Tags), map[string]interface{}{"value": value}, ts)
+}
+
+// httpError writes an error response in OpenTSDB's JSON format.
+func (h *Handler) httpError(w http.ResponseWriter, message string, code int) {
+	var resp struct {
+		Error struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	resp.Error.Code = code
+	resp.Error.Message = message
+	h.writeJSON(w, code, resp)
+}
+
+// writeJSON writes v as the JSON body of a response with the given status.
+func (h *Handler) writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.Logger.Info(fmt.Sprint("error writing response: ", err))
+	}
 }
 
 // chanListener represents a listener that receives connections through a channel.
@@ -189,10 +290,55 @@ type readerConn struct {
 // Read implements the io.Reader interface.
 func (conn *readerConn) Read(b []byte) (n int, err error) { return conn.r.Read(b) }
 
-// point represents an incoming JSON data point.
+// point represents an incoming JSON data point.  The timestamp and value may
+// be JSON numbers or strings.
 type point struct {
 	Metric string            `json:"metric"`
-	Time   int64             `json:"timestamp"`
-	Value  float64           `json:"value"`
+	Time   number            `json:"timestamp"`
+	Value  number            `json:"value"`
 	Tags   map[string]string `json:"tags,omitempty"`
 }
+
+// number is a JSON number, or a string holding one.  Unlike json.Number, it
+// isn't validated when decoded, so an invalid number is reported as an
+// invalid timestamp or value whichever version of Go decodes it.
+type number string
+
+// UnmarshalJSON decodes a JSON number or string.
+func (n *number) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*n = number(s)
+		return nil
+	}
+	*n = number(b)
+	return nil
+}
+
+// Int64 returns the number as an int64.
+func (n number) Int64() (int64, error) { return strconv.ParseInt(string(n), 10, 64) }
+
+// Float64 returns the number as a float64.
+func (n number) Float64() (float64, error) { return strconv.ParseFloat(string(n), 64) }
+
+// putError is the error of a data point that could not be written.
+type putError struct {
+	Datapoint json.RawMessage `json:"datapoint"`
+	Error     string          `json:"error"`
+}
+
+// putSummary is the response of /api/put with the "summary" query parameter.
+type putSummary struct {
+	Failed  int `json:"failed"`
+	Success int `json:"success"`
+}
+
+// putDetails is the response of /api/put with the "details" query parameter.
+type putDetails struct {
+	Errors  []putError `json:"errors"`
+	Failed  int        `json:"failed"`
+	Success int        `json:"success"`
+}
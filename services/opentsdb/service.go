@@ -4,7 +4,9 @@ package opentsdb // import "github.com/influxdata/influxdb/services/opentsdb"
 import (
 	"bufio"
 	"bytes"
+	"crypto/subtle"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -16,7 +18,9 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/tlsconfig"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/uber-go/zap"
@@ -40,16 +44,31 @@ const (
 	statConnectionsActive        = "connsActive"
 	statConnectionsHandled       = "connsHandled"
 	statDroppedPointsInvalid     = "droppedPointsInvalid"
+	statAuthFail                 = "authFail"
 )
 
+// authTimeout is the time telnet connections have to authenticate.
+const authTimeout = 10 * time.Second
+
+// ErrAuthenticate is returned when a connection or request fails to
+// authenticate.
+var ErrAuthenticate = errors.New("authentication failed")
+
 // Service manages the listener and handler for an HTTP endpoint.
 type Service struct {
 	ln     net.Listener  // main listener
 	httpln *chanListener // http channel-based listener
 
-	wg   sync.WaitGroup
-	tls  bool
-	cert string
+	wg       sync.WaitGroup
+	tls      bool
+	cert     string
+	key      string
+	clientCA string
+
+	authEnabled  bool
+	sharedSecret string
+
+	maxBodySize int
 
 	mu    sync.RWMutex
 	ready bool          // Has the required database been created?
@@ -64,6 +83,7 @@ type Service struct {
 	}
 	MetaClient interface {
 		CreateDatabase(name string) (*meta.DatabaseInfo, error)
+		Authenticate(username, password string) (meta.User, error)
 	}
 
 	// Points received over the telnet protocol are batched.
@@ -87,6 +107,11 @@ func NewService(c Config) (*Service, error) {
 	s := &Service{
 		tls:             d.TLSEnabled,
 		cert:            d.Certificate,
+		key:             d.PrivateKey,
+		clientCA:        d.ClientCA,
+		authEnabled:     d.AuthEnabled,
+		sharedSecret:    d.SharedSecret,
+		maxBodySize:     d.MaxBodySize,
 		BindAddress:     d.BindAddress,
 		Database:        d.Database,
 		RetentionPolicy: d.RetentionPolicy,
@@ -122,14 +147,12 @@ func (s *Service) Open() error {
 
 	// Open listener.
 	if s.tls {
-		cert, err := tls.LoadX509KeyPair(s.cert, s.cert)
+		config, err := tlsconfig.Server(s.cert, s.key, s.clientCA)
 		if err != nil {
 			return err
 		}
 
-		listener, err := tls.Listen("tcp", s.BindAddress, &tls.Config{
-			Certificates: []tls.Certificate{cert},
-		})
+		listener, err := tls.Listen("tcp", s.BindAddress, config)
 		if err != nil {
 			return err
 		}
@@ -252,6 +275,7 @@ type Statistics struct {
 	ActiveConnections        int64
 	HandledConnections       int64
 	InvalidDroppedPoints     int64
+	AuthFail                 int64
 }
 
 // Statistics returns statistics for periodic monitoring.
@@ -276,6 +300,7 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 			statConnectionsActive:        atomic.LoadInt64(&s.stats.ActiveConnections),
 			statConnectionsHandled:       atomic.LoadInt64(&s.stats.HandledConnections),
 			statDroppedPointsInvalid:     atomic.LoadInt64(&s.stats.InvalidDroppedPoints),
+			statAuthFail:                 atomic.LoadInt64(&s.stats.AuthFail),
 		},
 	}}
 }
@@ -350,6 +375,23 @@ func (s *Service) handleTelnetConn(conn net.Conn) {
 
 	// Wrap connection in a text protocol reader.
 	r := textproto.NewReader(bufio.NewReader(conn))
+
+	// Authenticated connections start with "auth <shared-secret>" or
+	// "auth <username> <password>".
+	if s.authEnabled {
+		conn.SetReadDeadline(time.Now().Add(authTimeout))
+		line, err := r.ReadLine()
+		if err == nil {
+			err = s.authenticateLine(line)
+		}
+		if err != nil {
+			atomic.AddInt64(&s.stats.AuthFail, 1)
+			s.Logger.Info(fmt.Sprintf("failed to authenticate openTSDB connection from %s: %s", remoteAddr, err))
+			return
+		}
+		conn.SetReadDeadline(time.Time{})
+	}
+
 	for {
 		line, err := r.ReadLine()
 		if err != nil {
@@ -442,15 +484,54 @@ func (s *Service) handleTelnetConn(conn net.Conn) {
 	}
 }
 
+// authenticateLine authenticates the auth command of a telnet connection.
+func (s *Service) authenticateLine(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "auth" {
+		return errors.New("auth command required")
+	}
+
+	switch len(fields) {
+	case 2:
+		return s.authenticate("", fields[1])
+	case 3:
+		return s.authenticate(fields[1], fields[2])
+	}
+	return ErrAuthenticate
+}
+
+// authenticate returns nil if password is the shared secret, or if username
+// and password are the credentials of a user allowed to write to the database.
+func (s *Service) authenticate(username, password string) error {
+	if s.sharedSecret != "" && subtle.ConstantTimeCompare([]byte(password), []byte(s.sharedSecret)) == 1 {
+		return nil
+	} else if username == "" {
+		return ErrAuthenticate
+	}
+
+	u, err := s.MetaClient.Authenticate(username, password)
+	if err != nil {
+		return ErrAuthenticate
+	}
+	if !u.AuthorizeDatabase(influxql.WritePrivilege, s.Database) {
+		return fmt.Errorf("%q user is not authorized to write to database %q", u.ID(), s.Database)
+	}
+	return nil
+}
+
 // serveHTTP handles connections in HTTP format.
 func (s *Service) serveHTTP() {
 	handler := &Handler{
 		Database:        s.Database,
 		RetentionPolicy: s.RetentionPolicy,
 		PointsWriter:    s.PointsWriter,
+		MaxBodySize:     s.maxBodySize,
 		Logger:          s.Logger,
 		stats:           s.stats,
 	}
+	if s.authEnabled {
+		handler.Authenticate = s.authenticate
+	}
 	srv := &http.Server{Handler: handler}
 	srv.Serve(s.httpln)
 }
@@ -1,8 +1,12 @@
 package opentsdb
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
@@ -13,6 +17,7 @@ import (
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/internal"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
@@ -248,6 +253,198 @@ func TestService_HTTP(t *testing.T) {
 	}
 }
 
+// Ensure gzipped and chunked HTTP requests are accepted, and that the errors
+// of invalid data points are returned with the "details" query parameter.
+func TestService_HTTP_Details(t *testing.T) {
+	t.Parallel()
+
+	s := NewTestService("db0", "127.0.0.1:0")
+	if err := s.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Service.Close()
+
+	// Mock points writer.
+	var n int
+	s.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+		n += len(points)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(`[{"metric":"sys.cpu.nice", "timestamp":1346846400, "value":"18", "tags":{"host":"web01"}}, {"metric":"sys.cpu.nice", "timestamp":1346846400, "value":"x"}]`))
+	zw.Close()
+
+	// Hide the length of the body so the request is chunked.
+	req, err := http.NewRequest("POST", "http://"+s.Service.Addr().String()+"/api/put?details", ioutil.NopCloser(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	}
+	var details struct {
+		Errors []struct {
+			Datapoint map[string]interface{} `json:"datapoint"`
+			Error     string                 `json:"error"`
+		} `json:"errors"`
+		Failed  int `json:"failed"`
+		Success int `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
+		t.Fatal(err)
+	}
+	if details.Success != 1 || details.Failed != 1 || len(details.Errors) != 1 {
+		t.Fatalf("unexpected details: %+v", details)
+	} else if got, exp := details.Errors[0].Error, "unable to parse value to a number"; got != exp {
+		t.Fatalf("unexpected error: got %q, exp %q", got, exp)
+	} else if got, exp := details.Errors[0].Datapoint["value"], "x"; got != exp {
+		t.Fatalf("unexpected data point value: got %v, exp %v", got, exp)
+	}
+
+	if n != 1 {
+		t.Fatalf("unexpected number of points written: %d", n)
+	}
+}
+
+// Ensure HTTP requests with a body larger than the maximum are rejected.
+func TestService_HTTP_MaxBodySize(t *testing.T) {
+	t.Parallel()
+
+	s := NewTestService("db0", "127.0.0.1:0")
+	s.Service.maxBodySize = 10
+	if err := s.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Service.Close()
+
+	s.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+		t.Fatal("points writer called")
+		return nil
+	}
+
+	resp, err := http.Post("http://"+s.Service.Addr().String()+"/api/put", "application/json", strings.NewReader(`{"metric":"sys.cpu.nice", "timestamp":1346846400, "value":18}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+// Ensure telnet connections must authenticate when auth is enabled.
+func TestService_Telnet_Auth(t *testing.T) {
+	t.Parallel()
+
+	s := NewTestService("db0", "127.0.0.1:0")
+	s.Service.authEnabled = true
+	s.Service.sharedSecret = "secret"
+	if err := s.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Service.Close()
+
+	written := make(chan []models.Point, 1)
+	s.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+		written <- points
+		return nil
+	}
+
+	// A connection with the wrong secret is closed before its points are read.
+	conn, err := net.Dial("tcp", s.Service.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("auth wrong\nput sys.cpu.user 1356998400 1 host=a\n")); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := ioutil.ReadAll(conn); err != nil {
+		t.Fatalf("connection should have been closed: %s", err)
+	}
+	conn.Close()
+
+	conn, err = net.Dial("tcp", s.Service.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("auth secret\nput sys.cpu.user 1356998400 2 host=a\n")); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	select {
+	case points := <-written:
+		if len(points) != 1 || points[0].String() != "sys.cpu.user,host=a value=2 1356998400000000000" {
+			t.Fatalf("unexpected points: %v", points)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("points writer not called")
+	}
+
+	if n := atomic.LoadInt64(&s.Service.stats.AuthFail); n != 1 {
+		t.Fatalf("unexpected auth failures: %d", n)
+	}
+}
+
+// Ensure HTTP requests must authenticate when auth is enabled.
+func TestService_HTTP_Auth(t *testing.T) {
+	t.Parallel()
+
+	s := NewTestService("db0", "127.0.0.1:0")
+	s.Service.authEnabled = true
+	s.MetaClient.AuthenticateFn = func(username, password string) (meta.User, error) {
+		if username != "writer" || password != "password" {
+			return nil, meta.ErrAuthenticate
+		}
+		return &meta.UserInfo{Name: username, Privileges: map[string]influxql.Privilege{"db0": influxql.WritePrivilege}}, nil
+	}
+	s.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+		return nil
+	}
+	if err := s.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Service.Close()
+
+	for _, tt := range []struct {
+		username, password string
+		status             int
+	}{
+		{status: http.StatusUnauthorized},
+		{username: "writer", password: "wrong", status: http.StatusUnauthorized},
+		{username: "writer", password: "password", status: http.StatusNoContent},
+	} {
+		req, err := http.NewRequest("POST", "http://"+s.Service.Addr().String()+"/api/put", strings.NewReader(`{"metric":"sys.cpu.nice", "timestamp":1346846400, "value":18}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.username != "" {
+			req.SetBasicAuth(tt.username, tt.password)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Fatalf("%s: unexpected status code: %d", tt.username, resp.StatusCode)
+		}
+	}
+}
+
 type TestService struct {
 	Service       *Service
 	MetaClient    *internal.MetaClientMock
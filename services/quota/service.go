@@ -0,0 +1,275 @@
+// Package quota provides a service that enforces the disk, series and write
+// rate quotas of databases.
+package quota // import "github.com/influxdata/influxdb/services/quota"
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/uber-go/zap"
+)
+
+// Statistics for the quota service, per database.
+const (
+	statDiskBytes      = "diskBytes"
+	statDiskQuota      = "diskQuota"
+	statSeriesN        = "seriesN"
+	statSeriesQuota    = "seriesQuota"
+	statWritesQuota    = "writesQuota"
+	statDiskRejected   = "diskRejected"
+	statSeriesRejected = "seriesRejected"
+	statWritesRejected = "writesRejected"
+)
+
+// Service rejects writes to databases that have exceeded their quotas.  The
+// disk usage and series of databases are checked on an interval, so a
+// database may exceed its disk or series quota by what is written to it in
+// one interval.  The write rate is limited per request, allowing up to a
+// second's worth of points to be written at once.
+type Service struct {
+	config Config
+
+	MetaClient interface {
+		Database(name string) *meta.DatabaseInfo
+		Databases() []meta.DatabaseInfo
+	}
+
+	TSDBStore interface {
+		DatabaseStorage(name string) (tsdb.DatabaseStorage, error)
+	}
+
+	mu        sync.Mutex
+	databases map[string]*database
+
+	now func() time.Time
+
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	Logger zap.Logger
+}
+
+// database holds the usage of a database and the writes rejected to it.
+type database struct {
+	quota meta.QuotaInfo
+	usage tsdb.DatabaseStorage
+
+	// tokens is the number of points that may still be written in the
+	// current second.  It is refilled at the write quota per second, and
+	// goes negative when a write is larger than what is left.
+	tokens float64
+	last   time.Time
+
+	diskRejected   int64
+	seriesRejected int64
+	writesRejected int64
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		config:    c,
+		databases: make(map[string]*database),
+		now:       time.Now,
+		Logger:    zap.New(zap.NullEncoder()),
+	}
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log zap.Logger) {
+	s.Logger = log.With(zap.String("service", "quota"))
+}
+
+// Open starts checking the usage of databases.
+func (s *Service) Open() error {
+	if s.done != nil {
+		return nil
+	}
+
+	s.Logger.Info(fmt.Sprintf("Starting quota service, check interval: %v", s.config.CheckInterval))
+
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go s.run()
+	return nil
+}
+
+// Close stops checking the usage of databases.
+func (s *Service) Close() error {
+	if s.done == nil {
+		return nil
+	}
+
+	close(s.done)
+	s.wg.Wait()
+	s.done = nil
+	return nil
+}
+
+// Statistics returns statistics for periodic monitoring of the databases
+// with quotas.
+func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statistics := make([]models.Statistic, 0, len(s.databases))
+	for name, db := range s.databases {
+		if db.quota.IsZero() {
+			continue
+		}
+		statistics = append(statistics, models.Statistic{
+			Name: "quota",
+			Tags: models.StatisticTags{"database": name}.Merge(tags),
+			Values: map[string]interface{}{
+				statDiskBytes:      db.usage.DiskBytes,
+				statDiskQuota:      db.quota.DiskBytes,
+				statSeriesN:        db.usage.SeriesN,
+				statSeriesQuota:    db.quota.SeriesN,
+				statWritesQuota:    db.quota.WritesPerSecond,
+				statDiskRejected:   db.diskRejected,
+				statSeriesRejected: db.seriesRejected,
+				statWritesRejected: db.writesRejected,
+			},
+		})
+	}
+	return statistics
+}
+
+// CheckWrite returns an influxdb.QuotaError if n points may not be written
+// to the named database because it has exceeded one of its quotas.
+func (s *Service) CheckWrite(name string, n int) error {
+	di := s.MetaClient.Database(name)
+	if di == nil || di.Quota.IsZero() {
+		return nil
+	}
+	q := di.Quota
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db := s.database(name)
+	db.quota = q
+
+	switch exceeded(q, db.usage) {
+	case influxdb.QuotaDisk:
+		db.diskRejected++
+		return influxdb.QuotaError{Database: name, Quota: influxdb.QuotaDisk, Limit: q.DiskBytes}
+	case influxdb.QuotaSeries:
+		db.seriesRejected++
+		return influxdb.QuotaError{Database: name, Quota: influxdb.QuotaSeries, Limit: q.SeriesN}
+	}
+
+	if q.WritesPerSecond > 0 {
+		now := s.now()
+		rate := float64(q.WritesPerSecond)
+		if db.last.IsZero() {
+			db.tokens = rate
+		} else if db.tokens += now.Sub(db.last).Seconds() * rate; db.tokens > rate {
+			db.tokens = rate
+		}
+		db.last = now
+
+		if db.tokens <= 0 {
+			db.writesRejected++
+			return influxdb.QuotaError{Database: name, Quota: influxdb.QuotaWrites, Limit: q.WritesPerSecond}
+		}
+		db.tokens -= float64(n)
+	}
+	return nil
+}
+
+// database returns the state of the named database, creating it if needed.
+// s.mu must be held.
+func (s *Service) database(name string) *database {
+	db := s.databases[name]
+	if db == nil {
+		db = &database{usage: tsdb.DatabaseStorage{Name: name}}
+		s.databases[name] = db
+	}
+	return db
+}
+
+// exceeded returns the disk or series quota of q exceeded by usage, or an
+// empty string if neither is.
+func exceeded(q meta.QuotaInfo, usage tsdb.DatabaseStorage) string {
+	if q.DiskBytes > 0 && usage.DiskBytes >= q.DiskBytes {
+		return influxdb.QuotaDisk
+	} else if q.SeriesN > 0 && usage.SeriesN >= q.SeriesN {
+		return influxdb.QuotaSeries
+	}
+	return ""
+}
+
+// run checks the usage of databases every check interval.
+func (s *Service) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(s.config.CheckInterval))
+	defer ticker.Stop()
+
+	for {
+		if err := s.check(); err != nil {
+			s.Logger.Info(fmt.Sprintf("WARN: unable to check database quotas: %s", err))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// check reads the disk usage and series of the databases with a disk or
+// series quota, and logs the databases that have newly exceeded one.  Other
+// databases aren't read, so the check is free when no quota is set.
+func (s *Service) check() error {
+	dis := s.MetaClient.Databases()
+	quotas := make(map[string]meta.QuotaInfo, len(dis))
+	usage := make(map[string]tsdb.DatabaseStorage)
+	for _, di := range dis {
+		quotas[di.Name] = di.Quota
+		if di.Quota.DiskBytes <= 0 && di.Quota.SeriesN <= 0 {
+			continue
+		}
+
+		st, err := s.TSDBStore.DatabaseStorage(di.Name)
+		if err != nil {
+			return err
+		}
+		usage[di.Name] = st
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name := range usage {
+		s.database(name)
+	}
+
+	for name, db := range s.databases {
+		q, ok := quotas[name]
+		if !ok {
+			delete(s.databases, name)
+			continue
+		}
+
+		prev := exceeded(db.quota, db.usage)
+		db.quota = q
+		if st, ok := usage[name]; ok {
+			db.usage = st
+		} else {
+			db.usage = tsdb.DatabaseStorage{Name: name}
+		}
+
+		if quota := exceeded(db.quota, db.usage); quota != "" && quota != prev {
+			s.Logger.Info(fmt.Sprintf("WARN: database %s has exceeded its %s quota, rejecting writes", name, quota))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+func TestService_CheckWrite_Usage(t *testing.T) {
+	s := NewTestService()
+	s.quotas["db0"] = meta.QuotaInfo{DiskBytes: 1000, SeriesN: 10}
+	s.storage = []tsdb.DatabaseStorage{
+		{Name: "db0", DiskBytes: 999, SeriesN: 9},
+		{Name: "db1", DiskBytes: 5000, SeriesN: 50},
+	}
+
+	if err := s.check(); err != nil {
+		t.Fatal(err)
+	} else if len(s.read) != 1 || s.read[0] != "db0" {
+		t.Fatalf("unexpected databases read: %v", s.read)
+	} else if err := s.CheckWrite("db0", 1); err != nil {
+		t.Fatalf("unexpected error under quota: %s", err)
+	} else if err := s.CheckWrite("db1", 1); err != nil {
+		t.Fatalf("unexpected error without quota: %s", err)
+	}
+
+	// The series quota is reached.
+	s.storage[0].SeriesN = 10
+	if err := s.check(); err != nil {
+		t.Fatal(err)
+	}
+	exp := influxdb.QuotaError{Database: "db0", Quota: influxdb.QuotaSeries, Limit: 10}
+	if err := s.CheckWrite("db0", 1); err != exp {
+		t.Fatalf("unexpected error: got %v, exp %v", err, exp)
+	}
+
+	// The disk quota is reached.
+	s.storage[0].DiskBytes = 2000
+	if err := s.check(); err != nil {
+		t.Fatal(err)
+	}
+	exp = influxdb.QuotaError{Database: "db0", Quota: influxdb.QuotaDisk, Limit: 1000}
+	if err := s.CheckWrite("db0", 1); err != exp {
+		t.Fatalf("unexpected error: got %v, exp %v", err, exp)
+	}
+
+	// Removing the quotas allows writes again.
+	delete(s.quotas, "db0")
+	if err := s.CheckWrite("db0", 1); err != nil {
+		t.Fatalf("unexpected error after removing quota: %s", err)
+	}
+
+	stats := s.Statistics(nil)
+	if len(stats) != 1 {
+		t.Fatalf("unexpected statistics: %v", stats)
+	} else if v := stats[0].Values; v[statDiskRejected] != int64(1) || v[statSeriesRejected] != int64(1) || v[statDiskBytes] != int64(2000) {
+		t.Fatalf("unexpected statistic values: %v", v)
+	}
+}
+
+func TestService_CheckWrite_Rate(t *testing.T) {
+	s := NewTestService()
+	s.quotas["db0"] = meta.QuotaInfo{WritesPerSecond: 100}
+
+	now := time.Unix(0, 0)
+	s.now = func() time.Time { return now }
+
+	// A write larger than a second's worth is allowed, but exhausts the quota
+	// until it has been paid back.
+	if err := s.CheckWrite("db0", 150); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	exp := influxdb.QuotaError{Database: "db0", Quota: influxdb.QuotaWrites, Limit: 100}
+	if err := s.CheckWrite("db0", 1); err != exp {
+		t.Fatalf("unexpected error: got %v, exp %v", err, exp)
+	}
+
+	now = now.Add(time.Second)
+	if err := s.CheckWrite("db0", 1); err != nil {
+		t.Fatalf("unexpected error after refill: %s", err)
+	}
+
+	// The quota refills to at most a second's worth.
+	now = now.Add(time.Hour)
+	if err := s.CheckWrite("db0", 100); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := s.CheckWrite("db0", 1); err != exp {
+		t.Fatalf("unexpected error: got %v, exp %v", err, exp)
+	}
+}
+
+// TestService is a Service with in-memory quotas and storage.
+type TestService struct {
+	*Service
+	quotas  map[string]meta.QuotaInfo
+	storage []tsdb.DatabaseStorage
+	read    []string // databases whose storage was read
+}
+
+// NewTestService returns a new TestService.
+func NewTestService() *TestService {
+	s := &TestService{
+		Service: NewService(NewConfig()),
+		quotas:  make(map[string]meta.QuotaInfo),
+	}
+	s.Service.MetaClient = s
+	s.Service.TSDBStore = s
+	return s
+}
+
+// Database returns every database, with its quotas if it has any.
+func (s *TestService) Database(name string) *meta.DatabaseInfo {
+	return &meta.DatabaseInfo{Name: name, Quota: s.quotas[name]}
+}
+
+// Databases returns the databases of the storage, and the databases with
+// quotas.
+func (s *TestService) Databases() []meta.DatabaseInfo {
+	var a []meta.DatabaseInfo
+	for _, st := range s.storage {
+		if _, ok := s.quotas[st.Name]; !ok {
+			a = append(a, meta.DatabaseInfo{Name: st.Name})
+		}
+	}
+	for name, q := range s.quotas {
+		a = append(a, meta.DatabaseInfo{Name: name, Quota: q})
+	}
+	return a
+}
+
+// DatabaseStorage returns the storage of a database, and records that it
+// was read.
+func (s *TestService) DatabaseStorage(name string) (tsdb.DatabaseStorage, error) {
+	s.read = append(s.read, name)
+	for _, st := range s.storage {
+		if st.Name == name {
+			return st, nil
+		}
+	}
+	return tsdb.DatabaseStorage{Name: name}, nil
+}
@@ -0,0 +1,137 @@
+package replication
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/influxdata/influxdb/tcp"
+)
+
+// MuxHeader is the header byte used for the TCP muxer.
+const MuxHeader = 4
+
+// RequestType indicates the type of replication request.
+type RequestType uint8
+
+const (
+	// RequestStatus requests the archived segments available on a primary.
+	RequestStatus RequestType = iota
+
+	// RequestSegment requests the contents of a single archived segment.
+	RequestSegment
+
+	// RequestAck acknowledges that a follower has applied all segments of a
+	// shard up to and including SegmentID.
+	RequestAck
+
+	// RequestPromote asks a follower to stop replicating and accept writes.
+	RequestPromote
+)
+
+// Request represents a request sent to the replication service.
+type Request struct {
+	Type      RequestType
+	ShardID   uint64
+	SegmentID int
+
+	// Backfill is set for requests for the segments of the backfill WAL of a
+	// shard, which are numbered separately from those of its WAL.
+	Backfill bool `json:",omitempty"`
+}
+
+// Response represents the response to a status, ack or promote request.
+type Response struct {
+	Err string `json:",omitempty"`
+
+	// Segments holds the IDs of the archived segments, keyed by shard ID.
+	Segments map[uint64][]int `json:",omitempty"`
+
+	// BackfillSegments holds the IDs of the archived segments of the
+	// backfill WALs, keyed by shard ID.
+	BackfillSegments map[uint64][]int `json:",omitempty"`
+}
+
+// Client provides an API for the replication service.
+type Client struct {
+	host string
+}
+
+// NewClient returns a new *Client.
+func NewClient(host string) *Client {
+	return &Client{host: host}
+}
+
+// Status returns the archived segment IDs available on the primary, and those
+// of the backfill WALs, keyed by shard ID.
+func (c *Client) Status() (segments, backfill map[uint64][]int, err error) {
+	resp, err := c.do(&Request{Type: RequestStatus})
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Segments, resp.BackfillSegments, nil
+}
+
+// Segment copies the contents of an archived segment from the primary to w.
+// If backfill is set, the segment is one of the backfill WAL.
+func (c *Client) Segment(shardID uint64, segmentID int, backfill bool, w io.Writer) error {
+	conn, err := c.dial(&Request{Type: RequestSegment, ShardID: shardID, SegmentID: segmentID, Backfill: backfill})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if n, err := io.Copy(w, conn); err != nil {
+		return fmt.Errorf("copy segment: %s", err)
+	} else if n == 0 {
+		return fmt.Errorf("segment %d not found for shard %d", segmentID, shardID)
+	}
+	return nil
+}
+
+// Ack acknowledges all segments of a shard up to and including segmentID,
+// allowing the primary to remove them.  If backfill is set, the segments are
+// those of the backfill WAL.
+func (c *Client) Ack(shardID uint64, segmentID int, backfill bool) error {
+	_, err := c.do(&Request{Type: RequestAck, ShardID: shardID, SegmentID: segmentID, Backfill: backfill})
+	return err
+}
+
+// Promote asks a follower to stop replicating and start accepting writes.
+func (c *Client) Promote() error {
+	_, err := c.do(&Request{Type: RequestPromote})
+	return err
+}
+
+// do sends a request to the replication service and decodes the response.
+func (c *Client) do(req *Request) (*Response, error) {
+	conn, err := c.dial(req)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("decode response: %s", err)
+	} else if resp.Err != "" {
+		return nil, errors.New(resp.Err)
+	}
+	return &resp, nil
+}
+
+// dial connects to the replication service and writes the request.
+func (c *Client) dial(req *Request) (net.Conn, error) {
+	conn, err := tcp.Dial("tcp", c.host, MuxHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("encode replication request: %s", err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,97 @@
+package replication
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	// ModePrimary archives WAL segments and serves them to followers.
+	ModePrimary = "primary"
+
+	// ModeFollower replicates a primary and rejects local writes.
+	ModeFollower = "follower"
+
+	// DefaultPollInterval is the default interval at which a follower polls
+	// the primary for new segments.
+	DefaultPollInterval = 10 * time.Second
+
+	// DefaultArchiveRetention is the default amount of time a primary retains
+	// archived segments that no follower has acknowledged.
+	DefaultArchiveRetention = 24 * time.Hour
+)
+
+// Config represents the configuration for the replication service.
+type Config struct {
+	// Mode is the replication role of this node, either "primary" or
+	// "follower". An empty mode disables replication.
+	Mode string `toml:"mode"`
+
+	// PrimaryAddress is the bind-address of the primary. Only used by followers.
+	PrimaryAddress string `toml:"primary-address"`
+
+	// PollInterval is how often a follower polls the primary.
+	PollInterval toml.Duration `toml:"poll-interval"`
+
+	// ArchiveRetention is how long a primary retains unacknowledged segments.
+	ArchiveRetention toml.Duration `toml:"archive-retention"`
+}
+
+// NewConfig returns a new Config with defaults.
+func NewConfig() Config {
+	return Config{
+		PollInterval:     toml.Duration(DefaultPollInterval),
+		ArchiveRetention: toml.Duration(DefaultArchiveRetention),
+	}
+}
+
+// Enabled returns true if replication is enabled.
+func (c Config) Enabled() bool {
+	return c.Mode != ""
+}
+
+// Validate returns an error if the Config is invalid.
+func (c Config) Validate() error {
+	switch c.Mode {
+	case "":
+		return nil
+	case ModePrimary:
+		if c.ArchiveRetention <= 0 {
+			return errors.New("archive-retention must be positive")
+		}
+	case ModeFollower:
+		if c.PrimaryAddress == "" {
+			return errors.New("primary-address is required in follower mode")
+		}
+		if c.PollInterval <= 0 {
+			return errors.New("poll-interval must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown replication mode: %q", c.Mode)
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	switch c.Mode {
+	case ModePrimary:
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"mode":              c.Mode,
+			"archive-retention": c.ArchiveRetention,
+		}), nil
+	case ModeFollower:
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"mode":            c.Mode,
+			"primary-address": c.PrimaryAddress,
+			"poll-interval":   c.PollInterval,
+		}), nil
+	}
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"mode": "disabled",
+	}), nil
+}
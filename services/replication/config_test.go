@@ -0,0 +1,66 @@
+package replication_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/replication"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	var c replication.Config
+	if _, err := toml.Decode(`
+mode = "follower"
+primary-address = "primary:8088"
+poll-interval = "5s"
+archive-retention = "1h"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if c.Mode != replication.ModeFollower {
+		t.Fatalf("unexpected mode: %s", c.Mode)
+	} else if c.PrimaryAddress != "primary:8088" {
+		t.Fatalf("unexpected primary address: %s", c.PrimaryAddress)
+	} else if time.Duration(c.PollInterval) != 5*time.Second {
+		t.Fatalf("unexpected poll interval: %s", c.PollInterval)
+	} else if time.Duration(c.ArchiveRetention) != time.Hour {
+		t.Fatalf("unexpected archive retention: %s", c.ArchiveRetention)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := replication.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail from NewConfig: %s", err)
+	} else if c.Enabled() {
+		t.Fatal("expected replication to be disabled by default")
+	}
+
+	c = replication.NewConfig()
+	c.Mode = "standby"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for unknown mode, got nil")
+	}
+
+	c = replication.NewConfig()
+	c.Mode = replication.ModeFollower
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for follower without primary-address, got nil")
+	}
+
+	c.PrimaryAddress = "primary:8088"
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail: %s", err)
+	}
+
+	c = replication.NewConfig()
+	c.Mode = replication.ModePrimary
+	c.ArchiveRetention = 0
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for archive-retention = 0, got nil")
+	}
+}
@@ -0,0 +1,750 @@
+// Package replication provides warm-standby replication. A primary retains
+// closed WAL segments in an archive and serves them, along with shard
+// snapshots for bootstrapping, to a read-only follower which replays them.
+package replication // import "github.com/influxdata/influxdb/services/replication"
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/bytesutil"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/services/snapshotter"
+	"github.com/influxdata/influxdb/tcp"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+	"github.com/uber-go/zap"
+)
+
+const (
+	// stateFile is the name of the file, within Dir, recording the last
+	// segment applied to each shard by a follower.
+	stateFile = "replication.json"
+
+	// backfillStateFile is the name of the file, within Dir, recording the
+	// last backfill WAL segment applied to each shard by a follower.
+	backfillStateFile = "replication-backfill.json"
+
+	// promotedFile is the name of the marker file, within Dir, written when a
+	// follower is promoted.  A promoted follower no longer replicates.
+	promotedFile = "replication.promoted"
+)
+
+// Statistics for the replication service.
+const (
+	statSegmentsServed     = "segmentsServed"
+	statSegmentsApplied    = "segmentsApplied"
+	statPointsApplied      = "pointsApplied"
+	statShardsBootstrapped = "shardsBootstrapped"
+	statSyncErrors         = "syncErrors"
+)
+
+// ErrNotFollower is returned when a promote request is sent to a node that
+// is not a replicating follower.
+var ErrNotFollower = errors.New("node is not a replication follower")
+
+// Service ships archived WAL segments from a primary to a follower.
+type Service struct {
+	config Config
+
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	closing  chan struct{}
+	stopSync chan struct{}
+
+	// Dir is where a follower stores its replication state.
+	Dir string
+
+	Listener net.Listener
+	Logger   zap.Logger
+
+	MetaClient interface {
+		SetData(data *meta.Data) error
+	}
+
+	TSDBStore interface {
+		Shard(id uint64) *tsdb.Shard
+		ShardIDs() []uint64
+		CreateShard(database, retentionPolicy string, shardID uint64, enabled bool) error
+		DeleteShard(id uint64) error
+		RestoreShard(id uint64, r io.Reader) error
+		WriteToShard(shardID uint64, points []models.Point) error
+	}
+
+	PointsWriter interface {
+		SetReadOnly(readOnly bool)
+	}
+
+	// applied holds the last segment applied to each shard by a follower,
+	// and appliedBackfill the last segment of its backfill WAL.
+	applied         map[uint64]int
+	appliedBackfill map[uint64]int
+
+	stats *Statistics
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		config:          c,
+		Logger:          zap.New(zap.NullEncoder()),
+		applied:         make(map[uint64]int),
+		appliedBackfill: make(map[uint64]int),
+		stats:           &Statistics{},
+	}
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log zap.Logger) {
+	s.Logger = log.With(zap.String("service", "replication"))
+}
+
+// Open starts the service.
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closing != nil {
+		return nil
+	}
+
+	s.Logger.Info(fmt.Sprintf("Starting replication service in %s mode", s.config.Mode))
+	s.closing = make(chan struct{})
+
+	if s.Listener != nil {
+		s.wg.Add(1)
+		go s.serve()
+	}
+
+	switch s.config.Mode {
+	case ModePrimary:
+		s.wg.Add(1)
+		go s.runPrune()
+	case ModeFollower:
+		if _, err := os.Stat(filepath.Join(s.Dir, promotedFile)); err == nil {
+			s.Logger.Info("Follower has been promoted, not replicating")
+			return nil
+		}
+
+		if err := s.loadState(); err != nil {
+			return err
+		}
+
+		s.PointsWriter.SetReadOnly(true)
+		s.stopSync = make(chan struct{})
+		s.wg.Add(1)
+		go s.runSync(s.stopSync)
+	}
+	return nil
+}
+
+// Close stops the service.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	if s.closing == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	close(s.closing)
+	s.mu.Unlock()
+
+	if s.Listener != nil {
+		s.Listener.Close()
+	}
+	s.wg.Wait()
+
+	s.mu.Lock()
+	s.closing = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// Statistics maintains statistics for the replication service.
+type Statistics struct {
+	SegmentsServed     int64
+	SegmentsApplied    int64
+	PointsApplied      int64
+	ShardsBootstrapped int64
+	SyncErrors         int64
+}
+
+// Statistics returns statistics for periodic monitoring.
+func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	return []models.Statistic{{
+		Name: "replication",
+		Tags: models.StatisticTags{"mode": s.config.Mode}.Merge(tags),
+		Values: map[string]interface{}{
+			statSegmentsServed:     atomic.LoadInt64(&s.stats.SegmentsServed),
+			statSegmentsApplied:    atomic.LoadInt64(&s.stats.SegmentsApplied),
+			statPointsApplied:      atomic.LoadInt64(&s.stats.PointsApplied),
+			statShardsBootstrapped: atomic.LoadInt64(&s.stats.ShardsBootstrapped),
+			statSyncErrors:         atomic.LoadInt64(&s.stats.SyncErrors),
+		},
+	}}
+}
+
+// serve serves replication requests from the listener.
+func (s *Service) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.Listener.Accept()
+		if err != nil && strings.Contains(err.Error(), "connection closed") {
+			s.Logger.Info("replication listener closed")
+			return
+		} else if err != nil {
+			s.Logger.Info(fmt.Sprint("error accepting replication request: ", err.Error()))
+			continue
+		}
+
+		s.wg.Add(1)
+		go func(conn net.Conn) {
+			defer s.wg.Done()
+			defer conn.Close()
+			if err := s.handleConn(conn); err != nil {
+				s.Logger.Info(err.Error())
+			}
+		}(conn)
+	}
+}
+
+// handleConn processes conn. This is run in a separate goroutine.
+func (s *Service) handleConn(conn net.Conn) error {
+	var r Request
+	if err := json.NewDecoder(conn).Decode(&r); err != nil {
+		return fmt.Errorf("read request: %s", err)
+	}
+
+	var resp Response
+	switch r.Type {
+	case RequestSegment:
+		if s.config.Mode != ModePrimary {
+			return errors.New("segment requested from a node that is not a primary")
+		}
+		return s.writeSegment(conn, r.ShardID, r.SegmentID, r.Backfill)
+	case RequestStatus:
+		if s.config.Mode != ModePrimary {
+			resp.Err = "node is not a replication primary"
+			break
+		}
+		segments, err := s.archivedSegments(false)
+		if err != nil {
+			resp.Err = err.Error()
+			break
+		}
+		backfill, err := s.archivedSegments(true)
+		if err != nil {
+			resp.Err = err.Error()
+			break
+		}
+		resp.Segments, resp.BackfillSegments = segments, backfill
+	case RequestAck:
+		if err := s.ack(r.ShardID, r.SegmentID, r.Backfill); err != nil {
+			resp.Err = err.Error()
+		}
+	case RequestPromote:
+		if err := s.Promote(); err != nil {
+			resp.Err = err.Error()
+		}
+	default:
+		return fmt.Errorf("request type unknown: %v", r.Type)
+	}
+
+	return json.NewEncoder(conn).Encode(resp)
+}
+
+// walPath returns the WAL directory of a shard, or that of its backfill WAL.
+func walPath(sh *tsdb.Shard, backfill bool) string {
+	if backfill {
+		return filepath.Join(sh.WALPath(), tsm1.BackfillWALDirName)
+	}
+	return sh.WALPath()
+}
+
+// archivedSegments returns the IDs of all archived segments, or those of the
+// backfill WALs, keyed by shard ID.
+func (s *Service) archivedSegments(backfill bool) (map[uint64][]int, error) {
+	m := make(map[uint64][]int)
+	for _, id := range s.TSDBStore.ShardIDs() {
+		sh := s.TSDBStore.Shard(id)
+		if sh == nil {
+			continue
+		}
+
+		files, err := tsm1.ArchivedSegments(walPath(sh, backfill))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, fn := range files {
+			segmentID, err := tsm1.SegmentID(fn)
+			if err != nil {
+				return nil, err
+			}
+			m[id] = append(m[id], segmentID)
+		}
+	}
+	return m, nil
+}
+
+// writeSegment copies an archived segment, or one of the backfill WAL, to w.
+func (s *Service) writeSegment(w io.Writer, shardID uint64, segmentID int, backfill bool) error {
+	sh := s.TSDBStore.Shard(shardID)
+	if sh == nil {
+		return fmt.Errorf("shard %d doesn't exist on this server", shardID)
+	}
+
+	files, err := tsm1.ArchivedSegments(walPath(sh, backfill))
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range files {
+		if id, err := tsm1.SegmentID(fn); err != nil {
+			return err
+		} else if id != segmentID {
+			continue
+		}
+
+		f, err := os.Open(fn)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if _, err := io.Copy(w, f); err != nil {
+			return err
+		}
+		atomic.AddInt64(&s.stats.SegmentsServed, 1)
+		return nil
+	}
+	return fmt.Errorf("segment %d not found for shard %d", segmentID, shardID)
+}
+
+// ack removes the archived segments of a shard, or of its backfill WAL, up to
+// and including segmentID.
+func (s *Service) ack(shardID uint64, segmentID int, backfill bool) error {
+	if s.config.Mode != ModePrimary {
+		return errors.New("node is not a replication primary")
+	}
+
+	sh := s.TSDBStore.Shard(shardID)
+	if sh == nil {
+		return nil
+	}
+	return tsm1.RemoveArchivedSegments(walPath(sh, backfill), segmentID)
+}
+
+// runPrune periodically removes archived segments older than the archive retention.
+func (s *Service) runPrune() {
+	defer s.wg.Done()
+
+	retention := time.Duration(s.config.ArchiveRetention)
+	ticker := time.NewTicker(retention / 24)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+			if err := s.prune(time.Now().Add(-retention)); err != nil {
+				s.Logger.Info(fmt.Sprintf("failed to prune archived segments: %s", err))
+			}
+		}
+	}
+}
+
+// prune removes archived segments, including those of the backfill WALs,
+// last modified before cutoff.
+func (s *Service) prune(cutoff time.Time) error {
+	for _, id := range s.TSDBStore.ShardIDs() {
+		sh := s.TSDBStore.Shard(id)
+		if sh == nil {
+			continue
+		}
+
+		for _, backfill := range []bool{false, true} {
+			if err := s.pruneDir(walPath(sh, backfill), cutoff); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pruneDir removes the archived segments of the WAL in dir last modified
+// before cutoff.
+func (s *Service) pruneDir(dir string, cutoff time.Time) error {
+	files, err := tsm1.ArchivedSegments(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range files {
+		fi, err := os.Stat(fn)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		} else if fi.ModTime().After(cutoff) {
+			break
+		}
+
+		s.Logger.Info(fmt.Sprintf("Removing unacknowledged archived segment %s", fn))
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Promote stops replication and allows the follower to accept writes.  The
+// promotion is recorded so the node does not resume replicating on restart.
+func (s *Service) Promote() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config.Mode != ModeFollower || s.stopSync == nil {
+		return ErrNotFollower
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(s.Dir, promotedFile), []byte(time.Now().UTC().Format(time.RFC3339)), 0644); err != nil {
+		return err
+	}
+
+	close(s.stopSync)
+	s.stopSync = nil
+	s.PointsWriter.SetReadOnly(false)
+
+	s.Logger.Info("Follower promoted, now accepting writes")
+	return nil
+}
+
+// runSync periodically replicates the primary until stopped.
+func (s *Service) runSync(stop chan struct{}) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(s.config.PollInterval))
+	defer ticker.Stop()
+
+	for {
+		if err := s.sync(stop); err != nil {
+			atomic.AddInt64(&s.stats.SyncErrors, 1)
+			s.Logger.Info(fmt.Sprintf("replication from %s failed: %s", s.config.PrimaryAddress, err))
+		}
+
+		select {
+		case <-s.closing:
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync performs a single replication pass: the meta store is copied from the
+// primary, new shards are bootstrapped from snapshots, dropped shards are
+// removed and any unapplied archived segments are replayed.
+func (s *Service) sync(stop chan struct{}) error {
+	data, err := snapshotter.NewClient(s.config.PrimaryAddress).MetastoreBackup()
+	if err != nil {
+		return fmt.Errorf("metastore backup: %s", err)
+	}
+
+	if err := s.MetaClient.SetData(data); err != nil {
+		return fmt.Errorf("set meta data: %s", err)
+	}
+
+	// Determine which shards the primary owns.
+	owners := make(map[uint64]shardOwner)
+	for _, db := range data.Databases {
+		for _, rp := range db.RetentionPolicies {
+			for _, sg := range rp.ShardGroups {
+				if sg.Deleted() {
+					continue
+				}
+				for _, sh := range sg.Shards {
+					owners[sh.ID] = shardOwner{database: db.Name, retentionPolicy: rp.Name}
+				}
+			}
+		}
+	}
+
+	// Remove shards that no longer exist on the primary.
+	for _, id := range s.TSDBStore.ShardIDs() {
+		if _, ok := owners[id]; ok {
+			continue
+		}
+		if err := s.TSDBStore.DeleteShard(id); err != nil {
+			return err
+		}
+		delete(s.applied, id)
+		delete(s.appliedBackfill, id)
+	}
+
+	status, backfill, err := NewClient(s.config.PrimaryAddress).Status()
+	if err != nil {
+		return fmt.Errorf("status: %s", err)
+	}
+
+	ids := make([]uint64, 0, len(owners))
+	for id := range owners {
+		ids = append(ids, id)
+	}
+	sort.Sort(uint64Slice(ids))
+
+	for _, id := range ids {
+		select {
+		case <-stop:
+			return nil
+		case <-s.closing:
+			return nil
+		default:
+		}
+
+		if s.TSDBStore.Shard(id) == nil {
+			if err := s.bootstrapShard(id, owners[id]); err != nil {
+				return fmt.Errorf("bootstrap shard %d: %s", id, err)
+			}
+		}
+
+		// Backfill WAL segments hold points written after those in the WAL
+		// segments archived at the same time, so they are applied last.
+		if err := s.applySegments(id, status[id], false); err != nil {
+			return fmt.Errorf("apply segments to shard %d: %s", id, err)
+		}
+		if err := s.applySegments(id, backfill[id], true); err != nil {
+			return fmt.Errorf("apply backfill segments to shard %d: %s", id, err)
+		}
+	}
+
+	return s.saveState()
+}
+
+// bootstrapShard creates a local shard and restores it from a snapshot of the
+// primary's shard.  The shard is deleted if it can't be restored, so it's
+// bootstrapped again by the next sync.
+func (s *Service) bootstrapShard(id uint64, owner shardOwner) error {
+	if err := s.TSDBStore.CreateShard(owner.database, owner.retentionPolicy, id, true); err != nil {
+		return err
+	}
+
+	if err := s.restoreShard(id, owner); err != nil {
+		if e := s.TSDBStore.DeleteShard(id); e != nil {
+			s.Logger.Info(fmt.Sprintf("Failed to delete shard %d: %s", id, e))
+		}
+		return err
+	}
+
+	atomic.AddInt64(&s.stats.ShardsBootstrapped, 1)
+	s.Logger.Info(fmt.Sprintf("Bootstrapped shard %d from %s", id, s.config.PrimaryAddress))
+	return nil
+}
+
+// restoreShard restores a local shard from a snapshot of the primary's shard.
+func (s *Service) restoreShard(id uint64, owner shardOwner) error {
+	conn, err := tcp.Dial("tcp", s.config.PrimaryAddress, snapshotter.MuxHeader)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	req := &snapshotter.Request{
+		Type:            snapshotter.RequestShardBackup,
+		Database:        owner.database,
+		RetentionPolicy: owner.retentionPolicy,
+		ShardID:         id,
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return fmt.Errorf("encode snapshot request: %s", err)
+	}
+	return s.TSDBStore.RestoreShard(id, conn)
+}
+
+// applySegments replays each archived segment, or backfill WAL segment, newer
+// than the last applied segment and acknowledges the segments once they have
+// been applied.
+func (s *Service) applySegments(shardID uint64, segments []int, backfill bool) error {
+	if len(segments) == 0 {
+		return nil
+	}
+
+	applied := s.applied
+	if backfill {
+		applied = s.appliedBackfill
+	}
+
+	client := NewClient(s.config.PrimaryAddress)
+	last := applied[shardID]
+	for _, segmentID := range segments {
+		if segmentID <= last {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := client.Segment(shardID, segmentID, backfill, &buf); err != nil {
+			return err
+		}
+
+		if err := s.applySegment(shardID, &buf); err != nil {
+			return fmt.Errorf("segment %d: %s", segmentID, err)
+		}
+
+		last = segmentID
+		applied[shardID] = last
+		atomic.AddInt64(&s.stats.SegmentsApplied, 1)
+	}
+
+	// Persist the applied position before acknowledging so a restart never
+	// loses segments the primary has already removed.
+	if err := s.saveState(); err != nil {
+		return err
+	}
+	return client.Ack(shardID, segments[len(segments)-1], backfill)
+}
+
+// applySegment replays the entries of a WAL segment into a local shard.
+func (s *Service) applySegment(shardID uint64, r io.Reader) error {
+	sr := tsm1.NewWALSegmentReader(ioutil.NopCloser(r))
+	defer sr.Close()
+
+	for sr.Next() {
+		entry, err := sr.Read()
+		if err != nil {
+			return err
+		}
+
+		switch t := entry.(type) {
+		case *tsm1.WriteWALEntry:
+			points, err := pointsFromValues(t.Values)
+			if err != nil {
+				return err
+			}
+
+			if err := s.TSDBStore.WriteToShard(shardID, points); err != nil {
+				if _, ok := err.(tsdb.PartialWriteError); !ok {
+					return err
+				}
+			}
+			atomic.AddInt64(&s.stats.PointsApplied, int64(len(points)))
+		case *tsm1.DeleteWALEntry:
+			if err := s.deleteSeriesRange(shardID, t.Keys, models.MinNanoTime, models.MaxNanoTime); err != nil {
+				return err
+			}
+		case *tsm1.DeleteRangeWALEntry:
+			if err := s.deleteSeriesRange(shardID, t.Keys, t.Min, t.Max); err != nil {
+				return err
+			}
+		}
+	}
+	return sr.Error()
+}
+
+// deleteSeriesRange deletes the series owning the WAL keys from a local shard.
+func (s *Service) deleteSeriesRange(shardID uint64, keys [][]byte, min, max int64) error {
+	sh := s.TSDBStore.Shard(shardID)
+	if sh == nil {
+		return nil
+	}
+
+	seen := make(map[string]struct{}, len(keys))
+	seriesKeys := make([][]byte, 0, len(keys))
+	for _, k := range keys {
+		seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(k)
+		if _, ok := seen[string(seriesKey)]; ok {
+			continue
+		}
+		seen[string(seriesKey)] = struct{}{}
+		seriesKeys = append(seriesKeys, seriesKey)
+	}
+	bytesutil.Sort(seriesKeys)
+
+	return sh.DeleteSeriesRange(seriesKeys, min, max)
+}
+
+// pointsFromValues converts WAL values, keyed by series and field, into points.
+func pointsFromValues(values map[string][]tsm1.Value) ([]models.Point, error) {
+	points := make([]models.Point, 0, len(values))
+	for key, vs := range values {
+		seriesKey, field := tsm1.SeriesAndFieldFromCompositeKey([]byte(key))
+		name, tags := models.ParseKey(seriesKey)
+
+		for _, v := range vs {
+			pt, err := models.NewPoint(name, tags, models.Fields{string(field): v.Value()}, time.Unix(0, v.UnixNano()))
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, pt)
+		}
+	}
+	return points, nil
+}
+
+// loadState reads the last applied segment, and backfill WAL segment, of
+// each shard from disk.
+func (s *Service) loadState() error {
+	if err := readState(filepath.Join(s.Dir, stateFile), &s.applied); err != nil {
+		return err
+	}
+	return readState(filepath.Join(s.Dir, backfillStateFile), &s.appliedBackfill)
+}
+
+// saveState writes the last applied segment, and backfill WAL segment, of
+// each shard to disk.
+func (s *Service) saveState() error {
+	if err := writeState(filepath.Join(s.Dir, stateFile), s.applied); err != nil {
+		return err
+	}
+	return writeState(filepath.Join(s.Dir, backfillStateFile), s.appliedBackfill)
+}
+
+// readState reads the applied segments at path into applied.
+func readState(path string, applied *map[uint64]int) error {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, applied)
+}
+
+// writeState writes applied to path.
+func writeState(path string, applied map[uint64]int) error {
+	b, err := json.Marshal(applied)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path+".tmp", b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// shardOwner identifies the database and retention policy of a shard.
+type shardOwner struct {
+	database        string
+	retentionPolicy string
+}
+
+type uint64Slice []uint64
+
+func (a uint64Slice) Len() int           { return len(a) }
+func (a uint64Slice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a uint64Slice) Less(i, j int) bool { return a[i] < a[j] }
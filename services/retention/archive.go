@@ -0,0 +1,175 @@
+package retention
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/influxdb/pkg/s3"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+const (
+	// shardArchivePattern names shard archives the same way as
+	// influxd backup so they can be restored with influxd restore.
+	shardArchivePattern = "%s.%s.%05d.00"
+
+	// manifestPattern names the manifest of an archived shard group.
+	manifestPattern = "manifests/%s.%s.%05d.json"
+)
+
+// ArchiveManifest records the shards of an archived shard group.
+type ArchiveManifest struct {
+	Database        string          `json:"database"`
+	RetentionPolicy string          `json:"retention_policy"`
+	ShardGroupID    uint64          `json:"shard_group"`
+	StartTime       time.Time       `json:"start_time"`
+	EndTime         time.Time       `json:"end_time"`
+	ArchivedAt      time.Time       `json:"archived_at"`
+	Shards          []ArchivedShard `json:"shards"`
+}
+
+// ArchivedShard records a shard archive.
+type ArchivedShard struct {
+	ID   uint64 `json:"id"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// archiver stores shard archives.
+type archiver interface {
+	// Put stores the data written by fn under name. Nothing is stored if
+	// fn returns an error.
+	Put(name string, fn func(w io.Writer) error) error
+}
+
+// newArchiver returns the archiver for the archive path of the config.
+func newArchiver(c Config) (archiver, error) {
+	if !s3.IsURL(c.ArchivePath) {
+		return &dirArchiver{path: c.ArchivePath}, nil
+	}
+
+	client, prefix, err := s3.ParseURL(c.ArchivePath, c.ArchiveS3Region, c.ArchiveS3Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Archiver{client: client, prefix: prefix}, nil
+}
+
+// archive stores the local shards of a shard group and a manifest of them.
+// It returns the name of the manifest.
+func (s *Service) archive(database string, rp *meta.RetentionPolicyInfo, g *meta.ShardGroupInfo) (string, error) {
+	m := ArchiveManifest{
+		Database:        database,
+		RetentionPolicy: rp.Name,
+		ShardGroupID:    g.ID,
+		StartTime:       g.StartTime,
+		EndTime:         g.EndTime,
+		ArchivedAt:      time.Now().UTC(),
+		Shards:          []ArchivedShard{},
+	}
+
+	for _, sh := range g.Shards {
+		// Shards that are not stored locally are archived by their owners.
+		if s.TSDBStore.Shard(sh.ID) == nil {
+			continue
+		}
+
+		name := fmt.Sprintf(shardArchivePattern, database, rp.Name, sh.ID)
+		var size int64
+		if err := s.archiver.Put(name, func(w io.Writer) error {
+			cw := &countingWriter{w: w}
+			if err := s.TSDBStore.BackupShard(sh.ID, time.Time{}, cw); err != nil {
+				return err
+			}
+			size = cw.n
+			return nil
+		}); err != nil {
+			return "", fmt.Errorf("archive shard %d: %s", sh.ID, err)
+		}
+		m.Shards = append(m.Shards, ArchivedShard{ID: sh.ID, Path: name, Size: size})
+	}
+
+	// The manifest is written last so it only lists complete archives.
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf(manifestPattern, database, rp.Name, g.ID)
+	if err := s.archiver.Put(name, func(w io.Writer) error {
+		_, err := w.Write(buf)
+		return err
+	}); err != nil {
+		return "", fmt.Errorf("archive manifest: %s", err)
+	}
+	return name, nil
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// dirArchiver stores archives in a local directory.
+type dirArchiver struct {
+	path string
+}
+
+// Put writes the archive to a temporary file and renames it once complete.
+func (a *dirArchiver) Put(name string, fn func(w io.Writer) error) error {
+	p := filepath.Join(a.path, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(p + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if err := fn(f); err != nil {
+		return err
+	} else if err := f.Sync(); err != nil {
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+// s3Archiver stores archives in an S3 or Google Cloud Storage bucket.
+type s3Archiver struct {
+	client *s3.Client
+	prefix string
+}
+
+// Put buffers the archive to a temporary file and uploads it.
+func (a *s3Archiver) Put(name string, fn func(w io.Writer) error) error {
+	f, err := ioutil.TempFile("", "influxdb-archive-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if err := fn(f); err != nil {
+		return err
+	} else if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return a.client.Put(path.Join(a.prefix, name), f)
+}
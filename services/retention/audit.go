@@ -0,0 +1,58 @@
+package retention
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Deletion describes the deletion of a shard group, or of the data of a
+// measurement, by retention policy enforcement.
+type Deletion struct {
+	Time            time.Time `json:"time"`
+	Database        string    `json:"database"`
+	RetentionPolicy string    `json:"retention_policy"`
+	ShardGroupID    uint64    `json:"shard_group,omitempty"`
+	Measurement     string    `json:"measurement,omitempty"`
+	ShardIDs        []uint64  `json:"shards"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+
+	// Size is the size on disk, in bytes, of the shards stored locally.
+	Size int64 `json:"size"`
+
+	// Archive is the manifest of the archived shards, if they were archived.
+	Archive string `json:"archive,omitempty"`
+}
+
+// auditLog appends deletions to a file as JSON, one per line.
+type auditLog struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// openAuditLog opens the audit log at path, creating it if needed.
+func openAuditLog(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Record appends a deletion to the audit log.
+func (l *auditLog) Record(d Deletion) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(d); err != nil {
+		return err
+	}
+	return l.f.Sync()
+}
+
+// Close closes the audit log.
+func (l *auditLog) Close() error {
+	return l.f.Close()
+}
@@ -2,9 +2,12 @@ package retention
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/pkg/s3"
 	"github.com/influxdata/influxdb/toml"
 )
 
@@ -12,11 +15,31 @@ import (
 type Config struct {
 	Enabled       bool          `toml:"enabled"`
 	CheckInterval toml.Duration `toml:"check-interval"`
+
+	// DryRun logs the shard groups that would be deleted instead of
+	// deleting them.
+	DryRun bool `toml:"dry-run"`
+
+	// AuditLogPath is the file every deletion is appended to. Deletions
+	// are not audited if it is empty.
+	AuditLogPath string `toml:"audit-log-path"`
+
+	// ArchivePath is the directory, or s3://bucket/prefix or
+	// gs://bucket/prefix location, that expired shards are archived to
+	// before they are deleted. Expired shards are not archived if it is
+	// empty.
+	ArchivePath       string `toml:"archive-path"`
+	ArchiveS3Region   string `toml:"archive-s3-region"`
+	ArchiveS3Endpoint string `toml:"archive-s3-endpoint"`
 }
 
 // NewConfig returns an instance of Config with defaults.
 func NewConfig() Config {
-	return Config{Enabled: true, CheckInterval: toml.Duration(30 * time.Minute)}
+	return Config{
+		Enabled:         true,
+		CheckInterval:   toml.Duration(30 * time.Minute),
+		ArchiveS3Region: s3.DefaultRegion,
+	}
 }
 
 // Validate returns an error if the Config is invalid.
@@ -31,6 +54,15 @@ func (c Config) Validate() error {
 		return errors.New("check-interval must be positive")
 	}
 
+	if s3.IsURL(c.ArchivePath) {
+		if _, _, err := s3.ParseBucket(c.ArchivePath); err != nil {
+			return fmt.Errorf("archive-path: %s", err)
+		}
+		if strings.HasPrefix(c.ArchivePath, "s3://") && c.ArchiveS3Region == "" {
+			return errors.New("archive-s3-region must be set to archive to S3")
+		}
+	}
+
 	return nil
 }
 
@@ -45,5 +77,8 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 	return diagnostics.RowFromMap(map[string]interface{}{
 		"enabled":        true,
 		"check-interval": c.CheckInterval,
+		"dry-run":        c.DryRun,
+		"audit-log-path": c.AuditLogPath,
+		"archive-path":   c.ArchivePath,
 	}), nil
 }
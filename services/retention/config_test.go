@@ -14,6 +14,8 @@ func TestConfig_Parse(t *testing.T) {
 	if _, err := toml.Decode(`
 enabled = true
 check-interval = "1s"
+dry-run = true
+audit-log-path = "/var/log/influxdb/retention.log"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -23,6 +25,10 @@ check-interval = "1s"
 		t.Fatalf("unexpected enabled state: %v", c.Enabled)
 	} else if time.Duration(c.CheckInterval) != time.Second {
 		t.Fatalf("unexpected check interval: %v", c.CheckInterval)
+	} else if c.DryRun != true {
+		t.Fatalf("unexpected dry run: %v", c.DryRun)
+	} else if c.AuditLogPath != "/var/log/influxdb/retention.log" {
+		t.Fatalf("unexpected audit log path: %s", c.AuditLogPath)
 	}
 }
 
@@ -43,4 +49,17 @@ func TestConfig_Validate(t *testing.T) {
 	if err := c.Validate(); err == nil {
 		t.Fatal("expected error for negative check-interval, got nil")
 	}
+
+	c = retention.NewConfig()
+	c.ArchivePath = "s3:///archive"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for archive-path without bucket, got nil")
+	}
+
+	c = retention.NewConfig()
+	c.ArchivePath = "s3://bucket/archive"
+	c.ArchiveS3Region = ""
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for archive-s3-region = \"\", got nil")
+	}
 }
@@ -2,11 +2,16 @@
 package retention // import "github.com/influxdata/influxdb/services/retention"
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
 	"github.com/uber-go/zap"
 )
 
@@ -19,12 +24,26 @@ type Service struct {
 	}
 	TSDBStore interface {
 		ShardIDs() []uint64
+		Shard(id uint64) *tsdb.Shard
+		BackupShard(id uint64, since time.Time, w io.Writer) error
 		DeleteShard(shardID uint64) error
+		DeleteMeasurementRange(shardIDs []uint64, name string, min, max int64) error
 	}
 
+	// QueryExecutor runs the rollups of retention policies.
+	QueryExecutor *query.QueryExecutor
+
 	config Config
 	wg     sync.WaitGroup
 	done   chan struct{}
+	audit  *auditLog
+
+	archiver archiver
+
+	mu            sync.Mutex
+	nextCheck     time.Time
+	checkInterval time.Duration
+	reset         chan struct{}
 
 	logger zap.Logger
 }
@@ -32,8 +51,10 @@ type Service struct {
 // NewService returns a configured retention policy enforcement service.
 func NewService(c Config) *Service {
 	return &Service{
-		config: c,
-		logger: zap.New(zap.NullEncoder()),
+		config:        c,
+		checkInterval: time.Duration(c.CheckInterval),
+		reset:         make(chan struct{}, 1),
+		logger:        zap.New(zap.NullEncoder()),
 	}
 }
 
@@ -44,6 +65,30 @@ func (s *Service) Open() error {
 	}
 
 	s.logger.Info(fmt.Sprint("Starting retention policy enforcement service with check interval of ", s.config.CheckInterval))
+	if s.config.DryRun {
+		s.logger.Info("Retention policy enforcement is in dry run mode. No data will be deleted.")
+	}
+
+	if s.config.ArchivePath != "" {
+		a, err := newArchiver(s.config)
+		if err != nil {
+			return err
+		}
+		s.archiver = a
+	}
+
+	if s.config.AuditLogPath != "" {
+		audit, err := openAuditLog(s.config.AuditLogPath)
+		if err != nil {
+			return err
+		}
+		s.audit = audit
+	}
+
+	s.mu.Lock()
+	s.nextCheck = time.Now().Add(s.checkInterval)
+	s.mu.Unlock()
+
 	s.done = make(chan struct{})
 
 	s.wg.Add(1)
@@ -62,6 +107,13 @@ func (s *Service) Close() error {
 
 	s.wg.Wait()
 	s.done = nil
+
+	if s.audit != nil {
+		if err := s.audit.Close(); err != nil {
+			return err
+		}
+		s.audit = nil
+	}
 	return nil
 }
 
@@ -70,15 +122,44 @@ func (s *Service) WithLogger(log zap.Logger) {
 	s.logger = log.With(zap.String("service", "retention"))
 }
 
+// SetCheckInterval changes how often retention policies are enforced.  The
+// next check happens d after the change.
+func (s *Service) SetCheckInterval(d time.Duration) {
+	s.mu.Lock()
+	s.checkInterval = d
+	s.nextCheck = time.Now().Add(d)
+	s.mu.Unlock()
+
+	select {
+	case s.reset <- struct{}{}:
+	default:
+	}
+}
+
+// interval returns how often retention policies are enforced.
+func (s *Service) interval() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.checkInterval
+}
+
 func (s *Service) run() {
-	ticker := time.NewTicker(time.Duration(s.config.CheckInterval))
-	defer ticker.Stop()
+	ticker := time.NewTicker(s.interval())
+	defer func() { ticker.Stop() }()
 	for {
 		select {
 		case <-s.done:
 			return
 
+		case <-s.reset:
+			ticker.Stop()
+			ticker = time.NewTicker(s.interval())
+
 		case <-ticker.C:
+			s.mu.Lock()
+			s.nextCheck = time.Now().Add(s.checkInterval)
+			s.mu.Unlock()
+
 			s.logger.Info("Retention policy shard deletion check commencing.")
 
 			type deletionInfo struct {
@@ -90,13 +171,49 @@ func (s *Service) run() {
 			dbs := s.MetaClient.Databases()
 			for _, d := range dbs {
 				for _, r := range d.RetentionPolicies {
-					for _, g := range r.ExpiredShardGroups(time.Now().UTC()) {
+					now := time.Now().UTC()
+					s.deleteExpiredMeasurements(d.Name, &r, now)
+
+					for _, g := range r.ExpiredShardGroups(now) {
+						if s.config.DryRun {
+							del := s.deletion(d.Name, &r, g, now)
+							s.logger.Info(fmt.Sprintf("Dry run: would delete shard group %d from database %s, retention policy %s: shards %v, %s to %s, %d bytes.", g.ID, d.Name, r.Name, del.ShardIDs, g.StartTime.Format(time.RFC3339), g.EndTime.Format(time.RFC3339), del.Size))
+							continue
+						}
+
+						// Roll up the shard group before it is deleted. If the
+						// rollup fails the shard group is kept so it can be retried.
+						if r.Rollup != "" {
+							if err := s.rollup(d.Name, &r, g); err != nil {
+								s.logger.Info(fmt.Sprintf("Failed to roll up shard group %d from database %s, retention policy %s: %v. Retry in %v.", g.ID, d.Name, r.Name, err, s.interval()))
+								continue
+							}
+							s.logger.Info(fmt.Sprintf("Rolled up shard group %d from database %s, retention policy %s.", g.ID, d.Name, r.Name))
+						}
+
+						// Archive the shards so the data is kept in cold storage.
+						var manifest string
+						if s.archiver != nil {
+							name, err := s.archive(d.Name, &r, g)
+							if err != nil {
+								s.logger.Info(fmt.Sprintf("Failed to archive shard group %d from database %s, retention policy %s: %v. Retry in %v.", g.ID, d.Name, r.Name, err, s.interval()))
+								continue
+							}
+							manifest = name
+							s.logger.Info(fmt.Sprintf("Archived shard group %d from database %s, retention policy %s to %s.", g.ID, d.Name, r.Name, s.config.ArchivePath))
+						}
+
 						if err := s.MetaClient.DeleteShardGroup(d.Name, r.Name, g.ID); err != nil {
-							s.logger.Info(fmt.Sprintf("Failed to delete shard group %d from database %s, retention policy %s: %v. Retry in %v.", g.ID, d.Name, r.Name, err, s.config.CheckInterval))
+							s.logger.Info(fmt.Sprintf("Failed to delete shard group %d from database %s, retention policy %s: %v. Retry in %v.", g.ID, d.Name, r.Name, err, s.interval()))
 							continue
 						}
 
 						s.logger.Info(fmt.Sprintf("Deleted shard group %d from database %s, retention policy %s.", g.ID, d.Name, r.Name))
+						if s.audit != nil {
+							del := s.deletion(d.Name, &r, g, now)
+							del.Archive = manifest
+							s.record(del)
+						}
 
 						// Store all the shard IDs that may possibly need to be removed locally.
 						for _, sh := range g.Shards {
@@ -110,7 +227,7 @@ func (s *Service) run() {
 			for _, id := range s.TSDBStore.ShardIDs() {
 				if info, ok := deletedShardIDs[id]; ok {
 					if err := s.TSDBStore.DeleteShard(id); err != nil {
-						s.logger.Error(fmt.Sprintf("Failed to delete shard ID %d from database %s, retention policy %s: %v. Will retry in %v", id, info.db, info.rp, err, s.config.CheckInterval))
+						s.logger.Error(fmt.Sprintf("Failed to delete shard ID %d from database %s, retention policy %s: %v. Will retry in %v", id, info.db, info.rp, err, s.interval()))
 						continue
 					}
 					s.logger.Info(fmt.Sprintf("Shard ID %d from database %s, retention policy %s, deleted.", id, info.db, info.rp))
@@ -118,8 +235,151 @@ func (s *Service) run() {
 			}
 
 			if err := s.MetaClient.PruneShardGroups(); err != nil {
-				s.logger.Info(fmt.Sprintf("Problem pruning shard groups: %s. Will retry in %v", err, s.config.CheckInterval))
+				s.logger.Info(fmt.Sprintf("Problem pruning shard groups: %s. Will retry in %v", err, s.interval()))
 			}
 		}
 	}
 }
+
+// deleteExpiredMeasurements deletes the data of measurements that are older
+// than their duration in a retention policy.
+func (s *Service) deleteExpiredMeasurements(database string, rp *meta.RetentionPolicyInfo, now time.Time) {
+	for name, d := range rp.MeasurementDurations {
+		expiry := now.Add(-d)
+
+		// Find the shards holding data older than the measurement duration.
+		var shardIDs []uint64
+		var start time.Time
+		for _, g := range rp.ShardGroups {
+			if g.Deleted() || !g.StartTime.Before(expiry) {
+				continue
+			}
+			if start.IsZero() || g.StartTime.Before(start) {
+				start = g.StartTime
+			}
+			for _, sh := range g.Shards {
+				shardIDs = append(shardIDs, sh.ID)
+			}
+		}
+		if len(shardIDs) == 0 {
+			continue
+		}
+
+		if s.config.DryRun {
+			s.logger.Info(fmt.Sprintf("Dry run: would delete data of measurement %s before %s from database %s, retention policy %s: shards %v.", name, expiry.Format(time.RFC3339), database, rp.Name, shardIDs))
+			continue
+		}
+
+		if err := s.TSDBStore.DeleteMeasurementRange(shardIDs, name, influxql.MinTime, expiry.UnixNano()-1); err != nil {
+			s.logger.Info(fmt.Sprintf("Failed to delete expired data of measurement %s from database %s, retention policy %s: %v. Retry in %v.", name, database, rp.Name, err, s.interval()))
+			continue
+		}
+
+		if s.audit != nil {
+			s.record(Deletion{
+				Time:            now,
+				Database:        database,
+				RetentionPolicy: rp.Name,
+				Measurement:     name,
+				ShardIDs:        shardIDs,
+				StartTime:       start,
+				EndTime:         expiry,
+			})
+		}
+	}
+}
+
+// PendingDeletions returns the shard groups that will be deleted by the next
+// retention policy check, or that would be deleted in dry run mode.
+func (s *Service) PendingDeletions() []Deletion {
+	s.mu.Lock()
+	next := s.nextCheck
+	s.mu.Unlock()
+	if next.IsZero() {
+		next = time.Now()
+	}
+	next = next.UTC()
+
+	var deletions []Deletion
+	for _, d := range s.MetaClient.Databases() {
+		for _, r := range d.RetentionPolicies {
+			for _, g := range r.ExpiredShardGroups(next) {
+				deletions = append(deletions, s.deletion(d.Name, &r, g, next))
+			}
+		}
+	}
+	return deletions
+}
+
+// deletion returns the deletion of a shard group at time t.
+func (s *Service) deletion(database string, rp *meta.RetentionPolicyInfo, g *meta.ShardGroupInfo, t time.Time) Deletion {
+	d := Deletion{
+		Time:            t,
+		Database:        database,
+		RetentionPolicy: rp.Name,
+		ShardGroupID:    g.ID,
+		StartTime:       g.StartTime,
+		EndTime:         g.EndTime,
+	}
+	for _, sh := range g.Shards {
+		d.ShardIDs = append(d.ShardIDs, sh.ID)
+
+		// Only shards stored locally have a known size.
+		if local := s.TSDBStore.Shard(sh.ID); local != nil {
+			if n, err := local.DiskSize(); err == nil {
+				d.Size += n
+			}
+		}
+	}
+	return d
+}
+
+// record appends a deletion to the audit log.
+func (s *Service) record(d Deletion) {
+	if err := s.audit.Record(d); err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to record deletion in audit log %s: %v", s.config.AuditLogPath, err))
+	}
+}
+
+// rollup runs the rollup of a retention policy over the time range of a
+// shard group. Measurements without a retention policy are read from the
+// retention policy.
+func (s *Service) rollup(database string, rp *meta.RetentionPolicyInfo, g *meta.ShardGroupInfo) error {
+	if s.QueryExecutor == nil {
+		return errors.New("no query executor")
+	}
+
+	stmt, err := influxql.ParseStatement(rp.Rollup)
+	if err != nil {
+		return err
+	}
+	q, ok := stmt.(*influxql.SelectStatement)
+	if !ok || q.Target == nil {
+		return fmt.Errorf("rollup is not a SELECT INTO statement: %s", rp.Rollup)
+	}
+
+	for _, src := range q.Sources {
+		if m, ok := src.(*influxql.Measurement); ok && m.Database == "" && m.RetentionPolicy == "" {
+			m.RetentionPolicy = rp.Name
+		}
+	}
+	if err := q.SetTimeRange(g.StartTime, g.EndTime); err != nil {
+		return err
+	}
+
+	closing := make(chan struct{})
+	defer close(closing)
+
+	results := s.QueryExecutor.ExecuteQuery(&influxql.Query{
+		Statements: influxql.Statements{q},
+	}, query.ExecutionOptions{
+		Database: database,
+		Priority: query.PrioritySystem,
+	}, closing)
+	for res := range results {
+		if res.Err != nil {
+			return res.Err
+		}
+	}
+	return nil
+}
@@ -2,16 +2,29 @@ package retention_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/internal"
+	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/services/retention"
 	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxdb/tsdb"
 	"github.com/uber-go/zap"
 )
 
@@ -205,6 +218,539 @@ func testService_8819_repro(t *testing.T) (*Service, chan error) {
 	return s, errC
 }
 
+func TestService_Rollup(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		err     error
+		deleted bool
+	}{
+		{name: "Success", deleted: true},
+		{name: "Failure", err: fmt.Errorf("rollup failed")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := retention.NewConfig()
+			c.CheckInterval = toml.Duration(time.Millisecond)
+			s := NewService(c)
+
+			start := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+			end := time.Date(1980, 1, 8, 0, 0, 0, 0, time.UTC)
+			var mu sync.Mutex
+			var rolledUp, deleted bool
+			var deletedAt time.Time
+			s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+				mu.Lock()
+				defer mu.Unlock()
+				return []meta.DatabaseInfo{{
+					Name: "db0",
+					RetentionPolicies: []meta.RetentionPolicyInfo{{
+						Name:               "rp0",
+						Duration:           time.Hour,
+						ShardGroupDuration: time.Hour,
+						Rollup:             `SELECT mean(value) INTO "rp1".:MEASUREMENT FROM /.*/ GROUP BY time(1h), *`,
+						ShardGroups: []meta.ShardGroupInfo{
+							{ID: 1, StartTime: start, EndTime: end, DeletedAt: deletedAt, Shards: []meta.ShardInfo{{ID: 2}}},
+						},
+					}},
+				}}
+			}
+
+			s.QueryExecutor.StatementExecutor = &StatementExecutor{
+				ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+					sel := stmt.(*influxql.SelectStatement)
+					if ctx.Database != "db0" {
+						t.Errorf("unexpected database: %s", ctx.Database)
+					} else if rp := sel.Sources[0].(*influxql.Measurement).RetentionPolicy; rp != "rp0" {
+						t.Errorf("unexpected source retention policy: %s", rp)
+					}
+
+					_, timeRange, err := influxql.ConditionExpr(sel.Condition, nil)
+					if err != nil {
+						t.Error(err)
+					} else if !timeRange.Min.Equal(start) {
+						t.Errorf("unexpected min time: %s", timeRange.Min)
+					} else if !timeRange.Max.Equal(end.Add(-1)) {
+						t.Errorf("unexpected max time: %s", timeRange.Max)
+					}
+
+					mu.Lock()
+					defer mu.Unlock()
+					if deleted {
+						t.Error("shard group deleted before rollup")
+					}
+					rolledUp = true
+					return tt.err
+				},
+			}
+			s.MetaClient.DeleteShardGroupFn = func(database string, policy string, id uint64) error {
+				mu.Lock()
+				defer mu.Unlock()
+				deleted = true
+				deletedAt = time.Now().UTC()
+				return nil
+			}
+
+			done := make(chan struct{}, 1)
+			s.MetaClient.PruneShardGroupsFn = func() error {
+				select {
+				case done <- struct{}{}:
+				default:
+				}
+				return nil
+			}
+			s.TSDBStore.ShardIDsFn = func() []uint64 { return nil }
+
+			if err := s.Open(); err != nil {
+				t.Fatal(err)
+			}
+			<-done
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !rolledUp {
+				t.Error("expected rollup to run")
+			}
+			if deleted != tt.deleted {
+				t.Errorf("unexpected shard group deletion: got=%v exp=%v", deleted, tt.deleted)
+			}
+		})
+	}
+}
+
+func TestService_MeasurementDurations(t *testing.T) {
+	c := retention.NewConfig()
+	c.CheckInterval = toml.Duration(time.Millisecond)
+	s := NewService(c)
+
+	now := time.Now().UTC()
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name:                 "rp0",
+				ShardGroupDuration:   time.Hour,
+				MeasurementDurations: map[string]time.Duration{"cpu": 2 * time.Hour},
+				ShardGroups: []meta.ShardGroupInfo{
+					{ID: 1, StartTime: now.Add(-4 * time.Hour), EndTime: now.Add(-3 * time.Hour), Shards: []meta.ShardInfo{{ID: 1}}},
+					{ID: 2, StartTime: now.Add(-3 * time.Hour), EndTime: now.Add(-2 * time.Hour), Shards: []meta.ShardInfo{{ID: 2}}},
+					{ID: 3, StartTime: now.Add(-time.Hour), EndTime: now, Shards: []meta.ShardInfo{{ID: 3}}},
+					{ID: 4, StartTime: now.Add(-5 * time.Hour), EndTime: now.Add(-4 * time.Hour), DeletedAt: now, Shards: []meta.ShardInfo{{ID: 4}}},
+				},
+			}},
+		}}
+	}
+
+	errC := make(chan error, 1)
+	s.TSDBStore.DeleteMeasurementRangeFn = func(shardIDs []uint64, name string, min, max int64) error {
+		var err error
+		if !reflect.DeepEqual(shardIDs, []uint64{1, 2}) {
+			err = fmt.Errorf("unexpected shard ids: %v", shardIDs)
+		} else if name != "cpu" {
+			err = fmt.Errorf("unexpected measurement: %s", name)
+		} else if min != influxql.MinTime {
+			err = fmt.Errorf("unexpected min time: %d", min)
+		} else if exp := time.Unix(0, max+1); exp.Before(now.Add(-2*time.Hour)) || exp.After(time.Now().Add(-2*time.Hour)) {
+			err = fmt.Errorf("unexpected max time: %s", time.Unix(0, max))
+		}
+
+		select {
+		case errC <- err:
+		default:
+		}
+		return nil
+	}
+	s.MetaClient.PruneShardGroupsFn = func() error { return nil }
+	s.TSDBStore.ShardIDsFn = func() []uint64 { return nil }
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	select {
+	case err := <-errC:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("measurement data not deleted")
+	}
+}
+
+func TestService_DryRun(t *testing.T) {
+	c := retention.NewConfig()
+	c.CheckInterval = toml.Duration(time.Millisecond)
+	c.DryRun = true
+	s := NewService(c)
+
+	start := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(1980, 1, 8, 0, 0, 0, 0, time.UTC)
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name:               "rp0",
+				Duration:           time.Hour,
+				ShardGroupDuration: time.Hour,
+				ShardGroups: []meta.ShardGroupInfo{
+					{ID: 1, StartTime: start, EndTime: end, Shards: []meta.ShardInfo{{ID: 2}, {ID: 3}}},
+					{ID: 4, StartTime: time.Now().Add(-time.Minute), EndTime: time.Now().Add(time.Hour), Shards: []meta.ShardInfo{{ID: 5}}},
+				},
+			}},
+		}}
+	}
+	s.MetaClient.DeleteShardGroupFn = func(database string, policy string, id uint64) error {
+		t.Errorf("unexpected deletion of shard group %d", id)
+		return nil
+	}
+
+	done := make(chan struct{}, 1)
+	s.MetaClient.PruneShardGroupsFn = func() error {
+		select {
+		case done <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	s.TSDBStore.ShardIDsFn = func() []uint64 { return []uint64{2, 3, 5} }
+	s.TSDBStore.ShardFn = func(id uint64) *tsdb.Shard { return nil }
+	s.TSDBStore.DeleteShardFn = func(id uint64) error {
+		t.Errorf("unexpected deletion of shard %d", id)
+		return nil
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(s.LogBuf.String(), "Dry run: would delete shard group 1 from database db0, retention policy rp0") {
+		t.Fatalf("dry run deletion not logged: %s", s.LogBuf.String())
+	}
+
+	deletions := s.PendingDeletions()
+	if len(deletions) != 1 {
+		t.Fatalf("unexpected pending deletions: %v", deletions)
+	}
+	d := deletions[0]
+	if d.Database != "db0" || d.RetentionPolicy != "rp0" || d.ShardGroupID != 1 {
+		t.Fatalf("unexpected pending deletion: %+v", d)
+	} else if !reflect.DeepEqual(d.ShardIDs, []uint64{2, 3}) {
+		t.Fatalf("unexpected shard ids: %v", d.ShardIDs)
+	} else if !d.StartTime.Equal(start) || !d.EndTime.Equal(end) {
+		t.Fatalf("unexpected time range: %s to %s", d.StartTime, d.EndTime)
+	}
+}
+
+func TestService_AuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retention")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := retention.NewConfig()
+	c.CheckInterval = toml.Duration(time.Millisecond)
+	c.AuditLogPath = filepath.Join(dir, "audit.log")
+	s := NewService(c)
+
+	start := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(1980, 1, 8, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	var deletedAt time.Time
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		mu.Lock()
+		defer mu.Unlock()
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name:               "rp0",
+				Duration:           time.Hour,
+				ShardGroupDuration: time.Hour,
+				ShardGroups: []meta.ShardGroupInfo{
+					{ID: 1, StartTime: start, EndTime: end, DeletedAt: deletedAt, Shards: []meta.ShardInfo{{ID: 2}}},
+				},
+			}},
+		}}
+	}
+	s.MetaClient.DeleteShardGroupFn = func(database string, policy string, id uint64) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deletedAt = time.Now().UTC()
+		return nil
+	}
+
+	done := make(chan struct{}, 1)
+	s.MetaClient.PruneShardGroupsFn = func() error {
+		select {
+		case done <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	s.TSDBStore.ShardIDsFn = func() []uint64 { return nil }
+	s.TSDBStore.ShardFn = func(id uint64) *tsdb.Shard { return nil }
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	<-done
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The shard group is deleted once so it should be recorded once.
+	buf, err := ioutil.ReadFile(c.AuditLogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(buf)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("unexpected audit log: %s", buf)
+	}
+
+	var d retention.Deletion
+	if err := json.Unmarshal([]byte(lines[0]), &d); err != nil {
+		t.Fatal(err)
+	} else if d.Database != "db0" || d.RetentionPolicy != "rp0" || d.ShardGroupID != 1 {
+		t.Fatalf("unexpected deletion: %+v", d)
+	} else if !reflect.DeepEqual(d.ShardIDs, []uint64{2}) {
+		t.Fatalf("unexpected shard ids: %v", d.ShardIDs)
+	} else if !d.StartTime.Equal(start) || !d.EndTime.Equal(end) {
+		t.Fatalf("unexpected time range: %s to %s", d.StartTime, d.EndTime)
+	}
+}
+
+func TestService_Archive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retention")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := retention.NewConfig()
+	c.CheckInterval = toml.Duration(time.Millisecond)
+	c.ArchivePath = dir
+	s := NewArchiveService(c)
+
+	var archived bool
+	s.TSDBStore.BackupShardFn = func(id uint64, since time.Time, w io.Writer) error {
+		if s.Deleted() {
+			t.Error("shard group deleted before archive")
+		}
+		archived = true
+		_, err := fmt.Fprintf(w, "shard %d", id)
+		return err
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	<-s.Done
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !archived || !s.Deleted() {
+		t.Fatalf("expected shard group to be archived and deleted")
+	}
+
+	// Only the local shard is archived.
+	if buf, err := ioutil.ReadFile(filepath.Join(dir, "db0.rp0.00002.00")); err != nil {
+		t.Fatal(err)
+	} else if string(buf) != "shard 2" {
+		t.Fatalf("unexpected shard archive: %s", buf)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "db0.rp0.00003.00")); !os.IsNotExist(err) {
+		t.Fatalf("unexpected archive of remote shard: %v", err)
+	}
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "manifests", "db0.rp0.00001.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m retention.ArchiveManifest
+	if err := json.Unmarshal(buf, &m); err != nil {
+		t.Fatal(err)
+	} else if m.Database != "db0" || m.RetentionPolicy != "rp0" || m.ShardGroupID != 1 {
+		t.Fatalf("unexpected manifest: %+v", m)
+	} else if exp := []retention.ArchivedShard{{ID: 2, Path: "db0.rp0.00002.00", Size: 7}}; !reflect.DeepEqual(m.Shards, exp) {
+		t.Fatalf("unexpected archived shards: %+v", m.Shards)
+	}
+}
+
+func TestService_Archive_Error(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retention")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := retention.NewConfig()
+	c.CheckInterval = toml.Duration(time.Millisecond)
+	c.ArchivePath = dir
+	s := NewArchiveService(c)
+
+	s.TSDBStore.BackupShardFn = func(id uint64, since time.Time, w io.Writer) error {
+		fmt.Fprint(w, "partial")
+		return fmt.Errorf("backup failed")
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	<-s.Done
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The shard group must be kept and nothing archived.
+	if s.Deleted() {
+		t.Fatal("shard group deleted after failed archive")
+	}
+	if fis, err := ioutil.ReadDir(dir); err != nil {
+		t.Fatal(err)
+	} else if len(fis) != 0 {
+		t.Fatalf("unexpected files in archive: %d", len(fis))
+	}
+}
+
+func TestService_Archive_S3(t *testing.T) {
+	var mu sync.Mutex
+	objects := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		h := sha256.Sum256(body)
+		if r.Method != "PUT" {
+			t.Errorf("unexpected method: %s", r.Method)
+		} else if got := r.Header.Get("X-Amz-Content-Sha256"); got != hex.EncodeToString(h[:]) {
+			t.Errorf("unexpected payload hash: %s", got)
+		} else if got := r.Header.Get("Authorization"); !strings.HasPrefix(got, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(got, "/eu-west-1/s3/aws4_request") {
+			t.Errorf("unexpected authorization: %s", got)
+		}
+
+		mu.Lock()
+		objects[r.URL.Path] = string(body)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	defer os.Setenv("AWS_ACCESS_KEY_ID", os.Getenv("AWS_ACCESS_KEY_ID"))
+	defer os.Setenv("AWS_SECRET_ACCESS_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	os.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+
+	c := retention.NewConfig()
+	c.CheckInterval = toml.Duration(time.Millisecond)
+	c.ArchivePath = "s3://bucket/influxdb/archive"
+	c.ArchiveS3Region = "eu-west-1"
+	c.ArchiveS3Endpoint = server.URL
+	s := NewArchiveService(c)
+
+	s.TSDBStore.BackupShardFn = func(id uint64, since time.Time, w io.Writer) error {
+		_, err := fmt.Fprintf(w, "shard %d", id)
+		return err
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	<-s.Done
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := objects["/bucket/influxdb/archive/db0.rp0.00002.00"]; got != "shard 2" {
+		t.Fatalf("unexpected shard archive: %q", got)
+	} else if _, ok := objects["/bucket/influxdb/archive/manifests/db0.rp0.00001.json"]; !ok {
+		t.Fatalf("manifest not uploaded: %v", objects)
+	} else if !s.Deleted() {
+		t.Fatal("expected shard group to be deleted")
+	}
+}
+
+// ArchiveService is a test wrapper for a retention service with an expired
+// shard group of a local and a remote shard.
+type ArchiveService struct {
+	*Service
+
+	mu      sync.Mutex
+	deleted bool
+
+	// Done receives after each retention check.
+	Done chan struct{}
+}
+
+// NewArchiveService returns a new instance of ArchiveService.
+func NewArchiveService(c retention.Config) *ArchiveService {
+	s := &ArchiveService{
+		Service: NewService(c),
+		Done:    make(chan struct{}, 1),
+	}
+
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		var deletedAt time.Time
+		if s.Deleted() {
+			deletedAt = time.Now().UTC()
+		}
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name:               "rp0",
+				Duration:           time.Hour,
+				ShardGroupDuration: time.Hour,
+				ShardGroups: []meta.ShardGroupInfo{{
+					ID:        1,
+					StartTime: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC),
+					EndTime:   time.Date(1980, 1, 8, 0, 0, 0, 0, time.UTC),
+					DeletedAt: deletedAt,
+					Shards:    []meta.ShardInfo{{ID: 2}, {ID: 3}},
+				}},
+			}},
+		}}
+	}
+	s.MetaClient.DeleteShardGroupFn = func(database string, policy string, id uint64) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.deleted = true
+		return nil
+	}
+	s.MetaClient.PruneShardGroupsFn = func() error {
+		select {
+		case s.Done <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+	s.TSDBStore.ShardIDsFn = func() []uint64 { return nil }
+	s.TSDBStore.ShardFn = func(id uint64) *tsdb.Shard {
+		if id == 2 {
+			return &tsdb.Shard{}
+		}
+		return nil
+	}
+	return s
+}
+
+// Deleted returns true if the shard group has been deleted.
+func (s *ArchiveService) Deleted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleted
+}
+
 type Service struct {
 	MetaClient *internal.MetaClientMock
 	TSDBStore  *internal.TSDBStoreMock
@@ -230,5 +776,15 @@ func NewService(c retention.Config) *Service {
 
 	s.Service.MetaClient = s.MetaClient
 	s.Service.TSDBStore = s.TSDBStore
+	s.Service.QueryExecutor = query.NewQueryExecutor()
 	return s
 }
+
+// StatementExecutor is a mock statement executor.
+type StatementExecutor struct {
+	ExecuteStatementFn func(stmt influxql.Statement, ctx query.ExecutionContext) error
+}
+
+func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx query.ExecutionContext) error {
+	return e.ExecuteStatementFn(stmt, ctx)
+}
@@ -0,0 +1,90 @@
+package slowquery
+
+import (
+	"errors"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	// DefaultThreshold is the default duration a query must exceed to be
+	// logged.
+	DefaultThreshold = 10 * time.Second
+
+	// DefaultSampleRate is the default fraction of the slow queries logged.
+	DefaultSampleRate = 1.0
+
+	// DefaultMeasurement is the default measurement slow queries are written
+	// to.
+	DefaultMeasurement = "slow_queries"
+)
+
+// Config represents the configuration for the slow query log.
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// Threshold is the duration a query must exceed to be logged.
+	Threshold toml.Duration `toml:"threshold"`
+
+	// SampleRate is the fraction, greater than 0 and at most 1, of the slow
+	// queries logged.
+	SampleRate float64 `toml:"sample-rate"`
+
+	// Path is the file slow queries are appended to, if set.
+	Path string `toml:"path"`
+
+	// Database is the database slow queries are written to as points of
+	// Measurement, if set.
+	Database        string `toml:"database"`
+	RetentionPolicy string `toml:"retention-policy"`
+	Measurement     string `toml:"measurement"`
+}
+
+// NewConfig returns a new Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Enabled:     false,
+		Threshold:   toml.Duration(DefaultThreshold),
+		SampleRate:  DefaultSampleRate,
+		Measurement: DefaultMeasurement,
+	}
+}
+
+// Validate returns an error if the Config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Threshold <= 0 {
+		return errors.New("slow query log threshold must be positive")
+	} else if c.SampleRate <= 0 || c.SampleRate > 1 {
+		return errors.New("slow query log sample-rate must be greater than 0 and at most 1")
+	} else if c.Path == "" && c.Database == "" {
+		return errors.New("slow query log path or database must be specified")
+	} else if c.Database != "" && c.Measurement == "" {
+		return errors.New("slow query log measurement must be specified")
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":          true,
+		"threshold":        c.Threshold,
+		"sample-rate":      c.SampleRate,
+		"path":             c.Path,
+		"database":         c.Database,
+		"retention-policy": c.RetentionPolicy,
+		"measurement":      c.Measurement,
+	}), nil
+}
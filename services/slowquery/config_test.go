@@ -0,0 +1,55 @@
+package slowquery_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/slowquery"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	c := slowquery.NewConfig()
+	if _, err := toml.Decode(`
+enabled = true
+threshold = "5s"
+sample-rate = 0.5
+path = "/var/log/influxdb/slow.log"
+database = "_slow"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.Enabled {
+		t.Fatalf("unexpected enabled state: %v", c.Enabled)
+	} else if time.Duration(c.Threshold) != 5*time.Second {
+		t.Fatalf("unexpected threshold: %s", c.Threshold)
+	} else if c.SampleRate != 0.5 {
+		t.Fatalf("unexpected sample rate: %f", c.SampleRate)
+	} else if c.Path != "/var/log/influxdb/slow.log" {
+		t.Fatalf("unexpected path: %s", c.Path)
+	} else if c.Database != "_slow" || c.Measurement != slowquery.DefaultMeasurement {
+		t.Fatalf("unexpected destination: %s %s", c.Database, c.Measurement)
+	} else if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail: %s", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	for _, fn := range []func(c *slowquery.Config){
+		func(c *slowquery.Config) { c.Path = "" },
+		func(c *slowquery.Config) { c.Threshold = 0 },
+		func(c *slowquery.Config) { c.SampleRate = 0 },
+		func(c *slowquery.Config) { c.SampleRate = 1.5 },
+	} {
+		c := slowquery.NewConfig()
+		c.Enabled = true
+		c.Path = "/var/log/influxdb/slow.log"
+		fn(&c)
+		if err := c.Validate(); err == nil {
+			t.Fatalf("expected error: %+v", c)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Package slowquery provides a service that records the queries slower than
+// a threshold to a file or a measurement, so slow dashboards and performance
+// regressions can be traced to the queries behind them.
+package slowquery // import "github.com/influxdata/influxdb/services/slowquery"
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/uber-go/zap"
+)
+
+// Statistics for the slow query log.
+const (
+	statQueriesLogged  = "queriesLogged"
+	statQueriesSampled = "queriesSampledOut"
+	statQueriesDropped = "queriesDropped"
+	statWriteErr       = "writeErr"
+)
+
+// bufferSize is the number of slow queries waiting to be written before new
+// ones are dropped.
+const bufferSize = 1000
+
+// Entry is a single record of the slow query log.
+type Entry struct {
+	// Time is when the query finished.
+	Time time.Time `json:"time"`
+
+	QueryID  uint64 `json:"qid"`
+	Query    string `json:"query"`
+	Database string `json:"database,omitempty"`
+	User     string `json:"user,omitempty"`
+
+	// Duration is in nanoseconds.
+	Duration time.Duration `json:"duration"`
+	Status   string        `json:"status"`
+
+	// SeriesN is the number of series read, and Rows and Bytes the number
+	// of rows returned and an estimate of their size.
+	SeriesN int64 `json:"series"`
+	Rows    int64 `json:"rows"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// point returns the entry as a point of the measurement.
+func (e Entry) point(measurement string) (models.Point, error) {
+	tags := make(map[string]string)
+	if e.Database != "" {
+		tags["database"] = e.Database
+	}
+	if e.User != "" {
+		tags["user"] = e.User
+	}
+	return models.NewPoint(measurement, models.NewTags(tags), models.Fields{
+		"qid":      int64(e.QueryID),
+		"query":    e.Query,
+		"duration": int64(e.Duration),
+		"status":   e.Status,
+		"series":   e.SeriesN,
+		"rows":     e.Rows,
+		"bytes":    e.Bytes,
+	}, e.Time)
+}
+
+// Service records the queries slower than the threshold of its config.
+// Queries are written in the background, as JSON lines appended to a file,
+// as points written to a database, or both.
+type Service struct {
+	config Config
+
+	mu      sync.RWMutex
+	f       *os.File
+	entries chan Entry
+	wg      sync.WaitGroup
+
+	MetaClient interface {
+		CreateDatabase(name string) (*meta.DatabaseInfo, error)
+	}
+	PointsWriter interface {
+		WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+
+	stats  *Statistics
+	Logger zap.Logger
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		config: c,
+		stats:  &Statistics{},
+		Logger: zap.New(zap.NullEncoder()),
+	}
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log zap.Logger) {
+	s.Logger = log.With(zap.String("service", "slow-query-log"))
+}
+
+// Open opens the file of the slow query log and creates its database.
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.entries != nil {
+		return nil
+	}
+
+	s.Logger.Info(fmt.Sprintf("Starting slow query log, threshold %s", time.Duration(s.config.Threshold)))
+
+	if s.config.Path != "" {
+		if err := os.MkdirAll(filepath.Dir(s.config.Path), 0700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(s.config.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		s.f = f
+	}
+
+	if s.config.Database != "" {
+		if _, err := s.MetaClient.CreateDatabase(s.config.Database); err != nil {
+			if s.f != nil {
+				s.f.Close()
+				s.f = nil
+			}
+			return err
+		}
+	}
+
+	s.entries = make(chan Entry, bufferSize)
+	s.wg.Add(1)
+	go s.run(s.entries)
+	return nil
+}
+
+// Close writes the pending slow queries and closes the file of the slow
+// query log.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	if s.entries == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	close(s.entries)
+	s.entries = nil
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// Statistics maintains the statistics for the slow query log.
+type Statistics struct {
+	QueriesLogged  int64
+	QueriesSampled int64
+	QueriesDropped int64
+	WriteErr       int64
+}
+
+// Statistics returns statistics for periodic monitoring.
+func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	return []models.Statistic{{
+		Name: "slow_query_log",
+		Tags: tags,
+		Values: map[string]interface{}{
+			statQueriesLogged:  atomic.LoadInt64(&s.stats.QueriesLogged),
+			statQueriesSampled: atomic.LoadInt64(&s.stats.QueriesSampled),
+			statQueriesDropped: atomic.LoadInt64(&s.stats.QueriesDropped),
+			statWriteErr:       atomic.LoadInt64(&s.stats.WriteErr),
+		},
+	}}
+}
+
+// LogQuery records the finished query if it was slower than the threshold
+// and is sampled.  Slow queries are dropped if too many are waiting to be
+// written.
+func (s *Service) LogQuery(q query.QueryInfo) {
+	if q.Duration <= time.Duration(s.config.Threshold) {
+		return
+	} else if s.config.SampleRate < 1 && rand.Float64() >= s.config.SampleRate {
+		atomic.AddInt64(&s.stats.QueriesSampled, 1)
+		return
+	}
+
+	e := Entry{
+		Time:     time.Now().UTC(),
+		QueryID:  q.ID,
+		Query:    q.Query,
+		Database: q.Database,
+		User:     q.User,
+		Duration: q.Duration,
+		Status:   q.Status,
+		SeriesN:  q.SeriesN,
+		Rows:     q.Rows,
+		Bytes:    q.Memory,
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	select {
+	case s.entries <- e:
+	default:
+		// Closed, or too many queries waiting.
+		atomic.AddInt64(&s.stats.QueriesDropped, 1)
+	}
+}
+
+// run writes the entries until the channel is closed.
+func (s *Service) run(entries <-chan Entry) {
+	defer s.wg.Done()
+	for e := range entries {
+		if err := s.write(e); err != nil {
+			atomic.AddInt64(&s.stats.WriteErr, 1)
+			s.Logger.Info(fmt.Sprintf("WARN: unable to write slow query: %s", err))
+			continue
+		}
+		atomic.AddInt64(&s.stats.QueriesLogged, 1)
+	}
+}
+
+// write writes the entry to the file and the database of the slow query log.
+func (s *Service) write(e Entry) error {
+	if s.f != nil {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := s.f.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+
+	if s.config.Database != "" {
+		pt, err := e.point(s.config.Measurement)
+		if err != nil {
+			return err
+		}
+		return s.PointsWriter.WritePointsPrivileged(s.config.Database, s.config.RetentionPolicy, models.ConsistencyLevelAny, []models.Point{pt})
+	}
+	return nil
+}
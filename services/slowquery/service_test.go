@@ -0,0 +1,114 @@
+package slowquery_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/services/slowquery"
+	"github.com/influxdata/influxdb/toml"
+)
+
+func TestService_LogQuery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slowquery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := slowquery.NewConfig()
+	c.Enabled = true
+	c.Threshold = toml.Duration(time.Second)
+	c.Path = filepath.Join(dir, "log", "slow.log")
+	c.Database = "_slow"
+	s := slowquery.NewService(c)
+
+	var created string
+	s.MetaClient = &MetaClient{CreateDatabaseFn: func(name string) (*meta.DatabaseInfo, error) {
+		created = name
+		return &meta.DatabaseInfo{Name: name}, nil
+	}}
+	var points []models.Point
+	s.PointsWriter = &PointsWriter{WritePointsPrivilegedFn: func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, pts []models.Point) error {
+		if database != "_slow" {
+			t.Fatalf("unexpected database: %s", database)
+		}
+		points = append(points, pts...)
+		return nil
+	}}
+
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	} else if created != "_slow" {
+		t.Fatalf("database not created: %q", created)
+	}
+
+	s.LogQuery(query.QueryInfo{ID: 1, Query: "SELECT * FROM cpu", Database: "db0", Duration: 500 * time.Millisecond})
+	s.LogQuery(query.QueryInfo{ID: 2, Query: "SELECT mean(value) FROM cpu", Database: "db0", User: "fred", Duration: 2 * time.Second, Status: "running", SeriesN: 10, Rows: 20, Memory: 480})
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Queries logged after the service is closed are dropped.
+	s.LogQuery(query.QueryInfo{ID: 3, Duration: time.Minute})
+
+	f, err := os.Open(c.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []slowquery.Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e slowquery.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	} else if e := entries[0]; e.QueryID != 2 || e.Query != "SELECT mean(value) FROM cpu" || e.Database != "db0" || e.User != "fred" ||
+		e.Duration != 2*time.Second || e.SeriesN != 10 || e.Rows != 20 || e.Bytes != 480 || e.Time.IsZero() {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+
+	if len(points) != 1 {
+		t.Fatalf("unexpected points: %v", points)
+	} else if pt := points[0]; string(pt.Name()) != slowquery.DefaultMeasurement || pt.Tags().GetString("user") != "fred" {
+		t.Fatalf("unexpected point: %s", pt)
+	}
+
+	stats := s.Statistics(nil)[0].Values
+	if stats["queriesLogged"] != int64(1) || stats["queriesDropped"] != int64(1) {
+		t.Fatalf("unexpected statistics: %v", stats)
+	}
+}
+
+type MetaClient struct {
+	CreateDatabaseFn func(name string) (*meta.DatabaseInfo, error)
+}
+
+func (c *MetaClient) CreateDatabase(name string) (*meta.DatabaseInfo, error) {
+	return c.CreateDatabaseFn(name)
+}
+
+type PointsWriter struct {
+	WritePointsPrivilegedFn func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+}
+
+func (w *PointsWriter) WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	return w.WritePointsPrivilegedFn(database, retentionPolicy, consistencyLevel, points)
+}
@@ -41,6 +41,11 @@ type Service struct {
 
 	TSDBStore *tsdb.Store
 
+	// Auditor records the backups taken, if set.
+	Auditor interface {
+		AuditBackup(addr, target string, err error)
+	}
+
 	Listener net.Listener
 	Logger   zap.Logger
 }
@@ -115,11 +120,15 @@ func (s *Service) handleConn(conn net.Conn) error {
 
 	switch r.Type {
 	case RequestShardBackup:
-		if err := s.TSDBStore.BackupShard(r.ShardID, r.Since, conn); err != nil {
+		err := s.TSDBStore.BackupShard(r.ShardID, r.Since, conn)
+		s.auditBackup(conn, fmt.Sprintf("shard %d", r.ShardID), err)
+		if err != nil {
 			return err
 		}
 	case RequestMetastoreBackup:
-		if err := s.writeMetaStore(conn); err != nil {
+		err := s.writeMetaStore(conn)
+		s.auditBackup(conn, "meta", err)
+		if err != nil {
 			return err
 		}
 	case RequestDatabaseInfo:
@@ -133,6 +142,14 @@ func (s *Service) handleConn(conn net.Conn) error {
 	return nil
 }
 
+// auditBackup records a backup of target requested over conn.
+func (s *Service) auditBackup(conn net.Conn, target string, err error) {
+	if s.Auditor == nil {
+		return
+	}
+	s.Auditor.AuditBackup(conn.RemoteAddr().String(), target, err)
+}
+
 func (s *Service) writeMetaStore(conn net.Conn) error {
 	// Retrieve and serialize the current meta data.
 	metaBlob, err := s.MetaClient.MarshalBinary()
@@ -20,6 +20,35 @@ const (
 
 	// DefaultWriteBufferSize is the default write buffer size for a Config.
 	DefaultWriteBufferSize = 1000
+
+	// DefaultBatchSize is the default maximum number of points in a Kafka or
+	// NATS message.
+	DefaultBatchSize = 1000
+
+	// DefaultWriteTimeout is the default timeout of writes to Kafka and NATS.
+	DefaultWriteTimeout = 10 * time.Second
+
+	// DropOldest discards the oldest queued writes when a queue is full.
+	DropOldest = "oldest"
+
+	// DropNewest discards incoming writes when a queue is full.
+	DropNewest = "newest"
+
+	// DefaultMaxQueueSize is the default maximum size of each destination's queue.
+	DefaultMaxQueueSize = 100 * 1024 * 1024
+
+	// DefaultMaxSegmentSize is the default size of each queue segment file.
+	DefaultMaxSegmentSize = 10 * 1024 * 1024
+
+	// DefaultDropPolicy is the default policy applied when a queue is full.
+	DefaultDropPolicy = DropOldest
+
+	// DefaultRetryInterval is the default initial delay before retrying a
+	// queued write.
+	DefaultRetryInterval = time.Second
+
+	// DefaultRetryMaxInterval is the default maximum delay between retries.
+	DefaultRetryMaxInterval = time.Minute
 )
 
 // Config represents a configuration of the subscriber service.
@@ -42,6 +71,34 @@ type Config struct {
 
 	// The number of in-flight writes buffered in the write channel.
 	WriteBufferSize int `toml:"write-buffer-size"`
+
+	// The maximum number of points in each message written to Kafka and NATS
+	// destinations.
+	BatchSize int `toml:"batch-size"`
+
+	// The time to wait for Kafka and NATS to acknowledge a write.
+	WriteTimeout toml.Duration `toml:"write-timeout"`
+
+	// QueueEnabled queues writes to each destination on disk until they are
+	// acknowledged, so they are not lost while a destination is unavailable.
+	QueueEnabled bool `toml:"queue-enabled"`
+
+	// QueueDir is where the queues of undelivered writes are stored.
+	QueueDir string `toml:"queue-dir"`
+
+	// MaxQueueSize is the maximum size on disk of each destination's queue.
+	MaxQueueSize toml.Size `toml:"max-queue-size"`
+
+	// MaxSegmentSize is the size at which a queue starts a new segment file.
+	MaxSegmentSize toml.Size `toml:"max-segment-size"`
+
+	// DropPolicy determines which writes are discarded when a queue is full.
+	DropPolicy string `toml:"drop-policy"`
+
+	// RetryInterval is the delay before the first retry of a queued write. The
+	// delay doubles with each consecutive failure up to RetryMaxInterval.
+	RetryInterval    toml.Duration `toml:"retry-interval"`
+	RetryMaxInterval toml.Duration `toml:"retry-max-interval"`
 }
 
 // NewConfig returns a new instance of a subscriber config.
@@ -53,6 +110,13 @@ func NewConfig() Config {
 		CaCerts:            "",
 		WriteConcurrency:   DefaultWriteConcurrency,
 		WriteBufferSize:    DefaultWriteBufferSize,
+		BatchSize:          DefaultBatchSize,
+		WriteTimeout:       toml.Duration(DefaultWriteTimeout),
+		MaxQueueSize:       toml.Size(DefaultMaxQueueSize),
+		MaxSegmentSize:     toml.Size(DefaultMaxSegmentSize),
+		DropPolicy:         DefaultDropPolicy,
+		RetryInterval:      toml.Duration(DefaultRetryInterval),
+		RetryMaxInterval:   toml.Duration(DefaultRetryMaxInterval),
 	}
 }
 
@@ -78,6 +142,38 @@ func (c Config) Validate() error {
 		return errors.New("write-concurrency must be greater than 0")
 	}
 
+	if c.BatchSize < 0 {
+		return errors.New("batch-size must not be negative")
+	}
+
+	if c.WriteTimeout < 0 {
+		return errors.New("write-timeout must not be negative")
+	}
+
+	if c.QueueEnabled {
+		if c.QueueDir == "" {
+			return errors.New("queue-dir must be specified")
+		}
+
+		switch c.DropPolicy {
+		case DropOldest, DropNewest:
+		default:
+			return fmt.Errorf("unknown drop-policy: %q", c.DropPolicy)
+		}
+
+		if c.MaxSegmentSize <= 0 {
+			return errors.New("max-segment-size must be greater than 0")
+		} else if c.MaxQueueSize < c.MaxSegmentSize {
+			return errors.New("max-queue-size must not be less than max-segment-size")
+		}
+
+		if c.RetryInterval <= 0 {
+			return errors.New("retry-interval must be greater than 0")
+		} else if c.RetryMaxInterval < c.RetryInterval {
+			return errors.New("retry-max-interval must not be less than retry-interval")
+		}
+	}
+
 	return nil
 }
 
@@ -94,10 +190,21 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 		}), nil
 	}
 
-	return diagnostics.RowFromMap(map[string]interface{}{
+	m := map[string]interface{}{
 		"enabled":           true,
 		"http-timeout":      c.HTTPTimeout,
 		"write-concurrency": c.WriteConcurrency,
 		"write-buffer-size": c.WriteBufferSize,
-	}), nil
+		"batch-size":        c.BatchSize,
+		"write-timeout":     c.WriteTimeout,
+		"queue-enabled":     c.QueueEnabled,
+	}
+	if c.QueueEnabled {
+		m["queue-dir"] = c.QueueDir
+		m["max-queue-size"] = c.MaxQueueSize
+		m["drop-policy"] = c.DropPolicy
+		m["retry-interval"] = c.RetryInterval
+		m["retry-max-interval"] = c.RetryMaxInterval
+	}
+	return diagnostics.RowFromMap(m), nil
 }
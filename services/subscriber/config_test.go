@@ -109,3 +109,40 @@ write-concurrency = 10
 		t.Errorf("Expected Validation to succeed. Instead was: %v", err)
 	}
 }
+
+func TestConfig_ValidateQueue(t *testing.T) {
+	c := subscriber.NewConfig()
+	if _, err := toml.Decode(`
+queue-enabled = true
+queue-dir = "/var/lib/influxdb/subscriber"
+max-queue-size = 20971520
+drop-policy = "newest"
+retry-interval = "100ms"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if c.MaxQueueSize != 20971520 {
+		t.Fatalf("unexpected max queue size: %d", c.MaxQueueSize)
+	} else if c.DropPolicy != subscriber.DropNewest {
+		t.Fatalf("unexpected drop policy: %s", c.DropPolicy)
+	}
+
+	for _, tt := range []struct {
+		fn  func(c *subscriber.Config)
+		err string
+	}{
+		{func(c *subscriber.Config) { c.QueueDir = "" }, "queue-dir must be specified"},
+		{func(c *subscriber.Config) { c.DropPolicy = "all" }, `unknown drop-policy: "all"`},
+		{func(c *subscriber.Config) { c.MaxQueueSize = 1 }, "max-queue-size must not be less than max-segment-size"},
+		{func(c *subscriber.Config) { c.RetryInterval = 0 }, "retry-interval must be greater than 0"},
+	} {
+		v := c
+		tt.fn(&v)
+		if err := v.Validate(); err == nil || err.Error() != tt.err {
+			t.Errorf("unexpected error: got %v, exp %s", err, tt.err)
+		}
+	}
+}
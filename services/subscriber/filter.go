@@ -0,0 +1,121 @@
+package subscriber
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
+)
+
+// pointFilter reports whether a point with the given measurement name and
+// tags matches the condition of a subscription.
+type pointFilter func(name []byte, tags models.Tags) bool
+
+// newPointFilter compiles the condition of a subscription into a pointFilter.
+// The measurement is referenced as "measurement" or "_name", and any other
+// variable is the value of a tag, empty if the point does not have the tag.
+func newPointFilter(condition string) (pointFilter, error) {
+	expr, err := influxql.ParseExpr(condition)
+	if err != nil {
+		return nil, err
+	}
+	return compileFilter(expr)
+}
+
+func compileFilter(expr influxql.Expr) (pointFilter, error) {
+	switch expr := expr.(type) {
+	case *influxql.ParenExpr:
+		return compileFilter(expr.Expr)
+	case *influxql.BinaryExpr:
+		switch expr.Op {
+		case influxql.AND, influxql.OR:
+			lhs, err := compileFilter(expr.LHS)
+			if err != nil {
+				return nil, err
+			}
+			rhs, err := compileFilter(expr.RHS)
+			if err != nil {
+				return nil, err
+			}
+			if expr.Op == influxql.AND {
+				return func(name []byte, tags models.Tags) bool {
+					return lhs(name, tags) && rhs(name, tags)
+				}, nil
+			}
+			return func(name []byte, tags models.Tags) bool {
+				return lhs(name, tags) || rhs(name, tags)
+			}, nil
+		case influxql.EQ, influxql.NEQ, influxql.EQREGEX, influxql.NEQREGEX:
+			ref, ok := expr.LHS.(*influxql.VarRef)
+			if !ok {
+				break
+			}
+			value := filterValue(ref.Val)
+			negate := expr.Op == influxql.NEQ || expr.Op == influxql.NEQREGEX
+
+			switch lit := expr.RHS.(type) {
+			case *influxql.StringLiteral:
+				if expr.Op != influxql.EQ && expr.Op != influxql.NEQ {
+					break
+				}
+				s := []byte(lit.Val)
+				return func(name []byte, tags models.Tags) bool {
+					return bytes.Equal(value(name, tags), s) != negate
+				}, nil
+			case *influxql.RegexLiteral:
+				if expr.Op != influxql.EQREGEX && expr.Op != influxql.NEQREGEX {
+					break
+				}
+				re := lit.Val
+				return func(name []byte, tags models.Tags) bool {
+					return re.Match(value(name, tags)) != negate
+				}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("invalid subscription condition: %s", expr)
+}
+
+// filterValue returns a function returning the value of the variable key of a
+// point.
+func filterValue(key string) func(name []byte, tags models.Tags) []byte {
+	if key == "measurement" || key == "_name" {
+		return func(name []byte, tags models.Tags) []byte { return name }
+	}
+	k := []byte(key)
+	return func(name []byte, tags models.Tags) []byte { return tags.Get(k) }
+}
+
+// filterRequest returns the write request with only the points matching the
+// filter, which is the request itself if all of them match, or nil if none
+// does.
+func filterRequest(p *coordinator.WritePointsRequest, filter pointFilter) *coordinator.WritePointsRequest {
+	var points []models.Point
+	for i, pt := range p.Points {
+		if filter(pt.Name(), pt.Tags()) {
+			if points != nil {
+				points = append(points, pt)
+			}
+			continue
+		}
+
+		// Copy the points that matched so far on the first mismatch.
+		if points == nil {
+			points = make([]models.Point, i, len(p.Points))
+			copy(points, p.Points[:i])
+		}
+	}
+
+	if points == nil {
+		return p
+	} else if len(points) == 0 {
+		return nil
+	}
+	return &coordinator.WritePointsRequest{
+		Database:        p.Database,
+		RetentionPolicy: p.RetentionPolicy,
+		Points:          points,
+	}
+}
@@ -0,0 +1,104 @@
+package subscriber
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/influxdata/influxdb/coordinator"
+)
+
+// Kafka supports writing points to a Kafka topic using the line protocol.
+// Each message holds up to batchSize points.
+type Kafka struct {
+	broker    string
+	topic     string
+	batchSize int
+	timeout   time.Duration
+
+	mu          sync.Mutex
+	producer    sarama.SyncProducer
+	newProducer func() (sarama.SyncProducer, error)
+
+	stats messageStats
+}
+
+// NewKafka returns a new Kafka points writer for the topic of the cluster of
+// broker.  It connects to the broker when points are first written.
+func NewKafka(broker, topic string, batchSize int, timeout time.Duration) (*Kafka, error) {
+	if topic == "" {
+		return nil, errors.New("kafka topic required")
+	}
+
+	k := &Kafka{
+		broker:    broker,
+		topic:     topic,
+		batchSize: batchSize,
+		timeout:   timeout,
+	}
+	k.newProducer = k.connect
+	return k, nil
+}
+
+// connect returns a producer that waits for the leader of a partition to
+// acknowledge each message.
+func (k *Kafka) connect() (sarama.SyncProducer, error) {
+	config := sarama.NewConfig()
+	config.ClientID = "influxdb"
+	config.Producer.RequiredAcks = sarama.WaitForLocal
+	config.Producer.Timeout = k.timeout
+	config.Producer.Return.Successes = true
+	return sarama.NewSyncProducer([]string{k.broker}, config)
+}
+
+// WritePoints writes points to the Kafka topic.
+func (k *Kafka) WritePoints(p *coordinator.WritePointsRequest) error {
+	producer, err := k.getProducer()
+	if err != nil {
+		return err
+	}
+
+	batches := encodeBatches(p.Points, k.batchSize)
+	msgs := make([]*sarama.ProducerMessage, len(batches))
+	for i, b := range batches {
+		msgs[i] = &sarama.ProducerMessage{Topic: k.topic, Value: sarama.ByteEncoder(b)}
+	}
+	if err := producer.SendMessages(msgs); err != nil {
+		return err
+	}
+	k.stats.add(batches)
+	return nil
+}
+
+// getProducer returns the producer, connecting to the broker if needed.
+func (k *Kafka) getProducer() (sarama.SyncProducer, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.producer == nil {
+		producer, err := k.newProducer()
+		if err != nil {
+			return nil, err
+		}
+		k.producer = producer
+	}
+	return k.producer, nil
+}
+
+// Close closes the connections to the Kafka cluster.
+func (k *Kafka) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.producer == nil {
+		return nil
+	}
+	err := k.producer.Close()
+	k.producer = nil
+	return err
+}
+
+func (k *Kafka) messageStatistics() *messageStats {
+	return &k.stats
+}
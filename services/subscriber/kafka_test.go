@@ -0,0 +1,95 @@
+package subscriber
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/models"
+)
+
+func TestKafka_WritePoints(t *testing.T) {
+	k, err := NewKafka("localhost:9092", "metrics", 2, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var producer SyncProducer
+	k.newProducer = func() (sarama.SyncProducer, error) {
+		return &producer, nil
+	}
+
+	points, err := models.ParsePointsString("cpu value=1 1\ncpu value=2 2\ncpu value=3 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.WritePoints(&coordinator.WritePointsRequest{Database: "db0", RetentionPolicy: "rp0", Points: points}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The points are written in messages of at most 2 points.
+	exp := []string{"cpu value=1 1\ncpu value=2 2\n", "cpu value=3 3\n"}
+	if len(producer.messages) != len(exp) {
+		t.Fatalf("unexpected number of messages: %d", len(producer.messages))
+	}
+	for i, msg := range producer.messages {
+		b, _ := msg.Value.Encode()
+		if msg.Topic != "metrics" {
+			t.Fatalf("unexpected topic: %s", msg.Topic)
+		} else if string(b) != exp[i] {
+			t.Fatalf("unexpected message %d: %q", i, b)
+		}
+	}
+
+	if got := k.stats.messagesWritten; got != 2 {
+		t.Fatalf("unexpected messages written: %d", got)
+	} else if got := k.stats.bytesWritten; got != int64(len(exp[0])+len(exp[1])) {
+		t.Fatalf("unexpected bytes written: %d", got)
+	}
+
+	// Failed writes aren't counted.
+	producer.err = errors.New("marker")
+	if err := k.WritePoints(&coordinator.WritePointsRequest{Points: points}); err != producer.err {
+		t.Fatalf("unexpected error: %v", err)
+	} else if got := k.stats.messagesWritten; got != 2 {
+		t.Fatalf("unexpected messages written: %d", got)
+	}
+
+	if err := k.Close(); err != nil {
+		t.Fatal(err)
+	} else if !producer.closed {
+		t.Fatal("expected producer to be closed")
+	}
+}
+
+func TestKafka_TopicRequired(t *testing.T) {
+	if _, err := NewKafka("localhost:9092", "", 1, time.Second); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// SyncProducer is a mock implementation of sarama.SyncProducer.
+type SyncProducer struct {
+	messages []*sarama.ProducerMessage
+	err      error
+	closed   bool
+}
+
+func (p *SyncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	return 0, 0, p.SendMessages([]*sarama.ProducerMessage{msg})
+}
+
+func (p *SyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, msgs...)
+	return nil
+}
+
+func (p *SyncProducer) Close() error {
+	p.closed = true
+	return nil
+}
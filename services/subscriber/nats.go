@@ -0,0 +1,119 @@
+package subscriber
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/coordinator"
+	gonats "github.com/nats-io/go-nats"
+)
+
+// natsConn is the subset of a NATS connection used to publish points.
+type natsConn interface {
+	Publish(subject string, data []byte) error
+	FlushTimeout(timeout time.Duration) error
+	Close()
+}
+
+// NATS supports publishing points to a NATS subject using the line protocol.
+// Each message holds up to batchSize points.
+type NATS struct {
+	url       string
+	subject   string
+	batchSize int
+	timeout   time.Duration
+
+	mu      sync.Mutex
+	conn    natsConn
+	connect func() (natsConn, error)
+
+	stats messageStats
+}
+
+// NewNATS returns a new NATS points writer for the subject of the server at
+// url.  It connects to the server when points are first written.
+func NewNATS(url, subject string, batchSize int, timeout time.Duration) (*NATS, error) {
+	if subject == "" {
+		return nil, errors.New("nats subject required")
+	}
+
+	n := &NATS{
+		url:       url,
+		subject:   subject,
+		batchSize: batchSize,
+		timeout:   timeout,
+	}
+	n.connect = func() (natsConn, error) {
+		return gonats.Connect(n.url,
+			gonats.Name("influxdb"),
+			gonats.Timeout(n.timeout),
+			gonats.MaxReconnects(-1),
+		)
+	}
+	return n, nil
+}
+
+// WritePoints publishes points to the NATS subject and waits for the server
+// to receive them.
+func (n *NATS) WritePoints(p *coordinator.WritePointsRequest) error {
+	conn, err := n.getConn()
+	if err != nil {
+		return err
+	}
+
+	batches := encodeBatches(p.Points, n.batchSize)
+	for _, b := range batches {
+		if err = conn.Publish(n.subject, b); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = conn.FlushTimeout(n.timeout)
+	}
+
+	if err == gonats.ErrConnectionClosed {
+		// Connect again on the next write.
+		n.mu.Lock()
+		if n.conn == conn {
+			n.conn = nil
+		}
+		n.mu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+	n.stats.add(batches)
+	return nil
+}
+
+// getConn returns the connection, connecting to the server if needed.
+func (n *NATS) getConn() (natsConn, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn == nil {
+		conn, err := n.connect()
+		if err != nil {
+			return nil, err
+		}
+		n.conn = conn
+	}
+	return n.conn, nil
+}
+
+// Close closes the connection to the NATS server.
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	return nil
+}
+
+func (n *NATS) messageStatistics() *messageStats {
+	return &n.stats
+}
@@ -0,0 +1,211 @@
+package subscriber
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/diskqueue"
+	"github.com/uber-go/zap"
+)
+
+// queueStats are the statistics of a destination's queue.
+type queueStats struct {
+	pointsDelivered int64
+	writesDropped   int64
+	bytesDropped    int64
+	retries         int64
+}
+
+// queueWriter is a PointsWriter that queues writes on disk and replays them
+// to a destination, retrying with backoff while the destination is down.
+type queueWriter struct {
+	pw         PointsWriter
+	dest       string
+	queue      *diskqueue.Queue
+	dropOldest bool
+
+	retryInterval    time.Duration
+	retryMaxInterval time.Duration
+
+	notify  chan struct{}
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	stats  queueStats
+	logger zap.Logger
+}
+
+// newQueueWriter opens the queue in dir and starts replaying it to pw.
+func newQueueWriter(pw PointsWriter, dest, dir string, c Config, logger zap.Logger) (*queueWriter, error) {
+	q := &queueWriter{
+		pw:               pw,
+		dest:             dest,
+		queue:            diskqueue.New(dir, int64(c.MaxQueueSize), int64(c.MaxSegmentSize)),
+		dropOldest:       c.DropPolicy == DropOldest,
+		retryInterval:    time.Duration(c.RetryInterval),
+		retryMaxInterval: time.Duration(c.RetryMaxInterval),
+		notify:           make(chan struct{}, 1),
+		closing:          make(chan struct{}),
+		logger:           logger,
+	}
+	if err := q.queue.Open(); err != nil {
+		return nil, fmt.Errorf("open queue for %s: %s", dest, err)
+	}
+
+	q.wg.Add(1)
+	go q.replay()
+	return q, nil
+}
+
+// WritePoints queues the write for the destination. An error is returned only
+// if the write could not be queued.
+func (q *queueWriter) WritePoints(p *coordinator.WritePointsRequest) error {
+	dropped, err := q.queue.Append(encodeRequest(p), q.dropOldest)
+	if dropped > 0 {
+		atomic.AddInt64(&q.stats.bytesDropped, dropped)
+	}
+	if err != nil {
+		atomic.AddInt64(&q.stats.writesDropped, 1)
+		return fmt.Errorf("failed to queue write for %s: %s", q.dest, err)
+	}
+
+	// Wake the replay goroutine if it is waiting for writes.
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Close stops replaying and closes the queue and the destination. Queued
+// writes are replayed once the queue is reopened.
+func (q *queueWriter) Close() error {
+	close(q.closing)
+	q.wg.Wait()
+
+	var lastErr error
+	if c, ok := q.pw.(io.Closer); ok {
+		lastErr = c.Close()
+	}
+	if err := q.queue.Close(); err != nil {
+		lastErr = err
+	}
+	return lastErr
+}
+
+// replay writes queued entries to the destination until the writer is closed.
+func (q *queueWriter) replay() {
+	defer q.wg.Done()
+
+	backoff := q.retryInterval
+	for {
+		b, err := q.queue.Current()
+		if err == io.EOF {
+			select {
+			case <-q.closing:
+				return
+			case <-q.notify:
+			}
+			continue
+		} else if _, ok := err.(*diskqueue.CorruptSegmentError); ok {
+			// The corrupt segment was quarantined, so the next entry can be read.
+			q.logger.Info(fmt.Sprintf("skipping corrupt queue segment for %s: %s", q.dest, err))
+			continue
+		} else if err != nil {
+			q.logger.Info(fmt.Sprintf("failed to read queue for %s: %s", q.dest, err))
+		} else if p, err := decodeRequest(b); err != nil {
+			// Retrying an entry that cannot be decoded would block the queue forever.
+			atomic.AddInt64(&q.stats.bytesDropped, int64(len(b)))
+			q.logger.Info(fmt.Sprintf("dropping queued write for %s: %s", q.dest, err))
+			if err := q.queue.Advance(); err != nil {
+				q.logger.Info(fmt.Sprintf("failed to advance queue for %s: %s", q.dest, err))
+			}
+			continue
+		} else if err = q.pw.WritePoints(p); err == nil {
+			atomic.AddInt64(&q.stats.pointsDelivered, int64(len(p.Points)))
+			backoff = q.retryInterval
+			if err := q.queue.Advance(); err != nil {
+				q.logger.Info(fmt.Sprintf("failed to advance queue for %s: %s", q.dest, err))
+			}
+			continue
+		} else {
+			atomic.AddInt64(&q.stats.retries, 1)
+			q.logger.Info(fmt.Sprintf("failed to write to %s, retrying in %s: %s", q.dest, backoff, err))
+		}
+
+		select {
+		case <-q.closing:
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > q.retryMaxInterval {
+			backoff = q.retryMaxInterval
+		}
+	}
+}
+
+// queueDirName returns the name of the queue directory for a destination.
+func queueDirName(u url.URL) string {
+	r := strings.NewReplacer(":", "_", "/", "_", "\\", "_")
+	return r.Replace(u.Scheme + "_" + u.Host + strings.TrimSuffix(u.Path, "/"))
+}
+
+// encodeRequest encodes a write as the database and retention policy, each
+// prefixed by their length, followed by the points in line protocol.
+func encodeRequest(p *coordinator.WritePointsRequest) []byte {
+	var buf bytes.Buffer
+	var n [binary.MaxVarintLen64]byte
+
+	buf.Write(n[:binary.PutUvarint(n[:], uint64(len(p.Database)))])
+	buf.WriteString(p.Database)
+	buf.Write(n[:binary.PutUvarint(n[:], uint64(len(p.RetentionPolicy)))])
+	buf.WriteString(p.RetentionPolicy)
+	for _, pt := range p.Points {
+		buf.WriteString(pt.String())
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// decodeRequest decodes a write encoded by encodeRequest.
+func decodeRequest(b []byte) (*coordinator.WritePointsRequest, error) {
+	readString := func() (string, error) {
+		n, sz := binary.Uvarint(b)
+		if sz <= 0 || uint64(len(b)-sz) < n {
+			return "", errors.New("corrupt subscriber queue entry")
+		}
+		v := string(b[sz : sz+int(n)])
+		b = b[sz+int(n):]
+		return v, nil
+	}
+
+	database, err := readString()
+	if err != nil {
+		return nil, err
+	}
+	retentionPolicy, err := readString()
+	if err != nil {
+		return nil, err
+	}
+	points, err := models.ParsePoints(b)
+	if err != nil {
+		return nil, err
+	}
+	return &coordinator.WritePointsRequest{
+		Database:        database,
+		RetentionPolicy: retentionPolicy,
+		Points:          points,
+	}, nil
+}
@@ -0,0 +1,173 @@
+package subscriber
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/uber-go/zap"
+)
+
+func TestQueueWriter_Replay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "subscriber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := NewConfig()
+	c.RetryInterval = toml.Duration(time.Millisecond)
+	c.RetryMaxInterval = toml.Duration(time.Millisecond)
+
+	// The destination is down when the write is first queued.
+	dest := NewDestination()
+	dest.fail(true)
+	q, err := newQueueWriter(dest, "udp://localhost:8089", dir, c, zap.New(zap.NullEncoder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := models.ParsePointsString("cpu value=1 1\ncpu value=2 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.WritePoints(&coordinator.WritePointsRequest{Database: "db0", RetentionPolicy: "rp0", Points: points}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for a failed write, then close the writer with the write queued.
+	<-dest.attempts
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	} else if atomic.LoadInt64(&q.stats.retries) == 0 {
+		t.Fatal("expected retries")
+	} else if atomic.LoadInt64(&q.stats.pointsDelivered) != 0 {
+		t.Fatal("unexpected points delivered")
+	}
+
+	// The queued write is replayed when the queue is reopened and the
+	// destination recovers.
+	dest = NewDestination()
+	q, err = newQueueWriter(dest, "udp://localhost:8089", dir, c, zap.New(zap.NullEncoder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	select {
+	case p := <-dest.written:
+		if p.Database != "db0" || p.RetentionPolicy != "rp0" {
+			t.Fatalf("unexpected database and retention policy: %s %s", p.Database, p.RetentionPolicy)
+		} else if len(p.Points) != 2 || p.Points[1].String() != "cpu value=2 2" {
+			t.Fatalf("unexpected points: %v", p.Points)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for replay")
+	}
+
+	if got := atomic.LoadInt64(&q.stats.pointsDelivered); got != 2 {
+		t.Fatalf("unexpected points delivered: %d", got)
+	}
+}
+
+func TestQueueWriter_DropNewest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "subscriber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := NewConfig()
+	c.MaxQueueSize = 64
+	c.MaxSegmentSize = 64
+	c.DropPolicy = DropNewest
+
+	dest := NewDestination()
+	dest.fail(true)
+	q, err := newQueueWriter(dest, "udp://localhost:8089", dir, c, zap.New(zap.NullEncoder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	points, err := models.ParsePointsString("cpu value=1 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each entry is 23 bytes, so the third write does not fit.
+	for i := 0; i < 2; i++ {
+		if err := q.WritePoints(&coordinator.WritePointsRequest{Database: "db0", Points: points}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.WritePoints(&coordinator.WritePointsRequest{Database: "db0", Points: points}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if got := atomic.LoadInt64(&q.stats.writesDropped); got != 1 {
+		t.Fatalf("unexpected writes dropped: %d", got)
+	} else if got := q.queue.DiskSize(); got != 46 {
+		t.Fatalf("unexpected queue size: %d", got)
+	}
+}
+
+func TestEncodeRequest(t *testing.T) {
+	points, err := models.ParsePointsString("cpu,host=a value=1 1\nmem free=2i 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := decodeRequest(encodeRequest(&coordinator.WritePointsRequest{Database: "db0", Points: points}))
+	if err != nil {
+		t.Fatal(err)
+	} else if p.Database != "db0" || p.RetentionPolicy != "" {
+		t.Fatalf("unexpected database and retention policy: %s %s", p.Database, p.RetentionPolicy)
+	} else if len(p.Points) != 2 || p.Points[0].String() != points[0].String() || p.Points[1].String() != points[1].String() {
+		t.Fatalf("unexpected points: %v", p.Points)
+	}
+
+	if _, err := decodeRequest([]byte{10, 'd'}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// Destination is a PointsWriter that records writes and can be made to fail.
+type Destination struct {
+	failing  int32
+	attempts chan struct{}
+	written  chan *coordinator.WritePointsRequest
+}
+
+func NewDestination() *Destination {
+	return &Destination{
+		attempts: make(chan struct{}, 100),
+		written:  make(chan *coordinator.WritePointsRequest, 100),
+	}
+}
+
+func (d *Destination) fail(v bool) {
+	var i int32
+	if v {
+		i = 1
+	}
+	atomic.StoreInt32(&d.failing, i)
+}
+
+func (d *Destination) WritePoints(p *coordinator.WritePointsRequest) error {
+	select {
+	case d.attempts <- struct{}{}:
+	default:
+	}
+	if atomic.LoadInt32(&d.failing) == 1 {
+		return errors.New("destination down")
+	}
+	d.written <- p
+	return nil
+}
@@ -5,7 +5,11 @@ package subscriber // import "github.com/influxdata/influxdb/services/subscriber
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -19,9 +23,18 @@ import (
 
 // Statistics for the Subscriber service.
 const (
-	statCreateFailures = "createFailures"
-	statPointsWritten  = "pointsWritten"
-	statWriteFailures  = "writeFailures"
+	statCreateFailures  = "createFailures"
+	statPointsWritten   = "pointsWritten"
+	statWriteFailures   = "writeFailures"
+	statPointsFiltered  = "pointsFiltered"
+	statMessagesWritten = "messagesWritten"
+	statBytesWritten    = "bytesWritten"
+
+	statPointsDelivered    = "pointsDelivered"
+	statQueueBytes         = "queueBytes"
+	statQueueWritesDropped = "queueWritesDropped"
+	statQueueBytesDropped  = "queueBytesDropped"
+	statQueueRetries       = "queueRetries"
 )
 
 // PointsWriter is an interface for writing points to a subscription destination.
@@ -49,6 +62,7 @@ type Service struct {
 	NewPointsWriter func(u url.URL) (PointsWriter, error)
 	Logger          zap.Logger
 	update          chan struct{}
+	reset           chan Config
 	stats           *Statistics
 	points          chan *coordinator.WritePointsRequest
 	wg              sync.WaitGroup
@@ -89,6 +103,7 @@ func (s *Service) Open() error {
 
 	s.closing = make(chan struct{})
 	s.update = make(chan struct{})
+	s.reset = make(chan Config)
 	s.points = make(chan *coordinator.WritePointsRequest, 100)
 
 	s.wg.Add(2)
@@ -135,6 +150,7 @@ type Statistics struct {
 	CreateFailures int64
 	PointsWritten  int64
 	WriteFailures  int64
+	PointsFiltered int64
 }
 
 // Statistics returns statistics for periodic monitoring.
@@ -146,6 +162,7 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 			statCreateFailures: atomic.LoadInt64(&s.stats.CreateFailures),
 			statPointsWritten:  atomic.LoadInt64(&s.stats.PointsWritten),
 			statWriteFailures:  atomic.LoadInt64(&s.stats.WriteFailures),
+			statPointsFiltered: atomic.LoadInt64(&s.stats.PointsFiltered),
 		},
 	}}
 
@@ -184,6 +201,37 @@ func (s *Service) Update() error {
 	}
 }
 
+// SetConfig changes the settings of the writes to the destinations of the
+// subscriptions, reconnecting the subscriptions to their destinations.  The
+// other settings, such as those of the queues, require a restart.
+func (s *Service) SetConfig(c Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		s.setConfig(c)
+		return nil
+	}
+
+	select {
+	case s.reset <- c:
+		return nil
+	case <-s.closing:
+		return errors.New("service closed cannot update")
+	}
+}
+
+// setConfig copies the settings of the writes to the destinations of c.
+func (s *Service) setConfig(c Config) {
+	s.conf.HTTPTimeout = c.HTTPTimeout
+	s.conf.InsecureSkipVerify = c.InsecureSkipVerify
+	s.conf.CaCerts = c.CaCerts
+	s.conf.WriteConcurrency = c.WriteConcurrency
+	s.conf.WriteBufferSize = c.WriteBufferSize
+	s.conf.BatchSize = c.BatchSize
+	s.conf.WriteTimeout = c.WriteTimeout
+}
+
 func (s *Service) createSubscription(se subEntry, mode string, destinations []string) (PointsWriter, error) {
 	var bm BalanceMode
 	switch mode {
@@ -204,8 +252,18 @@ func (s *Service) createSubscription(se subEntry, mode string, destinations []st
 		}
 		w, err := s.NewPointsWriter(*u)
 		if err != nil {
+			closeWriters(writers)
 			return nil, fmt.Errorf("failed to create writer for destination: %s", dest)
 		}
+		if s.conf.QueueEnabled {
+			dir := filepath.Join(s.queueDir(se), queueDirName(*u))
+			qw, err := newQueueWriter(w, dest, dir, s.conf, s.Logger)
+			if err != nil {
+				closeWriters(append(writers, w))
+				return nil, err
+			}
+			w = qw
+		}
 		writers = append(writers, w)
 		stats = append(stats, writerStats{dest: dest})
 	}
@@ -223,6 +281,11 @@ func (s *Service) createSubscription(se subEntry, mode string, destinations []st
 	}, nil
 }
 
+// queueDir returns the directory of the queues of a subscription.
+func (s *Service) queueDir(se subEntry) string {
+	return filepath.Join(s.conf.QueueDir, se.db, se.rp, se.name)
+}
+
 // Points returns a channel into which write point requests can be sent.
 func (s *Service) Points() chan<- *coordinator.WritePointsRequest {
 	return s.points
@@ -238,6 +301,12 @@ func (s *Service) run() {
 		select {
 		case <-s.update:
 			s.updateSubs(&wg)
+		case c := <-s.reset:
+			// Reconnect the subscriptions with the new settings.  Their
+			// queues are kept.
+			s.close(&wg)
+			s.setConfig(c)
+			s.updateSubs(&wg)
 		case p, ok := <-s.points:
 			if !ok {
 				// Close out all chanWriters
@@ -246,8 +315,20 @@ func (s *Service) run() {
 			}
 			for se, cw := range s.subs {
 				if p.Database == se.db && p.RetentionPolicy == se.rp {
+					wr := p
+					if cw.filter != nil {
+						wr = filterRequest(p, cw.filter)
+						n := len(p.Points)
+						if wr != nil {
+							n -= len(wr.Points)
+						}
+						atomic.AddInt64(&s.stats.PointsFiltered, int64(n))
+						if wr == nil {
+							continue
+						}
+					}
 					select {
-					case cw.writeRequests <- p:
+					case cw.writeRequests <- wr:
 					default:
 						atomic.AddInt64(&s.stats.WriteFailures, 1)
 					}
@@ -293,6 +374,16 @@ func (s *Service) updateSubs(wg *sync.WaitGroup) {
 				if _, ok := s.subs[se]; ok {
 					continue
 				}
+				var filter pointFilter
+				if si.Filter != "" {
+					f, err := newPointFilter(si.Filter)
+					if err != nil {
+						atomic.AddInt64(&s.stats.CreateFailures, 1)
+						s.Logger.Info(fmt.Sprintf("Subscription creation failed for '%s' with error: %s", si.Name, err))
+						continue
+					}
+					filter = f
+				}
 				sub, err := s.createSubscription(se, si.Mode, si.Destinations)
 				if err != nil {
 					atomic.AddInt64(&s.stats.CreateFailures, 1)
@@ -302,17 +393,33 @@ func (s *Service) updateSubs(wg *sync.WaitGroup) {
 				cw := chanWriter{
 					writeRequests: make(chan *coordinator.WritePointsRequest, s.conf.WriteBufferSize),
 					pw:            sub,
+					filter:        filter,
 					pointsWritten: &s.stats.PointsWritten,
 					failures:      &s.stats.WriteFailures,
 					logger:        s.Logger,
+					removed:       new(int32),
 				}
+				if s.conf.QueueEnabled {
+					cw.queueDir = s.queueDir(se)
+				}
+				var cwg sync.WaitGroup
 				for i := 0; i < s.conf.WriteConcurrency; i++ {
 					wg.Add(1)
+					cwg.Add(1)
 					go func() {
 						defer wg.Done()
+						defer cwg.Done()
 						cw.Run()
 					}()
 				}
+				// Close the connections to the destinations once the
+				// subscription is closed and its writes are done.
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					cwg.Wait()
+					cw.closeWriter()
+				}()
 				s.subs[se] = cw
 				s.Logger.Info(fmt.Sprintf("added new subscription for %s %s", se.db, se.rp))
 			}
@@ -322,7 +429,8 @@ func (s *Service) updateSubs(wg *sync.WaitGroup) {
 	// Remove deleted subs
 	for se := range s.subs {
 		if !allEntries[se] {
-			// Close the chanWriter
+			// Close the chanWriter and discard its queues
+			atomic.StoreInt32(s.subs[se].removed, 1)
 			s.subs[se].Close()
 
 			// Remove it from the set
@@ -344,18 +452,89 @@ func (s *Service) newPointsWriter(u url.URL) (PointsWriter, error) {
 			s.Logger.Info("WARNING: 'insecure-skip-verify' is true. This will skip all certificate verifications.")
 		}
 		return NewHTTPS(u.String(), time.Duration(s.conf.HTTPTimeout), s.conf.InsecureSkipVerify, s.conf.CaCerts)
+	case "kafka":
+		return NewKafka(u.Host, strings.Trim(u.Path, "/"), s.batchSize(), s.writeTimeout())
+	case "nats":
+		server := url.URL{Scheme: "nats", User: u.User, Host: u.Host}
+		return NewNATS(server.String(), strings.Trim(u.Path, "/"), s.batchSize(), s.writeTimeout())
 	default:
 		return nil, fmt.Errorf("unknown destination scheme %s", u.Scheme)
 	}
 }
 
+// batchSize returns the maximum number of points in a Kafka or NATS message.
+func (s *Service) batchSize() int {
+	if s.conf.BatchSize == 0 {
+		return DefaultBatchSize
+	}
+	return s.conf.BatchSize
+}
+
+// writeTimeout returns the timeout of writes to Kafka and NATS.
+func (s *Service) writeTimeout() time.Duration {
+	if s.conf.WriteTimeout == 0 {
+		return DefaultWriteTimeout
+	}
+	return time.Duration(s.conf.WriteTimeout)
+}
+
+// encodeBatches returns the line protocol of points in batches of at most
+// batchSize points, one for each message written to a destination.
+func encodeBatches(points []models.Point, batchSize int) [][]byte {
+	var batches [][]byte
+	for len(points) > 0 {
+		n := batchSize
+		if n <= 0 || n > len(points) {
+			n = len(points)
+		}
+
+		var b []byte
+		for _, p := range points[:n] {
+			b = p.AppendString(b)
+			b = append(b, '\n')
+		}
+		batches = append(batches, b)
+		points = points[n:]
+	}
+	return batches
+}
+
+// messageStats are the statistics of a destination that writes points in
+// messages.
+type messageStats struct {
+	messagesWritten int64
+	bytesWritten    int64
+}
+
+// add adds the written messages to the statistics.
+func (s *messageStats) add(messages [][]byte) {
+	var n int
+	for _, m := range messages {
+		n += len(m)
+	}
+	atomic.AddInt64(&s.messagesWritten, int64(len(messages)))
+	atomic.AddInt64(&s.bytesWritten, int64(n))
+}
+
+// messageWriter is a PointsWriter that writes points in messages.
+type messageWriter interface {
+	PointsWriter
+	messageStatistics() *messageStats
+}
+
 // chanWriter sends WritePointsRequest to a PointsWriter received over a channel.
 type chanWriter struct {
 	writeRequests chan *coordinator.WritePointsRequest
 	pw            PointsWriter
+	filter        pointFilter
 	pointsWritten *int64
 	failures      *int64
 	logger        zap.Logger
+
+	// queueDir holds the queues of the subscription's destinations, which
+	// are removed once the writer is closed if removed is set.
+	queueDir string
+	removed  *int32
 }
 
 // Close closes the chanWriter.
@@ -375,6 +554,21 @@ func (c chanWriter) Run() {
 	}
 }
 
+// closeWriter closes the connections of the PointsWriter, and removes the
+// queues of a deleted subscription.
+func (c chanWriter) closeWriter() {
+	if w, ok := c.pw.(io.Closer); ok {
+		if err := w.Close(); err != nil {
+			c.logger.Info(err.Error())
+		}
+	}
+	if c.queueDir != "" && atomic.LoadInt32(c.removed) == 1 {
+		if err := os.RemoveAll(c.queueDir); err != nil {
+			c.logger.Info(fmt.Sprintf("failed to remove subscription queue %s: %s", c.queueDir, err))
+		}
+	}
+}
+
 // Statistics returns statistics for periodic monitoring.
 func (c chanWriter) Statistics(tags map[string]string) []models.Statistic {
 	if m, ok := c.pw.(monitor.Reporter); ok {
@@ -432,6 +626,24 @@ func (b *balancewriter) WritePoints(p *coordinator.WritePointsRequest) error {
 	return lastErr
 }
 
+// Close closes the connections of the PointsWriters.
+func (b *balancewriter) Close() error {
+	return closeWriters(b.writers)
+}
+
+// closeWriters closes each PointsWriter that is an io.Closer.
+func closeWriters(writers []PointsWriter) error {
+	var lastErr error
+	for _, w := range writers {
+		if c, ok := w.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				lastErr = err
+			}
+		}
+	}
+	return lastErr
+}
+
 // Statistics returns statistics for periodic monitoring.
 func (b *balancewriter) Statistics(tags map[string]string) []models.Statistic {
 	statistics := make([]models.Statistic, len(b.stats))
@@ -446,6 +658,21 @@ func (b *balancewriter) Statistics(tags map[string]string) []models.Statistic {
 				statWriteFailures: atomic.LoadInt64(&b.stats[i].failures),
 			},
 		}
+
+		w := b.writers[i]
+		if q, ok := w.(*queueWriter); ok {
+			statistics[i].Values[statPointsDelivered] = atomic.LoadInt64(&q.stats.pointsDelivered)
+			statistics[i].Values[statQueueBytes] = q.queue.DiskSize()
+			statistics[i].Values[statQueueWritesDropped] = atomic.LoadInt64(&q.stats.writesDropped)
+			statistics[i].Values[statQueueBytesDropped] = atomic.LoadInt64(&q.stats.bytesDropped)
+			statistics[i].Values[statQueueRetries] = atomic.LoadInt64(&q.stats.retries)
+			w = q.pw
+		}
+		if w, ok := w.(messageWriter); ok {
+			stats := w.messageStatistics()
+			statistics[i].Values[statMessagesWritten] = atomic.LoadInt64(&stats.messagesWritten)
+			statistics[i].Values[statBytesWritten] = atomic.LoadInt64(&stats.bytesWritten)
+		}
 	}
 	return statistics
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/services/subscriber"
 )
@@ -441,3 +442,95 @@ func TestService_WaitForDataChanged(t *testing.T) {
 
 	close(dataChanged)
 }
+
+func TestService_Filter(t *testing.T) {
+	dataChanged := make(chan struct{})
+	ms := MetaClient{}
+	ms.WaitForDataChangedFn = func() chan struct{} {
+		return dataChanged
+	}
+	ms.DatabasesFn = func() []meta.DatabaseInfo {
+		return []meta.DatabaseInfo{
+			{
+				Name: "db0",
+				RetentionPolicies: []meta.RetentionPolicyInfo{
+					{
+						Name: "rp0",
+						Subscriptions: []meta.SubscriptionInfo{
+							{
+								Name:         "s0",
+								Mode:         "ALL",
+								Destinations: []string{"udp://h0:9093"},
+								Filter:       `"measurement" =~ /^api_/ AND (region = 'us-east' OR host != '')`,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	prs := make(chan *coordinator.WritePointsRequest, 2)
+	newPointsWriter := func(u url.URL) (subscriber.PointsWriter, error) {
+		sub := Subscription{}
+		sub.WritePointsFn = func(p *coordinator.WritePointsRequest) error {
+			prs <- p
+			return nil
+		}
+		return sub, nil
+	}
+
+	s := subscriber.NewService(subscriber.NewConfig())
+	s.MetaClient = ms
+	s.NewPointsWriter = newPointsWriter
+	s.Open()
+	defer s.Close()
+
+	// Signal that data has changed
+	dataChanged <- struct{}{}
+
+	// Write points that don't match the filter.
+	s.Points() <- &coordinator.WritePointsRequest{
+		Database:        "db0",
+		RetentionPolicy: "rp0",
+		Points: []models.Point{
+			models.MustNewPoint("cpu", models.NewTags(map[string]string{"region": "us-east"}), models.Fields{"value": 1.0}, time.Unix(0, 0)),
+			models.MustNewPoint("api_requests", models.NewTags(map[string]string{"region": "us-west"}), models.Fields{"value": 1.0}, time.Unix(0, 0)),
+		},
+	}
+
+	// Write points of which some match the filter.
+	points := []models.Point{
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"region": "us-east"}), models.Fields{"value": 1.0}, time.Unix(0, 0)),
+		models.MustNewPoint("api_requests", models.NewTags(map[string]string{"region": "us-east"}), models.Fields{"value": 1.0}, time.Unix(0, 0)),
+		models.MustNewPoint("api_errors", models.NewTags(map[string]string{"host": "server01"}), models.Fields{"value": 1.0}, time.Unix(0, 0)),
+	}
+	s.Points() <- &coordinator.WritePointsRequest{
+		Database:        "db0",
+		RetentionPolicy: "rp0",
+		Points:          points,
+	}
+
+	var pr *coordinator.WritePointsRequest
+	select {
+	case pr = <-prs:
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("expected points request")
+	}
+	if got, exp := len(pr.Points), 2; got != exp {
+		t.Fatalf("unexpected number of points: got %d, exp %d", got, exp)
+	}
+	for i, p := range pr.Points {
+		if p != points[i+1] {
+			t.Fatalf("unexpected point: got %s, exp %s", p, points[i+1])
+		}
+	}
+
+	// Shouldn't get any other prs back
+	select {
+	case pr := <-prs:
+		t.Fatalf("unexpected points request %v", pr)
+	default:
+	}
+	close(dataChanged)
+}
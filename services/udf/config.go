@@ -0,0 +1,111 @@
+package udf
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	// ScalarFunction is the type of a function applied to each point.
+	ScalarFunction = "scalar"
+
+	// AggregateFunction is the type of a function that reduces the points of
+	// each interval.
+	AggregateFunction = "aggregate"
+
+	// DefaultProcessTimeout is the default time an external process has to
+	// respond to a call.
+	DefaultProcessTimeout = 10 * time.Second
+
+	// DefaultProcessPoolSize is the default number of processes started for
+	// a function to handle concurrent calls.
+	DefaultProcessPoolSize = 4
+)
+
+// Config represents the configuration of user-defined functions.
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// Plugins are the paths of Go plugins that export the functions they
+	// provide in a Functions variable.
+	Plugins []string `toml:"plugins"`
+
+	// Processes are functions implemented by external processes.
+	Processes []ProcessConfig `toml:"process"`
+}
+
+// ProcessConfig represents the configuration of a function implemented by an
+// external process.
+type ProcessConfig struct {
+	// Name is the name the function is called by in queries.
+	Name string `toml:"name"`
+
+	// Type is either "scalar" or "aggregate".
+	Type string `toml:"type"`
+
+	// Command is the program and arguments of the process.
+	Command []string `toml:"command"`
+
+	// Timeout is the time the process has to respond to a call.
+	Timeout toml.Duration `toml:"timeout"`
+
+	// PoolSize is the number of processes started to handle concurrent
+	// calls. Processes are only started when all the others are busy.
+	PoolSize int `toml:"pool-size"`
+}
+
+// NewConfig returns a new Config with defaults.
+func NewConfig() Config {
+	return Config{Enabled: false}
+}
+
+// Validate returns an error if the Config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	names := make(map[string]struct{}, len(c.Processes))
+	for _, p := range c.Processes {
+		if p.Name == "" {
+			return errors.New("udf process name must be specified")
+		} else if _, ok := names[p.Name]; ok {
+			return fmt.Errorf("duplicate udf process name: %s", p.Name)
+		}
+		names[p.Name] = struct{}{}
+
+		switch p.Type {
+		case ScalarFunction, AggregateFunction:
+		default:
+			return fmt.Errorf("unknown type of udf process %s: %q", p.Name, p.Type)
+		}
+
+		if len(p.Command) == 0 {
+			return fmt.Errorf("udf process %s must have a command", p.Name)
+		} else if p.Timeout < 0 {
+			return fmt.Errorf("timeout of udf process %s must not be negative", p.Name)
+		} else if p.PoolSize < 0 {
+			return fmt.Errorf("pool size of udf process %s must not be negative", p.Name)
+		}
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":   true,
+		"plugins":   len(c.Plugins),
+		"processes": len(c.Processes),
+	}), nil
+}
@@ -0,0 +1,95 @@
+package udf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/udf"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	c := udf.NewConfig()
+	if _, err := toml.Decode(`
+enabled = true
+plugins = ["/usr/lib/influxdb/geo.so"]
+
+[[process]]
+name = "fahrenheit"
+type = "scalar"
+command = ["/usr/local/bin/convert", "--to", "F"]
+timeout = "2s"
+pool-size = 2
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.Enabled {
+		t.Fatalf("unexpected enabled state: %v", c.Enabled)
+	} else if len(c.Plugins) != 1 || c.Plugins[0] != "/usr/lib/influxdb/geo.so" {
+		t.Fatalf("unexpected plugins: %v", c.Plugins)
+	} else if len(c.Processes) != 1 {
+		t.Fatalf("unexpected processes: %v", c.Processes)
+	} else if p := c.Processes[0]; p.Name != "fahrenheit" || p.Type != udf.ScalarFunction {
+		t.Fatalf("unexpected process: %v", p)
+	} else if len(p.Command) != 3 {
+		t.Fatalf("unexpected command: %v", p.Command)
+	} else if time.Duration(p.Timeout) != 2*time.Second {
+		t.Fatalf("unexpected timeout: %s", p.Timeout)
+	} else if p.PoolSize != 2 {
+		t.Fatalf("unexpected pool size: %d", p.PoolSize)
+	} else if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail: %s", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := udf.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail from NewConfig: %s", err)
+	}
+
+	valid := func() udf.Config {
+		c := udf.NewConfig()
+		c.Enabled = true
+		c.Processes = []udf.ProcessConfig{{Name: "f", Type: udf.AggregateFunction, Command: []string{"f"}}}
+		return c
+	}
+
+	c = valid()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail: %s", err)
+	}
+
+	c = valid()
+	c.Processes[0].Name = ""
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for empty name, got nil")
+	}
+
+	c = valid()
+	c.Processes = append(c.Processes, c.Processes[0])
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for duplicate name, got nil")
+	}
+
+	c = valid()
+	c.Processes[0].Type = "window"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for unknown type, got nil")
+	}
+
+	c = valid()
+	c.Processes[0].Command = nil
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for empty command, got nil")
+	}
+
+	c = valid()
+	c.Processes[0].PoolSize = -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for negative pool size, got nil")
+	}
+}
@@ -0,0 +1,200 @@
+package udf
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// processRequest is a call to a function written to an external process as
+// a line of JSON.
+type processRequest struct {
+	Function string        `json:"function"`
+	Values   []interface{} `json:"values"`
+	Args     []interface{} `json:"args"`
+}
+
+// processResponse is the result of a call read from an external process as a
+// line of JSON. Scalar functions return a result for each value in Results,
+// and aggregate functions a single Result.
+type processResponse struct {
+	Result  interface{}   `json:"result"`
+	Results []interface{} `json:"results,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// processFunction is a function implemented by a pool of external processes.
+// Each process is started on its first call and restarted if it fails or
+// does not respond in time. Each process handles one call at a time over its
+// stdin and stdout, so concurrent calls are spread over the pool.
+type processFunction struct {
+	name    string
+	command []string
+	timeout time.Duration
+
+	// pool holds the processes that aren't handling a call.
+	pool chan *process
+}
+
+func newProcessFunction(c ProcessConfig) *processFunction {
+	timeout := time.Duration(c.Timeout)
+	if timeout == 0 {
+		timeout = DefaultProcessTimeout
+	}
+	size := c.PoolSize
+	if size == 0 {
+		size = DefaultProcessPoolSize
+	}
+
+	f := &processFunction{
+		name:    c.Name,
+		command: c.Command,
+		timeout: timeout,
+		pool:    make(chan *process, size),
+	}
+	for i := 0; i < size; i++ {
+		f.pool <- &process{}
+	}
+	return f
+}
+
+// Call implements query.ScalarFunction.
+func (f *processFunction) Call(value interface{}, args []interface{}) (interface{}, error) {
+	results, err := f.CallBatch([]interface{}{value}, args)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// CallBatch implements query.BatchScalarFunction.
+func (f *processFunction) CallBatch(values []interface{}, args []interface{}) ([]interface{}, error) {
+	resp, err := f.call(values, args)
+	if err != nil {
+		return nil, err
+	} else if len(resp.Results) != len(values) {
+		return nil, fmt.Errorf("process returned %d results for %d values", len(resp.Results), len(values))
+	}
+	return resp.Results, nil
+}
+
+// Reduce implements query.AggregateFunction.
+func (f *processFunction) Reduce(values []interface{}, args []interface{}) (interface{}, error) {
+	resp, err := f.call(values, args)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+// call sends a request to an idle process of the pool, waiting for one if
+// they are all busy.
+func (f *processFunction) call(values, args []interface{}) (*processResponse, error) {
+	req, err := json.Marshal(processRequest{Function: f.name, Values: values, Args: args})
+	if err != nil {
+		return nil, err
+	}
+	req = append(req, '\n')
+
+	p := <-f.pool
+	defer func() { f.pool <- p }()
+
+	if p.cmd == nil {
+		if err := p.start(f.command); err != nil {
+			return nil, err
+		}
+	}
+
+	var resp processResponse
+	done := make(chan error, 1)
+	go func() {
+		if _, err := p.stdin.Write(req); err != nil {
+			done <- err
+			return
+		}
+		line, err := p.stdout.ReadBytes('\n')
+		if err != nil {
+			done <- err
+			return
+		}
+		done <- json.Unmarshal(line, &resp)
+	}()
+
+	timer := time.NewTimer(f.timeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-done:
+	case <-timer.C:
+		// Stopping the process unblocks the call.
+		p.stop()
+		<-done
+		return nil, errors.New("process timed out")
+	}
+
+	if err != nil {
+		p.stop()
+		return nil, fmt.Errorf("process failed: %s", err)
+	} else if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// Close stops the processes, once their current calls complete.
+func (f *processFunction) Close() error {
+	procs := make([]*process, 0, cap(f.pool))
+	for i := 0; i < cap(f.pool); i++ {
+		p := <-f.pool
+		p.stop()
+		procs = append(procs, p)
+	}
+	for _, p := range procs {
+		f.pool <- p
+	}
+	return nil
+}
+
+// process is an external process of a processFunction. It is only used by
+// the call that took it from the pool.
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// start starts the process.
+func (p *process) start(command []string) error {
+	cmd := exec.Command(command[0], command[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	p.cmd = cmd
+	p.stdin = stdin
+	p.stdout = bufio.NewReader(stdout)
+	return nil
+}
+
+// stop stops the process if it is running.
+func (p *process) stop() {
+	if p.cmd == nil {
+		return
+	}
+	p.stdin.Close()
+	p.cmd.Process.Kill()
+	p.cmd.Wait()
+	p.cmd, p.stdin, p.stdout = nil, nil, nil
+}
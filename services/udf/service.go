@@ -0,0 +1,129 @@
+// Package udf provides the service that registers user-defined functions
+// with the query engine.
+package udf // import "github.com/influxdata/influxdb/services/udf"
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/influxdata/influxdb/query"
+	"github.com/uber-go/zap"
+)
+
+// Service registers the user-defined functions of Go plugins and external
+// processes so they can be called by name in queries.
+type Service struct {
+	config Config
+
+	names     []string
+	processes []*processFunction
+
+	Logger zap.Logger
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		config: c,
+		Logger: zap.New(zap.NullEncoder()),
+	}
+}
+
+// WithLogger sets the logger for the service.
+func (s *Service) WithLogger(log zap.Logger) {
+	s.Logger = log.With(zap.String("service", "udf"))
+}
+
+// Open registers the configured functions.
+func (s *Service) Open() error {
+	for _, path := range s.config.Plugins {
+		fns, err := loadPlugin(path)
+		if err != nil {
+			s.Close()
+			return err
+		}
+		for name, fn := range fns {
+			if err := s.registerPluginFunction(name, fn); err != nil {
+				s.Close()
+				return fmt.Errorf("plugin %s: %s", path, err)
+			}
+		}
+	}
+
+	for _, c := range s.config.Processes {
+		fn := newProcessFunction(c)
+		s.processes = append(s.processes, fn)
+
+		var err error
+		if c.Type == AggregateFunction {
+			err = query.RegisterAggregateFunction(c.Name, fn)
+		} else {
+			err = query.RegisterScalarFunction(c.Name, fn)
+		}
+		if err != nil {
+			s.Close()
+			return err
+		}
+		s.registered(c.Name)
+	}
+	return nil
+}
+
+// registerPluginFunction registers a function exported by a plugin. A
+// function that implements both interfaces is registered as an aggregate.
+func (s *Service) registerPluginFunction(name string, fn interface{}) error {
+	var err error
+	switch fn := fn.(type) {
+	case query.AggregateFunction:
+		err = query.RegisterAggregateFunction(name, fn)
+	case query.ScalarFunction:
+		err = query.RegisterScalarFunction(name, fn)
+	default:
+		err = fmt.Errorf("function %s() must be a query.ScalarFunction or query.AggregateFunction, got %T", name, fn)
+	}
+	if err != nil {
+		return err
+	}
+	s.registered(name)
+	return nil
+}
+
+// registered records a function so it is unregistered when the service closes.
+func (s *Service) registered(name string) {
+	s.names = append(s.names, name)
+	s.Logger.Info(fmt.Sprintf("Registered user-defined function %s()", name))
+}
+
+// Close unregisters the functions and stops any external processes.
+func (s *Service) Close() error {
+	for _, name := range s.names {
+		query.UnregisterFunction(name)
+	}
+	s.names = nil
+
+	for _, fn := range s.processes {
+		fn.Close()
+	}
+	s.processes = nil
+	return nil
+}
+
+// loadPlugin opens a Go plugin and returns the functions it exports. The
+// plugin must export a Functions variable of type map[string]interface{}
+// holding each query.ScalarFunction or query.AggregateFunction by name.
+func loadPlugin(path string) (map[string]interface{}, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open plugin %s: %s", path, err)
+	}
+
+	sym, err := p.Lookup("Functions")
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %s", path, err)
+	}
+	fns, ok := sym.(*map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("plugin %s: Functions must be a map[string]interface{}, got %T", path, sym)
+	}
+	return *fns, nil
+}
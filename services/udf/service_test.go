@@ -0,0 +1,185 @@
+package udf
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/toml"
+)
+
+// TestHelperProcess is run as an external process by the other tests. It
+// returns each value of a request and their sum multiplied by the first
+// argument, and sleeps instead of responding if the first value is "sleep".
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("UDF_TEST_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req processRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			os.Exit(1)
+		}
+
+		var resp processResponse
+		if len(req.Values) > 0 && req.Values[0] == "sleep" {
+			time.Sleep(time.Minute)
+		} else if len(req.Args) == 0 {
+			resp.Error = "expected argument"
+		} else {
+			var sum float64
+			for _, v := range req.Values {
+				sum += v.(float64)
+				resp.Results = append(resp.Results, v.(float64)*req.Args[0].(float64))
+			}
+			resp.Result = sum * req.Args[0].(float64)
+		}
+
+		data, _ := json.Marshal(resp)
+		fmt.Println(string(data))
+	}
+	os.Exit(0)
+}
+
+func helperProcessConfig(name, typ string) ProcessConfig {
+	return ProcessConfig{
+		Name:    name,
+		Type:    typ,
+		Command: []string{os.Args[0], "-test.run=TestHelperProcess"},
+		Timeout: toml.Duration(5 * time.Second),
+	}
+}
+
+func TestProcessFunction(t *testing.T) {
+	os.Setenv("UDF_TEST_HELPER_PROCESS", "1")
+	defer os.Unsetenv("UDF_TEST_HELPER_PROCESS")
+
+	fn := newProcessFunction(helperProcessConfig("scale", ScalarFunction))
+	defer fn.Close()
+
+	if v, err := fn.Call(float64(2), []interface{}{float64(3)}); err != nil {
+		t.Fatal(err)
+	} else if v != float64(6) {
+		t.Fatalf("unexpected result: %v", v)
+	}
+
+	if v, err := fn.Reduce([]interface{}{float64(1), float64(2)}, []interface{}{float64(10)}); err != nil {
+		t.Fatal(err)
+	} else if v != float64(30) {
+		t.Fatalf("unexpected result: %v", v)
+	}
+
+	if v, err := fn.CallBatch([]interface{}{float64(1), float64(2), float64(3)}, []interface{}{float64(10)}); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(v, []interface{}{float64(10), float64(20), float64(30)}) {
+		t.Fatalf("unexpected results: %v", v)
+	}
+
+	// Errors returned by the process are returned by the call.
+	if _, err := fn.Call(float64(2), nil); err == nil || err.Error() != "expected argument" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a process that does not respond in time is restarted.
+func TestProcessFunction_Timeout(t *testing.T) {
+	os.Setenv("UDF_TEST_HELPER_PROCESS", "1")
+	defer os.Unsetenv("UDF_TEST_HELPER_PROCESS")
+
+	c := helperProcessConfig("scale", ScalarFunction)
+	c.Timeout = toml.Duration(100 * time.Millisecond)
+	fn := newProcessFunction(c)
+	defer fn.Close()
+
+	if _, err := fn.Call("sleep", []interface{}{float64(1)}); err == nil || err.Error() != "process timed out" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v, err := fn.Call(float64(2), []interface{}{float64(1)}); err != nil {
+		t.Fatal(err)
+	} else if v != float64(2) {
+		t.Fatalf("unexpected result: %v", v)
+	}
+}
+
+// Ensure a call isn't blocked by a busy process of the pool.
+func TestProcessFunction_Pool(t *testing.T) {
+	os.Setenv("UDF_TEST_HELPER_PROCESS", "1")
+	defer os.Unsetenv("UDF_TEST_HELPER_PROCESS")
+
+	c := helperProcessConfig("scale", ScalarFunction)
+	c.Timeout = toml.Duration(2 * time.Second)
+	c.PoolSize = 2
+	fn := newProcessFunction(c)
+	defer fn.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := fn.Call("sleep", []interface{}{float64(1)})
+		done <- err
+	}()
+
+	if v, err := fn.Call(float64(2), []interface{}{float64(1)}); err != nil {
+		t.Fatal(err)
+	} else if v != float64(2) {
+		t.Fatalf("unexpected result: %v", v)
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected the sleeping call to still run, got %v", err)
+	default:
+	}
+	if err := <-done; err == nil || err.Error() != "process timed out" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestService_Open(t *testing.T) {
+	c := NewConfig()
+	c.Enabled = true
+	c.Processes = []ProcessConfig{
+		helperProcessConfig("scale", ScalarFunction),
+		helperProcessConfig("total", AggregateFunction),
+	}
+
+	s := NewService(c)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	if aggregate, ok := influxql.LookupUserFunction("scale"); !ok || aggregate {
+		t.Fatalf("unexpected scale(): registered=%v aggregate=%v", ok, aggregate)
+	} else if aggregate, ok := influxql.LookupUserFunction("total"); !ok || !aggregate {
+		t.Fatalf("unexpected total(): registered=%v aggregate=%v", ok, aggregate)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	} else if _, ok := influxql.LookupUserFunction("scale"); ok {
+		t.Fatal("expected scale() to be unregistered")
+	}
+}
+
+// Ensure functions cannot replace the built-in functions.
+func TestService_Open_Builtin(t *testing.T) {
+	c := NewConfig()
+	c.Enabled = true
+	c.Processes = []ProcessConfig{
+		helperProcessConfig("scale", ScalarFunction),
+		helperProcessConfig("mean", AggregateFunction),
+	}
+
+	s := NewService(c)
+	if err := s.Open(); err == nil {
+		t.Fatal("expected error")
+	} else if _, ok := influxql.LookupUserFunction("scale"); ok {
+		t.Fatal("expected scale() to be unregistered")
+	}
+}
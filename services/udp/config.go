@@ -1,6 +1,8 @@
 package udp
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/influxdata/influxdb/monitor/diagnostics"
@@ -41,6 +43,10 @@ const (
 	//     Linux:      sudo sysctl -w net.core.rmem_max=<read-buffer>
 	//     BSD/Darwin: sudo sysctl -w kern.ipc.maxsockbuf=<read-buffer>
 	DefaultReadBuffer = 0
+
+	// DefaultParserQueueSize is the default number of packets that may wait
+	// to be parsed.  Packets received while the queue is full are dropped.
+	DefaultParserQueueSize = 1000
 )
 
 // Config holds various configuration settings for the UDP listener.
@@ -55,6 +61,7 @@ type Config struct {
 	ReadBuffer      int           `toml:"read-buffer"`
 	BatchTimeout    toml.Duration `toml:"batch-timeout"`
 	Precision       string        `toml:"precision"`
+	ParserQueueSize int           `toml:"parser-queue-size"`
 }
 
 // NewConfig returns a new instance of Config with defaults.
@@ -66,6 +73,7 @@ func NewConfig() Config {
 		BatchSize:       DefaultBatchSize,
 		BatchPending:    DefaultBatchPending,
 		BatchTimeout:    toml.Duration(DefaultBatchTimeout),
+		ParserQueueSize: DefaultParserQueueSize,
 	}
 }
 
@@ -91,16 +99,64 @@ func (c *Config) WithDefaults() *Config {
 	if d.ReadBuffer == 0 {
 		d.ReadBuffer = DefaultReadBuffer
 	}
+	if d.ParserQueueSize == 0 {
+		d.ParserQueueSize = DefaultParserQueueSize
+	}
 	return &d
 }
 
+// Validate returns an error if the config is invalid.
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.BindAddress == "" {
+		return errors.New("bind-address must be specified")
+	}
+
+	switch c.Precision {
+	case "", "n", "ns", "u", "ms", "s", "m", "h":
+	default:
+		return fmt.Errorf("invalid precision %q", c.Precision)
+	}
+
+	if c.BatchSize < 0 || c.BatchPending < 0 || c.BatchTimeout < 0 {
+		return errors.New("batch-size, batch-pending and batch-timeout must not be negative")
+	} else if c.ReadBuffer < 0 {
+		return errors.New("read-buffer must not be negative")
+	} else if c.ParserQueueSize < 0 {
+		return errors.New("parser-queue-size must not be negative")
+	}
+	return nil
+}
+
 // Configs wraps a slice of Config to aggregate diagnostics.
 type Configs []Config
 
+// Validate returns an error if any of the Configs is invalid, or if more than
+// one of them listens on the same address.
+func (c Configs) Validate() error {
+	bindAddresses := make(map[string]struct{})
+	for _, cc := range c {
+		if err := cc.Validate(); err != nil {
+			return err
+		} else if !cc.Enabled {
+			continue
+		}
+
+		if _, ok := bindAddresses[cc.BindAddress]; ok {
+			return fmt.Errorf("duplicate bind-address %q", cc.BindAddress)
+		}
+		bindAddresses[cc.BindAddress] = struct{}{}
+	}
+	return nil
+}
+
 // Diagnostics returns one set of diagnostics for all of the Configs.
 func (c Configs) Diagnostics() (*diagnostics.Diagnostics, error) {
 	d := &diagnostics.Diagnostics{
-		Columns: []string{"enabled", "bind-address", "database", "retention-policy", "batch-size", "batch-pending", "batch-timeout"},
+		Columns: []string{"enabled", "bind-address", "database", "retention-policy", "batch-size", "batch-pending", "batch-timeout", "read-buffer", "precision", "parser-queue-size"},
 	}
 
 	for _, cc := range c {
@@ -109,7 +165,7 @@ func (c Configs) Diagnostics() (*diagnostics.Diagnostics, error) {
 			continue
 		}
 
-		r := []interface{}{true, cc.BindAddress, cc.Database, cc.RetentionPolicy, cc.BatchSize, cc.BatchPending, cc.BatchTimeout}
+		r := []interface{}{true, cc.BindAddress, cc.Database, cc.RetentionPolicy, cc.BatchSize, cc.BatchPending, cc.BatchTimeout, cc.ReadBuffer, cc.Precision, cc.ParserQueueSize}
 		d.AddRow(r)
 	}
 
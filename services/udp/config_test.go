@@ -20,6 +20,9 @@ batch-size = 100
 batch-pending = 9
 batch-timeout = "10ms"
 udp-payload-size = 1500
+read-buffer = 1048576
+precision = "ms"
+parser-queue-size = 100
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -39,5 +42,56 @@ udp-payload-size = 1500
 		t.Fatalf("unexpected batch pending: %d", c.BatchPending)
 	} else if time.Duration(c.BatchTimeout) != (10 * time.Millisecond) {
 		t.Fatalf("unexpected batch timeout: %v", c.BatchTimeout)
+	} else if c.ReadBuffer != 1048576 {
+		t.Fatalf("unexpected read buffer: %d", c.ReadBuffer)
+	} else if c.Precision != "ms" {
+		t.Fatalf("unexpected precision: %s", c.Precision)
+	} else if c.ParserQueueSize != 100 {
+		t.Fatalf("unexpected parser queue size: %d", c.ParserQueueSize)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := udp.NewConfig()
+	c.Enabled = true
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c.Precision = "us"
+	if err := c.Validate(); err == nil || err.Error() != `invalid precision "us"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.Precision = "ms"
+	c.ParserQueueSize = -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Disabled listeners aren't validated.
+	c.Enabled = false
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestConfigs_Validate(t *testing.T) {
+	c1, c2 := udp.NewConfig(), udp.NewConfig()
+	c1.Enabled, c2.Enabled = true, true
+	c2.BindAddress = ":8090"
+	if err := udp.Configs([]udp.Config{c1, c2}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c2.BindAddress = c1.BindAddress
+	if err := udp.Configs([]udp.Config{c1, c2}).Validate(); err == nil || err.Error() != `duplicate bind-address ":8089"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Disabled listeners may share an address.
+	c2.Enabled = false
+	if err := udp.Configs([]udp.Config{c1, c2}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
 	}
 }
@@ -16,15 +16,14 @@ import (
 )
 
 const (
-	// Arbitrary, testing indicated that this doesn't typically get over 10
-	parserChanLen = 1000
-
 	// MaxUDPPayload is largest payload size the UDP service will accept.
 	MaxUDPPayload = 64 * 1024
 )
 
 // statistics gathered by the UDP package.
 const (
+	statPacketsReceived     = "packetsRx"
+	statPacketsDropped      = "packetsDropped"
 	statPointsReceived      = "pointsRx"
 	statBytesReceived       = "bytesRx"
 	statPointsParseFail     = "pointsParseFail"
@@ -66,7 +65,7 @@ func NewService(c Config) *Service {
 	d := *c.WithDefaults()
 	return &Service{
 		config:      d,
-		parserChan:  make(chan []byte, parserChanLen),
+		parserChan:  make(chan []byte, d.ParserQueueSize),
 		Logger:      zap.New(zap.NullEncoder()),
 		stats:       &Statistics{},
 		defaultTags: models.StatisticTags{"bind": d.BindAddress},
@@ -125,6 +124,8 @@ func (s *Service) Open() (err error) {
 
 // Statistics maintains statistics for the UDP service.
 type Statistics struct {
+	PacketsReceived     int64
+	PacketsDropped      int64
 	PointsReceived      int64
 	BytesReceived       int64
 	PointsParseFail     int64
@@ -140,6 +141,8 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 		Name: "udp",
 		Tags: s.defaultTags.Merge(tags),
 		Values: map[string]interface{}{
+			statPacketsReceived:     atomic.LoadInt64(&s.stats.PacketsReceived),
+			statPacketsDropped:      atomic.LoadInt64(&s.stats.PacketsDropped),
 			statPointsReceived:      atomic.LoadInt64(&s.stats.PointsReceived),
 			statBytesReceived:       atomic.LoadInt64(&s.stats.BytesReceived),
 			statPointsParseFail:     atomic.LoadInt64(&s.stats.PointsParseFail),
@@ -194,11 +197,20 @@ func (s *Service) serve() {
 				s.Logger.Info(fmt.Sprintf("Failed to read UDP message: %s", err))
 				continue
 			}
+			atomic.AddInt64(&s.stats.PacketsReceived, 1)
 			atomic.AddInt64(&s.stats.BytesReceived, int64(n))
 
 			bufCopy := make([]byte, n)
 			copy(bufCopy, buf[:n])
-			s.parserChan <- bufCopy
+
+			// Drop the packet rather than stop reading if the parser can't
+			// keep up, so the drops are counted instead of being left to the
+			// OS receive buffer.
+			select {
+			case s.parserChan <- bufCopy:
+			default:
+				atomic.AddInt64(&s.stats.PacketsDropped, 1)
+			}
 		}
 	}
 }
@@ -219,7 +231,11 @@ func (s *Service) parser() {
 			}
 
 			for _, point := range points {
-				s.batcher.In() <- point
+				select {
+				case s.batcher.In() <- point:
+				case <-s.done:
+					return
+				}
 			}
 			atomic.AddInt64(&s.stats.PointsReceived, int64(len(points)))
 		}
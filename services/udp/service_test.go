@@ -2,7 +2,9 @@ package udp
 
 import (
 	"errors"
+	"net"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -124,6 +126,68 @@ func TestService_CreatesDatabase(t *testing.T) {
 	s.Service.Close()
 }
 
+// Ensure packets are dropped and counted when the parser can't keep up.
+func TestService_PacketsDropped(t *testing.T) {
+	t.Parallel()
+
+	c := NewConfig()
+	c.BindAddress = "127.0.0.1:0"
+	c.BatchSize = 1
+	c.BatchPending = 1
+	c.ParserQueueSize = 1
+	s := NewTestService(&c)
+	s.MetaClient.CreateDatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
+		return nil, nil
+	}
+
+	// Block writes so the batcher and then the parser stop consuming.
+	unblock := make(chan struct{})
+	s.WritePointsFn = func(string, string, models.ConsistencyLevel, []models.Point) error {
+		<-unblock
+		return nil
+	}
+
+	if err := s.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Service.Close()
+
+	conn, err := net.Dial("udp", s.Service.conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	timeout := time.After(5 * time.Second)
+	for atomic.LoadInt64(&s.Service.stats.PacketsDropped) == 0 {
+		if _, err := conn.Write([]byte("cpu value=1\n")); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("expected packets to be dropped")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	stats := s.Service.Statistics(nil)[0]
+	if received := stats.Values[statPacketsReceived].(int64); received < 2 {
+		t.Fatalf("unexpected packets received: %d", received)
+	}
+
+	// Let the point of every queued packet be written before closing the
+	// service.
+	close(unblock)
+	for atomic.LoadInt64(&s.Service.stats.PointsTransmitted) < atomic.LoadInt64(&s.Service.stats.PacketsReceived)-atomic.LoadInt64(&s.Service.stats.PacketsDropped) {
+		select {
+		case <-timeout:
+			t.Fatal("expected points to be written")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
 type TestService struct {
 	Service       *Service
 	Config        Config
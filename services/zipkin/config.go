@@ -0,0 +1,106 @@
+package zipkin
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	// DefaultURL is the default URL of the Zipkin collector spans are sent to.
+	DefaultURL = "http://localhost:9411/api/v2/spans"
+
+	// DefaultSampleRate is the default fraction of the requests traced when
+	// the caller did not decide whether they are sampled.
+	DefaultSampleRate = 0.01
+
+	// DefaultServiceName is the default name of the service in the spans.
+	DefaultServiceName = "influxd"
+
+	// DefaultBatchSize is the default maximum number of spans sent at once.
+	DefaultBatchSize = 1000
+
+	// DefaultFlushInterval is the default maximum time spans wait before
+	// being sent.
+	DefaultFlushInterval = time.Second
+
+	// DefaultTimeout is the default timeout of the requests to the collector.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultBufferSize is the default number of traces waiting to be sent
+	// before new ones are dropped.
+	DefaultBufferSize = 1000
+)
+
+// Config represents the configuration for tracing requests to Zipkin.
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// URL is the URL of the v2 span API of the Zipkin collector.
+	URL string `toml:"url"`
+
+	// SampleRate is the fraction, from 0 to 1, of the requests traced when
+	// the X-B3-Sampled header of the request does not decide it.
+	SampleRate float64 `toml:"sample-rate"`
+
+	ServiceName   string        `toml:"service-name"`
+	BatchSize     int           `toml:"batch-size"`
+	FlushInterval toml.Duration `toml:"flush-interval"`
+	Timeout       toml.Duration `toml:"timeout"`
+	BufferSize    int           `toml:"buffer-size"`
+}
+
+// NewConfig returns a new Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Enabled:       false,
+		URL:           DefaultURL,
+		SampleRate:    DefaultSampleRate,
+		ServiceName:   DefaultServiceName,
+		BatchSize:     DefaultBatchSize,
+		FlushInterval: toml.Duration(DefaultFlushInterval),
+		Timeout:       toml.Duration(DefaultTimeout),
+		BufferSize:    DefaultBufferSize,
+	}
+}
+
+// Validate returns an error if the Config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("zipkin url must be an http or https URL")
+	} else if c.SampleRate < 0 || c.SampleRate > 1 {
+		return errors.New("zipkin sample-rate must be between 0 and 1")
+	} else if c.BatchSize <= 0 {
+		return errors.New("zipkin batch-size must be positive")
+	} else if c.FlushInterval <= 0 {
+		return errors.New("zipkin flush-interval must be positive")
+	} else if c.BufferSize <= 0 {
+		return errors.New("zipkin buffer-size must be positive")
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":        true,
+		"url":            c.URL,
+		"sample-rate":    c.SampleRate,
+		"service-name":   c.ServiceName,
+		"batch-size":     c.BatchSize,
+		"flush-interval": c.FlushInterval,
+	}), nil
+}
@@ -0,0 +1,42 @@
+package zipkin_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/zipkin"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	c := zipkin.NewConfig()
+	if _, err := toml.Decode(`
+enabled = true
+url = "http://zipkin:9411/api/v2/spans"
+sample-rate = 0.5
+flush-interval = "10s"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.Enabled {
+		t.Fatalf("unexpected enabled state: %v", c.Enabled)
+	} else if c.URL != "http://zipkin:9411/api/v2/spans" {
+		t.Fatalf("unexpected url: %s", c.URL)
+	} else if c.SampleRate != 0.5 {
+		t.Fatalf("unexpected sample rate: %f", c.SampleRate)
+	} else if time.Duration(c.FlushInterval) != 10*time.Second {
+		t.Fatalf("unexpected flush interval: %s", c.FlushInterval)
+	} else if c.ServiceName != zipkin.DefaultServiceName {
+		t.Fatalf("unexpected service name: %s", c.ServiceName)
+	} else if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail: %s", err)
+	}
+
+	c.URL = "zipkin:9411"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error")
+	}
+}
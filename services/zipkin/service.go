@@ -0,0 +1,320 @@
+// Package zipkin provides a service that traces a sample of the requests and
+// reports their spans to a Zipkin collector.
+package zipkin // import "github.com/influxdata/influxdb/services/zipkin"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/tracing"
+	"github.com/uber-go/zap"
+)
+
+// Statistics for the zipkin service.
+const (
+	statTracesSampled = "tracesSampled"
+	statTracesDropped = "tracesDropped"
+	statSpansSent     = "spansSent"
+	statSendErr       = "sendErr"
+)
+
+// Headers propagating a trace, as sent by Zipkin instrumented clients.
+const (
+	headerTraceID = "X-B3-TraceId"
+	headerSpanID  = "X-B3-SpanId"
+	headerSampled = "X-B3-Sampled"
+	headerFlags   = "X-B3-Flags"
+)
+
+// Service traces a sample of the requests, continuing the traces of the
+// requests sent with B3 headers, and sends the spans of the finished traces
+// to a Zipkin collector in the background.
+type Service struct {
+	config Config
+	client *http.Client
+
+	mu      sync.RWMutex
+	traces  chan *tracing.Trace
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	stats  *Statistics
+	Logger zap.Logger
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		config: c,
+		client: &http.Client{Timeout: time.Duration(c.Timeout)},
+		stats:  &Statistics{},
+		Logger: zap.New(zap.NullEncoder()),
+	}
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log zap.Logger) {
+	s.Logger = log.With(zap.String("service", "zipkin"))
+}
+
+// Open starts sending the spans of the reported traces.
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.traces != nil {
+		return nil
+	}
+
+	s.Logger.Info(fmt.Sprintf("Starting zipkin service, sending spans to %s", s.config.URL))
+
+	s.traces = make(chan *tracing.Trace, s.config.BufferSize)
+	s.closing = make(chan struct{})
+	s.wg.Add(1)
+	go s.run(s.traces, s.closing)
+	return nil
+}
+
+// Close sends the spans of the traces already reported and stops the
+// service.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	if s.traces == nil {
+		s.mu.Unlock()
+		return nil
+	}
+	close(s.closing)
+	s.traces, s.closing = nil, nil
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+// Statistics maintains the statistics for the zipkin service.
+type Statistics struct {
+	TracesSampled int64
+	TracesDropped int64
+	SpansSent     int64
+	SendErr       int64
+}
+
+// Statistics returns statistics for periodic monitoring.
+func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	return []models.Statistic{{
+		Name: "zipkin",
+		Tags: tags,
+		Values: map[string]interface{}{
+			statTracesSampled: atomic.LoadInt64(&s.stats.TracesSampled),
+			statTracesDropped: atomic.LoadInt64(&s.stats.TracesDropped),
+			statSpansSent:     atomic.LoadInt64(&s.stats.SpansSent),
+			statSendErr:       atomic.LoadInt64(&s.stats.SendErr),
+		},
+	}}
+}
+
+// StartTrace starts the trace of a request with the given headers, returning
+// its root span named name, or nil if the request is not sampled.  The trace
+// continues the trace of the B3 headers, if any.  Only the low 64 bits of
+// 128 bit trace IDs are kept.
+func (s *Service) StartTrace(name string, header http.Header) (*tracing.Trace, *tracing.Span) {
+	parent, sampled, ok := extract(header)
+	if !ok {
+		sampled = s.config.SampleRate > 0 && rand.Float64() < s.config.SampleRate
+	}
+	if !sampled {
+		return nil, nil
+	}
+	atomic.AddInt64(&s.stats.TracesSampled, 1)
+
+	if parent.TraceID != 0 {
+		return tracing.NewTraceFromSpan(name, parent)
+	}
+	return tracing.NewTrace(name)
+}
+
+// Report queues the spans of the finished trace to be sent to the collector.
+// The trace is dropped if too many are waiting to be sent.
+func (s *Service) Report(t *tracing.Trace) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	select {
+	case s.traces <- t:
+	default:
+		// Closed, or too many traces waiting.
+		atomic.AddInt64(&s.stats.TracesDropped, 1)
+	}
+}
+
+// run sends the spans of the traces in batches until the service is closed.
+func (s *Service) run(traces <-chan *tracing.Trace, closing <-chan struct{}) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(s.config.FlushInterval))
+	defer ticker.Stop()
+
+	var batch []span
+	for {
+		select {
+		case t := <-traces:
+			batch = append(batch, s.spans(t)...)
+			if len(batch) < s.config.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-closing:
+			// Send the traces reported before the service was closed.
+			for n := len(traces); n > 0; n-- {
+				batch = append(batch, s.spans(<-traces)...)
+			}
+			s.send(batch)
+			return
+		}
+		s.send(batch)
+		batch = batch[:0]
+	}
+}
+
+// send posts the batch of spans to the collector.
+func (s *Service) send(batch []span) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := s.post(batch); err != nil {
+		atomic.AddInt64(&s.stats.SendErr, 1)
+		s.Logger.Info(fmt.Sprintf("WARN: unable to send %d spans to zipkin: %s", len(batch), err))
+		return
+	}
+	atomic.AddInt64(&s.stats.SpansSent, int64(len(batch)))
+}
+
+func (s *Service) post(batch []span) error {
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(s.config.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// span is a span in the format of the v2 API of Zipkin.
+type span struct {
+	TraceID       string            `json:"traceId"`
+	ID            string            `json:"id"`
+	ParentID      string            `json:"parentId,omitempty"`
+	Name          string            `json:"name"`
+	Kind          string            `json:"kind,omitempty"`
+	Timestamp     int64             `json:"timestamp"`
+	Duration      int64             `json:"duration"`
+	LocalEndpoint endpoint          `json:"localEndpoint"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+type endpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// spans returns the finished spans of the trace in the format of Zipkin.
+// The spans whose parent is not in the trace are the server spans of the
+// requests.
+func (s *Service) spans(t *tracing.Trace) []span {
+	raws := t.Spans()
+
+	ids := make(map[uint64]struct{}, len(raws))
+	for _, raw := range raws {
+		ids[raw.Context.SpanID] = struct{}{}
+	}
+
+	spans := make([]span, 0, len(raws))
+	for _, raw := range raws {
+		sp := span{
+			TraceID:       formatID(raw.Context.TraceID),
+			ID:            formatID(raw.Context.SpanID),
+			Name:          raw.Name,
+			Timestamp:     raw.Start.UnixNano() / int64(time.Microsecond),
+			Duration:      int64(raw.Duration / time.Microsecond),
+			LocalEndpoint: endpoint{ServiceName: s.config.ServiceName},
+		}
+		if sp.Duration < 1 {
+			sp.Duration = 1
+		}
+		if raw.ParentSpanID != 0 {
+			sp.ParentID = formatID(raw.ParentSpanID)
+		}
+		if _, ok := ids[raw.ParentSpanID]; !ok {
+			sp.Kind = "SERVER"
+		}
+
+		if len(raw.Labels) > 0 || len(raw.Fields) > 0 {
+			sp.Tags = make(map[string]string, len(raw.Labels)+len(raw.Fields))
+			for _, l := range raw.Labels {
+				sp.Tags[l.Key] = l.Value
+			}
+			for _, f := range raw.Fields {
+				sp.Tags[f.Key()] = fmt.Sprint(f.Value())
+			}
+		}
+		spans = append(spans, sp)
+	}
+	return spans
+}
+
+// extract returns the span context of the B3 headers and whether the trace
+// is sampled.  It returns false if the headers do not decide whether the
+// trace is sampled.
+func extract(header http.Header) (parent tracing.SpanContext, sampled, ok bool) {
+	if traceID, err := parseID(header.Get(headerTraceID)); err == nil {
+		if spanID, err := parseID(header.Get(headerSpanID)); err == nil {
+			parent = tracing.SpanContext{TraceID: traceID, SpanID: spanID}
+		}
+	}
+
+	if header.Get(headerFlags) == "1" {
+		return parent, true, true
+	}
+	switch strings.ToLower(header.Get(headerSampled)) {
+	case "1", "true":
+		return parent, true, true
+	case "0", "false":
+		return parent, false, true
+	}
+	return parent, false, false
+}
+
+// parseID parses a hex encoded B3 ID, keeping the low 64 bits of 128 bit
+// trace IDs.
+func parseID(s string) (uint64, error) {
+	if len(s) > 16 {
+		s = s[len(s)-16:]
+	}
+	id, err := strconv.ParseUint(s, 16, 64)
+	if err == nil && id == 0 {
+		return 0, fmt.Errorf("invalid id: %q", s)
+	}
+	return id, err
+}
+
+// formatID returns the B3 encoding of an ID.
+func formatID(id uint64) string {
+	return fmt.Sprintf("%016x", id)
+}
@@ -0,0 +1,86 @@
+package zipkin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/influxdb/services/zipkin"
+)
+
+// Ensure the service continues the traces of B3 headers and sends their spans
+// to the collector when closed.
+func TestService_Report(t *testing.T) {
+	var mu sync.Mutex
+	var spans []map[string]interface{}
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		mu.Lock()
+		spans = append(spans, batch...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer collector.Close()
+
+	c := zipkin.NewConfig()
+	c.Enabled = true
+	c.URL = collector.URL
+	c.SampleRate = 0
+	s := zipkin.NewService(c)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests are not sampled unless the headers decide it.
+	if _, span := s.StartTrace("query", http.Header{}); span != nil {
+		t.Fatal("unexpected sampled request")
+	}
+	h := http.Header{}
+	h.Set("X-B3-TraceId", "463ac35c9f6413ad48485a3953bb6124")
+	h.Set("X-B3-SpanId", "a2fb4a1d1a96d312")
+	h.Set("X-B3-Sampled", "0")
+	if _, span := s.StartTrace("query", h); span != nil {
+		t.Fatal("unexpected sampled request")
+	}
+
+	h.Set("X-B3-Sampled", "1")
+	trace, span := s.StartTrace("query", h)
+	if span == nil {
+		t.Fatal("expected sampled request")
+	} else if sc := span.Context(); sc.TraceID != 0x48485a3953bb6124 {
+		t.Fatalf("unexpected trace id: %x", sc.TraceID)
+	}
+	span.SetLabels("http.method", "GET")
+	child := span.StartSpan("statement")
+	child.Finish()
+	span.Finish()
+	s.Report(trace)
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(spans) != 2 {
+		t.Fatalf("unexpected spans: %v", spans)
+	}
+	root, stmt := spans[0], spans[1]
+	if root["traceId"] != "48485a3953bb6124" || root["parentId"] != "a2fb4a1d1a96d312" || root["name"] != "query" || root["kind"] != "SERVER" {
+		t.Fatalf("unexpected root span: %v", root)
+	} else if tags, _ := root["tags"].(map[string]interface{}); tags["http.method"] != "GET" {
+		t.Fatalf("unexpected root span tags: %v", root)
+	}
+	if stmt["parentId"] != root["id"] || stmt["name"] != "statement" || stmt["kind"] != nil {
+		t.Fatalf("unexpected child span: %v", stmt)
+	}
+
+	if stats := s.Statistics(nil)[0].Values; stats["tracesSampled"] != int64(1) || stats["spansSent"] != int64(2) {
+		t.Fatalf("unexpected statistics: %v", stats)
+	}
+}
@@ -5,11 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
-	"os"
 	"sync"
 	"time"
+
+	"github.com/uber-go/zap"
 )
 
 const (
@@ -31,7 +31,7 @@ type Mux struct {
 	Timeout time.Duration
 
 	// Out-of-band error logger
-	Logger *log.Logger
+	Logger zap.Logger
 }
 
 type replayConn struct {
@@ -59,10 +59,15 @@ func NewMux() *Mux {
 	return &Mux{
 		m:       make(map[byte]*listener),
 		Timeout: DefaultTimeout,
-		Logger:  log.New(os.Stderr, "[tcp] ", log.LstdFlags),
+		Logger:  zap.New(zap.NullEncoder()),
 	}
 }
 
+// WithLogger sets the logger of the mux.
+func (mux *Mux) WithLogger(log zap.Logger) {
+	mux.Logger = log.With(zap.String("service", "tcp"))
+}
+
 // Serve handles connections from ln and multiplexes then across registered listeners.
 func (mux *Mux) Serve(ln net.Listener) error {
 	mux.mu.Lock()
@@ -103,7 +108,7 @@ func (mux *Mux) handleConn(conn net.Conn) {
 	// Set a read deadline so connections with no data don't timeout.
 	if err := conn.SetReadDeadline(time.Now().Add(mux.Timeout)); err != nil {
 		conn.Close()
-		mux.Logger.Printf("tcp.Mux: cannot set read deadline: %s", err)
+		mux.Logger.Error("cannot set read deadline", zap.Error(err))
 		return
 	}
 
@@ -111,14 +116,14 @@ func (mux *Mux) handleConn(conn net.Conn) {
 	var typ [1]byte
 	if _, err := io.ReadFull(conn, typ[:]); err != nil {
 		conn.Close()
-		mux.Logger.Printf("tcp.Mux: cannot read header byte: %s", err)
+		mux.Logger.Error("cannot read header byte", zap.Error(err))
 		return
 	}
 
 	// Reset read deadline and let the listener handle that.
 	if err := conn.SetReadDeadline(time.Time{}); err != nil {
 		conn.Close()
-		mux.Logger.Printf("tcp.Mux: cannot reset set read deadline: %s", err)
+		mux.Logger.Error("cannot reset read deadline", zap.Error(err))
 		return
 	}
 
@@ -127,7 +132,7 @@ func (mux *Mux) handleConn(conn net.Conn) {
 	if handler == nil {
 		if mux.defaultListener == nil {
 			conn.Close()
-			mux.Logger.Printf("tcp.Mux: handler not registered: %d. Connection from %s closed", typ[0], conn.RemoteAddr())
+			mux.Logger.Info(fmt.Sprintf("handler not registered: %d. Connection from %s closed", typ[0], conn.RemoteAddr()))
 			return
 		}
 
@@ -146,7 +151,7 @@ func (mux *Mux) handleConn(conn net.Conn) {
 	case handler.c <- conn:
 	case <-timer.C:
 		conn.Close()
-		mux.Logger.Printf("tcp.Mux: handler not ready: %d. Connection from %s closed", typ[0], conn.RemoteAddr())
+		mux.Logger.Info(fmt.Sprintf("handler not ready: %d. Connection from %s closed", typ[0], conn.RemoteAddr()))
 		return
 	}
 }
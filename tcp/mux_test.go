@@ -4,9 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"net"
+	"os"
 	"strings"
 	"sync"
 	"testing"
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/influxdata/influxdb/tcp"
+	"github.com/uber-go/zap"
 )
 
 // Ensure the muxer can split a listener's connections across multiple listeners.
@@ -39,8 +40,11 @@ func TestMux(t *testing.T) {
 		// Setup muxer & listeners.
 		mux := tcp.NewMux()
 		mux.Timeout = 200 * time.Millisecond
-		if !testing.Verbose() {
-			mux.Logger = log.New(ioutil.Discard, "", 0)
+		if testing.Verbose() {
+			mux.WithLogger(zap.New(
+				zap.NewTextEncoder(),
+				zap.Output(os.Stderr),
+			))
 		}
 
 		errC := make(chan error)
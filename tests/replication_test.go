@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/replication"
+	"github.com/influxdata/influxdb/toml"
+)
+
+// Ensure points written to the backfill WAL of a primary reach a follower.
+func TestServer_Replication_Backfill(t *testing.T) {
+	if RemoteEnabled() {
+		t.Skip("Skipping.  Cannot configure replication of a remote server")
+	}
+
+	pc := NewConfig()
+	pc.BindAddress = freePort()
+	pc.Data.BackfillThreshold = toml.Duration(time.Hour)
+	pc.Replication.Mode = replication.ModePrimary
+	primary := OpenServer(pc)
+	defer primary.Close()
+
+	// A long shard duration keeps both points in the same shard, so the
+	// backfilled point can't arrive in the snapshot of a new shard.
+	rp := newRetentionPolicySpec("rp0", 1, 0)
+	rp.ShardGroupDuration = 52 * 7 * 24 * time.Hour
+	if err := primary.CreateDatabaseAndRetentionPolicy("db0", rp, true); err != nil {
+		t.Fatal(err)
+	}
+
+	recent := now().Add(-30 * time.Minute).Truncate(time.Second)
+	old := now().Add(-2 * time.Hour).Truncate(time.Second)
+	primary.MustWrite("db0", "rp0", fmt.Sprintf("cpu value=1 %d", recent.UnixNano()), nil)
+
+	fc := NewConfig()
+	fc.Replication.Mode = replication.ModeFollower
+	fc.Replication.PrimaryAddress = pc.BindAddress
+	fc.Replication.PollInterval = toml.Duration(100 * time.Millisecond)
+	follower := OpenServer(fc)
+	defer follower.Close()
+
+	waitFor := func(exp string) {
+		var res string
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+			var err error
+			if res, err = follower.Query(`SELECT count(value) FROM db0.rp0.cpu`); err != nil {
+				t.Fatal(err)
+			} else if strings.Contains(res, exp) {
+				return
+			}
+		}
+		t.Fatalf("unexpected follower results: %s", res)
+	}
+
+	// The follower bootstraps the shard before the old point is written.
+	waitFor(`"values":[["1970-01-01T00:00:00Z",1]]`)
+
+	primary.MustWrite("db0", "rp0", fmt.Sprintf("cpu value=2 %d", old.UnixNano()), nil)
+
+	// Snapshots archive the segments of the WAL and the backfill WAL.
+	store := primary.(*LocalServer).TSDBStore
+	for _, id := range store.ShardIDs() {
+		if err := store.Shard(id).WriteSnapshot(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitFor(`"values":[["1970-01-01T00:00:00Z",2]]`)
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influxdb/cmd/influxd/restore"
+)
+
+// restoreFixture is a backup set checked in under testdata/restore. Each
+// fixture is restored with the current restore command into an empty server
+// and queried to check nothing was lost.
+//
+// The backup sets are stored rather than generated so that changes to the
+// backup, metastore and TSM writers can't change them along with the reader.
+// testdata/restore/README.md records how each was made.
+//
+// When the backup format changes, add a fixture for the new layout rather
+// than changing an existing one, so that older backups stay restorable.
+type restoreFixture struct {
+	name string
+
+	// dir is the backup set, relative to testdata/restore.
+	dir string
+
+	// args are passed to restore in addition to -metadir, -datadir and the path.
+	args []string
+
+	command string
+	exp     string
+}
+
+var restoreFixtures = []restoreFixture{
+	{
+		// A full database backup: a metastore snapshot and one archive per shard.
+		name:    "full",
+		dir:     "full",
+		args:    []string{"-database", "mydb"},
+		command: `SELECT * FROM mydb.forever.cpu`,
+		exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","host","value"],"values":[["1970-01-01T00:00:01Z","a",1],["1970-01-01T00:00:02Z","a",2]]}]}]}`,
+	},
+	{
+		// Incremental backups add a numbered archive per shard for each run.
+		name:    "incremental",
+		dir:     "incremental",
+		args:    []string{"-database", "mydb"},
+		command: `SELECT * FROM mydb.forever.cpu`,
+		exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","host","value"],"values":[["1970-01-01T00:00:01Z","a",1],["1970-01-01T00:00:02Z","a",2]]}]}]}`,
+	},
+	{
+		// Repeated backups into one directory leave several metastore
+		// snapshots; the latest must be used.
+		name:    "multiple metastore snapshots",
+		dir:     "multiple-metastore-snapshots",
+		args:    []string{"-database", "mydb"},
+		command: `SHOW DATABASES`,
+		exp:     `{"results":[{"statement_id":0,"series":[{"name":"databases","columns":["name"],"values":[["mydb"],["newer"]]}]}]}`,
+	},
+	{
+		// Shard archives include the tombstone files of deleted series.
+		name:    "tombstones",
+		dir:     "tombstones",
+		args:    []string{"-database", "mydb"},
+		command: `SELECT * FROM mydb.forever.cpu`,
+		exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","host","value"],"values":[["1970-01-01T00:00:01Z","a",1]]}]}]}`,
+	},
+	{
+		// Restoring a single retention policy.
+		name:    "retention policy",
+		dir:     "single-point",
+		args:    []string{"-database", "mydb", "-retention", "forever"},
+		command: `SELECT * FROM mydb.forever.cpu`,
+		exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","host","value"],"values":[["1970-01-01T00:00:01Z","a",1]]}]}]}`,
+	},
+	{
+		// Restoring a single shard.
+		name:    "shard",
+		dir:     "single-point",
+		args:    []string{"-database", "mydb", "-retention", "forever", "-shard", "1"},
+		command: `SELECT * FROM mydb.forever.cpu`,
+		exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","host","value"],"values":[["1970-01-01T00:00:01Z","a",1]]}]}]}`,
+	},
+	{
+		// Backups with a manifest of timestamp precisions, restored at a
+		// coarser precision.
+		name:    "manifest with precision",
+		dir:     "manifest-with-precision",
+		args:    []string{"-database", "mydb", "-precision", "s"},
+		command: `SELECT * FROM mydb.forever.cpu`,
+		exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","host","value"],"values":[["1970-01-01T00:00:01Z","a",1],["1970-01-01T00:00:02Z","a",2]]}]}]}`,
+	},
+	{
+		// Scrubbing tag and string field values on restore, with tombstones
+		// applied before series are merged.
+		name:    "scrub",
+		dir:     "scrub",
+		args:    []string{"-database", "mydb", "-scrub", "tag:host=redact", "-scrub", "field:user=redact"},
+		command: `SELECT * FROM mydb.forever.cpu`,
+		exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","host","user","value"],"values":[["1970-01-01T00:00:01Z","redacted","redacted",1],["1970-01-01T00:00:03Z","redacted",null,3]]}]}]}`,
+	},
+}
+
+// Ensure every historical backup layout can be restored.
+func TestServer_RestoreCompatibility(t *testing.T) {
+	if RemoteEnabled() {
+		t.Skip("Skipping. Cannot restore into a remote server")
+	}
+
+	for _, f := range restoreFixtures {
+		t.Run(f.name, func(t *testing.T) {
+			backupDir := filepath.Join("testdata", "restore", f.dir)
+
+			config := NewConfig()
+			// The fixtures predate the tsi1 index, which is rebuilt separately.
+			config.Data.Index = "inmem"
+
+			cmd := restore.NewCommand()
+			cmd.Stdout = ioutil.Discard
+			args := append([]string{"-metadir", config.Meta.Dir, "-datadir", config.Data.Dir}, f.args...)
+			if err := cmd.Run(append(args, backupDir)...); err != nil {
+				t.Fatalf("restore: %s", err)
+			}
+
+			s := OpenServer(config)
+			defer s.Close()
+
+			res, err := s.Query(f.command)
+			if err != nil {
+				t.Fatalf("query: %s", err)
+			} else if res != f.exp {
+				t.Fatalf("unexpected results:\n\texp: %s\n\tgot: %s", f.exp, res)
+			}
+		})
+	}
+}
@@ -311,7 +311,7 @@ func (s *LocalServer) CreateDatabaseAndRetentionPolicy(db string, rp *meta.Reten
 func (s *LocalServer) CreateSubscription(database, rp, name, mode string, destinations []string) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.MetaClient.CreateSubscription(database, rp, name, mode, destinations)
+	return s.MetaClient.CreateSubscription(database, rp, name, mode, destinations, "")
 }
 
 func (s *LocalServer) DropDatabase(db string) error {
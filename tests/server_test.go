@@ -14,6 +14,7 @@ import (
 
 	"github.com/influxdata/influxdb/coordinator"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/toml"
 	"github.com/influxdata/influxdb/tsdb"
 )
 
@@ -5464,6 +5465,12 @@ func TestServer_Query_SubqueryWithGroupBy(t *testing.T) {
 			command: `SELECT mean(mean) FROM (SELECT mean(value) FROM cpu GROUP BY time(2s), host, region) WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:00:04Z' GROUP BY time(2s), host`,
 			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"server01"},"columns":["time","mean"],"values":[["2000-01-01T00:00:00Z",5.5],["2000-01-01T00:00:02Z",7.5]]},{"name":"cpu","tags":{"host":"server02"},"columns":["time","mean"],"values":[["2000-01-01T00:00:00Z",9.5],["2000-01-01T00:00:02Z",11.5]]}]}]}`,
 		},
+		&Query{
+			name:    "max of aliased mean - time(4s) - time(2s), host",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT max(mean_value) FROM (SELECT mean(value) AS mean_value FROM cpu GROUP BY time(2s), host) WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:00:04Z' GROUP BY time(4s)`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","max"],"values":[["2000-01-01T00:00:00Z",11.5]]}]}]}`,
+		},
 	}...)
 
 	for i, query := range test.queries {
@@ -6590,6 +6597,275 @@ func TestServer_Query_LimitAndOffset(t *testing.T) {
 	}
 }
 
+func TestServer_Query_MathFunctions(t *testing.T) {
+	t.Parallel()
+	s := OpenServer(NewConfig())
+	defer s.Close()
+
+	if err := s.CreateDatabaseAndRetentionPolicy("db0", newRetentionPolicySpec("rp0", 1, 0), true); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := []string{
+		fmt.Sprintf(`cpu,host=server01 value=-2.5,n=-3i %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:00Z").UnixNano()),
+		fmt.Sprintf(`cpu,host=server01 value=4,n=4i %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:10Z").UnixNano()),
+		fmt.Sprintf(`cpu,host=server01 value=16,n=10i %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:20Z").UnixNano()),
+	}
+
+	test := NewTest("db0", "rp0")
+	test.writes = Writes{
+		&Write{data: strings.Join(writes, "\n")},
+	}
+
+	test.addQueries([]*Query{
+		&Query{
+			name:    "abs and rounding",
+			command: `SELECT abs(n), round(value), floor(value), ceil(value) FROM cpu`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","abs","round","floor","ceil"],"values":[["2000-01-01T00:00:00Z",3,-3,-3,-2],["2000-01-01T00:00:10Z",4,4,4,4],["2000-01-01T00:00:20Z",10,16,16,16]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    "sqrt, log and pow",
+			command: `SELECT sqrt(value), log(value, 2), pow(n, 2) FROM cpu`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","sqrt","log","pow"],"values":[["2000-01-01T00:00:00Z",null,null,9],["2000-01-01T00:00:10Z",2,2,16],["2000-01-01T00:00:20Z",4,4,100]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    "conditional",
+			command: `SELECT if(value > 0, value, 0) AS positive FROM cpu`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","positive"],"values":[["2000-01-01T00:00:00Z",0],["2000-01-01T00:00:10Z",4],["2000-01-01T00:00:20Z",16]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    "math on an aggregate",
+			command: `SELECT sqrt(max(value)) FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:00:30Z' GROUP BY time(20s)`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","sqrt"],"values":[["2000-01-01T00:00:00Z",2],["2000-01-01T00:00:20Z",4]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+	}...)
+
+	for i, query := range test.queries {
+		t.Run(query.name, func(t *testing.T) {
+			if i == 0 {
+				if err := test.init(s); err != nil {
+					t.Fatalf("test init failed: %s", err)
+				}
+			}
+			if query.skip {
+				t.Skipf("SKIP:: %s", query.name)
+			}
+			if err := query.Execute(s); err != nil {
+				t.Error(query.Error(err))
+			} else if !query.success() {
+				t.Error(query.failureMessage())
+			}
+		})
+	}
+}
+
+func TestServer_Query_ApproximateFunctions(t *testing.T) {
+	t.Parallel()
+	s := OpenServer(NewConfig())
+	defer s.Close()
+
+	if err := s.CreateDatabaseAndRetentionPolicy("db0", newRetentionPolicySpec("rp0", 1, 0), true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write the points in two shard groups so the sketches are merged.
+	var writes []string
+	for i := 0; i < 100; i++ {
+		ts := mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:00Z").Add(time.Duration(i) * time.Second)
+		if i%2 == 1 {
+			ts = ts.Add(30 * 24 * time.Hour)
+		}
+		writes = append(writes, fmt.Sprintf(`latency,host=server0%d value=%d,session="s%d" %d`, i%3, i+1, i%10, ts.UnixNano()))
+	}
+
+	test := NewTest("db0", "rp0")
+	test.writes = Writes{
+		&Write{data: strings.Join(writes, "\n")},
+	}
+
+	test.addQueries([]*Query{
+		&Query{
+			name:    "count_hll",
+			command: `SELECT count_hll(session), count_hll(value) FROM latency`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"latency","columns":["time","count_hll","count_hll_1"],"values":[["1970-01-01T00:00:00Z",10,100]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    "percentile_tdigest",
+			command: `SELECT percentile_tdigest(value, 50) AS p50, percentile_tdigest(value, 99) AS p99 FROM latency`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"latency","columns":["time","p50","p99"],"values":[["1970-01-01T00:00:00Z",50.5,99.5]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+	}...)
+
+	for i, query := range test.queries {
+		t.Run(query.name, func(t *testing.T) {
+			if i == 0 {
+				if err := test.init(s); err != nil {
+					t.Fatalf("test init failed: %s", err)
+				}
+			}
+			if query.skip {
+				t.Skipf("SKIP:: %s", query.name)
+			}
+			if err := query.Execute(s); err != nil {
+				t.Error(query.Error(err))
+			} else if !query.success() {
+				t.Error(query.failureMessage())
+			}
+		})
+	}
+}
+
+func TestServer_Query_OrderByField(t *testing.T) {
+	t.Parallel()
+	s := OpenServer(NewConfig())
+	defer s.Close()
+
+	if err := s.CreateDatabaseAndRetentionPolicy("db0", newRetentionPolicySpec("rp0", 1, 0), true); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := []string{
+		fmt.Sprintf(`cpu,host=server01 value=2,status="ok" %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:00Z").UnixNano()),
+		fmt.Sprintf(`cpu,host=server01 value=9,status="warn" %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:10Z").UnixNano()),
+		fmt.Sprintf(`cpu,host=server01 value=5,status="ok" %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:20Z").UnixNano()),
+		fmt.Sprintf(`cpu,host=server02 value=7,status="warn" %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:00Z").UnixNano()),
+		fmt.Sprintf(`cpu,host=server02 value=1,status="ok" %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:10Z").UnixNano()),
+		fmt.Sprintf(`cpu,host=server02 value=5,status="ok" %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:20Z").UnixNano()),
+	}
+
+	test := NewTest("db0", "rp0")
+	test.writes = Writes{
+		&Write{data: strings.Join(writes, "\n")},
+	}
+
+	test.addQueries([]*Query{
+		&Query{
+			name:    "raw values",
+			command: `SELECT value FROM cpu ORDER BY value DESC`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","value"],"values":[["2000-01-01T00:00:10Z",9],["2000-01-01T00:00:00Z",7],["2000-01-01T00:00:20Z",5],["2000-01-01T00:00:20Z",5],["2000-01-01T00:00:00Z",2],["2000-01-01T00:00:10Z",1]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    "top rows with limit",
+			command: `SELECT value, host FROM cpu ORDER BY value DESC LIMIT 2`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","value","host"],"values":[["2000-01-01T00:00:10Z",9,"server01"],["2000-01-01T00:00:00Z",7,"server02"]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    "limit and offset",
+			command: `SELECT value FROM cpu ORDER BY value LIMIT 2 OFFSET 1`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","value"],"values":[["2000-01-01T00:00:00Z",2],["2000-01-01T00:00:20Z",5]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    "multiple keys",
+			command: `SELECT status, value FROM cpu ORDER BY status DESC, value, time DESC`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","status","value"],"values":[["2000-01-01T00:00:00Z","warn",7],["2000-01-01T00:00:10Z","warn",9],["2000-01-01T00:00:10Z","ok",1],["2000-01-01T00:00:00Z","ok",2],["2000-01-01T00:00:20Z","ok",5],["2000-01-01T00:00:20Z","ok",5]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    "aggregate per series",
+			command: `SELECT max(value) FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:00:30Z' GROUP BY time(10s), host ORDER BY max DESC LIMIT 1`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"server01"},"columns":["time","max"],"values":[["2000-01-01T00:00:10Z",9]]},{"name":"cpu","tags":{"host":"server02"},"columns":["time","max"],"values":[["2000-01-01T00:00:00Z",7]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    "field not selected",
+			command: `SELECT value FROM cpu ORDER BY status`,
+			exp:     `{"results":[{"statement_id":0,"error":"cannot ORDER BY status: field is not selected"}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+	}...)
+
+	for i, query := range test.queries {
+		t.Run(query.name, func(t *testing.T) {
+			if i == 0 {
+				if err := test.init(s); err != nil {
+					t.Fatalf("test init failed: %s", err)
+				}
+			}
+			if query.skip {
+				t.Skipf("SKIP:: %s", query.name)
+			}
+			if err := query.Execute(s); err != nil {
+				t.Error(query.Error(err))
+			} else if !query.success() {
+				t.Error(query.failureMessage())
+			}
+		})
+	}
+}
+
+func TestServer_Query_Join(t *testing.T) {
+	t.Parallel()
+	s := OpenServer(NewConfig())
+	defer s.Close()
+
+	if err := s.CreateDatabaseAndRetentionPolicy("db0", newRetentionPolicySpec("rp0", 1, 0), true); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := []string{
+		fmt.Sprintf(`errors,host=server01 value=1 %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:00Z").UnixNano()),
+		fmt.Sprintf(`errors,host=server01 value=3 %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:10Z").UnixNano()),
+		fmt.Sprintf(`errors,host=server02 value=2 %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:00Z").UnixNano()),
+		fmt.Sprintf(`requests,host=server01 value=10 %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:00Z").UnixNano()),
+		fmt.Sprintf(`requests,host=server01 value=20 %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:10Z").UnixNano()),
+		fmt.Sprintf(`requests,host=server02 value=40 %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:00Z").UnixNano()),
+		fmt.Sprintf(`requests,host=server02 value=60 %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:10Z").UnixNano()),
+	}
+
+	test := NewTest("db0", "rp0")
+	test.writes = Writes{
+		&Write{data: strings.Join(writes, "\n")},
+	}
+
+	test.addQueries([]*Query{
+		&Query{
+			name:    "raw fields",
+			command: `SELECT errors.value, requests.value FROM errors JOIN requests GROUP BY host`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"errors_requests","tags":{"host":"server01"},"columns":["time","errors.value","requests.value"],"values":[["2000-01-01T00:00:00Z",1,10],["2000-01-01T00:00:10Z",3,20]]},{"name":"errors_requests","tags":{"host":"server02"},"columns":["time","errors.value","requests.value"],"values":[["2000-01-01T00:00:00Z",2,40],["2000-01-01T00:00:10Z",null,60]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    "ratio of fields",
+			command: `SELECT errors.value / requests.value AS ratio FROM errors JOIN requests WHERE host = 'server01'`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"errors_requests","columns":["time","ratio"],"values":[["2000-01-01T00:00:00Z",0.1],["2000-01-01T00:00:10Z",0.15]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    "ratio of aggregates",
+			command: `SELECT sum(errors.value) / sum(requests.value) AS ratio FROM errors JOIN requests WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:00:20Z' GROUP BY time(20s), host`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"errors_requests","tags":{"host":"server01"},"columns":["time","ratio"],"values":[["2000-01-01T00:00:00Z",0.13333333333333333]]},{"name":"errors_requests","tags":{"host":"server02"},"columns":["time","ratio"],"values":[["2000-01-01T00:00:00Z",0.02]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+	}...)
+
+	for i, query := range test.queries {
+		t.Run(query.name, func(t *testing.T) {
+			if i == 0 {
+				if err := test.init(s); err != nil {
+					t.Fatalf("test init failed: %s", err)
+				}
+			}
+			if query.skip {
+				t.Skipf("SKIP:: %s", query.name)
+			}
+			if err := query.Execute(s); err != nil {
+				t.Error(query.Error(err))
+			} else if !query.success() {
+				t.Error(query.failureMessage())
+			}
+		})
+	}
+}
+
 func TestServer_Query_Fill(t *testing.T) {
 	t.Parallel()
 	s := OpenServer(NewConfig())
@@ -6636,6 +6912,12 @@ func TestServer_Query_Fill(t *testing.T) {
 			exp:     `{"results":[{"statement_id":0,"series":[{"name":"fills","columns":["time","mean"],"values":[["2009-11-10T23:00:00Z",4],["2009-11-10T23:00:05Z",4],["2009-11-10T23:00:10Z",4],["2009-11-10T23:00:15Z",10]]}]}]}`,
 			params:  url.Values{"db": []string{"db0"}},
 		},
+		&Query{
+			name:    "fill with linear",
+			command: `select mean(val) from fills where time >= '2009-11-10T23:00:00Z' and time < '2009-11-10T23:00:20Z' group by time(5s) FILL(linear)`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"fills","columns":["time","mean"],"values":[["2009-11-10T23:00:00Z",4],["2009-11-10T23:00:05Z",4],["2009-11-10T23:00:10Z",7],["2009-11-10T23:00:15Z",10]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
 		&Query{
 			name:    "fill with none, i.e. clear out nulls",
 			command: `select mean(val) from fills where time >= '2009-11-10T23:00:00Z' and time < '2009-11-10T23:00:20Z' group by time(5s) FILL(none)`,
@@ -8698,6 +8980,52 @@ func TestServer_Query_IntoTarget(t *testing.T) {
 
 // Ensure that binary operators of aggregates of separate fields, when a field is sometimes missing and sometimes present,
 // result in values that are still properly time-aligned.
+// Ensure rollups write the points of expired shard groups beyond the write
+// limits of their database.
+func TestServer_Rollup_WriteLimits(t *testing.T) {
+	if RemoteEnabled() {
+		t.Skip("Skipping.  Cannot configure the retention service of a remote server")
+	}
+	t.Parallel()
+	c := NewConfig()
+	c.Retention.CheckInterval = toml.Duration(100 * time.Millisecond)
+	s := OpenServer(c)
+	defer s.Close()
+
+	if err := s.CreateDatabaseAndRetentionPolicy("db0", newRetentionPolicySpec("rp0", 1, 0), true); err != nil {
+		t.Fatal(err)
+	}
+	start := mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:00Z")
+	if _, err := s.Write("db0", "rp0", fmt.Sprintf("cpu,host=server01 value=1 %d\ncpu,host=server01 value=3 %d", start.UnixNano(), start.Add(time.Minute).UnixNano()), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, command := range []string{
+		`CREATE RETENTION POLICY rp1 ON db0 DURATION INF REPLICATION 1`,
+		`ALTER DATABASE db0 SET WRITE LIMIT past=1h`,
+		`ALTER RETENTION POLICY rp0 ON db0 DURATION 1h SHARD DURATION 1h ROLLUP BEGIN SELECT mean(value) INTO "rp1".:MEASUREMENT FROM /.*/ GROUP BY time(1h), * END`,
+	} {
+		if res, err := s.Query(command); err != nil {
+			t.Fatal(err)
+		} else if res != `{"results":[{"statement_id":0}]}` {
+			t.Fatalf("unexpected result for %s: %s", command, res)
+		}
+	}
+
+	// The shard group of rp0 has expired, so it is rolled up into rp1.
+	exp := `{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"server01"},"columns":["time","mean"],"values":[["2000-01-01T00:00:00Z",2]]}]}]}`
+	var got string
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		var err error
+		if got, err = s.QueryWithParams(`SELECT mean FROM rp1.cpu GROUP BY *`, url.Values{"db": []string{"db0"}}); err != nil {
+			t.Fatal(err)
+		} else if got == exp {
+			return
+		}
+	}
+	t.Fatalf("unexpected rollup: %s", got)
+}
+
 func TestServer_Query_IntoTarget_Sparse(t *testing.T) {
 	t.Parallel()
 	s := OpenServer(NewConfig())
@@ -0,0 +1,288 @@
+// Command generate writes the restore fixtures under testdata/restore.  It
+// must be built in a checkout of the tree the fixtures are made by; see
+// testdata/restore/README.md.
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+)
+
+const backupMagicHeader = 0x59590101
+
+type fileManifest struct {
+	Database        string                      `json:"database"`
+	RetentionPolicy string                      `json:"retentionPolicy"`
+	ShardID         uint64                      `json:"shardID"`
+	Precisions      map[string]map[string]int64 `json:"precisions"`
+}
+
+var fixtures = map[string]func(dir string) error{
+	"full": func(dir string) error {
+		if err := writeMetaBackup(filepath.Join(dir, "meta.00"), fixtureMetaData()); err != nil {
+			return err
+		}
+		return writeShardBackup(filepath.Join(dir, "mydb.forever.00001.00"), "mydb/forever/1", map[string]fixtureValues{
+			"000000001-000000001.tsm": {"cpu,host=a#!~#value": {tsm1.NewValue(1e9, 1.0), tsm1.NewValue(2e9, 2.0)}},
+		})
+	},
+	"incremental": func(dir string) error {
+		if err := writeMetaBackup(filepath.Join(dir, "meta.00"), fixtureMetaData()); err != nil {
+			return err
+		}
+		if err := writeShardBackup(filepath.Join(dir, "mydb.forever.00001.00"), "mydb/forever/1", map[string]fixtureValues{
+			"000000001-000000001.tsm": {"cpu,host=a#!~#value": {tsm1.NewValue(1e9, 1.0)}},
+		}); err != nil {
+			return err
+		}
+		return writeShardBackup(filepath.Join(dir, "mydb.forever.00001.01"), "mydb/forever/1", map[string]fixtureValues{
+			"000000002-000000001.tsm": {"cpu,host=a#!~#value": {tsm1.NewValue(2e9, 2.0)}},
+		})
+	},
+	"multiple-metastore-snapshots": func(dir string) error {
+		if err := writeMetaBackup(filepath.Join(dir, "meta.00"), fixtureMetaData()); err != nil {
+			return err
+		}
+		data := fixtureMetaData()
+		if err := data.CreateDatabase("newer"); err != nil {
+			return err
+		}
+		if err := writeMetaBackup(filepath.Join(dir, "meta.01"), data); err != nil {
+			return err
+		}
+		return writeShardBackup(filepath.Join(dir, "mydb.forever.00001.00"), "mydb/forever/1", map[string]fixtureValues{
+			"000000001-000000001.tsm": {"cpu,host=a#!~#value": {tsm1.NewValue(1e9, 1.0)}},
+		})
+	},
+	"tombstones": func(dir string) error {
+		if err := writeMetaBackup(filepath.Join(dir, "meta.00"), fixtureMetaData()); err != nil {
+			return err
+		}
+		return writeShardBackupWith(filepath.Join(dir, "mydb.forever.00001.00"), "mydb/forever/1", map[string]fixtureValues{
+			"000000001-000000001.tsm": {
+				"cpu,host=a#!~#value": {tsm1.NewValue(1e9, 1.0)},
+				"cpu,host=b#!~#value": {tsm1.NewValue(1e9, 2.0)},
+			},
+		}, func(r *tsm1.TSMReader) error {
+			return r.Delete([][]byte{[]byte("cpu,host=b#!~#value")})
+		})
+	},
+	"single-point": func(dir string) error {
+		if err := writeMetaBackup(filepath.Join(dir, "meta.00"), fixtureMetaData()); err != nil {
+			return err
+		}
+		return writeShardBackup(filepath.Join(dir, "mydb.forever.00001.00"), "mydb/forever/1", map[string]fixtureValues{
+			"000000001-000000001.tsm": {"cpu,host=a#!~#value": {tsm1.NewValue(1e9, 1.0)}},
+		})
+	},
+	"manifest-with-precision": func(dir string) error {
+		if err := writeMetaBackup(filepath.Join(dir, "meta.00"), fixtureMetaData()); err != nil {
+			return err
+		}
+		if err := writeShardBackup(filepath.Join(dir, "mydb.forever.00001.00"), "mydb/forever/1", map[string]fixtureValues{
+			"000000001-000000001.tsm": {"cpu,host=a#!~#value": {tsm1.NewValue(1e9+5e8, 1.0), tsm1.NewValue(2e9, 2.0)}},
+		}); err != nil {
+			return err
+		}
+		m := map[string]interface{}{"files": map[string]*fileManifest{
+			"mydb.forever.00001.00": {
+				Database:        "mydb",
+				RetentionPolicy: "forever",
+				ShardID:         1,
+				Precisions:      map[string]map[string]int64{"cpu": {"ms": 1, "s": 1}},
+			},
+		}}
+		b, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dir, "manifest"), b, 0600)
+	},
+	"scrub": func(dir string) error {
+		if err := writeMetaBackup(filepath.Join(dir, "meta.00"), fixtureMetaData()); err != nil {
+			return err
+		}
+		return writeShardBackupWith(filepath.Join(dir, "mydb.forever.00001.00"), "mydb/forever/1", map[string]fixtureValues{
+			"000000001-000000001.tsm": {
+				"cpu,host=a#!~#user":  {tsm1.NewValue(1e9, "alice")},
+				"cpu,host=a#!~#value": {tsm1.NewValue(1e9, 1.0)},
+				"cpu,host=b#!~#value": {tsm1.NewValue(2e9, 2.0)},
+				"cpu,host=c#!~#value": {tsm1.NewValue(3e9, 3.0)},
+			},
+		}, func(r *tsm1.TSMReader) error {
+			return r.Delete([][]byte{[]byte("cpu,host=b#!~#value")})
+		})
+	},
+}
+
+func main() {
+	root := os.Args[1]
+	for name, fn := range fixtures {
+		dir := filepath.Join(root, name)
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			panic(err)
+		}
+		if err := fn(dir); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// fixtureValues maps composite series and field keys to their values.
+type fixtureValues map[string][]tsm1.Value
+
+// fixtureMetaData returns metadata with a database "mydb", whose default
+// retention policy "forever" has a single shard with ID 1 starting at the epoch.
+func fixtureMetaData() *meta.Data {
+	data := &meta.Data{Index: 1, ClusterID: 1}
+	if err := data.CreateDatabase("mydb"); err != nil {
+		panic(err)
+	}
+
+	rpi := &meta.RetentionPolicyInfo{Name: "forever", ReplicaN: 1, ShardGroupDuration: 7 * 24 * time.Hour}
+	if err := data.CreateRetentionPolicy("mydb", rpi, true); err != nil {
+		panic(err)
+	}
+	if err := data.CreateShardGroup("mydb", "forever", time.Unix(0, 0)); err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// writeMetaBackup writes a metastore snapshot in the format sent by the snapshotter.
+func writeMetaBackup(path string, data *meta.Data) error {
+	metaBlob, err := data.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	nodeBytes, err := json.Marshal(&influxdb.Node{ID: 1})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], backupMagicHeader)
+	buf.Write(n[:])
+	binary.BigEndian.PutUint64(n[:], uint64(len(metaBlob)))
+	buf.Write(n[:])
+	buf.Write(metaBlob)
+	binary.BigEndian.PutUint64(n[:], uint64(len(nodeBytes)))
+	buf.Write(n[:])
+	buf.Write(nodeBytes)
+	return ioutil.WriteFile(path, buf.Bytes(), 0666)
+}
+
+// writeShardBackup writes a shard archive containing the given TSM files.
+func writeShardBackup(path, shardRelativePath string, files map[string]fixtureValues) error {
+	return writeShardBackupWith(path, shardRelativePath, files, nil)
+}
+
+// writeShardBackupWith writes a shard archive containing the given TSM files
+// and any files created by calling fn with a reader for each of them.
+func writeShardBackupWith(path, shardRelativePath string, files map[string]fixtureValues, fn func(r *tsm1.TSMReader) error) error {
+	dir, err := ioutil.TempDir("", "restore-compat-shard")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	for name, values := range files {
+		if err := writeFixtureTSM(filepath.Join(dir, name), values, fn); err != nil {
+			return err
+		}
+	}
+
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	for _, fi := range fis {
+		b, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return err
+		}
+
+		hdr := &tar.Header{
+			Name:    filepath.ToSlash(filepath.Join(shardRelativePath, fi.Name())),
+			ModTime: fi.ModTime(),
+			Size:    int64(len(b)),
+			Mode:    0666,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		} else if _, err := tw.Write(b); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// writeFixtureTSM writes values to a TSM file at path.
+func writeFixtureTSM(path string, values fixtureValues, fn func(r *tsm1.TSMReader) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := w.Write([]byte(k), values[k]); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if fn == nil {
+		return nil
+	}
+
+	f, err = os.Open(path)
+	if err != nil {
+		return err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	defer r.Close()
+	return fn(r)
+}
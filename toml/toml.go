@@ -2,6 +2,7 @@
 package toml // import "github.com/influxdata/influxdb/toml"
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -43,27 +44,35 @@ func (d Duration) MarshalText() (text []byte, err error) {
 
 // Size represents a TOML parseable file size.
 // Users can specify size using "m" for megabytes and "g" for gigabytes.
+// A size without a unit is in bytes.
 type Size int
 
 // UnmarshalText parses a byte size from text.
 func (s *Size) UnmarshalText(text []byte) error {
-	// Parse numeric portion of value.
-	length := len(string(text))
-	size, err := strconv.ParseInt(string(text[:length-1]), 10, 64)
-	if err != nil {
-		return err
+	if len(text) == 0 {
+		return fmt.Errorf("size was empty")
 	}
 
-	// Parse unit of measure ("m", "g", etc).
-	switch suffix := text[len(text)-1]; suffix {
-	case 'm':
-		size *= 1 << 20 // MB
-	case 'g':
-		size *= 1 << 30 // GB
+	// Parse unit of measure ("m", "g", etc).  A size without a unit is in bytes.
+	numeric, unit := text[:len(text)-1], int64(1)
+	switch suffix := text[len(text)-1]; {
+	case suffix == 'm':
+		unit = 1 << 20 // MB
+	case suffix == 'g':
+		unit = 1 << 30 // GB
+	case suffix >= '0' && suffix <= '9':
+		numeric = text
 	default:
 		return fmt.Errorf("unknown size suffix: %c", suffix)
 	}
 
+	// Parse numeric portion of value.
+	size, err := strconv.ParseInt(string(numeric), 10, 64)
+	if err != nil {
+		return err
+	}
+	size *= unit
+
 	// Check for overflow.
 	if size > maxInt {
 		return fmt.Errorf("size %d cannot be represented by an int", size)
@@ -72,3 +81,31 @@ func (s *Size) UnmarshalText(text []byte) error {
 	*s = Size(size)
 	return nil
 }
+
+// FileMode is a TOML wrapper for the permissions of a file, written in octal
+// such as "0770".
+type FileMode uint32
+
+// UnmarshalText parses an octal file mode from text.
+func (m *FileMode) UnmarshalText(text []byte) error {
+	// Ignore if there is no value set.
+	if len(text) == 0 {
+		return nil
+	}
+
+	mode, err := strconv.ParseUint(string(text), 8, 32)
+	if err != nil {
+		return err
+	} else if mode == 0 {
+		return errors.New("file mode cannot be zero")
+	} else if mode > 0777 {
+		return fmt.Errorf("file mode %04o is not a permission", mode)
+	}
+	*m = FileMode(mode)
+	return nil
+}
+
+// MarshalText converts a file mode to octal text.
+func (m FileMode) MarshalText() (text []byte, err error) {
+	return []byte(fmt.Sprintf("%04o", uint32(m))), nil
+}
@@ -31,6 +31,32 @@ func TestSize_UnmarshalText_GB(t *testing.T) {
 	}
 }
 
+// Ensure that sizes without a unit are parsed as bytes.
+func TestSize_UnmarshalText_Bytes(t *testing.T) {
+	var s itoml.Size
+	if err := s.UnmarshalText([]byte("1048576")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if s != 1048576 {
+		t.Fatalf("unexpected size: %d", s)
+	}
+}
+
+// Ensure that file modes are parsed as octal permissions.
+func TestFileMode_UnmarshalText(t *testing.T) {
+	var m itoml.FileMode
+	if err := m.UnmarshalText([]byte("0770")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if m != 0770 {
+		t.Fatalf("unexpected file mode: %o", m)
+	}
+
+	for _, text := range []string{"0", "0888", "01777"} {
+		if err := m.UnmarshalText([]byte(text)); err == nil {
+			t.Fatalf("expected error for file mode %q", text)
+		}
+	}
+}
+
 func TestConfig_Encode(t *testing.T) {
 	var c run.Config
 	c.Coordinator.WriteTimeout = itoml.Duration(time.Minute)
@@ -3,9 +3,12 @@ package tsdb
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/pkg/encryption"
+	"github.com/influxdata/influxdb/pkg/s3"
 	"github.com/influxdata/influxdb/toml"
 )
 
@@ -33,6 +36,10 @@ const (
 	// the shard hasn't received writes or deletes
 	DefaultCacheSnapshotWriteColdDuration = time.Duration(10 * time.Minute)
 
+	// DefaultBackfillSnapshotMemorySize is the size at which the engine will
+	// snapshot the backfill cache and write it to a TSM file
+	DefaultBackfillSnapshotMemorySize = 256 * 1024 * 1024 // 256MB
+
 	// DefaultCompactFullWriteColdDuration is the duration at which the engine
 	// will compact all TSM files in a shard if it hasn't received a write or delete
 	DefaultCompactFullWriteColdDuration = time.Duration(4 * time.Hour)
@@ -51,6 +58,64 @@ const (
 	// DefaultMaxConcurrentCompactions is the maximum number of concurrent full and level compactions
 	// that can run at one time.  A value of 0 results in 50% of runtime.GOMAXPROCS(0) used at runtime.
 	DefaultMaxConcurrentCompactions = 0
+
+	// DefaultTSMMmapMaxAge is the age of the newest data in a TSM file after which
+	// the file is read with pread rather than memory-mapped. A value of 0 memory-maps
+	// every file.
+	DefaultTSMMmapMaxAge = time.Duration(0)
+
+	// DefaultBackupDropPageCache is whether files read for backups are dropped from
+	// the OS page cache as they are read.
+	DefaultBackupDropPageCache = true
+
+	// DefaultTieringAge is the time since a shard was last modified after which
+	// its files are offloaded to the tiering store.
+	DefaultTieringAge = time.Duration(30 * 24 * time.Hour)
+
+	// DefaultTieringCheckInterval is how often shards are checked for offloading.
+	DefaultTieringCheckInterval = time.Duration(time.Hour)
+
+	// DefaultTieringCacheMaxSize is the maximum size of the local cache of the
+	// blocks of offloaded files.
+	DefaultTieringCacheMaxSize = 10 * 1024 * 1024 * 1024 // 10GB
+
+	// DefaultScrubInterval is the time between the passes of the scrubber over the
+	// TSM files of all shards.
+	DefaultScrubInterval = time.Duration(24 * time.Hour)
+
+	// DefaultScrubIOLimit is the maximum rate, in bytes per second, at which the
+	// scrubber reads TSM files.
+	DefaultScrubIOLimit = 10 * 1024 * 1024 // 10MB
+
+	// TSMCompressionZstd compresses the float and string blocks of TSM files with zstd.
+	TSMCompressionZstd = "zstd"
+
+	// WALSyncEveryWrite fsyncs the WAL before each write returns.  Writes waiting at the
+	// same time share an fsync, and wal-fsync-delay delays fsyncs to share them further.
+	WALSyncEveryWrite = "every-write"
+
+	// WALSyncInterval returns writes once they are written to the WAL, which is fsynced
+	// every wal-fsync-delay.
+	WALSyncInterval = "interval"
+
+	// WALSyncOSDefault returns writes once they are written to the WAL, and leaves
+	// flushing them to disk to the OS.  Segments are fsynced when they are closed.
+	WALSyncOSDefault = "os-default"
+
+	// DefaultWALSyncMode is the default WAL sync mode.
+	DefaultWALSyncMode = WALSyncEveryWrite
+
+	// FieldTypeConflictReject drops points with a field whose type conflicts with the
+	// type the field already has in the shard.
+	FieldTypeConflictReject = "reject"
+
+	// FieldTypeConflictCoerce casts conflicting integer and float fields to the type of
+	// the field.  Other conflicts are rejected.
+	FieldTypeConflictCoerce = "coerce"
+
+	// FieldTypeConflictRename writes conflicting fields to a field named after the field
+	// and suffixed with their type, such as value_str.
+	FieldTypeConflictRename = "rename"
 )
 
 // Config holds the configuration for the tsbd package.
@@ -59,6 +124,11 @@ type Config struct {
 	Engine string `toml:"-"`
 	Index  string `toml:"index-version"`
 
+	// TSI1Databases lists databases whose new shards use the "tsi1" index, whatever
+	// the index-version.  Existing shards keep the index they were created with until
+	// converted with "influx_inspect inmem2tsi".
+	TSI1Databases []string `toml:"tsi1-databases"`
+
 	// General WAL configuration options
 	WALDir string `toml:"wal-dir"`
 
@@ -67,6 +137,11 @@ type Config struct {
 	// disks or when WAL write contention is seen.  A value of 0 fsyncs every write to the WAL.
 	WALFsyncDelay toml.Duration `toml:"wal-fsync-delay"`
 
+	// WALSyncMode is when writes to the WAL are fsynced: "every-write", "interval" or
+	// "os-default".  Modes other than "every-write" trade the durability of recent writes
+	// on power loss for write throughput, and suit battery-backed or replicated storage.
+	WALSyncMode string `toml:"wal-sync-mode"`
+
 	// Query logging
 	QueryLogEnabled bool `toml:"query-log-enabled"`
 
@@ -76,6 +151,21 @@ type Config struct {
 	CacheSnapshotWriteColdDuration toml.Duration `toml:"cache-snapshot-write-cold-duration"`
 	CompactFullWriteColdDuration   toml.Duration `toml:"compact-full-write-cold-duration"`
 
+	// CacheSnapshotOnShutdown writes the cache of each shard, and the keys of its TSM files,
+	// to a snapshot in its WAL directory on shutdown.  On startup, the cache is loaded from
+	// the snapshot instead of replaying the WAL, and the index from the keys instead of the
+	// index of every TSM file, unless the WAL or TSM files changed since.
+	CacheSnapshotOnShutdown bool `toml:"cache-snapshot-on-shutdown"`
+
+	// BackfillThreshold is the age of points, relative to the time they are written, after
+	// which they are written to a separate backfill cache and WAL rather than the cache.  The
+	// backfill cache is written to TSM files once it reaches BackfillSnapshotMemorySize, or
+	// is cold, so historical imports are written in large batches rather than mixed into
+	// every snapshot of recent points.  A value of 0 disables the backfill cache; a backfill
+	// WAL left from when it was enabled is still replayed and written to TSM files.
+	BackfillThreshold          toml.Duration `toml:"backfill-threshold"`
+	BackfillSnapshotMemorySize uint64        `toml:"backfill-snapshot-memory-size"`
+
 	// Limits
 
 	// MaxSeriesPerDatabase is the maximum number of series a node can hold per database.
@@ -94,6 +184,125 @@ type Config struct {
 	// not affected by this limit.  A value of 0 limits compactions to runtime.GOMAXPROCS(0).
 	MaxConcurrentCompactions int `toml:"max-concurrent-compactions"`
 
+	// MaxConcurrentFullCompactions is the maximum number of concurrent full and optimize
+	// compactions across all shards, within the limit of MaxConcurrentCompactions.  Full
+	// compactions rewrite whole shards, so limiting them leaves room for level compactions.
+	// A value of 0 only applies MaxConcurrentCompactions.
+	MaxConcurrentFullCompactions int `toml:"max-concurrent-full-compactions"`
+
+	// CompactFullWindows restricts full and optimize compactions to times of day, in local
+	// time, such as "01:00-05:00".  A window ending before it starts spans midnight.  Level
+	// compactions are not restricted.  An empty list allows full compactions at any time.
+	CompactFullWindows []string `toml:"compact-full-windows"`
+
+	// Read path options
+
+	// TSMMmapMaxAge is the age of the newest data in a TSM file after which the file is
+	// read with pread rather than memory-mapped, so that queries of old data do not keep
+	// it in the page cache. A value of 0 memory-maps every file.
+	TSMMmapMaxAge toml.Duration `toml:"tsm-mmap-max-age"`
+
+	// BackupDropPageCache drops the pages of files read for backups from the OS page
+	// cache as they are read, so that backups do not evict the working set of queries.
+	// Pages of memory-mapped files are not dropped.
+	BackupDropPageCache bool `toml:"backup-drop-page-cache"`
+
+	// TSMCompression is the compression of the blocks of TSM files written by compactions.
+	// An empty value keeps the default encodings of each type, while "zstd" recompresses
+	// float and string blocks with zstd, using a dictionary trained on the data of each shard.
+	// Files written with either compression are always readable.
+	TSMCompression string `toml:"tsm-compression"`
+
+	// TSMCompressionDatabases restricts TSMCompression to the listed databases.  Shards of
+	// other databases keep the default encodings.  An empty list applies it to all databases.
+	TSMCompressionDatabases []string `toml:"tsm-compression-databases"`
+
+	// FieldTypeConflict is how writes of a field with a type other than the type the field
+	// already has are handled: "reject", "coerce" or "rename".
+	FieldTypeConflict string `toml:"field-type-conflict"`
+
+	// FieldTypeConflictDatabases maps databases to how conflicts are handled in writes to
+	// them, overriding FieldTypeConflict.
+	FieldTypeConflictDatabases map[string]string `toml:"field-type-conflict-databases"`
+
+	// IO limits, in bytes per second.  A value of 0 disables a limit.
+
+	// IOLimit is the total IO allowed to compactions, backups, restores and shard deletes
+	// by the retention policy service.  When it is exceeded, restores proceed before backups,
+	// and backups before compactions and deletes.  Writes to the WAL count against the limit
+	// but are never throttled.
+	IOLimit toml.Size `toml:"io-limit"`
+
+	// CompactionIOLimit limits the IO of level and full compactions across all shards.
+	// Snapshots of the cache are not limited.
+	CompactionIOLimit toml.Size `toml:"compaction-io-limit"`
+
+	// BackupIOLimit limits the IO of shard backups.
+	BackupIOLimit toml.Size `toml:"backup-io-limit"`
+
+	// RestoreIOLimit limits the IO of shard restores.
+	RestoreIOLimit toml.Size `toml:"restore-io-limit"`
+
+	// RetentionIOLimit limits the rate at which the files of deleted shards are removed.
+	RetentionIOLimit toml.Size `toml:"retention-io-limit"`
+
+	// Tiering options
+
+	// TieringPath is where the files of cold shards are offloaded to: a local directory,
+	// such as a mount of network storage, or an s3://bucket/prefix or gs://bucket/prefix
+	// URL.  Offloaded files are replaced locally by a stub holding their index, and their
+	// blocks are fetched when queried.  An empty path disables tiering.
+	TieringPath string `toml:"tiering-path"`
+
+	// TieringS3Region and TieringS3Endpoint override the region and endpoint of the bucket.
+	TieringS3Region   string `toml:"tiering-s3-region"`
+	TieringS3Endpoint string `toml:"tiering-s3-endpoint"`
+
+	// TieringAge is the time since a shard was last written to after which its files
+	// are offloaded.
+	TieringAge toml.Duration `toml:"tiering-age"`
+
+	// TieringCheckInterval is how often shards are checked for offloading.
+	TieringCheckInterval toml.Duration `toml:"tiering-check-interval"`
+
+	// TieringCacheDir is where fetched blocks are cached.  It defaults to a tiering-cache
+	// directory beside the data directory.
+	TieringCacheDir string `toml:"tiering-cache-dir"`
+
+	// TieringCacheMaxSize is the maximum size of the cache.  A value of 0 disables it.
+	TieringCacheMaxSize toml.Size `toml:"tiering-cache-max-size"`
+
+	// Encryption options
+
+	// EncryptionKeyProvider is the provider of the keys TSM files and WAL segments are
+	// encrypted with: "file" reads keys from the file at EncryptionKeySource, and other
+	// providers may be registered with the encryption package.  An empty value disables
+	// encryption.  Series keys, in the indexes of TSM files and shards, are not encrypted.
+	EncryptionKeyProvider string `toml:"encryption-key-provider"`
+
+	// EncryptionKeySource is where the provider reads keys from, such as a key file path.
+	EncryptionKeySource string `toml:"encryption-key-source"`
+
+	// Scrubbing options
+
+	// ScrubEnabled starts a scrubber that re-reads the blocks of the TSM files of all shards
+	// in the background, checking them against their checksums, so that corruption is found
+	// before a query reads it.  Corrupt blocks are logged and counted in the tsm1_filestore
+	// statistics.
+	ScrubEnabled bool `toml:"scrub-enabled"`
+
+	// ScrubInterval is the time between passes of the scrubber over all shards.
+	ScrubInterval toml.Duration `toml:"scrub-interval"`
+
+	// ScrubIOLimit limits the IO of the scrubber, which takes from the io-limit budget after
+	// all other IO.  A value of 0 disables the limit.
+	ScrubIOLimit toml.Size `toml:"scrub-io-limit"`
+
+	// ScrubQuarantineDir is where TSM files with corrupt blocks are moved to, under the path
+	// of their shard, which no longer reads them.  An empty directory leaves corrupt files in
+	// place.
+	ScrubQuarantineDir string `toml:"scrub-quarantine-dir"`
+
 	TraceLoggingEnabled bool `toml:"trace-logging-enabled"`
 }
 
@@ -103,17 +312,31 @@ func NewConfig() Config {
 		Engine: DefaultEngine,
 		Index:  DefaultIndex,
 
+		WALSyncMode:       DefaultWALSyncMode,
+		FieldTypeConflict: FieldTypeConflictReject,
+
 		QueryLogEnabled: true,
 
 		CacheMaxMemorySize:             DefaultCacheMaxMemorySize,
 		CacheSnapshotMemorySize:        DefaultCacheSnapshotMemorySize,
 		CacheSnapshotWriteColdDuration: toml.Duration(DefaultCacheSnapshotWriteColdDuration),
 		CompactFullWriteColdDuration:   toml.Duration(DefaultCompactFullWriteColdDuration),
+		BackfillSnapshotMemorySize:     DefaultBackfillSnapshotMemorySize,
 
 		MaxSeriesPerDatabase:     DefaultMaxSeriesPerDatabase,
 		MaxValuesPerTag:          DefaultMaxValuesPerTag,
 		MaxConcurrentCompactions: DefaultMaxConcurrentCompactions,
 
+		TSMMmapMaxAge:       toml.Duration(DefaultTSMMmapMaxAge),
+		BackupDropPageCache: DefaultBackupDropPageCache,
+
+		TieringAge:           toml.Duration(DefaultTieringAge),
+		TieringCheckInterval: toml.Duration(DefaultTieringCheckInterval),
+		TieringCacheMaxSize:  DefaultTieringCacheMaxSize,
+
+		ScrubInterval: toml.Duration(DefaultScrubInterval),
+		ScrubIOLimit:  DefaultScrubIOLimit,
+
 		TraceLoggingEnabled: false,
 	}
 }
@@ -126,8 +349,101 @@ func (c *Config) Validate() error {
 		return errors.New("Data.WALDir must be specified")
 	}
 
+	switch c.WALSyncMode {
+	case "", WALSyncEveryWrite, WALSyncOSDefault:
+	case WALSyncInterval:
+		if c.WALFsyncDelay <= 0 {
+			return errors.New("wal-fsync-delay must be greater than 0 when wal-sync-mode is interval")
+		}
+	default:
+		return fmt.Errorf("unrecognized wal-sync-mode %s", c.WALSyncMode)
+	}
+
 	if c.MaxConcurrentCompactions < 0 {
 		return errors.New("max-concurrent-compactions must be greater than 0")
+	} else if c.MaxConcurrentFullCompactions < 0 {
+		return errors.New("max-concurrent-full-compactions must be greater than or equal to 0")
+	}
+
+	if _, err := c.FullCompactionWindows(); err != nil {
+		return err
+	}
+
+	for name, limit := range map[string]toml.Size{
+		"io-limit":            c.IOLimit,
+		"compaction-io-limit": c.CompactionIOLimit,
+		"backup-io-limit":     c.BackupIOLimit,
+		"restore-io-limit":    c.RestoreIOLimit,
+		"retention-io-limit":  c.RetentionIOLimit,
+	} {
+		if limit < 0 {
+			return fmt.Errorf("%s must be greater than or equal to 0", name)
+		}
+	}
+
+	if c.TSMMmapMaxAge < 0 {
+		return errors.New("tsm-mmap-max-age must be greater than or equal to 0")
+	}
+
+	if c.BackfillThreshold < 0 {
+		return errors.New("backfill-threshold must be greater than or equal to 0")
+	}
+
+	switch c.FieldTypeConflict {
+	case "", FieldTypeConflictReject, FieldTypeConflictCoerce, FieldTypeConflictRename:
+	default:
+		return fmt.Errorf("unrecognized field-type-conflict %s", c.FieldTypeConflict)
+	}
+	for name, mode := range c.FieldTypeConflictDatabases {
+		switch mode {
+		case FieldTypeConflictReject, FieldTypeConflictCoerce, FieldTypeConflictRename:
+		default:
+			return fmt.Errorf("unrecognized field-type-conflict %s for database %s", mode, name)
+		}
+	}
+
+	switch c.TSMCompression {
+	case "", TSMCompressionZstd:
+	default:
+		return fmt.Errorf("unrecognized tsm-compression %s", c.TSMCompression)
+	}
+
+	if c.EncryptionKeyProvider != "" {
+		valid := false
+		for _, p := range encryption.RegisteredKeyProviders() {
+			if p == c.EncryptionKeyProvider {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unrecognized encryption-key-provider %s", c.EncryptionKeyProvider)
+		} else if c.EncryptionKeySource == "" {
+			return errors.New("encryption-key-source must be set")
+		}
+	}
+
+	if c.TieringPath != "" {
+		if s3.IsURL(c.TieringPath) {
+			if _, _, err := s3.ParseBucket(c.TieringPath); err != nil {
+				return fmt.Errorf("invalid tiering-path: %s", err)
+			}
+		}
+		if c.TieringAge <= 0 {
+			return errors.New("tiering-age must be greater than 0")
+		} else if c.TieringCheckInterval <= 0 {
+			return errors.New("tiering-check-interval must be greater than 0")
+		} else if c.TieringCacheMaxSize < 0 {
+			return errors.New("tiering-cache-max-size must be greater than or equal to 0")
+		}
+	}
+
+	if c.ScrubEnabled {
+		if c.ScrubInterval <= 0 {
+			return errors.New("scrub-interval must be greater than 0")
+		} else if c.ScrubIOLimit < 0 {
+			return errors.New("scrub-io-limit must be greater than or equal to 0")
+		}
 	}
 
 	valid := false
@@ -155,18 +471,134 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// FullCompactionWindows returns the parsed CompactFullWindows.
+func (c Config) FullCompactionWindows() ([]TimeOfDayWindow, error) {
+	var windows []TimeOfDayWindow
+	for _, s := range c.CompactFullWindows {
+		w, err := ParseTimeOfDayWindow(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compact-full-windows: %s", err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// TSMCompressionFor returns the compression of the TSM files of shards of database.
+func (c Config) TSMCompressionFor(database string) string {
+	if len(c.TSMCompressionDatabases) == 0 {
+		return c.TSMCompression
+	}
+	for _, name := range c.TSMCompressionDatabases {
+		if name == database {
+			return c.TSMCompression
+		}
+	}
+	return ""
+}
+
+// FieldTypeConflictFor returns how field type conflicts are handled in writes to database.
+func (c Config) FieldTypeConflictFor(database string) string {
+	if mode, ok := c.FieldTypeConflictDatabases[database]; ok {
+		return mode
+	} else if c.FieldTypeConflict == "" {
+		return FieldTypeConflictReject
+	}
+	return c.FieldTypeConflict
+}
+
 // Diagnostics returns a diagnostics representation of a subset of the Config.
 func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 	return diagnostics.RowFromMap(map[string]interface{}{
 		"dir":                                c.Dir,
+		"index-version":                      c.Index,
+		"tsi1-databases":                     c.TSI1Databases,
 		"wal-dir":                            c.WALDir,
 		"wal-fsync-delay":                    c.WALFsyncDelay,
+		"wal-sync-mode":                      c.WALSyncMode,
 		"cache-max-memory-size":              c.CacheMaxMemorySize,
 		"cache-snapshot-memory-size":         c.CacheSnapshotMemorySize,
 		"cache-snapshot-write-cold-duration": c.CacheSnapshotWriteColdDuration,
 		"compact-full-write-cold-duration":   c.CompactFullWriteColdDuration,
+		"cache-snapshot-on-shutdown":         c.CacheSnapshotOnShutdown,
+		"backfill-threshold":                 c.BackfillThreshold,
+		"backfill-snapshot-memory-size":      c.BackfillSnapshotMemorySize,
 		"max-series-per-database":            c.MaxSeriesPerDatabase,
 		"max-values-per-tag":                 c.MaxValuesPerTag,
 		"max-concurrent-compactions":         c.MaxConcurrentCompactions,
+		"max-concurrent-full-compactions":    c.MaxConcurrentFullCompactions,
+		"compact-full-windows":               c.CompactFullWindows,
+		"tsm-mmap-max-age":                   c.TSMMmapMaxAge,
+		"backup-drop-page-cache":             c.BackupDropPageCache,
+		"tsm-compression":                    c.TSMCompression,
+		"tsm-compression-databases":          c.TSMCompressionDatabases,
+		"field-type-conflict":                c.FieldTypeConflict,
+		"field-type-conflict-databases":      c.FieldTypeConflictDatabases,
+		"io-limit":                           c.IOLimit,
+		"compaction-io-limit":                c.CompactionIOLimit,
+		"backup-io-limit":                    c.BackupIOLimit,
+		"restore-io-limit":                   c.RestoreIOLimit,
+		"retention-io-limit":                 c.RetentionIOLimit,
+		"tiering-path":                       c.TieringPath,
+		"tiering-age":                        c.TieringAge,
+		"tiering-check-interval":             c.TieringCheckInterval,
+		"tiering-cache-dir":                  c.TieringCacheDir,
+		"tiering-cache-max-size":             c.TieringCacheMaxSize,
+		"encryption-key-provider":            c.EncryptionKeyProvider,
+		"scrub-enabled":                      c.ScrubEnabled,
+		"scrub-interval":                     c.ScrubInterval,
+		"scrub-io-limit":                     c.ScrubIOLimit,
+		"scrub-quarantine-dir":               c.ScrubQuarantineDir,
 	}), nil
 }
+
+// TimeOfDayWindow is a daily window of time, as offsets from midnight.
+type TimeOfDayWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseTimeOfDayWindow parses a window written as "HH:MM-HH:MM".
+func ParseTimeOfDayWindow(s string) (TimeOfDayWindow, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return TimeOfDayWindow{}, fmt.Errorf("window %q must be written as HH:MM-HH:MM", s)
+	}
+
+	var w TimeOfDayWindow
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			return TimeOfDayWindow{}, fmt.Errorf("window %q must be written as HH:MM-HH:MM", s)
+		}
+		d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		if i == 0 {
+			w.Start = d
+		} else {
+			w.End = d
+		}
+	}
+
+	if w.Start == w.End {
+		return TimeOfDayWindow{}, fmt.Errorf("window %q is empty", s)
+	}
+	return w, nil
+}
+
+// Contains returns true if t is within the window, in the location of t.
+func (w TimeOfDayWindow) Contains(t time.Time) bool {
+	d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	if w.Start < w.End {
+		return d >= w.Start && d < w.End
+	}
+	// The window spans midnight.
+	return d >= w.Start || d < w.End
+}
+
+// String returns the window as "HH:MM-HH:MM".
+func (w TimeOfDayWindow) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d",
+		int(w.Start/time.Hour), int(w.Start%time.Hour/time.Minute),
+		int(w.End/time.Hour), int(w.End%time.Hour/time.Minute))
+}
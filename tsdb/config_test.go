@@ -1,10 +1,12 @@
 package tsdb_test
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/BurntSushi/toml"
+	itoml "github.com/influxdata/influxdb/toml"
 	"github.com/influxdata/influxdb/tsdb"
 )
 
@@ -15,6 +17,24 @@ func TestConfig_Parse(t *testing.T) {
 dir = "/var/lib/influxdb/data"
 wal-dir = "/var/lib/influxdb/wal"
 wal-fsync-delay = "10s"
+wal-sync-mode = "interval"
+cache-snapshot-on-shutdown = true
+backfill-threshold = "24h"
+tsm-mmap-max-age = "168h"
+backup-drop-page-cache = false
+tsm-compression = "zstd"
+max-concurrent-full-compactions = 1
+compact-full-windows = ["01:00-05:00", "22:30-00:30"]
+tsm-compression-databases = ["telegraf"]
+field-type-conflict = "coerce"
+field-type-conflict-databases = { db0 = "reject", metrics = "rename" }
+io-limit = "100m"
+compaction-io-limit = 1048576
+tiering-path = "s3://bucket/influxdb"
+tiering-age = "2160h"
+tiering-cache-max-size = "1g"
+encryption-key-provider = "file"
+encryption-key-source = "/etc/influxdb/keys"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -32,7 +52,65 @@ wal-fsync-delay = "10s"
 	if got, exp := c.WALFsyncDelay, time.Duration(10*time.Second); time.Duration(got).Nanoseconds() != exp.Nanoseconds() {
 		t.Errorf("unexpected wal-fsync-delay:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
 	}
-
+	if got, exp := c.WALSyncMode, tsdb.WALSyncInterval; got != exp {
+		t.Errorf("unexpected wal-sync-mode:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if !c.CacheSnapshotOnShutdown {
+		t.Error("expected cache-snapshot-on-shutdown to be true")
+	}
+	if got, exp := time.Duration(c.BackfillThreshold), 24*time.Hour; got != exp {
+		t.Errorf("unexpected backfill-threshold:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.BackfillSnapshotMemorySize, uint64(tsdb.DefaultBackfillSnapshotMemorySize); got != exp {
+		t.Errorf("unexpected backfill-snapshot-memory-size:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := time.Duration(c.TSMMmapMaxAge), 168*time.Hour; got != exp {
+		t.Errorf("unexpected tsm-mmap-max-age:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if c.BackupDropPageCache {
+		t.Error("expected backup-drop-page-cache to be false")
+	}
+	if got, exp := c.TSMCompressionFor("telegraf"), tsdb.TSMCompressionZstd; got != exp {
+		t.Errorf("unexpected tsm-compression for telegraf:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got := c.TSMCompressionFor("db0"); got != "" {
+		t.Errorf("unexpected tsm-compression for db0: %v", got)
+	}
+	if got, exp := c.FieldTypeConflictFor("telegraf"), tsdb.FieldTypeConflictCoerce; got != exp {
+		t.Errorf("unexpected field-type-conflict for telegraf:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.FieldTypeConflictFor("db0"), tsdb.FieldTypeConflictReject; got != exp {
+		t.Errorf("unexpected field-type-conflict for db0:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.FieldTypeConflictFor("metrics"), tsdb.FieldTypeConflictRename; got != exp {
+		t.Errorf("unexpected field-type-conflict for metrics:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.MaxConcurrentFullCompactions, 1; got != exp {
+		t.Errorf("unexpected max-concurrent-full-compactions:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if windows, err := c.FullCompactionWindows(); err != nil {
+		t.Error(err)
+	} else if got, exp := fmt.Sprint(windows), "[01:00-05:00 22:30-00:30]"; got != exp {
+		t.Errorf("unexpected compact-full-windows:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.IOLimit, itoml.Size(100<<20); got != exp {
+		t.Errorf("unexpected io-limit:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.CompactionIOLimit, itoml.Size(1<<20); got != exp {
+		t.Errorf("unexpected compaction-io-limit:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.TieringPath, "s3://bucket/influxdb"; got != exp {
+		t.Errorf("unexpected tiering-path:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := time.Duration(c.TieringAge), 2160*time.Hour; got != exp {
+		t.Errorf("unexpected tiering-age:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := time.Duration(c.TieringCheckInterval), tsdb.DefaultTieringCheckInterval; got != exp {
+		t.Errorf("unexpected tiering-check-interval:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
+	if got, exp := c.TieringCacheMaxSize, itoml.Size(1<<30); got != exp {
+		t.Errorf("unexpected tiering-cache-max-size:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+	}
 }
 
 func TestConfig_Validate_Error(t *testing.T) {
@@ -47,6 +125,17 @@ func TestConfig_Validate_Error(t *testing.T) {
 	}
 
 	c.WALDir = "/var/lib/influxdb/wal"
+	c.WALSyncMode = "never"
+	if err := c.Validate(); err == nil || err.Error() != "unrecognized wal-sync-mode never" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.WALSyncMode = tsdb.WALSyncInterval
+	if err := c.Validate(); err == nil || err.Error() != "wal-fsync-delay must be greater than 0 when wal-sync-mode is interval" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	c.WALSyncMode = tsdb.WALSyncEveryWrite
+
 	c.Engine = "fake1"
 	if err := c.Validate(); err == nil || err.Error() != "unrecognized engine fake1" {
 		t.Errorf("unexpected error: %s", err)
@@ -67,4 +156,110 @@ func TestConfig_Validate_Error(t *testing.T) {
 	if err := c.Validate(); err != nil {
 		t.Error(err)
 	}
+
+	c.BackupIOLimit = -1
+	if err := c.Validate(); err == nil || err.Error() != "backup-io-limit must be greater than or equal to 0" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	c.BackupIOLimit = 0
+
+	c.TSMMmapMaxAge = -1
+	if err := c.Validate(); err == nil || err.Error() != "tsm-mmap-max-age must be greater than or equal to 0" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	c.TSMMmapMaxAge = 0
+
+	c.FieldTypeConflict = "drop"
+	if err := c.Validate(); err == nil || err.Error() != "unrecognized field-type-conflict drop" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	c.FieldTypeConflict = tsdb.FieldTypeConflictReject
+
+	c.FieldTypeConflictDatabases = map[string]string{"db0": "drop"}
+	if err := c.Validate(); err == nil || err.Error() != "unrecognized field-type-conflict drop for database db0" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	c.FieldTypeConflictDatabases = nil
+
+	c.ScrubEnabled = true
+	c.ScrubInterval = 0
+	if err := c.Validate(); err == nil || err.Error() != "scrub-interval must be greater than 0" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	c.ScrubInterval = itoml.Duration(time.Hour)
+	c.ScrubIOLimit = -1
+	if err := c.Validate(); err == nil || err.Error() != "scrub-io-limit must be greater than or equal to 0" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	c.ScrubEnabled = false
+	c.ScrubIOLimit = 0
+
+	c.BackfillThreshold = itoml.Duration(-time.Hour)
+	if err := c.Validate(); err == nil || err.Error() != "backfill-threshold must be greater than or equal to 0" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	c.BackfillThreshold = 0
+
+	c.TSMCompression = "gzip"
+	if err := c.Validate(); err == nil || err.Error() != "unrecognized tsm-compression gzip" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	c.TSMCompression = ""
+
+	c.CompactFullWindows = []string{"01:00-25:00"}
+	if err := c.Validate(); err == nil || err.Error() != `invalid compact-full-windows: window "01:00-25:00" must be written as HH:MM-HH:MM` {
+		t.Errorf("unexpected error: %s", err)
+	}
+	c.CompactFullWindows = nil
+
+	c.EncryptionKeyProvider = "vault"
+	if err := c.Validate(); err == nil || err.Error() != "unrecognized encryption-key-provider vault" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.EncryptionKeyProvider = "file"
+	if err := c.Validate(); err == nil || err.Error() != "encryption-key-source must be set" {
+		t.Errorf("unexpected error: %s", err)
+	}
+	c.EncryptionKeyProvider = ""
+
+	c.TieringPath = "s3://"
+	if err := c.Validate(); err == nil || err.Error() != "invalid tiering-path: missing bucket: s3://" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.TieringPath = "/mnt/tiering"
+	c.TieringAge = 0
+	if err := c.Validate(); err == nil || err.Error() != "tiering-age must be greater than 0" {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestTimeOfDayWindow_Contains(t *testing.T) {
+	day := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		window string
+		at     time.Duration
+		exp    bool
+	}{
+		{window: "01:00-05:00", at: 0, exp: false},
+		{window: "01:00-05:00", at: time.Hour, exp: true},
+		{window: "01:00-05:00", at: 5*time.Hour - time.Second, exp: true},
+		{window: "01:00-05:00", at: 5 * time.Hour, exp: false},
+		{window: "22:30-00:30", at: 22 * time.Hour, exp: false},
+		{window: "22:30-00:30", at: 23 * time.Hour, exp: true},
+		{window: "22:30-00:30", at: 0, exp: true},
+		{window: "22:30-00:30", at: time.Hour, exp: false},
+	} {
+		w, err := tsdb.ParseTimeOfDayWindow(tt.window)
+		if err != nil {
+			t.Fatal(err)
+		} else if got := w.Contains(day.Add(tt.at)); got != tt.exp {
+			t.Errorf("%s contains %s: got %v, exp %v", tt.window, tt.at, got, tt.exp)
+		}
+	}
+
+	if _, err := tsdb.ParseTimeOfDayWindow("02:00-02:00"); err == nil {
+		t.Error("expected error parsing empty window")
+	}
 }
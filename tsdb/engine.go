@@ -12,9 +12,11 @@ import (
 
 	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/encryption"
 	"github.com/influxdata/influxdb/pkg/estimator"
 	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/tsdb/tiering"
 	"github.com/uber-go/zap"
 )
 
@@ -40,9 +42,12 @@ type Engine interface {
 	LoadMetadataIndex(shardID uint64, index Index) error
 
 	CreateSnapshot() (string, error)
+	WriteSnapshot() error
 	Backup(w io.Writer, basePath string, since time.Time) error
 	Restore(r io.Reader, basePath string) error
 	Import(r io.Reader, basePath string) error
+	Offload(prefix string) (int, error)
+	Scrub(throttle *limiter.IOSubsystem, quarantineDir string, done <-chan struct{}) (int, error)
 
 	CreateIterator(ctx context.Context, measurement string, opt query.IteratorOptions) (query.Iterator, error)
 	CreateCursor(ctx context.Context, r *CursorRequest) (Cursor, error)
@@ -79,6 +84,8 @@ type Engine interface {
 	Statistics(tags map[string]string) []models.Statistic
 	LastModified() time.Time
 	DiskSize() int64
+	FileCount() int
+	CompactionBacklog() int64
 	IsIdle() bool
 	Free() error
 
@@ -153,6 +160,30 @@ type EngineOptions struct {
 
 	CompactionLimiter limiter.Fixed
 
+	// FullCompactionLimiter, if set, limits the full and optimize compactions
+	// of the engines of a store, which also take from CompactionLimiter.
+	FullCompactionLimiter limiter.Fixed
+
+	// IO budgets shared by the engines of a store.  Compactions, backups and
+	// restores wait for their budget, and writes to the WAL are counted
+	// against the shared budget.
+	CompactionIO *limiter.IOSubsystem
+	BackupIO     *limiter.IOSubsystem
+	RestoreIO    *limiter.IOSubsystem
+	WriteIO      *limiter.IOSubsystem
+
+	// WALArchiveEnabled retains closed WAL segments in an archive directory
+	// once they have been snapshotted, rather than deleting them, so that
+	// they can be shipped to a replication follower.
+	WALArchiveEnabled bool
+
+	// Tiering is the store the files of cold shards are offloaded to, if
+	// tiering is enabled.
+	Tiering *tiering.Store
+
+	// Cipher, if set, encrypts the TSM files and WAL segments written.
+	Cipher *encryption.Cipher
+
 	Config Config
 }
 
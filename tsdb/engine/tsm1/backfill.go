@@ -0,0 +1,111 @@
+package tsm1
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// BackfillWALDirName is the name of the directory of the backfill WAL in the
+// WAL directory of an engine.
+const BackfillWALDirName = "backfill"
+
+// hasBackfillWAL returns true if the backfill WAL in walPath has segments
+// with points to replay.
+func hasBackfillWAL(walPath string) bool {
+	names, err := segmentFileNames(filepath.Join(walPath, BackfillWALDirName))
+	if err != nil {
+		return false
+	}
+	for _, name := range names {
+		if stat, err := os.Stat(name); err == nil && stat.Size() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheWAL is a cache of an engine and the WAL its writes are logged to.
+type cacheWAL struct {
+	cache *Cache
+	wal   *WAL
+}
+
+// cacheWALs returns the cache of the engine and, if backfill is enabled, its
+// backfill cache, with their WALs.
+func (e *Engine) cacheWALs() []cacheWAL {
+	a := []cacheWAL{{cache: e.Cache, wal: e.WAL}}
+	if e.BackfillCache != nil {
+		a = append(a, cacheWAL{cache: e.BackfillCache, wal: e.BackfillWAL})
+	}
+	return a
+}
+
+// cacheValues returns the values of key in the cache, merged with those in the
+// backfill cache.  Points are only written to the backfill cache once they are
+// older than the backfill threshold, so its values take precedence.
+func (e *Engine) cacheValues(key []byte) Values {
+	values := e.Cache.Values(key)
+	if e.BackfillCache == nil {
+		return values
+	}
+	return values.Merge(e.BackfillCache.Values(key))
+}
+
+// shouldCompactBackfill returns true if the backfill cache is over its flush
+// threshold or hasn't been written to for the write cold duration.  A backfill
+// cache replayed after backfill was disabled is written as soon as possible,
+// so that its WAL is empty and isn't opened again.
+func (e *Engine) shouldCompactBackfill() bool {
+	if e.BackfillCache == nil {
+		return false
+	}
+
+	sz := e.BackfillCache.Size()
+	if sz == 0 {
+		return false
+	} else if e.BackfillThreshold == 0 {
+		return true
+	}
+
+	return sz > e.BackfillFlushMemorySizeThreshold ||
+		time.Since(e.BackfillWAL.LastWriteTime()) > e.CacheFlushWriteColdDuration
+}
+
+// splitBackfill splits values into the values at or after cutoff and those
+// before it, which are written to the backfill cache.
+func splitBackfill(values map[string][]Value, cutoff int64) (recent, backfill map[string][]Value) {
+	for k, vs := range values {
+		n := 0
+		for _, v := range vs {
+			if v.UnixNano() < cutoff {
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+
+		if backfill == nil {
+			backfill = make(map[string][]Value)
+		}
+		if n == len(vs) {
+			backfill[k] = vs
+			delete(values, k)
+			continue
+		}
+
+		old := make([]Value, 0, n)
+		newer := make([]Value, 0, len(vs)-n)
+		for _, v := range vs {
+			if v.UnixNano() < cutoff {
+				old = append(old, v)
+			} else {
+				newer = append(newer, v)
+			}
+		}
+		backfill[k] = old
+		values[k] = newer
+	}
+	return values, backfill
+}
@@ -0,0 +1,207 @@
+package tsm1
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/index/inmem"
+)
+
+func TestEngine_Backfill(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	openEngine := func() *Engine {
+		opt := tsdb.NewEngineOptions()
+		opt.Config.BackfillThreshold = toml.Duration(time.Hour)
+		opt.InmemIndex = inmem.NewIndex("db0")
+		idx := tsdb.MustOpenIndex(1, "db0", filepath.Join(dir, "index"), opt)
+
+		e := NewEngine(1, idx, "db0", filepath.Join(dir, "data"), filepath.Join(dir, "wal"), opt).(*Engine)
+		e.SetEnabled(false)
+		if err := e.Open(); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	now := time.Now().UnixNano()
+	old := now - int64(2*time.Hour)
+	key := []byte("cpu,host=A#!~#value")
+
+	e := openEngine()
+	points, err := models.ParsePointsString(fmt.Sprintf("cpu,host=A value=1 %d\ncpu,host=A value=2 %d", now, old))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := e.WritePoints(points); err != nil {
+		t.Fatal(err)
+	}
+
+	// Points older than the threshold are written to the backfill cache only.
+	if got := e.Cache.Values(key); len(got) != 1 || got[0].UnixNano() != now {
+		t.Fatalf("unexpected cache values: %v", got)
+	} else if got := e.BackfillCache.Values(key); len(got) != 1 || got[0].UnixNano() != old {
+		t.Fatalf("unexpected backfill cache values: %v", got)
+	} else if got := e.cacheValues(key); len(got) != 2 || got[0].UnixNano() != old || got[1].UnixNano() != now {
+		t.Fatalf("unexpected merged values: %v", got)
+	}
+
+	// A newer value of a backfilled point takes precedence over the cache.
+	points, err = models.ParsePointsString(fmt.Sprintf("cpu,host=A value=3 %d", old))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := e.WritePoints(points); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.cacheValues(key); len(got) != 2 || got[0].Value() != 3.0 {
+		t.Fatalf("unexpected merged values: %v", got)
+	}
+
+	// The backfill cache is reloaded from its own WAL.
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	e = openEngine()
+	defer e.Close()
+	if got := e.BackfillCache.Values(key); len(got) != 1 || got[0].Value() != 3.0 {
+		t.Fatalf("unexpected backfill cache values after reopen: %v", got)
+	} else if got := e.Cache.Values(key); len(got) != 1 || got[0].UnixNano() != now {
+		t.Fatalf("unexpected cache values after reopen: %v", got)
+	}
+
+	// Writing a snapshot writes both caches to separate files and removes
+	// their WAL segments.
+	if err := e.WriteSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.FileStore.Count(); got != 2 {
+		t.Fatalf("unexpected file count: %d", got)
+	} else if e.Cache.Size() != 0 || e.BackfillCache.Size() != 0 {
+		t.Fatalf("expected caches to be empty: %d, %d", e.Cache.Size(), e.BackfillCache.Size())
+	}
+
+	segments, err := segmentFileNames(e.BackfillWAL.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range segments {
+		if stat, err := os.Stat(name); err != nil {
+			t.Fatal(err)
+		} else if stat.Size() != 0 {
+			t.Fatalf("unexpected backfill WAL segment: %s", name)
+		}
+	}
+}
+
+// Ensure a backfill WAL is replayed and flushed after backfill is disabled.
+func TestEngine_Backfill_Disabled(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	openEngine := func(threshold time.Duration) *Engine {
+		opt := tsdb.NewEngineOptions()
+		opt.Config.BackfillThreshold = toml.Duration(threshold)
+		opt.InmemIndex = inmem.NewIndex("db0")
+		idx := tsdb.MustOpenIndex(1, "db0", filepath.Join(dir, "index"), opt)
+
+		e := NewEngine(1, idx, "db0", filepath.Join(dir, "data"), filepath.Join(dir, "wal"), opt).(*Engine)
+		e.SetEnabled(false)
+		if err := e.Open(); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	old := time.Now().Add(-2 * time.Hour).UnixNano()
+	key := []byte("cpu,host=A#!~#value")
+
+	e := openEngine(time.Hour)
+	points, err := models.ParsePointsString(fmt.Sprintf("cpu,host=A value=1 %d", old))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := e.WritePoints(points); err != nil {
+		t.Fatal(err)
+	} else if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The backfill WAL is replayed with backfill disabled, and flushed first.
+	e = openEngine(0)
+	if e.BackfillCache == nil {
+		t.Fatal("expected backfill WAL to be replayed")
+	} else if got := e.cacheValues(key); len(got) != 1 || got[0].UnixNano() != old {
+		t.Fatalf("unexpected values after reopen: %v", got)
+	} else if !e.shouldCompactBackfill() {
+		t.Fatal("expected replayed backfill cache to be compacted")
+	}
+
+	// New points aren't written to it.
+	older := old - int64(time.Hour)
+	points, err = models.ParsePointsString(fmt.Sprintf("cpu,host=A value=2 %d", older))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := e.WritePoints(points); err != nil {
+		t.Fatal(err)
+	} else if got := e.Cache.Values(key); len(got) != 1 || got[0].UnixNano() != older {
+		t.Fatalf("unexpected cache values: %v", got)
+	}
+
+	if err := e.WriteSnapshot(); err != nil {
+		t.Fatal(err)
+	} else if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once flushed, the backfill WAL isn't opened again.
+	e = openEngine(0)
+	defer e.Close()
+	if e.BackfillCache != nil {
+		t.Fatal("expected flushed backfill WAL not to be opened")
+	} else if got := e.FileStore.Count(); got != 2 {
+		t.Fatalf("unexpected file count: %d", got)
+	}
+}
+
+func TestEngine_ShouldCompactBackfill(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	opt := tsdb.NewEngineOptions()
+	opt.Config.BackfillThreshold = toml.Duration(time.Hour)
+	opt.Config.BackfillSnapshotMemorySize = 1024
+	opt.InmemIndex = inmem.NewIndex("db0")
+	idx := tsdb.MustOpenIndex(1, "db0", filepath.Join(dir, "index"), opt)
+
+	e := NewEngine(1, idx, "db0", filepath.Join(dir, "data"), filepath.Join(dir, "wal"), opt).(*Engine)
+	e.SetEnabled(false)
+	if err := e.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	if e.shouldCompactBackfill() {
+		t.Fatal("expected empty backfill cache not to be compacted")
+	}
+
+	old := time.Now().Add(-2 * time.Hour).UnixNano()
+	points, err := models.ParsePointsString(fmt.Sprintf("cpu,host=A value=1 %d", old))
+	if err != nil {
+		t.Fatal(err)
+	} else if err := e.WritePoints(points); err != nil {
+		t.Fatal(err)
+	}
+	if e.shouldCompactBackfill() {
+		t.Fatal("expected small, recently written backfill cache not to be compacted")
+	}
+
+	e.BackfillFlushMemorySizeThreshold = 1
+	if !e.shouldCompactBackfill() {
+		t.Fatal("expected backfill cache over its threshold to be compacted")
+	}
+}
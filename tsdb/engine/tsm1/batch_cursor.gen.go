@@ -17,7 +17,7 @@ import (
 // buildFloatCursor creates a cursor for a float field.
 func (e *Engine) buildFloatCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) floatCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
-	cacheValues := e.Cache.Values(key)
+	cacheValues := e.cacheValues(key)
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newFloatCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -25,7 +25,7 @@ func (e *Engine) buildFloatCursor(ctx context.Context, measurement, seriesKey, f
 // buildFloatBatchCursor creates a batch cursor for a float field.
 func (e *Engine) buildFloatBatchCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) tsdb.FloatBatchCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
-	cacheValues := e.Cache.Values(key)
+	cacheValues := e.cacheValues(key)
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newFloatBatchCursor(seriesKey, opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -33,7 +33,7 @@ func (e *Engine) buildFloatBatchCursor(ctx context.Context, measurement, seriesK
 // buildIntegerCursor creates a cursor for a integer field.
 func (e *Engine) buildIntegerCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) integerCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
-	cacheValues := e.Cache.Values(key)
+	cacheValues := e.cacheValues(key)
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newIntegerCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -41,7 +41,7 @@ func (e *Engine) buildIntegerCursor(ctx context.Context, measurement, seriesKey,
 // buildIntegerBatchCursor creates a batch cursor for a integer field.
 func (e *Engine) buildIntegerBatchCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) tsdb.IntegerBatchCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
-	cacheValues := e.Cache.Values(key)
+	cacheValues := e.cacheValues(key)
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newIntegerBatchCursor(seriesKey, opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -49,7 +49,7 @@ func (e *Engine) buildIntegerBatchCursor(ctx context.Context, measurement, serie
 // buildUnsignedCursor creates a cursor for a unsigned field.
 func (e *Engine) buildUnsignedCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) unsignedCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
-	cacheValues := e.Cache.Values(key)
+	cacheValues := e.cacheValues(key)
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newUnsignedCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -57,7 +57,7 @@ func (e *Engine) buildUnsignedCursor(ctx context.Context, measurement, seriesKey
 // buildUnsignedBatchCursor creates a batch cursor for a unsigned field.
 func (e *Engine) buildUnsignedBatchCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) tsdb.UnsignedBatchCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
-	cacheValues := e.Cache.Values(key)
+	cacheValues := e.cacheValues(key)
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newUnsignedBatchCursor(seriesKey, opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -65,7 +65,7 @@ func (e *Engine) buildUnsignedBatchCursor(ctx context.Context, measurement, seri
 // buildStringCursor creates a cursor for a string field.
 func (e *Engine) buildStringCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) stringCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
-	cacheValues := e.Cache.Values(key)
+	cacheValues := e.cacheValues(key)
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newStringCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -73,7 +73,7 @@ func (e *Engine) buildStringCursor(ctx context.Context, measurement, seriesKey,
 // buildStringBatchCursor creates a batch cursor for a string field.
 func (e *Engine) buildStringBatchCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) tsdb.StringBatchCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
-	cacheValues := e.Cache.Values(key)
+	cacheValues := e.cacheValues(key)
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newStringBatchCursor(seriesKey, opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -81,7 +81,7 @@ func (e *Engine) buildStringBatchCursor(ctx context.Context, measurement, series
 // buildBooleanCursor creates a cursor for a boolean field.
 func (e *Engine) buildBooleanCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) booleanCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
-	cacheValues := e.Cache.Values(key)
+	cacheValues := e.cacheValues(key)
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newBooleanCursor(opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -89,7 +89,7 @@ func (e *Engine) buildBooleanCursor(ctx context.Context, measurement, seriesKey,
 // buildBooleanBatchCursor creates a batch cursor for a boolean field.
 func (e *Engine) buildBooleanBatchCursor(ctx context.Context, measurement, seriesKey, field string, opt query.IteratorOptions) tsdb.BooleanBatchCursor {
 	key := SeriesFieldKeyBytes(seriesKey, field)
-	cacheValues := e.Cache.Values(key)
+	cacheValues := e.cacheValues(key)
 	keyCursor := e.KeyCursor(ctx, key, opt.SeekTime(), opt.Ascending)
 	return newBooleanBatchCursor(seriesKey, opt.SeekTime(), opt.Ascending, cacheValues, keyCursor)
 }
@@ -24,6 +24,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/influxdata/influxdb/pkg/encryption"
+	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/tsdb"
 )
 
@@ -92,6 +94,10 @@ type DefaultPlanner struct {
 	// should always be greater than the CacheFlushWriteColdDuraion
 	compactFullWriteColdDuration time.Duration
 
+	// FullCompactionWindows, if set, restricts Plan and PlanOptimize to the
+	// times of day within one of the windows.
+	FullCompactionWindows []tsdb.TimeOfDayWindow
+
 	// lastPlanCheck is the last time Plan was called
 	lastPlanCheck time.Time
 
@@ -121,6 +127,19 @@ func NewDefaultPlanner(fs fileStore, writeColdDuration time.Duration) *DefaultPl
 	}
 }
 
+// inFullCompactionWindow returns true if full compactions may be planned at t.
+func (c *DefaultPlanner) inFullCompactionWindow(t time.Time) bool {
+	if len(c.FullCompactionWindows) == 0 {
+		return true
+	}
+	for _, w := range c.FullCompactionWindows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
 // tsmGeneration represents the TSM files within a generation.
 // 000001-01.tsm, 000001-02.tsm would be in the same generation
 // 000001 each with different sequence numbers.
@@ -276,6 +295,10 @@ func (c *DefaultPlanner) PlanLevel(level int) []CompactionGroup {
 // to optimize the index across TSM files.  Each returned compaction group can be
 // compacted concurrently.
 func (c *DefaultPlanner) PlanOptimize() []CompactionGroup {
+	if !c.inFullCompactionWindow(time.Now()) {
+		return nil
+	}
+
 	// Determine the generations from all files on disk.  We need to treat
 	// a generation conceptually as a single file even though it may be
 	// split across several files in sequence.
@@ -353,6 +376,10 @@ func (c *DefaultPlanner) PlanOptimize() []CompactionGroup {
 // Plan returns a set of TSM files to rewrite for level 4 or higher.  The planning returns
 // multiple groups if possible to allow compactions to run concurrently.
 func (c *DefaultPlanner) Plan(lastWrite time.Time) []CompactionGroup {
+	if !c.inFullCompactionWindow(time.Now()) {
+		return nil
+	}
+
 	generations := c.findGenerations(true)
 
 	// first check if we should be doing a full compaction because nothing has been written in a long time
@@ -631,6 +658,17 @@ type Compactor struct {
 		TSMReader(path string) *TSMReader
 	}
 
+	// IO throttles the writes of level and full compactions.  Snapshots
+	// are not throttled so that the cache does not fill up.
+	IO *limiter.IOSubsystem
+
+	// Zstd, if set, recompresses the float and string blocks written by
+	// level and full compactions with zstd.
+	Zstd *zstdCodec
+
+	// Cipher, if set, encrypts the blocks of the files written.
+	Cipher *encryption.Cipher
+
 	mu                 sync.RWMutex
 	snapshotsEnabled   bool
 	compactionsEnabled bool
@@ -761,7 +799,7 @@ func (c *Compactor) WriteSnapshot(cache *Cache) ([]string, error) {
 	for i := 0; i < concurrency; i++ {
 		go func(sp *Cache) {
 			iter := NewCacheKeyIterator(sp, tsdb.DefaultMaxPointsPerBlock, intC)
-			files, err := c.writeNewFiles(c.FileStore.NextGeneration(), 0, iter)
+			files, err := c.writeNewFiles(c.FileStore.NextGeneration(), 0, iter, nil)
 			resC <- res{files: files, err: err}
 
 		}(splits[i])
@@ -848,7 +886,13 @@ func (c *Compactor) compact(fast bool, tsmFiles []string) ([]string, error) {
 		return nil, err
 	}
 
-	return c.writeNewFiles(maxGeneration, maxSequence, tsm)
+	if c.Zstd != nil {
+		if tsm, err = c.Zstd.iterator(tsm, trs); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.writeNewFiles(maxGeneration, maxSequence, tsm, c.IO)
 }
 
 // CompactFull writes multiple smaller TSM files into 1 or more larger files.
@@ -928,18 +972,23 @@ func (c *Compactor) removeTmpFiles(files []string) error {
 }
 
 // writeNewFiles writes from the iterator into new TSM files, rotating
-// to a new file once it has reached the max TSM file size.
-func (c *Compactor) writeNewFiles(generation, sequence int, iter KeyIterator) ([]string, error) {
+// to a new file once it has reached the max TSM file size.  Writes wait for
+// the throttle, if not nil.
+func (c *Compactor) writeNewFiles(generation, sequence int, iter KeyIterator, throttle *limiter.IOSubsystem) ([]string, error) {
 	// These are the new TSM files written
 	var files []string
 
+	if c.Cipher != nil {
+		iter = &encryptingKeyIterator{KeyIterator: iter, cipher: c.Cipher}
+	}
+
 	for {
 		sequence++
 		// New TSM files are written to a temp file and renamed when fully completed.
 		fileName := filepath.Join(c.Dir, fmt.Sprintf("%09d-%09d.%s.tmp", generation, sequence, TSMFileExtension))
 
 		// Write as much as possible to this file
-		err := c.write(fileName, iter)
+		err := c.write(fileName, iter, throttle)
 
 		// We've hit the max file limit and there is more to write.  Create a new file
 		// and continue.
@@ -978,7 +1027,7 @@ func (c *Compactor) writeNewFiles(generation, sequence int, iter KeyIterator) ([
 	return files, nil
 }
 
-func (c *Compactor) write(path string, iter KeyIterator) (err error) {
+func (c *Compactor) write(path string, iter KeyIterator, throttle *limiter.IOSubsystem) (err error) {
 	fd, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_EXCL|os.O_SYNC, 0666)
 	if err != nil {
 		return errCompactionInProgress{err: err}
@@ -1023,6 +1072,7 @@ func (c *Compactor) write(path string, iter KeyIterator) (err error) {
 		if err != nil {
 			return err
 		}
+		throttle.Wait(len(block))
 
 		// Write the key and value
 		if err := w.WriteBlock(key, minTime, maxTime, block); err == ErrMaxBlocksExceeded {
@@ -1262,6 +1312,9 @@ func (k *tsmKeyIterator) Next() bool {
 			iter := k.iterators[i]
 			if iter.Next() {
 				key, minTime, maxTime, typ, _, b, err := iter.Read()
+				if err == nil {
+					b, err = DecryptBlock(b)
+				}
 				if err != nil {
 					k.err = err
 				}
@@ -1295,6 +1348,9 @@ func (k *tsmKeyIterator) Next() bool {
 				for bytes.Equal(iter.PeekNext(), blockKey) {
 					iter.Next()
 					key, minTime, maxTime, typ, _, b, err := iter.Read()
+					if err == nil {
+						b, err = DecryptBlock(b)
+					}
 					if err != nil {
 						k.err = err
 					}
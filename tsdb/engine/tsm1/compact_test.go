@@ -2120,6 +2120,48 @@ func TestDefaultPlanner_Plan_FullOnCold(t *testing.T) {
 	}
 }
 
+// Ensure that the planner only plans full compactions within its windows.
+func TestDefaultPlanner_Plan_FullCompactionWindows(t *testing.T) {
+	data := []tsm1.FileStat{
+		tsm1.FileStat{
+			Path: "01-01.tsm1",
+			Size: 1 * 1024 * 1024,
+		},
+		tsm1.FileStat{
+			Path: "02-01.tsm1",
+			Size: 1 * 1024 * 1024,
+		},
+	}
+
+	cp := tsm1.NewDefaultPlanner(
+		&fakeFileStore{
+			PathsFn: func() []tsm1.FileStat {
+				return data
+			},
+		},
+		time.Nanosecond,
+	)
+
+	// A window starting in an hour excludes now.
+	now := time.Now()
+	start := time.Duration(now.Add(time.Hour).Hour()) * time.Hour
+	cp.FullCompactionWindows = []tsdb.TimeOfDayWindow{{Start: start, End: start + time.Hour}}
+	if tsm := cp.Plan(now.Add(-time.Second)); len(tsm) != 0 {
+		t.Fatalf("unexpected full compaction outside window: %v", tsm)
+	} else if tsm := cp.PlanOptimize(); len(tsm) != 0 {
+		t.Fatalf("unexpected optimize compaction outside window: %v", tsm)
+	}
+
+	// A window of the current hour includes now.
+	cp.FullCompactionWindows = append(cp.FullCompactionWindows, tsdb.TimeOfDayWindow{
+		Start: time.Duration(now.Hour()) * time.Hour,
+		End:   time.Duration(now.Hour()+1) * time.Hour,
+	})
+	if tsm := cp.Plan(now.Add(-time.Second)); len(tsm) != 1 {
+		t.Fatalf("expected full compaction within window: %v", tsm)
+	}
+}
+
 // Ensure that the planner will not return files that are over the max
 // allowable size
 func TestDefaultPlanner_Plan_SkipMaxSizeFiles(t *testing.T) {
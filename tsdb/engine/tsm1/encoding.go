@@ -224,7 +224,8 @@ func (a Values) InfluxQLType() (influxql.DataType, error) {
 // BlockType returns the type of value encoded in a block or an error
 // if the block type is unknown.
 func BlockType(block []byte) (byte, error) {
-	blockType := block[0]
+	// Encrypted blocks keep their type, flagged as encrypted.
+	blockType := block[0] &^ blockEncrypted
 	switch blockType {
 	case BlockFloat64, BlockInteger, BlockUnsigned, BlockBoolean, BlockString:
 		return blockType, nil
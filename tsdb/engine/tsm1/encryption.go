@@ -0,0 +1,118 @@
+package tsm1
+
+// Engines configured with a cipher encrypt the blocks of the TSM files they
+// write and the entries of their WAL segments.  An encrypted block keeps its
+// type byte, flagged as encrypted, followed by the rest of the block
+// encrypted with the current key.  An encrypted WAL entry is flagged in its
+// type, and its compressed data is encrypted.
+//
+// Blocks are decrypted as they are decoded, and the checksums of the blocks
+// are of the stored, encrypted bytes, which ReadBytes returns as they are.
+// Compactions decrypt the blocks they read and encrypt them with the current
+// key, rotating the keys of the files of a shard as they are compacted.
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/influxdata/influxdb/pkg/encryption"
+)
+
+const (
+	// blockEncrypted is set in the type byte of encrypted blocks.
+	blockEncrypted = byte(0x80)
+
+	// walEntryEncrypted is set in the type of encrypted WAL entries.
+	walEntryEncrypted = WalEntryType(0x80)
+)
+
+// ErrEncryptionDisabled is returned when reading encrypted data without a cipher.
+var ErrEncryptionDisabled = errors.New("data is encrypted but encryption is not enabled")
+
+// decryptCipher is the cipher encrypted data is decrypted with.
+var decryptCipher struct {
+	mu     sync.RWMutex
+	cipher *encryption.Cipher
+}
+
+// SetCipher sets the cipher encrypted TSM blocks and WAL entries are
+// decrypted with.  Engines configured with a cipher set it when created, and
+// other readers of encrypted files must set it.
+func SetCipher(c *encryption.Cipher) {
+	decryptCipher.mu.Lock()
+	decryptCipher.cipher = c
+	decryptCipher.mu.Unlock()
+}
+
+// currentCipher returns the cipher set with SetCipher.
+func currentCipher() *encryption.Cipher {
+	decryptCipher.mu.RLock()
+	defer decryptCipher.mu.RUnlock()
+	return decryptCipher.cipher
+}
+
+// encryptBlock returns block encrypted with c.
+func encryptBlock(c *encryption.Cipher, block []byte) ([]byte, error) {
+	if len(block) == 0 || block[0]&blockEncrypted != 0 {
+		return block, nil
+	}
+
+	b := make([]byte, 1, len(block)+encryption.Overhead)
+	b[0] = block[0] | blockEncrypted
+	return c.Encrypt(b, block[1:])
+}
+
+// DecryptBlock returns block, a block without its checksum, decrypted with
+// the cipher set with SetCipher, if it is encrypted.
+func DecryptBlock(block []byte) ([]byte, error) {
+	if len(block) == 0 || block[0]&blockEncrypted == 0 {
+		return block, nil
+	}
+
+	c := currentCipher()
+	if c == nil {
+		return nil, ErrEncryptionDisabled
+	}
+
+	b := make([]byte, 1, len(block))
+	b[0] = block[0] &^ blockEncrypted
+	return c.Decrypt(b, block[1:])
+}
+
+// decryptWALEntry returns the type and compressed data of a WAL entry,
+// decrypted if it is encrypted.
+func decryptWALEntry(typ WalEntryType, b []byte) (WalEntryType, []byte, error) {
+	if typ&walEntryEncrypted == 0 {
+		return typ, b, nil
+	}
+
+	c := currentCipher()
+	if c == nil {
+		return 0, nil, ErrEncryptionDisabled
+	}
+
+	b, err := c.Decrypt(nil, b)
+	if err != nil {
+		return 0, nil, err
+	}
+	return typ &^ walEntryEncrypted, b, nil
+}
+
+// encryptingKeyIterator encrypts the blocks of a key iterator.
+type encryptingKeyIterator struct {
+	KeyIterator
+	cipher *encryption.Cipher
+}
+
+func (k *encryptingKeyIterator) Read() ([]byte, int64, int64, []byte, error) {
+	key, minTime, maxTime, block, err := k.KeyIterator.Read()
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+
+	block, err = encryptBlock(k.cipher, block)
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+	return key, minTime, maxTime, block, nil
+}
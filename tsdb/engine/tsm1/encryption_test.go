@@ -0,0 +1,189 @@
+package tsm1
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/pkg/encryption"
+)
+
+func TestCompactor_Encryption(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	cipher := mustEncryptionCipher(dir)
+	SetCipher(cipher)
+	defer SetCipher(nil)
+
+	values := []Value{
+		NewValue(1, "secret-value-0001"),
+		NewValue(2, "secret-value-0002"),
+	}
+	cache := NewCache(0, "")
+	if err := cache.Write([]byte("cpu,host=A#!~#value"), values); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewFileStore(dir)
+	if err := fs.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	c := &Compactor{Dir: dir, FileStore: fs, Cipher: cipher}
+	c.Open()
+	defer c.Close()
+
+	files, err := c.WriteSnapshot(cache)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(files) != 1 {
+		t.Fatalf("unexpected file count: %d", len(files))
+	}
+
+	buf, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	} else if bytes.Contains(buf, []byte("secret-value")) {
+		t.Fatal("expected values to be encrypted")
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	got, err := r.ReadAll([]byte("cpu,host=A#!~#value"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(got) != len(values) {
+		t.Fatalf("unexpected value count: %d", len(got))
+	}
+	for i := range values {
+		if got[i].String() != values[i].String() {
+			t.Fatalf("unexpected value %d: got %v, exp %v", i, got[i], values[i])
+		}
+	}
+
+	// Compactions decrypt the blocks they read and encrypt the blocks they write.
+	if err := fs.Replace(nil, files); err != nil {
+		t.Fatal(err)
+	}
+	compacted, err := c.CompactFull([]string{strings.TrimSuffix(files[0], "."+CompactionTempExtension)})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(compacted) != 1 {
+		t.Fatalf("unexpected file count: %d", len(compacted))
+	}
+
+	buf, err = ioutil.ReadFile(compacted[0])
+	if err != nil {
+		t.Fatal(err)
+	} else if bytes.Contains(buf, []byte("secret-value")) {
+		t.Fatal("expected compacted values to be encrypted")
+	}
+
+	f, err = os.Open(compacted[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr, err := NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cr.Close()
+
+	if got, err := cr.ReadAll([]byte("cpu,host=A#!~#value")); err != nil {
+		t.Fatal(err)
+	} else if len(got) != len(values) {
+		t.Fatalf("unexpected compacted value count: %d", len(got))
+	}
+
+	// Encrypted blocks can't be read without a cipher.
+	SetCipher(nil)
+	if _, err := r.ReadAll([]byte("cpu,host=A#!~#value")); err != ErrEncryptionDisabled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestWAL_Encryption(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	cipher := mustEncryptionCipher(dir)
+	SetCipher(cipher)
+	defer SetCipher(nil)
+
+	w := NewWAL(filepath.Join(dir, "wal"))
+	w.cipher = cipher
+	if err := w.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string][]Value{
+		"cpu,host=A#!~#value": {NewValue(1, "secret-value-0001")},
+	}
+	if _, err := w.WriteMulti(values); err != nil {
+		t.Fatal(err)
+	} else if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := segmentFileNames(w.Path())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(names) != 1 {
+		t.Fatalf("unexpected segment count: %d", len(names))
+	}
+
+	f, err := os.Open(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var typ [1]byte
+	if _, err := f.ReadAt(typ[:], 0); err != nil {
+		t.Fatal(err)
+	} else if WalEntryType(typ[0]) != WriteWALEntryType|walEntryEncrypted {
+		t.Fatalf("unexpected entry type: %x", typ[0])
+	}
+
+	r := NewWALSegmentReader(f)
+	if !r.Next() {
+		t.Fatal("expected entry")
+	}
+	entry, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	we, ok := entry.(*WriteWALEntry)
+	if !ok {
+		t.Fatalf("unexpected entry: %#v", entry)
+	} else if got := we.Values["cpu,host=A#!~#value"]; len(got) != 1 || got[0].String() != values["cpu,host=A#!~#value"][0].String() {
+		t.Fatalf("unexpected values: %v", got)
+	}
+}
+
+// mustEncryptionCipher returns a cipher using a key file written to dir.
+func mustEncryptionCipher(dir string) *encryption.Cipher {
+	path := filepath.Join(dir, "keys")
+	key := "1 000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f\n"
+	if err := ioutil.WriteFile(path, []byte(key), 0600); err != nil {
+		panic(err)
+	}
+	p, err := encryption.NewFileKeyProvider(path)
+	if err != nil {
+		panic(err)
+	}
+	return encryption.NewCipher(p)
+}
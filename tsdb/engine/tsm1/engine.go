@@ -64,6 +64,14 @@ const (
 	// keyFieldSeparator separates the series key from the field name in the composite key
 	// that identifies a specific field in series
 	keyFieldSeparator = "#!~#"
+
+	// backupChunkSize is the number of bytes of a file copied to a backup
+	// before they are dropped from the page cache.
+	backupChunkSize = 4 * 1024 * 1024
+
+	// seriesIteratorSize is an estimate of the memory, in bytes, held by the
+	// iterator of a series: one decoded block of timestamps and values.
+	seriesIteratorSize = tsdb.DefaultMaxPointsPerBlock * 16
 )
 
 // Statistics gathered by the engine.
@@ -146,28 +154,79 @@ type Engine struct {
 	// a snapshot of the cache to a TSM file
 	CacheFlushWriteColdDuration time.Duration
 
+	// BackfillWAL and BackfillCache hold the points older than
+	// BackfillThreshold when they are written.  They are nil when backfill
+	// is disabled and no backfill WAL is left to replay.  BackfillThreshold
+	// is zero when backfill is disabled.
+	BackfillWAL       *WAL
+	BackfillCache     *Cache
+	BackfillThreshold time.Duration
+
+	// BackfillFlushMemorySizeThreshold specifies the minimum size of the
+	// backfill cache when the engine should write it to a TSM file.
+	BackfillFlushMemorySizeThreshold uint64
+
 	// Controls whether to enabled compactions when the engine is open
 	enableCompactionsOnOpen bool
 
+	// backupDropPageCache drops files from the OS page cache as they are backed up.
+	backupDropPageCache bool
+
+	// snapshotOnShutdown writes a shutdown snapshot when the engine is closed.
+	snapshotOnShutdown bool
+
+	// snapshotKeys are the keys of the TSM files loaded from a shutdown
+	// snapshot, until the index is loaded.
+	snapshotKeys []seriesKey
+
+	// IO budgets for backups and restores.
+	backupIO  *limiter.IOSubsystem
+	restoreIO *limiter.IOSubsystem
+
 	stats *EngineStatistics
 
 	// Limiter for concurrent compactions.
-	compactionLimiter limiter.Fixed
+	compactionLimiter     limiter.Fixed
+	fullCompactionLimiter limiter.Fixed
 
 	scheduler *scheduler
 }
 
 // NewEngine returns a new instance of Engine.
 func NewEngine(id uint64, idx tsdb.Index, database, path string, walPath string, opt tsdb.EngineOptions) tsdb.Engine {
-	w := NewWAL(walPath)
-	w.syncDelay = time.Duration(opt.Config.WALFsyncDelay)
+	newWAL := func(path string) *WAL {
+		w := NewWAL(path)
+		w.syncDelay = time.Duration(opt.Config.WALFsyncDelay)
+		w.syncMode = opt.Config.WALSyncMode
+		w.io = opt.WriteIO
+		w.archive = opt.WALArchiveEnabled
+		w.cipher = opt.Cipher
+		return w
+	}
+	w := newWAL(walPath)
 
 	fs := NewFileStore(path)
+	fs.mmapMaxAge = time.Duration(opt.Config.TSMMmapMaxAge)
+	fs.tiering = opt.Tiering
 	cache := NewCache(uint64(opt.Config.CacheMaxMemorySize), path)
 
 	c := &Compactor{
 		Dir:       path,
 		FileStore: fs,
+		IO:        opt.CompactionIO,
+	}
+	if opt.Config.TSMCompressionFor(database) == tsdb.TSMCompressionZstd {
+		c.Zstd = newZstdCodec(path)
+	}
+	if opt.Cipher != nil {
+		SetCipher(opt.Cipher)
+		c.Cipher = opt.Cipher
+	}
+
+	planner := NewDefaultPlanner(fs, time.Duration(opt.Config.CompactFullWriteColdDuration))
+	// Windows are checked when the config is validated.
+	if windows, err := opt.Config.FullCompactionWindows(); err == nil {
+		planner.FullCompactionWindows = windows
 	}
 
 	logger := zap.New(zap.NullEncoder())
@@ -188,14 +247,28 @@ func NewEngine(id uint64, idx tsdb.Index, database, path string, walPath string,
 
 		FileStore:      fs,
 		Compactor:      c,
-		CompactionPlan: NewDefaultPlanner(fs, time.Duration(opt.Config.CompactFullWriteColdDuration)),
+		CompactionPlan: planner,
 
 		CacheFlushMemorySizeThreshold: opt.Config.CacheSnapshotMemorySize,
 		CacheFlushWriteColdDuration:   time.Duration(opt.Config.CacheSnapshotWriteColdDuration),
 		enableCompactionsOnOpen:       true,
-		stats:             stats,
-		compactionLimiter: opt.CompactionLimiter,
-		scheduler:         newScheduler(stats, opt.CompactionLimiter.Capacity()),
+		backupDropPageCache:           opt.Config.BackupDropPageCache,
+		snapshotOnShutdown:            opt.Config.CacheSnapshotOnShutdown,
+		backupIO:                      opt.BackupIO,
+		restoreIO:                     opt.RestoreIO,
+		stats:                         stats,
+		compactionLimiter:             opt.CompactionLimiter,
+		fullCompactionLimiter:         opt.FullCompactionLimiter,
+		scheduler:                     newScheduler(stats, opt.CompactionLimiter.Capacity()),
+	}
+
+	// A backfill WAL written while backfill was enabled is replayed and
+	// flushed even if it has since been disabled.
+	if opt.Config.BackfillThreshold > 0 || hasBackfillWAL(walPath) {
+		e.BackfillWAL = newWAL(filepath.Join(walPath, BackfillWALDirName))
+		e.BackfillCache = NewCache(uint64(opt.Config.CacheMaxMemorySize), path)
+		e.BackfillThreshold = time.Duration(opt.Config.BackfillThreshold)
+		e.BackfillFlushMemorySizeThreshold = opt.Config.BackfillSnapshotMemorySize
 	}
 
 	// Attach fieldset to index.
@@ -204,6 +277,9 @@ func NewEngine(id uint64, idx tsdb.Index, database, path string, walPath string,
 	if e.traceLogging {
 		fs.enableTraceLogging(true)
 		w.enableTraceLogging(true)
+		if e.BackfillWAL != nil {
+			e.BackfillWAL.enableTraceLogging(true)
+		}
 	}
 
 	return e
@@ -317,8 +393,10 @@ func (e *Engine) disableSnapshotCompactions() {
 	e.snapWG.Wait()
 
 	// If the cache is empty, free up its resources as well.
-	if e.Cache.Size() == 0 {
-		e.Cache.Free()
+	for _, cw := range e.cacheWALs() {
+		if cw.cache.Size() == 0 {
+			cw.cache.Free()
+		}
 	}
 }
 
@@ -391,6 +469,11 @@ func (e *Engine) MeasurementsSketches() (estimator.Sketch, estimator.Sketch, err
 // LastModified returns the time when this shard was last modified.
 func (e *Engine) LastModified() time.Time {
 	walTime := e.WAL.LastWriteTime()
+	if e.BackfillWAL != nil {
+		if t := e.BackfillWAL.LastWriteTime(); t.After(walTime) {
+			walTime = t
+		}
+	}
 	fsTime := e.FileStore.LastModified()
 
 	if walTime.After(fsTime) {
@@ -473,12 +556,39 @@ func (e *Engine) Statistics(tags map[string]string) []models.Statistic {
 	statistics = append(statistics, e.Cache.Statistics(tags)...)
 	statistics = append(statistics, e.FileStore.Statistics(tags)...)
 	statistics = append(statistics, e.WAL.Statistics(tags)...)
+
+	if e.BackfillWAL != nil {
+		backfillTags := map[string]string{"backfill": "true"}
+		for k, v := range tags {
+			backfillTags[k] = v
+		}
+		statistics = append(statistics, e.BackfillCache.Statistics(backfillTags)...)
+		statistics = append(statistics, e.BackfillWAL.Statistics(backfillTags)...)
+	}
 	return statistics
 }
 
 // DiskSize returns the total size in bytes of all TSM and WAL segments on disk.
 func (e *Engine) DiskSize() int64 {
-	return e.FileStore.DiskSizeBytes() + e.WAL.DiskSizeBytes()
+	size := e.FileStore.DiskSizeBytes() + e.WAL.DiskSizeBytes()
+	if e.BackfillWAL != nil {
+		size += e.BackfillWAL.DiskSizeBytes()
+	}
+	return size
+}
+
+// FileCount returns the number of TSM files of the engine.
+func (e *Engine) FileCount() int {
+	return e.FileStore.Count()
+}
+
+// CompactionBacklog returns the number of compactions planned but not yet run.
+func (e *Engine) CompactionBacklog() int64 {
+	n := atomic.LoadInt64(&e.stats.TSMOptimizeCompactionsQueue) + atomic.LoadInt64(&e.stats.TSMFullCompactionsQueue)
+	for i := range e.stats.TSMCompactionsQueue {
+		n += atomic.LoadInt64(&e.stats.TSMCompactionsQueue[i])
+	}
+	return n
 }
 
 // Open opens and initializes the engine.
@@ -495,6 +605,18 @@ func (e *Engine) Open() error {
 		return err
 	}
 
+	if e.BackfillWAL != nil {
+		if err := e.BackfillWAL.Open(); err != nil {
+			return err
+		}
+	}
+
+	if e.Compactor.Zstd != nil {
+		if err := e.Compactor.Zstd.Open(); err != nil {
+			return err
+		}
+	}
+
 	if err := e.FileStore.Open(); err != nil {
 		return err
 	}
@@ -521,10 +643,23 @@ func (e *Engine) Close() error {
 	defer e.mu.Unlock()
 	e.done = nil // Ensures that the channel will not be closed again.
 
-	if err := e.FileStore.Close(); err != nil {
+	if err := e.WAL.Close(); err != nil {
 		return err
 	}
-	return e.WAL.Close()
+
+	if e.BackfillWAL != nil {
+		if err := e.BackfillWAL.Close(); err != nil {
+			return err
+		}
+	}
+
+	// The WAL is replayed on startup if the snapshot can't be written.
+	if e.snapshotOnShutdown {
+		if err := e.writeShutdownSnapshot(); err != nil {
+			e.logger.Info(fmt.Sprintf("WARN: error writing shutdown snapshot for %s: %v", e.path, err))
+		}
+	}
+	return e.FileStore.Close()
 }
 
 // WithLogger sets the logger for the engine.
@@ -537,6 +672,9 @@ func (e *Engine) WithLogger(log zap.Logger) {
 
 	e.WAL.WithLogger(e.logger)
 	e.FileStore.WithLogger(e.logger)
+	if e.BackfillWAL != nil {
+		e.BackfillWAL.WithLogger(e.logger)
+	}
 }
 
 // LoadMetadataIndex loads the shard metadata into memory.
@@ -546,7 +684,7 @@ func (e *Engine) LoadMetadataIndex(shardID uint64, index tsdb.Index) error {
 	// Save reference to index for iterator creation.
 	e.index = index
 
-	if err := e.FileStore.WalkKeys(func(key []byte, typ byte) error {
+	addKey := func(key []byte, typ byte) error {
 		fieldType, err := tsmFieldTypeToInfluxQLDataType(typ)
 		if err != nil {
 			return err
@@ -556,23 +694,35 @@ func (e *Engine) LoadMetadataIndex(shardID uint64, index tsdb.Index) error {
 			return err
 		}
 		return nil
-	}); err != nil {
+	}
+
+	// Keys loaded from a shutdown snapshot save reading the index of every file.
+	if keys := e.snapshotKeys; keys != nil {
+		e.snapshotKeys = nil
+		for _, k := range keys {
+			if err := addKey(k.key, k.typ); err != nil {
+				return err
+			}
+		}
+	} else if err := e.FileStore.WalkKeys(addKey); err != nil {
 		return err
 	}
 
 	// load metadata from the Cache
-	if err := e.Cache.ApplyEntryFn(func(key []byte, entry *entry) error {
-		fieldType, err := entry.values.InfluxQLType()
-		if err != nil {
-			e.logger.Info(fmt.Sprintf("error getting the data type of values for key %s: %s", key, err.Error()))
-		}
+	for _, cw := range e.cacheWALs() {
+		if err := cw.cache.ApplyEntryFn(func(key []byte, entry *entry) error {
+			fieldType, err := entry.values.InfluxQLType()
+			if err != nil {
+				e.logger.Info(fmt.Sprintf("error getting the data type of values for key %s: %s", key, err.Error()))
+			}
 
-		if err := e.addToIndexFromKey(key, fieldType); err != nil {
+			if err := e.addToIndexFromKey(key, fieldType); err != nil {
+				return err
+			}
+			return nil
+		}); err != nil {
 			return err
 		}
-		return nil
-	}); err != nil {
-		return err
 	}
 
 	e.traceLogger.Info(fmt.Sprintf("Meta data index for shard %d loaded in %v", shardID, time.Since(now)))
@@ -583,6 +733,9 @@ func (e *Engine) LoadMetadataIndex(shardID uint64, index tsdb.Index) error {
 // shard is fully compacted.
 func (e *Engine) IsIdle() bool {
 	cacheEmpty := e.Cache.Size() == 0
+	if e.BackfillCache != nil && e.BackfillCache.Size() != 0 {
+		cacheEmpty = false
+	}
 
 	runningCompactions := atomic.LoadInt64(&e.stats.CacheCompactionsActive)
 	runningCompactions += atomic.LoadInt64(&e.stats.TSMCompactionsActive[0])
@@ -596,7 +749,9 @@ func (e *Engine) IsIdle() bool {
 
 // Free releases any resources held by the engine to free up memory or CPU.
 func (e *Engine) Free() error {
-	e.Cache.Free()
+	for _, cw := range e.cacheWALs() {
+		cw.cache.Free()
+	}
 	return e.FileStore.Free()
 }
 
@@ -635,7 +790,8 @@ func (e *Engine) Backup(w io.Writer, basePath string, since time.Time) error {
 		fi, err := os.Stat(filepath.Join(path, file))
 		if err != nil {
 			return err
-		} else if !fi.ModTime().After(since) {
+		} else if !fi.ModTime().After(since) && filepath.Ext(file) != "."+ZstdDictExtension {
+			// Dictionaries are always included, as changed files may need them.
 			continue
 		}
 		filtered = append(filtered, file)
@@ -677,9 +833,26 @@ func (e *Engine) writeFileToBackup(name string, shardRelativePath, fullPath stri
 
 	defer fr.Close()
 
-	_, err = io.CopyN(tw, fr, h.Size)
+	w := e.backupIO.Writer(tw)
+	if !e.backupDropPageCache {
+		_, err = io.CopyN(w, fr, h.Size)
+		return err
+	}
 
-	return err
+	// Copy the file in chunks, dropping each from the page cache once written.
+	var offset int64
+	for offset < h.Size {
+		n := h.Size - offset
+		if n > backupChunkSize {
+			n = backupChunkSize
+		}
+		if _, err := io.CopyN(w, fr, n); err != nil {
+			return err
+		}
+		fadviseDontNeed(fr, offset, n)
+		offset += n
+	}
+	return nil
 }
 
 // Restore reads a tar archive generated by Backup().
@@ -707,7 +880,7 @@ func (e *Engine) overlay(r io.Reader, basePath string, asNew bool) error {
 		defer e.mu.Unlock()
 
 		var newFiles []string
-		tr := tar.NewReader(r)
+		tr := tar.NewReader(e.restoreIO.Reader(r))
 		for {
 			if fileName, err := e.readFileFromBackup(tr, basePath, asNew); err == io.EOF {
 				break
@@ -775,6 +948,16 @@ func (e *Engine) overlay(r io.Reader, basePath string, asNew bool) error {
 		if err := e.addToIndexFromKey(v.key, fieldType); err != nil {
 			return err
 		}
+
+		// Persistent indexes are not rebuilt by addToIndexFromKey.
+		if e.index.Type() != inmem.IndexName {
+			seriesKey, _ := SeriesAndFieldFromCompositeKey(v.key)
+			name := tsdb.MeasurementFromSeriesKey(seriesKey)
+			tags, _ := models.ParseTags(seriesKey)
+			if err := e.index.CreateSeriesIfNotExists(seriesKey, name, tags); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -801,6 +984,29 @@ func (e *Engine) readFileFromBackup(tr *tar.Reader, shardRelativePath string, as
 		return "", err
 	}
 
+	// Zstd dictionaries are named after their contents and are not replaced
+	// by the file store, so they are written in place, and loaded for the
+	// files read after them.
+	if filepath.Ext(filename) == "."+ZstdDictExtension {
+		id, err := parseZstdDictID(filename)
+		if err != nil {
+			return "", err
+		}
+		dict, err := ioutil.ReadAll(io.LimitReader(tr, hdr.Size))
+		if err != nil {
+			return "", err
+		} else if err := writeZstdDict(filepath.Join(e.path, filename), dict); err != nil {
+			return "", err
+		}
+		return "", loadZstdDict(e.path, id, dict)
+	}
+
+	// Only TSM files are restored. Index files are skipped, as the index is
+	// rebuilt from the keys of the restored files.
+	if filepath.Ext(filename) != "."+TSMFileExtension {
+		return "", nil
+	}
+
 	if asNew {
 		filename = fmt.Sprintf("%09d-%09d.%s", e.FileStore.NextGeneration(), 1, TSMFileExtension)
 	}
@@ -907,6 +1113,21 @@ func (e *Engine) WritePoints(points []models.Point) error {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
+	if e.BackfillThreshold > 0 {
+		var backfill map[string][]Value
+		values, backfill = splitBackfill(values, time.Now().Add(-e.BackfillThreshold).UnixNano())
+		if len(backfill) > 0 {
+			if err := e.BackfillCache.WriteMulti(backfill); err != nil {
+				return err
+			} else if _, err := e.BackfillWAL.WriteMulti(backfill); err != nil {
+				return err
+			}
+		}
+		if len(values) == 0 {
+			return nil
+		}
+	}
+
 	// first try to write to the cache
 	err := e.Cache.WriteMulti(values)
 	if err != nil {
@@ -927,9 +1148,11 @@ func (e *Engine) containsSeries(keys [][]byte) (map[string]bool, error) {
 		keyMap[string(k)] = false
 	}
 
-	for _, k := range e.Cache.unsortedKeys() {
-		seriesKey, _ := SeriesAndFieldFromCompositeKey([]byte(k))
-		keyMap[string(seriesKey)] = true
+	for _, cw := range e.cacheWALs() {
+		for _, k := range cw.cache.unsortedKeys() {
+			seriesKey, _ := SeriesAndFieldFromCompositeKey([]byte(k))
+			keyMap[string(seriesKey)] = true
+		}
 	}
 
 	if err := e.FileStore.WalkKeys(func(k []byte, _ byte) error {
@@ -996,28 +1219,30 @@ func (e *Engine) DeleteSeriesRange(seriesKeys [][]byte, min, max int64) error {
 		return err
 	}
 
-	// find the keys in the cache and remove them
-	walKeys := deleteKeys[:0]
+	for _, cw := range e.cacheWALs() {
+		// find the keys in the cache and remove them
+		walKeys := deleteKeys[:0]
 
-	// ApplySerialEntryFn cannot return an error in this invocation.
-	_ = e.Cache.ApplyEntryFn(func(k []byte, _ *entry) error {
-		seriesKey, _ := SeriesAndFieldFromCompositeKey([]byte(k))
+		// ApplySerialEntryFn cannot return an error in this invocation.
+		_ = cw.cache.ApplyEntryFn(func(k []byte, _ *entry) error {
+			seriesKey, _ := SeriesAndFieldFromCompositeKey([]byte(k))
 
-		// Cache does not walk keys in sorted order, so search the sorted
-		// series we need to delete to see if any of the cache keys match.
-		i := bytesutil.SearchBytes(seriesKeys, seriesKey)
-		if i < len(seriesKeys) && bytes.Equal(seriesKey, seriesKeys[i]) {
-			// k is the measurement + tags + sep + field
-			walKeys = append(walKeys, k)
-		}
-		return nil
-	})
+			// Cache does not walk keys in sorted order, so search the sorted
+			// series we need to delete to see if any of the cache keys match.
+			i := bytesutil.SearchBytes(seriesKeys, seriesKey)
+			if i < len(seriesKeys) && bytes.Equal(seriesKey, seriesKeys[i]) {
+				// k is the measurement + tags + sep + field
+				walKeys = append(walKeys, k)
+			}
+			return nil
+		})
 
-	e.Cache.DeleteRange(walKeys, min, max)
+		cw.cache.DeleteRange(walKeys, min, max)
 
-	// delete from the WAL
-	if _, err := e.WAL.DeleteRange(walKeys, min, max); err != nil {
-		return err
+		// delete from the WAL
+		if _, err := cw.wal.DeleteRange(walKeys, min, max); err != nil {
+			return err
+		}
 	}
 
 	// Have we deleted all points for the series? If so, we need to remove
@@ -1053,26 +1278,10 @@ func (e *Engine) DeleteMeasurement(name []byte) error {
 	// the measurement.  If data exists, we can't delete the field set yet as there
 	// were writes to the measurement while we are deleting it.
 	if err := e.fieldset.DeleteWithLock(string(name), func() error {
-		encodedName := models.EscapeMeasurement(name)
-
-		// First scan the cache to see if any series exists for this measurement.
-		if err := e.Cache.ApplyEntryFn(func(k []byte, _ *entry) error {
-			if bytes.HasPrefix(k, encodedName) {
-				return abortErr
-			}
-			return nil
-		}); err != nil {
-			return err
+		if e.containsMeasurement(measurementKeyPrefixes(name)) {
+			return abortErr
 		}
-
-		// Check the filestore.
-		return e.FileStore.WalkKeys(func(k []byte, typ byte) error {
-			if bytes.HasPrefix(k, encodedName) {
-				return abortErr
-			}
-			return nil
-		})
-
+		return nil
 	}); err != nil && err != abortErr {
 		// Something else failed, return it
 		return err
@@ -1081,20 +1290,111 @@ func (e *Engine) DeleteMeasurement(name []byte) error {
 	return nil
 }
 
-// DeleteMeasurement deletes a measurement and all related series.
+// deleteMeasurement deletes the data of a measurement and removes its series
+// from the index.
 func (e *Engine) deleteMeasurement(name []byte) error {
 	// Attempt to find the series keys.
 	keys, err := e.index.MeasurementSeriesKeysByExpr(name, nil)
 	if err != nil {
 		return err
-	} else if len(keys) > 0 {
-		if err := e.deleteSeries(keys); err != nil {
+	} else if len(keys) == 0 {
+		return nil
+	}
+
+	// Disable compactions so that they don't write out the deleted keys, as
+	// in DeleteSeriesRange.
+	e.disableLevelCompactions()
+	defer e.enableLevelCompactions()
+
+	// The keys of a measurement are contiguous in TSM files, so they are removed
+	// from each file as a range, and recorded as a single prefix tombstone rather
+	// than one tombstone per key.  Compactions drop the removed ranges.
+	prefixes := measurementKeyPrefixes(name)
+	if err := e.FileStore.DeletePrefix(prefixes); err != nil {
+		return err
+	}
+
+	for _, cw := range e.cacheWALs() {
+		var walKeys [][]byte
+		_ = cw.cache.ApplyEntryFn(func(k []byte, _ *entry) error {
+			if hasAnyPrefix(k, prefixes) {
+				walKeys = append(walKeys, k)
+			}
+			return nil
+		})
+
+		if len(walKeys) > 0 {
+			cw.cache.DeleteRange(walKeys, math.MinInt64, math.MaxInt64)
+			if _, err := cw.wal.DeleteRange(walKeys, math.MinInt64, math.MaxInt64); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Series written while the measurement was deleted are kept in the index.
+	existing := make(map[string]bool)
+	if e.containsMeasurement(prefixes) {
+		if existing, err = e.containsSeries(keys); err != nil {
 			return err
 		}
 	}
+
+	for _, k := range keys {
+		if !existing[string(k)] {
+			if err := e.index.UnassignShard(string(k), e.id); err != nil {
+				return err
+			}
+		}
+	}
+	go e.index.Rebuild()
+
 	return nil
 }
 
+// containsMeasurement returns true if the cache or any TSM file holds a key
+// starting with any of prefixes.
+func (e *Engine) containsMeasurement(prefixes [][]byte) bool {
+	// A sentinel error to stop scanning the cache once a key is found.
+	foundErr := fmt.Errorf("key found")
+	for _, cw := range e.cacheWALs() {
+		if err := cw.cache.ApplyEntryFn(func(k []byte, _ *entry) error {
+			if hasAnyPrefix(k, prefixes) {
+				return foundErr
+			}
+			return nil
+		}); err == foundErr {
+			return true
+		}
+	}
+
+	for _, p := range prefixes {
+		if e.FileStore.ContainsPrefix(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// measurementKeyPrefixes returns the prefixes of the keys of the series of a
+// measurement, with and without tags.
+func measurementKeyPrefixes(name []byte) [][]byte {
+	encodedName := models.EscapeMeasurement(name)
+	return [][]byte{
+		append(append([]byte{}, encodedName...), ','),
+		append(append([]byte{}, encodedName...), keyFieldSeparator...),
+	}
+}
+
+// hasAnyPrefix returns true if k starts with any of prefixes.
+func hasAnyPrefix(k []byte, prefixes [][]byte) bool {
+	for _, p := range prefixes {
+		if bytes.HasPrefix(k, p) {
+			return true
+		}
+	}
+	return false
+}
+
 // ForEachMeasurementName iterates over each measurement name in the engine.
 func (e *Engine) ForEachMeasurementName(fn func(name []byte) error) error {
 	return e.index.ForEachMeasurementName(fn)
@@ -1117,7 +1417,20 @@ func (e *Engine) CreateSeriesIfNotExists(key, name []byte, tags models.Tags) err
 func (e *Engine) WriteTo(w io.Writer) (n int64, err error) { panic("not implemented") }
 
 // WriteSnapshot will snapshot the cache and write a new TSM file with its contents, releasing the snapshot when done.
+// The backfill cache is written after the cache, as it holds the newer values of points in both.
 func (e *Engine) WriteSnapshot() error {
+	if err := e.writeSnapshot(e.WAL, e.Cache); err != nil {
+		return err
+	}
+	if e.BackfillCache != nil {
+		return e.writeSnapshot(e.BackfillWAL, e.BackfillCache)
+	}
+	return nil
+}
+
+// writeSnapshot snapshots cache and writes a new TSM file with its contents,
+// removing the closed segments of w once written.
+func (e *Engine) writeSnapshot(w *WAL, cache *Cache) error {
 	// Lock and grab the cache snapshot along with all the closed WAL
 	// filenames associated with the snapshot
 
@@ -1125,7 +1438,7 @@ func (e *Engine) WriteSnapshot() error {
 
 	defer func() {
 		if started != nil {
-			e.Cache.UpdateCompactTime(time.Since(*started))
+			cache.UpdateCompactTime(time.Since(*started))
 			e.logger.Info(fmt.Sprintf("Snapshot for path %s written in %v", e.path, time.Since(*started)))
 		}
 	}()
@@ -1137,16 +1450,16 @@ func (e *Engine) WriteSnapshot() error {
 		now := time.Now()
 		started = &now
 
-		if err := e.WAL.CloseSegment(); err != nil {
+		if err := w.CloseSegment(); err != nil {
 			return nil, nil, err
 		}
 
-		segments, err := e.WAL.ClosedSegments()
+		segments, err := w.ClosedSegments()
 		if err != nil {
 			return nil, nil, err
 		}
 
-		snapshot, err := e.Cache.Snapshot()
+		snapshot, err := cache.Snapshot()
 		if err != nil {
 			return nil, nil, err
 		}
@@ -1159,7 +1472,7 @@ func (e *Engine) WriteSnapshot() error {
 	}
 
 	if snapshot.Size() == 0 {
-		e.Cache.ClearSnapshot(true)
+		cache.ClearSnapshot(true)
 		return nil
 	}
 
@@ -1170,7 +1483,22 @@ func (e *Engine) WriteSnapshot() error {
 	snapshot.Deduplicate()
 	e.traceLogger.Info(fmt.Sprintf("Snapshot for path %s deduplicated in %v", e.path, time.Since(dedup)))
 
-	return e.writeSnapshotAndCommit(closedFiles, snapshot)
+	return e.writeSnapshotAndCommit(w, cache, closedFiles, snapshot)
+}
+
+// Offload offloads the TSM files of the engine to the tiering store under
+// prefix. It returns the number of files offloaded.
+func (e *Engine) Offload(prefix string) (int, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.FileStore.Offload(prefix)
+}
+
+// Scrub checks the blocks of the TSM files of the engine against their
+// checksums, quarantining files with corrupt blocks to quarantineDir if it is
+// set.  It returns the number of corrupt files found.
+func (e *Engine) Scrub(throttle *limiter.IOSubsystem, quarantineDir string, done <-chan struct{}) (int, error) {
+	return e.FileStore.Scrub(throttle, quarantineDir, done)
 }
 
 // CreateSnapshot will create a temp directory that holds
@@ -1187,10 +1515,10 @@ func (e *Engine) CreateSnapshot() (string, error) {
 }
 
 // writeSnapshotAndCommit will write the passed cache to a new TSM file and remove the closed WAL segments.
-func (e *Engine) writeSnapshotAndCommit(closedFiles []string, snapshot *Cache) (err error) {
+func (e *Engine) writeSnapshotAndCommit(w *WAL, cache *Cache, closedFiles []string, snapshot *Cache) (err error) {
 	defer func() {
 		if err != nil {
-			e.Cache.ClearSnapshot(false)
+			cache.ClearSnapshot(false)
 		}
 	}()
 
@@ -1211,9 +1539,9 @@ func (e *Engine) writeSnapshotAndCommit(closedFiles []string, snapshot *Cache) (
 	}
 
 	// clear the snapshot from the in-memory cache, then the old WAL files
-	e.Cache.ClearSnapshot(true)
+	cache.ClearSnapshot(true)
 
-	if err := e.WAL.Remove(closedFiles); err != nil {
+	if err := w.Remove(closedFiles); err != nil {
 		e.logger.Info(fmt.Sprintf("error removing closed wal segments: %v", err))
 	}
 
@@ -1231,10 +1559,22 @@ func (e *Engine) compactCache(quit <-chan struct{}) {
 
 		case <-t.C:
 			e.Cache.UpdateAge()
-			if e.ShouldCompactCache(e.WAL.LastWriteTime()) {
+			if e.BackfillCache != nil {
+				e.BackfillCache.UpdateAge()
+			}
+
+			// Writing the backfill cache writes the cache first.
+			var snapshot func() error
+			if e.shouldCompactBackfill() {
+				snapshot = e.WriteSnapshot
+			} else if e.ShouldCompactCache(e.WAL.LastWriteTime()) {
+				snapshot = func() error { return e.writeSnapshot(e.WAL, e.Cache) }
+			}
+
+			if snapshot != nil {
 				start := time.Now()
 				e.traceLogger.Info(fmt.Sprintf("Compacting cache for %s", e.path))
-				err := e.WriteSnapshot()
+				err := snapshot()
 				if err != nil && err != errCompactionsDisabled {
 					e.logger.Info(fmt.Sprintf("error writing snapshot: %v", err))
 					atomic.AddInt64(&e.stats.CacheCompactionErrors, 1)
@@ -1305,7 +1645,13 @@ func (e *Engine) compact(quit <-chan struct{}) {
 			e.scheduler.setDepth(1, len(level1Groups))
 			e.scheduler.setDepth(2, len(level2Groups))
 			e.scheduler.setDepth(3, len(level3Groups))
-			e.scheduler.setDepth(4, len(level4Groups))
+			// Full compactions wait while the limit of full compactions is reached,
+			// so that the scheduler picks from the other levels.
+			if e.fullCompactionLimiter != nil && e.fullCompactionLimiter.Available() == 0 {
+				e.scheduler.setDepth(4, 0)
+			} else {
+				e.scheduler.setDepth(4, len(level4Groups))
+			}
 
 			// Find the next compaction that can run and try to kick it off
 			if level, runnable := e.scheduler.next(); runnable {
@@ -1412,6 +1758,10 @@ func (e *Engine) compactFull(grp CompactionGroup) bool {
 		return false
 	}
 
+	if e.fullCompactionLimiter != nil && !e.fullCompactionLimiter.TryTake() {
+		return false
+	}
+
 	// Try the lo priority limiter, otherwise steal a little from the high priority if we can.
 	if e.compactionLimiter.TryTake() {
 		atomic.AddInt64(&e.stats.TSMFullCompactionsActive, 1)
@@ -1420,12 +1770,19 @@ func (e *Engine) compactFull(grp CompactionGroup) bool {
 			defer e.wg.Done()
 			defer atomic.AddInt64(&e.stats.TSMFullCompactionsActive, -1)
 			defer e.compactionLimiter.Release()
+			if e.fullCompactionLimiter != nil {
+				defer e.fullCompactionLimiter.Release()
+			}
 			s.Apply()
 			// Release the files in the compaction plan
 			e.CompactionPlan.Release([]CompactionGroup{s.group})
 		}()
 		return true
 	}
+
+	if e.fullCompactionLimiter != nil {
+		e.fullCompactionLimiter.Release()
+	}
 	return false
 }
 
@@ -1622,27 +1979,47 @@ func (e *Engine) fullCompactionStrategy(group CompactionGroup, optimize bool) *c
 
 // reloadCache reads the WAL segment files and loads them into the cache.
 func (e *Engine) reloadCache() error {
+	if e.BackfillWAL != nil {
+		if err := e.loadWAL(e.BackfillWAL, e.BackfillCache); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	if ok, err := e.loadShutdownSnapshot(); err != nil {
+		e.logger.Info(fmt.Sprintf("WARN: error loading shutdown snapshot for %s, replaying WAL: %v", e.path, err))
+	} else if ok {
+		e.traceLogger.Info(fmt.Sprintf("Loaded cache %s from shutdown snapshot in %v", e.WAL.Path(), time.Since(now)))
+		return nil
+	}
+
+	return e.loadWAL(e.WAL, e.Cache)
+}
+
+// loadWAL reads the segment files of w and loads them into cache.
+func (e *Engine) loadWAL(w *WAL, cache *Cache) error {
 	now := time.Now()
-	files, err := segmentFileNames(e.WAL.Path())
+
+	files, err := segmentFileNames(w.Path())
 	if err != nil {
 		return err
 	}
 
-	limit := e.Cache.MaxSize()
+	limit := cache.MaxSize()
 	defer func() {
-		e.Cache.SetMaxSize(limit)
+		cache.SetMaxSize(limit)
 	}()
 
 	// Disable the max size during loading
-	e.Cache.SetMaxSize(0)
+	cache.SetMaxSize(0)
 
 	loader := NewCacheLoader(files)
 	loader.WithLogger(e.logger)
-	if err := loader.Load(e.Cache); err != nil {
+	if err := loader.Load(cache); err != nil {
 		return err
 	}
 
-	e.traceLogger.Info(fmt.Sprintf("Reloaded WAL cache %s in %v", e.WAL.Path(), time.Since(now)))
+	e.traceLogger.Info(fmt.Sprintf("Reloaded WAL cache %s in %v", w.Path(), time.Since(now)))
 	return nil
 }
 
@@ -1984,6 +2361,11 @@ func (e *Engine) createTagSetGroupIterators(ctx context.Context, ref *influxql.V
 			return nil, fmt.Errorf("max-select-series limit exceeded: (%d/%d)", len(itrs), opt.MaxSeriesN)
 		}
 
+		// Account for the blocks the cursors of the series will decode.
+		if err := opt.Memory.Grow(seriesIteratorSize); err != nil {
+			query.Iterators(itrs).Close()
+			return nil, err
+		}
 	}
 	return itrs, nil
 }
@@ -2299,7 +2681,7 @@ func (e *Engine) IteratorCost(measurement string, opt query.IteratorOptions) (qu
 	}
 
 	// Count the number of series concatenated from the tag set.
-	cost := query.IteratorCost{NumShards: 1}
+	cost := query.IteratorCost{NumShards: 1, ShardIDs: []uint64{e.id}}
 	for _, t := range tagSets {
 		cost.NumSeries += int64(len(t.SeriesKeys))
 		for i, key := range t.SeriesKeys {
@@ -2338,7 +2720,7 @@ func (e *Engine) seriesCost(seriesKey, field string, tmin, tmax int64) query.Ite
 	c := e.FileStore.Cost(key, tmin, tmax)
 
 	// Retrieve the range of values within the cache.
-	cacheValues := e.Cache.Values(key)
+	cacheValues := e.cacheValues(key)
 	c.CachedValues = int64(len(cacheValues.Include(tmin, tmax)))
 	return c
 }
@@ -624,6 +624,76 @@ func TestEngine_DeleteSeries(t *testing.T) {
 	}
 }
 
+// Ensures that dropping a measurement removes its series from TSM files, the
+// cache and the index, leaving measurements sharing its prefix alone.
+func TestEngine_DeleteMeasurement(t *testing.T) {
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) {
+			points := []models.Point{
+				MustParsePointString("cpu value=1.0 1000000000"),
+				MustParsePointString("cpu,host=A value=1.1 1000000000"),
+				MustParsePointString("cpu,host=B value=1.2 2000000000"),
+				MustParsePointString("cpu2,host=A value=1.3 3000000000"),
+			}
+
+			e := NewEngine(index)
+			// mock the planner so compactions don't run during the test
+			e.CompactionPlan = &mockPlanner{}
+
+			if err := e.Open(); err != nil {
+				panic(err)
+			}
+			defer e.Close()
+
+			for _, p := range append(points, MustParsePointString("cpu,host=C value=1.4 4000000000")) {
+				if err := e.CreateSeriesIfNotExists(p.Key(), p.Name(), p.Tags()); err != nil {
+					t.Fatalf("failed to create series: %s", err.Error())
+				}
+			}
+
+			if err := e.WritePoints(points); err != nil {
+				t.Fatalf("failed to write points: %s", err.Error())
+			}
+			if err := e.WriteSnapshot(); err != nil {
+				t.Fatalf("failed to snapshot: %s", err.Error())
+			}
+
+			// Leave a point of the measurement in the cache.
+			if err := e.WritePoints([]models.Point{MustParsePointString("cpu,host=C value=1.4 4000000000")}); err != nil {
+				t.Fatalf("failed to write points: %s", err.Error())
+			}
+
+			if err := e.DeleteMeasurement([]byte("cpu")); err != nil {
+				t.Fatalf("failed to delete measurement: %v", err)
+			}
+
+			keys := e.FileStore.Keys()
+			if exp, got := 1, len(keys); exp != got {
+				t.Fatalf("series count mismatch: exp %v, got %v", exp, got)
+			}
+			exp := "cpu2,host=A#!~#value"
+			if _, ok := keys[exp]; !ok {
+				t.Fatalf("wrong series deleted: exp %v, got %v", exp, keys)
+			}
+
+			if got := e.Cache.Keys(); len(got) != 0 {
+				t.Fatalf("unexpected cache keys: %q", got)
+			}
+
+			if got, err := e.MeasurementSeriesKeysByExpr([]byte("cpu"), nil); err != nil {
+				t.Fatal(err)
+			} else if len(got) != 0 {
+				t.Fatalf("unexpected series in index: %q", got)
+			}
+			if got, err := e.MeasurementSeriesKeysByExpr([]byte("cpu2"), nil); err != nil {
+				t.Fatal(err)
+			} else if len(got) != 1 {
+				t.Fatalf("unexpected series in index: %q", got)
+			}
+		})
+	}
+}
+
 func TestEngine_LastModified(t *testing.T) {
 	for _, index := range tsdb.RegisteredIndexes() {
 		t.Run(index, func(t *testing.T) {
@@ -0,0 +1,14 @@
+package tsm1
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// fadviseDontNeed advises the kernel to drop the pages of f from offset to
+// offset+length from the page cache. A length of 0 covers the rest of the
+// file. Pages mapped into memory are not dropped.
+func fadviseDontNeed(f *os.File, offset, length int64) error {
+	return unix.Fadvise(int(f.Fd()), offset, length, unix.FADV_DONTNEED)
+}
@@ -0,0 +1,10 @@
+// +build !linux
+
+package tsm1
+
+import "os"
+
+// fadviseDontNeed is a no-op on platforms without posix_fadvise support.
+func fadviseDontNeed(f *os.File, offset, length int64) error {
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/pkg/metrics"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/tsdb/tiering"
 	"github.com/uber-go/zap"
 )
 
@@ -76,6 +77,12 @@ type TSMFile interface {
 	// DeleteRange removes the values for keys between timestamps min and max.
 	DeleteRange(keys [][]byte, min, max int64) error
 
+	// DeletePrefix removes the keys starting with any of prefixes.
+	DeletePrefix(prefixes [][]byte) error
+
+	// ContainsPrefix returns true if a key starting with prefix exists in the file.
+	ContainsPrefix(prefix []byte) bool
+
 	// HasTombstones returns true if file contains values that have been deleted.
 	HasTombstones() bool
 
@@ -118,8 +125,12 @@ type TSMFile interface {
 
 // Statistics gathered by the FileStore.
 const (
-	statFileStoreBytes = "diskBytes"
-	statFileStoreCount = "numFiles"
+	statFileStoreBytes       = "diskBytes"
+	statFileStoreCount       = "numFiles"
+	statScrubBlocks          = "scrubBlocks"
+	statScrubBytes           = "scrubBytes"
+	statScrubCorruptBlocks   = "scrubCorruptBlocks"
+	statScrubQuarantineFiles = "scrubQuarantinedFiles"
 )
 
 var (
@@ -156,6 +167,13 @@ type FileStore struct {
 	purger *purger
 
 	currentTempDirID int
+
+	// mmapMaxAge is the age of the newest data in a file after which the file
+	// is read with pread rather than memory-mapped. Zero memory-maps every file.
+	mmapMaxAge time.Duration
+
+	// tiering is the store files are offloaded to, if tiering is enabled.
+	tiering *tiering.Store
 }
 
 // FileStat holds information about a TSM file on disk.
@@ -223,6 +241,11 @@ func (f *FileStore) WithLogger(log zap.Logger) {
 type FileStoreStatistics struct {
 	DiskBytes int64
 	FileCount int64
+
+	ScrubBlocks           int64 // Counter of blocks checked by the scrubber.
+	ScrubBytes            int64 // Counter of bytes read by the scrubber.
+	ScrubCorruptBlocks    int64 // Counter of corrupt blocks found by the scrubber.
+	ScrubQuarantinedFiles int64 // Counter of files quarantined by the scrubber.
 }
 
 // Statistics returns statistics for periodic monitoring.
@@ -231,8 +254,12 @@ func (f *FileStore) Statistics(tags map[string]string) []models.Statistic {
 		Name: "tsm1_filestore",
 		Tags: tags,
 		Values: map[string]interface{}{
-			statFileStoreBytes: atomic.LoadInt64(&f.stats.DiskBytes),
-			statFileStoreCount: atomic.LoadInt64(&f.stats.FileCount),
+			statFileStoreBytes:       atomic.LoadInt64(&f.stats.DiskBytes),
+			statFileStoreCount:       atomic.LoadInt64(&f.stats.FileCount),
+			statScrubBlocks:          atomic.LoadInt64(&f.stats.ScrubBlocks),
+			statScrubBytes:           atomic.LoadInt64(&f.stats.ScrubBytes),
+			statScrubCorruptBlocks:   atomic.LoadInt64(&f.stats.ScrubCorruptBlocks),
+			statScrubQuarantineFiles: atomic.LoadInt64(&f.stats.ScrubQuarantinedFiles),
 		},
 	}}
 }
@@ -363,6 +390,63 @@ func (f *FileStore) DeleteRange(keys [][]byte, min, max int64) error {
 	return nil
 }
 
+// DeletePrefix removes the keys starting with any of prefixes, such as the keys
+// of a measurement.  Each file records a single tombstone entry per prefix, and
+// files left without keys are removed.  Compactions must be disabled while the
+// keys are deleted.
+func (f *FileStore) DeletePrefix(prefixes [][]byte) error {
+	var mu sync.Mutex
+	var empty []string
+	if err := f.walkFiles(func(tsm TSMFile) error {
+		if err := tsm.DeletePrefix(prefixes); err != nil {
+			return err
+		}
+		if tsm.KeyCount() == 0 {
+			mu.Lock()
+			empty = append(empty, tsm.Path())
+			mu.Unlock()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := f.Replace(empty, nil); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.lastModified = time.Now().UTC()
+	f.lastFileStats = nil
+	f.mu.Unlock()
+	return nil
+}
+
+// ContainsPrefix returns true if a key starting with prefix exists in any file.
+func (f *FileStore) ContainsPrefix(prefix []byte) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, tsm := range f.files {
+		if tsm.ContainsPrefix(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readerOptions returns the options TSM files are opened with.
+func (f *FileStore) readerOptions() []tsmReaderOption {
+	var options []tsmReaderOption
+	if f.mmapMaxAge > 0 {
+		options = append(options, withMmapCutoff(time.Now().Add(-f.mmapMaxAge).UnixNano()))
+	}
+	if f.tiering != nil {
+		options = append(options, withTiering(f.tiering))
+	}
+	return options
+}
+
 // Open loads all the TSM files in the configured directory.
 func (f *FileStore) Open() error {
 	f.mu.Lock()
@@ -421,7 +505,7 @@ func (f *FileStore) Open() error {
 
 		go func(idx int, file *os.File) {
 			start := time.Now()
-			df, err := NewTSMReader(file)
+			df, err := NewTSMReader(file, f.readerOptions()...)
 			f.logger.Info(fmt.Sprintf("%s (#%d) opened in %v", file.Name(), idx, time.Since(start)))
 
 			if err != nil {
@@ -604,7 +688,7 @@ func (f *FileStore) replace(oldFiles, newFiles []string, updatedFn func(r []TSMF
 			}
 		}
 
-		tsm, err := NewTSMReader(fd)
+		tsm, err := NewTSMReader(fd, f.readerOptions()...)
 		if err != nil {
 			return err
 		}
@@ -644,6 +728,9 @@ func (f *FileStore) replace(oldFiles, newFiles []string, updatedFn func(r []TSMF
 						deletes = append(deletes, t.Path)
 					}
 					deletes = append(deletes, file.Path())
+					if r, ok := file.(*TSMReader); ok && r.Remote() {
+						deletes = append(deletes, refPath(file.Path()))
+					}
 
 					// Rename the TSM file used by this reader
 					tempPath := file.Path() + ".tmp"
@@ -744,6 +831,10 @@ func (f *FileStore) BlockCount(path string, idx int) int {
 				}
 			}
 			_, _, _, _, _, block, _ := iter.Read()
+			block, err := DecryptBlock(block)
+			if err != nil {
+				return 0
+			}
 			return BlockCount(block)
 		}
 	}
@@ -890,7 +981,8 @@ func (f *FileStore) locations(key []byte, t int64, ascending bool) []*location {
 }
 
 // CreateSnapshot creates hardlinks for all tsm and tombstone files
-// in the path provided.
+// in the path provided. Offloaded files are downloaded rather than linked
+// so that the snapshot holds their blocks.
 func (f *FileStore) CreateSnapshot() (string, error) {
 	f.traceLogger.Info(fmt.Sprintf("Creating snapshot in %s", f.dir))
 	files := f.Files()
@@ -911,7 +1003,11 @@ func (f *FileStore) CreateSnapshot() (string, error) {
 
 	for _, tsmf := range files {
 		newpath := filepath.Join(tmpPath, filepath.Base(tsmf.Path()))
-		if err := os.Link(tsmf.Path(), newpath); err != nil {
+		if r, ok := tsmf.(*TSMReader); ok && r.Remote() {
+			if err := f.download(r, newpath); err != nil {
+				return "", fmt.Errorf("error downloading offloaded tsm file: %q", err)
+			}
+		} else if err := os.Link(tsmf.Path(), newpath); err != nil {
 			return "", fmt.Errorf("error creating tsm hard link: %q", err)
 		}
 		// Check for tombstones and link those as well
@@ -923,6 +1019,17 @@ func (f *FileStore) CreateSnapshot() (string, error) {
 		}
 	}
 
+	// Link the zstd dictionaries the blocks of the files may be compressed with.
+	dicts, err := filepath.Glob(filepath.Join(f.dir, "*."+ZstdDictExtension))
+	if err != nil {
+		return "", err
+	}
+	for _, dict := range dicts {
+		if err := os.Link(dict, filepath.Join(tmpPath, filepath.Base(dict))); err != nil {
+			return "", fmt.Errorf("error creating zstd dictionary hard link: %q", err)
+		}
+	}
+
 	return tmpPath, nil
 }
 
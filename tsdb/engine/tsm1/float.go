@@ -25,6 +25,10 @@ const (
 
 	// floatCompressedGorilla is a compressed format using the gorilla paper encoding
 	floatCompressedGorilla = 1
+
+	// floatCompressedZstd is the gorilla encoding further compressed with zstd
+	// by compactions.
+	floatCompressedZstd = 2
 )
 
 // uvnan is the constant returned from math.NaN().
@@ -166,8 +170,14 @@ func (it *FloatDecoder) SetBytes(b []byte) error {
 		v = uvnan
 	} else {
 		// first byte is the compression type.
-		// we currently just have gorilla compression.
-		it.br.Reset(b[1:])
+		data := b[1:]
+		if b[0]>>4 == floatCompressedZstd {
+			var err error
+			if data, err = decompressZstd(data); err != nil {
+				return fmt.Errorf("failed to decode float block: %v", err)
+			}
+		}
+		it.br.Reset(data)
 
 		var err error
 		v, err = it.br.ReadBits(64)
@@ -169,6 +169,11 @@ func (itr *floatInstrumentedIterator) Close() error {
 			panic("unexpected metrics")
 		}
 	})
+	stats := itr.Stats()
+	f = append(f,
+		fields.Int64("series_read", int64(stats.SeriesN)),
+		fields.Int64("points_read", int64(stats.PointN)),
+	)
 	itr.span.SetFields(f)
 	itr.span.Finish()
 
@@ -632,6 +637,11 @@ func (itr *integerInstrumentedIterator) Close() error {
 			panic("unexpected metrics")
 		}
 	})
+	stats := itr.Stats()
+	f = append(f,
+		fields.Int64("series_read", int64(stats.SeriesN)),
+		fields.Int64("points_read", int64(stats.PointN)),
+	)
 	itr.span.SetFields(f)
 	itr.span.Finish()
 
@@ -1095,6 +1105,11 @@ func (itr *unsignedInstrumentedIterator) Close() error {
 			panic("unexpected metrics")
 		}
 	})
+	stats := itr.Stats()
+	f = append(f,
+		fields.Int64("series_read", int64(stats.SeriesN)),
+		fields.Int64("points_read", int64(stats.PointN)),
+	)
 	itr.span.SetFields(f)
 	itr.span.Finish()
 
@@ -1558,6 +1573,11 @@ func (itr *stringInstrumentedIterator) Close() error {
 			panic("unexpected metrics")
 		}
 	})
+	stats := itr.Stats()
+	f = append(f,
+		fields.Int64("series_read", int64(stats.SeriesN)),
+		fields.Int64("points_read", int64(stats.PointN)),
+	)
 	itr.span.SetFields(f)
 	itr.span.Finish()
 
@@ -2021,6 +2041,11 @@ func (itr *booleanInstrumentedIterator) Close() error {
 			panic("unexpected metrics")
 		}
 	})
+	stats := itr.Stats()
+	f = append(f,
+		fields.Int64("series_read", int64(stats.SeriesN)),
+		fields.Int64("points_read", int64(stats.PointN)),
+	)
 	itr.span.SetFields(f)
 	itr.span.Finish()
 
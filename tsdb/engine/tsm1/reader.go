@@ -7,12 +7,15 @@ import (
 	"io"
 	"math"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 
 	"github.com/influxdata/influxdb/pkg/bytesutil"
+	"github.com/influxdata/influxdb/tsdb/tiering"
 )
 
 // ErrFileInUse is returned when attempting to remove or close a TSM file that is still being used.
@@ -34,11 +37,68 @@ type TSMReader struct {
 	// tombstoner ensures tombstoned keys are not available by the index.
 	tombstoner *Tombstoner
 
-	// size is the size of the file on disk.
+	// size is the size of the file on disk, or of the offloaded file if the
+	// file is a stub.
 	size int64
 
 	// lastModified is the last time this file was modified on disk
 	lastModified int64
+
+	// pread and dropPageCache select the preadAccessor over the mmapAccessor.
+	pread         bool
+	dropPageCache bool
+
+	// mmapCutoff is the time at or after which the file must contain data to
+	// be memory-mapped. Files with older data are read with pread.
+	mmapCutoff int64
+
+	// tiering is the store files are offloaded to. ref refers to the
+	// offloaded file if the file is a stub holding only the index.
+	tiering *tiering.Store
+	ref     *tiering.Ref
+
+	// zstdDir is the directory whose zstd dictionaries the reader holds
+	// until it is closed.
+	zstdDir string
+}
+
+// tsmReaderOption configures how a TSMReader reads its file.
+type tsmReaderOption func(*TSMReader)
+
+// WithPread reads blocks with pread rather than memory-mapping the file.
+func WithPread(enabled bool) tsmReaderOption {
+	return func(r *TSMReader) {
+		r.pread = enabled
+	}
+}
+
+// WithDropPageCache reads blocks with pread and drops the pages of each block
+// from the OS page cache once read. It is intended for large sequential reads,
+// such as exports, that should not evict the working set of queries.
+func WithDropPageCache(enabled bool) tsmReaderOption {
+	return func(r *TSMReader) {
+		r.dropPageCache = enabled
+	}
+}
+
+// withMmapCutoff memory-maps the file only if its newest data is at or after
+// t, in unix nanoseconds.
+func withMmapCutoff(t int64) tsmReaderOption {
+	return func(r *TSMReader) {
+		r.mmapCutoff = t
+	}
+}
+
+// withTiering reads the blocks of offloaded files from the store s.
+func withTiering(s *tiering.Store) tsmReaderOption {
+	return func(r *TSMReader) {
+		r.tiering = s
+	}
+}
+
+// refPath returns the path of the ref file of the TSM file at path.
+func refPath(path string) string {
+	return strings.TrimSuffix(path, "."+TSMFileExtension) + "." + tiering.RefExtension
 }
 
 // TSMIndex represent the index section of a TSM file.  The index records all
@@ -50,6 +110,12 @@ type TSMIndex interface {
 	// DeleteRange removes the given keys with data between minTime and maxTime from the index.
 	DeleteRange(keys [][]byte, minTime, maxTime int64)
 
+	// DeletePrefix removes the keys starting with prefix from the index.
+	DeletePrefix(prefix []byte)
+
+	// ContainsPrefix returns true if a key starting with prefix exists in the index.
+	ContainsPrefix(prefix []byte) bool
+
 	// Contains return true if the given key exists in the index.
 	Contains(key []byte) bool
 
@@ -209,9 +275,13 @@ type blockAccessor interface {
 	free() error
 }
 
-// NewTSMReader returns a new TSMReader from the given file.
-func NewTSMReader(f *os.File) (*TSMReader, error) {
-	t := &TSMReader{}
+// NewTSMReader returns a new TSMReader from the given file. The file is
+// memory-mapped unless options select otherwise.
+func NewTSMReader(f *os.File, options ...tsmReaderOption) (*TSMReader, error) {
+	t := &TSMReader{mmapCutoff: math.MinInt64}
+	for _, option := range options {
+		option(t)
+	}
 
 	stat, err := f.Stat()
 	if err != nil {
@@ -219,8 +289,36 @@ func NewTSMReader(f *os.File) (*TSMReader, error) {
 	}
 	t.size = stat.Size()
 	t.lastModified = stat.ModTime().UnixNano()
-	t.accessor = &mmapAccessor{
-		f: f,
+
+	ref, err := tiering.ReadRef(refPath(f.Name()))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// A file as large as the offloaded file was not replaced by its stub
+	// before the offload was interrupted, and is read locally.
+	remote := err == nil && ref.Size != t.size
+
+	forcePread := t.pread || t.dropPageCache
+	if remote {
+		if t.tiering == nil {
+			return nil, fmt.Errorf("file %s is offloaded but tiering is not enabled", f.Name())
+		}
+		t.ref = &ref
+		t.size = ref.Size
+		t.accessor = &preadAccessor{
+			f:      f,
+			blocks: t.tiering.Object(ref),
+		}
+	} else if forcePread || t.mmapCutoff != math.MinInt64 {
+		t.accessor = &preadAccessor{
+			f:             f,
+			dropPageCache: t.dropPageCache,
+		}
+	} else {
+		t.accessor = &mmapAccessor{
+			f: f,
+		}
 	}
 
 	index, err := t.accessor.init()
@@ -228,6 +326,19 @@ func NewTSMReader(f *os.File) (*TSMReader, error) {
 		return nil, err
 	}
 
+	// The time range of a file is only known once its index is read, so files
+	// with recent data are read again to be memory-mapped.
+	if _, maxTime := index.TimeRange(); !remote && !forcePread && maxTime >= t.mmapCutoff {
+		if _, ok := t.accessor.(*preadAccessor); ok {
+			t.accessor = &mmapAccessor{
+				f: f,
+			}
+			if index, err = t.accessor.init(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	t.index = index
 	t.tombstoner = &Tombstoner{Path: t.Path()}
 
@@ -235,6 +346,13 @@ func NewTSMReader(f *os.File) (*TSMReader, error) {
 		return nil, err
 	}
 
+	// Blocks compressed with zstd need the dictionaries beside the file.
+	dir := filepath.Dir(f.Name())
+	if err := acquireZstdDicts(dir); err != nil {
+		return nil, err
+	}
+	t.zstdDir = dir
+
 	return t, nil
 }
 
@@ -243,6 +361,15 @@ func (t *TSMReader) applyTombstones() error {
 	batch := make([][]byte, 0, 4096)
 
 	if err := t.tombstoner.Walk(func(ts Tombstone) error {
+		if ts.Prefix {
+			if len(batch) > 0 {
+				t.index.DeleteRange(batch, prev.Min, prev.Max)
+				batch = batch[:0]
+			}
+			t.index.DeletePrefix(ts.Key)
+			return nil
+		}
+
 		cur = ts
 		if len(batch) > 0 {
 			if prev.Min != cur.Min || prev.Max != cur.Max {
@@ -381,6 +508,10 @@ func (t *TSMReader) Close() error {
 		return err
 	}
 
+	if t.zstdDir != "" {
+		releaseZstdDicts(t.zstdDir)
+		t.zstdDir = ""
+	}
 	return t.index.Close()
 }
 
@@ -433,9 +564,25 @@ func (t *TSMReader) remove() error {
 	if err := t.tombstoner.Delete(); err != nil {
 		return err
 	}
+
+	// The offloaded file is no longer referenced once its stub is removed.
+	if t.ref != nil {
+		if err := os.Remove(refPath(path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := t.tiering.Delete(t.ref.Key); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// Remote returns true if the file is a stub of a file offloaded to the
+// tiering store.
+func (t *TSMReader) Remote() bool {
+	return t.ref != nil
+}
+
 // Contains returns whether the given key is present in the index.
 func (t *TSMReader) Contains(key []byte) bool {
 	return t.index.Contains(key)
@@ -474,6 +621,33 @@ func (t *TSMReader) DeleteRange(keys [][]byte, minTime, maxTime int64) error {
 	return nil
 }
 
+// DeletePrefix removes the keys starting with any of prefixes.
+func (t *TSMReader) DeletePrefix(prefixes [][]byte) error {
+	var matched [][]byte
+	for _, p := range prefixes {
+		if t.index.ContainsPrefix(p) {
+			matched = append(matched, p)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	if err := t.tombstoner.AddPrefix(matched); err != nil {
+		return err
+	}
+
+	for _, p := range matched {
+		t.index.DeletePrefix(p)
+	}
+	return nil
+}
+
+// ContainsPrefix returns true if a key starting with prefix exists in the file.
+func (t *TSMReader) ContainsPrefix(prefix []byte) bool {
+	return t.index.ContainsPrefix(prefix)
+}
+
 // Delete deletes blocks indicated by keys.
 func (t *TSMReader) Delete(keys [][]byte) error {
 	if err := t.tombstoner.Add(keys); err != nil {
@@ -514,7 +688,9 @@ func (t *TSMReader) IndexSize() uint32 {
 	return t.index.Size()
 }
 
-// Size returns the size of the underlying file in bytes.
+// Size returns the size of the underlying file in bytes. The size of an
+// offloaded file is reported for stubs so that they are planned for
+// compaction as the files they replaced.
 func (t *TSMReader) Size() uint32 {
 	t.mu.RLock()
 	size := t.size
@@ -834,6 +1010,53 @@ func (d *indirectIndex) Delete(keys [][]byte) {
 	d.offsets = d.offsets[:j]
 }
 
+// prefixRange returns the positions in offsets of the first key starting with
+// prefix and of the first key after them.  Callers must hold a lock on d.
+func (d *indirectIndex) prefixRange(prefix []byte) (int, int) {
+	key := func(x []byte) []byte {
+		offset := int32(binary.BigEndian.Uint32(x))
+		keyLen := int32(binary.BigEndian.Uint16(d.b[offset : offset+2]))
+		return d.b[offset+2 : offset+2+keyLen]
+	}
+
+	start := bytesutil.SearchBytesFixed(d.offsets, 4, func(x []byte) bool {
+		return bytes.Compare(key(x), prefix) >= 0
+	})
+	end := bytesutil.SearchBytesFixed(d.offsets, 4, func(x []byte) bool {
+		k := key(x)
+		return bytes.Compare(k, prefix) >= 0 && !bytes.HasPrefix(k, prefix)
+	})
+	return start, end
+}
+
+// DeletePrefix removes the keys starting with prefix from the index.  The keys
+// are contiguous in the index, so they are removed as a single range.
+func (d *indirectIndex) DeletePrefix(prefix []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	start, end := d.prefixRange(prefix)
+	if start >= end {
+		return
+	}
+
+	for k := range d.tombstones {
+		if strings.HasPrefix(k, string(prefix)) {
+			delete(d.tombstones, k)
+		}
+	}
+	d.offsets = append(d.offsets[:start], d.offsets[end:]...)
+}
+
+// ContainsPrefix returns true if a key starting with prefix exists in the index.
+func (d *indirectIndex) ContainsPrefix(prefix []byte) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	start, end := d.prefixRange(prefix)
+	return start < end
+}
+
 // DeleteRange removes the given keys with data between minTime and maxTime from the index.
 func (d *indirectIndex) DeleteRange(keys [][]byte, minTime, maxTime int64) {
 	// No keys, nothing to do
@@ -1274,8 +1497,11 @@ func (m *mmapAccessor) readBlock(entry *IndexEntry, values []Value) ([]Value, er
 		return nil, ErrTSMClosed
 	}
 	//TODO: Validate checksum
-	var err error
-	values, err = DecodeBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	block, err := DecryptBlock(m.b[entry.Offset+4 : entry.Offset+int64(entry.Size)])
+	if err != nil {
+		return nil, err
+	}
+	values, err = DecodeBlock(block, values)
 	if err != nil {
 		return nil, err
 	}
@@ -1292,7 +1518,12 @@ func (m *mmapAccessor) readFloatBlock(entry *IndexEntry, values *[]FloatValue) (
 		return nil, ErrTSMClosed
 	}
 
-	a, err := DecodeFloatBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	block, err := DecryptBlock(m.b[entry.Offset+4 : entry.Offset+int64(entry.Size)])
+	if err != nil {
+		m.mu.RUnlock()
+		return nil, err
+	}
+	a, err := DecodeFloatBlock(block, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -1311,7 +1542,12 @@ func (m *mmapAccessor) readIntegerBlock(entry *IndexEntry, values *[]IntegerValu
 		return nil, ErrTSMClosed
 	}
 
-	a, err := DecodeIntegerBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	block, err := DecryptBlock(m.b[entry.Offset+4 : entry.Offset+int64(entry.Size)])
+	if err != nil {
+		m.mu.RUnlock()
+		return nil, err
+	}
+	a, err := DecodeIntegerBlock(block, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -1330,7 +1566,12 @@ func (m *mmapAccessor) readUnsignedBlock(entry *IndexEntry, values *[]UnsignedVa
 		return nil, ErrTSMClosed
 	}
 
-	a, err := DecodeUnsignedBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	block, err := DecryptBlock(m.b[entry.Offset+4 : entry.Offset+int64(entry.Size)])
+	if err != nil {
+		m.mu.RUnlock()
+		return nil, err
+	}
+	a, err := DecodeUnsignedBlock(block, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -1349,7 +1590,12 @@ func (m *mmapAccessor) readStringBlock(entry *IndexEntry, values *[]StringValue)
 		return nil, ErrTSMClosed
 	}
 
-	a, err := DecodeStringBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	block, err := DecryptBlock(m.b[entry.Offset+4 : entry.Offset+int64(entry.Size)])
+	if err != nil {
+		m.mu.RUnlock()
+		return nil, err
+	}
+	a, err := DecodeStringBlock(block, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -1368,7 +1614,12 @@ func (m *mmapAccessor) readBooleanBlock(entry *IndexEntry, values *[]BooleanValu
 		return nil, ErrTSMClosed
 	}
 
-	a, err := DecodeBooleanBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
+	block, err := DecryptBlock(m.b[entry.Offset+4 : entry.Offset+int64(entry.Size)])
+	if err != nil {
+		m.mu.RUnlock()
+		return nil, err
+	}
+	a, err := DecodeBooleanBlock(block, values)
 	m.mu.RUnlock()
 
 	if err != nil {
@@ -1409,7 +1660,6 @@ func (m *mmapAccessor) readAll(key []byte) ([]Value, error) {
 	defer m.mu.RUnlock()
 
 	var temp []Value
-	var err error
 	var values []Value
 	for _, block := range blocks {
 		var skip bool
@@ -1427,7 +1677,11 @@ func (m *mmapAccessor) readAll(key []byte) ([]Value, error) {
 		//TODO: Validate checksum
 		temp = temp[:0]
 		// The +4 is the 4 byte checksum length
-		temp, err = DecodeBlock(m.b[block.Offset+4:block.Offset+int64(block.Size)], temp)
+		b, err := DecryptBlock(m.b[block.Offset+4 : block.Offset+int64(block.Size)])
+		if err != nil {
+			return nil, err
+		}
+		temp, err = DecodeBlock(b, temp)
 		if err != nil {
 			return nil, err
 		}
@@ -1467,6 +1721,285 @@ func (m *mmapAccessor) close() error {
 	return m.f.Close()
 }
 
+// preadAccessor is a blockAccessor that reads blocks with pread rather than
+// memory-mapping the file, holding the index on the heap.
+type preadAccessor struct {
+	mu sync.RWMutex
+
+	f      *os.File
+	index  *indirectIndex
+	closed bool
+
+	// blocks, if set, is read for blocks rather than f. It is set for stubs
+	// of offloaded files, which hold only the index of the file.
+	blocks io.ReaderAt
+
+	// dropPageCache drops the pages of each block from the OS page cache once read.
+	dropPageCache bool
+}
+
+func (p *preadAccessor) init() (*indirectIndex, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := verifyVersion(p.f); err != nil {
+		return nil, err
+	}
+
+	stat, err := p.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if stat.Size() < 8 {
+		return nil, fmt.Errorf("preadAccessor: file too small for indirectIndex")
+	}
+
+	indexOfsPos := stat.Size() - 8
+	var footer [8]byte
+	if _, err := p.f.ReadAt(footer[:], indexOfsPos); err != nil {
+		return nil, err
+	}
+	indexStart := int64(binary.BigEndian.Uint64(footer[:]))
+	if indexStart < 0 || indexStart >= indexOfsPos {
+		return nil, fmt.Errorf("preadAccessor: invalid indexStart")
+	}
+
+	b := make([]byte, indexOfsPos-indexStart)
+	if _, err := p.f.ReadAt(b, indexStart); err != nil {
+		return nil, err
+	}
+	if p.dropPageCache {
+		fadviseDontNeed(p.f, indexStart, stat.Size()-indexStart)
+	}
+
+	p.index = NewIndirectIndex()
+	if err := p.index.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return p.index, nil
+}
+
+// readEntry reads the checksum and block of entry into buf, growing it if needed.
+func (p *preadAccessor) readEntry(entry *IndexEntry, buf []byte) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return nil, ErrTSMClosed
+	}
+
+	if cap(buf) < int(entry.Size) {
+		buf = make([]byte, entry.Size)
+	}
+	buf = buf[:entry.Size]
+	if p.blocks != nil {
+		if _, err := p.blocks.ReadAt(buf, entry.Offset); err != nil {
+			return nil, err
+		}
+		return buf, nil
+	}
+
+	if _, err := p.f.ReadAt(buf, entry.Offset); err != nil {
+		return nil, err
+	}
+
+	if p.dropPageCache {
+		fadviseDontNeed(p.f, entry.Offset, int64(entry.Size))
+	}
+	return buf, nil
+}
+
+func (p *preadAccessor) read(key []byte, timestamp int64) ([]Value, error) {
+	entry := p.index.Entry(key, timestamp)
+	if entry == nil {
+		return nil, nil
+	}
+
+	return p.readBlock(entry, nil)
+}
+
+func (p *preadAccessor) readAll(key []byte) ([]Value, error) {
+	blocks := p.index.Entries(key)
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+
+	tombstones := p.index.TombstoneRange(key)
+
+	var temp []Value
+	var values []Value
+	var buf []byte
+	for _, block := range blocks {
+		var skip bool
+		for _, t := range tombstones {
+			// Should we skip this block because it contains points that have been deleted
+			if t.Min <= block.MinTime && t.Max >= block.MaxTime {
+				skip = true
+				break
+			}
+		}
+
+		if skip {
+			continue
+		}
+
+		b, err := p.readEntry(&block, buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = b
+
+		// The first 4 bytes are the checksum
+		temp = temp[:0]
+		b, err = DecryptBlock(buf[4:])
+		if err != nil {
+			return nil, err
+		}
+		temp, err = DecodeBlock(b, temp)
+		if err != nil {
+			return nil, err
+		}
+
+		// Filter out any values that were deleted
+		for _, t := range tombstones {
+			temp = Values(temp).Exclude(t.Min, t.Max)
+		}
+
+		values = append(values, temp...)
+	}
+
+	return values, nil
+}
+
+func (p *preadAccessor) readBlock(entry *IndexEntry, values []Value) ([]Value, error) {
+	buf, err := p.readEntry(entry, nil)
+	if err != nil {
+		return nil, err
+	}
+	block, err := DecryptBlock(buf[4:])
+	if err != nil {
+		return nil, err
+	}
+	return DecodeBlock(block, values)
+}
+
+func (p *preadAccessor) readFloatBlock(entry *IndexEntry, values *[]FloatValue) ([]FloatValue, error) {
+	buf, err := p.readEntry(entry, nil)
+	if err != nil {
+		return nil, err
+	}
+	block, err := DecryptBlock(buf[4:])
+	if err != nil {
+		return nil, err
+	}
+	return DecodeFloatBlock(block, values)
+}
+
+func (p *preadAccessor) readIntegerBlock(entry *IndexEntry, values *[]IntegerValue) ([]IntegerValue, error) {
+	buf, err := p.readEntry(entry, nil)
+	if err != nil {
+		return nil, err
+	}
+	block, err := DecryptBlock(buf[4:])
+	if err != nil {
+		return nil, err
+	}
+	return DecodeIntegerBlock(block, values)
+}
+
+func (p *preadAccessor) readUnsignedBlock(entry *IndexEntry, values *[]UnsignedValue) ([]UnsignedValue, error) {
+	buf, err := p.readEntry(entry, nil)
+	if err != nil {
+		return nil, err
+	}
+	block, err := DecryptBlock(buf[4:])
+	if err != nil {
+		return nil, err
+	}
+	return DecodeUnsignedBlock(block, values)
+}
+
+func (p *preadAccessor) readStringBlock(entry *IndexEntry, values *[]StringValue) ([]StringValue, error) {
+	buf, err := p.readEntry(entry, nil)
+	if err != nil {
+		return nil, err
+	}
+	block, err := DecryptBlock(buf[4:])
+	if err != nil {
+		return nil, err
+	}
+	return DecodeStringBlock(block, values)
+}
+
+func (p *preadAccessor) readBooleanBlock(entry *IndexEntry, values *[]BooleanValue) ([]BooleanValue, error) {
+	buf, err := p.readEntry(entry, nil)
+	if err != nil {
+		return nil, err
+	}
+	block, err := DecryptBlock(buf[4:])
+	if err != nil {
+		return nil, err
+	}
+	return DecodeBooleanBlock(block, values)
+}
+
+func (p *preadAccessor) readBytes(entry *IndexEntry, b []byte) (uint32, []byte, error) {
+	buf, err := p.readEntry(entry, b)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// return the bytes after the 4 byte checksum
+	return binary.BigEndian.Uint32(buf[:4]), buf[4:], nil
+}
+
+func (p *preadAccessor) rename(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.f.Close(); err != nil {
+		return err
+	}
+
+	if err := renameFile(p.f.Name(), path); err != nil {
+		return err
+	}
+
+	var err error
+	p.f, err = os.Open(path)
+	return err
+}
+
+func (p *preadAccessor) path() string {
+	p.mu.RLock()
+	path := p.f.Name()
+	p.mu.RUnlock()
+	return path
+}
+
+// free drops the pages of the file from the OS page cache, as the
+// mmapAccessor does for its mapping.
+func (p *preadAccessor) free() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return nil
+	}
+	return fadviseDontNeed(p.f, 0, 0)
+}
+
+func (p *preadAccessor) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	return p.f.Close()
+}
+
 type indexEntries struct {
 	Type    byte
 	entries []IndexEntry
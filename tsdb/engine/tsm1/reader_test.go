@@ -341,6 +341,94 @@ func TestTSMReader_MMAP_Tombstone(t *testing.T) {
 	}
 }
 
+func TestTSMReader_DeletePrefix(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	f := mustTempFile(dir)
+	defer f.Close()
+
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+
+	keys := []string{
+		"cpu#!~#value",
+		"cpu,host=A#!~#value",
+		"cpu,host=B#!~#value",
+		"cpu2,host=A#!~#value",
+		"mem,host=A#!~#value",
+	}
+	for _, k := range keys {
+		if err := w.Write([]byte(k), []Value{NewValue(1, 1.0)}); err != nil {
+			t.Fatalf("unexpected error writing: %v", err)
+		}
+	}
+
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error writing index: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	f, err = os.Open(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error open file: %v", err)
+	}
+
+	r, err := NewTSMReader(f)
+	if err != nil {
+		t.Fatalf("unexpected error created reader: %v", err)
+	}
+
+	prefixes := [][]byte{[]byte("cpu,"), []byte("cpu#!~#"), []byte("disk,")}
+	if err := r.DeletePrefix(prefixes); err != nil {
+		t.Fatalf("unexpected error deleting: %v", err)
+	}
+
+	exp := []string{"cpu2,host=A#!~#value", "mem,host=A#!~#value"}
+	if got := r.KeyCount(); got != len(exp) {
+		t.Fatalf("key count mismatch: got %v, exp %v", got, len(exp))
+	}
+	for i, k := range exp {
+		if got, _ := r.KeyAt(i); string(got) != k {
+			t.Fatalf("key mismatch: got %s, exp %s", got, k)
+		}
+	}
+
+	// Only the prefixes matching keys of the file are recorded.
+	entries, err := r.tombstoner.ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error reading tombstones: %v", err)
+	} else if len(entries) != 2 || !entries[0].Prefix || !entries[1].Prefix {
+		t.Fatalf("unexpected tombstones: %v", entries)
+	}
+
+	// The tombstones are applied when the file is reopened.
+	if err := r.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	f, err = os.Open(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error open file: %v", err)
+	}
+	r, err = NewTSMReader(f)
+	if err != nil {
+		t.Fatalf("unexpected error created reader: %v", err)
+	}
+	defer r.Close()
+
+	if got := r.KeyCount(); got != len(exp) {
+		t.Fatalf("key count mismatch: got %v, exp %v", got, len(exp))
+	} else if r.ContainsPrefix([]byte("cpu,")) {
+		t.Fatal("expected cpu keys to be deleted")
+	} else if !r.ContainsPrefix([]byte("cpu2,")) {
+		t.Fatal("expected cpu2 keys to exist")
+	}
+}
+
 func TestTSMReader_MMAP_TombstoneRange(t *testing.T) {
 	dir := mustTempDir()
 	defer os.RemoveAll(dir)
@@ -1056,6 +1144,259 @@ func TestTSMReader_MMAP_Stats(t *testing.T) {
 }
 
 // Ensure that we return an error if we try to open a non-tsm file
+func TestTSMReader_Pread_ReadAll(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	f := mustTempFile(dir)
+	defer f.Close()
+
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+
+	var data = map[string][]Value{
+		"bool":   []Value{NewValue(1, true), NewValue(2, false)},
+		"float":  []Value{NewValue(1, 1.0), NewValue(2, 2.0)},
+		"int":    []Value{NewValue(1, int64(1)), NewValue(2, int64(2))},
+		"string": []Value{NewValue(1, "foo"), NewValue(2, "bar")},
+		"uint":   []Value{NewValue(1, ^uint64(0)), NewValue(2, uint64(2))},
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := w.Write([]byte(k), data[k]); err != nil {
+			t.Fatalf("unexpected error writing: %v", err)
+		}
+	}
+
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error writing index: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	for _, option := range []tsmReaderOption{WithPread(true), WithDropPageCache(true)} {
+		f, err = os.Open(f.Name())
+		if err != nil {
+			t.Fatalf("unexpected error open file: %v", err)
+		}
+
+		r, err := NewTSMReader(f, option)
+		if err != nil {
+			t.Fatalf("unexpected error created reader: %v", err)
+		}
+
+		if _, ok := r.accessor.(*preadAccessor); !ok {
+			t.Fatalf("unexpected accessor: %T", r.accessor)
+		}
+
+		for k, vals := range data {
+			readValues, err := r.ReadAll([]byte(k))
+			if err != nil {
+				t.Fatalf("unexpected error readin: %v", err)
+			}
+
+			if exp := len(vals); exp != len(readValues) {
+				t.Fatalf("read values length mismatch: got %v, exp %v", len(readValues), exp)
+			}
+
+			for i, v := range vals {
+				if v.Value() != readValues[i].Value() {
+					t.Fatalf("read value mismatch(%d): got %v, exp %v", i, readValues[i].Value(), v.Value())
+				}
+			}
+
+			entry := &r.index.Entries([]byte(k))[0]
+			if _, b, err := r.ReadBytes(entry, nil); err != nil {
+				t.Fatalf("unexpected error reading bytes: %v", err)
+			} else if got, exp := len(b), int(entry.Size)-4; got != exp {
+				t.Fatalf("read bytes length mismatch: got %v, exp %v", got, exp)
+			}
+		}
+
+		var buf []FloatValue
+		entry := &r.index.Entries([]byte("float"))[0]
+		if values, err := r.ReadFloatBlockAt(entry, &buf); err != nil {
+			t.Fatalf("unexpected error reading block: %v", err)
+		} else if got, exp := len(values), 2; got != exp {
+			t.Fatalf("read values length mismatch: got %v, exp %v", got, exp)
+		}
+
+		if err := r.Close(); err != nil {
+			t.Fatalf("unexpected error closing: %v", err)
+		}
+	}
+}
+
+func TestTSMReader_Pread_TombstoneRange(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	f := mustTempFile(dir)
+	defer f.Close()
+
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+
+	expValues := []Value{
+		NewValue(1, 1.0),
+		NewValue(2, 2.0),
+		NewValue(3, 3.0),
+	}
+	if err := w.Write([]byte("cpu"), expValues); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error writing index: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	f, err = os.Open(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error open file: %v", err)
+	}
+
+	r, err := NewTSMReader(f, WithPread(true))
+	if err != nil {
+		t.Fatalf("unexpected error created reader: %v", err)
+	}
+	defer r.Close()
+
+	if err := r.DeleteRange([][]byte{[]byte("cpu")}, 2, math.MaxInt64); err != nil {
+		t.Fatalf("unexpected error deleting: %v", err)
+	}
+
+	values, err := r.ReadAll([]byte("cpu"))
+	if err != nil {
+		t.Fatalf("unexpected error reading all: %v", err)
+	}
+
+	if got, exp := len(values), 1; got != exp {
+		t.Fatalf("values length mismatch: got %v, exp %v", got, exp)
+	} else if got, exp := values[0].String(), expValues[0].String(); got != exp {
+		t.Fatalf("value mismatch: got %v, exp %v", got, exp)
+	}
+}
+
+func TestTSMReader_Pread_Rename(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	f := mustTempFile(dir)
+
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+
+	if err := w.Write([]byte("cpu"), []Value{NewValue(1, 1.0)}); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error writing index: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	f, err = os.Open(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error open file: %v", err)
+	}
+
+	r, err := NewTSMReader(f, WithPread(true))
+	if err != nil {
+		t.Fatalf("unexpected error created reader: %v", err)
+	}
+	defer r.Close()
+
+	path := filepath.Join(dir, "renamed.tsm")
+	if err := r.Rename(path); err != nil {
+		t.Fatalf("unexpected error renaming: %v", err)
+	}
+
+	if got, exp := r.Path(), path; got != exp {
+		t.Fatalf("path mismatch: got %v, exp %v", got, exp)
+	}
+
+	if values, err := r.ReadAll([]byte("cpu")); err != nil {
+		t.Fatalf("unexpected error reading all: %v", err)
+	} else if got, exp := len(values), 1; got != exp {
+		t.Fatalf("values length mismatch: got %v, exp %v", got, exp)
+	}
+}
+
+func TestTSMReader_MmapCutoff(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+	f := mustTempFile(dir)
+	defer f.Close()
+
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+
+	if err := w.Write([]byte("cpu"), []Value{NewValue(10, 1.0), NewValue(20, 2.0)}); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error writing index: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	for _, tt := range []struct {
+		cutoff int64
+		pread  bool
+	}{
+		{cutoff: 10, pread: false},
+		{cutoff: 20, pread: false},
+		{cutoff: 21, pread: true},
+	} {
+		f, err = os.Open(f.Name())
+		if err != nil {
+			t.Fatalf("unexpected error open file: %v", err)
+		}
+
+		r, err := NewTSMReader(f, withMmapCutoff(tt.cutoff))
+		if err != nil {
+			t.Fatalf("unexpected error created reader: %v", err)
+		}
+
+		if _, ok := r.accessor.(*preadAccessor); ok != tt.pread {
+			t.Fatalf("cutoff %d: unexpected accessor: %T", tt.cutoff, r.accessor)
+		}
+
+		if values, err := r.ReadAll([]byte("cpu")); err != nil {
+			t.Fatalf("unexpected error reading all: %v", err)
+		} else if got, exp := len(values), 2; got != exp {
+			t.Fatalf("values length mismatch: got %v, exp %v", got, exp)
+		}
+
+		if err := r.Close(); err != nil {
+			t.Fatalf("unexpected error closing: %v", err)
+		}
+	}
+}
+
 func TestTSMReader_VerifiesFileType(t *testing.T) {
 	dir := mustTempDir()
 	defer os.RemoveAll(dir)
@@ -0,0 +1,177 @@
+package tsm1
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/influxdata/influxdb/pkg/limiter"
+)
+
+// Scrub re-reads the blocks of the TSM files of the store from disk and checks
+// them against their checksums, waiting for throttle before each read.
+// Corrupt blocks are logged and counted in the statistics of the store.  If
+// quarantineDir is set, files with corrupt blocks are moved to it and removed
+// from the store.  Offloaded files are skipped.  Scrubbing stops when done is
+// closed.  It returns the number of corrupt files found.
+func (f *FileStore) Scrub(throttle *limiter.IOSubsystem, quarantineDir string, done <-chan struct{}) (int, error) {
+	var n int
+	for _, file := range f.Files() {
+		r, ok := file.(*TSMReader)
+		if !ok || r.Remote() {
+			continue
+		}
+
+		select {
+		case <-done:
+			return n, nil
+		default:
+		}
+
+		// Files compacted while others were scrubbed are skipped.
+		if !f.ref(r) {
+			continue
+		}
+		corrupt, err := f.scrubFile(r, throttle, done)
+		r.Unref()
+		if err != nil {
+			return n, err
+		} else if corrupt == 0 {
+			continue
+		}
+
+		n++
+		atomic.AddInt64(&f.stats.ScrubCorruptBlocks, int64(corrupt))
+		f.logger.Info(fmt.Sprintf("WARN: scrubbing found %d corrupt blocks in %s", corrupt, r.Path()))
+
+		if quarantineDir == "" {
+			continue
+		}
+		if err := f.quarantine(r, quarantineDir); err != nil {
+			return n, fmt.Errorf("error quarantining %s: %v", r.Path(), err)
+		}
+		atomic.AddInt64(&f.stats.ScrubQuarantinedFiles, 1)
+		f.logger.Info(fmt.Sprintf("WARN: quarantined %s to %s", r.Path(), quarantineDir))
+	}
+	return n, nil
+}
+
+// ref records a usage of r, if r is still a file of the store.
+func (f *FileStore) ref(r *TSMReader) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, file := range f.files {
+		if file == r {
+			r.Ref()
+			return true
+		}
+	}
+	return false
+}
+
+// scrubFile checks the blocks of the file of r against their checksums, and
+// returns the number of corrupt blocks.  The blocks are read from the file
+// rather than through r, so that they are checked as they are stored.
+func (f *FileStore) scrubFile(r *TSMReader, throttle *limiter.IOSubsystem, done <-chan struct{}) (int, error) {
+	fd, err := os.Open(r.Path())
+	if os.IsNotExist(err) {
+		// The file was compacted.
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	defer fd.Close()
+
+	// Scrubbing shouldn't evict the blocks of recent queries from the page cache.
+	defer fadviseDontNeed(fd, 0, 0)
+
+	var corrupt int
+	var buf []byte
+	var entries []IndexEntry
+	for i := 0; i < r.KeyCount(); i++ {
+		select {
+		case <-done:
+			return corrupt, nil
+		default:
+		}
+
+		var key []byte
+		key, _, entries = r.Key(i, &entries)
+		for j := range entries {
+			e := &entries[j]
+			if cap(buf) < int(e.Size) {
+				buf = make([]byte, e.Size)
+			}
+			buf = buf[:e.Size]
+
+			throttle.Wait(len(buf))
+			_, err := fd.ReadAt(buf, e.Offset)
+			if err != nil && err != io.EOF {
+				return corrupt, err
+			}
+			atomic.AddInt64(&f.stats.ScrubBlocks, 1)
+			atomic.AddInt64(&f.stats.ScrubBytes, int64(len(buf)))
+
+			if err == io.EOF || len(buf) < crc32.Size {
+				corrupt++
+				f.logger.Info(fmt.Sprintf("WARN: block of key %q at offset %d of %s is truncated", key, e.Offset, r.Path()))
+			} else if checksum := binary.BigEndian.Uint32(buf[:crc32.Size]); checksum != crc32.ChecksumIEEE(buf[crc32.Size:]) {
+				corrupt++
+				f.logger.Info(fmt.Sprintf("WARN: block of key %q at offset %d of %s does not match its checksum", key, e.Offset, r.Path()))
+			}
+		}
+	}
+	return corrupt, nil
+}
+
+// quarantine moves the file of r, and its tombstones, to dir and removes the
+// file from the store.
+func (f *FileStore) quarantine(r *TSMReader, dir string) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+
+	paths := []string{r.Path()}
+	for _, t := range r.TombstoneFiles() {
+		paths = append(paths, t.Path)
+	}
+
+	// The files are copied before they are removed, as the reader may still
+	// be in use, and dir may be on another file system.
+	for _, path := range paths {
+		dst := filepath.Join(dir, filepath.Base(path))
+		if err := os.Link(path, dst); err == nil {
+			continue
+		}
+		if err := copyFile(path, dst); err != nil {
+			return err
+		}
+	}
+	return f.Replace([]string{r.Path()}, nil)
+}
+
+// copyFile copies the file at src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	} else if err := out.Sync(); err != nil {
+		return err
+	}
+	return out.Close()
+}
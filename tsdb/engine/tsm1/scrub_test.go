@@ -0,0 +1,94 @@
+package tsm1
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFileStore_Scrub(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	shardDir := filepath.Join(dir, "shard")
+	if err := os.MkdirAll(shardDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for i, key := range []string{"cpu", "mem"} {
+		path := filepath.Join(shardDir, fmt.Sprintf("%09d-%09d.tsm", i+1, 1))
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewTSMWriter(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write([]byte(key), []Value{NewValue(0, 1.0), NewValue(1, 2.0)}); err != nil {
+			t.Fatal(err)
+		} else if err := w.WriteIndex(); err != nil {
+			t.Fatal(err)
+		} else if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	// Flip a bit in the block of the second file, after its header and checksum.
+	f, err := os.OpenFile(paths[1], os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b [1]byte
+	if _, err := f.ReadAt(b[:], 5+4+2); err != nil {
+		t.Fatal(err)
+	}
+	b[0] ^= 1
+	if _, err := f.WriteAt(b[:], 5+4+2); err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewFileStore(shardDir)
+	if err := fs.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	// Without a quarantine directory, the corrupt file is kept.
+	if n, err := fs.Scrub(nil, "", nil); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("unexpected corrupt files: %d", n)
+	} else if got := fs.Count(); got != 2 {
+		t.Fatalf("unexpected file count: %d", got)
+	}
+
+	quarantineDir := filepath.Join(dir, "quarantine")
+	if n, err := fs.Scrub(nil, quarantineDir, nil); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("unexpected corrupt files: %d", n)
+	}
+
+	if got := fs.Count(); got != 1 {
+		t.Fatalf("unexpected file count: %d", got)
+	} else if _, err := os.Stat(paths[1]); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be removed: %v", paths[1], err)
+	} else if _, err := os.Stat(filepath.Join(quarantineDir, filepath.Base(paths[1]))); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := atomic.LoadInt64(&fs.stats.ScrubBlocks), int64(4); got != exp {
+		t.Fatalf("unexpected scrubbed blocks: got %d, exp %d", got, exp)
+	} else if got, exp := atomic.LoadInt64(&fs.stats.ScrubCorruptBlocks), int64(2); got != exp {
+		t.Fatalf("unexpected corrupt blocks: got %d, exp %d", got, exp)
+	} else if got, exp := atomic.LoadInt64(&fs.stats.ScrubQuarantinedFiles), int64(1); got != exp {
+		t.Fatalf("unexpected quarantined files: got %d, exp %d", got, exp)
+	}
+}
@@ -0,0 +1,371 @@
+package tsm1
+
+// Engines configured to snapshot on shutdown write their cache, and the keys
+// of their TSM files, to a shutdown snapshot in the WAL directory when they
+// are closed.  When the engine is opened again, the cache is loaded from the
+// snapshot rather than by replaying the WAL, and the index from its keys
+// rather than from the index of every TSM file, as long as the WAL segments
+// and TSM files are still those the snapshot was written with.  The snapshot
+// is removed once it is read, as the WAL is written to again, so the WAL
+// remains the source of the cache after a crash.
+//
+// A shutdown snapshot starts with a header:
+//
+// ┌────────────────────────────────────┐
+// │               Header               │
+// ├─────────┬─────────┬────────────────┤
+// │  Magic  │ Version │ Encrypted Flag │
+// │ 4 bytes │ 1 byte  │     1 byte     │
+// └─────────┴─────────┴────────────────┘
+//
+// followed by the names and sizes of the non-empty WAL segments and of the
+// TSM and tombstone files of the engine, the keys of the TSM files and their
+// types, the cache entries encoded as WAL write entries, and a CRC32 of all
+// of it.  Series keys are not encrypted, but cache entries are when the
+// engine encrypts its WAL.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// ShutdownSnapshotFileName is the name of the shutdown snapshot in the
+	// WAL directory of an engine.
+	ShutdownSnapshotFileName = "shutdown.snapshot"
+
+	shutdownSnapshotMagic   = 0x16D1D1A5
+	shutdownSnapshotVersion = 1
+)
+
+// errShutdownSnapshotCorrupt is returned when reading a truncated or modified
+// shutdown snapshot.
+var errShutdownSnapshotCorrupt = errors.New("shutdown snapshot corrupt")
+
+// snapshotFile is the name and size of a file a shutdown snapshot was written
+// with.
+type snapshotFile struct {
+	name string
+	size int64
+}
+
+// walManifest returns the names and sizes of the non-empty WAL segments in dir.
+// Empty segments are skipped, as the WAL removes or creates them when opened.
+func walManifest(dir string) ([]snapshotFile, error) {
+	names, err := segmentFileNames(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []snapshotFile
+	for _, name := range names {
+		stat, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		} else if stat.Size() == 0 {
+			continue
+		}
+		files = append(files, snapshotFile{name: filepath.Base(name), size: stat.Size()})
+	}
+	return files, nil
+}
+
+// fileStoreManifest returns the names and sizes of the TSM files and
+// tombstone files of a file store.
+func fileStoreManifest(tsmFiles []TSMFile) []snapshotFile {
+	var files []snapshotFile
+	for _, f := range tsmFiles {
+		files = append(files, snapshotFile{name: filepath.Base(f.Path()), size: int64(f.Size())})
+		for _, t := range f.TombstoneFiles() {
+			files = append(files, snapshotFile{name: filepath.Base(t.Path), size: int64(t.Size)})
+		}
+	}
+	return files
+}
+
+// equalManifests returns true if a and b hold the same files of the same sizes.
+func equalManifests(a, b []snapshotFile) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeShutdownSnapshot writes the cache of the engine, and the keys of its
+// TSM files, to the shutdown snapshot.  The WAL must be closed.
+func (e *Engine) writeShutdownSnapshot() error {
+	now := time.Now()
+
+	walFiles, err := walManifest(e.WAL.Path())
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(e.WAL.Path(), ShutdownSnapshotFileName)
+	tmpPath := path + "." + CompactionTempExtension
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	defer os.Remove(tmpPath)
+
+	h := crc32.NewIEEE()
+	w := bufio.NewWriter(io.MultiWriter(f, h))
+	cipher := e.WAL.cipher
+
+	var header [6]byte
+	binary.BigEndian.PutUint32(header[0:4], shutdownSnapshotMagic)
+	header[4] = shutdownSnapshotVersion
+	if cipher != nil {
+		header[5] = 1
+	}
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+
+	for _, files := range [][]snapshotFile{walFiles, fileStoreManifest(e.FileStore.Files())} {
+		if err := writeUint32(w, uint32(len(files))); err != nil {
+			return err
+		}
+		for _, sf := range files {
+			if err := writeBytes(w, []byte(sf.name)); err != nil {
+				return err
+			} else if err := writeUint64(w, uint64(sf.size)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Keys in several files are walked once for each file, one after the other.
+	var prev []byte
+	if err := e.FileStore.WalkKeys(func(key []byte, typ byte) error {
+		if bytes.Equal(key, prev) {
+			return nil
+		}
+		prev = append(prev[:0], key...)
+
+		if err := writeBytes(w, key); err != nil {
+			return err
+		}
+		return w.WriteByte(typ)
+	}); err != nil {
+		return err
+	} else if err := writeBytes(w, nil); err != nil {
+		return err
+	}
+
+	for _, key := range e.Cache.Keys() {
+		entry := &WriteWALEntry{Values: map[string][]Value{string(key): e.Cache.Values(key)}}
+		b, err := entry.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if cipher != nil {
+			if b, err = cipher.Encrypt(nil, b); err != nil {
+				return err
+			}
+		}
+		if err := writeBytes(w, b); err != nil {
+			return err
+		}
+	}
+	if err := writeBytes(w, nil); err != nil {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	} else if err := writeUint32(f, h.Sum32()); err != nil {
+		return err
+	} else if err := f.Sync(); err != nil {
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := renameFile(tmpPath, path); err != nil {
+		return err
+	}
+
+	e.traceLogger.Info(fmt.Sprintf("Wrote shutdown snapshot %s in %v", path, time.Since(now)))
+	return syncDir(e.WAL.Path())
+}
+
+// loadShutdownSnapshot loads the cache from the shutdown snapshot, and keeps
+// the keys of the TSM files for LoadMetadataIndex, if the WAL segments and TSM
+// files are those the snapshot was written with.  It returns false if there
+// is no snapshot or it is out of date.  The snapshot is removed.
+func (e *Engine) loadShutdownSnapshot() (bool, error) {
+	path := filepath.Join(e.WAL.Path(), ShutdownSnapshotFileName)
+	defer os.Remove(path)
+	os.Remove(path + "." + CompactionTempExtension)
+
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if len(buf) < 10 {
+		return false, errShutdownSnapshotCorrupt
+	}
+	n := len(buf) - 4
+	if crc32.ChecksumIEEE(buf[:n]) != binary.BigEndian.Uint32(buf[n:]) {
+		return false, errShutdownSnapshotCorrupt
+	}
+
+	d := &snapshotDecoder{b: buf[:n]}
+	if d.uint32() != shutdownSnapshotMagic {
+		return false, errShutdownSnapshotCorrupt
+	} else if version := d.uint8(); version != shutdownSnapshotVersion {
+		return false, fmt.Errorf("unsupported shutdown snapshot version %d", version)
+	}
+	encrypted := d.uint8() == 1
+
+	walFiles, err := walManifest(e.WAL.Path())
+	if err != nil {
+		return false, err
+	}
+	for _, files := range [][]snapshotFile{walFiles, fileStoreManifest(e.FileStore.Files())} {
+		snapshotFiles := make([]snapshotFile, d.uint32())
+		for i := range snapshotFiles {
+			snapshotFiles[i] = snapshotFile{name: string(d.bytes()), size: int64(d.uint64())}
+		}
+		if d.err != nil {
+			return false, d.err
+		} else if !equalManifests(files, snapshotFiles) {
+			e.logger.Info(fmt.Sprintf("shutdown snapshot %s is out of date", path))
+			return false, nil
+		}
+	}
+
+	var keys []seriesKey
+	for {
+		key := d.bytes()
+		if len(key) == 0 {
+			break
+		}
+		keys = append(keys, seriesKey{key: key, typ: d.uint8()})
+	}
+	if d.err != nil {
+		return false, d.err
+	}
+
+	limit := e.Cache.MaxSize()
+	defer e.Cache.SetMaxSize(limit)
+	e.Cache.SetMaxSize(0)
+
+	for {
+		b := d.bytes()
+		if d.err != nil {
+			return false, d.err
+		} else if len(b) == 0 {
+			break
+		}
+
+		if encrypted {
+			c := currentCipher()
+			if c == nil {
+				return false, ErrEncryptionDisabled
+			} else if b, err = c.Decrypt(nil, b); err != nil {
+				return false, err
+			}
+		}
+
+		entry := &WriteWALEntry{Values: make(map[string][]Value, 1)}
+		if err := entry.UnmarshalBinary(b); err != nil {
+			return false, err
+		}
+		for k, v := range entry.Values {
+			if err := e.Cache.Write([]byte(k), v); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	e.snapshotKeys = keys
+	return true, nil
+}
+
+// writeUint32 writes v to w.
+func writeUint32(w io.Writer, v uint32) error {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	_, err := w.Write(b[:])
+	return err
+}
+
+// writeUint64 writes v to w.
+func writeUint64(w io.Writer, v uint64) error {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	_, err := w.Write(b[:])
+	return err
+}
+
+// writeBytes writes b to w, prefixed by its length.
+func writeBytes(w io.Writer, b []byte) error {
+	if err := writeUint32(w, uint32(len(b))); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// snapshotDecoder reads the fields of a shutdown snapshot.  Once a field is
+// truncated, err is set and all further fields are zero.
+type snapshotDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *snapshotDecoder) next(n int) []byte {
+	if d.err != nil || len(d.b) < n {
+		d.err = errShutdownSnapshotCorrupt
+		return nil
+	}
+	b := d.b[:n:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *snapshotDecoder) uint8() byte {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *snapshotDecoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *snapshotDecoder) uint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *snapshotDecoder) bytes() []byte {
+	return d.next(int(d.uint32()))
+}
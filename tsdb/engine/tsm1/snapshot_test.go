@@ -0,0 +1,102 @@
+package tsm1
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/index/inmem"
+)
+
+func TestEngine_ShutdownSnapshot(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	walPath := filepath.Join(dir, "wal")
+	snapshotPath := filepath.Join(walPath, ShutdownSnapshotFileName)
+
+	// openEngine opens the engine and loads its index.
+	openEngine := func() *Engine {
+		opt := tsdb.NewEngineOptions()
+		opt.Config.CacheSnapshotOnShutdown = true
+		opt.InmemIndex = inmem.NewIndex("db0")
+		idx := tsdb.MustOpenIndex(1, "db0", filepath.Join(dir, "index"), opt)
+
+		e := NewEngine(1, idx, "db0", filepath.Join(dir, "data"), walPath, opt).(*Engine)
+		e.SetEnabled(false)
+		if err := e.Open(); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	e := openEngine()
+	points, err := models.ParsePointsString("cpu,host=A value=1 1000000000\ncpu,host=B value=2 2000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range points {
+		if err := e.CreateSeriesIfNotExists(p.Key(), p.Name(), p.Tags()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.WritePoints(points[:1]); err != nil {
+		t.Fatal(err)
+	} else if err := e.WriteSnapshot(); err != nil {
+		t.Fatal(err)
+	} else if err := e.WritePoints(points[1:]); err != nil {
+		t.Fatal(err)
+	} else if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(snapshotPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// The cache and keys are loaded from the snapshot, which is removed.
+	e = openEngine()
+	if got, exp := len(e.snapshotKeys), 1; got != exp {
+		t.Fatalf("unexpected snapshot keys: got %d, exp %d", got, exp)
+	} else if got := e.Cache.Values([]byte("cpu,host=B#!~#value")); len(got) != 1 || got[0].Value() != 2.0 {
+		t.Fatalf("unexpected cache values: %v", got)
+	} else if _, err := os.Stat(snapshotPath); !os.IsNotExist(err) {
+		t.Fatalf("expected snapshot to be removed: %v", err)
+	}
+
+	if err := e.LoadMetadataIndex(1, e.index); err != nil {
+		t.Fatal(err)
+	} else if e.snapshotKeys != nil {
+		t.Fatal("expected snapshot keys to be released")
+	}
+	if keys, err := e.MeasurementSeriesKeysByExpr([]byte("cpu"), nil); err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 2 {
+		t.Fatalf("unexpected series keys: %q", keys)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A snapshot older than the WAL is not used.
+	w := NewWAL(walPath)
+	if err := w.Open(); err != nil {
+		t.Fatal(err)
+	} else if _, err := w.WriteMulti(map[string][]Value{"cpu,host=C#!~#value": {NewValue(3000000000, 3.0)}}); err != nil {
+		t.Fatal(err)
+	} else if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	e = openEngine()
+	defer e.Close()
+	if e.snapshotKeys != nil {
+		t.Fatal("expected out of date snapshot not to be loaded")
+	} else if got := len(e.Cache.Keys()); got != 2 {
+		t.Fatalf("unexpected cache keys: %d", got)
+	} else if _, err := os.Stat(snapshotPath); !os.IsNotExist(err) {
+		t.Fatalf("expected snapshot to be removed: %v", err)
+	}
+}
@@ -19,6 +19,10 @@ const (
 
 	// stringCompressedSnappy is a compressed encoding using Snappy compression
 	stringCompressedSnappy = 1
+
+	// stringCompressedZstd is a compressed encoding using zstd compression,
+	// written by compactions.
+	stringCompressedZstd = 2
 )
 
 // StringEncoder encodes multiple strings into a byte slice.
@@ -72,12 +76,15 @@ type StringDecoder struct {
 // SetBytes initializes the decoder with bytes to read from.
 // This must be called before calling any other method.
 func (e *StringDecoder) SetBytes(b []byte) error {
-	// First byte stores the encoding type, only have snappy format
-	// currently so ignore for now.
+	// First byte stores the encoding type.
 	var data []byte
 	if len(b) > 0 {
 		var err error
-		data, err = snappy.Decode(nil, b[1:])
+		if b[0]>>4 == stringCompressedZstd {
+			data, err = decompressZstd(b[1:])
+		} else {
+			data, err = snappy.Decode(nil, b[1:])
+		}
 		if err != nil {
 			return fmt.Errorf("failed to decode string block: %v", err.Error())
 		}
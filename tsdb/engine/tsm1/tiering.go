@@ -0,0 +1,197 @@
+package tsm1
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/influxdata/influxdb/tsdb/tiering"
+)
+
+// ErrTieringDisabled is returned when offloading files without a tiering
+// store.
+var ErrTieringDisabled = errors.New("tiering is not enabled")
+
+// stubExtension is appended to the path of a TSM file to name its stub
+// while it is written. Stubs left by an interrupted offload are removed at
+// startup with the other temp files.
+const stubExtension = ".stub." + CompactionTempExtension
+
+// Offload uploads the TSM files that are not yet offloaded to the tiering
+// store under prefix, and replaces each by a stub holding only its index.
+// Blocks are then read from the tiering store. Files in use by queries are
+// skipped and offloaded by a later call. It returns the number of files
+// offloaded.
+func (f *FileStore) Offload(prefix string) (int, error) {
+	if f.tiering == nil {
+		return 0, ErrTieringDisabled
+	}
+
+	var n int
+	for _, file := range f.Files() {
+		r, ok := file.(*TSMReader)
+		if !ok || r.Remote() {
+			continue
+		}
+
+		ok, err := f.offload(r, path.Join(prefix, filepath.Base(r.Path())))
+		if err != nil {
+			return n, err
+		} else if ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// offload uploads the file of r under key and replaces it by its stub. It
+// returns false if the file was not replaced because it was compacted or
+// is in use.
+func (f *FileStore) offload(r *TSMReader, key string) (bool, error) {
+	tsmPath := r.Path()
+	stubPath := tsmPath + stubExtension
+
+	ref, err := f.tiering.Upload(key, tsmPath)
+	if err != nil {
+		return false, err
+	}
+
+	// The ref is written before the stub replaces the file, so a stub always
+	// has a ref. A file left with a ref by an interrupted offload is still
+	// read locally, as it is as large as the offloaded file.
+	if err := writeStub(tsmPath, stubPath); err != nil {
+		return false, err
+	}
+	defer os.Remove(stubPath)
+
+	if err := tiering.WriteRef(refPath(tsmPath), ref); err != nil {
+		return false, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	idx := -1
+	for i, file := range f.files {
+		if file == r {
+			idx = i
+			break
+		}
+	}
+
+	if idx == -1 || r.InUse() {
+		if err := os.Remove(refPath(tsmPath)); err != nil {
+			return false, err
+		}
+
+		// The object of a file in use is overwritten when it is offloaded
+		// again, but the file of a compacted one is gone.
+		if idx == -1 {
+			return false, f.tiering.Delete(key)
+		}
+		return false, nil
+	}
+
+	if err := renameFile(stubPath, tsmPath); err != nil {
+		return false, err
+	}
+
+	fd, err := os.Open(tsmPath)
+	if err != nil {
+		return false, err
+	}
+	stub, err := NewTSMReader(fd, f.readerOptions()...)
+	if err != nil {
+		fd.Close()
+		return false, fmt.Errorf("error opening stub %s: %v", tsmPath, err)
+	}
+
+	f.files[idx] = stub
+	f.lastFileStats = nil
+	if err := r.Close(); err != nil {
+		return false, err
+	}
+
+	f.logger.Info(fmt.Sprintf("offloaded %s to %s", tsmPath, key))
+	return true, syncDir(f.dir)
+}
+
+// download writes the offloaded file of the stub r to path.
+func (f *FileStore) download(r *TSMReader, path string) error {
+	fd, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	if err := f.tiering.Download(*r.ref, fd); err != nil {
+		return err
+	} else if err := fd.Sync(); err != nil {
+		return err
+	}
+	return fd.Close()
+}
+
+// writeStub writes a stub of the TSM file at path to stubPath. The stub holds
+// the header, index and footer of the file, so the index still refers to the
+// offsets of the blocks in the file.
+func writeStub(path, stubPath string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	var footer [8]byte
+	if stat.Size() < int64(5+len(footer)) {
+		return fmt.Errorf("file %s too small to offload", path)
+	} else if _, err := f.ReadAt(footer[:], stat.Size()-8); err != nil {
+		return err
+	}
+
+	indexStart := int64(binary.BigEndian.Uint64(footer[:]))
+	indexEnd := stat.Size() - 8
+	if indexStart < 5 || indexStart > indexEnd {
+		return fmt.Errorf("file %s has an invalid index offset", path)
+	}
+
+	stub, err := os.Create(stubPath)
+	if err != nil {
+		return err
+	}
+	defer stub.Close()
+
+	var header [5]byte
+	binary.BigEndian.PutUint32(header[0:4], MagicNumber)
+	header[4] = Version
+	if _, err := stub.Write(header[:]); err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(stub, io.NewSectionReader(f, indexStart, indexEnd-indexStart)); err != nil {
+		return err
+	}
+
+	// The index of the stub starts right after the header.
+	binary.BigEndian.PutUint64(footer[:], uint64(len(header)))
+	if _, err := stub.Write(footer[:]); err != nil {
+		return err
+	} else if err := stub.Sync(); err != nil {
+		return err
+	} else if err := stub.Close(); err != nil {
+		return err
+	}
+
+	// The stub keeps the modification time of the file, which the last
+	// modified time of the shard is restored from.
+	return os.Chtimes(stubPath, stat.ModTime(), stat.ModTime())
+}
@@ -0,0 +1,155 @@
+package tsm1
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influxdb/tsdb/tiering"
+)
+
+func TestFileStore_Offload(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	objects, err := tiering.NewObjectStore(filepath.Join(dir, "objects"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := tiering.NewStore(objects, filepath.Join(dir, "cache"), 1<<20)
+	store.ChunkSize = 512
+	if err := store.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write a file with enough blocks to span several chunks.
+	shardDir := filepath.Join(dir, "shard")
+	if err := os.MkdirAll(shardDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	tsmPath := filepath.Join(shardDir, "000000001-000000001.tsm")
+	f, err := os.Create(tsmPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []Value
+	for i := 0; i < 5000; i++ {
+		values = append(values, NewValue(int64(i), float64(i)*1.5))
+	}
+	for _, key := range []string{"cpu", "mem"} {
+		if err := w.Write([]byte(key), values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		t.Fatal(err)
+	} else if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(tsmPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewFileStore(shardDir)
+	fs.tiering = store
+	if err := fs.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	if n, err := fs.Offload("db/rp/1"); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("unexpected offloaded file count: %d", n)
+	}
+
+	// The file is replaced by a stub, which reports the size of the file.
+	if stub, err := os.Stat(tsmPath); err != nil {
+		t.Fatal(err)
+	} else if stub.Size() >= fi.Size() {
+		t.Fatalf("expected stub to be smaller than %d bytes: %d", fi.Size(), stub.Size())
+	} else if !stub.ModTime().Equal(fi.ModTime()) {
+		t.Fatalf("unexpected stub modification time: %v", stub.ModTime())
+	}
+	if ref, err := tiering.ReadRef(refPath(tsmPath)); err != nil {
+		t.Fatal(err)
+	} else if ref.Key != "db/rp/1/000000001-000000001.tsm" || ref.Size != fi.Size() {
+		t.Fatalf("unexpected ref: %+v", ref)
+	}
+	if got, exp := int64(fs.Stats()[0].Size), fi.Size(); got != exp {
+		t.Fatalf("unexpected file size: got %d, exp %d", got, exp)
+	}
+
+	// Files already offloaded are skipped.
+	if n, err := fs.Offload("db/rp/1"); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatalf("unexpected offloaded file count: %d", n)
+	}
+
+	mustReadAll := func(fs *FileStore) {
+		r := fs.Files()[0].(*TSMReader)
+		if !r.Remote() {
+			t.Fatal("expected remote file")
+		}
+		for _, key := range []string{"cpu", "mem"} {
+			got, err := r.ReadAll([]byte(key))
+			if err != nil {
+				t.Fatal(err)
+			} else if len(got) != len(values) {
+				t.Fatalf("unexpected value count for %s: %d", key, len(got))
+			}
+			for i := range values {
+				if got[i].UnixNano() != values[i].UnixNano() || got[i].Value() != values[i].Value() {
+					t.Fatalf("unexpected value %d for %s: %v", i, key, got[i])
+				}
+			}
+		}
+	}
+	mustReadAll(fs)
+
+	// Stubs are read from the tiering store once reopened.
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fs = NewFileStore(shardDir)
+	fs.tiering = store
+	if err := fs.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	mustReadAll(fs)
+
+	// Snapshots hold the offloaded file rather than its stub.
+	snapshot, err := fs.CreateSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.Stat(filepath.Join(snapshot, "000000001-000000001.tsm")); err != nil {
+		t.Fatal(err)
+	} else if got.Size() != fi.Size() {
+		t.Fatalf("unexpected snapshot file size: %d", got.Size())
+	}
+
+	// Stubs can't be read without tiering.
+	other := NewFileStore(shardDir)
+	if err := other.Open(); err == nil {
+		other.Close()
+		t.Fatal("expected error opening stub without tiering")
+	}
+
+	// Removing a stub deletes the offloaded file.
+	if err := fs.Replace([]string{tsmPath}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(refPath(tsmPath)); !os.IsNotExist(err) {
+		t.Fatalf("expected ref to be removed: %v", err)
+	} else if _, err := os.Stat(filepath.Join(dir, "objects", "db/rp/1/000000001-000000001.tsm")); !os.IsNotExist(err) {
+		t.Fatalf("expected offloaded file to be deleted: %v", err)
+	}
+}
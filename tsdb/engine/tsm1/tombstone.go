@@ -17,6 +17,7 @@ const (
 	headerSize = 4
 	v2header   = 0x1502
 	v3header   = 0x1503
+	v4header   = 0x1504
 )
 
 // Tombstoner records tombstones when entries are deleted.
@@ -42,6 +43,10 @@ type Tombstone struct {
 	// Min and Max are the min and max unix nanosecond time ranges of Key that are deleted.  If
 	// the full range is deleted, both values are -1.
 	Min, Max int64
+
+	// Prefix is set if every key starting with Key is deleted, such as the keys
+	// of a dropped measurement.
+	Prefix bool
 }
 
 // Add adds the all keys, across all timestamps, to the tombstone.
@@ -88,6 +93,42 @@ func (t *Tombstoner) AddRange(keys [][]byte, min, max int64) error {
 	return t.writeTombstone(tombstones)
 }
 
+// AddPrefix adds all keys starting with any of prefixes, across all timestamps,
+// to the tombstone.  A single entry is recorded for each prefix, however many
+// keys it deletes.
+func (t *Tombstoner) AddPrefix(prefixes [][]byte) error {
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// If this TSMFile has not been written (mainly in tests), don't write a
+	// tombstone because the keys will not be written when it's actually saved.
+	if t.Path == "" {
+		return nil
+	}
+
+	t.statsLoaded = false
+
+	tombstones, err := t.readTombstone()
+	if err != nil {
+		return nil
+	}
+
+	for _, p := range prefixes {
+		tombstones = append(tombstones, Tombstone{
+			Key:    p,
+			Min:    math.MinInt64,
+			Max:    math.MaxInt64,
+			Prefix: true,
+		})
+	}
+
+	return t.writeTombstone(tombstones)
+}
+
 // ReadAll returns all the tombstones in the Tombstoner's directory.
 func (t *Tombstoner) ReadAll() ([]Tombstone, error) {
 	return t.readTombstone()
@@ -167,7 +208,9 @@ func (t *Tombstoner) Walk(fn func(t Tombstone) error) error {
 	}
 
 	header := binary.BigEndian.Uint32(b[:])
-	if header == v3header {
+	if header == v4header {
+		return t.readTombstoneV4(f, fn)
+	} else if header == v3header {
 		return t.readTombstoneV3(f, fn)
 	} else if header == v2header {
 		return t.readTombstoneV2(f, fn)
@@ -186,7 +229,17 @@ func (t *Tombstoner) writeTombstone(tombstones []Tombstone) error {
 
 	bw := bufio.NewWriterSize(tmp, 1024*1024)
 
-	binary.BigEndian.PutUint32(b[:4], v3header)
+	// Prefix tombstones need the v4 format, which records the kind of each
+	// tombstone.  Files without them are kept readable by earlier versions.
+	header := uint32(v3header)
+	for _, t := range tombstones {
+		if t.Prefix {
+			header = v4header
+			break
+		}
+	}
+
+	binary.BigEndian.PutUint32(b[:4], header)
 	if _, err := bw.Write(b[:4]); err != nil {
 		return err
 	}
@@ -194,6 +247,16 @@ func (t *Tombstoner) writeTombstone(tombstones []Tombstone) error {
 	gz := gzip.NewWriter(bw)
 
 	for _, t := range tombstones {
+		if header == v4header {
+			var kind [1]byte
+			if t.Prefix {
+				kind[0] = 1
+			}
+			if _, err := gz.Write(kind[:]); err != nil {
+				return err
+			}
+		}
+
 		binary.BigEndian.PutUint32(b[:4], uint32(len(t.Key)))
 		if _, err := gz.Write(b[:4]); err != nil {
 			return err
@@ -338,6 +401,18 @@ func (t *Tombstoner) readTombstoneV2(f *os.File, fn func(t Tombstone) error) err
 // of storing keys and the range of time for the key that points were deleted. This
 // format is a binary and compressed with gzip.
 func (t *Tombstoner) readTombstoneV3(f *os.File, fn func(t Tombstone) error) error {
+	return t.readTombstoneGzip(f, false, fn)
+}
+
+// readTombstoneV4 reads the fourth version of tombstone files, which are the
+// third version with the kind of each tombstone, a key or a prefix of keys,
+// recorded in a byte before it.
+func (t *Tombstoner) readTombstoneV4(f *os.File, fn func(t Tombstone) error) error {
+	return t.readTombstoneGzip(f, true, fn)
+}
+
+// readTombstoneGzip reads the gzip compressed tombstone entries of v3 and v4 files.
+func (t *Tombstoner) readTombstoneGzip(f *os.File, kinds bool, fn func(t Tombstone) error) error {
 	// Skip header, already checked earlier
 	if _, err := f.Seek(headerSize, io.SeekStart); err != nil {
 		return err
@@ -356,6 +431,16 @@ func (t *Tombstoner) readTombstoneV3(f *os.File, fn func(t Tombstone) error) err
 
 	b := make([]byte, 4096)
 	for {
+		var prefix bool
+		if kinds {
+			if _, err = io.ReadFull(gr, b[:1]); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			prefix = b[0] == 1
+		}
+
 		if _, err = io.ReadFull(gr, b[:4]); err == io.EOF || err == io.ErrUnexpectedEOF {
 			return nil
 		} else if err != nil {
@@ -388,9 +473,10 @@ func (t *Tombstoner) readTombstoneV3(f *os.File, fn func(t Tombstone) error) err
 		max = int64(binary.BigEndian.Uint64(b[:8]))
 
 		if err := fn(Tombstone{
-			Key: key,
-			Min: min,
-			Max: max,
+			Key:    key,
+			Min:    min,
+			Max:    max,
+			Prefix: prefix,
 		}); err != nil {
 			return err
 		}
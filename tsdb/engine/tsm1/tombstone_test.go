@@ -157,6 +157,36 @@ func TestTombstoner_Delete(t *testing.T) {
 	}
 }
 
+func TestTombstoner_AddPrefix(t *testing.T) {
+	dir := MustTempDir()
+	defer func() { os.RemoveAll(dir) }()
+
+	f := MustTempFile(dir)
+	ts := &tsm1.Tombstoner{Path: f.Name()}
+
+	ts.Add([][]byte{[]byte("foo")})
+	ts.AddPrefix([][]byte{[]byte("cpu,")})
+
+	// Use a new Tombstoner to verify values are persisted
+	ts = &tsm1.Tombstoner{Path: f.Name()}
+	entries, err := ts.ReadAll()
+	if err != nil {
+		fatal(t, "ReadAll", err)
+	}
+
+	if got, exp := len(entries), 2; got != exp {
+		t.Fatalf("length mismatch: got %v, exp %v", got, exp)
+	}
+
+	if got, exp := string(entries[0].Key), "foo"; got != exp || entries[0].Prefix {
+		t.Fatalf("value mismatch: got %s (prefix %v), exp %s", got, entries[0].Prefix, exp)
+	}
+
+	if got, exp := string(entries[1].Key), "cpu,"; got != exp || !entries[1].Prefix {
+		t.Fatalf("value mismatch: got %s (prefix %v), exp %s", got, entries[1].Prefix, exp)
+	}
+}
+
 func TestTombstoner_ReadV1(t *testing.T) {
 	dir := MustTempDir()
 	defer func() { os.RemoveAll(dir) }()
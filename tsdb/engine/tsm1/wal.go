@@ -19,8 +19,10 @@ import (
 
 	"github.com/golang/snappy"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/encryption"
 	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/pkg/pool"
+	"github.com/influxdata/influxdb/tsdb"
 	"github.com/uber-go/zap"
 )
 
@@ -34,6 +36,10 @@ const (
 	// WALFilePrefix is the prefix on all wal segment files.
 	WALFilePrefix = "_"
 
+	// WALArchiveDirName is the name of the directory, relative to the WAL
+	// directory, where closed segments are retained when archiving is enabled.
+	WALArchiveDirName = "archive"
+
 	// walEncodeBufSize is the size of the wal entry encoding buffer
 	walEncodeBufSize = 4 * 1024 * 1024
 
@@ -103,6 +109,26 @@ type WAL struct {
 	// is opened if a non-default value is required.
 	syncDelay time.Duration
 
+	// syncMode is when writes are fsynced, one of the tsdb.WALSync* modes.  An
+	// empty mode fsyncs every write.  This must be set before the WAL is opened.
+	syncMode string
+
+	// unsynced is set when writes that did not wait for an fsync have not been
+	// fsynced since.
+	unsynced bool
+
+	// archive causes removed segments to be moved into the archive directory
+	// rather than deleted.  This must be set before the WAL is opened.
+	archive bool
+
+	// io counts writes against the IO budget shared with background IO.
+	// This must be set before the WAL is opened.
+	io *limiter.IOSubsystem
+
+	// cipher, if set, encrypts the entries written.  This must be set
+	// before the WAL is opened.
+	cipher *encryption.Cipher
+
 	// WALOutput is the writer used by the logger.
 	logger       zap.Logger // Logger to be used for important messages
 	traceLogger  zap.Logger // Logger to be used when trace-logging is on.
@@ -196,6 +222,22 @@ func (l *WAL) Open() error {
 		return err
 	}
 
+	// Segment IDs must never be reused while archived copies of earlier
+	// segments exist, otherwise a follower could skip or overwrite them.
+	if l.archive {
+		archived, err := ArchivedSegments(l.path)
+		if err != nil {
+			return err
+		}
+		if len(archived) > 0 {
+			id, err := idFromFileName(archived[len(archived)-1])
+			if err != nil {
+				return err
+			}
+			l.currentSegmentID = id
+		}
+	}
+
 	if len(segments) > 0 {
 		lastSegment := segments[len(segments)-1]
 		id, err := idFromFileName(lastSegment)
@@ -203,7 +245,9 @@ func (l *WAL) Open() error {
 			return err
 		}
 
-		l.currentSegmentID = id
+		if id > l.currentSegmentID {
+			l.currentSegmentID = id
+		}
 		stat, err := os.Stat(lastSegment)
 		if err != nil {
 			return err
@@ -234,9 +278,36 @@ func (l *WAL) Open() error {
 
 	l.closing = make(chan struct{})
 
+	if l.syncMode == tsdb.WALSyncInterval {
+		go l.syncEvery(l.syncDelay, l.closing)
+	}
+
 	return nil
 }
 
+// syncEvery fsyncs the current wal segment every interval if it has been written
+// to, until closing is closed.
+func (l *WAL) syncEvery(interval time.Duration, closing <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			l.mu.Lock()
+			if l.unsynced && l.currentSegmentWriter != nil {
+				if err := l.currentSegmentWriter.sync(); err != nil {
+					l.logger.Info(fmt.Sprintf("error syncing WAL: %v", err))
+				}
+				l.unsynced = false
+			}
+			l.mu.Unlock()
+		case <-closing:
+			return
+		}
+	}
+}
+
 // scheduleSync will schedule an fsync to the current wal segment and notify any
 // waiting gorutines.  If an fsync is already scheduled, subsequent calls will
 // not schedule a new fsync and will be handle by the existing scheduled fsync.
@@ -287,6 +358,7 @@ func (l *WAL) scheduleSync() {
 // a write lock on the WAL is obtained before calling sync.
 func (l *WAL) sync() {
 	err := l.currentSegmentWriter.sync()
+	l.unsynced = false
 	for len(l.syncWaiters) > 0 {
 		errC := <-l.syncWaiters
 		errC <- err
@@ -344,10 +416,25 @@ func (l *WAL) ClosedSegments() ([]string, error) {
 }
 
 // Remove deletes the given segment file paths from disk and cleans up any associated objects.
+// If archiving is enabled, the segments are moved into the archive directory instead.
 func (l *WAL) Remove(files []string) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+
+	if l.archive {
+		if err := os.MkdirAll(filepath.Join(l.path, WALArchiveDirName), 0777); err != nil {
+			return err
+		}
+	}
+
 	for _, fn := range files {
+		if l.archive {
+			l.traceLogger.Info(fmt.Sprintf("Archiving %s", fn))
+			if err := os.Rename(fn, filepath.Join(l.path, WALArchiveDirName, filepath.Base(fn))); err != nil {
+				return err
+			}
+			continue
+		}
 		l.traceLogger.Info(fmt.Sprintf("Removing %s", fn))
 		os.RemoveAll(fn)
 	}
@@ -400,7 +487,21 @@ func (l *WAL) writeToLog(entry WALEntry) (int, error) {
 	compressed := snappy.Encode(encBuf, b)
 	bytesPool.Put(bytes)
 
-	syncErr := make(chan error)
+	typ := entry.Type()
+	if l.cipher != nil {
+		compressed, err = l.cipher.Encrypt(nil, compressed)
+		if err != nil {
+			bytesPool.Put(encBuf)
+			return -1, err
+		}
+		typ |= walEntryEncrypted
+	}
+
+	// Writes only wait for an fsync when every write is fsynced.
+	var syncErr chan error
+	if l.syncMode == "" || l.syncMode == tsdb.WALSyncEveryWrite {
+		syncErr = make(chan error)
+	}
 
 	segID, err := func() (int, error) {
 		l.mu.Lock()
@@ -419,16 +520,26 @@ func (l *WAL) writeToLog(entry WALEntry) (int, error) {
 		}
 
 		// write and sync
-		if err := l.currentSegmentWriter.Write(entry.Type(), compressed); err != nil {
+		l.io.Wait(len(compressed))
+		if err := l.currentSegmentWriter.Write(typ, compressed); err != nil {
 			return -1, fmt.Errorf("error writing WAL entry: %v", err)
 		}
 
-		select {
-		case l.syncWaiters <- syncErr:
-		default:
-			return -1, fmt.Errorf("error syncing wal")
+		if syncErr != nil {
+			select {
+			case l.syncWaiters <- syncErr:
+			default:
+				return -1, fmt.Errorf("error syncing wal")
+			}
+			l.scheduleSync()
+		} else {
+			// Hand the write to the OS, which flushes it to disk in its own time
+			// unless it is fsynced first.
+			if err := l.currentSegmentWriter.Flush(); err != nil {
+				return -1, fmt.Errorf("error writing WAL entry: %v", err)
+			}
+			l.unsynced = true
 		}
-		l.scheduleSync()
 
 		// Update stats for current segment size
 		atomic.StoreInt64(&l.stats.CurrentBytes, int64(l.currentSegmentWriter.size))
@@ -441,7 +552,7 @@ func (l *WAL) writeToLog(entry WALEntry) (int, error) {
 
 	bytesPool.Put(encBuf)
 
-	if err != nil {
+	if err != nil || syncErr == nil {
 		return segID, err
 	}
 
@@ -544,6 +655,40 @@ func segmentFileNames(dir string) ([]string, error) {
 	return names, nil
 }
 
+// ArchivedSegments returns the archived segment files for the WAL in dir,
+// sorted by ascending ID.
+func ArchivedSegments(dir string) ([]string, error) {
+	return segmentFileNames(filepath.Join(dir, WALArchiveDirName))
+}
+
+// RemoveArchivedSegments removes all archived segments in the WAL directory
+// dir with an ID less than or equal to id.
+func RemoveArchivedSegments(dir string, id int) error {
+	files, err := ArchivedSegments(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range files {
+		fid, err := idFromFileName(fn)
+		if err != nil {
+			return err
+		} else if fid > id {
+			break
+		}
+
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// SegmentID returns the ID of the WAL segment file at path.
+func SegmentID(path string) (int, error) {
+	return idFromFileName(path)
+}
+
 // newSegmentFile will close the current segment file and open a new one, updating bookkeeping info on the log.
 func (l *WAL) newSegmentFile() error {
 	l.currentSegmentID++
@@ -1140,7 +1285,13 @@ func (r *WALSegmentReader) Next() bool {
 	}
 	nReadOK += n
 
-	decLen, err := snappy.DecodedLen(b[:length])
+	typ, compressed, err := decryptWALEntry(WalEntryType(entryType), b[:length])
+	if err != nil {
+		r.err = err
+		return true
+	}
+
+	decLen, err := snappy.DecodedLen(compressed)
 	if err != nil {
 		r.err = err
 		return true
@@ -1148,14 +1299,14 @@ func (r *WALSegmentReader) Next() bool {
 	decBuf := *(getBuf(decLen))
 	defer putBuf(&decBuf)
 
-	data, err := snappy.Decode(decBuf, b[:length])
+	data, err := snappy.Decode(decBuf, compressed)
 	if err != nil {
 		r.err = err
 		return true
 	}
 
 	// and marshal it and send it to the cache
-	switch WalEntryType(entryType) {
+	switch typ {
 	case WriteWALEntryType:
 		r.entry = &WriteWALEntry{
 			Values: make(map[string][]Value),
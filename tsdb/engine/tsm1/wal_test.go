@@ -3,7 +3,9 @@ package tsm1_test
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/golang/snappy"
@@ -546,6 +548,38 @@ func TestWAL_Delete(t *testing.T) {
 	}
 }
 
+func TestWAL_RemoveArchivedSegments(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, tsm1.WALArchiveDirName), 0777); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		fn := filepath.Join(dir, tsm1.WALArchiveDirName, fmt.Sprintf("%s%05d.%s", tsm1.WALFilePrefix, i, tsm1.WALFileExtension))
+		if err := ioutil.WriteFile(fn, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tsm1.RemoveArchivedSegments(dir, 2); err != nil {
+		t.Fatalf("error removing archived segments: %v", err)
+	}
+
+	files, err := tsm1.ArchivedSegments(dir)
+	if err != nil {
+		t.Fatalf("error getting archived segments: %v", err)
+	}
+	if got, exp := len(files), 1; got != exp {
+		t.Fatalf("archived segment length mismatch: got %v, exp %v", got, exp)
+	}
+	if id, err := tsm1.SegmentID(files[0]); err != nil {
+		t.Fatal(err)
+	} else if id != 3 {
+		t.Fatalf("archived segment id mismatch: got %v, exp %v", id, 3)
+	}
+}
+
 func TestWALWriter_Corrupt(t *testing.T) {
 	dir := MustTempDir()
 	defer os.RemoveAll(dir)
@@ -0,0 +1,513 @@
+package tsm1
+
+// Compactions of shards of databases configured for zstd compression
+// recompress the values of float and string blocks with zstd.  Float values
+// keep their gorilla encoding, which zstd compresses further, while string
+// values are compressed in place of snappy.  Both use a dictionary trained
+// on the data of the shard when the shard is first compacted.
+//
+// The values of a zstd block are prefixed with a 1 byte header, as for the
+// other encodings, followed by the 4 byte ID of the dictionary, or 0 if the
+// block was compressed without one, and a zstd frame.  Dictionaries are
+// stored beside the TSM files of the shard and named after their ID, so
+// that they are loaded by any reader of the files.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// ZstdDictExtension is the extension of the zstd dictionaries of a shard.
+	ZstdDictExtension = "zdict"
+
+	// zstdDictSize is the maximum size of a dictionary.
+	zstdDictSize = 64 * 1024
+
+	// zstdDictMinSize is the minimum amount of data a dictionary is trained
+	// on. Smaller shards are compressed without a dictionary until they grow.
+	zstdDictMinSize = 4 * 1024
+
+	// zstdDictSampleSize is the maximum amount of data sampled from a block
+	// to train a dictionary.
+	zstdDictSampleSize = 1024
+)
+
+// zstdDicts holds the decoders of the dictionaries of the directories with
+// open readers, and the decoder of blocks compressed without a dictionary
+// under ID 0.  The dictionaries of a directory are loaded once, by its first
+// reader, and their decoders are closed with its last reader.
+var zstdDicts = struct {
+	mu       sync.RWMutex
+	decoders map[uint32]*zstdDictDecoder
+	dirs     map[string]*zstdDictDir
+}{
+	decoders: make(map[uint32]*zstdDictDecoder),
+	dirs:     make(map[string]*zstdDictDir),
+}
+
+// zstdDictDecoder is the decoder of a dictionary, and the number of
+// directories holding the dictionary.
+type zstdDictDecoder struct {
+	dec  *zstd.Decoder
+	refs int
+}
+
+// zstdDictDir is the IDs of the dictionaries of a directory, and the number
+// of open readers of its files.
+type zstdDictDir struct {
+	ids  []uint32
+	refs int
+}
+
+// zstdDecoder returns the decoder for the dictionary with the given ID.
+func zstdDecoder(id uint32) (*zstd.Decoder, error) {
+	zstdDicts.mu.RLock()
+	d := zstdDicts.decoders[id]
+	zstdDicts.mu.RUnlock()
+	if d != nil {
+		return d.dec, nil
+	} else if id != 0 {
+		return nil, fmt.Errorf("zstd dictionary %08x not found", id)
+	}
+
+	zstdDicts.mu.Lock()
+	defer zstdDicts.mu.Unlock()
+	if d := zstdDicts.decoders[0]; d != nil {
+		return d.dec, nil
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	zstdDicts.decoders[0] = &zstdDictDecoder{dec: dec, refs: 1}
+	return dec, nil
+}
+
+// acquireZstdDicts makes the dictionaries in dir available to decoders,
+// loading them if no reader of dir holds them yet.  They are held until
+// released as many times.
+func acquireZstdDicts(dir string) error {
+	zstdDicts.mu.Lock()
+	defer zstdDicts.mu.Unlock()
+
+	if d := zstdDicts.dirs[dir]; d != nil {
+		d.refs++
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*."+ZstdDictExtension))
+	if err != nil {
+		return err
+	}
+
+	d := &zstdDictDir{refs: 1}
+	for _, path := range paths {
+		id, err := parseZstdDictID(path)
+		if err == nil {
+			err = addZstdDict(d, id, func() ([]byte, error) { return ioutil.ReadFile(path) })
+		}
+		if err != nil {
+			for _, id := range d.ids {
+				releaseZstdDict(id)
+			}
+			return fmt.Errorf("error loading zstd dictionary %s: %v", path, err)
+		}
+	}
+	zstdDicts.dirs[dir] = d
+	return nil
+}
+
+// releaseZstdDicts releases the dictionaries of dir, closing their decoders
+// once they are held by no directory.
+func releaseZstdDicts(dir string) {
+	zstdDicts.mu.Lock()
+	defer zstdDicts.mu.Unlock()
+
+	d := zstdDicts.dirs[dir]
+	if d == nil {
+		return
+	} else if d.refs--; d.refs > 0 {
+		return
+	}
+
+	for _, id := range d.ids {
+		releaseZstdDict(id)
+	}
+	delete(zstdDicts.dirs, dir)
+}
+
+// loadZstdDict makes a dictionary written to dir available to decoders, if
+// the dictionaries of dir were already loaded.
+func loadZstdDict(dir string, id uint32, dict []byte) error {
+	zstdDicts.mu.Lock()
+	defer zstdDicts.mu.Unlock()
+
+	d := zstdDicts.dirs[filepath.Clean(dir)]
+	if d == nil {
+		return nil
+	}
+	for _, other := range d.ids {
+		if other == id {
+			return nil
+		}
+	}
+	return addZstdDict(d, id, func() ([]byte, error) { return dict, nil })
+}
+
+// addZstdDict adds the dictionary with the given ID to d, creating its
+// decoder if no other directory holds it.  zstdDicts.mu must be held.
+func addZstdDict(d *zstdDictDir, id uint32, read func() ([]byte, error)) error {
+	if dd := zstdDicts.decoders[id]; dd != nil {
+		dd.refs++
+		d.ids = append(d.ids, id)
+		return nil
+	}
+
+	dict, err := read()
+	if err != nil {
+		return err
+	}
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderDictRaw(id, dict))
+	if err != nil {
+		return err
+	}
+	zstdDicts.decoders[id] = &zstdDictDecoder{dec: dec, refs: 1}
+	d.ids = append(d.ids, id)
+	return nil
+}
+
+// releaseZstdDict releases the dictionary with the given ID, closing its
+// decoder once no directory holds it.  zstdDicts.mu must be held.
+func releaseZstdDict(id uint32) {
+	dd := zstdDicts.decoders[id]
+	if dd == nil {
+		return
+	} else if dd.refs--; dd.refs > 0 {
+		return
+	}
+	dd.dec.Close()
+	delete(zstdDicts.decoders, id)
+}
+
+// decompressZstd decompresses the values of a zstd block, following their
+// header byte.
+func decompressZstd(b []byte) ([]byte, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("zstd block too short")
+	}
+
+	dec, err := zstdDecoder(binary.BigEndian.Uint32(b[:4]))
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeAll(b[4:], nil)
+}
+
+// zstdDictPath returns the path of the dictionary with the given ID in dir.
+func zstdDictPath(dir string, id uint32) string {
+	return filepath.Join(dir, fmt.Sprintf("%08x.%s", id, ZstdDictExtension))
+}
+
+// parseZstdDictID returns the ID of the dictionary at path.
+func parseZstdDictID(path string) (uint32, error) {
+	name := strings.TrimSuffix(filepath.Base(path), "."+ZstdDictExtension)
+	id, err := strconv.ParseUint(name, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("zstd dictionary %s is named incorrectly", path)
+	}
+	return uint32(id), nil
+}
+
+// zstdCodec recompresses the float and string blocks written by the
+// compactions of a shard.
+type zstdCodec struct {
+	mu  sync.Mutex
+	dir string
+
+	// id is the ID of the dictionary of the shard, or 0 if it has none yet.
+	id  uint32
+	enc *zstd.Encoder
+}
+
+// newZstdCodec returns a codec for the shard in dir.
+func newZstdCodec(dir string) *zstdCodec {
+	return &zstdCodec{dir: dir}
+}
+
+// Open loads the most recently trained dictionary of the shard, if any.
+func (z *zstdCodec) Open() error {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(z.dir, "*."+ZstdDictExtension))
+	if err != nil {
+		return err
+	}
+
+	var newest string
+	var modTime int64
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		} else if newest == "" || fi.ModTime().UnixNano() > modTime {
+			newest, modTime = path, fi.ModTime().UnixNano()
+		}
+	}
+	if newest == "" {
+		return nil
+	}
+
+	id, err := parseZstdDictID(newest)
+	if err != nil {
+		return err
+	}
+	dict, err := ioutil.ReadFile(newest)
+	if err != nil {
+		return err
+	}
+	return z.setDict(id, dict)
+}
+
+// setDict sets the dictionary new blocks are compressed with.
+func (z *zstdCodec) setDict(id uint32, dict []byte) error {
+	enc, err := zstd.NewWriter(nil,
+		zstd.WithEncoderConcurrency(1),
+		zstd.WithEncoderCRC(false),
+		zstd.WithEncoderDictRaw(id, dict),
+	)
+	if err != nil {
+		return err
+	}
+
+	if z.enc != nil {
+		z.enc.Close()
+	}
+	z.id, z.enc = id, enc
+	return nil
+}
+
+// encoder returns the encoder blocks are compressed with and the ID of its
+// dictionary. If the shard has no dictionary yet, one is trained on the
+// blocks of readers and saved to the shard.
+func (z *zstdCodec) encoder(readers []*TSMReader) (*zstd.Encoder, uint32, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	if z.id != 0 {
+		return z.enc, z.id, nil
+	}
+
+	dict, err := trainZstdDict(readers)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if dict == nil {
+		// Too little data to train a dictionary on.
+		if z.enc == nil {
+			enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithEncoderCRC(false))
+			if err != nil {
+				return nil, 0, err
+			}
+			z.enc = enc
+		}
+		return z.enc, 0, nil
+	}
+
+	id := crc32.ChecksumIEEE(dict)
+	if id == 0 {
+		id = 1
+	}
+	if err := writeZstdDict(zstdDictPath(z.dir, id), dict); err != nil {
+		return nil, 0, err
+	} else if err := loadZstdDict(z.dir, id, dict); err != nil {
+		return nil, 0, err
+	}
+	if err := z.setDict(id, dict); err != nil {
+		return nil, 0, err
+	}
+	return z.enc, z.id, nil
+}
+
+// iterator returns a key iterator compressing the blocks of itr, which
+// iterates the blocks of readers.
+func (z *zstdCodec) iterator(itr KeyIterator, readers []*TSMReader) (KeyIterator, error) {
+	enc, id, err := z.encoder(readers)
+	if err != nil {
+		return nil, err
+	}
+	return &zstdKeyIterator{KeyIterator: itr, enc: enc, id: id}, nil
+}
+
+// writeZstdDict writes a dictionary to path once complete.
+func writeZstdDict(path string, dict []byte) error {
+	tmp := path + "." + CompactionTempExtension
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	if _, err := f.Write(dict); err != nil {
+		return err
+	} else if err := f.Sync(); err != nil {
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	} else if err := renameFile(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// trainZstdDict returns a dictionary of samples of the values of the float
+// and string blocks of readers, or nil if there is too little data.
+func trainZstdDict(readers []*TSMReader) ([]byte, error) {
+	var samples [][]byte
+	var size int
+	for _, r := range readers {
+		for i, n := 0, r.KeyCount(); i < n && size < zstdDictSize; i++ {
+			key, typ := r.KeyAt(i)
+			if typ != BlockFloat64 && typ != BlockString {
+				continue
+			}
+
+			entries := r.ReadEntries(key, nil)
+			if len(entries) == 0 {
+				continue
+			}
+
+			// Sample the last block of each key, which is the most likely to
+			// resemble data compacted later.
+			_, block, err := r.ReadBytes(&entries[len(entries)-1], nil)
+			if err != nil {
+				return nil, err
+			}
+			if block, err = DecryptBlock(block); err != nil {
+				return nil, err
+			}
+			_, values, err := unpackBlock(block[1:])
+			if err != nil {
+				return nil, err
+			}
+			raw, err := zstdRawValues(typ, values)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(raw) > zstdDictSampleSize {
+				raw = raw[:zstdDictSampleSize]
+			}
+			samples = append(samples, raw)
+			size += len(raw)
+		}
+	}
+
+	if size < zstdDictMinSize {
+		return nil, nil
+	}
+
+	// The end of a raw dictionary is the closest to the data compressed, so
+	// the samples of the most keys are kept last.
+	sort.SliceStable(samples, func(i, j int) bool { return len(samples[i]) > len(samples[j]) })
+	dict := make([]byte, 0, size)
+	for _, s := range samples {
+		dict = append(dict, s...)
+	}
+	if len(dict) > zstdDictSize {
+		dict = dict[len(dict)-zstdDictSize:]
+	}
+	return dict, nil
+}
+
+// zstdRawValues returns the values of a float or string block in the form
+// zstd compresses: the gorilla encoding of floats, and the uncompressed
+// strings.
+func zstdRawValues(typ byte, values []byte) ([]byte, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	switch enc := values[0] >> 4; {
+	case (typ == BlockFloat64 && enc == floatCompressedZstd) || (typ == BlockString && enc == stringCompressedZstd):
+		return decompressZstd(values[1:])
+	case typ == BlockFloat64:
+		return values[1:], nil
+	default:
+		return snappy.Decode(nil, values[1:])
+	}
+}
+
+// compressZstdBlock returns block with its values compressed with enc, if
+// it is a float or string block and compression makes it smaller.
+func compressZstdBlock(block []byte, enc *zstd.Encoder, id uint32) ([]byte, error) {
+	if len(block) == 0 {
+		return block, nil
+	}
+
+	typ := block[0]
+	var header byte
+	switch typ {
+	case BlockFloat64:
+		header = floatCompressedZstd << 4
+	case BlockString:
+		header = stringCompressedZstd << 4
+	default:
+		return block, nil
+	}
+
+	ts, values, err := unpackBlock(block[1:])
+	if err != nil {
+		return nil, err
+	} else if len(values) == 0 || values[0] == header {
+		return block, nil
+	}
+
+	raw, err := zstdRawValues(typ, values)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 5, 5+len(raw))
+	b[0] = header
+	binary.BigEndian.PutUint32(b[1:5], id)
+	b = enc.EncodeAll(raw, b)
+	if len(b) >= len(values) {
+		return block, nil
+	}
+	return packBlock(nil, typ, ts, b), nil
+}
+
+// zstdKeyIterator compresses the blocks of a key iterator with zstd.
+type zstdKeyIterator struct {
+	KeyIterator
+	enc *zstd.Encoder
+	id  uint32
+}
+
+func (k *zstdKeyIterator) Read() ([]byte, int64, int64, []byte, error) {
+	key, minTime, maxTime, block, err := k.KeyIterator.Read()
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+
+	block, err = compressZstdBlock(block, k.enc, k.id)
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+	return key, minTime, maxTime, block, nil
+}
@@ -0,0 +1,226 @@
+package tsm1
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompressZstdBlock(t *testing.T) {
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer enc.Close()
+
+	var floats, strs, ints Values
+	for i := 0; i < 1000; i++ {
+		floats = append(floats, NewValue(int64(i), float64(i%10)*0.1))
+		strs = append(strs, NewValue(int64(i), fmt.Sprintf("status=ok region=us-west-%d", i%4)))
+		ints = append(ints, NewValue(int64(i), int64(i)))
+	}
+
+	for _, values := range []Values{floats, strs} {
+		block, err := values.Encode(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		compressed, err := compressZstdBlock(block, enc, 0)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(compressed) >= len(block) {
+			t.Fatalf("expected block to be compressed: %d >= %d bytes", len(compressed), len(block))
+		}
+
+		got, err := DecodeBlock(compressed, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(got) != len(values) {
+			t.Fatalf("unexpected value count: %d", len(got))
+		}
+		for i := range values {
+			if got[i].UnixNano() != values[i].UnixNano() || got[i].Value() != values[i].Value() {
+				t.Fatalf("unexpected value %d: got %v, exp %v", i, got[i], values[i])
+			}
+		}
+
+		// Blocks already compressed are kept as they are.
+		if again, err := compressZstdBlock(compressed, enc, 0); err != nil {
+			t.Fatal(err)
+		} else if string(again) != string(compressed) {
+			t.Fatal("expected compressed block to be unchanged")
+		}
+	}
+
+	// Other types are not compressed.
+	block, err := ints.Encode(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := compressZstdBlock(block, enc, 0); err != nil {
+		t.Fatal(err)
+	} else if string(got) != string(block) {
+		t.Fatal("expected integer block to be unchanged")
+	}
+}
+
+func TestCompactor_Zstd(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	writes := make(map[string][]Value)
+	for i := 0; i < 20; i++ {
+		var floats, strs []Value
+		for j := 0; j < 500; j++ {
+			floats = append(floats, NewValue(int64(j), float64(j%50)*1.25))
+			strs = append(strs, NewValue(int64(j), fmt.Sprintf("GET /api/v2/items/%d 200", j%100)))
+		}
+		writes[fmt.Sprintf("cpu,host=server%02d#!~#usage", i)] = floats
+		writes[fmt.Sprintf("http,host=server%02d#!~#request", i)] = strs
+	}
+
+	// Split the values across two files, so the compaction merges them.
+	var files []string
+	for gen, half := range [][2]int{{0, 250}, {250, 500}} {
+		path := filepath.Join(dir, fmt.Sprintf("%09d-%09d.%s", gen+1, 1, TSMFileExtension))
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewTSMWriter(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range sortedKeys(writes) {
+			if err := w.Write([]byte(key), writes[key][half[0]:half[1]]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.WriteIndex(); err != nil {
+			t.Fatal(err)
+		} else if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+
+	fs := NewFileStore(dir)
+	if err := fs.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	c := &Compactor{Dir: dir, FileStore: fs, Zstd: newZstdCodec(dir)}
+	if err := c.Zstd.Open(); err != nil {
+		t.Fatal(err)
+	}
+	c.Open()
+	defer c.Close()
+
+	newFiles, err := c.CompactFull(files)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(newFiles) != 1 {
+		t.Fatalf("unexpected file count: %d", len(newFiles))
+	}
+
+	// A dictionary is trained on the shard and stored beside its files.
+	dicts, err := filepath.Glob(filepath.Join(dir, "*."+ZstdDictExtension))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(dicts) != 1 {
+		t.Fatalf("unexpected dictionary count: %d", len(dicts))
+	} else if got, exp := filepath.Base(dicts[0]), fmt.Sprintf("%08x.%s", c.Zstd.id, ZstdDictExtension); got != exp {
+		t.Fatalf("unexpected dictionary: got %s, exp %s", got, exp)
+	}
+
+	f, err := os.Open(newFiles[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for key, values := range writes {
+		entries := r.ReadEntries([]byte(key), nil)
+		if len(entries) == 0 {
+			t.Fatalf("missing key %s", key)
+		}
+		_, block, err := r.ReadBytes(&entries[0], nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, v, err := unpackBlock(block[1:])
+		if err != nil {
+			t.Fatal(err)
+		} else if v[0]>>4 != floatCompressedZstd {
+			t.Fatalf("expected zstd block for %s: %x", key, v[0])
+		}
+
+		got, err := r.ReadAll([]byte(key))
+		if err != nil {
+			t.Fatal(err)
+		} else if len(got) != len(values) {
+			t.Fatalf("unexpected value count for %s: %d", key, len(got))
+		}
+		for i := range values {
+			if got[i].UnixNano() != values[i].UnixNano() || got[i].Value() != values[i].Value() {
+				t.Fatalf("unexpected value %d for %s: got %v, exp %v", i, key, got[i], values[i])
+			}
+		}
+	}
+}
+
+// Ensure the dictionaries of a directory are loaded by its first reader, and
+// their decoders closed with its last reader.
+func TestZstdDicts_Acquire(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	if err := writeZstdDict(zstdDictPath(dir, 0xfeed), []byte("GET /api/v2/items 200")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := acquireZstdDicts(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := zstdDecoder(0xfeed); err != nil {
+		t.Fatal(err)
+	}
+
+	// A dictionary written after the directory was loaded is loaded too.
+	if err := loadZstdDict(dir, 0xbeef, []byte("POST /api/v2/items 201")); err != nil {
+		t.Fatal(err)
+	} else if _, err := zstdDecoder(0xbeef); err != nil {
+		t.Fatal(err)
+	}
+
+	releaseZstdDicts(dir)
+	if _, err := zstdDecoder(0xfeed); err != nil {
+		t.Fatalf("expected dictionary to be held by a reader: %v", err)
+	}
+	releaseZstdDicts(dir)
+	for _, id := range []uint32{0xfeed, 0xbeef} {
+		if _, err := zstdDecoder(id); err == nil {
+			t.Fatalf("expected dictionary %08x to be released", id)
+		}
+	}
+}
+
+func sortedKeys(m map[string][]Value) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
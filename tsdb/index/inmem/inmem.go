@@ -835,7 +835,7 @@ func (idx *ShardIndex) CreateSeriesListIfNotExists(keys, names [][]byte, tagsSli
 	}
 
 	var reason string
-	var dropped int
+	var dropped, maxSeriesDropped, maxValuesDropped int
 	var droppedKeys map[string]struct{}
 
 	// Ensure that no tags go over the maximum cardinality.
@@ -858,6 +858,7 @@ func (idx *ShardIndex) CreateSeriesListIfNotExists(keys, names [][]byte, tagsSli
 				}
 
 				dropped++
+				maxValuesDropped++
 				reason = fmt.Sprintf("max-values-per-tag limit exceeded (%d/%d): measurement=%q tag=%q value=%q",
 					n, maxValuesPerTag, name, string(tag.Key), string(tag.Value))
 
@@ -881,7 +882,8 @@ func (idx *ShardIndex) CreateSeriesListIfNotExists(keys, names [][]byte, tagsSli
 	for i := range keys {
 		if err := idx.CreateSeriesIfNotExists(keys[i], names[i], tagsSlice[i]); err == errMaxSeriesPerDatabaseExceeded {
 			dropped++
-			reason = fmt.Sprintf("max-series-per-database limit exceeded: (%d)", idx.opt.Config.MaxSeriesPerDatabase)
+			maxSeriesDropped++
+			reason = fmt.Sprintf("max-series-per-database limit exceeded: (%d) series=%q", idx.opt.Config.MaxSeriesPerDatabase, keys[i])
 			if droppedKeys == nil {
 				droppedKeys = make(map[string]struct{})
 			}
@@ -895,9 +897,11 @@ func (idx *ShardIndex) CreateSeriesListIfNotExists(keys, names [][]byte, tagsSli
 	// Report partial writes back to shard.
 	if dropped > 0 {
 		return &tsdb.PartialWriteError{
-			Reason:      reason,
-			Dropped:     dropped,
-			DroppedKeys: droppedKeys,
+			Reason:           reason,
+			Dropped:          dropped,
+			DroppedKeys:      droppedKeys,
+			MaxSeriesDropped: maxSeriesDropped,
+			MaxValuesDropped: maxValuesDropped,
 		}
 	}
 
@@ -11,6 +11,7 @@ import (
 	"regexp"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -30,6 +31,13 @@ import (
 // for the purpose of determining certain monitoring statistics.
 const monitorStatInterval = 30 * time.Second
 
+// limitLogInterval is the minimum interval between the logs of the series
+// dropped by a shard for exceeding the cardinality limits.
+const limitLogInterval = time.Minute
+
+// limitLogSampleN is the number of dropped series keys included in each log.
+const limitLogSampleN = 5
+
 const (
 	statWriteReq           = "writeReq"
 	statWriteReqOK         = "writeReqOk"
@@ -38,6 +46,9 @@ const (
 	statFieldsCreate       = "fieldsCreate"
 	statWritePointsErr     = "writePointsErr"
 	statWritePointsDropped = "writePointsDropped"
+	statWriteMaxSeriesDrop = "writeMaxSeriesDropped"
+	statWriteMaxValuesDrop = "writeMaxValuesDropped"
+	statFieldTypeConflicts = "fieldTypeConflicts"
 	statWritePointsOK      = "writePointsOk"
 	statWriteBytes         = "writeBytes"
 	statDiskBytes          = "diskBytes"
@@ -99,6 +110,11 @@ type PartialWriteError struct {
 
 	// The set of series keys that were dropped. Can be nil.
 	DroppedKeys map[string]struct{}
+
+	// The number of series dropped for exceeding the max-series-per-database
+	// and max-values-per-tag limits.
+	MaxSeriesDropped int
+	MaxValuesDropped int
 }
 
 func (e PartialWriteError) Error() string {
@@ -133,6 +149,13 @@ type Shard struct {
 	baseLogger zap.Logger
 	logger     zap.Logger
 
+	// limitLog samples the logs of series dropped by the cardinality limits.
+	limitLog struct {
+		mu      sync.Mutex
+		last    time.Time
+		dropped int
+	}
+
 	EnableOnOpen bool
 }
 
@@ -215,6 +238,9 @@ type ShardStatistics struct {
 	FieldsCreated      int64
 	WritePointsErr     int64
 	WritePointsDropped int64
+	MaxSeriesDropped   int64
+	MaxValuesDropped   int64
+	FieldTypeConflicts int64
 	WritePointsOK      int64
 	BytesWritten       int64
 	DiskBytes          int64
@@ -245,6 +271,9 @@ func (s *Shard) Statistics(tags map[string]string) []models.Statistic {
 			statFieldsCreate:       atomic.LoadInt64(&s.stats.FieldsCreated),
 			statWritePointsErr:     atomic.LoadInt64(&s.stats.WritePointsErr),
 			statWritePointsDropped: atomic.LoadInt64(&s.stats.WritePointsDropped),
+			statWriteMaxSeriesDrop: atomic.LoadInt64(&s.stats.MaxSeriesDropped),
+			statWriteMaxValuesDrop: atomic.LoadInt64(&s.stats.MaxValuesDropped),
+			statFieldTypeConflicts: atomic.LoadInt64(&s.stats.FieldTypeConflicts),
 			statWritePointsOK:      atomic.LoadInt64(&s.stats.WritePointsOK),
 			statWriteBytes:         atomic.LoadInt64(&s.stats.BytesWritten),
 			statDiskBytes:          atomic.LoadInt64(&s.stats.DiskBytes),
@@ -259,6 +288,9 @@ func (s *Shard) Statistics(tags map[string]string) []models.Statistic {
 // Path returns the path set on the shard when it was created.
 func (s *Shard) Path() string { return s.path }
 
+// WALPath returns the WAL path set on the shard when it was created.
+func (s *Shard) WALPath() string { return s.walPath }
+
 // Open initializes and opens the shard's store.
 func (s *Shard) Open() error {
 	if err := func() error {
@@ -556,6 +588,9 @@ func (s *Shard) validateSeriesAndFields(points []models.Point) ([]models.Point,
 			dropped += err.Dropped
 			droppedKeys = err.DroppedKeys
 			atomic.AddInt64(&s.stats.WritePointsDropped, int64(err.Dropped))
+			atomic.AddInt64(&s.stats.MaxSeriesDropped, int64(err.MaxSeriesDropped))
+			atomic.AddInt64(&s.stats.MaxValuesDropped, int64(err.MaxValuesDropped))
+			s.logLimitDrops(err)
 		default:
 			return nil, nil, err
 		}
@@ -564,6 +599,8 @@ func (s *Shard) validateSeriesAndFields(points []models.Point) ([]models.Point,
 	// get the shard mutex for locally defined fields
 	n := 0
 
+	conflictMode := s.options.Config.FieldTypeConflictFor(s.database)
+
 	// mfCache is a local cache of MeasurementFields to reduce lock contention when validating
 	// field types.
 	mfCache := make(map[string]*MeasurementFields, 16)
@@ -604,6 +641,16 @@ func (s *Shard) validateSeriesAndFields(points []models.Point) ([]models.Point,
 			mf = engine.MeasurementFields(name).Clone()
 			mfCache[string(name)] = mf
 		}
+
+		// Coerce or rename fields whose types conflict, if configured to.  The
+		// fields are only decoded if a type conflicts.
+		if conflictMode != FieldTypeConflictReject && hasFieldTypeConflict(iter, mf) {
+			if resolved, ok := resolveFieldTypeConflicts(p, mf, conflictMode); ok {
+				atomic.AddInt64(&s.stats.FieldTypeConflicts, 1)
+				p, points[i] = resolved, resolved
+				iter = p.FieldIterator()
+			}
+		}
 		iter.Reset()
 
 		// validate field types and encode data
@@ -633,6 +680,9 @@ func (s *Shard) validateSeriesAndFields(points []models.Point) ([]models.Point,
 			if f := mf.FieldBytes(iter.FieldKey()); f != nil {
 				// Field present in shard metadata, make sure there is no type conflict.
 				if f.Type != fieldType {
+					if !skip {
+						atomic.AddInt64(&s.stats.FieldTypeConflicts, 1)
+					}
 					atomic.AddInt64(&s.stats.WritePointsDropped, 1)
 					dropped++
 					if reason == "" {
@@ -663,6 +713,133 @@ func (s *Shard) validateSeriesAndFields(points []models.Point) ([]models.Point,
 	return points, fieldsToCreate, err
 }
 
+// logLimitDrops logs the series dropped by a write for exceeding the cardinality
+// limits, so that the writer can be found.  Logs are sampled to one every
+// limitLogInterval, which reports the number of series dropped since the last log.
+func (s *Shard) logLimitDrops(err *PartialWriteError) {
+	if err.MaxSeriesDropped+err.MaxValuesDropped == 0 {
+		return
+	}
+
+	s.limitLog.mu.Lock()
+	s.limitLog.dropped += err.MaxSeriesDropped + err.MaxValuesDropped
+	now := time.Now()
+	if now.Sub(s.limitLog.last) < limitLogInterval {
+		s.limitLog.mu.Unlock()
+		return
+	}
+	dropped := s.limitLog.dropped
+	s.limitLog.last, s.limitLog.dropped = now, 0
+	s.limitLog.mu.Unlock()
+
+	keys := make([]string, 0, limitLogSampleN)
+	for key := range err.DroppedKeys {
+		if len(keys) == limitLogSampleN {
+			break
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	s.logger.Info(fmt.Sprintf("WARN: %d series dropped for exceeding limits since last report, db=%s: %s, series=%q",
+		dropped, s.database, err.Reason, keys))
+}
+
+// fieldTypeSuffixes are the suffixes of the names of fields renamed for
+// having a conflicting type.
+var fieldTypeSuffixes = map[influxql.DataType]string{
+	influxql.Float:    "_float",
+	influxql.Integer:  "_int",
+	influxql.Unsigned: "_uint",
+	influxql.Boolean:  "_bool",
+	influxql.String:   "_str",
+}
+
+// hasFieldTypeConflict returns true if the type of a field of iter conflicts
+// with the type of the field in mf.  iter is reset before returning.
+func hasFieldTypeConflict(iter models.FieldIterator, mf *MeasurementFields) bool {
+	defer iter.Reset()
+	for iter.Next() {
+		f := mf.FieldBytes(iter.FieldKey())
+		if f == nil {
+			continue
+		}
+
+		var typ influxql.DataType
+		switch iter.Type() {
+		case models.Float:
+			typ = influxql.Float
+		case models.Integer:
+			typ = influxql.Integer
+		case models.Unsigned:
+			typ = influxql.Unsigned
+		case models.Boolean:
+			typ = influxql.Boolean
+		case models.String:
+			typ = influxql.String
+		default:
+			continue
+		}
+		if f.Type != typ {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveFieldTypeConflicts returns p with the fields whose types conflict with
+// mf coerced to the type of the field, or renamed, as set by mode, and true.
+// Conflicts that can't be resolved are left for the point to be rejected. If
+// there are no conflicts, p is returned with false.
+func resolveFieldTypeConflicts(p models.Point, mf *MeasurementFields, mode string) (models.Point, bool) {
+	fields, err := p.Fields()
+	if err != nil {
+		return p, false
+	}
+
+	var changed bool
+	for k, v := range fields {
+		typ := influxql.InspectDataType(v)
+		f := mf.Field(k)
+		if f == nil || f.Type == typ {
+			continue
+		}
+
+		switch mode {
+		case FieldTypeConflictCoerce:
+			switch v := v.(type) {
+			case int64:
+				if f.Type == influxql.Float {
+					fields[k], changed = float64(v), true
+				}
+			case float64:
+				// Only floats that are whole numbers are cast to integers.
+				if f.Type == influxql.Integer && v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+					fields[k], changed = int64(v), true
+				}
+			}
+		case FieldTypeConflictRename:
+			name := k + fieldTypeSuffixes[typ]
+			if _, ok := fields[name]; ok {
+				continue
+			} else if rf := mf.Field(name); rf != nil && rf.Type != typ {
+				continue
+			}
+			delete(fields, k)
+			fields[name], changed = v, true
+		}
+	}
+	if !changed {
+		return p, false
+	}
+
+	resolved, err := models.NewPoint(string(p.Name()), p.Tags(), fields, p.Time())
+	if err != nil {
+		return p, false
+	}
+	return resolved, true
+}
+
 func (s *Shard) createFieldsAndMeasurements(fieldsToCreate []*FieldCreate) error {
 	if len(fieldsToCreate) == 0 {
 		return nil
@@ -718,6 +895,39 @@ func (s *Shard) SeriesN() int64 {
 	return engine.SeriesN()
 }
 
+// ShardStorage describes the storage used by a shard.
+type ShardStorage struct {
+	ID                uint64    `json:"id"`
+	Database          string    `json:"database"`
+	RetentionPolicy   string    `json:"retentionPolicy"`
+	DiskBytes         int64     `json:"diskBytes"`
+	TSMFiles          int       `json:"tsmFiles"`
+	SeriesN           int64     `json:"seriesN"`
+	LastModified      time.Time `json:"lastModified"`
+	CompactionBacklog int64     `json:"compactionBacklog"`
+}
+
+// Storage returns the storage used by the shard.  Like DiskSize, it is
+// reported for disabled shards.
+func (s *Shard) Storage() (ShardStorage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s._engine == nil {
+		return ShardStorage{}, ErrEngineClosed
+	}
+
+	return ShardStorage{
+		ID:                s.id,
+		Database:          s.database,
+		RetentionPolicy:   s.retentionPolicy,
+		DiskBytes:         s._engine.DiskSize(),
+		TSMFiles:          s._engine.FileCount(),
+		SeriesN:           s._engine.SeriesN(),
+		LastModified:      s._engine.LastModified(),
+		CompactionBacklog: s._engine.CompactionBacklog(),
+	}, nil
+}
+
 // SeriesSketches returns the series sketches for the shard.
 func (s *Shard) SeriesSketches() (estimator.Sketch, estimator.Sketch, error) {
 	engine, err := s.engine()
@@ -1065,6 +1275,30 @@ func (s *Shard) Backup(w io.Writer, basePath string, since time.Time) error {
 	return engine.Backup(w, basePath, since)
 }
 
+// Offload offloads the files of the shard to the tiering store. It returns
+// the number of files offloaded.
+func (s *Shard) Offload() (int, error) {
+	engine, err := s.engine()
+	if err != nil {
+		return 0, err
+	}
+	return engine.Offload(fmt.Sprintf("%s/%s/%d", s.database, s.retentionPolicy, s.id))
+}
+
+// Scrub checks the blocks of the shard's TSM files against their checksums.
+// Files with corrupt blocks are moved under quarantineDir, if it is set, in
+// the path of the shard.  It returns the number of corrupt files found.
+func (s *Shard) Scrub(throttle *limiter.IOSubsystem, quarantineDir string, done <-chan struct{}) (int, error) {
+	engine, err := s.engine()
+	if err != nil {
+		return 0, err
+	}
+	if quarantineDir != "" {
+		quarantineDir = filepath.Join(quarantineDir, s.database, s.retentionPolicy, strconv.FormatUint(s.id, 10))
+	}
+	return engine.Scrub(throttle, quarantineDir, done)
+}
+
 // Restore restores data to the underlying engine for the shard.
 // The shard is reopened after restore.
 func (s *Shard) Restore(r io.Reader, basePath string) error {
@@ -1120,6 +1354,16 @@ func (s *Shard) CreateSnapshot() (string, error) {
 	return engine.CreateSnapshot()
 }
 
+// WriteSnapshot writes the cache of the shard to a new TSM file and removes
+// the WAL segments it holds.
+func (s *Shard) WriteSnapshot() error {
+	engine, err := s.engine()
+	if err != nil {
+		return err
+	}
+	return engine.WriteSnapshot()
+}
+
 // ForEachMeasurementName iterates over each measurement in the shard.
 func (s *Shard) ForEachMeasurementName(fn func(name []byte) error) error {
 	engine, err := s.engine()
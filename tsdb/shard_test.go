@@ -140,10 +140,14 @@ func TestMaxSeriesLimit(t *testing.T) {
 	err = sh.WritePoints([]models.Point{pt})
 	if err == nil {
 		t.Fatal("expected error")
-	} else if exp, got := `partial write: max-series-per-database limit exceeded: (1000) dropped=1`, err.Error(); exp != got {
+	} else if exp, got := `partial write: max-series-per-database limit exceeded: (1000) series="cpu,host=server9999" dropped=1`, err.Error(); exp != got {
 		t.Fatalf("unexpected error message:\n\texp = %s\n\tgot = %s", exp, got)
 	}
 
+	if got := sh.Statistics(nil)[0].Values["writeMaxSeriesDropped"]; got != int64(1) {
+		t.Fatalf("unexpected writeMaxSeriesDropped: %v", got)
+	}
+
 	sh.Close()
 }
 
@@ -197,6 +201,10 @@ func TestShard_MaxTagValuesLimit(t *testing.T) {
 		t.Fatalf("unexpected error message:\n\texp = %s\n\tgot = %s", exp, got)
 	}
 
+	if got := sh.Statistics(nil)[0].Values["writeMaxValuesDropped"]; got != int64(1) {
+		t.Fatalf("unexpected writeMaxValuesDropped: %v", got)
+	}
+
 	sh.Close()
 }
 
@@ -326,6 +334,81 @@ func TestShardWriteAddNewField(t *testing.T) {
 	}
 }
 
+func TestShard_WritePoints_FieldTypeConflict(t *testing.T) {
+	for _, tt := range []struct {
+		mode   string
+		fields map[string]influxql.DataType
+		err    bool
+	}{
+		{
+			mode:   tsdb.FieldTypeConflictReject,
+			fields: map[string]influxql.DataType{"value": influxql.Integer},
+			err:    true,
+		},
+		{
+			mode:   tsdb.FieldTypeConflictCoerce,
+			fields: map[string]influxql.DataType{"value": influxql.Integer},
+			err:    true,
+		},
+		{
+			mode: tsdb.FieldTypeConflictRename,
+			fields: map[string]influxql.DataType{
+				"value":       influxql.Integer,
+				"value_float": influxql.Float,
+				"value_str":   influxql.String,
+			},
+		},
+	} {
+		t.Run(tt.mode, func(t *testing.T) {
+			tmpDir, _ := ioutil.TempDir("", "shard_test")
+			defer os.RemoveAll(tmpDir)
+			tmpShard := path.Join(tmpDir, "shard")
+			tmpWal := path.Join(tmpDir, "wal")
+
+			opts := tsdb.NewEngineOptions()
+			opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+			opts.Config.FieldTypeConflict = tt.mode
+			opts.InmemIndex = inmem.NewIndex(path.Base(tmpDir))
+
+			sh := tsdb.NewShard(1, tmpShard, tmpWal, opts)
+			if err := sh.Open(); err != nil {
+				t.Fatalf("error opening shard: %s", err.Error())
+			}
+			defer sh.Close()
+
+			if err := sh.WritePoints(MustParsePointsString("cpu,host=server value=1i 1")); err != nil {
+				t.Fatal(err)
+			}
+
+			// A whole float is coerced to an integer, but not a fractional one.
+			err := sh.WritePoints(MustParsePointsString("cpu,host=server value=2 2\ncpu,host=server value=2.5 3"))
+			if tt.mode == tsdb.FieldTypeConflictCoerce {
+				if err == nil || !strings.Contains(err.Error(), "field type conflict") {
+					t.Fatalf("unexpected error: %v", err)
+				} else if e, ok := err.(tsdb.PartialWriteError); !ok || e.Dropped != 1 {
+					t.Fatalf("unexpected error: %#v", err)
+				}
+			} else if tt.mode == tsdb.FieldTypeConflictReject && err == nil {
+				t.Fatal("expected error")
+			} else if tt.mode == tsdb.FieldTypeConflictRename && err != nil {
+				t.Fatal(err)
+			}
+
+			err = sh.WritePoints(MustParsePointsString(`cpu,host=server value="x" 4`))
+			if tt.err && err == nil {
+				t.Fatal("expected error")
+			} else if !tt.err && err != nil {
+				t.Fatal(err)
+			}
+
+			mf := sh.MeasurementFields([]byte("cpu"))
+			if got, exp := mf.FieldSet(), tt.fields; !deep.Equal(got, exp) {
+				t.Fatalf("unexpected fields:\n\nexp=%v\n\ngot=%v\n\n", exp, got)
+			}
+		})
+	}
+}
+
 // Tests concurrently writing to the same shard with different field types which
 // can trigger a panic when the shard is snapshotted to TSM files.
 func TestShard_WritePoints_FieldConflictConcurrent(t *testing.T) {
@@ -1578,3 +1661,12 @@ func (sh *Shard) MustWritePointsString(s string) {
 		panic(err)
 	}
 }
+
+// MustParsePointsString parses the line protocol. Panic on error.
+func MustParsePointsString(s string) []models.Point {
+	a, err := models.ParsePointsString(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
@@ -18,9 +18,11 @@ import (
 	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/pkg/bytesutil"
+	"github.com/influxdata/influxdb/pkg/encryption"
 	"github.com/influxdata/influxdb/pkg/estimator"
 	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/tsdb/tiering"
 	"github.com/uber-go/zap"
 )
 
@@ -53,6 +55,12 @@ type Store struct {
 
 	EngineOptions EngineOptions
 
+	// retentionIO throttles the removal of deleted shards.
+	retentionIO *limiter.IOSubsystem
+
+	// scrubIO throttles the scrubber.
+	scrubIO *limiter.IOSubsystem
+
 	baseLogger zap.Logger
 	Logger     zap.Logger
 
@@ -123,6 +131,13 @@ func (s *Store) Statistics(tags map[string]string) []models.Statistic {
 	return statistics
 }
 
+// Opened returns true if the store is open.
+func (s *Store) Opened() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.opened
+}
+
 // Path returns the store's root path.
 func (s *Store) Path() string { return s.path }
 
@@ -142,6 +157,14 @@ func (s *Store) Open() error {
 		return err
 	}
 
+	if err := s.openTiering(); err != nil {
+		return err
+	}
+
+	if err := s.openEncryption(); err != nil {
+		return err
+	}
+
 	if err := s.loadShards(); err != nil {
 		return err
 	}
@@ -150,6 +173,66 @@ func (s *Store) Open() error {
 	s.wg.Add(1)
 	go s.monitorShards()
 
+	if s.EngineOptions.Tiering != nil {
+		s.wg.Add(1)
+		go s.tierShards()
+	}
+
+	if s.EngineOptions.Config.ScrubEnabled {
+		s.wg.Add(1)
+		go s.scrubShards()
+	}
+
+	return nil
+}
+
+// openTiering opens the store the files of cold shards are offloaded to, if
+// tiering is enabled.
+func (s *Store) openTiering() error {
+	c := s.EngineOptions.Config
+	if c.TieringPath == "" {
+		return nil
+	}
+
+	objects, err := tiering.NewObjectStore(c.TieringPath, c.TieringS3Region, c.TieringS3Endpoint)
+	if err != nil {
+		return err
+	}
+
+	// The cache is kept out of the data directory, whose subdirectories are
+	// loaded as databases.
+	cacheDir := c.TieringCacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(filepath.Dir(s.path), "tiering-cache")
+	}
+
+	t := tiering.NewStore(objects, cacheDir, int64(c.TieringCacheMaxSize))
+	if err := t.Open(); err != nil {
+		return err
+	}
+	s.EngineOptions.Tiering = t
+	return nil
+}
+
+// openEncryption sets up the cipher TSM files and WAL segments are encrypted
+// with, if encryption is enabled.
+func (s *Store) openEncryption() error {
+	c := s.EngineOptions.Config
+	if c.EncryptionKeyProvider == "" {
+		return nil
+	}
+
+	p, err := encryption.NewKeyProvider(c.EncryptionKeyProvider, c.EncryptionKeySource)
+	if err != nil {
+		return err
+	}
+
+	// Fail now, rather than on the first write, if the current key is unavailable.
+	if _, err := p.CurrentKeyID(); err != nil {
+		return err
+	}
+
+	s.EngineOptions.Cipher = encryption.NewCipher(p)
 	return nil
 }
 
@@ -176,6 +259,20 @@ func (s *Store) loadShards() error {
 
 	s.EngineOptions.CompactionLimiter = limiter.NewFixed(lim)
 
+	if full := s.EngineOptions.Config.MaxConcurrentFullCompactions; full > 0 && full < lim {
+		s.EngineOptions.FullCompactionLimiter = limiter.NewFixed(full)
+	}
+
+	// Setup a shared IO budget for background IO.
+	c := s.EngineOptions.Config
+	iom := limiter.NewIOManager(int(c.IOLimit))
+	s.EngineOptions.CompactionIO = iom.Register("compaction", limiter.PriorityCompaction, int(c.CompactionIOLimit))
+	s.EngineOptions.BackupIO = iom.Register("backup", limiter.PriorityBackup, int(c.BackupIOLimit))
+	s.EngineOptions.RestoreIO = iom.Register("restore", limiter.PriorityRestore, int(c.RestoreIOLimit))
+	s.EngineOptions.WriteIO = iom.Register("write", limiter.PriorityWrite, 0)
+	s.retentionIO = iom.Register("retention", limiter.PriorityCompaction, int(c.RetentionIOLimit))
+	s.scrubIO = iom.Register("scrub", limiter.PriorityScrub, int(c.ScrubIOLimit))
+
 	t := limiter.NewFixed(runtime.GOMAXPROCS(0))
 	resC := make(chan *res)
 	var n int
@@ -311,6 +408,22 @@ func (s *Store) Close() error {
 	return nil
 }
 
+// Flush writes the cache of every shard to TSM files, so the WAL does not
+// have to be replayed when the store is opened again.  Closed and disabled
+// shards are skipped.
+func (s *Store) Flush() error {
+	s.mu.RLock()
+	shards := s.shardsSlice()
+	s.mu.RUnlock()
+
+	return s.walkShards(shards, func(sh *Shard) error {
+		if err := sh.WriteSnapshot(); err != nil && err != ErrEngineClosed && err != ErrShardDisabled {
+			return err
+		}
+		return nil
+	})
+}
+
 // createIndexIfNotExists returns a shared index for a database, if the inmem
 // index is being used. If the TSI index is being used, then this method is
 // basically a no-op.
@@ -328,6 +441,16 @@ func (s *Store) createIndexIfNotExists(name string) (interface{}, error) {
 	return idx, nil
 }
 
+// indexVersion returns the index of new shards of a database.
+func (s *Store) indexVersion(database string) string {
+	for _, name := range s.EngineOptions.Config.TSI1Databases {
+		if name == database {
+			return "tsi1"
+		}
+	}
+	return s.EngineOptions.IndexVersion
+}
+
 // Shard returns a shard by id.
 func (s *Store) Shard(id uint64) *Shard {
 	s.mu.RLock()
@@ -402,6 +525,7 @@ func (s *Store) CreateShard(database, retentionPolicy string, shardID uint64, en
 	// Copy index options and pass in shared index.
 	opt := s.EngineOptions
 	opt.InmemIndex = idx
+	opt.IndexVersion = s.indexVersion(database)
 
 	path := filepath.Join(s.path, database, retentionPolicy, strconv.FormatUint(shardID, 10))
 	shard := NewShard(shardID, path, walPath, opt)
@@ -455,7 +579,8 @@ func (s *Store) DeleteShard(shardID uint64) error {
 		return err
 	}
 
-	if err := os.RemoveAll(sh.path); err != nil {
+	s.deleteOffloaded(sh.path)
+	if err := removeThrottled(sh.path, s.retentionIO); err != nil {
 		return err
 	}
 
@@ -470,6 +595,37 @@ func (s *Store) DeleteShard(shardID uint64) error {
 	return nil
 }
 
+// deleteOffloaded deletes the offloaded files of the shards under path from
+// the tiering store. Errors are logged, as the local files are removed
+// regardless.
+func (s *Store) deleteOffloaded(path string) {
+	if s.EngineOptions.Tiering == nil {
+		return
+	}
+	if err := s.EngineOptions.Tiering.DeleteRefs(path); err != nil {
+		s.Logger.Warn(fmt.Sprintf("error deleting offloaded files of %s", path), zap.Error(err))
+	}
+}
+
+// removeThrottled removes path and its contents, waiting for the IO budget
+// for the size of each file before removing it.
+func removeThrottled(path string, throttle *limiter.IOSubsystem) error {
+	if throttle != nil {
+		if err := filepath.Walk(path, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			} else if fi.IsDir() {
+				return nil
+			}
+			throttle.Wait(int(fi.Size()))
+			return os.Remove(path)
+		}); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.RemoveAll(path)
+}
+
 // DeleteDatabase will close all shards associated with a database and remove the directory and files from disk.
 func (s *Store) DeleteDatabase(name string) error {
 	s.mu.RLock()
@@ -501,6 +657,7 @@ func (s *Store) DeleteDatabase(name string) error {
 		return fmt.Errorf("invalid database directory location for database '%s': %s", name, dbPath)
 	}
 
+	s.deleteOffloaded(dbPath)
 	if err := os.RemoveAll(dbPath); err != nil {
 		return err
 	}
@@ -559,6 +716,7 @@ func (s *Store) DeleteRetentionPolicy(database, name string) error {
 	}
 
 	// Remove the retention policy folder.
+	s.deleteOffloaded(rpPath)
 	if err := os.RemoveAll(filepath.Join(s.path, database, name)); err != nil {
 		return err
 	}
@@ -714,6 +872,98 @@ func (s *Store) DiskSize() (int64, error) {
 	return size, nil
 }
 
+// DatabaseStorage describes the storage used by the shards of a database.
+type DatabaseStorage struct {
+	Name              string    `json:"name"`
+	ShardN            int       `json:"shards"`
+	DiskBytes         int64     `json:"diskBytes"`
+	TSMFiles          int       `json:"tsmFiles"`
+	SeriesN           int64     `json:"seriesN"`
+	LastModified      time.Time `json:"lastModified"`
+	CompactionBacklog int64     `json:"compactionBacklog"`
+}
+
+// add adds the storage of a shard of the database.
+func (db *DatabaseStorage) add(st ShardStorage) {
+	db.ShardN++
+	db.DiskBytes += st.DiskBytes
+	db.TSMFiles += st.TSMFiles
+	db.CompactionBacklog += st.CompactionBacklog
+	if st.LastModified.After(db.LastModified) {
+		db.LastModified = st.LastModified
+	}
+}
+
+// ShardsStorage returns the storage used by each open shard, sorted by shard ID.
+func (s *Store) ShardsStorage() []ShardStorage {
+	s.mu.RLock()
+	shards := s.filterShards(nil)
+	s.mu.RUnlock()
+
+	a := make([]ShardStorage, 0, len(shards))
+	for _, sh := range shards {
+		st, err := sh.Storage()
+		if err != nil {
+			// The shard was closed.
+			continue
+		}
+		a = append(a, st)
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].ID < a[j].ID })
+	return a
+}
+
+// DatabaseStorage returns the storage used by the shards of the named
+// database.
+func (s *Store) DatabaseStorage(name string) (DatabaseStorage, error) {
+	s.mu.RLock()
+	shards := s.filterShards(byDatabase(name))
+	s.mu.RUnlock()
+
+	db := DatabaseStorage{Name: name}
+	for _, sh := range shards {
+		st, err := sh.Storage()
+		if err != nil {
+			// The shard was closed.
+			continue
+		}
+		db.add(st)
+	}
+
+	n, err := s.SeriesCardinality(name)
+	if err != nil {
+		return DatabaseStorage{}, err
+	}
+	db.SeriesN = n
+	return db, nil
+}
+
+// DatabasesStorage returns the storage used by the shards of each database,
+// sorted by name.  The series count of a database is its series cardinality,
+// as series are shared by its shards.
+func (s *Store) DatabasesStorage() ([]DatabaseStorage, error) {
+	var a []DatabaseStorage
+	for _, st := range s.ShardsStorage() {
+		i := sort.Search(len(a), func(i int) bool { return a[i].Name >= st.Database })
+		if i == len(a) || a[i].Name != st.Database {
+			a = append(a, DatabaseStorage{})
+			copy(a[i+1:], a[i:])
+			a[i] = DatabaseStorage{Name: st.Database}
+		}
+
+		a[i].add(st)
+	}
+
+	for i := range a {
+		n, err := s.SeriesCardinality(a[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		a[i].SeriesN = n
+	}
+	return a, nil
+}
+
 func (s *Store) estimateCardinality(dbName string, getSketches func(*Shard) (estimator.Sketch, estimator.Sketch, error)) (int64, error) {
 	var (
 		ss estimator.Sketch // Sketch estimating number of items.
@@ -911,6 +1161,37 @@ func (s *Store) DeleteSeries(database string, sources []influxql.Source, conditi
 	})
 }
 
+// DeleteMeasurementRange removes the points of a measurement between min and
+// max, inclusive, from the given shards. Shards that are not stored locally
+// are ignored.
+func (s *Store) DeleteMeasurementRange(shardIDs []uint64, name string, min, max int64) error {
+	shards := s.Shards(shardIDs)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Limit to 1 delete for each shard since expanding the measurement into the list
+	// of series keys can be very memory intensive if run concurrently.
+	limit := limiter.NewFixed(1)
+
+	return s.walkShards(shards, func(sh *Shard) error {
+		limit.Take()
+		defer limit.Release()
+
+		keys, err := sh.MeasurementSeriesKeysByExpr([]byte(name), nil)
+		if err != nil {
+			return err
+		} else if len(keys) == 0 {
+			return nil
+		}
+
+		if !bytesutil.IsSorted(keys) {
+			bytesutil.Sort(keys)
+		}
+		return sh.DeleteSeriesRange(keys, min, max)
+	})
+}
+
 // ExpandSources expands sources against all local shards.
 func (s *Store) ExpandSources(sources influxql.Sources) (influxql.Sources, error) {
 	shards := func() Shards {
@@ -1006,6 +1287,18 @@ type tagValues struct {
 	values [][]string
 }
 
+// truncate keeps the first n key/value pairs of tv.
+func (tv *tagValues) truncate(n int) {
+	for i := range tv.keys {
+		if len(tv.values[i]) >= n {
+			tv.values[i] = tv.values[i][:n]
+			tv.keys, tv.values = tv.keys[:i+1], tv.values[:i+1]
+			return
+		}
+		n -= len(tv.values[i])
+	}
+}
+
 // Is a slice of tagValues that can be sorted by measurement.
 type tagValuesSlice []tagValues
 
@@ -1015,6 +1308,15 @@ func (a tagValuesSlice) Less(i, j int) bool { return bytes.Compare(a[i].name, a[
 
 // TagValues returns the tag keys and values in the given database, matching the condition.
 func (s *Store) TagValues(auth query.Authorizer, database string, cond influxql.Expr) ([]TagValues, error) {
+	return s.TagValuesLimit(auth, database, cond, 0, 0)
+}
+
+// TagValuesLimit is like TagValues but skips the first offset values of each
+// measurement and returns at most limit values per measurement. The values
+// of each shard are still read from its index, but only the first
+// offset+limit of them are kept for merging. A limit of zero returns all
+// values.
+func (s *Store) TagValuesLimit(auth query.Authorizer, database string, cond influxql.Expr, offset, limit int) ([]TagValues, error) {
 	if cond == nil {
 		return nil, errors.New("a condition is required")
 	}
@@ -1056,8 +1358,16 @@ func (s *Store) TagValues(auth query.Authorizer, database string, cond influxql.
 
 	// If we're using the inmem index then all shards contain a duplicate
 	// version of the global index. We don't need to iterate over all shards
-	// since we have everything we need from the first shard.
-	if s.EngineOptions.IndexVersion == "inmem" && len(shards) > 0 {
+	// since we have everything we need from the first shard.  Databases
+	// being converted to tsi1 may have shards of both indexes.
+	inmemOnly := len(shards) > 0
+	for _, sh := range shards {
+		if sh.IndexType() != "inmem" {
+			inmemOnly = false
+			break
+		}
+	}
+	if inmemOnly {
 		shards = shards[:1]
 	}
 
@@ -1128,6 +1438,12 @@ func (s *Store) TagValues(auth query.Authorizer, database string, cond influxql.
 			result.keys = result.keys[:j]
 			result.values = result.values[:j]
 
+			// Values past the first offset+limit of this shard can't be
+			// in the merged result.
+			if limit > 0 {
+				result.truncate(offset + limit)
+			}
+
 			// only include result if there are keys with values
 			if len(result.keys) > 0 {
 				allResults = append(allResults, result)
@@ -1160,7 +1476,7 @@ func (s *Store) TagValues(auth query.Authorizer, database string, cond influxql.
 			return nil, fmt.Errorf("unexpected results returned engine. Got %d measurement sets for %d shards", got, exp)
 		}
 
-		nextResult := mergeTagValues(idxBuf, allResults[i:j+1]...)
+		nextResult := mergeTagValues(idxBuf, offset, limit, allResults[i:j+1]...)
 		i = j + 1
 		if len(nextResult.Values) > 0 {
 			result = append(result, nextResult)
@@ -1171,12 +1487,13 @@ func (s *Store) TagValues(auth query.Authorizer, database string, cond influxql.
 
 // mergeTagValues merges multiple sorted sets of temporary tagValues using a
 // direct k-way merge whilst also removing duplicated entries. The result is a
-// single TagValue type.
+// single TagValue type. The first offset entries are skipped and the merge
+// stops once limit entries have been collected, unless limit is zero.
 //
 // TODO(edd): a Tournament based merge (see: Knuth's TAOCP 5.4.1) might be more
 // appropriate at some point.
 //
-func mergeTagValues(valueIdxs [][2]int, tvs ...tagValues) TagValues {
+func mergeTagValues(valueIdxs [][2]int, offset, limit int, tvs ...tagValues) TagValues {
 	var result TagValues
 	if len(tvs) == 0 {
 		return TagValues{}
@@ -1185,9 +1502,16 @@ func mergeTagValues(valueIdxs [][2]int, tvs ...tagValues) TagValues {
 		// TODO(edd): will be too small likely. Find a hint?
 		result.Values = make([]KeyValue, 0, len(tvs[0].values))
 
+		var n int
 		for ki, key := range tvs[0].keys {
 			for _, value := range tvs[0].values[ki] {
+				if n++; n <= offset {
+					continue
+				}
 				result.Values = append(result.Values, KeyValue{Key: key, Value: value})
+				if limit > 0 && len(result.Values) >= limit {
+					return result
+				}
 			}
 		}
 		return result
@@ -1261,11 +1585,15 @@ func mergeTagValues(valueIdxs [][2]int, tvs ...tagValues) TagValues {
 			break
 		}
 
-		// Append the smallest KeyValue
-		result.Values = append(result.Values, KeyValue{
-			Key:   string(tvs[j].keys[valueIdxs[j][0]]),
-			Value: tvs[j].values[valueIdxs[j][0]][valueIdxs[j][1]],
-		})
+		// Append the smallest KeyValue unless it is within the offset.
+		if offset > 0 {
+			offset--
+		} else {
+			result.Values = append(result.Values, KeyValue{
+				Key:   string(tvs[j].keys[valueIdxs[j][0]]),
+				Value: tvs[j].values[valueIdxs[j][0]][valueIdxs[j][1]],
+			})
+		}
 		// Increment the indexes for the chosen TagValue.
 		valueIdxs[j][1]++
 		if valueIdxs[j][1] >= len(tvs[j].values[valueIdxs[j][0]]) {
@@ -1273,10 +1601,89 @@ func mergeTagValues(valueIdxs [][2]int, tvs ...tagValues) TagValues {
 			valueIdxs[j][0]++
 			valueIdxs[j][1] = 0
 		}
+
+		if limit > 0 && len(result.Values) >= limit {
+			break
+		}
 	}
 	return result
 }
 
+// tierShards periodically offloads the files of shards that have not been
+// written to for the tiering age.
+func (s *Store) tierShards() {
+	defer s.wg.Done()
+	c := s.EngineOptions.Config
+	t := time.NewTicker(time.Duration(c.TieringCheckInterval))
+	defer t.Stop()
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-t.C:
+			cutoff := time.Now().Add(-time.Duration(c.TieringAge))
+			s.mu.RLock()
+			shards := s.filterShards(func(sh *Shard) bool {
+				return sh.IsIdle() && sh.LastModified().Before(cutoff)
+			})
+			s.mu.RUnlock()
+
+			for _, sh := range shards {
+				select {
+				case <-s.closing:
+					return
+				default:
+				}
+
+				n, err := sh.Offload()
+				if err != nil {
+					s.Logger.Warn(fmt.Sprintf("error offloading shard %d", sh.id), zap.Error(err))
+				} else if n > 0 {
+					s.Logger.Info(fmt.Sprintf("Offloaded %d files of shard %d", n, sh.id))
+				}
+			}
+		}
+	}
+}
+
+// scrubShards checks the blocks of the TSM files of all shards against their
+// checksums, pausing for the scrub interval between passes.
+func (s *Store) scrubShards() {
+	defer s.wg.Done()
+	c := s.EngineOptions.Config
+	for {
+		s.mu.RLock()
+		shards := s.filterShards(nil)
+		s.mu.RUnlock()
+
+		start := time.Now()
+		var corrupt int
+		for _, sh := range shards {
+			select {
+			case <-s.closing:
+				return
+			default:
+			}
+
+			n, err := sh.Scrub(s.scrubIO, c.ScrubQuarantineDir, s.closing)
+			if err == ErrEngineClosed || err == ErrShardDisabled {
+				// The shard was closed or deleted.
+				continue
+			} else if err != nil {
+				s.Logger.Warn(fmt.Sprintf("error scrubbing shard %d", sh.id), zap.Error(err))
+			}
+			corrupt += n
+		}
+		s.Logger.Info(fmt.Sprintf("Scrubbed %d shards in %s, found %d corrupt files", len(shards), time.Since(start), corrupt))
+
+		select {
+		case <-s.closing:
+			return
+		case <-time.After(time.Duration(c.ScrubInterval)):
+		}
+	}
+}
+
 func (s *Store) monitorShards() {
 	defer s.wg.Done()
 	t := time.NewTicker(10 * time.Second)
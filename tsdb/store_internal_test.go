@@ -114,13 +114,61 @@ func TestStore_mergeTagValues(t *testing.T) {
 	buf := make([][2]int, 10)
 	for i, example := range examples {
 		t.Run(fmt.Sprintf("example_%d", i+1), func(t *testing.T) {
-			if got, exp := mergeTagValues(buf, example.in...), example.out; !reflect.DeepEqual(got, exp) {
+			if got, exp := mergeTagValues(buf, 0, 0, example.in...), example.out; !reflect.DeepEqual(got, exp) {
 				t.Fatalf("\ngot\n %#v\n\n expected\n %#v", got, exp)
 			}
 		})
 	}
 }
 
+func TestStore_mergeTagValues_OffsetLimit(t *testing.T) {
+	single := []tagValues{
+		createtagValues("m0", map[string][]string{"host": {"server-a", "server-b", "server-c"}, "region": {"east"}}),
+	}
+	multi := []tagValues{
+		createtagValues("m0", map[string][]string{"host": {"server-a", "server-c"}, "region": {"east"}}),
+		createtagValues("m0", map[string][]string{"host": {"server-a", "server-b"}}),
+	}
+
+	for _, in := range [][]tagValues{single, multi} {
+		buf := make([][2]int, 10)
+		got := mergeTagValues(buf, 1, 2, in...)
+		exp := TagValues{
+			Measurement: "m0",
+			Values: []KeyValue{
+				{Key: "host", Value: "server-b"},
+				{Key: "host", Value: "server-c"},
+			},
+		}
+		if got.Measurement != exp.Measurement || !reflect.DeepEqual(got.Values, exp.Values) {
+			t.Fatalf("\ngot\n %#v\n\n expected\n %#v", got, exp)
+		}
+
+		if got := mergeTagValues(buf, 4, 0, in...); len(got.Values) != 0 {
+			t.Fatalf("unexpected values beyond offset: %#v", got.Values)
+		}
+	}
+}
+
+func TestStore_tagValues_truncate(t *testing.T) {
+	for _, tt := range []struct {
+		n    int
+		keys []string
+		vals [][]string
+	}{
+		{n: 1, keys: []string{"host"}, vals: [][]string{{"server-a"}}},
+		{n: 3, keys: []string{"host"}, vals: [][]string{{"server-a", "server-b", "server-c"}}},
+		{n: 4, keys: []string{"host", "region"}, vals: [][]string{{"server-a", "server-b", "server-c"}, {"east"}}},
+		{n: 10, keys: []string{"host", "region"}, vals: [][]string{{"server-a", "server-b", "server-c"}, {"east", "west"}}},
+	} {
+		tv := createtagValues("m0", map[string][]string{"host": {"server-a", "server-b", "server-c"}, "region": {"east", "west"}})
+		tv.truncate(tt.n)
+		if !reflect.DeepEqual(tv.keys, tt.keys) || !reflect.DeepEqual(tv.values, tt.vals) {
+			t.Fatalf("truncate(%d): got %v %v, exp %v %v", tt.n, tv.keys, tv.values, tt.keys, tt.vals)
+		}
+	}
+}
+
 // Helper to create some tagValues.
 func createtagValues(mname string, kvs map[string][]string) tagValues {
 	out := tagValues{
@@ -139,6 +139,38 @@ func TestStore_CreateShard(t *testing.T) {
 	}
 }
 
+// Ensure new shards of the databases opted in to tsi1 use it.
+func TestStore_CreateShard_TSI1Databases(t *testing.T) {
+	t.Parallel()
+
+	s := NewStore()
+	s.EngineOptions.IndexVersion = "inmem"
+	s.EngineOptions.Config.TSI1Databases = []string{"db1"}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.CreateShard("db0", "rp0", 1, true); err != nil {
+		t.Fatal(err)
+	} else if err := s.CreateShard("db1", "rp0", 2, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := s.Shard(1).IndexType(), "inmem"; got != exp {
+		t.Fatalf("unexpected index for db0: got %s, exp %s", got, exp)
+	} else if got, exp := s.Shard(2).IndexType(), "tsi1"; got != exp {
+		t.Fatalf("unexpected index for db1: got %s, exp %s", got, exp)
+	}
+
+	// Shards keep their index once the database is no longer opted in.
+	if err := s.Reopen(); err != nil {
+		t.Fatal(err)
+	} else if got, exp := s.Shard(2).IndexType(), "tsi1"; got != exp {
+		t.Fatalf("unexpected index for db1 after reopen: got %s, exp %s", got, exp)
+	}
+}
+
 // Ensure the store can delete an existing shard.
 func TestStore_DeleteShard(t *testing.T) {
 	t.Parallel()
@@ -490,6 +522,84 @@ func TestStore_BackupRestoreShard(t *testing.T) {
 	}
 }
 
+// Ensure the store reports the storage used by shards and databases.
+func TestStore_Storage(t *testing.T) {
+	test := func(index string) {
+		s := MustOpenStore(index)
+		defer s.Close()
+
+		s.MustCreateShardWithData("db0", "rp0", 1, `cpu,host=a value=1 0`, `cpu,host=b value=1 0`)
+		s.MustCreateShardWithData("db0", "rp0", 2, `cpu,host=a value=1 10`)
+		s.MustCreateShardWithData("db1", "rp0", 3, `mem value=1 0`)
+
+		shards := s.ShardsStorage()
+		if got, exp := len(shards), 3; got != exp {
+			t.Fatalf("unexpected shards: got %d, exp %d", got, exp)
+		}
+		for i, sh := range shards {
+			if sh.ID != uint64(i+1) {
+				t.Fatalf("unexpected shard id: %d", sh.ID)
+			} else if sh.DiskBytes <= 0 {
+				t.Fatalf("unexpected disk bytes for shard %d: %d", sh.ID, sh.DiskBytes)
+			}
+		}
+		if shards[0].Database != "db0" || shards[0].RetentionPolicy != "rp0" || shards[0].SeriesN != 2 {
+			t.Fatalf("unexpected shard: %+v", shards[0])
+		}
+
+		databases, err := s.DatabasesStorage()
+		if err != nil {
+			t.Fatal(err)
+		} else if got, exp := len(databases), 2; got != exp {
+			t.Fatalf("unexpected databases: got %d, exp %d", got, exp)
+		}
+		if db := databases[0]; db.Name != "db0" || db.ShardN != 2 || db.SeriesN != 2 || db.DiskBytes != shards[0].DiskBytes+shards[1].DiskBytes {
+			t.Fatalf("unexpected database: %+v", db)
+		} else if db := databases[1]; db.Name != "db1" || db.ShardN != 1 || db.SeriesN != 1 {
+			t.Fatalf("unexpected database: %+v", db)
+		}
+
+		if db, err := s.DatabaseStorage("db0"); err != nil {
+			t.Fatal(err)
+		} else if db != databases[0] {
+			t.Fatalf("unexpected database: %+v", db)
+		}
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) { test(index) })
+	}
+}
+
+// Ensure the cache of every shard is written to TSM files when flushed.
+func TestStore_Flush(t *testing.T) {
+	test := func(index string) {
+		s := MustOpenStore(index)
+		defer s.Close()
+
+		s.MustCreateShardWithData("db0", "rp0", 1, `cpu,host=a value=1 0`)
+		s.MustCreateShardWithData("db0", "rp0", 2, `cpu,host=a value=1 10`)
+		if err := s.SetShardEnabled(2, false); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		shards := s.ShardsStorage()
+		if got, exp := shards[0].TSMFiles, 1; got != exp {
+			t.Fatalf("unexpected TSM files for shard 1: got %d, exp %d", got, exp)
+		} else if got, exp := shards[1].TSMFiles, 0; got != exp {
+			t.Fatalf("unexpected TSM files for disabled shard 2: got %d, exp %d", got, exp)
+		}
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) { test(index) })
+	}
+}
+
 func TestStore_MeasurementNames_Deduplicate(t *testing.T) {
 	t.Parallel()
 
@@ -530,6 +640,71 @@ func TestStore_MeasurementNames_Deduplicate(t *testing.T) {
 	}
 }
 
+// Ensure the store can delete a time range of a measurement from some shards.
+func TestStore_DeleteMeasurementRange(t *testing.T) {
+	t.Parallel()
+
+	test := func(index string) {
+		s := MustOpenStore(index)
+		defer s.Close()
+
+		for _, id := range []int{1, 2} {
+			s.MustCreateShardWithData("db0", "rp0", id,
+				`cpu value=1 0`,
+				`cpu value=2 10`,
+				`cpu value=3 20`,
+				`mem value=1 0`,
+			)
+		}
+
+		// Delete the first two points of cpu from shard 1. Unknown shards are ignored.
+		if err := s.DeleteMeasurementRange([]uint64{1, 3}, "cpu", influxql.MinTime, int64(10*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tt := range []struct {
+			id    uint64
+			name  string
+			times []int64
+		}{
+			{id: 1, name: "cpu", times: []int64{int64(20 * time.Second)}},
+			{id: 1, name: "mem", times: []int64{0}},
+			{id: 2, name: "cpu", times: []int64{0, int64(10 * time.Second), int64(20 * time.Second)}},
+		} {
+			itr, err := s.Shard(tt.id).CreateIterator(context.Background(), tt.name, query.IteratorOptions{
+				Expr:      influxql.MustParseExpr(`value`),
+				Ascending: true,
+				StartTime: influxql.MinTime,
+				EndTime:   influxql.MaxTime,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var times []int64
+			fitr := itr.(query.FloatIterator)
+			for {
+				p, err := fitr.Next()
+				if err != nil {
+					t.Fatal(err)
+				} else if p == nil {
+					break
+				}
+				times = append(times, p.Time)
+			}
+			itr.Close()
+
+			if !reflect.DeepEqual(times, tt.times) {
+				t.Fatalf("shard %d: unexpected times for %s: exp=%v got=%v", tt.id, tt.name, tt.times, times)
+			}
+		}
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) { test(index) })
+	}
+}
+
 func testStoreCardinalityTombstoning(t *testing.T, store *Store) {
 	// Generate point data to write to the shards.
 	series := genTestSeries(10, 2, 4) // 160 series
@@ -0,0 +1,190 @@
+package tiering
+
+import (
+	"container/list"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Cache keeps chunks of offloaded files on local disk, evicting the least
+// recently used chunks once it exceeds its maximum size.
+type Cache struct {
+	mu      sync.Mutex
+	dir     string
+	maxSize int64
+	size    int64
+
+	// lru holds the names of the chunks, most recently used first.
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+// cacheEntry is a chunk stored in the cache.
+type cacheEntry struct {
+	name string
+	size int64
+}
+
+// NewCache returns a cache storing up to maxSize bytes in dir. A maxSize of
+// 0 disables caching.
+func NewCache(dir string, maxSize int64) *Cache {
+	return &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Open loads the chunks cached in the directory, least recently modified
+// first, and removes incomplete ones.
+func (c *Cache) Open() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxSize == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.dir, 0777); err != nil {
+		return err
+	}
+
+	fis, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(fis, func(i, j int) bool { return fis[i].ModTime().Before(fis[j].ModTime()) })
+
+	for _, fi := range fis {
+		if fi.IsDir() {
+			continue
+		} else if isTempFile(fi.Name()) {
+			if err := os.Remove(filepath.Join(c.dir, fi.Name())); err != nil {
+				return err
+			}
+			continue
+		}
+		c.add(fi.Name(), fi.Size())
+	}
+	return c.evict()
+}
+
+// Size returns the total size of the cached chunks.
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Get returns the chunk with the given name, if it is cached.
+func (c *Cache) Get(name string) ([]byte, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[name]
+	if ok {
+		c.lru.MoveToFront(e)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	buf, err := ioutil.ReadFile(filepath.Join(c.dir, name))
+	if err != nil {
+		// The chunk is fetched again if its file can't be read.
+		c.remove(name)
+		return nil, false
+	}
+	return buf, true
+}
+
+// Put caches a chunk, evicting the least recently used chunks if the cache
+// exceeds its maximum size.
+func (c *Cache) Put(name string, buf []byte) error {
+	if c.maxSize == 0 || int64(len(buf)) > c.maxSize {
+		return nil
+	}
+
+	// Write the chunk to a temporary file so that incomplete chunks are
+	// never read.
+	path := filepath.Join(c.dir, name)
+	if err := ioutil.WriteFile(path+".tmp", buf, 0666); err != nil {
+		return err
+	} else if err := os.Rename(path+".tmp", path); err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[name]; ok {
+		c.size -= e.Value.(*cacheEntry).size
+		c.lru.Remove(e)
+		delete(c.entries, name)
+	}
+	c.add(name, int64(len(buf)))
+	return c.evict()
+}
+
+// RemovePrefix removes the chunks whose names start with prefix.
+func (c *Cache) RemovePrefix(prefix string) error {
+	c.mu.Lock()
+	var names []string
+	for name := range c.entries {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, name := range names {
+		if err := c.remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add adds a chunk as the most recently used. The lock must be held.
+func (c *Cache) add(name string, size int64) {
+	c.entries[name] = c.lru.PushFront(&cacheEntry{name: name, size: size})
+	c.size += size
+}
+
+// evict removes the least recently used chunks until the cache fits its
+// maximum size. The lock must be held.
+func (c *Cache) evict() error {
+	for c.size > c.maxSize {
+		e := c.lru.Back()
+		entry := e.Value.(*cacheEntry)
+		c.lru.Remove(e)
+		delete(c.entries, entry.name)
+		c.size -= entry.size
+
+		if err := os.Remove(filepath.Join(c.dir, entry.name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove removes a chunk from the cache.
+func (c *Cache) remove(name string) error {
+	c.mu.Lock()
+	if e, ok := c.entries[name]; ok {
+		c.size -= e.Value.(*cacheEntry).size
+		c.lru.Remove(e)
+		delete(c.entries, name)
+	}
+	c.mu.Unlock()
+
+	if err := os.Remove(filepath.Join(c.dir, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
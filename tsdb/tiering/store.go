@@ -0,0 +1,181 @@
+package tiering
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultChunkSize is the size of the chunks offloaded files are read and
+// cached in.
+const DefaultChunkSize = 1 << 20
+
+// Store offloads files to an object store and reads them back through a
+// local cache.
+type Store struct {
+	objects ObjectStore
+	cache   *Cache
+
+	// ChunkSize is the size of the chunks files are read and cached in.
+	ChunkSize int64
+}
+
+// NewStore returns a store keeping files in objects and caching up to
+// cacheMaxSize bytes of them in cacheDir.
+func NewStore(objects ObjectStore, cacheDir string, cacheMaxSize int64) *Store {
+	return &Store{
+		objects:   objects,
+		cache:     NewCache(cacheDir, cacheMaxSize),
+		ChunkSize: DefaultChunkSize,
+	}
+}
+
+// Open opens the cache of the store.
+func (s *Store) Open() error {
+	return s.cache.Open()
+}
+
+// Cache returns the cache of the store.
+func (s *Store) Cache() *Cache { return s.cache }
+
+// Upload stores the file at path under key and returns a ref to it.
+func (s *Store) Upload(key, path string) (Ref, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Ref{}, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return Ref{}, err
+	}
+
+	if err := s.objects.Put(key, f); err != nil {
+		return Ref{}, fmt.Errorf("upload %s: %s", path, err)
+	}
+	return Ref{Key: key, Size: fi.Size()}, nil
+}
+
+// Download writes the file a ref refers to to w.
+func (s *Store) Download(ref Ref, w io.Writer) error {
+	rc, err := s.objects.Get(ref.Key)
+	if err != nil {
+		return fmt.Errorf("download %s: %s", ref.Key, err)
+	}
+	defer rc.Close()
+
+	if n, err := io.Copy(w, rc); err != nil {
+		return fmt.Errorf("download %s: %s", ref.Key, err)
+	} else if n != ref.Size {
+		return fmt.Errorf("download %s: read %d bytes, expected %d", ref.Key, n, ref.Size)
+	}
+	return nil
+}
+
+// Object returns a reader of the file a ref refers to. Reads fetch the
+// chunks they need from the object store and cache them.
+func (s *Store) Object(ref Ref) io.ReaderAt {
+	return &object{store: s, ref: ref, prefix: chunkPrefix(ref.Key)}
+}
+
+// Delete removes the object stored under key and its cached chunks.
+func (s *Store) Delete(key string) error {
+	if err := s.cache.RemovePrefix(chunkPrefix(key)); err != nil {
+		return err
+	}
+	return s.objects.Delete(key)
+}
+
+// DeleteRefs removes the objects referred to by the ref files in dir and
+// its subdirectories.
+func (s *Store) DeleteRefs(dir string) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		} else if fi.IsDir() || !strings.HasSuffix(path, "."+RefExtension) {
+			return nil
+		}
+
+		ref, err := ReadRef(path)
+		if err != nil {
+			return err
+		}
+		return s.Delete(ref.Key)
+	})
+}
+
+// chunkPrefix returns the prefix of the names of the cached chunks of the
+// object stored under key.
+func chunkPrefix(key string) string {
+	h := sha1.Sum([]byte(key))
+	return hex.EncodeToString(h[:]) + "-"
+}
+
+// object reads an offloaded file in chunks.
+type object struct {
+	store  *Store
+	ref    Ref
+	prefix string
+}
+
+// ReadAt reads len(p) bytes from offset off, reading each chunk from the
+// cache or fetching it from the object store.
+func (o *object) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("read %s: negative offset", o.ref.Key)
+	}
+
+	chunkSize := o.store.ChunkSize
+	var n int
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= o.ref.Size {
+			return n, io.EOF
+		}
+
+		idx := pos / chunkSize
+		chunk, err := o.chunk(idx)
+		if err != nil {
+			return n, err
+		}
+
+		start := pos - idx*chunkSize
+		if start >= int64(len(chunk)) {
+			return n, io.EOF
+		}
+		n += copy(p[n:], chunk[start:])
+	}
+	return n, nil
+}
+
+// chunk returns the chunk with the given index.
+func (o *object) chunk(idx int64) ([]byte, error) {
+	name := fmt.Sprintf("%s%d", o.prefix, idx)
+	if buf, ok := o.store.cache.Get(name); ok {
+		return buf, nil
+	}
+
+	off := idx * o.store.ChunkSize
+	size := o.store.ChunkSize
+	if off+size > o.ref.Size {
+		size = o.ref.Size - off
+	}
+
+	buf := make([]byte, size)
+	n, err := o.store.objects.ReadAt(o.ref.Key, buf, off)
+	if err != nil && !(err == io.EOF && int64(n) == size) {
+		return nil, fmt.Errorf("read %s: %s", o.ref.Key, err)
+	}
+
+	// A chunk that can't be cached is still returned.
+	o.store.cache.Put(name, buf)
+	return buf, nil
+}
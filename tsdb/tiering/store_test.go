@@ -0,0 +1,181 @@
+package tiering_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influxdb/tsdb/tiering"
+)
+
+func TestStore_Object(t *testing.T) {
+	s := MustOpenStore(1 << 20)
+	defer s.Close()
+
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	ref := s.MustUpload("db/rp/1/000000001-000000001.tsm", data)
+	if got, exp := ref.Size, int64(len(data)); got != exp {
+		t.Fatalf("unexpected size: got %d, exp %d", got, exp)
+	}
+
+	obj := s.Object(ref)
+
+	// Read across chunks.
+	buf := make([]byte, 250)
+	if n, err := obj.ReadAt(buf, 90); err != nil {
+		t.Fatal(err)
+	} else if n != len(buf) {
+		t.Fatalf("unexpected read length: %d", n)
+	} else if !bytes.Equal(buf, data[90:340]) {
+		t.Fatal("unexpected data")
+	}
+
+	// Read past the end of the file.
+	if n, err := obj.ReadAt(buf, 900); err != io.EOF {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n != 100 || !bytes.Equal(buf[:n], data[900:]) {
+		t.Fatalf("unexpected data: %d bytes", n)
+	}
+
+	// The chunks read are cached, including the short last chunk.
+	if got, exp := s.Cache().Size(), int64(500); got != exp {
+		t.Fatalf("unexpected cache size: got %d, exp %d", got, exp)
+	}
+
+	// Cached chunks are read without the object.
+	if err := os.RemoveAll(s.objectsDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := obj.ReadAt(buf[:10], 100); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(buf[:10], data[100:110]) {
+		t.Fatal("unexpected cached data")
+	}
+	if _, err := obj.ReadAt(buf[:10], 500); err == nil {
+		t.Fatal("expected error reading uncached chunk")
+	}
+}
+
+func TestStore_Cache_Evict(t *testing.T) {
+	s := MustOpenStore(300)
+	defer s.Close()
+
+	ref := s.MustUpload("a.tsm", make([]byte, 1000))
+	obj := s.Object(ref)
+
+	buf := make([]byte, 1)
+	for _, off := range []int64{0, 100, 200, 300} {
+		if _, err := obj.ReadAt(buf, off); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The least recently used chunk is evicted.
+	if got, exp := s.Cache().Size(), int64(300); got != exp {
+		t.Fatalf("unexpected cache size: got %d, exp %d", got, exp)
+	}
+	fis, err := ioutil.ReadDir(s.cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(fis) != 3 {
+		t.Fatalf("unexpected cached chunks: %d", len(fis))
+	}
+
+	// Reopening the cache restores it, evicting to the new maximum size.
+	c := tiering.NewCache(s.cacheDir, 200)
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	} else if got, exp := c.Size(), int64(200); got != exp {
+		t.Fatalf("unexpected cache size: got %d, exp %d", got, exp)
+	}
+}
+
+func TestStore_DeleteRefs(t *testing.T) {
+	s := MustOpenStore(1 << 20)
+	defer s.Close()
+
+	ref := s.MustUpload("db/rp/1/000000001-000000001.tsm", []byte("data"))
+	if _, err := s.Object(ref).ReadAt(make([]byte, 4), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(s.root, "data", "db", "rp", "1")
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	} else if err := tiering.WriteRef(filepath.Join(dir, "000000001-000000001.remote"), ref); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := tiering.ReadRef(filepath.Join(dir, "000000001-000000001.remote")); err != nil {
+		t.Fatal(err)
+	} else if got != ref {
+		t.Fatalf("unexpected ref: %+v", got)
+	}
+
+	if err := s.DeleteRefs(filepath.Join(s.root, "data", "db")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(s.objectsDir, "db/rp/1/000000001-000000001.tsm")); !os.IsNotExist(err) {
+		t.Fatalf("expected object to be deleted: %v", err)
+	} else if got := s.Cache().Size(); got != 0 {
+		t.Fatalf("expected cached chunks to be deleted: %d bytes", got)
+	}
+}
+
+// Store is a test wrapper for tiering.Store using a directory object store.
+type Store struct {
+	*tiering.Store
+	root       string
+	objectsDir string
+	cacheDir   string
+}
+
+// MustOpenStore returns an open store reading chunks of 100 bytes and
+// caching up to cacheMaxSize bytes of them.
+func MustOpenStore(cacheMaxSize int64) *Store {
+	root, err := ioutil.TempDir("", "tiering-")
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Store{
+		root:       root,
+		objectsDir: filepath.Join(root, "objects"),
+		cacheDir:   filepath.Join(root, "cache"),
+	}
+
+	objects, err := tiering.NewObjectStore(s.objectsDir, "", "")
+	if err != nil {
+		panic(err)
+	}
+	s.Store = tiering.NewStore(objects, s.cacheDir, cacheMaxSize)
+	s.ChunkSize = 100
+	if err := s.Open(); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Close removes the directories of the store.
+func (s *Store) Close() error {
+	return os.RemoveAll(s.root)
+}
+
+// MustUpload uploads data under key.
+func (s *Store) MustUpload(key string, data []byte) tiering.Ref {
+	path := filepath.Join(s.root, "upload")
+	if err := ioutil.WriteFile(path, data, 0666); err != nil {
+		panic(err)
+	}
+	ref, err := s.Upload(key, path)
+	if err != nil {
+		panic(err)
+	}
+	return ref
+}
@@ -0,0 +1,173 @@
+// Package tiering offloads the files of cold shards to object storage and
+// reads them back on demand through a local cache.
+package tiering // import "github.com/influxdata/influxdb/tsdb/tiering"
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/influxdb/pkg/s3"
+)
+
+// RefExtension is the extension of the files that refer to offloaded files.
+const RefExtension = "remote"
+
+// ObjectStore stores the objects offloaded files are kept in.
+type ObjectStore interface {
+	// Put stores the contents of r under key.
+	Put(key string, r io.ReadSeeker) error
+
+	// Get returns a reader of the object stored under key.
+	Get(key string) (io.ReadCloser, error)
+
+	// ReadAt reads len(p) bytes of the object stored under key starting at
+	// offset off. It returns io.EOF if fewer bytes are available.
+	ReadAt(key string, p []byte, off int64) (int, error)
+
+	// Delete removes the object stored under key.
+	Delete(key string) error
+}
+
+// NewObjectStore returns the object store at path, which is either a local
+// directory or an s3://bucket/prefix or gs://bucket/prefix location.
+func NewObjectStore(path, region, endpoint string) (ObjectStore, error) {
+	if !s3.IsURL(path) {
+		return &dirStore{path: path}, nil
+	}
+
+	client, prefix, err := s3.ParseURL(path, region, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{client: client, prefix: prefix}, nil
+}
+
+// Ref refers to an offloaded file.
+type Ref struct {
+	// Key is the key of the object the file is stored in.
+	Key string `json:"key"`
+
+	// Size is the size of the file.
+	Size int64 `json:"size"`
+}
+
+// ReadRef reads the ref file at path.
+func ReadRef(path string) (Ref, error) {
+	var ref Ref
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ref, err
+	}
+	if err := json.Unmarshal(buf, &ref); err != nil {
+		return ref, fmt.Errorf("invalid ref file %s: %s", path, err)
+	}
+	return ref, nil
+}
+
+// WriteRef writes a ref file to path and syncs it.
+func WriteRef(path string, ref Ref) error {
+	buf, err := json.Marshal(ref)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(buf); err != nil {
+		return err
+	} else if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// dirStore stores objects as files in a local directory, such as a mount
+// of network storage.
+type dirStore struct {
+	path string
+}
+
+func (s *dirStore) objectPath(key string) string {
+	return filepath.Join(s.path, filepath.FromSlash(key))
+}
+
+// Put writes the object to a temporary file and renames it once complete.
+func (s *dirStore) Put(key string, r io.ReadSeeker) error {
+	p := s.objectPath(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+		return err
+	}
+
+	f, err := os.Create(p + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return err
+	} else if err := f.Sync(); err != nil {
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+func (s *dirStore) Get(key string) (io.ReadCloser, error) {
+	return os.Open(s.objectPath(key))
+}
+
+func (s *dirStore) ReadAt(key string, p []byte, off int64) (int, error) {
+	f, err := os.Open(s.objectPath(key))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return f.ReadAt(p, off)
+}
+
+func (s *dirStore) Delete(key string) error {
+	if err := os.Remove(s.objectPath(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// s3Store stores objects in an S3 or Google Cloud Storage bucket.
+type s3Store struct {
+	client *s3.Client
+	prefix string
+}
+
+func (s *s3Store) Put(key string, r io.ReadSeeker) error {
+	return s.client.Put(path.Join(s.prefix, key), r)
+}
+
+func (s *s3Store) Get(key string) (io.ReadCloser, error) {
+	return s.client.Get(path.Join(s.prefix, key))
+}
+
+func (s *s3Store) ReadAt(key string, p []byte, off int64) (int, error) {
+	return s.client.ReadAt(path.Join(s.prefix, key), p, off)
+}
+
+func (s *s3Store) Delete(key string) error {
+	return s.client.Delete(path.Join(s.prefix, key))
+}
+
+// isTempFile returns true if name is a temporary file.
+func isTempFile(name string) bool {
+	return strings.HasSuffix(name, ".tmp")
+}